@@ -0,0 +1,240 @@
+// Command mcpsearch is an end-to-end example of a paid search MCP server and
+// a client paying for it, settled by a real facilitator.
+//
+// The server prices each search by its max_results argument, bills payments
+// per MCP session with a per-session cap, and publishes the bills at
+// /billing. The client pays within a total budget, restricted to the
+// server's network. The reusable parts live in the search package.
+//
+// Usage:
+//
+//	mcpsearch server --pay-to <address> [flags]
+//	mcpsearch client --key <private key> [flags]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"time"
+
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/x402-go/examples/v2/mcpsearch/search"
+	v2 "github.com/mark3labs/x402-go/v2"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+	"github.com/mark3labs/x402-go/v2/mcp/client"
+	"github.com/mark3labs/x402-go/v2/mcp/server"
+	"github.com/mark3labs/x402-go/v2/signers/evm"
+	"github.com/mark3labs/x402-go/v2/signers/svm"
+)
+
+// corpus is the example's document index.
+var corpus = []search.Document{
+	{ID: "x402", Title: "x402 protocol", Text: "x402 revives the HTTP 402 Payment Required status code for internet-native payments with stablecoins such as USDC."},
+	{ID: "caip2", Title: "CAIP-2 network identifiers", Text: "x402 v2 names networks with CAIP-2 identifiers like eip155:8453 for Base and solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp for Solana."},
+	{ID: "eip3009", Title: "EIP-3009 transfers", Text: "The exact scheme on EVM chains signs an EIP-3009 transferWithAuthorization that the facilitator submits, so payers need no gas."},
+	{ID: "facilitator", Title: "Facilitators", Text: "A facilitator verifies payment payloads and settles them on chain on behalf of the resource server."},
+	{ID: "mcp", Title: "Paid MCP tools", Text: "MCP servers charge for tool calls by returning a payment required error; clients retry the call with the payment in _meta."},
+	{ID: "rag", Title: "Retrieval augmented generation", Text: "RAG pipelines retrieve relevant passages from a search index and add them to the prompt of a language model."},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(1)
+	}
+	switch os.Args[1] {
+	case "server":
+		runServer(os.Args[2:])
+	case "client":
+		runClient(os.Args[2:])
+	default:
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Println("mcpsearch - Paid search MCP server and client with x402 v2")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  mcpsearch server [flags]  - Serve a search tool priced per requested result")
+	fmt.Println("  mcpsearch client [flags]  - Search the server within a budget")
+	fmt.Println()
+	fmt.Println("Run 'mcpsearch server --help' or 'mcpsearch client --help' for more information.")
+}
+
+func runServer(args []string) {
+	fs := flag.NewFlagSet("server", flag.ExitOnError)
+	port := fs.String("port", "8080", "Server port")
+	network := fs.String("network", v2.NetworkBaseSepolia, "Network to accept payments on (CAIP-2)")
+	payTo := fs.String("pay-to", "", "Address to receive payments (required)")
+	basePrice := fs.String("base-price", "1000", "Price of a search, in atomic units")
+	perResult := fs.String("per-result", "500", "Price per requested result, in atomic units")
+	maxResults := fs.Int64("max-results", 20, "Largest max_results accepted")
+	sessionLimit := fs.String("session-limit", "100000", "Most a session may spend, in atomic units (0 for no limit)")
+	facilitatorURL := fs.String("facilitator", "https://facilitator.x402.rs", "Facilitator URL")
+	_ = fs.Parse(args)
+
+	if *payTo == "" {
+		fmt.Println("Error: --pay-to is required")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	chain, err := v2.GetChainConfig(*network)
+	if err != nil {
+		log.Fatalf("Unsupported network: %v", err)
+	}
+	perResultAmount, ok := new(big.Int).SetString(*perResult, 10)
+	if !ok {
+		log.Fatalf("Invalid per-result price: %s", *perResult)
+	}
+
+	// Fetch facilitator data, like the Solana fee payer, into the requirement
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           *network,
+		Amount:            *basePrice,
+		Asset:             chain.USDCAddress,
+		PayTo:             *payTo,
+		MaxTimeoutSeconds: 60,
+		Extra: map[string]interface{}{
+			"name":    chain.EIP3009Name,
+			"version": chain.EIP3009Version,
+		},
+	}
+	facilitator := &v2http.FacilitatorClient{BaseURL: *facilitatorURL, Client: &http.Client{Timeout: 10 * time.Second}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	requirements, err := facilitator.EnrichRequirements(ctx, []v2.PaymentRequirements{requirement})
+	cancel()
+	if err != nil {
+		log.Printf("Warning: failed to enrich payment requirement: %v", err)
+	}
+
+	config := &server.Config{
+		FacilitatorURL:       *facilitatorURL,
+		PaymentNotifications: true,
+		Allowlist:            v2.PaymentAllowlist{{Network: *network, Asset: chain.USDCAddress}},
+	}
+	var billingOpts []server.SessionBillingOption
+	if limit, ok := new(big.Int).SetString(*sessionLimit, 10); ok && limit.Sign() > 0 {
+		billingOpts = append(billingOpts, server.WithSessionLimit(limit))
+	}
+	billing := server.NewSessionBilling(billingOpts...)
+	billing.Install(config)
+
+	srv := server.NewX402Server("x402-search", "1.0.0", config)
+	err = search.Register(srv, search.NewIndex(corpus...), search.Pricing{
+		Requirements:   requirements,
+		PerResult:      perResultAmount,
+		DefaultResults: 5,
+		MaxResults:     *maxResults,
+	})
+	if err != nil {
+		log.Fatalf("Failed to add search tool: %v", err)
+	}
+
+	handler, err := srv.Handler()
+	if err != nil {
+		log.Fatalf("Failed to create handler: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", handler)
+	mux.HandleFunc("/billing", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(billing.Sessions())
+	})
+
+	fmt.Printf("Search server on :%s/mcp (network %s, facilitator %s)\n", *port, *network, *facilitatorURL)
+	fmt.Printf("Price: %s + %s per requested result; session bills at /billing\n", *basePrice, *perResult)
+	log.Fatal(http.ListenAndServe(":"+*port, mux))
+}
+
+func runClient(args []string) {
+	fs := flag.NewFlagSet("client", flag.ExitOnError)
+	network := fs.String("network", v2.NetworkBaseSepolia, "Network to pay on (CAIP-2)")
+	key := fs.String("key", "", "Private key (hex for EVM, base58 for Solana) (required)")
+	serverURL := fs.String("server", "http://localhost:8080/mcp", "MCP server URL")
+	query := fs.String("query", "x402 facilitator", "Search query")
+	maxResults := fs.Int("max-results", 3, "Results to request")
+	budget := fs.String("budget", "50000", "Most the client pays in total, in atomic units")
+	_ = fs.Parse(args)
+
+	if *key == "" {
+		fmt.Println("Error: --key is required")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	chain, err := v2.GetChainConfig(*network)
+	if err != nil {
+		log.Fatalf("Unsupported network: %v", err)
+	}
+	total, ok := new(big.Int).SetString(*budget, 10)
+	if !ok {
+		log.Fatalf("Invalid budget: %s", *budget)
+	}
+
+	tokens := []v2.TokenConfig{v2.NewUSDCTokenConfig(chain, 1)}
+	var signer v2.Signer
+	if networkType, _ := v2.ValidateNetwork(*network); networkType == v2.NetworkTypeSVM {
+		signer, err = svm.NewSigner(*network, *key, tokens)
+	} else {
+		signer, err = evm.NewSigner(*network, *key, tokens)
+	}
+	if err != nil {
+		log.Fatalf("Failed to create signer: %v", err)
+	}
+
+	// Pay at most the budget, and only with USDC on the chosen network
+	options, guard := search.Budget{
+		Total:   total,
+		Allowed: []v2.AllowedPayment{{Network: *network, Asset: chain.USDCAddress}},
+	}.Options()
+	options = append(options, client.WithSigner(signer), client.WithPaymentCallback(func(event v2.PaymentEvent) {
+		if event.Type == v2.PaymentEventSuccess {
+			log.Printf("Paid %s on %s: %s", event.Amount, event.Network, event.Transaction)
+		}
+	}))
+	transport, err := client.NewTransport(*serverURL, options...)
+	if err != nil {
+		log.Fatalf("Failed to create transport: %v", err)
+	}
+
+	ctx := context.Background()
+	mcpClient := mcpclient.NewClient(transport)
+	if err := mcpClient.Start(ctx); err != nil {
+		log.Fatalf("Failed to start client: %v", err)
+	}
+	defer mcpClient.Close()
+	if _, err := mcpClient.Initialize(ctx, mcp.InitializeRequest{
+		Params: mcp.InitializeParams{
+			ProtocolVersion: mcp.LATEST_PROTOCOL_VERSION,
+			ClientInfo:      mcp.Implementation{Name: "x402-search-client", Version: "1.0.0"},
+		},
+	}); err != nil {
+		log.Fatalf("Failed to initialize: %v", err)
+	}
+
+	result, err := mcpClient.CallTool(ctx, mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name:      search.ToolName,
+			Arguments: map[string]interface{}{"query": *query, "max_results": *maxResults},
+		},
+	})
+	if err != nil {
+		log.Fatalf("Search failed: %v", err)
+	}
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			fmt.Println(text.Text)
+		}
+	}
+	status := guard.Status()
+	fmt.Printf("Spent %s of %s\n", status.Spent, status.Limit)
+}
@@ -0,0 +1,204 @@
+// Package search serves a small retrieval index as a paid MCP tool.
+//
+// It packages the pattern of the mcpsearch example for embedding in other
+// servers and clients: Register adds the search tool to an X402Server, priced
+// per requested result and billed per MCP session, and Budget configures a
+// client transport that pays for it within limits. Index is a plain in-memory
+// TF-IDF index; replace it with any retriever by implementing Retriever.
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/mcp/client"
+	"github.com/mark3labs/x402-go/v2/mcp/server"
+)
+
+// ToolName is the name of the search tool.
+const ToolName = "search"
+
+// Document is a passage that can be retrieved.
+type Document struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Text  string `json:"text"`
+	URL   string `json:"url,omitempty"`
+}
+
+// Result is a retrieved document with its relevance score.
+type Result struct {
+	Document
+	Score float64 `json:"score"`
+}
+
+// Retriever returns the documents most relevant to a query, best first.
+type Retriever interface {
+	Search(ctx context.Context, query string, limit int) ([]Result, error)
+}
+
+// Index is an in-memory TF-IDF index. It is safe for concurrent use.
+type Index struct {
+	mu        sync.RWMutex
+	documents []Document
+	terms     []map[string]int
+	frequency map[string]int
+}
+
+// NewIndex creates an Index holding documents.
+func NewIndex(documents ...Document) *Index {
+	index := &Index{frequency: make(map[string]int)}
+	for _, document := range documents {
+		index.Add(document)
+	}
+	return index
+}
+
+// Add indexes document.
+func (i *Index) Add(document Document) {
+	terms := make(map[string]int)
+	for _, term := range tokenize(document.Title + " " + document.Text) {
+		terms[term]++
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.documents = append(i.documents, document)
+	i.terms = append(i.terms, terms)
+	for term := range terms {
+		i.frequency[term]++
+	}
+}
+
+// Search returns up to limit documents matching query, best first.
+func (i *Index) Search(_ context.Context, query string, limit int) ([]Result, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var results []Result
+	for d, terms := range i.terms {
+		score := 0.0
+		for _, term := range tokenize(query) {
+			if count := terms[term]; count > 0 {
+				idf := math.Log(1 + float64(len(i.documents))/float64(i.frequency[term]))
+				score += float64(count) * idf
+			}
+		}
+		if score > 0 {
+			results = append(results, Result{Document: i.documents[d], Score: score})
+		}
+	}
+	sort.SliceStable(results, func(a, b int) bool { return results[a].Score > results[b].Score })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// tokenize splits text into lower-case words.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Pricing configures the price of the search tool.
+type Pricing struct {
+	// Requirements are the accepted payments for a search returning no
+	// results, enriched by the facilitator where needed.
+	Requirements []v2.PaymentRequirements
+
+	// PerResult is added to each requirement's amount per requested result.
+	PerResult *big.Int
+
+	// DefaultResults and MaxResults bound max_results, which defaults to
+	// DefaultResults.
+	DefaultResults int64
+	MaxResults     int64
+}
+
+// Tool returns the definition of the search tool.
+func Tool(maxResults int64) mcp.Tool {
+	return mcp.NewTool(
+		ToolName,
+		mcp.WithDescription("Search the document index; priced per requested result (requires x402 payment)"),
+		mcp.WithString("query", mcp.Required(), mcp.Description("Search query")),
+		mcp.WithNumber("max_results", mcp.Description(fmt.Sprintf("Maximum number of results, 1 to %d", maxResults))),
+	)
+}
+
+// Handler returns the handler of the search tool, answering with the results
+// as JSON.
+func Handler(retriever Retriever, defaultResults int64) mcpserver.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		query, err := req.RequireString("query")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		limit := req.GetInt("max_results", int(defaultResults))
+		results, err := retriever.Search(ctx, query, limit)
+		if err != nil {
+			// Tool errors are not settled, so the caller is not charged
+			return mcp.NewToolResultError(fmt.Sprintf("search failed: %v", err)), nil
+		}
+		encoded, err := json.Marshal(results)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(string(encoded)), nil
+	}
+}
+
+// Register adds the search tool to srv, priced by pricing. The price is fixed
+// by max_results, not by the number of results found, so clients know it
+// before paying.
+func Register(srv *server.X402Server, retriever Retriever, pricing Pricing) error {
+	argumentPricing := server.ArgumentPricing{
+		Argument: "max_results",
+		PerUnit:  pricing.PerResult,
+		Default:  pricing.DefaultResults,
+		Min:      1,
+		Max:      pricing.MaxResults,
+	}
+	resource := v2.ResourceInfo{
+		Description: "Document search, priced per requested result",
+		MimeType:    "application/json",
+	}
+	return srv.AddPricedTool(Tool(pricing.MaxResults), resource, pricing.Requirements, argumentPricing.Requirements, Handler(retriever, pricing.DefaultResults))
+}
+
+// Budget is the spending policy of a search client.
+type Budget struct {
+	// Total caps the amount paid over the client's lifetime, summed across
+	// assets. Nil means no cap.
+	Total *big.Int
+
+	// Allowed restricts the schemes, networks and assets paid with, e.g.
+	// to testnet USDC. Empty allows any.
+	Allowed []v2.AllowedPayment
+}
+
+// Options returns the MCP client transport options enforcing the budget, and
+// the spend guard tracking it, which is nil without a Total.
+func (b Budget) Options() ([]client.Option, *v2.SpendGuard) {
+	var options []client.Option
+	var guard *v2.SpendGuard
+	if b.Total != nil {
+		guard = v2.NewSpendGuard(b.Total)
+		options = append(options, client.WithSpendGuard(guard))
+	}
+	if len(b.Allowed) > 0 {
+		options = append(options, client.WithPaymentAllowlist(b.Allowed...))
+	}
+	return options, guard
+}
@@ -14,6 +14,12 @@ type Config struct {
 	InitialDelay time.Duration // Initial delay between retries
 	MaxDelay     time.Duration // Maximum delay between retries
 	Multiplier   float64       // Multiplier for exponential backoff
+
+	// Clock schedules backoff delays. If nil, the time package is used.
+	// Tests can set it to a fake clock to retry without sleeping.
+	Clock interface {
+		After(d time.Duration) <-chan time.Time
+	}
 }
 
 // DefaultConfig provides sensible defaults for retry operations.
@@ -38,6 +44,10 @@ func WithRetry[T any](
 	var zero T
 	var lastErr error
 	delay := config.InitialDelay
+	after := time.After
+	if config.Clock != nil {
+		after = config.Clock.After
+	}
 
 	// Validate configuration
 	if config.MaxAttempts <= 0 {
@@ -66,7 +76,7 @@ func WithRetry[T any](
 		if attempt < config.MaxAttempts-1 {
 			// Apply exponential backoff
 			select {
-			case <-time.After(delay):
+			case <-after(delay):
 				delay = time.Duration(float64(delay) * config.Multiplier)
 				if delay > config.MaxDelay {
 					delay = config.MaxDelay
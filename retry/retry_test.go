@@ -310,6 +310,49 @@ func TestWithRetry(t *testing.T) {
 	})
 }
 
+// recordingClock fires every timer immediately and records the requested delays.
+type recordingClock struct {
+	delays []time.Duration
+}
+
+func (c *recordingClock) After(d time.Duration) <-chan time.Time {
+	c.delays = append(c.delays, d)
+	ch := make(chan time.Time, 1)
+	ch <- time.Time{}
+	return ch
+}
+
+func TestWithRetry_Clock(t *testing.T) {
+	clock := &recordingClock{}
+	config := Config{MaxAttempts: 4, InitialDelay: time.Second, MaxDelay: 3 * time.Second, Multiplier: 2, Clock: clock}
+
+	calls := 0
+	_, err := WithRetry(context.Background(), config,
+		func(error) bool { return true },
+		func() (string, error) {
+			calls++
+			return "", errors.New("temporary error")
+		},
+	)
+	if err == nil {
+		t.Fatal("expected error after max attempts")
+	}
+	if calls != 4 {
+		t.Errorf("expected 4 calls, got %d", calls)
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+	if len(clock.delays) != len(want) {
+		t.Fatalf("delays = %v, want %v", clock.delays, want)
+	}
+	for i := range want {
+		if clock.delays[i] != want[i] {
+			t.Errorf("delays = %v, want %v", clock.delays, want)
+			break
+		}
+	}
+}
+
 func BenchmarkWithRetry(b *testing.B) {
 	config := DefaultConfig
 
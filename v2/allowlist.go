@@ -0,0 +1,126 @@
+package v2
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AllowedPayment is an entry of a PaymentAllowlist. Empty fields match
+// anything.
+type AllowedPayment struct {
+	// Scheme is the payment scheme, e.g. SchemeExact.
+	Scheme string
+
+	// Network is a CAIP-2 network identifier, or a bare namespace ("eip155")
+	// matching every network of the namespace.
+	Network string
+
+	// Asset is the asset address, compared case-insensitively.
+	Asset string
+}
+
+// Matches reports whether requirements are allowed by the entry.
+func (a AllowedPayment) Matches(requirements PaymentRequirements) bool {
+	if a.Scheme != "" && a.Scheme != requirements.Scheme {
+		return false
+	}
+	if a.Network != "" && a.Network != requirements.Network {
+		namespace, _, _ := strings.Cut(requirements.Network, ":")
+		if strings.Contains(a.Network, ":") || a.Network != namespace {
+			return false
+		}
+	}
+	return a.Asset == "" || strings.EqualFold(a.Asset, requirements.Asset)
+}
+
+// PaymentAllowlist restricts the payments a deployment accepts or makes to
+// those matching one of its entries, e.g. only "exact" payments of USDC on
+// Base in production, so that a misconfigured route or a server cannot
+// introduce a testnet or unexpected asset. An empty allowlist allows
+// everything.
+type PaymentAllowlist []AllowedPayment
+
+// Validate ensures every entry restricts something and names a well-formed
+// network.
+func (a PaymentAllowlist) Validate() error {
+	for i, entry := range a {
+		if entry == (AllowedPayment{}) {
+			return fmt.Errorf("payment allowlist entry %d allows everything", i)
+		}
+		if entry.Network != "" && strings.Contains(entry.Network, ":") {
+			if _, err := ValidateNetwork(entry.Network); err != nil {
+				return fmt.Errorf("payment allowlist entry %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Allows reports whether requirements match an entry of the allowlist.
+func (a PaymentAllowlist) Allows(requirements PaymentRequirements) bool {
+	if len(a) == 0 {
+		return true
+	}
+	for _, entry := range a {
+		if entry.Matches(requirements) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an error wrapping ErrPaymentNotAllowed if requirements are not
+// allowed.
+func (a PaymentAllowlist) Check(requirements PaymentRequirements) error {
+	if a.Allows(requirements) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s on %s with asset %s", ErrPaymentNotAllowed, requirements.Scheme, requirements.Network, requirements.Asset)
+}
+
+// Filter returns the allowed requirements, or the first error if there are
+// none.
+func (a PaymentAllowlist) Filter(requirements []PaymentRequirements) ([]PaymentRequirements, error) {
+	if len(a) == 0 {
+		return requirements, nil
+	}
+	allowed := make([]PaymentRequirements, 0, len(requirements))
+	var firstErr error
+	for _, req := range requirements {
+		if err := a.Check(req); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		allowed = append(allowed, req)
+	}
+	if len(allowed) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return allowed, nil
+}
+
+// FindMatchingRequirement is FindMatchingRequirement restricted to the
+// allowed requirements. A payment matching only requirements that are not
+// allowed fails with ErrCodeUnsupportedScheme wrapping ErrPaymentNotAllowed.
+func (a PaymentAllowlist) FindMatchingRequirement(payment *PaymentPayload, requirements []PaymentRequirements) (*PaymentRequirements, error) {
+	if len(a) == 0 {
+		return FindMatchingRequirement(payment, requirements)
+	}
+	allowed := make([]PaymentRequirements, 0, len(requirements))
+	for _, req := range requirements {
+		if a.Allows(req) {
+			allowed = append(allowed, req)
+		}
+	}
+	requirement, err := FindMatchingRequirement(payment, allowed)
+	if err == nil {
+		return requirement, nil
+	}
+	if denied, deniedErr := FindMatchingRequirement(payment, requirements); deniedErr == nil {
+		return nil, NewPaymentError(ErrCodeUnsupportedScheme, "payment not allowed", a.Check(*denied)).
+			WithDetails("network", payment.Accepted.Network).WithDetails("scheme", payment.Accepted.Scheme)
+	}
+	return nil, err
+}
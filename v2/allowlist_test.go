@@ -0,0 +1,80 @@
+package v2
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPaymentAllowlist(t *testing.T) {
+	const usdc = "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"
+	baseUSDC := PaymentRequirements{Scheme: "exact", Network: "eip155:8453", Asset: usdc}
+	sepoliaUSDC := PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Asset: "0x036CbD53842c5426634e7929541eC2318f3dCF7e"}
+	solana := PaymentRequirements{Scheme: "exact", Network: "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp", Asset: "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"}
+
+	tests := []struct {
+		name      string
+		allowlist PaymentAllowlist
+		req       PaymentRequirements
+		allowed   bool
+	}{
+		{"empty allows everything", nil, sepoliaUSDC, true},
+		{"exact entry", PaymentAllowlist{{Scheme: "exact", Network: "eip155:8453", Asset: usdc}}, baseUSDC, true},
+		{"asset compared case-insensitively", PaymentAllowlist{{Network: "eip155:8453", Asset: "0x833589fcd6edb6e08f4c7c32d4f71b54bda02913"}}, baseUSDC, true},
+		{"other network", PaymentAllowlist{{Network: "eip155:8453"}}, sepoliaUSDC, false},
+		{"namespace entry", PaymentAllowlist{{Network: "eip155"}}, sepoliaUSDC, true},
+		{"namespace entry other namespace", PaymentAllowlist{{Network: "eip155"}}, solana, false},
+		{"other scheme", PaymentAllowlist{{Scheme: "upto"}}, baseUSDC, false},
+		{"any entry", PaymentAllowlist{{Network: "eip155:8453"}, {Network: "solana"}}, solana, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.allowlist.Allows(tt.req); got != tt.allowed {
+				t.Errorf("Allows() = %v, want %v", got, tt.allowed)
+			}
+			if err := tt.allowlist.Check(tt.req); (err == nil) != tt.allowed || (err != nil && !errors.Is(err, ErrPaymentNotAllowed)) {
+				t.Errorf("Check() = %v", err)
+			}
+		})
+	}
+
+	t.Run("validate", func(t *testing.T) {
+		if err := (PaymentAllowlist{{Network: "eip155:8453"}, {Network: "solana"}}).Validate(); err != nil {
+			t.Errorf("valid allowlist: %v", err)
+		}
+		if err := (PaymentAllowlist{{}}).Validate(); err == nil {
+			t.Error("expected error for empty entry")
+		}
+		if err := (PaymentAllowlist{{Network: "eip155:"}}).Validate(); err == nil {
+			t.Error("expected error for malformed network")
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		allowlist := PaymentAllowlist{{Network: "eip155:8453"}}
+		got, err := allowlist.Filter([]PaymentRequirements{sepoliaUSDC, baseUSDC})
+		if err != nil || len(got) != 1 || got[0].Network != baseUSDC.Network {
+			t.Errorf("Filter() = %v, %v", got, err)
+		}
+		if _, err := allowlist.Filter([]PaymentRequirements{sepoliaUSDC}); !errors.Is(err, ErrPaymentNotAllowed) {
+			t.Errorf("expected ErrPaymentNotAllowed, got %v", err)
+		}
+	})
+
+	t.Run("find matching requirement", func(t *testing.T) {
+		allowlist := PaymentAllowlist{{Network: "eip155:8453"}}
+		requirements := []PaymentRequirements{sepoliaUSDC, baseUSDC}
+		got, err := allowlist.FindMatchingRequirement(&PaymentPayload{Accepted: baseUSDC}, requirements)
+		if err != nil || got.Network != baseUSDC.Network {
+			t.Errorf("FindMatchingRequirement() = %v, %v", got, err)
+		}
+		_, err = allowlist.FindMatchingRequirement(&PaymentPayload{Accepted: sepoliaUSDC}, requirements)
+		var paymentErr *PaymentError
+		if !errors.As(err, &paymentErr) || paymentErr.Code != ErrCodeUnsupportedScheme || !errors.Is(err, ErrPaymentNotAllowed) {
+			t.Errorf("expected payment not allowed, got %v", err)
+		}
+		_, err = allowlist.FindMatchingRequirement(&PaymentPayload{Accepted: solana}, requirements)
+		if !errors.Is(err, ErrUnsupportedScheme) {
+			t.Errorf("expected ErrUnsupportedScheme, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,236 @@
+// Package analytics aggregates the payments a client makes, so agent
+// operators can see where the money goes: which hosts and tools cost the
+// most, what one tool call costs, which networks pay, and how spend trends
+// from day to day.
+//
+// A Recorder collects successful payment events from HTTP clients and MCP
+// transports (see Recorder.OnPaymentSuccess) and summarizes them over a
+// period. Summaries are plain values for use from Go and encode to JSON;
+// Recorder.Handler serves them over HTTP.
+package analytics
+
+import (
+	"context"
+	"math/big"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/ledger"
+)
+
+// Payment is one payment made by the client.
+type Payment struct {
+	// Time is when the payment succeeded.
+	Time time.Time `json:"time"`
+
+	// Method is the transport that paid, "HTTP" or "MCP".
+	Method string `json:"method,omitempty"`
+
+	// Host is the host of the paid URL or MCP server, if known.
+	Host string `json:"host,omitempty"`
+
+	// Tool is the tool the payment was made for, if any.
+	Tool string `json:"tool,omitempty"`
+
+	// Network is the CAIP-2 network identifier.
+	Network string `json:"network"`
+
+	// Asset is the token address or mint.
+	Asset string `json:"asset"`
+
+	// Amount is the payment amount in atomic units.
+	Amount string `json:"amount"`
+
+	// Recipient is the address paid.
+	Recipient string `json:"recipient,omitempty"`
+
+	// Transaction is the settlement transaction hash, if known.
+	Transaction string `json:"transaction,omitempty"`
+}
+
+// PaymentFromEvent converts a successful payment event into a Payment.
+func PaymentFromEvent(event v2.PaymentEvent) Payment {
+	payment := Payment{
+		Time:        event.Timestamp,
+		Method:      event.Method,
+		Tool:        event.Tool,
+		Network:     event.Network,
+		Asset:       event.Asset,
+		Amount:      event.Amount,
+		Recipient:   event.Recipient,
+		Transaction: event.Transaction,
+	}
+	if u, err := url.Parse(event.URL); err == nil {
+		payment.Host = u.Host
+	}
+	return payment
+}
+
+// Recorder records payments in memory and summarizes them. It is safe for
+// concurrent use and may be shared between clients.
+type Recorder struct {
+	oracle    ledger.PriceOracle
+	decimals  map[string]int
+	retention time.Duration
+	location  *time.Location
+	clock     v2.Clock
+
+	mu       sync.Mutex
+	payments []Payment
+}
+
+// Option configures a Recorder.
+type Option func(*Recorder)
+
+// WithPriceOracle values payments in USD with oracle, so spend in different
+// assets can be added up and ranked. Without it, totals are kept per asset
+// and groups are ranked by their number of payments.
+func WithPriceOracle(oracle ledger.PriceOracle) Option {
+	return func(r *Recorder) {
+		r.oracle = oracle
+	}
+}
+
+// WithTokenDecimals sets the decimals of a non-USDC asset, needed to value it
+// in USD. USDC decimals are known.
+func WithTokenDecimals(network, asset string, decimals int) Option {
+	return func(r *Recorder) {
+		r.decimals[assetKey(network, asset)] = decimals
+	}
+}
+
+// WithRetention forgets payments older than d (default: payments are kept).
+func WithRetention(d time.Duration) Option {
+	return func(r *Recorder) {
+		r.retention = d
+	}
+}
+
+// WithLocation sets the time zone days are counted in (default: UTC).
+func WithLocation(location *time.Location) Option {
+	return func(r *Recorder) {
+		r.location = location
+	}
+}
+
+// WithClock sets the clock used for retention and default periods (default:
+// v2.SystemClock).
+func WithClock(clock v2.Clock) Option {
+	return func(r *Recorder) {
+		r.clock = v2.ClockOrSystem(clock)
+	}
+}
+
+// NewRecorder creates an empty Recorder.
+func NewRecorder(opts ...Option) *Recorder {
+	r := &Recorder{
+		decimals: make(map[string]int),
+		location: time.UTC,
+		clock:    v2.SystemClock,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Record adds payment to the recorder.
+func (r *Recorder) Record(payment Payment) {
+	if payment.Time.IsZero() {
+		payment.Time = r.clock.Now()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payments = append(r.payments, payment)
+	r.pruneLocked()
+}
+
+// OnPaymentSuccess returns a callback recording every successful payment. Use
+// it with the HTTP client's WithPaymentCallback(v2.PaymentEventSuccess, ...)
+// or the MCP transport's WithPaymentSuccessCallback.
+func (r *Recorder) OnPaymentSuccess() v2.PaymentCallback {
+	return func(event v2.PaymentEvent) {
+		if event.Type == v2.PaymentEventSuccess {
+			r.Record(PaymentFromEvent(event))
+		}
+	}
+}
+
+// Payments returns the payments made in [from, to), oldest first.
+func (r *Recorder) Payments(from, to time.Time) []Payment {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneLocked()
+
+	var payments []Payment
+	for _, payment := range r.payments {
+		if !payment.Time.Before(from) && payment.Time.Before(to) {
+			payments = append(payments, payment)
+		}
+	}
+	sortPayments(payments)
+	return payments
+}
+
+// pruneLocked drops the payments older than the retention.
+func (r *Recorder) pruneLocked() {
+	if r.retention <= 0 {
+		return
+	}
+	cutoff := r.clock.Now().Add(-r.retention)
+	kept := r.payments[:0]
+	for _, payment := range r.payments {
+		if !payment.Time.Before(cutoff) {
+			kept = append(kept, payment)
+		}
+	}
+	clear(r.payments[len(kept):])
+	r.payments = kept
+}
+
+// sortPayments sorts payments oldest first, keeping the recording order of
+// simultaneous payments.
+func sortPayments(payments []Payment) {
+	sort.SliceStable(payments, func(i, j int) bool {
+		return payments[i].Time.Before(payments[j].Time)
+	})
+}
+
+// value returns the USD value of payment, or nil if it cannot be valued.
+func (r *Recorder) value(ctx context.Context, payment Payment) *big.Rat {
+	if r.oracle == nil {
+		return nil
+	}
+	amount, ok := new(big.Int).SetString(payment.Amount, 10)
+	if !ok {
+		return nil
+	}
+	decimals, ok := r.tokenDecimals(payment.Network, payment.Asset)
+	if !ok {
+		return nil
+	}
+	price, err := r.oracle.PriceUSD(ctx, payment.Network, payment.Asset, payment.Time)
+	if err != nil {
+		return nil
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	return new(big.Rat).Mul(new(big.Rat).SetFrac(amount, scale), price)
+}
+
+func (r *Recorder) tokenDecimals(network, asset string) (int, bool) {
+	if decimals, ok := r.decimals[assetKey(network, asset)]; ok {
+		return decimals, true
+	}
+	if chain, err := v2.GetChainConfig(network); err == nil && strings.EqualFold(chain.USDCAddress, asset) {
+		return int(chain.Decimals), true
+	}
+	return 0, false
+}
+
+func assetKey(network, asset string) string {
+	return network + ":" + strings.ToLower(asset)
+}
@@ -0,0 +1,244 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/ledger"
+)
+
+const (
+	baseUSDC    = "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"
+	polygonWETH = "0x7ceB23fD6bC0adD59E62ac25578270cFf1b9f619"
+)
+
+var testNow = time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+// record records the payments of a week: a search tool called often and
+// cheaply, a report tool called rarely and expensively.
+func record(r *Recorder) {
+	callback := r.OnPaymentSuccess()
+	events := []v2.PaymentEvent{
+		{Type: v2.PaymentEventSuccess, Method: "MCP", URL: "https://tools.example.com/mcp", Tool: "search", Network: v2.NetworkBase, Asset: baseUSDC, Amount: "1000", Timestamp: testNow.AddDate(0, 0, -6)},
+		{Type: v2.PaymentEventSuccess, Method: "MCP", URL: "https://tools.example.com/mcp", Tool: "search", Network: v2.NetworkBase, Asset: baseUSDC, Amount: "2000", Timestamp: testNow.AddDate(0, 0, -1)},
+		{Type: v2.PaymentEventSuccess, Method: "MCP", URL: "https://tools.example.com/mcp", Tool: "search", Network: v2.NetworkBase, Asset: baseUSDC, Amount: "3000", Timestamp: testNow.Add(-time.Hour)},
+		{Type: v2.PaymentEventSuccess, Method: "MCP", URL: "https://reports.example.com/mcp", Tool: "report", Network: v2.NetworkBase, Asset: baseUSDC, Amount: "500000", Timestamp: testNow.Add(-2 * time.Hour)},
+		{Type: v2.PaymentEventSuccess, Method: "HTTP", URL: "https://api.example.com/weather", Network: v2.NetworkPolygon, Asset: polygonWETH, Amount: "1000000000000000", Timestamp: testNow.AddDate(0, 0, -2)},
+		{Type: v2.PaymentEventFailure, Method: "HTTP", URL: "https://api.example.com/weather", Network: v2.NetworkBase, Asset: baseUSDC, Amount: "1000", Timestamp: testNow},
+	}
+	for _, event := range events {
+		callback(event)
+	}
+}
+
+func groupKeys(groups []Group) []string {
+	keys := make([]string, len(groups))
+	for i, group := range groups {
+		keys[i] = group.Key
+	}
+	return keys
+}
+
+func TestRecorder_Summarize(t *testing.T) {
+	oracle := ledger.NewUSDCPriceOracle()
+	oracle.Set(v2.NetworkPolygon, polygonWETH, big.NewRat(3000, 1))
+	clock := v2.NewFakeClock(testNow)
+	from, to := testNow.AddDate(0, 0, -7), testNow.AddDate(0, 0, 1)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		hosts     []string
+		total     string
+		weatherUS string
+		trend     Trend
+	}{
+		{
+			name: "per asset",
+			// Without prices, the most paid host ranks first
+			hosts: []string{"tools.example.com", "api.example.com", "reports.example.com"},
+			trend: Trend{Measure: "payments", Previous: "1", Current: "4", Change: 3},
+		},
+		{
+			name:      "in USD",
+			opts:      []Option{WithPriceOracle(oracle), WithTokenDecimals(v2.NetworkPolygon, polygonWETH, 18)},
+			hosts:     []string{"api.example.com", "reports.example.com", "tools.example.com"},
+			total:     "3.506000",
+			weatherUS: "3.000000",
+			trend:     Trend{Measure: "usd", Previous: "0.001000", Current: "3.505000", Change: 3504},
+		},
+		{
+			name: "unknown decimals",
+			opts: []Option{WithPriceOracle(oracle)},
+			// WETH cannot be valued without its decimals
+			hosts:     []string{"reports.example.com", "tools.example.com", "api.example.com"},
+			total:     "0.506000",
+			weatherUS: "0.000000",
+			trend:     Trend{Measure: "usd", Previous: "0.001000", Current: "0.505000", Change: 504},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRecorder(append(tt.opts, WithClock(clock))...)
+			record(r)
+			summary := r.Summarize(ctx, from, to)
+
+			if summary.Total.Payments != 5 {
+				t.Errorf("expected 5 payments, got %d", summary.Total.Payments)
+			}
+			if summary.Total.USD != tt.total {
+				t.Errorf("Total.USD = %q, want %q", summary.Total.USD, tt.total)
+			}
+			if got := groupKeys(summary.Hosts); !equal(got, tt.hosts) {
+				t.Errorf("Hosts = %v, want %v", got, tt.hosts)
+			}
+			if got := groupKeys(summary.TopHosts(1)); !equal(got, tt.hosts[:1]) {
+				t.Errorf("TopHosts(1) = %v", got)
+			}
+			if weather := find(summary.Hosts, "api.example.com"); weather.USD != tt.weatherUS {
+				t.Errorf("api.example.com USD = %q, want %q", weather.USD, tt.weatherUS)
+			}
+
+			// The cost of a call is the average payment of the tool
+			search := find(summary.Tools, "search")
+			if search.Key != "search" || search.Payments != 3 || search.Totals[0].Amount != "6000" || search.Totals[0].Average != "2000" {
+				t.Errorf("unexpected search tool group %+v", search)
+			}
+			if tt.total != "" && search.AverageUSD != "0.002000" {
+				t.Errorf("search AverageUSD = %q, want 0.002000", search.AverageUSD)
+			}
+
+			// Days run from the start of the period to today
+			if len(summary.Days) != 8 || summary.Days[0].Key != "2025-03-03" || summary.Days[7].Key != "2025-03-10" {
+				t.Fatalf("unexpected days %v", groupKeys(summary.Days))
+			}
+			if summary.Days[7].Payments != 2 || summary.Days[1].Payments != 1 || summary.Days[2].Payments != 0 {
+				t.Errorf("unexpected daily payments in %+v", summary.Days)
+			}
+
+			if summary.Trend != tt.trend {
+				t.Errorf("Trend = %+v, want %+v", summary.Trend, tt.trend)
+			}
+		})
+	}
+}
+
+func find(groups []Group, key string) Group {
+	for _, group := range groups {
+		if group.Key == key {
+			return group
+		}
+	}
+	return Group{}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRecorder_Retention(t *testing.T) {
+	clock := v2.NewFakeClock(testNow)
+	r := NewRecorder(WithClock(clock), WithRetention(48*time.Hour))
+	record(r)
+
+	payments := r.Payments(time.Time{}, testNow.Add(time.Hour))
+	if len(payments) != 4 {
+		t.Fatalf("expected the payments of the last two days, got %d", len(payments))
+	}
+	for i := 1; i < len(payments); i++ {
+		if payments[i].Time.Before(payments[i-1].Time) {
+			t.Fatal("expected payments oldest first")
+		}
+	}
+	if payments[0].Host != "api.example.com" || payments[0].Method != "HTTP" {
+		t.Errorf("unexpected first payment %+v", payments[0])
+	}
+}
+
+func TestRecorder_Handler(t *testing.T) {
+	clock := v2.NewFakeClock(testNow)
+	r := NewRecorder(WithClock(clock), WithLocation(time.FixedZone("UTC-13", -13*3600)))
+	record(r)
+	server := httptest.NewServer(r.Handler())
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		query    string
+		status   int
+		payments int
+		days     int
+	}{
+		{name: "default period", query: "", status: http.StatusOK, payments: 5, days: 30},
+		{name: "last days", query: "?days=2", status: http.StatusOK, payments: 3, days: 2},
+		{name: "explicit period", query: "?from=2025-03-08T00:00:00Z&to=2025-03-09T00:00:00Z", status: http.StatusOK, payments: 1, days: 2},
+		{name: "invalid days", query: "?days=zero", status: http.StatusBadRequest},
+		{name: "inverted period", query: "?from=2025-03-09T00:00:00Z&to=2025-03-08T00:00:00Z", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(server.URL + tt.query)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("expected %d, got %d", tt.status, resp.StatusCode)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var summary Summary
+			if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+				t.Fatalf("decoding summary failed: %v", err)
+			}
+			if summary.Total.Payments != tt.payments || len(summary.Days) != tt.days {
+				t.Errorf("expected %d payments over %d days, got %d over %d", tt.payments, tt.days, summary.Total.Payments, len(summary.Days))
+			}
+		})
+	}
+
+	t.Run("export", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "?export=1&days=7")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.Header.Get("Content-Disposition") == "" {
+			t.Error("expected the export to be downloaded as a file")
+		}
+		var export Export
+		if err := json.NewDecoder(resp.Body).Decode(&export); err != nil {
+			t.Fatalf("decoding export failed: %v", err)
+		}
+		if len(export.Payments) != 5 || export.Summary.Total.Payments != 5 {
+			t.Errorf("expected 5 payments, got %d", len(export.Payments))
+		}
+
+		var buf bytes.Buffer
+		if err := r.ExportJSON(context.Background(), &buf, testNow, testNow); err != nil {
+			t.Fatalf("ExportJSON failed: %v", err)
+		}
+		if !bytes.Contains(buf.Bytes(), []byte(`"payments": []`)) {
+			t.Errorf("expected an empty period to export no payments, got %s", buf.String())
+		}
+	})
+}
@@ -0,0 +1,397 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// usdDecimals is the number of decimal places of USD values in summaries.
+const usdDecimals = 6
+
+// maxDays bounds the days listed in a summary.
+const maxDays = 3660
+
+// Total is the amount paid in one asset.
+type Total struct {
+	Network string `json:"network"`
+	Asset   string `json:"asset"`
+
+	// Amount is the total in atomic units.
+	Amount string `json:"amount"`
+
+	// Payments is the number of payments in the asset.
+	Payments int `json:"payments"`
+
+	// Average is the mean amount per payment in atomic units, rounded down.
+	Average string `json:"average"`
+}
+
+// Group aggregates the payments sharing a host, tool, network or day.
+type Group struct {
+	// Key is the host, tool, network or day ("2006-01-02").
+	Key string `json:"key"`
+
+	// Payments is the number of payments.
+	Payments int `json:"payments"`
+
+	// Totals are the amounts paid per asset.
+	Totals []Total `json:"totals"`
+
+	// USD is the total value in USD, when a price oracle is configured.
+	USD string `json:"usd,omitempty"`
+
+	// AverageUSD is the mean value of a payment in USD: for tools, the cost
+	// of one call.
+	AverageUSD string `json:"averageUsd,omitempty"`
+
+	// Unvalued counts the payments the price oracle could not value, which
+	// USD leaves out.
+	Unvalued int `json:"unvalued,omitempty"`
+}
+
+// Trend compares spend in the second half of a period with the first.
+type Trend struct {
+	// Measure is what is compared: "usd" with a price oracle, otherwise
+	// "payments".
+	Measure string `json:"measure"`
+
+	// Previous and Current are the measure in the first and second half.
+	Previous string `json:"previous"`
+	Current  string `json:"current"`
+
+	// Change is the relative change from Previous to Current, like 0.25 for
+	// 25% more. It is zero when Previous is zero.
+	Change float64 `json:"change"`
+}
+
+// Summary aggregates the payments of a period.
+type Summary struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	// Total aggregates every payment of the period; its Key is empty.
+	Total Group `json:"total"`
+
+	// Hosts, Tools and Networks are ranked by spend, the largest first:
+	// by USD value with a price oracle, otherwise by number of payments.
+	// Payments without a host or tool are left out of Hosts and Tools.
+	Hosts    []Group `json:"hosts"`
+	Tools    []Group `json:"tools"`
+	Networks []Group `json:"networks"`
+
+	// Days lists every day of the period in order, including days without
+	// payments.
+	Days []Group `json:"days"`
+
+	// Trend compares the second half of the period with the first.
+	Trend Trend `json:"trend"`
+}
+
+// TopHosts returns the n hosts the most was spent on.
+func (s *Summary) TopHosts(n int) []Group {
+	return top(s.Hosts, n)
+}
+
+// TopTools returns the n tools the most was spent on.
+func (s *Summary) TopTools(n int) []Group {
+	return top(s.Tools, n)
+}
+
+func top(groups []Group, n int) []Group {
+	if n < len(groups) {
+		return groups[:n]
+	}
+	return groups
+}
+
+// aggregate accumulates the payments of a group.
+type aggregate struct {
+	payments int
+	totals   map[string]*assetTotal
+	usd      *big.Rat
+	unvalued int
+}
+
+type assetTotal struct {
+	network, asset string
+	amount         *big.Int
+	payments       int
+}
+
+func newAggregate() *aggregate {
+	return &aggregate{totals: make(map[string]*assetTotal), usd: new(big.Rat)}
+}
+
+func (a *aggregate) add(payment Payment, value *big.Rat) {
+	a.payments++
+	if value == nil {
+		a.unvalued++
+	} else {
+		a.usd.Add(a.usd, value)
+	}
+	amount, ok := new(big.Int).SetString(payment.Amount, 10)
+	if !ok {
+		return
+	}
+	key := assetKey(payment.Network, payment.Asset)
+	total := a.totals[key]
+	if total == nil {
+		total = &assetTotal{network: payment.Network, asset: payment.Asset, amount: new(big.Int)}
+		a.totals[key] = total
+	}
+	total.amount.Add(total.amount, amount)
+	total.payments++
+}
+
+// group returns the aggregate as the Group key, with USD values if valued.
+func (a *aggregate) group(key string, valued bool) Group {
+	group := Group{Key: key, Payments: a.payments, Totals: []Total{}}
+	for _, total := range a.totals {
+		average := new(big.Int).Quo(total.amount, big.NewInt(int64(total.payments)))
+		group.Totals = append(group.Totals, Total{
+			Network:  total.network,
+			Asset:    total.asset,
+			Amount:   total.amount.String(),
+			Payments: total.payments,
+			Average:  average.String(),
+		})
+	}
+	sort.Slice(group.Totals, func(i, j int) bool {
+		if group.Totals[i].Network != group.Totals[j].Network {
+			return group.Totals[i].Network < group.Totals[j].Network
+		}
+		return group.Totals[i].Asset < group.Totals[j].Asset
+	})
+	if valued {
+		group.USD = a.usd.FloatString(usdDecimals)
+		group.Unvalued = a.unvalued
+		if valuedPayments := a.payments - a.unvalued; valuedPayments > 0 {
+			average := new(big.Rat).Quo(a.usd, new(big.Rat).SetInt64(int64(valuedPayments)))
+			group.AverageUSD = average.FloatString(usdDecimals)
+		}
+	}
+	return group
+}
+
+// groupBy aggregates payments by the key of each.
+type groupBy map[string]*aggregate
+
+func (g groupBy) add(key string, payment Payment, value *big.Rat) {
+	if key == "" {
+		return
+	}
+	if g[key] == nil {
+		g[key] = newAggregate()
+	}
+	g[key].add(payment, value)
+}
+
+// ranked returns the groups, the largest spend first.
+func (g groupBy) ranked(valued bool) []Group {
+	groups := make([]Group, 0, len(g))
+	usd := make(map[string]*big.Rat, len(g))
+	for key, aggregate := range g {
+		groups = append(groups, aggregate.group(key, valued))
+		usd[key] = aggregate.usd
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if valued {
+			if c := usd[groups[i].Key].Cmp(usd[groups[j].Key]); c != 0 {
+				return c > 0
+			}
+		}
+		if groups[i].Payments != groups[j].Payments {
+			return groups[i].Payments > groups[j].Payments
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups
+}
+
+// Summarize aggregates the payments made in [from, to). A zero from starts
+// at the first payment; days after the current one are not listed.
+func (r *Recorder) Summarize(ctx context.Context, from, to time.Time) *Summary {
+	payments := r.Payments(from, to)
+	valued := r.oracle != nil
+
+	total := newAggregate()
+	hosts, tools, networks, days := groupBy{}, groupBy{}, groupBy{}, groupBy{}
+	values := make([]*big.Rat, len(payments))
+	for i, payment := range payments {
+		value := r.value(ctx, payment)
+		values[i] = value
+		total.add(payment, value)
+		hosts.add(payment.Host, payment, value)
+		tools.add(payment.Tool, payment, value)
+		networks.add(payment.Network, payment, value)
+		days.add(r.day(payment.Time), payment, value)
+	}
+
+	summary := &Summary{
+		From:     from,
+		To:       to,
+		Total:    total.group("", valued),
+		Hosts:    hosts.ranked(valued),
+		Tools:    tools.ranked(valued),
+		Networks: networks.ranked(valued),
+		Days:     []Group{},
+	}
+
+	// The period covered, bounded by the first payment and now
+	start, end := from, to
+	if start.IsZero() && len(payments) > 0 {
+		start = payments[0].Time
+	}
+	if now := r.clock.Now(); end.After(now) {
+		end = now
+	}
+	if start.IsZero() || !end.After(start) {
+		summary.Trend = Trend{Measure: trendMeasure(valued), Previous: "0", Current: "0"}
+		return summary
+	}
+
+	first := r.startOfDay(start)
+	for day, n := first, 0; day.Before(end) && n < maxDays; day, n = day.AddDate(0, 0, 1), n+1 {
+		key := day.Format(time.DateOnly)
+		if aggregate, ok := days[key]; ok {
+			summary.Days = append(summary.Days, aggregate.group(key, valued))
+		} else {
+			summary.Days = append(summary.Days, newAggregate().group(key, valued))
+		}
+	}
+
+	summary.Trend = trend(payments, values, start.Add(end.Sub(start)/2), valued)
+	return summary
+}
+
+// trend compares the payments before and after middle.
+func trend(payments []Payment, values []*big.Rat, middle time.Time, valued bool) Trend {
+	previous, current := new(big.Rat), new(big.Rat)
+	for i, payment := range payments {
+		measure := big.NewRat(1, 1)
+		if valued {
+			if values[i] == nil {
+				continue
+			}
+			measure = values[i]
+		}
+		if payment.Time.Before(middle) {
+			previous.Add(previous, measure)
+		} else {
+			current.Add(current, measure)
+		}
+	}
+
+	t := Trend{Measure: trendMeasure(valued)}
+	if valued {
+		t.Previous, t.Current = previous.FloatString(usdDecimals), current.FloatString(usdDecimals)
+	} else {
+		t.Previous, t.Current = previous.FloatString(0), current.FloatString(0)
+	}
+	if previous.Sign() > 0 {
+		change, _ := new(big.Rat).Quo(new(big.Rat).Sub(current, previous), previous).Float64()
+		t.Change = change
+	}
+	return t
+}
+
+func trendMeasure(valued bool) string {
+	if valued {
+		return "usd"
+	}
+	return "payments"
+}
+
+// day returns the day of t in the Recorder's time zone.
+func (r *Recorder) day(t time.Time) string {
+	return t.In(r.location).Format(time.DateOnly)
+}
+
+// startOfDay returns the start of the day of t in the Recorder's time zone.
+func (r *Recorder) startOfDay(t time.Time) time.Time {
+	t = t.In(r.location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, r.location)
+}
+
+// Export is the JSON export of a period: its summary and its payments.
+type Export struct {
+	Summary  *Summary  `json:"summary"`
+	Payments []Payment `json:"payments"`
+}
+
+// ExportJSON writes the summary and payments of [from, to) to w as JSON.
+func (r *Recorder) ExportJSON(ctx context.Context, w io.Writer, from, to time.Time) error {
+	payments := r.Payments(from, to)
+	if payments == nil {
+		payments = []Payment{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(Export{Summary: r.Summarize(ctx, from, to), Payments: payments})
+}
+
+// Handler serves summaries as JSON. Query parameters:
+//
+//	days    summarize the last days days, including today (default 30)
+//	from,to summarize [from, to), as RFC 3339 timestamps
+//	export  "1" to download the summary with every payment (see ExportJSON)
+func (r *Recorder) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		from, to, err := r.period(req.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if req.URL.Query().Get("export") == "1" {
+			filename := fmt.Sprintf("x402-spend-%s.json", from.Format(time.DateOnly))
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+			_ = r.ExportJSON(req.Context(), w, from, to)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(r.Summarize(req.Context(), from, to))
+	})
+}
+
+// period parses the period of a Handler request.
+func (r *Recorder) period(query map[string][]string) (time.Time, time.Time, error) {
+	get := func(name string) string {
+		if values := query[name]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	if from, to := get("from"), get("to"); from != "" || to != "" {
+		start, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from %q", from)
+		}
+		end, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to %q", to)
+		}
+		if !end.After(start) {
+			return time.Time{}, time.Time{}, fmt.Errorf("to must be after from")
+		}
+		return start, end, nil
+	}
+
+	days := 30
+	if value := get("days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxDays {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid days %q", value)
+		}
+		days = n
+	}
+	end := r.startOfDay(r.clock.Now()).AddDate(0, 0, 1)
+	return end.AddDate(0, 0, -days), end, nil
+}
@@ -0,0 +1,39 @@
+package v2
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ApprovalFunc approves a signed payment before it is sent, for clients whose
+// payments need a person's consent, such as operator-supervised agents. It
+// returns an error to refuse the payment, blocking until approval is given or
+// ctx is done.
+type ApprovalFunc func(ctx context.Context, payment *PaymentPayload) error
+
+// Approve asks approve to approve payment. Refusals are returned wrapped in
+// ErrPaymentNotApproved; a nil approve approves every payment.
+func Approve(ctx context.Context, approve ApprovalFunc, payment *PaymentPayload) error {
+	if approve == nil {
+		return nil
+	}
+	err := approve(ctx, payment)
+	if err == nil || errors.Is(err, ErrPaymentNotApproved) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrPaymentNotApproved, err)
+}
+
+// PaymentHash returns the SHA-256 hash of the JSON encoding of payment. It
+// covers the signed authorization, nonce included, so an approval bound to it
+// approves that payment and no other.
+func PaymentHash(payment *PaymentPayload) ([32]byte, error) {
+	data, err := json.Marshal(payment)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
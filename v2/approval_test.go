@@ -0,0 +1,53 @@
+package v2
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestApprove(t *testing.T) {
+	payment := &PaymentPayload{X402Version: 2, Accepted: PaymentRequirements{Scheme: "exact", Amount: "1000"}}
+	denied := errors.New("operator denied")
+
+	tests := []struct {
+		name    string
+		approve ApprovalFunc
+		wantErr error
+	}{
+		{name: "no approval needed"},
+		{name: "approved", approve: func(context.Context, *PaymentPayload) error { return nil }},
+		{name: "denied", approve: func(context.Context, *PaymentPayload) error { return denied }, wantErr: denied},
+		{name: "timed out", approve: func(context.Context, *PaymentPayload) error { return context.DeadlineExceeded }, wantErr: context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Approve(context.Background(), tt.approve, payment)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Approve() = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) || !errors.Is(err, ErrPaymentNotApproved) {
+				t.Errorf("Approve() = %v; want %v wrapped in ErrPaymentNotApproved", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPaymentHash(t *testing.T) {
+	payment := &PaymentPayload{X402Version: 2, Payload: map[string]interface{}{"signature": "0x01", "nonce": "0xaa"}}
+	hash, err := PaymentHash(payment)
+	if err != nil {
+		t.Fatalf("PaymentHash failed: %v", err)
+	}
+	again, _ := PaymentHash(&PaymentPayload{X402Version: 2, Payload: map[string]interface{}{"nonce": "0xaa", "signature": "0x01"}})
+	if hash != again {
+		t.Error("expected equal payments to hash equally")
+	}
+	other, _ := PaymentHash(&PaymentPayload{X402Version: 2, Payload: map[string]interface{}{"signature": "0x01", "nonce": "0xab"}})
+	if hash == other {
+		t.Error("expected payments with different nonces to hash differently")
+	}
+}
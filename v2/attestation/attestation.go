@@ -0,0 +1,366 @@
+// Package attestation signs and verifies x402 v2 PaymentRequired bodies, so
+// clients can detect a compromised or intercepted server that swaps the PayTo
+// address or other requirements.
+//
+// A seller (or a facilitator acting for it) signs the canonical JSON encoding of
+// the resource and accepted requirements with a secp256k1 key (EIP-191
+// personal_sign). The attestation travels in PaymentRequired.Extensions under
+// v2.RequirementsAttestationExtension. Clients trust signer addresses configured
+// locally or discovered through the facilitator's /supported endpoint
+// (v2.SupportedResponse.AttestationKeys), or pinned per origin from keys the
+// seller publishes at WellKnownPath or in DNS (see KeyDirectory).
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/keymem"
+)
+
+// DefaultTTL is how long an attestation is valid when no TTL is configured.
+const DefaultTTL = 5 * time.Minute
+
+// Attestation is the signed statement carried in the extension info.
+type Attestation struct {
+	// Signer is the EVM address of the attesting key.
+	Signer string `json:"signer"`
+
+	// Signature is the hex-encoded 65-byte EIP-191 signature.
+	Signature string `json:"signature"`
+
+	// IssuedAt is the Unix time the attestation was created.
+	IssuedAt int64 `json:"issuedAt"`
+
+	// ExpiresAt is the Unix time after which the attestation is rejected.
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+// signedBody is the part of a PaymentRequired covered by the signature.
+type signedBody struct {
+	X402Version int                      `json:"x402Version"`
+	Resource    *v2.ResourceInfo         `json:"resource,omitempty"`
+	Accepts     []v2.PaymentRequirements `json:"accepts"`
+	IssuedAt    int64                    `json:"issuedAt"`
+	ExpiresAt   int64                    `json:"expiresAt"`
+}
+
+// Message returns the canonical message signed for pr: the JSON encoding of its
+// version, resource, accepts, and the attestation validity window, with object
+// keys sorted. The error message and extensions are not covered.
+func Message(pr v2.PaymentRequired, issuedAt, expiresAt int64) ([]byte, error) {
+	raw, err := json.Marshal(signedBody{
+		X402Version: pr.X402Version,
+		Resource:    pr.Resource,
+		Accepts:     pr.Accepts,
+		IssuedAt:    issuedAt,
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode requirements: %w", err)
+	}
+
+	// Re-encode through a generic value so Extra maps and struct fields share one
+	// key order regardless of how the body was produced.
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("canonicalize requirements: %w", err)
+	}
+	return json.Marshal(generic)
+}
+
+// FromPaymentRequired extracts the attestation of pr.
+// Returns v2.ErrUnattestedRequirements if pr carries none.
+func FromPaymentRequired(pr v2.PaymentRequired) (*Attestation, error) {
+	ext, ok := pr.Extensions[v2.RequirementsAttestationExtension]
+	if !ok || ext.Info == nil {
+		return nil, v2.ErrUnattestedRequirements
+	}
+	raw, err := json.Marshal(ext.Info)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", v2.ErrInvalidAttestation, err)
+	}
+	var att Attestation
+	if err := json.Unmarshal(raw, &att); err != nil {
+		return nil, fmt.Errorf("%w: %v", v2.ErrInvalidAttestation, err)
+	}
+	return &att, nil
+}
+
+// Signer attests PaymentRequired bodies with a secp256k1 key.
+type Signer struct {
+	key     *keymem.Key
+	address common.Address
+	ttl     time.Duration
+	clock   v2.Clock
+}
+
+// SignerOption configures a Signer.
+type SignerOption func(*Signer)
+
+// WithTTL sets how long attestations remain valid (default: DefaultTTL).
+func WithTTL(ttl time.Duration) SignerOption {
+	return func(s *Signer) {
+		s.ttl = ttl
+	}
+}
+
+// WithSignerClock sets the clock used for IssuedAt and ExpiresAt.
+func WithSignerClock(clock v2.Clock) SignerOption {
+	return func(s *Signer) {
+		s.clock = clock
+	}
+}
+
+// NewSigner creates a Signer from a hex-encoded private key (with or without 0x).
+func NewSigner(privateKeyHex string, opts ...SignerOption) (*Signer, error) {
+	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(privateKeyHex, "0x"))
+	if err != nil {
+		return nil, v2.ErrInvalidKey
+	}
+	defer keymem.WipeECDSA(privateKey)
+
+	s := &Signer{
+		key:     keymem.FromECDSA(privateKey),
+		address: crypto.PubkeyToAddress(privateKey.PublicKey),
+		ttl:     DefaultTTL,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Address returns the signer address clients should trust. Facilitators publish
+// it in v2.SupportedResponse.AttestationKeys.
+func (s *Signer) Address() string {
+	return s.address.Hex()
+}
+
+// Close wipes the signing key. Later Attest calls fail with v2.ErrSignerClosed.
+func (s *Signer) Close() error {
+	return s.key.Close()
+}
+
+// Attest signs pr and stores the attestation in its extensions.
+func (s *Signer) Attest(pr *v2.PaymentRequired) error {
+	issuedAt := v2.ClockOrSystem(s.clock).Now()
+	att := Attestation{
+		Signer:    s.address.Hex(),
+		IssuedAt:  issuedAt.Unix(),
+		ExpiresAt: issuedAt.Add(s.ttl).Unix(),
+	}
+
+	message, err := Message(*pr, att.IssuedAt, att.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	signature, err := keymem.WithECDSA(s.key, func(key *ecdsa.PrivateKey) ([]byte, error) {
+		return crypto.Sign(accounts.TextHash(message), key)
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", v2.ErrSigningFailed, err)
+	}
+	signature[64] += 27
+	att.Signature = hexutil.Encode(signature)
+
+	if pr.Extensions == nil {
+		pr.Extensions = make(map[string]v2.Extension)
+	}
+	pr.Extensions[v2.RequirementsAttestationExtension] = v2.Extension{
+		Info: map[string]interface{}{
+			"signer":    att.Signer,
+			"signature": att.Signature,
+			"issuedAt":  att.IssuedAt,
+			"expiresAt": att.ExpiresAt,
+		},
+	}
+	return nil
+}
+
+// Verifier checks PaymentRequired attestations on the client side.
+type Verifier struct {
+	mu       sync.RWMutex
+	trusted  map[common.Address]bool
+	required bool
+	clock    v2.Clock
+	skew     time.Duration
+	keys     *KeyDirectory
+}
+
+// VerifierOption configures a Verifier.
+type VerifierOption func(*Verifier)
+
+// WithTrustedKeys trusts attestations signed by the given EVM addresses.
+func WithTrustedKeys(addresses ...string) VerifierOption {
+	return func(v *Verifier) {
+		v.Trust(addresses...)
+	}
+}
+
+// WithRequired rejects PaymentRequired bodies without an attestation. By default
+// unattested bodies are accepted and only present attestations are checked.
+func WithRequired() VerifierOption {
+	return func(v *Verifier) {
+		v.required = true
+	}
+}
+
+// WithKeyDirectory pins attestation keys per origin: VerifyFor trusts the
+// keys an origin publishes in directory, and requires attestations from
+// origins that publish any.
+func WithKeyDirectory(directory *KeyDirectory) VerifierOption {
+	return func(v *Verifier) {
+		v.keys = directory
+	}
+}
+
+// WithVerifierClock sets the clock used for expiry checks.
+func WithVerifierClock(clock v2.Clock) VerifierOption {
+	return func(v *Verifier) {
+		v.clock = clock
+	}
+}
+
+// WithClockSkew sets the tolerated clock difference (default: v2.DefaultClockSkew).
+func WithClockSkew(skew time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.skew = skew
+	}
+}
+
+// NewVerifier creates a Verifier.
+func NewVerifier(opts ...VerifierOption) *Verifier {
+	v := &Verifier{
+		trusted: make(map[common.Address]bool),
+		skew:    v2.DefaultClockSkew,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Trust adds trusted signer addresses. Invalid addresses are ignored.
+func (v *Verifier) Trust(addresses ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, address := range addresses {
+		if common.IsHexAddress(address) {
+			v.trusted[common.HexToAddress(address)] = true
+		}
+	}
+}
+
+// TrustSupported trusts the attestation keys a facilitator advertises in its
+// /supported response.
+func (v *Verifier) TrustSupported(supported *v2.SupportedResponse) {
+	if supported != nil {
+		v.Trust(supported.AttestationKeys...)
+	}
+}
+
+// Verify checks the attestation of pr. It returns v2.ErrUnattestedRequirements
+// when the attestation is missing and required, and an error wrapping
+// v2.ErrInvalidAttestation when it is malformed, expired, does not match the
+// body, or is signed by an untrusted key.
+func (v *Verifier) Verify(pr v2.PaymentRequired) error {
+	return v.VerifyFor(context.Background(), pr, "")
+}
+
+// VerifyFor is like Verify for a PaymentRequired received from origin, like
+// "https://api.example.com". With a KeyDirectory, keys origin publishes are
+// trusted in addition to the configured keys, and origins publishing keys
+// must attest their requirements. An unknown signer triggers a refetch of
+// origin's keys, so rotated keys are picked up before their cache expires.
+// If origin's keys cannot be fetched and none are cached, verification fails.
+func (v *Verifier) VerifyFor(ctx context.Context, pr v2.PaymentRequired, origin string) error {
+	var published *KeySet
+	if v.keys != nil && origin != "" {
+		set, err := v.keys.Keys(ctx, origin)
+		if err != nil {
+			return fmt.Errorf("%w: %v", v2.ErrInvalidAttestation, err)
+		}
+		if len(set.Keys) > 0 {
+			published = set
+		}
+	}
+
+	att, err := FromPaymentRequired(pr)
+	if err != nil {
+		if errors.Is(err, v2.ErrUnattestedRequirements) && !v.required && published == nil {
+			return nil
+		}
+		return err
+	}
+
+	recovered, err := v.recoverSigner(pr, att)
+	if err != nil {
+		return err
+	}
+
+	v.mu.RLock()
+	trusted := v.trusted[recovered]
+	v.mu.RUnlock()
+	if !trusted && published != nil {
+		trusted = published.Trusts(recovered, att.IssuedAt)
+		if !trusted {
+			if set, err := v.keys.refresh(ctx, origin); err == nil {
+				trusted = set.Trusts(recovered, att.IssuedAt)
+			}
+		}
+	}
+	if !trusted {
+		return fmt.Errorf("%w: untrusted signer %s", v2.ErrInvalidAttestation, recovered.Hex())
+	}
+	return nil
+}
+
+// recoverSigner checks the validity window and signature of att and returns
+// the signing address.
+func (v *Verifier) recoverSigner(pr v2.PaymentRequired, att *Attestation) (common.Address, error) {
+	now := v2.ClockOrSystem(v.clock).Now()
+	if now.After(time.Unix(att.ExpiresAt, 0).Add(v.skew)) {
+		return common.Address{}, fmt.Errorf("%w: expired at %d", v2.ErrInvalidAttestation, att.ExpiresAt)
+	}
+	if now.Add(v.skew).Before(time.Unix(att.IssuedAt, 0)) {
+		return common.Address{}, fmt.Errorf("%w: issued in the future", v2.ErrInvalidAttestation)
+	}
+
+	signature, err := hexutil.Decode(att.Signature)
+	if err != nil || len(signature) != 65 {
+		return common.Address{}, fmt.Errorf("%w: malformed signature", v2.ErrInvalidAttestation)
+	}
+	if signature[64] >= 27 {
+		signature[64] -= 27
+	}
+
+	message, err := Message(pr, att.IssuedAt, att.ExpiresAt)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: %v", v2.ErrInvalidAttestation, err)
+	}
+	publicKey, err := crypto.SigToPub(accounts.TextHash(message), signature)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: %v", v2.ErrInvalidAttestation, err)
+	}
+
+	recovered := crypto.PubkeyToAddress(*publicKey)
+	if !common.IsHexAddress(att.Signer) || recovered != common.HexToAddress(att.Signer) {
+		return common.Address{}, fmt.Errorf("%w: signature does not match requirements", v2.ErrInvalidAttestation)
+	}
+	return recovered, nil
+}
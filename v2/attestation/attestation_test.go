@@ -0,0 +1,186 @@
+package attestation
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+const (
+	testKey   = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+	otherKey  = "0x59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d"
+	testPayTo = "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+)
+
+func testPaymentRequired() v2.PaymentRequired {
+	return v2.PaymentRequired{
+		X402Version: 2,
+		Error:       "Payment required",
+		Resource:    &v2.ResourceInfo{URL: "https://api.example.com/data"},
+		Accepts: []v2.PaymentRequirements{{
+			Scheme:            "exact",
+			Network:           "eip155:84532",
+			Amount:            "10000",
+			Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+			PayTo:             testPayTo,
+			MaxTimeoutSeconds: 60,
+			Extra:             map[string]interface{}{"name": "USDC", "version": "2"},
+		}},
+	}
+}
+
+// roundTrip encodes and decodes pr, as a client receiving it would.
+func roundTrip(t *testing.T, pr v2.PaymentRequired) v2.PaymentRequired {
+	t.Helper()
+	raw, err := json.Marshal(pr)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var decoded v2.PaymentRequired
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	return decoded
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	signer, err := NewSigner(testKey, WithTTL(time.Minute), WithSignerClock(v2.NewFakeClock(now)))
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+	other, err := NewSigner(otherKey, WithSignerClock(v2.NewFakeClock(now)))
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+
+	attested := func(s *Signer) v2.PaymentRequired {
+		pr := testPaymentRequired()
+		if err := s.Attest(&pr); err != nil {
+			t.Fatalf("Attest failed: %v", err)
+		}
+		return roundTrip(t, pr)
+	}
+
+	tests := []struct {
+		name     string
+		pr       func() v2.PaymentRequired
+		opts     []VerifierOption
+		elapsed  time.Duration
+		wantErr  error
+		noErrors bool
+	}{
+		{
+			name:     "valid",
+			pr:       func() v2.PaymentRequired { return attested(signer) },
+			noErrors: true,
+		},
+		{
+			name: "error message is not covered",
+			pr: func() v2.PaymentRequired {
+				pr := attested(signer)
+				pr.Error = "No matching payment requirement"
+				return pr
+			},
+			noErrors: true,
+		},
+		{
+			name: "swapped payTo",
+			pr: func() v2.PaymentRequired {
+				pr := attested(signer)
+				pr.Accepts[0].PayTo = "0x000000000000000000000000000000000000dEaD"
+				return pr
+			},
+			wantErr: v2.ErrInvalidAttestation,
+		},
+		{
+			name: "changed extra",
+			pr: func() v2.PaymentRequired {
+				pr := attested(signer)
+				pr.Accepts[0].Extra["version"] = "1"
+				return pr
+			},
+			wantErr: v2.ErrInvalidAttestation,
+		},
+		{
+			name:    "expired",
+			pr:      func() v2.PaymentRequired { return attested(signer) },
+			elapsed: 2 * time.Minute,
+			wantErr: v2.ErrInvalidAttestation,
+		},
+		{
+			name:    "untrusted signer",
+			pr:      func() v2.PaymentRequired { return attested(other) },
+			wantErr: v2.ErrInvalidAttestation,
+		},
+		{
+			name:     "missing and optional",
+			pr:       testPaymentRequired,
+			noErrors: true,
+		},
+		{
+			name:    "missing and required",
+			pr:      testPaymentRequired,
+			opts:    []VerifierOption{WithRequired()},
+			wantErr: v2.ErrUnattestedRequirements,
+		},
+		{
+			name: "malformed signature",
+			pr: func() v2.PaymentRequired {
+				pr := attested(signer)
+				pr.Extensions[v2.RequirementsAttestationExtension].Info["signature"] = "0x1234"
+				return pr
+			},
+			wantErr: v2.ErrInvalidAttestation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]VerifierOption{
+				WithTrustedKeys(signer.Address()),
+				WithVerifierClock(v2.NewFakeClock(now.Add(tt.elapsed))),
+			}, tt.opts...)
+			err := NewVerifier(opts...).Verify(tt.pr())
+			if tt.noErrors {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifier_TrustSupported(t *testing.T) {
+	signer, err := NewSigner(testKey)
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+	pr := testPaymentRequired()
+	if err := signer.Attest(&pr); err != nil {
+		t.Fatalf("Attest failed: %v", err)
+	}
+
+	verifier := NewVerifier(WithRequired())
+	if err := verifier.Verify(pr); !errors.Is(err, v2.ErrInvalidAttestation) {
+		t.Errorf("expected untrusted signer before discovery, got %v", err)
+	}
+
+	verifier.TrustSupported(&v2.SupportedResponse{AttestationKeys: []string{signer.Address()}})
+	if err := verifier.Verify(pr); err != nil {
+		t.Errorf("expected discovered key to be trusted, got %v", err)
+	}
+}
+
+func TestNewSigner_InvalidKey(t *testing.T) {
+	if _, err := NewSigner("not-a-key"); !errors.Is(err, v2.ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+}
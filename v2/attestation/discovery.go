@@ -0,0 +1,241 @@
+package attestation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// WellKnownPath is where sellers publish their attestation KeySet.
+const WellKnownPath = "/.well-known/x402-key"
+
+// KeyTXTPrefix starts the DNS TXT records DNSKeys reads, one per key:
+//
+//	_x402-key.api.example.com. TXT "x402-key=0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+const KeyTXTPrefix = "x402-key="
+
+// DefaultKeyTTL is how long a KeyDirectory caches a published KeySet.
+const DefaultKeyTTL = time.Hour
+
+// MinKeyRefresh is the minimum interval between fetches of one origin's keys
+// when an attestation names an unknown signer, which bounds the requests a
+// hostile server can trigger.
+const MinKeyRefresh = time.Minute
+
+// PublishedKey is an attestation key a seller publishes for its origin.
+// Keys are rotated by publishing the new key next to the old one and
+// retiring the old key with NotAfter.
+type PublishedKey struct {
+	// Address is the EVM address of the attesting key.
+	Address string `json:"address"`
+
+	// NotBefore and NotAfter optionally bound, in Unix time, when attestations
+	// issued by the key are accepted.
+	NotBefore int64 `json:"notBefore,omitempty"`
+	NotAfter  int64 `json:"notAfter,omitempty"`
+}
+
+// ValidAt reports whether an attestation issued at the Unix time issuedAt may
+// be signed by k.
+func (k PublishedKey) ValidAt(issuedAt int64) bool {
+	if k.NotBefore != 0 && issuedAt < k.NotBefore {
+		return false
+	}
+	return k.NotAfter == 0 || issuedAt <= k.NotAfter
+}
+
+// KeySet is the document served at WellKnownPath.
+type KeySet struct {
+	Keys []PublishedKey `json:"keys"`
+}
+
+// Trusts reports whether address may sign attestations issued at issuedAt.
+func (s *KeySet) Trusts(address common.Address, issuedAt int64) bool {
+	for _, key := range s.Keys {
+		if common.IsHexAddress(key.Address) && common.HexToAddress(key.Address) == address && key.ValidAt(issuedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewKeySetHandler serves keys as a KeySet. Mount it at WellKnownPath.
+func NewKeySetHandler(keys ...PublishedKey) http.Handler {
+	body, _ := json.Marshal(KeySet{Keys: keys})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(DefaultKeyTTL/time.Second)))
+		_, _ = w.Write(body)
+	})
+}
+
+// KeySource fetches the keys an origin, like "https://api.example.com",
+// publishes. It returns an empty KeySet if the origin publishes none.
+type KeySource interface {
+	FetchKeys(ctx context.Context, origin string) (*KeySet, error)
+}
+
+// WellKnownKeys fetches KeySets from WellKnownPath of each origin.
+type WellKnownKeys struct {
+	// Client fetches the key sets (default: http.DefaultClient).
+	Client *http.Client
+}
+
+// FetchKeys implements KeySource. A 404 response means no keys are published.
+func (w WellKnownKeys) FetchKeys(ctx context.Context, origin string) (*KeySet, error) {
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(origin, "/")+WellKnownPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating key request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching keys of %s: %w", origin, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return &KeySet{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching keys of %s: status %d", origin, resp.StatusCode)
+	}
+	var set KeySet
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decoding keys of %s: %w", origin, err)
+	}
+	return &set, nil
+}
+
+// DNSKeys reads keys from DNS TXT records at "_x402-key.<host>", so a
+// compromised web server cannot replace them. Use a DNSSEC-validating
+// resolver to also protect the lookup itself.
+type DNSKeys struct {
+	// Resolver looks up TXT records (default: net.DefaultResolver).
+	Resolver *net.Resolver
+}
+
+// FetchKeys implements KeySource.
+func (d DNSKeys) FetchKeys(ctx context.Context, origin string) (*KeySet, error) {
+	u, err := url.Parse(origin)
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid origin %q", origin)
+	}
+	resolver := d.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	records, err := resolver.LookupTXT(ctx, "_x402-key."+u.Hostname())
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return &KeySet{}, nil
+		}
+		return nil, fmt.Errorf("looking up keys of %s: %w", u.Hostname(), err)
+	}
+
+	set := &KeySet{}
+	for _, record := range records {
+		if address, ok := strings.CutPrefix(record, KeyTXTPrefix); ok {
+			set.Keys = append(set.Keys, PublishedKey{Address: strings.TrimSpace(address)})
+		}
+	}
+	return set, nil
+}
+
+// KeyDirectory caches the keys origins publish through a KeySource. Once an
+// origin publishes keys, a Verifier using the directory requires every
+// PaymentRequired from that origin to be attested by one of them.
+type KeyDirectory struct {
+	source KeySource
+	ttl    time.Duration
+	clock  v2.Clock
+
+	mu      sync.Mutex
+	entries map[string]*keyEntry
+}
+
+type keyEntry struct {
+	set     *KeySet
+	fetched time.Time
+}
+
+// DirectoryOption configures a KeyDirectory.
+type DirectoryOption func(*KeyDirectory)
+
+// WithKeyTTL sets how long fetched keys are cached (default: DefaultKeyTTL).
+func WithKeyTTL(ttl time.Duration) DirectoryOption {
+	return func(d *KeyDirectory) {
+		d.ttl = ttl
+	}
+}
+
+// WithDirectoryClock sets the clock used for cache expiry.
+func WithDirectoryClock(clock v2.Clock) DirectoryOption {
+	return func(d *KeyDirectory) {
+		d.clock = clock
+	}
+}
+
+// NewKeyDirectory creates a KeyDirectory fetching keys from source, such as
+// WellKnownKeys or DNSKeys.
+func NewKeyDirectory(source KeySource, opts ...DirectoryOption) *KeyDirectory {
+	d := &KeyDirectory{
+		source:  source,
+		ttl:     DefaultKeyTTL,
+		entries: make(map[string]*keyEntry),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Keys returns the keys origin publishes, fetching them when the cached set
+// has expired. If a refetch fails, the expired set is used.
+func (d *KeyDirectory) Keys(ctx context.Context, origin string) (*KeySet, error) {
+	return d.keys(ctx, origin, d.ttl)
+}
+
+// refresh refetches the keys of origin unless they were fetched within
+// MinKeyRefresh, picking up rotated keys.
+func (d *KeyDirectory) refresh(ctx context.Context, origin string) (*KeySet, error) {
+	return d.keys(ctx, origin, MinKeyRefresh)
+}
+
+// keys returns the keys of origin, refetching them if older than maxAge.
+func (d *KeyDirectory) keys(ctx context.Context, origin string, maxAge time.Duration) (*KeySet, error) {
+	now := v2.ClockOrSystem(d.clock).Now()
+	d.mu.Lock()
+	entry := d.entries[origin]
+	d.mu.Unlock()
+	if entry != nil && now.Sub(entry.fetched) < maxAge {
+		return entry.set, nil
+	}
+
+	set, err := d.source.FetchKeys(ctx, origin)
+	if err != nil {
+		if entry != nil {
+			return entry.set, nil
+		}
+		return nil, err
+	}
+	d.mu.Lock()
+	d.entries[origin] = &keyEntry{set: set, fetched: now}
+	d.mu.Unlock()
+	return set, nil
+}
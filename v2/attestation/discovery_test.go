@@ -0,0 +1,111 @@
+package attestation
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func TestVerifyFor_KeyDirectory(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	clock := v2.NewFakeClock(now)
+	signer, err := NewSigner(testKey, WithSignerClock(clock))
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+	rotated, err := NewSigner(otherKey, WithSignerClock(clock))
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+
+	var mu sync.Mutex
+	published := NewKeySetHandler(PublishedKey{Address: signer.Address()})
+	var fetches int32
+	mux := http.NewServeMux()
+	mux.HandleFunc(WellKnownPath, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		mu.Lock()
+		handler := published
+		mu.Unlock()
+		handler.ServeHTTP(w, r)
+	})
+	seller := httptest.NewServer(mux)
+	defer seller.Close()
+	unpublished := httptest.NewServer(http.NotFoundHandler())
+	defer unpublished.Close()
+
+	verifier := NewVerifier(
+		WithVerifierClock(clock),
+		WithKeyDirectory(NewKeyDirectory(WellKnownKeys{}, WithDirectoryClock(clock))),
+	)
+	attest := func(s *Signer) v2.PaymentRequired {
+		pr := testPaymentRequired()
+		if err := s.Attest(&pr); err != nil {
+			t.Fatalf("Attest failed: %v", err)
+		}
+		return roundTrip(t, pr)
+	}
+	ctx := context.Background()
+
+	if err := verifier.VerifyFor(ctx, attest(signer), seller.URL); err != nil {
+		t.Errorf("expected published key to be trusted, got %v", err)
+	}
+	if err := verifier.VerifyFor(ctx, testPaymentRequired(), seller.URL); !errors.Is(err, v2.ErrUnattestedRequirements) {
+		t.Errorf("expected unattested requirements to be refused for a pinned origin, got %v", err)
+	}
+	if err := verifier.VerifyFor(ctx, attest(rotated), seller.URL); !errors.Is(err, v2.ErrInvalidAttestation) {
+		t.Errorf("expected unpublished key to be refused, got %v", err)
+	}
+	if err := verifier.VerifyFor(ctx, testPaymentRequired(), unpublished.URL); err != nil {
+		t.Errorf("expected origins without keys to be unrestricted, got %v", err)
+	}
+	if err := verifier.VerifyFor(ctx, attest(signer), unpublished.URL); !errors.Is(err, v2.ErrInvalidAttestation) {
+		t.Errorf("expected keys not to be trusted across origins, got %v", err)
+	}
+	// Refreshes for unknown keys are rate limited by MinKeyRefresh
+	if got := atomic.LoadInt32(&fetches); got != 1 {
+		t.Errorf("expected keys to be fetched once, got %d", got)
+	}
+
+	// The seller rotates to a new key and retires the old one
+	mu.Lock()
+	published = NewKeySetHandler(
+		PublishedKey{Address: signer.Address(), NotAfter: now.Unix()},
+		PublishedKey{Address: rotated.Address(), NotBefore: now.Unix()},
+	)
+	mu.Unlock()
+	clock.Advance(MinKeyRefresh)
+
+	if err := verifier.VerifyFor(ctx, attest(rotated), seller.URL); err != nil {
+		t.Errorf("expected rotated key to be picked up, got %v", err)
+	}
+	clock.Advance(time.Second)
+	if err := verifier.VerifyFor(ctx, attest(signer), seller.URL); !errors.Is(err, v2.ErrInvalidAttestation) {
+		t.Errorf("expected retired key to be refused, got %v", err)
+	}
+}
+
+func TestPublishedKey_ValidAt(t *testing.T) {
+	key := PublishedKey{Address: "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266", NotBefore: 100, NotAfter: 200}
+	tests := []struct {
+		issuedAt int64
+		want     bool
+	}{
+		{99, false},
+		{100, true},
+		{200, true},
+		{201, false},
+	}
+	for _, tt := range tests {
+		if got := key.ValidAt(tt.issuedAt); got != tt.want {
+			t.Errorf("ValidAt(%d) = %v; want %v", tt.issuedAt, got, tt.want)
+		}
+	}
+}
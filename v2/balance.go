@@ -0,0 +1,180 @@
+package v2
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// BalanceReporter is implemented by signers that can report their on-chain token balance.
+type BalanceReporter interface {
+	// Balance returns the signer's balance of asset in atomic units.
+	Balance(ctx context.Context, asset string) (*big.Int, error)
+}
+
+// LowBalanceHandler is invoked when a signer's balance drops below a configured threshold.
+// Implementations typically bridge or transfer funds to the signer, or page an operator.
+type LowBalanceHandler interface {
+	OnLowBalance(ctx context.Context, network, token string, balance *big.Int) error
+}
+
+// LowBalanceHandlerFunc adapts a function to the LowBalanceHandler interface.
+type LowBalanceHandlerFunc func(ctx context.Context, network, token string, balance *big.Int) error
+
+// OnLowBalance calls f(ctx, network, token, balance).
+func (f LowBalanceHandlerFunc) OnLowBalance(ctx context.Context, network, token string, balance *big.Int) error {
+	return f(ctx, network, token, balance)
+}
+
+// BalanceMonitor checks signer balances against per-token thresholds and invokes
+// a LowBalanceHandler when a balance drops below its threshold.
+//
+// The handler fires once when a balance crosses below the threshold and is re-armed
+// once the balance is back at or above it, so a slow top-up does not trigger repeated
+// transfers. The EVM signer reports balances when configured with a preflight
+// (evm.WithPreflight) and the SVM signer through its RPC endpoint; signers that
+// do not implement BalanceReporter are skipped.
+//
+// BalanceMonitor is safe for concurrent use.
+type BalanceMonitor struct {
+	signers    []Signer
+	handler    LowBalanceHandler
+	thresholds map[string]*big.Int
+	onError    func(network, token string, err error)
+
+	mu  sync.Mutex
+	low map[string]bool
+}
+
+// BalanceMonitorOption configures a BalanceMonitor.
+type BalanceMonitorOption func(*BalanceMonitor)
+
+// WithBalanceThreshold sets the threshold, in atomic units, below which the handler
+// is invoked for token on network.
+func WithBalanceThreshold(network, token string, threshold *big.Int) BalanceMonitorOption {
+	return func(m *BalanceMonitor) {
+		m.thresholds[assetKey(network, token)] = new(big.Int).Set(threshold)
+	}
+}
+
+// WithBalanceErrorHandler sets a callback for balance lookup and handler errors.
+// By default such errors are ignored and retried on the next check.
+func WithBalanceErrorHandler(onError func(network, token string, err error)) BalanceMonitorOption {
+	return func(m *BalanceMonitor) {
+		m.onError = onError
+	}
+}
+
+// NewBalanceMonitor creates a BalanceMonitor for signers.
+// Thresholds are configured with WithBalanceThreshold; tokens without a threshold are not checked.
+func NewBalanceMonitor(signers []Signer, handler LowBalanceHandler, opts ...BalanceMonitorOption) *BalanceMonitor {
+	m := &BalanceMonitor{
+		signers:    signers,
+		handler:    handler,
+		thresholds: make(map[string]*big.Int),
+		low:        make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Check queries the balance of every configured token and invokes the handler
+// for each token that has dropped below its threshold.
+func (m *BalanceMonitor) Check(ctx context.Context) {
+	for _, signer := range m.signers {
+		m.checkSigner(ctx, signer)
+	}
+}
+
+// CheckNetwork is like Check but only queries signers on network.
+func (m *BalanceMonitor) CheckNetwork(ctx context.Context, network string) {
+	for _, signer := range m.signers {
+		if signer.Network() == network {
+			m.checkSigner(ctx, signer)
+		}
+	}
+}
+
+// Run calls Check every interval until ctx is cancelled.
+func (m *BalanceMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	m.Check(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check(ctx)
+		}
+	}
+}
+
+// PaymentCallback returns a callback that re-checks the paid network after each
+// successful payment. Register it with a client's success callback so balances are
+// checked as they are spent rather than only on a timer.
+func (m *BalanceMonitor) PaymentCallback() PaymentCallback {
+	return func(event PaymentEvent) {
+		if event.Type != PaymentEventSuccess || event.Network == "" {
+			return
+		}
+		go m.CheckNetwork(context.Background(), event.Network)
+	}
+}
+
+// checkSigner checks the thresholded tokens of a single signer.
+func (m *BalanceMonitor) checkSigner(ctx context.Context, signer Signer) {
+	reporter, ok := signer.(BalanceReporter)
+	if !ok {
+		return
+	}
+
+	network := signer.Network()
+	for _, token := range signer.GetTokens() {
+		key := assetKey(network, token.Address)
+		threshold, ok := m.thresholds[key]
+		if !ok {
+			continue
+		}
+
+		balance, err := reporter.Balance(ctx, token.Address)
+		if err != nil {
+			m.reportError(network, token.Address, err)
+			continue
+		}
+
+		if balance.Cmp(threshold) >= 0 {
+			m.setLow(key, false)
+			continue
+		}
+		if !m.setLow(key, true) {
+			continue
+		}
+
+		if err := m.handler.OnLowBalance(ctx, network, token.Address, balance); err != nil {
+			// Re-arm so the handler is retried on the next check
+			m.setLow(key, false)
+			m.reportError(network, token.Address, err)
+		}
+	}
+}
+
+// setLow records whether key is below its threshold and reports whether the state changed.
+func (m *BalanceMonitor) setLow(key string, low bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	changed := m.low[key] != low
+	m.low[key] = low
+	return changed
+}
+
+// reportError forwards an error to the configured error handler, if any.
+func (m *BalanceMonitor) reportError(network, token string, err error) {
+	if m.onError != nil {
+		m.onError(network, token, err)
+	}
+}
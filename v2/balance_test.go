@@ -0,0 +1,96 @@
+package v2
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// mockBalanceSigner is a mockSigner that reports configurable balances.
+type mockBalanceSigner struct {
+	mockSigner
+	balances map[string]*big.Int
+	err      error
+}
+
+func (m *mockBalanceSigner) Balance(ctx context.Context, asset string) (*big.Int, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.balances[asset], nil
+}
+
+func TestBalanceMonitor_Check(t *testing.T) {
+	token := BaseSepolia.USDCAddress
+	signer := &mockBalanceSigner{
+		mockSigner: mockSigner{network: NetworkBaseSepolia, scheme: "exact", tokens: []TokenConfig{{Address: token}}},
+		balances:   map[string]*big.Int{token: big.NewInt(500)},
+	}
+
+	type call struct {
+		network, token, balance string
+	}
+	var calls []call
+	handler := LowBalanceHandlerFunc(func(ctx context.Context, network, token string, balance *big.Int) error {
+		calls = append(calls, call{network, token, balance.String()})
+		return nil
+	})
+
+	monitor := NewBalanceMonitor([]Signer{signer}, handler,
+		WithBalanceThreshold(NetworkBaseSepolia, token, big.NewInt(1000)))
+
+	monitor.Check(context.Background())
+	if len(calls) != 1 || calls[0] != (call{NetworkBaseSepolia, token, "500"}) {
+		t.Fatalf("expected one low balance call, got %+v", calls)
+	}
+
+	// Still low: the handler is not invoked again
+	monitor.Check(context.Background())
+	if len(calls) != 1 {
+		t.Fatalf("expected handler to fire once while low, got %d calls", len(calls))
+	}
+
+	// Topped up, then low again: the handler fires again
+	signer.balances[token] = big.NewInt(2000)
+	monitor.Check(context.Background())
+	signer.balances[token] = big.NewInt(10)
+	monitor.Check(context.Background())
+	if len(calls) != 2 || calls[1].balance != "10" {
+		t.Errorf("expected handler to re-arm after top-up, got %+v", calls)
+	}
+}
+
+func TestBalanceMonitor_Errors(t *testing.T) {
+	token := BaseSepolia.USDCAddress
+	signer := &mockBalanceSigner{
+		mockSigner: mockSigner{network: NetworkBaseSepolia, scheme: "exact", tokens: []TokenConfig{{Address: token}}},
+		balances:   map[string]*big.Int{token: big.NewInt(0)},
+	}
+
+	handlerErr := errors.New("webhook down")
+	attempts := 0
+	handler := LowBalanceHandlerFunc(func(ctx context.Context, network, token string, balance *big.Int) error {
+		attempts++
+		return handlerErr
+	})
+
+	var reported []error
+	monitor := NewBalanceMonitor([]Signer{signer, &mockSigner{network: NetworkBase}}, handler,
+		WithBalanceThreshold(NetworkBaseSepolia, token, big.NewInt(1)),
+		WithBalanceErrorHandler(func(network, token string, err error) {
+			reported = append(reported, err)
+		}))
+
+	monitor.Check(context.Background())
+	monitor.Check(context.Background())
+	if attempts != 2 {
+		t.Errorf("expected failed handler to be retried, got %d attempts", attempts)
+	}
+
+	signer.err = errors.New("rpc unavailable")
+	monitor.Check(context.Background())
+	if len(reported) != 3 || !errors.Is(reported[0], handlerErr) || reported[2] != signer.err {
+		t.Errorf("unexpected reported errors: %v", reported)
+	}
+}
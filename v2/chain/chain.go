@@ -0,0 +1,90 @@
+// Package chain confirms on-chain token transfers: that a transaction was
+// included with enough confirmations and paid the expected recipient at least
+// the expected amount of a token. EVM confirms ERC-20 transfers through an
+// Ethereum JSON-RPC node and SVM confirms SPL token transfers through a Solana
+// RPC node.
+//
+// A confirmation is a single check; callers waiting for a pending transaction
+// retry while ConfirmTransaction fails with ErrTxNotFound or ErrNotConfirmed.
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+var (
+	// ErrUnsupportedNetwork indicates the confirmer does not serve the network.
+	ErrUnsupportedNetwork = errors.New("chain: unsupported network")
+
+	// ErrTxNotFound indicates the transaction is unknown or not yet included.
+	ErrTxNotFound = errors.New("chain: transaction not found")
+
+	// ErrNotConfirmed indicates the transaction is included but not yet at the
+	// required confirmation depth or commitment.
+	ErrNotConfirmed = errors.New("chain: transaction not confirmed")
+
+	// ErrTxFailed indicates the transaction was included but failed.
+	ErrTxFailed = errors.New("chain: transaction failed")
+
+	// ErrTransferNotFound indicates the transaction does not make the expected
+	// transfer.
+	ErrTransferNotFound = errors.New("chain: expected transfer not found")
+)
+
+// Transfer is the transfer a transaction is expected to make.
+type Transfer struct {
+	// Asset is the token contract address (EVM) or mint (SVM).
+	Asset string
+
+	// To is the recipient: the token holder on EVM, the owner of the receiving
+	// token account on SVM.
+	To string
+
+	// Amount is the minimum amount in atomic units.
+	Amount *big.Int
+}
+
+// Confirmation describes a confirmed transfer.
+type Confirmation struct {
+	// Network is the CAIP-2 network of the transaction.
+	Network string
+
+	// Transaction is the transaction hash (EVM) or signature (SVM).
+	Transaction string
+
+	// From is the sender of the transfer.
+	From string
+
+	// Amount is the amount actually transferred, in atomic units.
+	Amount *big.Int
+
+	// Block is the block number (EVM) or slot (SVM) that included the
+	// transaction.
+	Block uint64
+
+	// Time is the block time, or zero if the node does not report it.
+	Time time.Time
+}
+
+// Confirmer confirms transfers on one or more networks.
+type Confirmer interface {
+	// ConfirmTransaction checks that transaction on network is confirmed and
+	// makes the expected transfer.
+	ConfirmTransaction(ctx context.Context, network, transaction string, expected Transfer) (*Confirmation, error)
+}
+
+// Networks is a Confirmer routing each network to its own Confirmer.
+type Networks map[string]Confirmer
+
+// ConfirmTransaction confirms transaction with the Confirmer of network.
+func (n Networks) ConfirmTransaction(ctx context.Context, network, transaction string, expected Transfer) (*Confirmation, error) {
+	confirmer, ok := n[network]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedNetwork, network)
+	}
+	return confirmer.ConfirmTransaction(ctx, network, transaction, expected)
+}
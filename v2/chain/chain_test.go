@@ -0,0 +1,220 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	evmNetwork = "eip155:84532"
+	evmAsset   = "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
+	evmPayTo   = "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+	evmPayer   = "0x857b06519E91e3A54538791bDbb0E22373e36b66"
+	svmNetwork = "solana:EtWTRABZaYq6iMfeYKouRu166VU2xqa1"
+)
+
+// fakeEVM is an EVMReader serving fixed receipts.
+type fakeEVM struct {
+	head     uint64
+	receipts map[common.Hash]*types.Receipt
+}
+
+func (c *fakeEVM) TransactionReceipt(_ context.Context, hash common.Hash) (*types.Receipt, error) {
+	receipt, ok := c.receipts[hash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func (c *fakeEVM) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	n := c.head
+	if number != nil {
+		n = number.Uint64()
+	}
+	return &types.Header{Number: new(big.Int).SetUint64(n), Time: 1700000000 + n}, nil
+}
+
+func erc20Receipt(block, status uint64, asset, to string, value int64) *types.Receipt {
+	return &types.Receipt{
+		Status:      status,
+		BlockNumber: new(big.Int).SetUint64(block),
+		Logs: []*types.Log{{
+			Address: common.HexToAddress(asset),
+			Topics: []common.Hash{
+				transferTopic,
+				common.BytesToHash(common.HexToAddress(evmPayer).Bytes()),
+				common.BytesToHash(common.HexToAddress(to).Bytes()),
+			},
+			Data: common.LeftPadBytes(big.NewInt(value).Bytes(), 32),
+		}},
+	}
+}
+
+func TestEVM_ConfirmTransaction(t *testing.T) {
+	client := &fakeEVM{head: 102, receipts: map[common.Hash]*types.Receipt{}}
+	hashes := map[string]string{}
+	addTx := func(name string, receipt *types.Receipt) {
+		hash := crypto.Keccak256Hash([]byte(name))
+		hashes[name] = hash.Hex()
+		if receipt != nil {
+			client.receipts[hash] = receipt
+		}
+	}
+	addTx("valid", erc20Receipt(100, types.ReceiptStatusSuccessful, evmAsset, evmPayTo, 1000))
+	addTx("failed", erc20Receipt(100, types.ReceiptStatusFailed, evmAsset, evmPayTo, 1000))
+	addTx("too small", erc20Receipt(100, types.ReceiptStatusSuccessful, evmAsset, evmPayTo, 999))
+	addTx("wrong recipient", erc20Receipt(100, types.ReceiptStatusSuccessful, evmAsset, evmPayer, 1000))
+	addTx("wrong asset", erc20Receipt(100, types.ReceiptStatusSuccessful, evmPayTo, evmPayTo, 1000))
+	addTx("recent", erc20Receipt(101, types.ReceiptStatusSuccessful, evmAsset, evmPayTo, 1000))
+	addTx("pending", nil)
+
+	confirmer := NewEVM(evmNetwork, client, WithConfirmations(3))
+	expected := Transfer{Asset: evmAsset, To: evmPayTo, Amount: big.NewInt(1000)}
+
+	tests := []struct {
+		name    string
+		network string
+		tx      string
+		wantErr error
+	}{
+		{"valid", evmNetwork, hashes["valid"], nil},
+		{"failed", evmNetwork, hashes["failed"], ErrTxFailed},
+		{"amount too small", evmNetwork, hashes["too small"], ErrTransferNotFound},
+		{"wrong recipient", evmNetwork, hashes["wrong recipient"], ErrTransferNotFound},
+		{"wrong asset", evmNetwork, hashes["wrong asset"], ErrTransferNotFound},
+		{"not enough confirmations", evmNetwork, hashes["recent"], ErrNotConfirmed},
+		{"not included", evmNetwork, hashes["pending"], ErrTxNotFound},
+		{"malformed hash", evmNetwork, "0x12", ErrTxNotFound},
+		{"other network", "eip155:8453", hashes["valid"], ErrUnsupportedNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confirmation, err := confirmer.ConfirmTransaction(context.Background(), tt.network, tt.tx, expected)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ConfirmTransaction error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if confirmation.From != evmPayer || confirmation.Amount.Int64() != 1000 || confirmation.Block != 100 {
+				t.Errorf("unexpected confirmation %+v", confirmation)
+			}
+			if !confirmation.Time.Equal(time.Unix(1700000100, 0)) {
+				t.Errorf("Time = %v, want the time of block 100", confirmation.Time)
+			}
+		})
+	}
+}
+
+// fakeSVM is an SVMReader serving fixed transactions.
+type fakeSVM struct {
+	transactions map[solana.Signature]*rpc.GetTransactionResult
+	commitment   rpc.CommitmentType
+}
+
+func (c *fakeSVM) GetTransaction(_ context.Context, signature solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+	c.commitment = opts.Commitment
+	result, ok := c.transactions[signature]
+	if !ok {
+		return nil, rpc.ErrNotFound
+	}
+	return result, nil
+}
+
+func tokenBalance(mint, owner solana.PublicKey, amount string) rpc.TokenBalance {
+	return rpc.TokenBalance{Mint: mint, Owner: &owner, UiTokenAmount: &rpc.UiTokenAmount{Amount: amount}}
+}
+
+func TestSVM_ConfirmTransaction(t *testing.T) {
+	mint, payer, payTo := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	blockTime := solana.UnixTimeSeconds(1700000000)
+
+	client := &fakeSVM{transactions: map[solana.Signature]*rpc.GetTransactionResult{}}
+	signature := func(name string) solana.Signature {
+		var signature solana.Signature
+		copy(signature[:], crypto.Keccak256([]byte(name)))
+		return signature
+	}
+	addTx := func(name string, meta *rpc.TransactionMeta) {
+		client.transactions[signature(name)] = &rpc.GetTransactionResult{Slot: 42, BlockTime: &blockTime, Meta: meta}
+	}
+	transfer := func(to solana.PublicKey, amount int64) *rpc.TransactionMeta {
+		return &rpc.TransactionMeta{
+			PreTokenBalances:  []rpc.TokenBalance{tokenBalance(mint, payer, "5000"), tokenBalance(mint, to, "0")},
+			PostTokenBalances: []rpc.TokenBalance{tokenBalance(mint, payer, big.NewInt(5000-amount).String()), tokenBalance(mint, to, big.NewInt(amount).String())},
+		}
+	}
+	addTx("valid", transfer(payTo, 1000))
+	addTx("too small", transfer(payTo, 999))
+	addTx("wrong recipient", transfer(payer, 1000))
+	failed := transfer(payTo, 1000)
+	failed.Err = map[string]interface{}{"InstructionError": []interface{}{0, "Custom"}}
+	addTx("failed", failed)
+	addTx("no meta", nil)
+
+	confirmer := NewSVM(svmNetwork, client, WithCommitment(rpc.CommitmentFinalized))
+	expected := Transfer{Asset: mint.String(), To: payTo.String(), Amount: big.NewInt(1000)}
+
+	tests := []struct {
+		name    string
+		network string
+		tx      string
+		wantErr error
+	}{
+		{"valid", svmNetwork, signature("valid").String(), nil},
+		{"amount too small", svmNetwork, signature("too small").String(), ErrTransferNotFound},
+		{"wrong recipient", svmNetwork, signature("wrong recipient").String(), ErrTransferNotFound},
+		{"failed", svmNetwork, signature("failed").String(), ErrTxFailed},
+		{"not at commitment", svmNetwork, signature("unconfirmed").String(), ErrTxNotFound},
+		{"no status", svmNetwork, signature("no meta").String(), ErrNotConfirmed},
+		{"malformed signature", svmNetwork, "not-a-signature", ErrTxNotFound},
+		{"other network", "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp", signature("valid").String(), ErrUnsupportedNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confirmation, err := confirmer.ConfirmTransaction(context.Background(), tt.network, tt.tx, expected)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ConfirmTransaction error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if confirmation.From != payer.String() || confirmation.Amount.Int64() != 1000 || confirmation.Block != 42 {
+				t.Errorf("unexpected confirmation %+v", confirmation)
+			}
+			if !confirmation.Time.Equal(time.Unix(1700000000, 0)) {
+				t.Errorf("Time = %v, want the block time", confirmation.Time)
+			}
+			if client.commitment != rpc.CommitmentFinalized {
+				t.Errorf("commitment = %q, want %q", client.commitment, rpc.CommitmentFinalized)
+			}
+		})
+	}
+}
+
+func TestNetworks(t *testing.T) {
+	client := &fakeEVM{head: 100, receipts: map[common.Hash]*types.Receipt{}}
+	hash := crypto.Keccak256Hash([]byte("valid"))
+	client.receipts[hash] = erc20Receipt(100, types.ReceiptStatusSuccessful, evmAsset, evmPayTo, 1)
+
+	networks := Networks{evmNetwork: NewEVM(evmNetwork, client)}
+	expected := Transfer{Asset: evmAsset, To: evmPayTo, Amount: big.NewInt(1)}
+
+	if _, err := networks.ConfirmTransaction(context.Background(), evmNetwork, hash.Hex(), expected); err != nil {
+		t.Errorf("expected routed confirmation to succeed, got %v", err)
+	}
+	if _, err := networks.ConfirmTransaction(context.Background(), svmNetwork, hash.Hex(), expected); !errors.Is(err, ErrUnsupportedNetwork) {
+		t.Errorf("expected ErrUnsupportedNetwork, got %v", err)
+	}
+}
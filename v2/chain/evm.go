@@ -0,0 +1,129 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DefaultEVMConfirmations is the number of blocks, including the one that
+// included the transaction, EVM requires by default.
+const DefaultEVMConfirmations = 1
+
+// transferTopic is the topic of the ERC-20 Transfer(address,address,uint256) event.
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+var evmTxHashRegex = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+
+// EVMReader is the RPC access EVM needs. It is satisfied by *ethclient.Client.
+type EVMReader interface {
+	TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// EVM confirms ERC-20 transfers on one EVM network from their Transfer events.
+type EVM struct {
+	network       string
+	client        EVMReader
+	confirmations uint64
+}
+
+// EVMOption configures an EVM confirmer.
+type EVMOption func(*EVM)
+
+// WithConfirmations sets the number of blocks, including the one that included
+// the transaction, required to confirm it (default: DefaultEVMConfirmations).
+func WithConfirmations(n uint64) EVMOption {
+	return func(e *EVM) {
+		e.confirmations = n
+	}
+}
+
+// NewEVM creates a confirmer for network, reading the chain through client.
+func NewEVM(network string, client EVMReader, opts ...EVMOption) *EVM {
+	e := &EVM{network: network, client: client, confirmations: DefaultEVMConfirmations}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// ConfirmTransaction confirms that transaction emitted a Transfer event of
+// expected.Asset to expected.To of at least expected.Amount.
+func (e *EVM) ConfirmTransaction(ctx context.Context, network, transaction string, expected Transfer) (*Confirmation, error) {
+	if network != e.network {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedNetwork, network)
+	}
+	if !evmTxHashRegex.MatchString(transaction) {
+		return nil, fmt.Errorf("%w: malformed transaction hash %q", ErrTxNotFound, transaction)
+	}
+	hash := common.HexToHash(transaction)
+
+	receipt, err := e.client.TransactionReceipt(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, ErrTxNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting receipt of %s: %w", transaction, err)
+	}
+	if receipt.BlockNumber == nil {
+		return nil, ErrTxNotFound
+	}
+
+	head, err := e.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("getting latest header: %w", err)
+	}
+	if head.Number == nil || head.Number.Cmp(receipt.BlockNumber) < 0 ||
+		new(big.Int).Sub(head.Number, receipt.BlockNumber).Uint64()+1 < e.confirmations {
+		return nil, ErrNotConfirmed
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, ErrTxFailed
+	}
+
+	from, amount, ok := findERC20Transfer(receipt, common.HexToAddress(expected.Asset), common.HexToAddress(expected.To), expected.Amount)
+	if !ok {
+		return nil, ErrTransferNotFound
+	}
+
+	confirmation := &Confirmation{
+		Network:     network,
+		Transaction: hash.Hex(),
+		From:        from.Hex(),
+		Amount:      amount,
+		Block:       receipt.BlockNumber.Uint64(),
+	}
+	block, err := e.client.HeaderByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("getting block %s: %w", receipt.BlockNumber, err)
+	}
+	confirmation.Time = time.Unix(int64(block.Time), 0)
+	return confirmation, nil
+}
+
+// findERC20Transfer returns the sender and value of the first Transfer event of
+// asset to to of at least min in receipt.
+func findERC20Transfer(receipt *types.Receipt, asset, to common.Address, min *big.Int) (common.Address, *big.Int, bool) {
+	for _, log := range receipt.Logs {
+		if log.Address != asset || len(log.Topics) != 3 || log.Topics[0] != transferTopic || len(log.Data) != 32 {
+			continue
+		}
+		if common.BytesToAddress(log.Topics[2].Bytes()) != to {
+			continue
+		}
+		value := new(big.Int).SetBytes(log.Data)
+		if min == nil || value.Cmp(min) >= 0 {
+			return common.BytesToAddress(log.Topics[1].Bytes()), value, true
+		}
+	}
+	return common.Address{}, nil, false
+}
@@ -0,0 +1,136 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// SVMReader is the RPC access SVM needs. It is satisfied by *rpc.Client.
+type SVMReader interface {
+	GetTransaction(ctx context.Context, signature solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error)
+}
+
+// SVM confirms SPL token transfers on one Solana network from the token
+// balance changes of the transaction.
+type SVM struct {
+	network    string
+	client     SVMReader
+	commitment rpc.CommitmentType
+}
+
+// SVMOption configures an SVM confirmer.
+type SVMOption func(*SVM)
+
+// WithCommitment sets the commitment a transaction must reach (default:
+// rpc.CommitmentConfirmed). rpc.CommitmentProcessed is not supported by the
+// getTransaction RPC method.
+func WithCommitment(commitment rpc.CommitmentType) SVMOption {
+	return func(s *SVM) {
+		s.commitment = commitment
+	}
+}
+
+// NewSVM creates a confirmer for network, reading the chain through client.
+func NewSVM(network string, client SVMReader, opts ...SVMOption) *SVM {
+	s := &SVM{network: network, client: client, commitment: rpc.CommitmentConfirmed}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ConfirmTransaction confirms that transaction increased the expected.Asset
+// balance owned by expected.To by at least expected.Amount. The sender is the
+// owner whose balance of the asset decreased the most.
+func (s *SVM) ConfirmTransaction(ctx context.Context, network, transaction string, expected Transfer) (*Confirmation, error) {
+	if network != s.network {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedNetwork, network)
+	}
+	signature, err := solana.SignatureFromBase58(transaction)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed transaction signature %q", ErrTxNotFound, transaction)
+	}
+	mint, err := solana.PublicKeyFromBase58(expected.Asset)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mint %q: %w", expected.Asset, err)
+	}
+	to, err := solana.PublicKeyFromBase58(expected.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", expected.To, err)
+	}
+
+	maxVersion := uint64(0)
+	result, err := s.client.GetTransaction(ctx, signature, &rpc.GetTransactionOpts{
+		Encoding:                       solana.EncodingBase64,
+		Commitment:                     s.commitment,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if errors.Is(err, rpc.ErrNotFound) {
+		// Transactions below the commitment are not returned either
+		return nil, ErrTxNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting transaction %s: %w", transaction, err)
+	}
+	if result.Meta == nil {
+		return nil, ErrNotConfirmed
+	}
+	if result.Meta.Err != nil {
+		return nil, ErrTxFailed
+	}
+
+	changes := tokenBalanceChanges(result.Meta, mint)
+	received := changes[to]
+	if received == nil || received.Sign() <= 0 || (expected.Amount != nil && received.Cmp(expected.Amount) < 0) {
+		return nil, ErrTransferNotFound
+	}
+
+	confirmation := &Confirmation{
+		Network:     network,
+		Transaction: signature.String(),
+		Amount:      received,
+		Block:       result.Slot,
+	}
+	var sent *big.Int
+	for owner, change := range changes {
+		if change.Sign() < 0 && (sent == nil || change.Cmp(sent) < 0) {
+			sent = change
+			confirmation.From = owner.String()
+		}
+	}
+	if result.BlockTime != nil {
+		confirmation.Time = time.Unix(int64(*result.BlockTime), 0)
+	}
+	return confirmation, nil
+}
+
+// tokenBalanceChanges returns the change of the balance of mint per owner.
+func tokenBalanceChanges(meta *rpc.TransactionMeta, mint solana.PublicKey) map[solana.PublicKey]*big.Int {
+	changes := make(map[solana.PublicKey]*big.Int)
+	add := func(balances []rpc.TokenBalance, sign int64) {
+		for _, balance := range balances {
+			if balance.Mint != mint || balance.Owner == nil || balance.UiTokenAmount == nil {
+				continue
+			}
+			amount, ok := new(big.Int).SetString(balance.UiTokenAmount.Amount, 10)
+			if !ok {
+				continue
+			}
+			change, ok := changes[*balance.Owner]
+			if !ok {
+				change = new(big.Int)
+				changes[*balance.Owner] = change
+			}
+			change.Add(change, amount.Mul(amount, big.NewInt(sign)))
+		}
+	}
+	add(meta.PreTokenBalances, -1)
+	add(meta.PostTokenBalances, 1)
+	return changes
+}
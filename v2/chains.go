@@ -16,6 +16,8 @@ const (
 	NetworkTypeEVM
 	// NetworkTypeSVM represents Solana Virtual Machine chains.
 	NetworkTypeSVM
+	// NetworkTypeCustom represents chains of a namespace added with RegisterNamespace.
+	NetworkTypeCustom
 )
 
 // CAIP-2 network identifiers
@@ -45,7 +47,7 @@ type ChainConfig struct {
 	// USDCAddress is the official Circle USDC contract or mint address.
 	USDCAddress string
 
-	// Decimals is the number of decimal places for USDC (always 6).
+	// Decimals is the number of decimal places for USDC (6, or 7 on Stellar).
 	Decimals uint8
 
 	// EIP3009Name is the EIP-3009 domain parameter "name" (empty for non-EVM chains).
@@ -184,16 +186,21 @@ var chainConfigByNetwork = map[string]ChainConfig{
 // GetChainConfig returns the chain configuration for a CAIP-2 network identifier.
 // Returns an error if the network is not recognized.
 func GetChainConfig(network string) (ChainConfig, error) {
-	config, ok := chainConfigByNetwork[network]
-	if !ok {
-		return ChainConfig{}, fmt.Errorf("%w: %s", ErrInvalidNetwork, network)
+	if config, ok := chainConfigByNetwork[network]; ok {
+		return config, nil
 	}
-	return config, nil
+	if namespace, ok := NamespaceOf(network); ok {
+		if config, ok := namespace.ChainConfig(network); ok {
+			return config, nil
+		}
+	}
+	return ChainConfig{}, fmt.Errorf("%w: %s", ErrInvalidNetwork, network)
 }
 
 // ValidateNetwork validates a CAIP-2 network identifier and returns its type.
 // Returns NetworkTypeEVM for EIP-155 chains, NetworkTypeSVM for Solana chains,
-// or NetworkTypeUnknown with an error for unrecognized networks.
+// NetworkTypeCustom for namespaces added with RegisterNamespace, or
+// NetworkTypeUnknown with an error for unrecognized networks.
 func ValidateNetwork(network string) (NetworkType, error) {
 	if network == "" {
 		return NetworkTypeUnknown, fmt.Errorf("%w: network cannot be empty", ErrInvalidNetwork)
@@ -213,20 +220,27 @@ func ValidateNetwork(network string) (NetworkType, error) {
 	}
 
 	switch namespace {
-	case "eip155":
+	case NamespaceEIP155:
 		// Validate that reference is a valid chain ID (numeric)
 		if _, err := strconv.ParseInt(reference, 10, 64); err != nil {
 			return NetworkTypeUnknown, fmt.Errorf("%w: invalid EIP-155 chain ID: %s", ErrInvalidNetwork, reference)
 		}
 		return NetworkTypeEVM, nil
-	case "solana":
+	case NamespaceSolana:
 		// Validate that reference is a valid base58 genesis hash (32-44 chars)
 		if len(reference) < 32 || len(reference) > 44 {
 			return NetworkTypeUnknown, fmt.Errorf("%w: invalid Solana genesis hash length: %s", ErrInvalidNetwork, reference)
 		}
 		return NetworkTypeSVM, nil
 	default:
-		return NetworkTypeUnknown, fmt.Errorf("%w: unsupported namespace: %s", ErrInvalidNetwork, namespace)
+		plugin, ok := LookupNamespace(namespace)
+		if !ok {
+			return NetworkTypeUnknown, fmt.Errorf("%w: unsupported namespace: %s", ErrInvalidNetwork, namespace)
+		}
+		if err := plugin.ValidateReference(reference); err != nil {
+			return NetworkTypeUnknown, fmt.Errorf("%w: invalid %s reference %s: %v", ErrInvalidNetwork, namespace, reference, err)
+		}
+		return NetworkTypeCustom, nil
 	}
 }
 
@@ -268,10 +282,14 @@ func GetSolanaGenesisHash(network string) (string, error) {
 // NewUSDCTokenConfig creates a TokenConfig for USDC on the given chain with the specified priority.
 // This is a convenience helper for USDC. For other tokens, construct TokenConfig directly.
 func NewUSDCTokenConfig(chain ChainConfig, priority int) TokenConfig {
+	decimals := int(chain.Decimals)
+	if decimals == 0 {
+		decimals = 6
+	}
 	return TokenConfig{
 		Address:  chain.USDCAddress,
 		Symbol:   "USDC",
-		Decimals: 6,
+		Decimals: decimals,
 		Priority: priority,
 		Name:     "USD Coin",
 	}
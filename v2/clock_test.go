@@ -0,0 +1,73 @@
+package v2
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	late := clock.After(2 * time.Minute)
+	early := clock.After(time.Minute)
+	if clock.Waiters() != 2 {
+		t.Fatalf("expected 2 waiters, got %d", clock.Waiters())
+	}
+
+	select {
+	case <-clock.After(0):
+	default:
+		t.Error("expected zero duration to fire immediately")
+	}
+
+	clock.Advance(90 * time.Second)
+	select {
+	case fired := <-early:
+		if !fired.Equal(start.Add(90 * time.Second)) {
+			t.Errorf("fired at %v, want %v", fired, start.Add(90*time.Second))
+		}
+	default:
+		t.Error("expected early timer to fire")
+	}
+	select {
+	case <-late:
+		t.Error("expected late timer not to fire yet")
+	default:
+	}
+
+	// Setting the clock backwards fires nothing
+	clock.Set(start)
+	if clock.Waiters() != 1 {
+		t.Errorf("expected 1 waiter, got %d", clock.Waiters())
+	}
+
+	clock.Set(start.Add(time.Hour))
+	<-late
+	if !clock.Now().Equal(start.Add(time.Hour)) {
+		t.Errorf("Now() = %v, want %v", clock.Now(), start.Add(time.Hour))
+	}
+}
+
+func TestFakeClock_BlockUntil(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	done := make(chan struct{})
+	go func() {
+		<-clock.After(time.Second)
+		close(done)
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	<-done
+}
+
+func TestClockOrSystem(t *testing.T) {
+	if ClockOrSystem(nil) != SystemClock {
+		t.Error("expected nil clock to default to SystemClock")
+	}
+	clock := NewFakeClock(time.Unix(0, 0))
+	if ClockOrSystem(clock) != clock {
+		t.Error("expected configured clock to be returned")
+	}
+}
@@ -0,0 +1,254 @@
+// Package cluster coordinates state between replicas of a server running the
+// x402 middleware: payment replay claims, shared budgets and per-payer
+// concurrency slots. A Coordinator keeps that state in a storage.Store shared
+// by all replicas, normally storage.Redis.
+//
+// # Consistency
+//
+// Claims (replay protection) and slots (concurrency limits) are single-key
+// atomic writes, so they are linearizable on one Redis server or shard: two
+// replicas can never both claim the same payment or the same slot. During a
+// Redis failover, writes acknowledged by the old primary but not yet
+// replicated are lost, so a payment may be claimed twice across the failover;
+// the facilitator still refuses to settle it twice on-chain.
+//
+// Counters (budgets) are atomic increments. A budget check increments first and
+// rolls back when over the limit, so concurrent replicas never overspend, but
+// may briefly refuse a payment that would have fit while another replica's
+// rollback is in flight.
+//
+// Slots are leases that expire after the lease TTL, so slots held by a replica
+// that crashed free themselves. Requests running longer than the lease TTL can
+// be joined by more concurrent requests than the limit allows.
+//
+// # Settlement results
+//
+// A replica that claimed a payment can Resolve the claim with its outcome, such
+// as the settlement response, and replicas receiving retries of the same
+// payment Await it instead of settling again. Results are plain store values,
+// so a result written during a Redis failover can be lost like a claim; the
+// waiting replica then times out and refuses the retry.
+//
+// # Local fallback
+//
+// By default a store error fails the operation, and the middleware refuses the
+// request. With WithLocalFallback, a Coordinator falls back to process-local
+// state when the store fails: the service stays up, but limits apply per
+// replica and replays across replicas are not detected until the store
+// recovers.
+package cluster
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/storage"
+)
+
+// DefaultLeaseTTL is how long a concurrency slot is held before it expires on
+// its own.
+const DefaultLeaseTTL = 5 * time.Minute
+
+// DefaultPollInterval is how often Await checks the store for a result.
+const DefaultPollInterval = 100 * time.Millisecond
+
+// Coordinator shares replay claims, budgets and concurrency slots between
+// replicas through a storage.Store. It is safe for concurrent use.
+type Coordinator struct {
+	store      storage.Store
+	local      *storage.Memory
+	fallback   bool
+	leaseTTL   time.Duration
+	poll       time.Duration
+	clock      v2.Clock
+	onFallback func(op string, err error)
+}
+
+// Option configures a Coordinator.
+type Option func(*Coordinator)
+
+// WithLocalFallback makes the Coordinator use process-local state when the
+// shared store fails, instead of failing the operation.
+func WithLocalFallback() Option {
+	return func(c *Coordinator) {
+		c.fallback = true
+	}
+}
+
+// WithOnFallback calls onFallback with the operation and store error whenever
+// local state is used instead of the shared store. Fallbacks are also logged.
+func WithOnFallback(onFallback func(op string, err error)) Option {
+	return func(c *Coordinator) {
+		c.onFallback = onFallback
+	}
+}
+
+// WithLeaseTTL sets how long concurrency slots are held before expiring
+// (default: DefaultLeaseTTL).
+func WithLeaseTTL(ttl time.Duration) Option {
+	return func(c *Coordinator) {
+		c.leaseTTL = ttl
+	}
+}
+
+// WithPollInterval sets how often Await checks for a result (default:
+// DefaultPollInterval).
+func WithPollInterval(interval time.Duration) Option {
+	return func(c *Coordinator) {
+		c.poll = interval
+	}
+}
+
+// WithClock sets the clock of the local fallback state and of Await (default:
+// v2.SystemClock).
+func WithClock(clock v2.Clock) Option {
+	return func(c *Coordinator) {
+		c.clock = clock
+		c.local = storage.NewMemory(storage.WithMemoryClock(clock))
+	}
+}
+
+// New creates a Coordinator sharing state through store. Use storage.Prefixed
+// to share one store with other components.
+func New(store storage.Store, opts ...Option) *Coordinator {
+	c := &Coordinator{
+		store:    store,
+		leaseTTL: DefaultLeaseTTL,
+		poll:     DefaultPollInterval,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.local == nil {
+		c.local = storage.NewMemory()
+	}
+	return c
+}
+
+// Claim records key as used for ttl, reporting false if it already was. It is
+// used to reject replayed payments.
+func (c *Coordinator) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	claimed, err := c.store.Add(ctx, "claim:"+key, []byte{1}, ttl)
+	if err != nil {
+		if !c.useLocal("claim", err) {
+			return false, err
+		}
+		return c.local.Add(ctx, "claim:"+key, []byte{1}, ttl)
+	}
+	return claimed, nil
+}
+
+// Unclaim releases a claim, so the payment can be used again, for example
+// after settlement failed.
+func (c *Coordinator) Unclaim(ctx context.Context, key string) error {
+	_ = c.local.Delete(ctx, "claim:"+key)
+	if err := c.store.Delete(ctx, "claim:"+key); err != nil && !c.useLocal("unclaim", err) {
+		return err
+	}
+	return nil
+}
+
+// Resolve records result as the outcome of the claim on key for ttl, so
+// replicas waiting in Await can reuse it. The claim itself is kept.
+func (c *Coordinator) Resolve(ctx context.Context, key string, result []byte, ttl time.Duration) error {
+	if err := c.store.Set(ctx, "result:"+key, result, ttl); err != nil {
+		if !c.useLocal("resolve", err) {
+			return err
+		}
+		return c.local.Set(ctx, "result:"+key, result, ttl)
+	}
+	return nil
+}
+
+// Await waits until the claim on key is resolved and returns its result. It
+// returns storage.ErrNotFound if the claim is released without a result, in
+// which case the caller may claim key itself, and ctx's error once ctx is done.
+func (c *Coordinator) Await(ctx context.Context, key string) ([]byte, error) {
+	clock := v2.ClockOrSystem(c.clock)
+	for {
+		result, err := c.lookup(ctx, "result:"+key)
+		if err == nil {
+			return result, nil
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+		if _, err := c.lookup(ctx, "claim:"+key); err != nil {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-clock.After(c.poll):
+		}
+	}
+}
+
+// lookup gets key from the store, or from local state if the store fails.
+func (c *Coordinator) lookup(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.store.Get(ctx, key)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		if !c.useLocal("get", err) {
+			return nil, err
+		}
+		return c.local.Get(ctx, key)
+	}
+	return value, err
+}
+
+// IncrBy adds delta to the shared counter key, creating it with ttl if absent,
+// and returns the new value. It makes a Coordinator a v2.SpendCounter.
+func (c *Coordinator) IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	n, err := storage.IncrBy(ctx, c.store, "counter:"+key, delta, ttl)
+	if err != nil {
+		if !c.useLocal("incr", err) {
+			return 0, err
+		}
+		return c.local.IncrBy(ctx, "counter:"+key, delta, ttl)
+	}
+	return n, nil
+}
+
+// AcquireSlot takes one of max concurrency slots of key for the lease TTL. It
+// returns a release function, which must be called once the slot is no longer
+// needed, or ok false if all slots are taken.
+func (c *Coordinator) AcquireSlot(ctx context.Context, key string, max int) (release func(), ok bool, err error) {
+	store := c.store
+	for i := 0; i < max; i++ {
+		slot := "slot:" + key + ":" + strconv.Itoa(i)
+		taken, err := store.Add(ctx, slot, []byte{1}, c.leaseTTL)
+		if err != nil {
+			if store == c.local || !c.useLocal("slot", err) {
+				return nil, false, err
+			}
+			store = c.local
+			i--
+			continue
+		}
+		if taken {
+			return func() {
+				if err := store.Delete(context.Background(), slot); err != nil {
+					slog.Default().Warn("failed to release concurrency slot, it expires with its lease", "slot", slot, "error", err)
+				}
+			}, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// useLocal reports whether to fall back to local state after err.
+func (c *Coordinator) useLocal(op string, err error) bool {
+	if !c.fallback {
+		return false
+	}
+	slog.Default().Warn("shared store failed, using local state", "op", op, "error", err)
+	if c.onFallback != nil {
+		c.onFallback(op, err)
+	}
+	return true
+}
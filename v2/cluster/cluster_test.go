@@ -0,0 +1,206 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/storage"
+)
+
+var errDown = errors.New("store down")
+
+// downStore is a storage.Store whose every operation fails.
+type downStore struct{}
+
+func (downStore) Get(context.Context, string) ([]byte, error) { return nil, errDown }
+func (downStore) Set(context.Context, string, []byte, time.Duration) error {
+	return errDown
+}
+func (downStore) Add(context.Context, string, []byte, time.Duration) (bool, error) {
+	return false, errDown
+}
+func (downStore) CompareAndSwap(context.Context, string, []byte, []byte, time.Duration) (bool, error) {
+	return false, errDown
+}
+func (downStore) Delete(context.Context, string) error { return errDown }
+
+func TestCoordinator_Claim(t *testing.T) {
+	ctx := context.Background()
+	clock := v2.NewFakeClock(time.Unix(1700000000, 0))
+	store := storage.NewMemory(storage.WithMemoryClock(clock))
+
+	// Two replicas sharing one store
+	a, b := New(store), New(store)
+
+	if ok, err := a.Claim(ctx, "p1", time.Minute); err != nil || !ok {
+		t.Fatalf("first claim = %v, %v", ok, err)
+	}
+	if ok, err := b.Claim(ctx, "p1", time.Minute); err != nil || ok {
+		t.Fatalf("replayed claim on other replica = %v, %v", ok, err)
+	}
+
+	if err := a.Unclaim(ctx, "p1"); err != nil {
+		t.Fatalf("Unclaim: %v", err)
+	}
+	if ok, _ := b.Claim(ctx, "p1", time.Minute); !ok {
+		t.Error("expected claim to succeed after Unclaim")
+	}
+
+	clock.Advance(2 * time.Minute)
+	if ok, _ := a.Claim(ctx, "p1", time.Minute); !ok {
+		t.Error("expected claim to succeed after it expired")
+	}
+}
+
+func TestCoordinator_Await(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemory()
+	a, b := New(store), New(store, WithPollInterval(time.Millisecond))
+
+	if _, err := b.Await(ctx, "p1"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Await without claim = %v, want storage.ErrNotFound", err)
+	}
+
+	if ok, _ := a.Claim(ctx, "p1", time.Minute); !ok {
+		t.Fatal("expected claim")
+	}
+	results := make(chan error, 1)
+	go func() {
+		result, err := b.Await(ctx, "p1")
+		if err == nil && string(result) != "settled" {
+			t.Errorf("Await result = %q", result)
+		}
+		results <- err
+	}()
+	if err := a.Resolve(ctx, "p1", []byte("settled"), time.Minute); err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if err := <-results; err != nil {
+		t.Errorf("Await: %v", err)
+	}
+
+	// A released claim stops waiters
+	if ok, _ := a.Claim(ctx, "p2", time.Minute); !ok {
+		t.Fatal("expected claim")
+	}
+	go func() {
+		_, err := b.Await(ctx, "p2")
+		results <- err
+	}()
+	_ = a.Unclaim(ctx, "p2")
+	if err := <-results; !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Await after Unclaim = %v, want storage.ErrNotFound", err)
+	}
+
+	// Waiting ends with the context
+	_, _ = a.Claim(ctx, "p3", time.Minute)
+	timeout, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	if _, err := b.Await(timeout, "p3"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Await past deadline = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestCoordinator_AcquireSlot(t *testing.T) {
+	ctx := context.Background()
+	clock := v2.NewFakeClock(time.Unix(1700000000, 0))
+	store := storage.NewMemory(storage.WithMemoryClock(clock))
+	a, b := New(store, WithLeaseTTL(time.Minute)), New(store, WithLeaseTTL(time.Minute))
+
+	release, ok, err := a.AcquireSlot(ctx, "payer", 2)
+	if err != nil || !ok {
+		t.Fatalf("first slot = %v, %v", ok, err)
+	}
+	if _, ok, _ := b.AcquireSlot(ctx, "payer", 2); !ok {
+		t.Fatal("expected second slot")
+	}
+	if _, ok, _ := b.AcquireSlot(ctx, "payer", 2); ok {
+		t.Fatal("expected all slots to be taken")
+	}
+	if _, ok, _ := b.AcquireSlot(ctx, "other", 2); !ok {
+		t.Error("expected slots of another key to be free")
+	}
+
+	release()
+	if _, ok, _ := b.AcquireSlot(ctx, "payer", 2); !ok {
+		t.Error("expected released slot to be free")
+	}
+
+	// Leases of a crashed replica expire
+	clock.Advance(2 * time.Minute)
+	if _, ok, _ := a.AcquireSlot(ctx, "payer", 2); !ok {
+		t.Error("expected expired slot to be free")
+	}
+}
+
+func TestCoordinator_IncrBy(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemory()
+	a, b := New(store), New(store)
+
+	if n, err := a.IncrBy(ctx, "budget", 5, 0); err != nil || n != 5 {
+		t.Fatalf("IncrBy = %d, %v", n, err)
+	}
+	if n, err := b.IncrBy(ctx, "budget", 3, 0); err != nil || n != 8 {
+		t.Fatalf("IncrBy on other replica = %d, %v", n, err)
+	}
+}
+
+func TestCoordinator_StoreFailure(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("fails by default", func(t *testing.T) {
+		c := New(downStore{})
+		if _, err := c.Claim(ctx, "p1", time.Minute); !errors.Is(err, errDown) {
+			t.Errorf("Claim error = %v, want %v", err, errDown)
+		}
+		if _, err := c.IncrBy(ctx, "budget", 1, 0); !errors.Is(err, errDown) {
+			t.Errorf("IncrBy error = %v, want %v", err, errDown)
+		}
+		if _, _, err := c.AcquireSlot(ctx, "payer", 1); !errors.Is(err, errDown) {
+			t.Errorf("AcquireSlot error = %v, want %v", err, errDown)
+		}
+	})
+
+	t.Run("local fallback", func(t *testing.T) {
+		var ops []string
+		c := New(downStore{}, WithLocalFallback(), WithOnFallback(func(op string, err error) {
+			if !errors.Is(err, errDown) {
+				t.Errorf("unexpected fallback error %v", err)
+			}
+			ops = append(ops, op)
+		}))
+
+		if ok, err := c.Claim(ctx, "p1", time.Minute); err != nil || !ok {
+			t.Fatalf("Claim = %v, %v", ok, err)
+		}
+		if ok, _ := c.Claim(ctx, "p1", time.Minute); ok {
+			t.Error("expected local state to detect replay")
+		}
+		if n, err := c.IncrBy(ctx, "budget", 2, 0); err != nil || n != 2 {
+			t.Errorf("IncrBy = %d, %v", n, err)
+		}
+		release, ok, err := c.AcquireSlot(ctx, "payer", 1)
+		if err != nil || !ok {
+			t.Fatalf("AcquireSlot = %v, %v", ok, err)
+		}
+		if _, ok, _ := c.AcquireSlot(ctx, "payer", 1); ok {
+			t.Error("expected local slot to be taken")
+		}
+		release()
+
+		want := []string{"claim", "claim", "incr", "slot", "slot"}
+		if len(ops) != len(want) {
+			t.Fatalf("fallbacks = %v, want %v", ops, want)
+		}
+		for i := range want {
+			if ops[i] != want[i] {
+				t.Errorf("fallbacks = %v, want %v", ops, want)
+				break
+			}
+		}
+	})
+}
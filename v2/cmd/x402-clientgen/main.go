@@ -0,0 +1,68 @@
+// Command x402-clientgen generates a typed Go client for a paid API from its
+// OpenAPI document or the 402 response of one of its resources.
+//
+// Usage:
+//
+//	x402-clientgen -in <file or URL> -package <name> [-type Client] [-out client_gen.go]
+//
+// It is meant for go:generate:
+//
+//	//go:generate go run github.com/mark3labs/x402-go/v2/cmd/x402-clientgen -in openapi.json -package weather -out client_gen.go
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/x402-go/v2/http/clientgen"
+)
+
+func main() {
+	in := flag.String("in", "", "OpenAPI document or 402 requirements JSON: a file path or http(s) URL")
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "package name of the generated file (defaults to $GOPACKAGE)")
+	typeName := flag.String("type", "Client", "name of the generated client type")
+	out := flag.String("out", "", "output file (defaults to stdout)")
+	flag.Parse()
+
+	if err := run(*in, *pkg, *typeName, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "x402-clientgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, pkg, typeName, out string) error {
+	if in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	var spec *clientgen.Spec
+	var err error
+	if strings.HasPrefix(in, "http://") || strings.HasPrefix(in, "https://") {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		spec, err = clientgen.Fetch(ctx, nil, in)
+	} else {
+		var data []byte
+		if data, err = os.ReadFile(in); err != nil {
+			return err
+		}
+		spec, err = clientgen.Parse(data)
+	}
+	if err != nil {
+		return err
+	}
+
+	source, err := clientgen.Generate(spec, clientgen.Options{Package: pkg, TypeName: typeName})
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(source)
+		return err
+	}
+	return os.WriteFile(out, source, 0o644)
+}
@@ -0,0 +1,118 @@
+// Command x402-migrate converts v1 payment requirements to v2.
+//
+// Usage:
+//
+//	x402-migrate -in <file> [-out <file>] [-strict]
+//
+// The input is a JSON array of v1 requirements, a v1 402 response body, or a
+// route table mapping paths to arrays of v1 requirements. The output has the
+// same shape with each array replaced by {"resource": ..., "accepts": [...]}.
+// Changes are printed to stderr as a diff; lines starting with "!" need
+// review, and -strict makes them fail the command.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	x402 "github.com/mark3labs/x402-go"
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/http/migrate"
+)
+
+// route is the v2 translation of one array of v1 requirements.
+type route struct {
+	Resource v2.ResourceInfo          `json:"resource"`
+	Accepts  []v2.PaymentRequirements `json:"accepts"`
+}
+
+func main() {
+	in := flag.String("in", "", "JSON file of v1 requirements, a v1 402 response, or a route table")
+	out := flag.String("out", "", "output file (defaults to stdout)")
+	strict := flag.Bool("strict", false, "fail if any change needs review")
+	flag.Parse()
+
+	if err := run(*in, *out, *strict); err != nil {
+		fmt.Fprintln(os.Stderr, "x402-migrate:", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out string, strict bool) error {
+	if in == "" {
+		return fmt.Errorf("-in is required")
+	}
+	data, err := os.ReadFile(in)
+	if err != nil {
+		return err
+	}
+
+	var (
+		result any
+		lossy  int
+	)
+	convert := func(path string, requirements []x402.PaymentRequirement) (route, error) {
+		accepts, resource, changes, err := migrate.Requirements(requirements)
+		if err != nil && path != "" {
+			return route{}, fmt.Errorf("%s: %w", path, err)
+		} else if err != nil {
+			return route{}, err
+		}
+		if len(changes) > 0 && path != "" {
+			fmt.Fprintln(os.Stderr, path)
+		}
+		fmt.Fprint(os.Stderr, migrate.Format(changes))
+		lossy += len(migrate.Lossy(changes))
+		return route{Resource: resource, Accepts: accepts}, nil
+	}
+
+	var requirements []x402.PaymentRequirement
+	var response x402.PaymentRequirementsResponse
+	var table map[string][]x402.PaymentRequirement
+	switch {
+	case json.Unmarshal(data, &requirements) == nil:
+		if result, err = convert("", requirements); err != nil {
+			return err
+		}
+	case json.Unmarshal(data, &response) == nil && response.Accepts != nil:
+		if result, err = convert("", response.Accepts); err != nil {
+			return err
+		}
+	case json.Unmarshal(data, &table) == nil:
+		paths := make([]string, 0, len(table))
+		for path := range table {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		routes := make(map[string]route, len(table))
+		for _, path := range paths {
+			if routes[path], err = convert(path, table[path]); err != nil {
+				return err
+			}
+		}
+		result = routes
+	default:
+		return fmt.Errorf("%s is not a v1 requirements array, 402 response or route table", in)
+	}
+
+	encoded, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	encoded = append(encoded, '\n')
+	if out == "" {
+		_, err = os.Stdout.Write(encoded)
+	} else {
+		err = os.WriteFile(out, encoded, 0o644)
+	}
+	if err != nil {
+		return err
+	}
+	if strict && lossy > 0 {
+		return fmt.Errorf("%d changes need review", lossy)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Command x402-rpcproxy serves a pay-per-call JSON-RPC proxy in front of a
+// blockchain RPC node (see the http/jsonrpc package).
+//
+// Usage:
+//
+//	x402-rpcproxy -config <file> [-listen <addr>]
+//
+// The config file is JSON:
+//
+//	{
+//	  "upstream": "https://mainnet.base.org",
+//	  "facilitatorUrl": "https://facilitator.x402.org",
+//	  "requirement": {"scheme": "exact", "network": "eip155:8453", "asset": "0x...", "payTo": "0x...",
+//	                  "maxTimeoutSeconds": 60, "extra": {"name": "USD Coin", "version": "2"}},
+//	  "methods": {"eth_chainId": "0", "eth_call": "100", "eth_getLogs": "1000"},
+//	  "defaultPrice": "500"
+//	}
+//
+// Method prices are amounts of the requirement's asset in atomic units; "0"
+// makes a method free. Methods not listed cost defaultPrice, or are refused
+// if it is not set.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	x402http "github.com/mark3labs/x402-go/v2/http"
+	"github.com/mark3labs/x402-go/v2/http/jsonrpc"
+)
+
+// config is the proxy configuration file.
+type config struct {
+	Upstream       string                 `json:"upstream"`
+	FacilitatorURL string                 `json:"facilitatorUrl"`
+	Requirement    v2.PaymentRequirements `json:"requirement"`
+	Methods        map[string]string      `json:"methods"`
+	DefaultPrice   string                 `json:"defaultPrice"`
+}
+
+func main() {
+	configFile := flag.String("config", "", "JSON configuration file")
+	listen := flag.String("listen", ":8545", "address to listen on")
+	flag.Parse()
+
+	if err := run(*configFile, *listen); err != nil {
+		fmt.Fprintln(os.Stderr, "x402-rpcproxy:", err)
+		os.Exit(1)
+	}
+}
+
+func run(configFile, listen string) error {
+	if configFile == "" {
+		return fmt.Errorf("-config is required")
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	var cfg config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("parsing %s: %w", configFile, err)
+	}
+
+	price := func(amount string) v2.PaymentRequirements {
+		requirement := cfg.Requirement
+		requirement.Amount = amount
+		return requirement
+	}
+	opts := make([]jsonrpc.Option, 0, len(cfg.Methods))
+	for method, amount := range cfg.Methods {
+		if amount == "0" {
+			opts = append(opts, jsonrpc.WithMethod(method))
+			continue
+		}
+		opts = append(opts, jsonrpc.WithMethod(method, price(amount)))
+	}
+	serverConfig := x402http.Config{FacilitatorURL: cfg.FacilitatorURL}
+	if cfg.DefaultPrice != "" {
+		serverConfig.PaymentRequirements = []v2.PaymentRequirements{price(cfg.DefaultPrice)}
+	}
+	if err := serverConfig.Validate(); err != nil {
+		return err
+	}
+
+	proxy, err := jsonrpc.NewProxy(serverConfig, cfg.Upstream, opts...)
+	if err != nil {
+		return err
+	}
+	slog.Info("serving JSON-RPC proxy", "listen", listen, "upstream", cfg.Upstream, "methods", len(cfg.Methods))
+	server := &http.Server{Addr: listen, Handler: proxy, ReadHeaderTimeout: 10 * time.Second}
+	return server.ListenAndServe()
+}
@@ -2,6 +2,7 @@ package v2
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -24,6 +25,59 @@ var DefaultTimeouts = TimeoutConfig{
 	RequestTimeout: 120 * time.Second,
 }
 
+// NetworkTimeouts overrides timeouts per network, for chains whose operations
+// take longer than others (e.g., Solana settlement under congestion). Keys are
+// CAIP-2 network identifiers ("solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp") or bare
+// namespaces ("solana") applying to every network of the namespace. Zero fields
+// of an override keep the base value.
+type NetworkTimeouts map[string]TimeoutConfig
+
+// Resolve returns the timeouts for network: base, overridden by the entry for
+// the network's namespace and then by the entry for the network itself.
+func (nt NetworkTimeouts) Resolve(base TimeoutConfig, network string) TimeoutConfig {
+	if len(nt) == 0 {
+		return base
+	}
+	if namespace, _, ok := strings.Cut(network, ":"); ok {
+		base = base.merge(nt[namespace])
+	}
+	return base.merge(nt[network])
+}
+
+// Longest returns the longest timeout of any operation on any network, for
+// bounding transports shared across operations (e.g., http.Client.Timeout).
+func (nt NetworkTimeouts) Longest(base TimeoutConfig) time.Duration {
+	longest := base.longest()
+	for network := range nt {
+		if d := nt.Resolve(base, network).longest(); d > longest {
+			longest = d
+		}
+	}
+	return longest
+}
+
+// Validate ensures the resolved timeouts of every network are reasonable.
+func (nt NetworkTimeouts) Validate(base TimeoutConfig) error {
+	for network := range nt {
+		if err := nt.Resolve(base, network).Validate(); err != nil {
+			return fmt.Errorf("%s: %w", network, err)
+		}
+	}
+	return nil
+}
+
+// TimeoutsOrDefault returns tc, or DefaultTimeouts if tc is the zero value.
+func TimeoutsOrDefault(tc TimeoutConfig) TimeoutConfig {
+	if tc == (TimeoutConfig{}) {
+		return DefaultTimeouts
+	}
+	return tc
+}
+
+// DefaultClockSkew is the default tolerated clock difference between client and
+// server when checking authorization validity windows.
+const DefaultClockSkew = 30 * time.Second
+
 // WithVerifyTimeout returns a new TimeoutConfig with updated verify timeout.
 func (tc TimeoutConfig) WithVerifyTimeout(d time.Duration) TimeoutConfig {
 	tc.VerifyTimeout = d
@@ -42,6 +96,25 @@ func (tc TimeoutConfig) WithRequestTimeout(d time.Duration) TimeoutConfig {
 	return tc
 }
 
+// merge returns tc with the non-zero fields of override applied.
+func (tc TimeoutConfig) merge(override TimeoutConfig) TimeoutConfig {
+	if override.VerifyTimeout > 0 {
+		tc.VerifyTimeout = override.VerifyTimeout
+	}
+	if override.SettleTimeout > 0 {
+		tc.SettleTimeout = override.SettleTimeout
+	}
+	if override.RequestTimeout > 0 {
+		tc.RequestTimeout = override.RequestTimeout
+	}
+	return tc
+}
+
+// longest returns the longest of the timeouts.
+func (tc TimeoutConfig) longest() time.Duration {
+	return max(tc.VerifyTimeout, tc.SettleTimeout, tc.RequestTimeout)
+}
+
 // Validate ensures timeout values are reasonable.
 func (tc TimeoutConfig) Validate() error {
 	if tc.VerifyTimeout <= 0 {
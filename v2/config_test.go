@@ -0,0 +1,67 @@
+package v2
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNetworkTimeouts_Resolve(t *testing.T) {
+	base := TimeoutConfig{VerifyTimeout: 5 * time.Second, SettleTimeout: 60 * time.Second, RequestTimeout: 120 * time.Second}
+	timeouts := NetworkTimeouts{
+		NamespaceSolana:      {SettleTimeout: 180 * time.Second},
+		NetworkSolanaMainnet: {VerifyTimeout: 10 * time.Second, RequestTimeout: 240 * time.Second},
+		NetworkBase:          {SettleTimeout: 30 * time.Second},
+	}
+
+	tests := []struct {
+		name    string
+		network string
+		want    TimeoutConfig
+	}{
+		{name: "no override", network: NetworkPolygon, want: base},
+		{name: "network override", network: NetworkBase, want: base.WithSettleTimeout(30 * time.Second)},
+		{name: "namespace override", network: NetworkSolanaDevnet, want: base.WithSettleTimeout(180 * time.Second)},
+		{
+			name:    "namespace and network overrides",
+			network: NetworkSolanaMainnet,
+			want:    TimeoutConfig{VerifyTimeout: 10 * time.Second, SettleTimeout: 180 * time.Second, RequestTimeout: 240 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := timeouts.Resolve(base, tt.network); got != tt.want {
+				t.Errorf("Resolve(%q) = %+v, want %+v", tt.network, got, tt.want)
+			}
+		})
+	}
+
+	if got := timeouts.Longest(base); got != 240*time.Second {
+		t.Errorf("Longest() = %v, want 4m0s", got)
+	}
+	if got := NetworkTimeouts(nil).Resolve(base, NetworkBase); got != base {
+		t.Errorf("nil Resolve() = %+v, want %+v", got, base)
+	}
+}
+
+func TestNetworkTimeouts_Validate(t *testing.T) {
+	valid := NetworkTimeouts{NamespaceSolana: {SettleTimeout: 180 * time.Second}}
+	if err := valid.Validate(DefaultTimeouts); err != nil {
+		t.Errorf("Expected valid timeouts, got %v", err)
+	}
+
+	invalid := NetworkTimeouts{NetworkBase: {VerifyTimeout: 90 * time.Second}}
+	if err := invalid.Validate(DefaultTimeouts); err == nil {
+		t.Error("Expected error for verify timeout above settle timeout")
+	}
+}
+
+func TestTimeoutsOrDefault(t *testing.T) {
+	if got := TimeoutsOrDefault(TimeoutConfig{}); got != DefaultTimeouts {
+		t.Errorf("Expected DefaultTimeouts, got %+v", got)
+	}
+	custom := DefaultTimeouts.WithSettleTimeout(time.Minute * 3)
+	if got := TimeoutsOrDefault(custom); got != custom {
+		t.Errorf("Expected %+v, got %+v", custom, got)
+	}
+}
@@ -0,0 +1,67 @@
+package v2
+
+import "time"
+
+// Payment scheme identifiers.
+const (
+	// SchemeExact pays the exact amount, settled before the response is delivered.
+	SchemeExact = "exact"
+
+	// SchemeDeferred authorizes a payment that the seller claims after delivering
+	// the resource, within a claim window. The payload is an EIP-3009
+	// authorization whose validBefore is the end of the claim window; until then
+	// the payer can cancel it on-chain.
+	SchemeDeferred = "deferred"
+
+	// SchemeUpTo authorizes payment of up to the requirement's Amount; the seller
+	// settles the amount actually owed once the response has been produced (e.g.,
+	// priced by response size or compute time). The payload is a Permit2 permit
+	// for the maximum, and the settlement requirements carry the final Amount.
+	SchemeUpTo = "upto"
+
+	// SchemeDirect is paid by the payer submitting an ERC-20 transfer to payTo
+	// on-chain themselves, for sellers that trust payers to pay first. The
+	// payload is the transaction hash (see DirectPayload), which the seller
+	// confirms on-chain instead of settling through a facilitator.
+	SchemeDirect = "direct"
+)
+
+// ClaimWindow returns the claim window for deferred requirements, taken from
+// Extra["claimWindowSeconds"] and falling back to MaxTimeoutSeconds.
+func ClaimWindow(requirements *PaymentRequirements) time.Duration {
+	switch seconds := requirements.Extra["claimWindowSeconds"].(type) {
+	case int:
+		if seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	case int64:
+		if seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	case float64:
+		if seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return time.Duration(requirements.MaxTimeoutSeconds) * time.Second
+}
+
+// DeferredCancellation is a signed EIP-3009 cancelAuthorization for an
+// outstanding deferred payment. Submitting it on-chain (or to a facilitator that
+// relays it) before the seller claims the payment voids the authorization.
+type DeferredCancellation struct {
+	// Network is the CAIP-2 network of the authorization.
+	Network string `json:"network"`
+
+	// Asset is the token contract address.
+	Asset string `json:"asset"`
+
+	// Authorizer is the payer's address.
+	Authorizer string `json:"authorizer"`
+
+	// Nonce is the 32-byte hex-encoded nonce of the cancelled authorization.
+	Nonce string `json:"nonce"`
+
+	// Signature is the hex-encoded signature over CancelAuthorization.
+	Signature string `json:"signature"`
+}
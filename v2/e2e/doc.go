@@ -0,0 +1,20 @@
+// Package e2e contains end-to-end tests that settle real x402 v2 payments on
+// local chains: a paywalled server using the HTTP middleware, a paying client,
+// and a minimal facilitator that submits the payments, with assertions on the
+// resulting on-chain balances.
+//
+// The tests are behind the e2e build tag and need local nodes:
+//
+//	go test -tags e2e ./e2e/...
+//
+// The EVM test uses the node at X402_E2E_EVM_RPC, or starts anvil forking
+// X402_E2E_BASE_SEPOLIA_RPC when anvil is on PATH. The node must expose the
+// anvil_setBalance and anvil_setStorageAt methods, which are used to fund the
+// accounts with ETH and Base Sepolia USDC.
+//
+// The Solana test uses the node at X402_E2E_SVM_RPC, or starts
+// solana-test-validator when it is on PATH. It creates its own SPL token.
+//
+// In CI the nodes can run as service containers, with the RPC variables
+// pointing at them. Tests whose chain is unavailable are skipped.
+package e2e
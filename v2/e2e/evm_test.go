@@ -0,0 +1,148 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	v2 "github.com/mark3labs/x402-go/v2"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+	"github.com/mark3labs/x402-go/v2/signers/evm"
+)
+
+func TestEVMExactPayment(t *testing.T) {
+	ctx := context.Background()
+	client, err := ethclient.Dial(evmNode(t))
+	if err != nil {
+		t.Fatalf("failed to dial EVM node: %v", err)
+	}
+	defer client.Close()
+
+	payerKey, _ := crypto.GenerateKey()
+	facilitatorKey, _ := crypto.GenerateKey()
+	payeeKey, _ := crypto.GenerateKey()
+	payer := crypto.PubkeyToAddress(payerKey.PublicKey)
+	payee := crypto.PubkeyToAddress(payeeKey.PublicKey)
+	usdc := common.HexToAddress(v2.BaseSepolia.USDCAddress)
+
+	setETHBalance(t, client, crypto.PubkeyToAddress(facilitatorKey.PublicKey), big.NewInt(1e18))
+	setTokenBalance(t, client, usdc, payer, big.NewInt(1_000_000))
+
+	facilitatorServer := httptest.NewServer(&localFacilitator{
+		evm:        client,
+		evmKey:     facilitatorKey,
+		evmNetwork: v2.NetworkBaseSepolia,
+	})
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           v2.NetworkBaseSepolia,
+		Amount:            "10000",
+		Asset:             usdc.Hex(),
+		PayTo:             payee.Hex(),
+		MaxTimeoutSeconds: 60,
+		Extra: map[string]interface{}{
+			"name":    v2.BaseSepolia.EIP3009Name,
+			"version": v2.BaseSepolia.EIP3009Version,
+		},
+	}
+	server := httptest.NewServer(v2http.NewX402Middleware(v2http.Config{
+		FacilitatorURL:      facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{requirement},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("paid content"))
+	})))
+	defer server.Close()
+
+	signer, err := evm.NewSigner(v2.NetworkBaseSepolia, hexutil.Encode(crypto.FromECDSA(payerKey))[2:],
+		[]v2.TokenConfig{v2.NewUSDCTokenConfig(v2.BaseSepolia, 1)})
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	payingClient, err := v2http.NewClient(v2http.WithSigner(signer))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	resp, err := payingClient.Get(server.URL + "/paid")
+	if err != nil {
+		t.Fatalf("paid request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "paid content" {
+		t.Fatalf("Expected paid content, got %d: %s", resp.StatusCode, body)
+	}
+
+	settlement := v2http.GetSettlement(resp)
+	if settlement == nil || !settlement.Success {
+		t.Fatalf("Expected successful settlement, got %+v", settlement)
+	}
+	receipt, err := client.TransactionReceipt(ctx, common.HexToHash(settlement.Transaction))
+	if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("Expected successful transaction %s, got %+v (%v)", settlement.Transaction, receipt, err)
+	}
+
+	if got := tokenBalance(t, client, usdc, payer); got.Cmp(big.NewInt(990_000)) != 0 {
+		t.Errorf("Expected payer balance 990000, got %s", got)
+	}
+	if got := tokenBalance(t, client, usdc, payee); got.Cmp(big.NewInt(10_000)) != 0 {
+		t.Errorf("Expected payee balance 10000, got %s", got)
+	}
+}
+
+// setETHBalance sets the native balance of account.
+func setETHBalance(t *testing.T, client *ethclient.Client, account common.Address, wei *big.Int) {
+	t.Helper()
+	if err := client.Client().Call(nil, "anvil_setBalance", account, hexutil.EncodeBig(wei)); err != nil {
+		t.Fatalf("anvil_setBalance failed: %v", err)
+	}
+}
+
+// setTokenBalance writes the token balance of account directly to storage. The
+// balances mapping slot is found by probing the first slots with balanceOf.
+func setTokenBalance(t *testing.T, client *ethclient.Client, token, account common.Address, amount *big.Int) {
+	t.Helper()
+	value := common.BigToHash(amount)
+	for slot := int64(0); slot < 20; slot++ {
+		key := crypto.Keccak256Hash(common.LeftPadBytes(account.Bytes(), 32), common.BigToHash(big.NewInt(slot)).Bytes())
+
+		var previous common.Hash
+		if err := client.Client().Call(&previous, "eth_getStorageAt", token, key, "latest"); err != nil {
+			t.Fatalf("eth_getStorageAt failed: %v", err)
+		}
+		if err := client.Client().Call(nil, "anvil_setStorageAt", token, key, value); err != nil {
+			t.Fatalf("anvil_setStorageAt failed: %v", err)
+		}
+		if tokenBalance(t, client, token, account).Cmp(amount) == 0 {
+			return
+		}
+		if err := client.Client().Call(nil, "anvil_setStorageAt", token, key, previous); err != nil {
+			t.Fatalf("anvil_setStorageAt failed: %v", err)
+		}
+	}
+	t.Fatalf("could not find the balances slot of token %s", token)
+}
+
+// tokenBalance returns the token balance of account.
+func tokenBalance(t *testing.T, client *ethclient.Client, token, account common.Address) *big.Int {
+	t.Helper()
+	data, _ := tokenABI.Pack("balanceOf", account)
+	result, err := client.CallContract(context.Background(), ethereum.CallMsg{To: &token, Data: data}, nil)
+	if err != nil {
+		t.Fatalf("balanceOf failed: %v", err)
+	}
+	return new(big.Int).SetBytes(result)
+}
@@ -0,0 +1,358 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/facilitator"
+)
+
+// tokenABI covers the FiatToken methods used by the tests.
+var tokenABI = mustParseABI(`[
+	{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"transferWithAuthorization","stateMutability":"nonpayable","inputs":[
+		{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"},
+		{"name":"validAfter","type":"uint256"},{"name":"validBefore","type":"uint256"},{"name":"nonce","type":"bytes32"},
+		{"name":"signature","type":"bytes"}],"outputs":[]}
+]`)
+
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// localFacilitator is a minimal facilitator for the "exact" scheme on local
+// chains. Verification simulates the transfer; settlement submits it and waits
+// for confirmation. Either chain may be left unconfigured.
+type localFacilitator struct {
+	evm        *ethclient.Client
+	evmKey     *ecdsa.PrivateKey
+	evmNetwork string
+
+	svm        *rpc.Client
+	svmKey     solana.PrivateKey
+	svmNetwork string
+}
+
+var _ facilitator.Interface = (*localFacilitator)(nil)
+
+// ServeHTTP serves the facilitator HTTP API used by http.FacilitatorClient.
+func (f *localFacilitator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		result any
+		err    error
+	)
+	switch r.URL.Path {
+	case "/supported":
+		result, err = f.Supported(r.Context())
+	case "/verify", "/settle":
+		var req facilitator.VerifyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path == "/verify" {
+			result, err = f.Verify(r.Context(), req.PaymentPayload, req.PaymentRequirements)
+		} else {
+			result, err = f.Settle(r.Context(), req.PaymentPayload, req.PaymentRequirements)
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// Supported lists the exact scheme on every configured chain.
+func (f *localFacilitator) Supported(_ context.Context) (*v2.SupportedResponse, error) {
+	supported := &v2.SupportedResponse{Extensions: []string{}}
+	if f.evm != nil {
+		supported.Kinds = append(supported.Kinds, v2.SupportedKind{X402Version: 2, Scheme: "exact", Network: f.evmNetwork})
+	}
+	if f.svm != nil {
+		supported.Kinds = append(supported.Kinds, v2.SupportedKind{
+			X402Version: 2,
+			Scheme:      "exact",
+			Network:     f.svmNetwork,
+			Extra:       map[string]interface{}{"feePayer": f.svmKey.PublicKey().String()},
+		})
+	}
+	return supported, nil
+}
+
+// Verify simulates the payment without submitting it.
+func (f *localFacilitator) Verify(ctx context.Context, payload v2.PaymentPayload, requirements v2.PaymentRequirements) (*v2.VerifyResponse, error) {
+	var (
+		payer string
+		err   error
+	)
+	switch requirements.Network {
+	case f.evmNetwork:
+		var call ethereum.CallMsg
+		if call, payer, err = f.evmTransfer(payload, requirements); err == nil {
+			_, err = f.evm.CallContract(ctx, call, nil)
+		}
+	case f.svmNetwork:
+		var tx *solana.Transaction
+		if tx, payer, err = f.svmTransfer(payload, requirements); err == nil {
+			err = f.svmSimulate(ctx, tx)
+		}
+	default:
+		err = fmt.Errorf("unsupported network %s", requirements.Network)
+	}
+	if err != nil {
+		return &v2.VerifyResponse{IsValid: false, InvalidReason: err.Error(), Payer: payer}, nil
+	}
+	return &v2.VerifyResponse{IsValid: true, Payer: payer}, nil
+}
+
+// Settle submits the payment and waits until it is confirmed.
+func (f *localFacilitator) Settle(ctx context.Context, payload v2.PaymentPayload, requirements v2.PaymentRequirements) (*v2.SettleResponse, error) {
+	var (
+		payer, transaction string
+		err                error
+	)
+	switch requirements.Network {
+	case f.evmNetwork:
+		var call ethereum.CallMsg
+		if call, payer, err = f.evmTransfer(payload, requirements); err == nil {
+			transaction, err = f.evmSubmit(ctx, call)
+		}
+	case f.svmNetwork:
+		var tx *solana.Transaction
+		if tx, payer, err = f.svmTransfer(payload, requirements); err == nil {
+			transaction, err = f.svmSubmit(ctx, tx)
+		}
+	default:
+		err = fmt.Errorf("unsupported network %s", requirements.Network)
+	}
+	if err != nil {
+		return &v2.SettleResponse{Success: false, ErrorReason: err.Error(), Payer: payer, Network: requirements.Network}, nil
+	}
+	return &v2.SettleResponse{Success: true, Transaction: transaction, Network: requirements.Network, Payer: payer}, nil
+}
+
+// evmTransfer builds the transferWithAuthorization call for an EIP-3009
+// payment, checking it pays the required amount to the required recipient.
+func (f *localFacilitator) evmTransfer(payload v2.PaymentPayload, requirements v2.PaymentRequirements) (ethereum.CallMsg, string, error) {
+	var evmPayload v2.EVMPayload
+	if err := remarshal(payload.Payload, &evmPayload); err != nil {
+		return ethereum.CallMsg{}, "", err
+	}
+	auth := evmPayload.Authorization
+
+	if !strings.EqualFold(auth.To, requirements.PayTo) {
+		return ethereum.CallMsg{}, auth.From, fmt.Errorf("recipient %s does not match %s", auth.To, requirements.PayTo)
+	}
+	if auth.Value != requirements.Amount {
+		return ethereum.CallMsg{}, auth.From, fmt.Errorf("value %s does not match %s", auth.Value, requirements.Amount)
+	}
+
+	value, validAfter, validBefore := new(big.Int), new(big.Int), new(big.Int)
+	for target, s := range map[*big.Int]string{value: auth.Value, validAfter: auth.ValidAfter, validBefore: auth.ValidBefore} {
+		if _, ok := target.SetString(s, 10); !ok {
+			return ethereum.CallMsg{}, auth.From, fmt.Errorf("invalid integer %q", s)
+		}
+	}
+	signature, err := hexutil.Decode(evmPayload.Signature)
+	if err != nil || len(signature) != 65 {
+		return ethereum.CallMsg{}, auth.From, fmt.Errorf("invalid signature %q", evmPayload.Signature)
+	}
+	if signature[64] < 27 {
+		signature[64] += 27
+	}
+
+	data, err := tokenABI.Pack("transferWithAuthorization",
+		common.HexToAddress(auth.From), common.HexToAddress(auth.To), value, validAfter, validBefore,
+		common.HexToHash(auth.Nonce), signature)
+	if err != nil {
+		return ethereum.CallMsg{}, auth.From, err
+	}
+
+	token := common.HexToAddress(requirements.Asset)
+	return ethereum.CallMsg{
+		From: crypto.PubkeyToAddress(f.evmKey.PublicKey),
+		To:   &token,
+		Data: data,
+	}, auth.From, nil
+}
+
+// evmSubmit sends call as a transaction from the facilitator account and waits
+// for a successful receipt.
+func (f *localFacilitator) evmSubmit(ctx context.Context, call ethereum.CallMsg) (string, error) {
+	chainID, err := f.evm.ChainID(ctx)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := f.evm.PendingNonceAt(ctx, call.From)
+	if err != nil {
+		return "", err
+	}
+	gas, err := f.evm.EstimateGas(ctx, call)
+	if err != nil {
+		return "", err
+	}
+	gasPrice, err := f.evm.SuggestGasPrice(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := types.SignTx(types.NewTransaction(nonce, *call.To, big.NewInt(0), gas, gasPrice, call.Data),
+		types.LatestSignerForChainID(chainID), f.evmKey)
+	if err != nil {
+		return "", err
+	}
+	if err := f.evm.SendTransaction(ctx, tx); err != nil {
+		return "", err
+	}
+
+	receipt, err := waitForReceipt(ctx, f.evm, tx.Hash())
+	if err != nil {
+		return "", err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return tx.Hash().Hex(), fmt.Errorf("transaction %s reverted", tx.Hash().Hex())
+	}
+	return tx.Hash().Hex(), nil
+}
+
+// svmTransfer decodes the client's partially signed transaction and adds the
+// facilitator's fee payer signature.
+func (f *localFacilitator) svmTransfer(payload v2.PaymentPayload, requirements v2.PaymentRequirements) (*solana.Transaction, string, error) {
+	var svmPayload v2.SVMPayload
+	if err := remarshal(payload.Payload, &svmPayload); err != nil {
+		return nil, "", err
+	}
+	tx, err := solana.TransactionFromBase64(svmPayload.Transaction)
+	if err != nil {
+		return nil, "", err
+	}
+
+	feePayer := f.svmKey.PublicKey()
+	signers := tx.Message.Signers()
+	if len(signers) < 2 || !signers[0].Equals(feePayer) {
+		return nil, "", errors.New("transaction is not paid for by the facilitator")
+	}
+	payer := signers[1].String()
+
+	if _, err := tx.PartialSign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(feePayer) {
+			return &f.svmKey
+		}
+		return nil
+	}); err != nil {
+		return nil, payer, err
+	}
+	return tx, payer, nil
+}
+
+// svmSimulate checks tx executes, including its signatures.
+func (f *localFacilitator) svmSimulate(ctx context.Context, tx *solana.Transaction) error {
+	result, err := f.svm.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		SigVerify:  true,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return err
+	}
+	if result.Value.Err != nil {
+		return fmt.Errorf("simulation failed: %v", result.Value.Err)
+	}
+	return nil
+}
+
+// svmSubmit sends tx and waits until it is confirmed.
+func (f *localFacilitator) svmSubmit(ctx context.Context, tx *solana.Transaction) (string, error) {
+	signature, err := f.svm.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{
+		PreflightCommitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := waitForSignature(ctx, f.svm, signature); err != nil {
+		return signature.String(), err
+	}
+	return signature.String(), nil
+}
+
+// remarshal converts a decoded JSON payload into a typed struct.
+func remarshal(from, to any) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, to)
+}
+
+// waitForReceipt polls until the transaction is mined.
+func waitForReceipt(ctx context.Context, client *ethclient.Client, hash common.Hash) (*types.Receipt, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	for {
+		receipt, err := client.TransactionReceipt(ctx, hash)
+		if err == nil {
+			return receipt, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// waitForSignature polls until the transaction is confirmed.
+func waitForSignature(ctx context.Context, client *rpc.Client, signature solana.Signature) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	for {
+		statuses, err := client.GetSignatureStatuses(ctx, false, signature)
+		if err != nil {
+			return err
+		}
+		if len(statuses.Value) == 1 && statuses.Value[0] != nil {
+			status := statuses.Value[0]
+			if status.Err != nil {
+				return fmt.Errorf("transaction %s failed: %v", signature, status.Err)
+			}
+			if status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed || status.ConfirmationStatus == rpc.ConfirmationStatusFinalized {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
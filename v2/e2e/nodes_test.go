@@ -0,0 +1,105 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// evmNode returns the RPC URL of a local EVM node with Base Sepolia state,
+// starting anvil if needed, or skips the test.
+func evmNode(t *testing.T) string {
+	t.Helper()
+	if url := os.Getenv("X402_E2E_EVM_RPC"); url != "" {
+		return url
+	}
+
+	fork := os.Getenv("X402_E2E_BASE_SEPOLIA_RPC")
+	anvil, err := exec.LookPath("anvil")
+	if fork == "" || err != nil {
+		t.Skip("set X402_E2E_EVM_RPC, or X402_E2E_BASE_SEPOLIA_RPC with anvil on PATH")
+	}
+
+	port := freePort(t)
+	startNode(t, anvil, "--fork-url", fork, "--port", port, "--silent")
+	url := "http://127.0.0.1:" + port
+	waitForRPC(t, url, "eth_chainId")
+	return url
+}
+
+// svmNode returns the RPC URL of a local Solana node, starting
+// solana-test-validator if needed, or skips the test.
+func svmNode(t *testing.T) string {
+	t.Helper()
+	if url := os.Getenv("X402_E2E_SVM_RPC"); url != "" {
+		return url
+	}
+
+	validator, err := exec.LookPath("solana-test-validator")
+	if err != nil {
+		t.Skip("set X402_E2E_SVM_RPC, or put solana-test-validator on PATH")
+	}
+
+	port := freePort(t)
+	startNode(t, validator, "--reset", "--quiet", "--ledger", t.TempDir(), "--rpc-port", port, "--faucet-port", freePort(t))
+	url := "http://127.0.0.1:" + port
+	waitForRPC(t, url, "getHealth")
+	return url
+}
+
+// startNode runs a node binary until the test ends.
+func startNode(t *testing.T, name string, args ...string) {
+	t.Helper()
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+}
+
+// freePort returns a TCP port that is free at the time of the call.
+func freePort(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+}
+
+// waitForRPC polls url with a parameterless JSON-RPC method until it answers.
+func waitForRPC(t *testing.T, url, method string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	body := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":%q,"params":[]}`, method)
+	for {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("node at %s did not become ready", url)
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,180 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	v2 "github.com/mark3labs/x402-go/v2"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+	"github.com/mark3labs/x402-go/v2/signers/svm"
+)
+
+func TestSVMExactPayment(t *testing.T) {
+	client := rpc.New(svmNode(t))
+
+	payerKey := solana.NewWallet().PrivateKey
+	facilitatorKey := solana.NewWallet().PrivateKey
+	payee := solana.NewWallet().PublicKey()
+
+	airdrop(t, client, facilitatorKey.PublicKey())
+	mint := createMint(t, client, facilitatorKey, 6)
+	payerATA := mintTo(t, client, facilitatorKey, mint, payerKey.PublicKey(), 1_000_000)
+
+	facilitatorServer := httptest.NewServer(&localFacilitator{
+		svm:        client,
+		svmKey:     facilitatorKey,
+		svmNetwork: v2.NetworkSolanaDevnet,
+	})
+	defer facilitatorServer.Close()
+
+	// feePayer is filled in from the facilitator's /supported response
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           v2.NetworkSolanaDevnet,
+		Amount:            "10000",
+		Asset:             mint.String(),
+		PayTo:             payee.String(),
+		MaxTimeoutSeconds: 60,
+	}
+	server := httptest.NewServer(v2http.NewX402Middleware(v2http.Config{
+		FacilitatorURL:      facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{requirement},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("paid content"))
+	})))
+	defer server.Close()
+
+	signer, err := svm.NewSignerFromKey(v2.NetworkSolanaDevnet, payerKey,
+		[]v2.TokenConfig{{Address: mint.String(), Symbol: "TEST", Decimals: 6}},
+		svm.WithRPCClient(client))
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	payingClient, err := v2http.NewClient(v2http.WithSigner(signer))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	resp, err := payingClient.Get(server.URL + "/paid")
+	if err != nil {
+		t.Fatalf("paid request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "paid content" {
+		t.Fatalf("Expected paid content, got %d: %s", resp.StatusCode, body)
+	}
+
+	settlement := v2http.GetSettlement(resp)
+	if settlement == nil || !settlement.Success || settlement.Payer != payerKey.PublicKey().String() {
+		t.Fatalf("Expected successful settlement by %s, got %+v", payerKey.PublicKey(), settlement)
+	}
+
+	payeeATA, _, _ := solana.FindAssociatedTokenAddress(payee, mint)
+	if got := tokenAccountBalance(t, client, payerATA); got != "990000" {
+		t.Errorf("Expected payer balance 990000, got %s", got)
+	}
+	if got := tokenAccountBalance(t, client, payeeATA); got != "10000" {
+		t.Errorf("Expected payee balance 10000, got %s", got)
+	}
+}
+
+// airdrop funds account with 10 SOL and waits for the airdrop to confirm.
+func airdrop(t *testing.T, client *rpc.Client, account solana.PublicKey) {
+	t.Helper()
+	ctx := context.Background()
+	signature, err := client.RequestAirdrop(ctx, account, 10*solana.LAMPORTS_PER_SOL, rpc.CommitmentConfirmed)
+	if err != nil {
+		t.Fatalf("airdrop failed: %v", err)
+	}
+	if err := waitForSignature(ctx, client, signature); err != nil {
+		t.Fatalf("airdrop failed: %v", err)
+	}
+}
+
+// createMint creates an SPL token with authority as mint authority.
+func createMint(t *testing.T, client *rpc.Client, authority solana.PrivateKey, decimals uint8) solana.PublicKey {
+	t.Helper()
+	mintKey := solana.NewWallet().PrivateKey
+	rent, err := client.GetMinimumBalanceForRentExemption(context.Background(), token.MINT_SIZE, rpc.CommitmentConfirmed)
+	if err != nil {
+		t.Fatalf("failed to get rent: %v", err)
+	}
+
+	sendTransaction(t, client, []solana.PrivateKey{authority, mintKey},
+		system.NewCreateAccountInstruction(rent, token.MINT_SIZE, solana.TokenProgramID, authority.PublicKey(), mintKey.PublicKey()).Build(),
+		token.NewInitializeMint2Instruction(decimals, authority.PublicKey(), authority.PublicKey(), mintKey.PublicKey()).Build(),
+	)
+	return mintKey.PublicKey()
+}
+
+// mintTo creates the associated token account of owner and mints amount into it.
+func mintTo(t *testing.T, client *rpc.Client, authority solana.PrivateKey, mint, owner solana.PublicKey, amount uint64) solana.PublicKey {
+	t.Helper()
+	ata, _, err := solana.FindAssociatedTokenAddress(owner, mint)
+	if err != nil {
+		t.Fatalf("failed to derive token account: %v", err)
+	}
+
+	sendTransaction(t, client, []solana.PrivateKey{authority},
+		associatedtokenaccount.NewCreateInstruction(authority.PublicKey(), owner, mint).Build(),
+		token.NewMintToInstruction(amount, mint, ata, authority.PublicKey(), nil).Build(),
+	)
+	return ata
+}
+
+// sendTransaction sends instructions paid for by the first signer and waits for
+// confirmation.
+func sendTransaction(t *testing.T, client *rpc.Client, signers []solana.PrivateKey, instructions ...solana.Instruction) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	recent, err := client.GetLatestBlockhash(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		t.Fatalf("failed to get blockhash: %v", err)
+	}
+	tx, err := solana.NewTransaction(instructions, recent.Value.Blockhash, solana.TransactionPayer(signers[0].PublicKey()))
+	if err != nil {
+		t.Fatalf("failed to build transaction: %v", err)
+	}
+	if _, err := tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		for i := range signers {
+			if signers[i].PublicKey().Equals(key) {
+				return &signers[i]
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+
+	signature, err := client.SendTransaction(ctx, tx)
+	if err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	if err := waitForSignature(ctx, client, signature); err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+}
+
+// tokenAccountBalance returns the raw balance of a token account.
+func tokenAccountBalance(t *testing.T, client *rpc.Client, account solana.PublicKey) string {
+	t.Helper()
+	balance, err := client.GetTokenAccountBalance(context.Background(), account, rpc.CommitmentConfirmed)
+	if err != nil {
+		t.Fatalf("failed to get token balance of %s: %v", account, err)
+	}
+	return balance.Value.Amount
+}
@@ -0,0 +1,87 @@
+package encoding
+
+import (
+	"encoding/json"
+	"sync/atomic"
+)
+
+// Codec marshals and unmarshals JSON. Implementations must produce and accept
+// the same JSON as encoding/json, honoring its struct tags, Marshaler and
+// Unmarshaler interfaces, so that swapping codecs does not change the wire
+// format. jsoniter.ConfigCompatibleWithStandardLibrary is a Codec as is;
+// packages exposing functions, like github.com/segmentio/encoding/json, are
+// adapted with CodecFuncs.
+//
+// Unmarshal must not retain data, or memory aliasing it, after it returns:
+// DecodePayment passes a pooled buffer that is reused by the next decode.
+// Decoded strings, byte slices and json.RawMessage values must be copies, as
+// they are with encoding/json; codecs that alias their input to avoid copying
+// cannot be used.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// StdCodec is the Codec of encoding/json.
+type StdCodec struct{}
+
+// Marshal implements Codec.
+func (StdCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements Codec.
+func (StdCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// CodecFuncs adapts a pair of marshaling functions to a Codec, e.g.
+//
+//	encoding.SetCodec(encoding.CodecFuncs{MarshalFunc: segjson.Marshal, UnmarshalFunc: segjson.Unmarshal})
+type CodecFuncs struct {
+	MarshalFunc   func(v any) ([]byte, error)
+	UnmarshalFunc func(data []byte, v any) error
+}
+
+// Marshal implements Codec.
+func (c CodecFuncs) Marshal(v any) ([]byte, error) {
+	return c.MarshalFunc(v)
+}
+
+// Unmarshal implements Codec.
+func (c CodecFuncs) Unmarshal(data []byte, v any) error {
+	return c.UnmarshalFunc(data, v)
+}
+
+// codecHolder lets atomic.Value store Codecs of different concrete types.
+type codecHolder struct {
+	codec Codec
+}
+
+var defaultCodec atomic.Value
+
+// SetCodec sets the Codec used by the functions of this package and by
+// components without a Codec of their own, like facilitator clients. A nil
+// codec restores StdCodec. Set it once at startup, before encoding begins.
+func SetCodec(codec Codec) {
+	if codec == nil {
+		codec = StdCodec{}
+	}
+	defaultCodec.Store(codecHolder{codec: codec})
+}
+
+// DefaultCodec returns the Codec set with SetCodec, or StdCodec.
+func DefaultCodec() Codec {
+	if holder, ok := defaultCodec.Load().(codecHolder); ok {
+		return holder.codec
+	}
+	return StdCodec{}
+}
+
+// CodecOrDefault returns codec, or DefaultCodec if it is nil.
+func CodecOrDefault(codec Codec) Codec {
+	if codec == nil {
+		return DefaultCodec()
+	}
+	return codec
+}
@@ -0,0 +1,169 @@
+package encoding
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// countingCodec counts the calls it forwards to encoding/json.
+type countingCodec struct {
+	marshals, unmarshals int
+}
+
+func (c *countingCodec) Marshal(v any) ([]byte, error) {
+	c.marshals++
+	return json.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v any) error {
+	c.unmarshals++
+	return json.Unmarshal(data, v)
+}
+
+func TestSetCodec(t *testing.T) {
+	codec := &countingCodec{}
+	SetCodec(codec)
+	t.Cleanup(func() { SetCodec(nil) })
+
+	encoded, err := EncodePayment(benchmarkPayment)
+	if err != nil {
+		t.Fatalf("EncodePayment failed: %v", err)
+	}
+	decoded, err := DecodePayment(encoded)
+	if err != nil {
+		t.Fatalf("DecodePayment failed: %v", err)
+	}
+	if decoded.Accepted.Amount != benchmarkPayment.Accepted.Amount {
+		t.Errorf("round trip changed the amount to %q", decoded.Accepted.Amount)
+	}
+	if codec.marshals != 1 || codec.unmarshals != 1 {
+		t.Errorf("expected one marshal and one unmarshal, got %d and %d", codec.marshals, codec.unmarshals)
+	}
+
+	// The encoding matches encoding/json whatever the codec
+	SetCodec(CodecFuncs{MarshalFunc: json.Marshal, UnmarshalFunc: json.Unmarshal})
+	again, err := EncodePayment(benchmarkPayment)
+	if err != nil || again != encoded {
+		t.Errorf("expected identical encodings, got %v", err)
+	}
+
+	SetCodec(nil)
+	if _, ok := DefaultCodec().(StdCodec); !ok {
+		t.Errorf("expected StdCodec after reset, got %T", DefaultCodec())
+	}
+	if got := CodecOrDefault(codec); got != codec {
+		t.Errorf("CodecOrDefault ignored an explicit codec")
+	}
+}
+
+// benchmarkPayloads are payloads of realistic sizes: an EIP-3009
+// authorization, a Solana transaction near the 1232-byte packet limit, a 402
+// response offering four networks, and a settlement.
+func benchmarkPayloads() map[string]struct {
+	value  any
+	decode func([]byte) error
+} {
+	transaction := make([]byte, 1200)
+	_, _ = rand.Read(transaction)
+	svm := v2.PaymentPayload{
+		X402Version: 2,
+		Accepted: v2.PaymentRequirements{
+			Scheme:            "exact",
+			Network:           v2.NetworkSolanaMainnet,
+			Amount:            "10000",
+			Asset:             "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v",
+			PayTo:             "2wKupLR9q6wXYppw8Gr2NvWxKBUqm4PPJKkQfoxHDBg4",
+			MaxTimeoutSeconds: 60,
+			Extra:             map[string]interface{}{"feePayer": "3oBdYQbV9bqH7yCBzF5m4mGDWBqCHYx7zLAB7qAMNbkP"},
+		},
+		Payload: v2.SVMPayload{Transaction: base64.StdEncoding.EncodeToString(transaction)},
+	}
+
+	required := v2.PaymentRequired{
+		X402Version: 2,
+		Error:       "payment required",
+		Resource:    &v2.ResourceInfo{URL: "https://api.example.com/v1/search?q=x402", Description: "Search", MimeType: "application/json"},
+	}
+	for _, network := range []string{v2.NetworkBase, v2.NetworkPolygon, v2.NetworkAvalanche, v2.NetworkSolanaMainnet} {
+		requirement := benchmarkPayment.Accepted
+		requirement.Network = network
+		required.Accepts = append(required.Accepts, requirement)
+	}
+
+	settlement := v2.SettleResponse{
+		Success:     true,
+		Transaction: "0x" + fmt.Sprintf("%064x", 1),
+		Network:     v2.NetworkBase,
+		Payer:       "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+	}
+
+	return map[string]struct {
+		value  any
+		decode func([]byte) error
+	}{
+		"evm-payment":  {benchmarkPayment, func(data []byte) error { var p v2.PaymentPayload; return DefaultCodec().Unmarshal(data, &p) }},
+		"svm-payment":  {svm, func(data []byte) error { var p v2.PaymentPayload; return DefaultCodec().Unmarshal(data, &p) }},
+		"requirements": {required, func(data []byte) error { var r v2.PaymentRequired; return DefaultCodec().Unmarshal(data, &r) }},
+		"settlement":   {settlement, func(data []byte) error { var s v2.SettleResponse; return DefaultCodec().Unmarshal(data, &s) }},
+	}
+}
+
+// BenchmarkCodec measures the JSON cost of x402 messages with the default
+// codec. Run it after SetCodec in a deployment's own benchmark to compare
+// implementations.
+func BenchmarkCodec(b *testing.B) {
+	for name, payload := range benchmarkPayloads() {
+		data, err := DefaultCodec().Marshal(payload.value)
+		if err != nil {
+			b.Fatalf("Marshal failed: %v", err)
+		}
+
+		b.Run(name+"/encode", func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := DefaultCodec().Marshal(payload.value); err != nil {
+					b.Fatalf("Marshal failed: %v", err)
+				}
+			}
+		})
+		b.Run(name+"/decode", func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := payload.decode(data); err != nil {
+					b.Fatalf("Unmarshal failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEncodeRequirements(b *testing.B) {
+	required := benchmarkPayloads()["requirements"].value.(v2.PaymentRequired)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := EncodeRequirements(required); err != nil {
+			b.Fatalf("EncodeRequirements failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkDecodeSettlement(b *testing.B) {
+	encoded, err := EncodeSettlement(benchmarkPayloads()["settlement"].value.(v2.SettleResponse))
+	if err != nil {
+		b.Fatalf("EncodeSettlement failed: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodeSettlement(encoded); err != nil {
+			b.Fatalf("DecodeSettlement failed: %v", err)
+		}
+	}
+}
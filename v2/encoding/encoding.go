@@ -1,11 +1,14 @@
 // Package encoding provides utilities for encoding and decoding x402 v2 payment data.
 // It handles base64 and JSON marshaling for payment payloads, settlements, and requirements.
+//
+// JSON is marshaled with encoding/json unless another Codec is set with
+// SetCodec, e.g. a faster implementation for high-throughput deployments.
 package encoding
 
 import (
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
+	"sync"
 
 	v2 "github.com/mark3labs/x402-go/v2"
 )
@@ -15,7 +18,7 @@ import (
 //
 // Returns an error if JSON marshaling fails.
 func EncodePayment(payment v2.PaymentPayload) (string, error) {
-	paymentJSON, err := json.Marshal(payment)
+	paymentJSON, err := DefaultCodec().Marshal(payment)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal payment: %w", err)
 	}
@@ -25,27 +28,56 @@ func EncodePayment(payment v2.PaymentPayload) (string, error) {
 // DecodePayment converts a base64-encoded JSON string to PaymentPayload.
 //
 // Returns an error if base64 decoding or JSON unmarshaling fails.
+//
+// DecodePayment runs on every paid request, so the base64 stage decodes into
+// pooled buffers and does not allocate; the codec copies what it keeps.
 func DecodePayment(encoded string) (v2.PaymentPayload, error) {
 	var payment v2.PaymentPayload
 
-	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	buf := decodeBuffers.Get().(*decodeBuffer)
+	defer decodeBuffers.Put(buf)
+
+	decoded, err := buf.decode(encoded)
 	if err != nil {
 		return payment, fmt.Errorf("failed to decode base64: %w", err)
 	}
 
-	if err := json.Unmarshal(decoded, &payment); err != nil {
+	if err := DefaultCodec().Unmarshal(decoded, &payment); err != nil {
 		return payment, fmt.Errorf("failed to unmarshal payment: %w", err)
 	}
 
 	return payment, nil
 }
 
+// maxPooledBufferSize caps the buffers kept for reuse, so one oversized header
+// does not pin its memory in the pool.
+const maxPooledBufferSize = 64 << 10
+
+// decodeBuffer holds the scratch space of one base64 decode.
+type decodeBuffer struct {
+	src, dst []byte
+}
+
+var decodeBuffers = sync.Pool{New: func() any { return new(decodeBuffer) }}
+
+// decode returns the base64 decoding of encoded, valid until the next call.
+func (b *decodeBuffer) decode(encoded string) ([]byte, error) {
+	b.src = append(b.src[:0], encoded...)
+	var err error
+	b.dst, err = base64.StdEncoding.AppendDecode(b.dst[:0], b.src)
+	decoded := b.dst
+	if cap(b.src) > maxPooledBufferSize || cap(b.dst) > maxPooledBufferSize {
+		b.src, b.dst = nil, nil
+	}
+	return decoded, err
+}
+
 // EncodeSettlement converts a SettleResponse to base64-encoded JSON string.
 // This is used for HTTP X-PAYMENT-RESPONSE headers.
 //
 // Returns an error if JSON marshaling fails.
 func EncodeSettlement(settlement v2.SettleResponse) (string, error) {
-	settlementJSON, err := json.Marshal(settlement)
+	settlementJSON, err := DefaultCodec().Marshal(settlement)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal settlement: %w", err)
 	}
@@ -63,7 +95,7 @@ func DecodeSettlement(encoded string) (v2.SettleResponse, error) {
 		return settlement, fmt.Errorf("failed to decode base64: %w", err)
 	}
 
-	if err := json.Unmarshal(decoded, &settlement); err != nil {
+	if err := DefaultCodec().Unmarshal(decoded, &settlement); err != nil {
 		return settlement, fmt.Errorf("failed to unmarshal settlement: %w", err)
 	}
 
@@ -74,7 +106,7 @@ func DecodeSettlement(encoded string) (v2.SettleResponse, error) {
 //
 // Returns an error if JSON marshaling fails.
 func EncodeRequirements(requirements v2.PaymentRequired) (string, error) {
-	reqJSON, err := json.Marshal(requirements)
+	reqJSON, err := DefaultCodec().Marshal(requirements)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal requirements: %w", err)
 	}
@@ -92,7 +124,7 @@ func DecodeRequirements(encoded string) (v2.PaymentRequired, error) {
 		return requirements, fmt.Errorf("failed to decode base64: %w", err)
 	}
 
-	if err := json.Unmarshal(decoded, &requirements); err != nil {
+	if err := DefaultCodec().Unmarshal(decoded, &requirements); err != nil {
 		return requirements, fmt.Errorf("failed to unmarshal requirements: %w", err)
 	}
 
@@ -103,7 +135,7 @@ func DecodeRequirements(encoded string) (v2.PaymentRequired, error) {
 //
 // Returns an error if JSON marshaling fails.
 func EncodeVerifyResponse(response v2.VerifyResponse) (string, error) {
-	responseJSON, err := json.Marshal(response)
+	responseJSON, err := DefaultCodec().Marshal(response)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal verify response: %w", err)
 	}
@@ -121,7 +153,7 @@ func DecodeVerifyResponse(encoded string) (v2.VerifyResponse, error) {
 		return response, fmt.Errorf("failed to decode base64: %w", err)
 	}
 
-	if err := json.Unmarshal(decoded, &response); err != nil {
+	if err := DefaultCodec().Unmarshal(decoded, &response); err != nil {
 		return response, fmt.Errorf("failed to unmarshal verify response: %w", err)
 	}
 
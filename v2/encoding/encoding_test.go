@@ -3,6 +3,7 @@ package encoding
 import (
 	"encoding/base64"
 	"encoding/json"
+	"strings"
 	"testing"
 
 	v2 "github.com/mark3labs/x402-go/v2"
@@ -364,6 +365,29 @@ func TestRoundTripPreservesExtensions(t *testing.T) {
 	}
 }
 
+func TestRoundTripPreservesUnknownFields(t *testing.T) {
+	body := `{"x402Version":2,"accepted":{"scheme":"exact","network":"eip155:8453","amount":"1000","asset":"0xasset","payTo":"0xpayto","maxTimeoutSeconds":60,"facilitatorHint":"fast"},"payload":{"test":"data"},"trace":{"id":"t-1"}}`
+
+	decoded, err := DecodePayment(base64.StdEncoding.EncodeToString([]byte(body)))
+	if err != nil {
+		t.Fatalf("DecodePayment() error = %v", err)
+	}
+	encoded, err := EncodePayment(decoded)
+	if err != nil {
+		t.Fatalf("EncodePayment() error = %v", err)
+	}
+	again, err := DecodePayment(encoded)
+	if err != nil {
+		t.Fatalf("DecodePayment() error = %v", err)
+	}
+	if string(again.UnknownFields["trace"]) != `{"id":"t-1"}` {
+		t.Errorf("UnknownFields = %v; want trace", again.UnknownFields)
+	}
+	if string(again.Accepted.UnknownFields["facilitatorHint"]) != `"fast"` {
+		t.Errorf("Accepted.UnknownFields = %v; want facilitatorHint", again.Accepted.UnknownFields)
+	}
+}
+
 func TestEncodedFormatIsValidJSON(t *testing.T) {
 	payment := v2.PaymentPayload{
 		X402Version: 2,
@@ -407,3 +431,79 @@ func TestEncodedFormatIsValidJSON(t *testing.T) {
 		t.Errorf("accepted.network = %v; want eip155:8453", accepted["network"])
 	}
 }
+
+// benchmarkPayment is a typical EVM exact payment, as sent in X-PAYMENT headers.
+var benchmarkPayment = v2.PaymentPayload{
+	X402Version: 2,
+	Resource:    &v2.ResourceInfo{URL: "https://api.example.com/data", Description: "Data"},
+	Accepted: v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:8453",
+		Amount:            "10000",
+		Asset:             "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+		Extra:             map[string]interface{}{"name": "USD Coin", "version": "2"},
+	},
+	Payload: v2.EVMPayload{
+		Signature: "0x" + strings.Repeat("ab", 65),
+		Authorization: v2.EVMAuthorization{
+			From:        "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+			To:          "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+			Value:       "10000",
+			ValidAfter:  "1700000000",
+			ValidBefore: "1700000060",
+			Nonce:       "0x" + strings.Repeat("cd", 32),
+		},
+	},
+}
+
+// TestDecodePayment_AllocationBudget checks the base64 stage of DecodePayment
+// allocates nothing: DecodePayment may allocate no more than json.Unmarshal of
+// the same payment alone.
+func TestDecodePayment_AllocationBudget(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops buffers under the race detector")
+	}
+
+	encoded, err := EncodePayment(benchmarkPayment)
+	if err != nil {
+		t.Fatalf("EncodePayment() error = %v", err)
+	}
+	decoded, _ := base64.StdEncoding.DecodeString(encoded)
+
+	budget := testing.AllocsPerRun(100, func() {
+		var payment v2.PaymentPayload
+		_ = json.Unmarshal(decoded, &payment)
+	})
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = DecodePayment(encoded)
+	})
+	if allocs > budget {
+		t.Errorf("DecodePayment allocs = %v; want <= %v (json.Unmarshal alone)", allocs, budget)
+	}
+}
+
+func BenchmarkEncodePayment(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := EncodePayment(benchmarkPayment); err != nil {
+			b.Fatalf("EncodePayment failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkDecodePayment(b *testing.B) {
+	encoded, err := EncodePayment(benchmarkPayment)
+	if err != nil {
+		b.Fatalf("EncodePayment failed: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodePayment(encoded); err != nil {
+			b.Fatalf("DecodePayment failed: %v", err)
+		}
+	}
+}
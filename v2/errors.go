@@ -37,6 +37,9 @@ var (
 	// ErrInvalidMnemonic indicates an invalid BIP39 mnemonic phrase.
 	ErrInvalidMnemonic = errors.New("x402: invalid mnemonic phrase")
 
+	// ErrSignerClosed indicates the signer was closed and its key material wiped.
+	ErrSignerClosed = errors.New("x402: signer is closed")
+
 	// ErrNoTokens indicates no tokens are configured for the signer.
 	ErrNoTokens = errors.New("x402: no tokens configured")
 
@@ -57,6 +60,109 @@ var (
 
 	// ErrUnsupportedScheme indicates an unsupported payment scheme.
 	ErrUnsupportedScheme = errors.New("x402: unsupported payment scheme")
+
+	// ErrSchemeRegistered indicates a scheme handler is already registered for the scheme.
+	ErrSchemeRegistered = errors.New("x402: payment scheme already registered")
+
+	// ErrNamespaceRegistered indicates a chain namespace is already registered.
+	ErrNamespaceRegistered = errors.New("x402: chain namespace already registered")
+
+	// ErrUntrustedDomain indicates the EIP-712 domain supplied by the server does not match trusted values.
+	ErrUntrustedDomain = errors.New("x402: untrusted EIP-712 domain")
+
+	// ErrUntrustedSpender indicates the Permit2 spender or allowance relayer supplied by the server is not trusted.
+	ErrUntrustedSpender = errors.New("x402: untrusted spender")
+
+	// ErrUntrustedFeePayer indicates the fee payer supplied by the server is not a signer advertised by the facilitator.
+	ErrUntrustedFeePayer = errors.New("x402: fee payer is not an advertised facilitator signer")
+
+	// ErrUntrustedPayTo indicates the payment recipient supplied by the server is not a known address of the merchant.
+	ErrUntrustedPayTo = errors.New("x402: payTo is not a known merchant address")
+
+	// ErrUnattestedRequirements indicates a 402 response lacks a required requirements attestation.
+	ErrUnattestedRequirements = errors.New("x402: payment requirements are not attested")
+
+	// ErrInvalidAttestation indicates a requirements attestation is invalid, expired, or untrusted.
+	ErrInvalidAttestation = errors.New("x402: invalid requirements attestation")
+
+	// ErrUnknownAuthorization indicates a deferred authorization is not outstanding.
+	ErrUnknownAuthorization = errors.New("x402: unknown or expired authorization")
+
+	// ErrSpendLimitExceeded indicates a process-wide spend limit has been reached.
+	ErrSpendLimitExceeded = errors.New("x402: spend limit reached")
+
+	// ErrChargeRejected indicates the final charge of an "upto" payment exceeds the
+	// authorized amount or was not accepted by the client.
+	ErrChargeRejected = errors.New("x402: final charge rejected")
+
+	// ErrInvalidQuote indicates a price quote echoed by the client is forged,
+	// altered, or does not cover the request.
+	ErrInvalidQuote = errors.New("x402: invalid price quote")
+
+	// ErrQuoteExpired indicates a price quote echoed by the client has expired.
+	ErrQuoteExpired = errors.New("x402: price quote expired")
+
+	// ErrPaymentReplayed indicates a payment was already used for another
+	// request, possibly on another replica.
+	ErrPaymentReplayed = errors.New("x402: payment already used")
+
+	// ErrPaymentTooEarly indicates a payment's authorization is not valid yet
+	// at the server, e.g. its validAfter is ahead of the server's clock.
+	ErrPaymentTooEarly = errors.New("x402: payment not yet valid")
+
+	// ErrRateLimited indicates a paid request was rate limited by the server.
+	ErrRateLimited = errors.New("x402: rate limited")
+
+	// ErrAuthorizationExpired indicates a payment's authorization expired, or
+	// its requirement's timeout passed, before it could be verified or settled.
+	ErrAuthorizationExpired = errors.New("x402: payment authorization expired")
+
+	// ErrSimulationFailed indicates a payment transaction failed when simulated
+	// before it was presented.
+	ErrSimulationFailed = errors.New("x402: payment transaction simulation failed")
+
+	// ErrInsufficientFunds indicates the payer does not hold enough of the asset.
+	ErrInsufficientFunds = errors.New("x402: insufficient funds")
+
+	// ErrTokenAccountNotFound indicates a token account the payment transfers
+	// from does not exist.
+	ErrTokenAccountNotFound = errors.New("x402: token account not found")
+
+	// ErrBalanceUnavailable indicates a signer cannot report its balance of a
+	// token, e.g. because it has no RPC access configured.
+	ErrBalanceUnavailable = errors.New("x402: balance unavailable")
+
+	// ErrComputeBudgetExceeded indicates a payment transaction exceeds its
+	// compute unit limit.
+	ErrComputeBudgetExceeded = errors.New("x402: compute budget exceeded")
+
+	// ErrBelowMinimum indicates a payment amount is below the minimum worth
+	// transferring for its asset.
+	ErrBelowMinimum = errors.New("x402: payment amount below minimum")
+
+	// ErrPaymentNotAllowed indicates a payment's scheme, network or asset is
+	// not on the deployment's PaymentAllowlist.
+	ErrPaymentNotAllowed = errors.New("x402: payment not allowed")
+
+	// ErrRequirementsChanged indicates the requirements of a resource differ
+	// from those it was previously paid with by more than the client tolerates.
+	ErrRequirementsChanged = errors.New("x402: payment requirements changed")
+
+	// ErrInvalidFacilitatorResponse indicates a facilitator response is
+	// missing required fields or has fields of the wrong type.
+	ErrInvalidFacilitatorResponse = errors.New("x402: invalid facilitator response")
+
+	// ErrSignatureRejected indicates the user declined to sign a payment in
+	// their wallet.
+	ErrSignatureRejected = errors.New("x402: signature rejected by wallet")
+
+	// ErrApprovalTimeout indicates the user did not approve a payment in
+	// their wallet, or an operator did not approve it, in time.
+	ErrApprovalTimeout = errors.New("x402: payment approval timed out")
+
+	// ErrPaymentNotApproved indicates a payment that needed approval was
+	// refused, or could not be approved, before it was sent.
+	ErrPaymentNotApproved = errors.New("x402: payment not approved")
 )
 
 // ErrorCode represents payment error codes for programmatic handling.
@@ -83,6 +189,25 @@ const (
 
 	// ErrCodeUnsupportedVersion indicates unsupported x402 protocol version.
 	ErrCodeUnsupportedVersion ErrorCode = "UNSUPPORTED_VERSION"
+
+	// ErrCodeSpendLimitExceeded indicates a process-wide spend limit has been reached.
+	ErrCodeSpendLimitExceeded ErrorCode = "SPEND_LIMIT_EXCEEDED"
+
+	// ErrCodeAuthorizationExpired indicates a payment authorization expired
+	// before it could be settled.
+	ErrCodeAuthorizationExpired ErrorCode = "AUTHORIZATION_EXPIRED"
+
+	// ErrCodeNonceConflict indicates the server refused a payment whose nonce
+	// was already used (409 Conflict).
+	ErrCodeNonceConflict ErrorCode = "NONCE_CONFLICT"
+
+	// ErrCodeTooEarly indicates the server refused a payment that is not yet
+	// valid (425 Too Early).
+	ErrCodeTooEarly ErrorCode = "TOO_EARLY"
+
+	// ErrCodeRateLimited indicates the server rate limited a paid request
+	// (429 Too Many Requests).
+	ErrCodeRateLimited ErrorCode = "RATE_LIMITED"
 )
 
 // PaymentError provides structured error information.
@@ -1,6 +1,9 @@
 package v2
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // PaymentEventType represents the type of payment event.
 type PaymentEventType string
@@ -14,13 +17,17 @@ const (
 
 	// PaymentEventFailure indicates a payment failed.
 	PaymentEventFailure PaymentEventType = "failure"
+
+	// PaymentEventRetry indicates a request is being retried after a transient
+	// failure.
+	PaymentEventRetry PaymentEventType = "retry"
 )
 
 // PaymentEvent represents a payment lifecycle event.
 // This type is used by both HTTP and MCP packages to provide consistent
 // payment event notifications for logging, monitoring, and debugging.
 type PaymentEvent struct {
-	// Type is the event type (attempt, success, failure).
+	// Type is the event type (attempt, success, failure, retry).
 	Type PaymentEventType
 
 	// Timestamp is when the event occurred.
@@ -32,7 +39,7 @@ type PaymentEvent struct {
 	// Tool is the MCP tool/resource being accessed (MCP only).
 	Tool string
 
-	// URL is the HTTP URL being accessed (HTTP only).
+	// URL is the HTTP URL being accessed, or the MCP server's URL.
 	URL string
 
 	// Amount is the payment amount in atomic units.
@@ -71,3 +78,41 @@ type PaymentEvent struct {
 // should be fast to avoid blocking the payment flow. For longer operations,
 // consider using goroutines within the callback.
 type PaymentCallback func(PaymentEvent)
+
+// paymentCallbackKey is the context key for request-scoped payment callbacks.
+type paymentCallbackKey struct{}
+
+// ContextWithPaymentCallback returns a copy of ctx carrying callback.
+// Transports invoke the callback for every payment event of a request made with
+// this context, in addition to their configured callbacks. This lets callers
+// attribute payments to a single request on a shared client.
+// If ctx already carries a callback, both are invoked.
+func ContextWithPaymentCallback(ctx context.Context, callback PaymentCallback) context.Context {
+	if callback == nil {
+		return ctx
+	}
+	if parent := PaymentCallbackFromContext(ctx); parent != nil {
+		next := callback
+		callback = func(event PaymentEvent) {
+			parent(event)
+			next(event)
+		}
+	}
+	return context.WithValue(ctx, paymentCallbackKey{}, callback)
+}
+
+// PaymentCallbackFromContext returns the payment callback carried by ctx, or nil.
+func PaymentCallbackFromContext(ctx context.Context) PaymentCallback {
+	callback, _ := ctx.Value(paymentCallbackKey{}).(PaymentCallback)
+	return callback
+}
+
+// NotifyPaymentEvent invokes callback, if set, followed by any callback carried by ctx.
+func NotifyPaymentEvent(ctx context.Context, callback PaymentCallback, event PaymentEvent) {
+	if callback != nil {
+		callback(event)
+	}
+	if contextCallback := PaymentCallbackFromContext(ctx); contextCallback != nil {
+		contextCallback(event)
+	}
+}
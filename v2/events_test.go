@@ -0,0 +1,43 @@
+package v2
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextWithPaymentCallback(t *testing.T) {
+	var calls []string
+
+	ctx := ContextWithPaymentCallback(context.Background(), func(PaymentEvent) {
+		calls = append(calls, "outer")
+	})
+	ctx = ContextWithPaymentCallback(ctx, func(PaymentEvent) {
+		calls = append(calls, "inner")
+	})
+
+	NotifyPaymentEvent(ctx, func(PaymentEvent) {
+		calls = append(calls, "configured")
+	}, PaymentEvent{Type: PaymentEventSuccess})
+
+	want := []string{"configured", "outer", "inner"}
+	if len(calls) != len(want) {
+		t.Fatalf("expected %v, got %v", want, calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("call %d: expected %s, got %s", i, want[i], calls[i])
+		}
+	}
+}
+
+func TestNotifyPaymentEvent_NoCallbacks(t *testing.T) {
+	// Must not panic without any callbacks
+	NotifyPaymentEvent(context.Background(), nil, PaymentEvent{})
+
+	if PaymentCallbackFromContext(context.Background()) != nil {
+		t.Error("expected nil callback from empty context")
+	}
+	if ctx := ContextWithPaymentCallback(context.Background(), nil); PaymentCallbackFromContext(ctx) != nil {
+		t.Error("expected nil callback to be ignored")
+	}
+}
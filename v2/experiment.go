@@ -0,0 +1,50 @@
+package v2
+
+import "encoding/json"
+
+// ExperimentExtraKey is the PaymentRequirements.Extra key tagging the price
+// variant a requirement was advertised under.
+const ExperimentExtraKey = "experiment"
+
+// ExperimentTag identifies the price experiment and variant of a requirement.
+type ExperimentTag struct {
+	// Experiment is the experiment name.
+	Experiment string `json:"name"`
+
+	// Variant is the name of the variant whose price was advertised.
+	Variant string `json:"variant"`
+}
+
+// WithExperiment returns a copy of requirements tagged with tag.
+func WithExperiment(requirements PaymentRequirements, tag ExperimentTag) PaymentRequirements {
+	extra := make(map[string]interface{}, len(requirements.Extra)+1)
+	for key, value := range requirements.Extra {
+		extra[key] = value
+	}
+	extra[ExperimentExtraKey] = tag
+	requirements.Extra = extra
+	return requirements
+}
+
+// ExperimentFromRequirements returns the experiment tag in requirements'
+// Extra, whether stored as ExperimentTag or decoded from JSON. It returns
+// false if there is none.
+func ExperimentFromRequirements(requirements PaymentRequirements) (ExperimentTag, bool) {
+	value, ok := requirements.Extra[ExperimentExtraKey]
+	if !ok {
+		return ExperimentTag{}, false
+	}
+	if tag, ok := value.(ExperimentTag); ok {
+		return tag, true
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ExperimentTag{}, false
+	}
+	var tag ExperimentTag
+	if err := json.Unmarshal(data, &tag); err != nil || tag.Variant == "" {
+		return ExperimentTag{}, false
+	}
+	return tag, true
+}
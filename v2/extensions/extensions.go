@@ -0,0 +1,276 @@
+// Package extensions provides typed access to the "extensions" field of x402
+// PaymentRequired responses and PaymentPayloads.
+//
+// A Registry holds the Definitions of the extensions one side of a payment
+// understands: their name, the JSON schema their info must satisfy, whether
+// they are required, and the info that side attaches itself. Servers set it
+// as the Extensions field of the http middleware Config or the MCP server
+// Config, advertising their extensions in 402 responses and validating those
+// of incoming payments; clients set it with the WithExtensions options of the
+// http and MCP clients, validating the extensions of 402 responses and adding
+// their own to payments.
+//
+// Schemas are JSON schemas restricted to the keywords listed on Validate,
+// which cover the shapes extension info takes in practice. Set and Get read
+// and write extension info as Go values.
+package extensions
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+var (
+	// ErrInvalidExtension is returned when an extension's info does not
+	// satisfy its schema, or a definition is malformed.
+	ErrInvalidExtension = errors.New("extensions: invalid extension")
+
+	// ErrMissingExtension is returned when a required extension is absent.
+	ErrMissingExtension = errors.New("extensions: missing required extension")
+
+	// ErrNotFound is returned by Get when the extension is absent.
+	ErrNotFound = errors.New("extensions: extension not found")
+)
+
+// Definition describes an extension.
+type Definition struct {
+	// Name is the key of the extension in the extensions map.
+	Name string
+
+	// Schema is the JSON schema the extension's info must satisfy, and is
+	// published with the info the registry attaches. Nil accepts any info.
+	Schema map[string]interface{}
+
+	// Info, if set, is attached under Name by the side using the registry:
+	// servers advertise it in 402 responses and clients add it to their
+	// payments. It must encode to a JSON object satisfying Schema.
+	Info interface{}
+
+	// Required makes validation fail when the extension is absent: from
+	// payments received by a server, or 402 responses received by a client.
+	Required bool
+}
+
+// Registry holds extension definitions. It is safe for concurrent use.
+type Registry struct {
+	mu          sync.RWMutex
+	definitions map[string]Definition
+	info        map[string]map[string]interface{}
+}
+
+// NewRegistry creates a Registry holding definitions.
+func NewRegistry(definitions ...Definition) (*Registry, error) {
+	r := &Registry{
+		definitions: make(map[string]Definition),
+		info:        make(map[string]map[string]interface{}),
+	}
+	for _, definition := range definitions {
+		if err := r.Register(definition); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register adds definition, replacing any definition with the same name. It
+// fails if the schema uses unsupported keywords or the info does not satisfy
+// it.
+func (r *Registry) Register(definition Definition) error {
+	if definition.Name == "" {
+		return fmt.Errorf("%w: definition has no name", ErrInvalidExtension)
+	}
+	if err := CheckSchema(definition.Schema); err != nil {
+		return fmt.Errorf("schema of %s: %w", definition.Name, err)
+	}
+	var info map[string]interface{}
+	if definition.Info != nil {
+		var err error
+		if info, err = toInfo(definition.Info); err != nil {
+			return fmt.Errorf("%w: info of %s: %v", ErrInvalidExtension, definition.Name, err)
+		}
+		if err := Validate(definition.Schema, info); err != nil {
+			return fmt.Errorf("info of %s: %w", definition.Name, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.definitions[definition.Name] = definition
+	if info != nil {
+		r.info[definition.Name] = info
+	} else {
+		delete(r.info, definition.Name)
+	}
+	return nil
+}
+
+// Definition returns the definition registered under name.
+func (r *Registry) Definition(name string) (Definition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	definition, ok := r.definitions[name]
+	return definition, ok
+}
+
+// Names returns the names of the registered extensions, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return sortedNames(r.definitions)
+}
+
+// Validate checks extensions received from the other side. Registered
+// extensions must satisfy their registered schema, never the schema they
+// carry, and required ones must be present. Unregistered extensions carrying
+// a schema must satisfy it; others are passed through unchecked.
+func (r *Registry) Validate(extensions map[string]v2.Extension) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, name := range sortedNames(r.definitions) {
+		definition := r.definitions[name]
+		extension, ok := extensions[name]
+		if !ok {
+			if definition.Required {
+				return fmt.Errorf("%w: %s", ErrMissingExtension, name)
+			}
+			continue
+		}
+		if err := Validate(definition.Schema, extension.Info); err != nil {
+			return fmt.Errorf("extension %s: %w", name, err)
+		}
+	}
+	for _, name := range sortedNames(extensions) {
+		if _, ok := r.definitions[name]; ok {
+			continue
+		}
+		extension := extensions[name]
+		if extension.Schema == nil {
+			continue
+		}
+		if err := CheckSchema(extension.Schema); err != nil {
+			return fmt.Errorf("extension %s: %w", name, err)
+		}
+		if err := Validate(extension.Schema, extension.Info); err != nil {
+			return fmt.Errorf("extension %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Attach adds the info of every definition that has one to extensions, with
+// its schema, allocating the map if needed. Extensions already present are
+// left unchanged.
+func (r *Registry) Attach(extensions *map[string]v2.Extension) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for name, info := range r.info {
+		if _, ok := (*extensions)[name]; ok {
+			continue
+		}
+		if *extensions == nil {
+			*extensions = make(map[string]v2.Extension)
+		}
+		(*extensions)[name] = v2.Extension{Info: copyInfo(info), Schema: r.definitions[name].Schema}
+	}
+}
+
+// Set stores info, which must encode to a JSON object, under name in
+// extensions with schema, allocating the map if needed. The info must satisfy
+// schema.
+func Set(extensions *map[string]v2.Extension, name string, info interface{}, schema map[string]interface{}) error {
+	if err := CheckSchema(schema); err != nil {
+		return fmt.Errorf("schema of %s: %w", name, err)
+	}
+	encoded, err := toInfo(info)
+	if err != nil {
+		return fmt.Errorf("%w: info of %s: %v", ErrInvalidExtension, name, err)
+	}
+	if err := Validate(schema, encoded); err != nil {
+		return fmt.Errorf("info of %s: %w", name, err)
+	}
+	if *extensions == nil {
+		*extensions = make(map[string]v2.Extension)
+	}
+	(*extensions)[name] = v2.Extension{Info: encoded, Schema: schema}
+	return nil
+}
+
+// Get decodes the info of the extension name into a T. Returns ErrNotFound if
+// extensions has no such extension.
+func Get[T any](extensions map[string]v2.Extension, name string) (T, error) {
+	var value T
+	extension, ok := extensions[name]
+	if !ok {
+		return value, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	raw, err := json.Marshal(extension.Info)
+	if err != nil {
+		return value, fmt.Errorf("%w: info of %s: %v", ErrInvalidExtension, name, err)
+	}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return value, fmt.Errorf("%w: info of %s: %v", ErrInvalidExtension, name, err)
+	}
+	return value, nil
+}
+
+// toInfo encodes value as a JSON object, the form extension info takes on the
+// wire.
+func toInfo(value interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var info map[string]interface{}
+	if err := decoder.Decode(&info); err != nil {
+		return nil, fmt.Errorf("info is not a JSON object")
+	}
+	if info == nil {
+		return nil, fmt.Errorf("info is null")
+	}
+	return info, nil
+}
+
+// copyInfo returns a deep copy of info, so attached extensions can be modified
+// without affecting the registry.
+func copyInfo(info map[string]interface{}) map[string]interface{} {
+	copied, _ := copyValue(info).(map[string]interface{})
+	return copied
+}
+
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = copyValue(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// sortedNames returns the keys of m, sorted, so validation reports the same
+// error for the same input.
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
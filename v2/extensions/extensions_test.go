@@ -0,0 +1,160 @@
+package extensions
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// bazaarSchema describes the info of a discovery extension.
+var bazaarSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []string{"category"},
+	"properties": map[string]interface{}{
+		"category": map[string]interface{}{"type": "string", "enum": []string{"weather", "news"}},
+		"tags":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string", "minLength": 1}, "maxItems": 3},
+		"rating":   map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 5},
+	},
+	"additionalProperties": false,
+}
+
+type bazaarInfo struct {
+	Category string   `json:"category"`
+	Tags     []string `json:"tags,omitempty"`
+	Rating   int      `json:"rating,omitempty"`
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    string
+		wantErr bool
+	}{
+		{"valid", `{"category":"weather","tags":["rain"],"rating":4}`, false},
+		{"minimal", `{"category":"news"}`, false},
+		{"missing required", `{"tags":["rain"]}`, true},
+		{"not in enum", `{"category":"sports"}`, true},
+		{"wrong type", `{"category":"news","rating":"4"}`, true},
+		{"not an integer", `{"category":"news","rating":4.5}`, true},
+		{"above maximum", `{"category":"news","rating":6}`, true},
+		{"too many items", `{"category":"news","tags":["a","b","c","d"]}`, true},
+		{"empty item", `{"category":"news","tags":[""]}`, true},
+		{"additional property", `{"category":"news","extra":true}`, true},
+		{"not an object", `"news"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var info interface{}
+			if err := json.Unmarshal([]byte(tt.info), &info); err != nil {
+				t.Fatal(err)
+			}
+			err := Validate(bazaarSchema, info)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidExtension) {
+				t.Errorf("expected ErrInvalidExtension, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  map[string]interface{}
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"valid", bazaarSchema, false},
+		{"annotations", map[string]interface{}{"title": "x", "$schema": "https://json-schema.org/draft/2020-12/schema"}, false},
+		{"unknown type", map[string]interface{}{"type": "date"}, true},
+		{"bad pattern", map[string]interface{}{"pattern": "("}, true},
+		{"bad nested schema", map[string]interface{}{"properties": map[string]interface{}{"a": map[string]interface{}{"minLength": -1}}}, true},
+		{"bad required", map[string]interface{}{"required": "a"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckSchema(tt.schema); (err != nil) != tt.wantErr {
+				t.Errorf("CheckSchema = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	if _, err := NewRegistry(Definition{Name: "bazaar", Schema: bazaarSchema, Info: bazaarInfo{Category: "sports"}}); !errors.Is(err, ErrInvalidExtension) {
+		t.Fatalf("expected invalid info to be refused, got %v", err)
+	}
+
+	registry, err := NewRegistry(
+		Definition{Name: "bazaar", Schema: bazaarSchema, Info: bazaarInfo{Category: "weather"}, Required: true},
+		Definition{Name: "receipt"},
+	)
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+
+	// The registry attaches its info without overwriting extensions already set
+	var attached map[string]v2.Extension
+	registry.Attach(&attached)
+	if len(attached) != 1 {
+		t.Fatalf("expected one attached extension, got %v", attached)
+	}
+	info, err := Get[bazaarInfo](attached, "bazaar")
+	if err != nil || info.Category != "weather" {
+		t.Fatalf("Get = %+v, %v", info, err)
+	}
+	if err := Set(&attached, "bazaar", bazaarInfo{Category: "news", Rating: 3}, bazaarSchema); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	registry.Attach(&attached)
+	if info, _ := Get[bazaarInfo](attached, "bazaar"); info.Category != "news" || info.Rating != 3 {
+		t.Errorf("expected Attach to keep the set extension, got %+v", info)
+	}
+	if _, err := Get[bazaarInfo](attached, "receipt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	// Validation round-trips through JSON like received extensions
+	raw, _ := json.Marshal(attached)
+	var received map[string]v2.Extension
+	if err := json.Unmarshal(raw, &received); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Validate(received); err != nil {
+		t.Errorf("Validate(received) = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		extensions map[string]v2.Extension
+		wantErr    error
+	}{
+		{"missing required", map[string]v2.Extension{}, ErrMissingExtension},
+		{"registered schema wins over the carried one", map[string]v2.Extension{
+			"bazaar": {Info: map[string]interface{}{"category": "sports"}, Schema: map[string]interface{}{}},
+		}, ErrInvalidExtension},
+		{"unregistered with carried schema", map[string]v2.Extension{
+			"bazaar": {Info: map[string]interface{}{"category": "news"}},
+			"other":  {Info: map[string]interface{}{"a": 1.0}, Schema: map[string]interface{}{"properties": map[string]interface{}{"a": map[string]interface{}{"type": "string"}}}},
+		}, ErrInvalidExtension},
+		{"unregistered without schema", map[string]v2.Extension{
+			"bazaar": {Info: map[string]interface{}{"category": "news"}},
+			"other":  {Info: map[string]interface{}{"a": 1.0}},
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := registry.Validate(tt.extensions)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Validate = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package extensions
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// schemaTypes are the values of the "type" keyword.
+var schemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// CheckSchema reports whether schema is a well-formed schema for Validate. A
+// nil schema is well-formed and accepts anything.
+func CheckSchema(schema map[string]interface{}) error {
+	normalized, err := normalizeSchema(schema)
+	if err != nil {
+		return err
+	}
+	return checkSchema(normalized, "")
+}
+
+func checkSchema(schema map[string]interface{}, path string) error {
+	invalid := func(keyword, reason string) error {
+		return fmt.Errorf("%w: schema%s.%s %s", ErrInvalidExtension, path, keyword, reason)
+	}
+	for keyword, value := range schema {
+		switch keyword {
+		case "type":
+			types, ok := typeList(value)
+			if !ok || len(types) == 0 {
+				return invalid(keyword, "must be a type name or a list of them")
+			}
+			for _, t := range types {
+				if !schemaTypes[t] {
+					return invalid(keyword, fmt.Sprintf("has unknown type %q", t))
+				}
+			}
+		case "properties":
+			properties, ok := value.(map[string]interface{})
+			if !ok {
+				return invalid(keyword, "must be an object")
+			}
+			for name, property := range properties {
+				sub, ok := property.(map[string]interface{})
+				if !ok {
+					return invalid(keyword, fmt.Sprintf("%q must be a schema", name))
+				}
+				if err := checkSchema(sub, path+".properties."+name); err != nil {
+					return err
+				}
+			}
+		case "items":
+			sub, ok := value.(map[string]interface{})
+			if !ok {
+				return invalid(keyword, "must be a schema")
+			}
+			if err := checkSchema(sub, path+".items"); err != nil {
+				return err
+			}
+		case "additionalProperties":
+			switch v := value.(type) {
+			case bool:
+			case map[string]interface{}:
+				if err := checkSchema(v, path+".additionalProperties"); err != nil {
+					return err
+				}
+			default:
+				return invalid(keyword, "must be a boolean or a schema")
+			}
+		case "required":
+			if _, ok := stringList(value); !ok {
+				return invalid(keyword, "must be a list of property names")
+			}
+		case "enum":
+			if _, ok := value.([]interface{}); !ok {
+				return invalid(keyword, "must be a list")
+			}
+		case "pattern":
+			pattern, ok := value.(string)
+			if !ok {
+				return invalid(keyword, "must be a string")
+			}
+			if _, err := regexp.Compile(pattern); err != nil {
+				return invalid(keyword, fmt.Sprintf("is not a valid regular expression: %v", err))
+			}
+		case "minLength", "maxLength", "minItems", "maxItems":
+			if n, ok := toFloat(value); !ok || n < 0 || n != math.Trunc(n) {
+				return invalid(keyword, "must be a non-negative integer")
+			}
+		case "minimum", "maximum":
+			if _, ok := toFloat(value); !ok {
+				return invalid(keyword, "must be a number")
+			}
+		}
+		// Other keywords, like "const", "$schema", "title" and
+		// "description", need no checking or are annotations.
+	}
+	return nil
+}
+
+// Validate checks value against schema, which must be well-formed (see
+// CheckSchema). It supports the keywords type, properties, required,
+// additionalProperties, items, enum, const, pattern, minLength, maxLength,
+// minItems, maxItems, minimum, and maximum, and ignores others. Values are
+// compared in their JSON form.
+func Validate(schema map[string]interface{}, value interface{}) error {
+	if schema == nil {
+		return nil
+	}
+	normalizedSchema, err := normalizeSchema(schema)
+	if err != nil {
+		return err
+	}
+	normalized, err := normalize(value)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidExtension, err)
+	}
+	return validate(normalizedSchema, normalized, "info")
+}
+
+// normalizeSchema converts schema to its JSON form, so schemas written as Go
+// literals, e.g. with []string lists, are read like decoded ones.
+func normalizeSchema(schema map[string]interface{}) (map[string]interface{}, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	normalized, err := normalize(schema)
+	if err != nil {
+		return nil, fmt.Errorf("%w: schema: %v", ErrInvalidExtension, err)
+	}
+	object, _ := normalized.(map[string]interface{})
+	return object, nil
+}
+
+func validate(schema map[string]interface{}, value interface{}, path string) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s %s", ErrInvalidExtension, path, fmt.Sprintf(format, args...))
+	}
+
+	if raw, ok := schema["type"]; ok {
+		types, _ := typeList(raw)
+		matched := false
+		for _, t := range types {
+			if hasType(value, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return invalid("must be of type %s", strings.Join(types, " or "))
+		}
+	}
+	if expected, ok := schema["const"]; ok && !equalJSON(expected, value) {
+		return invalid("must be %v", expected)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, expected := range enum {
+			if equalJSON(expected, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return invalid("must be one of %v", enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateObject(schema, v, path)
+	case []interface{}:
+		if n, ok := toFloat(schema["minItems"]); ok && float64(len(v)) < n {
+			return invalid("must have at least %v items", n)
+		}
+		if n, ok := toFloat(schema["maxItems"]); ok && float64(len(v)) > n {
+			return invalid("must have at most %v items", n)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validate(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if n, ok := toFloat(schema["minLength"]); ok && length < n {
+			return invalid("must be at least %v characters long", n)
+		}
+		if n, ok := toFloat(schema["maxLength"]); ok && length > n {
+			return invalid("must be at most %v characters long", n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if matched, _ := regexp.MatchString(pattern, v); !matched {
+				return invalid("must match %q", pattern)
+			}
+		}
+	case json.Number:
+		n, _ := new(big.Float).SetString(v.String())
+		if minimum, ok := toFloat(schema["minimum"]); ok && n.Cmp(big.NewFloat(minimum)) < 0 {
+			return invalid("must be at least %v", minimum)
+		}
+		if maximum, ok := toFloat(schema["maximum"]); ok && n.Cmp(big.NewFloat(maximum)) > 0 {
+			return invalid("must be at most %v", maximum)
+		}
+	}
+	return nil
+}
+
+func validateObject(schema map[string]interface{}, object map[string]interface{}, path string) error {
+	required, _ := stringList(schema["required"])
+	for _, name := range required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("%w: %s.%s is required", ErrInvalidExtension, path, name)
+		}
+	}
+	properties, _ := schema["properties"].(map[string]interface{})
+	for _, name := range sortedNames(object) {
+		if property, ok := properties[name].(map[string]interface{}); ok {
+			if err := validate(property, object[name], path+"."+name); err != nil {
+				return err
+			}
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return fmt.Errorf("%w: %s.%s is not allowed", ErrInvalidExtension, path, name)
+			}
+		case map[string]interface{}:
+			if err := validate(additional, object[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasType reports whether value, in normalized JSON form, is of the JSON
+// schema type t.
+func hasType(value interface{}, t string) bool {
+	switch v := value.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case json.Number:
+		if t == "number" {
+			return true
+		}
+		if t != "integer" {
+			return false
+		}
+		n, ok := new(big.Float).SetString(v.String())
+		return ok && n.IsInt()
+	case []interface{}:
+		return t == "array"
+	case map[string]interface{}:
+		return t == "object"
+	}
+	return false
+}
+
+// normalize converts value to the form encoding/json decodes JSON into, with
+// numbers as json.Number.
+func normalize(value interface{}) (interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(raw)))
+	decoder.UseNumber()
+	var normalized interface{}
+	if err := decoder.Decode(&normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// equalJSON reports whether a and b are equal in their JSON form.
+func equalJSON(a, b interface{}) bool {
+	na, errA := normalize(a)
+	nb, errB := normalize(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	if x, ok := na.(json.Number); ok {
+		if y, ok := nb.(json.Number); ok {
+			fx, okX := new(big.Float).SetString(x.String())
+			fy, okY := new(big.Float).SetString(y.String())
+			return okX && okY && fx.Cmp(fy) == 0
+		}
+	}
+	return reflect.DeepEqual(na, nb)
+}
+
+// typeList returns the type names of a "type" keyword.
+func typeList(value interface{}) ([]string, bool) {
+	if t, ok := value.(string); ok {
+		return []string{t}, true
+	}
+	return stringList(value)
+}
+
+// stringList converts a JSON list of strings.
+func stringList(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			list = append(list, s)
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+// toFloat converts a numeric schema keyword value.
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,298 @@
+// Package direct verifies payments of the "direct" scheme (see
+// v2.SchemeDirect) on-chain, for sellers whose payers submit ERC-20 transfers
+// themselves. A Confirmer implements facilitator.Interface, so it can take the
+// place of a facilitator for that scheme, e.g. through the
+// SchemeFacilitators field of the http middleware Config.
+//
+// Verify waits for the transaction to be confirmed by a chain.Confirmer, such
+// as a chain.EVM, which checks that it transferred at least the required
+// amount of the asset to payTo; it then checks that the transaction is recent
+// enough and that the payload is signed by the transfer's sender.
+// Settle records the transaction as used, so it pays for one request only;
+// nothing is submitted on-chain.
+package direct
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/chain"
+	"github.com/mark3labs/x402-go/v2/facilitator"
+	"github.com/mark3labs/x402-go/v2/storage"
+)
+
+// Invalid reasons reported by a Confirmer.
+const (
+	ReasonUnsupported      = "unsupported_scheme"
+	ReasonMalformed        = "invalid_payload"
+	ReasonNotFound         = "transaction_not_found"
+	ReasonNotConfirmed     = "transaction_not_confirmed"
+	ReasonReverted         = "transaction_reverted"
+	ReasonExpired          = "transaction_expired"
+	ReasonTransferNotFound = "transfer_not_found"
+	ReasonInvalidSignature = "invalid_signature"
+	ReasonAlreadyUsed      = "transaction_already_used"
+)
+
+const (
+	// DefaultMaxAge is how old a transfer may be when it is presented.
+	DefaultMaxAge = 10 * time.Minute
+
+	// DefaultWait is how long Verify waits for a transaction to be mined and
+	// confirmed.
+	DefaultWait = 30 * time.Second
+
+	// DefaultPollInterval is how often Verify checks a pending transaction.
+	DefaultPollInterval = time.Second
+)
+
+var txHashRegex = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+
+// Confirmer confirms direct payments on one network. It is safe for concurrent
+// use.
+type Confirmer struct {
+	network string
+	chain   chain.Confirmer
+	maxAge  time.Duration
+	wait    time.Duration
+	poll    time.Duration
+	used    storage.Store
+	clock   v2.Clock
+}
+
+// Verify that Confirmer implements facilitator.Interface.
+var _ facilitator.Interface = (*Confirmer)(nil)
+
+// Option configures a Confirmer.
+type Option func(*Confirmer)
+
+// WithMaxAge sets how old a transfer may be when it is presented (default:
+// DefaultMaxAge). Used transactions are remembered for twice as long.
+func WithMaxAge(d time.Duration) Option {
+	return func(c *Confirmer) {
+		c.maxAge = d
+	}
+}
+
+// WithWait sets how long Verify waits for a pending transaction (default:
+// DefaultWait) and how often it checks it (default: DefaultPollInterval).
+func WithWait(wait, poll time.Duration) Option {
+	return func(c *Confirmer) {
+		c.wait = wait
+		c.poll = poll
+	}
+}
+
+// WithUsedStore records used transactions in store, which must be shared by
+// all replicas of a server (default: a process-local storage.Memory).
+func WithUsedStore(store storage.Store) Option {
+	return func(c *Confirmer) {
+		c.used = store
+	}
+}
+
+// WithClock sets the clock used for transaction age and polling (default:
+// v2.SystemClock).
+func WithClock(clock v2.Clock) Option {
+	return func(c *Confirmer) {
+		c.clock = clock
+	}
+}
+
+// New creates a Confirmer for direct payments on network, confirming
+// transfers with confirmer, e.g. chain.NewEVM(network, ethClient).
+func New(network string, confirmer chain.Confirmer, opts ...Option) *Confirmer {
+	c := &Confirmer{
+		network: network,
+		chain:   confirmer,
+		maxAge:  DefaultMaxAge,
+		wait:    DefaultWait,
+		poll:    DefaultPollInterval,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.used == nil {
+		c.used = storage.NewMemory(storage.WithMemoryClock(c.clock))
+	}
+	return c
+}
+
+// Verify confirms the transfer presented by payload on-chain. A transfer that
+// is not mined or confirmed within the wait is reported invalid with
+// ReasonNotFound or ReasonNotConfirmed; RPC failures are returned as errors.
+func (c *Confirmer) Verify(ctx context.Context, payload v2.PaymentPayload, requirements v2.PaymentRequirements) (*v2.VerifyResponse, error) {
+	transfer, reason, err := c.confirm(ctx, payload, requirements)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		return &v2.VerifyResponse{IsValid: false, InvalidReason: reason, Payer: transfer.from}, nil
+	}
+
+	if _, err := c.used.Get(ctx, transfer.hash); err == nil {
+		return &v2.VerifyResponse{IsValid: false, InvalidReason: ReasonAlreadyUsed, Payer: transfer.from}, nil
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("checking used transactions: %w", err)
+	}
+	return &v2.VerifyResponse{IsValid: true, Payer: transfer.from}, nil
+}
+
+// Settle re-confirms the transfer and records its transaction as used.
+func (c *Confirmer) Settle(ctx context.Context, payload v2.PaymentPayload, requirements v2.PaymentRequirements) (*v2.SettleResponse, error) {
+	transfer, reason, err := c.confirm(ctx, payload, requirements)
+	if err != nil {
+		return nil, err
+	}
+	response := &v2.SettleResponse{
+		Transaction: transfer.hash,
+		Network:     c.network,
+		Payer:       transfer.from,
+	}
+	if reason != "" {
+		response.ErrorReason = reason
+		return response, nil
+	}
+
+	added, err := c.used.Add(ctx, transfer.hash, []byte(transfer.from), 2*c.maxAge)
+	if err != nil {
+		return nil, fmt.Errorf("recording used transaction: %w", err)
+	}
+	if !added {
+		response.ErrorReason = ReasonAlreadyUsed
+		return response, nil
+	}
+	response.Success = true
+	response.Amount = transfer.value.String()
+	return response, nil
+}
+
+// Supported reports the direct scheme on the Confirmer's network.
+func (c *Confirmer) Supported(ctx context.Context) (*v2.SupportedResponse, error) {
+	return &v2.SupportedResponse{
+		Kinds: []v2.SupportedKind{{X402Version: v2.X402Version, Scheme: v2.SchemeDirect, Network: c.network}},
+	}, nil
+}
+
+// transfer is a confirmed payment.
+type transfer struct {
+	hash  string
+	from  string
+	value *big.Int
+}
+
+// confirm waits for the transaction of payload and checks it against
+// requirements, returning an invalid reason if it does not pay them.
+func (c *Confirmer) confirm(ctx context.Context, payment v2.PaymentPayload, requirements v2.PaymentRequirements) (transfer, string, error) {
+	if payment.Accepted.Scheme != v2.SchemeDirect || requirements.Scheme != v2.SchemeDirect || requirements.Network != c.network {
+		return transfer{}, ReasonUnsupported, nil
+	}
+	payload, err := decodePayload(payment.Payload)
+	if err != nil || !txHashRegex.MatchString(payload.Transaction) {
+		return transfer{}, ReasonMalformed, nil
+	}
+	signature, err := hexutil.Decode(payload.Signature)
+	if err != nil || len(signature) != crypto.SignatureLength {
+		return transfer{}, ReasonMalformed, nil
+	}
+	required, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok {
+		return transfer{}, "", fmt.Errorf("%w: %q", v2.ErrInvalidAmount, requirements.Amount)
+	}
+
+	hash := common.HexToHash(payload.Transaction)
+	result := transfer{hash: hash.Hex()}
+	confirmation, reason, err := c.awaitConfirmation(ctx, hash.Hex(), chain.Transfer{Asset: requirements.Asset, To: requirements.PayTo, Amount: required})
+	if err != nil || reason != "" {
+		return result, reason, err
+	}
+	result.from, result.value = confirmation.From, confirmation.Amount
+
+	if confirmation.Time.IsZero() || v2.ClockOrSystem(c.clock).Now().Sub(confirmation.Time) > c.maxAge {
+		return result, ReasonExpired, nil
+	}
+	if signer, err := recoverSigner(hash, signature); err != nil || !strings.EqualFold(signer.Hex(), confirmation.From) {
+		return result, ReasonInvalidSignature, nil
+	}
+	return result, "", nil
+}
+
+// awaitConfirmation retries confirming transaction while it is pending, until
+// the wait is over.
+func (c *Confirmer) awaitConfirmation(ctx context.Context, transaction string, expected chain.Transfer) (*chain.Confirmation, string, error) {
+	clock := v2.ClockOrSystem(c.clock)
+	deadline := clock.Now().Add(c.wait)
+	for {
+		confirmation, err := c.chain.ConfirmTransaction(ctx, c.network, transaction, expected)
+		var reason string
+		switch {
+		case err == nil:
+			return confirmation, "", nil
+		case errors.Is(err, chain.ErrTxNotFound):
+			reason = ReasonNotFound
+		case errors.Is(err, chain.ErrNotConfirmed):
+			reason = ReasonNotConfirmed
+		case errors.Is(err, chain.ErrTxFailed):
+			return nil, ReasonReverted, nil
+		case errors.Is(err, chain.ErrTransferNotFound):
+			return nil, ReasonTransferNotFound, nil
+		case errors.Is(err, chain.ErrUnsupportedNetwork):
+			return nil, ReasonUnsupported, nil
+		default:
+			return nil, "", err
+		}
+
+		if !clock.Now().Before(deadline) {
+			return nil, reason, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		case <-clock.After(c.poll):
+		}
+	}
+}
+
+// recoverSigner returns the address that signed the EIP-191 message hash.
+func recoverSigner(hash common.Hash, signature []byte) (common.Address, error) {
+	sig := append([]byte(nil), signature...)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	key, err := crypto.SigToPub(accounts.TextHash(hash.Bytes()), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*key), nil
+}
+
+// decodePayload decodes a v2.DirectPayload, typed or from its JSON form.
+func decodePayload(payload interface{}) (v2.DirectPayload, error) {
+	if direct, ok := payload.(v2.DirectPayload); ok {
+		return direct, nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return v2.DirectPayload{}, err
+	}
+	var direct v2.DirectPayload
+	if err := json.Unmarshal(data, &direct); err != nil {
+		return v2.DirectPayload{}, err
+	}
+	if strings.TrimSpace(direct.Transaction) == "" {
+		return v2.DirectPayload{}, errors.New("missing transaction")
+	}
+	return direct, nil
+}
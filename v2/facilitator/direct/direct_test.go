@@ -0,0 +1,194 @@
+package direct
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/chain"
+)
+
+const (
+	testNetwork = "eip155:84532"
+	testAsset   = "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
+	testPayTo   = "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+)
+
+// transferTopic is the topic of the ERC-20 Transfer(address,address,uint256) event.
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// fakeChain is a chain.EVMReader serving fixed receipts.
+type fakeChain struct {
+	head     uint64
+	receipts map[common.Hash]*types.Receipt
+	times    map[uint64]uint64
+}
+
+func (c *fakeChain) TransactionReceipt(_ context.Context, hash common.Hash) (*types.Receipt, error) {
+	receipt, ok := c.receipts[hash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func (c *fakeChain) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	n := c.head
+	if number != nil {
+		n = number.Uint64()
+	}
+	return &types.Header{Number: new(big.Int).SetUint64(n), Time: c.times[n]}, nil
+}
+
+// transferReceipt returns a receipt of a transfer of value from from to to.
+func transferReceipt(block uint64, status uint64, asset, from, to common.Address, value int64) *types.Receipt {
+	return &types.Receipt{
+		Status:      status,
+		BlockNumber: new(big.Int).SetUint64(block),
+		Logs: []*types.Log{{
+			Address: asset,
+			Topics:  []common.Hash{transferTopic, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+			Data:    common.LeftPadBytes(big.NewInt(value).Bytes(), 32),
+		}},
+	}
+}
+
+func TestConfirmer(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	payer, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	payerAddress := crypto.PubkeyToAddress(payer.PublicKey)
+	asset, payTo := common.HexToAddress(testAsset), common.HexToAddress(testPayTo)
+
+	signHash := func(hash common.Hash, byOther bool) string {
+		key := payer
+		if byOther {
+			key = other
+		}
+		signature, err := crypto.Sign(accounts.TextHash(hash.Bytes()), key)
+		if err != nil {
+			t.Fatalf("signing: %v", err)
+		}
+		signature[crypto.RecoveryIDOffset] += 27
+		return hexutil.Encode(signature)
+	}
+
+	client := &fakeChain{
+		head:     105,
+		receipts: map[common.Hash]*types.Receipt{},
+		times:    map[uint64]uint64{100: uint64(now.Add(-time.Minute).Unix()), 90: uint64(now.Add(-time.Hour).Unix()), 105: uint64(now.Unix())},
+	}
+	hashes := map[string]common.Hash{}
+	addTx := func(name string, receipt *types.Receipt) {
+		hash := crypto.Keccak256Hash([]byte(name))
+		hashes[name] = hash
+		if receipt != nil {
+			client.receipts[hash] = receipt
+		}
+	}
+	addTx("valid", transferReceipt(100, types.ReceiptStatusSuccessful, asset, payerAddress, payTo, 1000))
+	addTx("reverted", transferReceipt(100, types.ReceiptStatusFailed, asset, payerAddress, payTo, 1000))
+	addTx("too small", transferReceipt(100, types.ReceiptStatusSuccessful, asset, payerAddress, payTo, 999))
+	addTx("wrong recipient", transferReceipt(100, types.ReceiptStatusSuccessful, asset, payerAddress, asset, 1000))
+	addTx("wrong asset", transferReceipt(100, types.ReceiptStatusSuccessful, payTo, payerAddress, payTo, 1000))
+	addTx("old", transferReceipt(90, types.ReceiptStatusSuccessful, asset, payerAddress, payTo, 1000))
+	addTx("unconfirmed", transferReceipt(105, types.ReceiptStatusSuccessful, asset, payerAddress, payTo, 1000))
+	addTx("pending", nil)
+
+	confirmer := New(testNetwork, chain.NewEVM(testNetwork, client, chain.WithConfirmations(3)), WithWait(0, time.Millisecond), WithClock(v2.NewFakeClock(now)))
+	requirement := v2.PaymentRequirements{Scheme: v2.SchemeDirect, Network: testNetwork, Asset: testAsset, PayTo: testPayTo, Amount: "1000"}
+	payment := func(tx string, byOther bool) v2.PaymentPayload {
+		hash := hashes[tx]
+		return v2.PaymentPayload{
+			X402Version: v2.X402Version,
+			Accepted:    requirement,
+			Payload:     map[string]interface{}{"transaction": hash.Hex(), "signature": signHash(hash, byOther)},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		payment v2.PaymentPayload
+		reason  string
+	}{
+		{"valid", payment("valid", false), ""},
+		{"reverted", payment("reverted", false), ReasonReverted},
+		{"amount too small", payment("too small", false), ReasonTransferNotFound},
+		{"wrong recipient", payment("wrong recipient", false), ReasonTransferNotFound},
+		{"wrong asset", payment("wrong asset", false), ReasonTransferNotFound},
+		{"too old", payment("old", false), ReasonExpired},
+		{"not confirmed", payment("unconfirmed", false), ReasonNotConfirmed},
+		{"not mined", payment("pending", false), ReasonNotFound},
+		{"presented by another account", payment("valid", true), ReasonInvalidSignature},
+		{"malformed", v2.PaymentPayload{Accepted: requirement, Payload: map[string]interface{}{"transaction": "0x12"}}, ReasonMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := confirmer.Verify(context.Background(), tt.payment, requirement)
+			if err != nil {
+				t.Fatalf("Verify failed: %v", err)
+			}
+			if resp.IsValid != (tt.reason == "") || resp.InvalidReason != tt.reason {
+				t.Errorf("Verify = %+v, want reason %q", resp, tt.reason)
+			}
+		})
+	}
+
+	// A transaction pays for one request only
+	settlement, err := confirmer.Settle(context.Background(), payment("valid", false), requirement)
+	if err != nil || !settlement.Success {
+		t.Fatalf("Settle = %+v, %v", settlement, err)
+	}
+	if settlement.Payer != payerAddress.Hex() || settlement.Transaction != hashes["valid"].Hex() || settlement.Amount != "1000" {
+		t.Errorf("unexpected settlement %+v", settlement)
+	}
+	if resp, _ := confirmer.Verify(context.Background(), payment("valid", false), requirement); resp.InvalidReason != ReasonAlreadyUsed {
+		t.Errorf("expected used transaction to be refused, got %+v", resp)
+	}
+	if settlement, _ := confirmer.Settle(context.Background(), payment("valid", false), requirement); settlement.Success || settlement.ErrorReason != ReasonAlreadyUsed {
+		t.Errorf("expected used transaction not to settle again, got %+v", settlement)
+	}
+}
+
+func TestConfirmer_WaitsForConfirmation(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := v2.NewFakeClock(now)
+	payer, _ := crypto.GenerateKey()
+	hash := crypto.Keccak256Hash([]byte("tx"))
+	signature, _ := crypto.Sign(accounts.TextHash(hash.Bytes()), payer)
+
+	client := &fakeChain{head: 100, receipts: map[common.Hash]*types.Receipt{}, times: map[uint64]uint64{100: uint64(now.Unix())}}
+	confirmer := New(testNetwork, chain.NewEVM(testNetwork, client), WithWait(time.Minute, time.Second), WithClock(clock))
+	requirement := v2.PaymentRequirements{Scheme: v2.SchemeDirect, Network: testNetwork, Asset: testAsset, PayTo: testPayTo, Amount: "1"}
+	payment := v2.PaymentPayload{
+		Accepted: requirement,
+		Payload:  v2.DirectPayload{Transaction: hash.Hex(), Signature: hexutil.Encode(signature)},
+	}
+
+	done := make(chan *v2.VerifyResponse, 1)
+	go func() {
+		resp, err := confirmer.Verify(context.Background(), payment, requirement)
+		if err != nil {
+			t.Errorf("Verify failed: %v", err)
+		}
+		done <- resp
+	}()
+
+	// The transaction is mined while Verify waits
+	clock.BlockUntil(1)
+	client.receipts[hash] = transferReceipt(100, types.ReceiptStatusSuccessful, common.HexToAddress(testAsset), crypto.PubkeyToAddress(payer.PublicKey), common.HexToAddress(testPayTo), 1)
+	clock.Advance(time.Second)
+
+	if resp := <-done; !resp.IsValid {
+		t.Errorf("expected payment mined during the wait to be valid, got %+v", resp)
+	}
+}
@@ -0,0 +1,109 @@
+package faucet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// maxBalanceSlot bounds the storage slots probed for a token's balances
+// mapping.
+const maxBalanceSlot = 20
+
+// balanceOfSelector is the selector of balanceOf(address).
+var balanceOfSelector = crypto.Keccak256([]byte("balanceOf(address)"))[:4]
+
+// evmAddresser is implemented by EVM signers.
+type evmAddresser interface {
+	Address() common.Address
+}
+
+// fundEVM sets the balance of the signer's account on the anvil node.
+func (f *Faucet) fundEVM(ctx context.Context, signer v2.Signer, asset string, amount *big.Int) error {
+	addresser, ok := signer.(evmAddresser)
+	if !ok {
+		return ErrNoAddress
+	}
+	account := addresser.Address()
+	faucet := circleFaucet
+	if asset == AssetNative {
+		faucet = baseSepoliaFaucet
+	}
+	if f.evmRPC == "" {
+		return manualFunding(signer.Network(), account.Hex(), faucet, "no anvil node configured")
+	}
+	if asset != AssetNative && !common.IsHexAddress(asset) {
+		return fmt.Errorf("faucet: invalid token address %q", asset)
+	}
+
+	client, err := gethrpc.DialOptions(ctx, f.evmRPC, gethrpc.WithHTTPClient(f.httpClient))
+	if err != nil {
+		return fmt.Errorf("faucet: dialing EVM node: %w", err)
+	}
+	defer client.Close()
+
+	if asset == AssetNative {
+		err = client.CallContext(ctx, nil, "anvil_setBalance", account, hexutil.EncodeBig(amount))
+	} else {
+		err = setTokenBalance(ctx, client, common.HexToAddress(asset), account, amount)
+	}
+	if isMethodNotFound(err) {
+		return manualFunding(signer.Network(), account.Hex(), faucet, "node is not anvil")
+	}
+	return err
+}
+
+// setTokenBalance writes the token balance of account directly to storage. The
+// balances mapping slot is found by probing the first slots with balanceOf,
+// restoring each slot that is not it.
+func setTokenBalance(ctx context.Context, client *gethrpc.Client, token, account common.Address, amount *big.Int) error {
+	value := common.BigToHash(amount)
+	for slot := int64(0); slot < maxBalanceSlot; slot++ {
+		key := crypto.Keccak256Hash(common.LeftPadBytes(account.Bytes(), 32), common.BigToHash(big.NewInt(slot)).Bytes())
+
+		var previous common.Hash
+		if err := client.CallContext(ctx, &previous, "eth_getStorageAt", token, key, "latest"); err != nil {
+			return fmt.Errorf("faucet: reading storage of %s: %w", token, err)
+		}
+		if err := client.CallContext(ctx, nil, "anvil_setStorageAt", token, key, value); err != nil {
+			return err
+		}
+		balance, err := tokenBalance(ctx, client, token, account)
+		if err != nil {
+			return err
+		}
+		if balance.Cmp(amount) == 0 {
+			return nil
+		}
+		if err := client.CallContext(ctx, nil, "anvil_setStorageAt", token, key, previous); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("faucet: could not find the balances slot of token %s", token)
+}
+
+// tokenBalance returns the token balance of account.
+func tokenBalance(ctx context.Context, client *gethrpc.Client, token, account common.Address) (*big.Int, error) {
+	call := map[string]interface{}{
+		"to":   token,
+		"data": hexutil.Bytes(append(append([]byte(nil), balanceOfSelector...), common.LeftPadBytes(account.Bytes(), 32)...)),
+	}
+	var result hexutil.Bytes
+	if err := client.CallContext(ctx, &result, "eth_call", call, "latest"); err != nil {
+		return nil, fmt.Errorf("faucet: balanceOf on %s: %w", token, err)
+	}
+	return new(big.Int).SetBytes(result), nil
+}
+
+// isMethodNotFound reports whether err is a JSON-RPC "method not found" error.
+func isMethodNotFound(err error) bool {
+	var rpcErr gethrpc.Error
+	return errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601
+}
@@ -0,0 +1,190 @@
+// Package faucet funds development accounts on test networks, so examples and
+// integration tests can pay for themselves.
+//
+// A Faucet funds the account of a signer on its network. On a local anvil
+// node, ETH and ERC-20 balances are written directly with anvil_setBalance and
+// anvil_setStorageAt. On Solana devnet and solana-test-validator, SOL is
+// requested with requestAirdrop, and SPL tokens whose mint authority is given
+// with WithMintAuthority are minted. Public faucets for Base Sepolia and for
+// Circle USDC need a browser; funding from them fails with ErrManualFunding,
+// whose message says where to go.
+//
+// The package is meant for development and tests only: it never funds
+// mainnet accounts.
+package faucet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+var (
+	// ErrUnsupportedNetwork is returned for networks the faucet cannot fund,
+	// including every mainnet.
+	ErrUnsupportedNetwork = errors.New("faucet: unsupported network")
+
+	// ErrManualFunding is returned when the account can only be funded by
+	// hand, e.g. from a public faucet behind a captcha.
+	ErrManualFunding = errors.New("faucet: manual funding required")
+
+	// ErrNoAddress is returned when the signer does not expose its address.
+	ErrNoAddress = errors.New("faucet: signer has no address")
+)
+
+// AssetNative is the asset of Fund requesting the network's native currency,
+// ETH or SOL.
+const AssetNative = "native"
+
+// SolanaDevnetRPC is the public Solana devnet RPC endpoint, used by default
+// for Solana devnet.
+const SolanaDevnetRPC = "https://api.devnet.solana.com"
+
+// Public faucets, named in ErrManualFunding errors.
+const (
+	circleFaucet      = "https://faucet.circle.com"
+	baseSepoliaFaucet = "https://docs.base.org/base-chain/tools/network-faucets"
+	solanaFaucet      = "https://faucet.solana.com"
+)
+
+// Default amounts in atomic units, used when Fund is given no amount.
+var (
+	defaultETH  = big.NewInt(1e18)
+	defaultSOL  = big.NewInt(1e9)
+	defaultUSDC = big.NewInt(100_000_000)
+)
+
+// Faucet funds development accounts. The zero value is not usable; create
+// one with New.
+type Faucet struct {
+	evmRPC        string
+	svmRPC        string
+	mintAuthority []byte
+	httpClient    *http.Client
+	timeout       time.Duration
+}
+
+// Option configures a Faucet.
+type Option func(*Faucet)
+
+// WithEVMNode funds EVM accounts through the anvil node at rpcURL. Without
+// it, EVM funding fails with ErrManualFunding.
+func WithEVMNode(rpcURL string) Option {
+	return func(f *Faucet) {
+		f.evmRPC = rpcURL
+	}
+}
+
+// WithSolanaNode funds Solana accounts through the node at rpcURL, e.g. a
+// local solana-test-validator, instead of SolanaDevnetRPC.
+func WithSolanaNode(rpcURL string) Option {
+	return func(f *Faucet) {
+		f.svmRPC = rpcURL
+	}
+}
+
+// WithMintAuthority mints SPL tokens whose mint authority is the 64-byte
+// Solana private key, e.g. a token created on a local validator for tests.
+// Its account pays for the token accounts it creates.
+func WithMintAuthority(privateKey []byte) Option {
+	return func(f *Faucet) {
+		f.mintAuthority = append([]byte(nil), privateKey...)
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for RPC calls.
+func WithHTTPClient(client *http.Client) Option {
+	return func(f *Faucet) {
+		f.httpClient = client
+	}
+}
+
+// WithTimeout bounds how long Fund takes on Solana, including waiting for
+// its transaction to confirm.
+// The default is one minute.
+func WithTimeout(timeout time.Duration) Option {
+	return func(f *Faucet) {
+		f.timeout = timeout
+	}
+}
+
+// New creates a Faucet.
+func New(opts ...Option) *Faucet {
+	f := &Faucet{
+		svmRPC:     SolanaDevnetRPC,
+		httpClient: http.DefaultClient,
+		timeout:    time.Minute,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Fund gives the account of signer amount of asset on the signer's network.
+// Asset is AssetNative, "usdc" for the network's USDC, or a token address. A
+// nil amount funds 1 ETH, 1 SOL, or 100 tokens of 6 decimals. On anvil the
+// balance is set to amount; airdrops and mints add it to the balance.
+func (f *Faucet) Fund(ctx context.Context, signer v2.Signer, asset string, amount *big.Int) error {
+	network := signer.Network()
+	networkType, err := v2.ValidateNetwork(network)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUnsupportedNetwork, network)
+	}
+	if isMainnet(network) {
+		return fmt.Errorf("%w: %s is a mainnet", ErrUnsupportedNetwork, network)
+	}
+	if strings.EqualFold(asset, "usdc") {
+		chain, err := v2.GetChainConfig(network)
+		if err != nil || chain.USDCAddress == "" {
+			return fmt.Errorf("%w: no USDC on %s", ErrUnsupportedNetwork, network)
+		}
+		asset = chain.USDCAddress
+	}
+	if amount == nil {
+		amount = defaultAmount(networkType, asset)
+	}
+	if amount.Sign() <= 0 {
+		return fmt.Errorf("faucet: amount must be positive")
+	}
+
+	switch networkType {
+	case v2.NetworkTypeEVM:
+		return f.fundEVM(ctx, signer, asset, amount)
+	case v2.NetworkTypeSVM:
+		return f.fundSVM(ctx, signer, asset, amount)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedNetwork, network)
+	}
+}
+
+// isMainnet reports whether network is a known mainnet.
+func isMainnet(network string) bool {
+	switch network {
+	case v2.NetworkBase, v2.NetworkPolygon, v2.NetworkAvalanche, v2.NetworkEthereum, v2.NetworkSolanaMainnet:
+		return true
+	}
+	return false
+}
+
+// defaultAmount returns the amount funded when Fund is given none.
+func defaultAmount(networkType v2.NetworkType, asset string) *big.Int {
+	if asset != AssetNative {
+		return new(big.Int).Set(defaultUSDC)
+	}
+	if networkType == v2.NetworkTypeSVM {
+		return new(big.Int).Set(defaultSOL)
+	}
+	return new(big.Int).Set(defaultETH)
+}
+
+// manualFunding returns an ErrManualFunding error pointing at a public faucet.
+func manualFunding(network, address, faucet, reason string) error {
+	return fmt.Errorf("%w: %s; fund %s on %s from %s", ErrManualFunding, reason, address, network, faucet)
+}
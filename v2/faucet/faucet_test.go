@@ -0,0 +1,136 @@
+package faucet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// testSigner is an EVM signer on network.
+type testSigner struct {
+	network string
+	address common.Address
+}
+
+func (s testSigner) Network() string                                          { return s.network }
+func (s testSigner) Scheme() string                                           { return "exact" }
+func (s testSigner) GetPriority() int                                         { return 0 }
+func (s testSigner) GetTokens() []v2.TokenConfig                              { return nil }
+func (s testSigner) GetMaxAmount() *big.Int                                   { return nil }
+func (s testSigner) CanSign(*v2.PaymentRequirements) bool                     { return true }
+func (s testSigner) Sign(*v2.PaymentRequirements) (*v2.PaymentPayload, error) { return nil, nil }
+func (s testSigner) Address() common.Address                                  { return s.address }
+
+// fakeAnvil serves the anvil methods used by the faucet, for a token keeping
+// its balances mapping at slot 9 like USDC. Without anvil methods, they fail
+// as unknown.
+func fakeAnvil(t *testing.T, anvil bool) (*httptest.Server, map[common.Address]*big.Int, map[common.Hash]common.Hash) {
+	t.Helper()
+	balances := make(map[common.Address]*big.Int)
+	storage := make(map[common.Hash]common.Hash)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("bad request: %v", err)
+			return
+		}
+		reply := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		switch {
+		case req.Method == "anvil_setBalance" && anvil:
+			var account common.Address
+			var amount hexutil.Big
+			_ = json.Unmarshal(req.Params[0], &account)
+			_ = json.Unmarshal(req.Params[1], &amount)
+			balances[account] = amount.ToInt()
+			reply["result"] = nil
+		case req.Method == "anvil_setStorageAt" && anvil:
+			var key, value common.Hash
+			_ = json.Unmarshal(req.Params[1], &key)
+			_ = json.Unmarshal(req.Params[2], &value)
+			storage[key] = value
+			reply["result"] = true
+		case req.Method == "eth_getStorageAt":
+			var key common.Hash
+			_ = json.Unmarshal(req.Params[1], &key)
+			reply["result"] = storage[key]
+		case req.Method == "eth_call":
+			var call struct {
+				Data hexutil.Bytes `json:"data"`
+			}
+			_ = json.Unmarshal(req.Params[0], &call)
+			key := crypto.Keccak256Hash(call.Data[4:], common.BigToHash(big.NewInt(9)).Bytes())
+			value := storage[key]
+			reply["result"] = hexutil.Bytes(value.Bytes())
+		default:
+			reply["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+		}
+		_ = json.NewEncoder(w).Encode(reply)
+	}))
+	t.Cleanup(server.Close)
+	return server, balances, storage
+}
+
+func TestFundEVM(t *testing.T) {
+	ctx := context.Background()
+	account := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	signer := testSigner{network: v2.NetworkBaseSepolia, address: account}
+
+	t.Run("anvil", func(t *testing.T) {
+		server, balances, storage := fakeAnvil(t, true)
+		faucet := New(WithEVMNode(server.URL))
+
+		if err := faucet.Fund(ctx, signer, AssetNative, nil); err != nil {
+			t.Fatalf("Fund(native) failed: %v", err)
+		}
+		if balances[account].Cmp(big.NewInt(1e18)) != 0 {
+			t.Errorf("ETH balance = %v, want 1e18", balances[account])
+		}
+
+		if err := faucet.Fund(ctx, signer, "usdc", big.NewInt(5_000_000)); err != nil {
+			t.Fatalf("Fund(usdc) failed: %v", err)
+		}
+		key := crypto.Keccak256Hash(common.LeftPadBytes(account.Bytes(), 32), common.BigToHash(big.NewInt(9)).Bytes())
+		if got := storage[key].Big(); got.Cmp(big.NewInt(5_000_000)) != 0 {
+			t.Errorf("USDC balance = %v, want 5000000", got)
+		}
+		for slot, value := range storage {
+			if slot != key && value != (common.Hash{}) {
+				t.Errorf("probed slot %s not restored", slot)
+			}
+		}
+	})
+
+	plainNode, _, _ := fakeAnvil(t, false)
+	tests := []struct {
+		name    string
+		faucet  *Faucet
+		signer  v2.Signer
+		asset   string
+		wantErr error
+	}{
+		{"no node", New(), signer, AssetNative, ErrManualFunding},
+		{"not anvil", New(WithEVMNode(plainNode.URL)), signer, "usdc", ErrManualFunding},
+		{"mainnet", New(), testSigner{network: v2.NetworkBase, address: account}, AssetNative, ErrUnsupportedNetwork},
+		{"no usdc", New(), testSigner{network: "eip155:31337", address: account}, "usdc", ErrUnsupportedNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.faucet.Fund(ctx, tt.signer, tt.asset, nil); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Fund() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
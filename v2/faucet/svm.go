@@ -0,0 +1,121 @@
+package faucet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// svmAddresser is implemented by Solana signers.
+type svmAddresser interface {
+	Address() solana.PublicKey
+}
+
+// fundSVM airdrops SOL to the signer's account, or mints it tokens with the
+// mint authority.
+func (f *Faucet) fundSVM(ctx context.Context, signer v2.Signer, asset string, amount *big.Int) error {
+	addresser, ok := signer.(svmAddresser)
+	if !ok {
+		return ErrNoAddress
+	}
+	account := addresser.Address()
+	if !amount.IsUint64() {
+		return fmt.Errorf("faucet: amount %s out of range", amount)
+	}
+
+	client := rpc.NewWithCustomRPCClient(jsonrpc.NewClientWithOpts(f.svmRPC, &jsonrpc.RPCClientOpts{HTTPClient: f.httpClient}))
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	if asset == AssetNative {
+		signature, err := client.RequestAirdrop(ctx, account, amount.Uint64(), rpc.CommitmentConfirmed)
+		if err == nil {
+			err = waitForSignature(ctx, client, signature)
+		}
+		if err != nil && f.svmRPC == SolanaDevnetRPC {
+			return manualFunding(signer.Network(), account.String(), solanaFaucet, fmt.Sprintf("airdrop failed: %v", err))
+		}
+		return err
+	}
+
+	mint, err := solana.PublicKeyFromBase58(asset)
+	if err != nil {
+		return fmt.Errorf("faucet: invalid mint %q: %w", asset, err)
+	}
+	if f.mintAuthority == nil {
+		return manualFunding(signer.Network(), account.String(), circleFaucet, "no mint authority configured")
+	}
+	return mintTo(ctx, client, solana.PrivateKey(f.mintAuthority), mint, account, amount.Uint64())
+}
+
+// mintTo mints amount of mint to the associated token account of owner,
+// creating it if needed.
+func mintTo(ctx context.Context, client *rpc.Client, authority solana.PrivateKey, mint, owner solana.PublicKey, amount uint64) error {
+	ata, _, err := solana.FindAssociatedTokenAddress(owner, mint)
+	if err != nil {
+		return fmt.Errorf("faucet: deriving token account: %w", err)
+	}
+
+	var instructions []solana.Instruction
+	if _, err := client.GetAccountInfo(ctx, ata); errors.Is(err, rpc.ErrNotFound) {
+		instructions = append(instructions, associatedtokenaccount.NewCreateInstruction(authority.PublicKey(), owner, mint).Build())
+	} else if err != nil {
+		return fmt.Errorf("faucet: reading token account: %w", err)
+	}
+	instructions = append(instructions, token.NewMintToInstruction(amount, mint, ata, authority.PublicKey(), nil).Build())
+
+	recent, err := client.GetLatestBlockhash(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("faucet: getting blockhash: %w", err)
+	}
+	tx, err := solana.NewTransaction(instructions, recent.Value.Blockhash, solana.TransactionPayer(authority.PublicKey()))
+	if err != nil {
+		return fmt.Errorf("faucet: building transaction: %w", err)
+	}
+	if _, err := tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(authority.PublicKey()) {
+			return &authority
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("faucet: signing transaction: %w", err)
+	}
+	signature, err := client.SendTransaction(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("faucet: sending transaction: %w", err)
+	}
+	return waitForSignature(ctx, client, signature)
+}
+
+// waitForSignature polls until the transaction is confirmed or ctx is done.
+func waitForSignature(ctx context.Context, client *rpc.Client, signature solana.Signature) error {
+	for {
+		statuses, err := client.GetSignatureStatuses(ctx, false, signature)
+		if err != nil {
+			return err
+		}
+		if len(statuses.Value) == 1 && statuses.Value[0] != nil {
+			status := statuses.Value[0]
+			if status.Err != nil {
+				return fmt.Errorf("faucet: transaction %s failed: %v", signature, status.Err)
+			}
+			if status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed || status.ConfirmationStatus == rpc.ConfirmationStatusFinalized {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("faucet: waiting for %s: %w", signature, ctx.Err())
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
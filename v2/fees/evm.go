@@ -0,0 +1,157 @@
+package fees
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/eip3009"
+)
+
+// DefaultGasOverhead is the gas EVM adds to the estimate for the balance
+// updates its zero-value probe transfer does not make.
+const DefaultGasOverhead = 10_000
+
+// selectorTransferWithAuthorization is the function selector of EIP-3009
+// transferWithAuthorization(address,address,uint256,uint256,uint256,bytes32,uint8,bytes32,bytes32).
+var selectorTransferWithAuthorization = crypto.Keccak256([]byte("transferWithAuthorization(address,address,uint256,uint256,uint256,bytes32,uint8,bytes32,bytes32)"))[:4]
+
+// EVMBackend is the RPC access EVM needs. It is satisfied by *ethclient.Client.
+type EVMBackend interface {
+	EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// EVM estimates the fee of settling "exact" payments on one EVM network with
+// transferWithAuthorization. The gas is estimated with eth_estimateGas for a
+// zero-value authorization signed by a throwaway account, plus an overhead for
+// the balance updates a real transfer makes, at the latest base fee plus the
+// suggested priority fee.
+type EVM struct {
+	network  string
+	backend  EVMBackend
+	overhead uint64
+}
+
+// EVMOption configures an EVM estimator.
+type EVMOption func(*EVM)
+
+// WithGasOverhead sets the gas added to the eth_estimateGas result (default:
+// DefaultGasOverhead).
+func WithGasOverhead(gas uint64) EVMOption {
+	return func(e *EVM) {
+		e.overhead = gas
+	}
+}
+
+// NewEVM creates an estimator for network, querying the chain through backend.
+func NewEVM(network string, backend EVMBackend, opts ...EVMOption) *EVM {
+	e := &EVM{network: network, backend: backend, overhead: DefaultGasOverhead}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// EstimateFee implements Estimator. The requirement's Extra must hold the
+// token's EIP-712 name and version, as for signing.
+func (e *EVM) EstimateFee(ctx context.Context, requirement v2.PaymentRequirements) (*Estimate, error) {
+	if requirement.Network != e.network {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedNetwork, requirement.Network)
+	}
+	if requirement.Scheme != v2.SchemeExact {
+		return nil, fmt.Errorf("%w: %s", v2.ErrUnsupportedScheme, requirement.Scheme)
+	}
+	if !common.IsHexAddress(requirement.Asset) || !common.IsHexAddress(requirement.PayTo) {
+		return nil, fmt.Errorf("%w: invalid asset or payTo", v2.ErrInvalidRequirements)
+	}
+
+	// The authorization window is checked against block time, so it uses
+	// the system clock
+	now := time.Now()
+	data, err := e.probeCall(requirement, now)
+	if err != nil {
+		return nil, err
+	}
+	asset := common.HexToAddress(requirement.Asset)
+	gas, err := e.backend.EstimateGas(ctx, ethereum.CallMsg{To: &asset, Data: data})
+	if err != nil {
+		return nil, fmt.Errorf("estimating gas: %w", err)
+	}
+	gas += e.overhead
+
+	tip, err := e.backend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting gas tip: %w", err)
+	}
+	head, err := e.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("getting latest header: %w", err)
+	}
+	price := new(big.Int).Set(tip)
+	if head.BaseFee != nil {
+		price.Add(price, head.BaseFee)
+	}
+
+	return &Estimate{
+		Network:   e.network,
+		Fee:       new(big.Int).Mul(price, new(big.Int).SetUint64(gas)).String(),
+		Currency:  "wei",
+		Units:     gas,
+		UnitPrice: price.String(),
+		Time:      now,
+	}, nil
+}
+
+// probeCall returns the calldata of a zero-value transferWithAuthorization to
+// the requirement's payTo, signed by a throwaway account.
+func (e *EVM) probeCall(requirement v2.PaymentRequirements, now time.Time) ([]byte, error) {
+	chainID, err := v2.GetChainID(e.network)
+	if err != nil {
+		return nil, err
+	}
+	name, _ := requirement.Extra["name"].(string)
+	version, _ := requirement.Extra["version"].(string)
+	if name == "" || version == "" {
+		return nil, fmt.Errorf("%w: missing EIP-712 name or version", v2.ErrInvalidRequirements)
+	}
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	auth, err := eip3009.CreateAuthorizationWithWindow(crypto.PubkeyToAddress(key.PublicKey), common.HexToAddress(requirement.PayTo), big.NewInt(0), now.Add(-eip3009.DefaultValidAfterBackdate), now.Add(time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	signature, err := eip3009.SignAuthorization(key, common.HexToAddress(requirement.Asset), big.NewInt(chainID), auth, name, version)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := hexutil.Decode(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, 4+9*32)
+	data = append(data, selectorTransferWithAuthorization...)
+	data = append(data, common.LeftPadBytes(auth.From.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(auth.To.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(auth.Value.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(auth.ValidAfter.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(auth.ValidBefore.Bytes(), 32)...)
+	data = append(data, auth.Nonce[:]...)
+	data = append(data, common.LeftPadBytes(sig[64:], 32)...)
+	data = append(data, sig[:32]...)
+	data = append(data, sig[32:64]...)
+	return data, nil
+}
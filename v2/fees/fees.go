@@ -0,0 +1,60 @@
+// Package fees estimates the network fee of settling a payment, so sellers
+// can price endpoints above what settlement costs. EVM estimates the gas of
+// a transferWithAuthorization call through an Ethereum JSON-RPC node and SVM
+// the signature and priority fees of an SPL token transfer.
+//
+// Fees are in the atomic unit of the network's native currency (wei on EVM,
+// lamports on Solana); they are paid by the facilitator, which may pass them
+// on to the seller.
+package fees
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// ErrUnsupportedNetwork indicates the estimator does not serve the network.
+var ErrUnsupportedNetwork = errors.New("fees: unsupported network")
+
+// Estimate is the estimated fee of settling a payment.
+type Estimate struct {
+	// Network is the CAIP-2 network of the payment.
+	Network string `json:"network"`
+
+	// Fee is the estimated fee in atomic units of Currency.
+	Fee string `json:"fee"`
+
+	// Currency is the unit of Fee: "wei" or "lamports".
+	Currency string `json:"currency"`
+
+	// Units is the gas (EVM) or compute unit limit (SVM) of the settlement.
+	Units uint64 `json:"units"`
+
+	// UnitPrice is the price per unit in wei (EVM) or micro-lamports (SVM).
+	UnitPrice string `json:"unitPrice"`
+
+	// Time is when the estimate was made.
+	Time time.Time `json:"time"`
+}
+
+// Estimator estimates settlement fees on one or more networks.
+type Estimator interface {
+	// EstimateFee estimates the fee of settling a payment of requirement.
+	EstimateFee(ctx context.Context, requirement v2.PaymentRequirements) (*Estimate, error)
+}
+
+// Networks is an Estimator routing each network to its own Estimator.
+type Networks map[string]Estimator
+
+// EstimateFee estimates the fee with the Estimator of the requirement's network.
+func (n Networks) EstimateFee(ctx context.Context, requirement v2.PaymentRequirements) (*Estimate, error) {
+	estimator, ok := n[requirement.Network]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedNetwork, requirement.Network)
+	}
+	return estimator.EstimateFee(ctx, requirement)
+}
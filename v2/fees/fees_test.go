@@ -0,0 +1,135 @@
+package fees
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gagliardetto/solana-go"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+const (
+	testEVMNetwork = "eip155:84532"
+	testSVMNetwork = "solana:EtWTRABZaYq6iMfeYKouRu166VU2xqa1"
+)
+
+// fakeBackend is an EVMBackend with fixed gas and prices.
+type fakeBackend struct {
+	gas     uint64
+	tip     *big.Int
+	baseFee *big.Int
+	call    ethereum.CallMsg
+}
+
+func (b *fakeBackend) EstimateGas(_ context.Context, call ethereum.CallMsg) (uint64, error) {
+	b.call = call
+	return b.gas, nil
+}
+
+func (b *fakeBackend) SuggestGasTipCap(context.Context) (*big.Int, error) {
+	return b.tip, nil
+}
+
+func (b *fakeBackend) HeaderByNumber(context.Context, *big.Int) (*types.Header, error) {
+	return &types.Header{BaseFee: b.baseFee}, nil
+}
+
+// fixedFees is a PriorityFees with a fixed price.
+type fixedFees uint64
+
+func (f fixedFees) EstimateComputeUnitPrice(context.Context, []solana.PublicKey) (uint64, error) {
+	return uint64(f), nil
+}
+
+func TestEVM_EstimateFee(t *testing.T) {
+	requirement := v2.PaymentRequirements{
+		Scheme:  v2.SchemeExact,
+		Network: testEVMNetwork,
+		Amount:  "10000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		Extra:   map[string]interface{}{"name": "USDC", "version": "2"},
+	}
+	backend := &fakeBackend{gas: 60_000, tip: big.NewInt(1_000_000), baseFee: big.NewInt(9_000_000)}
+	estimator := NewEVM(testEVMNetwork, backend, WithGasOverhead(5_000))
+
+	estimate, err := estimator.EstimateFee(context.Background(), requirement)
+	if err != nil {
+		t.Fatalf("EstimateFee failed: %v", err)
+	}
+	if estimate.Units != 65_000 || estimate.UnitPrice != "10000000" || estimate.Fee != "650000000000" || estimate.Currency != "wei" {
+		t.Errorf("unexpected estimate %+v", estimate)
+	}
+	if len(backend.call.Data) != 4+9*32 || !bytes.Equal(backend.call.Data[:4], selectorTransferWithAuthorization) {
+		t.Errorf("expected a transferWithAuthorization call, got %x", backend.call.Data)
+	}
+	if backend.call.To == nil || backend.call.To.Hex() != requirement.Asset {
+		t.Errorf("expected call to the asset, got %v", backend.call.To)
+	}
+
+	invalid := []v2.PaymentRequirements{
+		{Scheme: v2.SchemeExact, Network: "eip155:1", Asset: requirement.Asset, PayTo: requirement.PayTo, Extra: requirement.Extra},
+		{Scheme: v2.SchemeUpTo, Network: testEVMNetwork, Asset: requirement.Asset, PayTo: requirement.PayTo, Extra: requirement.Extra},
+		{Scheme: v2.SchemeExact, Network: testEVMNetwork, Asset: requirement.Asset, PayTo: requirement.PayTo},
+	}
+	for _, req := range invalid {
+		if _, err := estimator.EstimateFee(context.Background(), req); err == nil {
+			t.Errorf("expected error for %+v", req)
+		}
+	}
+}
+
+func TestSVM_EstimateFee(t *testing.T) {
+	requirement := v2.PaymentRequirements{
+		Scheme:  v2.SchemeExact,
+		Network: testSVMNetwork,
+		Amount:  "10000",
+		Asset:   "4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU",
+		PayTo:   "9xQeWvG816bUx9EPjHmaT23yvVM2ZWbrrpZb9PusVFin",
+	}
+
+	tests := []struct {
+		name  string
+		fees  PriorityFees
+		units uint32
+		want  string
+	}{
+		// 2 signatures + ceil(1000 * 200000 / 1e6)
+		{name: "estimated price", fees: fixedFees(1_000), want: "10200"},
+		// 2 signatures + ceil(10000 * 200000 / 1e6)
+		{name: "default price", want: "12000"},
+		// 2 signatures + ceil(3 * 100001 / 1e6)
+		{name: "rounded up", fees: fixedFees(3), units: 100_001, want: "10001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []SVMOption
+			if tt.units > 0 {
+				opts = append(opts, WithComputeUnits(tt.units))
+			}
+			estimate, err := NewSVM(testSVMNetwork, tt.fees, opts...).EstimateFee(context.Background(), requirement)
+			if err != nil {
+				t.Fatalf("EstimateFee failed: %v", err)
+			}
+			if estimate.Fee != tt.want || estimate.Currency != "lamports" {
+				t.Errorf("Fee = %s %s, want %s lamports", estimate.Fee, estimate.Currency, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetworks(t *testing.T) {
+	networks := Networks{testSVMNetwork: NewSVM(testSVMNetwork, nil)}
+	if _, err := networks.EstimateFee(context.Background(), v2.PaymentRequirements{Scheme: v2.SchemeExact, Network: testSVMNetwork}); err != nil {
+		t.Errorf("EstimateFee failed: %v", err)
+	}
+	if _, err := networks.EstimateFee(context.Background(), v2.PaymentRequirements{Network: testEVMNetwork}); !errors.Is(err, ErrUnsupportedNetwork) {
+		t.Errorf("expected ErrUnsupportedNetwork, got %v", err)
+	}
+}
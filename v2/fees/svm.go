@@ -0,0 +1,100 @@
+package fees
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	solutil "github.com/mark3labs/x402-go/v2/internal/solana"
+)
+
+// LamportsPerSignature is the base fee Solana charges per transaction
+// signature.
+const LamportsPerSignature = 5000
+
+// PriorityFees estimates the compute unit price of a transaction. It is
+// satisfied by svm.RecentFeeEstimator.
+type PriorityFees interface {
+	// EstimateComputeUnitPrice returns a price in micro-lamports per compute
+	// unit for a transaction that writes to the given accounts.
+	EstimateComputeUnitPrice(ctx context.Context, accounts []solana.PublicKey) (uint64, error)
+}
+
+// SVM estimates the fee of settling "exact" payments on one Solana network: a
+// signature fee for the payer and the fee payer, plus the priority fee of the
+// transaction's compute unit limit.
+type SVM struct {
+	network string
+	fees    PriorityFees
+	units   uint32
+}
+
+// SVMOption configures an SVM estimator.
+type SVMOption func(*SVM)
+
+// WithComputeUnits sets the compute unit limit of payment transactions
+// (default: the svm signer's default limit).
+func WithComputeUnits(units uint32) SVMOption {
+	return func(s *SVM) {
+		s.units = units
+	}
+}
+
+// NewSVM creates an estimator for network, pricing compute units with fees,
+// e.g. svm.NewRecentFeeEstimator(rpcClient, 0). If fees is nil, the svm
+// signer's default compute unit price is used.
+func NewSVM(network string, fees PriorityFees, opts ...SVMOption) *SVM {
+	s := &SVM{network: network, fees: fees, units: solutil.DefaultComputeUnits}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// EstimateFee implements Estimator.
+func (s *SVM) EstimateFee(ctx context.Context, requirement v2.PaymentRequirements) (*Estimate, error) {
+	if requirement.Network != s.network {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedNetwork, requirement.Network)
+	}
+	if requirement.Scheme != v2.SchemeExact {
+		return nil, fmt.Errorf("%w: %s", v2.ErrUnsupportedScheme, requirement.Scheme)
+	}
+
+	price := solutil.DefaultComputeUnitPrice
+	if s.fees != nil {
+		mint, err := solana.PublicKeyFromBase58(requirement.Asset)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid asset: %v", v2.ErrInvalidRequirements, err)
+		}
+		payTo, err := solana.PublicKeyFromBase58(requirement.PayTo)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid payTo: %v", v2.ErrInvalidRequirements, err)
+		}
+		destination, err := solutil.DeriveAssociatedTokenAddress(payTo, mint)
+		if err != nil {
+			return nil, err
+		}
+		if price, err = s.fees.EstimateComputeUnitPrice(ctx, []solana.PublicKey{destination}); err != nil {
+			return nil, fmt.Errorf("estimating priority fee: %w", err)
+		}
+	}
+
+	// Priority fees are charged in micro-lamports, rounded up
+	priority := new(big.Int).Mul(new(big.Int).SetUint64(price), big.NewInt(int64(s.units)))
+	priority.Add(priority, big.NewInt(999_999))
+	priority.Div(priority, big.NewInt(1_000_000))
+	fee := priority.Add(priority, big.NewInt(2*LamportsPerSignature))
+
+	return &Estimate{
+		Network:   s.network,
+		Fee:       fee.String(),
+		Currency:  "lamports",
+		Units:     uint64(s.units),
+		UnitPrice: fmt.Sprint(price),
+		Time:      time.Now(),
+	}, nil
+}
@@ -0,0 +1,150 @@
+package v2
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// RequirementsChange describes how the requirements of a resource differ from
+// those it was last paid with.
+type RequirementsChange struct {
+	// Resource is the resource whose requirements changed.
+	Resource string
+
+	// Previous is the requirement the resource was last paid with.
+	Previous PaymentRequirements
+
+	// Current is the requirement now offered for the same network and scheme.
+	Current PaymentRequirements
+
+	// Fields lists what changed: "amount", "payTo", and/or "asset".
+	Fields []string
+}
+
+// RequirementsHistory remembers the requirements each resource was paid with,
+// per network and scheme, and reports requirements that later differ from
+// them: a price rising beyond a tolerance or a different payTo or asset. This
+// defends long-running clients against sudden price hikes and payout address
+// swaps. It is safe for concurrent use.
+type RequirementsHistory struct {
+	tolerance *big.Rat
+	onChange  func(RequirementsChange) error
+
+	mu   sync.Mutex
+	paid map[string]PaymentRequirements
+}
+
+// RequirementsHistoryOption configures a RequirementsHistory.
+type RequirementsHistoryOption func(*RequirementsHistory)
+
+// WithPriceTolerance allows prices to rise by up to fraction of the previously
+// paid amount, e.g. 0.1 for 10%, without being reported (default: 0, any
+// increase is reported). Price decreases are never reported.
+func WithPriceTolerance(fraction float64) RequirementsHistoryOption {
+	return func(h *RequirementsHistory) {
+		if fraction > 0 {
+			h.tolerance = new(big.Rat).SetFloat64(1 + fraction)
+		}
+	}
+}
+
+// WithOnRequirementsChange calls callback with every reported change. The
+// change is accepted if callback returns nil, and refused with its error
+// otherwise. Without a callback, changes are refused with an error wrapping
+// ErrRequirementsChanged.
+func WithOnRequirementsChange(callback func(RequirementsChange) error) RequirementsHistoryOption {
+	return func(h *RequirementsHistory) {
+		h.onChange = callback
+	}
+}
+
+// NewRequirementsHistory creates an empty RequirementsHistory.
+func NewRequirementsHistory(opts ...RequirementsHistoryOption) *RequirementsHistory {
+	h := &RequirementsHistory{paid: make(map[string]PaymentRequirements)}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Check compares requirements with those resource was last paid with on the
+// same network and scheme, returning an error if they changed and the change
+// is not accepted. Requirements of resources never paid are accepted.
+func (h *RequirementsHistory) Check(resource string, requirements PaymentRequirements) error {
+	previous, ok := h.Last(resource, requirements.Network, requirements.Scheme)
+	if !ok {
+		return nil
+	}
+	fields := h.changes(previous, requirements)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	change := RequirementsChange{Resource: resource, Previous: previous, Current: requirements, Fields: fields}
+	if h.onChange != nil {
+		return h.onChange(change)
+	}
+	return fmt.Errorf("%w: %s of %s", ErrRequirementsChanged, strings.Join(fields, ", "), resource)
+}
+
+// Record remembers that resource was paid with requirements.
+func (h *RequirementsHistory) Record(resource string, requirements PaymentRequirements) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.paid[historyKey(resource, requirements.Network, requirements.Scheme)] = requirements
+}
+
+// Last returns the requirements resource was last paid with on network and
+// scheme.
+func (h *RequirementsHistory) Last(resource, network, scheme string) (PaymentRequirements, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	requirements, ok := h.paid[historyKey(resource, network, scheme)]
+	return requirements, ok
+}
+
+// changes returns the fields of current that differ from previous beyond the
+// tolerance.
+func (h *RequirementsHistory) changes(previous, current PaymentRequirements) []string {
+	var fields []string
+	if h.priceRose(previous.Amount, current.Amount) {
+		fields = append(fields, "amount")
+	}
+	if !sameAddress(previous.Network, previous.PayTo, current.PayTo) {
+		fields = append(fields, "payTo")
+	}
+	if !sameAddress(previous.Network, previous.Asset, current.Asset) {
+		fields = append(fields, "asset")
+	}
+	return fields
+}
+
+// priceRose reports whether current exceeds previous by more than the
+// tolerance. Amounts that do not parse are compared as strings.
+func (h *RequirementsHistory) priceRose(previous, current string) bool {
+	old, okOld := new(big.Int).SetString(previous, 10)
+	now, okNow := new(big.Int).SetString(current, 10)
+	if !okOld || !okNow {
+		return previous != current
+	}
+	limit := new(big.Rat).SetInt(old)
+	if h.tolerance != nil {
+		limit.Mul(limit, h.tolerance)
+	}
+	return new(big.Rat).SetInt(now).Cmp(limit) > 0
+}
+
+// sameAddress compares addresses, case-insensitively on EVM networks.
+func sameAddress(network, a, b string) bool {
+	if networkType, _ := ValidateNetwork(network); networkType == NetworkTypeEVM {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// historyKey identifies the requirements of a resource on network and scheme.
+func historyKey(resource, network, scheme string) string {
+	return resource + "|" + network + "|" + scheme
+}
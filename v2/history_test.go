@@ -0,0 +1,74 @@
+package v2
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRequirementsHistory(t *testing.T) {
+	const resource = "https://api.example.com/weather"
+	paid := PaymentRequirements{
+		Scheme:  "exact",
+		Network: NetworkBaseSepolia,
+		Amount:  "1000",
+		Asset:   BaseSepolia.USDCAddress,
+		PayTo:   "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+	}
+	with := func(change func(*PaymentRequirements)) PaymentRequirements {
+		r := paid
+		change(&r)
+		return r
+	}
+
+	tests := []struct {
+		name         string
+		opts         []RequirementsHistoryOption
+		requirements PaymentRequirements
+		resource     string
+		wantErr      bool
+	}{
+		{"unchanged", nil, paid, resource, false},
+		{"payTo in another case", nil, with(func(r *PaymentRequirements) { r.PayTo = "0x209693bc6afc0c5328ba36faf03c514ef312287c" }), resource, false},
+		{"price decrease", nil, with(func(r *PaymentRequirements) { r.Amount = "500" }), resource, false},
+		{"price increase", nil, with(func(r *PaymentRequirements) { r.Amount = "1001" }), resource, true},
+		{"price increase within tolerance", []RequirementsHistoryOption{WithPriceTolerance(0.1)}, with(func(r *PaymentRequirements) { r.Amount = "1100" }), resource, false},
+		{"price increase beyond tolerance", []RequirementsHistoryOption{WithPriceTolerance(0.1)}, with(func(r *PaymentRequirements) { r.Amount = "1101" }), resource, true},
+		{"payTo swapped", nil, with(func(r *PaymentRequirements) { r.PayTo = "0x0000000000000000000000000000000000000001" }), resource, true},
+		{"asset changed", nil, with(func(r *PaymentRequirements) { r.Asset = BaseMainnet.USDCAddress }), resource, true},
+		{"other network", nil, with(func(r *PaymentRequirements) { r.Network = NetworkBase; r.Amount = "5000" }), resource, false},
+		{"other resource", nil, with(func(r *PaymentRequirements) { r.Amount = "5000" }), "https://api.example.com/news", false},
+		{"change accepted by callback", []RequirementsHistoryOption{WithOnRequirementsChange(func(RequirementsChange) error { return nil })}, with(func(r *PaymentRequirements) { r.Amount = "5000" }), resource, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := NewRequirementsHistory(tt.opts...)
+			history.Record(resource, paid)
+			err := history.Check(tt.resource, tt.requirements)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrRequirementsChanged) {
+				t.Errorf("expected ErrRequirementsChanged, got %v", err)
+			}
+		})
+	}
+
+	// The callback sees what changed and may refuse the change
+	refused := errors.New("refused")
+	var changes []RequirementsChange
+	history := NewRequirementsHistory(WithOnRequirementsChange(func(change RequirementsChange) error {
+		changes = append(changes, change)
+		return refused
+	}))
+	history.Record(resource, paid)
+	swapped := with(func(r *PaymentRequirements) {
+		r.Amount = "2000"
+		r.PayTo = "0x0000000000000000000000000000000000000001"
+	})
+	if err := history.Check(resource, swapped); !errors.Is(err, refused) {
+		t.Fatalf("expected callback error, got %v", err)
+	}
+	if len(changes) != 1 || len(changes[0].Fields) != 2 || changes[0].Fields[0] != "amount" || changes[0].Fields[1] != "payTo" || changes[0].Previous.Amount != "1000" {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+}
@@ -0,0 +1,369 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/fees"
+)
+
+// DefaultRecentPayments is the default size of the admin recent payments buffer.
+const DefaultRecentPayments = 100
+
+// Payment record statuses reported by the admin endpoints.
+const (
+	PaymentStatusVerified = "verified"
+	PaymentStatusSettled  = "settled"
+	PaymentStatusFailed   = "failed"
+)
+
+// PaymentRecord is a recent payment reported by the admin endpoints.
+type PaymentRecord struct {
+	Time        time.Time `json:"time"`
+	Status      string    `json:"status"`
+	Scheme      string    `json:"scheme"`
+	Network     string    `json:"network"`
+	Asset       string    `json:"asset"`
+	Amount      string    `json:"amount"`
+	Payer       string    `json:"payer,omitempty"`
+	Transaction string    `json:"transaction,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Admin collects runtime state from the middleware and serves it as read-only
+// JSON endpoints, meant to be exposed on a separate, private port:
+//
+//	GET /config        effective configuration (credentials redacted)
+//	GET /enrichment    requirements enrichment result
+//	GET /facilitators  live facilitator health probes
+//	GET /payments      recent payments, newest first
+//	GET /settlements   in-flight settlements and payer queue depth
+//	GET /fees          settlement fee estimates (see WithFeeEstimator)
+//
+// Set it as Config.Admin; one Admin serves one middleware.
+type Admin struct {
+	mu           sync.Mutex
+	config       Config
+	enrichment   adminEnrichment
+	facilitators []*FacilitatorClient
+	fees         fees.Estimator
+	payments     []PaymentRecord
+	next         int
+	inFlight     int
+	settled      int
+	failed       int
+}
+
+// AdminOption configures an Admin.
+type AdminOption func(*Admin)
+
+// WithRecentPayments sets how many recent payments the admin endpoints retain.
+func WithRecentPayments(n int) AdminOption {
+	return func(a *Admin) {
+		if n > 0 {
+			a.payments = make([]PaymentRecord, 0, n)
+		}
+	}
+}
+
+// WithFeeEstimator serves estimates of the fee of settling each of the
+// middleware's requirements at GET /fees, e.g. a fees.Networks.
+func WithFeeEstimator(estimator fees.Estimator) AdminOption {
+	return func(a *Admin) {
+		a.fees = estimator
+	}
+}
+
+// NewAdmin creates an Admin.
+func NewAdmin(opts ...AdminOption) *Admin {
+	a := &Admin{payments: make([]PaymentRecord, 0, DefaultRecentPayments)}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+type adminEnrichment struct {
+	Time         time.Time                `json:"time"`
+	Enriched     bool                     `json:"enriched"`
+	Error        string                   `json:"error,omitempty"`
+	Requirements []v2.PaymentRequirements `json:"requirements"`
+}
+
+// Register records the middleware configuration and enrichment result. It is
+// called by the net/http and Gin middleware at construction. Nil facilitators
+// (e.g., an unconfigured fallback) are ignored.
+func (a *Admin) Register(config Config, requirements []v2.PaymentRequirements, enrichErr error, facilitators ...*FacilitatorClient) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.config = config
+	a.facilitators = a.facilitators[:0]
+	for _, facilitator := range facilitators {
+		if facilitator != nil {
+			a.facilitators = append(a.facilitators, facilitator)
+		}
+	}
+	a.enrichment = adminEnrichment{
+		Time:         time.Now(),
+		Enriched:     enrichErr == nil,
+		Requirements: requirements,
+	}
+	if enrichErr != nil {
+		a.enrichment.Error = enrichErr.Error()
+	}
+}
+
+// RecordPayment adds record to the recent payments buffer.
+func (a *Admin) RecordPayment(record PaymentRecord) {
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.payments) < cap(a.payments) {
+		a.payments = append(a.payments, record)
+		return
+	}
+	a.payments[a.next] = record
+	a.next = (a.next + 1) % len(a.payments)
+}
+
+// TrackSettle wraps settle to count in-flight settlements and record outcomes.
+func (a *Admin) TrackSettle(settle SettleFunc) SettleFunc {
+	return func(ctx context.Context, payment v2.PaymentPayload, requirement v2.PaymentRequirements) (*v2.SettleResponse, error) {
+		a.mu.Lock()
+		a.inFlight++
+		a.mu.Unlock()
+
+		resp, err := settle(ctx, payment, requirement)
+
+		record := PaymentRecord{
+			Status:  PaymentStatusSettled,
+			Scheme:  requirement.Scheme,
+			Network: requirement.Network,
+			Asset:   requirement.Asset,
+			Amount:  requirement.Amount,
+		}
+		switch {
+		case err != nil:
+			record.Status, record.Error = PaymentStatusFailed, err.Error()
+		case !resp.Success:
+			record.Status, record.Error, record.Payer = PaymentStatusFailed, resp.ErrorReason, resp.Payer
+		default:
+			record.Payer, record.Transaction = resp.Payer, resp.Transaction
+		}
+
+		a.mu.Lock()
+		a.inFlight--
+		if record.Status == PaymentStatusSettled {
+			a.settled++
+		} else {
+			a.failed++
+		}
+		a.mu.Unlock()
+
+		a.RecordPayment(record)
+		return resp, err
+	}
+}
+
+// RecordVerified records a payment that is verified but not settled by the
+// middleware (verify-only and deferred payments).
+func (a *Admin) RecordVerified(requirement v2.PaymentRequirements, verifyResp *v2.VerifyResponse) {
+	a.RecordPayment(PaymentRecord{
+		Status:  PaymentStatusVerified,
+		Scheme:  requirement.Scheme,
+		Network: requirement.Network,
+		Asset:   requirement.Asset,
+		Amount:  requirement.Amount,
+		Payer:   verifyResp.Payer,
+	})
+}
+
+// RecentPayments returns the recent payments buffer, newest first.
+func (a *Admin) RecentPayments() []PaymentRecord {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	payments := make([]PaymentRecord, 0, len(a.payments))
+	for i := len(a.payments) - 1; i >= 0; i-- {
+		payments = append(payments, a.payments[(a.next+i)%len(a.payments)])
+	}
+	return payments
+}
+
+// Handler returns the admin endpoints.
+func (a *Admin) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /config", func(w http.ResponseWriter, r *http.Request) {
+		a.mu.Lock()
+		config := a.configView()
+		a.mu.Unlock()
+		writeAdminJSON(w, config)
+	})
+	mux.HandleFunc("GET /enrichment", func(w http.ResponseWriter, r *http.Request) {
+		a.mu.Lock()
+		enrichment := a.enrichment
+		a.mu.Unlock()
+		writeAdminJSON(w, enrichment)
+	})
+	mux.HandleFunc("GET /facilitators", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, a.facilitatorHealth(r.Context()))
+	})
+	mux.HandleFunc("GET /payments", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, a.RecentPayments())
+	})
+	mux.HandleFunc("GET /settlements", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, a.settlementStats())
+	})
+	if a.fees != nil {
+		mux.HandleFunc("GET /fees", func(w http.ResponseWriter, r *http.Request) {
+			writeAdminJSON(w, a.feeEstimates(r.Context()))
+		})
+	}
+	return mux
+}
+
+// ListenAndServe serves the admin endpoints on addr, e.g. "127.0.0.1:9402".
+func (a *Admin) ListenAndServe(addr string) error {
+	server := &http.Server{Addr: addr, Handler: a.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	return server.ListenAndServe()
+}
+
+type adminConfig struct {
+	FacilitatorURL           string                   `json:"facilitatorUrl"`
+	FallbackFacilitatorURL   string                   `json:"fallbackFacilitatorUrl,omitempty"`
+	FacilitatorAuthorization bool                     `json:"facilitatorAuthorization"`
+	Resource                 v2.ResourceInfo          `json:"resource"`
+	PaymentRequirements      []v2.PaymentRequirements `json:"paymentRequirements"`
+	DynamicPricing           bool                     `json:"dynamicPricing"`
+	RevenueSplits            []v2.Split               `json:"revenueSplits,omitempty"`
+	VerifyOnly               bool                     `json:"verifyOnly"`
+	ManualCapture            bool                     `json:"manualCapture"`
+	PayerLimiter             bool                     `json:"payerLimiter"`
+	CheckAuthorizationWindow bool                     `json:"checkAuthorizationWindow"`
+	ClockSkew                string                   `json:"clockSkew"`
+}
+
+// configView returns the configuration with credentials reduced to presence flags.
+func (a *Admin) configView() adminConfig {
+	c := a.config
+	return adminConfig{
+		FacilitatorURL:           c.FacilitatorURL,
+		FallbackFacilitatorURL:   c.FallbackFacilitatorURL,
+		FacilitatorAuthorization: c.FacilitatorAuthorization != "" || c.FacilitatorAuthorizationProvider != nil,
+		Resource:                 c.Resource,
+		PaymentRequirements:      c.PaymentRequirements,
+		DynamicPricing:           c.RequirementsFunc != nil,
+		RevenueSplits:            c.RevenueSplits,
+		VerifyOnly:               c.VerifyOnly,
+		ManualCapture:            c.ManualCapture,
+		PayerLimiter:             c.PayerLimiter != nil,
+		CheckAuthorizationWindow: c.CheckAuthorizationWindow,
+		ClockSkew:                c.clockSkew().String(),
+	}
+}
+
+type facilitatorHealth struct {
+	URL     string             `json:"url"`
+	Healthy bool               `json:"healthy"`
+	Latency string             `json:"latency"`
+	Error   string             `json:"error,omitempty"`
+	Kinds   []v2.SupportedKind `json:"kinds,omitempty"`
+}
+
+// facilitatorHealth probes each facilitator's /supported endpoint concurrently.
+func (a *Admin) facilitatorHealth(ctx context.Context) []facilitatorHealth {
+	a.mu.Lock()
+	facilitators := a.facilitators
+	a.mu.Unlock()
+
+	health := make([]facilitatorHealth, len(facilitators))
+	var wg sync.WaitGroup
+	for i, facilitator := range facilitators {
+		wg.Add(1)
+		go func(i int, facilitator *FacilitatorClient) {
+			defer wg.Done()
+
+			start := time.Now()
+			supported, err := facilitator.Supported(ctx)
+			health[i] = facilitatorHealth{URL: facilitator.BaseURL, Latency: time.Since(start).String()}
+			if err != nil {
+				health[i].Error = err.Error()
+				return
+			}
+			health[i].Healthy = true
+			health[i].Kinds = supported.Kinds
+		}(i, facilitator)
+	}
+	wg.Wait()
+	return health
+}
+
+type feeEstimate struct {
+	Scheme   string         `json:"scheme"`
+	Network  string         `json:"network"`
+	Asset    string         `json:"asset"`
+	Amount   string         `json:"amount"`
+	Estimate *fees.Estimate `json:"estimate,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// feeEstimates estimates the settlement fee of each enriched requirement.
+func (a *Admin) feeEstimates(ctx context.Context) []feeEstimate {
+	a.mu.Lock()
+	requirements := a.enrichment.Requirements
+	a.mu.Unlock()
+
+	estimates := make([]feeEstimate, len(requirements))
+	for i, requirement := range requirements {
+		estimates[i] = feeEstimate{
+			Scheme:  requirement.Scheme,
+			Network: requirement.Network,
+			Asset:   requirement.Asset,
+			Amount:  requirement.Amount,
+		}
+		estimate, err := a.fees.EstimateFee(ctx, requirement)
+		if err != nil {
+			estimates[i].Error = err.Error()
+			continue
+		}
+		estimates[i].Estimate = estimate
+	}
+	return estimates
+}
+
+type settlementStats struct {
+	InFlight int          `json:"inFlight"`
+	Settled  int          `json:"settled"`
+	Failed   int          `json:"failed"`
+	Queued   int          `json:"queued"`
+	Payers   []PayerStats `json:"payers,omitempty"`
+}
+
+func (a *Admin) settlementStats() settlementStats {
+	a.mu.Lock()
+	stats := settlementStats{InFlight: a.inFlight, Settled: a.settled, Failed: a.failed}
+	limiter := a.config.PayerLimiter
+	a.mu.Unlock()
+
+	if limiter != nil {
+		stats.Payers = limiter.Stats()
+		for _, payer := range stats.Payers {
+			stats.Queued += payer.Queued
+		}
+	}
+	return stats
+}
+
+func writeAdminJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,175 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
+	"github.com/mark3labs/x402-go/v2/fees"
+)
+
+func TestAdmin(t *testing.T) {
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{
+				Kinds: []v2.SupportedKind{{X402Version: 2, Scheme: "exact", Network: "eip155:84532"}},
+			})
+		case "/verify":
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xtx", Payer: "0xPayerAddress"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	admin := NewAdmin(WithRecentPayments(2))
+	config := Config{
+		FacilitatorURL:           facilitatorServer.URL,
+		FallbackFacilitatorURL:   "http://127.0.0.1:1",
+		FacilitatorAuthorization: "Bearer secret",
+		Resource:                 v2.ResourceInfo{URL: "https://example.com/api/data"},
+		PaymentRequirements: []v2.PaymentRequirements{
+			{
+				Scheme:            "exact",
+				Network:           "eip155:84532",
+				Amount:            "10000",
+				Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+				PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+				MaxTimeoutSeconds: 60,
+			},
+		},
+		PayerLimiter: NewPayerLimiter(1, 0),
+		Admin:        admin,
+	}
+
+	handler := NewX402Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	paymentHeader, _ := encoding.EncodePayment(v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: "10000"},
+		Payload:     map[string]interface{}{"signature": "0xsig"},
+	})
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/api/data", nil)
+		req.Header.Set("X-PAYMENT", paymentHeader)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+	}
+
+	get := func(path string, v interface{}) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		admin.Handler().ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status 200, got %d", path, rec.Code)
+		}
+		if strings.Contains(rec.Body.String(), "secret") {
+			t.Errorf("GET %s leaked credentials: %s", path, rec.Body.String())
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("GET %s: invalid JSON: %v", path, err)
+		}
+	}
+
+	var cfg adminConfig
+	get("/config", &cfg)
+	if cfg.FacilitatorURL != facilitatorServer.URL || !cfg.FacilitatorAuthorization || !cfg.PayerLimiter {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	var enrichment adminEnrichment
+	get("/enrichment", &enrichment)
+	if !enrichment.Enriched || len(enrichment.Requirements) != 1 {
+		t.Errorf("unexpected enrichment: %+v", enrichment)
+	}
+
+	var health []facilitatorHealth
+	get("/facilitators", &health)
+	if len(health) != 2 || !health[0].Healthy || health[1].Healthy {
+		t.Errorf("unexpected facilitator health: %+v", health)
+	}
+
+	var payments []PaymentRecord
+	get("/payments", &payments)
+	if len(payments) != 2 || payments[0].Status != PaymentStatusSettled || payments[0].Transaction != "0xtx" {
+		t.Errorf("unexpected payments: %+v", payments)
+	}
+
+	var stats settlementStats
+	get("/settlements", &stats)
+	if stats.Settled != 3 || stats.InFlight != 0 || stats.Queued != 0 {
+		t.Errorf("unexpected settlement stats: %+v", stats)
+	}
+
+	rec := httptest.NewRecorder()
+	admin.Handler().ServeHTTP(rec, httptest.NewRequest("POST", "/config", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected admin endpoints to be read-only, got status %d", rec.Code)
+	}
+}
+
+func TestAdmin_RecentPaymentsRing(t *testing.T) {
+	admin := NewAdmin(WithRecentPayments(3))
+	for _, amount := range []string{"1", "2", "3", "4", "5"} {
+		admin.RecordPayment(PaymentRecord{Amount: amount})
+	}
+
+	payments := admin.RecentPayments()
+	var got []string
+	for _, p := range payments {
+		got = append(got, p.Amount)
+	}
+	if strings.Join(got, ",") != "5,4,3" {
+		t.Errorf("RecentPayments() = %v, want [5 4 3]", got)
+	}
+}
+
+// stubEstimator is a fees.Estimator returning a fixed fee.
+type stubEstimator struct{}
+
+func (stubEstimator) EstimateFee(_ context.Context, requirement v2.PaymentRequirements) (*fees.Estimate, error) {
+	return &fees.Estimate{Network: requirement.Network, Fee: "42", Currency: "wei"}, nil
+}
+
+func TestAdmin_FeeEstimates(t *testing.T) {
+	admin := NewAdmin(WithFeeEstimator(fees.Networks{"eip155:84532": stubEstimator{}}))
+	admin.Register(Config{}, []v2.PaymentRequirements{
+		{Scheme: "exact", Network: "eip155:84532", Amount: "10000"},
+		{Scheme: "exact", Network: "eip155:1", Amount: "10000"},
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	admin.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/fees", nil))
+	var estimates []feeEstimate
+	if err := json.Unmarshal(rec.Body.Bytes(), &estimates); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(estimates) != 2 {
+		t.Fatalf("expected 2 estimates, got %+v", estimates)
+	}
+	if estimates[0].Estimate == nil || estimates[0].Estimate.Fee != "42" {
+		t.Errorf("unexpected estimate: %+v", estimates[0])
+	}
+	if estimates[1].Estimate != nil || !strings.Contains(estimates[1].Error, "unsupported network") {
+		t.Errorf("expected unsupported network error, got %+v", estimates[1])
+	}
+
+	// Without an estimator, the endpoint is not served
+	rec = httptest.NewRecorder()
+	NewAdmin().Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/fees", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 without an estimator, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// LowBalanceWebhook is a reference v2.LowBalanceHandler that POSTs a JSON
+// LowBalanceEvent to a URL, e.g. to trigger a treasury service or a bridge job.
+type LowBalanceWebhook struct {
+	// URL is the webhook endpoint.
+	URL string
+
+	// Client is the HTTP client to use. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// Authorization is an optional Authorization header value.
+	Authorization string
+
+	// Address is an optional identifier of the wallet to top up, included in the event.
+	Address string
+}
+
+// LowBalanceEvent is the JSON body sent by LowBalanceWebhook.
+type LowBalanceEvent struct {
+	// Network is the blockchain network (CAIP-2 format).
+	Network string `json:"network"`
+
+	// Token is the token contract or mint address.
+	Token string `json:"token"`
+
+	// Balance is the current balance in atomic units.
+	Balance string `json:"balance"`
+
+	// Address is the wallet to top up, if configured.
+	Address string `json:"address,omitempty"`
+
+	// Timestamp is when the low balance was detected.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Verify that LowBalanceWebhook implements v2.LowBalanceHandler.
+var _ v2.LowBalanceHandler = (*LowBalanceWebhook)(nil)
+
+// NewLowBalanceWebhook creates a LowBalanceWebhook that posts to url.
+func NewLowBalanceWebhook(url string) *LowBalanceWebhook {
+	return &LowBalanceWebhook{URL: url}
+}
+
+// OnLowBalance implements v2.LowBalanceHandler.
+// Any non-2xx response is returned as an error so the monitor retries on its next check.
+func (w *LowBalanceWebhook) OnLowBalance(ctx context.Context, network, token string, balance *big.Int) error {
+	data, err := json.Marshal(LowBalanceEvent{
+		Network:   network,
+		Token:     token,
+		Balance:   balance.String(),
+		Address:   w.Address,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal low balance event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", w.URL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.Authorization != "" {
+		req.Header.Set("Authorization", w.Authorization)
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("low balance webhook failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("low balance webhook returned status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return nil
+}
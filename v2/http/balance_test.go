@@ -0,0 +1,50 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLowBalanceWebhook(t *testing.T) {
+	var received LowBalanceEvent
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode event: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	webhook := NewLowBalanceWebhook(server.URL)
+	webhook.Authorization = "Bearer secret"
+	webhook.Address = "0xwallet"
+
+	err := webhook.OnLowBalance(context.Background(), "eip155:8453", "0xtoken", big.NewInt(42))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if received.Network != "eip155:8453" || received.Token != "0xtoken" || received.Balance != "42" || received.Address != "0xwallet" {
+		t.Errorf("unexpected event: %+v", received)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("expected Authorization header, got %q", auth)
+	}
+}
+
+func TestLowBalanceWebhook_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := NewLowBalanceWebhook(server.URL).OnLowBalance(context.Background(), "eip155:8453", "0xtoken", big.NewInt(0))
+	if err == nil {
+		t.Fatal("expected error for non-2xx response")
+	}
+}
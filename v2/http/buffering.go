@@ -0,0 +1,115 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// DefaultMaxBufferedResponseBytes is the default cap on responses held by
+// BufferResponses.
+const DefaultMaxBufferedResponseBytes = 1 << 20
+
+// BuffersResponse reports whether the response to r is buffered until
+// settlement: BufferResponsesFor decides if set, and BufferResponses
+// otherwise.
+func (c Config) BuffersResponse(r *http.Request) bool {
+	if c.BufferResponsesFor != nil {
+		return c.BufferResponsesFor(r)
+	}
+	return c.BufferResponses
+}
+
+// maxBufferedResponseBytes returns the configured buffer cap, or
+// DefaultMaxBufferedResponseBytes if unset.
+func (c Config) maxBufferedResponseBytes() int64 {
+	if c.MaxBufferedResponseBytes > 0 {
+		return c.MaxBufferedResponseBytes
+	}
+	return DefaultMaxBufferedResponseBytes
+}
+
+// bufferedWriter holds a handler's response until the handler returns, then
+// commits it to a settlementInterceptor, which settles the payment before
+// anything reaches the client. Responses outgrowing limit are committed when
+// the limit is reached and streamed from then on.
+type bufferedWriter struct {
+	interceptor *settlementInterceptor
+	limit       int64
+	status      int
+	body        bytes.Buffer
+	streaming   bool
+}
+
+func newBufferedWriter(interceptor *settlementInterceptor, limit int64) *bufferedWriter {
+	// The handler's headers are kept apart until settlement succeeds, so a
+	// failed settlement is answered without them
+	interceptor.header = interceptor.w.Header().Clone()
+	return &bufferedWriter{interceptor: interceptor, limit: limit}
+}
+
+func (b *bufferedWriter) Header() http.Header {
+	return b.interceptor.Header()
+}
+
+func (b *bufferedWriter) WriteHeader(statusCode int) {
+	if b.streaming {
+		b.interceptor.WriteHeader(statusCode)
+		return
+	}
+	if b.status == 0 {
+		b.status = statusCode
+	}
+}
+
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	if b.streaming {
+		return b.interceptor.Write(p)
+	}
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	if int64(b.body.Len()+len(p)) > b.limit {
+		b.commit()
+		return b.interceptor.Write(p)
+	}
+	return b.body.Write(p)
+}
+
+// Flush is a no-op until the response outgrows the buffer: buffered
+// responses reach the client when the handler returns.
+func (b *bufferedWriter) Flush() {
+	if b.streaming {
+		b.interceptor.Flush()
+	}
+}
+
+// Hijack settles the payment and hands over the connection, provided the
+// handler has not written anything yet.
+func (b *bufferedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if !b.streaming && (b.status != 0 || b.body.Len() > 0) {
+		return nil, nil, errors.New("cannot hijack a buffered response")
+	}
+	b.streaming = true
+	return b.interceptor.Hijack()
+}
+
+// commit settles the payment through the interceptor and sends the buffered
+// response, switching to streaming. A handler that wrote nothing is answered
+// with 200, as net/http does.
+func (b *bufferedWriter) commit() {
+	if b.streaming {
+		return
+	}
+	b.streaming = true
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	b.interceptor.WriteHeader(b.status)
+	if b.body.Len() > 0 {
+		_, _ = b.interceptor.Write(b.body.Bytes())
+	}
+	b.body.Reset()
+}
@@ -0,0 +1,310 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// PaymentReceiptHeader carries the receipt issued with a cached paid response.
+// Presenting it on the same GET request within the cache TTL replays the response
+// without another payment.
+const PaymentReceiptHeader = "X-PAYMENT-RECEIPT"
+
+// DefaultMaxCachedBodySize is the largest response body ResponseCache stores.
+const DefaultMaxCachedBodySize = 1 << 20
+
+// ResponseCache maps settled payments for idempotent GET requests to their
+// responses for a TTL, so a client whose response was lost can retry without
+// paying twice. A retry is recognized either by the same X-PAYMENT header or by
+// the receipt returned in PaymentReceiptHeader, and only for the same URL.
+//
+// Settled payments are public on-chain, so anyone able to reconstruct the exact
+// X-PAYMENT header can fetch the cached response within the TTL; keep the TTL
+// short for sensitive resources. Receipts are random and never leave the
+// server except in the paid response.
+type ResponseCache struct {
+	ttl         time.Duration
+	maxBodySize int
+	maxEntries  int
+	clock       v2.Clock
+	headers     PaymentHeaders
+
+	mu      sync.Mutex
+	entries map[string]*cachedResponse
+}
+
+type cachedResponse struct {
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+	keys    [2]string
+}
+
+// ResponseCacheOption configures a ResponseCache.
+type ResponseCacheOption func(*ResponseCache)
+
+// WithMaxCachedBodySize sets the largest body that is cached (default: DefaultMaxCachedBodySize).
+// Larger responses are served normally but not cached.
+func WithMaxCachedBodySize(size int) ResponseCacheOption {
+	return func(c *ResponseCache) {
+		c.maxBodySize = size
+	}
+}
+
+// WithMaxCacheEntries bounds the number of cached responses (default: unbounded).
+// When full, the responses closest to expiry are evicted first.
+func WithMaxCacheEntries(n int) ResponseCacheOption {
+	return func(c *ResponseCache) {
+		c.maxEntries = n
+	}
+}
+
+// WithCacheClock sets the clock used for expiry.
+func WithCacheClock(clock v2.Clock) ResponseCacheOption {
+	return func(c *ResponseCache) {
+		c.clock = clock
+	}
+}
+
+// WithCachePaymentHeaders sets where retried payments are read from, matching
+// the Config.Headers of the middleware using the cache.
+func WithCachePaymentHeaders(headers PaymentHeaders) ResponseCacheOption {
+	return func(c *ResponseCache) {
+		c.headers = headers
+	}
+}
+
+// NewResponseCache creates a cache keeping paid responses for ttl.
+func NewResponseCache(ttl time.Duration, opts ...ResponseCacheOption) *ResponseCache {
+	c := &ResponseCache{
+		ttl:         ttl,
+		maxBodySize: DefaultMaxCachedBodySize,
+		entries:     make(map[string]*cachedResponse),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Len returns the number of cached responses, including expired ones not yet evicted.
+func (c *ResponseCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries) / 2
+}
+
+// Serve writes the cached response for r, if any, and reports whether it did.
+func (c *ResponseCache) Serve(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+
+	var keys []string
+	if receipt := r.Header.Get(PaymentReceiptHeader); receipt != "" {
+		keys = append(keys, receiptKey(r, receipt))
+	}
+	if payment := c.headers.ReadPayment(r); payment != "" {
+		keys = append(keys, paymentKey(r, payment))
+	}
+
+	now := v2.ClockOrSystem(c.clock).Now()
+	c.mu.Lock()
+	var entry *cachedResponse
+	for _, key := range keys {
+		if e, ok := c.entries[key]; ok {
+			if now.Before(e.expires) {
+				entry = e
+				break
+			}
+			c.removeLocked(e)
+		}
+	}
+	c.mu.Unlock()
+	if entry == nil {
+		return false
+	}
+
+	for key, values := range entry.header {
+		// CORS headers belong to the origin of the current request
+		if strings.HasPrefix(key, "Access-Control-") {
+			continue
+		}
+		w.Header()[key] = append([]string(nil), values...)
+	}
+	w.WriteHeader(entry.status)
+	_, _ = w.Write(entry.body)
+	return true
+}
+
+// Capture wraps w to record the response to r. Call Settled once the payment
+// has settled, then Commit after the handler returns.
+func (c *ResponseCache) Capture(w http.ResponseWriter, r *http.Request) *CaptureWriter {
+	return &CaptureWriter{
+		ResponseWriter: w,
+		cache:          c,
+		request:        r,
+		cacheable:      r.Method == http.MethodGet,
+	}
+}
+
+func (c *ResponseCache) store(entry *cachedResponse) {
+	now := v2.ClockOrSystem(c.clock).Now()
+	entry.expires = now.Add(c.ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.entries {
+		if !now.Before(e.expires) {
+			c.removeLocked(e)
+		}
+	}
+	for c.maxEntries > 0 && len(c.entries)/2 >= c.maxEntries {
+		var oldest *cachedResponse
+		for _, e := range c.entries {
+			if oldest == nil || e.expires.Before(oldest.expires) {
+				oldest = e
+			}
+		}
+		c.removeLocked(oldest)
+	}
+	for _, key := range entry.keys {
+		c.entries[key] = entry
+	}
+}
+
+func (c *ResponseCache) removeLocked(entry *cachedResponse) {
+	for _, key := range entry.keys {
+		delete(c.entries, key)
+	}
+}
+
+// paymentKey identifies a retry of r with the same payment header.
+func paymentKey(r *http.Request, payment string) string {
+	return cacheKey("payment", r, payment)
+}
+
+// receiptKey identifies a retry of r presenting a receipt.
+func receiptKey(r *http.Request, receipt string) string {
+	return cacheKey("receipt", r, receipt)
+}
+
+func cacheKey(kind string, r *http.Request, secret string) string {
+	sum := sha256.Sum256([]byte(kind + "\n" + r.Host + r.URL.RequestURI() + "\n" + secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CaptureWriter records a paid response for a ResponseCache while writing it through.
+type CaptureWriter struct {
+	http.ResponseWriter
+
+	cache     *ResponseCache
+	request   *http.Request
+	cacheable bool
+	receipt   string
+
+	wroteHeader bool
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+}
+
+// Settled marks the response as paid and adds the receipt header. It must be
+// called before the response header is written.
+func (w *CaptureWriter) Settled() {
+	if !w.cacheable || w.wroteHeader {
+		return
+	}
+	var token [16]byte
+	if _, err := rand.Read(token[:]); err != nil {
+		w.cacheable = false
+		return
+	}
+	w.receipt = hex.EncodeToString(token[:])
+	w.Header().Set(PaymentReceiptHeader, w.receipt)
+}
+
+// WriteHeader records the status before writing it through.
+func (w *CaptureWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the body while writing it through. The header is recorded on
+// the first write, since some frameworks (e.g., Gin) defer sending it until then.
+func (w *CaptureWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.header == nil {
+		w.header = w.Header().Clone()
+	}
+	if w.cacheable {
+		if w.body.Len()+len(b) > w.cache.maxBodySize {
+			w.cacheable = false
+			w.body.Reset()
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher.
+func (w *CaptureWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker. Hijacked responses are not cached.
+func (w *CaptureWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.cacheable = false
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, errors.New("hijacking not supported")
+}
+
+// Push implements http.Pusher.
+func (w *CaptureWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Commit caches the response if the payment settled and the handler succeeded.
+func (w *CaptureWriter) Commit() {
+	if !w.cacheable || w.receipt == "" || !w.wroteHeader || w.status < 200 || w.status >= 300 {
+		return
+	}
+	if w.header == nil {
+		w.header = w.Header().Clone()
+	}
+	entry := &cachedResponse{
+		status: w.status,
+		header: w.header,
+		body:   append([]byte(nil), w.body.Bytes()...),
+		keys: [2]string{
+			receiptKey(w.request, w.receipt),
+			paymentKey(w.request, w.cache.headers.ReadPayment(w.request)),
+		},
+	}
+	w.cache.store(entry)
+}
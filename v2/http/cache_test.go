@@ -0,0 +1,119 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
+)
+
+func TestMiddleware_ResponseCache(t *testing.T) {
+	var verifyCalls, settleCalls atomic.Int32
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+		case "/verify":
+			verifyCalls.Add(1)
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			settleCalls.Add(1)
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	paymentHeader, _ := encoding.EncodePayment(v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    requirement,
+		Payload:     map[string]interface{}{"signature": "0xsig"},
+	})
+
+	clock := v2.NewFakeClock(time.Now())
+	cache := NewResponseCache(time.Minute, WithCacheClock(clock))
+	handlerCalls := 0
+	handler := NewX402Middleware(Config{
+		FacilitatorURL:      facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{requirement},
+		ResponseCache:       cache,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalls++
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("result"))
+	}))
+
+	serve := func(method, path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	first := serve("GET", "/api/data", map[string]string{"X-PAYMENT": paymentHeader})
+	receipt := first.Header().Get(PaymentReceiptHeader)
+	if first.Code != http.StatusOK || receipt == "" {
+		t.Fatalf("Expected paid 200 with receipt, got %d %q", first.Code, receipt)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		headers     map[string]string
+		advance     time.Duration
+		wantStatus  int
+		wantSettles int32
+	}{
+		{name: "same payment", method: "GET", path: "/api/data", headers: map[string]string{"X-PAYMENT": paymentHeader}, wantStatus: http.StatusOK},
+		{name: "receipt", method: "GET", path: "/api/data", headers: map[string]string{PaymentReceiptHeader: receipt}, wantStatus: http.StatusOK},
+		{name: "receipt for other resource", method: "GET", path: "/api/other", headers: map[string]string{PaymentReceiptHeader: receipt}, wantStatus: http.StatusPaymentRequired},
+		{name: "POST is not cached", method: "POST", path: "/api/data", headers: map[string]string{"X-PAYMENT": paymentHeader}, wantStatus: http.StatusOK, wantSettles: 1},
+		{name: "expired", method: "GET", path: "/api/data", headers: map[string]string{PaymentReceiptHeader: receipt}, advance: time.Minute, wantStatus: http.StatusPaymentRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Advance(tt.advance)
+			settleCalls.Store(0)
+			before := handlerCalls
+
+			w := serve(tt.method, tt.path, tt.headers)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if got := settleCalls.Load(); got != tt.wantSettles {
+				t.Errorf("Expected %d settle calls, got %d", tt.wantSettles, got)
+			}
+			if tt.wantStatus == http.StatusOK && tt.wantSettles == 0 {
+				if handlerCalls != before {
+					t.Error("Expected cached response without calling the handler")
+				}
+				if w.Body.String() != "result" || w.Header().Get("Content-Type") != "text/plain" ||
+					w.Header().Get("X-PAYMENT-RESPONSE") == "" {
+					t.Errorf("Unexpected cached response: %q %v", w.Body.String(), w.Header())
+				}
+			}
+		})
+	}
+
+	if verifyCalls.Load() != 2 {
+		t.Errorf("Expected only the first GET and the POST to be verified, got %d", verifyCalls.Load())
+	}
+}
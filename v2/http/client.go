@@ -3,9 +3,11 @@ package http
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	v2 "github.com/mark3labs/x402-go/v2"
-	"github.com/mark3labs/x402-go/v2/http/internal/helpers"
+	"github.com/mark3labs/x402-go/v2/attestation"
+	"github.com/mark3labs/x402-go/v2/extensions"
 )
 
 // Client is an HTTP client that automatically handles x402 v2 payment flows.
@@ -72,6 +74,23 @@ func WithSigner(signer v2.Signer) ClientOption {
 	}
 }
 
+// WithScopedSigner adds a payment signer that only pays for requests to
+// resources, URL patterns like "api.example.com" or
+// "https://*.example.com/v1/" (see SignerScope). Requirements served by any
+// other host are never paid from its wallet, even if no other signer can pay
+// them.
+func WithScopedSigner(signer v2.Signer, resources ...string) ClientOption {
+	return func(c *Client) error {
+		scope := SignerScope{Signer: signer, Resources: resources}
+		if err := scope.validate(); err != nil {
+			return err
+		}
+		transport := getOrCreateTransport(c)
+		transport.SignerScopes = append(transport.SignerScopes, scope)
+		return nil
+	}
+}
+
 // WithSelector sets a custom payment selector.
 func WithSelector(selector v2.PaymentSelector) ClientOption {
 	return func(c *Client) error {
@@ -93,6 +112,23 @@ func WithSelector(selector v2.PaymentSelector) ClientOption {
 	}
 }
 
+// WithParallelSigning signs payments for the best ranked requirements of a 402
+// response concurrently, up to width of them (DefaultRaceWidth if zero), and
+// pays with the first signed, discarding the others unsent. It ranks with the
+// selector configured so far if it can select without signing, and with the
+// default selector otherwise. See v2.RacingSelector.
+func WithParallelSigning(width int) ClientOption {
+	return func(c *Client) error {
+		transport := getOrCreateTransport(c)
+		opts := []v2.RacingSelectorOption{v2.WithRaceWidth(width)}
+		if selector, ok := transport.Selector.(v2.RequirementSelector); ok {
+			opts = append(opts, v2.WithRaceSelector(selector))
+		}
+		transport.Selector = v2.NewRacingSelector(opts...)
+		return nil
+	}
+}
+
 // WithPaymentCallback sets a callback for a specific payment event type.
 func WithPaymentCallback(eventType v2.PaymentEventType, callback v2.PaymentCallback) ClientOption {
 	return func(c *Client) error {
@@ -135,6 +171,200 @@ func WithPaymentCallbacks(onAttempt, onSuccess, onFailure v2.PaymentCallback) Cl
 	}
 }
 
+// WithSpendGuard enforces a process-wide spend limit on the client.
+// The same guard may be shared by multiple clients and MCP transports.
+func WithSpendGuard(guard *v2.SpendGuard) ClientOption {
+	return func(c *Client) error {
+		transport := getOrCreateTransport(c)
+		transport.SpendGuard = guard
+		return nil
+	}
+}
+
+// WithDryRun makes the client report what it would pay instead of paying.
+// Requests that receive a 402 response fail with a *v2.DryRunError; use
+// v2.PaymentPlanFromError to retrieve the PaymentPlan.
+func WithDryRun() ClientOption {
+	return func(c *Client) error {
+		transport := getOrCreateTransport(c)
+		transport.DryRun = true
+		return nil
+	}
+}
+
+// WithRequirementsAttestation verifies the requirements attestation of 402
+// responses with verifier before paying (see the attestation package). Trusted
+// keys can be discovered with verifier.TrustSupported.
+func WithRequirementsAttestation(verifier *attestation.Verifier) ClientOption {
+	return func(c *Client) error {
+		transport := getOrCreateTransport(c)
+		transport.Attestation = verifier
+		return nil
+	}
+}
+
+// WithExtensions validates the extensions of 402 responses with registry and
+// adds the info of its extensions to payments (see the extensions package).
+func WithExtensions(registry *extensions.Registry) ClientOption {
+	return func(c *Client) error {
+		transport := getOrCreateTransport(c)
+		transport.Extensions = registry
+		return nil
+	}
+}
+
+// WithFacilitatorSigners refuses to pay requirements whose feePayer is not
+// one of the signers supported advertises for the network, as returned by
+// FacilitatorClient.Supported.
+func WithFacilitatorSigners(supported *v2.SupportedResponse) ClientOption {
+	return func(c *Client) error {
+		transport := getOrCreateTransport(c)
+		transport.FacilitatorSigners = supported
+		return nil
+	}
+}
+
+// WithTrustedFeePayers adds addresses to the fee payers the client accepts on
+// network, which may be a pattern like "solana:*". Requirements on a matching
+// network naming any other feePayer are refused with v2.ErrUntrustedFeePayer.
+func WithTrustedFeePayers(network string, addresses ...string) ClientOption {
+	return func(c *Client) error {
+		if len(addresses) == 0 {
+			return fmt.Errorf("no trusted fee payers given for %s", network)
+		}
+		transport := getOrCreateTransport(c)
+		if transport.TrustedFeePayers == nil {
+			transport.TrustedFeePayers = make(map[string][]string)
+		}
+		transport.TrustedFeePayers[network] = append(transport.TrustedFeePayers[network], addresses...)
+		return nil
+	}
+}
+
+// WithPaymentAllowlist only pays requirements matching one of entries, e.g.
+// only "exact" USDC on Base in production. Other requirements fail with
+// v2.ErrPaymentNotAllowed.
+func WithPaymentAllowlist(entries ...v2.AllowedPayment) ClientOption {
+	return func(c *Client) error {
+		allowlist := v2.PaymentAllowlist(entries)
+		if len(allowlist) == 0 {
+			return fmt.Errorf("no allowed payments given")
+		}
+		if err := allowlist.Validate(); err != nil {
+			return err
+		}
+		transport := getOrCreateTransport(c)
+		transport.Allowlist = append(transport.Allowlist, allowlist...)
+		return nil
+	}
+}
+
+// WithDustThreshold refuses to pay less than amount, in atomic units, of
+// asset, since such transfers cost more in fees than they are worth.
+// Requirements below it fail with v2.ErrBelowMinimum.
+func WithDustThreshold(asset, amount string) ClientOption {
+	return func(c *Client) error {
+		threshold := v2.MinimumAmounts{asset: amount}
+		if err := threshold.Validate(); err != nil {
+			return err
+		}
+		transport := getOrCreateTransport(c)
+		if transport.DustThresholds == nil {
+			transport.DustThresholds = make(v2.MinimumAmounts)
+		}
+		transport.DustThresholds[asset] = amount
+		return nil
+	}
+}
+
+// WithPayToPolicy refuses to pay a host at addresses other than those resolver
+// returns for it, such as a StaticPayTo of known merchants or DNSPayTo.
+func WithPayToPolicy(resolver PayToResolver) ClientOption {
+	return func(c *Client) error {
+		transport := getOrCreateTransport(c)
+		transport.PayTo = resolver
+		return nil
+	}
+}
+
+// WithRequirementsHistory remembers the requirements each resource is paid
+// with in history, refusing to pay requirements that later change beyond its
+// tolerance unless its change callback accepts them. The same history may be
+// shared by multiple clients.
+func WithRequirementsHistory(history *v2.RequirementsHistory) ClientOption {
+	return func(c *Client) error {
+		transport := getOrCreateTransport(c)
+		transport.History = history
+		return nil
+	}
+}
+
+// WithChargeAcceptor checks the final charge of every "upto" payment with accept.
+// Charges above the authorized amount are always rejected.
+func WithChargeAcceptor(accept ChargeAcceptor) ClientOption {
+	return func(c *Client) error {
+		transport := getOrCreateTransport(c)
+		transport.AcceptCharge = accept
+		return nil
+	}
+}
+
+// WithApproval has approve approve every payment after it is signed and
+// before it is sent, such as passkey.Approver.Approve for payments an
+// operator confirms with a passkey.
+func WithApproval(approve v2.ApprovalFunc) ClientOption {
+	return func(c *Client) error {
+		transport := getOrCreateTransport(c)
+		transport.Approve = approve
+		return nil
+	}
+}
+
+// WithCoalescing makes concurrent identical GET requests share one payment
+// and one response. Enable it only for idempotent routes; use SkipCoalescing
+// to opt out for individual requests.
+func WithCoalescing() ClientOption {
+	return func(c *Client) error {
+		transport := getOrCreateTransport(c)
+		transport.Coalesce = true
+		return nil
+	}
+}
+
+// WithStatusRetries bounds the corrective retries of paid requests answered
+// with 409, 425 or 429 to maxRetries, each waiting at most maxWait. A negative
+// maxRetries surfaces these statuses as errors without retrying.
+func WithStatusRetries(maxRetries int, maxWait time.Duration) ClientOption {
+	return func(c *Client) error {
+		transport := getOrCreateTransport(c)
+		transport.MaxStatusRetries = maxRetries
+		transport.MaxRetryWait = maxWait
+		return nil
+	}
+}
+
+// WithPaymentCache answers repeated GET requests for paid resources from cache
+// for as long as the paid response's Cache-Control allows, instead of paying
+// for them again.
+func WithPaymentCache(cache *PaymentCache) ClientOption {
+	return func(c *Client) error {
+		transport := getOrCreateTransport(c)
+		transport.Cache = cache
+		return nil
+	}
+}
+
+// WithPaymentHeaders sets the payment and settlement header names and where
+// payments are sent, for servers behind intermediaries that strip the
+// default X-PAYMENT header.
+func WithPaymentHeaders(headers PaymentHeaders) ClientOption {
+	return func(c *Client) error {
+		transport := getOrCreateTransport(c)
+		transport.Headers = headers
+		return nil
+	}
+}
+
 // getOrCreateTransport gets the X402Transport or creates one if it doesn't exist.
 func getOrCreateTransport(c *Client) *X402Transport {
 	transport, ok := c.Transport.(*X402Transport)
@@ -150,12 +380,12 @@ func getOrCreateTransport(c *Client) *X402Transport {
 	return transport
 }
 
-// GetSettlement extracts settlement information from an HTTP response.
-// Returns nil if no settlement header is present or if parsing fails.
+// GetSettlement extracts settlement information from an HTTP response, from
+// the X-PAYMENT-RESPONSE header or, for responses settled after their header
+// was sent, the X-PAYMENT-RESPONSE trailer, which is only available once the
+// body has been read to the end.
+// Returns nil if no settlement is present or if parsing fails.
+// Use PaymentHeaders.GetSettlement for servers with other header names.
 func GetSettlement(resp *http.Response) *v2.SettleResponse {
-	settlementHeader := resp.Header.Get("X-PAYMENT-RESPONSE")
-	if settlementHeader == "" {
-		return nil
-	}
-	return helpers.ParseSettlement(settlementHeader)
+	return PaymentHeaders{}.GetSettlement(resp)
 }
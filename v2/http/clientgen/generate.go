@@ -0,0 +1,273 @@
+package clientgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// Options configures the generated code.
+type Options struct {
+	// Package is the package name of the generated file.
+	Package string
+
+	// TypeName is the name of the client type. It defaults to "Client".
+	TypeName string
+}
+
+// Generate emits the source of a client for spec.
+func Generate(spec *Spec, opts Options) ([]byte, error) {
+	if !token.IsIdentifier(opts.Package) {
+		return nil, fmt.Errorf("clientgen: invalid package name %q", opts.Package)
+	}
+	if opts.TypeName == "" {
+		opts.TypeName = "Client"
+	}
+	if !token.IsIdentifier(opts.TypeName) || !token.IsExported(opts.TypeName) {
+		return nil, fmt.Errorf("clientgen: invalid type name %q", opts.TypeName)
+	}
+
+	data := templateData{Package: opts.Package, Type: opts.TypeName, BaseURL: spec.BaseURL}
+	names := make(map[string]int)
+	for _, endpoint := range spec.Endpoints {
+		m := newMethod(endpoint)
+		if names[m.Name]++; names[m.Name] > 1 {
+			m.Name += strconv.Itoa(names[m.Name])
+		}
+		if len(m.Params) > 0 {
+			data.UsesURL = true
+		}
+		data.Methods = append(data.Methods, m)
+	}
+
+	var buf bytes.Buffer
+	if err := clientTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("clientgen: %w", err)
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("clientgen: formatting generated code: %w", err)
+	}
+	return source, nil
+}
+
+type templateData struct {
+	Package string
+	Type    string
+	BaseURL string
+	UsesURL bool
+	Methods []method
+}
+
+type method struct {
+	Name     string
+	Method   string
+	Path     string
+	Summary  string
+	Prices   []string
+	Params   []param
+	PathExpr string
+	Body     bool
+}
+
+type param struct {
+	Name  string
+	Ident string
+}
+
+// reservedIdents are identifiers of generated methods that parameters must
+// not shadow.
+var reservedIdents = map[string]bool{"c": true, "ctx": true, "body": true, "url": true, "http": true, "io": true}
+
+func newMethod(endpoint Endpoint) method {
+	m := method{
+		Method:  strings.ToUpper(endpoint.Method),
+		Path:    endpoint.Path,
+		Summary: strings.Join(strings.Fields(endpoint.Summary), " "),
+	}
+	switch m.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		m.Body = true
+	}
+	for _, req := range endpoint.Accepts {
+		m.Prices = append(m.Prices, describePrice(req))
+	}
+
+	// The path becomes a concatenation of literals and escaped parameters,
+	// and the method name is built from its literals and parameter names.
+	var name strings.Builder
+	name.WriteString(exportedIdent(strings.ToLower(m.Method)))
+	var parts []string
+	rest := endpoint.Path
+	for {
+		open := strings.IndexByte(rest, '{')
+		closing := strings.IndexByte(rest, '}')
+		if open < 0 || closing < open {
+			name.WriteString(exportedIdent(rest))
+			if rest != "" || len(parts) == 0 {
+				parts = append(parts, strconv.Quote(rest))
+			}
+			break
+		}
+		if open > 0 {
+			name.WriteString(exportedIdent(rest[:open]))
+			parts = append(parts, strconv.Quote(rest[:open]))
+		}
+
+		p := param{Name: rest[open+1 : closing]}
+		p.Ident = unexportedIdent(p.Name)
+		if token.IsKeyword(p.Ident) || reservedIdents[p.Ident] {
+			p.Ident += "Param"
+		}
+		m.Params = append(m.Params, p)
+		name.WriteString("By" + exportedIdent(p.Name))
+		parts = append(parts, "url.PathEscape("+p.Ident+")")
+		rest = rest[closing+1:]
+	}
+	m.PathExpr = strings.Join(parts, " + ")
+
+	m.Name = name.String()
+	if endpoint.Name != "" {
+		if ident := exportedIdent(endpoint.Name); ident != "" {
+			m.Name = ident
+		}
+	}
+	return m
+}
+
+// words splits s into its alphanumeric runs, also breaking camelCase.
+func words(s string) []string {
+	var result []string
+	var current []rune
+	runes := []rune(s)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(current) > 0 {
+				result = append(result, string(current))
+				current = nil
+			}
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 && i > 0 && unicode.IsLower(runes[i-1]) {
+			result = append(result, string(current))
+			current = nil
+		}
+		current = append(current, r)
+	}
+	if len(current) > 0 {
+		result = append(result, string(current))
+	}
+	return result
+}
+
+// exportedIdent converts s to an exported Go identifier, like "weatherReport"
+// or "weather-report" to "WeatherReport".
+func exportedIdent(s string) string {
+	var b strings.Builder
+	for _, word := range words(s) {
+		runes := []rune(word)
+		b.WriteRune(unicode.ToUpper(runes[0]))
+		b.WriteString(string(runes[1:]))
+	}
+	ident := b.String()
+	if ident != "" && unicode.IsDigit([]rune(ident)[0]) {
+		ident = "N" + ident
+	}
+	return ident
+}
+
+// unexportedIdent converts s to an unexported Go identifier.
+func unexportedIdent(s string) string {
+	ident := exportedIdent(s)
+	if ident == "" {
+		return "param"
+	}
+	runes := []rune(ident)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// describePrice renders req for a doc comment, in display units when the
+// asset is a known USDC deployment.
+func describePrice(req v2.PaymentRequirements) string {
+	if chain, err := v2.GetChainConfig(req.Network); err == nil && strings.EqualFold(chain.USDCAddress, req.Asset) {
+		if amount, ok := new(big.Int).SetString(req.Amount, 10); ok {
+			return fmt.Sprintf("%s USDC on %s (%s)", v2.BigIntToAmount(amount, int(chain.Decimals)), req.Network, req.Scheme)
+		}
+	}
+	return fmt.Sprintf("%s units of %s on %s (%s)", req.Amount, req.Asset, req.Network, req.Scheme)
+}
+
+var clientTemplate = template.Must(template.New("client").Parse(`// Code generated by x402-clientgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"io"
+	"net/http"
+{{- if .UsesURL}}
+	"net/url"
+{{- end}}
+	"strings"
+
+	x402http "github.com/mark3labs/x402-go/v2/http"
+)
+
+// Default{{.Type}}BaseURL is the base URL of the API the {{.Type}} was generated for.
+const Default{{.Type}}BaseURL = {{printf "%q" .BaseURL}}
+
+// {{.Type}} calls the endpoints of the API, paying for them with x402.
+type {{.Type}} struct {
+	*x402http.Client
+
+	// BaseURL is the base URL requests are sent to.
+	BaseURL string
+}
+
+// New{{.Type}} creates a {{.Type}} for Default{{.Type}}BaseURL. Configure payments
+// with options like x402http.WithSigner.
+func New{{.Type}}(opts ...x402http.ClientOption) (*{{.Type}}, error) {
+	client, err := x402http.NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &{{.Type}}{Client: client, BaseURL: Default{{.Type}}BaseURL}, nil
+}
+{{range .Methods}}
+// {{.Name}} calls {{.Method}} {{.Path}}.
+{{- if .Summary}}
+// {{.Summary}}
+{{- end}}
+{{- if .Prices}}
+//
+// Accepted payments:
+{{- range .Prices}}
+//   - {{.}}
+{{- end}}
+{{- end}}
+func (c *{{$.Type}}) {{.Name}}(ctx context.Context{{range .Params}}, {{.Ident}} string{{end}}{{if .Body}}, body io.Reader{{end}}) (*http.Response, error) {
+	return c.do(ctx, {{printf "%q" .Method}}, {{.PathExpr}}, {{if .Body}}body{{else}}nil{{end}})
+}
+{{end}}
+// do sends a request to path relative to BaseURL.
+func (c *{{.Type}}) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.Do(req)
+}
+`))
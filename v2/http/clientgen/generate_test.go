@@ -0,0 +1,147 @@
+package clientgen
+
+import (
+	"context"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+)
+
+var testRequirement = v2.PaymentRequirements{
+	Scheme:            "exact",
+	Network:           v2.BaseSepolia.Network,
+	Amount:            "10000",
+	Asset:             v2.BaseSepolia.USDCAddress,
+	PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+	MaxTimeoutSeconds: 60,
+}
+
+// methods parses source and returns its method names with their parameters.
+func methods(t *testing.T, source []byte) map[string][]string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "client_gen.go", source, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Generated code does not parse: %v\n%s", err, source)
+	}
+	result := make(map[string][]string)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil {
+			continue
+		}
+		var params []string
+		for _, field := range fn.Type.Params.List {
+			for _, name := range field.Names {
+				params = append(params, name.Name)
+			}
+		}
+		result[fn.Name.Name] = params
+	}
+	return result
+}
+
+func TestGenerate_OpenAPI(t *testing.T) {
+	document, err := v2http.GenerateOpenAPI(v2http.OpenAPIInfo{Title: "Weather", Version: "1", Servers: []string{"https://api.example.com"}}, []v2http.Route{
+		{Method: "GET", Path: "/weather/{city}", Summary: "Current weather", Config: v2http.Config{PaymentRequirements: []v2.PaymentRequirements{testRequirement}}},
+		{Method: "GET", Path: "/weather/{city}/days/{type}", Config: v2http.Config{PaymentRequirements: []v2.PaymentRequirements{testRequirement}}},
+		{Method: "POST", Path: "/reports", Config: v2http.Config{PaymentRequirements: []v2.PaymentRequirements{testRequirement}}},
+	})
+	if err != nil {
+		t.Fatalf("GenerateOpenAPI failed: %v", err)
+	}
+
+	spec, err := Parse(document)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if spec.BaseURL != "https://api.example.com" || len(spec.Endpoints) != 3 {
+		t.Fatalf("Unexpected spec: %+v", spec)
+	}
+
+	source, err := Generate(spec, Options{Package: "weather", TypeName: "WeatherClient"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	got := methods(t, source)
+
+	want := map[string][]string{
+		"GetWeatherByCity":           {"ctx", "city"},
+		"GetWeatherByCityDaysByType": {"ctx", "city", "typeParam"},
+		"PostReports":                {"ctx", "body"},
+		"do":                         {"ctx", "method", "path", "body"},
+	}
+	for name, params := range want {
+		if strings.Join(got[name], ",") != strings.Join(params, ",") {
+			t.Errorf("Expected %s(%v), got %v", name, params, got[name])
+		}
+	}
+	for _, fragment := range []string{
+		`const DefaultWeatherClientBaseURL = "https://api.example.com"`,
+		`"/weather/"+url.PathEscape(city)+"/days/"+url.PathEscape(typeParam)`,
+		"0.010000 USDC on eip155:84532 (exact)",
+		"// Current weather",
+	} {
+		if !strings.Contains(string(source), fragment) {
+			t.Errorf("Expected generated code to contain %q:\n%s", fragment, source)
+		}
+	}
+}
+
+func TestFetch_PaymentRequired(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPaymentRequired)
+		_ = json.NewEncoder(w).Encode(v2.PaymentRequired{
+			X402Version: 2,
+			Resource:    &v2.ResourceInfo{URL: "https://api.example.com/premium-data", Description: "Premium data"},
+			Accepts:     []v2.PaymentRequirements{testRequirement},
+		})
+	}))
+	defer server.Close()
+
+	spec, err := Fetch(context.Background(), server.Client(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	source, err := Generate(spec, Options{Package: "premium"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if params, ok := methods(t, source)["GetPremiumData"]; !ok || len(params) != 1 {
+		t.Errorf("Expected GetPremiumData(ctx), got %v:\n%s", params, source)
+	}
+	if strings.Contains(string(source), `"net/url"`) {
+		t.Error("Expected no net/url import without path parameters")
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "not json", input: `nope`},
+		{name: "unknown format", input: `{"hello":"world"}`},
+		{name: "no resource", input: `{"x402Version":2,"accepts":[]}`},
+		{name: "relative resource", input: `{"x402Version":2,"resource":{"url":"/data"},"accepts":[]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.input)); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+
+	if _, err := Generate(&Spec{}, Options{Package: "not-a-package"}); err == nil {
+		t.Error("Expected error for invalid package name")
+	}
+}
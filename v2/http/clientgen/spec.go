@@ -0,0 +1,190 @@
+// Package clientgen generates typed Go clients for paid APIs.
+//
+// It reads either an OpenAPI document with x-x402 extensions, as served by
+// x402http.OpenAPIHandler, or the v2.PaymentRequired body of a 402 response,
+// and emits a client that embeds an x402http.Client and has one method per
+// endpoint. The x402-clientgen command wraps it for go:generate:
+//
+//	//go:generate go run github.com/mark3labs/x402-go/v2/cmd/x402-clientgen -in https://api.example.com/openapi.json -package weather -out client_gen.go
+package clientgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+)
+
+// ErrUnknownFormat is returned when an input is neither an OpenAPI document
+// nor a 402 payment requirements body.
+var ErrUnknownFormat = errors.New("clientgen: input is neither an OpenAPI document nor payment requirements")
+
+// Spec describes the API a client is generated for.
+type Spec struct {
+	// BaseURL is the default base URL of the API.
+	BaseURL string
+
+	Endpoints []Endpoint
+}
+
+// Endpoint is a single API operation.
+type Endpoint struct {
+	// Name is the operation ID, if any; the method name is derived from it or
+	// from Method and Path.
+	Name string
+
+	// Method is the HTTP method, like "GET".
+	Method string
+
+	// Path is the path template, like "/weather/{city}".
+	Path string
+
+	// Summary documents the endpoint.
+	Summary string
+
+	// Accepts lists the accepted payment options; empty for free endpoints.
+	Accepts []v2.PaymentRequirements
+}
+
+// Parse reads a Spec from an OpenAPI document or a v2.PaymentRequired body.
+func Parse(data []byte) (*Spec, error) {
+	var probe struct {
+		OpenAPI     string `json:"openapi"`
+		X402Version int    `json:"x402Version"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("clientgen: decoding input: %w", err)
+	}
+	switch {
+	case probe.OpenAPI != "":
+		return ParseOpenAPI(data)
+	case probe.X402Version != 0:
+		return ParsePaymentRequired(data)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// openAPIMethods are the operation keys of an OpenAPI path item.
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// ParseOpenAPI reads a Spec from an OpenAPI 3 document. Payment options are
+// taken from the x-x402 extension of each operation.
+func ParseOpenAPI(data []byte) (*Spec, error) {
+	var document struct {
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("clientgen: decoding OpenAPI document: %w", err)
+	}
+
+	spec := &Spec{}
+	if len(document.Servers) > 0 {
+		spec.BaseURL = document.Servers[0].URL
+	}
+
+	paths := make([]string, 0, len(document.Paths))
+	for path := range document.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		for _, method := range openAPIMethods {
+			raw, ok := document.Paths[path][method]
+			if !ok {
+				continue
+			}
+			var operation struct {
+				OperationID string                 `json:"operationId"`
+				Summary     string                 `json:"summary"`
+				Payment     *v2http.OpenAPIPayment `json:"x-x402"`
+			}
+			if err := json.Unmarshal(raw, &operation); err != nil {
+				return nil, fmt.Errorf("clientgen: decoding %s %s: %w", strings.ToUpper(method), path, err)
+			}
+
+			endpoint := Endpoint{
+				Name:    operation.OperationID,
+				Method:  strings.ToUpper(method),
+				Path:    path,
+				Summary: operation.Summary,
+			}
+			if operation.Payment != nil {
+				for _, price := range operation.Payment.Accepts {
+					endpoint.Accepts = append(endpoint.Accepts, price.PaymentRequirements)
+				}
+			}
+			spec.Endpoints = append(spec.Endpoints, endpoint)
+		}
+	}
+	return spec, nil
+}
+
+// ParsePaymentRequired reads a single-endpoint Spec from the body of a 402
+// response. The endpoint is a GET of the advertised resource URL.
+func ParsePaymentRequired(data []byte) (*Spec, error) {
+	var required v2.PaymentRequired
+	if err := json.Unmarshal(data, &required); err != nil {
+		return nil, fmt.Errorf("clientgen: decoding payment requirements: %w", err)
+	}
+	if required.Resource == nil || required.Resource.URL == "" {
+		return nil, fmt.Errorf("clientgen: payment requirements have no resource URL")
+	}
+
+	resource, err := url.Parse(required.Resource.URL)
+	if err != nil || resource.Scheme == "" || resource.Host == "" {
+		return nil, fmt.Errorf("clientgen: invalid resource URL %q", required.Resource.URL)
+	}
+	path := resource.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	return &Spec{
+		BaseURL: resource.Scheme + "://" + resource.Host,
+		Endpoints: []Endpoint{{
+			Method:  http.MethodGet,
+			Path:    path,
+			Summary: required.Resource.Description,
+			Accepts: required.Accepts,
+		}},
+	}, nil
+}
+
+// Fetch reads a Spec from url: an OpenAPI document, or a paid resource whose
+// 402 response describes it.
+func Fetch(ctx context.Context, client *http.Client, url string) (*Spec, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("clientgen: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("clientgen: fetching %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPaymentRequired {
+		return nil, fmt.Errorf("clientgen: fetching %s: unexpected status %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("clientgen: reading %s: %w", url, err)
+	}
+	return Parse(data)
+}
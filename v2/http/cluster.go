@@ -0,0 +1,212 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/cluster"
+	"github.com/mark3labs/x402-go/v2/http/internal/checks"
+	"github.com/mark3labs/x402-go/v2/storage"
+	"github.com/mark3labs/x402-go/v2/validation"
+)
+
+// claimWait bounds how long a retry waits for the settlement of the request
+// that claimed its payment first.
+const claimWait = 30 * time.Second
+
+// PaymentClaim is a payment claimed through Config.ClaimPayment. Its methods
+// may be called on a nil claim, which ClaimPayment returns when no
+// Coordinator is configured.
+type PaymentClaim struct {
+	coordinator *cluster.Coordinator
+	key         string
+	resource    string
+	requirement v2.PaymentRequirements
+	accepted    v2.PaymentRequirements
+	ttl         time.Duration
+	settlement  *v2.SettleResponse
+	done        bool
+}
+
+// claimResult is the outcome of a claim shared with retries, with the
+// requirement the payment was settled against and the payment's accepted
+// requirement, which retries must repeat.
+type claimResult struct {
+	Resource    string                  `json:"resource"`
+	Requirement *v2.PaymentRequirements `json:"requirement,omitempty"`
+	Accepted    *v2.PaymentRequirements `json:"accepted,omitempty"`
+	Settlement  *v2.SettleResponse      `json:"settlement,omitempty"`
+}
+
+// ClaimPayment claims payment through Coordinator so only one request, on any
+// replica, can use it. A retry of a request that already claimed the payment
+// waits for its settlement instead of settling again: the returned claim's
+// Settlement is then the settlement to reuse, and Verification the
+// verification result. Other uses of a claimed payment, including retries for
+// a different resource or with a different requirement or accepted
+// requirement (e.g., another price or range), fail with v2.ErrPaymentReplayed.
+// Claim payments once they are priced and matched (see CheckPrice and
+// StrictMatching) but before verifying them, since the facilitator may reject a
+// payment that was already settled.
+//
+// The claim lasts until the payment's authorization expires. Call Settled or
+// Consumed once the payment is used, and Release when it ends up unused, for
+// example when the handler fails, so the client can retry with it. Without a
+// Coordinator it returns a nil claim.
+func (c Config) ClaimPayment(ctx context.Context, payment *v2.PaymentPayload, requirement *v2.PaymentRequirements, resource string) (*PaymentClaim, error) {
+	if c.Coordinator == nil {
+		return nil, nil
+	}
+
+	signed, err := json.Marshal(payment.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("encoding payment: %w", err)
+	}
+	sum := sha256.Sum256(append([]byte(payment.Accepted.Network+"|"), signed...))
+	key := "payment:" + hex.EncodeToString(sum[:])
+	ttl := c.claimTTL(payment, requirement)
+
+	for {
+		claimed, err := c.Coordinator.Claim(ctx, key, ttl)
+		if err != nil {
+			return nil, fmt.Errorf("claiming payment: %w", err)
+		}
+		if claimed {
+			return &PaymentClaim{
+				coordinator: c.Coordinator,
+				key:         key,
+				resource:    resource,
+				requirement: *requirement,
+				accepted:    payment.Accepted,
+				ttl:         ttl,
+			}, nil
+		}
+
+		settlement, err := c.awaitSettlement(ctx, key, resource, payment, requirement)
+		if errors.Is(err, storage.ErrNotFound) {
+			// The claim was released without settling; try to claim it again
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &PaymentClaim{settlement: settlement, done: true}, nil
+	}
+}
+
+// awaitSettlement waits for the settlement of the claim on key, which must be
+// for resource, requirement and the accepted requirement of payment.
+func (c Config) awaitSettlement(ctx context.Context, key, resource string, payment *v2.PaymentPayload, requirement *v2.PaymentRequirements) (*v2.SettleResponse, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, claimWait)
+	defer cancel()
+
+	raw, err := c.Coordinator.Await(waitCtx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		if waitCtx.Err() != nil && ctx.Err() == nil {
+			// The first request is still running; refuse rather than wait longer
+			return nil, v2.ErrPaymentReplayed
+		}
+		return nil, fmt.Errorf("awaiting payment settlement: %w", err)
+	}
+
+	var result claimResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("decoding payment settlement: %w", err)
+	}
+	if result.Resource != resource || result.Settlement == nil {
+		return nil, v2.ErrPaymentReplayed
+	}
+	if result.Requirement == nil || !checks.SameRequirement(*result.Requirement, *requirement) {
+		return nil, fmt.Errorf("%w: requirement differs from the settled request", v2.ErrPaymentReplayed)
+	}
+	if result.Accepted == nil || !checks.SameRequirement(*result.Accepted, payment.Accepted) {
+		return nil, fmt.Errorf("%w: accepted requirement differs from the settled request", v2.ErrPaymentReplayed)
+	}
+	return result.Settlement, nil
+}
+
+// claimTTL returns how long a payment stays claimed: until its authorization
+// deadline when known, otherwise for the requirement's timeout, plus the
+// tolerated clock skew.
+func (c Config) claimTTL(payment *v2.PaymentPayload, requirement *v2.PaymentRequirements) time.Duration {
+	ttl := time.Duration(requirement.MaxTimeoutSeconds) * time.Second
+	if deadline, ok := validation.AuthorizationDeadline(*payment); ok {
+		ttl = deadline.Sub(v2.ClockOrSystem(c.Clock).Now())
+	}
+	if ttl < time.Minute {
+		ttl = time.Minute
+	}
+	return ttl + c.clockSkew()
+}
+
+// Settlement returns the settlement to reuse when the request is a retry of
+// one that already settled the payment, or nil if the payment is still to be
+// settled.
+func (p *PaymentClaim) Settlement() *v2.SettleResponse {
+	if p == nil {
+		return nil
+	}
+	return p.settlement
+}
+
+// Verification returns the verification result to reuse when the request is
+// a retry of one that already settled the payment, or nil if the payment is
+// still to be verified.
+func (p *PaymentClaim) Verification() *v2.VerifyResponse {
+	if p == nil || p.settlement == nil {
+		return nil
+	}
+	return &v2.VerifyResponse{IsValid: true, Payer: p.settlement.Payer}
+}
+
+// Settled records the payment's settlement, so retries of the request reuse it.
+func (p *PaymentClaim) Settled(settlement *v2.SettleResponse) {
+	p.resolve(settlement)
+}
+
+// Consumed marks the payment as used without a settlement to share, as in
+// verify-only mode. Retries are refused.
+func (p *PaymentClaim) Consumed() {
+	p.resolve(nil)
+}
+
+// Release gives up the claim unless the payment was settled or consumed, so
+// the client can retry with the payment.
+func (p *PaymentClaim) Release() {
+	if p == nil || p.done {
+		return
+	}
+	p.done = true
+	if err := p.coordinator.Unclaim(context.Background(), p.key); err != nil {
+		slog.Default().Warn("failed to release payment claim", "error", err)
+	}
+}
+
+func (p *PaymentClaim) resolve(settlement *v2.SettleResponse) {
+	if p == nil || p.done {
+		return
+	}
+	p.done = true
+	result, err := json.Marshal(claimResult{
+		Resource:    p.resource,
+		Requirement: &p.requirement,
+		Accepted:    &p.accepted,
+		Settlement:  settlement,
+	})
+	if err == nil {
+		err = p.coordinator.Resolve(context.Background(), p.key, result, p.ttl)
+	}
+	if err != nil {
+		slog.Default().Warn("failed to record payment settlement for retries", "error", err)
+	}
+}
@@ -0,0 +1,257 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/cluster"
+	"github.com/mark3labs/x402-go/v2/encoding"
+	"github.com/mark3labs/x402-go/v2/storage"
+)
+
+func TestMiddleware_Coordinator(t *testing.T) {
+	// Like a real facilitator, payments are rejected once settled
+	verifyValid, settleSuccess, settled := false, false, false
+	settleCalls := 0
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+		case "/verify":
+			if !verifyValid || settled {
+				_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: false, InvalidReason: "invalid_exact_evm_payload_authorization_nonce_used"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			settleCalls++
+			if !settleSuccess {
+				_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: false, ErrorReason: "nonce_pending"})
+				return
+			}
+			settled = true
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc", Payer: "0xPayerAddress"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+
+	// Two replicas sharing one store
+	store := storage.NewMemory()
+	newReplica := func() http.Handler {
+		middleware := NewX402Middleware(Config{
+			FacilitatorURL:      facilitatorServer.URL,
+			PaymentRequirements: []v2.PaymentRequirements{requirement},
+			Coordinator:         cluster.New(store),
+		})
+		return middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if payment := GetPaymentFromContext(r.Context()); payment == nil || payment.Payer != "0xPayerAddress" {
+				t.Errorf("expected the payer in the context, got %+v", payment)
+			}
+			_, _ = w.Write([]byte("OK"))
+		}))
+	}
+	replicaA, replicaB := newReplica(), newReplica()
+
+	paymentHeader, _ := encoding.EncodePayment(v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    requirement,
+		Payload:     map[string]interface{}{"signature": "0xsig"},
+	})
+	serve := func(handler http.Handler, path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-PAYMENT", paymentHeader)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// A payment failing verification is not left claimed
+	if code := serve(replicaA, "/api/data"); code != http.StatusPaymentRequired {
+		t.Fatalf("expected failed verification, got %d", code)
+	}
+	verifyValid = true
+
+	// A failed settlement releases the claim, so the payment can be retried
+	if code := serve(replicaA, "/api/data"); code == http.StatusOK {
+		t.Fatalf("expected failed settlement, got %d", code)
+	}
+	settleSuccess = true
+	if code := serve(replicaB, "/api/data"); code != http.StatusOK {
+		t.Fatalf("expected retried payment to succeed, got %d", code)
+	}
+
+	// A retry of the settled request reuses its settlement on any replica,
+	// without verifying the used payment again
+	if code := serve(replicaA, "/api/data"); code != http.StatusOK {
+		t.Errorf("expected retry to be served, got %d", code)
+	}
+	// The payment cannot be used for another resource
+	if code := serve(replicaA, "/api/other"); code != http.StatusPaymentRequired {
+		t.Errorf("expected replay for another resource to be refused with 402, got %d", code)
+	}
+	if settleCalls != 2 {
+		t.Errorf("expected 2 settle calls, got %d", settleCalls)
+	}
+}
+
+func TestMiddleware_CoordinatorRetryPrice(t *testing.T) {
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+		case "/verify":
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc", Payer: "0xPayerAddress"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	content := strings.Repeat("x", 1000)
+	middleware := NewX402Middleware(Config{
+		FacilitatorURL: facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{{
+			Scheme:  "exact",
+			Network: "eip155:84532",
+			Amount:  "10000",
+			Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+			PayTo:   "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		}},
+		RequirementsFunc: RangePricing(func(r *http.Request) (int64, error) { return int64(len(content)), nil }),
+		Coordinator:      cluster.New(storage.NewMemory()),
+	})
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+
+	// The signed payload is the same in every request; only the accepted
+	// amount and the Range header change
+	request := func(amount, rangeHeader string) int {
+		paymentHeader, _ := encoding.EncodePayment(v2.PaymentPayload{
+			X402Version: 2,
+			Accepted:    v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: amount},
+			Payload:     map[string]interface{}{"signature": "0xsig"},
+		})
+		req := httptest.NewRequest("GET", "/file", nil)
+		req.Header.Set("X-PAYMENT", paymentHeader)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request("10", "bytes=0-0"); code != http.StatusPartialContent {
+		t.Fatalf("expected paid range to be served, got %d", code)
+	}
+	if code := request("10", "bytes=0-0"); code != http.StatusPartialContent {
+		t.Errorf("expected retry of the range to be served, got %d", code)
+	}
+	if code := request("10000", ""); code != http.StatusPaymentRequired {
+		t.Errorf("expected retry for the whole resource to be refused with 402, got %d", code)
+	}
+	if code := request("10", "bytes=1-1"); code != http.StatusPaymentRequired {
+		t.Errorf("expected retry for another range to be refused with 402, got %d", code)
+	}
+}
+
+func TestConfig_ClaimPayment(t *testing.T) {
+	ctx := context.Background()
+	payment := &v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    v2.PaymentRequirements{Network: "eip155:84532"},
+		Payload:     map[string]interface{}{"signature": "0xsig"},
+	}
+	requirement := &payment.Accepted
+
+	// Without a Coordinator claims are not tracked
+	claim, err := (Config{}).ClaimPayment(ctx, payment, requirement, "GET /a")
+	if err != nil || claim != nil {
+		t.Fatalf("ClaimPayment without coordinator = %v, %v", claim, err)
+	}
+	claim.Settled(&v2.SettleResponse{Success: true})
+	claim.Release()
+
+	config := Config{Coordinator: cluster.New(storage.NewMemory(), cluster.WithPollInterval(time.Millisecond))}
+	claim, err = config.ClaimPayment(ctx, payment, requirement, "GET /a")
+	if err != nil || claim.Settlement() != nil {
+		t.Fatalf("ClaimPayment = %v, %v", claim, err)
+	}
+
+	// A released claim can be claimed again
+	claim.Release()
+	claim, err = config.ClaimPayment(ctx, payment, requirement, "GET /a")
+	if err != nil {
+		t.Fatalf("expected released payment to be claimable, got %v", err)
+	}
+
+	// A concurrent retry waits for the settlement and reuses it
+	retried := make(chan *PaymentClaim, 1)
+	go func() {
+		retry, err := config.ClaimPayment(ctx, payment, requirement, "GET /a")
+		if err != nil {
+			t.Errorf("retry ClaimPayment: %v", err)
+		}
+		retried <- retry
+	}()
+	claim.Settled(&v2.SettleResponse{Success: true, Transaction: "0xabc"})
+	claim.Release() // no-op once settled
+	retry := <-retried
+	if retry.Settlement() == nil || retry.Settlement().Transaction != "0xabc" {
+		t.Errorf("expected retry to reuse settlement, got %+v", retry.Settlement())
+	}
+
+	if _, err := config.ClaimPayment(ctx, payment, requirement, "GET /b"); !errors.Is(err, v2.ErrPaymentReplayed) {
+		t.Errorf("expected ErrPaymentReplayed for another resource, got %v", err)
+	}
+
+	// Retries must repeat the settled requirement and accepted requirement
+	repriced := *requirement
+	repriced.Amount = "10000"
+	if _, err := config.ClaimPayment(ctx, payment, &repriced, "GET /a"); !errors.Is(err, v2.ErrPaymentReplayed) {
+		t.Errorf("expected ErrPaymentReplayed for another requirement, got %v", err)
+	}
+	edited := *payment
+	edited.Accepted.Amount = "10000"
+	if _, err := config.ClaimPayment(ctx, &edited, requirement, "GET /a"); !errors.Is(err, v2.ErrPaymentReplayed) {
+		t.Errorf("expected ErrPaymentReplayed for an edited accepted requirement, got %v", err)
+	}
+}
+
+func TestConfig_ClaimPayment_Consumed(t *testing.T) {
+	ctx := context.Background()
+	payment := &v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    v2.PaymentRequirements{Network: "eip155:84532"},
+		Payload:     map[string]interface{}{"signature": "0xsig"},
+	}
+	config := Config{Coordinator: cluster.New(storage.NewMemory())}
+
+	claim, err := config.ClaimPayment(ctx, payment, &payment.Accepted, "GET /a")
+	if err != nil {
+		t.Fatalf("ClaimPayment: %v", err)
+	}
+	claim.Consumed()
+	if _, err := config.ClaimPayment(ctx, payment, &payment.Accepted, "GET /a"); !errors.Is(err, v2.ErrPaymentReplayed) {
+		t.Errorf("expected retry of consumed payment to be refused, got %v", err)
+	}
+}
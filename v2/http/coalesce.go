@@ -0,0 +1,220 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// maxSharedBody bounds the paid response body buffered for coalesced callers.
+// Larger responses are streamed to the first caller only.
+const maxSharedBody = 1 << 20
+
+// errNotShared reports that a paid response could not be shared, so waiting
+// callers pay on their own.
+var errNotShared = errors.New("x402: paid response not shared")
+
+// skipCoalescingKey marks requests that must not share a payment.
+type skipCoalescingKey struct{}
+
+// SkipCoalescing returns a context that makes the client pay for the request
+// on its own even if an identical request is in flight. Use it for GET routes
+// that are not idempotent, where every call must be paid and executed.
+func SkipCoalescing(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipCoalescingKey{}, true)
+}
+
+// sharedResponse is a paid response buffered for every coalesced caller.
+type sharedResponse struct {
+	resp *http.Response
+	body []byte
+}
+
+// response returns a copy of the shared response with its own body for req.
+func (s *sharedResponse) response(req *http.Request) *http.Response {
+	resp := *s.resp
+	resp.Header = s.resp.Header.Clone()
+	resp.Trailer = s.resp.Trailer.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(s.body))
+	resp.ContentLength = int64(len(s.body))
+	resp.Request = req
+	return &resp
+}
+
+// sharedCall is a paid request that identical requests wait for.
+type sharedCall struct {
+	done      chan struct{}
+	followers int // guarded by X402Transport.inflightMu
+	shared    *sharedResponse
+	err       error
+}
+
+// finish hands shared, or err when the response is not shared, to the
+// waiting callers.
+func (c *sharedCall) finish(shared *sharedResponse, err error) {
+	c.shared, c.err = shared, err
+	close(c.done)
+}
+
+// coalescingKey identifies req among concurrent paid requests. Only GET
+// requests without a body are coalesced, and only when the method, URL and
+// headers are identical. Range requests and event streams are never
+// coalesced.
+func (t *X402Transport) coalescingKey(req *http.Request) (string, bool) {
+	if !t.Coalesce || req.Method != http.MethodGet {
+		return "", false
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		return "", false
+	}
+	if req.Header.Get("Range") != "" || strings.Contains(req.Header.Get("Accept"), "text/event-stream") {
+		return "", false
+	}
+	if skip, _ := req.Context().Value(skipCoalescingKey{}).(bool); skip {
+		return "", false
+	}
+	return requestKey(req), true
+}
+
+// requestKey identifies req by its URL and headers.
+func requestKey(req *http.Request) string {
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var key strings.Builder
+	key.WriteString(req.URL.String())
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			fmt.Fprintf(&key, "\n%s: %s", name, value)
+		}
+	}
+	return key.String()
+}
+
+// shareable reports whether resp can be buffered for coalesced callers.
+// Partial content, event streams, downloads and large bodies are streamed to
+// the first caller only.
+func shareable(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusPartialContent || resp.ContentLength > maxSharedBody {
+		return false
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "text/event-stream" {
+		return false
+	}
+	disposition, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Disposition"))
+	return disposition != "attachment"
+}
+
+// payShared pays for req once for all concurrent callers with the same key.
+// The first caller signs the payment and receives the response as it
+// streams. Callers that arrived before the response did wait for it, and
+// receive copies of the body once the first caller has read it completely;
+// if the response cannot be shared or the first caller stops reading early,
+// they pay on their own. Payment callbacks fire once per payment.
+func (t *X402Transport) payShared(key string, req *http.Request, paymentReq *v2.PaymentRequired) (*http.Response, error) {
+	t.inflightMu.Lock()
+	if call, ok := t.inflight[key]; ok {
+		call.followers++
+		t.inflightMu.Unlock()
+		return t.follow(call, req, paymentReq)
+	}
+	call := &sharedCall{done: make(chan struct{})}
+	if t.inflight == nil {
+		t.inflight = make(map[string]*sharedCall)
+	}
+	t.inflight[key] = call
+	t.inflightMu.Unlock()
+
+	resp, err := t.pay(req, paymentReq)
+
+	// Later callers can no longer see the whole body
+	t.inflightMu.Lock()
+	delete(t.inflight, key)
+	followers := call.followers
+	t.inflightMu.Unlock()
+
+	if err != nil {
+		call.finish(nil, err)
+		return nil, err
+	}
+	if followers == 0 || !shareable(resp) {
+		call.finish(nil, errNotShared)
+		return resp, nil
+	}
+	resp.Body = &teeBody{body: resp.Body, resp: resp, call: call}
+	return resp, nil
+}
+
+// follow waits for call and returns a copy of its response for req.
+func (t *X402Transport) follow(call *sharedCall, req *http.Request, paymentReq *v2.PaymentRequired) (*http.Response, error) {
+	select {
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	case <-call.done:
+	}
+	if call.shared != nil {
+		return call.shared.response(req), nil
+	}
+	// The caller that was paying gave up, or the response was not shared;
+	// pay for this request separately.
+	if errors.Is(call.err, errNotShared) || errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded) {
+		return t.pay(req, paymentReq)
+	}
+	return nil, call.err
+}
+
+// teeBody streams a paid response body to the first caller and buffers up to
+// maxSharedBody bytes of it for the callers waiting on call.
+type teeBody struct {
+	body     io.ReadCloser
+	resp     *http.Response
+	call     *sharedCall
+	buf      bytes.Buffer
+	finished bool
+}
+
+func (b *teeBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if !b.finished {
+		if b.buf.Len()+n > maxSharedBody {
+			b.finish(false)
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err != nil {
+		b.finish(err == io.EOF)
+	}
+	return n, err
+}
+
+func (b *teeBody) Close() error {
+	b.finish(false)
+	return b.body.Close()
+}
+
+// finish shares the buffered body if complete, and otherwise releases the
+// waiting callers to pay on their own.
+func (b *teeBody) finish(complete bool) {
+	if b.finished {
+		return
+	}
+	b.finished = true
+	if complete {
+		b.call.finish(&sharedResponse{resp: b.resp, body: b.buf.Bytes()}, nil)
+		return
+	}
+	b.buf = bytes.Buffer{}
+	b.call.finish(nil, errNotShared)
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
+)
+
+func TestTransport_Coalescing(t *testing.T) {
+	const callers = 5
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		coalesce     bool
+		skip         bool
+		header       http.Header
+		contentType  string
+		bodySize     int
+		wantPayments int32
+	}{
+		{name: "identical GETs share a payment", method: "GET", coalesce: true, wantPayments: 1},
+		{name: "disabled by default", method: "GET", wantPayments: callers},
+		{name: "skipped per request", method: "GET", coalesce: true, skip: true, wantPayments: callers},
+		{name: "POST is never coalesced", method: "POST", coalesce: true, wantPayments: callers},
+		{name: "range requests are not coalesced", method: "GET", coalesce: true, header: http.Header{"Range": {"bytes=0-99"}}, wantPayments: callers},
+		{name: "event streams are not shared", method: "GET", coalesce: true, contentType: "text/event-stream", wantPayments: callers},
+		{name: "large responses are not shared", method: "GET", coalesce: true, bodySize: maxSharedBody + 1, wantPayments: callers},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "Protected content"
+			if tt.bodySize > 0 {
+				content = strings.Repeat("x", tt.bodySize)
+			}
+			var challenged, payments int32
+			allChallenged := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("X-PAYMENT") == "" {
+					if atomic.AddInt32(&challenged, 1) == callers {
+						close(allChallenged)
+					}
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusPaymentRequired)
+					_ = json.NewEncoder(w).Encode(v2.PaymentRequired{
+						X402Version: 2,
+						Accepts:     []v2.PaymentRequirements{requirement},
+					})
+					return
+				}
+				atomic.AddInt32(&payments, 1)
+				// Hold the paid response until every caller is waiting for one.
+				<-allChallenged
+				time.Sleep(50 * time.Millisecond)
+				encoded, _ := encoding.EncodeSettlement(v2.SettleResponse{Success: true, Transaction: "0xtx", Network: requirement.Network})
+				w.Header().Set("X-PAYMENT-RESPONSE", encoded)
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				_, _ = w.Write([]byte(content))
+			}))
+			defer server.Close()
+
+			transport := &X402Transport{
+				Base:     http.DefaultTransport,
+				Signers:  []v2.Signer{&mockSigner{network: "eip155:84532", scheme: "exact"}},
+				Selector: v2.NewDefaultPaymentSelector(),
+				Coalesce: tt.coalesce,
+			}
+
+			var wg sync.WaitGroup
+			for i := 0; i < callers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ctx := context.Background()
+					if tt.skip {
+						ctx = SkipCoalescing(ctx)
+					}
+					req, _ := http.NewRequestWithContext(ctx, tt.method, server.URL+"/report", nil)
+					for name, values := range tt.header {
+						req.Header[name] = values
+					}
+					resp, err := transport.RoundTrip(req)
+					if err != nil {
+						t.Errorf("RoundTrip failed: %v", err)
+						return
+					}
+					defer resp.Body.Close()
+					body, _ := io.ReadAll(resp.Body)
+					if resp.StatusCode != http.StatusOK || string(body) != content {
+						t.Errorf("Unexpected response %d of %d bytes", resp.StatusCode, len(body))
+					}
+					if settlement := GetSettlement(resp); settlement == nil || settlement.Transaction != "0xtx" {
+						t.Errorf("Expected settlement on every response, got %+v", settlement)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if got := atomic.LoadInt32(&payments); got != tt.wantPayments {
+				t.Errorf("Expected %d payments, got %d", tt.wantPayments, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Package connect provides x402 v2 payment gating for connect-go services.
+//
+// Clients send the base64-encoded payment in the X-PAYMENT request header.
+// Calls without a valid payment fail with a Connect error whose metadata
+// carries the base64-encoded v2.PaymentRequired in X-PAYMENT-REQUIRED, the RPC
+// equivalent of an HTTP 402 response. Settlements are returned in the
+// X-PAYMENT-RESPONSE response header, or trailer for streaming calls.
+package connect
+
+import (
+	"context"
+	"errors"
+
+	"connectrpc.com/connect"
+	v2 "github.com/mark3labs/x402-go/v2"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+	"github.com/mark3labs/x402-go/v2/http/internal/rpcgate"
+)
+
+// Header names used by the interceptor.
+const (
+	PaymentHeader         = rpcgate.PaymentHeader
+	PaymentRequiredHeader = rpcgate.PaymentRequiredHeader
+	PaymentResponseHeader = rpcgate.PaymentResponseHeader
+)
+
+// Option configures an Interceptor.
+type Option func(*options)
+
+type options struct {
+	procedures          map[string][]v2.PaymentRequirements
+	paymentRequiredCode connect.Code
+}
+
+// WithProcedure requires payment for procedure (like "/acme.v1.WeatherService/Forecast")
+// with requirements. Once any procedure is listed, unlisted procedures are
+// free; without WithProcedure every procedure requires
+// Config.PaymentRequirements.
+func WithProcedure(procedure string, requirements ...v2.PaymentRequirements) Option {
+	return func(o *options) {
+		if o.procedures == nil {
+			o.procedures = make(map[string][]v2.PaymentRequirements)
+		}
+		o.procedures[procedure] = requirements
+	}
+}
+
+// WithPaymentRequiredCode sets the code of payment required errors. It
+// defaults to connect.CodeFailedPrecondition.
+func WithPaymentRequiredCode(code connect.Code) Option {
+	return func(o *options) {
+		o.paymentRequiredCode = code
+	}
+}
+
+// Interceptor is a connect.Interceptor that gates handlers behind payments.
+// It passes client calls through unchanged.
+type Interceptor struct {
+	gate                *rpcgate.Gate
+	paymentRequiredCode connect.Code
+}
+
+var _ connect.Interceptor = (*Interceptor)(nil)
+
+// NewInterceptor creates an Interceptor using the facilitators, requirements
+// and settings of config.
+//
+// Example:
+//
+//	interceptor := connect.NewInterceptor(x402http.Config{
+//	    FacilitatorURL: "https://facilitator.x402.org",
+//	}, connect.WithProcedure(weatherv1connect.WeatherServiceForecastProcedure, requirement))
+//	mux.Handle(weatherv1connect.NewWeatherServiceHandler(svc, connectrpc.WithInterceptors(interceptor)))
+func NewInterceptor(config v2http.Config, opts ...Option) *Interceptor {
+	o := options{paymentRequiredCode: connect.CodeFailedPrecondition}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Interceptor{
+		gate:                rpcgate.New(config, o.procedures),
+		paymentRequiredCode: o.paymentRequiredCode,
+	}
+}
+
+// WrapUnary implements connect.Interceptor.
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+
+		payment, failure := i.gate.Verify(ctx, req.Spec().Procedure, i.gate.ReadPaymentHeader(req.Header()))
+		if failure != nil {
+			return nil, i.error(failure)
+		}
+		if payment == nil {
+			return next(ctx, req)
+		}
+
+		resp, err := next(payment.Context(ctx), req)
+		if err != nil {
+			payment.Release()
+			return resp, err
+		}
+		settlement, failure := payment.Settle(ctx)
+		if failure != nil {
+			return nil, i.error(failure)
+		}
+		if settlement != "" {
+			resp.Header().Set(i.gate.PaymentResponseHeader(), settlement)
+		}
+		return resp, nil
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor.
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor. Streams are settled
+// after the handler returns successfully, with the settlement in the
+// PaymentResponseHeader trailer.
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		payment, failure := i.gate.Verify(ctx, conn.Spec().Procedure, i.gate.ReadPaymentHeader(conn.RequestHeader()))
+		if failure != nil {
+			return i.error(failure)
+		}
+		if payment == nil {
+			return next(ctx, conn)
+		}
+
+		if err := next(payment.Context(ctx), conn); err != nil {
+			payment.Release()
+			return err
+		}
+		settlement, failure := payment.Settle(ctx)
+		if failure != nil {
+			return i.error(failure)
+		}
+		if settlement != "" {
+			conn.ResponseTrailer().Set(i.gate.PaymentResponseHeader(), settlement)
+		}
+		return nil
+	}
+}
+
+// error converts a gating failure to a Connect error.
+func (i *Interceptor) error(failure *rpcgate.Failure) *connect.Error {
+	code := connect.CodeUnavailable
+	switch failure.Kind {
+	case rpcgate.KindPaymentRequired:
+		code = i.paymentRequiredCode
+	case rpcgate.KindInvalidPayment:
+		code = connect.CodeInvalidArgument
+	}
+
+	err := connect.NewError(code, errors.New(failure.Message))
+	if failure.PaymentRequired != "" {
+		err.Meta().Set(PaymentRequiredHeader, failure.PaymentRequired)
+	}
+	return err
+}
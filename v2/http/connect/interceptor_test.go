@@ -0,0 +1,155 @@
+package connect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+)
+
+// jsonCodec lets the tests use plain structs instead of generated messages.
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                       { return "json" }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type message struct {
+	Text string `json:"text"`
+}
+
+func TestInterceptor_Unary(t *testing.T) {
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	const paidProcedure = "/test.v1.TestService/Paid"
+	const freeProcedure = "/test.v1.TestService/Free"
+
+	tests := []struct {
+		name         string
+		procedure    string
+		payment      string
+		valid        bool
+		down         bool
+		wantCode     connect.Code
+		wantRequired bool
+		wantSettled  bool
+		wantPayer    string
+	}{
+		{name: "no payment", procedure: paidProcedure, wantCode: connect.CodeFailedPrecondition, wantRequired: true},
+		{name: "invalid header", procedure: paidProcedure, payment: "not-base64!", wantCode: connect.CodeInvalidArgument},
+		{name: "rejected payment", procedure: paidProcedure, payment: "valid", wantCode: connect.CodeFailedPrecondition, wantRequired: true},
+		{name: "facilitator down", procedure: paidProcedure, payment: "valid", down: true, wantCode: connect.CodeUnavailable},
+		{name: "paid", procedure: paidProcedure, payment: "valid", valid: true, wantSettled: true, wantPayer: "0xPayerAddress"},
+		{name: "free procedure", procedure: freeProcedure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settled := false
+			facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/supported":
+					_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+				case "/verify":
+					if tt.down {
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: tt.valid, InvalidReason: "insufficient_funds", Payer: "0xPayerAddress"})
+				case "/settle":
+					settled = true
+					_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xtx", Network: "eip155:84532"})
+				}
+			}))
+			defer facilitatorServer.Close()
+
+			interceptor := NewInterceptor(v2http.Config{FacilitatorURL: facilitatorServer.URL},
+				WithProcedure(paidProcedure, requirement))
+
+			var payer string
+			handle := func(ctx context.Context, req *connect.Request[message]) (*connect.Response[message], error) {
+				if payment := v2http.GetPaymentFromContext(ctx); payment != nil {
+					payer = payment.Payer
+				}
+				return connect.NewResponse(&message{Text: "hello " + req.Msg.Text}), nil
+			}
+			mux := http.NewServeMux()
+			for _, procedure := range []string{paidProcedure, freeProcedure} {
+				mux.Handle(procedure, connect.NewUnaryHandler(procedure, handle,
+					connect.WithInterceptors(interceptor), connect.WithCodec(jsonCodec{})))
+			}
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			client := connect.NewClient[message, message](server.Client(), server.URL+tt.procedure, connect.WithCodec(jsonCodec{}))
+			req := connect.NewRequest(&message{Text: "world"})
+			switch tt.payment {
+			case "valid":
+				header, _ := encoding.EncodePayment(v2.PaymentPayload{
+					X402Version: 2,
+					Accepted:    requirement,
+					Payload:     map[string]interface{}{"signature": "0xsig"},
+				})
+				req.Header().Set(PaymentHeader, header)
+			case "":
+			default:
+				req.Header().Set(PaymentHeader, tt.payment)
+			}
+			resp, err := client.CallUnary(context.Background(), req)
+
+			if tt.wantCode != 0 {
+				var connectErr *connect.Error
+				if !errors.As(err, &connectErr) {
+					t.Fatalf("Expected connect error, got %v", err)
+				}
+				if connectErr.Code() != tt.wantCode {
+					t.Errorf("Expected code %v, got %v", tt.wantCode, connectErr.Code())
+				}
+				required := connectErr.Meta().Get(PaymentRequiredHeader)
+				if (required != "") != tt.wantRequired {
+					t.Fatalf("Expected payment requirements=%v, got %q", tt.wantRequired, required)
+				}
+				if tt.wantRequired {
+					paymentRequired, err := encoding.DecodeRequirements(required)
+					if err != nil {
+						t.Fatalf("Failed to decode payment requirements: %v", err)
+					}
+					if len(paymentRequired.Accepts) != 1 || paymentRequired.Resource == nil || paymentRequired.Resource.URL != paidProcedure {
+						t.Errorf("Unexpected payment requirements: %+v", paymentRequired)
+					}
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Call failed: %v", err)
+			}
+			if resp.Msg.Text != "hello world" {
+				t.Errorf("Unexpected response %q", resp.Msg.Text)
+			}
+			if settled != tt.wantSettled {
+				t.Errorf("Expected settled=%v, got %v", tt.wantSettled, settled)
+			}
+			if got := resp.Header().Get(PaymentResponseHeader) != ""; got != tt.wantSettled {
+				t.Errorf("Expected payment response header=%v, got %v", tt.wantSettled, got)
+			}
+			if payer != tt.wantPayer {
+				t.Errorf("Expected payer %q in handler context, got %q", tt.wantPayer, payer)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCORS is returned by Config.Validate for a CORS policy browsers
+// reject, like credentials with a wildcard origin.
+var ErrInvalidCORS = errors.New("x402: invalid CORS configuration")
+
+// CORSConfig lets browser-based payers call paid endpoints cross-origin: it
+// allows the payment header in preflights and exposes the settlement, receipt
+// and delivery headers, which browsers otherwise hide from scripts. The
+// payment header names follow Config.Headers.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed, like "https://app.example.com",
+	// or "*" for any. Empty allows any origin.
+	AllowedOrigins []string
+
+	// AllowedMethods lists the methods allowed in preflights (default: GET,
+	// HEAD, POST, PUT, PATCH and DELETE).
+	AllowedMethods []string
+
+	// AllowedHeaders lists request headers allowed besides the payment
+	// header, Authorization when it carries payments, and Content-Type.
+	AllowedHeaders []string
+
+	// ExposedHeaders lists response headers exposed besides the settlement,
+	// receipt and delivery headers.
+	ExposedHeaders []string
+
+	// AllowCredentials lets requests carry cookies and HTTP authentication.
+	// It requires explicit AllowedOrigins.
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache a preflight response (default:
+	// not cached beyond the browser's own limit).
+	MaxAge time.Duration
+}
+
+// defaultCORSMethods are the methods allowed when AllowedMethods is empty.
+var defaultCORSMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// Validate reports policies browsers reject.
+func (c *CORSConfig) Validate() error {
+	if c == nil || !c.AllowCredentials {
+		return nil
+	}
+	if c.anyOrigin() {
+		return errors.Join(ErrInvalidCORS, errors.New("credentials require explicit allowed origins"))
+	}
+	return nil
+}
+
+// anyOrigin reports whether every origin is allowed.
+func (c *CORSConfig) anyOrigin() bool {
+	if len(c.AllowedOrigins) == 0 {
+		return true
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed reports whether origin is allowed.
+func (c *CORSConfig) allowed(origin string) bool {
+	if c.anyOrigin() {
+		return true
+	}
+	for _, allowed := range c.AllowedOrigins {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle adds the CORS headers for r to w and reports whether r was a
+// preflight request, which it answers with 204 No Content. It is shared by
+// the net/http and Gin middleware, which serve other OPTIONS requests without
+// payment when CORS is configured.
+func (c *CORSConfig) Handle(w http.ResponseWriter, r *http.Request, headers PaymentHeaders) bool {
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	preflight := IsPreflight(r)
+	if preflight {
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+	}
+	if origin == "" || !c.allowed(origin) {
+		if preflight {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return preflight
+	}
+
+	if c.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Set("Access-Control-Allow-Credentials", "true")
+	} else if c.anyOrigin() {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if !preflight {
+		exposed := append([]string{headers.PaymentResponseHeader(), PaymentReceiptHeader, PaymentDeliveryHeader}, c.ExposedHeaders...)
+		header.Set("Access-Control-Expose-Headers", strings.Join(exposed, ", "))
+		return false
+	}
+
+	methods := c.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	allowed := []string{headers.PaymentHeader(), PaymentReceiptHeader, "Content-Type"}
+	if headers.Authorization {
+		allowed = append(allowed, "Authorization")
+	}
+	allowed = append(allowed, c.AllowedHeaders...)
+	header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	header.Set("Access-Control-Allow-Headers", strings.Join(allowed, ", "))
+	if c.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge/time.Second)))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// IsPreflight reports whether r is a CORS preflight request.
+func IsPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func TestCORSConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cors    *CORSConfig
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "any origin", cors: &CORSConfig{}},
+		{name: "credentials with origins", cors: &CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true}},
+		{name: "credentials with any origin", cors: &CORSConfig{AllowCredentials: true}, wantErr: true},
+		{name: "credentials with wildcard", cors: &CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Config{CORS: tt.cors}.Validate()
+			if tt.wantErr != errors.Is(err, ErrInvalidCORS) {
+				t.Errorf("Validate() = %v; wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMiddleware_CORS(t *testing.T) {
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+	}))
+	defer facilitatorServer.Close()
+
+	var reached []string
+	handler := NewX402Middleware(Config{
+		FacilitatorURL: facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{{
+			Scheme:  "exact",
+			Network: "eip155:84532",
+			Amount:  "10000",
+			Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+			PayTo:   "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		}},
+		Headers: PaymentHeaders{Authorization: true},
+		CORS: &CORSConfig{
+			AllowedOrigins:   []string{"https://app.example.com"},
+			AllowCredentials: true,
+			MaxAge:           10 * time.Minute,
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = append(reached, r.Method)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	t.Run("preflight", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodOptions, "/paid", nil)
+		r.Header.Set("Origin", "https://app.example.com")
+		r.Header.Set("Access-Control-Request-Method", "GET")
+		r.Header.Set("Access-Control-Request-Headers", "x-payment")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d", w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+			t.Errorf("Access-Control-Allow-Origin = %q", got)
+		}
+		allowed := w.Header().Get("Access-Control-Allow-Headers")
+		if !strings.Contains(allowed, "X-PAYMENT") || !strings.Contains(allowed, "Authorization") {
+			t.Errorf("Access-Control-Allow-Headers = %q; want the payment headers", allowed)
+		}
+		if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+			t.Errorf("Access-Control-Max-Age = %q", got)
+		}
+	})
+
+	t.Run("402 exposes settlement headers", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/paid", nil)
+		r.Header.Set("Origin", "https://app.example.com")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != http.StatusPaymentRequired {
+			t.Fatalf("expected 402, got %d", w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+			t.Errorf("Access-Control-Allow-Credentials = %q", got)
+		}
+		if exposed := w.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(exposed, DefaultPaymentResponseHeader) {
+			t.Errorf("Access-Control-Expose-Headers = %q; want %s", exposed, DefaultPaymentResponseHeader)
+		}
+	})
+
+	t.Run("other origin", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/paid", nil)
+		r.Header.Set("Origin", "https://evil.example.com")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Access-Control-Allow-Origin = %q for a disallowed origin", got)
+		}
+	})
+
+	t.Run("plain OPTIONS bypasses payment", func(t *testing.T) {
+		reached = nil
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/paid", nil))
+
+		if w.Code != http.StatusOK || len(reached) != 1 {
+			t.Errorf("expected the handler to serve OPTIONS, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/validation"
+)
+
+// PaymentDeadline returns the time after which payment can no longer be
+// verified or settled: the earlier of its authorization deadline (validBefore
+// or the Permit2 deadline) and received plus requirement's MaxTimeoutSeconds,
+// where received is when the payment arrived. It returns false when neither
+// is known.
+func (c Config) PaymentDeadline(payment *v2.PaymentPayload, requirement *v2.PaymentRequirements, received time.Time) (time.Time, bool) {
+	deadline, ok := validation.AuthorizationDeadline(*payment)
+	if requirement.MaxTimeoutSeconds > 0 {
+		timeout := received.Add(time.Duration(requirement.MaxTimeoutSeconds) * time.Second)
+		if !ok || timeout.Before(deadline) {
+			deadline, ok = timeout, true
+		}
+	}
+	return deadline, ok
+}
+
+// PaymentContext returns ctx bounded by the PaymentDeadline of payment, for
+// facilitator calls about it, so that a payment is never settled after its
+// authorization expired. ClockSkew is allowed past the deadline. It fails with
+// v2.ErrAuthorizationExpired if the deadline has already passed. Without a
+// deadline, ctx is returned unchanged and the facilitator's Timeouts apply.
+func (c Config) PaymentContext(ctx context.Context, payment *v2.PaymentPayload, requirement *v2.PaymentRequirements, received time.Time) (context.Context, context.CancelFunc, error) {
+	deadline, ok := c.PaymentDeadline(payment, requirement, received)
+	if !ok {
+		return ctx, func() {}, nil
+	}
+	remaining := deadline.Add(c.clockSkew()).Sub(v2.ClockOrSystem(c.Clock).Now())
+	if remaining <= 0 {
+		return nil, nil, fmt.Errorf("%w at %s", v2.ErrAuthorizationExpired, deadline.UTC().Format(time.RFC3339))
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, remaining,
+		fmt.Errorf("%w at %s before the facilitator responded", v2.ErrAuthorizationExpired, deadline.UTC().Format(time.RFC3339)))
+	return ctx, cancel, nil
+}
+
+// DeadlineError returns the v2.ErrAuthorizationExpired cause of ctx, as
+// returned by PaymentContext, if the payment deadline passed; otherwise it
+// returns err.
+func DeadlineError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if cause := context.Cause(ctx); errors.Is(cause, v2.ErrAuthorizationExpired) {
+		return cause
+	}
+	return err
+}
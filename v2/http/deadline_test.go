@@ -0,0 +1,136 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
+)
+
+func TestConfig_PaymentDeadline(t *testing.T) {
+	received := time.Unix(1700000000, 0)
+	evmPayment := func(validBefore int64) *v2.PaymentPayload {
+		return &v2.PaymentPayload{Payload: v2.EVMPayload{Authorization: v2.EVMAuthorization{ValidBefore: strconv.FormatInt(validBefore, 10)}}}
+	}
+	svmPayment := &v2.PaymentPayload{Payload: v2.SVMPayload{Transaction: "AQ=="}}
+
+	tests := []struct {
+		name       string
+		payment    *v2.PaymentPayload
+		maxTimeout int
+		want       time.Time
+		wantOK     bool
+	}{
+		{"authorization expires first", evmPayment(received.Unix() + 30), 60, received.Add(30 * time.Second), true},
+		{"timeout passes first", evmPayment(received.Unix() + 600), 60, received.Add(time.Minute), true},
+		{"authorization without timeout", evmPayment(received.Unix() + 600), 0, received.Add(10 * time.Minute), true},
+		{"timeout without authorization", svmPayment, 60, received.Add(time.Minute), true},
+		{"neither", svmPayment, 0, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deadline, ok := Config{}.PaymentDeadline(tt.payment, &v2.PaymentRequirements{MaxTimeoutSeconds: tt.maxTimeout}, received)
+			if ok != tt.wantOK || !deadline.Equal(tt.want) {
+				t.Errorf("PaymentDeadline = %v, %v; want %v, %v", deadline, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestConfig_PaymentContext(t *testing.T) {
+	clock := v2.NewFakeClock(time.Unix(1700000000, 0))
+	config := Config{Clock: clock, ClockSkew: 5 * time.Second}
+	requirement := &v2.PaymentRequirements{MaxTimeoutSeconds: 60}
+	payment := &v2.PaymentPayload{Payload: v2.SVMPayload{Transaction: "AQ=="}}
+
+	ctx, cancel, err := config.PaymentContext(context.Background(), payment, requirement, clock.Now())
+	if err != nil {
+		t.Fatalf("PaymentContext failed: %v", err)
+	}
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Minute+5*time.Second {
+		t.Errorf("expected a deadline within the requirement's timeout and skew, got %v, %v", deadline, ok)
+	}
+
+	// Cancellation is not mistaken for expiry
+	cancel()
+	if err := DeadlineError(ctx, context.Canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancellation to be kept, got %v", err)
+	}
+
+	if _, _, err := config.PaymentContext(context.Background(), payment, requirement, clock.Now().Add(-time.Minute-5*time.Second)); !errors.Is(err, v2.ErrAuthorizationExpired) {
+		t.Errorf("expected ErrAuthorizationExpired, got %v", err)
+	}
+}
+
+func TestMiddleware_PaymentDeadline(t *testing.T) {
+	settleCalled := false
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+		case "/verify":
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			settleCalled = true
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	clock := v2.NewFakeClock(time.Now())
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	config := Config{
+		FacilitatorURL:      facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{requirement},
+		Clock:               clock,
+	}
+
+	// The handler outlives the authorization
+	handler := NewX402Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clock.Advance(time.Minute)
+		_, _ = w.Write([]byte("OK"))
+	}))
+
+	paymentHeader, _ := encoding.EncodePayment(v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    requirement,
+		Payload: v2.EVMPayload{
+			Signature: "0xsig",
+			Authorization: v2.EVMAuthorization{
+				ValidAfter:  strconv.FormatInt(clock.Now().Unix()-10, 10),
+				ValidBefore: strconv.FormatInt(clock.Now().Unix()+30, 10),
+			},
+		},
+	})
+	req := httptest.NewRequest("GET", "/api/data", nil)
+	req.Header.Set("X-PAYMENT", paymentHeader)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPaymentRequired {
+		t.Fatalf("expected status 402, got %d", w.Code)
+	}
+	if settleCalled {
+		t.Error("expected no settlement after the authorization expired")
+	}
+	if !strings.Contains(w.Body.String(), "authorization expired") {
+		t.Errorf("expected an expiry error, got %s", w.Body.String())
+	}
+}
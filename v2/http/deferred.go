@@ -0,0 +1,71 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/validation"
+)
+
+// DeferredPaymentContextKey is the context key for storing a verified deferred payment.
+const DeferredPaymentContextKey = contextKey("x402_v2_deferred_payment")
+
+// DeferredPayment is a verified payment using the "deferred" scheme. The middleware
+// does not settle it; the handler claims it with Claim once the resource has been
+// delivered, before ClaimDeadline.
+type DeferredPayment struct {
+	// Payment is the payment payload sent by the client.
+	Payment v2.PaymentPayload
+
+	// Requirement is the payment requirement the payment satisfies.
+	Requirement v2.PaymentRequirements
+
+	// Payer is the payer address reported by the facilitator.
+	Payer string
+
+	// ClaimDeadline is the time after which the payment can no longer be claimed.
+	ClaimDeadline time.Time
+
+	// Clock is used to check ClaimDeadline. If nil, v2.SystemClock is used.
+	Clock v2.Clock
+}
+
+// NewDeferredPayment creates a DeferredPayment from a verified payment.
+func NewDeferredPayment(payment v2.PaymentPayload, requirement v2.PaymentRequirements, verifyResp *v2.VerifyResponse) *DeferredPayment {
+	deferred := &DeferredPayment{
+		Payment:     payment,
+		Requirement: requirement,
+	}
+	if verifyResp != nil {
+		deferred.Payer = verifyResp.Payer
+	}
+	if deadline, ok := validation.AuthorizationDeadline(payment); ok {
+		deferred.ClaimDeadline = deadline
+	}
+	return deferred
+}
+
+// Claim settles the deferred payment through the facilitator.
+func (p *DeferredPayment) Claim(ctx context.Context, facilitator *FacilitatorClient) (*v2.SettleResponse, error) {
+	if !p.ClaimDeadline.IsZero() && !v2.ClockOrSystem(p.Clock).Now().Before(p.ClaimDeadline) {
+		return nil, fmt.Errorf("claim window closed at %s", p.ClaimDeadline.Format(time.RFC3339))
+	}
+
+	resp, err := facilitator.Settle(ctx, p.Payment, p.Requirement)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return resp, fmt.Errorf("settlement unsuccessful: %s", resp.ErrorReason)
+	}
+	return resp, nil
+}
+
+// GetDeferredPaymentFromContext extracts the verified deferred payment from the
+// request context. Returns nil if the request was not paid with the deferred scheme.
+func GetDeferredPaymentFromContext(ctx context.Context) *DeferredPayment {
+	deferred, _ := ctx.Value(DeferredPaymentContextKey).(*DeferredPayment)
+	return deferred
+}
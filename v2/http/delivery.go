@@ -0,0 +1,183 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net"
+	"net/http"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// PaymentDeliveryHeader carries the Delivery of a paid response, base64-encoded
+// JSON. It is sent as a trailer, since the digest is only known once the body
+// has been written, except for metered responses, which are buffered.
+const PaymentDeliveryHeader = "X-PAYMENT-DELIVERY"
+
+// Delivery binds a settled payment to the response it paid for, so buyers can
+// later prove what they paid for and sellers that they delivered it.
+type Delivery struct {
+	// Transaction is the settlement transaction.
+	Transaction string `json:"transaction"`
+
+	// Network is the network the payment settled on (CAIP-2 format).
+	Network string `json:"network"`
+
+	// Payer is the address that made the payment.
+	Payer string `json:"payer,omitempty"`
+
+	// BlockNumber, Slot and Timestamp locate the settlement on chain and in
+	// time, when the facilitator reported them.
+	BlockNumber uint64 `json:"blockNumber,omitempty"`
+	Slot        uint64 `json:"slot,omitempty"`
+	Timestamp   int64  `json:"timestamp,omitempty"`
+
+	// Resource is the URL of the paid resource.
+	Resource string `json:"resource"`
+
+	// Status is the status code of the response.
+	Status int `json:"status"`
+
+	// ContentSHA256 is the hex-encoded SHA-256 digest of the response body.
+	ContentSHA256 string `json:"contentSha256"`
+
+	// ContentLength is the length of the response body in bytes.
+	ContentLength int64 `json:"contentLength"`
+}
+
+// Matches reports whether body is the content d was issued for.
+func (d Delivery) Matches(body []byte) bool {
+	sum := sha256.Sum256(body)
+	return int64(len(body)) == d.ContentLength && hex.EncodeToString(sum[:]) == d.ContentSHA256
+}
+
+// DeliveryFunc receives the Delivery of a settled response, e.g. to store it
+// with the payment or post it to a webhook. It runs after the response has
+// been written, before the handler chain returns.
+type DeliveryFunc func(ctx context.Context, delivery Delivery)
+
+// GetDelivery extracts the Delivery from an HTTP response, from the
+// X-PAYMENT-DELIVERY header or trailer; the trailer is only available once
+// the body has been read to the end.
+// Returns nil if no delivery is present or if parsing fails.
+func GetDelivery(resp *http.Response) *Delivery {
+	encoded := resp.Header.Get(PaymentDeliveryHeader)
+	if encoded == "" {
+		encoded = resp.Trailer.Get(PaymentDeliveryHeader)
+	}
+	if encoded == "" {
+		return nil
+	}
+	delivery, err := decodeDelivery(encoded)
+	if err != nil {
+		return nil
+	}
+	return &delivery
+}
+
+// newDelivery describes body, delivered with status for a payment settled
+// with settlement.
+func newDelivery(settlement *v2.SettleResponse, resource string, status int, sum []byte, length int64) Delivery {
+	return Delivery{
+		Transaction:   settlement.Transaction,
+		Network:       settlement.Network,
+		Payer:         settlement.Payer,
+		BlockNumber:   settlement.BlockNumber,
+		Slot:          settlement.Slot,
+		Timestamp:     settlement.Timestamp,
+		Resource:      resource,
+		Status:        status,
+		ContentSHA256: hex.EncodeToString(sum),
+		ContentLength: length,
+	}
+}
+
+func encodeDelivery(delivery Delivery) (string, error) {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal delivery: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func decodeDelivery(encoded string) (Delivery, error) {
+	var delivery Delivery
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return delivery, fmt.Errorf("failed to decode delivery: %w", err)
+	}
+	if err := json.Unmarshal(data, &delivery); err != nil {
+		return delivery, fmt.Errorf("failed to unmarshal delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// digestWriter hashes the body written through it.
+type digestWriter struct {
+	http.ResponseWriter
+	hash     hash.Hash
+	length   int64
+	status   int
+	hijacked bool
+}
+
+func newDigestWriter(w http.ResponseWriter) *digestWriter {
+	return &digestWriter{ResponseWriter: w, hash: sha256.New()}
+}
+
+func (d *digestWriter) WriteHeader(statusCode int) {
+	if d.status == 0 {
+		d.status = statusCode
+	}
+	d.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (d *digestWriter) Write(b []byte) (int, error) {
+	if d.status == 0 {
+		d.status = http.StatusOK
+	}
+	n, err := d.ResponseWriter.Write(b)
+	d.hash.Write(b[:n])
+	d.length += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher.
+func (d *digestWriter) Flush() {
+	if flusher, ok := d.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker. Hijacked responses have no delivery.
+func (d *digestWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	d.hijacked = true
+	if hijacker, ok := d.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, errors.New("hijacking not supported")
+}
+
+// Push implements http.Pusher.
+func (d *digestWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := d.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// delivery describes the body written so far.
+func (d *digestWriter) delivery(settlement *v2.SettleResponse, resource string) Delivery {
+	status := d.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return newDelivery(settlement, resource, status, d.hash.Sum(nil), d.length)
+}
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/validation"
+)
+
+// VerificationFailure returns the 402 error for a payment the facilitator
+// rejected with reason. With Diagnostics, the payment is decoded locally and
+// the problem found, if any, is logged and appended to reason.
+func (c Config) VerificationFailure(payment *v2.PaymentPayload, requirement *v2.PaymentRequirements, reason string) string {
+	if !c.Diagnostics {
+		return reason
+	}
+	err := validation.Diagnose(*payment, *requirement, v2.ClockOrSystem(c.Clock).Now(), c.clockSkew())
+	if err == nil {
+		return reason
+	}
+
+	attrs := []any{"reason", reason, "diagnosis", err}
+	var mismatch *validation.MismatchError
+	if errors.As(err, &mismatch) {
+		attrs = append(attrs, "field", mismatch.Field, "got", mismatch.Got, "want", mismatch.Want)
+	}
+	slog.Default().Warn("payment verification diagnosis", attrs...)
+	if reason == "" {
+		return err.Error()
+	}
+	return reason + " (" + err.Error() + ")"
+}
@@ -0,0 +1,24 @@
+package http
+
+import (
+	"strings"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/validation"
+)
+
+func TestConfig_VerificationFailure(t *testing.T) {
+	requirement := v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: "10000", PayTo: "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"}
+	payment := &v2.PaymentPayload{Accepted: requirement}
+	payment.Accepted.Amount = "100"
+
+	if got := (Config{}).VerificationFailure(payment, &requirement, "invalid_payload"); got != "invalid_payload" {
+		t.Errorf("Expected the facilitator reason without Diagnostics, got %q", got)
+	}
+
+	got := Config{Diagnostics: true}.VerificationFailure(payment, &requirement, "invalid_payload")
+	if !strings.HasPrefix(got, "invalid_payload (") || !strings.Contains(got, validation.ReasonAmountBelow) {
+		t.Errorf("Expected the diagnosis appended to the reason, got %q", got)
+	}
+}
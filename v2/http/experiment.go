@@ -0,0 +1,143 @@
+package http
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"net"
+	"net/http"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// PriceVariant is one arm of a PriceExperiment.
+type PriceVariant struct {
+	// Name identifies the variant in requirements and settlement records,
+	// like "control" or "discount".
+	Name string
+
+	// Weight is the variant's share of clients relative to the other variants
+	// (default 1).
+	Weight int
+
+	// BasisPoints is the variant's price in hundredths of a percent of the
+	// base price (default 10000, the base price).
+	BasisPoints int
+}
+
+// FingerprintFunc identifies the client of a request for bucketing into
+// experiment variants. Requests with the same fingerprint see the same price.
+type FingerprintFunc func(r *http.Request) string
+
+// PriceExperiment varies advertised prices per client for revenue experiments.
+// Each client is assigned a variant by hashing its fingerprint, the variant's
+// price is advertised, and the requirements are tagged with the variant in
+// Extra["experiment"] (see v2.ExperimentFromRequirements), so settlement
+// hooks and the ledger can attribute revenue to it. A payment must match the
+// variant advertised to its client exactly.
+type PriceExperiment struct {
+	// Name identifies the experiment. Changing it reshuffles clients.
+	Name string
+
+	// Variants are the arms of the experiment.
+	Variants []PriceVariant
+
+	// Fingerprint identifies clients (default: ClientFingerprint). Prefer a
+	// server-issued key, like HeaderFingerprint on an API key header, where
+	// clients have one.
+	Fingerprint FingerprintFunc
+}
+
+// ClientFingerprint identifies a client by its remote IP address and
+// User-Agent header. Both are under the client's control: a client can change
+// its User-Agent or address until it is assigned the cheapest variant, so use
+// it only where that is acceptable.
+func ClientFingerprint(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host + "|" + r.UserAgent()
+}
+
+// HeaderFingerprint identifies clients by the value of a header carrying a
+// server-issued key, like an API key or session token, which clients cannot
+// vary to pick their variant. Requests without the header share one variant.
+func HeaderFingerprint(header string) FingerprintFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(header)
+	}
+}
+
+// Variant returns the variant assigned to the client of r. It returns false
+// if the experiment has no variants.
+func (e *PriceExperiment) Variant(r *http.Request) (PriceVariant, bool) {
+	total := 0
+	for _, variant := range e.Variants {
+		total += variantWeight(variant)
+	}
+	if total == 0 {
+		return PriceVariant{}, false
+	}
+
+	fingerprint := e.Fingerprint
+	if fingerprint == nil {
+		fingerprint = ClientFingerprint
+	}
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(e.Name + "\x00" + fingerprint(r)))
+	bucket := int(hash.Sum64() % uint64(total))
+
+	for _, variant := range e.Variants {
+		if bucket < variantWeight(variant) {
+			return variant, true
+		}
+		bucket -= variantWeight(variant)
+	}
+	return e.Variants[len(e.Variants)-1], true
+}
+
+// Apply returns base priced and tagged for the variant assigned to r.
+func (e *PriceExperiment) Apply(r *http.Request, base []v2.PaymentRequirements) ([]v2.PaymentRequirements, error) {
+	variant, ok := e.Variant(r)
+	if !ok {
+		return base, nil
+	}
+	basisPoints := variant.BasisPoints
+	if basisPoints == 0 {
+		basisPoints = 10000
+	}
+
+	priced := make([]v2.PaymentRequirements, len(base))
+	for i, req := range base {
+		amount, ok := new(big.Int).SetString(req.Amount, 10)
+		if !ok {
+			return nil, &StatusError{StatusCode: http.StatusInternalServerError, Err: fmt.Errorf("invalid amount in requirements: %q", req.Amount)}
+		}
+		req.Amount = proportionalAmount(amount, int64(basisPoints), 10000).String()
+		priced[i] = v2.WithExperiment(req, v2.ExperimentTag{Experiment: e.Name, Variant: variant.Name})
+	}
+	return priced, nil
+}
+
+// variantWeight returns the weight of variant, defaulting to 1.
+func variantWeight(variant PriceVariant) int {
+	if variant.Weight <= 0 {
+		return 1
+	}
+	return variant.Weight
+}
+
+// checkExperiment rejects payments that accepted another variant than the one
+// advertised in requirement.
+func checkExperiment(payment *v2.PaymentPayload, requirement *v2.PaymentRequirements) error {
+	want, ok := v2.ExperimentFromRequirements(*requirement)
+	if !ok {
+		return nil
+	}
+	got, _ := v2.ExperimentFromRequirements(payment.Accepted)
+	if got != want {
+		return fmt.Errorf("payment accepted price variant %q, but %q was advertised", got.Variant, want.Variant)
+	}
+	return nil
+}
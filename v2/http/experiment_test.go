@@ -0,0 +1,85 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func TestPriceExperiment_Apply(t *testing.T) {
+	experiment := &PriceExperiment{
+		Name: "spring-pricing",
+		Variants: []PriceVariant{
+			{Name: "control", Weight: 3},
+			{Name: "discount", BasisPoints: 7500},
+		},
+		Fingerprint: HeaderFingerprint("X-Client"),
+	}
+	base := []v2.PaymentRequirements{{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "10000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+	}}
+
+	amounts := map[string]string{"control": "10000", "discount": "7500"}
+	counts := make(map[string]int)
+	for i := 0; i < 400; i++ {
+		r := httptest.NewRequest("GET", "/data", nil)
+		r.Header.Set("X-Client", fmt.Sprintf("client-%d", i))
+
+		priced, err := experiment.Apply(r, base)
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		tag, ok := v2.ExperimentFromRequirements(priced[0])
+		if !ok || tag.Experiment != "spring-pricing" {
+			t.Fatalf("Expected experiment tag, got %+v", priced[0].Extra)
+		}
+		if priced[0].Amount != amounts[tag.Variant] {
+			t.Errorf("Variant %s priced at %s", tag.Variant, priced[0].Amount)
+		}
+		counts[tag.Variant]++
+
+		// The same client always sees the same variant, whatever its address
+		r.RemoteAddr = fmt.Sprintf("10.0.0.%d:1234", i%256)
+		r.Header.Set("User-Agent", fmt.Sprintf("agent-%d", i))
+		again, _ := experiment.Apply(r, base)
+		if againTag, _ := v2.ExperimentFromRequirements(again[0]); againTag != tag {
+			t.Errorf("Client %d moved from %s to %s", i, tag.Variant, againTag.Variant)
+		}
+	}
+	if counts["control"] < 250 || counts["discount"] < 50 {
+		t.Errorf("Unexpected variant distribution: %v", counts)
+	}
+	if base[0].Extra != nil {
+		t.Error("Apply must not modify the base requirements")
+	}
+}
+
+func TestConfig_CheckPrice_Experiment(t *testing.T) {
+	config := Config{Experiment: &PriceExperiment{Name: "test", Variants: []PriceVariant{{Name: "a"}, {Name: "b"}}}}
+	requirement := v2.WithExperiment(v2.PaymentRequirements{Amount: "100"}, v2.ExperimentTag{Experiment: "test", Variant: "a"})
+
+	// The accepted requirement arrives decoded from JSON
+	accepted := func(variant string) v2.PaymentRequirements {
+		data, _ := json.Marshal(v2.WithExperiment(v2.PaymentRequirements{Amount: "100"}, v2.ExperimentTag{Experiment: "test", Variant: variant}))
+		var decoded v2.PaymentRequirements
+		_ = json.Unmarshal(data, &decoded)
+		return decoded
+	}
+
+	if err := config.CheckPrice(&v2.PaymentPayload{Accepted: accepted("a")}, &requirement); err != nil {
+		t.Errorf("Expected advertised variant to be accepted, got %v", err)
+	}
+	if err := config.CheckPrice(&v2.PaymentPayload{Accepted: accepted("b")}, &requirement); err == nil {
+		t.Error("Expected other variant to be rejected")
+	}
+	if err := config.CheckPrice(&v2.PaymentPayload{Accepted: v2.PaymentRequirements{Amount: "100"}}, &requirement); err == nil {
+		t.Error("Expected untagged payment to be rejected")
+	}
+}
@@ -4,15 +4,18 @@ package http
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/mark3labs/x402-go/retry"
 	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
 	"github.com/mark3labs/x402-go/v2/facilitator"
 )
 
@@ -48,6 +51,11 @@ type FacilitatorClient struct {
 	// Timeouts contains timeout configuration for payment operations.
 	Timeouts v2.TimeoutConfig
 
+	// NetworkTimeouts overrides Timeouts for verify and settle requests on
+	// specific networks or namespaces. Client's own timeout still applies, so it
+	// should be at least NetworkTimeouts.Longest(Timeouts).
+	NetworkTimeouts v2.NetworkTimeouts
+
 	// MaxRetries is the maximum number of retry attempts for failed requests (default: 0).
 	// Set to 0 to disable retries.
 	MaxRetries int
@@ -56,6 +64,9 @@ type FacilitatorClient struct {
 	// Exponential backoff is applied with a multiplier of 2.0.
 	RetryDelay time.Duration
 
+	// Clock schedules retry backoff. If nil, v2.SystemClock is used.
+	Clock v2.Clock
+
 	// Authorization is a static Authorization header value (e.g., "Bearer token" or "Basic base64").
 	// If AuthorizationProvider is also set, the provider takes precedence.
 	Authorization string
@@ -65,6 +76,14 @@ type FacilitatorClient struct {
 	// If set, this takes precedence over the static Authorization field.
 	AuthorizationProvider AuthorizationProvider
 
+	// TLSConfig enables mutual TLS with the facilitator (see LoadClientTLSConfig).
+	// It is applied to a copy of Client's transport.
+	TLSConfig *tls.Config
+
+	// RequestSigner, if set, signs verify and settle requests with HTTP Message
+	// Signatures (RFC 9421), for facilitators requiring more than a bearer token.
+	RequestSigner *RequestSigner
+
 	// OnBeforeVerify is called before the Verify operation starts.
 	// If it returns an error, the operation is aborted immediately.
 	OnBeforeVerify OnBeforeFunc
@@ -78,17 +97,61 @@ type FacilitatorClient struct {
 
 	// OnAfterSettle is called after the Settle operation completes (success or failure).
 	OnAfterSettle OnAfterSettleFunc
+
+	// Codec marshals requests and unmarshals responses. If nil,
+	// encoding.DefaultCodec is used.
+	Codec encoding.Codec
+
+	// LenientResponses decodes facilitator responses without validating
+	// their shape. By default, responses missing required fields or with
+	// fields of the wrong type fail with a *FacilitatorResponseError instead
+	// of being decoded with zero values.
+	LenientResponses bool
+
+	// Schemes routes verify and settle requests for payments in the given
+	// schemes to another implementation, such as a local direct.Confirmer,
+	// instead of BaseURL. Hooks still apply.
+	Schemes map[string]facilitator.Interface
+
+	tlsOnce   sync.Once
+	tlsClient *http.Client
 }
 
 // Verify that FacilitatorClient implements facilitator.Interface.
 var _ facilitator.Interface = (*FacilitatorClient)(nil)
 
 // httpClient returns the HTTP client to use, defaulting to http.DefaultClient.
+// With TLSConfig set, it returns a copy of that client using TLSConfig.
 func (c *FacilitatorClient) httpClient() *http.Client {
-	if c.Client != nil {
-		return c.Client
+	base := c.Client
+	if base == nil {
+		base = http.DefaultClient
+	}
+	if c.TLSConfig == nil {
+		return base
 	}
-	return http.DefaultClient
+
+	c.tlsOnce.Do(func() {
+		transport, ok := base.Transport.(*http.Transport)
+		if !ok {
+			transport = http.DefaultTransport.(*http.Transport)
+		}
+		transport = transport.Clone()
+		transport.TLSClientConfig = c.TLSConfig.Clone()
+
+		client := *base
+		client.Transport = transport
+		c.tlsClient = &client
+	})
+	return c.tlsClient
+}
+
+// signRequest signs a verify or settle request if RequestSigner is configured.
+func (c *FacilitatorClient) signRequest(req *http.Request, body []byte) error {
+	if c.RequestSigner == nil {
+		return nil
+	}
+	return c.RequestSigner.Sign(req, body)
 }
 
 // setAuthorizationHeader sets the Authorization header on the request if configured.
@@ -106,6 +169,11 @@ func (c *FacilitatorClient) setAuthorizationHeader(req *http.Request) {
 	}
 }
 
+// timeouts returns the timeouts for operations on network.
+func (c *FacilitatorClient) timeouts(network string) v2.TimeoutConfig {
+	return c.NetworkTimeouts.Resolve(c.Timeouts, network)
+}
+
 // retryConfig returns the retry configuration based on client settings.
 func (c *FacilitatorClient) retryConfig() retry.Config {
 	retryDelay := c.RetryDelay
@@ -123,6 +191,7 @@ func (c *FacilitatorClient) retryConfig() retry.Config {
 		InitialDelay: retryDelay,
 		MaxDelay:     retryDelay * 4,
 		Multiplier:   2.0,
+		Clock:        v2.ClockOrSystem(c.Clock),
 	}
 }
 
@@ -134,6 +203,14 @@ func (c *FacilitatorClient) Verify(ctx context.Context, payload v2.PaymentPayloa
 		}
 	}
 
+	if local, ok := c.Schemes[payload.Accepted.Scheme]; ok {
+		resp, err := local.Verify(ctx, payload, requirements)
+		if c.OnAfterVerify != nil {
+			c.OnAfterVerify(ctx, payload, requirements, resp, err)
+		}
+		return resp, err
+	}
+
 	// Create request payload
 	req := facilitator.VerifyRequest{
 		X402Version:         v2.X402Version,
@@ -142,17 +219,18 @@ func (c *FacilitatorClient) Verify(ctx context.Context, payload v2.PaymentPayloa
 	}
 
 	// Marshal to JSON
-	data, err := json.Marshal(req)
+	data, err := encoding.CodecOrDefault(c.Codec).Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	verifyTimeout := c.timeouts(requirements.Network).VerifyTimeout
 	resp, resultErr := retry.WithRetry(ctx, c.retryConfig(), isFacilitatorUnavailableError, func() (*v2.VerifyResponse, error) {
 		// Use provided context, apply timeout only if not already set
 		reqCtx := ctx
-		if _, hasDeadline := ctx.Deadline(); !hasDeadline && c.Timeouts.VerifyTimeout > 0 {
+		if _, hasDeadline := ctx.Deadline(); !hasDeadline && verifyTimeout > 0 {
 			var cancel context.CancelFunc
-			reqCtx, cancel = context.WithTimeout(ctx, c.Timeouts.VerifyTimeout)
+			reqCtx, cancel = context.WithTimeout(ctx, verifyTimeout)
 			defer cancel()
 		}
 
@@ -162,6 +240,9 @@ func (c *FacilitatorClient) Verify(ctx context.Context, payload v2.PaymentPayloa
 		}
 		httpReq.Header.Set("Content-Type", "application/json")
 		c.setAuthorizationHeader(httpReq)
+		if err := c.signRequest(httpReq, data); err != nil {
+			return nil, err
+		}
 
 		// Send request
 		httpResp, err := c.httpClient().Do(httpReq)
@@ -175,8 +256,17 @@ func (c *FacilitatorClient) Verify(ctx context.Context, payload v2.PaymentPayloa
 		}
 
 		// Parse response
+		data, err := readFacilitatorResponse("/verify", httpResp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if !c.LenientResponses {
+			if err := validateVerifyResponse(data); err != nil {
+				return nil, err
+			}
+		}
 		var verifyResp v2.VerifyResponse
-		if err := json.NewDecoder(httpResp.Body).Decode(&verifyResp); err != nil {
+		if err := encoding.CodecOrDefault(c.Codec).Unmarshal(data, &verifyResp); err != nil {
 			return nil, fmt.Errorf("failed to decode verify response: %w", err)
 		}
 
@@ -203,6 +293,14 @@ func (c *FacilitatorClient) Settle(ctx context.Context, payload v2.PaymentPayloa
 		}
 	}
 
+	if local, ok := c.Schemes[payload.Accepted.Scheme]; ok {
+		resp, err := local.Settle(ctx, payload, requirements)
+		if c.OnAfterSettle != nil {
+			c.OnAfterSettle(ctx, payload, requirements, resp, err)
+		}
+		return resp, err
+	}
+
 	// Create request payload
 	req := facilitator.SettleRequest{
 		X402Version:         v2.X402Version,
@@ -211,17 +309,18 @@ func (c *FacilitatorClient) Settle(ctx context.Context, payload v2.PaymentPayloa
 	}
 
 	// Marshal to JSON
-	data, err := json.Marshal(req)
+	data, err := encoding.CodecOrDefault(c.Codec).Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	settleTimeout := c.timeouts(requirements.Network).SettleTimeout
 	resp, resultErr := retry.WithRetry(ctx, c.retryConfig(), isFacilitatorUnavailableError, func() (*v2.SettleResponse, error) {
 		// Use provided context, apply timeout only if not already set
 		reqCtx := ctx
-		if _, hasDeadline := ctx.Deadline(); !hasDeadline && c.Timeouts.SettleTimeout > 0 {
+		if _, hasDeadline := ctx.Deadline(); !hasDeadline && settleTimeout > 0 {
 			var cancel context.CancelFunc
-			reqCtx, cancel = context.WithTimeout(ctx, c.Timeouts.SettleTimeout)
+			reqCtx, cancel = context.WithTimeout(ctx, settleTimeout)
 			defer cancel()
 		}
 
@@ -231,6 +330,9 @@ func (c *FacilitatorClient) Settle(ctx context.Context, payload v2.PaymentPayloa
 		}
 		httpReq.Header.Set("Content-Type", "application/json")
 		c.setAuthorizationHeader(httpReq)
+		if err := c.signRequest(httpReq, data); err != nil {
+			return nil, err
+		}
 
 		// Send request
 		httpResp, err := c.httpClient().Do(httpReq)
@@ -244,8 +346,17 @@ func (c *FacilitatorClient) Settle(ctx context.Context, payload v2.PaymentPayloa
 		}
 
 		// Parse response
+		data, err := readFacilitatorResponse("/settle", httpResp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if !c.LenientResponses {
+			if err := validateSettleResponse(data); err != nil {
+				return nil, err
+			}
+		}
 		var settleResp v2.SettleResponse
-		if err := json.NewDecoder(httpResp.Body).Decode(&settleResp); err != nil {
+		if err := encoding.CodecOrDefault(c.Codec).Unmarshal(data, &settleResp); err != nil {
 			return nil, fmt.Errorf("failed to decode settle response: %w", err)
 		}
 
@@ -287,8 +398,17 @@ func (c *FacilitatorClient) Supported(ctx context.Context) (*v2.SupportedRespons
 	}
 
 	// Parse response
+	data, err := readFacilitatorResponse("/supported", httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if !c.LenientResponses {
+		if err := validateSupportedResponse(data); err != nil {
+			return nil, err
+		}
+	}
 	var supportedResp v2.SupportedResponse
-	if err := json.NewDecoder(httpResp.Body).Decode(&supportedResp); err != nil {
+	if err := encoding.CodecOrDefault(c.Codec).Unmarshal(data, &supportedResp); err != nil {
 		return nil, fmt.Errorf("failed to decode supported response: %w", err)
 	}
 
@@ -298,6 +418,9 @@ func (c *FacilitatorClient) Supported(ctx context.Context) (*v2.SupportedRespons
 // EnrichRequirements fetches supported payment types from the facilitator and
 // enriches the provided payment requirements with network-specific data like feePayer.
 // This is particularly useful for SVM chains where the feePayer must be specified.
+// The facilitator signers advertised for each network are listed under
+// v2.FacilitatorSignersExtraKey, and SVM requirements without a feePayer get
+// the first of them.
 func (c *FacilitatorClient) EnrichRequirements(ctx context.Context, requirements []v2.PaymentRequirements) ([]v2.PaymentRequirements, error) {
 	// Fetch supported payment types
 	supported, err := c.Supported(ctx)
@@ -305,19 +428,11 @@ func (c *FacilitatorClient) EnrichRequirements(ctx context.Context, requirements
 		return requirements, fmt.Errorf("failed to fetch supported payment types: %w", err)
 	}
 
-	// Create a lookup map for supported kinds by network and scheme
-	supportedMap := make(map[string]v2.SupportedKind)
-	for _, kind := range supported.Kinds {
-		key := kind.Network + "-" + kind.Scheme
-		supportedMap[key] = kind
-	}
-
 	// Enrich each requirement with extra data from the facilitator
 	enriched := make([]v2.PaymentRequirements, len(requirements))
 	for i, req := range requirements {
 		enriched[i] = req
-		key := req.Network + "-" + req.Scheme
-		if kind, ok := supportedMap[key]; ok && kind.Extra != nil {
+		if kind, ok := supported.Kind(req.Network, req.Scheme); ok && kind.Extra != nil {
 			// Initialize Extra map if it doesn't exist
 			if enriched[i].Extra == nil {
 				enriched[i].Extra = make(map[string]interface{})
@@ -330,6 +445,19 @@ func (c *FacilitatorClient) EnrichRequirements(ctx context.Context, requirements
 				}
 			}
 		}
+
+		signers := supported.SignersFor(req.Network)
+		if len(signers) == 0 {
+			continue
+		}
+		if _, exists := enriched[i].Extra[v2.FacilitatorSignersExtraKey]; !exists {
+			enriched[i] = v2.WithFacilitatorSigners(enriched[i], signers)
+		}
+		if networkType, _ := v2.ValidateNetwork(req.Network); networkType == v2.NetworkTypeSVM {
+			if _, exists := enriched[i].Extra["feePayer"]; !exists {
+				enriched[i].Extra["feePayer"] = signers[0]
+			}
+		}
 	}
 
 	return enriched, nil
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// Algorithms supported by RequestSigner, as registered for RFC 9421.
+const (
+	SignatureAlgorithmEd25519    = "ed25519"
+	SignatureAlgorithmHMACSHA256 = "hmac-sha256"
+)
+
+// signedComponents are the components covered by facilitator request signatures.
+var signedComponents = []string{"@method", "@target-uri", "content-digest", "content-type"}
+
+// LoadClientTLSConfig builds a TLS configuration for mutual TLS with a facilitator
+// from a PEM client certificate and key. If caFile is non-empty, the facilitator's
+// certificate must chain to the CA certificates it contains instead of the system roots.
+func LoadClientTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load client certificate: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA certificates: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no CA certificates found in %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// RequestSigner signs facilitator verify and settle requests with HTTP Message
+// Signatures (RFC 9421). It adds a Content-Digest header (RFC 9530) and signs the
+// method, target URI, content digest, and content type.
+type RequestSigner struct {
+	keyID     string
+	algorithm string
+	label     string
+	sign      func(base []byte) []byte
+	clock     v2.Clock
+}
+
+// RequestSignerOption configures a RequestSigner.
+type RequestSignerOption func(*RequestSigner)
+
+// WithSignatureLabel sets the signature label (default: "sig1").
+func WithSignatureLabel(label string) RequestSignerOption {
+	return func(s *RequestSigner) {
+		s.label = label
+	}
+}
+
+// WithRequestSignerClock sets the clock used for the created parameter.
+func WithRequestSignerClock(clock v2.Clock) RequestSignerOption {
+	return func(s *RequestSigner) {
+		s.clock = clock
+	}
+}
+
+// NewEd25519RequestSigner creates a RequestSigner using an Ed25519 key registered
+// with the facilitator under keyID.
+func NewEd25519RequestSigner(keyID string, key ed25519.PrivateKey, opts ...RequestSignerOption) *RequestSigner {
+	return newRequestSigner(keyID, SignatureAlgorithmEd25519, func(base []byte) []byte {
+		return ed25519.Sign(key, base)
+	}, opts)
+}
+
+// NewHMACRequestSigner creates a RequestSigner using an HMAC-SHA256 secret shared
+// with the facilitator under keyID.
+func NewHMACRequestSigner(keyID string, secret []byte, opts ...RequestSignerOption) *RequestSigner {
+	return newRequestSigner(keyID, SignatureAlgorithmHMACSHA256, func(base []byte) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(base)
+		return mac.Sum(nil)
+	}, opts)
+}
+
+func newRequestSigner(keyID, algorithm string, sign func([]byte) []byte, opts []RequestSignerOption) *RequestSigner {
+	s := &RequestSigner{
+		keyID:     keyID,
+		algorithm: algorithm,
+		label:     "sig1",
+		sign:      sign,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Sign adds Content-Digest, Signature-Input, and Signature headers to req.
+// body must be the exact request body.
+func (s *RequestSigner) Sign(req *http.Request, body []byte) error {
+	digest := sha256.Sum256(body)
+	req.Header.Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(digest[:])+":")
+
+	params := signatureParams(v2.ClockOrSystem(s.clock).Now().Unix(), s.keyID, s.algorithm)
+	base, err := signatureBase(req, params)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Signature-Input", s.label+"="+params)
+	req.Header.Set("Signature", s.label+"=:"+base64.StdEncoding.EncodeToString(s.sign(base))+":")
+	return nil
+}
+
+// signatureParams returns the serialized signature parameters of the covered components.
+func signatureParams(created int64, keyID, algorithm string) string {
+	quoted := make([]string, len(signedComponents))
+	for i, component := range signedComponents {
+		quoted[i] = strconv.Quote(component)
+	}
+	return "(" + strings.Join(quoted, " ") + ");created=" + strconv.FormatInt(created, 10) +
+		";keyid=" + strconv.Quote(keyID) + ";alg=" + strconv.Quote(algorithm)
+}
+
+// signatureBase builds the RFC 9421 signature base of req for params.
+func signatureBase(req *http.Request, params string) ([]byte, error) {
+	var b strings.Builder
+	for _, component := range signedComponents {
+		var value string
+		switch component {
+		case "@method":
+			value = req.Method
+		case "@target-uri":
+			value = req.URL.String()
+		default:
+			value = strings.TrimSpace(req.Header.Get(component))
+			if value == "" {
+				return nil, fmt.Errorf("cannot sign request: missing %s header", component)
+			}
+		}
+		b.WriteString(strconv.Quote(component) + ": " + value + "\n")
+	}
+	b.WriteString(`"@signature-params": ` + params)
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,168 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// checkSignature verifies the RFC 9421 signature of r as a facilitator would.
+func checkSignature(t *testing.T, r *http.Request, verify func(base, signature []byte) bool) {
+	t.Helper()
+	body, _ := io.ReadAll(r.Body)
+	digest := sha256.Sum256(body)
+	if got, want := r.Header.Get("Content-Digest"), "sha-256=:"+base64.StdEncoding.EncodeToString(digest[:])+":"; got != want {
+		t.Errorf("expected Content-Digest %q, got %q", want, got)
+	}
+
+	params, ok := strings.CutPrefix(r.Header.Get("Signature-Input"), "sig1=")
+	if !ok || !strings.Contains(params, `keyid="facilitator-key"`) {
+		t.Fatalf("unexpected Signature-Input %q", r.Header.Get("Signature-Input"))
+	}
+	encoded, _ := strings.CutPrefix(r.Header.Get("Signature"), "sig1=")
+	signature, err := base64.StdEncoding.DecodeString(strings.Trim(encoded, ":"))
+	if err != nil {
+		t.Fatalf("malformed Signature %q", r.Header.Get("Signature"))
+	}
+
+	// The server sees a relative request URI, so rebuild the target URI
+	r.URL.Scheme, r.URL.Host = "http", r.Host
+	base, err := signatureBase(r, params)
+	if err != nil {
+		t.Fatalf("signatureBase failed: %v", err)
+	}
+	if !verify(base, signature) {
+		t.Errorf("signature does not verify over %q", base)
+	}
+}
+
+func TestFacilitatorClient_RequestSigner(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	secret := []byte("shared-secret")
+
+	tests := []struct {
+		name   string
+		signer *RequestSigner
+		verify func(base, signature []byte) bool
+	}{
+		{
+			name:   "ed25519",
+			signer: NewEd25519RequestSigner("facilitator-key", privateKey),
+			verify: func(base, signature []byte) bool { return ed25519.Verify(publicKey, base, signature) },
+		},
+		{
+			name:   "hmac-sha256",
+			signer: NewHMACRequestSigner("facilitator-key", secret, WithRequestSignerClock(v2.NewFakeClock(time.Unix(1_700_000_000, 0)))),
+			verify: func(base, signature []byte) bool {
+				mac := hmac.New(sha256.New, secret)
+				mac.Write(base)
+				return hmac.Equal(mac.Sum(nil), signature)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				checkSignature(t, r, tt.verify)
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/verify":
+					_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayer"})
+				case "/settle":
+					_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true})
+				}
+			}))
+			defer server.Close()
+
+			client := &FacilitatorClient{BaseURL: server.URL, RequestSigner: tt.signer}
+			payload := v2.PaymentPayload{X402Version: 2, Payload: map[string]interface{}{"signature": "0xsig"}}
+			requirement := v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: "10000"}
+			if _, err := client.Verify(context.Background(), payload, requirement); err != nil {
+				t.Errorf("Verify failed: %v", err)
+			}
+			if _, err := client.Settle(context.Background(), payload, requirement); err != nil {
+				t.Errorf("Settle failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestFacilitatorClient_MutualTLS(t *testing.T) {
+	clientCert := selfSignedCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert.Leaf)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(server.Certificate())
+
+	// Without a client certificate the handshake is rejected
+	anonymous := &FacilitatorClient{
+		BaseURL:   server.URL,
+		TLSConfig: &tls.Config{RootCAs: rootCAs},
+	}
+	if _, err := anonymous.Supported(context.Background()); err == nil {
+		t.Error("expected request without client certificate to fail")
+	}
+
+	client := &FacilitatorClient{
+		BaseURL:   server.URL,
+		Client:    &http.Client{Timeout: 5 * time.Second},
+		TLSConfig: &tls.Config{RootCAs: rootCAs, Certificates: []tls.Certificate{clientCert}},
+	}
+	if _, err := client.Supported(context.Background()); err != nil {
+		t.Errorf("Supported over mutual TLS failed: %v", err)
+	}
+}
+
+// selfSignedCertificate creates a client certificate for TLS tests.
+func selfSignedCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "x402-middleware"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate failed: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate failed: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
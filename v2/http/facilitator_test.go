@@ -11,6 +11,7 @@ import (
 	"time"
 
 	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/facilitator"
 )
 
 func TestFacilitatorClient_Verify(t *testing.T) {
@@ -785,6 +786,52 @@ func TestFacilitatorClient_EnrichRequirements_PreservesUserValues(t *testing.T)
 	}
 }
 
+func TestFacilitatorClient_EnrichRequirements_Signers(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := v2.SupportedResponse{
+			Kinds: []v2.SupportedKind{
+				{X402Version: 2, Scheme: "exact", Network: "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp"},
+				{X402Version: 2, Scheme: "exact", Network: "eip155:8453"},
+			},
+			Signers: map[string][]string{
+				"solana:*": {"3oBdYQbV9bqH7yCBzF5m4mGDWBqCHYx7zLAB7qAMNbkP"},
+			},
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer mockServer.Close()
+
+	client := &FacilitatorClient{
+		BaseURL: mockServer.URL,
+		Client:  &http.Client{},
+	}
+
+	requirements := []v2.PaymentRequirements{
+		{Scheme: "exact", Network: "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp", Amount: "1000000"},
+		{Scheme: "exact", Network: "eip155:8453", Amount: "1000000"},
+	}
+
+	enriched, err := client.EnrichRequirements(context.Background(), requirements)
+	if err != nil {
+		t.Fatalf("EnrichRequirements failed: %v", err)
+	}
+
+	// The wildcard signer becomes the SVM fee payer
+	if feePayer, _ := enriched[0].Extra["feePayer"].(string); feePayer != "3oBdYQbV9bqH7yCBzF5m4mGDWBqCHYx7zLAB7qAMNbkP" {
+		t.Errorf("Expected feePayer from wildcard signer, got %v", enriched[0].Extra)
+	}
+	if signers := v2.FacilitatorSignersFromRequirements(enriched[0]); len(signers) != 1 {
+		t.Errorf("Expected facilitator signers to be listed, got %v", signers)
+	}
+
+	// EVM requirements match no signer pattern
+	if enriched[1].Extra != nil {
+		t.Errorf("Expected EVM requirement to be unchanged, got %v", enriched[1].Extra)
+	}
+}
+
 func TestFacilitatorClient_DefaultClient(t *testing.T) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		response := v2.VerifyResponse{IsValid: true}
@@ -834,3 +881,171 @@ func TestFacilitatorClient_Timeout(t *testing.T) {
 		t.Errorf("Expected ErrFacilitatorUnavailable due to timeout, got %v", err)
 	}
 }
+
+func TestFacilitatorClient_NetworkTimeouts(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/settle" {
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true})
+	}))
+	defer mockServer.Close()
+
+	client := &FacilitatorClient{
+		BaseURL: mockServer.URL,
+		Client:  &http.Client{},
+		Timeouts: v2.TimeoutConfig{
+			VerifyTimeout: 20 * time.Millisecond,
+			SettleTimeout: 20 * time.Millisecond,
+		},
+		NetworkTimeouts: v2.NetworkTimeouts{
+			"solana":                {SettleTimeout: time.Second},
+			v2.NetworkSolanaMainnet: {VerifyTimeout: time.Second},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		network string
+		ctx     func() (context.Context, context.CancelFunc)
+		wantErr [2]bool // verify, settle
+	}{
+		{name: "base timeouts", network: v2.NetworkBaseSepolia, wantErr: [2]bool{true, true}},
+		{name: "namespace override", network: v2.NetworkSolanaDevnet, wantErr: [2]bool{true, false}},
+		{name: "network override", network: v2.NetworkSolanaMainnet, wantErr: [2]bool{false, false}},
+		{
+			name:    "caller deadline is kept",
+			network: v2.NetworkSolanaMainnet,
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 20*time.Millisecond)
+			},
+			wantErr: [2]bool{true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requirements := v2.PaymentRequirements{Network: tt.network}
+
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tt.ctx != nil {
+				ctx, cancel = tt.ctx()
+			}
+			_, err := client.Verify(ctx, v2.PaymentPayload{}, requirements)
+			cancel()
+			if (err != nil) != tt.wantErr[0] {
+				t.Errorf("Verify: expected error %v, got %v", tt.wantErr[0], err)
+			}
+
+			if tt.ctx != nil {
+				ctx, cancel = tt.ctx()
+			}
+			_, err = client.Settle(ctx, v2.PaymentPayload{}, requirements)
+			cancel()
+			if (err != nil) != tt.wantErr[1] {
+				t.Errorf("Settle: expected error %v, got %v", tt.wantErr[1], err)
+			}
+		})
+	}
+}
+
+// localFacilitator is a facilitator.Interface answering without a server.
+type localFacilitator struct {
+	verified, settled int
+}
+
+func (f *localFacilitator) Verify(context.Context, v2.PaymentPayload, v2.PaymentRequirements) (*v2.VerifyResponse, error) {
+	f.verified++
+	return &v2.VerifyResponse{IsValid: true, Payer: "0xLocal"}, nil
+}
+
+func (f *localFacilitator) Settle(context.Context, v2.PaymentPayload, v2.PaymentRequirements) (*v2.SettleResponse, error) {
+	f.settled++
+	return &v2.SettleResponse{Success: true, Transaction: "0xlocal"}, nil
+}
+
+func (f *localFacilitator) Supported(context.Context) (*v2.SupportedResponse, error) {
+	return &v2.SupportedResponse{}, nil
+}
+
+func TestFacilitatorClient_Schemes(t *testing.T) {
+	var remote atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xRemote"})
+	}))
+	defer mockServer.Close()
+
+	local := &localFacilitator{}
+	var afterVerify int
+	client := &FacilitatorClient{
+		BaseURL: mockServer.URL,
+		Client:  &http.Client{},
+		Schemes: map[string]facilitator.Interface{v2.SchemeDirect: local},
+		OnAfterVerify: func(context.Context, v2.PaymentPayload, v2.PaymentRequirements, *v2.VerifyResponse, error) {
+			afterVerify++
+		},
+	}
+
+	direct := v2.PaymentPayload{X402Version: 2, Accepted: v2.PaymentRequirements{Scheme: v2.SchemeDirect, Network: "eip155:84532"}}
+	resp, err := client.Verify(context.Background(), direct, direct.Accepted)
+	if err != nil || resp.Payer != "0xLocal" {
+		t.Fatalf("Verify = %+v, %v", resp, err)
+	}
+	if settlement, err := client.Settle(context.Background(), direct, direct.Accepted); err != nil || settlement.Transaction != "0xlocal" {
+		t.Fatalf("Settle = %+v, %v", settlement, err)
+	}
+
+	exact := v2.PaymentPayload{X402Version: 2, Accepted: v2.PaymentRequirements{Scheme: v2.SchemeExact, Network: "eip155:84532"}}
+	if resp, err := client.Verify(context.Background(), exact, exact.Accepted); err != nil || resp.Payer != "0xRemote" {
+		t.Fatalf("Verify exact = %+v, %v", resp, err)
+	}
+
+	if local.verified != 1 || local.settled != 1 || remote.Load() != 1 {
+		t.Errorf("expected direct payments handled locally, got %d local verifications, %d local settlements, %d remote requests", local.verified, local.settled, remote.Load())
+	}
+	if afterVerify != 2 {
+		t.Errorf("expected hooks to run for local and remote verifications, got %d", afterVerify)
+	}
+}
+
+// countingCodec counts the calls it forwards to encoding/json.
+type countingCodec struct {
+	calls atomic.Int32
+}
+
+func (c *countingCodec) Marshal(v any) ([]byte, error) {
+	c.calls.Add(1)
+	return json.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v any) error {
+	c.calls.Add(1)
+	return json.Unmarshal(data, v)
+}
+
+func TestFacilitatorClient_Codec(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req facilitator.VerifyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PaymentRequirements.Network != "eip155:8453" {
+			t.Errorf("unexpected request %+v: %v", req, err)
+		}
+		_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayer"})
+	}))
+	defer server.Close()
+
+	codec := &countingCodec{}
+	client := &FacilitatorClient{BaseURL: server.URL, Codec: codec}
+	requirements := v2.PaymentRequirements{Scheme: "exact", Network: "eip155:8453", Amount: "1000"}
+	resp, err := client.Verify(context.Background(), v2.PaymentPayload{X402Version: 2, Accepted: requirements}, requirements)
+	if err != nil || !resp.IsValid {
+		t.Fatalf("Verify = %+v, %v", resp, err)
+	}
+	if codec.calls.Load() != 2 {
+		t.Errorf("expected the codec to marshal the request and unmarshal the response, got %d calls", codec.calls.Load())
+	}
+}
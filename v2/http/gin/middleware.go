@@ -4,13 +4,18 @@
 package gin
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	v2 "github.com/mark3labs/x402-go/v2"
 	v2http "github.com/mark3labs/x402-go/v2/http"
+	"github.com/mark3labs/x402-go/v2/http/internal/checks"
 	"github.com/mark3labs/x402-go/v2/http/internal/helpers"
 )
 
@@ -20,11 +25,17 @@ type Config = v2http.Config
 // PaymentContextKey is the gin context key for storing verified payment information.
 const PaymentContextKey = "x402_v2_payment"
 
+// DeferredPaymentContextKey is the gin context key for storing a verified deferred payment.
+const DeferredPaymentContextKey = "x402_v2_deferred_payment"
+
+// HoldContextKey is the gin context key for storing a payment hold.
+const HoldContextKey = "x402_v2_payment_hold"
+
 // NewX402Middleware creates a new x402 v2 payment middleware for Gin.
 // It returns a Gin-compatible middleware function that wraps handlers with payment gating.
 //
 // The middleware:
-//   - Checks for X-PAYMENT header in requests
+//   - Checks for the payment header (see Config.Headers) in requests
 //   - Returns 402 Payment Required if missing or invalid
 //   - Verifies payments with the facilitator
 //   - Settles payments (unless VerifyOnly=true)
@@ -58,50 +69,64 @@ const PaymentContextKey = "x402_v2_payment"
 //	    }
 //	})
 func NewX402Middleware(config Config) gin.HandlerFunc {
-	// Create facilitator client
-	facilitator := &v2http.FacilitatorClient{
-		BaseURL:               config.FacilitatorURL,
-		Client:                &http.Client{Timeout: v2.DefaultTimeouts.RequestTimeout},
-		Timeouts:              v2.DefaultTimeouts,
-		Authorization:         config.FacilitatorAuthorization,
-		AuthorizationProvider: config.FacilitatorAuthorizationProvider,
-		OnBeforeVerify:        config.FacilitatorOnBeforeVerify,
-		OnAfterVerify:         config.FacilitatorOnAfterVerify,
-		OnBeforeSettle:        config.FacilitatorOnBeforeSettle,
-		OnAfterSettle:         config.FacilitatorOnAfterSettle,
-	}
-
-	// Create fallback facilitator client if configured
-	var fallbackFacilitator *v2http.FacilitatorClient
-	if config.FallbackFacilitatorURL != "" {
-		fallbackFacilitator = &v2http.FacilitatorClient{
-			BaseURL:               config.FallbackFacilitatorURL,
-			Client:                &http.Client{Timeout: v2.DefaultTimeouts.RequestTimeout},
-			Timeouts:              v2.DefaultTimeouts,
-			Authorization:         config.FallbackFacilitatorAuthorization,
-			AuthorizationProvider: config.FallbackFacilitatorAuthorizationProvider,
-			OnBeforeVerify:        config.FallbackFacilitatorOnBeforeVerify,
-			OnAfterVerify:         config.FallbackFacilitatorOnAfterVerify,
-			OnBeforeSettle:        config.FallbackFacilitatorOnBeforeSettle,
-			OnAfterSettle:         config.FallbackFacilitatorOnAfterSettle,
-		}
+	facilitator, fallbackFacilitator := config.FacilitatorClients()
+	timeouts, _ := config.FacilitatorTimeouts()
+
+	if err := config.Validate(); err != nil {
+		slog.Default().Error("invalid x402 middleware configuration", "error", err)
+	}
+	policy := checks.Policy{
+		MinAmounts:               config.MinAmounts,
+		CheckAuthorizationWindow: config.CheckAuthorizationWindow,
+		RejectExpired:            config.RejectExpired,
+		StrictMatching:           config.StrictMatching,
+		Extensions:               config.Extensions,
+		Quotes:                   config.Quotes,
+		Clock:                    config.Clock,
+		ClockSkew:                config.ClockSkew,
 	}
 
 	// Enrich payment requirements with facilitator-specific data (like feePayer)
-	ctx, cancel := context.WithTimeout(context.Background(), v2.DefaultTimeouts.RequestTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.RequestTimeout)
 	defer cancel()
-	enrichedRequirements, err := facilitator.EnrichRequirements(ctx, config.PaymentRequirements)
+	baseRequirements, err := checks.SplitRequirements(config.PaymentRequirements, config.RevenueSplits)
+	if err != nil {
+		slog.Default().Error("invalid revenue splits, serving requirements without splits", "error", err)
+		baseRequirements = config.PaymentRequirements
+	}
+	enrichedRequirements, err := facilitator.EnrichRequirements(ctx, baseRequirements)
 	if err != nil {
 		// Log warning but continue with original requirements
 		slog.Default().Warn("failed to enrich payment requirements from facilitator", "error", err)
-		enrichedRequirements = config.PaymentRequirements
+		enrichedRequirements = baseRequirements
 	} else {
 		slog.Default().Info("payment requirements enriched from facilitator", "count", len(enrichedRequirements))
 	}
+	if config.Admin != nil {
+		config.Admin.Register(config, enrichedRequirements, err, facilitator, fallbackFacilitator)
+	}
 
 	// Return Gin middleware function
 	return func(c *gin.Context) {
 		logger := slog.Default()
+		c.Request = config.Headers.TakeQueryPayment(c.Request)
+
+		if config.CORS != nil {
+			if config.CORS.Handle(c.Writer, c.Request, config.Headers) {
+				c.Abort()
+				return
+			}
+			if c.Request.Method == http.MethodOptions {
+				c.Next()
+				return
+			}
+		}
+
+		if config.ResponseCache != nil && config.ResponseCache.Serve(c.Writer, c.Request) {
+			logger.Info("served cached paid response", "path", c.Request.URL.Path)
+			c.Abort()
+			return
+		}
 
 		// Build resource info from request
 		resource := config.Resource
@@ -112,17 +137,67 @@ func NewX402Middleware(config Config) gin.HandlerFunc {
 			resource.Description = "Payment required for " + c.Request.URL.Path
 		}
 
-		// Check for X-PAYMENT header
-		paymentHeader := c.GetHeader("X-PAYMENT")
+		requirements, err := config.RequirementsFor(c.Request, enrichedRequirements)
+		if err != nil {
+			logger.Warn("failed to compute payment requirements", "error", err)
+			status := http.StatusBadRequest
+			var statusErr *v2http.StatusError
+			if errors.As(err, &statusErr) {
+				status = statusErr.StatusCode
+				for key, values := range statusErr.Header {
+					for _, value := range values {
+						c.Writer.Header().Add(key, value)
+					}
+				}
+			}
+			c.AbortWithStatusJSON(status, gin.H{
+				"x402Version": v2.X402Version,
+				"error":       err.Error(),
+			})
+			return
+		}
+
+		// Check for a payment
+		paymentHeader := config.Headers.ReadPayment(c.Request)
 		if paymentHeader == "" {
+			// Unpaid revalidations are free if the resource has not changed
+			if config.Revalidation == v2http.RevalidationFree && v2http.IsConditional(c.Request) {
+				gate := newStatusGateWriter(c, func(statusCode int) bool {
+					if statusCode == http.StatusNotModified {
+						logger.Info("served free revalidation", "path", c.Request.URL.Path)
+						return true
+					}
+					logger.Info("no payment header provided", "path", c.Request.URL.Path)
+					sendPaymentRequiredGin(c, config, resource, requirements, "Payment required")
+					return false
+				})
+				c.Next()
+				gate.finish()
+				return
+			}
+
 			// No payment provided - return 402 with requirements
 			logger.Info("no payment header provided", "path", c.Request.URL.Path)
-			sendPaymentRequiredGin(c, resource, enrichedRequirements, "Payment required")
+			sendPaymentRequiredGin(c, config, resource, requirements, "Payment required")
 			return
 		}
 
+		if config.Drainer != nil {
+			release, err := config.Drainer.Acquire()
+			if err != nil {
+				logger.Warn("refusing paid request during shutdown", "path", c.Request.URL.Path)
+				c.Header("Retry-After", "5")
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+					"x402Version": v2.X402Version,
+					"error":       "Server shutting down",
+				})
+				return
+			}
+			defer release()
+		}
+
 		// Parse payment header
-		payment, err := helpers.ParsePaymentHeader(c.Request)
+		payment, err := helpers.DecodePaymentHeader(paymentHeader)
 		if err != nil {
 			logger.Warn("invalid payment header", "error", err)
 			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
@@ -132,46 +207,175 @@ func NewX402Middleware(config Config) gin.HandlerFunc {
 			return
 		}
 
+		if err := policy.CheckExtensions(payment); err != nil {
+			logger.Warn("invalid payment extensions", "error", err)
+			sendPaymentRequiredGin(c, config, resource, requirements, err.Error())
+			return
+		}
+
+		// Honor the price quoted in the 402 response, if the payment echoes one
+		quoted, err := policy.QuotedRequirements(payment, resource.URL, config.Rotator.Retired(payment, requirements))
+		if err != nil {
+			logger.Warn("invalid price quote", "error", err)
+			sendPaymentRequiredGin(c, config, resource, requirements, err.Error())
+			return
+		}
+		requirements = quoted
+
 		// Find matching requirement
-		requirement, err := v2.FindMatchingRequirement(payment, enrichedRequirements)
+		requirement, err := config.Allowlist.FindMatchingRequirement(payment, requirements)
 		if err != nil {
 			logger.Warn("no matching requirement", "error", err)
-			sendPaymentRequiredGin(c, resource, enrichedRequirements, "No matching payment requirement")
+			sendPaymentRequiredGin(c, config, resource, requirements, "No matching payment requirement")
+			return
+		}
+
+		if err := config.CheckPrice(payment, requirement); err != nil {
+			logger.Warn("payment does not match request price", "error", err)
+			sendPaymentRequiredGin(c, config, resource, requirements, err.Error())
+			return
+		}
+
+		if err := policy.CheckAccepted(payment, requirement); err != nil {
+			logger.Warn("payment does not match requirement", "error", err)
+			sendPaymentRequiredGin(c, config, resource, requirements, err.Error())
 			return
 		}
 
-		// Verify payment with facilitator
-		logger.Info("verifying payment", "scheme", payment.Accepted.Scheme, "network", payment.Accepted.Network)
-		verifyResp, err := facilitator.Verify(c.Request.Context(), *payment, *requirement)
-		if err != nil && fallbackFacilitator != nil {
-			logger.Warn("primary facilitator failed, trying fallback", "error", err)
-			verifyResp, err = fallbackFacilitator.Verify(c.Request.Context(), *payment, *requirement)
+		// Check the authorization locally before calling the facilitator
+		if err := policy.CheckExpired(payment); err != nil {
+			logger.Warn("authorization expired", "error", err)
+			sendPaymentRequiredGin(c, config, resource, requirements, checks.Reason(err))
+			return
+		}
+		if err := policy.CheckWindow(payment, requirement); err != nil {
+			logger.Warn("invalid authorization window", "error", err)
+			sendPaymentRequiredGin(c, config, resource, requirements, checks.Reason(err))
+			return
+		}
+
+		// Claim the payment so it cannot be used again, on this or another
+		// replica. Retries of a request that settled it reuse the settlement
+		// without verifying again, as the facilitator may reject a used payment
+		claim, err := config.ClaimPayment(c.Request.Context(), payment, requirement, c.Request.Method+" "+helpers.BuildResourceURL(c.Request))
+		if errors.Is(err, v2.ErrPaymentReplayed) {
+			logger.Warn("payment replayed")
+			sendPaymentRequiredGin(c, config, resource, requirements, err.Error())
+			return
 		}
 		if err != nil {
-			logger.Error("facilitator verification failed", "error", err)
+			logger.Error("failed to claim payment", "error", err)
 			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
 				"x402Version": v2.X402Version,
-				"error":       "Payment verification failed",
+				"error":       "Payment coordination unavailable",
 			})
 			return
 		}
+		defer claim.Release()
+		// Verify payment with facilitator, no later than the payment can be settled
+		received := v2.ClockOrSystem(config.Clock).Now()
+		verifyResp := claim.Verification()
+		if verifyResp != nil {
+			logger.Info("reusing verification of retried request", "payer", verifyResp.Payer)
+		} else {
+			verifyCtx, cancelVerify, err := config.PaymentContext(c.Request.Context(), payment, requirement, received)
+			if err != nil {
+				logger.Warn("payment expired before verification", "error", err)
+				sendPaymentRequiredGin(c, config, resource, requirements, checks.Reason(err))
+				return
+			}
+			logger.Info("verifying payment", "scheme", payment.Accepted.Scheme, "network", payment.Accepted.Network)
+			verifyResp, err = facilitator.Verify(verifyCtx, *payment, *requirement)
+			if err != nil && fallbackFacilitator != nil && verifyCtx.Err() == nil {
+				logger.Warn("primary facilitator failed, trying fallback", "error", err)
+				verifyResp, err = fallbackFacilitator.Verify(verifyCtx, *payment, *requirement)
+			}
+			err = v2http.DeadlineError(verifyCtx, err)
+			cancelVerify()
+			if errors.Is(err, v2.ErrAuthorizationExpired) {
+				logger.Warn("payment expired during verification", "error", err)
+				sendPaymentRequiredGin(c, config, resource, requirements, checks.Reason(err))
+				return
+			}
+			if err != nil {
+				logger.Error("facilitator verification failed", "error", err)
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+					"x402Version": v2.X402Version,
+					"error":       "Payment verification failed",
+				})
+				return
+			}
 
-		if !verifyResp.IsValid {
-			logger.Warn("payment verification failed", "reason", verifyResp.InvalidReason)
-			sendPaymentRequiredGin(c, resource, enrichedRequirements, verifyResp.InvalidReason)
-			return
+			if !verifyResp.IsValid {
+				logger.Warn("payment verification failed", "reason", verifyResp.InvalidReason)
+				sendPaymentRequiredGin(c, config, resource, requirements, config.VerificationFailure(payment, requirement, verifyResp.InvalidReason))
+				return
+			}
+
+			// Payment verified successfully
+			logger.Info("payment verified", "payer", verifyResp.Payer)
 		}
 
-		// Payment verified successfully
-		logger.Info("payment verified", "payer", verifyResp.Payer)
+		if config.PayerLimiter != nil {
+			release, err := config.PayerLimiter.Acquire(c.Request.Context(), verifyResp.Payer)
+			if err != nil {
+				logger.Warn("payer concurrency limit reached", "payer", verifyResp.Payer, "error", err)
+				c.Header("Retry-After", strconv.Itoa(config.PayerLimiter.RetryAfter()))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+					"x402Version": v2.X402Version,
+					"error":       "Too many concurrent requests",
+				})
+				return
+			}
+			defer release()
+		}
 
-		// Settle payment if not verify-only mode
-		if !config.VerifyOnly {
+		settle := func(ctx context.Context, payment v2.PaymentPayload, requirement v2.PaymentRequirements) (*v2.SettleResponse, error) {
+			ctx, cancel, err := config.PaymentContext(ctx, &payment, &requirement, received)
+			if err != nil {
+				return nil, err
+			}
+			defer cancel()
 			logger.Info("settling payment", "payer", verifyResp.Payer)
-			settlementResp, err := facilitator.Settle(c.Request.Context(), *payment, *requirement)
-			if err != nil && fallbackFacilitator != nil {
+			settlementResp, err := facilitator.Settle(ctx, payment, requirement)
+			if err != nil && fallbackFacilitator != nil && ctx.Err() == nil {
 				logger.Warn("primary facilitator settlement failed, trying fallback", "error", err)
-				settlementResp, err = fallbackFacilitator.Settle(c.Request.Context(), *payment, *requirement)
+				settlementResp, err = fallbackFacilitator.Settle(ctx, payment, requirement)
+			}
+			return settlementResp, v2http.DeadlineError(ctx, err)
+		}
+
+		// Settle payment if not verify-only mode; deferred payments are claimed by the
+		// handler, and held payments are captured by the handler
+		deferred := payment.Accepted.Scheme == v2.SchemeDeferred
+		manualCapture := config.ManualCapture && !config.VerifyOnly && !deferred
+		if config.Admin != nil {
+			settle = config.Admin.TrackSettle(settle)
+			if config.VerifyOnly || deferred {
+				config.Admin.RecordVerified(*requirement, verifyResp)
+			}
+		}
+		settles := !config.VerifyOnly && !deferred && !manualCapture
+		if !settles {
+			claim.Consumed()
+		}
+		metered := config.Metered(payment)
+		var capture *v2http.CaptureWriter
+		if settles && !metered && config.ResponseCache != nil {
+			capture = config.ResponseCache.Capture(c.Writer, c.Request)
+		}
+		settlePayment := func(requirement v2.PaymentRequirements) bool {
+			settlementResp := claim.Settlement()
+			var err error
+			if settlementResp != nil {
+				logger.Info("reusing settlement of retried request", "transaction", settlementResp.Transaction)
+			} else {
+				settlementResp, err = settle(c.Request.Context(), *payment, requirement)
+			}
+			if errors.Is(err, v2.ErrAuthorizationExpired) {
+				logger.Warn("payment expired before settlement", "error", err)
+				sendPaymentRequiredGin(c, config, resource, requirements, checks.Reason(err))
+				return false
 			}
 			if err != nil {
 				logger.Error("settlement failed", "error", err)
@@ -179,22 +383,64 @@ func NewX402Middleware(config Config) gin.HandlerFunc {
 					"x402Version": v2.X402Version,
 					"error":       "Payment settlement failed",
 				})
-				return
+				return false
 			}
 
 			if !settlementResp.Success {
 				logger.Warn("settlement unsuccessful", "reason", settlementResp.ErrorReason)
-				sendPaymentRequiredGin(c, resource, enrichedRequirements, settlementResp.ErrorReason)
-				return
+				sendPaymentRequiredGin(c, config, resource, requirements, settlementResp.ErrorReason)
+				return false
 			}
 
 			logger.Info("payment settled", "transaction", settlementResp.Transaction)
+			if requirement.Scheme == v2.SchemeUpTo && settlementResp.Amount == "" {
+				settlementResp.Amount = requirement.Amount
+			}
+			claim.Settled(settlementResp)
 
-			// Add X-PAYMENT-RESPONSE header with settlement info
-			if err := helpers.AddPaymentResponseHeader(c.Writer, settlementResp); err != nil {
+			// Add the payment response header with settlement info
+			if err := helpers.SetPaymentResponseHeader(c.Writer, config.Headers.PaymentResponseHeader(), settlementResp); err != nil {
 				logger.Warn("failed to add payment response header", "error", err)
 				// Continue anyway - payment was successful
 			}
+			if capture != nil {
+				capture.Settled()
+			}
+			return true
+		}
+
+		// Conditional requests under a revalidation policy settle once the
+		// handler's status is known
+		revalidating := settles && !metered && config.Revalidating(c.Request)
+		if settles && !metered && !revalidating && !settlePayment(*requirement) {
+			return
+		}
+		if capture != nil {
+			c.Writer = &cacheWriter{ResponseWriter: c.Writer, capture: capture}
+		}
+		var gate *statusGateWriter
+		if revalidating {
+			gate = newStatusGateWriter(c, func(statusCode int) bool {
+				if statusCode >= 400 {
+					logger.Warn("handler returned non-success, skipping payment settlement", "status", statusCode)
+					return true
+				}
+				settleRevalidation, deliver := config.RevalidationOutcome(c.Request, statusCode)
+				if !deliver {
+					logger.Info("resource changed, revalidation payment does not cover content", "status", statusCode)
+					full, err := config.FullRequirementsFor(c.Request, enrichedRequirements)
+					if err != nil {
+						full = enrichedRequirements
+					}
+					sendPaymentRequiredGin(c, config, resource, full, "Resource changed; full payment required")
+					return false
+				}
+				if !settleRevalidation {
+					logger.Info("resource not modified, skipping payment settlement")
+					return true
+				}
+				return settlePayment(*requirement)
+			})
 		}
 
 		// Store payment info in Gin context for handler access
@@ -202,21 +448,330 @@ func NewX402Middleware(config Config) gin.HandlerFunc {
 
 		// Also store in stdlib context for compatibility with http package helpers
 		ctx := context.WithValue(c.Request.Context(), v2http.PaymentContextKey, verifyResp)
+		if deferred {
+			deferredPayment := v2http.NewDeferredPayment(*payment, *requirement, verifyResp)
+			deferredPayment.Clock = config.Clock
+			c.Set(DeferredPaymentContextKey, deferredPayment)
+			ctx = context.WithValue(ctx, v2http.DeferredPaymentContextKey, deferredPayment)
+		}
+		var hold *v2http.PaymentHold
+		if manualCapture {
+			hold = v2http.NewPaymentHold(*payment, *requirement, verifyResp, settle, c.Writer)
+			hold.ResponseHeader = config.Headers.PaymentResponseHeader()
+			c.Set(HoldContextKey, hold)
+			ctx = context.WithValue(ctx, v2http.HoldContextKey, hold)
+		}
 		c.Request = c.Request.WithContext(ctx)
 
+		// Metered payments are settled for what the response cost once the
+		// handler completes, so the response is buffered until then
+		if metered {
+			start := v2.ClockOrSystem(config.Clock).Now()
+			underlying := c.Writer
+			limit := config.MaxBufferedResponseBytes
+			if limit <= 0 {
+				limit = v2http.DefaultMaxBufferedResponseBytes
+			}
+			buffer := &meteredWriter{ResponseWriter: underlying, header: underlying.Header().Clone(), limit: limit}
+			buffer.overflow = func(status int) bool {
+				if status >= 400 {
+					logger.Warn("handler returned non-success, skipping payment settlement", "status", status)
+					return true
+				}
+				// Too large to hold until metered: charged the authorized maximum
+				c.Writer = underlying
+				defer func() { c.Writer = buffer }()
+				return settlePayment(*requirement)
+			}
+			c.Writer = buffer
+			c.Next()
+			c.Writer = underlying
+			if buffer.committed {
+				return
+			}
+
+			usage := v2http.Usage{
+				Status:   buffer.Status(),
+				Bytes:    int64(buffer.body.Len()),
+				Duration: v2.ClockOrSystem(config.Clock).Now().Sub(start),
+			}
+			if usage.Status >= 400 {
+				logger.Warn("handler returned non-success, skipping payment settlement", "status", usage.Status)
+				buffer.flush()
+				return
+			}
+			settlement, owed, err := config.MeteredRequirement(c.Request, usage, *requirement)
+			if err != nil {
+				logger.Error("failed to meter response", "error", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+					"x402Version": v2.X402Version,
+					"error":       "Payment metering failed",
+				})
+				return
+			}
+			if !owed {
+				// The payment was used for this response, so it is not released for reuse
+				claim.Consumed()
+			} else if !settlePayment(settlement) {
+				return
+			}
+			buffer.flush()
+			return
+		}
+
 		// Payment successful - call next handler
 		c.Next()
+
+		if gate != nil {
+			gate.finish()
+		}
+		if capture != nil {
+			capture.Commit()
+		}
+
+		if hold != nil && hold.State() == v2http.HoldReserved {
+			logger.Warn("payment hold neither captured nor voided, voiding", "payer", verifyResp.Payer)
+			_ = hold.Void()
+		}
+	}
+}
+
+// cacheWriter records the response written through Gin for a v2http.ResponseCache.
+type cacheWriter struct {
+	gin.ResponseWriter
+	capture *v2http.CaptureWriter
+}
+
+func (w *cacheWriter) WriteHeader(statusCode int) {
+	w.capture.WriteHeader(statusCode)
+}
+
+func (w *cacheWriter) Write(b []byte) (int, error) {
+	return w.capture.Write(b)
+}
+
+func (w *cacheWriter) WriteString(s string) (int, error) {
+	return w.capture.Write([]byte(s))
+}
+
+// meteredWriter buffers a handler's response until the amount owed for it is known.
+// Responses outgrowing limit are handed to overflow, which settles for them
+// before the buffered part is sent and the rest streamed; if it fails, the
+// handler's output is discarded.
+type meteredWriter struct {
+	gin.ResponseWriter
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	wrote    bool
+	limit    int64
+	overflow func(status int) bool
+
+	// committed is set once the response outgrew limit; refused is set if
+	// overflow failed
+	committed bool
+	refused   bool
+}
+
+// errMeteredRefused is returned to handlers writing a metered response that
+// outgrew the buffer and could not be settled.
+var errMeteredRefused = errors.New("x402: metered response too large and not settled")
+
+func (w *meteredWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *meteredWriter) WriteHeader(statusCode int) {
+	if statusCode > 0 && !w.wrote {
+		w.status = statusCode
+	}
+}
+
+func (w *meteredWriter) WriteHeaderNow() {
+	w.wrote = true
+}
+
+func (w *meteredWriter) Write(b []byte) (int, error) {
+	if w.refused {
+		return 0, errMeteredRefused
+	}
+	if w.committed {
+		return w.ResponseWriter.Write(b)
+	}
+	w.wrote = true
+	if int64(w.body.Len()+len(b)) > w.limit {
+		w.committed = true
+		if !w.overflow(w.Status()) {
+			w.refused = true
+			return 0, errMeteredRefused
+		}
+		w.flush()
+		return w.ResponseWriter.Write(b)
+	}
+	return w.body.Write(b)
+}
+
+func (w *meteredWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *meteredWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *meteredWriter) Size() int {
+	if !w.wrote {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *meteredWriter) Written() bool {
+	return w.wrote
+}
+
+// Flush is a no-op: metered responses are sent once settled.
+func (w *meteredWriter) Flush() {}
+
+// flush sends the buffered response to the underlying writer.
+func (w *meteredWriter) flush() {
+	for key, values := range w.header {
+		w.ResponseWriter.Header()[key] = values
+	}
+	w.ResponseWriter.WriteHeader(w.Status())
+	w.ResponseWriter.WriteHeaderNow()
+	_, _ = w.ResponseWriter.Write(w.body.Bytes())
+}
+
+// statusGateWriter holds back a handler's response until its status is known,
+// then lets decide pass it through or replace it. decide runs once, with the
+// underlying writer installed on the context and the handler's headers removed,
+// so it can write its own response; if it returns false the handler's output is
+// discarded.
+type statusGateWriter struct {
+	gin.ResponseWriter
+	c       *gin.Context
+	initial http.Header
+	discard http.Header
+	decide  func(statusCode int) bool
+	decided bool
+	blocked bool
+}
+
+// newStatusGateWriter installs a statusGateWriter on c.
+func newStatusGateWriter(c *gin.Context, decide func(statusCode int) bool) *statusGateWriter {
+	w := &statusGateWriter{
+		ResponseWriter: c.Writer,
+		c:              c,
+		initial:        c.Writer.Header().Clone(),
+		decide:         decide,
+	}
+	c.Writer = w
+	return w
+}
+
+func (w *statusGateWriter) gate(statusCode int) bool {
+	if w.decided {
+		return !w.blocked
+	}
+	w.decided = true
+
+	header := w.ResponseWriter.Header()
+	handlerHeader := header.Clone()
+	replaceHeader(header, w.initial)
+
+	w.c.Writer = w.ResponseWriter
+	w.blocked = !w.decide(statusCode)
+	w.c.Writer = w
+	if w.blocked {
+		return false
+	}
+
+	// Keep the handler's headers along with any decide added (e.g., X-PAYMENT-RESPONSE)
+	added := make(http.Header)
+	for key, values := range header {
+		if !slices.Equal(values, w.initial[key]) {
+			added[key] = values
+		}
+	}
+	replaceHeader(header, handlerHeader)
+	for key, values := range added {
+		header[key] = values
+	}
+	return true
+}
+
+// replaceHeader makes dst a copy of src.
+func replaceHeader(dst, src http.Header) {
+	for key := range dst {
+		delete(dst, key)
+	}
+	for key, values := range src {
+		dst[key] = append([]string(nil), values...)
+	}
+}
+
+// Header returns the response header map, or a throwaway map once the
+// handler's response has been replaced.
+func (w *statusGateWriter) Header() http.Header {
+	if w.blocked {
+		if w.discard == nil {
+			w.discard = make(http.Header)
+		}
+		return w.discard
+	}
+	return w.ResponseWriter.Header()
+}
+
+func (w *statusGateWriter) WriteHeader(statusCode int) {
+	if w.gate(statusCode) {
+		w.ResponseWriter.WriteHeader(statusCode)
+	}
+}
+
+func (w *statusGateWriter) WriteHeaderNow() {
+	if w.gate(w.ResponseWriter.Status()) {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *statusGateWriter) Write(b []byte) (int, error) {
+	if !w.gate(w.ResponseWriter.Status()) {
+		return len(b), nil
 	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusGateWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// finish decides for handlers that returned without writing anything.
+func (w *statusGateWriter) finish() {
+	w.gate(w.ResponseWriter.Status())
 }
 
-// sendPaymentRequiredGin sends a 402 Payment Required response using Gin's JSON methods.
+// sendPaymentRequiredGin sends a 402 Payment Required response with config's messages,
+// using Gin's JSON methods unless an HTML paywall is configured.
 // It aborts the request chain and returns the payment requirements to the client.
-func sendPaymentRequiredGin(c *gin.Context, resource v2.ResourceInfo, requirements []v2.PaymentRequirements, errMsg string) {
-	response := v2.PaymentRequired{
-		X402Version: v2.X402Version,
-		Error:       errMsg,
-		Resource:    &resource,
-		Accepts:     requirements,
+func sendPaymentRequiredGin(c *gin.Context, config Config, resource v2.ResourceInfo, requirements []v2.PaymentRequirements, errMsg string) {
+	if config.Messages.HTML {
+		if err := config.WritePaymentRequired(c.Writer, c.Request, resource, requirements, errMsg); err != nil {
+			slog.Default().Error("failed to send payment required response", "error", err)
+		}
+		c.Abort()
+		return
+	}
+
+	response, err := config.PaymentRequiredResponse(c.Request, resource, requirements, errMsg)
+	if err != nil {
+		slog.Default().Error("failed to render payment required messages", "error", err)
+	}
+	if err := helpers.Attest(&response, config.Attester); err != nil {
+		slog.Default().Error("failed to attest payment requirements", "error", err)
 	}
 
 	c.AbortWithStatusJSON(http.StatusPaymentRequired, response)
@@ -235,3 +790,53 @@ func GetPaymentFromContext(c *gin.Context) *v2.VerifyResponse {
 	}
 	return resp
 }
+
+// GetDeferredPaymentFromContext extracts the verified deferred payment from the Gin
+// context. Returns nil if the request was not paid with the deferred scheme.
+func GetDeferredPaymentFromContext(c *gin.Context) *v2http.DeferredPayment {
+	value, exists := c.Get(DeferredPaymentContextKey)
+	if !exists {
+		return nil
+	}
+	deferred, _ := value.(*v2http.DeferredPayment)
+	return deferred
+}
+
+// GetPaymentHoldFromContext extracts the payment hold from the Gin context.
+// Returns nil unless the middleware runs with ManualCapture.
+func GetPaymentHoldFromContext(c *gin.Context) *v2http.PaymentHold {
+	value, exists := c.Get(HoldContextKey)
+	if !exists {
+		return nil
+	}
+	hold, _ := value.(*v2http.PaymentHold)
+	return hold
+}
+
+// TenantContextKey is the gin context key for storing the resolved tenant ID.
+const TenantContextKey = "x402_v2_tenant"
+
+// NewMultiTenantMiddleware creates a Gin payment middleware that routes each
+// request to a per-tenant configuration. See v2http.NewMultiTenantMiddleware.
+func NewMultiTenantMiddleware(tenants map[string]Config, resolver v2http.TenantResolver) gin.HandlerFunc {
+	handlers := make(map[string]gin.HandlerFunc, len(tenants))
+	for id, config := range tenants {
+		handlers[id] = NewX402Middleware(config)
+	}
+
+	return func(c *gin.Context) {
+		tenant, handler, ok := v2http.ResolveTenant(c.Request, resolver, handlers)
+		if !ok {
+			slog.Default().Warn("no tenant for request", "host", c.Request.Host, "path", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"x402Version": v2.X402Version,
+				"error":       "Unknown tenant",
+			})
+			return
+		}
+
+		c.Set(TenantContextKey, tenant)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), v2http.TenantContextKey, tenant))
+		handler(c)
+	}
+}
@@ -1,13 +1,18 @@
 package gin
 
 import (
+	"context"
 	"encoding/json"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
+	"github.com/mark3labs/x402-go/v2/facilitator"
 	v2http "github.com/mark3labs/x402-go/v2/http"
 )
 
@@ -450,3 +455,301 @@ func TestGinMiddleware_MultipleMiddlewares(t *testing.T) {
 		}
 	}
 }
+
+// TestGinMiddleware_Revalidation tests how 304 responses are charged under each revalidation policy
+func TestGinMiddleware_Revalidation(t *testing.T) {
+	settleCalls := 0
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+		case "/verify":
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			settleCalls++
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	pay := func(amount string) string {
+		accepted := requirement
+		accepted.Amount = amount
+		header, _ := encoding.EncodePayment(v2.PaymentPayload{
+			X402Version: 2,
+			Accepted:    accepted,
+			Payload:     map[string]interface{}{"signature": "0xsig"},
+		})
+		return header
+	}
+
+	tests := []struct {
+		name        string
+		policy      v2http.RevalidationPolicy
+		ifNoneMatch string
+		payment     string
+		wantStatus  int
+		wantSettles int
+		wantAmount  string
+	}{
+		{name: "free: unpaid 304", policy: v2http.RevalidationFree, ifNoneMatch: `"v1"`, wantStatus: http.StatusNotModified},
+		{name: "free: unpaid changed resource", policy: v2http.RevalidationFree, ifNoneMatch: `"v0"`, wantStatus: http.StatusPaymentRequired, wantAmount: "10000"},
+		{name: "free: paid 304 does not settle", policy: v2http.RevalidationFree, ifNoneMatch: `"v1"`, payment: pay("10000"), wantStatus: http.StatusNotModified},
+		{name: "free: paid changed resource settles", policy: v2http.RevalidationFree, ifNoneMatch: `"v0"`, payment: pay("10000"), wantStatus: http.StatusOK, wantSettles: 1},
+		{name: "reduced: 304 settles reduced payment", policy: v2http.RevalidationReduced, ifNoneMatch: `"v1"`, payment: pay("1000"), wantStatus: http.StatusNotModified, wantSettles: 1},
+		{name: "reduced: changed resource asks for full price", policy: v2http.RevalidationReduced, ifNoneMatch: `"v0"`, payment: pay("1000"), wantStatus: http.StatusPaymentRequired, wantAmount: "10000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settleCalls = 0
+			r := gin.New()
+			r.Use(NewX402Middleware(Config{
+				FacilitatorURL:      facilitatorServer.URL,
+				PaymentRequirements: []v2.PaymentRequirements{requirement},
+				Revalidation:        tt.policy,
+			}))
+			r.GET("/api/data", func(c *gin.Context) {
+				c.Header("ETag", `"v1"`)
+				if c.GetHeader("If-None-Match") == `"v1"` {
+					c.Status(http.StatusNotModified)
+					return
+				}
+				c.String(http.StatusOK, "content")
+			})
+
+			req := httptest.NewRequest("GET", "/api/data", nil)
+			req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			if tt.payment != "" {
+				req.Header.Set("X-PAYMENT", tt.payment)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if settleCalls != tt.wantSettles {
+				t.Errorf("Expected %d settle calls, got %d", tt.wantSettles, settleCalls)
+			}
+			if tt.wantAmount != "" {
+				var body v2.PaymentRequired
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || len(body.Accepts) != 1 {
+					t.Fatalf("Expected payment requirements, got %s", rec.Body.String())
+				}
+				if body.Accepts[0].Amount != tt.wantAmount {
+					t.Errorf("Expected amount %s, got %s", tt.wantAmount, body.Accepts[0].Amount)
+				}
+				if rec.Header().Get("ETag") != "" {
+					t.Error("Expected handler headers to be dropped from the 402")
+				}
+			} else if rec.Code != http.StatusPaymentRequired && rec.Header().Get("ETag") != `"v1"` {
+				t.Errorf("Expected handler ETag on the response, got %v", rec.Header())
+			}
+		})
+	}
+}
+
+// localFacilitator verifies and settles payments in place of the remote facilitator.
+type localFacilitator struct {
+	settles int
+}
+
+func (f *localFacilitator) Verify(ctx context.Context, payload v2.PaymentPayload, requirements v2.PaymentRequirements) (*v2.VerifyResponse, error) {
+	return &v2.VerifyResponse{IsValid: true, Payer: "0xLocalPayer"}, nil
+}
+
+func (f *localFacilitator) Settle(ctx context.Context, payload v2.PaymentPayload, requirements v2.PaymentRequirements) (*v2.SettleResponse, error) {
+	f.settles++
+	return &v2.SettleResponse{Success: true, Transaction: "0xlocal", Network: requirements.Network, Payer: "0xLocalPayer"}, nil
+}
+
+func (f *localFacilitator) Supported(ctx context.Context) (*v2.SupportedResponse, error) {
+	return &v2.SupportedResponse{}, nil
+}
+
+func TestGinMiddleware_SchemeFacilitators(t *testing.T) {
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+		default:
+			t.Errorf("Unexpected remote facilitator call: %s", r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            "direct",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	local := &localFacilitator{}
+	r := gin.New()
+	r.Use(NewX402Middleware(Config{
+		FacilitatorURL:      facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{requirement},
+		SchemeFacilitators:  map[string]facilitator.Interface{"direct": local},
+	}))
+	r.GET("/api/data", func(c *gin.Context) {
+		c.String(http.StatusOK, "content")
+	})
+
+	header, _ := encoding.EncodePayment(v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    requirement,
+		Payload:     map[string]interface{}{"txHash": "0xtx"},
+	})
+	req := httptest.NewRequest("GET", "/api/data", nil)
+	req.Header.Set("X-PAYMENT", header)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if local.settles != 1 {
+		t.Errorf("Expected the scheme facilitator to settle once, got %d", local.settles)
+	}
+}
+
+func TestGinMiddleware_RotatesFacilitatorAuthorization(t *testing.T) {
+	var authorizations []string
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+		case "/verify":
+			authorizations = append(authorizations, r.Header.Get("Authorization"))
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	rotator := v2http.NewRotator()
+	r := gin.New()
+	r.Use(NewX402Middleware(Config{
+		FacilitatorURL:           facilitatorServer.URL,
+		FacilitatorAuthorization: "Bearer old",
+		PaymentRequirements:      []v2.PaymentRequirements{requirement},
+		Rotator:                  rotator,
+	}))
+	r.GET("/api/data", func(c *gin.Context) {
+		c.String(http.StatusOK, "content")
+	})
+
+	pay := func(signature string) {
+		header, _ := encoding.EncodePayment(v2.PaymentPayload{
+			X402Version: 2,
+			Accepted:    requirement,
+			Payload:     map[string]interface{}{"signature": signature},
+		})
+		req := httptest.NewRequest("GET", "/api/data", nil)
+		req.Header.Set("X-PAYMENT", header)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	pay("0xsig1")
+	rotator.RotateFacilitatorAuthorization("Bearer new")
+	pay("0xsig2")
+	if len(authorizations) != 2 || authorizations[0] != "Bearer old" || authorizations[1] != "Bearer new" {
+		t.Errorf("Expected the rotated credentials to be used, got %v", authorizations)
+	}
+}
+
+func TestGinMiddleware_Metered(t *testing.T) {
+	var settled []string
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+		case "/verify":
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			var body struct {
+				PaymentRequirements v2.PaymentRequirements `json:"paymentRequirements"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			settled = append(settled, body.PaymentRequirements.Amount)
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            v2.SchemeUpTo,
+		Network:           "eip155:84532",
+		Amount:            "10",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+		Extra: map[string]interface{}{
+			"assetTransferMethod": v2.AssetTransferMethodPermit2,
+			"spender":             "0x3333333333333333333333333333333333333333",
+		},
+	}
+	r := gin.New()
+	r.Use(NewX402Middleware(Config{
+		FacilitatorURL:           facilitatorServer.URL,
+		PaymentRequirements:      []v2.PaymentRequirements{requirement},
+		Meter:                    v2http.PerByte(big.NewInt(1), 1000),
+		MaxBufferedResponseBytes: 4000,
+	}))
+	r.GET("/api/data", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		for i := 0; i < 6; i++ {
+			_, _ = c.Writer.WriteString(strings.Repeat("x", 1000))
+		}
+	})
+
+	header, _ := encoding.EncodePayment(v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    requirement,
+		Payload:     map[string]interface{}{"signature": "0xsig"},
+	})
+	req := httptest.NewRequest("GET", "/api/data", nil)
+	req.Header.Set("X-PAYMENT", header)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	// Outgrowing the buffer settles the authorized maximum and streams the rest
+	if rec.Code != http.StatusOK || rec.Body.Len() != 6000 {
+		t.Fatalf("Expected the full response, got %d with %d bytes", rec.Code, rec.Body.Len())
+	}
+	if len(settled) != 1 || settled[0] != "10" {
+		t.Errorf("Expected one settlement of the maximum, got %v", settled)
+	}
+	if settlement := v2http.GetSettlement(rec.Result()); settlement == nil || settlement.Amount != "10" {
+		t.Errorf("Expected the charge in X-PAYMENT-RESPONSE, got %+v", settlement)
+	}
+}
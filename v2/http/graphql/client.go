@@ -0,0 +1,257 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// AnonymousOperation is the operation name under which Client accounts for
+// requests whose operation has no name.
+const AnonymousOperation = "(anonymous)"
+
+// Request is a GraphQL request sent by Client.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// OperationSpend records the payments Client made for one operation.
+type OperationSpend struct {
+	// Operation is the operation name, or AnonymousOperation.
+	Operation string
+
+	// Payments is the number of successful payments.
+	Payments int
+
+	// Failures is the number of failed payments, including payments refused
+	// by the operation's budget.
+	Failures int
+
+	// Spent is the amount paid per asset, in atomic units.
+	Spent map[string]*big.Int
+
+	// LastTransaction is the transaction of the most recent payment.
+	LastTransaction string
+
+	// LastPayment is when the most recent payment was made.
+	LastPayment time.Time
+}
+
+// Client sends GraphQL requests to one endpoint through an x402-enabled
+// http.Client, such as a v2http.Client. Since every operation is sent to the
+// same URL, Client tells them apart by operation name: each operation has its
+// own budget, enforced in addition to any spend guard of the underlying
+// client, and its own payment record. It is safe for concurrent use.
+type Client struct {
+	endpoint      string
+	http          *http.Client
+	budgets       map[string]*big.Int
+	defaultBudget *big.Int
+
+	mu     sync.Mutex
+	guards map[string]*v2.SpendGuard
+	spend  map[string]*OperationSpend
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithOperationBudget limits the total amount, summed across assets, paid for
+// operation. Payments beyond it fail with a *v2.PaymentError with
+// v2.ErrCodeSpendLimitExceeded.
+func WithOperationBudget(operation string, limit *big.Int) ClientOption {
+	return func(c *Client) {
+		c.budgets[operation] = limit
+	}
+}
+
+// WithDefaultOperationBudget limits the total amount paid for each operation
+// without a budget of its own.
+func WithDefaultOperationBudget(limit *big.Int) ClientOption {
+	return func(c *Client) {
+		c.defaultBudget = limit
+	}
+}
+
+// NewClient creates a Client sending requests to endpoint with httpClient.
+func NewClient(endpoint string, httpClient *http.Client, opts ...ClientOption) *Client {
+	c := &Client{
+		endpoint: endpoint,
+		http:     httpClient,
+		budgets:  make(map[string]*big.Int),
+		guards:   make(map[string]*v2.SpendGuard),
+		spend:    make(map[string]*OperationSpend),
+	}
+	if c.http == nil {
+		c.http = http.DefaultClient
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Post sends request as a JSON POST to the Client's endpoint.
+func (c *Client) Post(ctx context.Context, request Request) (*http.Response, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("encoding request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.Do(req)
+}
+
+// Do sends req, a GraphQL request in any form the server accepts, and
+// accounts for its payments under its operation name.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	operation, err := operationName(req)
+	if err != nil {
+		return nil, err
+	}
+	ctx := req.Context()
+	if guard := c.guard(operation); guard != nil {
+		ctx = v2.ContextWithSpendGuard(ctx, guard)
+	}
+	ctx = v2.ContextWithPaymentCallback(ctx, func(event v2.PaymentEvent) {
+		c.record(operation, event)
+	})
+	return c.http.Do(req.WithContext(ctx))
+}
+
+// Spend returns the payment record of operation.
+func (c *Client) Spend(operation string) OperationSpend {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if spend, ok := c.spend[operation]; ok {
+		return copySpend(spend)
+	}
+	return OperationSpend{Operation: operation, Spent: map[string]*big.Int{}}
+}
+
+// Spending returns the payment records of every operation that made or
+// attempted a payment, sorted by operation name.
+func (c *Client) Spending() []OperationSpend {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	records := make([]OperationSpend, 0, len(c.spend))
+	for _, spend := range c.spend {
+		records = append(records, copySpend(spend))
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Operation < records[j].Operation })
+	return records
+}
+
+// guard returns the spend guard enforcing operation's budget, or nil if it
+// has none.
+func (c *Client) guard(operation string) *v2.SpendGuard {
+	limit, ok := c.budgets[operation]
+	if !ok {
+		limit = c.defaultBudget
+	}
+	if limit == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	guard, ok := c.guards[operation]
+	if !ok {
+		guard = v2.NewSpendGuard(limit)
+		c.guards[operation] = guard
+	}
+	return guard
+}
+
+// record adds a payment event to operation's record.
+func (c *Client) record(operation string, event v2.PaymentEvent) {
+	if event.Type != v2.PaymentEventSuccess && event.Type != v2.PaymentEventFailure {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	spend, ok := c.spend[operation]
+	if !ok {
+		spend = &OperationSpend{Operation: operation, Spent: make(map[string]*big.Int)}
+		c.spend[operation] = spend
+	}
+	if event.Type == v2.PaymentEventFailure {
+		spend.Failures++
+		return
+	}
+	spend.Payments++
+	spend.LastTransaction = event.Transaction
+	spend.LastPayment = event.Timestamp
+	if amount, ok := new(big.Int).SetString(event.Amount, 10); ok {
+		if spend.Spent[event.Asset] == nil {
+			spend.Spent[event.Asset] = new(big.Int)
+		}
+		spend.Spent[event.Asset].Add(spend.Spent[event.Asset], amount)
+	}
+}
+
+// copySpend returns a copy of spend that shares no state with it.
+func copySpend(spend *OperationSpend) OperationSpend {
+	out := *spend
+	out.Spent = make(map[string]*big.Int, len(spend.Spent))
+	for asset, amount := range spend.Spent {
+		out.Spent[asset] = new(big.Int).Set(amount)
+	}
+	return out
+}
+
+// operationName returns the name of the operation req executes, restoring
+// its body. The name comes from the operationName parameter or, if there is
+// none, from the query's only operation.
+func operationName(req *http.Request) (string, error) {
+	var p params
+	if req.Method == http.MethodGet || req.Body == nil || req.Body == http.NoBody {
+		p.Query = req.URL.Query().Get("query")
+		p.OperationName = req.URL.Query().Get("operationName")
+	} else {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("reading request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		if mediaType == "application/graphql" {
+			p.Query = string(body)
+			p.OperationName = req.URL.Query().Get("operationName")
+		} else if err := json.Unmarshal(body, &p); err != nil {
+			return "", fmt.Errorf("decoding request body: %w", err)
+		}
+	}
+
+	if p.OperationName != "" {
+		return p.OperationName, nil
+	}
+	doc, err := parser.ParseQuery(&ast.Source{Input: p.Query})
+	if err == nil && len(doc.Operations) == 1 && doc.Operations[0].Name != "" {
+		return doc.Operations[0].Name, nil
+	}
+	return AnonymousOperation, nil
+}
@@ -0,0 +1,126 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+)
+
+// testSigner signs any requirement on the test network.
+type testSigner struct{}
+
+func (testSigner) Network() string             { return "eip155:84532" }
+func (testSigner) Scheme() string              { return "exact" }
+func (testSigner) GetPriority() int            { return 0 }
+func (testSigner) GetTokens() []v2.TokenConfig { return nil }
+func (testSigner) GetMaxAmount() *big.Int      { return nil }
+func (testSigner) CanSign(req *v2.PaymentRequirements) bool {
+	return req.Network == "eip155:84532" && req.Scheme == "exact"
+}
+func (testSigner) Sign(req *v2.PaymentRequirements) (*v2.PaymentPayload, error) {
+	return &v2.PaymentPayload{X402Version: v2.X402Version, Accepted: *req, Payload: map[string]interface{}{"signature": "0xsig"}}, nil
+}
+
+func TestClient(t *testing.T) {
+	prices := map[string]string{"Forecast": "1000", "News": "500", AnonymousOperation: "100"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p params
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil || p.Query == "" {
+			t.Errorf("server received body without query: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		name := p.OperationName
+		if name == "" {
+			name = AnonymousOperation
+			if strings.Contains(p.Query, "query News") {
+				name = "News"
+			}
+		}
+		if r.Header.Get(PaymentHeader) == "" {
+			w.WriteHeader(http.StatusPaymentRequired)
+			_ = json.NewEncoder(w).Encode(v2.PaymentRequired{
+				X402Version: v2.X402Version,
+				Accepts:     []v2.PaymentRequirements{requirement(prices[name])},
+			})
+			return
+		}
+		encoded, _ := encoding.EncodeSettlement(v2.SettleResponse{Success: true, Transaction: "0x" + name, Network: "eip155:84532"})
+		w.Header().Set(PaymentResponseHeader, encoded)
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	}))
+	defer server.Close()
+
+	httpClient, err := v2http.NewClient(v2http.WithSigner(testSigner{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(server.URL, httpClient.Client, WithOperationBudget("Forecast", big.NewInt(1500)))
+	ctx := context.Background()
+
+	post := func(request Request) error {
+		resp, err := client.Post(ctx, request)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+
+	forecast := Request{Query: "query Forecast { forecast(city: \"Paris\") }", OperationName: "Forecast"}
+	if err := post(forecast); err != nil {
+		t.Fatalf("first Forecast failed: %v", err)
+	}
+	// The second Forecast exceeds its budget
+	var paymentErr *v2.PaymentError
+	if err := post(forecast); !errors.As(err, &paymentErr) || paymentErr.Code != v2.ErrCodeSpendLimitExceeded {
+		t.Fatalf("expected spend limit error, got %v", err)
+	}
+	// Other operations have their own budgets; the name is read from the query
+	for i := 0; i < 3; i++ {
+		if err := post(Request{Query: "query News { news }"}); err != nil {
+			t.Fatalf("News failed: %v", err)
+		}
+	}
+	if err := post(Request{Query: "{ news }"}); err != nil {
+		t.Fatalf("anonymous operation failed: %v", err)
+	}
+
+	tests := []struct {
+		operation   string
+		payments    int
+		failures    int
+		spent       string
+		transaction string
+	}{
+		{"Forecast", 1, 1, "1000", "0xForecast"},
+		{"News", 3, 0, "1500", "0xNews"},
+		{AnonymousOperation, 1, 0, "100", "0x" + AnonymousOperation},
+		{"Unknown", 0, 0, "", ""},
+	}
+	for _, tt := range tests {
+		spend := client.Spend(tt.operation)
+		asset := requirement("").Asset
+		if spend.Payments != tt.payments || spend.Failures != tt.failures || spend.LastTransaction != tt.transaction {
+			t.Errorf("Spend(%q) = %+v", tt.operation, spend)
+		}
+		if got := spend.Spent[asset]; (got == nil && tt.spent != "") || (got != nil && got.String() != tt.spent) {
+			t.Errorf("Spend(%q) spent %v, want %s", tt.operation, got, tt.spent)
+		}
+	}
+	if records := client.Spending(); len(records) != 3 || records[0].Operation != AnonymousOperation {
+		t.Errorf("unexpected records %+v", records)
+	}
+}
@@ -0,0 +1,389 @@
+// Package graphql provides x402 v2 payment gating for GraphQL servers.
+//
+// The middleware sits in front of a GraphQL handler, such as gqlgen's
+// handler.Server, and prices operations by the fields they select. Fields are
+// priced by schema coordinate with WithField, or in the schema itself with the
+// @x402 directive (see DirectiveName). Operations selecting paid fields must
+// carry a payment in the X-PAYMENT header; otherwise the middleware answers
+// with a GraphQL error whose extensions hold the accepted payment options:
+//
+//	{"errors":[{"message":"Payment required","extensions":{"code":"PAYMENT_REQUIRED","x402Version":2,"accepts":[...]}}],"data":null}
+//
+// Payments are settled once the handler has resolved every paid field without
+// errors, with the settlement returned in the X-PAYMENT-RESPONSE header.
+// Resolvers read the verified payment with v2http.GetPaymentFromContext.
+//
+// Queries and mutations sent with GET or POST are gated; POST bodies must be
+// JSON or application/graphql. Other requests, like WebSocket upgrades for
+// subscriptions, are passed through unchanged and must be protected by their
+// transport.
+//
+// On the client side, Client sends operations to a paid GraphQL endpoint and
+// keeps budgets and payment records per operation name.
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+	"github.com/mark3labs/x402-go/v2/http/internal/helpers"
+	"github.com/mark3labs/x402-go/v2/http/internal/rpcgate"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// Header names used by the middleware.
+const (
+	PaymentHeader         = rpcgate.PaymentHeader
+	PaymentRequiredHeader = rpcgate.PaymentRequiredHeader
+	PaymentResponseHeader = rpcgate.PaymentResponseHeader
+)
+
+// Error codes set in the "code" extension of GraphQL errors.
+const (
+	CodePaymentRequired    = "PAYMENT_REQUIRED"
+	CodeInvalidPayment     = "INVALID_PAYMENT"
+	CodePaymentUnavailable = "PAYMENT_UNAVAILABLE"
+	CodeBadRequest         = "BAD_REQUEST"
+)
+
+// DefaultMaxBodySize is the default limit on request bodies inspected by the
+// middleware.
+const DefaultMaxBodySize = 4 << 20
+
+// Option configures the middleware.
+type Option func(*options)
+
+type options struct {
+	fields      map[string][]v2.PaymentRequirements
+	prices      map[string][]v2.PaymentRequirements
+	schema      *ast.Schema
+	maxBodySize int64
+}
+
+// WithField requires payment for a field, given by its schema coordinate like
+// "Query.forecast" or "Mutation.createReport". Without WithSchema only root
+// fields can be priced. Once any field or price is configured, unlisted fields
+// are free; otherwise every operation requires Config.PaymentRequirements.
+func WithField(coordinate string, requirements ...v2.PaymentRequirements) Option {
+	return func(o *options) {
+		if o.fields == nil {
+			o.fields = make(map[string][]v2.PaymentRequirements)
+		}
+		o.fields[coordinate] = requirements
+	}
+}
+
+// WithPrice registers the requirements of fields annotated with
+// @x402(price: name) in the schema given to WithSchema.
+func WithPrice(name string, requirements ...v2.PaymentRequirements) Option {
+	return func(o *options) {
+		if o.prices == nil {
+			o.prices = make(map[string][]v2.PaymentRequirements)
+		}
+		o.prices[name] = requirements
+	}
+}
+
+// WithSchema sets the schema served by the handler, such as the one returned
+// by gqlgen's ExecutableSchema.Schema. It enables pricing of nested fields and
+// of fields annotated with the @x402 directive.
+func WithSchema(schema *ast.Schema) Option {
+	return func(o *options) {
+		o.schema = schema
+	}
+}
+
+// WithMaxBodySize limits the request bodies the middleware reads. It defaults
+// to DefaultMaxBodySize.
+func WithMaxBodySize(size int64) Option {
+	return func(o *options) {
+		o.maxBodySize = size
+	}
+}
+
+// middleware holds the state shared by the requests of a NewMiddleware.
+type middleware struct {
+	config   v2http.Config
+	options  options
+	gate     *rpcgate.Gate
+	perField bool
+}
+
+// NewMiddleware creates GraphQL payment gating middleware using the
+// facilitators, requirements and settings of config.
+//
+// Example:
+//
+//	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: resolver}))
+//	paywall := graphql.NewMiddleware(x402http.Config{
+//	    FacilitatorURL: "https://facilitator.x402.org",
+//	}, graphql.WithSchema(srv.ExecutableSchema().Schema()),
+//	    graphql.WithField("Query.forecast", requirement))
+//	http.Handle("/query", paywall(srv))
+func NewMiddleware(config v2http.Config, opts ...Option) func(http.Handler) http.Handler {
+	o := options{maxBodySize: DefaultMaxBodySize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// Fields and named prices share the gate's table; prices are keyed by
+	// "@name", which no coordinate can collide with.
+	priced := make(map[string][]v2.PaymentRequirements, len(o.fields)+len(o.prices))
+	for coordinate, requirements := range o.fields {
+		priced[coordinate] = requirements
+	}
+	for name, requirements := range o.prices {
+		priced["@"+name] = requirements
+	}
+
+	m := &middleware{
+		config:   config,
+		options:  o,
+		gate:     rpcgate.New(config, priced),
+		perField: len(priced) > 0,
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m.serve(w, r, next)
+		})
+	}
+}
+
+func (m *middleware) serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	logger := slog.Default()
+	r = m.gate.TakeQueryPayment(r)
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		next.ServeHTTP(w, r)
+		return
+	}
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	params, err := m.readParams(r)
+	if err != nil {
+		logger.Warn("unable to read GraphQL request", "error", err)
+		status := http.StatusBadRequest
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, graphQLError{
+			Message:    "Unable to read GraphQL request",
+			Extensions: map[string]interface{}{"code": CodeBadRequest},
+		})
+		return
+	}
+
+	fields, err := m.paidFields(params)
+	if err != nil {
+		logger.Error("failed to price GraphQL operation", "error", err)
+		writeError(w, http.StatusInternalServerError, graphQLError{
+			Message:    "Payment configuration error",
+			Extensions: map[string]interface{}{"code": CodePaymentUnavailable},
+		})
+		return
+	}
+	if len(fields) == 0 {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	// Per-field prices add up; otherwise the operation is paid once.
+	requirements := fields[0].requirements
+	if m.perField {
+		requirements, err = combine(fields)
+	}
+	if err != nil {
+		logger.Warn("failed to combine payment requirements", "error", err)
+		writeError(w, http.StatusBadRequest, graphQLError{
+			Message:    "Paid fields cannot be paid together",
+			Extensions: map[string]interface{}{"code": CodeBadRequest},
+		})
+		return
+	}
+
+	var coordinates []string
+	for _, field := range fields {
+		if !slices.Contains(coordinates, field.coordinate) {
+			coordinates = append(coordinates, field.coordinate)
+		}
+	}
+	resource := m.config.Resource
+	if resource.URL == "" {
+		resource.URL = helpers.BuildResourceURL(r)
+	}
+	if resource.Description == "" {
+		resource.Description = "Payment required for " + strings.Join(coordinates, ", ")
+	}
+
+	payment, failure := m.gate.Check(r.Context(), resource, requirements, m.gate.ReadPayment(r))
+	if failure != nil {
+		writeFailure(w, failure)
+		return
+	}
+
+	buffer := &responseBuffer{header: make(http.Header), status: http.StatusOK}
+	next.ServeHTTP(buffer, r.WithContext(payment.Context(r.Context())))
+
+	var response graphQLResponse
+	if buffer.status < 300 && json.Unmarshal(buffer.body.Bytes(), &response) == nil && resolved(response, fields) {
+		settlement, failure := payment.Settle(r.Context())
+		if failure != nil {
+			writeFailure(w, failure)
+			return
+		}
+		if settlement != "" {
+			buffer.header.Set(m.gate.PaymentResponseHeader(), settlement)
+		}
+	} else {
+		logger.Info("paid fields not resolved, skipping settlement", "fields", coordinates)
+		payment.Release()
+	}
+
+	for key, values := range buffer.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(buffer.status)
+	_, _ = buffer.body.WriteTo(w)
+}
+
+// params are the GraphQL request parameters the middleware inspects.
+type params struct {
+	Query         string `json:"query"`
+	OperationName string `json:"operationName"`
+}
+
+// readParams reads the query and operation name of r, restoring its body for
+// the next handler.
+func (m *middleware) readParams(r *http.Request) (params, error) {
+	var p params
+	if r.Method == http.MethodGet {
+		p.Query = r.URL.Query().Get("query")
+		p.OperationName = r.URL.Query().Get("operationName")
+		return p, nil
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, m.options.maxBodySize))
+	if err != nil {
+		return p, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/graphql" {
+		p.Query = string(body)
+		p.OperationName = r.URL.Query().Get("operationName")
+		return p, nil
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return p, fmt.Errorf("decoding request body: %w", err)
+	}
+	return p, nil
+}
+
+// paidFields returns the paid fields selected by the request's operation.
+// Documents the handler will reject, for failing to parse or validate or
+// lacking the operation, have none.
+func (m *middleware) paidFields(p params) ([]paidField, error) {
+	var doc *ast.QueryDocument
+	var err error
+	if m.options.schema != nil {
+		doc, err = loadQuery(m.options.schema, p.Query)
+	} else {
+		doc, err = parser.ParseQuery(&ast.Source{Input: p.Query})
+	}
+	if err != nil {
+		return nil, nil
+	}
+	operation := doc.Operations.ForName(p.OperationName)
+	if operation == nil {
+		return nil, nil
+	}
+
+	rootType := m.rootType(operation.Operation)
+	pricer := &pricer{doc: doc, lookup: m.lookup, active: make(map[string]bool)}
+	if !m.perField {
+		// Every root field carries the configured requirements, so that the
+		// operation settles only once all of them resolve.
+		pricer.lookup = func(coordinate string, _ *ast.FieldDefinition) ([]v2.PaymentRequirements, error) {
+			if !strings.HasPrefix(coordinate, rootType+".") {
+				return nil, nil
+			}
+			return m.gate.Requirements(""), nil
+		}
+	}
+	return pricer.collect(operation.SelectionSet, rootType, nil, nil)
+}
+
+// loadQuery parses and validates query against schema.
+func loadQuery(schema *ast.Schema, query string) (*ast.QueryDocument, error) {
+	doc, errs := gqlparser.LoadQuery(schema, query)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return doc, nil
+}
+
+// rootType returns the name of the root type of operation.
+func (m *middleware) rootType(operation ast.Operation) string {
+	if schema := m.options.schema; schema != nil {
+		var definition *ast.Definition
+		switch operation {
+		case ast.Query:
+			definition = schema.Query
+		case ast.Mutation:
+			definition = schema.Mutation
+		case ast.Subscription:
+			definition = schema.Subscription
+		}
+		if definition != nil {
+			return definition.Name
+		}
+	}
+	switch operation {
+	case ast.Mutation:
+		return "Mutation"
+	case ast.Subscription:
+		return "Subscription"
+	default:
+		return "Query"
+	}
+}
+
+// lookup returns the requirements of the field at coordinate, configured with
+// WithField or named by its @x402 directive.
+func (m *middleware) lookup(coordinate string, definition *ast.FieldDefinition) ([]v2.PaymentRequirements, error) {
+	if requirements := m.gate.Requirements(coordinate); len(requirements) > 0 {
+		return requirements, nil
+	}
+	if definition == nil {
+		return nil, nil
+	}
+	directive := definition.Directives.ForName(DirectiveName)
+	if directive == nil {
+		return nil, nil
+	}
+	argument := directive.Arguments.ForName("price")
+	if argument == nil || argument.Value == nil {
+		return nil, fmt.Errorf("@%s on %s has no price", DirectiveName, coordinate)
+	}
+	requirements := m.gate.Requirements("@" + argument.Value.Raw)
+	if len(requirements) == 0 {
+		return nil, fmt.Errorf("unknown price %q for %s", argument.Value.Raw, coordinate)
+	}
+	return requirements, nil
+}
@@ -0,0 +1,254 @@
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const testSchema = `
+directive @x402(price: String!) on FIELD_DEFINITION
+
+type Query {
+	weather(city: String!): Weather!
+	forecast(city: String!): String!
+	news: String!
+}
+
+type Weather {
+	summary: String!
+	radar: String! @x402(price: "premium")
+}
+`
+
+func requirement(amount string) v2.PaymentRequirements {
+	return v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            amount,
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: testSchema})
+	perField := []Option{
+		WithField("Query.forecast", requirement("1000")),
+		WithField("Query.news", requirement("500")),
+		WithPrice("premium", requirement("2000")),
+	}
+
+	tests := []struct {
+		name          string
+		opts          []Option
+		config        v2http.Config
+		query         string
+		payment       string
+		response      string
+		wantStatus    int
+		wantCode      string
+		wantAmount    string
+		wantSettled   bool
+		wantForwarded bool
+	}{
+		{
+			name:          "free field",
+			opts:          perField,
+			query:         `{ weather(city: "Oslo") { summary } }`,
+			response:      `{"data":{"weather":{"summary":"rain"}}}`,
+			wantStatus:    http.StatusOK,
+			wantForwarded: true,
+		},
+		{
+			name:       "paid root field",
+			opts:       perField,
+			query:      `{ forecast(city: "Oslo") }`,
+			wantStatus: http.StatusPaymentRequired,
+			wantCode:   CodePaymentRequired,
+			wantAmount: "1000",
+		},
+		{
+			name:       "paid fields add up",
+			opts:       perField,
+			query:      `{ a: forecast(city: "Oslo") b: forecast(city: "Bergen") news }`,
+			wantStatus: http.StatusPaymentRequired,
+			wantCode:   CodePaymentRequired,
+			wantAmount: "2500",
+		},
+		{
+			name:       "paid field in fragment",
+			opts:       perField,
+			query:      `query { ...F } fragment F on Query { news }`,
+			wantStatus: http.StatusPaymentRequired,
+			wantCode:   CodePaymentRequired,
+			wantAmount: "500",
+		},
+		{
+			name:          "nested directive ignored without schema",
+			opts:          perField,
+			query:         `{ weather(city: "Oslo") { radar } }`,
+			response:      `{"data":{"weather":{"radar":"..."}}}`,
+			wantStatus:    http.StatusOK,
+			wantForwarded: true,
+		},
+		{
+			name:       "nested directive with schema",
+			opts:       append([]Option{WithSchema(schema)}, perField...),
+			query:      `{ weather(city: "Oslo") { summary radar } }`,
+			wantStatus: http.StatusPaymentRequired,
+			wantCode:   CodePaymentRequired,
+			wantAmount: "2000",
+		},
+		{
+			name:          "paid and resolved",
+			opts:          perField,
+			query:         `{ forecast(city: "Oslo") }`,
+			payment:       "1000",
+			response:      `{"data":{"forecast":"sunny"}}`,
+			wantStatus:    http.StatusOK,
+			wantSettled:   true,
+			wantForwarded: true,
+		},
+		{
+			name:          "paid but unresolved",
+			opts:          perField,
+			query:         `{ forecast(city: "Oslo") }`,
+			payment:       "1000",
+			response:      `{"data":null,"errors":[{"message":"boom","path":["forecast"]}]}`,
+			wantStatus:    http.StatusOK,
+			wantForwarded: true,
+		},
+		{
+			name:       "invalid payment",
+			opts:       perField,
+			query:      `{ forecast(city: "Oslo") }`,
+			payment:    "invalid",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   CodeInvalidPayment,
+		},
+		{
+			name:       "whole operation priced",
+			config:     v2http.Config{PaymentRequirements: []v2.PaymentRequirements{requirement("300")}},
+			query:      `{ forecast(city: "Oslo") news }`,
+			wantStatus: http.StatusPaymentRequired,
+			wantCode:   CodePaymentRequired,
+			wantAmount: "300",
+		},
+		{
+			name:          "introspection is free",
+			config:        v2http.Config{PaymentRequirements: []v2.PaymentRequirements{requirement("300")}},
+			query:         `{ __typename }`,
+			response:      `{"data":{"__typename":"Query"}}`,
+			wantStatus:    http.StatusOK,
+			wantForwarded: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settled := false
+			facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/supported":
+					_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+				case "/verify":
+					_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+				case "/settle":
+					settled = true
+					_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xtx", Network: "eip155:84532"})
+				}
+			}))
+			defer facilitatorServer.Close()
+
+			forwarded := false
+			graphQLHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				forwarded = true
+				var body params
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Query != tt.query {
+					t.Errorf("Expected the request body to be forwarded, got %+v (%v)", body, err)
+				}
+				if tt.payment != "" && v2http.GetPaymentFromContext(r.Context()) == nil {
+					t.Error("Expected payment in handler context")
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(tt.response))
+			})
+
+			config := tt.config
+			config.FacilitatorURL = facilitatorServer.URL
+			handler := NewMiddleware(config, tt.opts...)(graphQLHandler)
+
+			body, _ := json.Marshal(params{Query: tt.query})
+			req := httptest.NewRequest("POST", "/graphql", strings.NewReader(string(body)))
+			req.Header.Set("Content-Type", "application/json")
+			switch tt.payment {
+			case "":
+			case "invalid":
+				req.Header.Set(PaymentHeader, "not-base64!")
+			default:
+				header, _ := encoding.EncodePayment(v2.PaymentPayload{
+					X402Version: 2,
+					Accepted:    requirement(tt.payment),
+					Payload:     map[string]interface{}{"signature": "0xsig"},
+				})
+				req.Header.Set(PaymentHeader, header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if forwarded != tt.wantForwarded {
+				t.Errorf("Expected forwarded=%v, got %v", tt.wantForwarded, forwarded)
+			}
+			if settled != tt.wantSettled {
+				t.Errorf("Expected settled=%v, got %v", tt.wantSettled, settled)
+			}
+			if got := w.Header().Get(PaymentResponseHeader) != ""; got != tt.wantSettled {
+				t.Errorf("Expected payment response header=%v, got %v", tt.wantSettled, got)
+			}
+			if tt.wantForwarded && w.Body.String() != tt.response {
+				t.Errorf("Expected handler response %s, got %s", tt.response, w.Body.String())
+			}
+			if tt.wantCode == "" {
+				return
+			}
+
+			var resp struct {
+				Errors []struct {
+					Extensions struct {
+						Code    string                   `json:"code"`
+						Accepts []v2.PaymentRequirements `json:"accepts"`
+					} `json:"extensions"`
+				} `json:"errors"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || len(resp.Errors) != 1 {
+				t.Fatalf("Expected one GraphQL error, got %s", w.Body.String())
+			}
+			extensions := resp.Errors[0].Extensions
+			if extensions.Code != tt.wantCode {
+				t.Errorf("Expected code %s, got %s", tt.wantCode, extensions.Code)
+			}
+			if tt.wantAmount != "" {
+				if len(extensions.Accepts) != 1 || extensions.Accepts[0].Amount != tt.wantAmount {
+					t.Errorf("Expected accepted amount %s, got %+v", tt.wantAmount, extensions.Accepts)
+				}
+				if w.Header().Get(PaymentRequiredHeader) == "" {
+					t.Error("Expected payment requirements header")
+				}
+			}
+		})
+	}
+}
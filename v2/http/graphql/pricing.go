@@ -0,0 +1,202 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// ErrNoCommonRequirement is returned when an operation selects paid fields
+// that share no payment option.
+var ErrNoCommonRequirement = errors.New("paid fields share no payment option")
+
+// DirectiveName is the schema directive that prices a field by name:
+//
+//	directive @x402(price: String!) on FIELD_DEFINITION
+//
+//	type Query {
+//	    forecast(city: String!): Forecast! @x402(price: "premium")
+//	}
+//
+// The price is registered with WithPrice. Directives are only read when the
+// schema is given with WithSchema.
+const DirectiveName = "x402"
+
+// paidField is a paid field selected by an operation.
+type paidField struct {
+	// coordinate is the schema coordinate, like "Query.forecast".
+	coordinate string
+
+	// path is the field's location in the response, by response key.
+	path []string
+
+	requirements []v2.PaymentRequirements
+}
+
+// pricer finds the paid fields of an operation.
+type pricer struct {
+	doc *ast.QueryDocument
+
+	// lookup returns the requirements of a field, or nil if it is free.
+	lookup func(coordinate string, definition *ast.FieldDefinition) ([]v2.PaymentRequirements, error)
+
+	// active holds the fragments being expanded, to stop on cycles.
+	active map[string]bool
+}
+
+// collect appends the paid fields of set, a selection on typeName at path. An
+// empty typeName marks selections whose type is unknown without a schema;
+// their fields are never paid.
+func (p *pricer) collect(set ast.SelectionSet, typeName string, path []string, paid []paidField) ([]paidField, error) {
+	for _, selection := range set {
+		var err error
+		switch s := selection.(type) {
+		case *ast.Field:
+			key := s.Alias
+			if key == "" {
+				key = s.Name
+			}
+			fieldPath := append(path[:len(path):len(path)], key)
+
+			parent := typeName
+			if s.ObjectDefinition != nil {
+				parent = s.ObjectDefinition.Name
+			}
+			if parent != "" && !strings.HasPrefix(s.Name, "__") {
+				coordinate := parent + "." + s.Name
+				requirements, err := p.lookup(coordinate, s.Definition)
+				if err != nil {
+					return nil, err
+				}
+				if len(requirements) > 0 {
+					paid = append(paid, paidField{coordinate: coordinate, path: fieldPath, requirements: requirements})
+				}
+			}
+
+			childType := ""
+			if s.Definition != nil {
+				childType = s.Definition.Type.Name()
+			}
+			paid, err = p.collect(s.SelectionSet, childType, fieldPath, paid)
+		case *ast.InlineFragment:
+			fragmentType := typeName
+			if s.TypeCondition != "" && typeName != "" {
+				fragmentType = s.TypeCondition
+			}
+			paid, err = p.collect(s.SelectionSet, fragmentType, path, paid)
+		case *ast.FragmentSpread:
+			definition := s.Definition
+			if definition == nil {
+				definition = p.doc.Fragments.ForName(s.Name)
+			}
+			if definition == nil || p.active[s.Name] {
+				continue
+			}
+			fragmentType := typeName
+			if typeName != "" {
+				fragmentType = definition.TypeCondition
+			}
+			p.active[s.Name] = true
+			paid, err = p.collect(definition.SelectionSet, fragmentType, path, paid)
+			delete(p.active, s.Name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return paid, nil
+}
+
+// combine merges the requirements of several paid fields into the price of
+// the whole operation. A payment option is kept when every field accepts the
+// same scheme, network, asset and payee; its amount is the sum of the fields'
+// amounts and its timeout the longest of theirs.
+func combine(fields []paidField) ([]v2.PaymentRequirements, error) {
+	if len(fields) == 1 {
+		return fields[0].requirements, nil
+	}
+
+	var combined []v2.PaymentRequirements
+options:
+	for _, option := range fields[0].requirements {
+		total, ok := new(big.Int).SetString(option.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount %q for %s", option.Amount, fields[0].coordinate)
+		}
+		for _, field := range fields[1:] {
+			match := findOption(field.requirements, option)
+			if match == nil {
+				continue options
+			}
+			amount, ok := new(big.Int).SetString(match.Amount, 10)
+			if !ok {
+				return nil, fmt.Errorf("invalid amount %q for %s", match.Amount, field.coordinate)
+			}
+			total.Add(total, amount)
+			option.MaxTimeoutSeconds = max(option.MaxTimeoutSeconds, match.MaxTimeoutSeconds)
+		}
+		option.Amount = total.String()
+		combined = append(combined, option)
+	}
+
+	if len(combined) == 0 {
+		return nil, ErrNoCommonRequirement
+	}
+	return combined, nil
+}
+
+// findOption returns the requirement of requirements paying the same scheme,
+// network, asset and payee as option.
+func findOption(requirements []v2.PaymentRequirements, option v2.PaymentRequirements) *v2.PaymentRequirements {
+	for i := range requirements {
+		r := &requirements[i]
+		if r.Scheme == option.Scheme && r.Network == option.Network &&
+			strings.EqualFold(r.Asset, option.Asset) && strings.EqualFold(r.PayTo, option.PayTo) {
+			return r
+		}
+	}
+	return nil
+}
+
+// resolved reports whether a GraphQL response resolved every paid field: it
+// has data and no error located at, above or below a paid field.
+func resolved(response graphQLResponse, fields []paidField) bool {
+	if len(response.Data) == 0 || string(response.Data) == "null" {
+		return false
+	}
+	for _, responseErr := range response.Errors {
+		if len(responseErr.Path) == 0 {
+			return false
+		}
+		// List indices are dropped; a paid field inside a list is unresolved
+		// if any of its items failed.
+		var errPath []string
+		for _, element := range responseErr.Path {
+			if key, ok := element.(string); ok {
+				errPath = append(errPath, key)
+			}
+		}
+		for _, field := range fields {
+			if hasPrefix(errPath, field.path) || hasPrefix(field.path, errPath) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func hasPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func TestCombine(t *testing.T) {
+	evm := requirement("1000")
+	other := requirement("2000")
+	other.Network = "eip155:8453"
+	slow := requirement("250")
+	slow.MaxTimeoutSeconds = 300
+
+	tests := []struct {
+		name        string
+		fields      [][]v2.PaymentRequirements
+		wantAmounts []string
+		wantTimeout int
+		wantErr     error
+	}{
+		{name: "single field", fields: [][]v2.PaymentRequirements{{evm, other}}, wantAmounts: []string{"1000", "2000"}, wantTimeout: 60},
+		{name: "common option summed", fields: [][]v2.PaymentRequirements{{evm, other}, {slow}}, wantAmounts: []string{"1250"}, wantTimeout: 300},
+		{name: "no common option", fields: [][]v2.PaymentRequirements{{evm}, {other}}, wantErr: ErrNoCommonRequirement},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []paidField
+			for _, requirements := range tt.fields {
+				fields = append(fields, paidField{coordinate: "Query.field", requirements: requirements})
+			}
+			combined, err := combine(fields)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if len(combined) != len(tt.wantAmounts) {
+				t.Fatalf("Expected %d options, got %+v", len(tt.wantAmounts), combined)
+			}
+			for i, amount := range tt.wantAmounts {
+				if combined[i].Amount != amount {
+					t.Errorf("Expected amount %s, got %s", amount, combined[i].Amount)
+				}
+			}
+			if len(combined) > 0 && combined[0].MaxTimeoutSeconds != tt.wantTimeout {
+				t.Errorf("Expected timeout %d, got %d", tt.wantTimeout, combined[0].MaxTimeoutSeconds)
+			}
+		})
+	}
+}
+
+func TestResolved(t *testing.T) {
+	fields := []paidField{{path: []string{"weather", "radar"}}}
+
+	tests := []struct {
+		name     string
+		response string
+		want     bool
+	}{
+		{name: "no errors", response: `{"data":{"weather":{"radar":"..."}}}`, want: true},
+		{name: "null data", response: `{"data":null}`},
+		{name: "error on field", response: `{"data":{"weather":{"radar":null}},"errors":[{"path":["weather","radar"]}]}`},
+		{name: "error on parent", response: `{"data":{"weather":null},"errors":[{"path":["weather"]}]}`},
+		{name: "error in list item", response: `{"data":{"weather":{"radar":[]}},"errors":[{"path":["weather","radar",3,"url"]}]}`},
+		{name: "error without path", response: `{"data":{},"errors":[{"message":"boom"}]}`},
+		{name: "error on sibling", response: `{"data":{"weather":{"radar":"..."}},"errors":[{"path":["news"]}]}`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var response graphQLResponse
+			if err := json.Unmarshal([]byte(tt.response), &response); err != nil {
+				t.Fatalf("Invalid response: %v", err)
+			}
+			if got := resolved(response, fields); got != tt.want {
+				t.Errorf("Expected resolved=%v, got %v", tt.want, got)
+			}
+		})
+	}
+}
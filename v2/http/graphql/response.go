@@ -0,0 +1,88 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/mark3labs/x402-go/v2/http/internal/rpcgate"
+)
+
+// graphQLResponse is the part of a GraphQL response the middleware inspects.
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Path []interface{} `json:"path"`
+	} `json:"errors"`
+}
+
+// graphQLError is an entry of a GraphQL errors array.
+type graphQLError struct {
+	Message    string                 `json:"message"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// writeError writes a GraphQL response carrying only err.
+func writeError(w http.ResponseWriter, status int, err graphQLError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct {
+		Errors []graphQLError `json:"errors"`
+		Data   interface{}    `json:"data"`
+	}{Errors: []graphQLError{err}})
+}
+
+// writeFailure writes a gating failure as a GraphQL error. Payment required
+// errors carry the accepted payment options in their extensions and in the
+// PaymentRequiredHeader, and use status 402 so that x402 HTTP clients can
+// pay and retry.
+func writeFailure(w http.ResponseWriter, failure *rpcgate.Failure) {
+	extensions := make(map[string]interface{})
+	status := http.StatusServiceUnavailable
+	switch failure.Kind {
+	case rpcgate.KindPaymentRequired:
+		status = http.StatusPaymentRequired
+		extensions["code"] = CodePaymentRequired
+		if details := failure.Details; details != nil {
+			extensions["x402Version"] = details.X402Version
+			extensions["resource"] = details.Resource
+			extensions["accepts"] = details.Accepts
+			if len(details.Extensions) > 0 {
+				extensions["extensions"] = details.Extensions
+			}
+		}
+		if failure.PaymentRequired != "" {
+			w.Header().Set(PaymentRequiredHeader, failure.PaymentRequired)
+		}
+	case rpcgate.KindInvalidPayment:
+		status = http.StatusBadRequest
+		extensions["code"] = CodeInvalidPayment
+	default:
+		extensions["code"] = CodePaymentUnavailable
+	}
+	writeError(w, status, graphQLError{Message: failure.Message, Extensions: extensions})
+}
+
+// responseBuffer holds the handler's response until settlement.
+type responseBuffer struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if !b.wroteHeader {
+		b.status = status
+		b.wroteHeader = true
+	}
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
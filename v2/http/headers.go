@@ -0,0 +1,185 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/http/internal/helpers"
+)
+
+const (
+	// DefaultPaymentHeader is the request header carrying the payment.
+	DefaultPaymentHeader = "X-PAYMENT"
+
+	// DefaultPaymentResponseHeader is the response header, or trailer,
+	// carrying the settlement.
+	DefaultPaymentResponseHeader = "X-PAYMENT-RESPONSE"
+
+	// AuthorizationScheme is the Authorization scheme carrying a payment, as
+	// in "Authorization: X402 <payment>".
+	AuthorizationScheme = "X402"
+)
+
+// PaymentHeaders names where payments and settlements travel. The zero value
+// is the protocol's X-PAYMENT and X-PAYMENT-RESPONSE headers. Servers and
+// clients must agree on it; use the alternate locations where proxies, CDNs
+// or API gateways strip custom headers.
+type PaymentHeaders struct {
+	// Payment is the request header carrying the payment (default:
+	// DefaultPaymentHeader).
+	Payment string
+
+	// PaymentResponse is the response header and trailer carrying the
+	// settlement (default: DefaultPaymentResponseHeader).
+	PaymentResponse string
+
+	// Authorization carries the payment in the Authorization header with the
+	// X402 scheme. Servers accept it there as well as in the payment header;
+	// clients send it there instead.
+	Authorization bool
+
+	// QueryParameter, if set, names a query parameter carrying the payment.
+	// Servers accept it there when the payment is in neither header; clients
+	// send it there instead of a header unless Authorization is set. URLs are
+	// logged by most intermediaries, so prefer Authorization where it works.
+	QueryParameter string
+}
+
+// PaymentHeader returns the name of the request header carrying the payment.
+func (h PaymentHeaders) PaymentHeader() string {
+	if h.Payment == "" {
+		return DefaultPaymentHeader
+	}
+	return h.Payment
+}
+
+// PaymentResponseHeader returns the name of the response header carrying the
+// settlement.
+func (h PaymentHeaders) PaymentResponseHeader() string {
+	if h.PaymentResponse == "" {
+		return DefaultPaymentResponseHeader
+	}
+	return h.PaymentResponse
+}
+
+// ErrAuthorizationInUse is returned by WritePayment when the request already
+// carries an Authorization header the payment would replace.
+var ErrAuthorizationInUse = errors.New("x402: Authorization header already set")
+
+// queryPaymentKey is the context key of a payment taken from the query
+// parameter by TakeQueryPayment.
+type queryPaymentKey struct{}
+
+// ReadPayment returns the encoded payment of r from the payment header, then
+// the Authorization header and the query parameter when enabled, or "" if r
+// carries none. A payment taken from the query parameter by TakeQueryPayment
+// is read from r's context.
+func (h PaymentHeaders) ReadPayment(r *http.Request) string {
+	if payment := r.Header.Get(h.PaymentHeader()); payment != "" {
+		return payment
+	}
+	if h.Authorization {
+		scheme, payment, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if ok && strings.EqualFold(scheme, AuthorizationScheme) {
+			if payment = strings.TrimSpace(payment); payment != "" {
+				return payment
+			}
+		}
+	}
+	if h.QueryParameter == "" {
+		return ""
+	}
+	if payment, ok := r.Context().Value(queryPaymentKey{}).(string); ok {
+		return payment
+	}
+	if r.URL != nil {
+		return r.URL.Query().Get(h.QueryParameter)
+	}
+	return ""
+}
+
+// TakeQueryPayment returns r without the query parameter carrying the payment,
+// keeping the payment in its context for ReadPayment. Middleware call it before
+// anything else, so the signed payment stays out of resource URLs, quote,
+// claim and cache keys, and the URLs handlers see and log. Requests without
+// the parameter are returned as is.
+func (h PaymentHeaders) TakeQueryPayment(r *http.Request) *http.Request {
+	if h.QueryParameter == "" || r.URL == nil || r.URL.RawQuery == "" {
+		return r
+	}
+	query, payment, found := removeQueryParameter(r.URL.RawQuery, h.QueryParameter)
+	if !found {
+		return r
+	}
+	r = r.Clone(context.WithValue(r.Context(), queryPaymentKey{}, payment))
+	r.URL.RawQuery = query
+	r.URL.ForceQuery = false
+	if r.RequestURI != "" {
+		r.RequestURI = r.URL.RequestURI()
+	}
+	return r
+}
+
+// removeQueryParameter removes every occurrence of name from rawQuery, keeping
+// the order of the other parameters, and returns the first value removed.
+func removeQueryParameter(rawQuery, name string) (query, value string, found bool) {
+	pairs := strings.Split(rawQuery, "&")
+	kept := pairs[:0]
+	for _, pair := range pairs {
+		key, rawValue, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(key); err != nil || unescaped != name {
+			kept = append(kept, pair)
+			continue
+		}
+		if !found {
+			value, _ = url.QueryUnescape(rawValue)
+			found = true
+		}
+	}
+	return strings.Join(kept, "&"), value, found
+}
+
+// WritePayment adds the encoded payment to r: in the Authorization header if
+// enabled, else in the query parameter if set, else in the payment header.
+// It returns ErrAuthorizationInUse rather than replace an Authorization
+// header r already carries, such as API credentials.
+func (h PaymentHeaders) WritePayment(r *http.Request, payment string) error {
+	switch {
+	case h.Authorization:
+		if r.Header.Get("Authorization") != "" {
+			return ErrAuthorizationInUse
+		}
+		r.Header.Set("Authorization", AuthorizationScheme+" "+payment)
+	case h.QueryParameter != "":
+		// Other parameters keep their order, so the server sees the URL it
+		// quoted once it removes the payment
+		u := *r.URL
+		query, _, _ := removeQueryParameter(u.RawQuery, h.QueryParameter)
+		if query != "" {
+			query += "&"
+		}
+		u.RawQuery = query + url.QueryEscape(h.QueryParameter) + "=" + url.QueryEscape(payment)
+		r.URL = &u
+	default:
+		r.Header.Set(h.PaymentHeader(), payment)
+	}
+	return nil
+}
+
+// GetSettlement extracts the settlement from resp like the GetSettlement
+// function, reading the configured response header.
+func (h PaymentHeaders) GetSettlement(resp *http.Response) *v2.SettleResponse {
+	name := h.PaymentResponseHeader()
+	settlementHeader := resp.Header.Get(name)
+	if settlementHeader == "" {
+		settlementHeader = resp.Trailer.Get(name)
+	}
+	if settlementHeader == "" {
+		return nil
+	}
+	return helpers.ParseSettlement(settlementHeader)
+}
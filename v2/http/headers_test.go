@@ -0,0 +1,177 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func TestPaymentHeaders_ReadPayment(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers PaymentHeaders
+		url     string
+		header  http.Header
+		want    string
+	}{
+		{name: "default header", url: "/", header: http.Header{"X-Payment": {"p1"}}, want: "p1"},
+		{name: "renamed header", headers: PaymentHeaders{Payment: "X-Pay"}, url: "/", header: http.Header{"X-Pay": {"p1"}, "X-Payment": {"p2"}}, want: "p1"},
+		{name: "authorization disabled", url: "/", header: http.Header{"Authorization": {"X402 p1"}}},
+		{name: "authorization", headers: PaymentHeaders{Authorization: true}, url: "/", header: http.Header{"Authorization": {"x402 p1"}}, want: "p1"},
+		{name: "other authorization scheme", headers: PaymentHeaders{Authorization: true}, url: "/", header: http.Header{"Authorization": {"Bearer token"}}},
+		{name: "header before authorization", headers: PaymentHeaders{Authorization: true}, url: "/", header: http.Header{"X-Payment": {"p1"}, "Authorization": {"X402 p2"}}, want: "p1"},
+		{name: "query parameter", headers: PaymentHeaders{QueryParameter: "x402"}, url: "/?x402=p1", want: "p1"},
+		{name: "query parameter disabled", url: "/?x402=p1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			for name, values := range tt.header {
+				r.Header[name] = values
+			}
+			if got := tt.headers.ReadPayment(r); got != tt.want {
+				t.Errorf("ReadPayment() = %q; want %q", got, tt.want)
+			}
+
+			// What a client writes, a server with the same headers reads
+			out := httptest.NewRequest(http.MethodGet, "/path?a=b", nil)
+			if err := tt.headers.WritePayment(out, "written"); err != nil {
+				t.Fatalf("WritePayment failed: %v", err)
+			}
+			if got := tt.headers.ReadPayment(out); got != "written" {
+				t.Errorf("ReadPayment() after WritePayment = %q", got)
+			}
+		})
+	}
+}
+
+func TestPaymentHeaders_WritePayment_Authorization(t *testing.T) {
+	headers := PaymentHeaders{Authorization: true}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer api-key")
+	if err := headers.WritePayment(r, "payment"); !errors.Is(err, ErrAuthorizationInUse) {
+		t.Fatalf("expected ErrAuthorizationInUse, got %v", err)
+	}
+	if got := r.Header.Get("Authorization"); got != "Bearer api-key" {
+		t.Errorf("expected the credentials to be kept, got %q", got)
+	}
+}
+
+func TestPaymentHeaders_TakeQueryPayment(t *testing.T) {
+	headers := PaymentHeaders{QueryParameter: "x402"}
+	tests := []struct {
+		name    string
+		url     string
+		wantURI string
+		want    string
+	}{
+		{name: "keeps parameter order", url: "/p?b=2&x402=pay%2Bload&a=1", wantURI: "/p?b=2&a=1", want: "pay+load"},
+		{name: "only parameter", url: "/p?x402=pay", wantURI: "/p", want: "pay"},
+		{name: "no payment", url: "/p?b=2&a=1", wantURI: "/p?b=2&a=1"},
+		{name: "similar name", url: "/p?x4020=pay", wantURI: "/p?x4020=pay"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			taken := headers.TakeQueryPayment(r)
+			if taken.RequestURI != tt.wantURI || taken.URL.RequestURI() != tt.wantURI {
+				t.Errorf("expected %s, got RequestURI %s and URL %s", tt.wantURI, taken.RequestURI, taken.URL.RequestURI())
+			}
+			if got := headers.ReadPayment(taken); got != tt.want {
+				t.Errorf("ReadPayment() = %q, want %q", got, tt.want)
+			}
+			if r.RequestURI != tt.url {
+				t.Errorf("expected the original request to be unchanged, got %s", r.RequestURI)
+			}
+		})
+	}
+
+	// A client writing the payment keeps the other parameters in order
+	r := httptest.NewRequest(http.MethodGet, "/p?b=2&a=1", nil)
+	_ = headers.WritePayment(r, "pay")
+	if r.URL.RawQuery != "b=2&a=1&x402=pay" {
+		t.Errorf("unexpected query %s", r.URL.RawQuery)
+	}
+}
+
+func TestPaymentHeaders_RoundTrip(t *testing.T) {
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+		case "/verify":
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+
+	tests := []struct {
+		name    string
+		headers PaymentHeaders
+	}{
+		{"renamed", PaymentHeaders{Payment: "X-Api-Payment", PaymentResponse: "X-Api-Settlement"}},
+		{"authorization", PaymentHeaders{Authorization: true}},
+		{"query parameter", PaymentHeaders{QueryParameter: "x402"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(NewX402Middleware(Config{
+				FacilitatorURL:      facilitatorServer.URL,
+				PaymentRequirements: []v2.PaymentRequirements{requirement},
+				Headers:             tt.headers,
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Intermediaries stripping custom headers
+				if tt.headers.Authorization || tt.headers.QueryParameter != "" {
+					if r.Header.Get(DefaultPaymentHeader) != "" {
+						t.Error("payment sent in the default header")
+					}
+				}
+				// The payment stays out of the URL handlers see
+				if r.URL.RawQuery != "b=2&a=1" || r.RequestURI != "/?b=2&a=1" {
+					t.Errorf("unexpected URL %s", r.RequestURI)
+				}
+				_, _ = w.Write([]byte("paid"))
+			})))
+			defer server.Close()
+
+			client, err := NewClient(
+				WithSigner(&mockSigner{network: "eip155:84532", scheme: "exact"}),
+				WithPaymentHeaders(tt.headers),
+			)
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+			resp, err := client.Get(server.URL + "/?b=2&a=1")
+			if err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusOK || string(body) != "paid" {
+				t.Fatalf("expected the paid response, got %d %q", resp.StatusCode, body)
+			}
+			settlement := tt.headers.GetSettlement(resp)
+			if settlement == nil || settlement.Transaction != "0xabc" {
+				t.Errorf("expected the settlement in %s, got %v", tt.headers.PaymentResponseHeader(), settlement)
+			}
+		})
+	}
+}
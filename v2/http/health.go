@@ -0,0 +1,251 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// DefaultHealthTimeout bounds how long Readyz waits for all checks.
+const DefaultHealthTimeout = 5 * time.Second
+
+// HealthCheck reports whether a payment dependency is usable.
+type HealthCheck func(ctx context.Context) error
+
+// Health serves Kubernetes-style liveness and readiness probes. Readiness runs
+// every registered check, so pods stop receiving traffic while the payment path
+// is broken; liveness does not, since restarting cannot fix a dependency outage.
+type Health struct {
+	checks  []namedHealthCheck
+	timeout time.Duration
+}
+
+type namedHealthCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// HealthOption configures a Health.
+type HealthOption func(*Health)
+
+// WithHealthCheck adds a named readiness check.
+func WithHealthCheck(name string, check HealthCheck) HealthOption {
+	return func(h *Health) {
+		h.checks = append(h.checks, namedHealthCheck{name: name, check: check})
+	}
+}
+
+// WithHealthTimeout sets how long Readyz waits for all checks (default: 5s).
+func WithHealthTimeout(d time.Duration) HealthOption {
+	return func(h *Health) {
+		h.timeout = d
+	}
+}
+
+// NewHealth creates a Health with the given checks.
+func NewHealth(opts ...HealthOption) *Health {
+	h := &Health{timeout: DefaultHealthTimeout}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// CheckResult is the outcome of one readiness check.
+type CheckResult struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+	Latency string `json:"latency"`
+}
+
+// HealthReport is the body served by Readyz.
+type HealthReport struct {
+	Ready  bool                   `json:"ready"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Check runs all checks concurrently and reports their results.
+func (h *Health) Check(ctx context.Context) HealthReport {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	report := HealthReport{Ready: true, Checks: make(map[string]CheckResult, len(h.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func(c namedHealthCheck) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := c.check(ctx)
+			result := CheckResult{OK: err == nil, Latency: time.Since(start).String()}
+			if err != nil {
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			report.Checks[c.name] = result
+			report.Ready = report.Ready && result.OK
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+	return report
+}
+
+// Healthz returns the liveness handler, which always answers 200.
+func (h *Health) Healthz() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, map[string]string{"status": "ok"})
+	})
+}
+
+// Readyz returns the readiness handler. It answers 200 when every check passes
+// and 503 otherwise, with a HealthReport body.
+func (h *Health) Readyz() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := h.Check(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		if !report.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// FacilitatorCheck checks that the facilitator's /supported endpoint is reachable.
+func FacilitatorCheck(client *FacilitatorClient) HealthCheck {
+	return func(ctx context.Context) error {
+		_, err := client.Supported(ctx)
+		return err
+	}
+}
+
+// SupportedKindsCheck checks that the facilitator supports the scheme and network
+// of every requirement. The supported kinds are refetched after maxAge/2; the
+// check fails if they could not be refreshed within maxAge.
+func SupportedKindsCheck(client *FacilitatorClient, requirements []v2.PaymentRequirements, maxAge time.Duration) HealthCheck {
+	var (
+		mu        sync.Mutex
+		fetchedAt time.Time
+		supported *v2.SupportedResponse
+	)
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Refresh at half of maxAge, so one failed refresh does not fail the check
+		if time.Since(fetchedAt) >= maxAge/2 {
+			resp, err := client.Supported(ctx)
+			if err == nil {
+				supported, fetchedAt = resp, time.Now()
+			} else if supported == nil || time.Since(fetchedAt) >= maxAge {
+				return fmt.Errorf("supported kinds are stale: %w", err)
+			}
+		}
+
+		for _, req := range requirements {
+			if _, err := supported.NegotiateVersion(req.Network, req.Scheme); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// KeyChecker is implemented by signers whose key may become unavailable, such
+// as remote or hardware-backed signers.
+type KeyChecker interface {
+	CheckKey(ctx context.Context) error
+}
+
+// SignerCheck checks that every signer's key is available. Signers that do not
+// implement KeyChecker hold their key in memory and are always available.
+func SignerCheck(signers ...v2.Signer) HealthCheck {
+	return func(ctx context.Context) error {
+		for _, signer := range signers {
+			if checker, ok := signer.(KeyChecker); ok {
+				if err := checker.CheckKey(ctx); err != nil {
+					return fmt.Errorf("signer for %s: %w", signer.Network(), err)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// RPCCheck checks JSON-RPC connectivity to a node of network. For EVM networks
+// it also checks that eth_chainId matches the network; for Solana networks it
+// calls getHealth.
+func RPCCheck(network, url string) HealthCheck {
+	return func(ctx context.Context) error {
+		networkType, err := v2.ValidateNetwork(network)
+		if err != nil {
+			return err
+		}
+
+		switch networkType {
+		case v2.NetworkTypeEVM:
+			var result string
+			if err := callRPC(ctx, url, "eth_chainId", &result); err != nil {
+				return err
+			}
+			want, _ := v2.GetChainID(network)
+			got, ok := new(big.Int).SetString(strings.TrimPrefix(result, "0x"), 16)
+			if !ok || !got.IsInt64() || got.Int64() != want {
+				return fmt.Errorf("RPC chain ID %s does not match %s", result, network)
+			}
+		case v2.NetworkTypeSVM:
+			var result string
+			if err := callRPC(ctx, url, "getHealth", &result); err != nil {
+				return err
+			}
+			if result != "ok" {
+				return fmt.Errorf("RPC node unhealthy: %s", result)
+			}
+		}
+		return nil
+	}
+}
+
+// callRPC calls a parameterless JSON-RPC method and decodes its result.
+func callRPC(ctx context.Context, url, method string, result interface{}) error {
+	body, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": []interface{}{}})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("RPC %s failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("RPC %s failed: status %d", method, resp.StatusCode)
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("RPC %s returned invalid response: %w", method, err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("RPC %s failed: %s", method, rpcResp.Error.Message)
+	}
+	return json.Unmarshal(rpcResp.Result, result)
+}
@@ -0,0 +1,154 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+type keySigner struct {
+	v2.Signer
+	err error
+}
+
+func (s keySigner) Network() string                    { return "eip155:8453" }
+func (s keySigner) CheckKey(ctx context.Context) error { return s.err }
+
+func TestHealth_Readyz(t *testing.T) {
+	healthy := true
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v2.SupportedResponse{
+			Kinds: []v2.SupportedKind{{X402Version: 2, Scheme: "exact", Network: "eip155:84532"}},
+		})
+	}))
+	defer facilitatorServer.Close()
+
+	client := &FacilitatorClient{BaseURL: facilitatorServer.URL, Client: facilitatorServer.Client()}
+	requirements := []v2.PaymentRequirements{{Scheme: "exact", Network: "eip155:84532"}}
+
+	tests := []struct {
+		name      string
+		check     HealthCheck
+		unhealthy bool
+		wantReady bool
+	}{
+		{name: "facilitator reachable", check: FacilitatorCheck(client), wantReady: true},
+		{name: "facilitator down", check: FacilitatorCheck(client), unhealthy: true},
+		{name: "kinds supported", check: SupportedKindsCheck(client, requirements, time.Minute), wantReady: true},
+		{
+			name:  "kind unsupported",
+			check: SupportedKindsCheck(client, []v2.PaymentRequirements{{Scheme: "exact", Network: "eip155:8453"}}, time.Minute),
+		},
+		{name: "kinds never fetched", check: SupportedKindsCheck(client, requirements, time.Minute), unhealthy: true},
+		{name: "signer key available", check: SignerCheck(keySigner{}), wantReady: true},
+		{name: "signer key unavailable", check: SignerCheck(keySigner{err: errors.New("kms unreachable")})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			healthy = !tt.unhealthy
+			health := NewHealth(WithHealthCheck("check", tt.check))
+
+			rec := httptest.NewRecorder()
+			health.Readyz().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+			var report HealthReport
+			if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if report.Ready != tt.wantReady {
+				t.Errorf("ready = %v, want %v (%+v)", report.Ready, tt.wantReady, report.Checks)
+			}
+			wantStatus := http.StatusOK
+			if !tt.wantReady {
+				wantStatus = http.StatusServiceUnavailable
+			}
+			if rec.Code != wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, wantStatus)
+			}
+
+			// Liveness does not depend on checks
+			rec = httptest.NewRecorder()
+			health.Healthz().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("healthz status = %d, want 200", rec.Code)
+			}
+		})
+	}
+}
+
+func TestSupportedKindsCheck_Stale(t *testing.T) {
+	healthy := true
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(v2.SupportedResponse{
+			Kinds: []v2.SupportedKind{{X402Version: 2, Scheme: "exact", Network: "eip155:84532"}},
+		})
+	}))
+	defer facilitatorServer.Close()
+
+	client := &FacilitatorClient{BaseURL: facilitatorServer.URL, Client: facilitatorServer.Client()}
+	check := SupportedKindsCheck(client, []v2.PaymentRequirements{{Scheme: "exact", Network: "eip155:84532"}}, 100*time.Millisecond)
+	if err := check(context.Background()); err != nil {
+		t.Fatalf("expected check to pass, got %v", err)
+	}
+
+	healthy = false
+	time.Sleep(60 * time.Millisecond)
+	if err := check(context.Background()); err != nil {
+		t.Errorf("expected cached kinds to pass after one failed refresh, got %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if err := check(context.Background()); err == nil {
+		t.Error("expected stale kinds to fail")
+	}
+}
+
+func TestRPCCheck(t *testing.T) {
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		result := map[string]string{"eth_chainId": "0x" + big.NewInt(84532).Text(16), "getHealth": "ok"}[req.Method]
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result})
+	}))
+	defer rpcServer.Close()
+
+	tests := []struct {
+		name    string
+		network string
+		url     string
+		wantErr bool
+	}{
+		{name: "evm chain matches", network: v2.NetworkBaseSepolia, url: rpcServer.URL},
+		{name: "evm chain mismatch", network: v2.NetworkBase, url: rpcServer.URL, wantErr: true},
+		{name: "solana healthy", network: v2.NetworkSolanaDevnet, url: rpcServer.URL},
+		{name: "unreachable", network: v2.NetworkBaseSepolia, url: "http://127.0.0.1:1", wantErr: true},
+		{name: "invalid network", network: "base", url: rpcServer.URL, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RPCCheck(tt.network, tt.url)(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RPCCheck() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+var (
+	// ErrHoldVoided is returned when capturing a payment hold that was voided.
+	ErrHoldVoided = errors.New("x402: payment hold was voided")
+
+	// ErrHoldCaptured is returned when voiding a payment hold that was captured.
+	ErrHoldCaptured = errors.New("x402: payment hold was captured")
+
+	// ErrCaptureVerifyOnly is reported by Config.Validate when ManualCapture is
+	// combined with VerifyOnly, as captured holds would settle payments the
+	// configuration says must only be verified. The middleware logs it and
+	// ignores ManualCapture.
+	ErrCaptureVerifyOnly = errors.New("x402: ManualCapture cannot be combined with VerifyOnly")
+)
+
+// HoldState is the lifecycle state of a PaymentHold.
+type HoldState int
+
+const (
+	// HoldReserved means the payment is verified and awaiting capture or void.
+	HoldReserved HoldState = iota
+
+	// HoldCaptured means the payment was settled.
+	HoldCaptured
+
+	// HoldVoided means the payment was released without settlement.
+	HoldVoided
+)
+
+// String returns the state name.
+func (s HoldState) String() string {
+	switch s {
+	case HoldReserved:
+		return "reserved"
+	case HoldCaptured:
+		return "captured"
+	case HoldVoided:
+		return "voided"
+	default:
+		return fmt.Sprintf("HoldState(%d)", int(s))
+	}
+}
+
+// HoldContextKey is the context key for storing a PaymentHold.
+const HoldContextKey = contextKey("x402_v2_payment_hold")
+
+// SettleFunc settles a payment, e.g. through a facilitator with fallback.
+type SettleFunc func(ctx context.Context, payment v2.PaymentPayload, requirement v2.PaymentRequirements) (*v2.SettleResponse, error)
+
+// PaymentHold is a verified payment reserved for explicit capture. With
+// Config.ManualCapture the middleware verifies the payment (reserve), runs the
+// handler (deliver), and leaves it to the handler to Capture (settle) or Void it.
+// A hold that is neither captured nor voided when the handler returns is voided.
+//
+// Capture adds the X-PAYMENT-RESPONSE header with the settlement details. A
+// hold still reserved when the handler writes the response status, e.g. one
+// captured after streaming the body, sends them as the X-PAYMENT-RESPONSE
+// trailer instead; see GetSettlement for reading it.
+//
+// PaymentHold is safe for concurrent use.
+type PaymentHold struct {
+	// Payment is the payment payload sent by the client.
+	Payment v2.PaymentPayload
+
+	// Requirement is the payment requirement the payment satisfies.
+	Requirement v2.PaymentRequirements
+
+	// Payer is the payer address reported by the facilitator.
+	Payer string
+
+	// ResponseHeader names the header, or trailer, Capture sends the
+	// settlement in (default: DefaultPaymentResponseHeader).
+	ResponseHeader string
+
+	mu     sync.Mutex
+	state  HoldState
+	result *v2.SettleResponse
+	settle SettleFunc
+	w      http.ResponseWriter
+
+	// headerWritten, if set, reports whether the response header of w has
+	// been written, after which the settlement is sent as a trailer.
+	headerWritten func() bool
+}
+
+// NewPaymentHold creates a reserved hold for a verified payment. settle is called
+// on Capture; w, if not nil, receives the X-PAYMENT-RESPONSE header.
+func NewPaymentHold(payment v2.PaymentPayload, requirement v2.PaymentRequirements, verifyResp *v2.VerifyResponse, settle SettleFunc, w http.ResponseWriter) *PaymentHold {
+	hold := &PaymentHold{
+		Payment:     payment,
+		Requirement: requirement,
+		settle:      settle,
+		w:           w,
+	}
+	if verifyResp != nil {
+		hold.Payer = verifyResp.Payer
+	}
+	return hold
+}
+
+// State returns the hold's current state.
+func (h *PaymentHold) State() HoldState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.state
+}
+
+// Capture settles the payment. Capturing an already captured hold returns the
+// original settlement; capturing a voided hold returns ErrHoldVoided. If
+// settlement fails the hold stays reserved so capture can be retried.
+func (h *PaymentHold) Capture(ctx context.Context) (*v2.SettleResponse, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch h.state {
+	case HoldCaptured:
+		return h.result, nil
+	case HoldVoided:
+		return nil, ErrHoldVoided
+	}
+
+	resp, err := h.settle(ctx, h.Payment, h.Requirement)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return resp, fmt.Errorf("settlement unsuccessful: %s", resp.ErrorReason)
+	}
+
+	h.state = HoldCaptured
+	h.result = resp
+	if h.w != nil {
+		name := h.ResponseHeader
+		if name == "" {
+			name = DefaultPaymentResponseHeader
+		}
+		if err := setPaymentResponse(h.w, name, resp, h.headerWritten != nil && h.headerWritten()); err != nil {
+			return resp, fmt.Errorf("payment captured but response header not set: %w", err)
+		}
+	}
+	return resp, nil
+}
+
+// Void releases the hold without settling. The signed authorization is simply
+// never submitted. Voiding a captured hold returns ErrHoldCaptured.
+func (h *PaymentHold) Void() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.state == HoldCaptured {
+		return ErrHoldCaptured
+	}
+	h.state = HoldVoided
+	return nil
+}
+
+// GetPaymentHoldFromContext extracts the payment hold from the request context.
+// Returns nil unless the middleware runs with Config.ManualCapture.
+func GetPaymentHoldFromContext(ctx context.Context) *PaymentHold {
+	hold, _ := ctx.Value(HoldContextKey).(*PaymentHold)
+	return hold
+}
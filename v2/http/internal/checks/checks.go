@@ -0,0 +1,155 @@
+// Package checks implements the local payment checks run before a payment is
+// sent to the facilitator, for the HTTP and Gin middleware and the RPC
+// adapters.
+package checks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/extensions"
+	"github.com/mark3labs/x402-go/v2/quote"
+	"github.com/mark3labs/x402-go/v2/validation"
+)
+
+// Policy holds the configuration the checks read. Its fields mirror those of
+// the middleware Config.
+type Policy struct {
+	MinAmounts               v2.MinimumAmounts
+	CheckAuthorizationWindow bool
+	RejectExpired            bool
+	StrictMatching           bool
+	Extensions               *extensions.Registry
+	Quotes                   *quote.Issuer
+	Clock                    v2.Clock
+	ClockSkew                time.Duration
+}
+
+// clockSkew returns the configured clock skew, or v2.DefaultClockSkew if unset.
+func (p Policy) clockSkew() time.Duration {
+	if p.ClockSkew > 0 {
+		return p.ClockSkew
+	}
+	return v2.DefaultClockSkew
+}
+
+// CheckMinimum rejects requirements below MinAmounts.
+func (p Policy) CheckMinimum(requirement *v2.PaymentRequirements) error {
+	return p.MinAmounts.Check(*requirement)
+}
+
+// CheckWindow validates the payment's authorization window against requirement
+// when CheckAuthorizationWindow is enabled.
+func (p Policy) CheckWindow(payment *v2.PaymentPayload, requirement *v2.PaymentRequirements) error {
+	if !p.CheckAuthorizationWindow {
+		return nil
+	}
+	return validation.ValidateAuthorizationWindow(*payment, requirement.MaxTimeoutSeconds, v2.ClockOrSystem(p.Clock).Now(), p.clockSkew())
+}
+
+// CheckExpired rejects payments whose authorization expired when
+// RejectExpired is enabled.
+func (p Policy) CheckExpired(payment *v2.PaymentPayload) error {
+	if !p.RejectExpired {
+		return nil
+	}
+	return validation.ValidateNotExpired(*payment, v2.ClockOrSystem(p.Clock).Now(), p.clockSkew())
+}
+
+// CheckAccepted rejects payments that do not match requirement in every field
+// when StrictMatching is enabled. The error is a *validation.MismatchError.
+func (p Policy) CheckAccepted(payment *v2.PaymentPayload, requirement *v2.PaymentRequirements) error {
+	if !p.StrictMatching {
+		return nil
+	}
+	return validation.ValidateAccepted(*payment, *requirement)
+}
+
+// CheckExtensions validates the extensions of payment with Extensions, if set.
+func (p Policy) CheckExtensions(payment *v2.PaymentPayload) error {
+	if p.Extensions == nil {
+		return nil
+	}
+	return p.Extensions.Validate(payment.Extensions)
+}
+
+// QuotedRequirements returns the requirements quoted to the client when
+// payment echoes a valid quote from Quotes for resourceURL, and live
+// otherwise. Only quoted requirements matching live apart from Amount are
+// honored, so a quote for a range or revalidation cannot pay for the whole
+// resource. It fails with v2.ErrInvalidQuote or v2.ErrQuoteExpired for
+// unusable quotes.
+func (p Policy) QuotedRequirements(payment *v2.PaymentPayload, resourceURL string, live []v2.PaymentRequirements) ([]v2.PaymentRequirements, error) {
+	if p.Quotes == nil {
+		return live, nil
+	}
+	q, err := p.Quotes.Verify(*payment, resourceURL)
+	if errors.Is(err, quote.ErrNoQuote) {
+		return live, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var honored []v2.PaymentRequirements
+	for _, quoted := range q.Accepts {
+		for _, requirement := range live {
+			if samePricingInputs(quoted, requirement) {
+				honored = append(honored, quoted)
+				break
+			}
+		}
+	}
+	if len(honored) == 0 {
+		return nil, fmt.Errorf("%w: quote %s does not match the requirements of this request", v2.ErrInvalidQuote, q.ID)
+	}
+	return honored, nil
+}
+
+// samePricingInputs reports whether quoted and live differ at most in Amount.
+func samePricingInputs(quoted, live v2.PaymentRequirements) bool {
+	quoted.Amount = live.Amount
+	return SameRequirement(quoted, live)
+}
+
+// SameRequirement reports whether a and b are equal. They are compared by
+// their JSON encoding, as either may have been decoded from it.
+func SameRequirement(a, b v2.PaymentRequirements) bool {
+	want, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	got, err := json.Marshal(a)
+	return err == nil && bytes.Equal(got, want)
+}
+
+// Reason returns the 402 error for a payment refused locally with err,
+// prefixed with v2.ErrCodeAuthorizationExpired for expired authorizations so
+// clients can tell them apart.
+func Reason(err error) string {
+	if errors.Is(err, v2.ErrAuthorizationExpired) {
+		return string(v2.ErrCodeAuthorizationExpired) + ": " + err.Error()
+	}
+	return err.Error()
+}
+
+// SplitRequirements returns requirements with splits applied, or
+// requirements unchanged if there are none.
+func SplitRequirements(requirements []v2.PaymentRequirements, splits []v2.Split) ([]v2.PaymentRequirements, error) {
+	if len(splits) == 0 {
+		return requirements, nil
+	}
+
+	split := make([]v2.PaymentRequirements, len(requirements))
+	for i, req := range requirements {
+		var err error
+		if split[i], err = v2.WithSplits(req, splits); err != nil {
+			return nil, err
+		}
+	}
+	return split, nil
+}
@@ -0,0 +1,110 @@
+package checks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/quote"
+)
+
+func TestPolicy_QuotedRequirements(t *testing.T) {
+	issuer, err := quote.NewIssuer([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewIssuer failed: %v", err)
+	}
+	policy := Policy{Quotes: issuer}
+
+	requirement := func(amount string, extra map[string]interface{}) v2.PaymentRequirements {
+		return v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: amount, PayTo: "0xPayTo", Extra: extra}
+	}
+	quoted := func(requirements ...v2.PaymentRequirements) *v2.PaymentPayload {
+		required := v2.PaymentRequired{
+			Resource: &v2.ResourceInfo{URL: "https://example.com/file"},
+			Accepts:  requirements,
+		}
+		if err := issuer.Quote(&required); err != nil {
+			t.Fatalf("Quote failed: %v", err)
+		}
+		// The quote reaches the server through JSON, as in a payment header
+		raw, _ := json.Marshal(required)
+		_ = json.Unmarshal(raw, &required)
+		payment := &v2.PaymentPayload{Accepted: required.Accepts[0]}
+		quote.Echo(required, payment)
+		return payment
+	}
+
+	tests := []struct {
+		name       string
+		payment    *v2.PaymentPayload
+		live       []v2.PaymentRequirements
+		wantAmount string
+		wantErr    error
+	}{
+		{
+			name:       "no quote",
+			payment:    &v2.PaymentPayload{},
+			live:       []v2.PaymentRequirements{requirement("200", nil)},
+			wantAmount: "200",
+		},
+		{
+			name:       "quoted price",
+			payment:    quoted(requirement("100", map[string]interface{}{"decimals": 6})),
+			live:       []v2.PaymentRequirements{requirement("200", map[string]interface{}{"decimals": 6})},
+			wantAmount: "100",
+		},
+		{
+			name:    "quoted range",
+			payment: quoted(requirement("1", map[string]interface{}{"range": "bytes=0-0"})),
+			live:    []v2.PaymentRequirements{requirement("200", nil)},
+			wantErr: v2.ErrInvalidQuote,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policy.QuotedRequirements(tt.payment, "https://example.com/file", tt.live)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("QuotedRequirements failed: %v", err)
+			}
+			if len(got) != 1 || got[0].Amount != tt.wantAmount {
+				t.Errorf("expected amount %s, got %+v", tt.wantAmount, got)
+			}
+		})
+	}
+}
+
+func TestPolicy_Disabled(t *testing.T) {
+	// Checks that are not enabled accept anything
+	var policy Policy
+	payment := &v2.PaymentPayload{Accepted: v2.PaymentRequirements{Amount: "1"}}
+	requirement := &v2.PaymentRequirements{Amount: "2"}
+	for name, err := range map[string]error{
+		"minimum":    policy.CheckMinimum(requirement),
+		"window":     policy.CheckWindow(payment, requirement),
+		"expired":    policy.CheckExpired(payment),
+		"accepted":   policy.CheckAccepted(payment, requirement),
+		"extensions": policy.CheckExtensions(payment),
+	} {
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", name, err)
+		}
+	}
+}
+
+func TestReason(t *testing.T) {
+	expired := fmt.Errorf("%w at noon", v2.ErrAuthorizationExpired)
+	if got, want := Reason(expired), string(v2.ErrCodeAuthorizationExpired)+": "+expired.Error(); got != want {
+		t.Errorf("Reason(expired) = %q, want %q", got, want)
+	}
+	if got := Reason(errors.New("bad window")); got != "bad window" {
+		t.Errorf("Reason() = %q, want %q", got, "bad window")
+	}
+}
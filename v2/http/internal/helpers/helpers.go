@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/attestation"
 	"github.com/mark3labs/x402-go/v2/encoding"
 )
 
@@ -20,7 +21,12 @@ var ErrNilPayment = errors.New("payment is nil")
 // ParsePaymentHeader extracts and decodes a PaymentPayload from the X-PAYMENT header.
 // Returns ErrMalformedHeader if the header is missing or invalid.
 func ParsePaymentHeader(r *http.Request) (*v2.PaymentPayload, error) {
-	paymentHeader := r.Header.Get("X-PAYMENT")
+	return DecodePaymentHeader(r.Header.Get("X-PAYMENT"))
+}
+
+// DecodePaymentHeader decodes a PaymentPayload from an encoded payment, wherever
+// it was carried. Returns ErrMalformedHeader if it is empty.
+func DecodePaymentHeader(paymentHeader string) (*v2.PaymentPayload, error) {
 	if paymentHeader == "" {
 		return nil, v2.ErrMalformedHeader
 	}
@@ -39,34 +45,88 @@ func ParsePaymentHeader(r *http.Request) (*v2.PaymentPayload, error) {
 }
 
 // SendPaymentRequired writes a 402 Payment Required response with the given requirements.
+// If attester is non-nil, the requirements are signed with it; when signing fails the
+// response is sent unattested and the signing error is returned.
 // Returns an error if JSON encoding fails.
-func SendPaymentRequired(w http.ResponseWriter, resource v2.ResourceInfo, requirements []v2.PaymentRequirements, errMsg string) error {
-	response := v2.PaymentRequired{
+func SendPaymentRequired(w http.ResponseWriter, resource v2.ResourceInfo, requirements []v2.PaymentRequirements, errMsg string, attester *attestation.Signer) error {
+	return WritePaymentRequired(w, v2.PaymentRequired{
 		X402Version: v2.X402Version,
 		Error:       errMsg,
 		Resource:    &resource,
 		Accepts:     requirements,
-	}
+	}, attester)
+}
+
+// WritePaymentRequired writes response as a 402 Payment Required response,
+// attesting it like SendPaymentRequired.
+func WritePaymentRequired(w http.ResponseWriter, response v2.PaymentRequired, attester *attestation.Signer) error {
+	attestErr := Attest(&response, attester)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusPaymentRequired)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		return fmt.Errorf("encoding PaymentRequired response: %w", err)
 	}
+	return attestErr
+}
+
+// Attest signs response with attester, if non-nil. When signing fails the
+// response is left unattested and the error is returned.
+func Attest(response *v2.PaymentRequired, attester *attestation.Signer) error {
+	if attester == nil {
+		return nil
+	}
+	if err := attester.Attest(response); err != nil {
+		delete(response.Extensions, v2.RequirementsAttestationExtension)
+		return fmt.Errorf("attesting PaymentRequired response: %w", err)
+	}
 	return nil
 }
 
 // AddPaymentResponseHeader adds the X-PAYMENT-RESPONSE header with settlement information.
 // Returns an error if settlement is nil or encoding fails.
 func AddPaymentResponseHeader(w http.ResponseWriter, settlement *v2.SettleResponse) error {
+	if err := SetPaymentResponseHeader(w, "X-PAYMENT-RESPONSE", settlement); err != nil {
+		return fmt.Errorf("AddPaymentResponseHeader: %w", err)
+	}
+	return nil
+}
+
+// SetPaymentResponseHeader adds the header name with settlement information.
+// Returns an error if settlement is nil or encoding fails.
+func SetPaymentResponseHeader(w http.ResponseWriter, name string, settlement *v2.SettleResponse) error {
+	if settlement == nil {
+		return ErrNilSettlement
+	}
+	encoded, err := encoding.EncodeSettlement(*settlement)
+	if err != nil {
+		return fmt.Errorf("encode settlement: %w", err)
+	}
+	w.Header().Set(name, encoded)
+	return nil
+}
+
+// AddPaymentResponseTrailer adds the X-PAYMENT-RESPONSE trailer with
+// settlement information, for responses whose header was already written.
+// Returns an error if settlement is nil or encoding fails.
+func AddPaymentResponseTrailer(w http.ResponseWriter, settlement *v2.SettleResponse) error {
+	if err := SetPaymentResponseTrailer(w, "X-PAYMENT-RESPONSE", settlement); err != nil {
+		return fmt.Errorf("AddPaymentResponseTrailer: %w", err)
+	}
+	return nil
+}
+
+// SetPaymentResponseTrailer adds the trailer name with settlement information.
+// Returns an error if settlement is nil or encoding fails.
+func SetPaymentResponseTrailer(w http.ResponseWriter, name string, settlement *v2.SettleResponse) error {
 	if settlement == nil {
-		return fmt.Errorf("AddPaymentResponseHeader: %w", ErrNilSettlement)
+		return ErrNilSettlement
 	}
 	encoded, err := encoding.EncodeSettlement(*settlement)
 	if err != nil {
-		return fmt.Errorf("AddPaymentResponseHeader: encode settlement: %w", err)
+		return fmt.Errorf("encode settlement: %w", err)
 	}
-	w.Header().Set("X-PAYMENT-RESPONSE", encoded)
+	w.Header().Set(http.TrailerPrefix+name, encoded)
 	return nil
 }
 
@@ -108,7 +108,7 @@ func TestSendPaymentRequired(t *testing.T) {
 		},
 	}
 
-	err := SendPaymentRequired(w, resource, requirements, "Payment required for access")
+	err := SendPaymentRequired(w, resource, requirements, "Payment required for access", nil)
 	if err != nil {
 		t.Fatalf("SendPaymentRequired returned error: %v", err)
 	}
@@ -485,3 +485,41 @@ func TestParsePaymentHeader_WrongVersion_ErrorCode(t *testing.T) {
 		t.Errorf("Expected error to wrap ErrUnsupportedVersion, got %v", err)
 	}
 }
+
+func BenchmarkParsePaymentHeader(b *testing.B) {
+	header, err := encoding.EncodePayment(v2.PaymentPayload{
+		X402Version: 2,
+		Accepted: v2.PaymentRequirements{
+			Scheme:            "exact",
+			Network:           "eip155:8453",
+			Amount:            "10000",
+			Asset:             "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+			PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+			MaxTimeoutSeconds: 60,
+		},
+		Payload: map[string]interface{}{
+			"signature": "0x" + strings.Repeat("ab", 65),
+			"authorization": map[string]interface{}{
+				"from":        "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+				"to":          "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+				"value":       "10000",
+				"validAfter":  "1700000000",
+				"validBefore": "1700000060",
+				"nonce":       "0x" + strings.Repeat("cd", 32),
+			},
+		},
+	})
+	if err != nil {
+		b.Fatalf("EncodePayment failed: %v", err)
+	}
+	req := httptest.NewRequest("GET", "/api/data", nil)
+	req.Header.Set("X-PAYMENT", header)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParsePaymentHeader(req); err != nil {
+			b.Fatalf("ParsePaymentHeader failed: %v", err)
+		}
+	}
+}
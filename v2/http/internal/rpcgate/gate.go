@@ -0,0 +1,434 @@
+// Package rpcgate implements x402 v2 payment gating for RPC frameworks. It is
+// shared by the Connect, Twirp and GraphQL adapters, which map its failures
+// onto their own error formats and carry payment data in headers.
+package rpcgate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+	"github.com/mark3labs/x402-go/v2/http/internal/checks"
+	"github.com/mark3labs/x402-go/v2/http/internal/helpers"
+)
+
+// Header names used by the RPC adapters. Config.Headers renames the payment
+// and settlement headers, and carries payments in the Authorization header or
+// a query parameter, as for the HTTP middleware.
+const (
+	// PaymentHeader carries the base64-encoded payment on requests by default.
+	PaymentHeader = "X-PAYMENT"
+
+	// PaymentRequiredHeader carries the base64-encoded v2.PaymentRequired in
+	// the metadata of payment required errors.
+	PaymentRequiredHeader = "X-PAYMENT-REQUIRED"
+
+	// PaymentResponseHeader carries the base64-encoded settlement on responses
+	// by default.
+	PaymentResponseHeader = "X-PAYMENT-RESPONSE"
+)
+
+// Kind classifies a gating failure.
+type Kind int
+
+const (
+	// KindPaymentRequired means no payment was sent or it was rejected. The
+	// failure carries the payment requirements.
+	KindPaymentRequired Kind = iota
+
+	// KindInvalidPayment means the payment header could not be decoded.
+	KindInvalidPayment
+
+	// KindUnavailable means the facilitator could not verify or settle the
+	// payment.
+	KindUnavailable
+)
+
+// Failure is a gating failure, to be returned as an RPC error.
+type Failure struct {
+	Kind    Kind
+	Message string
+
+	// PaymentRequired is the PaymentRequiredHeader value for KindPaymentRequired.
+	PaymentRequired string
+
+	// Details is the decoded form of PaymentRequired.
+	Details *v2.PaymentRequired
+
+	// Err is the underlying error, if any.
+	Err error
+}
+
+func (f *Failure) Error() string {
+	if f.Err != nil {
+		return fmt.Sprintf("%s: %v", f.Message, f.Err)
+	}
+	return f.Message
+}
+
+func (f *Failure) Unwrap() error {
+	return f.Err
+}
+
+// Gate verifies and settles payments for RPC procedures.
+type Gate struct {
+	config              v2http.Config
+	policy              checks.Policy
+	facilitator         *v2http.FacilitatorClient
+	fallbackFacilitator *v2http.FacilitatorClient
+
+	// procedures maps procedure names to enriched requirements. If nil, every
+	// procedure requires all.
+	procedures map[string][]v2.PaymentRequirements
+	all        []v2.PaymentRequirements
+}
+
+// New creates a Gate for config. If procedures is non-empty, only the listed
+// procedures require payment, each with its own requirements; otherwise every
+// procedure requires config.PaymentRequirements. Requirements are enriched
+// from the facilitator like the HTTP middleware's.
+func New(config v2http.Config, procedures map[string][]v2.PaymentRequirements) *Gate {
+	if err := config.Validate(); err != nil {
+		slog.Default().Error("invalid x402 gate configuration", "error", err)
+	}
+	policy := checks.Policy{
+		MinAmounts:               config.MinAmounts,
+		CheckAuthorizationWindow: config.CheckAuthorizationWindow,
+		RejectExpired:            config.RejectExpired,
+		StrictMatching:           config.StrictMatching,
+		Extensions:               config.Extensions,
+		Quotes:                   config.Quotes,
+		Clock:                    config.Clock,
+		ClockSkew:                config.ClockSkew,
+	}
+	for procedure, requirements := range procedures {
+		for i := range requirements {
+			if err := policy.CheckMinimum(&requirements[i]); err != nil {
+				slog.Default().Error("invalid payment requirement", "procedure", procedure, "error", err)
+			}
+		}
+	}
+	facilitator, fallbackFacilitator := config.FacilitatorClients()
+	g := &Gate{
+		config:              config,
+		policy:              policy,
+		facilitator:         facilitator,
+		fallbackFacilitator: fallbackFacilitator,
+	}
+
+	timeouts, _ := config.FacilitatorTimeouts()
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.RequestTimeout)
+	defer cancel()
+
+	if len(procedures) == 0 {
+		base, err := checks.SplitRequirements(config.PaymentRequirements, config.RevenueSplits)
+		if err != nil {
+			slog.Default().Error("invalid revenue splits, serving requirements without splits", "error", err)
+			base = config.PaymentRequirements
+		}
+		g.all = g.enrich(ctx, base)
+		return g
+	}
+
+	g.procedures = make(map[string][]v2.PaymentRequirements, len(procedures))
+	for procedure, requirements := range procedures {
+		g.procedures[procedure] = g.enrich(ctx, requirements)
+	}
+	return g
+}
+
+// enrich adds facilitator data (like feePayer) to requirements, keeping them
+// unchanged if the facilitator cannot be reached.
+func (g *Gate) enrich(ctx context.Context, requirements []v2.PaymentRequirements) []v2.PaymentRequirements {
+	enriched, err := g.facilitator.EnrichRequirements(ctx, requirements)
+	if err != nil {
+		slog.Default().Warn("failed to enrich payment requirements from facilitator", "error", err)
+		return requirements
+	}
+	return enriched
+}
+
+// Requirements returns the requirements of procedure, or nil if it is free.
+func (g *Gate) Requirements(procedure string) []v2.PaymentRequirements {
+	if g.procedures == nil {
+		return g.config.Rotator.Apply(g.all)
+	}
+	return g.config.Rotator.Apply(g.procedures[procedure])
+}
+
+// TakeQueryPayment removes a payment sent in the query parameter from r's
+// URL, as the HTTP middleware does. Adapters call it before anything else.
+func (g *Gate) TakeQueryPayment(r *http.Request) *http.Request {
+	return g.config.Headers.TakeQueryPayment(r)
+}
+
+// ReadPayment returns the payment of the call r, wherever Config.Headers
+// carries it.
+func (g *Gate) ReadPayment(r *http.Request) string {
+	return g.config.Headers.ReadPayment(r)
+}
+
+// ReadPaymentHeader returns the payment of a call from its headers, for
+// adapters that do not see the request URL.
+func (g *Gate) ReadPaymentHeader(header http.Header) string {
+	return g.config.Headers.ReadPayment(&http.Request{Header: header, URL: &url.URL{}})
+}
+
+// PaymentResponseHeader returns the name of the header, or trailer, carrying
+// settlements.
+func (g *Gate) PaymentResponseHeader() string {
+	return g.config.Headers.PaymentResponseHeader()
+}
+
+// Payment is a verified payment for a procedure call.
+type Payment struct {
+	gate         *Gate
+	payload      v2.PaymentPayload
+	requirement  v2.PaymentRequirements
+	requirements []v2.PaymentRequirements
+	resource     v2.ResourceInfo
+	verify       *v2.VerifyResponse
+	claim        *v2http.PaymentClaim
+	received     time.Time
+}
+
+// Resource returns the config's resource, defaulting to one named after
+// procedure.
+func (g *Gate) Resource(procedure string) v2.ResourceInfo {
+	resource := g.config.Resource
+	if resource.URL == "" {
+		resource.URL = procedure
+	}
+	if resource.Description == "" {
+		resource.Description = "Payment required for " + procedure
+	}
+	return resource
+}
+
+// Verify checks the payment sent for a call of procedure, given the value of
+// its PaymentHeader. It returns a nil Payment and Failure for free procedures.
+func (g *Gate) Verify(ctx context.Context, procedure, header string) (*Payment, *Failure) {
+	requirements := g.Requirements(procedure)
+	if len(requirements) == 0 {
+		return nil, nil
+	}
+	return g.Check(ctx, g.Resource(procedure), requirements, header)
+}
+
+// Check verifies the payment in header against requirements, for callers that
+// compute the price of a call themselves.
+func (g *Gate) Check(ctx context.Context, resource v2.ResourceInfo, requirements []v2.PaymentRequirements, header string) (*Payment, *Failure) {
+	logger := slog.Default()
+
+	if header == "" {
+		logger.Info("no payment header provided", "resource", resource.URL)
+		return nil, g.paymentRequired(resource, requirements, "Payment required")
+	}
+
+	payload, err := encoding.DecodePayment(header)
+	if err != nil {
+		logger.Warn("invalid payment header", "error", err)
+		return nil, &Failure{Kind: KindInvalidPayment, Message: "Invalid payment header", Err: err}
+	}
+	if payload.X402Version != v2.X402Version {
+		return nil, &Failure{Kind: KindInvalidPayment, Message: "Invalid payment header", Err: v2.ErrUnsupportedVersion}
+	}
+
+	if err := g.policy.CheckExtensions(&payload); err != nil {
+		logger.Warn("invalid payment extensions", "error", err)
+		return nil, g.paymentRequired(resource, requirements, err.Error())
+	}
+
+	quoted, err := g.policy.QuotedRequirements(&payload, resource.URL, g.config.Rotator.Retired(&payload, requirements))
+	if err != nil {
+		logger.Warn("invalid price quote", "error", err)
+		return nil, g.paymentRequired(resource, requirements, err.Error())
+	}
+	requirements = quoted
+
+	requirement, err := g.config.Allowlist.FindMatchingRequirement(&payload, requirements)
+	if err != nil {
+		logger.Warn("no matching requirement", "error", err)
+		return nil, g.paymentRequired(resource, requirements, "No matching payment requirement")
+	}
+	if err := g.policy.CheckMinimum(requirement); err != nil {
+		logger.Error("payment requirement below minimum amount", "error", err)
+		return nil, g.paymentRequired(resource, requirements, err.Error())
+	}
+	if err := g.policy.CheckAccepted(&payload, requirement); err != nil {
+		logger.Warn("payment does not match requirement", "error", err)
+		return nil, g.paymentRequired(resource, requirements, err.Error())
+	}
+	if err := g.policy.CheckExpired(&payload); err != nil {
+		logger.Warn("authorization expired", "error", err)
+		return nil, g.paymentRequired(resource, requirements, checks.Reason(err))
+	}
+	if err := g.policy.CheckWindow(&payload, requirement); err != nil {
+		logger.Warn("invalid authorization window", "error", err)
+		return nil, g.paymentRequired(resource, requirements, checks.Reason(err))
+	}
+
+	// Claim the payment before verifying it: retries of a call that settled
+	// it reuse the settlement, as the facilitator may reject a used payment
+	claim, err := g.config.ClaimPayment(ctx, &payload, requirement, resource.URL)
+	if errors.Is(err, v2.ErrPaymentReplayed) {
+		logger.Warn("payment replayed")
+		return nil, g.paymentRequired(resource, requirements, err.Error())
+	}
+	if err != nil {
+		logger.Error("failed to claim payment", "error", err)
+		return nil, &Failure{Kind: KindUnavailable, Message: "Payment coordination unavailable", Err: err}
+	}
+
+	received := v2.ClockOrSystem(g.config.Clock).Now()
+	verifyResp := claim.Verification()
+	if verifyResp != nil {
+		logger.Info("reusing verification of retried call", "payer", verifyResp.Payer)
+	} else {
+		var failure *Failure
+		if verifyResp, failure = g.verify(ctx, &payload, requirement, requirements, resource, received); failure != nil {
+			claim.Release()
+			return nil, failure
+		}
+	}
+
+	return &Payment{
+		gate:         g,
+		payload:      payload,
+		requirement:  *requirement,
+		requirements: requirements,
+		resource:     resource,
+		verify:       verifyResp,
+		claim:        claim,
+		received:     received,
+	}, nil
+}
+
+// verify verifies payload with the facilitator, no later than it can be
+// settled.
+func (g *Gate) verify(ctx context.Context, payload *v2.PaymentPayload, requirement *v2.PaymentRequirements, requirements []v2.PaymentRequirements, resource v2.ResourceInfo, received time.Time) (*v2.VerifyResponse, *Failure) {
+	logger := slog.Default()
+	verifyCtx, cancelVerify, err := g.config.PaymentContext(ctx, payload, requirement, received)
+	if err != nil {
+		logger.Warn("payment expired before verification", "error", err)
+		return nil, g.paymentRequired(resource, requirements, checks.Reason(err))
+	}
+	logger.Info("verifying payment", "scheme", payload.Accepted.Scheme, "network", payload.Accepted.Network)
+	verifyResp, err := g.facilitator.Verify(verifyCtx, *payload, *requirement)
+	if err != nil && g.fallbackFacilitator != nil && verifyCtx.Err() == nil {
+		logger.Warn("primary facilitator failed, trying fallback", "error", err)
+		verifyResp, err = g.fallbackFacilitator.Verify(verifyCtx, *payload, *requirement)
+	}
+	err = v2http.DeadlineError(verifyCtx, err)
+	cancelVerify()
+	if errors.Is(err, v2.ErrAuthorizationExpired) {
+		logger.Warn("payment expired during verification", "error", err)
+		return nil, g.paymentRequired(resource, requirements, checks.Reason(err))
+	}
+	if err != nil {
+		logger.Error("facilitator verification failed", "error", err)
+		return nil, &Failure{Kind: KindUnavailable, Message: "Payment verification failed", Err: err}
+	}
+	if !verifyResp.IsValid {
+		logger.Warn("payment verification failed", "reason", verifyResp.InvalidReason)
+		return nil, g.paymentRequired(resource, requirements, g.config.VerificationFailure(payload, requirement, verifyResp.InvalidReason))
+	}
+
+	logger.Info("payment verified", "payer", verifyResp.Payer)
+	return verifyResp, nil
+}
+
+// paymentRequired builds a KindPaymentRequired failure.
+func (g *Gate) paymentRequired(resource v2.ResourceInfo, requirements []v2.PaymentRequirements, message string) *Failure {
+	response, err := g.config.PaymentRequiredResponse(nil, resource, requirements, message)
+	if err != nil {
+		slog.Default().Error("failed to build payment requirements", "error", err)
+	}
+	if err := helpers.Attest(&response, g.config.Attester); err != nil {
+		slog.Default().Error("failed to attest payment requirements", "error", err)
+	}
+
+	encoded, err := encoding.EncodeRequirements(response)
+	if err != nil {
+		slog.Default().Error("failed to encode payment requirements", "error", err)
+	}
+	return &Failure{Kind: KindPaymentRequired, Message: message, PaymentRequired: encoded, Details: &response}
+}
+
+// Context returns ctx carrying the verification result, for
+// v2http.GetPaymentFromContext.
+func (p *Payment) Context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, v2http.PaymentContextKey, p.verify)
+}
+
+// Release gives up the payment's claim (see v2http.Config.ClaimPayment) when
+// the call failed and the payment will not be settled, so the client can retry
+// with it.
+func (p *Payment) Release() {
+	p.claim.Release()
+}
+
+// Settle settles the payment after a successful call, or reuses the settlement
+// of the call it retries. It returns the PaymentResponseHeader value, or "" in
+// verify-only mode.
+func (p *Payment) Settle(ctx context.Context) (string, *Failure) {
+	if p.gate.config.VerifyOnly {
+		p.claim.Consumed()
+		return "", nil
+	}
+	logger := slog.Default()
+
+	if settlement := p.claim.Settlement(); settlement != nil {
+		logger.Info("reusing settlement of retried call", "transaction", settlement.Transaction)
+		encoded, err := encoding.EncodeSettlement(*settlement)
+		if err != nil {
+			logger.Warn("failed to encode payment response", "error", err)
+		}
+		return encoded, nil
+	}
+
+	ctx, cancel, err := p.gate.config.PaymentContext(ctx, &p.payload, &p.requirement, p.received)
+	if err != nil {
+		logger.Warn("payment expired before settlement", "error", err)
+		p.Release()
+		return "", p.gate.paymentRequired(p.resource, p.requirements, checks.Reason(err))
+	}
+	defer cancel()
+
+	logger.Info("settling payment", "payer", p.verify.Payer)
+	settlement, err := p.gate.facilitator.Settle(ctx, p.payload, p.requirement)
+	if err != nil && p.gate.fallbackFacilitator != nil && ctx.Err() == nil {
+		logger.Warn("primary facilitator settlement failed, trying fallback", "error", err)
+		settlement, err = p.gate.fallbackFacilitator.Settle(ctx, p.payload, p.requirement)
+	}
+	if err = v2http.DeadlineError(ctx, err); errors.Is(err, v2.ErrAuthorizationExpired) {
+		logger.Warn("payment expired during settlement", "error", err)
+		p.Release()
+		return "", p.gate.paymentRequired(p.resource, p.requirements, checks.Reason(err))
+	}
+	if err != nil {
+		logger.Error("settlement failed", "error", err)
+		p.Release()
+		return "", &Failure{Kind: KindUnavailable, Message: "Payment settlement failed", Err: err}
+	}
+	if !settlement.Success {
+		logger.Warn("settlement unsuccessful", "reason", settlement.ErrorReason)
+		p.Release()
+		return "", p.gate.paymentRequired(p.resource, p.requirements, settlement.ErrorReason)
+	}
+
+	logger.Info("payment settled", "transaction", settlement.Transaction)
+	p.claim.Settled(settlement)
+	encoded, err := encoding.EncodeSettlement(*settlement)
+	if err != nil {
+		logger.Warn("failed to encode payment response", "error", err)
+	}
+	return encoded, nil
+}
@@ -0,0 +1,100 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// ErrNoCommonRequirement is returned when the paid calls of a batch share no
+// payment option.
+var ErrNoCommonRequirement = errors.New("paid methods share no payment option")
+
+// call is the part of a JSON-RPC call the proxy inspects.
+type call struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+}
+
+// parseCalls decodes a JSON-RPC request, a single call or a batch, into its
+// calls.
+func parseCalls(body []byte) ([]call, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var calls []call
+		if err := json.Unmarshal(body, &calls); err != nil {
+			return nil, err
+		}
+		return calls, nil
+	}
+	var c call
+	if err := json.Unmarshal(body, &c); err != nil {
+		return nil, err
+	}
+	return []call{c}, nil
+}
+
+// requestID returns the id to answer a request with: the call's id for a
+// single call, and null for a batch.
+func requestID(calls []call) json.RawMessage {
+	if len(calls) == 1 {
+		return calls[0].ID
+	}
+	return nil
+}
+
+// combine merges the requirements of the paid calls of a request into its
+// price. A payment option is kept when every call accepts the same scheme,
+// network, asset and payee; its amount is the sum of the calls' amounts and
+// its timeout the longest of theirs.
+func combine(prices [][]v2.PaymentRequirements) ([]v2.PaymentRequirements, error) {
+	if len(prices) == 1 {
+		return prices[0], nil
+	}
+
+	var combined []v2.PaymentRequirements
+options:
+	for _, option := range prices[0] {
+		total, ok := new(big.Int).SetString(option.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", v2.ErrInvalidAmount, option.Amount)
+		}
+		for _, price := range prices[1:] {
+			match := findOption(price, option)
+			if match == nil {
+				continue options
+			}
+			amount, ok := new(big.Int).SetString(match.Amount, 10)
+			if !ok {
+				return nil, fmt.Errorf("%w: %q", v2.ErrInvalidAmount, match.Amount)
+			}
+			total.Add(total, amount)
+			option.MaxTimeoutSeconds = max(option.MaxTimeoutSeconds, match.MaxTimeoutSeconds)
+		}
+		option.Amount = total.String()
+		combined = append(combined, option)
+	}
+
+	if len(combined) == 0 {
+		return nil, ErrNoCommonRequirement
+	}
+	return combined, nil
+}
+
+// findOption returns the requirement of requirements paying the same scheme,
+// network, asset and payee as option.
+func findOption(requirements []v2.PaymentRequirements, option v2.PaymentRequirements) *v2.PaymentRequirements {
+	for i := range requirements {
+		r := &requirements[i]
+		if r.Scheme == option.Scheme && r.Network == option.Network &&
+			strings.EqualFold(r.Asset, option.Asset) && strings.EqualFold(r.PayTo, option.PayTo) {
+			return r
+		}
+	}
+	return nil
+}
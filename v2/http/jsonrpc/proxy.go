@@ -0,0 +1,333 @@
+// Package jsonrpc provides an x402 v2 payment-gated reverse proxy for JSON-RPC
+// 2.0 services, such as Ethereum and Solana RPC nodes, priced per method.
+//
+// Each call is priced by its method with WithMethod; unlisted methods cost
+// Config.PaymentRequirements, or are refused if it is empty. A batch costs the
+// sum of its calls, paid with a single payment. Requests that are not free
+// must carry a payment in the X-PAYMENT header; otherwise the proxy answers
+// with status 402 and a JSON-RPC error whose data holds the accepted payment
+// options:
+//
+//	{"jsonrpc":"2.0","id":1,"error":{"code":-32001,"message":"Payment required","data":{"x402Version":2,"accepts":[...]}}}
+//
+// Requests are forwarded to the upstream node once the payment is verified,
+// and the payment is settled when the upstream answers with a 2xx status,
+// whatever the outcome of the individual calls, since the node did the work.
+// The settlement is returned in the X-PAYMENT-RESPONSE header.
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+	"github.com/mark3labs/x402-go/v2/http/internal/checks"
+	"github.com/mark3labs/x402-go/v2/http/internal/helpers"
+	"github.com/mark3labs/x402-go/v2/http/internal/rpcgate"
+)
+
+// Header names used by the proxy.
+const (
+	PaymentHeader         = rpcgate.PaymentHeader
+	PaymentRequiredHeader = rpcgate.PaymentRequiredHeader
+	PaymentResponseHeader = rpcgate.PaymentResponseHeader
+)
+
+// JSON-RPC error codes returned by the proxy. The payment codes are in the
+// range reserved for implementation-defined server errors.
+const (
+	CodeParseError         = -32700
+	CodeInvalidRequest     = -32600
+	CodeMethodNotFound     = -32601
+	CodeInternalError      = -32603
+	CodePaymentRequired    = -32001
+	CodeInvalidPayment     = -32002
+	CodePaymentUnavailable = -32003
+)
+
+const (
+	// DefaultMaxBodySize is the default limit on request bodies.
+	DefaultMaxBodySize = 4 << 20
+
+	// DefaultMaxBatchSize is the default limit on the number of calls in a
+	// batch.
+	DefaultMaxBatchSize = 100
+)
+
+// defaultPrice is the gate procedure holding the price of unlisted methods.
+// Method names cannot be empty, so it does not collide with one.
+const defaultPrice = ""
+
+// Option configures a Proxy.
+type Option func(*options)
+
+type options struct {
+	prices       map[string][]v2.PaymentRequirements
+	free         map[string]bool
+	client       *http.Client
+	maxBodySize  int64
+	maxBatchSize int
+}
+
+// WithMethod sets the price of a call of method, such as "eth_getLogs". If no
+// requirements are given, the method is free.
+func WithMethod(method string, requirements ...v2.PaymentRequirements) Option {
+	return func(o *options) {
+		if len(requirements) == 0 {
+			o.free[method] = true
+			delete(o.prices, method)
+			return
+		}
+		o.prices[method] = requirements
+		delete(o.free, method)
+	}
+}
+
+// WithHTTPClient sets the client used to reach the upstream node (default:
+// http.DefaultClient).
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithMaxBodySize limits the request bodies the proxy reads (default:
+// DefaultMaxBodySize).
+func WithMaxBodySize(size int64) Option {
+	return func(o *options) {
+		o.maxBodySize = size
+	}
+}
+
+// WithMaxBatchSize limits the number of calls in a batch (default:
+// DefaultMaxBatchSize).
+func WithMaxBatchSize(n int) Option {
+	return func(o *options) {
+		o.maxBatchSize = n
+	}
+}
+
+// Proxy is a payment-gated JSON-RPC reverse proxy.
+type Proxy struct {
+	config   v2http.Config
+	upstream string
+	options  options
+	gate     *rpcgate.Gate
+}
+
+// NewProxy creates a Proxy forwarding paid requests to the JSON-RPC endpoint
+// at upstream, using the facilitators, default requirements and settings of
+// config.
+//
+// Example:
+//
+//	proxy, err := jsonrpc.NewProxy(x402http.Config{
+//	    FacilitatorURL: "https://facilitator.x402.org",
+//	}, "https://mainnet.base.org",
+//	    jsonrpc.WithMethod("eth_chainId"),
+//	    jsonrpc.WithMethod("eth_call", cheap),
+//	    jsonrpc.WithMethod("eth_getLogs", expensive))
+//	http.Handle("/rpc", proxy)
+func NewProxy(config v2http.Config, upstream string, opts ...Option) (*Proxy, error) {
+	if u, err := url.Parse(upstream); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("jsonrpc: invalid upstream URL %q", upstream)
+	}
+
+	o := options{
+		prices:       make(map[string][]v2.PaymentRequirements),
+		free:         make(map[string]bool),
+		client:       http.DefaultClient,
+		maxBodySize:  DefaultMaxBodySize,
+		maxBatchSize: DefaultMaxBatchSize,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	priced := make(map[string][]v2.PaymentRequirements, len(o.prices)+1)
+	for method, requirements := range o.prices {
+		priced[method] = requirements
+	}
+	if len(config.PaymentRequirements) > 0 {
+		base, err := checks.SplitRequirements(config.PaymentRequirements, config.RevenueSplits)
+		if err != nil {
+			return nil, fmt.Errorf("jsonrpc: %w", err)
+		}
+		priced[defaultPrice] = base
+	}
+
+	return &Proxy{
+		config:   config,
+		upstream: upstream,
+		options:  o,
+		gate:     rpcgate.New(config, priced),
+	}, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := slog.Default()
+	r = p.gate.TakeQueryPayment(r)
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, nil, rpcError{Code: CodeInvalidRequest, Message: "JSON-RPC requests must use POST"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, p.options.maxBodySize))
+	if err != nil {
+		status := http.StatusBadRequest
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, nil, rpcError{Code: CodeInvalidRequest, Message: "Unable to read request"})
+		return
+	}
+
+	calls, err := parseCalls(body)
+	if err != nil {
+		logger.Warn("invalid JSON-RPC request", "error", err)
+		writeError(w, http.StatusBadRequest, nil, rpcError{Code: CodeParseError, Message: "Parse error"})
+		return
+	}
+	id := requestID(calls)
+	if len(calls) == 0 || len(calls) > p.options.maxBatchSize {
+		writeError(w, http.StatusBadRequest, id, rpcError{Code: CodeInvalidRequest, Message: fmt.Sprintf("Batches must hold 1 to %d calls", p.options.maxBatchSize)})
+		return
+	}
+
+	for _, c := range calls {
+		if c.Method == "" {
+			writeError(w, http.StatusBadRequest, id, rpcError{Code: CodeInvalidRequest, Message: "Calls must name a method"})
+			return
+		}
+	}
+
+	prices, methods, err := p.price(calls)
+	if err != nil {
+		logger.Warn("unavailable JSON-RPC method", "error", err)
+		writeError(w, http.StatusOK, id, rpcError{Code: CodeMethodNotFound, Message: err.Error()})
+		return
+	}
+	if len(prices) == 0 {
+		p.forward(w, r, body, nil)
+		return
+	}
+
+	requirements, err := combine(prices)
+	if err != nil {
+		logger.Error("failed to price JSON-RPC request", "methods", methods, "error", err)
+		writeError(w, http.StatusInternalServerError, id, rpcError{Code: CodePaymentUnavailable, Message: "Payment configuration error"})
+		return
+	}
+
+	resource := p.config.Resource
+	if resource.URL == "" {
+		resource.URL = helpers.BuildResourceURL(r)
+	}
+	if resource.Description == "" {
+		resource.Description = "Payment required for " + strings.Join(methods, ", ")
+	}
+
+	payment, failure := p.gate.Check(r.Context(), resource, requirements, p.gate.ReadPayment(r))
+	if failure != nil {
+		writeFailure(w, id, failure)
+		return
+	}
+	p.forward(w, r.WithContext(payment.Context(r.Context())), body, payment)
+}
+
+// price returns the requirements of the paid calls and the distinct methods
+// called, or an error naming a method that is neither priced nor free.
+func (p *Proxy) price(calls []call) ([][]v2.PaymentRequirements, []string, error) {
+	var prices [][]v2.PaymentRequirements
+	var methods []string
+	for _, c := range calls {
+		if !slices.Contains(methods, c.Method) {
+			methods = append(methods, c.Method)
+		}
+		if p.options.free[c.Method] {
+			continue
+		}
+		requirements := p.gate.Requirements(c.Method)
+		if len(requirements) == 0 {
+			requirements = p.gate.Requirements(defaultPrice)
+		}
+		if len(requirements) == 0 {
+			return nil, nil, fmt.Errorf("method not available: %q", c.Method)
+		}
+		prices = append(prices, requirements)
+	}
+	return prices, methods, nil
+}
+
+// forward sends body to the upstream node and relays its response, settling
+// payment, if any, when the upstream succeeds.
+func (p *Proxy) forward(w http.ResponseWriter, r *http.Request, body []byte, payment *rpcgate.Payment) {
+	logger := slog.Default()
+
+	status, header, response, err := p.roundTrip(r.Context(), r, body)
+	if err != nil {
+		logger.Error("upstream request failed", "error", err)
+		if payment != nil {
+			payment.Release()
+		}
+		writeError(w, http.StatusBadGateway, nil, rpcError{Code: CodeInternalError, Message: "Upstream unavailable"})
+		return
+	}
+
+	if payment != nil {
+		if status < 300 {
+			settlement, failure := payment.Settle(r.Context())
+			if failure != nil {
+				writeFailure(w, nil, failure)
+				return
+			}
+			if settlement != "" {
+				w.Header().Set(p.gate.PaymentResponseHeader(), settlement)
+			}
+		} else {
+			logger.Info("upstream failed, skipping settlement", "status", status)
+			payment.Release()
+		}
+	}
+
+	if contentType := header.Get("Content-Type"); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(response)
+}
+
+// roundTrip posts body to the upstream node and reads its response.
+func (p *Proxy) roundTrip(ctx context.Context, r *http.Request, body []byte) (int, http.Header, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.upstream, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if accept := r.Header.Get("Accept"); accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	resp, err := p.options.client.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer resp.Body.Close()
+	response, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("reading upstream response: %w", err)
+	}
+	return resp.StatusCode, resp.Header, response, nil
+}
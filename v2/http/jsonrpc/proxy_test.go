@@ -0,0 +1,265 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+)
+
+func requirement(amount string) v2.PaymentRequirements {
+	return v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            amount,
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+}
+
+func TestProxy(t *testing.T) {
+	methods := []Option{
+		WithMethod("eth_chainId"),
+		WithMethod("eth_call", requirement("100")),
+		WithMethod("eth_getLogs", requirement("1000")),
+	}
+
+	tests := []struct {
+		name           string
+		config         v2http.Config
+		body           string
+		payment        string
+		upstreamStatus int
+		wantStatus     int
+		wantCode       int
+		wantAmount     string
+		wantSettled    bool
+		wantForwarded  bool
+	}{
+		{
+			name:          "free method",
+			body:          `{"jsonrpc":"2.0","id":1,"method":"eth_chainId"}`,
+			wantStatus:    http.StatusOK,
+			wantForwarded: true,
+		},
+		{
+			name:       "paid method",
+			body:       `{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{}]}`,
+			wantStatus: http.StatusPaymentRequired,
+			wantCode:   CodePaymentRequired,
+			wantAmount: "1000",
+		},
+		{
+			name:       "batch prices add up",
+			body:       `[{"jsonrpc":"2.0","id":1,"method":"eth_call"},{"jsonrpc":"2.0","id":2,"method":"eth_call"},{"jsonrpc":"2.0","id":3,"method":"eth_getLogs"},{"jsonrpc":"2.0","id":4,"method":"eth_chainId"}]`,
+			wantStatus: http.StatusPaymentRequired,
+			wantCode:   CodePaymentRequired,
+			wantAmount: "1200",
+		},
+		{
+			name:       "unlisted method refused",
+			body:       `{"jsonrpc":"2.0","id":1,"method":"debug_traceTransaction"}`,
+			wantStatus: http.StatusOK,
+			wantCode:   CodeMethodNotFound,
+		},
+		{
+			name:       "unlisted method at default price",
+			config:     v2http.Config{PaymentRequirements: []v2.PaymentRequirements{requirement("5")}},
+			body:       `{"jsonrpc":"2.0","id":1,"method":"debug_traceTransaction"}`,
+			wantStatus: http.StatusPaymentRequired,
+			wantCode:   CodePaymentRequired,
+			wantAmount: "5",
+		},
+		{
+			name:          "paid and settled",
+			body:          `[{"jsonrpc":"2.0","id":1,"method":"eth_call"},{"jsonrpc":"2.0","id":2,"method":"eth_getLogs"}]`,
+			payment:       "1100",
+			wantStatus:    http.StatusOK,
+			wantSettled:   true,
+			wantForwarded: true,
+		},
+		{
+			name:          "paid in renamed headers",
+			config:        v2http.Config{Headers: v2http.PaymentHeaders{Payment: "X-Api-Payment", PaymentResponse: "X-Api-Settlement"}},
+			body:          `{"jsonrpc":"2.0","id":1,"method":"eth_call"}`,
+			payment:       "100",
+			wantStatus:    http.StatusOK,
+			wantSettled:   true,
+			wantForwarded: true,
+		},
+		{
+			name:          "paid in the query parameter",
+			config:        v2http.Config{Headers: v2http.PaymentHeaders{QueryParameter: "x402"}},
+			body:          `{"jsonrpc":"2.0","id":1,"method":"eth_call"}`,
+			payment:       "100",
+			wantStatus:    http.StatusOK,
+			wantSettled:   true,
+			wantForwarded: true,
+		},
+		{
+			name:           "upstream failure not settled",
+			body:           `{"jsonrpc":"2.0","id":1,"method":"eth_call"}`,
+			payment:        "100",
+			upstreamStatus: http.StatusServiceUnavailable,
+			wantStatus:     http.StatusServiceUnavailable,
+			wantForwarded:  true,
+		},
+		{
+			name:       "invalid payment",
+			body:       `{"jsonrpc":"2.0","id":1,"method":"eth_call"}`,
+			payment:    "invalid",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   CodeInvalidPayment,
+		},
+		{
+			name:       "malformed request",
+			body:       `{"jsonrpc":`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   CodeParseError,
+		},
+		{
+			name:       "empty batch",
+			body:       `[]`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   CodeInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settled := false
+			facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/supported":
+					_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+				case "/verify":
+					_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+				case "/settle":
+					settled = true
+					_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xtx", Network: "eip155:84532"})
+				}
+			}))
+			defer facilitatorServer.Close()
+
+			forwarded := false
+			const response = `{"jsonrpc":"2.0","id":1,"result":"0x1"}`
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				forwarded = true
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != tt.body {
+					t.Errorf("Expected the request body to be forwarded, got %s", body)
+				}
+				if r.Header.Get(PaymentHeader) != "" || r.Header.Get("X-Api-Payment") != "" || r.URL.RawQuery != "" {
+					t.Error("Expected the payment not to be forwarded upstream")
+				}
+				w.Header().Set("Content-Type", "application/json")
+				if tt.upstreamStatus != 0 {
+					w.WriteHeader(tt.upstreamStatus)
+				}
+				_, _ = w.Write([]byte(response))
+			}))
+			defer upstream.Close()
+
+			config := tt.config
+			config.FacilitatorURL = facilitatorServer.URL
+			proxy, err := NewProxy(config, upstream.URL, methods...)
+			if err != nil {
+				t.Fatalf("NewProxy failed: %v", err)
+			}
+
+			headers := tt.config.Headers
+			req := httptest.NewRequest("POST", "/rpc", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			switch tt.payment {
+			case "":
+			case "invalid":
+				_ = headers.WritePayment(req, "not-base64!")
+			default:
+				header, _ := encoding.EncodePayment(v2.PaymentPayload{
+					X402Version: 2,
+					Accepted:    requirement(tt.payment),
+					Payload:     map[string]interface{}{"signature": "0xsig"},
+				})
+				_ = headers.WritePayment(req, header)
+			}
+			w := httptest.NewRecorder()
+			proxy.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if forwarded != tt.wantForwarded {
+				t.Errorf("Expected forwarded=%v, got %v", tt.wantForwarded, forwarded)
+			}
+			if settled != tt.wantSettled {
+				t.Errorf("Expected settled=%v, got %v", tt.wantSettled, settled)
+			}
+			if got := w.Header().Get(headers.PaymentResponseHeader()) != ""; got != tt.wantSettled {
+				t.Errorf("Expected payment response header=%v, got %v", tt.wantSettled, got)
+			}
+			if tt.wantForwarded && w.Body.String() != response {
+				t.Errorf("Expected upstream response %s, got %s", response, w.Body.String())
+			}
+			if tt.wantCode == 0 {
+				return
+			}
+
+			var resp struct {
+				Error struct {
+					Code int `json:"code"`
+					Data struct {
+						Accepts []v2.PaymentRequirements `json:"accepts"`
+					} `json:"data"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Expected a JSON-RPC error, got %s", w.Body.String())
+			}
+			if resp.Error.Code != tt.wantCode {
+				t.Errorf("Expected code %d, got %d", tt.wantCode, resp.Error.Code)
+			}
+			if tt.wantAmount != "" {
+				if len(resp.Error.Data.Accepts) != 1 || resp.Error.Data.Accepts[0].Amount != tt.wantAmount {
+					t.Errorf("Expected accepted amount %s, got %+v", tt.wantAmount, resp.Error.Data.Accepts)
+				}
+				if w.Header().Get(PaymentRequiredHeader) == "" {
+					t.Error("Expected payment requirements header")
+				}
+			}
+		})
+	}
+}
+
+func TestCombine(t *testing.T) {
+	other := requirement("10")
+	other.Network = "eip155:8453"
+
+	combined, err := combine([][]v2.PaymentRequirements{{requirement("100"), other}, {requirement("250")}})
+	if err != nil {
+		t.Fatalf("combine failed: %v", err)
+	}
+	if len(combined) != 1 || combined[0].Amount != "350" {
+		t.Errorf("Expected one option of 350, got %+v", combined)
+	}
+
+	if _, err := combine([][]v2.PaymentRequirements{{requirement("100")}, {other}}); !errors.Is(err, ErrNoCommonRequirement) {
+		t.Errorf("Expected ErrNoCommonRequirement, got %v", err)
+	}
+}
+
+func TestNewProxy_InvalidUpstream(t *testing.T) {
+	for _, upstream := range []string{"", "localhost:8545", "ftp://node"} {
+		if _, err := NewProxy(v2http.Config{}, upstream); err == nil {
+			t.Errorf("Expected error for upstream %q", upstream)
+		}
+	}
+}
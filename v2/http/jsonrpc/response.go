@@ -0,0 +1,54 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mark3labs/x402-go/v2/http/internal/rpcgate"
+)
+
+// rpcError is a JSON-RPC error object.
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// writeError writes a JSON-RPC response carrying only err, answering the call
+// with id (null if nil).
+func writeError(w http.ResponseWriter, status int, id json.RawMessage, err rpcError) {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Error   rpcError        `json:"error"`
+	}{JSONRPC: "2.0", ID: id, Error: err})
+}
+
+// writeFailure writes a gating failure as a JSON-RPC error. Payment required
+// errors carry the accepted payment options as their data and in the
+// PaymentRequiredHeader, and use status 402 so that x402 HTTP clients can pay
+// and retry.
+func writeFailure(w http.ResponseWriter, id json.RawMessage, failure *rpcgate.Failure) {
+	status := http.StatusServiceUnavailable
+	err := rpcError{Code: CodePaymentUnavailable, Message: failure.Message}
+	switch failure.Kind {
+	case rpcgate.KindPaymentRequired:
+		status = http.StatusPaymentRequired
+		err.Code = CodePaymentRequired
+		if failure.Details != nil {
+			err.Data = failure.Details
+		}
+		if failure.PaymentRequired != "" {
+			w.Header().Set(PaymentRequiredHeader, failure.PaymentRequired)
+		}
+	case rpcgate.KindInvalidPayment:
+		status = http.StatusBadRequest
+		err.Code = CodeInvalidPayment
+	}
+	writeError(w, status, id, err)
+}
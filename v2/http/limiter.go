@@ -0,0 +1,228 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/cluster"
+)
+
+// ErrPayerBusy is returned when a payer already has the maximum number of
+// requests in flight and no slot became free within the queue deadline.
+var ErrPayerBusy = errors.New("x402: too many concurrent requests for payer")
+
+// PayerStats is a snapshot of one payer's load on a PayerLimiter.
+type PayerStats struct {
+	// Payer is the payer address.
+	Payer string
+
+	// InFlight is the number of requests currently being served.
+	InFlight int
+
+	// Queued is the number of requests waiting for a slot.
+	Queued int
+}
+
+// PayerLimiter bounds the number of concurrent in-flight requests per payer, so
+// one aggressive paying client cannot starve others of backend capacity.
+// Requests beyond the limit wait in a per-payer FIFO queue until a slot frees up
+// or the queue deadline passes.
+//
+// Set it as Config.PayerLimiter; the middleware acquires a slot after the payment
+// is verified and before it is settled, and answers 429 Too Many Requests when
+// none is available, so rejected requests are never charged.
+//
+// PayerLimiter is safe for concurrent use.
+type PayerLimiter struct {
+	maxInFlight int
+	maxWait     time.Duration
+	maxQueue    int
+	clock       v2.Clock
+	coordinator *cluster.Coordinator
+
+	mu     sync.Mutex
+	payers map[string]*payerState
+}
+
+// payerState tracks one payer's slots and waiters.
+type payerState struct {
+	slots    chan struct{}
+	inFlight int
+	queued   int
+}
+
+// PayerLimiterOption configures a PayerLimiter.
+type PayerLimiterOption func(*PayerLimiter)
+
+// WithMaxQueue limits how many requests per payer may wait for a slot. Requests
+// beyond it are rejected immediately. The default is unbounded.
+func WithMaxQueue(n int) PayerLimiterOption {
+	return func(l *PayerLimiter) {
+		l.maxQueue = n
+	}
+}
+
+// WithLimiterClock sets the clock used for queue deadlines (default: v2.SystemClock).
+func WithLimiterClock(clock v2.Clock) PayerLimiterOption {
+	return func(l *PayerLimiter) {
+		l.clock = v2.ClockOrSystem(clock)
+	}
+}
+
+// WithCoordinator also bounds each payer's in-flight requests across replicas
+// sharing coordinator. Requests wait in the local queue only: once a local slot
+// is free, a request that finds all shared slots taken by other replicas is
+// rejected with ErrPayerBusy.
+func WithCoordinator(coordinator *cluster.Coordinator) PayerLimiterOption {
+	return func(l *PayerLimiter) {
+		l.coordinator = coordinator
+	}
+}
+
+// NewPayerLimiter creates a PayerLimiter allowing maxInFlight concurrent
+// requests per payer, with excess requests waiting up to maxWait for a slot.
+// A maxWait of zero rejects excess requests without queueing.
+func NewPayerLimiter(maxInFlight int, maxWait time.Duration, opts ...PayerLimiterOption) *PayerLimiter {
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+	l := &PayerLimiter{
+		maxInFlight: maxInFlight,
+		maxWait:     maxWait,
+		clock:       v2.SystemClock,
+		payers:      make(map[string]*payerState),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Acquire waits for an in-flight slot for payer. On success the returned release
+// function must be called exactly once when the request completes. Returns
+// ErrPayerBusy if no slot frees up within the queue deadline, or the context's
+// error if ctx is done first.
+func (l *PayerLimiter) Acquire(ctx context.Context, payer string) (func(), error) {
+	l.mu.Lock()
+	state, ok := l.payers[payer]
+	if !ok {
+		state = &payerState{slots: make(chan struct{}, l.maxInFlight)}
+		l.payers[payer] = state
+	}
+	if l.maxQueue > 0 && state.queued >= l.maxQueue {
+		l.mu.Unlock()
+		return nil, ErrPayerBusy
+	}
+	state.queued++
+	l.mu.Unlock()
+
+	err := l.wait(ctx, state)
+
+	l.mu.Lock()
+	state.queued--
+	if err == nil {
+		state.inFlight++
+	}
+	l.cleanupLocked(payer, state)
+	l.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	releaseShared := func() {}
+	if l.coordinator != nil {
+		release, ok, err := l.coordinator.AcquireSlot(ctx, "payer:"+payer, l.maxInFlight)
+		if err == nil && !ok {
+			err = ErrPayerBusy
+		}
+		if err != nil {
+			l.release(payer, state)
+			return nil, err
+		}
+		releaseShared = release
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			releaseShared()
+			l.release(payer, state)
+		})
+	}, nil
+}
+
+// release frees a local slot of payer.
+func (l *PayerLimiter) release(payer string, state *payerState) {
+	<-state.slots
+	l.mu.Lock()
+	state.inFlight--
+	l.cleanupLocked(payer, state)
+	l.mu.Unlock()
+}
+
+// wait blocks until a slot is free, the deadline passes, or ctx is done.
+func (l *PayerLimiter) wait(ctx context.Context, state *payerState) error {
+	select {
+	case state.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if l.maxWait <= 0 {
+		return ErrPayerBusy
+	}
+
+	select {
+	case state.slots <- struct{}{}:
+		return nil
+	case <-l.clock.After(l.maxWait):
+		return ErrPayerBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// cleanupLocked forgets idle payers. Callers must hold l.mu.
+func (l *PayerLimiter) cleanupLocked(payer string, state *payerState) {
+	if state.inFlight == 0 && state.queued == 0 {
+		delete(l.payers, payer)
+	}
+}
+
+// QueueDepth returns the number of requests waiting for a slot for payer.
+func (l *PayerLimiter) QueueDepth(payer string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if state, ok := l.payers[payer]; ok {
+		return state.queued
+	}
+	return 0
+}
+
+// Stats returns the load of every active payer, ordered by payer address.
+func (l *PayerLimiter) Stats() []PayerStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := make([]PayerStats, 0, len(l.payers))
+	for payer, state := range l.payers {
+		stats = append(stats, PayerStats{Payer: payer, InFlight: state.inFlight, Queued: state.queued})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Payer < stats[j].Payer
+	})
+	return stats
+}
+
+// RetryAfter returns the Retry-After value, in seconds, for rejected requests.
+func (l *PayerLimiter) RetryAfter() int {
+	seconds := int((l.maxWait + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
@@ -0,0 +1,200 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/cluster"
+	"github.com/mark3labs/x402-go/v2/encoding"
+	"github.com/mark3labs/x402-go/v2/storage"
+)
+
+func TestPayerLimiter(t *testing.T) {
+	limiter := NewPayerLimiter(1, 50*time.Millisecond)
+
+	release, err := limiter.Acquire(context.Background(), "0xA")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	// Another payer is unaffected
+	releaseB, err := limiter.Acquire(context.Background(), "0xB")
+	if err != nil {
+		t.Fatalf("Acquire for other payer failed: %v", err)
+	}
+	releaseB()
+
+	// Excess request for the same payer times out
+	if _, err := limiter.Acquire(context.Background(), "0xA"); !errors.Is(err, ErrPayerBusy) {
+		t.Errorf("expected ErrPayerBusy, got %v", err)
+	}
+
+	// A queued request gets the slot once it is released
+	acquired := make(chan error, 1)
+	go func() {
+		release, err := limiter.Acquire(context.Background(), "0xA")
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for limiter.QueueDepth("0xA") != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	stats := limiter.Stats()
+	if len(stats) != 1 || stats[0] != (PayerStats{Payer: "0xA", InFlight: 1, Queued: 1}) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	release()
+	release() // releasing twice is a no-op
+	if err := <-acquired; err != nil {
+		t.Errorf("queued Acquire failed: %v", err)
+	}
+	if stats := limiter.Stats(); len(stats) != 0 {
+		t.Errorf("expected idle payers to be forgotten, got %+v", stats)
+	}
+}
+
+func TestPayerLimiter_MaxQueue(t *testing.T) {
+	limiter := NewPayerLimiter(1, time.Second, WithMaxQueue(1))
+	release, err := limiter.Acquire(context.Background(), "0xA")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := limiter.Acquire(ctx, "0xA")
+		done <- err
+	}()
+	for limiter.QueueDepth("0xA") != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := limiter.Acquire(context.Background(), "0xA"); !errors.Is(err, ErrPayerBusy) {
+		t.Errorf("expected full queue to reject immediately, got %v", err)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestPayerLimiter_Clock(t *testing.T) {
+	clock := v2.NewFakeClock(time.Now())
+	limiter := NewPayerLimiter(1, time.Minute, WithLimiterClock(clock))
+	release, err := limiter.Acquire(context.Background(), "0xA")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer release()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := limiter.Acquire(context.Background(), "0xA")
+		done <- err
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	if err := <-done; !errors.Is(err, ErrPayerBusy) {
+		t.Errorf("expected ErrPayerBusy after queue deadline, got %v", err)
+	}
+}
+
+func TestMiddleware_PayerLimiter(t *testing.T) {
+	settleCalls := 0
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+		case "/verify":
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			settleCalls++
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "10000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+	}
+	limiter := NewPayerLimiter(1, 0)
+	middleware := NewX402Middleware(Config{
+		FacilitatorURL:      facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{requirement},
+		PayerLimiter:        limiter,
+	})
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	}))
+	paymentHeader, _ := encoding.EncodePayment(v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    requirement,
+		Payload:     map[string]interface{}{"signature": "0xsig"},
+	})
+
+	// Occupy the payer's only slot
+	release, err := limiter.Acquire(context.Background(), "0xPayerAddress")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/data", nil)
+	req.Header.Set("X-PAYMENT", paymentHeader)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 429 with Retry-After, got %d", w.Code)
+	}
+	if settleCalls != 0 {
+		t.Errorf("expected rejected request not to be settled, got %d settle calls", settleCalls)
+	}
+
+	release()
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || settleCalls != 1 {
+		t.Errorf("expected 200 with one settlement, got %d with %d settle calls", w.Code, settleCalls)
+	}
+}
+
+func TestPayerLimiter_Coordinator(t *testing.T) {
+	// Two replicas sharing their slots through one coordinator
+	coordinator := cluster.New(storage.NewMemory())
+	a := NewPayerLimiter(1, 0, WithCoordinator(coordinator))
+	b := NewPayerLimiter(1, 0, WithCoordinator(coordinator))
+
+	release, err := a.Acquire(context.Background(), "0xA")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if _, err := b.Acquire(context.Background(), "0xA"); !errors.Is(err, ErrPayerBusy) {
+		t.Errorf("expected ErrPayerBusy on other replica, got %v", err)
+	}
+
+	release()
+	release, err = b.Acquire(context.Background(), "0xA")
+	if err != nil {
+		t.Fatalf("expected released slot to be free, got %v", err)
+	}
+	release()
+}
@@ -0,0 +1,321 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"golang.org/x/text/language"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/http/internal/helpers"
+)
+
+// PaymentMessages customizes the text of 402 Payment Required responses.
+// Error, DocsURL and Support are text/template strings executed with a
+// MessageData, so they can mention the price, e.g.
+// "This report costs {{.Price}} {{.Currency}}."
+type PaymentMessages struct {
+	// Error replaces the error text of the response. The middleware's reason,
+	// like "Payment required" or a verification failure, is available as
+	// {{.Reason}}; by default the reason is sent as is.
+	Error string
+
+	// DocsURL links to documentation on paying for the resource.
+	DocsURL string
+
+	// Support is a support contact, such as an email address or URL.
+	Support string
+
+	// HTML serves an HTML paywall page instead of JSON to clients that accept
+	// text/html but not application/json, such as browsers.
+	HTML bool
+
+	// HTMLTemplate replaces the default paywall page. It is an html/template
+	// executed with a PaywallPage.
+	HTMLTemplate string
+
+	// Locales holds messages for other languages, keyed by BCP 47 tag like
+	// "de" or "pt-BR", chosen by the request's Accept-Language header. Empty
+	// fields fall back to the top-level messages.
+	Locales map[string]PaymentMessages
+}
+
+// MessageData is the data PaymentMessages templates are executed with.
+type MessageData struct {
+	// Reason is the middleware's error text.
+	Reason string
+
+	// Path is the request path.
+	Path string
+
+	// Resource describes the protected resource.
+	Resource v2.ResourceInfo
+
+	// Price is the first requirement's amount in display units, like "0.01",
+	// or its atomic amount when the asset is not a known USDC deployment.
+	Price string
+
+	// Currency is "USDC" for known USDC deployments, otherwise the asset.
+	Currency string
+
+	// Amount is the first requirement's atomic amount.
+	Amount string
+
+	// Network is the first requirement's network.
+	Network string
+
+	// Requirements are all accepted payment options.
+	Requirements []v2.PaymentRequirements
+}
+
+// PaywallPage is the data the HTML paywall template is executed with.
+type PaywallPage struct {
+	MessageData
+
+	// Message, DocsURL and Support are the rendered messages.
+	Message string
+	DocsURL string
+	Support string
+
+	// PaymentRequired is the indented JSON body clients pay with.
+	PaymentRequired string
+}
+
+// resolve returns the messages for the request's preferred language.
+func (m PaymentMessages) resolve(r *http.Request) PaymentMessages {
+	if len(m.Locales) == 0 || r == nil {
+		return m
+	}
+	accept := r.Header.Get("Accept-Language")
+	if accept == "" {
+		return m
+	}
+
+	keys := make([]string, 0, len(m.Locales))
+	for key := range m.Locales {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tags := []language.Tag{language.Und}
+	matched := []string{""}
+	for _, key := range keys {
+		tag, err := language.Parse(key)
+		if err != nil {
+			continue
+		}
+		tags = append(tags, tag)
+		matched = append(matched, key)
+	}
+
+	preferred, _, err := language.ParseAcceptLanguage(accept)
+	if err != nil || len(preferred) == 0 {
+		return m
+	}
+	_, index, confidence := language.NewMatcher(tags).Match(preferred...)
+	if index == 0 || confidence == language.No {
+		return m
+	}
+
+	locale := m.Locales[matched[index]]
+	result := m
+	if locale.Error != "" {
+		result.Error = locale.Error
+	}
+	if locale.DocsURL != "" {
+		result.DocsURL = locale.DocsURL
+	}
+	if locale.Support != "" {
+		result.Support = locale.Support
+	}
+	if locale.HTMLTemplate != "" {
+		result.HTMLTemplate = locale.HTMLTemplate
+	}
+	return result
+}
+
+// newMessageData describes requirements for message templates.
+func newMessageData(r *http.Request, resource v2.ResourceInfo, requirements []v2.PaymentRequirements, reason string) MessageData {
+	data := MessageData{Reason: reason, Resource: resource, Requirements: requirements}
+	if r != nil {
+		data.Path = r.URL.Path
+	}
+	if len(requirements) > 0 {
+		req := requirements[0]
+		data.Amount = req.Amount
+		data.Network = req.Network
+		data.Price, data.Currency = req.Amount, req.Asset
+		if price, ok := displayPrice(req); ok {
+			data.Price, data.Currency = price, "USDC"
+		}
+	}
+	return data
+}
+
+// displayPrice returns req's amount in display units, like "0.01", when its
+// asset is a known USDC deployment.
+func displayPrice(req v2.PaymentRequirements) (string, bool) {
+	chain, err := v2.GetChainConfig(req.Network)
+	if err != nil || !strings.EqualFold(chain.USDCAddress, req.Asset) {
+		return "", false
+	}
+	amount, ok := new(big.Int).SetString(req.Amount, 10)
+	if !ok {
+		return "", false
+	}
+	price := v2.BigIntToAmount(amount, int(chain.Decimals))
+	if strings.Contains(price, ".") {
+		price = strings.TrimSuffix(strings.TrimRight(price, "0"), ".")
+	}
+	return price, true
+}
+
+// messageTemplates caches parsed message templates by their text.
+var messageTemplates sync.Map
+
+// render executes text as a message template. Text without actions is
+// returned unchanged.
+func render(text string, data MessageData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	cached, ok := messageTemplates.Load(text)
+	if !ok {
+		tmpl, err := template.New("message").Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", fmt.Errorf("parsing message template: %w", err)
+		}
+		cached, _ = messageTemplates.LoadOrStore(text, tmpl)
+	}
+	var buf bytes.Buffer
+	if err := cached.(*template.Template).Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("executing message template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// PaymentRequiredResponse builds the 402 body for resource and requirements,
+// applying Messages for the request's language and attaching the info of
+// Extensions and a price quote when Quotes is set. When a message template fails, reason is used as the
+// error text and the template error is returned with the response.
+func (c Config) PaymentRequiredResponse(r *http.Request, resource v2.ResourceInfo, requirements []v2.PaymentRequirements, reason string) (v2.PaymentRequired, error) {
+	messages := c.Messages.resolve(r)
+	data := newMessageData(r, resource, requirements, reason)
+
+	response := v2.PaymentRequired{
+		X402Version: v2.X402Version,
+		Error:       reason,
+		Resource:    &resource,
+		Accepts:     requirements,
+	}
+	var errs []error
+	if messages.Error != "" {
+		if text, err := render(messages.Error, data); err != nil {
+			errs = append(errs, err)
+		} else {
+			response.Error = text
+		}
+	}
+	var err error
+	if response.DocsURL, err = render(messages.DocsURL, data); err != nil {
+		errs = append(errs, err)
+	}
+	if response.Support, err = render(messages.Support, data); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Extensions != nil {
+		c.Extensions.Attach(&response.Extensions)
+	}
+	if c.Quotes != nil {
+		if err := c.Quotes.Quote(&response); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return response, errors.Join(errs...)
+}
+
+// WritePaymentRequired sends a 402 Payment Required response built by
+// PaymentRequiredResponse, attested by Attester, as an HTML paywall page when
+// Messages.HTML is set and the client prefers HTML, otherwise as JSON.
+func (c Config) WritePaymentRequired(w http.ResponseWriter, r *http.Request, resource v2.ResourceInfo, requirements []v2.PaymentRequirements, reason string) error {
+	response, messageErr := c.PaymentRequiredResponse(r, resource, requirements, reason)
+	if !c.Messages.HTML || !prefersHTML(r) {
+		return errors.Join(messageErr, helpers.WritePaymentRequired(w, response, c.Attester))
+	}
+
+	attestErr := helpers.Attest(&response, c.Attester)
+	body, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding PaymentRequired response: %w", err)
+	}
+	page := PaywallPage{
+		MessageData:     newMessageData(r, resource, requirements, reason),
+		Message:         response.Error,
+		DocsURL:         response.DocsURL,
+		Support:         response.Support,
+		PaymentRequired: string(body),
+	}
+
+	tmpl := defaultPaywallTemplate
+	if text := c.Messages.resolve(r).HTMLTemplate; text != "" {
+		if tmpl, err = htmltemplate.New("paywall").Parse(text); err != nil {
+			return fmt.Errorf("parsing paywall template: %w", err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, page); err != nil {
+		return fmt.Errorf("executing paywall template: %w", err)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusPaymentRequired)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("writing paywall page: %w", err)
+	}
+	return errors.Join(messageErr, attestErr)
+}
+
+// prefersHTML reports whether the request accepts text/html and not
+// application/json, as browsers navigating to a page do.
+func prefersHTML(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "text/html") && !strings.Contains(accept, "application/json")
+}
+
+var defaultPaywallTemplate = htmltemplate.Must(htmltemplate.New("paywall").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Payment required</title>
+</head>
+<body>
+<h1>Payment required</h1>
+<p>{{.Message}}</p>
+{{- if .Price}}
+<p>Price: {{.Price}} {{.Currency}} on {{.Network}}</p>
+{{- end}}
+{{- if .DocsURL}}
+<p><a href="{{.DocsURL}}">How to pay</a></p>
+{{- end}}
+{{- if .Support}}
+<p>Support: {{.Support}}</p>
+{{- end}}
+<details>
+<summary>Payment requirements</summary>
+<pre>{{.PaymentRequired}}</pre>
+</details>
+</body>
+</html>
+`))
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func messagesConfig() Config {
+	return Config{
+		Messages: PaymentMessages{
+			Error:   "{{.Path}} costs {{.Price}} {{.Currency}}",
+			DocsURL: "https://docs.example.com/pay",
+			Support: "support@example.com",
+			HTML:    true,
+			Locales: map[string]PaymentMessages{
+				"de": {Error: "{{.Path}} kostet {{.Price}} {{.Currency}}"},
+			},
+		},
+	}
+}
+
+func TestConfig_WritePaymentRequired(t *testing.T) {
+	requirements := []v2.PaymentRequirements{{
+		Scheme:  "exact",
+		Network: v2.BaseSepolia.Network,
+		Amount:  "10000",
+		Asset:   v2.BaseSepolia.USDCAddress,
+		PayTo:   "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+	}}
+
+	tests := []struct {
+		name        string
+		config      Config
+		headers     map[string]string
+		wantType    string
+		wantError   string
+		wantContent string
+	}{
+		{
+			name:      "default reason",
+			config:    Config{},
+			wantType:  "application/json",
+			wantError: "Payment required",
+		},
+		{
+			name:      "templated message",
+			config:    messagesConfig(),
+			wantType:  "application/json",
+			wantError: "/report costs 0.01 USDC",
+		},
+		{
+			name:      "localized message",
+			config:    messagesConfig(),
+			headers:   map[string]string{"Accept-Language": "de-DE,de;q=0.9,en;q=0.8"},
+			wantType:  "application/json",
+			wantError: "/report kostet 0.01 USDC",
+		},
+		{
+			name:      "unmatched language",
+			config:    messagesConfig(),
+			headers:   map[string]string{"Accept-Language": "fr"},
+			wantType:  "application/json",
+			wantError: "/report costs 0.01 USDC",
+		},
+		{
+			name:        "html paywall",
+			config:      messagesConfig(),
+			headers:     map[string]string{"Accept": "text/html,application/xhtml+xml"},
+			wantType:    "text/html; charset=utf-8",
+			wantContent: `<a href="https://docs.example.com/pay">`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/report", nil)
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			if err := tt.config.WritePaymentRequired(w, r, v2.ResourceInfo{URL: "https://api.example.com/report"}, requirements, "Payment required"); err != nil {
+				t.Fatalf("WritePaymentRequired failed: %v", err)
+			}
+			if w.Code != http.StatusPaymentRequired || w.Header().Get("Content-Type") != tt.wantType {
+				t.Fatalf("Unexpected response %d %q", w.Code, w.Header().Get("Content-Type"))
+			}
+			if tt.wantContent != "" && !strings.Contains(w.Body.String(), tt.wantContent) {
+				t.Errorf("Expected body to contain %q:\n%s", tt.wantContent, w.Body.String())
+			}
+			if tt.wantError == "" {
+				return
+			}
+			var response v2.PaymentRequired
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Invalid body: %v", err)
+			}
+			if response.Error != tt.wantError {
+				t.Errorf("Expected error %q, got %q", tt.wantError, response.Error)
+			}
+			if tt.config.Messages.DocsURL != response.DocsURL || tt.config.Messages.Support != response.Support {
+				t.Errorf("Unexpected docs %q and support %q", response.DocsURL, response.Support)
+			}
+		})
+	}
+}
+
+func TestConfig_PaymentRequiredResponse_TemplateError(t *testing.T) {
+	config := Config{Messages: PaymentMessages{Error: "{{.Missing}}"}}
+	response, err := config.PaymentRequiredResponse(httptest.NewRequest("GET", "/", nil), v2.ResourceInfo{}, nil, "Payment required")
+	if err == nil {
+		t.Error("Expected template error")
+	}
+	if response.Error != "Payment required" {
+		t.Errorf("Expected fallback to reason, got %q", response.Error)
+	}
+}
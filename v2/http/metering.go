@@ -0,0 +1,169 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// Usage describes a completed response to a metered request.
+type Usage struct {
+	// Status is the response status code.
+	Status int
+
+	// Bytes is the size of the response body.
+	Bytes int64
+
+	// Duration is the time the handler took to produce the response.
+	Duration time.Duration
+}
+
+// MeterFunc computes the charge in atomic units for a completed response paid
+// with the "upto" scheme. requirement.Amount is the authorized maximum; larger
+// charges are capped at it.
+type MeterFunc func(r *http.Request, usage Usage, requirement v2.PaymentRequirements) (*big.Int, error)
+
+// PerByte returns a MeterFunc charging price atomic units for every unit bytes
+// of response body, rounded up.
+func PerByte(price *big.Int, unit int64) MeterFunc {
+	return func(_ *http.Request, usage Usage, _ v2.PaymentRequirements) (*big.Int, error) {
+		if unit <= 0 {
+			return nil, fmt.Errorf("invalid byte unit %d", unit)
+		}
+		return ceilUnits(price, usage.Bytes, unit), nil
+	}
+}
+
+// PerDuration returns a MeterFunc charging price atomic units for every unit of
+// handler time, rounded up.
+func PerDuration(price *big.Int, unit time.Duration) MeterFunc {
+	return func(_ *http.Request, usage Usage, _ v2.PaymentRequirements) (*big.Int, error) {
+		if unit <= 0 {
+			return nil, fmt.Errorf("invalid duration unit %s", unit)
+		}
+		return ceilUnits(price, int64(usage.Duration), int64(unit)), nil
+	}
+}
+
+// ceilUnits returns price * ceil(quantity / unit).
+func ceilUnits(price *big.Int, quantity, unit int64) *big.Int {
+	units := quantity / unit
+	if quantity%unit != 0 {
+		units++
+	}
+	return new(big.Int).Mul(price, big.NewInt(units))
+}
+
+// Metered reports whether payment is settled for a metered amount after the
+// handler completes, rather than for the full amount before the response is
+// sent.
+func (c Config) Metered(payment *v2.PaymentPayload) bool {
+	return c.Meter != nil && payment.Accepted.Scheme == v2.SchemeUpTo && !c.VerifyOnly && !c.ManualCapture
+}
+
+// MeteredRequirement returns requirement with Amount set to the metered charge
+// for usage, raised to MinAmounts and capped at the authorized maximum. ok is
+// false if nothing is owed.
+func (c Config) MeteredRequirement(r *http.Request, usage Usage, requirement v2.PaymentRequirements) (settlement v2.PaymentRequirements, ok bool, err error) {
+	maximum, valid := new(big.Int).SetString(requirement.Amount, 10)
+	if !valid {
+		return requirement, false, fmt.Errorf("invalid amount in requirements: %q", requirement.Amount)
+	}
+
+	charge, err := c.Meter(r, usage, requirement)
+	if err != nil {
+		return requirement, false, err
+	}
+	if charge == nil || charge.Sign() <= 0 {
+		return requirement, false, nil
+	}
+	if minimum := c.MinAmounts.Minimum(requirement.Asset); minimum != nil && charge.Cmp(minimum) < 0 {
+		charge = minimum
+	}
+	if charge.Cmp(maximum) > 0 {
+		charge = maximum
+	}
+
+	requirement.Amount = charge.String()
+	return requirement, true, nil
+}
+
+// meteredWriter buffers a response until the amount owed for it is known.
+// Responses outgrowing limit are handed to overflow, which settles for them
+// before the buffered part is sent and the rest streamed; if it fails, the
+// handler's output is discarded.
+type meteredWriter struct {
+	w        http.ResponseWriter
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	limit    int64
+	overflow func(status int) bool
+
+	// committed is set once the response outgrew limit; refused is set if
+	// overflow failed
+	committed bool
+	refused   bool
+}
+
+func newMeteredWriter(w http.ResponseWriter, limit int64, overflow func(status int) bool) *meteredWriter {
+	return &meteredWriter{w: w, header: w.Header().Clone(), limit: limit, overflow: overflow}
+}
+
+func (m *meteredWriter) Header() http.Header {
+	return m.header
+}
+
+func (m *meteredWriter) WriteHeader(statusCode int) {
+	if m.status == 0 {
+		m.status = statusCode
+	}
+}
+
+func (m *meteredWriter) Write(b []byte) (int, error) {
+	if m.refused {
+		return 0, errMeteredRefused
+	}
+	if m.committed {
+		return m.w.Write(b)
+	}
+	if m.status == 0 {
+		m.status = http.StatusOK
+	}
+	if int64(m.body.Len()+len(b)) > m.limit {
+		m.committed = true
+		if !m.overflow(m.status) {
+			m.refused = true
+			return 0, errMeteredRefused
+		}
+		m.writeTo(m.w)
+		return m.w.Write(b)
+	}
+	return m.body.Write(b)
+}
+
+// errMeteredRefused is returned to handlers writing a metered response that
+// outgrew the buffer and could not be settled.
+var errMeteredRefused = errors.New("x402: metered response too large and not settled")
+
+// statusCode returns the buffered status, defaulting to 200.
+func (m *meteredWriter) statusCode() int {
+	if m.status == 0 {
+		return http.StatusOK
+	}
+	return m.status
+}
+
+// writeTo sends the buffered response to w.
+func (m *meteredWriter) writeTo(w http.ResponseWriter) {
+	for key, values := range m.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(m.statusCode())
+	_, _ = w.Write(m.body.Bytes())
+}
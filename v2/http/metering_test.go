@@ -0,0 +1,178 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/cluster"
+	"github.com/mark3labs/x402-go/v2/encoding"
+	"github.com/mark3labs/x402-go/v2/storage"
+)
+
+func TestMiddleware_Metered(t *testing.T) {
+	var settled []string
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+		case "/verify":
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			var body struct {
+				PaymentRequirements v2.PaymentRequirements `json:"paymentRequirements"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			settled = append(settled, body.PaymentRequirements.Amount)
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            v2.SchemeUpTo,
+		Network:           "eip155:84532",
+		Amount:            "10",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+		Extra: map[string]interface{}{
+			"assetTransferMethod": v2.AssetTransferMethodPermit2,
+			"spender":             "0x3333333333333333333333333333333333333333",
+		},
+	}
+	pay := func(signature string) string {
+		header, _ := encoding.EncodePayment(v2.PaymentPayload{
+			X402Version: 2,
+			Accepted:    requirement,
+			Payload:     map[string]interface{}{"signature": signature},
+		})
+		return header
+	}
+
+	middleware := NewX402Middleware(Config{
+		FacilitatorURL:           facilitatorServer.URL,
+		PaymentRequirements:      []v2.PaymentRequirements{requirement},
+		Meter:                    PerByte(big.NewInt(1), 1000),
+		MaxBufferedResponseBytes: 20000,
+		Coordinator:              cluster.New(storage.NewMemory()),
+	})
+
+	tests := []struct {
+		name       string
+		status     int
+		size       int
+		wantSettle []string
+		wantCharge string
+	}{
+		{name: "charged per started kilobyte", status: http.StatusOK, size: 2500, wantSettle: []string{"3"}, wantCharge: "3"},
+		{name: "capped at the authorized amount", status: http.StatusOK, size: 15000, wantSettle: []string{"10"}, wantCharge: "10"},
+		{name: "outgrowing the buffer is charged the maximum", status: http.StatusOK, size: 25000, wantSettle: []string{"10"}, wantCharge: "10"},
+		{name: "empty response is free", status: http.StatusOK},
+		{name: "handler error is not charged", status: http.StatusInternalServerError, size: 2500},
+		{name: "large handler error is not charged", status: http.StatusInternalServerError, size: 25000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settled = nil
+			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.WriteHeader(tt.status)
+				// Written in chunks, as a streaming handler would
+				for written := 0; written < tt.size; written += 1000 {
+					_, _ = w.Write([]byte(strings.Repeat("x", min(1000, tt.size-written))))
+				}
+			}))
+
+			req := httptest.NewRequest("GET", "/api/data", nil)
+			req.Header.Set("X-PAYMENT", pay("0xsig-"+tt.name))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.status || w.Body.Len() != tt.size || w.Header().Get("Content-Type") != "text/plain" {
+				t.Errorf("Expected buffered response %d with %d bytes, got %d with %d bytes", tt.status, tt.size, w.Code, w.Body.Len())
+			}
+			if strings.Join(settled, ",") != strings.Join(tt.wantSettle, ",") {
+				t.Errorf("Expected settlements %v, got %v", tt.wantSettle, settled)
+			}
+			settlement := GetSettlement(w.Result())
+			if tt.wantCharge == "" {
+				if settlement != nil {
+					t.Errorf("Expected no settlement, got %+v", settlement)
+				}
+				return
+			}
+			if settlement == nil || settlement.Amount != tt.wantCharge {
+				t.Errorf("Expected charge %s in X-PAYMENT-RESPONSE, got %+v", tt.wantCharge, settlement)
+			}
+		})
+	}
+
+	// A payment that owed nothing was still used and cannot be sent again
+	served := 0
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+	}))
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/api/data", nil)
+		req.Header.Set("X-PAYMENT", pay("0xsig-free"))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if served != 1 {
+		t.Errorf("Expected the free payment to be used once, served %d times", served)
+	}
+}
+
+func TestTransport_CheckCharge(t *testing.T) {
+	payment := &v2.PaymentPayload{Accepted: v2.PaymentRequirements{Scheme: v2.SchemeUpTo, Amount: "100"}}
+
+	tests := []struct {
+		name        string
+		payment     *v2.PaymentPayload
+		settlement  *v2.SettleResponse
+		accept      ChargeAcceptor
+		wantCharged string
+		wantErr     bool
+	}{
+		{name: "exact scheme is not checked", payment: &v2.PaymentPayload{Accepted: v2.PaymentRequirements{Scheme: "exact", Amount: "100"}}, settlement: &v2.SettleResponse{Success: true, Amount: "500"}},
+		{name: "partial charge", payment: payment, settlement: &v2.SettleResponse{Success: true, Amount: "40"}, wantCharged: "40"},
+		{name: "missing amount is the full authorization", payment: payment, settlement: &v2.SettleResponse{Success: true}, wantCharged: "100"},
+		{name: "overcharge", payment: payment, settlement: &v2.SettleResponse{Success: true, Amount: "101"}, wantCharged: "101", wantErr: true},
+		{
+			name:       "refused by acceptor",
+			payment:    payment,
+			settlement: &v2.SettleResponse{Success: true, Amount: "90"},
+			accept: func(_ v2.PaymentRequirements, charged *big.Int) error {
+				if charged.Cmp(big.NewInt(50)) > 0 {
+					return errors.New("more than expected")
+				}
+				return nil
+			},
+			wantCharged: "90",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &X402Transport{AcceptCharge: tt.accept}
+			charged, err := transport.checkCharge(tt.payment, tt.settlement)
+			if charged != tt.wantCharged {
+				t.Errorf("Expected charge %q, got %q", tt.wantCharged, charged)
+			}
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+			if err != nil && !errors.Is(err, v2.ErrChargeRejected) {
+				t.Errorf("Expected ErrChargeRejected, got %v", err)
+			}
+		})
+	}
+}
@@ -3,13 +3,24 @@ package http
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
+	"strconv"
+	"time"
 
 	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/attestation"
+	"github.com/mark3labs/x402-go/v2/cluster"
+	"github.com/mark3labs/x402-go/v2/extensions"
+	"github.com/mark3labs/x402-go/v2/facilitator"
+	"github.com/mark3labs/x402-go/v2/http/internal/checks"
 	"github.com/mark3labs/x402-go/v2/http/internal/helpers"
+	"github.com/mark3labs/x402-go/v2/quote"
 )
 
 // Config holds the configuration for the x402 v2 middleware.
@@ -29,6 +40,49 @@ type Config struct {
 	// VerifyOnly skips settlement if true (only verifies payments).
 	VerifyOnly bool
 
+	// RequirementsFunc computes per-request payment requirements (e.g., RangePricing).
+	// When set, payments must match the computed amount exactly.
+	RequirementsFunc RequirementsFunc
+
+	// Experiment, if set, varies the advertised price per client for A/B
+	// revenue experiments, after RequirementsFunc (see PriceExperiment).
+	Experiment *PriceExperiment
+
+	// RevenueSplits splits every payment between PayTo and additional recipients
+	// (e.g., a platform fee). The splits are sent to the facilitator in each
+	// requirement's Extra, so the facilitator must support v2.RevenueSplitExtension.
+	RevenueSplits []v2.Split
+
+	// MinAmounts is the floor price per asset: requirements whose amount is
+	// below it, including computed ones, are never settled, since the transfer
+	// would cost more in fees than it is worth. Validate reports configured
+	// requirements below it when the middleware is created.
+	MinAmounts v2.MinimumAmounts
+
+	// Allowlist restricts the schemes, networks and assets of accepted
+	// payments for the whole deployment, e.g. only "exact" USDC on Base in
+	// production, so a misconfigured route cannot accept testnet payments.
+	// Payments matching only requirements outside it are refused, and
+	// Validate reports configured requirements outside it.
+	Allowlist v2.PaymentAllowlist
+
+	// PayerLimiter bounds concurrent in-flight requests per payer. Requests that
+	// cannot get a slot are answered with 429 and not settled.
+	PayerLimiter *PayerLimiter
+
+	// Drainer, if set, coordinates graceful shutdown: after Drainer.Shutdown is
+	// called, new paid requests are refused with 503 while in-flight ones settle.
+	Drainer *Drainer
+
+	// Admin, if set, collects runtime state for the admin endpoints (see Admin).
+	Admin *Admin
+
+	// ManualCapture replaces settle-on-2xx with explicit capture: verified payments
+	// are exposed to the handler as a PaymentHold (see GetPaymentHoldFromContext),
+	// which the handler captures or voids. Uncaptured holds are voided. Combined
+	// with VerifyOnly it is ignored and Validate reports ErrCaptureVerifyOnly.
+	ManualCapture bool
+
 	// FacilitatorAuthorization is a static Authorization header value for the primary facilitator.
 	// Example: "Bearer your-api-key" or "Basic base64-encoded-credentials"
 	FacilitatorAuthorization string
@@ -38,6 +92,13 @@ type Config struct {
 	// If set, this takes precedence over FacilitatorAuthorization.
 	FacilitatorAuthorizationProvider AuthorizationProvider
 
+	// FacilitatorTLSConfig enables mutual TLS with the primary facilitator (see LoadClientTLSConfig).
+	FacilitatorTLSConfig *tls.Config
+
+	// FacilitatorRequestSigner signs verify and settle requests to the primary
+	// facilitator with HTTP Message Signatures (RFC 9421).
+	FacilitatorRequestSigner *RequestSigner
+
 	// Facilitator hooks for custom logic before/after verify and settle operations.
 	FacilitatorOnBeforeVerify OnBeforeFunc
 	FacilitatorOnAfterVerify  OnAfterVerifyFunc
@@ -51,11 +112,217 @@ type Config struct {
 	// for the fallback facilitator. If set, this takes precedence over FallbackFacilitatorAuthorization.
 	FallbackFacilitatorAuthorizationProvider AuthorizationProvider
 
+	// FallbackFacilitatorTLSConfig enables mutual TLS with the fallback facilitator.
+	FallbackFacilitatorTLSConfig *tls.Config
+
+	// FallbackFacilitatorRequestSigner signs verify and settle requests to the fallback facilitator.
+	FallbackFacilitatorRequestSigner *RequestSigner
+
 	// FallbackFacilitator hooks for custom logic before/after verify and settle operations.
 	FallbackFacilitatorOnBeforeVerify OnBeforeFunc
 	FallbackFacilitatorOnAfterVerify  OnAfterVerifyFunc
 	FallbackFacilitatorOnBeforeSettle OnBeforeFunc
 	FallbackFacilitatorOnAfterSettle  OnAfterSettleFunc
+
+	// SchemeFacilitators verifies and settles payments in the given schemes
+	// locally instead of through the facilitators, such as direct payments with
+	// a direct.Confirmer (see the facilitator/direct package).
+	SchemeFacilitators map[string]facilitator.Interface
+
+	// CheckAuthorizationWindow enables a local check of EVM validAfter/validBefore
+	// windows before calling the facilitator, rejecting expired, not-yet-valid, or
+	// overly long authorizations early.
+	CheckAuthorizationWindow bool
+
+	// RejectExpired rejects EVM payments whose validBefore (or Permit2
+	// deadline) has passed, allowing ClockSkew, before calling the
+	// facilitator. Unlike CheckAuthorizationWindow, it checks nothing else.
+	// Expired payments are answered with a 402 whose error starts with
+	// v2.ErrCodeAuthorizationExpired.
+	RejectExpired bool
+
+	// Clock is used for authorization window checks, claim deadlines, and facilitator
+	// retry backoff. If nil, v2.SystemClock is used.
+	Clock v2.Clock
+
+	// ClockSkew is the tolerated clock difference for the authorization window and
+	// expiry checks and payment deadlines (default: v2.DefaultClockSkew).
+	ClockSkew time.Duration
+
+	// Timeouts bounds facilitator operations (default: v2.DefaultTimeouts).
+	Timeouts v2.TimeoutConfig
+
+	// NetworkTimeouts overrides Timeouts for payments on specific networks or
+	// namespaces, e.g. a longer SettleTimeout for "solana".
+	NetworkTimeouts v2.NetworkTimeouts
+
+	// Attester, if set, signs every 402 response body so clients can detect a
+	// man-in-the-middle swapping PayTo or other requirements (see the attestation package).
+	Attester *attestation.Signer
+
+	// StrictMatching cross-checks every field of the payment's accepted
+	// requirement, and the amount, asset and recipient signed in its payload,
+	// against the advertised requirement before calling the facilitator (see
+	// validation.ValidateAccepted). Mismatches are answered with 402 and the
+	// mismatch reason.
+	StrictMatching bool
+
+	// Quotes, if set, attaches a signed, expiring price quote to every 402
+	// response. Payments echoing a valid quote are matched against the quoted
+	// requirements instead of the live ones, so a price change between the 402
+	// and the payment does not fail the request. Only the price is quoted: the
+	// other fields must still match the live requirements for the request.
+	Quotes *quote.Issuer
+
+	// Extensions, if set, advertises the extensions that define info in every
+	// 402 response and validates the extensions of incoming payments against
+	// their registered schemas. Payments lacking a required extension or
+	// carrying invalid info are answered with 402.
+	Extensions *extensions.Registry
+
+	// Messages customizes the error text, docs URL and support contact of 402
+	// responses, optionally per language and as an HTML paywall page.
+	Messages PaymentMessages
+
+	// Headers names the payment and settlement headers and the alternate
+	// locations payments are accepted from (default: X-PAYMENT and
+	// X-PAYMENT-RESPONSE). A ResponseCache needs the same headers, set with
+	// WithCachePaymentHeaders.
+	Headers PaymentHeaders
+
+	// CORS, if set, answers CORS preflights, serves other OPTIONS requests
+	// without payment and exposes the settlement headers to cross-origin
+	// browser payers.
+	CORS *CORSConfig
+
+	// ResponseCache, if set, replays settled GET responses to clients retrying with
+	// the same payment or the receipt from PaymentReceiptHeader, so a lost response
+	// is not paid for twice.
+	ResponseCache *ResponseCache
+
+	// Revalidation controls how conditional requests answered with 304 Not
+	// Modified are charged (default: RevalidationCharge, the full price).
+	Revalidation RevalidationPolicy
+
+	// RevalidationBasisPoints is the revalidation price under RevalidationReduced,
+	// in hundredths of a percent of the full price
+	// (default: DefaultRevalidationBasisPoints).
+	RevalidationBasisPoints int
+
+	// Meter, if set, prices responses to payments using the "upto" scheme after
+	// the handler completes (e.g., PerByte or PerDuration): the client authorizes
+	// up to the requirement's Amount and is charged the metered amount. Metered
+	// responses are buffered until settlement, and the final charge is reported
+	// in the X-PAYMENT-RESPONSE amount. Responses outgrowing
+	// MaxBufferedResponseBytes are charged the authorized maximum when they
+	// reach it and streamed from then on.
+	Meter MeterFunc
+
+	// BufferResponses holds the handler's response until the handler returns
+	// and the payment settles, so the X-PAYMENT-RESPONSE header is always sent
+	// and a failed settlement is answered with a clean 402, without the
+	// handler's headers or body. It trades memory for correctness: responses
+	// outgrowing MaxBufferedResponseBytes are settled when they reach it and
+	// streamed from then on, and flushes are held until then. The Gin
+	// middleware settles before calling the handler and ignores it.
+	BufferResponses bool
+
+	// BufferResponsesFor, if set, decides per request whether to buffer the
+	// response, overriding BufferResponses, e.g. to buffer some routes only.
+	BufferResponsesFor func(r *http.Request) bool
+
+	// MaxBufferedResponseBytes caps buffered responses
+	// (default: DefaultMaxBufferedResponseBytes).
+	MaxBufferedResponseBytes int64
+
+	// DeliveryDigest binds payments settled by the middleware to the responses
+	// they paid for: the SHA-256 digest and length of the delivered body are
+	// sent with the settlement's transaction in the X-PAYMENT-DELIVERY trailer
+	// (see Delivery and GetDelivery) and passed to OnDelivery. Declaring the
+	// trailer makes net/http send responses chunked. The Gin middleware
+	// ignores it.
+	DeliveryDigest bool
+
+	// OnDelivery, if set, receives the Delivery of every response settled
+	// with DeliveryDigest.
+	OnDelivery DeliveryFunc
+
+	// Rotator, if set, rotates PayTo addresses and facilitator credentials at
+	// runtime (see Rotator). It overrides the configured values once rotated.
+	Rotator *Rotator
+
+	// Diagnostics explains payments the facilitator rejects by decoding them
+	// locally (see validation.Diagnose): the mismatching field, such as an
+	// expired window or a signature for the wrong chain ID or domain, is logged
+	// and appended to the 402 error. It helps attackers probe signatures, so
+	// enable it only outside production.
+	Diagnostics bool
+
+	// Coordinator, if set, shares state between replicas: payments are claimed
+	// so one cannot be used for two requests on different replicas, and retries
+	// of a paid request wait for and reuse its settlement instead of settling
+	// again (see ClaimPayment). Use it with PayerLimiter's WithCoordinator to
+	// share concurrency limits too.
+	Coordinator *cluster.Coordinator
+}
+
+// clockSkew returns the configured clock skew, or v2.DefaultClockSkew if unset.
+func (c Config) clockSkew() time.Duration {
+	if c.ClockSkew > 0 {
+		return c.ClockSkew
+	}
+	return v2.DefaultClockSkew
+}
+
+// FacilitatorTimeouts returns the configured timeouts, or v2.DefaultTimeouts if
+// unset, and the matching overall timeout for the facilitator HTTP client.
+func (c Config) FacilitatorTimeouts() (v2.TimeoutConfig, time.Duration) {
+	timeouts := v2.TimeoutsOrDefault(c.Timeouts)
+	return timeouts, c.NetworkTimeouts.Longest(timeouts)
+}
+
+// Validate reports configuration errors, joined: invalid MinAmounts, Allowlist
+// or CORS, payment requirements below MinAmounts or outside Allowlist, and
+// ManualCapture combined with VerifyOnly. The middleware logs them when it is
+// created and runs regardless, so callers wanting to refuse an invalid
+// configuration check Validate first.
+func (c Config) Validate() error {
+	var errs []error
+	if err := c.MinAmounts.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Allowlist.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.CORS.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, requirement := range c.PaymentRequirements {
+		if err := c.MinAmounts.Check(requirement); err != nil {
+			errs = append(errs, fmt.Errorf("payment requirement on %s: %w", requirement.Network, err))
+		}
+		if err := c.Allowlist.Check(requirement); err != nil {
+			errs = append(errs, fmt.Errorf("payment requirement on %s: %w", requirement.Network, err))
+		}
+	}
+	if c.ManualCapture && c.VerifyOnly {
+		errs = append(errs, ErrCaptureVerifyOnly)
+	}
+	return errors.Join(errs...)
+}
+
+// policy returns the configuration read by the local payment checks.
+func (c Config) policy() checks.Policy {
+	return checks.Policy{
+		MinAmounts:               c.MinAmounts,
+		CheckAuthorizationWindow: c.CheckAuthorizationWindow,
+		RejectExpired:            c.RejectExpired,
+		StrictMatching:           c.StrictMatching,
+		Extensions:               c.Extensions,
+		Quotes:                   c.Quotes,
+		Clock:                    c.Clock,
+		ClockSkew:                c.ClockSkew,
+	}
 }
 
 // contextKey is a custom type for context keys to avoid collisions.
@@ -69,50 +336,110 @@ const PaymentContextKey = contextKey("x402_v2_payment")
 // The middleware automatically fetches network-specific configuration (like feePayer for SVM chains)
 // from the facilitator's /supported endpoint.
 func NewX402Middleware(config Config) func(http.Handler) http.Handler {
+	facilitator, fallbackFacilitator := config.FacilitatorClients()
+	return newMiddleware(config, facilitator, fallbackFacilitator)
+}
+
+// FacilitatorClients creates the primary and, if configured, fallback
+// facilitator clients, as NewX402Middleware uses them.
+func (c Config) FacilitatorClients() (*FacilitatorClient, *FacilitatorClient) {
 	// Create facilitator client
+	timeouts, clientTimeout := c.FacilitatorTimeouts()
 	facilitator := &FacilitatorClient{
-		BaseURL:               config.FacilitatorURL,
-		Client:                &http.Client{Timeout: v2.DefaultTimeouts.RequestTimeout},
-		Timeouts:              v2.DefaultTimeouts,
-		Authorization:         config.FacilitatorAuthorization,
-		AuthorizationProvider: config.FacilitatorAuthorizationProvider,
-		OnBeforeVerify:        config.FacilitatorOnBeforeVerify,
-		OnAfterVerify:         config.FacilitatorOnAfterVerify,
-		OnBeforeSettle:        config.FacilitatorOnBeforeSettle,
-		OnAfterSettle:         config.FacilitatorOnAfterSettle,
+		BaseURL:               c.FacilitatorURL,
+		Client:                &http.Client{Timeout: clientTimeout},
+		Timeouts:              timeouts,
+		NetworkTimeouts:       c.NetworkTimeouts,
+		Clock:                 c.Clock,
+		Authorization:         c.FacilitatorAuthorization,
+		AuthorizationProvider: c.FacilitatorAuthorizationProvider,
+		TLSConfig:             c.FacilitatorTLSConfig,
+		RequestSigner:         c.FacilitatorRequestSigner,
+		OnBeforeVerify:        c.FacilitatorOnBeforeVerify,
+		OnAfterVerify:         c.FacilitatorOnAfterVerify,
+		OnBeforeSettle:        c.FacilitatorOnBeforeSettle,
+		OnAfterSettle:         c.FacilitatorOnAfterSettle,
+		Schemes:               c.SchemeFacilitators,
 	}
 
 	// Create fallback facilitator client if configured
 	var fallbackFacilitator *FacilitatorClient
-	if config.FallbackFacilitatorURL != "" {
+	if c.FallbackFacilitatorURL != "" {
 		fallbackFacilitator = &FacilitatorClient{
-			BaseURL:               config.FallbackFacilitatorURL,
-			Client:                &http.Client{Timeout: v2.DefaultTimeouts.RequestTimeout},
-			Timeouts:              v2.DefaultTimeouts,
-			Authorization:         config.FallbackFacilitatorAuthorization,
-			AuthorizationProvider: config.FallbackFacilitatorAuthorizationProvider,
-			OnBeforeVerify:        config.FallbackFacilitatorOnBeforeVerify,
-			OnAfterVerify:         config.FallbackFacilitatorOnAfterVerify,
-			OnBeforeSettle:        config.FallbackFacilitatorOnBeforeSettle,
-			OnAfterSettle:         config.FallbackFacilitatorOnAfterSettle,
+			BaseURL:               c.FallbackFacilitatorURL,
+			Client:                &http.Client{Timeout: clientTimeout},
+			Timeouts:              timeouts,
+			NetworkTimeouts:       c.NetworkTimeouts,
+			Clock:                 c.Clock,
+			Authorization:         c.FallbackFacilitatorAuthorization,
+			AuthorizationProvider: c.FallbackFacilitatorAuthorizationProvider,
+			TLSConfig:             c.FallbackFacilitatorTLSConfig,
+			RequestSigner:         c.FallbackFacilitatorRequestSigner,
+			OnBeforeVerify:        c.FallbackFacilitatorOnBeforeVerify,
+			OnAfterVerify:         c.FallbackFacilitatorOnAfterVerify,
+			OnBeforeSettle:        c.FallbackFacilitatorOnBeforeSettle,
+			OnAfterSettle:         c.FallbackFacilitatorOnAfterSettle,
+			Schemes:               c.SchemeFacilitators,
 		}
 	}
 
+	if c.Rotator != nil {
+		facilitator.AuthorizationProvider = c.Rotator.authorizationProvider(false, facilitator.Authorization, facilitator.AuthorizationProvider)
+		if fallbackFacilitator != nil {
+			fallbackFacilitator.AuthorizationProvider = c.Rotator.authorizationProvider(true, fallbackFacilitator.Authorization, fallbackFacilitator.AuthorizationProvider)
+		}
+	}
+	return facilitator, fallbackFacilitator
+}
+
+// newMiddleware creates the payment middleware for config using the given
+// facilitator clients, which may be shared with other middleware.
+func newMiddleware(config Config, facilitator, fallbackFacilitator *FacilitatorClient) func(http.Handler) http.Handler {
+	if err := config.Validate(); err != nil {
+		slog.Default().Error("invalid x402 middleware configuration", "error", err)
+	}
+	policy := config.policy()
+
 	// Enrich payment requirements with facilitator-specific data (like feePayer)
-	ctx, cancel := context.WithTimeout(context.Background(), v2.DefaultTimeouts.RequestTimeout)
+	timeouts, _ := config.FacilitatorTimeouts()
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.RequestTimeout)
 	defer cancel()
-	enrichedRequirements, err := facilitator.EnrichRequirements(ctx, config.PaymentRequirements)
+	baseRequirements, err := checks.SplitRequirements(config.PaymentRequirements, config.RevenueSplits)
+	if err != nil {
+		slog.Default().Error("invalid revenue splits, serving requirements without splits", "error", err)
+		baseRequirements = config.PaymentRequirements
+	}
+	enrichedRequirements, err := facilitator.EnrichRequirements(ctx, baseRequirements)
 	if err != nil {
 		// Log warning but continue with original requirements
 		slog.Default().Warn("failed to enrich payment requirements from facilitator", "error", err)
-		enrichedRequirements = config.PaymentRequirements
+		enrichedRequirements = baseRequirements
 	} else {
 		slog.Default().Info("payment requirements enriched from facilitator", "count", len(enrichedRequirements))
 	}
+	if config.Admin != nil {
+		config.Admin.Register(config, enrichedRequirements, err, facilitator, fallbackFacilitator)
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			logger := slog.Default()
+			r = config.Headers.TakeQueryPayment(r)
+
+			if config.CORS != nil {
+				if config.CORS.Handle(w, r, config.Headers) {
+					return
+				}
+				if r.Method == http.MethodOptions {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			if config.ResponseCache != nil && config.ResponseCache.Serve(w, r) {
+				logger.Info("served cached paid response", "path", r.URL.Path)
+				return
+			}
 
 			// Build resource info from request
 			resource := config.Resource
@@ -123,104 +450,418 @@ func NewX402Middleware(config Config) func(http.Handler) http.Handler {
 				resource.Description = "Payment required for " + r.URL.Path
 			}
 
-			// Check for X-PAYMENT header
-			paymentHeader := r.Header.Get("X-PAYMENT")
+			requirements, err := config.RequirementsFor(r, enrichedRequirements)
+			if err != nil {
+				logger.Warn("failed to compute payment requirements", "error", err)
+				writeStatusError(w, err)
+				return
+			}
+
+			// Check for a payment
+			paymentHeader := config.Headers.ReadPayment(r)
 			if paymentHeader == "" {
+				// Unpaid revalidations are free if the resource has not changed
+				if config.Revalidation == RevalidationFree && IsConditional(r) {
+					guard := &revalidationWriter{w: w}
+					next.ServeHTTP(guard, r)
+					if guard.notModified {
+						logger.Info("served free revalidation", "path", r.URL.Path)
+						return
+					}
+				}
+
 				// No payment provided - return 402 with requirements
 				logger.Info("no payment header provided", "path", r.URL.Path)
-				if err := helpers.SendPaymentRequired(w, resource, enrichedRequirements, "Payment required"); err != nil {
+				if err := config.WritePaymentRequired(w, r, resource, requirements, "Payment required"); err != nil {
 					logger.Error("failed to send payment required response", "error", err)
 				}
 				return
 			}
 
+			if config.Drainer != nil {
+				release, err := config.Drainer.Acquire()
+				if err != nil {
+					logger.Warn("refusing paid request during shutdown", "path", r.URL.Path)
+					w.Header().Set("Retry-After", "5")
+					http.Error(w, "Server shutting down", http.StatusServiceUnavailable)
+					return
+				}
+				defer release()
+			}
+
 			// Parse payment header
-			payment, err := helpers.ParsePaymentHeader(r)
+			payment, err := helpers.DecodePaymentHeader(paymentHeader)
 			if err != nil {
 				logger.Warn("invalid payment header", "error", err)
 				http.Error(w, "Invalid payment header", http.StatusBadRequest)
 				return
 			}
 
+			if err := policy.CheckExtensions(payment); err != nil {
+				logger.Warn("invalid payment extensions", "error", err)
+				if err := config.WritePaymentRequired(w, r, resource, requirements, err.Error()); err != nil {
+					logger.Error("failed to send payment required response", "error", err)
+				}
+				return
+			}
+
+			// Honor the price quoted in the 402 response, if the payment echoes one
+			quoted, err := policy.QuotedRequirements(payment, resource.URL, config.Rotator.Retired(payment, requirements))
+			if err != nil {
+				logger.Warn("invalid price quote", "error", err)
+				if err := config.WritePaymentRequired(w, r, resource, requirements, err.Error()); err != nil {
+					logger.Error("failed to send payment required response", "error", err)
+				}
+				return
+			}
+			requirements = quoted
+
 			// Find matching requirement
-			requirement, err := v2.FindMatchingRequirement(payment, enrichedRequirements)
+			requirement, err := config.Allowlist.FindMatchingRequirement(payment, requirements)
 			if err != nil {
 				logger.Warn("no matching requirement", "error", err)
-				if err := helpers.SendPaymentRequired(w, resource, enrichedRequirements, "No matching payment requirement"); err != nil {
+				if err := config.WritePaymentRequired(w, r, resource, requirements, "No matching payment requirement"); err != nil {
 					logger.Error("failed to send payment required response", "error", err)
 				}
 				return
 			}
 
-			// Verify payment with facilitator
-			logger.Info("verifying payment", "scheme", payment.Accepted.Scheme, "network", payment.Accepted.Network)
-			verifyResp, err := facilitator.Verify(r.Context(), *payment, *requirement)
-			if err != nil && fallbackFacilitator != nil {
-				logger.Warn("primary facilitator failed, trying fallback", "error", err)
-				verifyResp, err = fallbackFacilitator.Verify(r.Context(), *payment, *requirement)
+			if err := config.CheckPrice(payment, requirement); err != nil {
+				logger.Warn("payment does not match request price", "error", err)
+				if err := config.WritePaymentRequired(w, r, resource, requirements, err.Error()); err != nil {
+					logger.Error("failed to send payment required response", "error", err)
+				}
+				return
 			}
-			if err != nil {
-				logger.Error("facilitator verification failed", "error", err)
-				http.Error(w, "Payment verification failed", http.StatusServiceUnavailable)
+
+			if err := policy.CheckAccepted(payment, requirement); err != nil {
+				logger.Warn("payment does not match requirement", "error", err)
+				if err := config.WritePaymentRequired(w, r, resource, requirements, err.Error()); err != nil {
+					logger.Error("failed to send payment required response", "error", err)
+				}
+				return
+			}
+
+			// Check the authorization locally before calling the facilitator
+			if err := policy.CheckExpired(payment); err != nil {
+				logger.Warn("authorization expired", "error", err)
+				if err := config.WritePaymentRequired(w, r, resource, requirements, checks.Reason(err)); err != nil {
+					logger.Error("failed to send payment required response", "error", err)
+				}
+				return
+			}
+			if err := policy.CheckWindow(payment, requirement); err != nil {
+				logger.Warn("invalid authorization window", "error", err)
+				if err := config.WritePaymentRequired(w, r, resource, requirements, checks.Reason(err)); err != nil {
+					logger.Error("failed to send payment required response", "error", err)
+				}
 				return
 			}
 
-			if !verifyResp.IsValid {
-				logger.Warn("payment verification failed", "reason", verifyResp.InvalidReason)
-				if err := helpers.SendPaymentRequired(w, resource, enrichedRequirements, verifyResp.InvalidReason); err != nil {
+			// Claim the payment so it cannot be used again, on this or another
+			// replica. Retries of a request that settled it reuse the settlement
+			// without verifying again, as the facilitator may reject a used payment
+			claim, err := config.ClaimPayment(r.Context(), payment, requirement, r.Method+" "+helpers.BuildResourceURL(r))
+			if errors.Is(err, v2.ErrPaymentReplayed) {
+				logger.Warn("payment replayed")
+				if err := config.WritePaymentRequired(w, r, resource, requirements, err.Error()); err != nil {
 					logger.Error("failed to send payment required response", "error", err)
 				}
 				return
 			}
+			if err != nil {
+				logger.Error("failed to claim payment", "error", err)
+				http.Error(w, "Payment coordination unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			defer claim.Release()
+			// Verify payment with facilitator, no later than the payment can be settled
+			received := v2.ClockOrSystem(config.Clock).Now()
+			verifyResp := claim.Verification()
+			if verifyResp != nil {
+				logger.Info("reusing verification of retried request", "payer", verifyResp.Payer)
+			} else {
+				verifyCtx, cancelVerify, err := config.PaymentContext(r.Context(), payment, requirement, received)
+				if err != nil {
+					logger.Warn("payment expired before verification", "error", err)
+					if err := config.WritePaymentRequired(w, r, resource, requirements, checks.Reason(err)); err != nil {
+						logger.Error("failed to send payment required response", "error", err)
+					}
+					return
+				}
+				logger.Info("verifying payment", "scheme", payment.Accepted.Scheme, "network", payment.Accepted.Network)
+				verifyResp, err = facilitator.Verify(verifyCtx, *payment, *requirement)
+				if err != nil && fallbackFacilitator != nil && verifyCtx.Err() == nil {
+					logger.Warn("primary facilitator failed, trying fallback", "error", err)
+					verifyResp, err = fallbackFacilitator.Verify(verifyCtx, *payment, *requirement)
+				}
+				err = DeadlineError(verifyCtx, err)
+				cancelVerify()
+				if errors.Is(err, v2.ErrAuthorizationExpired) {
+					logger.Warn("payment expired during verification", "error", err)
+					if err := config.WritePaymentRequired(w, r, resource, requirements, checks.Reason(err)); err != nil {
+						logger.Error("failed to send payment required response", "error", err)
+					}
+					return
+				}
+				if err != nil {
+					logger.Error("facilitator verification failed", "error", err)
+					http.Error(w, "Payment verification failed", http.StatusServiceUnavailable)
+					return
+				}
+
+				if !verifyResp.IsValid {
+					logger.Warn("payment verification failed", "reason", verifyResp.InvalidReason)
+					if err := config.WritePaymentRequired(w, r, resource, requirements, config.VerificationFailure(payment, requirement, verifyResp.InvalidReason)); err != nil {
+						logger.Error("failed to send payment required response", "error", err)
+					}
+					return
+				}
 
-			// Payment verified successfully
-			logger.Info("payment verified", "payer", verifyResp.Payer)
+				// Payment verified successfully
+				logger.Info("payment verified", "payer", verifyResp.Payer)
+			}
+
+			if config.PayerLimiter != nil {
+				release, err := config.PayerLimiter.Acquire(r.Context(), verifyResp.Payer)
+				if err != nil {
+					logger.Warn("payer concurrency limit reached", "payer", verifyResp.Payer, "error", err)
+					w.Header().Set("Retry-After", strconv.Itoa(config.PayerLimiter.RetryAfter()))
+					http.Error(w, "Too many concurrent requests", http.StatusTooManyRequests)
+					return
+				}
+				defer release()
+			}
 
 			// Store payment info in context for handler access
 			ctx := context.WithValue(r.Context(), PaymentContextKey, verifyResp)
+
+			// Deferred payments are claimed by the handler after delivery
+			deferred := payment.Accepted.Scheme == v2.SchemeDeferred
+			if deferred {
+				deferredPayment := NewDeferredPayment(*payment, *requirement, verifyResp)
+				deferredPayment.Clock = config.Clock
+				ctx = context.WithValue(ctx, DeferredPaymentContextKey, deferredPayment)
+			}
+
+			settle := func(ctx context.Context, payment v2.PaymentPayload, requirement v2.PaymentRequirements) (*v2.SettleResponse, error) {
+				ctx, cancel, err := config.PaymentContext(ctx, &payment, &requirement, received)
+				if err != nil {
+					return nil, err
+				}
+				defer cancel()
+				logger.Info("settling payment", "payer", verifyResp.Payer)
+				settlementResp, err := facilitator.Settle(ctx, payment, requirement)
+				if err != nil && fallbackFacilitator != nil && ctx.Err() == nil {
+					logger.Warn("primary facilitator settlement failed, trying fallback", "error", err)
+					settlementResp, err = fallbackFacilitator.Settle(ctx, payment, requirement)
+				}
+				return settlementResp, DeadlineError(ctx, err)
+			}
+			if config.Admin != nil {
+				settle = config.Admin.TrackSettle(settle)
+				if config.VerifyOnly || deferred {
+					config.Admin.RecordVerified(*requirement, verifyResp)
+				}
+			}
+
+			var hold *PaymentHold
+			if config.ManualCapture && !config.VerifyOnly && !deferred {
+				hold = NewPaymentHold(*payment, *requirement, verifyResp, settle, w)
+				hold.ResponseHeader = config.Headers.PaymentResponseHeader()
+				ctx = context.WithValue(ctx, HoldContextKey, hold)
+			}
 			r = r.WithContext(ctx)
 
-			interceptor := &settlementInterceptor{
-				w: w,
-				settleFunc: func() bool {
-					if config.VerifyOnly {
+			var out http.ResponseWriter = w
+			var capture *CaptureWriter
+			if config.ResponseCache != nil && !config.Metered(payment) {
+				capture = config.ResponseCache.Capture(w, r)
+				out = capture
+			}
+
+			// settleOrFail settles the payment for requirement, writing the error
+			// response if settlement fails
+			var settled *v2.SettleResponse
+			settleOrFail := func(requirement v2.PaymentRequirements) bool {
+				settlementResp := claim.Settlement()
+				var err error
+				if settlementResp != nil {
+					logger.Info("reusing settlement of retried request", "transaction", settlementResp.Transaction)
+				} else {
+					settlementResp, err = settle(r.Context(), *payment, requirement)
+				}
+				if errors.Is(err, v2.ErrAuthorizationExpired) {
+					logger.Warn("payment expired before settlement", "error", err)
+					if err := config.WritePaymentRequired(w, r, resource, requirements, checks.Reason(err)); err != nil {
+						logger.Error("failed to send payment required response", "error", err)
+					}
+					return false
+				}
+				if err != nil {
+					logger.Error("settlement failed", "error", err)
+					http.Error(w, "Payment settlement failed", http.StatusServiceUnavailable)
+					return false
+				}
+
+				if !settlementResp.Success {
+					logger.Warn("settlement unsuccessful", "reason", settlementResp.ErrorReason)
+					if err := config.WritePaymentRequired(w, r, resource, requirements, settlementResp.ErrorReason); err != nil {
+						logger.Error("failed to send payment required response", "error", err)
+					}
+					return false
+				}
+
+				logger.Info("payment settled", "transaction", settlementResp.Transaction)
+				if requirement.Scheme == v2.SchemeUpTo && settlementResp.Amount == "" {
+					settlementResp.Amount = requirement.Amount
+				}
+				claim.Settled(settlementResp)
+				settled = settlementResp
+
+				// Add the payment response header with settlement info
+				if err := helpers.SetPaymentResponseHeader(w, config.Headers.PaymentResponseHeader(), settlementResp); err != nil {
+					logger.Warn("failed to add payment response header", "error", err)
+					// Continue anyway - payment was successful
+				}
+				if capture != nil {
+					capture.Settled()
+				}
+				return true
+			}
+
+			// Metered payments are settled for what the response cost once the
+			// handler completes, so the response is buffered until then
+			if config.Metered(payment) {
+				start := v2.ClockOrSystem(config.Clock).Now()
+				metered := newMeteredWriter(w, config.maxBufferedResponseBytes(), func(status int) bool {
+					if status >= 400 {
+						logger.Warn("handler returned non-success, skipping payment settlement", "status", status)
 						return true
 					}
+					// Too large to hold until metered: charged the authorized maximum
+					return settleOrFail(*requirement)
+				})
+				next.ServeHTTP(metered, r)
+				if metered.committed {
+					return
+				}
+				usage := Usage{
+					Status:   metered.statusCode(),
+					Bytes:    int64(metered.body.Len()),
+					Duration: v2.ClockOrSystem(config.Clock).Now().Sub(start),
+				}
 
-					logger.Info("settling payment", "payer", verifyResp.Payer)
-					settlementResp, err := facilitator.Settle(r.Context(), *payment, *requirement)
-					if err != nil && fallbackFacilitator != nil {
-						logger.Warn("primary facilitator settlement failed, trying fallback", "error", err)
-						settlementResp, err = fallbackFacilitator.Settle(r.Context(), *payment, *requirement)
+				if usage.Status >= 400 {
+					logger.Warn("handler returned non-success, skipping payment settlement", "status", usage.Status)
+					metered.writeTo(w)
+					return
+				}
+				settlement, owed, err := config.MeteredRequirement(r, usage, *requirement)
+				if err != nil {
+					logger.Error("failed to meter response", "error", err)
+					http.Error(w, "Payment metering failed", http.StatusInternalServerError)
+					return
+				}
+				if !owed {
+					// The payment was used for this response, so it is not released for reuse
+					claim.Consumed()
+				} else if !settleOrFail(settlement) {
+					return
+				}
+				// The body is known before it is sent, so the delivery goes
+				// in a header
+				var delivery *Delivery
+				if config.DeliveryDigest && settled != nil {
+					sum := sha256.Sum256(metered.body.Bytes())
+					d := newDelivery(settled, resource.URL, usage.Status, sum[:], usage.Bytes)
+					if encoded, err := encodeDelivery(d); err != nil {
+						logger.Warn("failed to add payment delivery header", "error", err)
+					} else {
+						metered.header.Set(PaymentDeliveryHeader, encoded)
 					}
-					if err != nil {
-						logger.Error("settlement failed", "error", err)
-						http.Error(w, "Payment settlement failed", http.StatusServiceUnavailable)
-						return false
+					delivery = &d
+				}
+				metered.writeTo(w)
+				if delivery != nil && config.OnDelivery != nil {
+					config.OnDelivery(r.Context(), *delivery)
+				}
+				return
+			}
+
+			var digest *digestWriter
+			if config.DeliveryDigest && !config.VerifyOnly && !deferred && hold == nil {
+				declareTrailer(w, PaymentDeliveryHeader)
+				digest = newDigestWriter(out)
+				out = digest
+			}
+
+			interceptor := &settlementInterceptor{
+				w: out,
+				settleFunc: func(statusCode int) bool {
+					if config.VerifyOnly || deferred || hold != nil {
+						claim.Consumed()
+						if hold != nil && hold.State() == HoldReserved {
+							// The handler may capture after responding
+							declareTrailer(w, config.Headers.PaymentResponseHeader())
+						}
+						return true
 					}
 
-					if !settlementResp.Success {
-						logger.Warn("settlement unsuccessful", "reason", settlementResp.ErrorReason)
-						if err := helpers.SendPaymentRequired(w, resource, enrichedRequirements, settlementResp.ErrorReason); err != nil {
+					settleRevalidation, deliver := config.RevalidationOutcome(r, statusCode)
+					if !deliver {
+						logger.Info("resource changed, revalidation payment does not cover content", "status", statusCode)
+						full, err := config.FullRequirementsFor(r, enrichedRequirements)
+						if err != nil {
+							full = enrichedRequirements
+						}
+						if err := config.WritePaymentRequired(w, r, resource, full, "Resource changed; full payment required"); err != nil {
 							logger.Error("failed to send payment required response", "error", err)
 						}
 						return false
 					}
-
-					logger.Info("payment settled", "transaction", settlementResp.Transaction)
-
-					// Add X-PAYMENT-RESPONSE header with settlement info
-					if err := helpers.AddPaymentResponseHeader(w, settlementResp); err != nil {
-						logger.Warn("failed to add payment response header", "error", err)
-						// Continue anyway - payment was successful
+					if !settleRevalidation {
+						logger.Info("resource not modified, skipping payment settlement")
+						return true
 					}
-					return true
+
+					return settleOrFail(*requirement)
 				},
 				onFailure: func(statusCode int) {
 					logger.Warn("handler returned non-success, skipping payment settlement", "status", statusCode)
 				},
 			}
-			next.ServeHTTP(interceptor, r)
+			if hold != nil {
+				hold.headerWritten = func() bool { return interceptor.committed }
+			}
+			if config.BuffersResponse(r) {
+				buffered := newBufferedWriter(interceptor, config.maxBufferedResponseBytes())
+				next.ServeHTTP(buffered, r)
+				buffered.commit()
+			} else {
+				next.ServeHTTP(interceptor, r)
+			}
+
+			if digest != nil && settled != nil && !digest.hijacked {
+				delivery := digest.delivery(settled, resource.URL)
+				if encoded, err := encodeDelivery(delivery); err != nil {
+					logger.Warn("failed to add payment delivery trailer", "error", err)
+				} else {
+					w.Header().Set(http.TrailerPrefix+PaymentDeliveryHeader, encoded)
+				}
+				if config.OnDelivery != nil {
+					config.OnDelivery(r.Context(), delivery)
+				}
+			}
+
+			if capture != nil {
+				capture.Commit()
+			}
+
+			if hold != nil && hold.State() == HoldReserved {
+				logger.Warn("payment hold neither captured nor voided, voiding", "payer", verifyResp.Payer)
+				_ = hold.Void()
+			}
 		})
 	}
 }
@@ -228,18 +869,32 @@ func NewX402Middleware(config Config) func(http.Handler) http.Handler {
 // settlementInterceptor wraps the ResponseWriter to intercept the moment of commitment.
 type settlementInterceptor struct {
 	w http.ResponseWriter
-	// settleFunc is the callback that performs the actual settlement logic
-	settleFunc func() bool
+	// settleFunc is the callback that performs the actual settlement logic for a
+	// response with the given status
+	settleFunc func(statusCode int) bool
 	// onFailure is an internal logging callback
 	onFailure func(statusCode int)
+	// header, if set, holds the handler's headers until the response is
+	// committed, so a failed settlement is answered without them
+	header    http.Header
 	committed bool
 	hijacked  bool
 }
 
 func (i *settlementInterceptor) Header() http.Header {
+	if i.header != nil {
+		return i.header
+	}
 	return i.w.Header()
 }
 
+// releaseHeader copies the held handler headers to the underlying writer.
+func (i *settlementInterceptor) releaseHeader() {
+	for key, values := range i.header {
+		i.w.Header()[key] = values
+	}
+}
+
 func (i *settlementInterceptor) Write(b []byte) (int, error) {
 	// If the handler calls Write without WriteHeader, it implies 200 OK.
 	// We must trigger our check now.
@@ -268,13 +923,14 @@ func (i *settlementInterceptor) WriteHeader(statusCode int) {
 		if i.onFailure != nil {
 			i.onFailure(statusCode)
 		}
+		i.releaseHeader()
 		i.w.WriteHeader(statusCode)
 		return
 	}
 
 	// Case 2: Handler wants to succeed. STOP!
 	// We run the settlement logic now.
-	if !i.settleFunc() {
+	if !i.settleFunc(statusCode) {
 		// Settlement failed. We mark as hijacked.
 		// The settleFunc has already written the 402/503 error to the underlying writer.
 		i.hijacked = true
@@ -284,6 +940,7 @@ func (i *settlementInterceptor) WriteHeader(statusCode int) {
 	// Case 3: Settlement succeeded.
 	// The settleFunc has already added the X-PAYMENT-RESPONSE headers.
 	// We now allow the original status code to proceed.
+	i.releaseHeader()
 	i.w.WriteHeader(statusCode)
 }
 
@@ -301,10 +958,11 @@ func (i *settlementInterceptor) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 		if !i.committed {
 			// Treat hijack as a successful upgrade path; settle first.
 			i.committed = true
-			if !i.settleFunc() {
+			if !i.settleFunc(http.StatusSwitchingProtocols) {
 				i.hijacked = true
 				return nil, nil, errors.New("payment settlement failed")
 			}
+			i.releaseHeader()
 		}
 		return hijacker.Hijack()
 	}
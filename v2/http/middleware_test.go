@@ -1,13 +1,21 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	v2 "github.com/mark3labs/x402-go/v2"
 	"github.com/mark3labs/x402-go/v2/encoding"
+	"github.com/mark3labs/x402-go/v2/extensions"
+	"github.com/mark3labs/x402-go/v2/quote"
 )
 
 func TestMiddleware_NoPaymentHeader(t *testing.T) {
@@ -435,3 +443,872 @@ func TestGetPaymentFromContext_NoPayment(t *testing.T) {
 		t.Error("Expected nil for context without payment")
 	}
 }
+
+func TestMiddleware_AuthorizationWindow(t *testing.T) {
+	// The facilitator must not be asked to verify a payment rejected locally
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/supported" {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{
+				Kinds: []v2.SupportedKind{{X402Version: 2, Scheme: "exact", Network: "eip155:84532"}},
+			})
+			return
+		}
+		t.Errorf("Unexpected facilitator call: %s %s", r.Method, r.URL.Path)
+	}))
+	defer facilitatorServer.Close()
+
+	config := Config{
+		FacilitatorURL: facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{
+			{
+				Scheme:            "exact",
+				Network:           "eip155:84532",
+				Amount:            "10000",
+				Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+				PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+				MaxTimeoutSeconds: 60,
+			},
+		},
+		CheckAuthorizationWindow: true,
+		ClockSkew:                5 * time.Second,
+	}
+
+	handler := NewX402Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called with an expired authorization")
+	}))
+
+	now := time.Now().Unix()
+	payment := v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532"},
+		Payload: v2.EVMPayload{
+			Signature: "0xsig",
+			Authorization: v2.EVMAuthorization{
+				ValidAfter:  strconv.FormatInt(now-120, 10),
+				ValidBefore: strconv.FormatInt(now-60, 10),
+			},
+		},
+	}
+	paymentHeader, _ := encoding.EncodePayment(payment)
+
+	req := httptest.NewRequest("GET", "/api/data", nil)
+	req.Header.Set("X-PAYMENT", paymentHeader)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPaymentRequired {
+		t.Errorf("Expected status 402, got %d", w.Code)
+	}
+}
+
+func TestMiddleware_RejectExpired(t *testing.T) {
+	// The facilitator must not be asked to verify an expired payment
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/supported" {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{
+				Kinds: []v2.SupportedKind{{X402Version: 2, Scheme: "exact", Network: "eip155:84532"}},
+			})
+			return
+		}
+		t.Errorf("Unexpected facilitator call: %s %s", r.Method, r.URL.Path)
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	config := Config{
+		FacilitatorURL:      facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{requirement},
+		RejectExpired:       true,
+		ClockSkew:           5 * time.Second,
+	}
+
+	handler := NewX402Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called with an expired authorization")
+	}))
+
+	now := time.Now().Unix()
+	paymentHeader, _ := encoding.EncodePayment(v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    requirement,
+		Payload: v2.EVMPayload{
+			Signature: "0xsig",
+			Authorization: v2.EVMAuthorization{
+				ValidAfter:  strconv.FormatInt(now-120, 10),
+				ValidBefore: strconv.FormatInt(now-60, 10),
+			},
+		},
+	})
+
+	req := httptest.NewRequest("GET", "/api/data", nil)
+	req.Header.Set("X-PAYMENT", paymentHeader)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPaymentRequired {
+		t.Fatalf("Expected status 402, got %d", w.Code)
+	}
+	var response v2.PaymentRequired
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !strings.HasPrefix(response.Error, string(v2.ErrCodeAuthorizationExpired)+":") {
+		t.Errorf("Expected an AUTHORIZATION_EXPIRED error, got %q", response.Error)
+	}
+}
+
+func TestConfig_MinAmounts(t *testing.T) {
+	const asset = "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
+	requirement := func(amount string) v2.PaymentRequirements {
+		return v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Asset: asset, Amount: amount}
+	}
+
+	tests := []struct {
+		name         string
+		minimums     v2.MinimumAmounts
+		requirements []v2.PaymentRequirements
+		wantErr      error
+	}{
+		{name: "no floor", requirements: []v2.PaymentRequirements{requirement("1")}},
+		{name: "above floor", minimums: v2.MinimumAmounts{asset: "1000"}, requirements: []v2.PaymentRequirements{requirement("10000")}},
+		{name: "below floor", minimums: v2.MinimumAmounts{asset: "1000"}, requirements: []v2.PaymentRequirements{requirement("10000"), requirement("10")}, wantErr: v2.ErrBelowMinimum},
+		{name: "invalid floor", minimums: v2.MinimumAmounts{asset: "-5"}, wantErr: v2.ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{MinAmounts: tt.minimums, PaymentRequirements: tt.requirements}
+			err := config.Validate()
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Requirements computed per request are checked when paid
+	config := Config{MinAmounts: v2.MinimumAmounts{asset: "1000"}}
+	dust := requirement("10")
+	payment := &v2.PaymentPayload{Accepted: dust}
+	if err := config.CheckPrice(payment, &dust); !errors.Is(err, v2.ErrBelowMinimum) {
+		t.Errorf("expected payment below floor to be refused, got %v", err)
+	}
+}
+
+func TestMiddleware_StrictMatching(t *testing.T) {
+	// The facilitator must not be asked to verify a mismatched payment
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/supported" {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{
+				Kinds: []v2.SupportedKind{{X402Version: 2, Scheme: "exact", Network: "eip155:84532"}},
+			})
+			return
+		}
+		t.Errorf("Unexpected facilitator call: %s %s", r.Method, r.URL.Path)
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	config := Config{
+		FacilitatorURL:      facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{requirement},
+		StrictMatching:      true,
+	}
+
+	handler := NewX402Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called with a mismatched payment")
+	}))
+
+	// The accepted requirement is copied, but the signed authorization pays someone else
+	payment := v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    requirement,
+		Payload: v2.EVMPayload{
+			Signature: "0xsig",
+			Authorization: v2.EVMAuthorization{
+				From:        "0x857b06519E91e3A54538791bDbb0E22373e36b66",
+				To:          "0x857b06519E91e3A54538791bDbb0E22373e36b66",
+				Value:       "10000",
+				ValidBefore: strconv.FormatInt(time.Now().Unix()+60, 10),
+			},
+		},
+	}
+	paymentHeader, _ := encoding.EncodePayment(payment)
+
+	req := httptest.NewRequest("GET", "/api/data", nil)
+	req.Header.Set("X-PAYMENT", paymentHeader)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPaymentRequired {
+		t.Fatalf("Expected status 402, got %d", w.Code)
+	}
+	var response v2.PaymentRequired
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Invalid body: %v", err)
+	}
+	if !strings.HasPrefix(response.Error, "payload_recipient_mismatch") {
+		t.Errorf("Expected recipient mismatch, got %q", response.Error)
+	}
+}
+
+func TestMiddleware_PriceQuote(t *testing.T) {
+	var verifiedAmount string
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{
+				Kinds: []v2.SupportedKind{{X402Version: 2, Scheme: "exact", Network: "eip155:84532"}},
+			})
+		case "/verify":
+			var body struct {
+				PaymentRequirements v2.PaymentRequirements `json:"paymentRequirements"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			verifiedAmount = body.PaymentRequirements.Amount
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	issuer, err := quote.NewIssuer([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewIssuer failed: %v", err)
+	}
+
+	// The live price changes between the 402 response and the payment
+	price := "10000"
+	config := Config{
+		FacilitatorURL: facilitatorServer.URL,
+		Resource:       v2.ResourceInfo{URL: "https://example.com/api/data"},
+		PaymentRequirements: []v2.PaymentRequirements{{
+			Scheme:            "exact",
+			Network:           "eip155:84532",
+			Amount:            "10000",
+			Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+			PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+			MaxTimeoutSeconds: 60,
+		}},
+		RequirementsFunc: func(r *http.Request, base []v2.PaymentRequirements) ([]v2.PaymentRequirements, error) {
+			requirements := append([]v2.PaymentRequirements(nil), base...)
+			requirements[0].Amount = price
+			if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+				requirements[0].Amount = "1"
+				requirements[0].Extra = map[string]interface{}{"range": rangeHeader}
+			}
+			return requirements, nil
+		},
+		Quotes: issuer,
+	}
+	handler := NewX402Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/data", nil))
+	var required v2.PaymentRequired
+	if err := json.Unmarshal(w.Body.Bytes(), &required); err != nil {
+		t.Fatalf("Invalid 402 body: %v", err)
+	}
+	if _, ok := required.Extensions[v2.PriceQuoteExtension]; !ok {
+		t.Fatal("Expected a price quote in the 402 response")
+	}
+
+	price = "20000"
+
+	send := func(payment v2.PaymentPayload) *httptest.ResponseRecorder {
+		paymentHeader, _ := encoding.EncodePayment(payment)
+		req := httptest.NewRequest("GET", "/api/data", nil)
+		req.Header.Set("X-PAYMENT", paymentHeader)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	payment := v2.PaymentPayload{X402Version: 2, Accepted: required.Accepts[0], Payload: map[string]interface{}{"signature": "0xsig"}}
+	if w := send(payment); w.Code != http.StatusPaymentRequired {
+		t.Errorf("Expected 402 without the quote after the price change, got %d", w.Code)
+	}
+
+	quote.Echo(required, &payment)
+	if w := send(payment); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with the quote, got %d: %s", w.Code, w.Body.String())
+	}
+	if verifiedAmount != "10000" {
+		t.Errorf("Expected the quoted amount to be verified, got %s", verifiedAmount)
+	}
+
+	// A quote for a range does not pay for the whole resource
+	rangeRequest := httptest.NewRequest("GET", "/api/data", nil)
+	rangeRequest.Header.Set("Range", "bytes=0-0")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, rangeRequest)
+	var rangeRequired v2.PaymentRequired
+	if err := json.Unmarshal(w.Body.Bytes(), &rangeRequired); err != nil {
+		t.Fatalf("Invalid 402 body: %v", err)
+	}
+	rangePayment := v2.PaymentPayload{X402Version: 2, Accepted: rangeRequired.Accepts[0], Payload: map[string]interface{}{"signature": "0xsig2"}}
+	quote.Echo(rangeRequired, &rangePayment)
+	if w := send(rangePayment); w.Code != http.StatusPaymentRequired {
+		t.Errorf("Expected 402 for a range quote on a full request, got %d", w.Code)
+	}
+}
+
+func TestMiddleware_DeferredPayment(t *testing.T) {
+	settleCalls := 0
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{
+				Kinds: []v2.SupportedKind{{X402Version: 2, Scheme: v2.SchemeDeferred, Network: "eip155:84532"}},
+			})
+		case "/verify":
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			settleCalls++
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532"})
+		default:
+			t.Errorf("Unexpected facilitator call: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            v2.SchemeDeferred,
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 3600,
+	}
+	middleware := NewX402Middleware(Config{
+		FacilitatorURL:      facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{requirement},
+	})
+
+	deadline := time.Now().Add(time.Hour).Unix()
+	var deferred *DeferredPayment
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deferred = GetDeferredPaymentFromContext(r.Context())
+		_, _ = w.Write([]byte("OK"))
+	}))
+
+	payment := v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    requirement,
+		Payload: v2.EVMPayload{
+			Signature: "0xsig",
+			Authorization: v2.EVMAuthorization{
+				ValidAfter:  strconv.FormatInt(time.Now().Unix()-10, 10),
+				ValidBefore: strconv.FormatInt(deadline, 10),
+			},
+		},
+	}
+	paymentHeader, _ := encoding.EncodePayment(payment)
+
+	req := httptest.NewRequest("GET", "/api/data", nil)
+	req.Header.Set("X-PAYMENT", paymentHeader)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if settleCalls != 0 {
+		t.Errorf("Expected no inline settlement, got %d settle calls", settleCalls)
+	}
+	if w.Header().Get("X-PAYMENT-RESPONSE") != "" {
+		t.Error("Expected no X-PAYMENT-RESPONSE header for deferred payment")
+	}
+	if deferred == nil {
+		t.Fatal("Expected deferred payment in context")
+	}
+	if deferred.Payer != "0xPayerAddress" || deferred.ClaimDeadline.Unix() != deadline {
+		t.Errorf("Unexpected deferred payment %+v", deferred)
+	}
+
+	resp, err := deferred.Claim(req.Context(), &FacilitatorClient{BaseURL: facilitatorServer.URL})
+	if err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+	if resp.Transaction != "0xabc" || settleCalls != 1 {
+		t.Errorf("Expected one settlement with transaction 0xabc, got %q (%d calls)", resp.Transaction, settleCalls)
+	}
+
+	expired := *deferred
+	expired.ClaimDeadline = time.Now().Add(-time.Second)
+	if _, err := expired.Claim(req.Context(), &FacilitatorClient{BaseURL: facilitatorServer.URL}); err == nil {
+		t.Error("Expected error claiming after the deadline")
+	}
+}
+
+func TestMiddleware_ManualCapture(t *testing.T) {
+	settleCalls := 0
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+		case "/verify":
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			settleCalls++
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532"})
+		default:
+			t.Errorf("Unexpected facilitator call: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	middleware := NewX402Middleware(Config{
+		FacilitatorURL:      facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{requirement},
+		ManualCapture:       true,
+	})
+	paymentHeader, _ := encoding.EncodePayment(v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    requirement,
+		Payload:     map[string]interface{}{"signature": "0xsig"},
+	})
+
+	tests := []struct {
+		name        string
+		action      func(t *testing.T, hold *PaymentHold)
+		wantSettles int
+		wantState   HoldState
+		wantHeader  bool
+	}{
+		{
+			name: "capture",
+			action: func(t *testing.T, hold *PaymentHold) {
+				if _, err := hold.Capture(context.Background()); err != nil {
+					t.Errorf("Capture failed: %v", err)
+				}
+				// Capturing twice does not settle twice
+				if _, err := hold.Capture(context.Background()); err != nil {
+					t.Errorf("second Capture failed: %v", err)
+				}
+				if err := hold.Void(); !errors.Is(err, ErrHoldCaptured) {
+					t.Errorf("Expected ErrHoldCaptured, got %v", err)
+				}
+			},
+			wantSettles: 1,
+			wantState:   HoldCaptured,
+			wantHeader:  true,
+		},
+		{
+			name: "void",
+			action: func(t *testing.T, hold *PaymentHold) {
+				if err := hold.Void(); err != nil {
+					t.Errorf("Void failed: %v", err)
+				}
+				if _, err := hold.Capture(context.Background()); !errors.Is(err, ErrHoldVoided) {
+					t.Errorf("Expected ErrHoldVoided, got %v", err)
+				}
+			},
+			wantState: HoldVoided,
+		},
+		{
+			name:      "neither captured nor voided",
+			action:    func(t *testing.T, hold *PaymentHold) {},
+			wantState: HoldVoided,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settleCalls = 0
+			var hold *PaymentHold
+			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hold = GetPaymentHoldFromContext(r.Context())
+				if hold == nil {
+					t.Fatal("Expected payment hold in context")
+				}
+				tt.action(t, hold)
+				_, _ = w.Write([]byte("OK"))
+			}))
+
+			req := httptest.NewRequest("GET", "/api/data", nil)
+			req.Header.Set("X-PAYMENT", paymentHeader)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", w.Code)
+			}
+			if settleCalls != tt.wantSettles {
+				t.Errorf("Expected %d settle calls, got %d", tt.wantSettles, settleCalls)
+			}
+			if hold.State() != tt.wantState {
+				t.Errorf("Expected state %s, got %s", tt.wantState, hold.State())
+			}
+			if got := w.Header().Get("X-PAYMENT-RESPONSE") != ""; got != tt.wantHeader {
+				t.Errorf("Expected X-PAYMENT-RESPONSE present=%v, got %v", tt.wantHeader, got)
+			}
+		})
+	}
+
+	// Holds would settle payments that must only be verified
+	verifyOnly := Config{
+		FacilitatorURL:      facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{requirement},
+		ManualCapture:       true,
+		VerifyOnly:          true,
+	}
+	if err := verifyOnly.Validate(); !errors.Is(err, ErrCaptureVerifyOnly) {
+		t.Errorf("Expected ErrCaptureVerifyOnly, got %v", err)
+	}
+	// Other configuration errors are still reported alongside it
+	invalid := verifyOnly
+	invalid.MinAmounts = v2.MinimumAmounts{requirement.Asset: "-5"}
+	if err := invalid.Validate(); !errors.Is(err, ErrCaptureVerifyOnly) || !errors.Is(err, v2.ErrInvalidAmount) {
+		t.Errorf("Expected ErrCaptureVerifyOnly and ErrInvalidAmount, got %v", err)
+	}
+	settleCalls = 0
+	handler := NewX402Middleware(verifyOnly)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hold := GetPaymentHoldFromContext(r.Context()); hold != nil {
+			_, _ = hold.Capture(r.Context())
+			t.Error("Expected no payment hold in verify-only mode")
+		}
+	}))
+	req := httptest.NewRequest("GET", "/api/data", nil)
+	req.Header.Set("X-PAYMENT", paymentHeader)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if settleCalls != 0 {
+		t.Errorf("Expected no settlement in verify-only mode, got %d", settleCalls)
+	}
+}
+
+func TestMiddleware_RevenueSplits(t *testing.T) {
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v2.SupportedResponse{
+			Kinds:      []v2.SupportedKind{{X402Version: 2, Scheme: "exact", Network: "eip155:84532"}},
+			Extensions: []string{v2.RevenueSplitExtension},
+		})
+	}))
+	defer facilitatorServer.Close()
+
+	platform := "0x1111111111111111111111111111111111111111"
+	config := Config{
+		FacilitatorURL: facilitatorServer.URL,
+		Resource:       v2.ResourceInfo{URL: "https://example.com/api/data"},
+		PaymentRequirements: []v2.PaymentRequirements{
+			{
+				Scheme:            "exact",
+				Network:           "eip155:84532",
+				Amount:            "10000",
+				Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+				PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+				MaxTimeoutSeconds: 60,
+			},
+		},
+		RevenueSplits: []v2.Split{{Recipient: platform, Bps: 500}},
+	}
+
+	handler := NewX402Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called without payment")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/data", nil))
+	if rec.Code != http.StatusPaymentRequired {
+		t.Fatalf("expected status 402, got %d", rec.Code)
+	}
+
+	var paymentRequired v2.PaymentRequired
+	if err := json.Unmarshal(rec.Body.Bytes(), &paymentRequired); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	payouts, err := v2.ComputePayouts(paymentRequired.Accepts[0])
+	if err != nil {
+		t.Fatalf("ComputePayouts() error = %v", err)
+	}
+	if len(payouts) != 2 || payouts[1].Recipient != platform || payouts[1].Amount != "500" || payouts[0].Amount != "9500" {
+		t.Errorf("unexpected payouts: %+v", payouts)
+	}
+
+	// The configured requirements must not be mutated
+	if _, ok := config.PaymentRequirements[0].Extra[v2.SplitExtraKey]; ok {
+		t.Error("expected config requirements to be left untouched")
+	}
+}
+
+func TestMiddleware_Extensions(t *testing.T) {
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/supported" {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{
+				Kinds: []v2.SupportedKind{{X402Version: 2, Scheme: "exact", Network: "eip155:84532"}},
+			})
+			return
+		}
+		t.Errorf("Unexpected facilitator call: %s %s", r.Method, r.URL.Path)
+	}))
+	defer facilitatorServer.Close()
+
+	registry, err := extensions.NewRegistry(
+		extensions.Definition{Name: "terms", Info: map[string]interface{}{"url": "https://example.com/terms"}},
+		extensions.Definition{
+			Name:     "buyer",
+			Schema:   map[string]interface{}{"type": "object", "required": []string{"id"}},
+			Required: true,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	handler := NewX402Middleware(Config{
+		FacilitatorURL:      facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{requirement},
+		Extensions:          registry,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called without the required extension")
+	}))
+
+	tests := []struct {
+		name       string
+		extensions map[string]v2.Extension
+		wantError  string
+	}{
+		{"no payment", nil, "Payment required"},
+		{"missing extension", map[string]v2.Extension{}, "extensions: missing required extension: buyer"},
+		{"invalid extension", map[string]v2.Extension{"buyer": {Info: map[string]interface{}{"name": "x"}}}, "extension buyer: extensions: invalid extension: info.id is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/data", nil)
+			if tt.extensions != nil {
+				paymentHeader, _ := encoding.EncodePayment(v2.PaymentPayload{X402Version: 2, Accepted: requirement, Payload: map[string]interface{}{}, Extensions: tt.extensions})
+				req.Header.Set("X-PAYMENT", paymentHeader)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusPaymentRequired {
+				t.Fatalf("Expected status 402, got %d", w.Code)
+			}
+			var response v2.PaymentRequired
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Invalid body: %v", err)
+			}
+			if response.Error != tt.wantError {
+				t.Errorf("Expected error %q, got %q", tt.wantError, response.Error)
+			}
+			if terms, ok := response.Extensions["terms"]; !ok || terms.Info["url"] != "https://example.com/terms" {
+				t.Errorf("Expected advertised terms extension, got %+v", response.Extensions)
+			}
+		})
+	}
+}
+
+func TestMiddleware_BufferResponses(t *testing.T) {
+	settleSucceeds := true
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{
+				Kinds: []v2.SupportedKind{{X402Version: 2, Scheme: "exact", Network: "eip155:84532"}},
+			})
+		case "/verify":
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			if !settleSucceeds {
+				_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: false, ErrorReason: "insufficient_funds"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532"})
+		default:
+			t.Errorf("Unexpected facilitator call: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Handler", "1")
+		_, _ = w.Write([]byte("hello "))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte("world"))
+	})
+
+	tests := []struct {
+		name           string
+		settleSucceeds bool
+		maxBytes       int64
+		buffer         func(r *http.Request) bool
+		wantStatus     int
+		wantBody       string
+		wantHandler    bool
+	}{
+		{"settled", true, 0, nil, http.StatusOK, "hello world", true},
+		{"settlement failure returns clean 402", false, 0, nil, http.StatusPaymentRequired, "", false},
+		{"oversized response streams", true, 8, nil, http.StatusOK, "hello world", true},
+		{"oversized response settlement failure", false, 8, nil, http.StatusPaymentRequired, "", false},
+		{"route not buffered", false, 0, func(r *http.Request) bool { return r.URL.Path != "/api/data" }, http.StatusPaymentRequired, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settleSucceeds = tt.settleSucceeds
+			middleware := NewX402Middleware(Config{
+				FacilitatorURL:           facilitatorServer.URL,
+				PaymentRequirements:      []v2.PaymentRequirements{requirement},
+				BufferResponses:          true,
+				BufferResponsesFor:       tt.buffer,
+				MaxBufferedResponseBytes: tt.maxBytes,
+			})(handler)
+
+			req := httptest.NewRequest("GET", "/api/data", nil)
+			paymentHeader, _ := encoding.EncodePayment(v2.PaymentPayload{X402Version: 2, Accepted: requirement, Payload: map[string]interface{}{}})
+			req.Header.Set("X-PAYMENT", paymentHeader)
+			w := httptest.NewRecorder()
+			middleware.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("X-Handler") != ""; got != tt.wantHandler {
+				t.Errorf("Expected handler header %v, got %v", tt.wantHandler, got)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if w.Body.String() != tt.wantBody {
+					t.Errorf("Expected body %q, got %q", tt.wantBody, w.Body.String())
+				}
+				if w.Header().Get("X-PAYMENT-RESPONSE") == "" {
+					t.Error("Expected X-PAYMENT-RESPONSE header")
+				}
+			}
+		})
+	}
+}
+
+func TestMiddleware_DeliveryDigest(t *testing.T) {
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{
+				Kinds: []v2.SupportedKind{{X402Version: 2, Scheme: "exact", Network: "eip155:84532"}},
+			})
+		case "/verify":
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532", Payer: "0xPayerAddress"})
+		default:
+			t.Errorf("Unexpected facilitator call: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	var delivered []Delivery
+	server := httptest.NewServer(NewX402Middleware(Config{
+		FacilitatorURL:      facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{requirement},
+		DeliveryDigest:      true,
+		OnDelivery: func(ctx context.Context, delivery Delivery) {
+			delivered = append(delivered, delivery)
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("paid "))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte("content"))
+	})))
+	defer server.Close()
+
+	paymentHeader, _ := encoding.EncodePayment(v2.PaymentPayload{X402Version: 2, Accepted: requirement, Payload: map[string]interface{}{}})
+	get := func(path string) (*http.Response, []byte) {
+		req, _ := http.NewRequest("GET", server.URL+path, nil)
+		req.Header.Set("X-PAYMENT", paymentHeader)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body
+	}
+
+	resp, body := get("/data")
+	delivery := GetDelivery(resp)
+	if delivery == nil {
+		t.Fatal("Expected X-PAYMENT-DELIVERY trailer")
+	}
+	if !delivery.Matches(body) || delivery.ContentLength != int64(len("paid content")) {
+		t.Errorf("Delivery %+v does not match body %q", delivery, body)
+	}
+	if delivery.Transaction != "0xabc" || delivery.Status != http.StatusOK || !strings.HasSuffix(delivery.Resource, "/data") {
+		t.Errorf("Unexpected delivery %+v", delivery)
+	}
+	if delivery.Matches([]byte("other content")) {
+		t.Error("Expected delivery not to match other content")
+	}
+	if len(delivered) != 1 || delivered[0] != *delivery {
+		t.Errorf("Expected OnDelivery to receive %+v, got %+v", delivery, delivered)
+	}
+
+	// Unsettled responses have no delivery
+	resp, _ = get("/missing")
+	if resp.StatusCode != http.StatusNotFound || GetDelivery(resp) != nil || len(delivered) != 1 {
+		t.Errorf("Expected no delivery for unsettled response, got %+v", GetDelivery(resp))
+	}
+}
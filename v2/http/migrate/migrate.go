@@ -0,0 +1,211 @@
+// Package migrate converts v1 middleware configuration to v2.
+//
+// Config and Requirements translate v1 networks to CAIP-2, move the resource
+// fields of v1 requirements into a v2.ResourceInfo, rename MaxAmountRequired to
+// Amount and copy Extra. Every difference is reported as a Change; changes
+// marked Lossy need a manual decision before the v2 configuration is
+// equivalent. The x402-migrate command wraps it for JSON route tables.
+package migrate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	x402 "github.com/mark3labs/x402-go"
+	x402http "github.com/mark3labs/x402-go/http"
+	v2 "github.com/mark3labs/x402-go/v2"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+)
+
+// networks maps v1 network names to CAIP-2 identifiers.
+var networks = map[string]string{
+	"base":           v2.NetworkBase,
+	"base-sepolia":   v2.NetworkBaseSepolia,
+	"polygon":        v2.NetworkPolygon,
+	"polygon-amoy":   v2.NetworkPolygonAmoy,
+	"avalanche":      v2.NetworkAvalanche,
+	"avalanche-fuji": v2.NetworkAvalancheFuji,
+	"ethereum":       v2.NetworkEthereum,
+	"sepolia":        v2.NetworkSepolia,
+	"solana":         v2.NetworkSolanaMainnet,
+	"solana-devnet":  v2.NetworkSolanaDevnet,
+}
+
+// Change is one difference between a v1 configuration and its v2 translation.
+type Change struct {
+	// Index is the position of the requirement in the v1 slice, or -1 for
+	// fields of the Config itself.
+	Index int
+
+	// Field is the v1 field name, like "Network" or "FacilitatorOnBeforeVerify".
+	Field string
+
+	// From is the v1 value and To the v2 value, empty when dropped.
+	From, To string
+
+	// Lossy is set when the field does not translate exactly and the v2
+	// configuration needs review.
+	Lossy bool
+
+	// Note explains the change.
+	Note string
+}
+
+// String formats the change as a diff line, prefixed with "!" when lossy.
+func (c Change) String() string {
+	prefix := " "
+	if c.Lossy {
+		prefix = "!"
+	}
+	location := "config"
+	if c.Index >= 0 {
+		location = fmt.Sprintf("requirement[%d]", c.Index)
+	}
+	line := fmt.Sprintf("%s %s.%s: %q -> %q", prefix, location, c.Field, c.From, c.To)
+	if c.Note != "" {
+		line += " (" + c.Note + ")"
+	}
+	return line
+}
+
+// Lossy returns the changes that need review.
+func Lossy(changes []Change) []Change {
+	var lossy []Change
+	for _, c := range changes {
+		if c.Lossy {
+			lossy = append(lossy, c)
+		}
+	}
+	return lossy
+}
+
+// Format returns changes as a diff, one change per line.
+func Format(changes []Change) string {
+	var b strings.Builder
+	for _, c := range changes {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Network returns the CAIP-2 identifier of a v1 network name. CAIP-2
+// identifiers are returned unchanged.
+func Network(network string) (string, error) {
+	if caip2, ok := networks[network]; ok {
+		return caip2, nil
+	}
+	if _, err := v2.ValidateNetwork(network); err == nil {
+		return network, nil
+	}
+	return "", fmt.Errorf("%w: %s", v2.ErrInvalidNetwork, network)
+}
+
+// Requirements converts v1 requirements to v2. The resource description and
+// MIME type of the first requirement become the ResourceInfo; other
+// requirements describing the resource differently are reported as lossy,
+// since v2 describes a resource once. The resource URL is dropped, as the v1
+// middleware always replaced it with the request URL and the v2 middleware
+// does the same when ResourceInfo.URL is empty.
+func Requirements(requirements []x402.PaymentRequirement) ([]v2.PaymentRequirements, v2.ResourceInfo, []Change, error) {
+	var (
+		accepts  = make([]v2.PaymentRequirements, 0, len(requirements))
+		resource v2.ResourceInfo
+		changes  []Change
+	)
+	for i, req := range requirements {
+		network, err := Network(req.Network)
+		if err != nil {
+			return nil, v2.ResourceInfo{}, nil, fmt.Errorf("requirement %d: %w", i, err)
+		}
+		if network != req.Network {
+			changes = append(changes, Change{Index: i, Field: "Network", From: req.Network, To: network, Note: "CAIP-2"})
+		}
+
+		if req.Resource != "" {
+			changes = append(changes, Change{Index: i, Field: "Resource", From: req.Resource, Note: "v2 uses the request URL"})
+		}
+		if i == 0 {
+			resource = v2.ResourceInfo{Description: req.Description, MimeType: req.MimeType}
+		} else {
+			if req.Description != resource.Description {
+				changes = append(changes, Change{Index: i, Field: "Description", From: req.Description, To: resource.Description, Lossy: true, Note: "v2 has one description per resource"})
+			}
+			if req.MimeType != resource.MimeType {
+				changes = append(changes, Change{Index: i, Field: "MimeType", From: req.MimeType, To: resource.MimeType, Lossy: true, Note: "v2 has one MIME type per resource"})
+			}
+		}
+		if req.OutputSchema != nil {
+			changes = append(changes, Change{Index: i, Field: "OutputSchema", From: "set", Lossy: true, Note: "v2 requirements have no output schema; describe it in the OpenAPI document"})
+		}
+
+		accepts = append(accepts, v2.PaymentRequirements{
+			Scheme:            req.Scheme,
+			Network:           network,
+			Amount:            req.MaxAmountRequired,
+			Asset:             req.Asset,
+			PayTo:             req.PayTo,
+			MaxTimeoutSeconds: req.MaxTimeoutSeconds,
+			Extra:             copyExtra(req.Extra),
+		})
+	}
+	return accepts, resource, changes, nil
+}
+
+// Config converts a v1 middleware configuration to v2. Facilitator hooks take
+// v1 types and are not carried over; each one set is reported as lossy.
+func Config(config x402http.Config) (v2http.Config, []Change, error) {
+	accepts, resource, changes, err := Requirements(config.PaymentRequirements)
+	if err != nil {
+		return v2http.Config{}, nil, err
+	}
+
+	migrated := v2http.Config{
+		FacilitatorURL:                   config.FacilitatorURL,
+		FallbackFacilitatorURL:           config.FallbackFacilitatorURL,
+		Resource:                         resource,
+		PaymentRequirements:              accepts,
+		VerifyOnly:                       config.VerifyOnly,
+		FacilitatorAuthorization:         config.FacilitatorAuthorization,
+		FallbackFacilitatorAuthorization: config.FallbackFacilitatorAuthorization,
+	}
+	if config.FacilitatorAuthorizationProvider != nil {
+		migrated.FacilitatorAuthorizationProvider = v2http.AuthorizationProvider(config.FacilitatorAuthorizationProvider)
+	}
+	if config.FallbackFacilitatorAuthorizationProvider != nil {
+		migrated.FallbackFacilitatorAuthorizationProvider = v2http.AuthorizationProvider(config.FallbackFacilitatorAuthorizationProvider)
+	}
+
+	hooks := []struct {
+		field string
+		hook  any
+	}{
+		{"FacilitatorOnBeforeVerify", config.FacilitatorOnBeforeVerify},
+		{"FacilitatorOnAfterVerify", config.FacilitatorOnAfterVerify},
+		{"FacilitatorOnBeforeSettle", config.FacilitatorOnBeforeSettle},
+		{"FacilitatorOnAfterSettle", config.FacilitatorOnAfterSettle},
+		{"FallbackFacilitatorOnBeforeVerify", config.FallbackFacilitatorOnBeforeVerify},
+		{"FallbackFacilitatorOnAfterVerify", config.FallbackFacilitatorOnAfterVerify},
+		{"FallbackFacilitatorOnBeforeSettle", config.FallbackFacilitatorOnBeforeSettle},
+		{"FallbackFacilitatorOnAfterSettle", config.FallbackFacilitatorOnAfterSettle},
+	}
+	for _, h := range hooks {
+		if !reflect.ValueOf(h.hook).IsNil() {
+			changes = append(changes, Change{Index: -1, Field: h.field, From: "set", Lossy: true, Note: "hooks take v1 types; port to the v2 signature"})
+		}
+	}
+	return migrated, changes, nil
+}
+
+// copyExtra returns a copy of extra, or nil if it is empty.
+func copyExtra(extra map[string]interface{}) map[string]interface{} {
+	if len(extra) == 0 {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(extra))
+	for k, v := range extra {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,122 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	x402 "github.com/mark3labs/x402-go"
+	x402http "github.com/mark3labs/x402-go/http"
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func TestNetwork(t *testing.T) {
+	tests := []struct {
+		network string
+		want    string
+		wantErr bool
+	}{
+		{network: "base", want: v2.NetworkBase},
+		{network: "base-sepolia", want: v2.NetworkBaseSepolia},
+		{network: "solana-devnet", want: v2.NetworkSolanaDevnet},
+		{network: "eip155:137", want: "eip155:137"},
+		{network: "dogecoin", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			got, err := Network(tt.network)
+			if tt.wantErr {
+				if !errors.Is(err, v2.ErrInvalidNetwork) {
+					t.Errorf("Expected ErrInvalidNetwork, got %v", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Network(%q) = %q, %v; want %q", tt.network, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+	config := x402http.Config{
+		FacilitatorURL:           "https://facilitator.example",
+		VerifyOnly:               true,
+		FacilitatorAuthorization: "Bearer key",
+		FacilitatorOnBeforeVerify: func(context.Context, x402.PaymentPayload, x402.PaymentRequirement) error {
+			return nil
+		},
+		FallbackFacilitatorAuthorizationProvider: func(*http.Request) string { return "Bearer fallback" },
+		PaymentRequirements: []x402.PaymentRequirement{
+			{
+				Scheme:            "exact",
+				Network:           "base",
+				MaxAmountRequired: "10000",
+				Asset:             "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+				PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+				Resource:          "https://api.example/data",
+				Description:       "Data",
+				MimeType:          "application/json",
+				MaxTimeoutSeconds: 60,
+				Extra:             map[string]interface{}{"name": "USD Coin", "version": "2"},
+			},
+			{
+				Scheme:            "exact",
+				Network:           "solana",
+				MaxAmountRequired: "10000",
+				Asset:             "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v",
+				PayTo:             "DRpbCBMxVnDK7maPM5tGv6MvB3v1sRMC86PZ8okm21hy",
+				Description:       "Other data",
+				MimeType:          "application/json",
+				MaxTimeoutSeconds: 60,
+				OutputSchema:      &x402.OutputSchema{},
+			},
+		},
+	}
+
+	migrated, changes, err := Config(config)
+	if err != nil {
+		t.Fatalf("Config failed: %v", err)
+	}
+
+	if migrated.FacilitatorURL != config.FacilitatorURL || !migrated.VerifyOnly || migrated.FacilitatorAuthorization != "Bearer key" {
+		t.Errorf("Facilitator settings not carried over: %+v", migrated)
+	}
+	if migrated.FallbackFacilitatorAuthorizationProvider == nil || migrated.FallbackFacilitatorAuthorizationProvider(nil) != "Bearer fallback" {
+		t.Error("Expected the fallback authorization provider to be carried over")
+	}
+	if migrated.Resource != (v2.ResourceInfo{Description: "Data", MimeType: "application/json"}) {
+		t.Errorf("Unexpected resource: %+v", migrated.Resource)
+	}
+	if len(migrated.PaymentRequirements) != 2 {
+		t.Fatalf("Expected 2 requirements, got %d", len(migrated.PaymentRequirements))
+	}
+	first := migrated.PaymentRequirements[0]
+	if first.Network != v2.NetworkBase || first.Amount != "10000" || first.Extra["name"] != "USD Coin" {
+		t.Errorf("Unexpected requirement: %+v", first)
+	}
+	if migrated.PaymentRequirements[1].Network != v2.NetworkSolanaMainnet {
+		t.Errorf("Expected Solana mainnet, got %s", migrated.PaymentRequirements[1].Network)
+	}
+
+	lossy := make(map[string]bool)
+	for _, c := range Lossy(changes) {
+		lossy[c.Field] = true
+	}
+	for _, field := range []string{"Description", "OutputSchema", "FacilitatorOnBeforeVerify"} {
+		if !lossy[field] {
+			t.Errorf("Expected %s to be reported as lossy, got:\n%s", field, Format(changes))
+		}
+	}
+	if lossy["MimeType"] || lossy["Network"] {
+		t.Errorf("Unexpected lossy changes:\n%s", Format(changes))
+	}
+}
+
+func TestRequirements_UnknownNetwork(t *testing.T) {
+	_, _, _, err := Requirements([]x402.PaymentRequirement{{Network: "base"}, {Network: "dogecoin"}})
+	if !errors.Is(err, v2.ErrInvalidNetwork) {
+		t.Errorf("Expected ErrInvalidNetwork, got %v", err)
+	}
+}
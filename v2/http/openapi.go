@@ -0,0 +1,269 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/http/internal/checks"
+)
+
+// OpenAPIExtension is the OpenAPI extension key that describes the payment
+// requirements of an operation.
+const OpenAPIExtension = "x-x402"
+
+// Route is a paid endpoint, described by the Config of the middleware that
+// protects it.
+type Route struct {
+	// Method is the HTTP method, like "GET".
+	Method string
+
+	// Path is the OpenAPI path template, like "/weather/{city}".
+	Path string
+
+	// Summary and Description document the operation.
+	Summary     string
+	Description string
+
+	// Config is the route's middleware configuration. Its payment requirements
+	// (with revenue splits), facilitator and resource are published.
+	Config Config
+}
+
+// OpenAPIInfo is the info and servers of a generated OpenAPI document.
+type OpenAPIInfo struct {
+	Title       string
+	Version     string
+	Description string
+
+	// Servers lists the base URLs of the API.
+	Servers []string
+}
+
+// OpenAPIPayment is the value of the OpenAPIExtension on an operation.
+type OpenAPIPayment struct {
+	X402Version int              `json:"x402Version"`
+	Facilitator string           `json:"facilitator,omitempty"`
+	Resource    *v2.ResourceInfo `json:"resource,omitempty"`
+	VerifyOnly  bool             `json:"verifyOnly,omitempty"`
+	Accepts     []OpenAPIPrice   `json:"accepts"`
+}
+
+// OpenAPIPrice is an accepted payment option, with its amount in display
+// units when the asset is a known USDC deployment.
+type OpenAPIPrice struct {
+	v2.PaymentRequirements
+
+	// Price is Amount in display units, like "0.010000".
+	Price string `json:"price,omitempty"`
+
+	// Currency is the symbol of the asset, like "USDC".
+	Currency string `json:"currency,omitempty"`
+}
+
+// MarshalJSON encodes the requirements with the price and currency alongside
+// their fields, since the embedded requirements encode themselves.
+func (p OpenAPIPrice) MarshalJSON() ([]byte, error) {
+	requirements := p.PaymentRequirements
+	fields := make(map[string]json.RawMessage, len(requirements.UnknownFields)+2)
+	for name, value := range requirements.UnknownFields {
+		fields[name] = value
+	}
+	for name, value := range map[string]string{"price": p.Price, "currency": p.Currency} {
+		if value != "" {
+			fields[name], _ = json.Marshal(value)
+		}
+	}
+	requirements.UnknownFields = fields
+	return json.Marshal(requirements)
+}
+
+// UnmarshalJSON decodes the requirements and their price and currency.
+func (p *OpenAPIPrice) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &p.PaymentRequirements); err != nil {
+		return err
+	}
+	p.Price, p.Currency = "", ""
+	for name, value := range map[string]*string{"price": &p.Price, "currency": &p.Currency} {
+		if raw, ok := p.UnknownFields[name]; ok {
+			if err := json.Unmarshal(raw, value); err != nil {
+				return err
+			}
+			delete(p.UnknownFields, name)
+		}
+	}
+	if len(p.UnknownFields) == 0 {
+		p.UnknownFields = nil
+	}
+	return nil
+}
+
+// GenerateOpenAPI emits an OpenAPI 3.1 document describing routes. Each
+// operation carries its payment requirements in the OpenAPIExtension, the
+// payment request header, the settlement response header (X-PAYMENT and
+// X-PAYMENT-RESPONSE unless Config.Headers renames them) and a 402 response
+// with the v2.PaymentRequired body.
+func GenerateOpenAPI(info OpenAPIInfo, routes []Route) ([]byte, error) {
+	paths := make(map[string]map[string]interface{})
+	for _, route := range routes {
+		method := strings.ToLower(route.Method)
+		if method == "" || route.Path == "" {
+			return nil, fmt.Errorf("route %q %q: method and path are required", route.Method, route.Path)
+		}
+		operation, err := openAPIOperation(route)
+		if err != nil {
+			return nil, fmt.Errorf("route %s %s: %w", route.Method, route.Path, err)
+		}
+		if paths[route.Path] == nil {
+			paths[route.Path] = make(map[string]interface{})
+		}
+		if _, ok := paths[route.Path][method]; ok {
+			return nil, fmt.Errorf("route %s %s: duplicate route", route.Method, route.Path)
+		}
+		paths[route.Path][method] = operation
+	}
+
+	document := map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]interface{}{
+			"title":       info.Title,
+			"version":     info.Version,
+			"description": info.Description,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"PaymentRequired": map[string]interface{}{
+					"type":     "object",
+					"required": []string{"x402Version", "accepts"},
+					"properties": map[string]interface{}{
+						"x402Version": map[string]interface{}{"type": "integer"},
+						"error":       map[string]interface{}{"type": "string"},
+						"resource":    map[string]interface{}{"type": "object"},
+						"accepts": map[string]interface{}{
+							"type":  "array",
+							"items": map[string]interface{}{"type": "object"},
+						},
+					},
+				},
+			},
+		},
+	}
+	if len(info.Servers) > 0 {
+		servers := make([]map[string]string, len(info.Servers))
+		for i, url := range info.Servers {
+			servers[i] = map[string]string{"url": url}
+		}
+		document["servers"] = servers
+	}
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// openAPIOperation describes a single route.
+func openAPIOperation(route Route) (map[string]interface{}, error) {
+	requirements, err := checks.SplitRequirements(route.Config.PaymentRequirements, route.Config.RevenueSplits)
+	if err != nil {
+		return nil, err
+	}
+
+	payment := OpenAPIPayment{
+		X402Version: v2.X402Version,
+		Facilitator: route.Config.FacilitatorURL,
+		VerifyOnly:  route.Config.VerifyOnly,
+		Accepts:     make([]OpenAPIPrice, len(requirements)),
+	}
+	if route.Config.Resource.URL != "" {
+		resource := route.Config.Resource
+		payment.Resource = &resource
+	}
+	for i, req := range requirements {
+		payment.Accepts[i] = openAPIPrice(req)
+	}
+
+	headers := route.Config.Headers
+	parameters := []map[string]interface{}{{
+		"name":        headers.PaymentHeader(),
+		"in":          "header",
+		"required":    false,
+		"description": "Base64-encoded x402 payment payload.",
+		"schema":      map[string]string{"type": "string"},
+	}}
+	if headers.QueryParameter != "" {
+		parameters = append(parameters, map[string]interface{}{
+			"name":        headers.QueryParameter,
+			"in":          "query",
+			"required":    false,
+			"description": "Base64-encoded x402 payment payload, for clients unable to send the header.",
+			"schema":      map[string]string{"type": "string"},
+		})
+	}
+
+	operation := map[string]interface{}{
+		"parameters": parameters,
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "Paid response.",
+				"headers": map[string]interface{}{
+					headers.PaymentResponseHeader(): map[string]interface{}{
+						"description": "Base64-encoded x402 settlement.",
+						"schema":      map[string]string{"type": "string"},
+					},
+				},
+			},
+			"402": map[string]interface{}{
+				"description": "Payment required.",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": map[string]string{"$ref": "#/components/schemas/PaymentRequired"},
+					},
+				},
+			},
+		},
+		OpenAPIExtension: payment,
+	}
+	if route.Summary != "" {
+		operation["summary"] = route.Summary
+	}
+	if route.Description != "" {
+		operation["description"] = route.Description
+	}
+	return operation, nil
+}
+
+// openAPIPrice adds display units to req when its asset is a known USDC
+// deployment.
+func openAPIPrice(req v2.PaymentRequirements) OpenAPIPrice {
+	price := OpenAPIPrice{PaymentRequirements: req}
+	chain, err := v2.GetChainConfig(req.Network)
+	if err != nil || !strings.EqualFold(chain.USDCAddress, req.Asset) {
+		return price
+	}
+	amount, ok := new(big.Int).SetString(req.Amount, 10)
+	if !ok {
+		return price
+	}
+	price.Price = v2.BigIntToAmount(amount, int(chain.Decimals))
+	price.Currency = "USDC"
+	return price
+}
+
+// OpenAPIHandler serves the OpenAPI document of routes as JSON, for example at
+// "/openapi.json" next to the API it describes. The document is generated once.
+func OpenAPIHandler(info OpenAPIInfo, routes []Route) (http.Handler, error) {
+	document, err := GenerateOpenAPI(info, routes)
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(document)
+	}), nil
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func TestGenerateOpenAPI(t *testing.T) {
+	routes := []Route{
+		{
+			Method:  "GET",
+			Path:    "/weather/{city}",
+			Summary: "Current weather",
+			Config: Config{
+				FacilitatorURL: "https://facilitator.x402.org",
+				Resource:       v2.ResourceInfo{URL: "https://api.example.com/weather", Description: "Weather"},
+				PaymentRequirements: []v2.PaymentRequirements{{
+					Scheme:            "exact",
+					Network:           v2.BaseSepolia.Network,
+					Amount:            "10000",
+					Asset:             v2.BaseSepolia.USDCAddress,
+					PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+					MaxTimeoutSeconds: 60,
+				}},
+			},
+		},
+		{
+			Method: "post",
+			Path:   "/reports",
+			Config: Config{
+				VerifyOnly: true,
+				PaymentRequirements: []v2.PaymentRequirements{{
+					Scheme:  "exact",
+					Network: "eip155:84532",
+					Amount:  "5",
+					Asset:   "0xUnknownToken",
+					PayTo:   "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+				}},
+			},
+		},
+	}
+
+	data, err := GenerateOpenAPI(OpenAPIInfo{Title: "Weather API", Version: "1.0.0", Servers: []string{"https://api.example.com"}}, routes)
+	if err != nil {
+		t.Fatalf("GenerateOpenAPI failed: %v", err)
+	}
+
+	var document struct {
+		OpenAPI string `json:"openapi"`
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths map[string]map[string]struct {
+			Summary   string                     `json:"summary"`
+			Responses map[string]json.RawMessage `json:"responses"`
+			Payment   OpenAPIPayment             `json:"x-x402"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatalf("Invalid document: %v", err)
+	}
+	if document.OpenAPI != "3.1.0" || len(document.Servers) != 1 {
+		t.Errorf("Unexpected document header: %s", data)
+	}
+
+	weather := document.Paths["/weather/{city}"]["get"]
+	if weather.Summary != "Current weather" || weather.Responses["402"] == nil {
+		t.Errorf("Unexpected weather operation: %+v", weather)
+	}
+	payment := weather.Payment
+	if payment.X402Version != 2 || payment.Facilitator != "https://facilitator.x402.org" || payment.Resource == nil {
+		t.Errorf("Unexpected payment extension: %+v", payment)
+	}
+	if len(payment.Accepts) != 1 || payment.Accepts[0].Price != "0.010000" || payment.Accepts[0].Currency != "USDC" || payment.Accepts[0].Amount != "10000" {
+		t.Errorf("Unexpected accepted price: %+v", payment.Accepts)
+	}
+
+	reports := document.Paths["/reports"]["post"].Payment
+	if !reports.VerifyOnly || len(reports.Accepts) != 1 || reports.Accepts[0].Price != "" {
+		t.Errorf("Unexpected reports extension: %+v", reports)
+	}
+
+	if _, err := GenerateOpenAPI(OpenAPIInfo{}, append(routes, routes[0])); err == nil {
+		t.Error("Expected error for duplicate route")
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	handler, err := OpenAPIHandler(OpenAPIInfo{Title: "API", Version: "1"}, nil)
+	if err != nil {
+		t.Fatalf("OpenAPIHandler failed: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" || !json.Valid(w.Body.Bytes()) {
+		t.Errorf("Unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/openapi.json", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", w.Code)
+	}
+}
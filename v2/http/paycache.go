@@ -0,0 +1,271 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/storage"
+)
+
+// DefaultMaxPaidCacheBodySize is the largest response body PaymentCache stores.
+const DefaultMaxPaidCacheBodySize = 1 << 20
+
+// PaymentCache keeps the paid responses of GET requests on the client for as
+// long as their Cache-Control allows, so identical requests within that time
+// are answered from the cache instead of paying again. Only 200 responses to
+// paid requests whose Cache-Control (or Expires) grants a positive lifetime
+// are stored; no-store and no-cache responses never are. A request sending
+// Cache-Control: no-cache or no-store bypasses the cache.
+//
+// Requests are identical when their URL and headers are. Responses served
+// from the cache carry an Age header and the X-PAYMENT-RESPONSE header of the
+// original payment, and fire no payment callbacks.
+type PaymentCache struct {
+	store       storage.Store
+	maxBodySize int
+	maxTTL      time.Duration
+	uncached    map[string]bool
+	clock       v2.Clock
+}
+
+// PaymentCacheOption configures a PaymentCache.
+type PaymentCacheOption func(*PaymentCache)
+
+// WithPaymentCacheStore sets where responses are kept (default: a
+// storage.Memory). Use a storage.Disk to keep them across restarts.
+func WithPaymentCacheStore(store storage.Store) PaymentCacheOption {
+	return func(c *PaymentCache) {
+		c.store = store
+	}
+}
+
+// WithMaxPaidCacheBodySize sets the largest body that is cached (default:
+// DefaultMaxPaidCacheBodySize). Larger responses are returned but not cached.
+func WithMaxPaidCacheBodySize(size int) PaymentCacheOption {
+	return func(c *PaymentCache) {
+		c.maxBodySize = size
+	}
+}
+
+// WithMaxPaidCacheTTL caps how long a response is cached, whatever its
+// Cache-Control says (default: uncapped).
+func WithMaxPaidCacheTTL(ttl time.Duration) PaymentCacheOption {
+	return func(c *PaymentCache) {
+		c.maxTTL = ttl
+	}
+}
+
+// WithUncachedHosts opts hosts out of the cache, e.g. merchants whose paid
+// responses must be fetched fresh despite their Cache-Control. Hosts are
+// matched without their port, ignoring case.
+func WithUncachedHosts(hosts ...string) PaymentCacheOption {
+	return func(c *PaymentCache) {
+		for _, host := range hosts {
+			c.uncached[strings.ToLower(host)] = true
+		}
+	}
+}
+
+// WithPaymentCacheClock sets the clock used to compute the Age of cached
+// responses. Expiry is enforced by the store's own clock.
+func WithPaymentCacheClock(clock v2.Clock) PaymentCacheOption {
+	return func(c *PaymentCache) {
+		c.clock = clock
+	}
+}
+
+// NewPaymentCache creates a client cache of paid responses.
+func NewPaymentCache(opts ...PaymentCacheOption) *PaymentCache {
+	c := &PaymentCache{
+		maxBodySize: DefaultMaxPaidCacheBodySize,
+		uncached:    make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.store == nil {
+		c.store = storage.NewMemory(storage.WithMemoryClock(c.clock))
+	}
+	c.clock = v2.ClockOrSystem(c.clock)
+	return c
+}
+
+// paidEntry is a cached response as kept in the store.
+type paidEntry struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+	Stored time.Time   `json:"stored"`
+}
+
+// cacheable reports whether req may be answered from and stored in the cache.
+func (c *PaymentCache) cacheable(req *http.Request) bool {
+	if req.Method != http.MethodGet || (req.Body != nil && req.Body != http.NoBody) {
+		return false
+	}
+	if c.uncached[strings.ToLower(req.URL.Hostname())] {
+		return false
+	}
+	directives := cacheControl(req.Header)
+	_, noCache := directives["no-cache"]
+	_, noStore := directives["no-store"]
+	return !noCache && !noStore
+}
+
+// key returns the store key of req.
+func (c *PaymentCache) key(req *http.Request) string {
+	sum := sha256.Sum256([]byte(requestKey(req)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Lookup returns the cached response to req, or nil if there is none. Store
+// errors are treated as misses.
+func (c *PaymentCache) Lookup(req *http.Request) *http.Response {
+	if !c.cacheable(req) {
+		return nil
+	}
+	data, err := c.store.Get(req.Context(), c.key(req))
+	if err != nil {
+		return nil
+	}
+	var entry paidEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+
+	header := entry.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	age := max(c.clock.Now().Sub(entry.Stored), 0)
+	header.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+	return &http.Response{
+		Status:        strconv.Itoa(entry.Status) + " " + http.StatusText(entry.Status),
+		StatusCode:    entry.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       req,
+	}
+}
+
+// Store arranges for resp, the paid response to req, to be cached once its
+// body has been read to the end, if its Cache-Control allows. It returns the
+// response to hand to the caller.
+func (c *PaymentCache) Store(req *http.Request, resp *http.Response) *http.Response {
+	if resp.StatusCode != http.StatusOK || !c.cacheable(req) {
+		return resp
+	}
+	ttl := c.lifetime(resp)
+	if ttl <= 0 {
+		return resp
+	}
+	header := resp.Header.Clone()
+	header.Del("Age")
+	key := c.key(req)
+	ctx := context.WithoutCancel(req.Context())
+	stored := c.clock.Now()
+	resp.Body = &cachingBody{
+		ReadCloser: resp.Body,
+		limit:      c.maxBodySize,
+		onEOF: func(body []byte) {
+			data, err := json.Marshal(paidEntry{Status: resp.StatusCode, Header: header, Body: body, Stored: stored})
+			if err != nil {
+				return
+			}
+			_ = c.store.Set(ctx, key, data, ttl)
+		},
+	}
+	return resp
+}
+
+// lifetime returns how long resp may be cached: its max-age less its Age,
+// or else the time until its Expires, capped by the maximum TTL.
+func (c *PaymentCache) lifetime(resp *http.Response) time.Duration {
+	directives := cacheControl(resp.Header)
+	for _, directive := range []string{"no-store", "no-cache"} {
+		if _, ok := directives[directive]; ok {
+			return 0
+		}
+	}
+
+	var ttl time.Duration
+	if maxAge, ok := directives["max-age"]; ok {
+		seconds, err := strconv.ParseInt(maxAge, 10, 64)
+		if err != nil {
+			return 0
+		}
+		ttl = time.Duration(seconds) * time.Second
+		if age, err := strconv.ParseInt(resp.Header.Get("Age"), 10, 64); err == nil {
+			ttl -= time.Duration(age) * time.Second
+		}
+	} else if expires, err := http.ParseTime(resp.Header.Get("Expires")); err == nil {
+		now := c.clock.Now()
+		if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+			now = date
+		}
+		ttl = expires.Sub(now)
+	}
+	if c.maxTTL > 0 && ttl > c.maxTTL {
+		ttl = c.maxTTL
+	}
+	return ttl
+}
+
+// cacheControl parses the Cache-Control directives of header into a map of
+// lower-case names to unquoted values.
+func cacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, line := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(line, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name == "" {
+				continue
+			}
+			directives[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return directives
+}
+
+// cachingBody buffers a response body as it is read and calls onEOF with it
+// once it has been read to the end, unless it exceeded limit.
+type cachingBody struct {
+	io.ReadCloser
+	limit int
+	onEOF func(body []byte)
+
+	buf  bytes.Buffer
+	over bool
+	once sync.Once
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if !b.over {
+		if b.buf.Len()+n > b.limit {
+			b.over = true
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if errors.Is(err, io.EOF) && !b.over {
+		b.once.Do(func() { b.onEOF(b.buf.Bytes()) })
+	}
+	return n, err
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
+	"github.com/mark3labs/x402-go/v2/storage"
+)
+
+// newCachingServer serves a paid resource with cacheControl, counting the
+// payments it receives.
+func newCachingServer(t *testing.T, cacheControl string, paid *atomic.Int32) *httptest.Server {
+	t.Helper()
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-PAYMENT") == "" {
+			w.WriteHeader(http.StatusPaymentRequired)
+			_ = json.NewEncoder(w).Encode(v2.PaymentRequired{X402Version: 2, Accepts: []v2.PaymentRequirements{requirement}})
+			return
+		}
+		paid.Add(1)
+		encoded, _ := encoding.EncodeSettlement(v2.SettleResponse{Success: true, Transaction: "0xtx", Network: requirement.Network})
+		w.Header().Set("X-PAYMENT-RESPONSE", encoded)
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		_, _ = w.Write([]byte("Protected content"))
+	}))
+}
+
+// fetch GETs url through transport and returns the body.
+func fetch(t *testing.T, transport http.RoundTripper, url string, header http.Header) string {
+	t.Helper()
+	req, _ := http.NewRequest("GET", url, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestPaymentCache(t *testing.T) {
+	tests := []struct {
+		name          string
+		cacheControl  string
+		requestHeader http.Header
+		opts          []PaymentCacheOption
+		wantPaid      int32
+	}{
+		{name: "max-age is cached", cacheControl: "public, max-age=60", wantPaid: 1},
+		{name: "no Cache-Control is not cached", wantPaid: 2},
+		{name: "no-store is not cached", cacheControl: "no-store", wantPaid: 2},
+		{name: "no-cache is not cached", cacheControl: "max-age=60, no-cache", wantPaid: 2},
+		{name: "zero max-age is not cached", cacheControl: "max-age=0", wantPaid: 2},
+		{name: "request no-cache bypasses", cacheControl: "max-age=60", requestHeader: http.Header{"Cache-Control": {"no-cache"}}, wantPaid: 2},
+		{name: "uncached host", cacheControl: "max-age=60", opts: []PaymentCacheOption{WithUncachedHosts("127.0.0.1")}, wantPaid: 2},
+		{name: "body too large", cacheControl: "max-age=60", opts: []PaymentCacheOption{WithMaxPaidCacheBodySize(4)}, wantPaid: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paid atomic.Int32
+			server := newCachingServer(t, tt.cacheControl, &paid)
+			defer server.Close()
+
+			transport := &X402Transport{
+				Base:     http.DefaultTransport,
+				Signers:  []v2.Signer{&mockSigner{network: "eip155:84532", scheme: "exact"}},
+				Selector: v2.NewDefaultPaymentSelector(),
+				Cache:    NewPaymentCache(tt.opts...),
+			}
+			for i := 0; i < 2; i++ {
+				if body := fetch(t, transport, server.URL, tt.requestHeader); body != "Protected content" {
+					t.Fatalf("request %d: unexpected body %q", i, body)
+				}
+			}
+			if paid.Load() != tt.wantPaid {
+				t.Errorf("expected %d payments, got %d", tt.wantPaid, paid.Load())
+			}
+		})
+	}
+}
+
+func TestPaymentCache_Expiry(t *testing.T) {
+	var paid atomic.Int32
+	server := newCachingServer(t, "max-age=60", &paid)
+	defer server.Close()
+
+	clock := v2.NewFakeClock(time.Now())
+	store, err := storage.NewDisk(t.TempDir(), storage.WithDiskClock(clock))
+	if err != nil {
+		t.Fatalf("NewDisk failed: %v", err)
+	}
+	transport := &X402Transport{
+		Base:     http.DefaultTransport,
+		Signers:  []v2.Signer{&mockSigner{network: "eip155:84532", scheme: "exact"}},
+		Selector: v2.NewDefaultPaymentSelector(),
+		Cache:    NewPaymentCache(WithPaymentCacheStore(store), WithPaymentCacheClock(clock)),
+	}
+
+	fetch(t, transport, server.URL, nil)
+	clock.Advance(30 * time.Second)
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("Age") != "30" {
+		t.Errorf("expected Age 30, got %q", resp.Header.Get("Age"))
+	}
+	if settlement := GetSettlement(resp); settlement == nil || settlement.Transaction != "0xtx" {
+		t.Errorf("expected the original settlement, got %+v", settlement)
+	}
+	if paid.Load() != 1 {
+		t.Fatalf("expected the cached response within max-age, got %d payments", paid.Load())
+	}
+
+	// Another path is a different resource
+	fetch(t, transport, server.URL+"/other", nil)
+	if paid.Load() != 2 {
+		t.Errorf("expected a payment for another URL, got %d payments", paid.Load())
+	}
+
+	clock.Advance(31 * time.Second)
+	fetch(t, transport, server.URL, nil)
+	if paid.Load() != 3 {
+		t.Errorf("expected a new payment after max-age, got %d payments", paid.Load())
+	}
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// PayToTXTPrefix starts the DNS TXT records DNSPayTo reads, one per address:
+//
+//	_x402.api.example.com. TXT "x402-payto=0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+const PayToTXTPrefix = "x402-payto="
+
+// PayToResolver returns the addresses a merchant host is known to be paid at.
+// A nil result with a nil error means the host is unknown and its payTo is not
+// checked.
+type PayToResolver interface {
+	ResolvePayTo(ctx context.Context, host string) ([]string, error)
+}
+
+// StaticPayTo maps hostnames, like "api.example.com", to their payTo addresses.
+// Hostnames are matched case-insensitively.
+type StaticPayTo map[string][]string
+
+// ResolvePayTo implements PayToResolver.
+func (s StaticPayTo) ResolvePayTo(_ context.Context, host string) ([]string, error) {
+	if addresses, ok := s[host]; ok {
+		return addresses, nil
+	}
+	for name, addresses := range s {
+		if strings.EqualFold(name, host) {
+			return addresses, nil
+		}
+	}
+	return nil, nil
+}
+
+// DNSPayTo discovers payTo addresses from DNS TXT records at "_x402.<host>",
+// so a compromised server cannot change them without also controlling DNS.
+type DNSPayTo struct {
+	// Resolver looks up TXT records (default: net.DefaultResolver).
+	Resolver *net.Resolver
+}
+
+// ResolvePayTo implements PayToResolver.
+func (d DNSPayTo) ResolvePayTo(ctx context.Context, host string) ([]string, error) {
+	resolver := d.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	records, err := resolver.LookupTXT(ctx, "_x402."+host)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("looking up payTo records for %s: %w", host, err)
+	}
+
+	var addresses []string
+	for _, record := range records {
+		if address, ok := strings.CutPrefix(record, PayToTXTPrefix); ok && address != "" {
+			addresses = append(addresses, strings.TrimSpace(address))
+		}
+	}
+	return addresses, nil
+}
+
+// checkPayTo returns the requirements paying an address known for the
+// request's host, or the first mismatch if there are none.
+func (t *X402Transport) checkPayTo(req *http.Request, requirements []v2.PaymentRequirements) ([]v2.PaymentRequirements, error) {
+	if t.PayTo == nil {
+		return requirements, nil
+	}
+	host := req.URL.Hostname()
+	known, err := t.PayTo.ResolvePayTo(req.Context(), host)
+	if err != nil {
+		return nil, err
+	}
+	if known == nil {
+		return requirements, nil
+	}
+
+	trusted := make([]v2.PaymentRequirements, 0, len(requirements))
+	for _, requirement := range requirements {
+		if knownPayTo(requirement, known) {
+			trusted = append(trusted, requirement)
+		}
+	}
+	if len(trusted) == 0 && len(requirements) > 0 {
+		return nil, fmt.Errorf("%w: %s for %s", v2.ErrUntrustedPayTo, requirements[0].PayTo, host)
+	}
+	return trusted, nil
+}
+
+// knownPayTo reports whether requirement pays one of known. Addresses on EVM
+// networks are compared case-insensitively.
+func knownPayTo(requirement v2.PaymentRequirements, known []string) bool {
+	networkType, _ := v2.ValidateNetwork(requirement.Network)
+	for _, address := range known {
+		if address == requirement.PayTo || (networkType == v2.NetworkTypeEVM && strings.EqualFold(address, requirement.PayTo)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+type payToResolverFunc func(ctx context.Context, host string) ([]string, error)
+
+func (f payToResolverFunc) ResolvePayTo(ctx context.Context, host string) ([]string, error) {
+	return f(ctx, host)
+}
+
+func TestTransport_PayToPolicy(t *testing.T) {
+	const merchant = "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             merchant,
+		MaxTimeoutSeconds: 60,
+	}
+	resolverErr := errors.New("lookup failed")
+
+	tests := []struct {
+		name     string
+		resolver PayToResolver
+		wantErr  error
+	}{
+		{name: "no policy"},
+		{name: "known address", resolver: StaticPayTo{"127.0.0.1": {"0x209693bc6afc0c5328ba36faf03c514ef312287c"}}},
+		{name: "unknown host", resolver: StaticPayTo{"api.example.com": {"0x000000000000000000000000000000000000dEaD"}}},
+		{
+			name:     "swapped address",
+			resolver: StaticPayTo{"127.0.0.1": {"0x000000000000000000000000000000000000dEaD"}},
+			wantErr:  v2.ErrUntrustedPayTo,
+		},
+		{
+			name: "resolver failure",
+			resolver: payToResolverFunc(func(context.Context, string) ([]string, error) {
+				return nil, resolverErr
+			}),
+			wantErr: resolverErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newPaidServer(t, requirement)
+			defer server.Close()
+
+			transport := &X402Transport{
+				Base:     http.DefaultTransport,
+				Signers:  []v2.Signer{&mockSigner{network: "eip155:84532", scheme: "exact"}},
+				Selector: v2.NewDefaultPaymentSelector(),
+				PayTo:    tt.resolver,
+			}
+
+			req, _ := http.NewRequest("GET", server.URL, nil)
+			resp, err := transport.RoundTrip(req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RoundTrip failed: %v", err)
+			}
+			resp.Body.Close()
+		})
+	}
+}
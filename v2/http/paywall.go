@@ -0,0 +1,143 @@
+package http
+
+import (
+	"net/http"
+	"sync"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// DefaultFacilitatorURL is the facilitator used by RequirePayment unless
+// WithFacilitatorURL is given.
+const DefaultFacilitatorURL = "https://facilitator.x402.org"
+
+// HandlerOption adjusts the Config of a single handler wrapped by
+// RequirePayment or a Paywall.
+type HandlerOption func(*Config)
+
+// WithFacilitatorURL selects the facilitator for RequirePayment. It has no
+// effect on a Paywall, whose facilitator is fixed by NewPaywall.
+func WithFacilitatorURL(url string) HandlerOption {
+	return func(c *Config) {
+		c.FacilitatorURL = url
+	}
+}
+
+// WithResource describes the protected resource. By default it is derived from
+// the request URL.
+func WithResource(resource v2.ResourceInfo) HandlerOption {
+	return func(c *Config) {
+		c.Resource = resource
+	}
+}
+
+// WithVerifyOnly verifies payments without settling them.
+func WithVerifyOnly() HandlerOption {
+	return func(c *Config) {
+		c.VerifyOnly = true
+	}
+}
+
+// WithRequirementsFunc computes per-request payment requirements, as
+// Config.RequirementsFunc.
+func WithRequirementsFunc(fn RequirementsFunc) HandlerOption {
+	return func(c *Config) {
+		c.RequirementsFunc = fn
+	}
+}
+
+// WithMessages customizes the 402 responses of the handler, as Config.Messages.
+func WithMessages(messages PaymentMessages) HandlerOption {
+	return func(c *Config) {
+		c.Messages = messages
+	}
+}
+
+// Paywall gates individual handlers behind payments, for servers that do not
+// use a middleware chain. Handlers wrapped by the same Paywall share its
+// facilitator clients.
+//
+// Example:
+//
+//	paywall := http.NewPaywall(http.Config{
+//	    FacilitatorURL:           "https://facilitator.x402.org",
+//	    FacilitatorAuthorization: "Bearer my-api-key",
+//	})
+//	mux.Handle("/report", paywall.RequirePaymentFunc(reportHandler, reportRequirements))
+//	mux.Handle("/export", paywall.RequirePaymentFunc(exportHandler, exportRequirements))
+type Paywall struct {
+	config              Config
+	facilitator         *FacilitatorClient
+	fallbackFacilitator *FacilitatorClient
+}
+
+// NewPaywall creates a Paywall using the facilitators and defaults of config.
+// config.PaymentRequirements is ignored; each wrapped handler brings its own.
+func NewPaywall(config Config) *Paywall {
+	facilitator, fallbackFacilitator := config.FacilitatorClients()
+	return &Paywall{
+		config:              config,
+		facilitator:         facilitator,
+		fallbackFacilitator: fallbackFacilitator,
+	}
+}
+
+// RequirePayment wraps handler so that requests must pay one of requirements.
+func (p *Paywall) RequirePayment(handler http.Handler, requirements []v2.PaymentRequirements, opts ...HandlerOption) http.Handler {
+	config := p.config
+	for _, opt := range opts {
+		opt(&config)
+	}
+	config.PaymentRequirements = requirements
+	return newMiddleware(config, p.facilitator, p.fallbackFacilitator)(handler)
+}
+
+// RequirePaymentFunc is RequirePayment for an http.HandlerFunc.
+func (p *Paywall) RequirePaymentFunc(handler http.HandlerFunc, requirements []v2.PaymentRequirements, opts ...HandlerOption) http.Handler {
+	return p.RequirePayment(handler, requirements, opts...)
+}
+
+var (
+	sharedPaywallsMu sync.Mutex
+	sharedPaywalls   = make(map[string]*Paywall)
+)
+
+// sharedPaywall returns the Paywall for facilitatorURL, creating it on first use.
+func sharedPaywall(facilitatorURL string) *Paywall {
+	sharedPaywallsMu.Lock()
+	defer sharedPaywallsMu.Unlock()
+	paywall, ok := sharedPaywalls[facilitatorURL]
+	if !ok {
+		paywall = NewPaywall(Config{FacilitatorURL: facilitatorURL})
+		sharedPaywalls[facilitatorURL] = paywall
+	}
+	return paywall
+}
+
+// RequirePayment wraps handler so that requests must pay one of requirements,
+// using the facilitator at DefaultFacilitatorURL unless WithFacilitatorURL is
+// given. Handlers wrapped for the same facilitator URL share its client. Use a
+// Paywall for facilitators that need authorization or other client settings.
+//
+// Example:
+//
+//	http.Handle("/weather", x402http.RequirePaymentFunc(weatherHandler, []v2.PaymentRequirements{{
+//	    Scheme:            "exact",
+//	    Network:           v2.NetworkBaseSepolia,
+//	    Amount:            "10000",
+//	    Asset:             v2.BaseSepolia.USDCAddress,
+//	    PayTo:             "0xYourAddress",
+//	    MaxTimeoutSeconds: 60,
+//	}}))
+func RequirePayment(handler http.Handler, requirements []v2.PaymentRequirements, opts ...HandlerOption) http.Handler {
+	config := Config{FacilitatorURL: DefaultFacilitatorURL}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return sharedPaywall(config.FacilitatorURL).RequirePayment(handler, requirements, opts...)
+}
+
+// RequirePaymentFunc is RequirePayment for an http.HandlerFunc.
+func RequirePaymentFunc(handler http.HandlerFunc, requirements []v2.PaymentRequirements, opts ...HandlerOption) http.Handler {
+	return RequirePayment(handler, requirements, opts...)
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
+)
+
+func TestPaywall_RequirePayment(t *testing.T) {
+	var mu sync.Mutex
+	var verified []string
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+		case "/verify":
+			var req struct {
+				PaymentRequirements v2.PaymentRequirements `json:"paymentRequirements"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			verified = append(verified, req.PaymentRequirements.Amount)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xtx", Network: "eip155:84532"})
+		default:
+			t.Errorf("Unexpected facilitator call: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := func(amount string) []v2.PaymentRequirements {
+		return []v2.PaymentRequirements{{
+			Scheme:            "exact",
+			Network:           "eip155:84532",
+			Amount:            amount,
+			Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+			PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+			MaxTimeoutSeconds: 60,
+		}}
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	}
+
+	paywall := NewPaywall(Config{FacilitatorURL: facilitatorServer.URL})
+	mux := http.NewServeMux()
+	mux.Handle("/cheap", paywall.RequirePaymentFunc(ok, requirement("1000")))
+	mux.Handle("/expensive", paywall.RequirePaymentFunc(ok, requirement("50000"),
+		WithResource(v2.ResourceInfo{URL: "https://example.com/expensive", Description: "Expensive"})))
+	mux.Handle("/direct", RequirePaymentFunc(ok, requirement("7000"), WithFacilitatorURL(facilitatorServer.URL), WithVerifyOnly()))
+
+	tests := []struct {
+		name         string
+		path         string
+		amount       string
+		wantStatus   int
+		wantResource string
+		wantSettled  bool
+	}{
+		{name: "unpaid", path: "/cheap", wantStatus: http.StatusPaymentRequired, wantResource: "http://example.com/cheap"},
+		{name: "resource option", path: "/expensive", wantStatus: http.StatusPaymentRequired, wantResource: "https://example.com/expensive"},
+		{name: "paid", path: "/cheap", amount: "1000", wantStatus: http.StatusOK, wantSettled: true},
+		{name: "package level verify only", path: "/direct", amount: "7000", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.amount != "" {
+				header, _ := encoding.EncodePayment(v2.PaymentPayload{
+					X402Version: 2,
+					Accepted:    v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: tt.amount},
+					Payload:     map[string]interface{}{"signature": "0xsig"},
+				})
+				req.Header.Set("X-PAYMENT", header)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantResource != "" {
+				var paymentReq v2.PaymentRequired
+				if err := json.NewDecoder(w.Body).Decode(&paymentReq); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if paymentReq.Resource == nil || paymentReq.Resource.URL != tt.wantResource {
+					t.Errorf("Expected resource %s, got %+v", tt.wantResource, paymentReq.Resource)
+				}
+			}
+			if settled := w.Header().Get("X-PAYMENT-RESPONSE") != ""; settled != tt.wantSettled {
+				t.Errorf("Expected settled=%v, got %v", tt.wantSettled, settled)
+			}
+		})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(verified) != 2 || verified[0] != "1000" || verified[1] != "7000" {
+		t.Errorf("Expected verification of 1000 and 7000, got %v", verified)
+	}
+}
+
+func TestRequirePayment_SharesFacilitator(t *testing.T) {
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+	}))
+	defer facilitatorServer.Close()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	RequirePayment(handler, nil, WithFacilitatorURL(facilitatorServer.URL))
+	first := sharedPaywall(facilitatorServer.URL)
+	RequirePayment(handler, nil, WithFacilitatorURL(facilitatorServer.URL))
+
+	if second := sharedPaywall(facilitatorServer.URL); second != first {
+		t.Error("Expected wrappers for the same facilitator to share a Paywall")
+	}
+	if other := sharedPaywall(facilitatorServer.URL + "/other"); other == first {
+		t.Error("Expected a separate Paywall for another facilitator")
+	}
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// RequirementsFunc computes the payment requirements for a single request from the
+// middleware's configured (and facilitator-enriched) requirements. It enables
+// per-request pricing such as RangePricing.
+type RequirementsFunc func(r *http.Request, base []v2.PaymentRequirements) ([]v2.PaymentRequirements, error)
+
+// StatusError is an error carrying the HTTP status the middleware responds with.
+// A RequirementsFunc returns it to reject a request, e.g. with 416 for an
+// unsatisfiable range. Other errors are answered with 400.
+type StatusError struct {
+	// StatusCode is the HTTP status code to respond with.
+	StatusCode int
+
+	// Header holds extra response headers (e.g., Content-Range).
+	Header http.Header
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements error.
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// RequirementsFor returns the payment requirements for r, applying
+// RequirementsFunc when set and the revalidation price for conditional requests
+// under RevalidationReduced.
+func (c Config) RequirementsFor(r *http.Request, base []v2.PaymentRequirements) ([]v2.PaymentRequirements, error) {
+	requirements, err := c.FullRequirementsFor(r, base)
+	if err != nil {
+		return nil, err
+	}
+	return c.revalidationRequirements(r, requirements)
+}
+
+// FullRequirementsFor returns the payment requirements for r without the
+// revalidation price, applying Rotator, RequirementsFunc and then Experiment
+// when set.
+func (c Config) FullRequirementsFor(r *http.Request, base []v2.PaymentRequirements) ([]v2.PaymentRequirements, error) {
+	requirements := c.Rotator.Apply(base)
+	if c.RequirementsFunc != nil {
+		var err error
+		if requirements, err = c.RequirementsFunc(r, requirements); err != nil {
+			return nil, err
+		}
+	}
+	if c.Experiment != nil {
+		return c.Experiment.Apply(r, requirements)
+	}
+	return requirements, nil
+}
+
+// CheckPrice rejects payments whose accepted amount differs from the per-request
+// requirement when RequirementsFunc, Experiment or RevalidationReduced is set, so
+// a payment priced for one request (e.g., a small range) cannot be replayed for
+// another. Under Experiment the accepted price variant must also be the one
+// advertised to the client. Requirements below MinAmounts are always rejected.
+func (c Config) CheckPrice(payment *v2.PaymentPayload, requirement *v2.PaymentRequirements) error {
+	if err := c.MinAmounts.Check(*requirement); err != nil {
+		return err
+	}
+	if c.RequirementsFunc == nil && c.Experiment == nil && c.Revalidation != RevalidationReduced {
+		return nil
+	}
+	if payment.Accepted.Amount != requirement.Amount {
+		return fmt.Errorf("payment amount %s does not match required amount %s", payment.Accepted.Amount, requirement.Amount)
+	}
+	return checkExperiment(payment, requirement)
+}
+
+// writeStatusError responds to a failed RequirementsFunc.
+func writeStatusError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		status = statusErr.StatusCode
+		for key, values := range statusErr.Header {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,229 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// ErrRangeNotSatisfiable indicates a Range header that cannot be served.
+var ErrRangeNotSatisfiable = errors.New("x402: range not satisfiable")
+
+// ByteRange is an inclusive byte range resolved against a resource size.
+type ByteRange struct {
+	Start int64
+	End   int64
+}
+
+// Length returns the number of bytes in the range.
+func (r ByteRange) Length() int64 {
+	return r.End - r.Start + 1
+}
+
+// String returns the range in Range header syntax (e.g., "bytes=0-1023").
+func (r ByteRange) String() string {
+	return fmt.Sprintf("bytes=%d-%d", r.Start, r.End)
+}
+
+// SizeFunc returns the total size in bytes of the resource a request addresses.
+type SizeFunc func(r *http.Request) (int64, error)
+
+// RangePricing returns a RequirementsFunc that prices Range requests in
+// proportion to the bytes requested: each requirement's Amount is the price of
+// the whole resource, and a range costs ceil(Amount * length / size), at least 1
+// atomic unit for non-free resources. Requests without a Range header pay the
+// full price.
+//
+// The priced range is added to each requirement's Extra["range"], so a
+// resumable download pays for each chunk with its own request and payment.
+// Unsatisfiable or malformed ranges are answered with 416. If-Range is removed
+// from priced requests: a handler honouring it (e.g., http.ServeContent) would
+// answer a stale validator with the whole resource, paid for as the range.
+func RangePricing(size SizeFunc) RequirementsFunc {
+	return func(r *http.Request, base []v2.PaymentRequirements) ([]v2.PaymentRequirements, error) {
+		header := r.Header.Get("Range")
+		if header == "" {
+			return base, nil
+		}
+		r.Header.Del("If-Range")
+
+		total, err := size(r)
+		if err != nil {
+			return nil, &StatusError{StatusCode: http.StatusInternalServerError, Err: fmt.Errorf("failed to determine resource size: %w", err)}
+		}
+
+		ranges, err := ParseRange(header, total)
+		if err != nil {
+			return nil, &StatusError{
+				StatusCode: http.StatusRequestedRangeNotSatisfiable,
+				Header:     http.Header{"Content-Range": []string{fmt.Sprintf("bytes */%d", total)}},
+				Err:        err,
+			}
+		}
+
+		var length int64
+		specs := make([]string, len(ranges))
+		for i, byteRange := range ranges {
+			length += byteRange.Length()
+			specs[i] = strings.TrimPrefix(byteRange.String(), "bytes=")
+		}
+		rangeSpec := "bytes=" + strings.Join(specs, ",")
+
+		priced := make([]v2.PaymentRequirements, len(base))
+		for i, req := range base {
+			amount, ok := new(big.Int).SetString(req.Amount, 10)
+			if !ok {
+				return nil, &StatusError{StatusCode: http.StatusInternalServerError, Err: fmt.Errorf("invalid amount in requirements: %q", req.Amount)}
+			}
+
+			extra := make(map[string]interface{}, len(req.Extra)+1)
+			for key, value := range req.Extra {
+				extra[key] = value
+			}
+			extra["range"] = rangeSpec
+
+			req.Amount = proportionalAmount(amount, length, total).String()
+			req.Extra = extra
+			priced[i] = req
+		}
+		return priced, nil
+	}
+}
+
+// proportionalAmount returns ceil(amount * length / total), at least 1 when amount is positive.
+func proportionalAmount(amount *big.Int, length, total int64) *big.Int {
+	if amount.Sign() <= 0 || total <= 0 {
+		return new(big.Int).Set(amount)
+	}
+
+	numerator := new(big.Int).Mul(amount, big.NewInt(length))
+	denominator := big.NewInt(total)
+	price, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+	if remainder.Sign() > 0 || price.Sign() == 0 {
+		price.Add(price, big.NewInt(1))
+	}
+	return price
+}
+
+// ParseRange parses a Range header ("bytes=0-99", "bytes=100-", "bytes=-500",
+// or a comma-separated list) against a resource of size bytes. End offsets past
+// the end of the resource are clamped. Returns ErrRangeNotSatisfiable for
+// malformed headers and ranges starting beyond the resource.
+func ParseRange(header string, size int64) ([]ByteRange, error) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported range unit in %q", ErrRangeNotSatisfiable, header)
+	}
+
+	var ranges []ByteRange
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		startStr, endStr, found := strings.Cut(part, "-")
+		if !found {
+			return nil, fmt.Errorf("%w: malformed range %q", ErrRangeNotSatisfiable, part)
+		}
+
+		var byteRange ByteRange
+		switch {
+		case startStr == "":
+			// Suffix range: the last n bytes
+			n, err := strconv.ParseInt(endStr, 10, 64)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("%w: malformed range %q", ErrRangeNotSatisfiable, part)
+			}
+			if n > size {
+				n = size
+			}
+			byteRange = ByteRange{Start: size - n, End: size - 1}
+		default:
+			start, err := strconv.ParseInt(startStr, 10, 64)
+			if err != nil || start < 0 {
+				return nil, fmt.Errorf("%w: malformed range %q", ErrRangeNotSatisfiable, part)
+			}
+			end := size - 1
+			if endStr != "" {
+				end, err = strconv.ParseInt(endStr, 10, 64)
+				if err != nil || end < start {
+					return nil, fmt.Errorf("%w: malformed range %q", ErrRangeNotSatisfiable, part)
+				}
+				if end > size-1 {
+					end = size - 1
+				}
+			}
+			byteRange = ByteRange{Start: start, End: end}
+		}
+
+		if byteRange.Start >= size || byteRange.Length() <= 0 {
+			return nil, fmt.Errorf("%w: range %q exceeds resource size %d", ErrRangeNotSatisfiable, part, size)
+		}
+		ranges = append(ranges, byteRange)
+	}
+	return ranges, nil
+}
+
+// DownloadRange downloads url in chunks of chunkSize bytes starting at offset,
+// writing each chunk to w. Every chunk is a separate Range request, so with an
+// x402 client each chunk carries its own payment priced for its size. To resume
+// an interrupted download, call it again with offset set to the bytes already
+// written. Returns the number of bytes written.
+func (c *Client) DownloadRange(ctx context.Context, url string, offset, chunkSize int64, w io.Writer) (int64, error) {
+	if chunkSize <= 0 {
+		return 0, fmt.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+
+	var written int64
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return written, err
+		}
+		req.Header.Set("Range", ByteRange{Start: offset, End: offset + chunkSize - 1}.String())
+
+		resp, err := c.Do(req)
+		if err != nil {
+			return written, err
+		}
+
+		switch resp.StatusCode {
+		case http.StatusPartialContent:
+		case http.StatusRequestedRangeNotSatisfiable:
+			// Offset is at or past the end of the resource
+			resp.Body.Close()
+			return written, nil
+		default:
+			resp.Body.Close()
+			return written, fmt.Errorf("unexpected status %d downloading range at offset %d", resp.StatusCode, offset)
+		}
+
+		n, err := io.Copy(w, resp.Body)
+		resp.Body.Close()
+		written += n
+		offset += n
+		if err != nil {
+			return written, err
+		}
+
+		total, ok := contentRangeTotal(resp.Header.Get("Content-Range"))
+		if n == 0 || (ok && offset >= total) {
+			return written, nil
+		}
+	}
+}
+
+// contentRangeTotal parses the total size from a Content-Range header
+// ("bytes 0-99/1000"). It returns false if the size is unknown.
+func contentRangeTotal(header string) (int64, bool) {
+	_, totalStr, found := strings.Cut(header, "/")
+	if !found || totalStr == "*" {
+		return 0, false
+	}
+	total, err := strconv.ParseInt(totalStr, 10, 64)
+	return total, err == nil
+}
@@ -0,0 +1,217 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    []ByteRange
+		wantErr bool
+	}{
+		{header: "bytes=0-99", want: []ByteRange{{0, 99}}},
+		{header: "bytes=900-", want: []ByteRange{{900, 999}}},
+		{header: "bytes=-100", want: []ByteRange{{900, 999}}},
+		{header: "bytes=950-2000", want: []ByteRange{{950, 999}}},
+		{header: "bytes=0-9, 20-29", want: []ByteRange{{0, 9}, {20, 29}}},
+		{header: "bytes=1000-", wantErr: true},
+		{header: "bytes=10-5", wantErr: true},
+		{header: "items=0-1", wantErr: true},
+		{header: "bytes=abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, err := ParseRange(tt.header, 1000)
+			if tt.wantErr {
+				if !errors.Is(err, ErrRangeNotSatisfiable) {
+					t.Errorf("expected ErrRangeNotSatisfiable, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRangePricing(t *testing.T) {
+	pricing := RangePricing(func(r *http.Request) (int64, error) { return 1000, nil })
+	base := []v2.PaymentRequirements{{Amount: "10000", Extra: map[string]interface{}{"name": "USDC"}}}
+
+	tests := []struct {
+		rangeHeader string
+		wantAmount  string
+		wantStatus  int
+	}{
+		{rangeHeader: "", wantAmount: "10000"},
+		{rangeHeader: "bytes=0-99", wantAmount: "1000"},
+		{rangeHeader: "bytes=0-0", wantAmount: "10"},
+		{rangeHeader: "bytes=0-2,10-12", wantAmount: "60"},
+		{rangeHeader: "bytes=5000-", wantStatus: http.StatusRequestedRangeNotSatisfiable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rangeHeader, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/file", nil)
+			if tt.rangeHeader != "" {
+				req.Header.Set("Range", tt.rangeHeader)
+			}
+
+			got, err := pricing(req, base)
+			if tt.wantStatus != 0 {
+				var statusErr *StatusError
+				if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantStatus {
+					t.Fatalf("expected status %d, got %v", tt.wantStatus, err)
+				}
+				if statusErr.Header.Get("Content-Range") != "bytes */1000" {
+					t.Errorf("expected Content-Range bytes */1000, got %q", statusErr.Header.Get("Content-Range"))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got[0].Amount != tt.wantAmount {
+				t.Errorf("expected amount %s, got %s", tt.wantAmount, got[0].Amount)
+			}
+			if tt.rangeHeader != "" && got[0].Extra["range"] == nil {
+				t.Error("expected range in Extra")
+			}
+		})
+	}
+
+	if _, ok := base[0].Extra["range"]; ok {
+		t.Error("base requirements must not be modified")
+	}
+	if got := proportionalAmount(big.NewInt(1), 1, 1000); got.String() != "1" {
+		t.Errorf("expected minimum price 1, got %s", got)
+	}
+}
+
+func TestMiddleware_RangePricing(t *testing.T) {
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+		case "/verify":
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	content := strings.Repeat("x", 1000)
+	middleware := NewX402Middleware(Config{
+		FacilitatorURL: facilitatorServer.URL,
+		PaymentRequirements: []v2.PaymentRequirements{{
+			Scheme:  "exact",
+			Network: "eip155:84532",
+			Amount:  "10000",
+			Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+			PayTo:   "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		}},
+		RequirementsFunc: RangePricing(func(r *http.Request) (int64, error) { return int64(len(content)), nil }),
+	})
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+
+	request := func(amount, ifRange string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/file", nil)
+		req.Header.Set("Range", "bytes=0-99")
+		if ifRange != "" {
+			req.Header.Set("If-Range", ifRange)
+		}
+		if amount != "" {
+			header, _ := encoding.EncodePayment(v2.PaymentPayload{
+				X402Version: 2,
+				Accepted:    v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: amount},
+				Payload:     map[string]interface{}{"signature": "0xsig"},
+			})
+			req.Header.Set("X-PAYMENT", header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("", "")
+	var paymentRequired v2.PaymentRequired
+	if err := json.NewDecoder(w.Body).Decode(&paymentRequired); err != nil {
+		t.Fatalf("failed to decode 402 response: %v", err)
+	}
+	if w.Code != http.StatusPaymentRequired || paymentRequired.Accepts[0].Amount != "1000" {
+		t.Errorf("expected 402 priced at 1000, got %d with %+v", w.Code, paymentRequired.Accepts)
+	}
+
+	if w := request("500", ""); w.Code != http.StatusPaymentRequired {
+		t.Errorf("expected underpaid range to be rejected, got %d", w.Code)
+	}
+
+	w = request("1000", "")
+	if w.Code != http.StatusPartialContent || w.Body.Len() != 100 {
+		t.Errorf("expected 206 with 100 bytes, got %d with %d bytes", w.Code, w.Body.Len())
+	}
+
+	// A stale If-Range must not turn the paid range into the whole resource
+	w = request("1000", `"stale"`)
+	if w.Code != http.StatusPartialContent || w.Body.Len() != 100 {
+		t.Errorf("expected 206 with 100 bytes despite If-Range, got %d with %d bytes", w.Code, w.Body.Len())
+	}
+}
+
+func TestClient_DownloadRange(t *testing.T) {
+	content := strings.Repeat("0123456789", 25)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	client := &Client{Client: server.Client()}
+
+	var buf bytes.Buffer
+	written, err := client.DownloadRange(context.Background(), server.URL, 0, 100, &buf)
+	if err != nil {
+		t.Fatalf("DownloadRange failed: %v", err)
+	}
+	if written != 250 || buf.String() != content {
+		t.Errorf("expected full content, got %d bytes", written)
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 chunk requests, got %d", requests)
+	}
+
+	// Resume from the middle
+	buf.Reset()
+	written, err = client.DownloadRange(context.Background(), server.URL, 200, 100, &buf)
+	if err != nil || written != 50 || buf.String() != content[200:] {
+		t.Errorf("expected resumed tail, got %d bytes, err %v", written, err)
+	}
+}
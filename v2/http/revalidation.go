@@ -0,0 +1,151 @@
+package http
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// RevalidationPolicy controls how conditional requests (If-None-Match or
+// If-Modified-Since) answered with 304 Not Modified are charged.
+type RevalidationPolicy int
+
+const (
+	// RevalidationCharge charges a 304 response like any other success. This is the default.
+	RevalidationCharge RevalidationPolicy = iota
+
+	// RevalidationFree does not charge 304 responses. Conditional requests without
+	// a payment reach the handler; if it answers 304 the response is delivered,
+	// otherwise the client gets the usual 402. Payments sent with a conditional
+	// request are settled only if the handler returns content.
+	RevalidationFree
+
+	// RevalidationReduced prices conditional requests at RevalidationBasisPoints
+	// of the full price. If the resource changed and the handler returns content,
+	// the reduced payment is not settled and the client gets a 402 with the full
+	// price instead.
+	RevalidationReduced
+)
+
+// DefaultRevalidationBasisPoints is the revalidation price used by
+// RevalidationReduced when Config.RevalidationBasisPoints is unset (10%).
+const DefaultRevalidationBasisPoints = 1000
+
+// String returns the policy name.
+func (p RevalidationPolicy) String() string {
+	switch p {
+	case RevalidationCharge:
+		return "charge"
+	case RevalidationFree:
+		return "free"
+	case RevalidationReduced:
+		return "reduced"
+	default:
+		return fmt.Sprintf("RevalidationPolicy(%d)", int(p))
+	}
+}
+
+// IsConditional reports whether r is a conditional GET or HEAD request that may
+// be answered with 304 Not Modified.
+func IsConditional(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	return r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != ""
+}
+
+// Revalidating reports whether r is a conditional request handled under a
+// policy other than RevalidationCharge. Settlement of such requests waits for
+// the handler's status.
+func (c Config) Revalidating(r *http.Request) bool {
+	return c.Revalidation != RevalidationCharge && IsConditional(r)
+}
+
+// RevalidationOutcome reports whether a paid response with statusCode to r is
+// settled, and whether it may be delivered at all. Only RevalidationReduced
+// refuses delivery, when a revalidation payment would otherwise buy content.
+func (c Config) RevalidationOutcome(r *http.Request, statusCode int) (settle, deliver bool) {
+	if !c.Revalidating(r) {
+		return true, true
+	}
+	if statusCode == http.StatusNotModified {
+		return c.Revalidation != RevalidationFree, true
+	}
+	if c.Revalidation == RevalidationReduced {
+		return false, false
+	}
+	return true, true
+}
+
+// revalidationRequirements prices requirements for a conditional request under
+// RevalidationReduced. The reduced price is marked with Extra["revalidation"].
+func (c Config) revalidationRequirements(r *http.Request, base []v2.PaymentRequirements) ([]v2.PaymentRequirements, error) {
+	if c.Revalidation != RevalidationReduced || !IsConditional(r) {
+		return base, nil
+	}
+
+	basisPoints := c.RevalidationBasisPoints
+	if basisPoints <= 0 {
+		basisPoints = DefaultRevalidationBasisPoints
+	}
+
+	priced := make([]v2.PaymentRequirements, len(base))
+	for i, req := range base {
+		amount, ok := new(big.Int).SetString(req.Amount, 10)
+		if !ok {
+			return nil, &StatusError{StatusCode: http.StatusInternalServerError, Err: fmt.Errorf("invalid amount in requirements: %q", req.Amount)}
+		}
+
+		extra := make(map[string]interface{}, len(req.Extra)+1)
+		for key, value := range req.Extra {
+			extra[key] = value
+		}
+		extra["revalidation"] = true
+
+		req.Amount = proportionalAmount(amount, int64(basisPoints), 10000).String()
+		req.Extra = extra
+		priced[i] = req
+	}
+	return priced, nil
+}
+
+// revalidationWriter forwards a handler's response only if it is 304 Not
+// Modified. It serves unpaid conditional requests under RevalidationFree.
+type revalidationWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	wroteHeader bool
+	notModified bool
+}
+
+func (rw *revalidationWriter) Header() http.Header {
+	if rw.header == nil {
+		rw.header = make(http.Header)
+	}
+	return rw.header
+}
+
+func (rw *revalidationWriter) WriteHeader(statusCode int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+	if statusCode != http.StatusNotModified {
+		return
+	}
+	rw.notModified = true
+	for key, values := range rw.header {
+		rw.w.Header()[key] = values
+	}
+	rw.w.WriteHeader(statusCode)
+}
+
+func (rw *revalidationWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	// 304 responses have no body, and anything else is replaced by a 402
+	return len(b), nil
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
+)
+
+func TestMiddleware_Revalidation(t *testing.T) {
+	var settleCalls atomic.Int32
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+		case "/verify":
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			settleCalls.Add(1)
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	pay := func(amount string) string {
+		accepted := requirement
+		accepted.Amount = amount
+		header, _ := encoding.EncodePayment(v2.PaymentPayload{
+			X402Version: 2,
+			Accepted:    accepted,
+			Payload:     map[string]interface{}{"signature": "0xsig"},
+		})
+		return header
+	}
+
+	// The handler serves ETag "v1"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte("content"))
+	})
+
+	tests := []struct {
+		name        string
+		policy      RevalidationPolicy
+		ifNoneMatch string
+		payment     string
+		wantStatus  int
+		wantSettles int32
+		wantAmount  string
+	}{
+		{name: "charge: 304 settles", policy: RevalidationCharge, ifNoneMatch: `"v1"`, payment: pay("10000"), wantStatus: http.StatusNotModified, wantSettles: 1},
+		{name: "free: unpaid 304", policy: RevalidationFree, ifNoneMatch: `"v1"`, wantStatus: http.StatusNotModified},
+		{name: "free: unpaid changed resource", policy: RevalidationFree, ifNoneMatch: `"v0"`, wantStatus: http.StatusPaymentRequired, wantAmount: "10000"},
+		{name: "free: paid 304 does not settle", policy: RevalidationFree, ifNoneMatch: `"v1"`, payment: pay("10000"), wantStatus: http.StatusNotModified},
+		{name: "free: paid changed resource settles", policy: RevalidationFree, ifNoneMatch: `"v0"`, payment: pay("10000"), wantStatus: http.StatusOK, wantSettles: 1},
+		{name: "reduced: unpaid is quoted the revalidation price", policy: RevalidationReduced, ifNoneMatch: `"v1"`, wantStatus: http.StatusPaymentRequired, wantAmount: "1000"},
+		{name: "reduced: 304 settles reduced payment", policy: RevalidationReduced, ifNoneMatch: `"v1"`, payment: pay("1000"), wantStatus: http.StatusNotModified, wantSettles: 1},
+		{name: "reduced: changed resource asks for full price", policy: RevalidationReduced, ifNoneMatch: `"v0"`, payment: pay("1000"), wantStatus: http.StatusPaymentRequired, wantAmount: "10000"},
+		{name: "reduced: full payment rejected for revalidation", policy: RevalidationReduced, ifNoneMatch: `"v1"`, payment: pay("10000"), wantStatus: http.StatusPaymentRequired, wantAmount: "1000"},
+		{name: "reduced: unconditional request pays full price", policy: RevalidationReduced, payment: pay("10000"), wantStatus: http.StatusOK, wantSettles: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settleCalls.Store(0)
+			middleware := NewX402Middleware(Config{
+				FacilitatorURL:      facilitatorServer.URL,
+				PaymentRequirements: []v2.PaymentRequirements{requirement},
+				Revalidation:        tt.policy,
+			})
+
+			req := httptest.NewRequest("GET", "/api/data", nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			if tt.payment != "" {
+				req.Header.Set("X-PAYMENT", tt.payment)
+			}
+			w := httptest.NewRecorder()
+			middleware(handler).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if got := settleCalls.Load(); got != tt.wantSettles {
+				t.Errorf("Expected %d settle calls, got %d", tt.wantSettles, got)
+			}
+			if tt.wantAmount != "" {
+				var body v2.PaymentRequired
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || len(body.Accepts) != 1 {
+					t.Fatalf("Expected payment requirements, got %s", w.Body.String())
+				}
+				if body.Accepts[0].Amount != tt.wantAmount {
+					t.Errorf("Expected amount %s, got %s", tt.wantAmount, body.Accepts[0].Amount)
+				}
+			}
+		})
+	}
+}
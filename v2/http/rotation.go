@@ -0,0 +1,264 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// DefaultRotationGrace is how long a retired PayTo address is still accepted
+// after rotation, so clients that received a 402 before the rotation can pay.
+const DefaultRotationGrace = 10 * time.Minute
+
+// Rotation event kinds reported in RotationEvent.Kind.
+const (
+	RotationPayTo                            = "payTo"
+	RotationFacilitatorAuthorization         = "facilitatorAuthorization"
+	RotationFallbackFacilitatorAuthorization = "fallbackFacilitatorAuthorization"
+)
+
+// RotationEvent is the audit record of a rotation.
+type RotationEvent struct {
+	// Time is when the rotation took effect.
+	Time time.Time
+
+	// Kind is one of the Rotation constants.
+	Kind string
+
+	// Network is the network pattern of a PayTo rotation, like "eip155:*".
+	Network string
+
+	// Old and New are the previous and new values. Credentials are reported as
+	// the first 8 bytes of their SHA-256 in hex, never in clear.
+	Old, New string
+}
+
+// Rotator rotates the PayTo addresses and facilitator credentials of a running
+// middleware without a restart. Set it as Config.Rotator.
+//
+// Each request uses the values current when it started, so in-flight
+// verifications and settlements complete against the old values while new
+// 402 responses advertise the new ones. Payments to a retired PayTo are still
+// accepted for a grace period.
+type Rotator struct {
+	grace   time.Duration
+	clock   v2.Clock
+	onEvent func(RotationEvent)
+
+	mu                    sync.RWMutex
+	payTo                 map[string]string
+	retired               []retiredPayTo
+	authorization         string
+	fallbackAuthorization string
+}
+
+type retiredPayTo struct {
+	network string
+	address string
+	until   time.Time
+}
+
+// RotatorOption configures a Rotator.
+type RotatorOption func(*Rotator)
+
+// WithRotationGrace sets how long retired PayTo addresses are accepted
+// (default: DefaultRotationGrace). It should cover the longest
+// MaxTimeoutSeconds of the requirements.
+func WithRotationGrace(grace time.Duration) RotatorOption {
+	return func(r *Rotator) {
+		r.grace = grace
+	}
+}
+
+// WithRotationClock sets the clock used for event times and grace periods.
+func WithRotationClock(clock v2.Clock) RotatorOption {
+	return func(r *Rotator) {
+		r.clock = clock
+	}
+}
+
+// WithRotationAudit calls onEvent after every rotation.
+func WithRotationAudit(onEvent func(RotationEvent)) RotatorOption {
+	return func(r *Rotator) {
+		r.onEvent = onEvent
+	}
+}
+
+// NewRotator creates a Rotator. Until a value is rotated, the configured one
+// is used.
+func NewRotator(opts ...RotatorOption) *Rotator {
+	r := &Rotator{
+		grace: DefaultRotationGrace,
+		payTo: make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// RotatePayTo makes address the PayTo of every requirement on networks
+// matching network, a CAIP-2 network or pattern like "solana:*". The previous
+// addresses remain accepted for the grace period.
+func (r *Rotator) RotatePayTo(network, address string, current []v2.PaymentRequirements) {
+	now := v2.ClockOrSystem(r.clock).Now()
+	r.mu.Lock()
+	old := r.payTo[network]
+	seen := make(map[string]bool)
+	for _, req := range r.apply(current) {
+		if v2.MatchNetworkPattern(network, req.Network) && req.PayTo != address && !seen[req.Network+req.PayTo] {
+			seen[req.Network+req.PayTo] = true
+			r.retired = append(r.retired, retiredPayTo{network: req.Network, address: req.PayTo, until: now.Add(r.grace)})
+			if old == "" {
+				old = req.PayTo
+			}
+		}
+	}
+	r.payTo[network] = address
+	r.mu.Unlock()
+
+	r.emit(RotationEvent{Time: now, Kind: RotationPayTo, Network: network, Old: old, New: address})
+}
+
+// RotateFacilitatorAuthorization replaces the Authorization header sent to
+// the primary facilitator. Requests already sent keep the old credentials.
+func (r *Rotator) RotateFacilitatorAuthorization(authorization string) {
+	r.mu.Lock()
+	old := r.authorization
+	r.authorization = authorization
+	r.mu.Unlock()
+	r.emit(RotationEvent{
+		Time: v2.ClockOrSystem(r.clock).Now(),
+		Kind: RotationFacilitatorAuthorization,
+		Old:  credentialFingerprint(old),
+		New:  credentialFingerprint(authorization),
+	})
+}
+
+// RotateFallbackFacilitatorAuthorization replaces the Authorization header
+// sent to the fallback facilitator.
+func (r *Rotator) RotateFallbackFacilitatorAuthorization(authorization string) {
+	r.mu.Lock()
+	old := r.fallbackAuthorization
+	r.fallbackAuthorization = authorization
+	r.mu.Unlock()
+	r.emit(RotationEvent{
+		Time: v2.ClockOrSystem(r.clock).Now(),
+		Kind: RotationFallbackFacilitatorAuthorization,
+		Old:  credentialFingerprint(old),
+		New:  credentialFingerprint(authorization),
+	})
+}
+
+// Apply returns requirements with the current PayTo addresses. A nil Rotator
+// returns requirements unchanged.
+func (r *Rotator) Apply(requirements []v2.PaymentRequirements) []v2.PaymentRequirements {
+	if r == nil {
+		return requirements
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.apply(requirements)
+}
+
+// apply rewrites PayTo with the most specific rotated pattern. The caller
+// holds r.mu.
+func (r *Rotator) apply(requirements []v2.PaymentRequirements) []v2.PaymentRequirements {
+	if len(r.payTo) == 0 {
+		return requirements
+	}
+	rotated := make([]v2.PaymentRequirements, len(requirements))
+	copy(rotated, requirements)
+	for i := range rotated {
+		if addresses := v2.SignersForNetwork(payToPatterns(r.payTo), rotated[i].Network); len(addresses) > 0 {
+			rotated[i].PayTo = addresses[0]
+		}
+	}
+	return rotated
+}
+
+// Retired returns requirements with PayTo set to the payment's PayTo on its
+// network when that address was retired within the grace period, so the
+// payment is verified against the address it was signed for. Otherwise it
+// returns requirements unchanged.
+func (r *Rotator) Retired(payment *v2.PaymentPayload, requirements []v2.PaymentRequirements) []v2.PaymentRequirements {
+	if r == nil {
+		return requirements
+	}
+	now := v2.ClockOrSystem(r.clock).Now()
+	r.mu.RLock()
+	retired := false
+	for _, old := range r.retired {
+		if old.network == payment.Accepted.Network && old.address == payment.Accepted.PayTo && now.Before(old.until) {
+			retired = true
+			break
+		}
+	}
+	r.mu.RUnlock()
+	if !retired {
+		return requirements
+	}
+
+	honored := make([]v2.PaymentRequirements, len(requirements))
+	copy(honored, requirements)
+	for i := range honored {
+		if honored[i].Network == payment.Accepted.Network {
+			honored[i].PayTo = payment.Accepted.PayTo
+		}
+	}
+	return honored
+}
+
+// authorizationProvider returns a provider sending the rotated credentials
+// of the primary or fallback facilitator, and otherwise those of next or
+// static.
+func (r *Rotator) authorizationProvider(fallback bool, static string, next AuthorizationProvider) AuthorizationProvider {
+	return func(req *http.Request) string {
+		r.mu.RLock()
+		authorization := r.authorization
+		if fallback {
+			authorization = r.fallbackAuthorization
+		}
+		r.mu.RUnlock()
+		switch {
+		case authorization != "":
+			return authorization
+		case next != nil:
+			return next(req)
+		default:
+			return static
+		}
+	}
+}
+
+// emit logs and reports event.
+func (r *Rotator) emit(event RotationEvent) {
+	slog.Default().Info("rotated payment configuration", "kind", event.Kind, "network", event.Network, "old", event.Old, "new", event.New)
+	if r.onEvent != nil {
+		r.onEvent(event)
+	}
+}
+
+// payToPatterns adapts rotated PayTo addresses to SignersForNetwork.
+func payToPatterns(payTo map[string]string) map[string][]string {
+	patterns := make(map[string][]string, len(payTo))
+	for pattern, address := range payTo {
+		patterns[pattern] = []string{address}
+	}
+	return patterns
+}
+
+// credentialFingerprint identifies a credential in audit events without
+// revealing it.
+func credentialFingerprint(credential string) string {
+	if credential == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(credential))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func TestRotator_PayTo(t *testing.T) {
+	clock := v2.NewFakeClock(time.Unix(1700000000, 0))
+	var events []RotationEvent
+	rotator := NewRotator(
+		WithRotationGrace(time.Minute),
+		WithRotationClock(clock),
+		WithRotationAudit(func(e RotationEvent) { events = append(events, e) }),
+	)
+	config := Config{Rotator: rotator}
+	base := []v2.PaymentRequirements{
+		{Scheme: "exact", Network: "eip155:8453", Amount: "100", PayTo: "0xOld"},
+		{Scheme: "exact", Network: "solana:mainnet", Amount: "100", PayTo: "SolOld"},
+	}
+
+	rotator.RotatePayTo("eip155:*", "0xNew", base)
+	if len(events) != 1 || events[0].Kind != RotationPayTo || events[0].Old != "0xOld" || events[0].New != "0xNew" {
+		t.Fatalf("Unexpected audit events: %+v", events)
+	}
+
+	advertised, err := config.RequirementsFor(httptest.NewRequest("GET", "/", nil), base)
+	if err != nil {
+		t.Fatalf("RequirementsFor failed: %v", err)
+	}
+	if advertised[0].PayTo != "0xNew" || advertised[1].PayTo != "SolOld" {
+		t.Errorf("Expected only the EVM PayTo rotated, got %s and %s", advertised[0].PayTo, advertised[1].PayTo)
+	}
+	if base[0].PayTo != "0xOld" {
+		t.Error("Apply must not modify the base requirements")
+	}
+
+	tests := []struct {
+		name    string
+		payTo   string
+		advance time.Duration
+		want    string
+	}{
+		{"new address", "0xNew", 0, "0xNew"},
+		{"retired address in grace", "0xOld", 0, "0xOld"},
+		{"unknown address", "0xOther", 0, "0xNew"},
+		{"retired address after grace", "0xOld", 2 * time.Minute, "0xNew"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Advance(tt.advance)
+			payment := &v2.PaymentPayload{Accepted: v2.PaymentRequirements{Scheme: "exact", Network: "eip155:8453", PayTo: tt.payTo}}
+			matched := config.Rotator.Retired(payment, advertised)
+			if matched[0].PayTo != tt.want {
+				t.Errorf("Expected PayTo %s, got %s", tt.want, matched[0].PayTo)
+			}
+		})
+	}
+}
+
+func TestRotator_FacilitatorAuthorization(t *testing.T) {
+	var events []RotationEvent
+	rotator := NewRotator(WithRotationAudit(func(e RotationEvent) { events = append(events, e) }))
+	config := Config{
+		FacilitatorURL:                   "http://primary",
+		FallbackFacilitatorURL:           "http://fallback",
+		FacilitatorAuthorization:         "Bearer old",
+		FallbackFacilitatorAuthorization: "Bearer fallback-old",
+		Rotator:                          rotator,
+	}
+	facilitator, fallback := config.FacilitatorClients()
+
+	header := func(c *FacilitatorClient) string {
+		req := httptest.NewRequest("POST", "/verify", nil)
+		c.setAuthorizationHeader(req)
+		return req.Header.Get("Authorization")
+	}
+	if got := header(facilitator); got != "Bearer old" {
+		t.Errorf("Expected configured credentials before rotation, got %q", got)
+	}
+
+	rotator.RotateFacilitatorAuthorization("Bearer new")
+	if got := header(facilitator); got != "Bearer new" {
+		t.Errorf("Expected rotated credentials, got %q", got)
+	}
+	if got := header(fallback); got != "Bearer fallback-old" {
+		t.Errorf("Expected fallback credentials unchanged, got %q", got)
+	}
+
+	rotator.RotateFallbackFacilitatorAuthorization("Bearer fallback-new")
+	if got := header(fallback); got != "Bearer fallback-new" {
+		t.Errorf("Expected rotated fallback credentials, got %q", got)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 audit events, got %d", len(events))
+	}
+	if events[0].New == "" || events[0].New == "Bearer new" {
+		t.Errorf("Expected a credential fingerprint, got %q", events[0].New)
+	}
+}
@@ -0,0 +1,235 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// maxFacilitatorResponseSize bounds the facilitator response bodies read.
+const maxFacilitatorResponseSize = 1 << 20
+
+// FacilitatorResponseError reports a facilitator response that does not have
+// the shape the protocol requires, such as a missing or mistyped field. It
+// wraps v2.ErrInvalidFacilitatorResponse.
+type FacilitatorResponseError struct {
+	// Endpoint is the facilitator endpoint, like "/verify".
+	Endpoint string
+
+	// Field is the JSON path of the offending field, like "kinds[1].network",
+	// or empty when the body itself is invalid.
+	Field string
+
+	// Reason describes the problem.
+	Reason string
+}
+
+func (e *FacilitatorResponseError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%v: %s: %s", v2.ErrInvalidFacilitatorResponse, e.Endpoint, e.Reason)
+	}
+	return fmt.Sprintf("%v: %s: %s: %s", v2.ErrInvalidFacilitatorResponse, e.Endpoint, e.Field, e.Reason)
+}
+
+func (e *FacilitatorResponseError) Unwrap() error {
+	return v2.ErrInvalidFacilitatorResponse
+}
+
+// jsonObject is a decoded JSON object whose fields are checked before the
+// object is decoded into its Go type, which would silently zero missing or
+// mistyped fields.
+type jsonObject struct {
+	endpoint string
+	path     string
+	fields   map[string]json.RawMessage
+}
+
+// parseObject parses data as a JSON object.
+func parseObject(endpoint, path string, data json.RawMessage) (*jsonObject, error) {
+	var fields map[string]json.RawMessage
+	if jsonType(data) != "object" || json.Unmarshal(data, &fields) != nil {
+		return nil, &FacilitatorResponseError{Endpoint: endpoint, Field: path, Reason: "not a JSON object"}
+	}
+	return &jsonObject{endpoint: endpoint, path: path, fields: fields}, nil
+}
+
+// check fails if field is present with a type other than want, or, if
+// required, is absent or null. It returns the field and whether it is set.
+func (o *jsonObject) check(field, want string, required bool) (json.RawMessage, bool, error) {
+	raw, ok := o.fields[field]
+	if !ok || jsonType(raw) == "null" {
+		if required {
+			return nil, false, o.fail(field, "missing")
+		}
+		return nil, false, nil
+	}
+	if got := jsonType(raw); got != want {
+		return nil, false, o.fail(field, fmt.Sprintf("expected %s, got %s", want, got))
+	}
+	return raw, true, nil
+}
+
+// fail returns the error for field.
+func (o *jsonObject) fail(field, reason string) error {
+	path := field
+	if o.path != "" {
+		path = o.path + "." + field
+	}
+	return &FacilitatorResponseError{Endpoint: o.endpoint, Field: path, Reason: reason}
+}
+
+// jsonType returns the JSON type of raw: object, array, string, number,
+// boolean or null.
+func jsonType(raw json.RawMessage) string {
+	trimmed := strings.TrimLeft(string(raw), " \t\r\n")
+	if trimmed == "" {
+		return "empty"
+	}
+	switch trimmed[0] {
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	default:
+		return "number"
+	}
+}
+
+// readFacilitatorResponse reads a facilitator response body.
+func readFacilitatorResponse(endpoint string, body io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(body, maxFacilitatorResponseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading %s response: %v", v2.ErrFacilitatorUnavailable, endpoint, err)
+	}
+	if len(data) > maxFacilitatorResponseSize {
+		return nil, &FacilitatorResponseError{Endpoint: endpoint, Reason: "response too large"}
+	}
+	return data, nil
+}
+
+// validateVerifyResponse checks the shape of a /verify response: isValid is
+// required, and the reason, message and payer are strings.
+func validateVerifyResponse(data []byte) error {
+	obj, err := parseObject("/verify", "", data)
+	if err != nil {
+		return err
+	}
+	if _, _, err := obj.check("isValid", "boolean", true); err != nil {
+		return err
+	}
+	for _, field := range []string{"invalidReason", "invalidMessage", "payer"} {
+		if _, _, err := obj.check(field, "string", false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSettleResponse checks the shape of a /settle response: success is
+// required, and successful settlements carry their transaction and network.
+func validateSettleResponse(data []byte) error {
+	obj, err := parseObject("/settle", "", data)
+	if err != nil {
+		return err
+	}
+	raw, _, err := obj.check("success", "boolean", true)
+	if err != nil {
+		return err
+	}
+	success := string(raw) == "true"
+	for _, field := range []string{"transaction", "network"} {
+		if _, _, err := obj.check(field, "string", success); err != nil {
+			return err
+		}
+	}
+	for _, field := range []string{"errorReason", "errorMessage", "payer"} {
+		if _, _, err := obj.check(field, "string", false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSupportedResponse checks the shape of a /supported response: kinds
+// is required, and every kind has a positive x402Version, a scheme and a
+// network, which is a CAIP-2 identifier for kinds of X402Version or later.
+func validateSupportedResponse(data []byte) error {
+	obj, err := parseObject("/supported", "", data)
+	if err != nil {
+		return err
+	}
+	// A facilitator supporting nothing may list kinds as null
+	if _, ok := obj.fields["kinds"]; !ok {
+		return obj.fail("kinds", "missing")
+	}
+	raw, ok, err := obj.check("kinds", "array", false)
+	if err != nil {
+		return err
+	}
+	var kinds []json.RawMessage
+	if ok && json.Unmarshal(raw, &kinds) != nil {
+		return obj.fail("kinds", "not a JSON array")
+	}
+	for i, rawKind := range kinds {
+		kind, err := parseObject("/supported", fmt.Sprintf("kinds[%d]", i), rawKind)
+		if err != nil {
+			return err
+		}
+		rawVersion, _, err := kind.check("x402Version", "number", true)
+		if err != nil {
+			return err
+		}
+		var version int
+		if err := json.Unmarshal(rawVersion, &version); err != nil || version < 1 {
+			return kind.fail("x402Version", "not a positive integer")
+		}
+		for _, field := range []string{"scheme", "network"} {
+			value, _, err := kind.check(field, "string", true)
+			if err != nil {
+				return err
+			}
+			if string(value) == `""` {
+				return kind.fail(field, "empty")
+			}
+		}
+		if version >= v2.X402Version {
+			var network string
+			_ = json.Unmarshal(kind.fields["network"], &network)
+			if !strings.Contains(network, ":") {
+				return kind.fail("network", fmt.Sprintf("%q is not a CAIP-2 network", network))
+			}
+		}
+		if _, _, err := kind.check("extra", "object", false); err != nil {
+			return err
+		}
+	}
+
+	for _, field := range []string{"extensions", "attestationKeys"} {
+		raw, ok, err := obj.check(field, "array", false)
+		if err != nil {
+			return err
+		}
+		var values []string
+		if ok && json.Unmarshal(raw, &values) != nil {
+			return obj.fail(field, "expected an array of strings")
+		}
+	}
+	raw, ok, err = obj.check("signers", "object", false)
+	if err != nil {
+		return err
+	}
+	var signers map[string][]string
+	if ok && json.Unmarshal(raw, &signers) != nil {
+		return obj.fail("signers", "expected an object of string arrays")
+	}
+	return nil
+}
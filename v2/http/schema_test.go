@@ -0,0 +1,88 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func TestValidateFacilitatorResponses(t *testing.T) {
+	tests := []struct {
+		name      string
+		validate  func([]byte) error
+		body      string
+		wantField string
+	}{
+		{name: "verify valid", validate: validateVerifyResponse, body: `{"isValid":true,"payer":"0xpayer"}`},
+		{name: "verify invalid with reason", validate: validateVerifyResponse, body: `{"isValid":false,"invalidReason":"insufficient_funds"}`},
+		{name: "verify missing isValid", validate: validateVerifyResponse, body: `{"payer":"0xpayer"}`, wantField: "isValid"},
+		{name: "verify isValid as string", validate: validateVerifyResponse, body: `{"isValid":"true"}`, wantField: "isValid"},
+		{name: "verify not an object", validate: validateVerifyResponse, body: `[]`, wantField: "-"},
+		{name: "settle success", validate: validateSettleResponse, body: `{"success":true,"transaction":"0xtx","network":"eip155:8453"}`},
+		{name: "settle failure without transaction", validate: validateSettleResponse, body: `{"success":false,"errorReason":"invalid_payment"}`},
+		{name: "settle success without transaction", validate: validateSettleResponse, body: `{"success":true,"network":"eip155:8453"}`, wantField: "transaction"},
+		{name: "settle v1 success field", validate: validateSettleResponse, body: `{"success":1,"transaction":"0xtx","network":"base"}`, wantField: "success"},
+		{name: "supported mixed versions", validate: validateSupportedResponse, body: `{"kinds":[{"x402Version":1,"scheme":"exact","network":"base"},{"x402Version":2,"scheme":"exact","network":"eip155:8453"}],"extensions":[],"signers":{"eip155:*":["0xsigner"]}}`},
+		{name: "supported null kinds", validate: validateSupportedResponse, body: `{"kinds":null,"extensions":null,"signers":null}`},
+		{name: "supported missing kinds", validate: validateSupportedResponse, body: `{"extensions":[]}`, wantField: "kinds"},
+		{name: "supported kind without version", validate: validateSupportedResponse, body: `{"kinds":[{"scheme":"exact","network":"eip155:8453"}]}`, wantField: "kinds[0].x402Version"},
+		{name: "supported v2 kind with v1 network", validate: validateSupportedResponse, body: `{"kinds":[{"x402Version":2,"scheme":"exact","network":"base"}]}`, wantField: "kinds[0].network"},
+		{name: "supported signers as list", validate: validateSupportedResponse, body: `{"kinds":[],"signers":["0xsigner"]}`, wantField: "signers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validate([]byte(tt.body))
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("expected a valid response, got %v", err)
+				}
+				return
+			}
+			var responseErr *FacilitatorResponseError
+			if !errors.As(err, &responseErr) || !errors.Is(err, v2.ErrInvalidFacilitatorResponse) {
+				t.Fatalf("expected a FacilitatorResponseError, got %v", err)
+			}
+			if tt.wantField != "-" && responseErr.Field != tt.wantField {
+				t.Errorf("expected field %q, got %q", tt.wantField, responseErr.Field)
+			}
+		})
+	}
+}
+
+func TestFacilitatorClient_InvalidResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			_, _ = w.Write([]byte(`{"valid":true}`))
+		case "/settle":
+			_, _ = w.Write([]byte(`{"success":true,"txHash":"0xtx"}`))
+		}
+	}))
+	defer server.Close()
+
+	payload := v2.PaymentPayload{X402Version: 2, Accepted: v2.PaymentRequirements{Scheme: "exact", Network: "eip155:8453"}}
+	requirements := payload.Accepted
+
+	client := &FacilitatorClient{BaseURL: server.URL}
+	if _, err := client.Verify(context.Background(), payload, requirements); !errors.Is(err, v2.ErrInvalidFacilitatorResponse) {
+		t.Errorf("expected an invalid verify response, got %v", err)
+	}
+	if _, err := client.Settle(context.Background(), payload, requirements); !errors.Is(err, v2.ErrInvalidFacilitatorResponse) {
+		t.Errorf("expected an invalid settle response, got %v", err)
+	}
+
+	client.LenientResponses = true
+	resp, err := client.Verify(context.Background(), payload, requirements)
+	if err != nil {
+		t.Fatalf("expected lenient decoding, got %v", err)
+	}
+	if resp.IsValid {
+		t.Error("expected the unknown field to be dropped")
+	}
+}
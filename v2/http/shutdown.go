@@ -0,0 +1,114 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrShuttingDown is returned by Drainer.Acquire once shutdown has begun.
+var ErrShuttingDown = errors.New("x402: server is shutting down")
+
+// ShutdownReport describes what a Drainer flushed during shutdown.
+type ShutdownReport struct {
+	// InFlight is the number of paid requests in flight when shutdown began.
+	InFlight int `json:"inFlight"`
+
+	// Flushed is the number of those requests that completed, including settlement.
+	Flushed int `json:"flushed"`
+
+	// Abandoned is the number still in flight when the shutdown context expired.
+	// Their settlements may be lost.
+	Abandoned int `json:"abandoned"`
+
+	// Rejected is the number of paid requests refused after shutdown began.
+	Rejected int `json:"rejected"`
+}
+
+// Drainer coordinates graceful shutdown of paid requests. Once Shutdown is
+// called, new paid requests are refused with 503 while requests already
+// accepted run to completion, so settlements already owed are not lost.
+//
+// Set it as Config.Drainer and call Shutdown after http.Server.Shutdown stops
+// accepting connections.
+type Drainer struct {
+	mu       sync.Mutex
+	closing  bool
+	inFlight int
+	report   ShutdownReport
+	idle     chan struct{}
+}
+
+// NewDrainer creates a Drainer.
+func NewDrainer() *Drainer {
+	return &Drainer{}
+}
+
+// Acquire registers a paid request. The returned release function must be
+// called when the request, including its settlement, has completed.
+// It returns ErrShuttingDown once shutdown has begun.
+func (d *Drainer) Acquire() (func(), error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closing {
+		d.report.Rejected++
+		return nil, ErrShuttingDown
+	}
+	d.inFlight++
+
+	var once sync.Once
+	return func() {
+		once.Do(d.release)
+	}, nil
+}
+
+func (d *Drainer) release() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.inFlight--
+	if d.closing {
+		d.report.Flushed++
+		if d.inFlight == 0 {
+			close(d.idle)
+		}
+	}
+}
+
+// ShuttingDown reports whether shutdown has begun.
+func (d *Drainer) ShuttingDown() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.closing
+}
+
+// Shutdown stops accepting paid requests and waits for in-flight ones to
+// complete or ctx to expire. It returns ctx.Err() if requests were abandoned.
+// Calling Shutdown again waits again and returns an updated report.
+func (d *Drainer) Shutdown(ctx context.Context) (ShutdownReport, error) {
+	d.mu.Lock()
+	if !d.closing {
+		d.closing = true
+		d.report.InFlight = d.inFlight
+		d.idle = make(chan struct{})
+		if d.inFlight == 0 {
+			close(d.idle)
+		}
+	}
+	idle := d.idle
+	d.mu.Unlock()
+
+	var err error
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	report := d.report
+	report.Abandoned = d.inFlight
+	return report, err
+}
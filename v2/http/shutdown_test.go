@@ -0,0 +1,159 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
+)
+
+func TestDrainer(t *testing.T) {
+	d := NewDrainer()
+	releaseA, err := d.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	releaseB, _ := d.Acquire()
+
+	done := make(chan ShutdownReport)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		report, err := d.Shutdown(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+		done <- report
+	}()
+
+	// Wait for shutdown to begin
+	for !d.ShuttingDown() {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := d.Acquire(); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("expected ErrShuttingDown, got %v", err)
+	}
+	releaseA()
+	releaseA() // release is idempotent
+
+	report := <-done
+	want := ShutdownReport{InFlight: 2, Flushed: 1, Abandoned: 1, Rejected: 1}
+	if report != want {
+		t.Errorf("report = %+v, want %+v", report, want)
+	}
+
+	// A second shutdown waits for the remaining request
+	releaseB()
+	report, err = d.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if report.Flushed != 2 || report.Abandoned != 0 {
+		t.Errorf("unexpected report after drain: %+v", report)
+	}
+}
+
+func TestMiddleware_Drainer(t *testing.T) {
+	settled := make(chan struct{}, 1)
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{
+				Kinds: []v2.SupportedKind{{X402Version: 2, Scheme: "exact", Network: "eip155:84532"}},
+			})
+		case "/verify":
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			settled <- struct{}{}
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xtx"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	drainer := NewDrainer()
+	config := Config{
+		FacilitatorURL: facilitatorServer.URL,
+		Resource:       v2.ResourceInfo{URL: "https://example.com/api/data"},
+		PaymentRequirements: []v2.PaymentRequirements{
+			{
+				Scheme:            "exact",
+				Network:           "eip155:84532",
+				Amount:            "10000",
+				Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+				PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+				MaxTimeoutSeconds: 60,
+			},
+		},
+		Drainer: drainer,
+	}
+
+	entered := make(chan struct{})
+	proceed := make(chan struct{})
+	handler := NewX402Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-proceed
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	paymentHeader, _ := encoding.EncodePayment(v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: "10000"},
+		Payload:     map[string]interface{}{"signature": "0xsig"},
+	})
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest("GET", "/api/data", nil)
+		req.Header.Set("X-PAYMENT", paymentHeader)
+		return req
+	}
+
+	inFlight := httptest.NewRecorder()
+	served := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(inFlight, newRequest())
+		close(served)
+	}()
+	<-entered
+
+	reports := make(chan ShutdownReport)
+	go func() {
+		report, err := drainer.Shutdown(context.Background())
+		if err != nil {
+			t.Errorf("Shutdown() error = %v", err)
+		}
+		reports <- report
+	}()
+	for !drainer.ShuttingDown() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// New paid requests are refused
+	rejected := httptest.NewRecorder()
+	handler.ServeHTTP(rejected, newRequest())
+	if rejected.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 during shutdown, got %d", rejected.Code)
+	}
+
+	// The in-flight request still settles
+	close(proceed)
+	<-served
+	if inFlight.Code != http.StatusOK {
+		t.Errorf("expected in-flight request to succeed, got %d", inFlight.Code)
+	}
+	select {
+	case <-settled:
+	default:
+		t.Error("expected in-flight payment to be settled")
+	}
+
+	report := <-reports
+	if report != (ShutdownReport{InFlight: 1, Flushed: 1, Rejected: 1}) {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// SignerScope restricts a signer to paying for requests to some resources, so
+// that requirements served by any other host cannot draw from its wallet.
+type SignerScope struct {
+	// Signer pays for requests matching Resources.
+	Signer v2.Signer
+
+	// Resources are the URL patterns of the requests Signer pays for. A
+	// pattern is a host, like "api.example.com", optionally with a leading
+	// "*." matching any subdomain, a scheme, like "https://api.example.com",
+	// and a path, like "api.example.com/v1/", matched as a prefix. A path
+	// ending in "*" is matched as a prefix too.
+	Resources []string
+}
+
+// Matches reports whether the scope's signer may pay for a request to u.
+func (s SignerScope) Matches(u *url.URL) bool {
+	for _, pattern := range s.Resources {
+		if matchResource(pattern, u) {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks that the scope has a signer and well-formed resources.
+func (s SignerScope) validate() error {
+	if s.Signer == nil {
+		return fmt.Errorf("signer scope has no signer")
+	}
+	if len(s.Resources) == 0 {
+		return fmt.Errorf("no resources given for scoped signer")
+	}
+	for _, pattern := range s.Resources {
+		scheme, host, _ := splitResource(pattern)
+		if host == "" || strings.Contains(strings.TrimPrefix(host, "*."), "*") || (scheme != "" && scheme != "http" && scheme != "https") {
+			return fmt.Errorf("invalid signer resource pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// signersFor returns the signers that may pay for req: the unscoped signers
+// and the scoped signers whose resources match its URL. The URL is the one the
+// request was sent to, not the resource the server claims in its
+// requirements.
+func (t *X402Transport) signersFor(req *http.Request) []v2.Signer {
+	if len(t.SignerScopes) == 0 {
+		return t.Signers
+	}
+	signers := append([]v2.Signer(nil), t.Signers...)
+	for _, scope := range t.SignerScopes {
+		if scope.Matches(req.URL) {
+			signers = append(signers, scope.Signer)
+		}
+	}
+	return signers
+}
+
+// matchResource reports whether u matches a SignerScope resource pattern.
+func matchResource(pattern string, u *url.URL) bool {
+	scheme, host, path := splitResource(pattern)
+	if scheme != "" && !strings.EqualFold(scheme, u.Scheme) {
+		return false
+	}
+	if !matchHost(host, u) {
+		return false
+	}
+	path = strings.TrimSuffix(path, "*")
+	if path == "" || path == "/" {
+		return true
+	}
+	requestPath := u.EscapedPath()
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	return strings.HasPrefix(requestPath, path)
+}
+
+// matchHost matches a host pattern against the host of u. A pattern without a
+// port matches any port.
+func matchHost(pattern string, u *url.URL) bool {
+	host := u.Host
+	if _, _, err := net.SplitHostPort(pattern); err != nil {
+		host = u.Hostname()
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return len(host) > len(suffix)+1 && strings.HasSuffix(strings.ToLower(host), "."+strings.ToLower(suffix))
+	}
+	return strings.EqualFold(pattern, host)
+}
+
+// splitResource splits a resource pattern into its scheme, host, and path.
+func splitResource(pattern string) (scheme, host, path string) {
+	rest := pattern
+	if before, after, ok := strings.Cut(pattern, "://"); ok {
+		scheme, rest = strings.ToLower(before), after
+	}
+	if i := strings.Index(rest, "/"); i >= 0 {
+		return scheme, rest[:i], rest[i:]
+	}
+	return scheme, rest, ""
+}
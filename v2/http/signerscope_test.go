@@ -0,0 +1,113 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func TestSignerScope_Matches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		url     string
+		want    bool
+	}{
+		{"api.example.com", "https://api.example.com/weather", true},
+		{"api.example.com", "http://api.example.com:8080/", true},
+		{"API.example.com", "https://api.example.com", true},
+		{"api.example.com", "https://evil.com/api.example.com", false},
+		{"api.example.com", "https://api.example.com.evil.com/", false},
+		{"*.example.com", "https://api.example.com/", true},
+		{"*.example.com", "https://example.com/", false},
+		{"*.example.com", "https://a.b.example.com/", true},
+		{"https://api.example.com", "http://api.example.com/", false},
+		{"api.example.com:8443", "https://api.example.com:8443/", true},
+		{"api.example.com:8443", "https://api.example.com/", false},
+		{"api.example.com/v1/", "https://api.example.com/v1/weather", true},
+		{"api.example.com/v1/", "https://api.example.com/v2/weather", false},
+		{"api.example.com/v1*", "https://api.example.com/v1beta", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			scope := SignerScope{Signer: &mockSigner{}, Resources: []string{tt.pattern}}
+			if err := scope.validate(); err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			if got := scope.Matches(u); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, pattern := range []string{"", "/v1/", "api.*.com", "ftp://api.example.com"} {
+		if err := (SignerScope{Signer: &mockSigner{}, Resources: []string{pattern}}).validate(); err == nil {
+			t.Errorf("expected pattern %q to be invalid", pattern)
+		}
+	}
+}
+
+func TestTransport_ScopedSigner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-PAYMENT") != "" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPaymentRequired)
+		_ = json.NewEncoder(w).Encode(v2.PaymentRequired{
+			X402Version: 2,
+			Accepts: []v2.PaymentRequirements{{
+				Scheme:  "exact",
+				Network: "eip155:84532",
+				Amount:  "10000",
+				Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+				PayTo:   "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+			}},
+		})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		resources []string
+		wantErr   bool
+	}{
+		{"scoped to the server", []string{"127.0.0.1"}, false},
+		{"scoped to another host", []string{"api.example.com"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(WithScopedSigner(&mockSigner{network: "eip155:84532", scheme: "exact"}, tt.resources...))
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			resp, err := client.Get(server.URL + "/data")
+			if tt.wantErr {
+				if !errors.Is(err, v2.ErrNoValidSigner) {
+					t.Errorf("expected ErrNoValidSigner, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("expected status 200, got %d", resp.StatusCode)
+			}
+		})
+	}
+
+	if _, err := NewClient(WithScopedSigner(&mockSigner{})); err == nil {
+		t.Error("expected scoped signer without resources to be refused")
+	}
+}
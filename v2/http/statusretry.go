@@ -0,0 +1,135 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/validation"
+)
+
+// DefaultMaxStatusRetries is the default number of corrective retries of a
+// paid request answered with 409, 425 or 429.
+const DefaultMaxStatusRetries = 2
+
+// DefaultMaxRetryWait is the default longest wait before a corrective retry.
+const DefaultMaxRetryWait = 30 * time.Second
+
+// defaultRetryWait is the wait before retrying a 425 or 429 response that
+// says nothing about when to retry.
+const defaultRetryWait = time.Second
+
+// correctableStatus reports whether a paid request answered with status can be
+// corrected and retried: 409 Conflict for a used nonce, 425 Too Early for an
+// authorization not yet valid, and 429 Too Many Requests.
+func correctableStatus(status int) bool {
+	return status == http.StatusConflict || status == http.StatusTooEarly || status == http.StatusTooManyRequests
+}
+
+// maxStatusRetries returns the number of corrective retries allowed.
+func (t *X402Transport) maxStatusRetries() int {
+	if t.MaxStatusRetries == 0 {
+		return DefaultMaxStatusRetries
+	}
+	return max(t.MaxStatusRetries, 0)
+}
+
+// maxRetryWait returns the longest wait before a corrective retry.
+func (t *X402Transport) maxRetryWait() time.Duration {
+	if t.MaxRetryWait <= 0 {
+		return DefaultMaxRetryWait
+	}
+	return t.MaxRetryWait
+}
+
+// correction returns how to retry a paid request answered with resp: after
+// wait, and with a newly signed payment if resign is set. It returns the
+// status error if the request cannot be retried within MaxRetryWait.
+func (t *X402Transport) correction(resp *http.Response, payment *v2.PaymentPayload, now time.Time) (wait time.Duration, resign bool, err error) {
+	retryAfter, hasRetryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	switch resp.StatusCode {
+	case http.StatusConflict:
+		// The nonce was used; a new signature carries a new one
+		return 0, true, nil
+	case http.StatusTooEarly:
+		wait = defaultRetryWait
+		if start, ok := validation.AuthorizationStart(*payment); ok {
+			// Wait for validAfter, plus a second for the server's clock
+			wait = start.Sub(now) + time.Second
+		}
+		if hasRetryAfter && retryAfter > wait {
+			wait = retryAfter
+		}
+	default:
+		wait = defaultRetryWait
+		if hasRetryAfter {
+			wait = retryAfter
+		}
+	}
+	if wait > t.maxRetryWait() {
+		return 0, false, statusError(resp)
+	}
+	wait = max(wait, 0)
+	// An authorization expiring during the wait is signed again
+	if deadline, ok := validation.AuthorizationDeadline(*payment); ok && !now.Add(wait).Before(deadline) {
+		resign = true
+	}
+	return wait, resign, nil
+}
+
+// statusError returns the typed error for a paid request answered with a
+// correctable status.
+func statusError(resp *http.Response) error {
+	var err *v2.PaymentError
+	switch resp.StatusCode {
+	case http.StatusConflict:
+		err = v2.NewPaymentError(v2.ErrCodeNonceConflict, "payment nonce already used", v2.ErrPaymentReplayed)
+	case http.StatusTooEarly:
+		err = v2.NewPaymentError(v2.ErrCodeTooEarly, "payment not yet valid", v2.ErrPaymentTooEarly)
+	default:
+		err = v2.NewPaymentError(v2.ErrCodeRateLimited, "paid request rate limited", v2.ErrRateLimited)
+	}
+	err.WithDetails("status", resp.StatusCode)
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		err.WithDetails("retryAfter", retryAfter)
+	}
+	return err
+}
+
+// parseRetryAfter parses a Retry-After header, in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// discard drains and closes a response body so its connection can be reused.
+func discard(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
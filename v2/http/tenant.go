@@ -0,0 +1,125 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ErrUnknownTenant is returned by resolvers when a request matches no tenant.
+var ErrUnknownTenant = errors.New("x402: unknown tenant")
+
+// TenantResolver selects the tenant that serves a request.
+type TenantResolver interface {
+	// ResolveTenant returns the tenant ID for r, or ErrUnknownTenant.
+	ResolveTenant(r *http.Request) (string, error)
+}
+
+// TenantResolverFunc adapts a function to the TenantResolver interface.
+type TenantResolverFunc func(r *http.Request) (string, error)
+
+// ResolveTenant calls f(r).
+func (f TenantResolverFunc) ResolveTenant(r *http.Request) (string, error) {
+	return f(r)
+}
+
+// HostResolver resolves tenants by the request's Host header, lowercased and
+// without port. Use host names as tenant IDs.
+func HostResolver() TenantResolver {
+	return TenantResolverFunc(func(r *http.Request) (string, error) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host == "" {
+			return "", ErrUnknownTenant
+		}
+		return strings.ToLower(host), nil
+	})
+}
+
+// APIKeyPrefixResolver resolves tenants by the prefix of an API key sent in
+// header (e.g., "X-API-Key"). prefixes maps key prefixes to tenant IDs; the
+// longest matching prefix wins.
+func APIKeyPrefixResolver(header string, prefixes map[string]string) TenantResolver {
+	return TenantResolverFunc(func(r *http.Request) (string, error) {
+		key := r.Header.Get(header)
+		if key == "" {
+			return "", ErrUnknownTenant
+		}
+
+		var tenant, match string
+		for prefix, id := range prefixes {
+			if strings.HasPrefix(key, prefix) && len(prefix) > len(match) {
+				tenant, match = id, prefix
+			}
+		}
+		if match == "" {
+			return "", ErrUnknownTenant
+		}
+		return tenant, nil
+	})
+}
+
+// TenantContextKey is the context key for storing the resolved tenant ID.
+const TenantContextKey = contextKey("x402_v2_tenant")
+
+// GetTenantFromContext returns the tenant ID resolved by the multi-tenant middleware,
+// or "" if none.
+func GetTenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(TenantContextKey).(string)
+	return tenant
+}
+
+// DefaultTenant is the tenant ID used when a request matches no other tenant.
+// Configure it to serve unmatched requests instead of rejecting them with 404.
+const DefaultTenant = ""
+
+// NewMultiTenantMiddleware creates a payment middleware that routes each request
+// to a per-tenant configuration, so one gateway can collect payments for several
+// sellers with different recipients, prices, and facilitators.
+//
+// tenants maps tenant IDs (as returned by resolver) to their Config. Requests
+// whose tenant cannot be resolved use the DefaultTenant config if present, and
+// are otherwise rejected with 404.
+func NewMultiTenantMiddleware(tenants map[string]Config, resolver TenantResolver) func(http.Handler) http.Handler {
+	middlewares := make(map[string]func(http.Handler) http.Handler, len(tenants))
+	for id, config := range tenants {
+		middlewares[id] = NewX402Middleware(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		handlers := make(map[string]http.Handler, len(middlewares))
+		for id, middleware := range middlewares {
+			handlers[id] = middleware(next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant, handler, ok := ResolveTenant(r, resolver, handlers)
+			if !ok {
+				slog.Default().Warn("no tenant for request", "host", r.Host, "path", r.URL.Path)
+				http.Error(w, "Unknown tenant", http.StatusNotFound)
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), TenantContextKey, tenant))
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ResolveTenant selects the entry of tenants for a request using resolver,
+// falling back to DefaultTenant.
+func ResolveTenant[H any](r *http.Request, resolver TenantResolver, tenants map[string]H) (string, H, bool) {
+	tenant, err := resolver.ResolveTenant(r)
+	if err == nil {
+		if handler, ok := tenants[tenant]; ok {
+			return tenant, handler, true
+		}
+	}
+	handler, ok := tenants[DefaultTenant]
+	return DefaultTenant, handler, ok
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func TestMultiTenantMiddleware(t *testing.T) {
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+	}))
+	defer facilitatorServer.Close()
+
+	tenantConfig := func(payTo, amount string) Config {
+		return Config{
+			FacilitatorURL: facilitatorServer.URL,
+			PaymentRequirements: []v2.PaymentRequirements{{
+				Scheme:  "exact",
+				Network: "eip155:84532",
+				Amount:  amount,
+				Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+				PayTo:   payTo,
+			}},
+		}
+	}
+	tenants := map[string]Config{
+		"seller-a.example.com": tenantConfig("0x1111111111111111111111111111111111111111", "1000"),
+		"seller-b.example.com": tenantConfig("0x2222222222222222222222222222222222222222", "2000"),
+	}
+
+	handler := NewMultiTenantMiddleware(tenants, HostResolver())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called without payment")
+	}))
+
+	tests := []struct {
+		host       string
+		wantStatus int
+		wantPayTo  string
+		wantAmount string
+	}{
+		{host: "seller-a.example.com", wantStatus: http.StatusPaymentRequired, wantPayTo: "0x1111111111111111111111111111111111111111", wantAmount: "1000"},
+		{host: "SELLER-B.example.com:8080", wantStatus: http.StatusPaymentRequired, wantPayTo: "0x2222222222222222222222222222222222222222", wantAmount: "2000"},
+		{host: "unknown.example.com", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/data", nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantPayTo == "" {
+				return
+			}
+			var paymentRequired v2.PaymentRequired
+			if err := json.NewDecoder(w.Body).Decode(&paymentRequired); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if got := paymentRequired.Accepts[0]; got.PayTo != tt.wantPayTo || got.Amount != tt.wantAmount {
+				t.Errorf("expected payTo %s amount %s, got %s %s", tt.wantPayTo, tt.wantAmount, got.PayTo, got.Amount)
+			}
+		})
+	}
+
+	// Unmatched requests fall back to the default tenant when configured
+	tenants[DefaultTenant] = tenantConfig("0x3333333333333333333333333333333333333333", "500")
+	var tenant string
+	handler = NewMultiTenantMiddleware(tenants, HostResolver())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = GetTenantFromContext(r.Context())
+	}))
+	req := httptest.NewRequest("GET", "/api/data", nil)
+	req.Host = "unknown.example.com"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusPaymentRequired {
+		t.Errorf("expected default tenant to require payment, got %d", w.Code)
+	}
+	if tenant != "" {
+		t.Errorf("expected handler not to run, got tenant %q", tenant)
+	}
+}
+
+func TestAPIKeyPrefixResolver(t *testing.T) {
+	resolver := APIKeyPrefixResolver("X-API-Key", map[string]string{
+		"sk_a_":      "seller-a",
+		"sk_a_test_": "seller-a-test",
+		"sk_b_":      "seller-b",
+	})
+
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{key: "sk_a_123", want: "seller-a"},
+		{key: "sk_a_test_123", want: "seller-a-test"},
+		{key: "sk_b_456", want: "seller-b"},
+		{key: "sk_c_789", wantErr: true},
+		{key: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			got, err := resolver.ResolveTenant(req)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnknownTenant) {
+					t.Errorf("expected ErrUnknownTenant, got %v", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("expected %s, got %s (err %v)", tt.want, got, err)
+			}
+		})
+	}
+}
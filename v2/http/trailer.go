@@ -0,0 +1,62 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/http/internal/helpers"
+)
+
+// setPaymentResponse adds the settlement header name with settlement to w,
+// or, once the response header has been written, the trailer of that name.
+// Trailers reach the client only on chunked (HTTP/1.1) or HTTP/2 responses, as
+// sent by handlers that flush or declare the trailer in advance.
+func setPaymentResponse(w http.ResponseWriter, name string, settlement *v2.SettleResponse, headerWritten bool) error {
+	if !headerWritten {
+		return helpers.SetPaymentResponseHeader(w, name, settlement)
+	}
+	return helpers.SetPaymentResponseTrailer(w, name, settlement)
+}
+
+// declareTrailer announces the trailer name in the Trailer header of w, once.
+// Declaring the settlement trailer for payments settled after the handler
+// starts responding also makes net/http send the response chunked, so the
+// trailer is delivered even if the handler never flushes.
+func declareTrailer(w http.ResponseWriter, name string) {
+	for _, declared := range w.Header().Values("Trailer") {
+		if http.CanonicalHeaderKey(declared) == http.CanonicalHeaderKey(name) {
+			return
+		}
+	}
+	w.Header().Add("Trailer", name)
+}
+
+// declaresTrailer reports whether resp announced the trailer name.
+func declaresTrailer(resp *http.Response, name string) bool {
+	_, ok := resp.Trailer[http.CanonicalHeaderKey(name)]
+	return ok
+}
+
+// settlementTrailerReader calls onEOF once its body is fully read, when the
+// trailers of the response are available. An error from onEOF is returned by
+// the final Read in place of io.EOF.
+type settlementTrailerReader struct {
+	io.ReadCloser
+	onEOF func() error
+	once  sync.Once
+	err   error
+}
+
+func (r *settlementTrailerReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if errors.Is(err, io.EOF) {
+		r.once.Do(func() { r.err = r.onEOF() })
+		if r.err != nil {
+			return n, r.err
+		}
+	}
+	return n, err
+}
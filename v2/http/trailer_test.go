@@ -0,0 +1,94 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func TestMiddleware_SettlementTrailer(t *testing.T) {
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/supported":
+			_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+		case "/verify":
+			_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"})
+		case "/settle":
+			_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532"})
+		}
+	}))
+	defer facilitatorServer.Close()
+
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	tests := []struct {
+		name  string
+		flush bool
+	}{
+		{"streamed", true},
+		{"buffered", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The handler captures the payment after sending the response
+			server := httptest.NewServer(NewX402Middleware(Config{
+				FacilitatorURL:      facilitatorServer.URL,
+				PaymentRequirements: []v2.PaymentRequirements{requirement},
+				ManualCapture:       true,
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("chunk 1,"))
+				if tt.flush {
+					w.(http.Flusher).Flush()
+				}
+				_, _ = w.Write([]byte("chunk 2"))
+				if _, err := GetPaymentHoldFromContext(r.Context()).Capture(context.Background()); err != nil {
+					t.Errorf("Capture failed: %v", err)
+				}
+			})))
+			defer server.Close()
+
+			var settled []v2.PaymentEvent
+			client, err := NewClient(
+				WithSigner(&mockSigner{network: "eip155:84532", scheme: "exact"}),
+				WithPaymentCallback(v2.PaymentEventSuccess, func(event v2.PaymentEvent) { settled = append(settled, event) }),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.Header.Get("X-PAYMENT-RESPONSE") != "" {
+				t.Error("expected no settlement header before capture")
+			}
+			if len(settled) != 0 {
+				t.Error("expected success callback to wait for the trailer")
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil || string(body) != "chunk 1,chunk 2" {
+				t.Fatalf("body = %q, %v", body, err)
+			}
+			if settlement := GetSettlement(resp); settlement == nil || settlement.Transaction != "0xabc" {
+				t.Errorf("expected settlement from trailer, got %+v", settlement)
+			}
+			if len(settled) != 1 || settled[0].Transaction != "0xabc" {
+				t.Errorf("expected one success event with the transaction, got %+v", settled)
+			}
+		})
+	}
+}
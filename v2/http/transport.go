@@ -1,13 +1,26 @@
 package http
 
 import (
+	"errors"
+	"fmt"
+	"math/big"
 	"net/http"
+	"sync"
 	"time"
 
 	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/attestation"
+	"github.com/mark3labs/x402-go/v2/extensions"
 	"github.com/mark3labs/x402-go/v2/http/internal/helpers"
+	"github.com/mark3labs/x402-go/v2/quote"
 )
 
+// ChargeAcceptor decides whether to accept the final charge of an "upto"
+// payment, reported by the server after the response was produced. Returning an
+// error fails the request with v2.ErrChargeRejected; the payment has already
+// been settled, so this is for detecting and reporting overcharging merchants.
+type ChargeAcceptor func(requirement v2.PaymentRequirements, charged *big.Int) error
+
 // X402Transport is a custom RoundTripper that handles x402 v2 payment flows.
 // It wraps an existing http.RoundTripper and automatically handles 402 Payment Required responses.
 type X402Transport struct {
@@ -17,6 +30,10 @@ type X402Transport struct {
 	// Signers is the list of available payment signers.
 	Signers []v2.Signer
 
+	// SignerScopes are signers that only pay for requests to some resources,
+	// in addition to Signers, which pay for any request.
+	SignerScopes []SignerScope
+
 	// Selector is used to choose the appropriate signer and create payments.
 	Selector v2.PaymentSelector
 
@@ -28,6 +45,95 @@ type X402Transport struct {
 
 	// OnPaymentFailure is called when a payment fails.
 	OnPaymentFailure v2.PaymentCallback
+
+	// SpendGuard optionally enforces a process-wide spend limit.
+	// Payments that would exceed the limit are not sent.
+	SpendGuard *v2.SpendGuard
+
+	// Attestation, if set, checks the requirements attestation of every 402
+	// response before paying. Responses failing the check are not paid.
+	Attestation *attestation.Verifier
+
+	// Extensions, if set, validates the extensions of every 402 response
+	// against their registered schemas, refusing to pay responses lacking a
+	// required extension, and adds the info of its extensions to payments.
+	Extensions *extensions.Registry
+
+	// FacilitatorSigners, if set, lists the signers the facilitator advertises
+	// in its /supported response. Requirements whose feePayer is not one of
+	// the signers for their network are not paid. Without it, a feePayer is
+	// checked against the signers listed in the requirement itself.
+	FacilitatorSigners *v2.SupportedResponse
+
+	// TrustedFeePayers, if set, lists the fee payers the client accepts, keyed
+	// by network pattern like "solana:*". Requirements on a matching network
+	// whose feePayer is not listed are not paid; other networks are unrestricted.
+	TrustedFeePayers map[string][]string
+
+	// Allowlist, if set, restricts the schemes, networks and assets the
+	// client pays with, e.g. only mainnet USDC in production. Requirements
+	// outside it are not paid.
+	Allowlist v2.PaymentAllowlist
+
+	// DustThresholds, if set, is the smallest amount per asset the client
+	// pays. Requirements below it are not paid, since their fees would
+	// outweigh the transfer.
+	DustThresholds v2.MinimumAmounts
+
+	// PayTo, if set, resolves the addresses each host is known to be paid at.
+	// Requirements paying any other address are not paid, defending against a
+	// compromised server swapping its payout address.
+	PayTo PayToResolver
+
+	// History, if set, remembers the requirements each resource was paid
+	// with. Requirements that later changed beyond its tolerance are not paid.
+	History *v2.RequirementsHistory
+
+	// AcceptCharge, if set, checks the final charge of "upto" payments. Charges
+	// above the authorized amount are rejected regardless.
+	AcceptCharge ChargeAcceptor
+
+	// Approve, if set, approves every signed payment before it is sent, such
+	// as passkey.Approver.Approve. Refused payments fail with
+	// v2.ErrPaymentNotApproved.
+	Approve v2.ApprovalFunc
+
+	// DryRun stops before signing and returns a *v2.DryRunError describing the
+	// payment that would have been made. No payment is signed or sent.
+	DryRun bool
+
+	// MaxStatusRetries bounds the corrective retries of a paid request
+	// answered with 409 Conflict (the payment is signed again with a new
+	// nonce), 425 Too Early (retried once the authorization is valid) or 429
+	// Too Many Requests (retried after Retry-After). Once exhausted, the
+	// request fails with a *v2.PaymentError of code v2.ErrCodeNonceConflict,
+	// v2.ErrCodeTooEarly or v2.ErrCodeRateLimited. Zero means
+	// DefaultMaxStatusRetries; negative disables the retries.
+	MaxStatusRetries int
+
+	// MaxRetryWait bounds the wait before a corrective retry. Responses asking
+	// for a longer wait fail immediately. Zero means DefaultMaxRetryWait.
+	MaxRetryWait time.Duration
+
+	// Cache, if set, keeps paid responses of GET requests for as long as
+	// their Cache-Control allows and answers identical requests from it
+	// without paying again.
+	Cache *PaymentCache
+
+	// Headers names the payment and settlement headers and where payments
+	// are sent, matching the server's configuration (default: X-PAYMENT and
+	// X-PAYMENT-RESPONSE).
+	Headers PaymentHeaders
+
+	// Coalesce makes concurrent identical GET requests share one payment and
+	// one response. Only callers that arrive before the paid response do;
+	// range requests, event streams, downloads and responses over 1 MiB are
+	// never shared. See SkipCoalescing to opt out for a single request.
+	Coalesce bool
+
+	// inflight holds the paid GET requests identical requests can wait for.
+	inflightMu sync.Mutex
+	inflight   map[string]*sharedCall
 }
 
 // RoundTrip implements http.RoundTripper.
@@ -39,6 +145,13 @@ func (t *X402Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		t.Base = http.DefaultTransport
 	}
 
+	// Answer from the responses already paid for
+	if t.Cache != nil {
+		if resp := t.Cache.Lookup(req); resp != nil {
+			return resp, nil
+		}
+	}
+
 	// Clone the request to avoid modifying the original
 	reqCopy := req.Clone(req.Context())
 
@@ -63,20 +176,144 @@ func (t *X402Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	// Close the 402 response body
 	resp.Body.Close()
 
+	// Refuse requirements that may have been tampered with in transit
+	if t.Attestation != nil {
+		origin := req.URL.Scheme + "://" + req.URL.Host
+		if err := t.Attestation.VerifyFor(req.Context(), *paymentReq, origin); err != nil {
+			return nil, v2.NewPaymentError(v2.ErrCodeInvalidRequirements, "payment requirements attestation failed", err)
+		}
+	}
+
+	// Refuse extensions that do not satisfy their schemas
+	if t.Extensions != nil {
+		if err := t.Extensions.Validate(paymentReq.Extensions); err != nil {
+			return nil, v2.NewPaymentError(v2.ErrCodeInvalidRequirements, "invalid payment requirements extensions", err)
+		}
+	}
+
+	// Refuse schemes, networks and assets the deployment does not allow
+	accepts, err := t.Allowlist.Filter(paymentReq.Accepts)
+	if err != nil {
+		return nil, v2.NewPaymentError(v2.ErrCodeUnsupportedScheme, "payment not allowed", err)
+	}
+	paymentReq.Accepts = accepts
+
+	// Refuse recipients that are not known addresses of the merchant
+	accepts, err = t.checkPayTo(req, paymentReq.Accepts)
+	if err != nil {
+		return nil, v2.NewPaymentError(v2.ErrCodeInvalidRequirements, "untrusted payment recipient", err)
+	}
+	paymentReq.Accepts = accepts
+
+	// Refuse fee payers that are not trusted or not advertised by the facilitator
+	accepts, err = t.trustedFeePayers(paymentReq.Accepts)
+	if err != nil {
+		return nil, v2.NewPaymentError(v2.ErrCodeInvalidRequirements, "untrusted fee payer", err)
+	}
+	paymentReq.Accepts = accepts
+
+	// Refuse transfers too small to be worth their fees
+	accepts, err = t.aboveDust(paymentReq.Accepts)
+	if err != nil {
+		return nil, v2.NewPaymentError(v2.ErrCodeInvalidRequirements, "payment below dust threshold", err)
+	}
+	paymentReq.Accepts = accepts
+
+	// Refuse requirements that changed since the resource was last paid
+	accepts, err = t.unchangedRequirements(req, paymentReq.Accepts)
+	if err != nil {
+		return nil, v2.NewPaymentError(v2.ErrCodeInvalidRequirements, "payment requirements changed", err)
+	}
+	paymentReq.Accepts = accepts
+
+	// In dry-run mode, report the plan instead of signing
+	if t.DryRun {
+		plan, err := v2.PlanPayment(t.Selector, t.signersFor(req), paymentReq.Accepts, paymentReq.Resource, t.SpendGuard)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &v2.DryRunError{Plan: plan}
+	}
+
+	// Share one payment between concurrent identical GET requests
+	if key, ok := t.coalescingKey(req); ok {
+		return t.payShared(key, req, paymentReq)
+	}
+	return t.pay(req, paymentReq)
+}
+
+// sign selects a signer for one of accepts and signs a payment for req.
+func (t *X402Transport) sign(req *http.Request, paymentReq *v2.PaymentRequired, accepts []v2.PaymentRequirements) (*v2.PaymentPayload, error) {
+	payment, err := v2.SelectAndSignContext(req.Context(), t.Selector, t.signersFor(req), accepts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Echo the server's price quote so it honors the quoted price
+	quote.Echo(*paymentReq, payment)
+	if t.Extensions != nil {
+		t.Extensions.Attach(&payment.Extensions)
+	}
+	if err := v2.Approve(req.Context(), t.Approve, payment); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// pay signs a payment for paymentReq and retries req with it.
+// reserve enforces the process-wide and request spend limits on payment
+// before it leaves the process, reporting a refusal as a payment failure.
+func (t *X402Transport) reserve(req *http.Request, payment *v2.PaymentPayload) error {
+	if t.SpendGuard == nil && v2.SpendGuardFromContext(req.Context()) == nil {
+		return nil
+	}
+	err := v2.ReserveSpend(req.Context(), t.SpendGuard, payment.Accepted)
+	if err != nil {
+		v2.NotifyPaymentEvent(req.Context(), t.OnPaymentFailure, v2.PaymentEvent{
+			Type:      v2.PaymentEventFailure,
+			Timestamp: time.Now(),
+			Method:    "HTTP",
+			URL:       req.URL.String(),
+			Network:   payment.Accepted.Network,
+			Scheme:    payment.Accepted.Scheme,
+			Amount:    payment.Accepted.Amount,
+			Asset:     payment.Accepted.Asset,
+			Recipient: payment.Accepted.PayTo,
+			Error:     err,
+		})
+	}
+	return err
+}
+
+func (t *X402Transport) pay(req *http.Request, paymentReq *v2.PaymentRequired) (*http.Response, error) {
 	// Select signer and create payment
-	payment, err := t.Selector.SelectAndSign(t.Signers, paymentReq.Accepts)
+	payment, err := t.sign(req, paymentReq, paymentReq.Accepts)
 	if err != nil {
+		// Report payments the user declined or did not approve in their wallet
+		if errors.Is(err, v2.ErrSignatureRejected) || errors.Is(err, v2.ErrApprovalTimeout) || errors.Is(err, v2.ErrPaymentNotApproved) {
+			v2.NotifyPaymentEvent(req.Context(), t.OnPaymentFailure, v2.PaymentEvent{
+				Type:      v2.PaymentEventFailure,
+				Timestamp: time.Now(),
+				Method:    "HTTP",
+				URL:       req.URL.String(),
+				Error:     err,
+			})
+		}
 		return nil, err
 	}
 
 	// Get the selected requirement for callback data
 	selectedRequirement, _ := v2.FindMatchingRequirement(payment, paymentReq.Accepts)
 
+	if err := t.reserve(req, payment); err != nil {
+		return nil, err
+	}
+
 	// Record start time for duration tracking
 	startTime := time.Now()
 
 	// Trigger payment attempt callback
-	if t.OnPaymentAttempt != nil && selectedRequirement != nil {
+	if selectedRequirement != nil {
 		event := v2.PaymentEvent{
 			Type:      v2.PaymentEventAttempt,
 			Timestamp: startTime,
@@ -88,58 +325,148 @@ func (t *X402Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 			Asset:     selectedRequirement.Asset,
 			Recipient: selectedRequirement.PayTo,
 		}
-		t.OnPaymentAttempt(event)
+		v2.NotifyPaymentEvent(req.Context(), t.OnPaymentAttempt, event)
 	}
 
 	// Build payment header
 	paymentHeader, err := helpers.BuildPaymentHeader(payment)
 	if err != nil {
 		// Trigger failure callback
-		if t.OnPaymentFailure != nil {
-			event := v2.PaymentEvent{
-				Type:      v2.PaymentEventFailure,
-				Timestamp: time.Now(),
-				Method:    "HTTP",
-				URL:       req.URL.String(),
-				Error:     err,
-				Duration:  time.Since(startTime),
-			}
-			t.OnPaymentFailure(event)
-		}
+		v2.NotifyPaymentEvent(req.Context(), t.OnPaymentFailure, v2.PaymentEvent{
+			Type:      v2.PaymentEventFailure,
+			Timestamp: time.Now(),
+			Method:    "HTTP",
+			URL:       req.URL.String(),
+			Error:     err,
+			Duration:  time.Since(startTime),
+		})
 		return nil, v2.NewPaymentError(v2.ErrCodeSigningFailed, "failed to build payment header", err)
 	}
 
-	// Clone the request again for the retry
-	reqRetry := req.Clone(req.Context())
-
-	// Add payment header
-	reqRetry.Header.Set("X-PAYMENT", paymentHeader)
-
-	// Retry the request with payment
-	respRetry, err := t.Base.RoundTrip(reqRetry)
+	// Retry the request with payment, correcting refusals the client can fix
+	respRetry, err := t.sendPaid(req, paymentHeader)
+	for retries := 0; err == nil && correctableStatus(respRetry.StatusCode); retries++ {
+		if retries >= t.maxStatusRetries() || !rewindable(req) {
+			err = statusError(respRetry)
+			discard(respRetry)
+			break
+		}
+		var wait time.Duration
+		var resign bool
+		wait, resign, err = t.correction(respRetry, payment, time.Now())
+		discard(respRetry)
+		if err != nil {
+			break
+		}
+		if err = sleepContext(req.Context(), wait); err != nil {
+			break
+		}
+		if resign {
+			if payment, err = t.sign(req, paymentReq, []v2.PaymentRequirements{payment.Accepted}); err != nil {
+				break
+			}
+			// Every new authorization can be settled, so each one is counted
+			if err = t.reserve(req, payment); err != nil {
+				return nil, err
+			}
+			if paymentHeader, err = helpers.BuildPaymentHeader(payment); err != nil {
+				err = v2.NewPaymentError(v2.ErrCodeSigningFailed, "failed to build payment header", err)
+				break
+			}
+		}
+		respRetry, err = t.sendPaid(req, paymentHeader)
+	}
 	duration := time.Since(startTime)
 
 	if err != nil {
 		// Trigger failure callback
-		if t.OnPaymentFailure != nil {
-			event := v2.PaymentEvent{
-				Type:      v2.PaymentEventFailure,
-				Timestamp: time.Now(),
-				Method:    "HTTP",
-				URL:       req.URL.String(),
-				Error:     err,
-				Duration:  duration,
-			}
-			t.OnPaymentFailure(event)
+		v2.NotifyPaymentEvent(req.Context(), t.OnPaymentFailure, v2.PaymentEvent{
+			Type:      v2.PaymentEventFailure,
+			Timestamp: time.Now(),
+			Method:    "HTTP",
+			URL:       req.URL.String(),
+			Error:     err,
+			Duration:  duration,
+		})
+		return nil, err
+	}
+
+	// Parse settlement response. Servers that settle after streaming the
+	// response header send it as a trailer, read once the body is consumed.
+	responseHeader := t.Headers.PaymentResponseHeader()
+	settlement := helpers.ParseSettlement(respRetry.Header.Get(responseHeader))
+	if settlement == nil && declaresTrailer(respRetry, responseHeader) {
+		respRetry.Body = &settlementTrailerReader{
+			ReadCloser: respRetry.Body,
+			onEOF: func() error {
+				settlement := helpers.ParseSettlement(respRetry.Trailer.Get(responseHeader))
+				return t.settled(req, payment, selectedRequirement, settlement, duration)
+			},
 		}
+		return respRetry, nil
+	}
+
+	if err := t.settled(req, payment, selectedRequirement, settlement, duration); err != nil {
+		respRetry.Body.Close()
 		return nil, err
 	}
+	if t.Cache != nil {
+		return t.Cache.Store(req, respRetry), nil
+	}
+	return respRetry, nil
+}
 
-	// Parse settlement response
-	settlement := helpers.ParseSettlement(respRetry.Header.Get("X-PAYMENT-RESPONSE"))
+// sendPaid sends a copy of req carrying paymentHeader, rewinding its body.
+func (t *X402Transport) sendPaid(req *http.Request, paymentHeader string) (*http.Response, error) {
+	reqRetry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("rewinding request body: %w", err)
+		}
+		reqRetry.Body = body
+	}
+	if err := t.Headers.WritePayment(reqRetry, paymentHeader); err != nil {
+		if reqRetry.Body != nil {
+			reqRetry.Body.Close()
+		}
+		return nil, err
+	}
+	return t.Base.RoundTrip(reqRetry)
+}
+
+// rewindable reports whether req can be sent again.
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// settled checks the final charge of a settled payment and reports its
+// outcome to the payment callbacks.
+func (t *X402Transport) settled(req *http.Request, payment *v2.PaymentPayload, selectedRequirement *v2.PaymentRequirements, settlement *v2.SettleResponse, duration time.Duration) error {
+	// Check the final charge of metered payments
+	charged, err := t.checkCharge(payment, settlement)
+	if err != nil {
+		v2.NotifyPaymentEvent(req.Context(), t.OnPaymentFailure, v2.PaymentEvent{
+			Type:        v2.PaymentEventFailure,
+			Timestamp:   time.Now(),
+			Method:      "HTTP",
+			URL:         req.URL.String(),
+			Network:     payment.Accepted.Network,
+			Scheme:      payment.Accepted.Scheme,
+			Amount:      charged,
+			Asset:       payment.Accepted.Asset,
+			Recipient:   payment.Accepted.PayTo,
+			Transaction: settlement.Transaction,
+			Error:       err,
+			Duration:    duration,
+		})
+		return v2.NewPaymentError(v2.ErrCodeAmountExceeded, "final charge rejected", err).
+			WithDetails("authorized", payment.Accepted.Amount).
+			WithDetails("charged", charged)
+	}
 
 	// Trigger success callback if settlement indicates success
-	if settlement != nil && settlement.Success && t.OnPaymentSuccess != nil {
+	if settlement != nil && settlement.Success {
 		event := v2.PaymentEvent{
 			Type:        v2.PaymentEventSuccess,
 			Timestamp:   time.Now(),
@@ -156,8 +483,135 @@ func (t *X402Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 			event.Asset = selectedRequirement.Asset
 			event.Recipient = selectedRequirement.PayTo
 		}
-		t.OnPaymentSuccess(event)
+		if charged != "" {
+			event.Amount = charged
+		}
+		v2.NotifyPaymentEvent(req.Context(), t.OnPaymentSuccess, event)
+
+		if t.History != nil && selectedRequirement != nil {
+			t.History.Record(historyResource(req), *selectedRequirement)
+		}
 	}
+	return nil
+}
 
-	return respRetry, nil
+// unchangedRequirements returns the requirements that have not changed since
+// the request's resource was last paid, or the first change if there are none.
+func (t *X402Transport) unchangedRequirements(req *http.Request, requirements []v2.PaymentRequirements) ([]v2.PaymentRequirements, error) {
+	if t.History == nil {
+		return requirements, nil
+	}
+	resource := historyResource(req)
+	unchanged := make([]v2.PaymentRequirements, 0, len(requirements))
+	var firstErr error
+	for _, requirement := range requirements {
+		if err := t.History.Check(resource, requirement); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		unchanged = append(unchanged, requirement)
+	}
+	if len(unchanged) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return unchanged, nil
+}
+
+// historyResource identifies the resource of req in the requirements history:
+// its URL without query or fragment.
+func historyResource(req *http.Request) string {
+	u := *req.URL
+	u.RawQuery, u.Fragment, u.RawFragment = "", "", ""
+	return u.String()
+}
+
+// trustedFeePayers returns the requirements whose feePayer is trusted, or the
+// first validation error if there are none.
+func (t *X402Transport) trustedFeePayers(requirements []v2.PaymentRequirements) ([]v2.PaymentRequirements, error) {
+	trusted := make([]v2.PaymentRequirements, 0, len(requirements))
+	var firstErr error
+	for _, req := range requirements {
+		if err := t.checkFeePayer(req); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		trusted = append(trusted, req)
+	}
+	if len(trusted) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return trusted, nil
+}
+
+// aboveDust returns the requirements at or above DustThresholds, or the first
+// error if there are none.
+func (t *X402Transport) aboveDust(requirements []v2.PaymentRequirements) ([]v2.PaymentRequirements, error) {
+	if len(t.DustThresholds) == 0 {
+		return requirements, nil
+	}
+	above := make([]v2.PaymentRequirements, 0, len(requirements))
+	var firstErr error
+	for _, req := range requirements {
+		if err := t.DustThresholds.Check(req); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		above = append(above, req)
+	}
+	if len(above) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return above, nil
+}
+
+// checkFeePayer checks the feePayer of req against the client's allowlist and
+// the facilitator signers.
+func (t *X402Transport) checkFeePayer(req v2.PaymentRequirements) error {
+	if allowed := v2.SignersForNetwork(t.TrustedFeePayers, req.Network); allowed != nil {
+		if err := v2.ValidateFeePayer(req, allowed); err != nil {
+			return err
+		}
+	}
+	signers := v2.FacilitatorSignersFromRequirements(req)
+	if t.FacilitatorSigners != nil {
+		signers = t.FacilitatorSigners.SignersFor(req.Network)
+	} else if signers == nil {
+		return nil
+	}
+	return v2.ValidateFeePayer(req, signers)
+}
+
+// checkCharge returns the final charge of a settled "upto" payment, or "" for
+// other payments, and rejects charges above the authorized amount or refused
+// by AcceptCharge.
+func (t *X402Transport) checkCharge(payment *v2.PaymentPayload, settlement *v2.SettleResponse) (string, error) {
+	if payment.Accepted.Scheme != v2.SchemeUpTo || settlement == nil || !settlement.Success {
+		return "", nil
+	}
+
+	authorized, ok := new(big.Int).SetString(payment.Accepted.Amount, 10)
+	if !ok {
+		return "", fmt.Errorf("%w: invalid authorized amount %q", v2.ErrChargeRejected, payment.Accepted.Amount)
+	}
+	charged := authorized
+	if settlement.Amount != "" {
+		if charged, ok = new(big.Int).SetString(settlement.Amount, 10); !ok || charged.Sign() < 0 {
+			return settlement.Amount, fmt.Errorf("%w: invalid charge %q", v2.ErrChargeRejected, settlement.Amount)
+		}
+	}
+	if charged.Cmp(authorized) > 0 {
+		return charged.String(), fmt.Errorf("%w: charged %s exceeds authorized %s", v2.ErrChargeRejected, charged, authorized)
+	}
+	if t.AcceptCharge != nil {
+		if err := t.AcceptCharge(payment.Accepted, charged); err != nil {
+			return charged.String(), fmt.Errorf("%w: %w", v2.ErrChargeRejected, err)
+		}
+	}
+	return charged.String(), nil
 }
@@ -1,15 +1,22 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/attestation"
 	"github.com/mark3labs/x402-go/v2/encoding"
+	"github.com/mark3labs/x402-go/v2/extensions"
 )
 
 // mockSigner implements v2.Signer for testing
@@ -327,3 +334,639 @@ func TestTransport_FailureCallback(t *testing.T) {
 		t.Error("Expected error for no signers")
 	}
 }
+
+// newPaidServer returns a server that answers 402 with requirement until a payment
+// header is present, and 200 with a settlement header afterwards.
+func newPaidServer(t *testing.T, requirement v2.PaymentRequirements) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-PAYMENT") == "" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusPaymentRequired)
+			_ = json.NewEncoder(w).Encode(v2.PaymentRequired{
+				X402Version: 2,
+				Accepts:     []v2.PaymentRequirements{requirement},
+			})
+			return
+		}
+		encoded, _ := encoding.EncodeSettlement(v2.SettleResponse{
+			Success:     true,
+			Transaction: "0xtx",
+			Network:     requirement.Network,
+		})
+		w.Header().Set("X-PAYMENT-RESPONSE", encoded)
+		_, _ = w.Write([]byte("Protected content"))
+	}))
+}
+
+func TestTransport_SpendGuard(t *testing.T) {
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	server := newPaidServer(t, requirement)
+	defer server.Close()
+
+	guard := v2.NewSpendGuard(big.NewInt(15000))
+	var failures int
+	transport := &X402Transport{
+		Base:             http.DefaultTransport,
+		Signers:          []v2.Signer{&mockSigner{network: "eip155:84532", scheme: "exact"}},
+		Selector:         v2.NewDefaultPaymentSelector(),
+		SpendGuard:       guard,
+		OnPaymentFailure: func(v2.PaymentEvent) { failures++ },
+	}
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("first payment should succeed: %v", err)
+	}
+	resp.Body.Close()
+
+	req, _ = http.NewRequest("GET", server.URL, nil)
+	if _, err := transport.RoundTrip(req); !errors.Is(err, v2.ErrSpendLimitExceeded) {
+		t.Fatalf("expected ErrSpendLimitExceeded, got %v", err)
+	}
+	if failures != 1 {
+		t.Errorf("expected one failure callback, got %d", failures)
+	}
+}
+
+func TestTransport_WalletFailureEvents(t *testing.T) {
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	server := newPaidServer(t, requirement)
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		err        error
+		wantEvents int
+	}{
+		{name: "rejected", err: v2.ErrSignatureRejected, wantEvents: 1},
+		{name: "timed out", err: fmt.Errorf("%w: no answer", v2.ErrApprovalTimeout), wantEvents: 1},
+		{name: "other signing error", err: errors.New("broken key")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []v2.PaymentEvent
+			transport := &X402Transport{
+				Base: http.DefaultTransport,
+				Signers: []v2.Signer{&mockSigner{network: "eip155:84532", scheme: "exact", signFunc: func(*v2.PaymentRequirements) (*v2.PaymentPayload, error) {
+					return nil, tt.err
+				}}},
+				Selector:         v2.NewDefaultPaymentSelector(),
+				OnPaymentFailure: func(event v2.PaymentEvent) { events = append(events, event) },
+			}
+
+			req, _ := http.NewRequest("GET", server.URL, nil)
+			if _, err := transport.RoundTrip(req); !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if len(events) != tt.wantEvents {
+				t.Fatalf("expected %d failure events, got %d", tt.wantEvents, len(events))
+			}
+			if tt.wantEvents > 0 && (events[0].Type != v2.PaymentEventFailure || !errors.Is(events[0].Error, tt.err)) {
+				t.Errorf("unexpected event %+v", events[0])
+			}
+		})
+	}
+}
+
+func TestTransport_Approval(t *testing.T) {
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	server := newPaidServer(t, requirement)
+	defer server.Close()
+
+	var approved []*v2.PaymentPayload
+	approve := true
+	var failures int
+	client, err := NewClient(
+		WithSigner(&mockSigner{network: "eip155:84532", scheme: "exact"}),
+		WithApproval(func(ctx context.Context, payment *v2.PaymentPayload) error {
+			approved = append(approved, payment)
+			if !approve {
+				return errors.New("denied")
+			}
+			return nil
+		}),
+		WithPaymentCallback(v2.PaymentEventFailure, func(v2.PaymentEvent) { failures++ }),
+	)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("approved payment failed: %v", err)
+	}
+	resp.Body.Close()
+	if len(approved) != 1 || approved[0].Accepted.Amount != "10000" {
+		t.Fatalf("expected the payment to be approved once, got %v", approved)
+	}
+
+	approve = false
+	if _, err := client.Get(server.URL); !errors.Is(err, v2.ErrPaymentNotApproved) {
+		t.Fatalf("expected ErrPaymentNotApproved, got %v", err)
+	}
+	if failures != 1 {
+		t.Errorf("expected one failure callback, got %d", failures)
+	}
+}
+
+func TestTransport_DryRun(t *testing.T) {
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	paid := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-PAYMENT") != "" {
+			paid = true
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPaymentRequired)
+		_ = json.NewEncoder(w).Encode(v2.PaymentRequired{
+			X402Version: 2,
+			Accepts:     []v2.PaymentRequirements{requirement},
+		})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(
+		WithSigner(&mockSigner{
+			network: "eip155:84532",
+			scheme:  "exact",
+			signFunc: func(*v2.PaymentRequirements) (*v2.PaymentPayload, error) {
+				t.Error("signer must not be called in dry-run mode")
+				return nil, errors.New("unexpected sign")
+			},
+		}),
+		WithDryRun(),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	_, err = client.Get(server.URL)
+	if !errors.Is(err, v2.ErrDryRun) {
+		t.Fatalf("expected ErrDryRun, got %v", err)
+	}
+	plan, ok := v2.PaymentPlanFromError(err)
+	if !ok {
+		t.Fatal("expected a payment plan")
+	}
+	if plan.Amount != requirement.Amount || plan.Asset != requirement.Asset || plan.Network != requirement.Network || plan.PayTo != requirement.PayTo {
+		t.Errorf("unexpected plan: %+v", plan)
+	}
+	if paid {
+		t.Error("no payment should be sent in dry-run mode")
+	}
+}
+
+func TestTransport_RequirementsAttestation(t *testing.T) {
+	const sellerKey = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+	attester, err := attestation.NewSigner(sellerKey)
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+
+	requirement := v2.PaymentRequirements{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "10000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+	}
+	upstream := httptest.NewServer(NewX402Middleware(Config{
+		FacilitatorURL:      "http://facilitator.invalid",
+		PaymentRequirements: []v2.PaymentRequirements{requirement},
+		Attester:            attester,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	defer upstream.Close()
+
+	// A man in the middle swaps the PayTo address of the signed body
+	tampered := false
+	mitm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := http.Get(upstream.URL)
+		if err != nil {
+			t.Errorf("upstream request failed: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+		var body v2.PaymentRequired
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		if tampered {
+			body.Accepts[0].PayTo = "0x000000000000000000000000000000000000dEaD"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPaymentRequired)
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	defer mitm.Close()
+
+	signCalls := 0
+	client, err := NewClient(
+		WithSigner(&mockSigner{
+			network: "eip155:84532",
+			scheme:  "exact",
+			signFunc: func(req *v2.PaymentRequirements) (*v2.PaymentPayload, error) {
+				signCalls++
+				return nil, errors.New("stop after signing")
+			},
+		}),
+		WithRequirementsAttestation(attestation.NewVerifier(
+			attestation.WithRequired(),
+			attestation.WithTrustedKeys(attester.Address()),
+		)),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	// The untampered body passes the check and reaches the signer
+	if _, err := client.Get(mitm.URL); errors.Is(err, v2.ErrInvalidAttestation) || signCalls != 1 {
+		t.Fatalf("expected attested requirements to be signed, got %v with %d sign calls", err, signCalls)
+	}
+
+	tampered = true
+	if _, err := client.Get(mitm.URL); !errors.Is(err, v2.ErrInvalidAttestation) {
+		t.Errorf("expected ErrInvalidAttestation for tampered requirements, got %v", err)
+	}
+	if signCalls != 1 {
+		t.Error("tampered requirements must not be signed")
+	}
+}
+
+func TestTransport_FacilitatorSigners(t *testing.T) {
+	const network = "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp"
+	supported := &v2.SupportedResponse{
+		Signers: map[string][]string{"solana:*": {"facilitator-signer"}},
+	}
+
+	tests := []struct {
+		name      string
+		extra     map[string]interface{}
+		supported *v2.SupportedResponse
+		trusted   map[string][]string
+		wantErr   bool
+	}{
+		{
+			name:      "advertised fee payer",
+			extra:     map[string]interface{}{"feePayer": "facilitator-signer"},
+			supported: supported,
+		},
+		{
+			name:      "fee payer not advertised",
+			extra:     map[string]interface{}{"feePayer": "attacker"},
+			supported: supported,
+			wantErr:   true,
+		},
+		{
+			name: "fee payer not among listed signers",
+			extra: map[string]interface{}{
+				"feePayer":                    "attacker",
+				v2.FacilitatorSignersExtraKey: []string{"facilitator-signer"},
+			},
+			wantErr: true,
+		},
+		{
+			name:  "no signers to check against",
+			extra: map[string]interface{}{"feePayer": "attacker"},
+		},
+		{
+			name:    "fee payer not in client allowlist",
+			extra:   map[string]interface{}{"feePayer": "attacker"},
+			trusted: map[string][]string{"solana:*": {"facilitator-signer"}},
+			wantErr: true,
+		},
+		{
+			name:    "allowlist for another network",
+			extra:   map[string]interface{}{"feePayer": "attacker"},
+			trusted: map[string][]string{"eip155:*": {"0x209693Bc6afc0C5328bA36FaF03C514EF312287C"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newPaidServer(t, v2.PaymentRequirements{
+				Scheme:  "exact",
+				Network: network,
+				Amount:  "10000",
+				Asset:   "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v",
+				PayTo:   "recipient",
+				Extra:   tt.extra,
+			})
+			defer server.Close()
+
+			transport := &X402Transport{
+				Base:               http.DefaultTransport,
+				Signers:            []v2.Signer{&mockSigner{network: network, scheme: "exact"}},
+				Selector:           v2.NewDefaultPaymentSelector(),
+				FacilitatorSigners: tt.supported,
+				TrustedFeePayers:   tt.trusted,
+			}
+
+			req, _ := http.NewRequest("GET", server.URL, nil)
+			resp, err := transport.RoundTrip(req)
+			if tt.wantErr {
+				if !errors.Is(err, v2.ErrUntrustedFeePayer) {
+					t.Fatalf("expected ErrUntrustedFeePayer, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RoundTrip failed: %v", err)
+			}
+			resp.Body.Close()
+		})
+	}
+}
+
+func TestTransport_DustThresholds(t *testing.T) {
+	const asset = "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
+
+	tests := []struct {
+		name    string
+		amount  string
+		wantErr bool
+	}{
+		{name: "above threshold", amount: "10000"},
+		{name: "below threshold", amount: "10", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newPaidServer(t, v2.PaymentRequirements{
+				Scheme:            "exact",
+				Network:           "eip155:84532",
+				Amount:            tt.amount,
+				Asset:             asset,
+				PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+				MaxTimeoutSeconds: 60,
+			})
+			defer server.Close()
+
+			signer := &mockSigner{network: "eip155:84532", scheme: "exact"}
+			client, err := NewClient(WithSigner(signer), WithDustThreshold(asset, "1000"))
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+
+			resp, err := client.Get(server.URL)
+			if tt.wantErr {
+				if !errors.Is(err, v2.ErrBelowMinimum) {
+					t.Fatalf("expected ErrBelowMinimum, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+		})
+	}
+
+	if _, err := NewClient(WithDustThreshold(asset, "0")); !errors.Is(err, v2.ErrInvalidAmount) {
+		t.Errorf("expected invalid threshold to be refused, got %v", err)
+	}
+}
+
+func TestTransport_RequirementsHistory(t *testing.T) {
+	price := "1000"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-PAYMENT") != "" {
+			encoded, _ := encoding.EncodeSettlement(v2.SettleResponse{Success: true, Transaction: "0x1", Network: "eip155:84532"})
+			w.Header().Set("X-PAYMENT-RESPONSE", encoded)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPaymentRequired)
+		_ = json.NewEncoder(w).Encode(v2.PaymentRequired{
+			X402Version: 2,
+			Accepts: []v2.PaymentRequirements{{
+				Scheme:  "exact",
+				Network: "eip155:84532",
+				Amount:  price,
+				Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+				PayTo:   "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+			}},
+		})
+	}))
+	defer server.Close()
+
+	transport := &X402Transport{
+		Base:     http.DefaultTransport,
+		Signers:  []v2.Signer{&mockSigner{network: "eip155:84532", scheme: "exact"}},
+		Selector: v2.NewDefaultPaymentSelector(),
+		History:  v2.NewRequirementsHistory(v2.WithPriceTolerance(0.5)),
+	}
+	get := func(query string) error {
+		req, _ := http.NewRequest("GET", server.URL+"/data"+query, nil)
+		resp, err := transport.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(""); err != nil {
+		t.Fatalf("first payment failed: %v", err)
+	}
+	price = "1500"
+	if err := get("?page=2"); err != nil {
+		t.Fatalf("payment within tolerance failed: %v", err)
+	}
+	// The history compares against the last payment, 1500, not the first
+	price = "2250"
+	if err := get(""); err != nil {
+		t.Fatalf("payment within tolerance of the last price failed: %v", err)
+	}
+	price = "3376"
+	err := get("")
+	var paymentErr *v2.PaymentError
+	if !errors.Is(err, v2.ErrRequirementsChanged) || !errors.As(err, &paymentErr) || paymentErr.Code != v2.ErrCodeInvalidRequirements {
+		t.Errorf("expected requirements change to be refused, got %v", err)
+	}
+}
+
+func TestTransport_Extensions(t *testing.T) {
+	var received map[string]v2.Extension
+	advertised := map[string]v2.Extension{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header := r.Header.Get("X-PAYMENT"); header != "" {
+			payment, _ := encoding.DecodePayment(header)
+			received = payment.Extensions
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPaymentRequired)
+		_ = json.NewEncoder(w).Encode(v2.PaymentRequired{
+			X402Version: 2,
+			Accepts:     []v2.PaymentRequirements{{Scheme: "exact", Network: "eip155:84532", Amount: "10000"}},
+			Extensions:  advertised,
+		})
+	}))
+	defer server.Close()
+
+	registry, err := extensions.NewRegistry(
+		extensions.Definition{Name: "buyer", Info: map[string]interface{}{"id": "agent-7"}},
+		extensions.Definition{Name: "terms", Schema: map[string]interface{}{"type": "object", "required": []string{"url"}}, Required: true},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &X402Transport{
+		Base:       http.DefaultTransport,
+		Signers:    []v2.Signer{&mockSigner{network: "eip155:84532", scheme: "exact"}},
+		Selector:   v2.NewDefaultPaymentSelector(),
+		Extensions: registry,
+	}
+	get := func() error {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		resp, err := transport.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// Responses lacking the required extension are not paid
+	if err := get(); !errors.Is(err, extensions.ErrMissingExtension) {
+		t.Fatalf("expected ErrMissingExtension, got %v", err)
+	}
+	if received != nil {
+		t.Fatal("expected no payment to be sent")
+	}
+
+	advertised["terms"] = v2.Extension{Info: map[string]interface{}{"url": "https://example.com/terms"}}
+	if err := get(); err != nil {
+		t.Fatalf("RoundTrip failed: %v", err)
+	}
+	if buyer, err := extensions.Get[map[string]string](received, "buyer"); err != nil || buyer["id"] != "agent-7" {
+		t.Errorf("expected payment to carry the buyer extension, got %v, %v", buyer, err)
+	}
+}
+
+func TestTransport_StatusRetries(t *testing.T) {
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+
+	tests := []struct {
+		name       string
+		statuses   []int
+		retryAfter string
+		maxRetries int
+		spendLimit int64
+		wantCode   v2.ErrorCode
+		wantErr    error
+		wantPaid   int
+		wantSigned int
+	}{
+		{name: "nonce conflict re-signs", statuses: []int{http.StatusConflict}, wantPaid: 2, wantSigned: 2},
+		{name: "too early waits for validAfter", statuses: []int{http.StatusTooEarly}, wantPaid: 2, wantSigned: 1},
+		{name: "rate limited respects Retry-After", statuses: []int{http.StatusTooManyRequests}, retryAfter: "0", wantPaid: 2, wantSigned: 1},
+		{name: "Retry-After too long", statuses: []int{http.StatusTooManyRequests}, retryAfter: "3600", wantCode: v2.ErrCodeRateLimited, wantErr: v2.ErrRateLimited, wantPaid: 1, wantSigned: 1},
+		{name: "retries exhausted", statuses: []int{http.StatusConflict, http.StatusConflict, http.StatusConflict}, wantCode: v2.ErrCodeNonceConflict, wantErr: v2.ErrPaymentReplayed, wantPaid: 3, wantSigned: 3},
+		{name: "retries disabled", statuses: []int{http.StatusTooEarly}, maxRetries: -1, wantCode: v2.ErrCodeTooEarly, wantErr: v2.ErrPaymentTooEarly, wantPaid: 1, wantSigned: 1},
+		{name: "re-signed payments count against the spend guard", statuses: []int{http.StatusConflict}, spendLimit: 15000, wantCode: v2.ErrCodeSpendLimitExceeded, wantErr: v2.ErrSpendLimitExceeded, wantPaid: 1, wantSigned: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paid atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("X-PAYMENT") == "" {
+					w.WriteHeader(http.StatusPaymentRequired)
+					_ = json.NewEncoder(w).Encode(v2.PaymentRequired{X402Version: 2, Accepts: []v2.PaymentRequirements{requirement}})
+					return
+				}
+				if n := int(paid.Add(1)); n <= len(tt.statuses) {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					w.WriteHeader(tt.statuses[n-1])
+					return
+				}
+				encoded, _ := encoding.EncodeSettlement(v2.SettleResponse{Success: true, Transaction: "0xtx", Network: requirement.Network})
+				w.Header().Set("X-PAYMENT-RESPONSE", encoded)
+				_, _ = w.Write([]byte("Protected content"))
+			}))
+			defer server.Close()
+
+			var signed int
+			signer := &mockSigner{network: "eip155:84532", scheme: "exact", signFunc: func(req *v2.PaymentRequirements) (*v2.PaymentPayload, error) {
+				signed++
+				now := time.Now().Unix()
+				return &v2.PaymentPayload{X402Version: 2, Accepted: *req, Payload: map[string]interface{}{
+					"signature": "0xsig",
+					"authorization": map[string]interface{}{
+						"from": "0xfrom", "to": req.PayTo, "value": req.Amount, "nonce": "0x01",
+						"validAfter":  strconv.FormatInt(now-10, 10),
+						"validBefore": strconv.FormatInt(now+60, 10),
+					},
+				}}, nil
+			}}
+			transport := &X402Transport{
+				Base:             http.DefaultTransport,
+				Signers:          []v2.Signer{signer},
+				Selector:         v2.NewDefaultPaymentSelector(),
+				MaxStatusRetries: tt.maxRetries,
+			}
+			if tt.spendLimit > 0 {
+				transport.SpendGuard = v2.NewSpendGuard(big.NewInt(tt.spendLimit))
+			}
+
+			req, _ := http.NewRequest("GET", server.URL, nil)
+			resp, err := transport.RoundTrip(req)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("expected success, got %v", err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					t.Errorf("expected 200, got %d", resp.StatusCode)
+				}
+			} else {
+				var paymentErr *v2.PaymentError
+				if !errors.As(err, &paymentErr) || paymentErr.Code != tt.wantCode || !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %s error, got %v", tt.wantCode, err)
+				}
+			}
+			if int(paid.Load()) != tt.wantPaid || signed != tt.wantSigned {
+				t.Errorf("expected %d paid requests and %d signatures, got %d and %d", tt.wantPaid, tt.wantSigned, paid.Load(), signed)
+			}
+		})
+	}
+}
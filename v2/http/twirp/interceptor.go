@@ -0,0 +1,156 @@
+// Package twirp provides x402 v2 payment gating for Twirp services.
+//
+// Twirp does not expose request headers to server interceptors, so services
+// are wrapped with WithPaymentHeaders to make the payment header available to
+// the interceptor returned by NewInterceptor. Calls without a valid payment
+// fail with a Twirp error whose metadata carries the base64-encoded
+// v2.PaymentRequired in X-PAYMENT-REQUIRED, the RPC equivalent of an HTTP 402
+// response. Settlements are returned in the X-PAYMENT-RESPONSE header.
+//
+// Example:
+//
+//	interceptor := twirp.NewInterceptor(x402http.Config{
+//	    FacilitatorURL: "https://facilitator.x402.org",
+//	}, twirp.WithProcedure("/acme.weather.WeatherService/Forecast", requirement))
+//	server := weather.NewWeatherServiceServer(svc, twirprpc.WithServerInterceptors(interceptor))
+//	mux.Handle(server.PathPrefix(), twirp.WithPaymentHeaders(server))
+package twirp
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+	"github.com/mark3labs/x402-go/v2/http/internal/rpcgate"
+	"github.com/twitchtv/twirp"
+)
+
+// Header names used by the interceptor.
+const (
+	PaymentHeader         = rpcgate.PaymentHeader
+	PaymentRequiredHeader = rpcgate.PaymentRequiredHeader
+	PaymentResponseHeader = rpcgate.PaymentResponseHeader
+)
+
+// paymentRequestKey is the context key of the request headers and URL stored
+// by WithPaymentHeaders.
+type paymentRequestKey struct{}
+
+// WithPaymentHeaders wraps a Twirp server so that its interceptors can read
+// the payment of each request, from the headers or query parameter named by
+// the interceptor's Config.Headers.
+func WithPaymentHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), paymentRequestKey{}, &http.Request{Header: r.Header, URL: r.URL})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// paymentRequest returns the request headers and URL stored by
+// WithPaymentHeaders.
+func paymentRequest(ctx context.Context) *http.Request {
+	if r, ok := ctx.Value(paymentRequestKey{}).(*http.Request); ok {
+		return r
+	}
+	return &http.Request{Header: http.Header{}, URL: &url.URL{}}
+}
+
+// Option configures the interceptor.
+type Option func(*options)
+
+type options struct {
+	procedures          map[string][]v2.PaymentRequirements
+	paymentRequiredCode twirp.ErrorCode
+}
+
+// WithProcedure requires payment for procedure (like "/acme.weather.WeatherService/Forecast")
+// with requirements. Once any procedure is listed, unlisted procedures are
+// free; without WithProcedure every procedure requires
+// Config.PaymentRequirements.
+func WithProcedure(procedure string, requirements ...v2.PaymentRequirements) Option {
+	return func(o *options) {
+		if o.procedures == nil {
+			o.procedures = make(map[string][]v2.PaymentRequirements)
+		}
+		o.procedures[procedure] = requirements
+	}
+}
+
+// WithPaymentRequiredCode sets the code of payment required errors. It
+// defaults to twirp.FailedPrecondition.
+func WithPaymentRequiredCode(code twirp.ErrorCode) Option {
+	return func(o *options) {
+		o.paymentRequiredCode = code
+	}
+}
+
+// NewInterceptor creates a server interceptor that gates methods behind
+// payments, using the facilitators, requirements and settings of config.
+// Successful calls are settled after the method returns.
+func NewInterceptor(config v2http.Config, opts ...Option) twirp.Interceptor {
+	o := options{paymentRequiredCode: twirp.FailedPrecondition}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	gate := rpcgate.New(config, o.procedures)
+
+	return func(next twirp.Method) twirp.Method {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			payment, failure := gate.Verify(ctx, procedure(ctx), gate.ReadPayment(paymentRequest(ctx)))
+			if failure != nil {
+				return nil, twirpError(failure, o.paymentRequiredCode)
+			}
+			if payment == nil {
+				return next(ctx, req)
+			}
+
+			resp, err := next(payment.Context(ctx), req)
+			if err != nil {
+				payment.Release()
+				return resp, err
+			}
+			settlement, failure := payment.Settle(ctx)
+			if failure != nil {
+				return nil, twirpError(failure, o.paymentRequiredCode)
+			}
+			if settlement != "" {
+				if err := twirp.SetHTTPResponseHeader(ctx, gate.PaymentResponseHeader(), settlement); err != nil {
+					slog.Default().Warn("failed to set payment response header", "error", err)
+				}
+			}
+			return resp, nil
+		}
+	}
+}
+
+// procedure returns the name of the called method, in the form
+// "/package.Service/Method".
+func procedure(ctx context.Context) string {
+	pkg, _ := twirp.PackageName(ctx)
+	service, _ := twirp.ServiceName(ctx)
+	method, _ := twirp.MethodName(ctx)
+	if pkg != "" {
+		service = pkg + "." + service
+	}
+	return "/" + service + "/" + method
+}
+
+// twirpError converts a gating failure to a Twirp error.
+func twirpError(failure *rpcgate.Failure, paymentRequiredCode twirp.ErrorCode) twirp.Error {
+	code := twirp.Unavailable
+	switch failure.Kind {
+	case rpcgate.KindPaymentRequired:
+		code = paymentRequiredCode
+	case rpcgate.KindInvalidPayment:
+		code = twirp.InvalidArgument
+	}
+
+	err := twirp.NewError(code, failure.Message)
+	if failure.PaymentRequired != "" {
+		err = err.WithMeta(PaymentRequiredHeader, failure.PaymentRequired)
+	}
+	return err
+}
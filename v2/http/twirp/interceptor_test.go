@@ -0,0 +1,116 @@
+package twirp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+	"github.com/twitchtv/twirp"
+	"github.com/twitchtv/twirp/ctxsetters"
+)
+
+func TestInterceptor(t *testing.T) {
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	validPayment, _ := encoding.EncodePayment(v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    requirement,
+		Payload:     map[string]interface{}{"signature": "0xsig"},
+	})
+
+	tests := []struct {
+		name         string
+		method       string
+		payment      string
+		valid        bool
+		opts         []Option
+		wantCode     twirp.ErrorCode
+		wantRequired bool
+		wantSettled  bool
+	}{
+		{name: "no payment", method: "Paid", wantCode: twirp.FailedPrecondition, wantRequired: true},
+		{name: "custom code", method: "Paid", opts: []Option{WithPaymentRequiredCode(twirp.PermissionDenied)}, wantCode: twirp.PermissionDenied, wantRequired: true},
+		{name: "invalid header", method: "Paid", payment: "not-base64!", wantCode: twirp.InvalidArgument},
+		{name: "rejected payment", method: "Paid", payment: validPayment, wantCode: twirp.FailedPrecondition, wantRequired: true},
+		{name: "paid", method: "Paid", payment: validPayment, valid: true, wantSettled: true},
+		{name: "free method", method: "Free"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settled := false
+			facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/supported":
+					_ = json.NewEncoder(w).Encode(v2.SupportedResponse{})
+				case "/verify":
+					_ = json.NewEncoder(w).Encode(v2.VerifyResponse{IsValid: tt.valid, InvalidReason: "insufficient_funds", Payer: "0xPayerAddress"})
+				case "/settle":
+					settled = true
+					_ = json.NewEncoder(w).Encode(v2.SettleResponse{Success: true, Transaction: "0xtx", Network: "eip155:84532"})
+				}
+			}))
+			defer facilitatorServer.Close()
+
+			opts := append([]Option{WithProcedure("/test.v1.TestService/Paid", requirement)}, tt.opts...)
+			interceptor := NewInterceptor(v2http.Config{FacilitatorURL: facilitatorServer.URL}, opts...)
+			method := interceptor(func(ctx context.Context, req interface{}) (interface{}, error) {
+				return "ok", nil
+			})
+
+			// Run the interceptor behind WithPaymentHeaders, as a Twirp server would.
+			var resp interface{}
+			var err error
+			handler := WithPaymentHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx := ctxsetters.WithPackageName(r.Context(), "test.v1")
+				ctx = ctxsetters.WithServiceName(ctx, "TestService")
+				ctx = ctxsetters.WithMethodName(ctx, tt.method)
+				ctx = ctxsetters.WithResponseWriter(ctx, w)
+				resp, err = method(ctx, "request")
+			}))
+			r := httptest.NewRequest("POST", "/twirp/test.v1.TestService/"+tt.method, nil)
+			if tt.payment != "" {
+				r.Header.Set(PaymentHeader, tt.payment)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if tt.wantCode != "" {
+				var twerr twirp.Error
+				if !errors.As(err, &twerr) {
+					t.Fatalf("Expected twirp error, got %v", err)
+				}
+				if twerr.Code() != tt.wantCode {
+					t.Errorf("Expected code %v, got %v", tt.wantCode, twerr.Code())
+				}
+				if required := twerr.Meta(PaymentRequiredHeader); (required != "") != tt.wantRequired {
+					t.Errorf("Expected payment requirements=%v, got %q", tt.wantRequired, required)
+				}
+				return
+			}
+
+			if err != nil || resp != "ok" {
+				t.Fatalf("Expected ok, got %v, %v", resp, err)
+			}
+			if settled != tt.wantSettled {
+				t.Errorf("Expected settled=%v, got %v", tt.wantSettled, settled)
+			}
+			if got := w.Header().Get(PaymentResponseHeader) != ""; got != tt.wantSettled {
+				t.Errorf("Expected payment response header=%v, got %v", tt.wantSettled, got)
+			}
+		})
+	}
+}
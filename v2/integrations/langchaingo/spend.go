@@ -0,0 +1,106 @@
+package langchaingo
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// Spend records a single payment made by a tool call.
+type Spend struct {
+	// Tool is the name of the tool that made the payment.
+	Tool string
+
+	// Amount is the payment amount in atomic units.
+	Amount string
+
+	// Asset is the token contract or mint address.
+	Asset string
+
+	// Network is the blockchain network (CAIP-2 format).
+	Network string
+
+	// Recipient is the payment recipient address.
+	Recipient string
+
+	// Transaction is the settlement transaction hash, if known.
+	Transaction string
+
+	// Timestamp is when the payment succeeded.
+	Timestamp time.Time
+}
+
+// SpendTracker accumulates the payments made by one or more tools.
+// It is safe for concurrent use and may be shared between tools.
+type SpendTracker struct {
+	mu     sync.Mutex
+	spends []Spend
+	totals map[string]*big.Int
+}
+
+// NewSpendTracker creates an empty SpendTracker.
+func NewSpendTracker() *SpendTracker {
+	return &SpendTracker{
+		totals: make(map[string]*big.Int),
+	}
+}
+
+// Record adds a spend to the tracker.
+func (t *SpendTracker) Record(spend Spend) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.spends = append(t.spends, spend)
+
+	amount, ok := new(big.Int).SetString(spend.Amount, 10)
+	if !ok {
+		return
+	}
+	key := totalKey(spend.Network, spend.Asset)
+	if t.totals[key] == nil {
+		t.totals[key] = new(big.Int)
+	}
+	t.totals[key].Add(t.totals[key], amount)
+}
+
+// Spends returns a copy of all recorded spends in the order they were recorded.
+func (t *SpendTracker) Spends() []Spend {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spends := make([]Spend, len(t.spends))
+	copy(spends, t.spends)
+	return spends
+}
+
+// Total returns the total amount spent in atomic units for the given network and asset.
+func (t *SpendTracker) Total(network, asset string) *big.Int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	total, ok := t.totals[totalKey(network, asset)]
+	if !ok {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(total)
+}
+
+// totalKey builds the map key used for per-asset totals.
+func totalKey(network, asset string) string {
+	return network + "|" + asset
+}
+
+// spendFromEvent converts a successful payment event into a Spend.
+func spendFromEvent(tool string, event v2.PaymentEvent) Spend {
+	return Spend{
+		Tool:        tool,
+		Amount:      event.Amount,
+		Asset:       event.Asset,
+		Network:     event.Network,
+		Recipient:   event.Recipient,
+		Transaction: event.Transaction,
+		Timestamp:   event.Timestamp,
+	}
+}
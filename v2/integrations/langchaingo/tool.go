@@ -0,0 +1,300 @@
+// Package langchaingo wraps paid HTTP APIs and MCP tools as LangChainGo tools.
+//
+// The tools returned by this package satisfy the tools.Tool interface from
+// github.com/tmc/langchaingo (Name, Description, Call) without importing it,
+// so agents can register them directly. Every payment made while a tool runs
+// is recorded in an optional SpendTracker and surfaced to the agent's
+// callbacks handler.
+package langchaingo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	mcpproto "github.com/mark3labs/mcp-go/mcp"
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// CallbacksHandler is the subset of langchaingo's callbacks.Handler used by the tools.
+// Any langchaingo callbacks handler satisfies it.
+type CallbacksHandler interface {
+	HandleToolStart(ctx context.Context, input string)
+	HandleToolEnd(ctx context.Context, output string)
+	HandleToolError(ctx context.Context, err error)
+	HandleText(ctx context.Context, text string)
+}
+
+// PaymentHandler can be implemented by a CallbacksHandler to receive structured
+// spend records. Handlers that do not implement it receive a text summary via HandleText.
+type PaymentHandler interface {
+	HandlePayment(ctx context.Context, spend Spend)
+}
+
+// Option configures a tool.
+type Option func(*toolConfig)
+
+// toolConfig holds the options shared by HTTP and MCP tools.
+type toolConfig struct {
+	tracker   *SpendTracker
+	callbacks CallbacksHandler
+	method    string
+	builder   RequestBuilder
+	argName   string
+}
+
+// WithSpendTracker records every payment made by the tool in tracker.
+func WithSpendTracker(tracker *SpendTracker) Option {
+	return func(c *toolConfig) {
+		c.tracker = tracker
+	}
+}
+
+// WithCallbacksHandler sets the agent callbacks handler that receives tool and spend events.
+func WithCallbacksHandler(handler CallbacksHandler) Option {
+	return func(c *toolConfig) {
+		c.callbacks = handler
+	}
+}
+
+// WithMethod sets the HTTP method used by an HTTP tool (default: GET).
+func WithMethod(method string) Option {
+	return func(c *toolConfig) {
+		c.method = method
+	}
+}
+
+// WithRequestBuilder sets a custom function that turns the tool input into an HTTP request.
+func WithRequestBuilder(builder RequestBuilder) Option {
+	return func(c *toolConfig) {
+		c.builder = builder
+	}
+}
+
+// WithArgumentName sets the argument name used when an MCP tool receives
+// plain-text input instead of a JSON object (default: "input").
+func WithArgumentName(name string) Option {
+	return func(c *toolConfig) {
+		c.argName = name
+	}
+}
+
+// RequestBuilder creates the HTTP request for a tool call from the agent's input.
+type RequestBuilder func(ctx context.Context, method, endpoint, input string) (*http.Request, error)
+
+// paidTool contains the name, description, and spend plumbing shared by all tools.
+type paidTool struct {
+	name        string
+	description string
+	config      toolConfig
+}
+
+// Name returns the tool name shown to the agent.
+func (t *paidTool) Name() string {
+	return t.name
+}
+
+// Description returns the tool description shown to the agent.
+func (t *paidTool) Description() string {
+	return t.description
+}
+
+// run invokes call with a context that records every successful payment.
+func (t *paidTool) run(ctx context.Context, input string, call func(context.Context) (string, error)) (string, error) {
+	if t.config.callbacks != nil {
+		t.config.callbacks.HandleToolStart(ctx, input)
+	}
+
+	ctx = v2.ContextWithPaymentCallback(ctx, func(event v2.PaymentEvent) {
+		if event.Type != v2.PaymentEventSuccess {
+			return
+		}
+		t.recordSpend(ctx, spendFromEvent(t.name, event))
+	})
+
+	output, err := call(ctx)
+	if err != nil {
+		if t.config.callbacks != nil {
+			t.config.callbacks.HandleToolError(ctx, err)
+		}
+		return "", err
+	}
+
+	if t.config.callbacks != nil {
+		t.config.callbacks.HandleToolEnd(ctx, output)
+	}
+	return output, nil
+}
+
+// recordSpend stores the spend and forwards it to the callbacks handler.
+func (t *paidTool) recordSpend(ctx context.Context, spend Spend) {
+	if t.config.tracker != nil {
+		t.config.tracker.Record(spend)
+	}
+	if t.config.callbacks == nil {
+		return
+	}
+	if handler, ok := t.config.callbacks.(PaymentHandler); ok {
+		handler.HandlePayment(ctx, spend)
+		return
+	}
+	t.config.callbacks.HandleText(ctx, fmt.Sprintf("x402: tool %s paid %s of %s on %s", spend.Tool, spend.Amount, spend.Asset, spend.Network))
+}
+
+// HTTPTool is a LangChainGo tool backed by a paid HTTP endpoint.
+type HTTPTool struct {
+	paidTool
+	endpoint string
+	client   *http.Client
+}
+
+// NewHTTPTool creates a tool that calls endpoint using client.
+// The client should be an x402-enabled client (e.g., v2http.NewClient(...).Client)
+// so that 402 responses are paid automatically.
+//
+// By default the tool sends a GET request with the agent input in the "input"
+// query parameter; use WithMethod or WithRequestBuilder to change this.
+func NewHTTPTool(name, description, endpoint string, client *http.Client, opts ...Option) *HTTPTool {
+	config := toolConfig{method: http.MethodGet}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.builder == nil {
+		config.builder = defaultRequestBuilder
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &HTTPTool{
+		paidTool: paidTool{name: name, description: description, config: config},
+		endpoint: endpoint,
+		client:   client,
+	}
+}
+
+// Call invokes the endpoint with the agent input and returns the response body.
+func (t *HTTPTool) Call(ctx context.Context, input string) (string, error) {
+	return t.run(ctx, input, func(ctx context.Context) (string, error) {
+		req, err := t.config.builder(ctx, t.config.method, t.endpoint, input)
+		if err != nil {
+			return "", fmt.Errorf("failed to build request: %w", err)
+		}
+
+		resp, err := t.client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", fmt.Errorf("failed to read response: %w", err)
+		}
+
+		if resp.StatusCode >= 400 {
+			return "", fmt.Errorf("tool %s: status %d: %s", t.name, resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+		return string(body), nil
+	})
+}
+
+// defaultRequestBuilder sends GET/DELETE input as a query parameter and other
+// methods' input as the request body.
+func defaultRequestBuilder(ctx context.Context, method, endpoint, input string) (*http.Request, error) {
+	if method == http.MethodGet || method == http.MethodDelete {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		if input != "" {
+			query := u.Query()
+			query.Set("input", input)
+			u.RawQuery = query.Encode()
+		}
+		return http.NewRequestWithContext(ctx, method, u.String(), nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewBufferString(input))
+	if err != nil {
+		return nil, err
+	}
+	if json.Valid([]byte(input)) {
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req.Header.Set("Content-Type", "text/plain")
+	}
+	return req, nil
+}
+
+// ToolCaller calls MCP tools. *client.Client from mcp-go satisfies it.
+type ToolCaller interface {
+	CallTool(ctx context.Context, request mcpproto.CallToolRequest) (*mcpproto.CallToolResult, error)
+}
+
+// MCPTool is a LangChainGo tool backed by a paid MCP tool.
+type MCPTool struct {
+	paidTool
+	toolName string
+	caller   ToolCaller
+}
+
+// NewMCPTool creates a tool that invokes toolName through caller.
+// The caller should use an x402-enabled transport (v2/mcp/client.NewTransport)
+// so that payment-required errors are paid automatically.
+//
+// The agent input is passed as the tool arguments when it is a JSON object;
+// otherwise it is passed as a single string argument (see WithArgumentName).
+func NewMCPTool(caller ToolCaller, toolName, description string, opts ...Option) *MCPTool {
+	config := toolConfig{argName: "input"}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &MCPTool{
+		paidTool: paidTool{name: toolName, description: description, config: config},
+		toolName: toolName,
+		caller:   caller,
+	}
+}
+
+// Call invokes the MCP tool and returns its text content.
+func (t *MCPTool) Call(ctx context.Context, input string) (string, error) {
+	return t.run(ctx, input, func(ctx context.Context) (string, error) {
+		var arguments map[string]any
+		if err := json.Unmarshal([]byte(input), &arguments); err != nil || arguments == nil {
+			arguments = map[string]any{t.config.argName: input}
+		}
+
+		request := mcpproto.CallToolRequest{}
+		request.Params.Name = t.toolName
+		request.Params.Arguments = arguments
+
+		result, err := t.caller.CallTool(ctx, request)
+		if err != nil {
+			return "", err
+		}
+
+		output := textContent(result)
+		if result.IsError {
+			return "", fmt.Errorf("tool %s: %s", t.toolName, output)
+		}
+		return output, nil
+	})
+}
+
+// textContent joins the text items of a tool result.
+func textContent(result *mcpproto.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		if text, ok := mcpproto.AsTextContent(content); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
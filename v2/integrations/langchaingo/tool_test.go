@@ -0,0 +1,187 @@
+package langchaingo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	mcpproto "github.com/mark3labs/mcp-go/mcp"
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// payingRoundTripper simulates an x402 transport that pays for every request.
+type payingRoundTripper struct {
+	lastRequest *http.Request
+}
+
+func (rt *payingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.lastRequest = req
+	v2.NotifyPaymentEvent(req.Context(), nil, v2.PaymentEvent{
+		Type:        v2.PaymentEventSuccess,
+		Timestamp:   time.Now(),
+		Method:      "HTTP",
+		Amount:      "10000",
+		Asset:       "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		Network:     "eip155:84532",
+		Transaction: "0xtx",
+	})
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader("weather: sunny")),
+		Header:     make(http.Header),
+	}, nil
+}
+
+// recordingHandler implements CallbacksHandler and PaymentHandler.
+type recordingHandler struct {
+	starts, ends, texts []string
+	errs                []error
+	spends              []Spend
+}
+
+func (h *recordingHandler) HandleToolStart(ctx context.Context, input string) {
+	h.starts = append(h.starts, input)
+}
+func (h *recordingHandler) HandleToolEnd(ctx context.Context, output string) {
+	h.ends = append(h.ends, output)
+}
+func (h *recordingHandler) HandleToolError(ctx context.Context, err error) {
+	h.errs = append(h.errs, err)
+}
+func (h *recordingHandler) HandleText(ctx context.Context, text string) {
+	h.texts = append(h.texts, text)
+}
+func (h *recordingHandler) HandlePayment(ctx context.Context, spend Spend) {
+	h.spends = append(h.spends, spend)
+}
+
+func TestHTTPTool_RecordsSpend(t *testing.T) {
+	rt := &payingRoundTripper{}
+	tracker := NewSpendTracker()
+	handler := &recordingHandler{}
+
+	tool := NewHTTPTool("weather", "Get the weather", "https://api.example.com/weather",
+		&http.Client{Transport: rt},
+		WithSpendTracker(tracker),
+		WithCallbacksHandler(handler),
+	)
+
+	if tool.Name() != "weather" || tool.Description() != "Get the weather" {
+		t.Errorf("unexpected name/description: %s / %s", tool.Name(), tool.Description())
+	}
+
+	output, err := tool.Call(context.Background(), "Berlin")
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if output != "weather: sunny" {
+		t.Errorf("unexpected output: %q", output)
+	}
+	if got := rt.lastRequest.URL.Query().Get("input"); got != "Berlin" {
+		t.Errorf("expected input query parameter Berlin, got %q", got)
+	}
+
+	total := tracker.Total("eip155:84532", "0x036CbD53842c5426634e7929541eC2318f3dCF7e")
+	if total.String() != "10000" {
+		t.Errorf("expected total 10000, got %s", total)
+	}
+	if len(handler.spends) != 1 || handler.spends[0].Tool != "weather" {
+		t.Errorf("expected one spend for weather, got %+v", handler.spends)
+	}
+	if len(handler.starts) != 1 || len(handler.ends) != 1 {
+		t.Errorf("expected start and end callbacks, got %d/%d", len(handler.starts), len(handler.ends))
+	}
+}
+
+func TestHTTPTool_PostBody(t *testing.T) {
+	rt := &payingRoundTripper{}
+	tool := NewHTTPTool("search", "Search", "https://api.example.com/search",
+		&http.Client{Transport: rt}, WithMethod(http.MethodPost))
+
+	if _, err := tool.Call(context.Background(), `{"q":"x402"}`); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if ct := rt.lastRequest.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected JSON content type, got %q", ct)
+	}
+}
+
+// fakeCaller simulates an x402-enabled MCP client.
+type fakeCaller struct {
+	request mcpproto.CallToolRequest
+	result  *mcpproto.CallToolResult
+	err     error
+}
+
+func (c *fakeCaller) CallTool(ctx context.Context, request mcpproto.CallToolRequest) (*mcpproto.CallToolResult, error) {
+	c.request = request
+	if c.err != nil {
+		return nil, c.err
+	}
+	v2.NotifyPaymentEvent(ctx, nil, v2.PaymentEvent{
+		Type:    v2.PaymentEventSuccess,
+		Method:  "MCP",
+		Amount:  "500",
+		Asset:   "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v",
+		Network: v2.NetworkSolanaMainnet,
+	})
+	return c.result, nil
+}
+
+func TestMCPTool_Call(t *testing.T) {
+	caller := &fakeCaller{result: mcpproto.NewToolResultText("42 results")}
+	tracker := NewSpendTracker()
+	handler := &recordingHandler{}
+
+	tool := NewMCPTool(caller, "search", "Paid search", WithSpendTracker(tracker), WithCallbacksHandler(handler))
+
+	output, err := tool.Call(context.Background(), "golang")
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if output != "42 results" {
+		t.Errorf("unexpected output: %q", output)
+	}
+	args, ok := caller.request.Params.Arguments.(map[string]any)
+	if !ok || args["input"] != "golang" {
+		t.Errorf("expected plain input wrapped in arguments, got %v", caller.request.Params.Arguments)
+	}
+
+	if _, err := tool.Call(context.Background(), `{"query":"golang","limit":5}`); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	args, _ = caller.request.Params.Arguments.(map[string]any)
+	if args["query"] != "golang" {
+		t.Errorf("expected JSON input used as arguments, got %v", args)
+	}
+
+	if len(tracker.Spends()) != 2 {
+		t.Errorf("expected 2 spends, got %d", len(tracker.Spends()))
+	}
+	if total := tracker.Total(v2.NetworkSolanaMainnet, "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"); total.String() != "1000" {
+		t.Errorf("expected total 1000, got %s", total)
+	}
+}
+
+func TestMCPTool_Errors(t *testing.T) {
+	handler := &recordingHandler{}
+	caller := &fakeCaller{err: errors.New("boom")}
+	tool := NewMCPTool(caller, "search", "Paid search", WithCallbacksHandler(handler))
+
+	if _, err := tool.Call(context.Background(), "q"); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(handler.errs) != 1 {
+		t.Errorf("expected tool error callback, got %d", len(handler.errs))
+	}
+
+	caller.err = nil
+	caller.result = mcpproto.NewToolResultError("bad query")
+	if _, err := tool.Call(context.Background(), "q"); err == nil || !strings.Contains(err.Error(), "bad query") {
+		t.Errorf("expected tool error result to surface, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+// Package allowance builds and signs relay orders for ERC-20 allowance
+// payments.
+//
+// A treasury approves a relayer to spend its tokens once, on-chain. Payments
+// are then authorized by a hot key signing a RelayOrder, an EIP-712 message
+// naming the treasury, the relayer, and the transfer, which the relayer
+// executes as transferFrom(treasury, to, value). The hot key never holds
+// funds, and the order binds the recipient and amount, so the relayer cannot
+// redirect them.
+package allowance
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Domain name and version of relay orders.
+const (
+	DomainName    = "x402 Allowance Relay"
+	DomainVersion = "1"
+)
+
+// Order is a RelayOrder message.
+type Order struct {
+	Owner       common.Address
+	Signer      common.Address
+	Spender     common.Address
+	Token       common.Address
+	To          common.Address
+	Value       *big.Int
+	ValidAfter  *big.Int
+	ValidBefore *big.Int
+	Nonce       [32]byte
+}
+
+// Sign signs order for chainID and returns the hex-encoded signature.
+func Sign(privateKey *ecdsa.PrivateKey, chainID *big.Int, order *Order) (string, error) {
+	digest, err := Digest(chainID, order)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := crypto.Sign(digest, privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign relay order: %w", err)
+	}
+	signature[64] += 27
+
+	return "0x" + hex.EncodeToString(signature), nil
+}
+
+// Recover returns the address that produced signature over order.
+func Recover(chainID *big.Int, order *Order, signature string) (common.Address, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != 65 {
+		return common.Address{}, fmt.Errorf("invalid signature encoding")
+	}
+	sig = append([]byte{}, sig...)
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+
+	digest, err := Digest(chainID, order)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	publicKey, err := crypto.SigToPub(digest, sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to recover signer: %w", err)
+	}
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
+
+// Digest returns the EIP-712 digest of order for chainID.
+func Digest(chainID *big.Int, order *Order) ([]byte, error) {
+	if order.Value == nil || order.ValidAfter == nil || order.ValidBefore == nil {
+		return nil, fmt.Errorf("incomplete relay order")
+	}
+
+	typedData := apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": []apitypes.Type{
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+			},
+			"RelayOrder": []apitypes.Type{
+				{Name: "owner", Type: "address"},
+				{Name: "signer", Type: "address"},
+				{Name: "spender", Type: "address"},
+				{Name: "token", Type: "address"},
+				{Name: "to", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "validAfter", Type: "uint256"},
+				{Name: "validBefore", Type: "uint256"},
+				{Name: "nonce", Type: "bytes32"},
+			},
+		},
+		PrimaryType: "RelayOrder",
+		Domain: apitypes.TypedDataDomain{
+			Name:    DomainName,
+			Version: DomainVersion,
+			ChainId: (*math.HexOrDecimal256)(chainID),
+		},
+		Message: apitypes.TypedDataMessage{
+			"owner":       order.Owner.Hex(),
+			"signer":      order.Signer.Hex(),
+			"spender":     order.Spender.Hex(),
+			"token":       order.Token.Hex(),
+			"to":          order.To.Hex(),
+			"value":       (*math.HexOrDecimal256)(order.Value),
+			"validAfter":  (*math.HexOrDecimal256)(order.ValidAfter),
+			"validBefore": (*math.HexOrDecimal256)(order.ValidBefore),
+			"nonce":       hexutil.Encode(order.Nonce[:]),
+		},
+	}
+
+	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash domain: %w", err)
+	}
+
+	messageHash, err := typedData.HashStruct("RelayOrder", typedData.Message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash relay order: %w", err)
+	}
+
+	rawData := append([]byte{0x19, 0x01}, append(domainSeparator, messageHash...)...)
+	return crypto.Keccak256(rawData), nil
+}
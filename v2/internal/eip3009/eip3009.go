@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
@@ -23,22 +24,33 @@ type Authorization struct {
 	Nonce       [32]byte
 }
 
+const DefaultValidAfterBackdate = 10 * time.Second
+
 func CreateAuthorization(from, to common.Address, value *big.Int, timeoutSeconds int) (*Authorization, error) {
-	nonce, err := GenerateNonce()
+	now := time.Now()
+	return CreateAuthorizationWithWindow(from, to, value, now.Add(-DefaultValidAfterBackdate), now.Add(time.Duration(timeoutSeconds)*time.Second))
+}
+
+func CreateAuthorizationWithWindow(from, to common.Address, value *big.Int, validAfter, validBefore time.Time) (*Authorization, error) {
+	return CreateAuthorizationFromSource(RandomNonceSource{}, NonceParams{From: from, To: to, Value: value}, validAfter, validBefore)
+}
+
+func CreateAuthorizationFromSource(source NonceSource, params NonceParams, validAfter, validBefore time.Time) (*Authorization, error) {
+	if !validBefore.After(validAfter) {
+		return nil, fmt.Errorf("invalid authorization window: validBefore %d is not after validAfter %d", validBefore.Unix(), validAfter.Unix())
+	}
+
+	nonce, err := source.Nonce(params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
-	now := time.Now().Unix()
-	validAfter := big.NewInt(now - 10)
-	validBefore := big.NewInt(now + int64(timeoutSeconds))
-
 	return &Authorization{
-		From:        from,
-		To:          to,
-		Value:       value,
-		ValidAfter:  validAfter,
-		ValidBefore: validBefore,
+		From:        params.From,
+		To:          params.To,
+		Value:       params.Value,
+		ValidAfter:  big.NewInt(validAfter.Unix()),
+		ValidBefore: big.NewInt(validBefore.Unix()),
 		Nonce:       nonce,
 	}, nil
 }
@@ -51,53 +63,84 @@ func GenerateNonce() ([32]byte, error) {
 	return nonce, nil
 }
 
+type Domain struct {
+	Name              string
+	Version           string
+	ChainID           *big.Int
+	VerifyingContract common.Address
+	Salt              *common.Hash
+}
+
 func SignAuthorization(privateKey *ecdsa.PrivateKey, tokenAddress common.Address, chainID *big.Int, auth *Authorization, name, version string) (string, error) {
-	typedData := apitypes.TypedData{
-		Types: apitypes.Types{
-			"EIP712Domain": []apitypes.Type{
-				{Name: "name", Type: "string"},
-				{Name: "version", Type: "string"},
-				{Name: "chainId", Type: "uint256"},
-				{Name: "verifyingContract", Type: "address"},
-			},
-			"TransferWithAuthorization": []apitypes.Type{
-				{Name: "from", Type: "address"},
-				{Name: "to", Type: "address"},
-				{Name: "value", Type: "uint256"},
-				{Name: "validAfter", Type: "uint256"},
-				{Name: "validBefore", Type: "uint256"},
-				{Name: "nonce", Type: "bytes32"},
-			},
-		},
-		PrimaryType: "TransferWithAuthorization",
-		Domain: apitypes.TypedDataDomain{
-			Name:              name,
-			Version:           version,
-			ChainId:           (*math.HexOrDecimal256)(chainID),
-			VerifyingContract: tokenAddress.Hex(),
-		},
-		Message: apitypes.TypedDataMessage{
-			"from":        auth.From.Hex(),
-			"to":          auth.To.Hex(),
-			"value":       (*math.HexOrDecimal256)(auth.Value),
-			"validAfter":  (*math.HexOrDecimal256)(auth.ValidAfter),
-			"validBefore": (*math.HexOrDecimal256)(auth.ValidBefore),
-			"nonce":       common.BytesToHash(auth.Nonce[:]).Hex(),
-		},
+	return SignAuthorizationWithDomain(privateKey, Domain{
+		Name:              name,
+		Version:           version,
+		ChainID:           chainID,
+		VerifyingContract: tokenAddress,
+	}, auth)
+}
+
+func SignAuthorizationWithDomain(privateKey *ecdsa.PrivateKey, domain Domain, auth *Authorization) (string, error) {
+	return signTypedData(privateKey, domain, "TransferWithAuthorization", transferFields, transferMessage(auth))
+}
+
+// Recover returns the address that produced signature over auth in domain.
+func Recover(domain Domain, auth *Authorization, signature string) (common.Address, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != 65 {
+		return common.Address{}, fmt.Errorf("invalid signature encoding")
+	}
+	sig = append([]byte{}, sig...)
+	if sig[64] >= 27 {
+		sig[64] -= 27
 	}
 
-	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+	digest, err := typedDataDigest(domain, "TransferWithAuthorization", transferFields, transferMessage(auth))
 	if err != nil {
-		return "", fmt.Errorf("failed to hash domain: %w", err)
+		return common.Address{}, err
 	}
-
-	messageHash, err := typedData.HashStruct("TransferWithAuthorization", typedData.Message)
+	publicKey, err := crypto.SigToPub(digest, sig)
 	if err != nil {
-		return "", fmt.Errorf("failed to hash message: %w", err)
+		return common.Address{}, fmt.Errorf("failed to recover signer: %w", err)
 	}
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
 
-	rawData := append([]byte{0x19, 0x01}, append(domainSeparator, messageHash...)...)
-	digest := crypto.Keccak256(rawData)
+var transferFields = []apitypes.Type{
+	{Name: "from", Type: "address"},
+	{Name: "to", Type: "address"},
+	{Name: "value", Type: "uint256"},
+	{Name: "validAfter", Type: "uint256"},
+	{Name: "validBefore", Type: "uint256"},
+	{Name: "nonce", Type: "bytes32"},
+}
+
+func transferMessage(auth *Authorization) apitypes.TypedDataMessage {
+	return apitypes.TypedDataMessage{
+		"from":        auth.From.Hex(),
+		"to":          auth.To.Hex(),
+		"value":       (*math.HexOrDecimal256)(auth.Value),
+		"validAfter":  (*math.HexOrDecimal256)(auth.ValidAfter),
+		"validBefore": (*math.HexOrDecimal256)(auth.ValidBefore),
+		"nonce":       common.BytesToHash(auth.Nonce[:]).Hex(),
+	}
+}
+
+func SignCancelAuthorizationWithDomain(privateKey *ecdsa.PrivateKey, domain Domain, authorizer common.Address, nonce [32]byte) (string, error) {
+	return signTypedData(privateKey, domain, "CancelAuthorization", []apitypes.Type{
+		{Name: "authorizer", Type: "address"},
+		{Name: "nonce", Type: "bytes32"},
+	}, apitypes.TypedDataMessage{
+		"authorizer": authorizer.Hex(),
+		"nonce":      common.BytesToHash(nonce[:]).Hex(),
+	})
+}
+
+func signTypedData(privateKey *ecdsa.PrivateKey, domain Domain, primaryType string, fields []apitypes.Type, message apitypes.TypedDataMessage) (string, error) {
+	digest, err := typedDataDigest(domain, primaryType, fields, message)
+	if err != nil {
+		return "", err
+	}
 
 	signature, err := crypto.Sign(digest, privateKey)
 	if err != nil {
@@ -108,3 +151,55 @@ func SignAuthorization(privateKey *ecdsa.PrivateKey, tokenAddress common.Address
 
 	return "0x" + hex.EncodeToString(signature), nil
 }
+
+// TypedData returns the EIP-712 typed data of auth in domain, as signed by
+// wallets through eth_signTypedData_v4.
+func TypedData(domain Domain, auth *Authorization) apitypes.TypedData {
+	return typedData(domain, "TransferWithAuthorization", transferFields, transferMessage(auth))
+}
+
+func typedData(domain Domain, primaryType string, fields []apitypes.Type, message apitypes.TypedDataMessage) apitypes.TypedData {
+	domainType := []apitypes.Type{
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	}
+	typedDomain := apitypes.TypedDataDomain{
+		Name:              domain.Name,
+		Version:           domain.Version,
+		ChainId:           (*math.HexOrDecimal256)(domain.ChainID),
+		VerifyingContract: domain.VerifyingContract.Hex(),
+	}
+	if domain.Salt != nil {
+		domainType = append(domainType, apitypes.Type{Name: "salt", Type: "bytes32"})
+		typedDomain.Salt = domain.Salt.Hex()
+	}
+
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": domainType,
+			primaryType:    fields,
+		},
+		PrimaryType: primaryType,
+		Domain:      typedDomain,
+		Message:     message,
+	}
+}
+
+func typedDataDigest(domain Domain, primaryType string, fields []apitypes.Type, message apitypes.TypedDataMessage) ([]byte, error) {
+	typedData := typedData(domain, primaryType, fields, message)
+
+	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash domain: %w", err)
+	}
+
+	messageHash, err := typedData.HashStruct(primaryType, typedData.Message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash message: %w", err)
+	}
+
+	rawData := append([]byte{0x19, 0x01}, append(domainSeparator, messageHash...)...)
+	return crypto.Keccak256(rawData), nil
+}
@@ -413,6 +413,57 @@ func TestSignAuthorization(t *testing.T) {
 	})
 }
 
+func TestSignAuthorizationWithDomain(t *testing.T) {
+	privateKey, err := crypto.HexToECDSA(testPrivateKey)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+
+	from := crypto.PubkeyToAddress(privateKey.PublicKey)
+	to := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+	tokenAddress := common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
+	chainID := big.NewInt(84532)
+
+	auth, err := CreateAuthorization(from, to, big.NewInt(1000000), 300)
+	if err != nil {
+		t.Fatalf("Failed to create authorization: %v", err)
+	}
+
+	domain := Domain{Name: "USD Coin", Version: "2", ChainID: chainID, VerifyingContract: tokenAddress}
+
+	t.Run("matches SignAuthorization without salt", func(t *testing.T) {
+		withDomain, err := SignAuthorizationWithDomain(privateKey, domain, auth)
+		if err != nil {
+			t.Fatalf("Failed to sign authorization: %v", err)
+		}
+		legacy, err := SignAuthorization(privateKey, tokenAddress, chainID, auth, "USD Coin", "2")
+		if err != nil {
+			t.Fatalf("Failed to sign authorization: %v", err)
+		}
+		if withDomain != legacy {
+			t.Error("Expected identical signatures for identical domains")
+		}
+	})
+
+	t.Run("salt changes the signature", func(t *testing.T) {
+		unsalted, err := SignAuthorizationWithDomain(privateKey, domain, auth)
+		if err != nil {
+			t.Fatalf("Failed to sign authorization: %v", err)
+		}
+
+		salt := common.HexToHash("0xabcd")
+		salted := domain
+		salted.Salt = &salt
+		sig, err := SignAuthorizationWithDomain(privateKey, salted, auth)
+		if err != nil {
+			t.Fatalf("Failed to sign authorization: %v", err)
+		}
+		if sig == unsalted {
+			t.Error("Expected salt to change the signature")
+		}
+	})
+}
+
 func TestAuthorizationFields(t *testing.T) {
 	t.Run("Authorization struct has all required fields", func(t *testing.T) {
 		auth := Authorization{}
@@ -428,3 +479,39 @@ func TestAuthorizationFields(t *testing.T) {
 		// If this compiles, the struct has all required fields with correct types
 	})
 }
+
+func TestRecover(t *testing.T) {
+	privateKey, err := crypto.HexToECDSA(testPrivateKey)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+
+	from := crypto.PubkeyToAddress(privateKey.PublicKey)
+	auth, err := CreateAuthorization(from, common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"), big.NewInt(1000000), 300)
+	if err != nil {
+		t.Fatalf("Failed to create authorization: %v", err)
+	}
+	domain := Domain{Name: "USD Coin", Version: "2", ChainID: big.NewInt(84532), VerifyingContract: common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")}
+	signature, err := SignAuthorizationWithDomain(privateKey, domain, auth)
+	if err != nil {
+		t.Fatalf("Failed to sign authorization: %v", err)
+	}
+
+	signer, err := Recover(domain, auth, signature)
+	if err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if signer != from {
+		t.Errorf("Expected signer %s, got %s", from.Hex(), signer.Hex())
+	}
+
+	other := domain
+	other.ChainID = big.NewInt(8453)
+	if signer, err := Recover(other, auth, signature); err == nil && signer == from {
+		t.Error("Expected a different signer for another chain ID")
+	}
+
+	if _, err := Recover(domain, auth, "0x1234"); err == nil {
+		t.Error("Expected an error for a malformed signature")
+	}
+}
@@ -0,0 +1,96 @@
+package eip3009
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type NonceParams struct {
+	From    common.Address
+	To      common.Address
+	Value   *big.Int
+	Token   common.Address
+	ChainID *big.Int
+	Key     string
+}
+
+type NonceSource interface {
+	Nonce(params NonceParams) ([32]byte, error)
+}
+
+type RandomNonceSource struct{}
+
+func (RandomNonceSource) Nonce(NonceParams) ([32]byte, error) {
+	return GenerateNonce()
+}
+
+type HMACNonceSource struct {
+	secret []byte
+}
+
+func NewHMACNonceSource(secret []byte) (*HMACNonceSource, error) {
+	if len(secret) < 16 {
+		return nil, errors.New("hmac nonce secret must be at least 16 bytes")
+	}
+	return &HMACNonceSource{secret: append([]byte(nil), secret...)}, nil
+}
+
+func (s *HMACNonceSource) Nonce(params NonceParams) ([32]byte, error) {
+	var nonce [32]byte
+	if params.Value == nil || params.ChainID == nil {
+		return nonce, errors.New("hmac nonce requires value and chain ID")
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(params.ChainID.Bytes())
+	mac.Write(params.Token.Bytes())
+	mac.Write(params.From.Bytes())
+	mac.Write(params.To.Bytes())
+	mac.Write(common.LeftPadBytes(params.Value.Bytes(), 32))
+	if params.Key != "" {
+		mac.Write([]byte(params.Key))
+	} else {
+		// Without a key there is nothing to tie retries together, so each
+		// attempt gets its own nonce
+		attempt, err := GenerateNonce()
+		if err != nil {
+			return nonce, err
+		}
+		mac.Write(attempt[:])
+	}
+	copy(nonce[:], mac.Sum(nil))
+	return nonce, nil
+}
+
+type SequentialNonceSource struct {
+	mu     sync.Mutex
+	prefix [24]byte
+	next   uint64
+}
+
+func NewSequentialNonceSource(prefix []byte, start uint64) (*SequentialNonceSource, error) {
+	if len(prefix) > 24 {
+		return nil, fmt.Errorf("sequential nonce prefix must be at most 24 bytes, got %d", len(prefix))
+	}
+	s := &SequentialNonceSource{next: start}
+	copy(s.prefix[24-len(prefix):], prefix)
+	return s, nil
+}
+
+func (s *SequentialNonceSource) Nonce(NonceParams) ([32]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var nonce [32]byte
+	copy(nonce[:24], s.prefix[:])
+	binary.BigEndian.PutUint64(nonce[24:], s.next)
+	s.next++
+	return nonce, nil
+}
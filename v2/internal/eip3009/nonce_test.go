@@ -0,0 +1,104 @@
+package eip3009
+
+import (
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestHMACNonceSource(t *testing.T) {
+	if _, err := NewHMACNonceSource([]byte("short")); err == nil {
+		t.Error("Expected error for short secret")
+	}
+
+	source, err := NewHMACNonceSource([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+
+	params := NonceParams{
+		From:    common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"),
+		To:      common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"),
+		Value:   big.NewInt(1000000),
+		Token:   common.HexToAddress("0x036CbD53842c5426634e7929541eC2318f3dCF7e"),
+		ChainID: big.NewInt(84532),
+		Key:     "order-1",
+	}
+
+	first, err := source.Nonce(params)
+	if err != nil {
+		t.Fatalf("Failed to generate nonce: %v", err)
+	}
+	second, _ := source.Nonce(params)
+	if first != second {
+		t.Error("Expected identical nonces for identical parameters")
+	}
+
+	params.Key = "order-2"
+	third, _ := source.Nonce(params)
+	if third == first {
+		t.Error("Expected different nonce for a different idempotency key")
+	}
+
+	// Two identical purchases without an idempotency key must both be able to settle
+	params.Key = ""
+	fourth, _ := source.Nonce(params)
+	fifth, _ := source.Nonce(params)
+	if fourth == fifth {
+		t.Error("Expected distinct nonces for back-to-back payments without an idempotency key")
+	}
+
+	params.Value = nil
+	if _, err := source.Nonce(params); err == nil {
+		t.Error("Expected error without value")
+	}
+}
+
+func TestSequentialNonceSource(t *testing.T) {
+	if _, err := NewSequentialNonceSource(make([]byte, 25), 0); err == nil {
+		t.Error("Expected error for prefix longer than 24 bytes")
+	}
+
+	source, err := NewSequentialNonceSource([]byte{0xaa, 0xbb}, 41)
+	if err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+
+	for want := uint64(41); want < 44; want++ {
+		nonce, err := source.Nonce(NonceParams{})
+		if err != nil {
+			t.Fatalf("Failed to generate nonce: %v", err)
+		}
+		if nonce[22] != 0xaa || nonce[23] != 0xbb {
+			t.Errorf("Expected prefix in bytes 22-23, got %x", nonce[:24])
+		}
+		if got := binary.BigEndian.Uint64(nonce[24:]); got != want {
+			t.Errorf("Expected counter %d, got %d", want, got)
+		}
+	}
+}
+
+func TestCreateAuthorizationFromSource(t *testing.T) {
+	source, _ := NewSequentialNonceSource(nil, 7)
+	params := NonceParams{
+		From:  common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"),
+		To:    common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"),
+		Value: big.NewInt(1),
+	}
+	now := time.Now()
+
+	auth, err := CreateAuthorizationFromSource(source, params, now, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to create authorization: %v", err)
+	}
+	if binary.BigEndian.Uint64(auth.Nonce[24:]) != 7 {
+		t.Errorf("Expected nonce from source, got %x", auth.Nonce)
+	}
+
+	if _, err := CreateAuthorizationFromSource(source, params, now, now); err == nil {
+		t.Error("Expected error for empty window")
+	}
+}
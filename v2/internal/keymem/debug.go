@@ -0,0 +1,26 @@
+//go:build x402debug
+
+package keymem
+
+import (
+	"log/slog"
+	"runtime"
+)
+
+// Debug reports whether the x402debug runtime checks are compiled in.
+const Debug = true
+
+// trackOpen reports keys that become unreachable without being closed, which
+// leaves key material in memory until the allocator reuses it.
+func trackOpen(k *Key) {
+	runtime.SetFinalizer(k, func(k *Key) {
+		if !k.closed {
+			slog.Default().Error("keymem: private key garbage collected without Close; call Close on signers holding keys")
+		}
+	})
+}
+
+// trackClosed stops tracking a closed key.
+func trackClosed(k *Key) {
+	runtime.SetFinalizer(k, nil)
+}
@@ -0,0 +1,146 @@
+// Package keymem holds private key material in locked, zeroizable memory.
+//
+// A Key copies key bytes into a buffer that is locked into RAM where the
+// platform supports it (so it is not written to swap), hands the bytes to
+// signing code only for the duration of a callback, and wipes them on Close.
+// Keys never format their contents, so a signer printed with %v or logged by
+// accident does not leak its key.
+//
+// Building with the x402debug tag enables a runtime check that reports keys
+// garbage collected without Close.
+package keymem
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// redacted is printed in place of key material.
+const redacted = "[REDACTED]"
+
+// Key is private key material in locked memory. It is safe for concurrent use.
+type Key struct {
+	mu     sync.RWMutex
+	buf    []byte
+	locked bool
+	closed bool
+}
+
+// New copies material into a new Key and wipes material.
+func New(material []byte) *Key {
+	k := &Key{buf: make([]byte, len(material))}
+	copy(k.buf, material)
+	Wipe(material)
+	k.locked = lock(k.buf) == nil
+	trackOpen(k)
+	return k
+}
+
+// Copy copies material into a new Key, leaving material intact.
+func Copy(material []byte) *Key {
+	return New(append([]byte(nil), material...))
+}
+
+// FromECDSA copies the scalar of key into a new Key. key itself is left intact;
+// callers that own it should wipe it with WipeECDSA.
+func FromECDSA(key *ecdsa.PrivateKey) *Key {
+	return New(crypto.FromECDSA(key))
+}
+
+// Use calls fn with the key bytes. fn must not retain the slice.
+// Returns v2.ErrSignerClosed if the key has been closed.
+func (k *Key) Use(fn func(material []byte) error) error {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.closed {
+		return v2.ErrSignerClosed
+	}
+	return fn(k.buf)
+}
+
+// WithBytes calls fn with the key bytes of k and returns its result.
+// fn must not retain the slice.
+func WithBytes[T any](k *Key, fn func(material []byte) (T, error)) (T, error) {
+	var result T
+	err := k.Use(func(material []byte) error {
+		var err error
+		result, err = fn(material)
+		return err
+	})
+	return result, err
+}
+
+// WithECDSA calls fn with a secp256k1 private key rebuilt from the key bytes of
+// k and returns its result. The rebuilt key is wiped when fn returns.
+func WithECDSA[T any](k *Key, fn func(key *ecdsa.PrivateKey) (T, error)) (T, error) {
+	return WithBytes(k, func(material []byte) (T, error) {
+		key, err := crypto.ToECDSA(material)
+		if err != nil {
+			var zero T
+			return zero, errors.New("stored key is not a valid secp256k1 key")
+		}
+		defer WipeECDSA(key)
+		return fn(key)
+	})
+}
+
+// Close wipes the key and unlocks its memory. Later Use calls fail with
+// v2.ErrSignerClosed. Close is idempotent.
+func (k *Key) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.closed {
+		return nil
+	}
+	k.closed = true
+	trackClosed(k)
+
+	Wipe(k.buf)
+	if k.locked {
+		if err := unlock(k.buf); err != nil {
+			return fmt.Errorf("unlock key memory: %w", err)
+		}
+	}
+	return nil
+}
+
+// Closed reports whether the key has been closed.
+func (k *Key) Closed() bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.closed
+}
+
+// String implements fmt.Stringer without revealing the key.
+func (k *Key) String() string {
+	return redacted
+}
+
+// GoString implements fmt.GoStringer without revealing the key.
+func (k *Key) GoString() string {
+	return redacted
+}
+
+// Wipe overwrites b with zeros.
+func Wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// WipeECDSA overwrites the private scalar of key with zeros.
+func WipeECDSA(key *ecdsa.PrivateKey) {
+	if key == nil || key.D == nil {
+		return
+	}
+	words := key.D.Bits()
+	for i := range words {
+		words[i] = 0
+	}
+	key.D.SetInt64(0)
+}
@@ -0,0 +1,78 @@
+package keymem
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func TestKey(t *testing.T) {
+	material := []byte{1, 2, 3, 4}
+	key := New(material)
+	if !bytes.Equal(material, make([]byte, 4)) {
+		t.Errorf("expected New to wipe its input, got %v", material)
+	}
+
+	var seen []byte
+	if err := key.Use(func(b []byte) error { seen = append(seen, b...); return nil }); err != nil {
+		t.Fatalf("Use failed: %v", err)
+	}
+	if !bytes.Equal(seen, []byte{1, 2, 3, 4}) {
+		t.Errorf("unexpected key bytes %v", seen)
+	}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		if got := fmt.Sprintf(format, key); got != redacted {
+			t.Errorf("%s formatted key as %q", format, got)
+		}
+	}
+
+	buf := key.buf
+	if err := key.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if !bytes.Equal(buf, make([]byte, 4)) {
+		t.Errorf("expected Close to wipe the buffer, got %v", buf)
+	}
+	if err := key.Use(func([]byte) error { return nil }); !errors.Is(err, v2.ErrSignerClosed) {
+		t.Errorf("expected ErrSignerClosed, got %v", err)
+	}
+	if err := key.Close(); err != nil {
+		t.Errorf("expected second Close to be a no-op, got %v", err)
+	}
+}
+
+func TestWithECDSA(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := crypto.PubkeyToAddress(privateKey.PublicKey)
+	key := FromECDSA(privateKey)
+	defer key.Close()
+
+	var used *ecdsa.PrivateKey
+	address, err := WithECDSA(key, func(k *ecdsa.PrivateKey) (string, error) {
+		used = k
+		return crypto.PubkeyToAddress(k.PublicKey).Hex(), nil
+	})
+	if err != nil || address != want.Hex() {
+		t.Fatalf("expected %s, got %s (%v)", want.Hex(), address, err)
+	}
+	if used.D.Sign() != 0 {
+		t.Error("expected the rebuilt key to be wiped after use")
+	}
+
+	words := privateKey.D.Bits()
+	WipeECDSA(privateKey)
+	for _, word := range words[:cap(words)] {
+		if word != 0 {
+			t.Fatal("expected WipeECDSA to zero the scalar's memory")
+		}
+	}
+}
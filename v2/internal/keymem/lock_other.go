@@ -0,0 +1,9 @@
+//go:build !unix
+
+package keymem
+
+// lock is a no-op on platforms without mlock; keys are still wiped on Close.
+func lock([]byte) error { return nil }
+
+// unlock is a no-op on platforms without mlock.
+func unlock([]byte) error { return nil }
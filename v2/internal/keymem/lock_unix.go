@@ -0,0 +1,21 @@
+//go:build unix
+
+package keymem
+
+import "syscall"
+
+// lock locks b into RAM so it is never written to swap.
+func lock(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	return syscall.Mlock(b)
+}
+
+// unlock releases a lock taken by lock.
+func unlock(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	return syscall.Munlock(b)
+}
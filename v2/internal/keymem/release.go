@@ -0,0 +1,9 @@
+//go:build !x402debug
+
+package keymem
+
+// Debug reports whether the x402debug runtime checks are compiled in.
+const Debug = false
+
+func trackOpen(*Key)   {}
+func trackClosed(*Key) {}
@@ -0,0 +1,155 @@
+// Package permit2 builds and signs Uniswap Permit2 SignatureTransfer permits.
+//
+// Permits are signed as PermitWitnessTransferFrom messages whose witness binds
+// the payment recipient and validity start, so the spender can only pull the
+// permitted amount to the recipient the payer agreed to.
+package permit2
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Address is the canonical Permit2 contract address, identical on every supported chain.
+var Address = common.HexToAddress("0x000000000022D473030F116dDEE9F6B43aC78BA3")
+
+// Witness is the payment data bound into the permit signature.
+type Witness struct {
+	To         common.Address
+	ValidAfter *big.Int
+	Extra      []byte
+}
+
+// Permit is a PermitWitnessTransferFrom message.
+type Permit struct {
+	Token    common.Address
+	Amount   *big.Int
+	Spender  common.Address
+	Nonce    *big.Int
+	Deadline *big.Int
+	Witness  Witness
+}
+
+// NewNonce returns a random 256-bit nonce. Permit2 uses unordered nonces, so
+// random values never collide in practice.
+func NewNonce() (*big.Int, error) {
+	var nonce [32]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(nonce[:]), nil
+}
+
+// Sign signs permit for chainID and returns the hex-encoded signature.
+func Sign(privateKey *ecdsa.PrivateKey, chainID *big.Int, permit *Permit) (string, error) {
+	digest, err := Digest(chainID, permit)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := crypto.Sign(digest, privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign permit: %w", err)
+	}
+	signature[64] += 27
+
+	return "0x" + hex.EncodeToString(signature), nil
+}
+
+// Recover returns the address that produced signature over permit.
+func Recover(chainID *big.Int, permit *Permit, signature string) (common.Address, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != 65 {
+		return common.Address{}, fmt.Errorf("invalid signature encoding")
+	}
+	sig = append([]byte{}, sig...)
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+
+	digest, err := Digest(chainID, permit)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	publicKey, err := crypto.SigToPub(digest, sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to recover signer: %w", err)
+	}
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
+
+// Digest returns the EIP-712 digest of permit for chainID.
+func Digest(chainID *big.Int, permit *Permit) ([]byte, error) {
+	if permit.Amount == nil || permit.Nonce == nil || permit.Deadline == nil || permit.Witness.ValidAfter == nil {
+		return nil, fmt.Errorf("incomplete permit")
+	}
+
+	typedData := apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": []apitypes.Type{
+				{Name: "name", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"PermitWitnessTransferFrom": []apitypes.Type{
+				{Name: "permitted", Type: "TokenPermissions"},
+				{Name: "spender", Type: "address"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+				{Name: "witness", Type: "Witness"},
+			},
+			"TokenPermissions": []apitypes.Type{
+				{Name: "token", Type: "address"},
+				{Name: "amount", Type: "uint256"},
+			},
+			"Witness": []apitypes.Type{
+				{Name: "to", Type: "address"},
+				{Name: "validAfter", Type: "uint256"},
+				{Name: "extra", Type: "bytes"},
+			},
+		},
+		PrimaryType: "PermitWitnessTransferFrom",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Permit2",
+			ChainId:           (*math.HexOrDecimal256)(chainID),
+			VerifyingContract: Address.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"permitted": map[string]interface{}{
+				"token":  permit.Token.Hex(),
+				"amount": (*math.HexOrDecimal256)(permit.Amount),
+			},
+			"spender":  permit.Spender.Hex(),
+			"nonce":    (*math.HexOrDecimal256)(permit.Nonce),
+			"deadline": (*math.HexOrDecimal256)(permit.Deadline),
+			"witness": map[string]interface{}{
+				"to":         permit.Witness.To.Hex(),
+				"validAfter": (*math.HexOrDecimal256)(permit.Witness.ValidAfter),
+				"extra":      hexutil.Bytes(permit.Witness.Extra),
+			},
+		},
+	}
+
+	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash domain: %w", err)
+	}
+
+	messageHash, err := typedData.HashStruct("PermitWitnessTransferFrom", typedData.Message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash permit: %w", err)
+	}
+
+	rawData := append([]byte{0x19, 0x01}, append(domainSeparator, messageHash...)...)
+	return crypto.Keccak256(rawData), nil
+}
@@ -0,0 +1,65 @@
+package permit2
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestSignAndRecover(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+	chainID := big.NewInt(84532)
+
+	nonce, err := NewNonce()
+	if err != nil {
+		t.Fatalf("NewNonce failed: %v", err)
+	}
+
+	permit := &Permit{
+		Token:    common.HexToAddress("0x036CbD53842c5426634e7929541eC2318f3dCF7e"),
+		Amount:   big.NewInt(1000000),
+		Spender:  common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		Nonce:    nonce,
+		Deadline: big.NewInt(1_700_000_300),
+		Witness: Witness{
+			To:         common.HexToAddress("0x209693Bc6afc0C5328bA36FaF03C514EF312287C"),
+			ValidAfter: big.NewInt(1_700_000_000),
+		},
+	}
+
+	signature, err := Sign(privateKey, chainID, permit)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if len(signature) != 132 {
+		t.Errorf("expected 65-byte hex signature, got length %d", len(signature))
+	}
+
+	recovered, err := Recover(chainID, permit, signature)
+	if err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if recovered != address {
+		t.Errorf("expected %s, got %s", address.Hex(), recovered.Hex())
+	}
+
+	// Changing any signed field must change the recovered address
+	tampered := *permit
+	tampered.Witness.To = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	if recovered, err := Recover(chainID, &tampered, signature); err == nil && recovered == address {
+		t.Error("expected tampered recipient to invalidate signature")
+	}
+	if recovered, err := Recover(big.NewInt(8453), permit, signature); err == nil && recovered == address {
+		t.Error("expected different chain ID to invalidate signature")
+	}
+
+	if _, err := Digest(chainID, &Permit{}); err == nil {
+		t.Error("expected error for incomplete permit")
+	}
+}
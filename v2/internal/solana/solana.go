@@ -14,6 +14,13 @@ import (
 // ComputeBudgetProgramID is the Solana Compute Budget program ID.
 var ComputeBudgetProgramID = solana.MustPublicKeyFromBase58("ComputeBudget111111111111111111111111111111")
 
+// MemoProgramID is the SPL Memo (v2) program ID.
+var MemoProgramID = solana.MustPublicKeyFromBase58("MemoSq4gqABAXKb96qnH8TysNcWxMyWCqXgDLGmfcHr")
+
+// MaxMemoLength is the maximum memo length accepted by BuildMemoInstruction.
+// The Memo program itself has no hard limit, but long memos waste transaction space.
+const MaxMemoLength = 256
+
 // DefaultComputeUnits is the default compute unit limit for transactions.
 const DefaultComputeUnits uint32 = 200_000
 
@@ -124,6 +131,35 @@ func BuildCreateIdempotentATAInstruction(payer, owner, mint solana.PublicKey) (s
 	), nil
 }
 
+// BuildMemoInstruction creates an SPL Memo instruction carrying memo.
+// The memo has no signer accounts, so it does not require additional signatures.
+func BuildMemoInstruction(memo string) (solana.Instruction, error) {
+	if memo == "" || len(memo) > MaxMemoLength {
+		return nil, fmt.Errorf("memo length must be between 1 and %d bytes, got %d", MaxMemoLength, len(memo))
+	}
+
+	return solana.NewInstruction(
+		MemoProgramID,
+		solana.AccountMetaSlice{},
+		[]byte(memo),
+	), nil
+}
+
+// ExtractMemo returns the data of the first SPL Memo instruction in tx.
+// Returns false if the transaction has no memo instruction.
+func ExtractMemo(tx *solana.Transaction) (string, bool) {
+	for _, inst := range tx.Message.Instructions {
+		programID, err := tx.Message.Program(inst.ProgramIDIndex)
+		if err != nil {
+			continue
+		}
+		if programID.Equals(MemoProgramID) {
+			return string(inst.Data), true
+		}
+	}
+	return "", false
+}
+
 // GetRPCURL returns the RPC URL for a CAIP-2 Solana network identifier.
 func GetRPCURL(network string) (string, error) {
 	switch network {
@@ -0,0 +1,107 @@
+package stellar
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestStrKey(t *testing.T) {
+	// The all-zero account is a well-known StrKey
+	zero := EncodeStrKey(VersionAccountID, make([]byte, 32))
+	if want := "GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF"; zero != want {
+		t.Errorf("expected %s, got %s", want, zero)
+	}
+
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	seed := EncodeStrKey(VersionSeed, key)
+	if !strings.HasPrefix(seed, "S") {
+		t.Errorf("expected seed to start with S, got %s", seed)
+	}
+	decoded, err := DecodeStrKey(VersionSeed, seed)
+	if err != nil || string(decoded) != string(key) {
+		t.Errorf("round trip failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{name: "wrong version", key: zero},
+		{name: "bad checksum", key: zero[:len(zero)-1] + "G"},
+		{name: "lower case", key: strings.ToLower(seed)},
+		{name: "truncated", key: seed[:40]},
+	}
+	for _, tt := range tests {
+		if _, err := DecodeStrKey(VersionSeed, tt.key); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestParseAsset(t *testing.T) {
+	issuer := EncodeStrKey(VersionAccountID, make([]byte, 32))
+	tests := []struct {
+		asset    string
+		wantCode string
+		wantErr  bool
+	}{
+		{asset: "native"},
+		{asset: "USDC:" + issuer, wantCode: "USDC"},
+		{asset: "LONGASSET12:" + issuer, wantCode: "LONGASSET12"},
+		{asset: "USDC", wantErr: true},
+		{asset: "TOOLONGASSETCODE:" + issuer, wantErr: true},
+		{asset: "USDC:GABC", wantErr: true},
+	}
+	for _, tt := range tests {
+		asset, err := ParseAsset(tt.asset)
+		if (err != nil) != tt.wantErr || asset.Code != tt.wantCode {
+			t.Errorf("ParseAsset(%q) = %+v, %v", tt.asset, asset, err)
+		}
+	}
+}
+
+func TestSignEnvelope(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	var source [32]byte
+	copy(source[:], publicKey)
+
+	tx := Payment{
+		Source:   source,
+		Asset:    Asset{Code: "USDC"},
+		Amount:   12345,
+		Fee:      100,
+		Sequence: 42,
+		MaxTime:  1700000000,
+		Memo:     Memo{Type: MemoText, Text: "invoice-7"},
+	}
+	txXDR, err := tx.MarshalXDR()
+	if err != nil {
+		t.Fatalf("MarshalXDR failed: %v", err)
+	}
+	if len(txXDR)%4 != 0 {
+		t.Errorf("expected 4-byte aligned XDR, got %d bytes", len(txXDR))
+	}
+	if seq := int64(binary.BigEndian.Uint64(txXDR[40:48])); seq != 42 {
+		t.Errorf("expected sequence 42 after source and fee, got %d", seq)
+	}
+
+	envelope := SignEnvelope(PassphraseTestnet, txXDR, privateKey)
+	signature := envelope[len(envelope)-64:]
+	hash := Hash(PassphraseTestnet, txXDR)
+	if !ed25519.Verify(publicKey, hash[:], signature) {
+		t.Error("expected envelope signature to verify")
+	}
+	if otherHash := Hash(PassphrasePubnet, txXDR); ed25519.Verify(publicKey, otherHash[:], signature) {
+		t.Error("expected signature to be bound to the network passphrase")
+	}
+
+	tx.Memo = Memo{Type: MemoText, Text: strings.Repeat("x", MaxMemoTextLength+1)}
+	if _, err := tx.MarshalXDR(); err == nil {
+		t.Error("expected long memo to be rejected")
+	}
+}
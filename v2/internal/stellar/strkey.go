@@ -0,0 +1,81 @@
+// Package stellar provides Stellar-specific utilities for the x402 v2 protocol:
+// StrKey encoding and the XDR encoding of payment transactions.
+package stellar
+
+import (
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// StrKey version bytes.
+const (
+	// VersionAccountID is the version byte of account IDs ("G...").
+	VersionAccountID byte = 6 << 3
+
+	// VersionSeed is the version byte of secret seeds ("S...").
+	VersionSeed byte = 18 << 3
+)
+
+var strkeyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// EncodeStrKey encodes a 32-byte key with the given version byte.
+func EncodeStrKey(version byte, key []byte) string {
+	data := make([]byte, 0, len(key)+3)
+	data = append(data, version)
+	data = append(data, key...)
+	data = binary.LittleEndian.AppendUint16(data, crc16(data))
+	return strkeyEncoding.EncodeToString(data)
+}
+
+// DecodeStrKey decodes a StrKey and checks its version byte and checksum.
+func DecodeStrKey(version byte, s string) ([]byte, error) {
+	if s != strings.ToUpper(s) {
+		return nil, errors.New("strkey must be upper case")
+	}
+	data, err := strkeyEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid strkey encoding: %w", err)
+	}
+	if len(data) != 35 {
+		return nil, fmt.Errorf("invalid strkey length %d", len(data))
+	}
+	if data[0] != version {
+		return nil, fmt.Errorf("unexpected strkey version byte %d", data[0])
+	}
+
+	payload, checksum := data[:33], binary.LittleEndian.Uint16(data[33:])
+	if crc16(payload) != checksum {
+		return nil, errors.New("invalid strkey checksum")
+	}
+	return payload[1:], nil
+}
+
+// DecodeAccountID decodes a "G..." account ID into its ed25519 public key.
+func DecodeAccountID(address string) ([32]byte, error) {
+	var key [32]byte
+	decoded, err := DecodeStrKey(VersionAccountID, address)
+	if err != nil {
+		return key, err
+	}
+	copy(key[:], decoded)
+	return key, nil
+}
+
+// crc16 computes the CRC16-XModem checksum used by StrKey.
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
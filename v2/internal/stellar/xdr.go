@@ -0,0 +1,195 @@
+package stellar
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Network passphrases.
+const (
+	PassphrasePubnet  = "Public Global Stellar Network ; September 2015"
+	PassphraseTestnet = "Test SDF Network ; September 2015"
+)
+
+// MaxMemoTextLength is the maximum length of a text memo in bytes.
+const MaxMemoTextLength = 28
+
+// XDR discriminants used by payment transactions.
+const (
+	envelopeTypeTx       = 2
+	keyTypeEd25519       = 0
+	preconditionTime     = 1
+	operationTypePayment = 1
+	assetTypeNative      = 0
+	assetTypeAlphanum4   = 1
+	assetTypeAlphanum12  = 2
+)
+
+// MemoType identifies the kind of transaction memo.
+type MemoType int32
+
+// Memo types.
+const (
+	MemoNone MemoType = iota
+	MemoText
+	MemoID
+	MemoHash
+)
+
+// Memo is a transaction memo, used by anchors to route incoming payments.
+type Memo struct {
+	Type MemoType
+	Text string
+	ID   uint64
+	Hash [32]byte
+}
+
+// Asset is a Stellar asset: native XLM when Code is empty, otherwise an issued asset.
+type Asset struct {
+	Code   string
+	Issuer [32]byte
+}
+
+var assetCodeRegex = regexp.MustCompile(`^[a-zA-Z0-9]{1,12}$`)
+
+// ParseAsset parses "native" or a "CODE:ISSUER" asset identifier.
+func ParseAsset(s string) (Asset, error) {
+	if s == "native" {
+		return Asset{}, nil
+	}
+	code, issuer, ok := strings.Cut(s, ":")
+	if !ok || !assetCodeRegex.MatchString(code) {
+		return Asset{}, fmt.Errorf("invalid Stellar asset %q (expected CODE:ISSUER or native)", s)
+	}
+	key, err := DecodeAccountID(issuer)
+	if err != nil {
+		return Asset{}, fmt.Errorf("invalid Stellar asset issuer: %w", err)
+	}
+	return Asset{Code: code, Issuer: key}, nil
+}
+
+// Payment is a single-operation payment transaction.
+type Payment struct {
+	Source      [32]byte
+	Destination [32]byte
+	Asset       Asset
+	Amount      int64
+	Fee         uint32
+	Sequence    int64
+	MinTime     uint64
+	MaxTime     uint64
+	Memo        Memo
+}
+
+// MarshalXDR returns the XDR encoding of the Transaction.
+func (p *Payment) MarshalXDR() ([]byte, error) {
+	var e encoder
+	e.account(p.Source)
+	e.uint32(p.Fee)
+	e.int64(p.Sequence)
+
+	e.int32(preconditionTime)
+	e.uint64(p.MinTime)
+	e.uint64(p.MaxTime)
+
+	e.int32(int32(p.Memo.Type))
+	switch p.Memo.Type {
+	case MemoNone:
+	case MemoText:
+		if len(p.Memo.Text) > MaxMemoTextLength {
+			return nil, fmt.Errorf("memo text exceeds %d bytes", MaxMemoTextLength)
+		}
+		e.opaque([]byte(p.Memo.Text))
+	case MemoID:
+		e.uint64(p.Memo.ID)
+	case MemoHash:
+		e.fixed(p.Memo.Hash[:])
+	default:
+		return nil, fmt.Errorf("unsupported memo type %d", p.Memo.Type)
+	}
+
+	e.uint32(1) // one operation
+	e.uint32(0) // no operation source account
+	e.int32(operationTypePayment)
+	e.account(p.Destination)
+	switch code := p.Asset.Code; {
+	case code == "":
+		e.int32(assetTypeNative)
+	case len(code) <= 4:
+		e.int32(assetTypeAlphanum4)
+		e.fixed(padCode(code, 4))
+		e.account(p.Asset.Issuer)
+	default:
+		e.int32(assetTypeAlphanum12)
+		e.fixed(padCode(code, 12))
+		e.account(p.Asset.Issuer)
+	}
+	e.int64(p.Amount)
+
+	e.int32(0) // ext
+	return e.buf, nil
+}
+
+// Hash returns the hash signed by the source account: SHA-256 of the network
+// ID, the envelope type, and the transaction.
+func Hash(passphrase string, tx []byte) [32]byte {
+	networkID := sha256.Sum256([]byte(passphrase))
+	var e encoder
+	e.fixed(networkID[:])
+	e.int32(envelopeTypeTx)
+	e.fixed(tx)
+	return sha256.Sum256(e.buf)
+}
+
+// SignEnvelope signs tx and returns the XDR encoding of its TransactionEnvelope.
+func SignEnvelope(passphrase string, tx []byte, key ed25519.PrivateKey) []byte {
+	hash := Hash(passphrase, tx)
+	signature := ed25519.Sign(key, hash[:])
+	publicKey := key.Public().(ed25519.PublicKey)
+
+	var e encoder
+	e.int32(envelopeTypeTx)
+	e.fixed(tx)
+	e.uint32(1) // one signature
+	e.fixed(publicKey[len(publicKey)-4:])
+	e.opaque(signature)
+	return e.buf
+}
+
+func padCode(code string, size int) []byte {
+	padded := make([]byte, size)
+	copy(padded, code)
+	return padded
+}
+
+// encoder appends XDR-encoded values.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) int32(v int32)   { e.uint32(uint32(v)) }
+func (e *encoder) uint32(v uint32) { e.buf = binary.BigEndian.AppendUint32(e.buf, v) }
+func (e *encoder) int64(v int64)   { e.uint64(uint64(v)) }
+func (e *encoder) uint64(v uint64) { e.buf = binary.BigEndian.AppendUint64(e.buf, v) }
+
+// fixed appends fixed-length opaque data, which callers keep 4-byte aligned.
+func (e *encoder) fixed(b []byte) { e.buf = append(e.buf, b...) }
+
+// opaque appends variable-length opaque data with its length and padding.
+func (e *encoder) opaque(b []byte) {
+	e.uint32(uint32(len(b)))
+	e.buf = append(e.buf, b...)
+	if pad := len(b) % 4; pad != 0 {
+		e.buf = append(e.buf, make([]byte, 4-pad)...)
+	}
+}
+
+// account appends an ed25519 MuxedAccount or AccountID, which share an encoding.
+func (e *encoder) account(key [32]byte) {
+	e.int32(keyTypeEd25519)
+	e.fixed(key[:])
+}
@@ -0,0 +1,48 @@
+// Package tron provides TRON-specific utilities for the x402 v2 protocol.
+package tron
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mr-tron/base58"
+)
+
+// AddressPrefix is the first byte of every TRON address.
+const AddressPrefix byte = 0x41
+
+// DecodeAddress decodes a base58check TRON address ("T...") into its 20-byte
+// account, which is also the account used in TIP-712 typed data.
+func DecodeAddress(address string) (common.Address, error) {
+	decoded, err := base58.Decode(address)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid TRON address encoding: %w", err)
+	}
+	if len(decoded) != 25 {
+		return common.Address{}, fmt.Errorf("invalid TRON address length %d", len(decoded))
+	}
+	if decoded[0] != AddressPrefix {
+		return common.Address{}, fmt.Errorf("invalid TRON address prefix 0x%02x", decoded[0])
+	}
+
+	payload, checksum := decoded[:21], decoded[21:]
+	if !bytes.Equal(doubleSHA256(payload)[:4], checksum) {
+		return common.Address{}, errors.New("invalid TRON address checksum")
+	}
+	return common.BytesToAddress(payload[1:]), nil
+}
+
+// EncodeAddress encodes a 20-byte account as a base58check TRON address.
+func EncodeAddress(account common.Address) string {
+	payload := append([]byte{AddressPrefix}, account.Bytes()...)
+	return base58.Encode(append(payload, doubleSHA256(payload)[:4]...))
+}
+
+func doubleSHA256(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
@@ -0,0 +1,32 @@
+package tron
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestAddress(t *testing.T) {
+	// USDT-TRC20 on mainnet: TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t is 0x41a614f803b6fd780986a42c78ec9c7f77e6ded13c
+	account, err := DecodeAddress("TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t")
+	if err != nil {
+		t.Fatalf("DecodeAddress failed: %v", err)
+	}
+	if want := common.HexToAddress("0xa614f803b6fd780986a42c78ec9c7f77e6ded13c"); account != want {
+		t.Errorf("expected %s, got %s", want.Hex(), account.Hex())
+	}
+	if encoded := EncodeAddress(account); encoded != "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t" {
+		t.Errorf("round trip produced %s", encoded)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6u", // bad checksum
+		"0xa614f803b6fd780986a42c78ec9c7f77e6ded13c",   // hex
+		"4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU", // wrong length
+	} {
+		if _, err := DecodeAddress(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
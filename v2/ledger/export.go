@@ -0,0 +1,250 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Exporter writes ledger entries in an accounting format.
+type Exporter interface {
+	// Export writes entries to w.
+	Export(w io.Writer, entries []Entry) error
+
+	// Extension is the file extension of the format, without a dot.
+	Extension() string
+}
+
+// Accounts names the ledger accounts journal entries post to.
+type Accounts struct {
+	// Asset is the account holding received crypto (debited).
+	Asset string
+
+	// Revenue is the income account (credited).
+	Revenue string
+}
+
+// DefaultAccounts are used when an exporter has no accounts configured.
+var DefaultAccounts = Accounts{Asset: "Crypto Assets", Revenue: "x402 Revenue"}
+
+// CSVExporter writes one row per settlement with raw and USD amounts.
+type CSVExporter struct{}
+
+// Extension returns "csv".
+func (CSVExporter) Extension() string { return "csv" }
+
+// Export writes entries as CSV.
+func (CSVExporter) Export(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"settled_at", "network", "asset", "amount", "units", "usd_price", "usd_value", "payer", "pay_to", "transaction", "resource", "experiment", "variant"})
+	for _, e := range entries {
+		_ = cw.Write([]string{
+			e.SettledAt.UTC().Format(time.RFC3339),
+			e.Network,
+			e.Asset,
+			e.Amount,
+			e.Units().FloatString(e.Decimals),
+			e.USDPrice.FloatString(6),
+			e.USDValue.FloatString(2),
+			e.Payer,
+			e.PayTo,
+			e.Transaction,
+			e.Resource,
+			e.Experiment,
+			e.Variant,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// QuickBooksExporter writes a QuickBooks Online journal entry import file.
+// Each settlement becomes a balanced journal entry debiting Accounts.Asset and
+// crediting Accounts.Revenue for its USD value.
+type QuickBooksExporter struct {
+	Accounts Accounts
+}
+
+// Extension returns "csv".
+func (QuickBooksExporter) Extension() string { return "csv" }
+
+// Export writes entries as QuickBooks journal entries.
+func (q QuickBooksExporter) Export(w io.Writer, entries []Entry) error {
+	accounts := accountsOrDefault(q.Accounts)
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"Journal No.", "Journal Date", "Account Name", "Debits", "Credits", "Description", "Name"})
+	for i, e := range entries {
+		number := fmt.Sprintf("X402-%d", i+1)
+		date := e.SettledAt.UTC().Format("01/02/2006")
+		value := e.USDValue.FloatString(2)
+		_ = cw.Write([]string{number, date, accounts.Asset, value, "", description(e), e.Payer})
+		_ = cw.Write([]string{number, date, accounts.Revenue, "", value, description(e), e.Payer})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// XeroExporter writes a Xero manual journal import file. Each settlement becomes
+// a journal with a positive (debit) line to Accounts.Asset and a negative
+// (credit) line to Accounts.Revenue; account values are Xero account codes.
+type XeroExporter struct {
+	Accounts Accounts
+
+	// TaxRate is the Xero tax rate name applied to each line (default "Tax Exempt").
+	TaxRate string
+}
+
+// Extension returns "csv".
+func (XeroExporter) Extension() string { return "csv" }
+
+// Export writes entries as Xero manual journals.
+func (x XeroExporter) Export(w io.Writer, entries []Entry) error {
+	accounts := accountsOrDefault(x.Accounts)
+	taxRate := x.TaxRate
+	if taxRate == "" {
+		taxRate = "Tax Exempt"
+	}
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"*Narration", "*Date", "Description", "*AccountCode", "*TaxRate", "*Amount"})
+	for _, e := range entries {
+		narration := "x402 settlement " + e.Transaction
+		date := e.SettledAt.UTC().Format("02/01/2006")
+		value := e.USDValue.FloatString(2)
+		_ = cw.Write([]string{narration, date, description(e), accounts.Asset, taxRate, value})
+		_ = cw.Write([]string{narration, date, description(e), accounts.Revenue, taxRate, "-" + value})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ExportPeriod exports the entries settled in [from, to).
+func (l *Ledger) ExportPeriod(exporter Exporter, from, to time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := exporter.Export(&buf, l.Entries(from, to)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ExportSink receives a scheduled export for the month starting at month.
+type ExportSink func(ctx context.Context, month time.Time, data []byte) error
+
+// RunMonthly exports the previous month's entries to sink at the start of each
+// month (UTC) until ctx is cancelled. Sink errors are logged and do not stop
+// the schedule.
+func (l *Ledger) RunMonthly(ctx context.Context, exporter Exporter, sink ExportSink) {
+	for {
+		now := l.clock.Now()
+		end := startOfMonth(now).AddDate(0, 1, 0)
+		select {
+		case <-ctx.Done():
+			return
+		case <-l.clock.After(end.Sub(now)):
+		}
+
+		start := end.AddDate(0, -1, 0)
+		data, err := l.ExportPeriod(exporter, start, end)
+		if err == nil {
+			err = sink(ctx, start, data)
+		}
+		if err != nil {
+			slog.Default().Error("scheduled ledger export failed", "month", start.Format("2006-01"), "error", err)
+		}
+	}
+}
+
+// Handler serves on-demand exports. Query parameters:
+//
+//	format  name of an exporter in exporters (default "csv")
+//	month   export a calendar month (UTC), e.g. "2025-01"
+//	from,to export [from, to), as RFC 3339 timestamps
+//
+// Without month or from/to, the current month is exported.
+func (l *Ledger) Handler(exporters map[string]Exporter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		format := query.Get("format")
+		if format == "" {
+			format = "csv"
+		}
+		exporter, ok := exporters[format]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown format %q", format), http.StatusBadRequest)
+			return
+		}
+
+		from, to, err := l.period(query.Get("month"), query.Get("from"), query.Get("to"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		data, err := l.ExportPeriod(exporter, from, to)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		filename := fmt.Sprintf("x402-%s-%s.%s", format, from.Format("2006-01-02"), exporter.Extension())
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		_, _ = w.Write(data)
+	})
+}
+
+// period parses the export period of a Handler request.
+func (l *Ledger) period(month, from, to string) (time.Time, time.Time, error) {
+	if month != "" {
+		start, err := time.Parse("2006-01", month)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid month %q", month)
+		}
+		return start, start.AddDate(0, 1, 0), nil
+	}
+	if from == "" && to == "" {
+		start := startOfMonth(l.clock.Now())
+		return start, start.AddDate(0, 1, 0), nil
+	}
+
+	start, err := time.Parse(time.RFC3339, from)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from %q", from)
+	}
+	end, err := time.Parse(time.RFC3339, to)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid to %q", to)
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("to must be after from")
+	}
+	return start, end, nil
+}
+
+func startOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func accountsOrDefault(accounts Accounts) Accounts {
+	if accounts.Asset == "" {
+		accounts.Asset = DefaultAccounts.Asset
+	}
+	if accounts.Revenue == "" {
+		accounts.Revenue = DefaultAccounts.Revenue
+	}
+	return accounts
+}
+
+func description(e Entry) string {
+	desc := fmt.Sprintf("%s %s on %s", e.Units().FloatString(e.Decimals), e.Asset, e.Network)
+	if e.Resource != "" {
+		desc += " for " + e.Resource
+	}
+	return desc
+}
@@ -0,0 +1,253 @@
+// Package ledger records settled x402 payments and exports them in formats
+// accounting tools can import (CSV, QuickBooks, and Xero journals), valued in
+// USD at settlement time.
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// Entry is one settled payment.
+type Entry struct {
+	// SettledAt is when the payment was settled, as reported by the
+	// facilitator or else when it was recorded.
+	SettledAt time.Time
+
+	// Network is the CAIP-2 network identifier.
+	Network string
+
+	// Asset is the token address or mint.
+	Asset string
+
+	// Amount is the payment amount in atomic units.
+	Amount string
+
+	// Decimals is the number of decimal places of Asset.
+	Decimals int
+
+	// Payer is the address that made the payment.
+	Payer string
+
+	// PayTo is the address that received the payment.
+	PayTo string
+
+	// Transaction is the settlement transaction hash.
+	Transaction string
+
+	// Resource is the URL of the paid resource, if known.
+	Resource string
+
+	// Experiment and Variant identify the price experiment variant the payment
+	// was advertised under, if any (see v2.ExperimentFromRequirements).
+	Experiment string
+	Variant    string
+
+	// USDPrice is the price of one whole token in USD at SettledAt.
+	USDPrice *big.Rat
+
+	// USDValue is the value of the payment in USD at SettledAt.
+	USDValue *big.Rat
+}
+
+// Units returns the amount in whole tokens.
+func (e Entry) Units() *big.Rat {
+	amount, ok := new(big.Int).SetString(e.Amount, 10)
+	if !ok {
+		return new(big.Rat)
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(e.Decimals)), nil)
+	return new(big.Rat).SetFrac(amount, scale)
+}
+
+// PriceOracle returns the USD price of one whole token at a point in time.
+type PriceOracle interface {
+	PriceUSD(ctx context.Context, network, asset string, at time.Time) (*big.Rat, error)
+}
+
+// PriceOracleFunc adapts a function to the PriceOracle interface.
+type PriceOracleFunc func(ctx context.Context, network, asset string, at time.Time) (*big.Rat, error)
+
+// PriceUSD calls f(ctx, network, asset, at).
+func (f PriceOracleFunc) PriceUSD(ctx context.Context, network, asset string, at time.Time) (*big.Rat, error) {
+	return f(ctx, network, asset, at)
+}
+
+// StaticPriceOracle prices assets at fixed USD rates, keyed by network and asset.
+// Use NewUSDCPriceOracle for the common case of USDC-only payments.
+type StaticPriceOracle struct {
+	prices map[string]*big.Rat
+}
+
+// NewStaticPriceOracle creates an empty StaticPriceOracle.
+func NewStaticPriceOracle() *StaticPriceOracle {
+	return &StaticPriceOracle{prices: make(map[string]*big.Rat)}
+}
+
+// NewUSDCPriceOracle prices USDC on every known chain at $1.
+func NewUSDCPriceOracle() *StaticPriceOracle {
+	oracle := NewStaticPriceOracle()
+	for _, network := range []string{
+		v2.NetworkBase, v2.NetworkPolygon, v2.NetworkAvalanche, v2.NetworkEthereum,
+		v2.NetworkBaseSepolia, v2.NetworkPolygonAmoy, v2.NetworkAvalancheFuji, v2.NetworkSepolia,
+		v2.NetworkSolanaMainnet, v2.NetworkSolanaDevnet,
+	} {
+		if chain, err := v2.GetChainConfig(network); err == nil {
+			oracle.Set(network, chain.USDCAddress, big.NewRat(1, 1))
+		}
+	}
+	return oracle
+}
+
+// Set sets the USD price of asset on network.
+func (o *StaticPriceOracle) Set(network, asset string, price *big.Rat) {
+	o.prices[assetKey(network, asset)] = price
+}
+
+// PriceUSD returns the configured price, or an error if the asset is unknown.
+func (o *StaticPriceOracle) PriceUSD(_ context.Context, network, asset string, _ time.Time) (*big.Rat, error) {
+	price, ok := o.prices[assetKey(network, asset)]
+	if !ok {
+		return nil, fmt.Errorf("no USD price for %s on %s", asset, network)
+	}
+	return price, nil
+}
+
+// Ledger records settlements in memory. It is safe for concurrent use.
+type Ledger struct {
+	oracle   PriceOracle
+	decimals map[string]int
+	clock    v2.Clock
+
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Option configures a Ledger.
+type Option func(*Ledger)
+
+// WithTokenDecimals sets the decimals of a non-USDC asset. USDC decimals are known.
+func WithTokenDecimals(network, asset string, decimals int) Option {
+	return func(l *Ledger) {
+		l.decimals[assetKey(network, asset)] = decimals
+	}
+}
+
+// WithClock sets the clock used to timestamp settlements and schedule exports
+// (default: v2.SystemClock).
+func WithClock(clock v2.Clock) Option {
+	return func(l *Ledger) {
+		l.clock = v2.ClockOrSystem(clock)
+	}
+}
+
+// New creates a Ledger that values settlements with oracle.
+func New(oracle PriceOracle, opts ...Option) *Ledger {
+	l := &Ledger{
+		oracle:   oracle,
+		decimals: make(map[string]int),
+		clock:    v2.SystemClock,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Record values a successful settlement in USD and adds it to the ledger.
+func (l *Ledger) Record(ctx context.Context, payload v2.PaymentPayload, requirements v2.PaymentRequirements, resp *v2.SettleResponse) (Entry, error) {
+	if resp == nil || !resp.Success {
+		return Entry{}, fmt.Errorf("cannot record unsuccessful settlement")
+	}
+
+	decimals, err := l.tokenDecimals(requirements.Network, requirements.Asset)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	entry := Entry{
+		SettledAt:   l.clock.Now(),
+		Network:     requirements.Network,
+		Asset:       requirements.Asset,
+		Amount:      requirements.Amount,
+		Decimals:    decimals,
+		Payer:       resp.Payer,
+		PayTo:       requirements.PayTo,
+		Transaction: resp.Transaction,
+	}
+	if settledAt, ok := resp.SettledAt(); ok {
+		entry.SettledAt = settledAt
+	}
+	if payload.Resource != nil {
+		entry.Resource = payload.Resource.URL
+	}
+	if tag, ok := v2.ExperimentFromRequirements(requirements); ok {
+		entry.Experiment, entry.Variant = tag.Experiment, tag.Variant
+	}
+
+	price, err := l.oracle.PriceUSD(ctx, entry.Network, entry.Asset, entry.SettledAt)
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to value settlement: %w", err)
+	}
+	entry.USDPrice = price
+	entry.USDValue = new(big.Rat).Mul(entry.Units(), price)
+
+	l.mu.Lock()
+	l.entries = append(l.entries, entry)
+	l.mu.Unlock()
+
+	return entry, nil
+}
+
+// OnAfterSettle returns a settle hook that records every successful settlement.
+// It can be assigned to the middleware's FacilitatorOnAfterSettle and
+// FallbackFacilitatorOnAfterSettle. Recording failures are logged.
+func (l *Ledger) OnAfterSettle() func(context.Context, v2.PaymentPayload, v2.PaymentRequirements, *v2.SettleResponse, error) {
+	return func(ctx context.Context, payload v2.PaymentPayload, requirements v2.PaymentRequirements, resp *v2.SettleResponse, err error) {
+		if err != nil || resp == nil || !resp.Success {
+			return
+		}
+		if _, err := l.Record(ctx, payload, requirements, resp); err != nil {
+			slog.Default().Error("failed to record settlement", "transaction", resp.Transaction, "error", err)
+		}
+	}
+}
+
+// Entries returns the entries settled in [from, to), oldest first.
+func (l *Ledger) Entries(from, to time.Time) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entries []Entry
+	for _, entry := range l.entries {
+		if !entry.SettledAt.Before(from) && entry.SettledAt.Before(to) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].SettledAt.Before(entries[j].SettledAt)
+	})
+	return entries
+}
+
+func (l *Ledger) tokenDecimals(network, asset string) (int, error) {
+	if decimals, ok := l.decimals[assetKey(network, asset)]; ok {
+		return decimals, nil
+	}
+	if chain, err := v2.GetChainConfig(network); err == nil && strings.EqualFold(chain.USDCAddress, asset) {
+		return int(chain.Decimals), nil
+	}
+	return 0, fmt.Errorf("unknown decimals for %s on %s", asset, network)
+}
+
+func assetKey(network, asset string) string {
+	return network + ":" + strings.ToLower(asset)
+}
@@ -0,0 +1,227 @@
+package ledger
+
+import (
+	"context"
+	"encoding/csv"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func testLedger(t *testing.T, settledAt ...time.Time) *Ledger {
+	t.Helper()
+
+	clock := v2.NewFakeClock(time.Time{})
+	l := New(NewUSDCPriceOracle(), WithClock(clock))
+	requirements := v2.PaymentRequirements{
+		Scheme:  "exact",
+		Network: v2.NetworkBase,
+		Asset:   v2.BaseMainnet.USDCAddress,
+		Amount:  "1250000",
+		PayTo:   "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+	}
+	payload := v2.PaymentPayload{Resource: &v2.ResourceInfo{URL: "https://example.com/api"}}
+
+	for i, at := range settledAt {
+		clock.Set(at)
+		resp := &v2.SettleResponse{Success: true, Transaction: "0xtx" + string(rune('a'+i)), Payer: "0xpayer"}
+		if _, err := l.Record(context.Background(), payload, requirements, resp); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	return l
+}
+
+func TestLedger_Record(t *testing.T) {
+	jan := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	l := testLedger(t, jan)
+
+	entries := l.Entries(jan.AddDate(0, 0, -1), jan.AddDate(0, 0, 1))
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if got := entries[0].USDValue.FloatString(2); got != "1.25" {
+		t.Errorf("USDValue = %s, want 1.25", got)
+	}
+
+	// Unknown asset has no decimals or price
+	_, err := l.Record(context.Background(), v2.PaymentPayload{}, v2.PaymentRequirements{
+		Network: v2.NetworkBase, Asset: "0x1111111111111111111111111111111111111111", Amount: "1",
+	}, &v2.SettleResponse{Success: true})
+	if err == nil {
+		t.Error("expected unknown asset to be rejected")
+	}
+
+	// Custom tokens need decimals and a price
+	oracle := NewStaticPriceOracle()
+	oracle.Set(v2.NetworkBase, "0x1111111111111111111111111111111111111111", big.NewRat(3, 2))
+	l = New(oracle, WithTokenDecimals(v2.NetworkBase, "0x1111111111111111111111111111111111111111", 18))
+	entry, err := l.Record(context.Background(), v2.PaymentPayload{}, v2.PaymentRequirements{
+		Network: v2.NetworkBase, Asset: "0x1111111111111111111111111111111111111111", Amount: "2000000000000000000",
+	}, &v2.SettleResponse{Success: true})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if got := entry.USDValue.FloatString(2); got != "3.00" {
+		t.Errorf("USDValue = %s, want 3.00", got)
+	}
+
+	// Experiment variants are attributed from the requirements
+	tagged := v2.WithExperiment(v2.PaymentRequirements{
+		Network: v2.NetworkBase, Asset: "0x1111111111111111111111111111111111111111", Amount: "1",
+	}, v2.ExperimentTag{Experiment: "spring", Variant: "discount"})
+	if entry, err := l.Record(context.Background(), v2.PaymentPayload{}, tagged, &v2.SettleResponse{Success: true}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	} else if entry.Experiment != "spring" || entry.Variant != "discount" {
+		t.Errorf("Experiment = %q/%q, want spring/discount", entry.Experiment, entry.Variant)
+	}
+
+	// The settle hook ignores failed settlements
+	l.OnAfterSettle()(context.Background(), v2.PaymentPayload{}, v2.PaymentRequirements{}, &v2.SettleResponse{Success: false}, nil)
+	if n := len(l.Entries(time.Time{}, time.Now().Add(time.Hour))); n != 2 {
+		t.Errorf("expected failed settlement to be ignored, got %d entries", n)
+	}
+}
+
+func TestExporters(t *testing.T) {
+	at := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	entries := testLedger(t, at).Entries(at, at.Add(time.Second))
+
+	tests := []struct {
+		name     string
+		exporter Exporter
+		want     [][]string
+	}{
+		{
+			name:     "csv",
+			exporter: CSVExporter{},
+			want: [][]string{
+				{"settled_at", "network", "asset", "amount", "units", "usd_price", "usd_value", "payer", "pay_to", "transaction", "resource", "experiment", "variant"},
+				{"2025-01-15T12:00:00Z", v2.NetworkBase, v2.BaseMainnet.USDCAddress, "1250000", "1.250000", "1.000000", "1.25", "0xpayer", "0x209693Bc6afc0C5328bA36FaF03C514EF312287C", "0xtxa", "https://example.com/api", "", ""},
+			},
+		},
+		{
+			name:     "quickbooks",
+			exporter: QuickBooksExporter{Accounts: Accounts{Asset: "USDC Wallet"}},
+			want: [][]string{
+				{"Journal No.", "Journal Date", "Account Name", "Debits", "Credits", "Description", "Name"},
+				{"X402-1", "01/15/2025", "USDC Wallet", "1.25", "", "1.250000 " + v2.BaseMainnet.USDCAddress + " on eip155:8453 for https://example.com/api", "0xpayer"},
+				{"X402-1", "01/15/2025", "x402 Revenue", "", "1.25", "1.250000 " + v2.BaseMainnet.USDCAddress + " on eip155:8453 for https://example.com/api", "0xpayer"},
+			},
+		},
+		{
+			name:     "xero",
+			exporter: XeroExporter{Accounts: Accounts{Asset: "610", Revenue: "200"}},
+			want: [][]string{
+				{"*Narration", "*Date", "Description", "*AccountCode", "*TaxRate", "*Amount"},
+				{"x402 settlement 0xtxa", "15/01/2025", "1.250000 " + v2.BaseMainnet.USDCAddress + " on eip155:8453 for https://example.com/api", "610", "Tax Exempt", "1.25"},
+				{"x402 settlement 0xtxa", "15/01/2025", "1.250000 " + v2.BaseMainnet.USDCAddress + " on eip155:8453 for https://example.com/api", "200", "Tax Exempt", "-1.25"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+			if err := tt.exporter.Export(&buf, entries); err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+			rows, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+			if err != nil {
+				t.Fatalf("invalid CSV: %v", err)
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d", len(rows), len(tt.want))
+			}
+			for i := range rows {
+				if strings.Join(rows[i], "|") != strings.Join(tt.want[i], "|") {
+					t.Errorf("row %d = %v, want %v", i, rows[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLedger_Handler(t *testing.T) {
+	l := testLedger(t,
+		time.Date(2025, 1, 31, 23, 59, 0, 0, time.UTC),
+		time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+	)
+	handler := l.Handler(map[string]Exporter{"csv": CSVExporter{}, "xero": XeroExporter{}})
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantRows   int
+	}{
+		{name: "month", query: "?month=2025-01", wantStatus: http.StatusOK, wantRows: 2},
+		{name: "range", query: "?from=2025-01-01T00:00:00Z&to=2025-03-01T00:00:00Z", wantStatus: http.StatusOK, wantRows: 3},
+		{name: "xero month", query: "?format=xero&month=2025-02", wantStatus: http.StatusOK, wantRows: 3},
+		{name: "unknown format", query: "?format=sage", wantStatus: http.StatusBadRequest},
+		{name: "invalid month", query: "?month=January", wantStatus: http.StatusBadRequest},
+		{name: "inverted range", query: "?from=2025-03-01T00:00:00Z&to=2025-01-01T00:00:00Z", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/export"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			rows, err := csv.NewReader(rec.Body).ReadAll()
+			if err != nil {
+				t.Fatalf("invalid CSV: %v", err)
+			}
+			if len(rows) != tt.wantRows {
+				t.Errorf("got %d rows, want %d", len(rows), tt.wantRows)
+			}
+			if !strings.Contains(rec.Header().Get("Content-Disposition"), "attachment") {
+				t.Error("expected attachment Content-Disposition")
+			}
+		})
+	}
+}
+
+func TestLedger_RunMonthly(t *testing.T) {
+	clock := v2.NewFakeClock(time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC))
+	l := New(NewUSDCPriceOracle(), WithClock(clock))
+	_, err := l.Record(context.Background(), v2.PaymentPayload{}, v2.PaymentRequirements{
+		Network: v2.NetworkBase, Asset: v2.BaseMainnet.USDCAddress, Amount: "1000000",
+	}, &v2.SettleResponse{Success: true, Transaction: "0xtx"})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	type export struct {
+		month time.Time
+		data  string
+	}
+	exports := make(chan export, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.RunMonthly(ctx, CSVExporter{}, func(ctx context.Context, month time.Time, data []byte) error {
+		exports <- export{month: month, data: string(data)}
+		return nil
+	})
+
+	clock.BlockUntil(1)
+	clock.Advance(12 * time.Hour)
+
+	got := <-exports
+	if want := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC); !got.month.Equal(want) {
+		t.Errorf("exported month %v, want %v", got.month, want)
+	}
+	if !strings.Contains(got.data, "0xtx") {
+		t.Errorf("expected January export to contain the settlement, got %q", got.data)
+	}
+}
@@ -0,0 +1,75 @@
+package mcp
+
+import (
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// BundleExtension is the extension under which a server offers bundles of
+// prepaid calls in payment required errors. Its info is a BundleInfo.
+//
+// A client buys a bundle by paying one of the offer's Accepts instead of the
+// price of the call. The server settles it once and returns a Voucher in the
+// call's result _meta under VoucherMetaKey. The client then sends the
+// voucher's token under VoucherMetaKey in the _meta of later calls of the
+// bundle's tools, which the server runs without payment until the voucher is
+// used up or expires.
+const BundleExtension = "bundle"
+
+// VoucherMetaKey is the _meta key carrying a voucher token in tools/call
+// requests, and the Voucher in their results.
+const VoucherMetaKey = "x402/voucher"
+
+// BundleInfo is the info of the bundle extension.
+type BundleInfo struct {
+	// Offers are the bundles including the called tool.
+	Offers []BundleOffer `json:"offers"`
+}
+
+// BundleOffer offers Calls calls of Tools for one payment.
+type BundleOffer struct {
+	// Tools are the tools the bundle's calls may be spent on.
+	Tools []string `json:"tools"`
+
+	// Calls is the number of calls, including the call buying the bundle.
+	Calls int `json:"calls"`
+
+	// ValiditySeconds is how long the voucher is valid after purchase, or
+	// zero if it does not expire.
+	ValiditySeconds int64 `json:"validitySeconds,omitempty"`
+
+	// Accepts are the ways to pay for the bundle.
+	Accepts []v2.PaymentRequirements `json:"accepts"`
+}
+
+// Voucher is a server's receipt for a bundle, redeemed for its calls.
+type Voucher struct {
+	// Token is the signed voucher sent to redeem calls.
+	Token string `json:"token"`
+
+	// Tools are the tools the voucher may be redeemed for.
+	Tools []string `json:"tools"`
+
+	// Calls is the number of calls bought.
+	Calls int `json:"calls"`
+
+	// Remaining is the number of calls left.
+	Remaining int `json:"remaining"`
+
+	// Expires is when the voucher expires, or zero if it does not.
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// Covers reports whether the voucher may be redeemed for tool at now.
+func (v Voucher) Covers(tool string, now time.Time) bool {
+	if v.Remaining <= 0 || (!v.Expires.IsZero() && !now.Before(v.Expires)) {
+		return false
+	}
+	for _, t := range v.Tools {
+		if t == tool {
+			return true
+		}
+	}
+	return false
+}
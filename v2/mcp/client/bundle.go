@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/mcp"
+	"github.com/mark3labs/x402-go/v2/storage"
+)
+
+// BundleChooser chooses which of the bundles offered for tool to buy, or
+// returns nil to pay for the call alone.
+type BundleChooser func(tool string, offers []mcp.BundleOffer) *mcp.BundleOffer
+
+// LargestBundle is a BundleChooser buying the bundle with the most calls.
+func LargestBundle(_ string, offers []mcp.BundleOffer) *mcp.BundleOffer {
+	var largest *mcp.BundleOffer
+	for i := range offers {
+		if largest == nil || offers[i].Calls > largest.Calls {
+			largest = &offers[i]
+		}
+	}
+	return largest
+}
+
+// vouchersKey is the store key of the vouchers bought from a server.
+func vouchersKey(serverURL string) string {
+	return "vouchers:" + serverURL
+}
+
+// Vouchers returns the stored vouchers of the bundles bought from the
+// server.
+func (t *Transport) Vouchers(ctx context.Context) ([]mcp.Voucher, error) {
+	if t.config.Vouchers == nil {
+		return nil, nil
+	}
+	data, err := t.config.Vouchers.Get(ctx, vouchersKey(t.config.ServerURL))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var vouchers []mcp.Voucher
+	if err := json.Unmarshal(data, &vouchers); err != nil {
+		return nil, err
+	}
+	return vouchers, nil
+}
+
+// voucherFor returns a voucher covering the tool called by req, if any.
+func (t *Transport) voucherFor(ctx context.Context, req transport.JSONRPCRequest) (mcp.Voucher, bool) {
+	if t.config.Vouchers == nil || req.Method != "tools/call" {
+		return mcp.Voucher{}, false
+	}
+	vouchers, err := t.Vouchers(ctx)
+	if err != nil {
+		return mcp.Voucher{}, false
+	}
+	tool, now := toolName(req), v2.ClockOrSystem(t.config.Clock).Now()
+	for _, voucher := range vouchers {
+		if voucher.Covers(tool, now) {
+			return voucher, true
+		}
+	}
+	return mcp.Voucher{}, false
+}
+
+// updateVouchers replaces the voucher with token by voucher, or removes it if
+// voucher is nil or used up. Vouchers that have expired are dropped.
+func (t *Transport) updateVouchers(ctx context.Context, token string, voucher *mcp.Voucher) {
+	if t.config.Vouchers == nil {
+		return
+	}
+	now := v2.ClockOrSystem(t.config.Clock).Now()
+	_, _ = storage.Update(ctx, t.config.Vouchers, vouchersKey(t.config.ServerURL), 0, func(old []byte, found bool) ([]byte, error) {
+		var vouchers []mcp.Voucher
+		if found {
+			_ = json.Unmarshal(old, &vouchers)
+		}
+		kept := vouchers[:0]
+		for _, v := range vouchers {
+			if v.Token != token && (v.Expires.IsZero() || now.Before(v.Expires)) {
+				kept = append(kept, v)
+			}
+		}
+		if voucher != nil && voucher.Remaining > 0 {
+			kept = append(kept, *voucher)
+		}
+		return json.Marshal(kept)
+	})
+}
+
+// redeem sends req paid with voucher. A voucher the server rejects is
+// dropped and the payment required error returned, to be paid as usual.
+func (t *Transport) redeem(ctx context.Context, req transport.JSONRPCRequest, voucher mcp.Voucher) (*transport.JSONRPCResponse, error) {
+	paidReq, err := t.injectMeta(req, mcp.VoucherMetaKey, voucher.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inject voucher: %w", err)
+	}
+	resp, err := t.send(ctx, paidReq)
+	if err != nil {
+		return resp, err
+	}
+	switch {
+	case resp.Error != nil && resp.Error.Code == 402:
+		t.updateVouchers(ctx, voucher.Token, nil)
+	case resp.Error == nil:
+		if updated, ok := resultVoucher(resp); ok {
+			t.updateVouchers(ctx, voucher.Token, &updated)
+		}
+	}
+	return resp, nil
+}
+
+// chooseBundle returns the requirements of the bundle bought for the tool
+// called by req among those offered in a payment required error's data, or
+// nil to pay for the call alone.
+func (t *Transport) chooseBundle(req transport.JSONRPCRequest, data json.RawMessage) []v2.PaymentRequirements {
+	if t.config.ChooseBundle == nil || req.Method != "tools/call" {
+		return nil
+	}
+	var reqData mcp.PaymentRequirements
+	if err := json.Unmarshal(data, &reqData); err != nil {
+		return nil
+	}
+	extension, ok := reqData.Extensions[mcp.BundleExtension]
+	if !ok {
+		return nil
+	}
+	encoded, err := json.Marshal(extension.Info)
+	if err != nil {
+		return nil
+	}
+	var info mcp.BundleInfo
+	if err := json.Unmarshal(encoded, &info); err != nil || len(info.Offers) == 0 {
+		return nil
+	}
+
+	offer := t.config.ChooseBundle(toolName(req), info.Offers)
+	if offer == nil {
+		return nil
+	}
+	accepts, err := t.config.Allowlist.Filter(offer.Accepts)
+	if err != nil {
+		return nil
+	}
+	return accepts
+}
+
+// resultVoucher returns the voucher in the result _meta of resp, if any.
+func resultVoucher(resp *transport.JSONRPCResponse) (mcp.Voucher, bool) {
+	var result struct {
+		Meta struct {
+			Voucher *mcp.Voucher `json:"x402/voucher"`
+		} `json:"_meta"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil || result.Meta.Voucher == nil || result.Meta.Voucher.Token == "" {
+		return mcp.Voucher{}, false
+	}
+	return *result.Meta.Voucher, true
+}
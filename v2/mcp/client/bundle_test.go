@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/mcp"
+	"github.com/mark3labs/x402-go/v2/storage"
+)
+
+func voucherResult(token string, remaining int) scriptedResult {
+	result := fmt.Sprintf(`{"content":[],"_meta":{"x402/voucher":{"token":%q,"tools":["search"],"calls":3,"remaining":%d,"expires":"2025-03-10T13:00:00Z"}}}`, token, remaining)
+	return scriptedResult{resp: &transport.JSONRPCResponse{Result: []byte(result)}}
+}
+
+func TestSendRequest_Bundles(t *testing.T) {
+	call := v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: "1000", Asset: "0xAsset", PayTo: "0xPayTo"}
+	price := call
+	price.Amount = "2500"
+	paymentRequired := rpcError(402, "Payment required", mcp.PaymentRequirements{
+		X402Version: v2.X402Version,
+		Accepts:     []v2.PaymentRequirements{call},
+		Extensions: map[string]v2.Extension{mcp.BundleExtension: {Info: map[string]interface{}{
+			"offers": []mcp.BundleOffer{{Tools: []string{"search"}, Calls: 3, Accepts: []v2.PaymentRequirements{price}}},
+		}}},
+	})
+
+	base := &scriptedTransport{results: []scriptedResult{
+		// The first call buys the bundle
+		paymentRequired, voucherResult("v1", 2),
+		// The next calls redeem its voucher
+		voucherResult("v1", 1), voucherResult("v1", 0),
+		// Once used up, the bundle is bought again, and a rejected voucher
+		// is dropped before paying
+		paymentRequired, voucherResult("v2", 2),
+		paymentRequired, voucherResult("v3", 2),
+	}}
+	clock := v2.NewFakeClock(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	config := DefaultConfig("http://example.com")
+	for _, opt := range []Option{
+		WithSigner(&mockSigner{network: "eip155:84532"}),
+		WithBundles(LargestBundle),
+		WithVoucherStore(storage.NewMemory()),
+		WithClock(clock),
+	} {
+		opt(config)
+	}
+	tr := &Transport{baseTransport: base, config: config}
+	ctx := context.Background()
+	req := transport.JSONRPCRequest{Method: "tools/call", Params: map[string]interface{}{"name": "search"}}
+
+	for i := 0; i < 5; i++ {
+		resp, err := tr.SendRequest(ctx, req)
+		if err != nil || resp.Error != nil {
+			t.Fatalf("call %d failed: %v %+v", i, err, resp)
+		}
+	}
+	if len(base.results) != 0 {
+		t.Errorf("expected all results consumed, %d left", len(base.results))
+	}
+
+	wantVouchers := []string{"", "", "v1", "v1", "", "", "v2", ""}
+	wantAmounts := []string{"", "2500", "", "", "", "2500", "", "2500"}
+	for i := range base.vouchers {
+		amount := ""
+		if base.payments[i] != nil {
+			amount = base.payments[i].Accepted.Amount
+		}
+		if base.vouchers[i] != wantVouchers[i] || amount != wantAmounts[i] {
+			t.Errorf("request %d: expected voucher %q and payment %q, got %q and %q", i, wantVouchers[i], wantAmounts[i], base.vouchers[i], amount)
+		}
+	}
+
+	vouchers, err := tr.Vouchers(ctx)
+	if err != nil || len(vouchers) != 1 || vouchers[0].Token != "v3" || vouchers[0].Remaining != 2 {
+		t.Errorf("expected the last voucher to be kept, got %+v (%v)", vouchers, err)
+	}
+
+	// Expired vouchers are not redeemed
+	clock.Advance(time.Hour)
+	if _, ok := tr.voucherFor(ctx, req); ok {
+		t.Error("expected an expired voucher not to be redeemed")
+	}
+}
@@ -3,8 +3,11 @@ package client
 
 import (
 	"net/http"
+	"time"
 
 	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/extensions"
+	"github.com/mark3labs/x402-go/v2/storage"
 )
 
 // Config holds configuration for the MCP client with x402 v2 payment support.
@@ -27,9 +30,55 @@ type Config struct {
 	// OnPaymentFailure is called when a payment fails.
 	OnPaymentFailure v2.PaymentCallback
 
+	// OnPaymentRetry is called before each retry of a request.
+	OnPaymentRetry v2.PaymentCallback
+
+	// MaxRetries is the maximum number of retries of a request after transient
+	// transport errors or facilitator verification failures (default: 2).
+	// Tool calls are only retried after transport errors if they never reached
+	// the server. Set to 0 to disable retries.
+	MaxRetries int
+
+	// RetryDelay is the initial delay between retries (default: 100ms).
+	// Exponential backoff is applied with a multiplier of 2.0.
+	RetryDelay time.Duration
+
+	// Clock schedules retry backoff. If nil, v2.SystemClock is used.
+	Clock v2.Clock
+
 	// Selector is the payment selector for choosing which signer to use (optional, uses default if nil).
 	Selector v2.PaymentSelector
 
+	// SpendGuard optionally enforces a process-wide spend limit.
+	// Payments that would exceed the limit are not sent.
+	SpendGuard *v2.SpendGuard
+
+	// Approve, if set, approves every signed payment before it is sent.
+	// Refused payments fail with v2.ErrPaymentNotApproved.
+	Approve v2.ApprovalFunc
+
+	// Allowlist, if set, restricts the schemes, networks and assets the
+	// transport pays with. Requirements outside it are not paid.
+	Allowlist v2.PaymentAllowlist
+
+	// Extensions, if set, validates the extensions of payment required errors
+	// against their registered schemas and adds the info of its extensions to
+	// payments.
+	Extensions *extensions.Registry
+
+	// ChooseBundle, if set, buys a bundle of prepaid calls when a server
+	// offers some for a called tool, choosing which. Later calls of the
+	// bundle's tools redeem its voucher instead of paying.
+	ChooseBundle BundleChooser
+
+	// Vouchers stores the vouchers of bought bundles. If nil and ChooseBundle
+	// is set, they are kept in memory.
+	Vouchers storage.Store
+
+	// DryRun stops before signing and returns a *v2.DryRunError describing the
+	// payment that would have been made. No payment is signed or sent.
+	DryRun bool
+
 	// Verbose enables detailed logging.
 	Verbose bool
 }
@@ -60,6 +109,7 @@ func WithPaymentCallback(callback v2.PaymentCallback) Option {
 		c.OnPaymentAttempt = callback
 		c.OnPaymentSuccess = callback
 		c.OnPaymentFailure = callback
+		c.OnPaymentRetry = callback
 	}
 }
 
@@ -84,6 +134,29 @@ func WithPaymentFailureCallback(callback v2.PaymentCallback) Option {
 	}
 }
 
+// WithPaymentRetryCallback sets the payment retry callback.
+func WithPaymentRetryCallback(callback v2.PaymentCallback) Option {
+	return func(c *Config) {
+		c.OnPaymentRetry = callback
+	}
+}
+
+// WithRetry sets the maximum number of retries and the initial backoff delay.
+// Use WithRetry(0, 0) to disable retries.
+func WithRetry(maxRetries int, delay time.Duration) Option {
+	return func(c *Config) {
+		c.MaxRetries = maxRetries
+		c.RetryDelay = delay
+	}
+}
+
+// WithClock sets the clock used for retry backoff.
+func WithClock(clock v2.Clock) Option {
+	return func(c *Config) {
+		c.Clock = clock
+	}
+}
+
 // WithSelector sets a custom payment selector.
 func WithSelector(selector v2.PaymentSelector) Option {
 	return func(c *Config) {
@@ -91,6 +164,65 @@ func WithSelector(selector v2.PaymentSelector) Option {
 	}
 }
 
+// WithSpendGuard enforces a process-wide spend limit on the transport.
+// The same guard may be shared by multiple transports and HTTP clients.
+func WithSpendGuard(guard *v2.SpendGuard) Option {
+	return func(c *Config) {
+		c.SpendGuard = guard
+	}
+}
+
+// WithApproval has approve approve every payment after it is signed and
+// before it is sent.
+func WithApproval(approve v2.ApprovalFunc) Option {
+	return func(c *Config) {
+		c.Approve = approve
+	}
+}
+
+// WithPaymentAllowlist only pays requirements matching one of entries.
+// Invalid entries are reported when the transport pays.
+func WithPaymentAllowlist(entries ...v2.AllowedPayment) Option {
+	return func(c *Config) {
+		c.Allowlist = append(c.Allowlist, entries...)
+	}
+}
+
+// WithExtensions validates the extensions of payment required errors with
+// registry and adds the info of its extensions to payments (see the extensions
+// package).
+func WithExtensions(registry *extensions.Registry) Option {
+	return func(c *Config) {
+		c.Extensions = registry
+	}
+}
+
+// WithBundles buys bundles of prepaid calls chosen by choose, like
+// LargestBundle, when servers offer them, and pays later calls of their tools
+// with the bundle's voucher: one settlement instead of one per call.
+func WithBundles(choose BundleChooser) Option {
+	return func(c *Config) {
+		c.ChooseBundle = choose
+	}
+}
+
+// WithVoucherStore keeps the vouchers of bought bundles in store, so they
+// survive restarts. Use a storage.Prefixed view when the store is shared.
+func WithVoucherStore(store storage.Store) Option {
+	return func(c *Config) {
+		c.Vouchers = store
+	}
+}
+
+// WithDryRun makes the transport report what it would pay instead of paying.
+// Requests that require payment fail with a *v2.DryRunError; use
+// v2.PaymentPlanFromError to retrieve the PaymentPlan.
+func WithDryRun() Option {
+	return func(c *Config) {
+		c.DryRun = true
+	}
+}
+
 // WithVerbose enables verbose logging.
 func WithVerbose() Option {
 	return func(c *Config) {
@@ -105,5 +237,7 @@ func DefaultConfig(serverURL string) *Config {
 		HTTPClient: http.DefaultClient,
 		Selector:   v2.NewDefaultPaymentSelector(),
 		Signers:    make([]v2.Signer, 0),
+		MaxRetries: 2,
+		RetryDelay: 100 * time.Millisecond,
 	}
 }
@@ -0,0 +1,184 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/mcp"
+)
+
+// mcpInternalError is the JSON-RPC internal error code.
+const mcpInternalError = -32603
+
+// retryableStatus matches the errors the streamable HTTP transport returns for
+// non-JSON-RPC HTTP responses that are worth retrying.
+var retryableStatus = regexp.MustCompile(`request failed with status (429|502|503|504)\b`)
+
+// isTransientError reports whether err from the base transport is a transient
+// network failure, as opposed to a cancelled request or a session or
+// authorization problem that retrying cannot fix.
+func isTransientError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, transport.ErrSessionTerminated) ||
+		errors.Is(err, transport.ErrOAuthAuthorizationRequired) {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		retryableStatus.MatchString(err.Error())
+}
+
+// isUndelivered reports whether err from the base transport means the request
+// never reached the server: the connection could not be established, or the
+// server turned the request away with a 429 before processing it.
+func isUndelivered(err error) bool {
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		(errors.As(err, &opErr) && opErr.Op == "dial") ||
+		errors.As(err, &dnsErr) ||
+		strings.Contains(err.Error(), "request failed with status 429")
+}
+
+// isVerificationFailure reports whether resp is the error a server returns when
+// its facilitator could not verify the payment. Such payments were not settled.
+func isVerificationFailure(resp *transport.JSONRPCResponse) bool {
+	return resp != nil && resp.Error != nil &&
+		resp.Error.Code == mcpInternalError &&
+		strings.HasPrefix(resp.Error.Message, mcp.VerificationFailedMessage)
+}
+
+// retryDelay returns the backoff before the given retry (starting at 1),
+// doubling from RetryDelay up to four times RetryDelay.
+func (t *Transport) retryDelay(retry int) time.Duration {
+	delay := t.config.RetryDelay
+	if delay <= 0 {
+		delay = 100 * time.Millisecond
+	}
+	maxDelay := delay * 4
+	for i := 1; i < retry && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// waitRetry notifies OnPaymentRetry of the given retry and waits out its
+// backoff. It returns the context error if ctx ends first.
+func (t *Transport) waitRetry(ctx context.Context, req transport.JSONRPCRequest, retry int, payment *v2.PaymentPayload, reused bool, cause error) error {
+	event := v2.PaymentEvent{
+		Type:      v2.PaymentEventRetry,
+		Timestamp: time.Now(),
+		Method:    "MCP",
+		Tool:      req.Method,
+		Error:     cause,
+		Metadata:  map[string]interface{}{"attempt": retry + 1},
+	}
+	if payment != nil {
+		event.Network = payment.Accepted.Network
+		event.Scheme = payment.Accepted.Scheme
+		event.Amount = payment.Accepted.Amount
+		event.Asset = payment.Accepted.Asset
+		event.Recipient = payment.Accepted.PayTo
+		event.Metadata["paymentReused"] = reused
+	}
+	v2.NotifyPaymentEvent(ctx, t.config.OnPaymentRetry, event)
+
+	select {
+	case <-v2.ClockOrSystem(t.config.Clock).After(t.retryDelay(retry)):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// send sends a request without payment, retrying transient transport errors.
+// Tool calls, which need not be idempotent, are only resent if they never
+// reached the server.
+func (t *Transport) send(ctx context.Context, req transport.JSONRPCRequest) (*transport.JSONRPCResponse, error) {
+	for retry := 0; ; retry++ {
+		resp, err := t.baseTransport.SendRequest(ctx, req)
+		if retry >= t.config.MaxRetries || !isTransientError(ctx, err) ||
+			(req.Method == "tools/call" && !isUndelivered(err)) {
+			return resp, err
+		}
+		if err := t.waitRetry(ctx, req, retry+1, nil, false, err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sendPaid sends a request with payment, retrying failures that cannot have
+// settled it.
+//
+// The payment is resent after transport errors that show the request never
+// reached the server, and after facilitator verification failures. A transport
+// error after the request may have been delivered is returned as a
+// *mcp.PaymentUncertainError carrying the payment: it may have settled, with
+// only its response lost, and the tool may have run.
+//
+// A new payment is only created when a payment the server explicitly failed to
+// verify is then rejected with a 402, for instance because it expired during
+// backoff. It returns the response and the payment that produced it.
+func (t *Transport) sendPaid(ctx context.Context, req transport.JSONRPCRequest, payment *v2.PaymentPayload, requirements []v2.PaymentRequirements, resource v2.ResourceInfo) (*transport.JSONRPCResponse, *v2.PaymentPayload, error) {
+	verifyFailed, regenerated := false, false
+	for retry := 0; ; retry++ {
+		paidReq, err := t.injectPaymentMeta(req, payment)
+		if err != nil {
+			return nil, payment, fmt.Errorf("failed to inject payment: %w", err)
+		}
+
+		resp, err := t.baseTransport.SendRequest(ctx, paidReq)
+
+		var cause error
+		reused := true
+		switch {
+		case err != nil:
+			if !isTransientError(ctx, err) {
+				return resp, payment, err
+			}
+			if !isUndelivered(err) {
+				return resp, payment, &mcp.PaymentUncertainError{Payment: payment, Tool: toolName(req), Err: err}
+			}
+			cause = err
+		case isVerificationFailure(resp):
+			cause = errors.New(resp.Error.Message)
+			verifyFailed = true
+		case verifyFailed && !regenerated && resp.Error != nil && resp.Error.Code == 402:
+			cause = fmt.Errorf("payment rejected on retry: %s", resp.Error.Message)
+			reused = false
+		default:
+			return resp, payment, nil
+		}
+
+		if retry >= t.config.MaxRetries {
+			return resp, payment, err
+		}
+		if !reused {
+			newPayment, _, err := t.createPayment(ctx, requirements, resource)
+			if err != nil {
+				return resp, payment, mcp.WrapX402Error(err, req.Method)
+			}
+			payment = newPayment
+			regenerated = true
+		}
+		if err := t.waitRetry(ctx, req, retry+1, payment, reused, cause); err != nil {
+			return nil, payment, err
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	mcpproto "github.com/mark3labs/mcp-go/mcp"
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/mcp"
+)
+
+// scriptedTransport answers requests with a fixed sequence of results.
+type scriptedTransport struct {
+	results  []scriptedResult
+	payments []*v2.PaymentPayload
+	vouchers []string
+}
+
+type scriptedResult struct {
+	resp *transport.JSONRPCResponse
+	err  error
+}
+
+func (s *scriptedTransport) SendRequest(ctx context.Context, req transport.JSONRPCRequest) (*transport.JSONRPCResponse, error) {
+	var payment *v2.PaymentPayload
+	var voucher string
+	if params, ok := req.Params.(map[string]interface{}); ok {
+		if meta, ok := params["_meta"].(map[string]interface{}); ok {
+			payment, _ = meta["x402/payment"].(*v2.PaymentPayload)
+			voucher, _ = meta[mcp.VoucherMetaKey].(string)
+		}
+	}
+	s.payments = append(s.payments, payment)
+	s.vouchers = append(s.vouchers, voucher)
+
+	if len(s.results) == 0 {
+		return nil, errors.New("unexpected request")
+	}
+	result := s.results[0]
+	s.results = s.results[1:]
+	return result.resp, result.err
+}
+
+func (s *scriptedTransport) Start(context.Context) error { return nil }
+func (s *scriptedTransport) SendNotification(context.Context, mcpproto.JSONRPCNotification) error {
+	return nil
+}
+func (s *scriptedTransport) SetNotificationHandler(func(mcpproto.JSONRPCNotification)) {}
+func (s *scriptedTransport) Close() error                                              { return nil }
+func (s *scriptedTransport) GetSessionId() string                                      { return "" }
+
+func rpcResult() scriptedResult {
+	return scriptedResult{resp: &transport.JSONRPCResponse{Result: []byte(`{}`)}}
+}
+
+func rpcError(code int, message string, data interface{}) scriptedResult {
+	return scriptedResult{resp: &transport.JSONRPCResponse{
+		Error: &mcpproto.JSONRPCErrorDetails{Code: code, Message: message, Data: data},
+	}}
+}
+
+func networkError() scriptedResult {
+	return scriptedResult{err: fmt.Errorf("failed to send request: %w", syscall.ECONNRESET)}
+}
+
+// refusedError is a transport error for a request that never reached the server.
+func refusedError() scriptedResult {
+	return scriptedResult{err: fmt.Errorf("failed to send request: %w", syscall.ECONNREFUSED)}
+}
+
+func TestSendRequest_Retry(t *testing.T) {
+	requirements := mcp.PaymentRequirements{
+		X402Version: v2.X402Version,
+		Accepts: []v2.PaymentRequirements{
+			{Scheme: "exact", Network: "eip155:84532", Amount: "10000", Asset: "0xAsset", PayTo: "0xPayTo"},
+		},
+	}
+	paymentRequired := rpcError(402, "Payment required", requirements)
+	verificationFailed := rpcError(mcpInternalError, mcp.VerificationFailedMessage+": facilitator unavailable", nil)
+
+	tests := []struct {
+		name        string
+		maxRetries  int
+		results     []scriptedResult
+		wantErr     bool
+		wantCode    int
+		wantRetries int
+		// wantUncertain expects a *mcp.PaymentUncertainError carrying the
+		// last payment sent
+		wantUncertain bool
+		// wantPayments lists, per request, 0 for no payment or the index of
+		// the distinct payment sent
+		wantPayments []int
+	}{
+		{
+			name:         "unpaid request retried",
+			maxRetries:   2,
+			results:      []scriptedResult{refusedError(), refusedError(), rpcResult()},
+			wantRetries:  2,
+			wantPayments: []int{0, 0, 0},
+		},
+		{
+			name:         "retries exhausted",
+			maxRetries:   1,
+			results:      []scriptedResult{refusedError(), refusedError()},
+			wantErr:      true,
+			wantRetries:  1,
+			wantPayments: []int{0, 0},
+		},
+		{
+			name:         "retries disabled",
+			results:      []scriptedResult{refusedError()},
+			wantErr:      true,
+			wantPayments: []int{0},
+		},
+		{
+			name:         "permanent error not retried",
+			maxRetries:   2,
+			results:      []scriptedResult{{err: transport.ErrSessionTerminated}},
+			wantErr:      true,
+			wantPayments: []int{0},
+		},
+		{
+			name:         "delivered call not retried",
+			maxRetries:   2,
+			results:      []scriptedResult{networkError()},
+			wantErr:      true,
+			wantPayments: []int{0},
+		},
+		{
+			name:         "payment reused after undelivered request",
+			maxRetries:   2,
+			results:      []scriptedResult{paymentRequired, refusedError(), rpcResult()},
+			wantRetries:  1,
+			wantPayments: []int{0, 1, 1},
+		},
+		{
+			name:          "payment outcome unknown after possible delivery",
+			maxRetries:    2,
+			results:       []scriptedResult{paymentRequired, networkError()},
+			wantErr:       true,
+			wantUncertain: true,
+			wantPayments:  []int{0, 1},
+		},
+		{
+			name:          "payment outcome unknown after lost response",
+			maxRetries:    2,
+			results:       []scriptedResult{paymentRequired, refusedError(), {err: io.ErrUnexpectedEOF}},
+			wantErr:       true,
+			wantUncertain: true,
+			wantRetries:   1,
+			wantPayments:  []int{0, 1, 1},
+		},
+		{
+			name:         "payment reused after verification failure",
+			maxRetries:   2,
+			results:      []scriptedResult{paymentRequired, verificationFailed, verificationFailed, rpcResult()},
+			wantRetries:  2,
+			wantPayments: []int{0, 1, 1, 1},
+		},
+		{
+			name:         "verification failures exhausted",
+			maxRetries:   1,
+			results:      []scriptedResult{paymentRequired, verificationFailed, verificationFailed},
+			wantCode:     mcpInternalError,
+			wantRetries:  1,
+			wantPayments: []int{0, 1, 1},
+		},
+		{
+			name:         "payment regenerated when rejected after verification failure",
+			maxRetries:   3,
+			results:      []scriptedResult{paymentRequired, verificationFailed, rpcError(402, "Payment invalid: expired", nil), rpcResult()},
+			wantRetries:  2,
+			wantPayments: []int{0, 1, 1, 2},
+		},
+		{
+			name:         "payment regenerated once",
+			maxRetries:   5,
+			results:      []scriptedResult{paymentRequired, verificationFailed, rpcError(402, "Payment invalid", nil), rpcError(402, "Payment invalid", nil)},
+			wantCode:     402,
+			wantRetries:  2,
+			wantPayments: []int{0, 1, 1, 2},
+		},
+		{
+			name:         "payment not regenerated without verification failure",
+			maxRetries:   3,
+			results:      []scriptedResult{paymentRequired, refusedError(), rpcError(402, "Payment invalid: nonce used", nil)},
+			wantCode:     402,
+			wantRetries:  1,
+			wantPayments: []int{0, 1, 1},
+		},
+		{
+			name:         "first rejection not retried",
+			maxRetries:   2,
+			results:      []scriptedResult{paymentRequired, rpcError(402, "Payment invalid", nil)},
+			wantCode:     402,
+			wantPayments: []int{0, 1},
+		},
+		{
+			name:         "settlement failure not retried",
+			maxRetries:   2,
+			results:      []scriptedResult{paymentRequired, rpcError(mcpInternalError, "Settlement failed: reverted", nil)},
+			wantCode:     mcpInternalError,
+			wantPayments: []int{0, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &scriptedTransport{results: tt.results}
+			var retries []v2.PaymentEvent
+			config := DefaultConfig("http://example.com")
+			for _, opt := range []Option{
+				WithSigner(&mockSigner{network: "eip155:84532"}),
+				WithRetry(tt.maxRetries, time.Nanosecond),
+				WithPaymentRetryCallback(func(event v2.PaymentEvent) { retries = append(retries, event) }),
+			} {
+				opt(config)
+			}
+			tr := &Transport{baseTransport: base, config: config}
+
+			resp, err := tr.SendRequest(context.Background(), transport.JSONRPCRequest{Method: "tools/call", Params: map[string]interface{}{"name": "paid_tool"}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantCode != 0 && (resp == nil || resp.Error == nil || resp.Error.Code != tt.wantCode) {
+				t.Errorf("Expected error code %d, got %+v", tt.wantCode, resp)
+			}
+			if !tt.wantErr && tt.wantCode == 0 && (resp == nil || resp.Error != nil) {
+				t.Errorf("Expected success, got %+v", resp)
+			}
+			if len(base.results) != 0 {
+				t.Errorf("Expected all %d results consumed, %d left", len(tt.results), len(base.results))
+			}
+			var uncertain *mcp.PaymentUncertainError
+			if errors.As(err, &uncertain) != tt.wantUncertain {
+				t.Errorf("Expected uncertain payment %v, got %v", tt.wantUncertain, err)
+			}
+			if tt.wantUncertain && uncertain.Payment != base.payments[len(base.payments)-1] {
+				t.Error("Expected the uncertain error to carry the payment sent")
+			}
+
+			if len(retries) != tt.wantRetries {
+				t.Errorf("Expected %d retry events, got %d", tt.wantRetries, len(retries))
+			}
+			for i, event := range retries {
+				if event.Type != v2.PaymentEventRetry || event.Error == nil || event.Metadata["attempt"] != i+2 {
+					t.Errorf("Unexpected retry event %d: %+v", i, event)
+				}
+			}
+
+			distinct := map[*v2.PaymentPayload]int{nil: 0}
+			for i, payment := range base.payments {
+				if _, ok := distinct[payment]; !ok {
+					distinct[payment] = len(distinct)
+				}
+				if i < len(tt.wantPayments) && distinct[payment] != tt.wantPayments[i] {
+					t.Errorf("Request %d: expected payment %d, got %d", i, tt.wantPayments[i], distinct[payment])
+				}
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tr := &Transport{config: &Config{RetryDelay: 100 * time.Millisecond}}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 400 * time.Millisecond}
+	for i, d := range want {
+		if got := tr.retryDelay(i + 1); got != d {
+			t.Errorf("retryDelay(%d) = %v, want %v", i+1, got, d)
+		}
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{name: "nil", ctx: context.Background(), want: false},
+		{name: "connection reset", ctx: context.Background(), err: syscall.ECONNRESET, want: true},
+		{name: "unexpected EOF", ctx: context.Background(), err: fmt.Errorf("failed to read: %w", io.ErrUnexpectedEOF), want: true},
+		{name: "bad gateway", ctx: context.Background(), err: errors.New("request failed with status 502: bad gateway"), want: true},
+		{name: "bad request", ctx: context.Background(), err: errors.New("request failed with status 400: bad"), want: false},
+		{name: "session terminated", ctx: context.Background(), err: transport.ErrSessionTerminated, want: false},
+		{name: "cancelled", ctx: cancelled, err: syscall.ECONNRESET, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientError(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("isTransientError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUndelivered(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "connection refused", err: fmt.Errorf("failed to send request: %w", syscall.ECONNREFUSED), want: true},
+		{name: "dial failure", err: &net.OpError{Op: "dial", Err: errors.New("no route to host")}, want: true},
+		{name: "too many requests", err: errors.New("request failed with status 429: slow down"), want: true},
+		{name: "connection reset", err: syscall.ECONNRESET, want: false},
+		{name: "read failure", err: &net.OpError{Op: "read", Err: errors.New("timeout")}, want: false},
+		{name: "bad gateway", err: errors.New("request failed with status 502: bad gateway"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUndelivered(tt.err); got != tt.want {
+				t.Errorf("isUndelivered(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
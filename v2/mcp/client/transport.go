@@ -4,12 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"time"
 
 	"github.com/mark3labs/mcp-go/client/transport"
 	mcpproto "github.com/mark3labs/mcp-go/mcp"
 	v2 "github.com/mark3labs/x402-go/v2"
 	"github.com/mark3labs/x402-go/v2/mcp"
+	"github.com/mark3labs/x402-go/v2/storage"
 )
 
 // Transport wraps an MCP transport and adds x402 v2 payment handling.
@@ -35,6 +37,9 @@ func NewTransport(serverURL string, opts ...Option) (*Transport, error) {
 	if config.Selector == nil {
 		config.Selector = v2.NewDefaultPaymentSelector()
 	}
+	if config.ChooseBundle != nil && config.Vouchers == nil {
+		config.Vouchers = storage.NewMemory()
+	}
 
 	return &Transport{
 		baseTransport: baseTransport,
@@ -49,8 +54,14 @@ func (t *Transport) Start(ctx context.Context) error {
 
 // SendRequest implements transport.Interface by intercepting requests and handling 402 errors.
 func (t *Transport) SendRequest(ctx context.Context, req transport.JSONRPCRequest) (*transport.JSONRPCResponse, error) {
-	// Send initial request
-	resp, err := t.baseTransport.SendRequest(ctx, req)
+	// Send initial request, paid with a voucher of a bundle bought earlier
+	var resp *transport.JSONRPCResponse
+	var err error
+	if voucher, ok := t.voucherFor(ctx, req); ok {
+		resp, err = t.redeem(ctx, req, voucher)
+	} else {
+		resp, err = t.send(ctx, req)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -72,6 +83,15 @@ func (t *Transport) SendRequest(ctx context.Context, req transport.JSONRPCReques
 		if err != nil {
 			return resp, fmt.Errorf("failed to extract payment requirements: %w", err)
 		}
+		// Buy a bundle of calls instead, if one is offered and chosen
+		if bundle := t.chooseBundle(req, data); len(bundle) > 0 {
+			requirements = bundle
+		}
+
+		// In dry-run mode, report the plan instead of signing
+		if t.config.DryRun {
+			return resp, t.planPayment(requirements, resource)
+		}
 
 		// Create payment
 		payment, startTime, err := t.createPayment(ctx, requirements, resource)
@@ -79,14 +99,14 @@ func (t *Transport) SendRequest(ctx context.Context, req transport.JSONRPCReques
 			return resp, mcp.WrapX402Error(err, req.Method)
 		}
 
-		// Inject payment and retry
-		modifiedReq, err := t.injectPaymentMeta(req, payment)
-		if err != nil {
-			return resp, fmt.Errorf("failed to inject payment: %w", err)
+		// Retry with payment, keeping the voucher of a bundle bought
+		resp, err = t.retryWithPayment(ctx, req, payment, startTime, requirements, resource)
+		if err == nil && resp.Error == nil {
+			if voucher, ok := resultVoucher(resp); ok {
+				t.updateVouchers(ctx, voucher.Token, &voucher)
+			}
 		}
-
-		// Retry with payment
-		return t.retryWithPayment(ctx, modifiedReq, payment, startTime)
+		return resp, err
 	}
 
 	return resp, nil
@@ -130,7 +150,21 @@ func (t *Transport) extractPaymentRequirements(data json.RawMessage) ([]v2.Payme
 		return nil, v2.ResourceInfo{}, mcp.ErrNoPaymentRequirements
 	}
 
-	return reqData.Accepts, reqData.Resource, nil
+	if t.config.Extensions != nil {
+		if err := t.config.Extensions.Validate(reqData.Extensions); err != nil {
+			return nil, v2.ResourceInfo{}, v2.NewPaymentError(v2.ErrCodeInvalidRequirements, "invalid payment requirements extensions", err)
+		}
+	}
+
+	if err := t.config.Allowlist.Validate(); err != nil {
+		return nil, v2.ResourceInfo{}, err
+	}
+	accepts, err := t.config.Allowlist.Filter(reqData.Accepts)
+	if err != nil {
+		return nil, v2.ResourceInfo{}, v2.NewPaymentError(v2.ErrCodeUnsupportedScheme, "payment not allowed", err)
+	}
+
+	return accepts, reqData.Resource, nil
 }
 
 // createPayment creates a payment using the configured signers.
@@ -143,23 +177,58 @@ func (t *Transport) createPayment(ctx context.Context, requirements []v2.Payment
 	}
 
 	// Use selector to choose signer and create payment
-	payment, err := t.config.Selector.SelectAndSign(t.config.Signers, requirements)
+	payment, err := v2.SelectAndSignContext(ctx, t.config.Selector, t.config.Signers, requirements)
 	if err != nil {
-		if t.config.OnPaymentFailure != nil {
-			t.config.OnPaymentFailure(v2.PaymentEvent{
+		v2.NotifyPaymentEvent(ctx, t.config.OnPaymentFailure, v2.PaymentEvent{
+			Type:      v2.PaymentEventFailure,
+			Timestamp: time.Now(),
+			Method:    "MCP",
+			Error:     err,
+			Duration:  time.Since(startTime),
+		})
+		return nil, startTime, err
+	}
+
+	// Set the resource info in the payment if provided
+	if resource.URL != "" {
+		payment.Resource = &resource
+	}
+	if t.config.Extensions != nil {
+		t.config.Extensions.Attach(&payment.Extensions)
+	}
+	if err := v2.Approve(ctx, t.config.Approve, payment); err != nil {
+		v2.NotifyPaymentEvent(ctx, t.config.OnPaymentFailure, v2.PaymentEvent{
+			Type:      v2.PaymentEventFailure,
+			Timestamp: time.Now(),
+			Method:    "MCP",
+			Network:   payment.Accepted.Network,
+			Scheme:    payment.Accepted.Scheme,
+			Amount:    payment.Accepted.Amount,
+			Asset:     payment.Accepted.Asset,
+			Recipient: payment.Accepted.PayTo,
+			Error:     err,
+			Duration:  time.Since(startTime),
+		})
+		return nil, startTime, err
+	}
+
+	// Enforce the process-wide and request spend limits before the payment leaves the process
+	if t.config.SpendGuard != nil || v2.SpendGuardFromContext(ctx) != nil {
+		if err := v2.ReserveSpend(ctx, t.config.SpendGuard, payment.Accepted); err != nil {
+			v2.NotifyPaymentEvent(ctx, t.config.OnPaymentFailure, v2.PaymentEvent{
 				Type:      v2.PaymentEventFailure,
 				Timestamp: time.Now(),
 				Method:    "MCP",
+				Network:   payment.Accepted.Network,
+				Scheme:    payment.Accepted.Scheme,
+				Amount:    payment.Accepted.Amount,
+				Asset:     payment.Accepted.Asset,
+				Recipient: payment.Accepted.PayTo,
 				Error:     err,
 				Duration:  time.Since(startTime),
 			})
+			return nil, startTime, err
 		}
-		return nil, startTime, err
-	}
-
-	// Set the resource info in the payment if provided
-	if resource.URL != "" {
-		payment.Resource = &resource
 	}
 
 	// Find the requirement that was actually selected by matching the payment's network and scheme
@@ -173,8 +242,8 @@ func (t *Transport) createPayment(ctx context.Context, requirements []v2.Payment
 	}
 
 	// Trigger payment attempt callback with the actually selected requirement
-	if t.config.OnPaymentAttempt != nil && selectedReq != nil {
-		t.config.OnPaymentAttempt(v2.PaymentEvent{
+	if selectedReq != nil {
+		v2.NotifyPaymentEvent(ctx, t.config.OnPaymentAttempt, v2.PaymentEvent{
 			Type:      v2.PaymentEventAttempt,
 			Timestamp: startTime,
 			Method:    "MCP",
@@ -189,8 +258,42 @@ func (t *Transport) createPayment(ctx context.Context, requirements []v2.Payment
 	return payment, startTime, nil
 }
 
+// planPayment selects a requirement and applies policy checks without signing.
+// Returns a *v2.DryRunError with the plan, or the selection or policy error.
+func (t *Transport) planPayment(requirements []v2.PaymentRequirements, resource v2.ResourceInfo) error {
+	var resourceInfo *v2.ResourceInfo
+	if resource.URL != "" {
+		resourceInfo = &resource
+	}
+
+	plan, err := v2.PlanPayment(t.config.Selector, t.config.Signers, requirements, resourceInfo, t.config.SpendGuard)
+	if err != nil {
+		return err
+	}
+	return &v2.DryRunError{Plan: plan}
+}
+
+// toolName returns the tool called by a tools/call request, or else the
+// request method.
+func toolName(req transport.JSONRPCRequest) string {
+	if req.Method == "tools/call" {
+		var params struct {
+			Name string `json:"name"`
+		}
+		if data, err := json.Marshal(req.Params); err == nil && json.Unmarshal(data, &params) == nil && params.Name != "" {
+			return params.Name
+		}
+	}
+	return req.Method
+}
+
 // injectPaymentMeta injects payment into request params._meta.
 func (t *Transport) injectPaymentMeta(req transport.JSONRPCRequest, payment *v2.PaymentPayload) (transport.JSONRPCRequest, error) {
+	return t.injectMeta(req, "x402/payment", payment)
+}
+
+// injectMeta sets key to value in request params._meta.
+func (t *Transport) injectMeta(req transport.JSONRPCRequest, key string, value interface{}) (transport.JSONRPCRequest, error) {
 	// Convert params to map
 	params, ok := req.Params.(map[string]interface{})
 	if !ok {
@@ -208,14 +311,16 @@ func (t *Transport) injectPaymentMeta(req transport.JSONRPCRequest, payment *v2.
 		}
 	}
 
-	// Get or create _meta
+	// Copy params and _meta, leaving the caller's request untouched
+	params = maps.Clone(params)
 	meta, ok := params["_meta"].(map[string]interface{})
-	if !ok {
+	if ok {
+		meta = maps.Clone(meta)
+	} else {
 		meta = make(map[string]interface{})
 	}
 
-	// Add payment to _meta
-	meta["x402/payment"] = payment
+	meta[key] = value
 	params["_meta"] = meta
 
 	// Create modified request
@@ -226,29 +331,27 @@ func (t *Transport) injectPaymentMeta(req transport.JSONRPCRequest, payment *v2.
 }
 
 // retryWithPayment retries the request with payment.
-func (t *Transport) retryWithPayment(ctx context.Context, req transport.JSONRPCRequest, payment *v2.PaymentPayload, startTime time.Time) (*transport.JSONRPCResponse, error) {
-	resp, err := t.baseTransport.SendRequest(ctx, req)
+func (t *Transport) retryWithPayment(ctx context.Context, req transport.JSONRPCRequest, payment *v2.PaymentPayload, startTime time.Time, requirements []v2.PaymentRequirements, resource v2.ResourceInfo) (*transport.JSONRPCResponse, error) {
+	resp, payment, err := t.sendPaid(ctx, req, payment, requirements, resource)
 	duration := time.Since(startTime)
 
 	if err != nil {
-		if t.config.OnPaymentFailure != nil {
-			t.config.OnPaymentFailure(v2.PaymentEvent{
-				Type:      v2.PaymentEventFailure,
-				Timestamp: time.Now(),
-				Method:    "MCP",
-				Error:     err,
-				Network:   payment.Accepted.Network,
-				Scheme:    payment.Accepted.Scheme,
-				Duration:  duration,
-			})
-		}
+		v2.NotifyPaymentEvent(ctx, t.config.OnPaymentFailure, v2.PaymentEvent{
+			Type:      v2.PaymentEventFailure,
+			Timestamp: time.Now(),
+			Method:    "MCP",
+			Error:     err,
+			Network:   payment.Accepted.Network,
+			Scheme:    payment.Accepted.Scheme,
+			Duration:  duration,
+		})
 		return resp, err
 	}
 
 	// Check if payment succeeded
 	if resp.Error != nil {
-		if resp.Error.Code == 402 && t.config.OnPaymentFailure != nil {
-			t.config.OnPaymentFailure(v2.PaymentEvent{
+		if resp.Error.Code == 402 {
+			v2.NotifyPaymentEvent(ctx, t.config.OnPaymentFailure, v2.PaymentEvent{
 				Type:      v2.PaymentEventFailure,
 				Timestamp: time.Now(),
 				Method:    "MCP",
@@ -262,19 +365,65 @@ func (t *Transport) retryWithPayment(ctx context.Context, req transport.JSONRPCR
 	}
 
 	// Payment succeeded
-	if t.config.OnPaymentSuccess != nil {
-		// Extract tool name from request method
-		toolName := req.Method
-		t.config.OnPaymentSuccess(v2.PaymentEvent{
-			Type:      v2.PaymentEventSuccess,
-			Timestamp: time.Now(),
-			Method:    "MCP",
-			Tool:      toolName,
-			Network:   payment.Accepted.Network,
-			Scheme:    payment.Accepted.Scheme,
-			Duration:  duration,
-		})
-	}
+	v2.NotifyPaymentEvent(ctx, t.config.OnPaymentSuccess, v2.PaymentEvent{
+		Type:      v2.PaymentEventSuccess,
+		Timestamp: time.Now(),
+		Method:    "MCP",
+		Tool:      toolName(req),
+		URL:       t.config.ServerURL,
+		Network:   payment.Accepted.Network,
+		Scheme:    payment.Accepted.Scheme,
+		Amount:    payment.Accepted.Amount,
+		Asset:     payment.Accepted.Asset,
+		Recipient: payment.Accepted.PayTo,
+		Duration:  duration,
+	})
 
 	return resp, nil
 }
+
+// ParsePaymentNotification decodes an x402 payment notification sent by the server.
+// Returns false if the notification is not a notifications/x402/payment message
+// or its params cannot be decoded.
+func ParsePaymentNotification(notif mcpproto.JSONRPCNotification) (*mcp.PaymentNotification, bool) {
+	if notif.Method != mcp.PaymentNotificationMethod {
+		return nil, false
+	}
+
+	data, err := json.Marshal(notif.Params.AdditionalFields)
+	if err != nil {
+		return nil, false
+	}
+
+	var notification mcp.PaymentNotification
+	if err := json.Unmarshal(data, &notification); err != nil {
+		return nil, false
+	}
+
+	return &notification, true
+}
+
+// ParseToolPrices decodes the prices a server lists for a tool in tools/list
+// results, so costs can be displayed before the tool is called. Returns false
+// if the tool lists no prices, as for free tools, or they cannot be decoded.
+func ParseToolPrices(tool mcpproto.Tool) ([]mcp.ToolPrice, bool) {
+	if tool.Meta == nil {
+		return nil, false
+	}
+	raw, ok := tool.Meta.AdditionalFields[mcp.ToolPriceMetaKey]
+	if !ok {
+		return nil, false
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, false
+	}
+
+	var prices []mcp.ToolPrice
+	if err := json.Unmarshal(data, &prices); err != nil || len(prices) == 0 {
+		return nil, false
+	}
+
+	return prices, true
+}
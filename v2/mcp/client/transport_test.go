@@ -1,9 +1,13 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"math/big"
 	"testing"
 
+	mcpproto "github.com/mark3labs/mcp-go/mcp"
 	v2 "github.com/mark3labs/x402-go/v2"
 	"github.com/mark3labs/x402-go/v2/mcp"
 )
@@ -306,3 +310,151 @@ func TestConfig_WithSelector(t *testing.T) {
 		t.Error("Expected custom selector to be set")
 	}
 }
+
+func TestParsePaymentNotification(t *testing.T) {
+	notif := mcpproto.JSONRPCNotification{
+		JSONRPC: mcpproto.JSONRPC_VERSION,
+		Notification: mcpproto.Notification{
+			Method: mcp.PaymentNotificationMethod,
+			Params: mcpproto.NotificationParams{
+				AdditionalFields: map[string]any{
+					"stage":       "settled",
+					"tool":        "search",
+					"network":     "eip155:84532",
+					"amount":      "10000",
+					"transaction": "0xabc",
+				},
+			},
+		},
+	}
+
+	notification, ok := ParsePaymentNotification(notif)
+	if !ok {
+		t.Fatal("expected notification to parse")
+	}
+	if notification.Stage != mcp.PaymentStageSettled {
+		t.Errorf("expected stage settled, got %s", notification.Stage)
+	}
+	if notification.Tool != "search" || notification.Amount != "10000" || notification.Transaction != "0xabc" {
+		t.Errorf("unexpected notification: %+v", notification)
+	}
+
+	notif.Method = "notifications/message"
+	if _, ok := ParsePaymentNotification(notif); ok {
+		t.Error("expected non-payment notification to be ignored")
+	}
+}
+
+func TestParseToolPrices(t *testing.T) {
+	tests := []struct {
+		name   string
+		tool   string
+		want   []mcp.ToolPrice
+		wantOK bool
+	}{
+		{
+			name: "paid tool",
+			tool: `{"name":"search","inputSchema":{"type":"object"},"_meta":{"x402/price":[{"scheme":"exact","network":"eip155:84532","amount":"10000","asset":"0xAsset","payTo":"0xPayTo","dynamic":true}]}}`,
+			want: []mcp.ToolPrice{
+				{Scheme: "exact", Network: "eip155:84532", Amount: "10000", Asset: "0xAsset", PayTo: "0xPayTo", Dynamic: true},
+			},
+			wantOK: true,
+		},
+		{
+			name: "free tool",
+			tool: `{"name":"echo","inputSchema":{"type":"object"}}`,
+		},
+		{
+			name: "other metadata",
+			tool: `{"name":"echo","inputSchema":{"type":"object"},"_meta":{"ui/icon":"echo.png"}}`,
+		},
+		{
+			name: "malformed prices",
+			tool: `{"name":"search","inputSchema":{"type":"object"},"_meta":{"x402/price":"0.01 USDC"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tool mcpproto.Tool
+			if err := json.Unmarshal([]byte(tt.tool), &tool); err != nil {
+				t.Fatalf("decoding tool failed: %v", err)
+			}
+			prices, ok := ParseToolPrices(tool)
+			if ok != tt.wantOK {
+				t.Fatalf("expected ok=%v, got %v", tt.wantOK, ok)
+			}
+			if len(prices) != len(tt.want) || (len(prices) > 0 && prices[0] != tt.want[0]) {
+				t.Errorf("expected prices %+v, got %+v", tt.want, prices)
+			}
+		})
+	}
+}
+
+// mockSigner implements v2.Signer for testing.
+type mockSigner struct {
+	network string
+}
+
+func (m *mockSigner) Network() string             { return m.network }
+func (m *mockSigner) Scheme() string              { return "exact" }
+func (m *mockSigner) GetPriority() int            { return 0 }
+func (m *mockSigner) GetTokens() []v2.TokenConfig { return nil }
+func (m *mockSigner) GetMaxAmount() *big.Int      { return nil }
+func (m *mockSigner) CanSign(req *v2.PaymentRequirements) bool {
+	return req.Network == m.network && req.Scheme == "exact"
+}
+func (m *mockSigner) Sign(req *v2.PaymentRequirements) (*v2.PaymentPayload, error) {
+	return &v2.PaymentPayload{
+		X402Version: v2.X402Version,
+		Accepted:    *req,
+		Payload:     map[string]interface{}{"signature": "0xmocksig"},
+	}, nil
+}
+
+func TestCreatePayment_SpendGuard(t *testing.T) {
+	guard := v2.NewSpendGuard(big.NewInt(15000))
+	config := DefaultConfig("http://example.com")
+	WithSigner(&mockSigner{network: "eip155:84532"})(config)
+	WithSpendGuard(guard)(config)
+	transport := &Transport{config: config}
+
+	requirements := []v2.PaymentRequirements{{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "10000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+	}}
+
+	if _, _, err := transport.createPayment(context.Background(), requirements, v2.ResourceInfo{}); err != nil {
+		t.Fatalf("first payment should succeed: %v", err)
+	}
+	if _, _, err := transport.createPayment(context.Background(), requirements, v2.ResourceInfo{}); !errors.Is(err, v2.ErrSpendLimitExceeded) {
+		t.Fatalf("expected ErrSpendLimitExceeded, got %v", err)
+	}
+}
+
+func TestPlanPayment_DryRun(t *testing.T) {
+	config := DefaultConfig("http://example.com")
+	WithSigner(&mockSigner{network: "eip155:84532"})(config)
+	WithDryRun()(config)
+	transport := &Transport{config: config}
+
+	requirements := []v2.PaymentRequirements{{
+		Scheme:  "exact",
+		Network: "eip155:84532",
+		Amount:  "10000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:   "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+	}}
+
+	err := transport.planPayment(requirements, v2.ResourceInfo{URL: "mcp://tools/search"})
+	plan, ok := v2.PaymentPlanFromError(err)
+	if !ok {
+		t.Fatalf("expected a payment plan, got %v", err)
+	}
+	if plan.Amount != "10000" || plan.Network != "eip155:84532" || plan.Resource == nil || plan.Resource.URL != "mcp://tools/search" {
+		t.Errorf("unexpected plan: %+v", plan)
+	}
+}
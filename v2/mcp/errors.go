@@ -43,6 +43,16 @@ var (
 
 	// ErrSettlementTimeout indicates that payment settlement took too long
 	ErrSettlementTimeout = errors.New("payment settlement timeout")
+
+	// ErrInvalidVoucher indicates a bundle voucher that is malformed, was not
+	// signed by the server, or does not cover the called tool
+	ErrInvalidVoucher = errors.New("invalid voucher")
+
+	// ErrVoucherExpired indicates a bundle voucher past its expiry
+	ErrVoucherExpired = errors.New("voucher expired")
+
+	// ErrVoucherExhausted indicates a bundle voucher with no calls left
+	ErrVoucherExhausted = errors.New("voucher exhausted")
 )
 
 // PaymentError wraps an x402 v2 error with MCP-specific context
@@ -70,6 +80,25 @@ func (e *PaymentError) Unwrap() error {
 	return e.Err
 }
 
+// PaymentUncertainError is returned when a paid request failed after it may
+// have reached the server, so its payment may have settled. The payment is not
+// resent or replaced: the caller decides whether to retry with it, which the
+// server settles at most once, or to check the payer's transactions first.
+type PaymentUncertainError struct {
+	// Payment is the payment sent with the request.
+	Payment *v2.PaymentPayload
+	Tool    string
+	Err     error
+}
+
+func (e *PaymentUncertainError) Error() string {
+	return fmt.Sprintf("payment outcome unknown for tool %s: %v", e.Tool, e.Err)
+}
+
+func (e *PaymentUncertainError) Unwrap() error {
+	return e.Err
+}
+
 // WrapX402Error wraps an x402 v2 error as a PaymentError
 func WrapX402Error(err error, tool string) error {
 	if err == nil {
@@ -87,12 +116,17 @@ func IsPaymentError(err error) bool {
 		return false
 	}
 	var paymentErr *PaymentError
+	var uncertainErr *PaymentUncertainError
 	return errors.As(err, &paymentErr) ||
+		errors.As(err, &uncertainErr) ||
 		// MCP-specific errors
 		errors.Is(err, ErrPaymentRequired) ||
 		errors.Is(err, ErrNoPaymentRequirements) ||
 		errors.Is(err, ErrVerificationTimeout) ||
 		errors.Is(err, ErrSettlementTimeout) ||
+		errors.Is(err, ErrInvalidVoucher) ||
+		errors.Is(err, ErrVoucherExpired) ||
+		errors.Is(err, ErrVoucherExhausted) ||
 		// Root v2 errors
 		errors.Is(err, v2.ErrNoValidSigner) ||
 		errors.Is(err, v2.ErrSigningFailed) ||
@@ -0,0 +1,324 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	mcpserver "github.com/mark3labs/mcp-go/server"
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/storage"
+)
+
+// SessionBill records the payments settled in one MCP session.
+type SessionBill struct {
+	// SessionID is the Mcp-Session-Id of the session. Calls made without a
+	// session are billed under the empty ID.
+	SessionID string
+
+	// Calls is the number of settled paid calls, per tool.
+	Calls map[string]int
+
+	// Spent is the amount settled per asset, in atomic units.
+	Spent map[string]*big.Int
+
+	// Transactions are the settlement transactions, oldest first.
+	Transactions []string
+
+	// LastPayment is when the most recent payment was settled.
+	LastPayment time.Time
+}
+
+// Total returns the amount settled, summed across assets.
+func (b SessionBill) Total() *big.Int {
+	total := new(big.Int)
+	for _, amount := range b.Spent {
+		total.Add(total, amount)
+	}
+	return total
+}
+
+// SessionBilling accounts for the payments settled in each MCP session and
+// can cap what a session spends. Install it on a Config to record settlements
+// and enforce the cap. Bills are kept in a storage.Store, so replicas sharing
+// one account for and cap sessions together. It is safe for concurrent use.
+type SessionBilling struct {
+	limit *big.Int
+	clock v2.Clock
+	store storage.Store
+}
+
+// sessionIndexKey is the store key listing the IDs of the billed sessions.
+const sessionIndexKey = "sessions"
+
+// SessionBillingOption configures a SessionBilling.
+type SessionBillingOption func(*SessionBilling)
+
+// WithSessionLimit caps the amount, summed across assets, a session may
+// spend. Calls that would exceed it are rejected when their payment is
+// verified. Concurrent calls of one session are checked independently, so
+// together they may exceed the cap by their amounts.
+func WithSessionLimit(limit *big.Int) SessionBillingOption {
+	return func(b *SessionBilling) {
+		b.limit = limit
+	}
+}
+
+// WithBillingClock sets the clock timestamping payments.
+func WithBillingClock(clock v2.Clock) SessionBillingOption {
+	return func(b *SessionBilling) {
+		b.clock = clock
+	}
+}
+
+// WithBillingStore keeps bills in store (default: storage.NewMemory()). Use a
+// storage.Prefixed view when the store is shared with other components.
+func WithBillingStore(store storage.Store) SessionBillingOption {
+	return func(b *SessionBilling) {
+		b.store = store
+	}
+}
+
+// NewSessionBilling creates a SessionBilling.
+func NewSessionBilling(opts ...SessionBillingOption) *SessionBilling {
+	b := &SessionBilling{
+		clock: v2.SystemClock,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.store == nil {
+		b.store = storage.NewMemory()
+	}
+	return b
+}
+
+// Install sets config's OnVerified and OnSettled hooks to enforce the session
+// limit and record settlements, calling the hooks already set first.
+func (b *SessionBilling) Install(config *Config) {
+	onVerified, onSettled := config.OnVerified, config.OnSettled
+	config.OnVerified = func(w http.ResponseWriter, r *http.Request, toolName string, payment v2.PaymentPayload, requirement v2.PaymentRequirements, resp *v2.VerifyResponse) error {
+		if onVerified != nil {
+			if err := onVerified(w, r, toolName, payment, requirement, resp); err != nil {
+				return err
+			}
+		}
+		return b.OnVerified(w, r, toolName, payment, requirement, resp)
+	}
+	config.OnSettled = func(w http.ResponseWriter, r *http.Request, toolName string, requirement v2.PaymentRequirements, resp *v2.SettleResponse) {
+		if onSettled != nil {
+			onSettled(w, r, toolName, requirement, resp)
+		}
+		b.OnSettled(w, r, toolName, requirement, resp)
+	}
+}
+
+// OnVerified is an OnVerifiedFunc rejecting payments that would take the
+// session past its limit. Payments are rejected while the store is
+// unavailable.
+func (b *SessionBilling) OnVerified(_ http.ResponseWriter, r *http.Request, _ string, _ v2.PaymentPayload, requirement v2.PaymentRequirements, _ *v2.VerifyResponse) error {
+	if b.limit == nil {
+		return nil
+	}
+	amount, ok := new(big.Int).SetString(requirement.Amount, 10)
+	if !ok {
+		return fmt.Errorf("invalid amount %q", requirement.Amount)
+	}
+
+	bill, err := b.load(r.Context(), sessionID(r))
+	if err != nil {
+		return fmt.Errorf("session billing unavailable: %w", err)
+	}
+	if amount.Add(amount, bill.Total()).Cmp(b.limit) > 0 {
+		return fmt.Errorf("session spend limit of %s exceeded", b.limit)
+	}
+	return nil
+}
+
+// OnSettled is an OnSettledFunc recording the settlement in the session's bill.
+// Failures to record it are logged.
+func (b *SessionBilling) OnSettled(_ http.ResponseWriter, r *http.Request, toolName string, requirement v2.PaymentRequirements, resp *v2.SettleResponse) {
+	amount, ok := new(big.Int).SetString(requirement.Amount, 10)
+	if !ok {
+		return
+	}
+	id := sessionID(r)
+	ctx := context.WithoutCancel(r.Context())
+
+	var created bool
+	_, err := storage.Update(ctx, b.store, billKey(id), 0, func(old []byte, found bool) ([]byte, error) {
+		bill := emptyBill(id)
+		if found {
+			if err := decodeBill(old, bill); err != nil {
+				return nil, err
+			}
+		}
+		created = !found
+
+		bill.Calls[toolName]++
+		key := assetKey(requirement.Network, requirement.Asset)
+		if bill.Spent[key] == nil {
+			bill.Spent[key] = new(big.Int)
+		}
+		bill.Spent[key].Add(bill.Spent[key], amount)
+		if resp != nil && resp.Transaction != "" {
+			bill.Transactions = append(bill.Transactions, resp.Transaction)
+		}
+		bill.LastPayment = b.clock.Now()
+		return json.Marshal(bill)
+	})
+	if err == nil && created {
+		err = b.updateIndex(ctx, func(ids []string) []string {
+			if slices.Contains(ids, id) {
+				return ids
+			}
+			return append(ids, id)
+		})
+	}
+	if err != nil {
+		slog.Default().Error("failed to record session payment", "session", id, "tool", toolName, "error", err)
+	}
+}
+
+// Session returns the bill of the session with id. If the store is
+// unavailable, the error is logged and an empty bill returned.
+func (b *SessionBilling) Session(id string) SessionBill {
+	bill, err := b.load(context.Background(), id)
+	if err != nil {
+		slog.Default().Error("failed to load session bill", "session", id, "error", err)
+		return *emptyBill(id)
+	}
+	return *bill
+}
+
+// Sessions returns the bills of every session with a settled payment, sorted
+// by session ID.
+func (b *SessionBilling) Sessions() []SessionBill {
+	ctx := context.Background()
+	ids, err := b.index(ctx)
+	if err != nil {
+		slog.Default().Error("failed to list session bills", "error", err)
+		return nil
+	}
+	bills := make([]SessionBill, 0, len(ids))
+	for _, id := range ids {
+		bill, err := b.load(ctx, id)
+		if err != nil {
+			slog.Default().Error("failed to load session bill", "session", id, "error", err)
+			continue
+		}
+		bills = append(bills, *bill)
+	}
+	sort.Slice(bills, func(i, j int) bool { return bills[i].SessionID < bills[j].SessionID })
+	return bills
+}
+
+// Close removes the bill of the session with id and returns it, e.g. when the
+// session ends and its bill is invoiced.
+func (b *SessionBilling) Close(id string) SessionBill {
+	ctx := context.Background()
+	bill, err := b.load(ctx, id)
+	if err == nil {
+		err = b.store.Delete(ctx, billKey(id))
+	}
+	if err == nil {
+		err = b.updateIndex(ctx, func(ids []string) []string {
+			return slices.DeleteFunc(ids, func(other string) bool { return other == id })
+		})
+	}
+	if err != nil {
+		slog.Default().Error("failed to close session bill", "session", id, "error", err)
+	}
+	if bill == nil {
+		return *emptyBill(id)
+	}
+	return *bill
+}
+
+// load returns the bill of the session with id, empty if it has none.
+func (b *SessionBilling) load(ctx context.Context, id string) (*SessionBill, error) {
+	bill := emptyBill(id)
+	raw, err := b.store.Get(ctx, billKey(id))
+	if errors.Is(err, storage.ErrNotFound) {
+		return bill, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeBill(raw, bill); err != nil {
+		return nil, err
+	}
+	return bill, nil
+}
+
+// index returns the IDs of the billed sessions.
+func (b *SessionBilling) index(ctx context.Context) ([]string, error) {
+	raw, err := b.store.Get(ctx, sessionIndexKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	if err := json.Unmarshal(raw, &ids); err != nil {
+		return nil, fmt.Errorf("decoding session index: %w", err)
+	}
+	return ids, nil
+}
+
+// updateIndex atomically replaces the IDs of the billed sessions with the
+// result of fn.
+func (b *SessionBilling) updateIndex(ctx context.Context, fn func(ids []string) []string) error {
+	_, err := storage.Update(ctx, b.store, sessionIndexKey, 0, func(old []byte, found bool) ([]byte, error) {
+		var ids []string
+		if found {
+			if err := json.Unmarshal(old, &ids); err != nil {
+				return nil, fmt.Errorf("decoding session index: %w", err)
+			}
+		}
+		return json.Marshal(fn(ids))
+	})
+	return err
+}
+
+// billKey is the store key of the bill of the session with id.
+func billKey(id string) string {
+	return "bill:" + id
+}
+
+// emptyBill returns a bill without payments for the session with id.
+func emptyBill(id string) *SessionBill {
+	return &SessionBill{SessionID: id, Calls: map[string]int{}, Spent: map[string]*big.Int{}}
+}
+
+// decodeBill decodes a stored bill into bill, keeping its maps non-nil.
+func decodeBill(raw []byte, bill *SessionBill) error {
+	if err := json.Unmarshal(raw, bill); err != nil {
+		return fmt.Errorf("decoding session bill: %w", err)
+	}
+	if bill.Calls == nil {
+		bill.Calls = map[string]int{}
+	}
+	if bill.Spent == nil {
+		bill.Spent = map[string]*big.Int{}
+	}
+	return nil
+}
+
+// sessionID returns the MCP session of r.
+func sessionID(r *http.Request) string {
+	return r.Header.Get(mcpserver.HeaderKeySessionID)
+}
+
+// assetKey identifies an asset across networks.
+func assetKey(network, asset string) string {
+	return network + "/" + asset
+}
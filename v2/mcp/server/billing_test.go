@@ -0,0 +1,86 @@
+package server
+
+import (
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	mcpserver "github.com/mark3labs/mcp-go/server"
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/storage"
+)
+
+func TestSessionBilling(t *testing.T) {
+	billing := NewSessionBilling(WithSessionLimit(big.NewInt(2500)))
+	config := &Config{}
+	billing.Install(config)
+
+	requirement := v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: "1000", Asset: "0xAsset", PayTo: "0xPayTo"}
+	call := func(session, tool string) error {
+		r := httptest.NewRequest("POST", "/mcp", nil)
+		r.Header.Set(mcpserver.HeaderKeySessionID, session)
+		w := httptest.NewRecorder()
+		if err := config.OnVerified(w, r, tool, v2.PaymentPayload{}, requirement, &v2.VerifyResponse{IsValid: true}); err != nil {
+			return err
+		}
+		config.OnSettled(w, r, tool, requirement, &v2.SettleResponse{Success: true, Transaction: "0x" + tool})
+		return nil
+	}
+
+	for _, tool := range []string{"search", "search"} {
+		if err := call("a", tool); err != nil {
+			t.Fatalf("call failed: %v", err)
+		}
+	}
+	if err := call("b", "fetch"); err != nil {
+		t.Fatalf("call in other session failed: %v", err)
+	}
+	// The third call of session a would take it to 3000
+	if err := call("a", "fetch"); err == nil {
+		t.Error("Expected session limit error")
+	}
+
+	bill := billing.Session("a")
+	if bill.Calls["search"] != 2 || bill.Calls["fetch"] != 0 || bill.Total().Cmp(big.NewInt(2000)) != 0 || len(bill.Transactions) != 2 {
+		t.Errorf("unexpected bill %+v", bill)
+	}
+	if bills := billing.Sessions(); len(bills) != 2 || bills[1].SessionID != "b" {
+		t.Errorf("unexpected bills %+v", bills)
+	}
+	if closed := billing.Close("a"); closed.Total().Cmp(big.NewInt(2000)) != 0 {
+		t.Errorf("unexpected closed bill %+v", closed)
+	}
+	if err := call("a", "fetch"); err != nil {
+		t.Errorf("call after close failed: %v", err)
+	}
+}
+
+func TestSessionBilling_SharedStore(t *testing.T) {
+	// Two replicas sharing a store cap a session together
+	store := storage.Prefixed(storage.NewMemory(), "billing:")
+	replicas := []*SessionBilling{
+		NewSessionBilling(WithSessionLimit(big.NewInt(1500)), WithBillingStore(store)),
+		NewSessionBilling(WithSessionLimit(big.NewInt(1500)), WithBillingStore(store)),
+	}
+
+	requirement := v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: "1000", Asset: "0xAsset", PayTo: "0xPayTo"}
+	r := httptest.NewRequest("POST", "/mcp", nil)
+	r.Header.Set(mcpserver.HeaderKeySessionID, "a")
+	w := httptest.NewRecorder()
+
+	if err := replicas[0].OnVerified(w, r, "search", v2.PaymentPayload{}, requirement, &v2.VerifyResponse{IsValid: true}); err != nil {
+		t.Fatalf("OnVerified failed: %v", err)
+	}
+	replicas[0].OnSettled(w, r, "search", requirement, &v2.SettleResponse{Success: true, Transaction: "0x1"})
+
+	if err := replicas[1].OnVerified(w, r, "search", v2.PaymentPayload{}, requirement, &v2.VerifyResponse{IsValid: true}); err == nil {
+		t.Error("expected the other replica to enforce the session limit")
+	}
+	if bills := replicas[1].Sessions(); len(bills) != 1 || bills[0].Calls["search"] != 1 || bills[0].Transactions[0] != "0x1" {
+		t.Errorf("unexpected bills %+v", bills)
+	}
+	replicas[1].Close("a")
+	if bills := replicas[0].Sessions(); len(bills) != 0 {
+		t.Errorf("expected the closed bill to be gone on every replica, got %+v", bills)
+	}
+}
@@ -0,0 +1,353 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Default body size limits, used when the Config limits are zero.
+const (
+	// DefaultMaxBodySize is the default limit on request bodies.
+	DefaultMaxBodySize = 4 << 20
+
+	// DefaultMaxResponseSize is the default limit on buffered tool responses.
+	DefaultMaxResponseSize = 64 << 20
+)
+
+// errBodyTooLarge is returned by bodyBuffer writes past its limit.
+var errBodyTooLarge = errors.New("body too large")
+
+// sizeLimit resolves a Config size limit: zero selects def, negative disables it.
+func sizeLimit(limit, def int64) int64 {
+	switch {
+	case limit == 0:
+		return def
+	case limit < 0:
+		return 0
+	default:
+		return limit
+	}
+}
+
+// readLimited reads r fully, failing with errBodyTooLarge past limit bytes.
+// A limit of zero reads without limit.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errBodyTooLarge
+	}
+	return data, nil
+}
+
+// bodyBuffer accumulates a response body. It keeps the body in memory until it
+// grows past spillThreshold, then moves it to a temporary file in spillDir.
+// Writes past limit fail with errBodyTooLarge. Zero disables either bound.
+// Close removes the temporary file.
+type bodyBuffer struct {
+	limit          int64
+	spillThreshold int64
+	spillDir       string
+
+	mem      bytes.Buffer
+	file     *os.File
+	size     int64
+	overflow bool
+}
+
+func (b *bodyBuffer) Write(p []byte) (int, error) {
+	if b.limit > 0 && b.size+int64(len(p)) > b.limit {
+		b.overflow = true
+		return 0, errBodyTooLarge
+	}
+
+	if b.file == nil && b.spillThreshold > 0 && int64(b.mem.Len()+len(p)) > b.spillThreshold {
+		if err := b.spill(); err != nil {
+			return 0, err
+		}
+	}
+
+	var n int
+	var err error
+	if b.file != nil {
+		n, err = b.file.Write(p)
+	} else {
+		n, err = b.mem.Write(p)
+	}
+	b.size += int64(n)
+	return n, err
+}
+
+// spill moves the buffered body to a temporary file.
+func (b *bodyBuffer) spill() error {
+	file, err := os.CreateTemp(b.spillDir, "x402-mcp-body-*")
+	if err != nil {
+		return fmt.Errorf("spilling body to disk: %w", err)
+	}
+	if _, err := file.Write(b.mem.Bytes()); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Errorf("spilling body to disk: %w", err)
+	}
+	b.file = file
+	b.mem = bytes.Buffer{}
+	return nil
+}
+
+// Len returns the number of bytes written.
+func (b *bodyBuffer) Len() int64 {
+	return b.size
+}
+
+// Overflowed reports whether a write was refused for exceeding the limit.
+func (b *bodyBuffer) Overflowed() bool {
+	return b.overflow
+}
+
+// Spilled reports whether the body was moved to disk.
+func (b *bodyBuffer) Spilled() bool {
+	return b.file != nil
+}
+
+// ReaderAt returns random access to the body written so far.
+func (b *bodyBuffer) ReaderAt() io.ReaderAt {
+	if b.file != nil {
+		return b.file
+	}
+	return bytes.NewReader(b.mem.Bytes())
+}
+
+// Bytes returns the body in memory, reading it back if it was spilled.
+func (b *bodyBuffer) Bytes() ([]byte, error) {
+	if b.file == nil {
+		return b.mem.Bytes(), nil
+	}
+	data := make([]byte, b.size)
+	_, err := b.file.ReadAt(data, 0)
+	if err == io.EOF {
+		err = nil
+	}
+	return data, err
+}
+
+// WriteTo copies the body to w.
+func (b *bodyBuffer) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, io.NewSectionReader(b.ReaderAt(), 0, b.size))
+}
+
+// Close releases the temporary file, if any.
+func (b *bodyBuffer) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	name := b.file.Name()
+	err := b.file.Close()
+	if removeErr := os.Remove(name); err == nil {
+		err = removeErr
+	}
+	b.file = nil
+	return err
+}
+
+// responseShape locates the parts of a JSON-RPC response that settlement
+// inspects and rewrites, so the rest can be copied through without decoding.
+type responseShape struct {
+	// IsError is true if the response has a non-null error.
+	IsError bool
+
+	// ResultObject is true if the result is a JSON object; the offsets below
+	// are only meaningful then.
+	ResultObject bool
+
+	// ResultStart is the offset just past the opening brace of the result.
+	ResultStart int64
+
+	// ResultEmpty is true if the result object has no members.
+	ResultEmpty bool
+
+	// HasMeta is true if the result has a _meta member.
+	HasMeta bool
+
+	// Meta is the decoded result._meta, or nil if it is absent or not an object.
+	Meta map[string]interface{}
+
+	// MetaKeyEnd and MetaEnd delimit the colon and value of result._meta, so
+	// that [MetaKeyEnd, MetaEnd) can be replaced with ":" and a new value.
+	MetaKeyEnd, MetaEnd int64
+}
+
+// inspectResponse walks a JSON-RPC response object token by token. Member
+// values other than result._meta are skipped without being held in memory as a
+// whole, apart from individual string and number tokens.
+func inspectResponse(r io.Reader) (responseShape, error) {
+	var shape responseShape
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return shape, err
+	}
+	for dec.More() {
+		key, err := objectKey(dec)
+		if err != nil {
+			return shape, err
+		}
+
+		switch key {
+		case "error":
+			tok, err := dec.Token()
+			if err != nil {
+				return shape, err
+			}
+			shape.IsError = tok != nil
+			if err := skipRest(dec, tok); err != nil {
+				return shape, err
+			}
+		case "result":
+			tok, err := dec.Token()
+			if err != nil {
+				return shape, err
+			}
+			if tok != json.Delim('{') {
+				if err := skipRest(dec, tok); err != nil {
+					return shape, err
+				}
+				continue
+			}
+			shape.ResultObject = true
+			shape.ResultStart = dec.InputOffset()
+			shape.ResultEmpty = !dec.More()
+			if err := inspectResult(dec, &shape); err != nil {
+				return shape, err
+			}
+		default:
+			if err := skipValue(dec); err != nil {
+				return shape, err
+			}
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return shape, err
+	}
+	return shape, nil
+}
+
+// inspectResult walks the members of the result object up to its closing brace.
+func inspectResult(dec *json.Decoder, shape *responseShape) error {
+	for dec.More() {
+		key, err := objectKey(dec)
+		if err != nil {
+			return err
+		}
+		if key != "_meta" {
+			if err := skipValue(dec); err != nil {
+				return err
+			}
+			continue
+		}
+
+		shape.HasMeta = true
+		shape.MetaKeyEnd = dec.InputOffset()
+		var meta interface{}
+		if err := dec.Decode(&meta); err != nil {
+			return err
+		}
+		shape.Meta, _ = meta.(map[string]interface{})
+		shape.MetaEnd = dec.InputOffset()
+	}
+	return expectDelim(dec, '}')
+}
+
+func objectKey(dec *json.Decoder) (string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("expected object key, got %v", tok)
+	}
+	return key, nil
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("expected %v, got %v", delim, tok)
+	}
+	return nil
+}
+
+// skipValue skips the next value.
+func skipValue(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	return skipRest(dec, tok)
+}
+
+// skipRest skips the remainder of a value whose first token was tok.
+func skipRest(dec *json.Decoder, tok json.Token) error {
+	depth := 0
+	for {
+		if delim, ok := tok.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				depth++
+			default:
+				depth--
+			}
+		}
+		if depth == 0 {
+			return nil
+		}
+		var err error
+		if tok, err = dec.Token(); err != nil {
+			return err
+		}
+	}
+}
+
+// writeWithMeta writes the buffered response with meta as the result's _meta.
+func writeWithMeta(w io.Writer, body *bodyBuffer, shape responseShape, meta map[string]interface{}) error {
+	encoded, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	src := body.ReaderAt()
+
+	var insertAt, resumeAt int64
+	var insert []byte
+	if shape.HasMeta {
+		insertAt, resumeAt = shape.MetaKeyEnd, shape.MetaEnd
+		insert = append([]byte(":"), encoded...)
+	} else {
+		insertAt, resumeAt = shape.ResultStart, shape.ResultStart
+		insert = append([]byte(`"_meta":`), encoded...)
+		if !shape.ResultEmpty {
+			insert = append(insert, ',')
+		}
+	}
+
+	if _, err := io.Copy(w, io.NewSectionReader(src, 0, insertAt)); err != nil {
+		return err
+	}
+	if _, err := w.Write(insert); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, io.NewSectionReader(src, resumeAt, body.Len()-resumeAt))
+	return err
+}
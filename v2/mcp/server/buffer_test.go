@@ -0,0 +1,192 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func TestWriteWithMeta(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   string
+		wantError  bool
+		wantObject bool
+		want       string
+	}{
+		{
+			name:       "no meta",
+			response:   `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"hi"}]}}`,
+			wantObject: true,
+			want:       `{"jsonrpc":"2.0","id":1,"result":{"_meta":{"paid":true},"content":[{"type":"text","text":"hi"}]}}`,
+		},
+		{
+			name:       "empty result",
+			response:   `{"jsonrpc":"2.0","id":1,"result":{}}`,
+			wantObject: true,
+			want:       `{"jsonrpc":"2.0","id":1,"result":{"_meta":{"paid":true}}}`,
+		},
+		{
+			name:       "existing meta is merged",
+			response:   `{"result": {"content": [], "_meta" : {"trace": 7}}, "id": 1, "jsonrpc": "2.0"}`,
+			wantObject: true,
+			want:       `{"result": {"content": [], "_meta":{"paid":true,"trace":7}}, "id": 1, "jsonrpc": "2.0"}`,
+		},
+		{
+			name:       "null meta is replaced",
+			response:   `{"jsonrpc":"2.0","id":1,"result":{"_meta":null}}`,
+			wantObject: true,
+			want:       `{"jsonrpc":"2.0","id":1,"result":{"_meta":{"paid":true}}}`,
+		},
+		{
+			name:      "error",
+			response:  `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"boom"}}`,
+			wantError: true,
+		},
+		{
+			name:       "null error",
+			response:   `{"jsonrpc":"2.0","id":1,"error":null,"result":{}}`,
+			wantObject: true,
+			want:       `{"jsonrpc":"2.0","id":1,"error":null,"result":{"_meta":{"paid":true}}}`,
+		},
+		{
+			name:     "non-object result",
+			response: `{"jsonrpc":"2.0","id":1,"result":[1,2]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, spill := range []bool{false, true} {
+			t.Run(tt.name, func(t *testing.T) {
+				body := &bodyBuffer{spillDir: t.TempDir()}
+				if spill {
+					body.spillThreshold = 8
+				}
+				defer body.Close()
+				_, _ = body.Write([]byte(tt.response))
+				if body.Spilled() != spill {
+					t.Fatalf("Expected spilled=%v", spill)
+				}
+
+				shape, err := inspectResponse(strings.NewReader(tt.response))
+				if err != nil {
+					t.Fatalf("inspectResponse failed: %v", err)
+				}
+				if shape.IsError != tt.wantError || shape.ResultObject != tt.wantObject {
+					t.Fatalf("Expected error=%v object=%v, got %+v", tt.wantError, tt.wantObject, shape)
+				}
+				if !shape.ResultObject {
+					return
+				}
+
+				meta := shape.Meta
+				if meta == nil {
+					meta = make(map[string]interface{})
+				}
+				meta["paid"] = true
+				var out bytes.Buffer
+				if err := writeWithMeta(&out, body, shape, meta); err != nil {
+					t.Fatalf("writeWithMeta failed: %v", err)
+				}
+				if out.String() != tt.want {
+					t.Errorf("Expected %s, got %s", tt.want, out.String())
+				}
+			})
+		}
+	}
+}
+
+func TestInspectResponse_Malformed(t *testing.T) {
+	for _, response := range []string{``, `[]`, `{"result":{"a":}}`, `{"result":{}`, `not json`} {
+		if _, err := inspectResponse(strings.NewReader(response)); err == nil {
+			t.Errorf("Expected error for %q", response)
+		}
+	}
+}
+
+func TestHandler_BodyLimits(t *testing.T) {
+	requirement := v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: "10000", Asset: "0xAsset", PayTo: "0xPayTo"}
+	payment, _ := json.Marshal(v2.PaymentPayload{X402Version: 2, Accepted: requirement, Payload: map[string]interface{}{"signature": "0xsig"}})
+	paidCall := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"paid_tool","_meta":{"x402/payment":` + string(payment) + `}}}`
+	largeResult := `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"` + strings.Repeat("x", 4096) + `"}]}}`
+
+	tests := []struct {
+		name            string
+		body            string
+		maxBodySize     int64
+		maxResponseSize int64
+		spillThreshold  int64
+		wantCode        int
+		wantSettled     bool
+	}{
+		{name: "request within limit", body: paidCall, maxBodySize: int64(len(paidCall)), wantSettled: true},
+		{name: "request too large", body: paidCall, maxBodySize: int64(len(paidCall)) - 1, wantCode: ErrorCodeInvalidRequest},
+		{name: "response too large", body: paidCall, maxResponseSize: 1024, wantCode: ErrorCodeInternal},
+		{name: "response spilled to disk", body: paidCall, spillThreshold: 1024, wantSettled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spillDir := t.TempDir()
+			mock := &mockFacilitator{
+				verifyResponse: &v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"},
+				settleResponse: &v2.SettleResponse{Success: true, Transaction: "0xtx"},
+			}
+			var spilled []os.DirEntry
+			mcpHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(largeResult[:100]))
+				_, _ = w.Write([]byte(largeResult[100:]))
+				spilled, _ = os.ReadDir(spillDir)
+			})
+			handler := &X402Handler{
+				mcpHandler:  mcpHandler,
+				facilitator: mock,
+				config: &Config{
+					PaymentTools: map[string]ToolPaymentConfig{
+						"paid_tool": {Resource: v2.ResourceInfo{URL: "mcp://tools/paid_tool"}, Requirements: []v2.PaymentRequirements{requirement}},
+					},
+					MaxBodySize:     tt.maxBodySize,
+					MaxResponseSize: tt.maxResponseSize,
+					SpillThreshold:  tt.spillThreshold,
+					SpillDir:        spillDir,
+				},
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("POST", "/mcp", strings.NewReader(tt.body)))
+
+			var resp struct {
+				Error  *rpcError `json:"error"`
+				Result struct {
+					Meta map[string]v2.SettleResponse `json:"_meta"`
+				} `json:"result"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if tt.wantCode != 0 && (resp.Error == nil || resp.Error.Code != tt.wantCode) {
+				t.Errorf("Expected error code %d, got %+v", tt.wantCode, resp.Error)
+			}
+			if mock.settleCalled != tt.wantSettled {
+				t.Errorf("Expected settled=%v, got %v", tt.wantSettled, mock.settleCalled)
+			}
+			if tt.wantSettled && resp.Result.Meta["x402/payment-response"].Transaction != "0xtx" {
+				t.Errorf("Expected payment response in result._meta, got %s", w.Body.String())
+			}
+
+			if wantSpilled := tt.spillThreshold > 0; (len(spilled) == 1) != wantSpilled {
+				t.Errorf("Expected spilled=%v, got %d files", wantSpilled, len(spilled))
+			}
+			if remaining, _ := os.ReadDir(spillDir); len(remaining) != 0 {
+				t.Errorf("Expected spilled files to be removed, found %d", len(remaining))
+			}
+		})
+	}
+}
@@ -2,12 +2,40 @@
 package server
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"strings"
 
 	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/extensions"
 	v2http "github.com/mark3labs/x402-go/v2/http"
+	"github.com/mark3labs/x402-go/v2/mcp"
 )
 
+// PaymentNotifier receives payment lifecycle notifications for paid tool calls.
+// sessionID is the MCP session of the calling client, or empty if the request
+// did not carry one.
+type PaymentNotifier func(ctx context.Context, sessionID string, notification mcp.PaymentNotification)
+
+// RequestMutator is called for every tools/call before it is handled. It may
+// return a modified request, for example with extra headers or a derived context,
+// or an error to reject the call. Returning a nil request keeps the original.
+type RequestMutator func(r *http.Request, toolName string) (*http.Request, error)
+
+// OnPaymentRequiredFunc is called when a paid tool is called without a payment,
+// before the payment required error is written.
+type OnPaymentRequiredFunc func(w http.ResponseWriter, r *http.Request, toolName string, config ToolPaymentConfig)
+
+// OnVerifiedFunc is called after a payment has been verified and before the tool
+// runs. Returning an error rejects the call without settling the payment.
+type OnVerifiedFunc func(w http.ResponseWriter, r *http.Request, toolName string, payment v2.PaymentPayload, requirement v2.PaymentRequirements, resp *v2.VerifyResponse) error
+
+// OnSettledFunc is called after a payment has been settled, before the tool
+// result is written. Headers set on w are sent with the response.
+type OnSettledFunc func(w http.ResponseWriter, r *http.Request, toolName string, requirement v2.PaymentRequirements, resp *v2.SettleResponse)
+
 // ToolPaymentConfig holds payment configuration for a specific MCP tool.
 type ToolPaymentConfig struct {
 	// Resource describes the protected resource.
@@ -15,6 +43,10 @@ type ToolPaymentConfig struct {
 
 	// Requirements is the list of acceptable payment options.
 	Requirements []v2.PaymentRequirements
+
+	// RequirementsFunc, if set, prices each call from its arguments, starting
+	// from Requirements. Payments must then match the price of the call.
+	RequirementsFunc RequirementsFunc
 }
 
 // Config holds configuration for the MCP server with x402 v2 payment support.
@@ -58,6 +90,68 @@ type Config struct {
 	FallbackFacilitatorOnBeforeSettle        v2http.OnBeforeFunc
 	FallbackFacilitatorOnAfterSettle         v2http.OnAfterSettleFunc
 
+	// PaymentNotifications enables notifications/x402/payment messages to the calling
+	// MCP session when payments are required, verified, settled, or fail.
+	// Only takes effect when the handler is created via X402Server.
+	PaymentNotifications bool
+
+	// PaymentNotifier is an optional custom receiver for payment lifecycle notifications.
+	// If set, it is used instead of the session notifier installed by PaymentNotifications.
+	PaymentNotifier PaymentNotifier
+
+	// RequestMutator is an optional hook applied to every tools/call request.
+	RequestMutator RequestMutator
+
+	// Payment lifecycle hooks for paid tool calls
+	OnPaymentRequired OnPaymentRequiredFunc
+	OnVerified        OnVerifiedFunc
+	OnSettled         OnSettledFunc
+
+	// Allowlist restricts the schemes, networks and assets of accepted
+	// payments for the whole deployment. Tools with requirements outside it
+	// are refused when the handler is created or the tool is added, and
+	// payments matching only such requirements are refused.
+	Allowlist v2.PaymentAllowlist
+
+	// Bundles offers bundles of prepaid calls of paid tools, in the payment
+	// required errors of their tools. Buyers pay once and redeem the other
+	// calls with a voucher issued by Vouchers, which must be set.
+	Bundles []Bundle
+
+	// Vouchers issues and redeems the vouchers of Bundles.
+	Vouchers *VoucherIssuer
+
+	// Extensions, if set, advertises the extensions that define info in every
+	// payment required error and validates the extensions of incoming
+	// payments against their registered schemas.
+	Extensions *extensions.Registry
+
+	// Timeouts bounds payment verification and settlement (default: v2.DefaultTimeouts).
+	Timeouts v2.TimeoutConfig
+
+	// NetworkTimeouts overrides Timeouts for payments on specific networks or
+	// namespaces, e.g. a longer SettleTimeout for "solana".
+	NetworkTimeouts v2.NetworkTimeouts
+
+	// MaxBodySize limits request bodies, in bytes. Larger requests are rejected
+	// with an Invalid Request error. Zero means DefaultMaxBodySize; negative
+	// means no limit.
+	MaxBodySize int64
+
+	// MaxResponseSize limits paid tool responses, in bytes, which are buffered
+	// until the payment is settled. A larger response is replaced with an error
+	// and the payment is not settled. Zero means DefaultMaxResponseSize;
+	// negative means no limit.
+	MaxResponseSize int64
+
+	// SpillThreshold, if positive, moves buffered paid tool responses larger
+	// than this many bytes from memory to a temporary file, removed once the
+	// response is sent.
+	SpillThreshold int64
+
+	// SpillDir is the directory for spilled responses. Empty means os.TempDir().
+	SpillDir string
+
 	// Logger is the logger for the server.
 	// If not set, slog.Default() is used.
 	Logger *slog.Logger
@@ -74,6 +168,67 @@ func DefaultConfig() *Config {
 	}
 }
 
+// timeouts returns the timeouts for payments on network.
+func (c *Config) timeouts(network string) v2.TimeoutConfig {
+	return c.NetworkTimeouts.Resolve(v2.TimeoutsOrDefault(c.Timeouts), network)
+}
+
+// checkAllowlist validates Allowlist and the requirements of every tool
+// against it.
+func (c *Config) checkAllowlist() error {
+	if err := c.Allowlist.Validate(); err != nil {
+		return err
+	}
+	for name, tool := range c.PaymentTools {
+		for _, requirement := range tool.Requirements {
+			if err := c.Allowlist.Check(requirement); err != nil {
+				return fmt.Errorf("requirement for tool %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkBundles validates Bundles against the paid tools.
+func (c *Config) checkBundles() error {
+	if len(c.Bundles) > 0 && c.Vouchers == nil {
+		return fmt.Errorf("x402: bundles require a voucher issuer")
+	}
+	for i, bundle := range c.Bundles {
+		if bundle.Calls <= 0 || len(bundle.Tools) == 0 || len(bundle.Requirements) == 0 {
+			return fmt.Errorf("bundle %d: tools, calls and requirements are required", i)
+		}
+		for _, requirement := range bundle.Requirements {
+			if err := c.Allowlist.Check(requirement); err != nil {
+				return fmt.Errorf("requirement for bundle %d: %w", i, err)
+			}
+		}
+		for _, name := range bundle.Tools {
+			tool, ok := c.PaymentTools[name]
+			if !ok || len(tool.Requirements) == 0 {
+				return fmt.Errorf("bundle %d: %s is not a paid tool", i, name)
+			}
+			if tool.RequirementsFunc != nil {
+				return fmt.Errorf("bundle %d: %s is priced by its arguments and cannot be bundled", i, name)
+			}
+			for _, requirement := range bundle.Requirements {
+				for _, call := range tool.Requirements {
+					if samePrice(requirement, call) {
+						return fmt.Errorf("bundle %d: price equals a call of %s", i, name)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// samePrice reports whether a and b request the same payment.
+func samePrice(a, b v2.PaymentRequirements) bool {
+	return a.Scheme == b.Scheme && a.Network == b.Network && a.Amount == b.Amount &&
+		strings.EqualFold(a.Asset, b.Asset) && strings.EqualFold(a.PayTo, b.PayTo)
+}
+
 // AddPaymentTool adds payment requirements for a tool.
 func (c *Config) AddPaymentTool(toolName string, resource v2.ResourceInfo, requirements ...v2.PaymentRequirements) {
 	if c.PaymentTools == nil {
@@ -2,10 +2,12 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	nethttp "net/http"
 
 	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/encoding"
 	v2http "github.com/mark3labs/x402-go/v2/http"
 )
 
@@ -47,6 +49,44 @@ func WithAuthorizationProvider(provider v2http.AuthorizationProvider) HTTPFacili
 	}
 }
 
+// WithTLSConfig enables mutual TLS with the facilitator (see v2http.LoadClientTLSConfig).
+func WithTLSConfig(config *tls.Config) HTTPFacilitatorOption {
+	return func(c *v2http.FacilitatorClient) {
+		c.TLSConfig = config
+	}
+}
+
+// WithRequestSigner signs verify and settle requests with HTTP Message Signatures (RFC 9421).
+func WithRequestSigner(signer *v2http.RequestSigner) HTTPFacilitatorOption {
+	return func(c *v2http.FacilitatorClient) {
+		c.RequestSigner = signer
+	}
+}
+
+// WithCodec sets the JSON codec of facilitator requests and responses (see
+// encoding.Codec).
+func WithCodec(codec encoding.Codec) HTTPFacilitatorOption {
+	return func(c *v2http.FacilitatorClient) {
+		c.Codec = codec
+	}
+}
+
+// WithTimeouts sets the timeouts for facilitator operations. The zero value
+// selects v2.DefaultTimeouts.
+func WithTimeouts(timeouts v2.TimeoutConfig) HTTPFacilitatorOption {
+	return func(c *v2http.FacilitatorClient) {
+		c.Timeouts = v2.TimeoutsOrDefault(timeouts)
+	}
+}
+
+// WithNetworkTimeouts overrides the timeouts for payments on specific networks
+// or namespaces.
+func WithNetworkTimeouts(timeouts v2.NetworkTimeouts) HTTPFacilitatorOption {
+	return func(c *v2http.FacilitatorClient) {
+		c.NetworkTimeouts = timeouts
+	}
+}
+
 // WithOnBeforeVerify sets a hook function to be called before verifying a payment.
 func WithOnBeforeVerify(f v2http.OnBeforeFunc) HTTPFacilitatorOption {
 	return func(c *v2http.FacilitatorClient) {
@@ -96,6 +136,7 @@ func NewHTTPFacilitator(facilitatorURL string, opts ...HTTPFacilitatorOption) *H
 	for _, opt := range opts {
 		opt(client)
 	}
+	client.Client.Timeout = client.NetworkTimeouts.Longest(client.Timeouts)
 
 	return &HTTPFacilitator{
 		client: client,
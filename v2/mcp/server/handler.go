@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 
+	mcpserver "github.com/mark3labs/mcp-go/server"
 	v2 "github.com/mark3labs/x402-go/v2"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+	"github.com/mark3labs/x402-go/v2/mcp"
 )
 
 // X402Handler wraps an MCP HTTP handler and adds x402 v2 payment verification.
@@ -18,6 +22,8 @@ type X402Handler struct {
 	config              *Config
 	facilitator         Facilitator
 	fallbackFacilitator Facilitator
+	notifier            PaymentNotifier
+	drainer             *v2http.Drainer
 }
 
 // NewX402Handler creates a new x402 v2 payment handler.
@@ -25,6 +31,12 @@ func NewX402Handler(mcpHandler http.Handler, config *Config) (*X402Handler, erro
 	if config == nil {
 		config = DefaultConfig()
 	}
+	if err := config.checkAllowlist(); err != nil {
+		return nil, err
+	}
+	if err := config.checkBundles(); err != nil {
+		return nil, err
+	}
 
 	facilitator, fallbackFacilitator, err := initializeFacilitators(config)
 	if err != nil {
@@ -36,17 +48,31 @@ func NewX402Handler(mcpHandler http.Handler, config *Config) (*X402Handler, erro
 		config:              config,
 		facilitator:         facilitator,
 		fallbackFacilitator: fallbackFacilitator,
+		notifier:            config.PaymentNotifier,
+		drainer:             v2http.NewDrainer(),
 	}, nil
 }
 
+// Shutdown stops accepting paid tool calls and waits for in-flight ones to
+// finish settling or ctx to expire. Free tools and other methods are unaffected.
+// It returns ctx.Err() if paid calls were abandoned.
+func (h *X402Handler) Shutdown(ctx context.Context) (v2http.ShutdownReport, error) {
+	if h.drainer == nil {
+		return v2http.ShutdownReport{}, nil
+	}
+	return h.drainer.Shutdown(ctx)
+}
+
 type facilitatorConfig struct {
-	url            string
-	auth           string
-	authProvider   AuthorizationProvider
-	onBeforeVerify OnBeforeFunc
-	onAfterVerify  OnAfterVerifyFunc
-	onBeforeSettle OnBeforeFunc
-	onAfterSettle  OnAfterSettleFunc
+	url             string
+	auth            string
+	authProvider    AuthorizationProvider
+	onBeforeVerify  OnBeforeFunc
+	onAfterVerify   OnAfterVerifyFunc
+	onBeforeSettle  OnBeforeFunc
+	onAfterSettle   OnAfterSettleFunc
+	timeouts        v2.TimeoutConfig
+	networkTimeouts v2.NetworkTimeouts
 }
 
 // AuthorizationProvider re-exports the type from v2http for convenience.
@@ -69,7 +95,9 @@ func createFacilitator(cfg facilitatorConfig) Facilitator {
 		WithOnBeforeVerify(cfg.onBeforeVerify),
 		WithOnAfterVerify(cfg.onAfterVerify),
 		WithOnBeforeSettle(cfg.onBeforeSettle),
-		WithOnAfterSettle(cfg.onAfterSettle))
+		WithOnAfterSettle(cfg.onAfterSettle),
+		WithTimeouts(cfg.timeouts),
+		WithNetworkTimeouts(cfg.networkTimeouts))
 }
 
 func initializeFacilitators(config *Config) (Facilitator, Facilitator, error) {
@@ -81,26 +109,31 @@ func initializeFacilitators(config *Config) (Facilitator, Facilitator, error) {
 		return nil, nil, fmt.Errorf("x402: at least one facilitator URL must be provided")
 	}
 
+	timeouts := v2.TimeoutsOrDefault(config.Timeouts)
 	facilitator = createFacilitator(facilitatorConfig{
-		url:            primaryURL,
-		auth:           config.FacilitatorAuthorization,
-		authProvider:   config.FacilitatorAuthorizationProvider,
-		onBeforeVerify: config.FacilitatorOnBeforeVerify,
-		onAfterVerify:  config.FacilitatorOnAfterVerify,
-		onBeforeSettle: config.FacilitatorOnBeforeSettle,
-		onAfterSettle:  config.FacilitatorOnAfterSettle,
+		url:             primaryURL,
+		auth:            config.FacilitatorAuthorization,
+		authProvider:    config.FacilitatorAuthorizationProvider,
+		onBeforeVerify:  config.FacilitatorOnBeforeVerify,
+		onAfterVerify:   config.FacilitatorOnAfterVerify,
+		onBeforeSettle:  config.FacilitatorOnBeforeSettle,
+		onAfterSettle:   config.FacilitatorOnAfterSettle,
+		timeouts:        timeouts,
+		networkTimeouts: config.NetworkTimeouts,
 	})
 
 	// Initialize fallback if configured
 	if config.FallbackFacilitatorURL != "" {
 		fallbackFacilitator = createFacilitator(facilitatorConfig{
-			url:            config.FallbackFacilitatorURL,
-			auth:           config.FallbackFacilitatorAuthorization,
-			authProvider:   config.FallbackFacilitatorAuthorizationProvider,
-			onBeforeVerify: config.FallbackFacilitatorOnBeforeVerify,
-			onAfterVerify:  config.FallbackFacilitatorOnAfterVerify,
-			onBeforeSettle: config.FallbackFacilitatorOnBeforeSettle,
-			onAfterSettle:  config.FallbackFacilitatorOnAfterSettle,
+			url:             config.FallbackFacilitatorURL,
+			auth:            config.FallbackFacilitatorAuthorization,
+			authProvider:    config.FallbackFacilitatorAuthorizationProvider,
+			onBeforeVerify:  config.FallbackFacilitatorOnBeforeVerify,
+			onAfterVerify:   config.FallbackFacilitatorOnAfterVerify,
+			onBeforeSettle:  config.FallbackFacilitatorOnBeforeSettle,
+			onAfterSettle:   config.FallbackFacilitatorOnAfterSettle,
+			timeouts:        timeouts,
+			networkTimeouts: config.NetworkTimeouts,
 		})
 	}
 
@@ -108,6 +141,8 @@ func initializeFacilitators(config *Config) (Facilitator, Facilitator, error) {
 }
 
 // ServeHTTP intercepts HTTP requests to check for x402 v2 payments.
+// Single messages and JSON-RPC batches are supported; batches containing paid
+// tool calls are processed message by message and their responses recombined.
 func (h *X402Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	logger := h.config.Logger
 	if logger == nil {
@@ -120,57 +155,67 @@ func (h *X402Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Read request body
-	bodyBytes, err := io.ReadAll(r.Body)
+	maxBodySize := sizeLimit(h.config.MaxBodySize, DefaultMaxBodySize)
+	bodyBytes, err := readLimited(r.Body, maxBodySize)
+	if errors.Is(err, errBodyTooLarge) {
+		h.writeError(w, nil, ErrorCodeInvalidRequest, "Invalid Request", fmt.Sprintf("request body exceeds %d bytes", maxBodySize))
+		return
+	}
 	if err != nil {
-		h.writeError(w, nil, -32700, "Parse error", nil)
+		h.writeError(w, nil, ErrorCodeParse, "Parse error", nil)
 		return
 	}
 	r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
-	// Parse JSON-RPC request
-	var jsonrpcReq struct {
-		JSONRPC string          `json:"jsonrpc"`
-		Method  string          `json:"method"`
-		Params  json.RawMessage `json:"params"`
-		ID      interface{}     `json:"id"`
+	messages, batch, rpcErr := parseBody(bodyBytes)
+	if rpcErr != nil {
+		h.writeError(w, nil, rpcErr.Code, rpcErr.Message, rpcErr.Data)
+		return
 	}
-	if err := json.Unmarshal(bodyBytes, &jsonrpcReq); err != nil {
-		h.writeError(w, nil, -32700, "Parse error", nil)
+
+	if !batch {
+		h.serveMessage(w, r, messages[0], logger)
+		return
+	}
+	if !h.batchRequiresPayment(messages) {
+		// Nothing to charge for - let the MCP server handle the batch as a whole
+		h.mcpHandler.ServeHTTP(w, r)
+		return
+	}
+	h.serveBatch(w, r, messages, logger)
+}
+
+// serveMessage handles a single JSON-RPC message.
+func (h *X402Handler) serveMessage(w http.ResponseWriter, r *http.Request, raw json.RawMessage, logger *slog.Logger) {
+	r.Body = io.NopCloser(bytes.NewReader(raw))
+
+	msg, rpcErr := parseMessage(raw)
+	if rpcErr != nil {
+		h.writeError(w, msg.ID, rpcErr.Code, rpcErr.Message, rpcErr.Data)
 		return
 	}
 
 	// Only intercept tools/call methods
-	if jsonrpcReq.Method != "tools/call" {
+	if msg.IsResponse || msg.Method != "tools/call" {
 		h.mcpHandler.ServeHTTP(w, r)
 		return
 	}
 
-	// Parse tool call params
-	var toolParams struct {
-		Name      string                 `json:"name"`
-		Arguments map[string]interface{} `json:"arguments"`
-		Meta      *struct {
-			AdditionalFields map[string]interface{} `json:"-"`
-		} `json:"_meta"`
-	}
-	if err := json.Unmarshal(jsonrpcReq.Params, &toolParams); err != nil {
-		h.writeError(w, jsonrpcReq.ID, -32602, "Invalid params", nil)
+	toolParams, rpcErr := parseToolCall(msg.Params)
+	if rpcErr != nil {
+		h.writeError(w, msg.ID, rpcErr.Code, rpcErr.Message, rpcErr.Data)
 		return
 	}
-	logger = logger.With("requestID", jsonrpcReq.ID, "tool", toolParams.Name)
-
-	// Unmarshal _meta separately to get AdditionalFields
-	if len(jsonrpcReq.Params) > 0 {
-		var params map[string]interface{}
-		if err := json.Unmarshal(jsonrpcReq.Params, &params); err == nil {
-			if meta, ok := params["_meta"].(map[string]interface{}); ok {
-				if toolParams.Meta == nil {
-					toolParams.Meta = &struct {
-						AdditionalFields map[string]interface{} `json:"-"`
-					}{}
-				}
-				toolParams.Meta.AdditionalFields = meta
-			}
+	logger = logger.With("requestID", msg.ID, "tool", toolParams.Name)
+
+	if h.config.RequestMutator != nil {
+		mutated, err := h.config.RequestMutator(r, toolParams.Name)
+		if err != nil {
+			h.writeError(w, msg.ID, ErrorCodeInvalidRequest, fmt.Sprintf("Request rejected: %v", err), nil)
+			return
+		}
+		if mutated != nil {
+			r = mutated
 		}
 	}
 
@@ -181,24 +226,89 @@ func (h *X402Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.mcpHandler.ServeHTTP(w, r)
 		return
 	}
+	if paymentConfig.RequirementsFunc != nil {
+		if rpcErr := h.priceCall(r.Context(), toolParams, paymentConfig); rpcErr != nil {
+			h.writeError(w, msg.ID, rpcErr.Code, rpcErr.Message, rpcErr.Data)
+			return
+		}
+	}
+
+	// Calls of a bundle bought earlier are paid with its voucher
+	if token, ok := toolParams.voucher(); ok && h.config.Vouchers != nil {
+		h.serveVoucher(w, r, raw, msg.ID, toolParams.Name, token, paymentConfig, logger)
+		return
+	}
 
 	// Tool requires payment - extract payment from _meta
-	payment := h.extractPayment(toolParams.Meta)
+	payment, rpcErr := toolParams.payment()
+	if rpcErr != nil {
+		if h.config.Verbose {
+			logger.InfoContext(r.Context(), "Malformed payment", "error", rpcErr.Message)
+		}
+		h.notify(r, mcp.PaymentNotification{
+			Stage:    mcp.PaymentStageFailed,
+			Tool:     toolParams.Name,
+			Resource: paymentConfig.Resource.URL,
+			Reason:   rpcErr.Message,
+		})
+		h.writeError(w, msg.ID, rpcErr.Code, rpcErr.Message, h.paymentRequiredData(toolParams.Name, paymentConfig))
+		return
+	}
 	if payment == nil {
 		// No payment provided - send 402 error
-		h.sendPaymentRequiredError(w, jsonrpcReq.ID, paymentConfig)
+		h.notify(r, mcp.PaymentNotification{
+			Stage:    mcp.PaymentStageRequired,
+			Tool:     toolParams.Name,
+			Resource: paymentConfig.Resource.URL,
+			Accepts:  paymentConfig.Requirements,
+		})
+		if h.config.OnPaymentRequired != nil {
+			h.config.OnPaymentRequired(w, r, toolParams.Name, *paymentConfig)
+		}
+		h.sendPaymentRequiredError(w, msg.ID, toolParams.Name, paymentConfig)
 		return
 	}
 
-	// Find matching requirement
-	requirement, err := h.findMatchingRequirement(payment, paymentConfig.Requirements)
-	if err != nil {
-		h.writeError(w, jsonrpcReq.ID, 402, fmt.Sprintf("Payment invalid: %v", err), nil)
-		return
+	if h.drainer != nil {
+		release, err := h.drainer.Acquire()
+		if err != nil {
+			h.writeError(w, msg.ID, ErrorCodeInternal, "Server shutting down", nil)
+			return
+		}
+		defer release()
+	}
+
+	if h.config.Extensions != nil {
+		if err := h.config.Extensions.Validate(payment.Extensions); err != nil {
+			h.notify(r, mcp.PaymentNotification{
+				Stage:    mcp.PaymentStageFailed,
+				Tool:     toolParams.Name,
+				Resource: paymentConfig.Resource.URL,
+				Reason:   err.Error(),
+			})
+			h.writeError(w, msg.ID, ErrorCodePaymentRequired, fmt.Sprintf("Payment invalid: %v", err), h.paymentRequiredData(toolParams.Name, paymentConfig))
+			return
+		}
+	}
+
+	// A payment of a bundle's price buys the bundle; others pay for the call
+	bundle, requirement := h.findBundle(toolParams.Name, payment)
+	if bundle == nil {
+		var err error
+		requirement, err = h.findMatchingRequirement(payment, paymentConfig.Requirements)
+		if err != nil {
+			h.writeError(w, msg.ID, ErrorCodePaymentRequired, fmt.Sprintf("Payment invalid: %v", err), nil)
+			return
+		}
+		// A payment priced for other arguments cannot be used for this call
+		if paymentConfig.RequirementsFunc != nil && payment.Accepted.Amount != requirement.Amount {
+			h.writeError(w, msg.ID, ErrorCodePaymentRequired, "Payment invalid: amount does not match the price of the call", h.paymentRequiredData(toolParams.Name, paymentConfig))
+			return
+		}
 	}
 
 	// Verify payment with facilitator
-	ctx, cancel := context.WithTimeout(r.Context(), v2.DefaultTimeouts.VerifyTimeout)
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.timeouts(requirement.Network).VerifyTimeout)
 	defer cancel()
 
 	verifyResp, err := h.facilitator.Verify(ctx, payment, *requirement)
@@ -210,7 +320,8 @@ func (h *X402Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if h.config.Verbose {
 			logger.InfoContext(ctx, "Payment verification failed", "error", err)
 		}
-		h.writeError(w, jsonrpcReq.ID, -32603, fmt.Sprintf("Verification failed: %v", err), nil)
+		h.notify(r, newPaymentNotification(mcp.PaymentStageFailed, toolParams.Name, requirement, "", "", err.Error()))
+		h.writeError(w, msg.ID, ErrorCodeInternal, fmt.Sprintf("%s: %v", mcp.VerificationFailedMessage, err), nil)
 		return
 	}
 
@@ -218,11 +329,99 @@ func (h *X402Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if h.config.Verbose {
 			logger.InfoContext(ctx, "Payment rejected", "reason", verifyResp.InvalidReason)
 		}
-		h.writeError(w, jsonrpcReq.ID, 402, fmt.Sprintf("Payment invalid: %s", verifyResp.InvalidReason), nil)
+		h.notify(r, newPaymentNotification(mcp.PaymentStageFailed, toolParams.Name, requirement, verifyResp.Payer, "", verifyResp.InvalidReason))
+		h.writeError(w, msg.ID, ErrorCodePaymentRequired, fmt.Sprintf("Payment invalid: %s", verifyResp.InvalidReason), nil)
 		return
 	}
 
-	h.forwardAndSettle(w, r, bodyBytes, jsonrpcReq.ID, payment, requirement, verifyResp, logger)
+	if h.config.OnVerified != nil {
+		if err := h.config.OnVerified(w, r, toolParams.Name, *payment, *requirement, verifyResp); err != nil {
+			if h.config.Verbose {
+				logger.InfoContext(ctx, "Payment rejected by OnVerified hook", "error", err)
+			}
+			h.notify(r, newPaymentNotification(mcp.PaymentStageFailed, toolParams.Name, requirement, verifyResp.Payer, "", err.Error()))
+			h.writeError(w, msg.ID, ErrorCodePaymentRequired, fmt.Sprintf("Payment rejected: %v", err), nil)
+			return
+		}
+	}
+
+	h.notify(r, newPaymentNotification(mcp.PaymentStageVerified, toolParams.Name, requirement, verifyResp.Payer, "", ""))
+
+	h.forwardAndSettle(w, r, raw, msg.ID, toolParams.Name, payment, requirement, verifyResp, bundle, logger)
+}
+
+// batchRequiresPayment reports whether a batch must be handled message by
+// message: any tools/call for a paid tool, or any message the handler cannot
+// classify, since forwarding it whole would let a malformed paid call through.
+func (h *X402Handler) batchRequiresPayment(messages []json.RawMessage) bool {
+	for _, raw := range messages {
+		msg, rpcErr := parseMessage(raw)
+		if rpcErr != nil {
+			return true
+		}
+		if msg.IsResponse || msg.Method != "tools/call" {
+			continue
+		}
+		call, rpcErr := parseToolCall(msg.Params)
+		if rpcErr != nil {
+			return true
+		}
+		if _, paid := h.checkPaymentRequired(call.Name); paid {
+			return true
+		}
+	}
+	return false
+}
+
+// serveBatch handles each message of a batch separately and writes the
+// responses as a JSON array. Notifications produce no response; if no message
+// produces one, 202 Accepted is returned.
+func (h *X402Handler) serveBatch(w http.ResponseWriter, r *http.Request, messages []json.RawMessage, logger *slog.Logger) {
+	var responses []json.RawMessage
+	for _, raw := range messages {
+		recorder := h.newRecorder(false)
+		h.serveMessage(recorder, r.Clone(r.Context()), raw, logger)
+		body, _ := recorder.body.Bytes()
+		overflowed := recorder.body.Overflowed()
+		recorder.body.Close()
+
+		for key, values := range recorder.headerMap {
+			if key != "Content-Length" && w.Header().Get(key) == "" {
+				w.Header()[key] = values
+			}
+		}
+
+		msg, rpcErr := parseMessage(raw)
+		if rpcErr == nil && (!msg.HasID || msg.IsResponse) {
+			// Notifications and client responses never get a reply
+			continue
+		}
+
+		body = bytes.TrimSpace(body)
+		if len(body) == 0 && !overflowed {
+			continue
+		}
+		if overflowed || !json.Valid(body) {
+			message := "Response cannot be batched"
+			if overflowed {
+				message = "Response too large"
+			}
+			body, _ = json.Marshal(map[string]interface{}{
+				"jsonrpc": jsonrpcVersion,
+				"id":      msg.ID,
+				"error":   rpcError{Code: ErrorCodeInternal, Message: message},
+			})
+		}
+		responses = append(responses, body)
+	}
+
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(responses)
 }
 
 // checkPaymentRequired checks if a tool requires payment.
@@ -247,68 +446,54 @@ func (h *X402Handler) checkPaymentRequired(toolName string) (*ToolPaymentConfig,
 	}
 
 	return &ToolPaymentConfig{
-		Resource:     resource,
-		Requirements: reqCopy,
+		Resource:         resource,
+		Requirements:     reqCopy,
+		RequirementsFunc: paymentConfig.RequirementsFunc,
 	}, true
 }
 
-// extractPayment extracts payment from params._meta["x402/payment"].
-func (h *X402Handler) extractPayment(meta *struct {
-	AdditionalFields map[string]interface{} `json:"-"`
-}) *v2.PaymentPayload {
-	if meta == nil || meta.AdditionalFields == nil {
-		return nil
-	}
-
-	paymentData, ok := meta.AdditionalFields["x402/payment"]
-	if !ok {
-		return nil
-	}
-
-	// Marshal and unmarshal to convert to PaymentPayload
-	paymentBytes, err := json.Marshal(paymentData)
-	if err != nil {
-		return nil
-	}
-
-	var payment v2.PaymentPayload
-	if err := json.Unmarshal(paymentBytes, &payment); err != nil {
-		return nil
-	}
-
-	// Validate X402Version
-	if payment.X402Version != v2.X402Version {
-		return nil
-	}
-
-	return &payment
-}
-
-// findMatchingRequirement finds a requirement that matches the payment.
+// findMatchingRequirement finds an allowed requirement that matches the payment.
 // This delegates to v2.FindMatchingRequirement for consistent matching logic across packages.
 func (h *X402Handler) findMatchingRequirement(payment *v2.PaymentPayload, requirements []v2.PaymentRequirements) (*v2.PaymentRequirements, error) {
-	return v2.FindMatchingRequirement(payment, requirements)
+	return h.config.Allowlist.FindMatchingRequirement(payment, requirements)
 }
 
 // sendPaymentRequiredError sends a 402 error with payment requirements (v2 format).
-func (h *X402Handler) sendPaymentRequiredError(w http.ResponseWriter, id interface{}, config *ToolPaymentConfig) {
-	errorData := map[string]interface{}{
+func (h *X402Handler) sendPaymentRequiredError(w http.ResponseWriter, id interface{}, toolName string, config *ToolPaymentConfig) {
+	h.writeError(w, id, ErrorCodePaymentRequired, "Payment required", h.paymentRequiredData(toolName, config))
+}
+
+// paymentRequiredData returns the error data listing the accepted payments for
+// a tool, the configured extensions, and the bundles including the tool.
+func (h *X402Handler) paymentRequiredData(toolName string, config *ToolPaymentConfig) map[string]interface{} {
+	data := map[string]interface{}{
 		"x402Version": v2.X402Version,
 		"error":       "Payment required to access this resource",
 		"resource":    config.Resource,
 		"accepts":     config.Requirements,
 	}
-
-	h.writeError(w, id, 402, "Payment required", errorData)
+	var extensions map[string]v2.Extension
+	if h.config.Extensions != nil {
+		h.config.Extensions.Attach(&extensions)
+	}
+	if bundles, ok := h.bundleExtension(toolName); ok {
+		if extensions == nil {
+			extensions = make(map[string]v2.Extension)
+		}
+		extensions[mcp.BundleExtension] = bundles
+	}
+	if len(extensions) > 0 {
+		data["extensions"] = extensions
+	}
+	return data
 }
 
 // forwardAndSettle executes the mcpHandler and on success, settles the payment and injects settlement response in result._meta.
-func (h *X402Handler) forwardAndSettle(w http.ResponseWriter, r *http.Request, requestBody []byte, requestID interface{}, payment *v2.PaymentPayload, requirement *v2.PaymentRequirements, verifyResp *v2.VerifyResponse, logger *slog.Logger) {
-	// Create a response recorder to capture the MCP handler's response
-	recorder := &responseRecorder{
-		headerMap:  make(http.Header),
-		statusCode: http.StatusOK,
-	}
+// If the payment bought a bundle, its voucher is issued and injected as well.
+func (h *X402Handler) forwardAndSettle(w http.ResponseWriter, r *http.Request, requestBody []byte, requestID interface{}, toolName string, payment *v2.PaymentPayload, requirement *v2.PaymentRequirements, verifyResp *v2.VerifyResponse, bundle *Bundle, logger *slog.Logger) {
+	// Capture the MCP handler's response until the payment is settled
+	recorder := h.newRecorder(true)
+	defer recorder.body.Close()
 
 	// Restore request body
 	r.Body = io.NopCloser(bytes.NewBuffer(requestBody))
@@ -316,36 +501,30 @@ func (h *X402Handler) forwardAndSettle(w http.ResponseWriter, r *http.Request, r
 	// Forward to MCP handler
 	h.mcpHandler.ServeHTTP(recorder, r)
 
-	// Parse response
-	var jsonrpcResp struct {
-		JSONRPC string          `json:"jsonrpc"`
-		Result  json.RawMessage `json:"result,omitempty"`
-		Error   interface{}     `json:"error,omitempty"`
-		ID      interface{}     `json:"id"`
+	if recorder.body.Overflowed() {
+		if h.config.Verbose {
+			logger.ErrorContext(r.Context(), "MCP response too large, skipping settlement", "limit", recorder.body.limit)
+		}
+		h.writeError(w, requestID, ErrorCodeInternal, "Response too large", fmt.Sprintf("tool response exceeds %d bytes", recorder.body.limit))
+		return
 	}
 
-	if err := json.Unmarshal(recorder.body.Bytes(), &jsonrpcResp); err != nil {
+	// Locate the error, result, and result._meta without decoding the result
+	shape, err := inspectResponse(io.NewSectionReader(recorder.body.ReaderAt(), 0, recorder.body.Len()))
+	if err != nil {
 		if h.config.Verbose {
 			logger.ErrorContext(r.Context(), "Failed to parse MCP response, skipping settlement", "error", err)
 		}
 		// If we can't parse response, just forward it as-is
-		for k, v := range recorder.headerMap {
-			w.Header()[k] = v
-		}
-		w.WriteHeader(recorder.statusCode)
-		_, _ = w.Write(recorder.body.Bytes())
+		recorder.forward(w)
 		return
 	}
 
-	if jsonrpcResp.Error != nil {
+	if shape.IsError {
 		if h.config.Verbose {
 			logger.InfoContext(r.Context(), "Execution failed. Payment will not be settled.")
 		}
-		for k, v := range recorder.headerMap {
-			w.Header()[k] = v
-		}
-		w.WriteHeader(recorder.statusCode)
-		_, _ = w.Write(recorder.body.Bytes())
+		recorder.forward(w)
 		return
 	}
 
@@ -355,7 +534,7 @@ func (h *X402Handler) forwardAndSettle(w http.ResponseWriter, r *http.Request, r
 		if h.config.Verbose {
 			logger.InfoContext(r.Context(), "Execution successful. Settling payment.")
 		}
-		settleCtx, settleCancel := context.WithTimeout(r.Context(), v2.DefaultTimeouts.SettleTimeout)
+		settleCtx, settleCancel := context.WithTimeout(r.Context(), h.config.timeouts(requirement.Network).SettleTimeout)
 		defer settleCancel()
 
 		var err error
@@ -379,6 +558,7 @@ func (h *X402Handler) forwardAndSettle(w http.ResponseWriter, r *http.Request, r
 			if verifyResp != nil {
 				payer = verifyResp.Payer
 			}
+			h.notify(r, newPaymentNotification(mcp.PaymentStageFailed, toolName, requirement, payer, "", reason))
 			errorData := map[string]interface{}{
 				"x402/payment-response": v2.SettleResponse{
 					Success:     false,
@@ -387,62 +567,103 @@ func (h *X402Handler) forwardAndSettle(w http.ResponseWriter, r *http.Request, r
 					ErrorReason: reason,
 				},
 			}
-			h.writeError(w, requestID, -32603, fmt.Sprintf("Settlement failed: %v", reason), errorData)
+			h.writeError(w, requestID, ErrorCodeInternal, fmt.Sprintf("Settlement failed: %v", reason), errorData)
 			return
 		} else if h.config.Verbose {
 			logger.InfoContext(settleCtx, "Payment successful", "transaction", settleResp.Transaction)
 		}
+		h.notify(r, newPaymentNotification(mcp.PaymentStageSettled, toolName, requirement, settleResp.Payer, settleResp.Transaction, ""))
 	}
 
-	if jsonrpcResp.Result != nil {
-		var result map[string]interface{}
-		if err := json.Unmarshal(jsonrpcResp.Result, &result); err == nil {
-			meta, ok := result["_meta"].(map[string]interface{})
-			if !ok {
-				meta = make(map[string]interface{})
-			}
+	meta := shape.Meta
+	if meta == nil {
+		meta = make(map[string]interface{})
+	}
+	if settleResp != nil {
+		meta["x402/payment-response"] = settleResp
+	} else {
+		// Verify-only mode: verification succeeded (we wouldn't be here if it failed)
+		// Set Success=true with empty Transaction to indicate verification passed but settlement was not attempted.
+		payer := ""
+		if verifyResp != nil {
+			payer = verifyResp.Payer
+		}
+		meta["x402/payment-response"] = v2.SettleResponse{
+			Success:     true, // Verification succeeded
+			Network:     payment.Accepted.Network,
+			Payer:       payer,
+			Transaction: "", // Settlement not attempted in verify-only mode
+		}
+	}
 
-			// Add settlement response
-			if settleResp != nil {
-				meta["x402/payment-response"] = settleResp
-			} else {
-				// Verify-only mode: verification succeeded (we wouldn't be here if it failed)
-				// Set Success=true with empty Transaction to indicate verification passed but settlement was not attempted.
-				payer := ""
-				if verifyResp != nil {
-					payer = verifyResp.Payer
-				}
-				meta["x402/payment-response"] = v2.SettleResponse{
-					Success:     true, // Verification succeeded
-					Network:     payment.Accepted.Network,
-					Payer:       payer,
-					Transaction: "", // Settlement not attempted in verify-only mode
-				}
+	if bundle != nil {
+		payer, transaction := "", ""
+		if settleResp != nil {
+			payer, transaction = settleResp.Payer, settleResp.Transaction
+		} else if verifyResp != nil {
+			payer = verifyResp.Payer
+		}
+		voucher, err := h.config.Vouchers.Issue(r.Context(), *bundle, payer, requirement.Network, transaction)
+		if err != nil {
+			// The bundle is paid for; tell the buyer so, with the transaction
+			// to claim it by, rather than returning the result without a voucher
+			logger.ErrorContext(r.Context(), "Failed to issue voucher for settled bundle", "transaction", transaction, "error", err)
+			if settleResp != nil && h.config.OnSettled != nil {
+				h.config.OnSettled(w, r, toolName, *requirement, settleResp)
 			}
-			result["_meta"] = meta
-
-			// Re-marshal result
-			modifiedResult, err := json.Marshal(result)
-			if err == nil {
-				jsonrpcResp.Result = modifiedResult
+			errorData := map[string]interface{}{
+				"x402/payment-response": meta["x402/payment-response"],
 			}
+			h.writeError(w, requestID, ErrorCodeInternal, fmt.Sprintf("Bundle paid but voucher could not be issued: %v", err), errorData)
+			return
 		}
+		meta[mcp.VoucherMetaKey] = voucher
 	}
 
-	// Write modified response
-	responseBytes, err := json.Marshal(jsonrpcResp)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	// Copy headers
+	// Copy headers; the body length changes when _meta is added
 	for k, v := range recorder.headerMap {
 		w.Header()[k] = v
 	}
+	if shape.ResultObject {
+		w.Header().Del("Content-Length")
+	}
+	if settleResp != nil && h.config.OnSettled != nil {
+		h.config.OnSettled(w, r, toolName, *requirement, settleResp)
+	}
 
 	w.WriteHeader(recorder.statusCode)
-	_, _ = w.Write(responseBytes)
+	if !shape.ResultObject {
+		_, _ = recorder.body.WriteTo(w)
+		return
+	}
+	if err := writeWithMeta(w, &recorder.body, shape, meta); err != nil && h.config.Verbose {
+		logger.ErrorContext(r.Context(), "Failed to write MCP response", "error", err)
+	}
+}
+
+// notify delivers a payment notification to the configured notifier, if any.
+// The MCP session is taken from the request's Mcp-Session-Id header.
+func (h *X402Handler) notify(r *http.Request, notification mcp.PaymentNotification) {
+	if h.notifier == nil {
+		return
+	}
+	h.notifier(r.Context(), r.Header.Get(mcpserver.HeaderKeySessionID), notification)
+}
+
+// newPaymentNotification builds a notification for the given stage from the matched requirement.
+func newPaymentNotification(stage mcp.PaymentStage, toolName string, requirement *v2.PaymentRequirements, payer, transaction, reason string) mcp.PaymentNotification {
+	return mcp.PaymentNotification{
+		Stage:       stage,
+		Tool:        toolName,
+		Scheme:      requirement.Scheme,
+		Network:     requirement.Network,
+		Amount:      requirement.Amount,
+		Asset:       requirement.Asset,
+		PayTo:       requirement.PayTo,
+		Payer:       payer,
+		Transaction: transaction,
+		Reason:      reason,
+	}
 }
 
 // writeError writes a JSON-RPC error response.
@@ -468,10 +689,31 @@ func (h *X402Handler) writeError(w http.ResponseWriter, id interface{}, code int
 // responseRecorder records HTTP responses for modification.
 type responseRecorder struct {
 	headerMap  http.Header
-	body       bytes.Buffer
+	body       bodyBuffer
 	statusCode int
 }
 
+// newRecorder returns a recorder bounded by MaxResponseSize. If spill is true,
+// bodies past SpillThreshold are moved to disk. Callers must Close its body.
+func (h *X402Handler) newRecorder(spill bool) *responseRecorder {
+	recorder := &responseRecorder{headerMap: make(http.Header), statusCode: http.StatusOK}
+	recorder.body.limit = sizeLimit(h.config.MaxResponseSize, DefaultMaxResponseSize)
+	if spill {
+		recorder.body.spillThreshold = h.config.SpillThreshold
+		recorder.body.spillDir = h.config.SpillDir
+	}
+	return recorder
+}
+
+// forward writes the recorded response to w unchanged.
+func (r *responseRecorder) forward(w http.ResponseWriter) {
+	for k, v := range r.headerMap {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(r.statusCode)
+	_, _ = r.body.WriteTo(w)
+}
+
 func (r *responseRecorder) Header() http.Header {
 	return r.headerMap
 }
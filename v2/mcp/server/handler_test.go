@@ -4,11 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	mcpproto "github.com/mark3labs/mcp-go/mcp"
 	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/mcp"
 )
 
 // mockFacilitator implements the Facilitator interface for testing.
@@ -19,10 +24,13 @@ type mockFacilitator struct {
 	settleErr      error
 	verifyCalled   bool
 	settleCalled   bool
+	verifyDeadline time.Time
+	settleDeadline time.Time
 }
 
 func (m *mockFacilitator) Verify(ctx context.Context, payment *v2.PaymentPayload, requirement v2.PaymentRequirements) (*v2.VerifyResponse, error) {
 	m.verifyCalled = true
+	m.verifyDeadline, _ = ctx.Deadline()
 	if m.verifyErr != nil {
 		return nil, m.verifyErr
 	}
@@ -31,6 +39,7 @@ func (m *mockFacilitator) Verify(ctx context.Context, payment *v2.PaymentPayload
 
 func (m *mockFacilitator) Settle(ctx context.Context, payment *v2.PaymentPayload, requirement v2.PaymentRequirements) (*v2.SettleResponse, error) {
 	m.settleCalled = true
+	m.settleDeadline, _ = ctx.Deadline()
 	if m.settleErr != nil {
 		return nil, m.settleErr
 	}
@@ -518,6 +527,34 @@ func TestHandler_NonToolsCall(t *testing.T) {
 	}
 }
 
+func TestHandler_CaseVariantMethod(t *testing.T) {
+	var handlerCalled bool
+	handler := &X402Handler{
+		mcpHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { handlerCalled = true }),
+		config: &Config{
+			FacilitatorURL: "http://example.com",
+			PaymentTools: map[string]ToolPaymentConfig{
+				"paid": {Requirements: []v2.PaymentRequirements{{Scheme: "exact", Network: "eip155:84532", Amount: "10000", Asset: "0xAsset", PayTo: "0xPayTo"}}},
+			},
+		},
+	}
+
+	// The MCP server dispatches this as a tools/call, so it must be paid for
+	body := `{"method":"ping","Method":"tools/call","params":{"name":"paid","arguments":{}},"jsonrpc":"2.0","id":1}`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/mcp", bytes.NewReader([]byte(body))))
+
+	if handlerCalled {
+		t.Fatal("expected the paid tool not to run without payment")
+	}
+	var resp struct {
+		Error *rpcError `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error == nil || resp.Error.Code != ErrorCodePaymentRequired {
+		t.Errorf("expected a payment required error, got %s", w.Body.String())
+	}
+}
+
 func TestHandler_ToolExecutionError_NoSettlement(t *testing.T) {
 	mock := &mockFacilitator{
 		verifyResponse: &v2.VerifyResponse{
@@ -592,6 +629,94 @@ func TestHandler_ToolExecutionError_NoSettlement(t *testing.T) {
 	}
 }
 
+func TestHandler_PaymentNotifications(t *testing.T) {
+	mock := &mockFacilitator{
+		verifyResponse: &v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"},
+		settleResponse: &v2.SettleResponse{Success: true, Transaction: "0xtx", Network: "eip155:84532", Payer: "0xPayerAddress"},
+	}
+
+	config := &Config{
+		FacilitatorURL: "http://example.com",
+		PaymentTools: map[string]ToolPaymentConfig{
+			"paid_tool": {
+				Resource: v2.ResourceInfo{URL: "mcp://tools/paid_tool"},
+				Requirements: []v2.PaymentRequirements{{
+					Scheme:            "exact",
+					Network:           "eip155:84532",
+					Amount:            "10000",
+					Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+					PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+					MaxTimeoutSeconds: 60,
+				}},
+			},
+		},
+	}
+
+	var sessions []string
+	var notifications []mcp.PaymentNotification
+	handler := &X402Handler{
+		mcpHandler: &mockMCPHandler{
+			response:   map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": map[string]interface{}{}},
+			statusCode: http.StatusOK,
+		},
+		config:      config,
+		facilitator: mock,
+		notifier: func(ctx context.Context, sessionID string, n mcp.PaymentNotification) {
+			sessions = append(sessions, sessionID)
+			notifications = append(notifications, n)
+		},
+	}
+
+	send := func(params map[string]interface{}) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "tools/call",
+			"id":      1,
+			"params":  params,
+		})
+		req := httptest.NewRequest("POST", "/mcp", bytes.NewReader(body))
+		req.Header.Set("Mcp-Session-Id", "session-1")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Unpaid call emits a "required" notification
+	send(map[string]interface{}{"name": "paid_tool"})
+
+	// Paid call emits "verified" then "settled"
+	send(map[string]interface{}{
+		"name": "paid_tool",
+		"_meta": map[string]interface{}{
+			"x402/payment": map[string]interface{}{
+				"x402Version": 2,
+				"accepted":    map[string]interface{}{"scheme": "exact", "network": "eip155:84532"},
+				"payload":     map[string]interface{}{"signature": "0xsig"},
+			},
+		},
+	})
+
+	wantStages := []mcp.PaymentStage{mcp.PaymentStageRequired, mcp.PaymentStageVerified, mcp.PaymentStageSettled}
+	if len(notifications) != len(wantStages) {
+		t.Fatalf("Expected %d notifications, got %d", len(wantStages), len(notifications))
+	}
+	for i, stage := range wantStages {
+		if notifications[i].Stage != stage {
+			t.Errorf("notification %d: expected stage %s, got %s", i, stage, notifications[i].Stage)
+		}
+		if notifications[i].Tool != "paid_tool" {
+			t.Errorf("notification %d: expected tool paid_tool, got %s", i, notifications[i].Tool)
+		}
+		if sessions[i] != "session-1" {
+			t.Errorf("notification %d: expected session-1, got %q", i, sessions[i])
+		}
+	}
+	if len(notifications[0].Accepts) != 1 {
+		t.Errorf("Expected required notification to list accepts, got %d", len(notifications[0].Accepts))
+	}
+	if notifications[2].Transaction != "0xtx" || notifications[2].Amount != "10000" {
+		t.Errorf("Unexpected settled notification: %+v", notifications[2])
+	}
+}
+
 func TestConfig_AddPaymentTool(t *testing.T) {
 	config := DefaultConfig()
 
@@ -692,3 +817,344 @@ func TestSetToolResource(t *testing.T) {
 		t.Errorf("Expected URL mcp://tools/my_tool, got %s", resource.URL)
 	}
 }
+
+func TestHandler_Shutdown(t *testing.T) {
+	mock := &mockFacilitator{
+		verifyResponse: &v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"},
+		settleResponse: &v2.SettleResponse{Success: true, Transaction: "0xtx"},
+	}
+	config := &Config{
+		FacilitatorURL: "http://example.com",
+		PaymentTools: map[string]ToolPaymentConfig{
+			"paid_tool": {
+				Resource: v2.ResourceInfo{URL: "mcp://tools/paid_tool"},
+				Requirements: []v2.PaymentRequirements{
+					{Scheme: "exact", Network: "eip155:84532", Amount: "10000", Asset: "0x036CbD53842c5426634e7929541eC2318f3dCF7e", PayTo: "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"},
+				},
+			},
+		},
+	}
+	handler, err := NewX402Handler(&mockMCPHandler{
+		response:   map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": map[string]interface{}{}},
+		statusCode: http.StatusOK,
+	}, config)
+	if err != nil {
+		t.Fatalf("NewX402Handler() error = %v", err)
+	}
+	handler.facilitator = mock
+
+	report, err := handler.Shutdown(context.Background())
+	if err != nil || report.InFlight != 0 {
+		t.Fatalf("Shutdown() = %+v, %v", report, err)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "tools/call",
+		"id":      1,
+		"params": map[string]interface{}{
+			"name": "paid_tool",
+			"_meta": map[string]interface{}{
+				"x402/payment": map[string]interface{}{
+					"x402Version": 2,
+					"accepted":    map[string]interface{}{"scheme": "exact", "network": "eip155:84532", "amount": "10000"},
+					"payload":     map[string]interface{}{"signature": "0xsig"},
+				},
+			},
+		},
+	})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/mcp", bytes.NewReader(body)))
+
+	var resp map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if _, hasError := resp["error"]; !hasError {
+		t.Error("Expected paid call to be refused after shutdown")
+	}
+	if mock.verifyCalled {
+		t.Error("Expected no verification after shutdown")
+	}
+
+	report, _ = handler.Shutdown(context.Background())
+	if report.Rejected != 1 {
+		t.Errorf("Expected 1 rejected call, got %+v", report)
+	}
+}
+
+func TestHandler_Hooks(t *testing.T) {
+	requirement := v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: "10000", Asset: "0xAsset", PayTo: "0xPayTo"}
+	payment, _ := json.Marshal(v2.PaymentPayload{X402Version: 2, Accepted: requirement, Payload: map[string]interface{}{"signature": "0xsig"}})
+	paidCall := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"paid_tool","_meta":{"x402/payment":` + string(payment) + `}}}`
+	unpaidCall := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"paid_tool"}}`
+
+	tests := []struct {
+		name         string
+		body         string
+		rejectVerify bool
+		rejectCall   bool
+		wantCode     int
+		wantSettled  bool
+		wantHeader   string
+	}{
+		{name: "payment required", body: unpaidCall, wantCode: ErrorCodePaymentRequired, wantHeader: "required"},
+		{name: "settled", body: paidCall, wantSettled: true, wantHeader: "settled"},
+		{name: "rejected by policy", body: paidCall, rejectVerify: true, wantCode: ErrorCodePaymentRequired, wantHeader: "verified"},
+		{name: "rejected by mutator", body: paidCall, rejectCall: true, wantCode: ErrorCodeInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockFacilitator{
+				verifyResponse: &v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"},
+				settleResponse: &v2.SettleResponse{Success: true, Transaction: "0xtx"},
+			}
+			var forwardedHeader string
+			mcpHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				forwardedHeader = r.Header.Get("X-Tool")
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{}}`))
+			})
+			handler := &X402Handler{
+				mcpHandler:  mcpHandler,
+				facilitator: mock,
+				config: &Config{
+					PaymentTools: map[string]ToolPaymentConfig{
+						"paid_tool": {Resource: v2.ResourceInfo{URL: "mcp://tools/paid_tool"}, Requirements: []v2.PaymentRequirements{requirement}},
+					},
+					RequestMutator: func(r *http.Request, toolName string) (*http.Request, error) {
+						if tt.rejectCall {
+							return nil, errors.New("blocked")
+						}
+						r.Header.Set("X-Tool", toolName)
+						return r, nil
+					},
+					OnPaymentRequired: func(w http.ResponseWriter, r *http.Request, toolName string, config ToolPaymentConfig) {
+						w.Header().Set("X-Hook", "required")
+					},
+					OnVerified: func(w http.ResponseWriter, r *http.Request, toolName string, payment v2.PaymentPayload, requirement v2.PaymentRequirements, resp *v2.VerifyResponse) error {
+						w.Header().Set("X-Hook", "verified")
+						if tt.rejectVerify {
+							return errors.New("payer not allowed")
+						}
+						return nil
+					},
+					OnSettled: func(w http.ResponseWriter, r *http.Request, toolName string, requirement v2.PaymentRequirements, resp *v2.SettleResponse) {
+						w.Header().Set("X-Hook", "settled")
+					},
+				},
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("POST", "/mcp", bytes.NewReader([]byte(tt.body))))
+
+			var resp struct {
+				Error *rpcError `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if tt.wantCode != 0 && (resp.Error == nil || resp.Error.Code != tt.wantCode) {
+				t.Errorf("Expected error code %d, got %+v", tt.wantCode, resp.Error)
+			}
+			if mock.settleCalled != tt.wantSettled {
+				t.Errorf("Expected settled=%v, got %v", tt.wantSettled, mock.settleCalled)
+			}
+			if got := w.Header().Get("X-Hook"); got != tt.wantHeader {
+				t.Errorf("Expected X-Hook %q, got %q", tt.wantHeader, got)
+			}
+			if tt.wantSettled && forwardedHeader != "paid_tool" {
+				t.Errorf("Expected mutated request to be forwarded, got X-Tool %q", forwardedHeader)
+			}
+		})
+	}
+}
+
+func TestHandler_NetworkTimeouts(t *testing.T) {
+	tests := []struct {
+		name       string
+		network    string
+		wantVerify time.Duration
+		wantSettle time.Duration
+	}{
+		{name: "base timeouts", network: "eip155:84532", wantVerify: 2 * time.Second, wantSettle: 10 * time.Second},
+		{name: "namespace override", network: v2.NetworkSolanaDevnet, wantVerify: 2 * time.Second, wantSettle: 90 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requirement := v2.PaymentRequirements{Scheme: "exact", Network: tt.network, Amount: "10000", Asset: "0xAsset", PayTo: "0xPayTo"}
+			payment, _ := json.Marshal(v2.PaymentPayload{X402Version: 2, Accepted: requirement, Payload: map[string]interface{}{"signature": "0xsig"}})
+			paidCall := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"paid_tool","_meta":{"x402/payment":` + string(payment) + `}}}`
+
+			mock := &mockFacilitator{
+				verifyResponse: &v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"},
+				settleResponse: &v2.SettleResponse{Success: true, Transaction: "0xtx"},
+			}
+			handler := &X402Handler{
+				mcpHandler: &mockMCPHandler{
+					response:   map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": map[string]interface{}{}},
+					statusCode: http.StatusOK,
+				},
+				facilitator: mock,
+				config: &Config{
+					PaymentTools: map[string]ToolPaymentConfig{
+						"paid_tool": {Requirements: []v2.PaymentRequirements{requirement}},
+					},
+					Timeouts:        v2.TimeoutConfig{VerifyTimeout: 2 * time.Second, SettleTimeout: 10 * time.Second, RequestTimeout: time.Minute},
+					NetworkTimeouts: v2.NetworkTimeouts{"solana": {SettleTimeout: 90 * time.Second}},
+				},
+			}
+
+			start := time.Now()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/mcp", bytes.NewReader([]byte(paidCall))))
+			elapsed := time.Since(start)
+
+			if got := mock.verifyDeadline.Sub(start); got < tt.wantVerify || got > tt.wantVerify+elapsed {
+				t.Errorf("Expected verify deadline in %v, got %v", tt.wantVerify, got)
+			}
+			if got := mock.settleDeadline.Sub(start); got < tt.wantSettle || got > tt.wantSettle+elapsed {
+				t.Errorf("Expected settle deadline in %v, got %v", tt.wantSettle, got)
+			}
+		})
+	}
+}
+
+func TestHandler_PricedTool(t *testing.T) {
+	base := v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: "1000", Asset: "0xAsset", PayTo: "0xPayTo"}
+	pricing := ArgumentPricing{Argument: "max_results", PerUnit: big.NewInt(100), Default: 5, Min: 1, Max: 20}
+	call := func(arguments string, amount string) string {
+		if amount == "" {
+			return `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search","arguments":` + arguments + `}}`
+		}
+		accepted := base
+		accepted.Amount = amount
+		payment, _ := json.Marshal(v2.PaymentPayload{X402Version: 2, Accepted: accepted, Payload: map[string]interface{}{"signature": "0xsig"}})
+		return `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search","arguments":` + arguments + `,"_meta":{"x402/payment":` + string(payment) + `}}}`
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		wantCode    int
+		wantAmount  string
+		wantSettled bool
+	}{
+		{name: "priced by argument", body: call(`{"max_results":10}`, ""), wantCode: ErrorCodePaymentRequired, wantAmount: "2000"},
+		{name: "default argument", body: call(`{}`, ""), wantCode: ErrorCodePaymentRequired, wantAmount: "1500"},
+		{name: "argument out of range", body: call(`{"max_results":50}`, ""), wantCode: ErrorCodeInvalidParams},
+		{name: "argument not a number", body: call(`{"max_results":"ten"}`, ""), wantCode: ErrorCodeInvalidParams},
+		{name: "paid at price", body: call(`{"max_results":10}`, "2000"), wantSettled: true},
+		{name: "paid for other arguments", body: call(`{"max_results":10}`, "1500"), wantCode: ErrorCodePaymentRequired, wantAmount: "2000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockFacilitator{
+				verifyResponse: &v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"},
+				settleResponse: &v2.SettleResponse{Success: true, Transaction: "0xtx"},
+			}
+			handler := &X402Handler{
+				mcpHandler:  &mockMCPHandler{response: map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": map[string]interface{}{}}, statusCode: http.StatusOK},
+				facilitator: mock,
+				config: &Config{
+					PaymentTools: map[string]ToolPaymentConfig{
+						"search": {Requirements: []v2.PaymentRequirements{base}, RequirementsFunc: pricing.Requirements},
+					},
+				},
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("POST", "/mcp", bytes.NewReader([]byte(tt.body))))
+
+			var resp struct {
+				Error *struct {
+					Code int `json:"code"`
+					Data struct {
+						Accepts []v2.PaymentRequirements `json:"accepts"`
+					} `json:"data"`
+				} `json:"error"`
+			}
+			_ = json.Unmarshal(w.Body.Bytes(), &resp)
+			if tt.wantCode == 0 && resp.Error != nil {
+				t.Fatalf("Expected success, got %+v", resp.Error)
+			}
+			if tt.wantCode != 0 && (resp.Error == nil || resp.Error.Code != tt.wantCode) {
+				t.Fatalf("Expected error code %d, got %s", tt.wantCode, w.Body.String())
+			}
+			if tt.wantAmount != "" && (len(resp.Error.Data.Accepts) != 1 || resp.Error.Data.Accepts[0].Amount != tt.wantAmount) {
+				t.Errorf("Expected price %s, got %+v", tt.wantAmount, resp.Error.Data.Accepts)
+			}
+			if mock.settleCalled != tt.wantSettled {
+				t.Errorf("Expected settled=%v, got %v", tt.wantSettled, mock.settleCalled)
+			}
+		})
+	}
+}
+
+func TestX402Server_ListsToolPrices(t *testing.T) {
+	const asset, payTo = "0x036CbD53842c5426634e7929541eC2318f3dCF7e", "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+	requirement := v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: "1000", Asset: asset, PayTo: payTo}
+	handler := func(context.Context, mcpproto.CallToolRequest) (*mcpproto.CallToolResult, error) {
+		return mcpproto.NewToolResultText("ok"), nil
+	}
+	s := NewX402Server("test", "1.0.0", DefaultConfig())
+	s.AddTool(mcpproto.NewTool("echo"), handler)
+	search := mcpproto.NewTool("search")
+	search.Meta = &mcpproto.Meta{AdditionalFields: map[string]any{"ui/icon": "search.png"}}
+	if err := s.AddPayableTool(search, v2.ResourceInfo{}, []v2.PaymentRequirements{requirement}, handler); err != nil {
+		t.Fatalf("AddPayableTool failed: %v", err)
+	}
+	pricing := ArgumentPricing{Argument: "max_results", PerUnit: big.NewInt(100)}
+	if err := s.AddPricedTool(mcpproto.NewTool("report"), v2.ResourceInfo{}, []v2.PaymentRequirements{requirement}, pricing.Requirements, handler); err != nil {
+		t.Fatalf("AddPricedTool failed: %v", err)
+	}
+	if _, exists := search.Meta.AdditionalFields[mcp.ToolPriceMetaKey]; exists {
+		t.Error("expected the caller's tool metadata to be left unchanged")
+	}
+
+	message := s.GetMCPServer().HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	data, _ := json.Marshal(message)
+	var resp struct {
+		Result struct {
+			Tools []struct {
+				Name string                     `json:"name"`
+				Meta map[string]json.RawMessage `json:"_meta"`
+			} `json:"tools"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("decoding tools/list failed: %v", err)
+	}
+
+	tests := map[string]struct {
+		prices bool
+		want   mcp.ToolPrice
+		icon   bool
+	}{
+		"echo":   {},
+		"search": {prices: true, want: mcp.ToolPrice{Scheme: "exact", Network: "eip155:84532", Amount: "1000", Asset: asset, PayTo: payTo}, icon: true},
+		"report": {prices: true, want: mcp.ToolPrice{Scheme: "exact", Network: "eip155:84532", Amount: "1000", Asset: asset, PayTo: payTo, Dynamic: true}},
+	}
+	if len(resp.Result.Tools) != len(tests) {
+		t.Fatalf("expected %d tools, got %s", len(tests), data)
+	}
+	for _, tool := range resp.Result.Tools {
+		tt := tests[tool.Name]
+		raw, ok := tool.Meta[mcp.ToolPriceMetaKey]
+		if ok != tt.prices {
+			t.Errorf("%s: expected listed prices=%v, got %s", tool.Name, tt.prices, data)
+			continue
+		}
+		if _, icon := tool.Meta["ui/icon"]; icon != tt.icon {
+			t.Errorf("%s: expected other metadata=%v", tool.Name, tt.icon)
+		}
+		if !ok {
+			continue
+		}
+		var prices []mcp.ToolPrice
+		if err := json.Unmarshal(raw, &prices); err != nil || len(prices) != 1 || prices[0] != tt.want {
+			t.Errorf("%s: expected price %+v, got %s", tool.Name, tt.want, raw)
+		}
+	}
+}
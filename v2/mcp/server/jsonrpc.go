@@ -0,0 +1,235 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// JSON-RPC error codes returned by X402Handler.
+const (
+	// ErrorCodeParse indicates the request body is not valid JSON.
+	ErrorCodeParse = -32700
+
+	// ErrorCodeInvalidRequest indicates a message that is not a valid JSON-RPC 2.0
+	// request: wrong or missing "jsonrpc" version, missing method, invalid id,
+	// or an empty batch.
+	ErrorCodeInvalidRequest = -32600
+
+	// ErrorCodeInvalidParams indicates malformed tools/call params, including a
+	// _meta that is not an object.
+	ErrorCodeInvalidParams = -32602
+
+	// ErrorCodeInternal indicates a server-side failure, such as an unreachable
+	// facilitator, a failed settlement, or shutdown.
+	ErrorCodeInternal = -32603
+
+	// ErrorCodePaymentRequired indicates a missing, malformed, or rejected payment.
+	ErrorCodePaymentRequired = 402
+)
+
+// jsonrpcVersion is the only JSON-RPC version accepted.
+const jsonrpcVersion = "2.0"
+
+// paymentMetaKey is the _meta key carrying the x402 payment payload.
+const paymentMetaKey = "x402/payment"
+
+// rpcError is a JSON-RPC error object.
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+// rpcMessage is one JSON-RPC message: a request, a notification, or a response
+// sent by the client to a server-initiated request.
+type rpcMessage struct {
+	Method string
+	Params json.RawMessage
+	ID     interface{}
+
+	// HasID is false for notifications.
+	HasID bool
+
+	// IsResponse is true for client responses, which carry result or error instead of method.
+	IsResponse bool
+}
+
+// toolCallParams are the params of a tools/call request.
+type toolCallParams struct {
+	Name      string
+	Arguments json.RawMessage
+
+	// Payment is the raw x402 payment from _meta, decoded by payment.
+	Payment json.RawMessage
+
+	// Voucher is the raw bundle voucher from _meta, decoded by voucher.
+	Voucher json.RawMessage
+}
+
+// parseBody splits a request body into its messages. batch reports whether the
+// body was a JSON array. Individual messages are not validated.
+func parseBody(body []byte) (messages []json.RawMessage, batch bool, rpcErr *rpcError) {
+	trimmed := bytes.TrimSpace(body)
+	if !json.Valid(trimmed) {
+		return nil, false, &rpcError{Code: ErrorCodeParse, Message: "Parse error"}
+	}
+
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &messages); err != nil {
+			return nil, true, &rpcError{Code: ErrorCodeParse, Message: "Parse error"}
+		}
+		if len(messages) == 0 {
+			return nil, true, &rpcError{Code: ErrorCodeInvalidRequest, Message: "Invalid Request", Data: "empty batch"}
+		}
+		return messages, true, nil
+	}
+	return []json.RawMessage{trimmed}, false, nil
+}
+
+// parseMessage validates a single JSON-RPC message. On error, the returned message
+// carries the request id when it could be determined, for use in the error response.
+//
+// The message is decoded into a struct, as the MCP server dispatching it does,
+// so keys match case-insensitively and the last duplicate wins: a message
+// cannot be classified here as one method and run as another.
+func parseMessage(raw json.RawMessage) (rpcMessage, *rpcError) {
+	var msg rpcMessage
+
+	var fields struct {
+		JSONRPC json.RawMessage `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Method  json.RawMessage `json:"method"`
+		Params  json.RawMessage `json:"params"`
+		Result  json.RawMessage `json:"result"`
+		Error   json.RawMessage `json:"error"`
+	}
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return msg, invalidRequest("message must be an object")
+	}
+	if err := json.Unmarshal(trimmed, &fields); err != nil {
+		return msg, invalidRequest("message must be an object")
+	}
+
+	if fields.ID != nil {
+		var id interface{}
+		if err := json.Unmarshal(fields.ID, &id); err != nil {
+			return msg, invalidRequest("invalid id")
+		}
+		switch id.(type) {
+		case string, float64, nil:
+			msg.ID, msg.HasID = id, true
+		default:
+			return msg, invalidRequest("id must be a string, number, or null")
+		}
+	}
+
+	var version string
+	if err := json.Unmarshal(fields.JSONRPC, &version); err != nil || version != jsonrpcVersion {
+		return msg, invalidRequest(fmt.Sprintf("jsonrpc must be %q", jsonrpcVersion))
+	}
+
+	if fields.Method == nil {
+		if (fields.Result != nil || fields.Error != nil) && msg.HasID {
+			msg.IsResponse = true
+			return msg, nil
+		}
+		return msg, invalidRequest("method is required")
+	}
+	if err := json.Unmarshal(fields.Method, &msg.Method); err != nil || msg.Method == "" {
+		return msg, invalidRequest("method must be a non-empty string")
+	}
+
+	if fields.Params != nil {
+		params := bytes.TrimSpace(fields.Params)
+		if len(params) == 0 || (params[0] != '{' && params[0] != '[') {
+			return msg, invalidRequest("params must be an object or array")
+		}
+		msg.Params = fields.Params
+	}
+	return msg, nil
+}
+
+// parseToolCall validates tools/call params. Only the fields the handler needs
+// are decoded; the payment is kept raw so it is unmarshaled once, by payment.
+func parseToolCall(params json.RawMessage) (*toolCallParams, *rpcError) {
+	if len(params) == 0 {
+		return nil, invalidParams("params are required")
+	}
+
+	var fields struct {
+		Name      json.RawMessage `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+		Meta      json.RawMessage `json:"_meta"`
+	}
+	if err := json.Unmarshal(params, &fields); err != nil {
+		return nil, invalidParams("params must be an object")
+	}
+
+	var call toolCallParams
+	if err := json.Unmarshal(fields.Name, &call.Name); err != nil || call.Name == "" {
+		return nil, invalidParams("name must be a non-empty string")
+	}
+	call.Arguments = fields.Arguments
+
+	if len(fields.Meta) > 0 && !isJSONNull(fields.Meta) {
+		var meta struct {
+			Payment json.RawMessage `json:"x402/payment"`
+			Voucher json.RawMessage `json:"x402/voucher"`
+		}
+		if err := json.Unmarshal(fields.Meta, &meta); err != nil {
+			return nil, invalidParams("_meta must be an object")
+		}
+		call.Payment = meta.Payment
+		call.Voucher = meta.Voucher
+	}
+	return &call, nil
+}
+
+// payment decodes the x402 payment from _meta. It returns nil if no payment was sent.
+func (p *toolCallParams) payment() (*v2.PaymentPayload, *rpcError) {
+	if len(p.Payment) == 0 || isJSONNull(p.Payment) {
+		return nil, nil
+	}
+
+	var payment v2.PaymentPayload
+	if err := json.Unmarshal(p.Payment, &payment); err != nil {
+		return nil, &rpcError{Code: ErrorCodePaymentRequired, Message: "Payment invalid: malformed " + paymentMetaKey}
+	}
+	if payment.X402Version != v2.X402Version {
+		return nil, &rpcError{
+			Code:    ErrorCodePaymentRequired,
+			Message: fmt.Sprintf("Payment invalid: unsupported x402 version %d, expected %d", payment.X402Version, v2.X402Version),
+		}
+	}
+	return &payment, nil
+}
+
+// voucher decodes the bundle voucher token from _meta. ok is false if no
+// voucher was sent; a voucher that is not a string decodes as empty.
+func (p *toolCallParams) voucher() (token string, ok bool) {
+	if len(p.Voucher) == 0 || isJSONNull(p.Voucher) {
+		return "", false
+	}
+	_ = json.Unmarshal(p.Voucher, &token)
+	return token, true
+}
+
+func invalidRequest(reason string) *rpcError {
+	return &rpcError{Code: ErrorCodeInvalidRequest, Message: "Invalid Request", Data: reason}
+}
+
+func invalidParams(reason string) *rpcError {
+	return &rpcError{Code: ErrorCodeInvalidParams, Message: "Invalid params", Data: reason}
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return string(bytes.TrimSpace(raw)) == "null"
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		wantCode   int
+		wantMethod string
+		wantID     interface{}
+		isResponse bool
+	}{
+		{name: "valid request", raw: `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, wantMethod: "tools/list", wantID: float64(1)},
+		{name: "string id", raw: `{"jsonrpc":"2.0","id":"a","method":"ping"}`, wantMethod: "ping", wantID: "a"},
+		{name: "notification", raw: `{"jsonrpc":"2.0","method":"notifications/initialized"}`, wantMethod: "notifications/initialized"},
+		{name: "client response", raw: `{"jsonrpc":"2.0","id":7,"result":{}}`, wantID: float64(7), isResponse: true},
+		{name: "null id", raw: `{"jsonrpc":"2.0","id":null,"method":"ping"}`, wantMethod: "ping"},
+		// Keys match as the MCP server dispatching the message matches them
+		{name: "case-variant method", raw: `{"jsonrpc":"2.0","id":1,"method":"ping","Method":"tools/call","params":{"name":"paid"}}`, wantMethod: "tools/call", wantID: float64(1)},
+		{name: "duplicate method", raw: `{"jsonrpc":"2.0","id":1,"method":"ping","method":"tools/call","params":{"name":"paid"}}`, wantMethod: "tools/call", wantID: float64(1)},
+		{name: "not an object", raw: `"tools/call"`, wantCode: ErrorCodeInvalidRequest},
+		{name: "missing version", raw: `{"id":1,"method":"ping"}`, wantCode: ErrorCodeInvalidRequest, wantID: float64(1)},
+		{name: "wrong version", raw: `{"jsonrpc":"1.0","id":1,"method":"ping"}`, wantCode: ErrorCodeInvalidRequest, wantID: float64(1)},
+		{name: "object id", raw: `{"jsonrpc":"2.0","id":{},"method":"ping"}`, wantCode: ErrorCodeInvalidRequest},
+		{name: "missing method", raw: `{"jsonrpc":"2.0","id":1}`, wantCode: ErrorCodeInvalidRequest, wantID: float64(1)},
+		{name: "non-string method", raw: `{"jsonrpc":"2.0","id":1,"method":5}`, wantCode: ErrorCodeInvalidRequest, wantID: float64(1)},
+		{name: "scalar params", raw: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":"x"}`, wantCode: ErrorCodeInvalidRequest, wantID: float64(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, rpcErr := parseMessage(json.RawMessage(tt.raw))
+			if tt.wantCode != 0 {
+				if rpcErr == nil || rpcErr.Code != tt.wantCode {
+					t.Fatalf("expected error code %d, got %v", tt.wantCode, rpcErr)
+				}
+			} else if rpcErr != nil {
+				t.Fatalf("unexpected error: %v", rpcErr)
+			}
+			if msg.ID != tt.wantID {
+				t.Errorf("expected id %v, got %v", tt.wantID, msg.ID)
+			}
+			if rpcErr == nil && (msg.Method != tt.wantMethod || msg.IsResponse != tt.isResponse) {
+				t.Errorf("unexpected message %+v", msg)
+			}
+		})
+	}
+}
+
+func TestParseToolCall(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     string
+		wantCode   int
+		hasPayment bool
+	}{
+		{name: "no payment", params: `{"name":"t","arguments":{}}`},
+		{name: "null meta", params: `{"name":"t","_meta":null}`},
+		{name: "payment", params: `{"name":"t","_meta":{"x402/payment":{"x402Version":2,"payload":{}}}}`, hasPayment: true},
+		{name: "missing name", params: `{"arguments":{}}`, wantCode: ErrorCodeInvalidParams},
+		{name: "array params", params: `["t"]`, wantCode: ErrorCodeInvalidParams},
+		{name: "meta not an object", params: `{"name":"t","_meta":"x"}`, wantCode: ErrorCodeInvalidParams},
+		{name: "malformed payment", params: `{"name":"t","_meta":{"x402/payment":"abc"}}`, wantCode: ErrorCodePaymentRequired},
+		{name: "wrong x402 version", params: `{"name":"t","_meta":{"x402/payment":{"x402Version":1}}}`, wantCode: ErrorCodePaymentRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call, rpcErr := parseToolCall(json.RawMessage(tt.params))
+			var payment *v2.PaymentPayload
+			if rpcErr == nil {
+				payment, rpcErr = call.payment()
+			}
+			if tt.wantCode != 0 {
+				if rpcErr == nil || rpcErr.Code != tt.wantCode {
+					t.Fatalf("expected error code %d, got %v", tt.wantCode, rpcErr)
+				}
+				return
+			}
+			if rpcErr != nil {
+				t.Fatalf("unexpected error: %v", rpcErr)
+			}
+			if (payment != nil) != tt.hasPayment {
+				t.Errorf("expected payment=%v, got %+v", tt.hasPayment, payment)
+			}
+		})
+	}
+}
+
+func TestHandler_MalformedRequests(t *testing.T) {
+	handler := &X402Handler{
+		mcpHandler: &mockMCPHandler{response: map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": map[string]interface{}{}}, statusCode: http.StatusOK},
+		config: &Config{
+			FacilitatorURL: "http://example.com",
+			PaymentTools: map[string]ToolPaymentConfig{
+				"paid_tool": {
+					Resource:     v2.ResourceInfo{URL: "mcp://tools/paid_tool"},
+					Requirements: []v2.PaymentRequirements{{Scheme: "exact", Network: "eip155:84532", Amount: "10000"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{name: "invalid JSON", body: `{"jsonrpc":`, wantCode: ErrorCodeParse},
+		{name: "empty batch", body: `[]`, wantCode: ErrorCodeInvalidRequest},
+		{name: "wrong version", body: `{"jsonrpc":"1.0","id":1,"method":"tools/call"}`, wantCode: ErrorCodeInvalidRequest},
+		{name: "meta not an object", body: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"paid_tool","_meta":[]}}`, wantCode: ErrorCodeInvalidParams},
+		{name: "malformed payment", body: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"paid_tool","_meta":{"x402/payment":42}}}`, wantCode: ErrorCodePaymentRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("POST", "/mcp", strings.NewReader(tt.body)))
+
+			var resp struct {
+				Error *rpcError `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response %q: %v", w.Body.String(), err)
+			}
+			if resp.Error == nil || resp.Error.Code != tt.wantCode {
+				t.Errorf("expected error code %d, got %+v", tt.wantCode, resp.Error)
+			}
+		})
+	}
+}
+
+func TestHandler_Batch(t *testing.T) {
+	handler := &X402Handler{
+		mcpHandler: &mockMCPHandler{response: map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": map[string]interface{}{}}, statusCode: http.StatusOK},
+		config: &Config{
+			FacilitatorURL: "http://example.com",
+			PaymentTools: map[string]ToolPaymentConfig{
+				"paid_tool": {
+					Resource:     v2.ResourceInfo{URL: "mcp://tools/paid_tool"},
+					Requirements: []v2.PaymentRequirements{{Scheme: "exact", Network: "eip155:84532", Amount: "10000"}},
+				},
+			},
+		},
+	}
+
+	body := `[
+		{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"free_tool"}},
+		{"jsonrpc":"2.0","method":"notifications/cancelled","params":{}},
+		{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"paid_tool"}}
+	]`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/mcp", strings.NewReader(body)))
+
+	var responses []struct {
+		ID     interface{}     `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &responses); err != nil {
+		t.Fatalf("expected a JSON array, got %q: %v", w.Body.String(), err)
+	}
+	// The notification gets no response even though the mock answers it
+	if len(responses) != 2 {
+		t.Fatalf("expected 2 responses, got %d", len(responses))
+	}
+	if responses[0].Result == nil {
+		t.Errorf("expected free tool to be forwarded, got %+v", responses[0])
+	}
+	if last := responses[1]; last.Error == nil || last.Error.Code != ErrorCodePaymentRequired || last.ID != float64(2) {
+		t.Errorf("expected payment required for paid tool, got %+v", last)
+	}
+}
+
+func TestHandler_BatchSettlement(t *testing.T) {
+	mock := &mockFacilitator{
+		verifyResponse: &v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"},
+		settleResponse: &v2.SettleResponse{Success: true, Transaction: "0xtx", Network: "eip155:84532", Payer: "0xPayerAddress"},
+	}
+	requirement := v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: "10000", Asset: "0xAsset", PayTo: "0xPayTo"}
+	handler := &X402Handler{
+		mcpHandler: &mockMCPHandler{response: map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": map[string]interface{}{"content": []interface{}{}}}, statusCode: http.StatusOK},
+		config: &Config{
+			FacilitatorURL: "http://example.com",
+			PaymentTools: map[string]ToolPaymentConfig{
+				"paid_tool": {Resource: v2.ResourceInfo{URL: "mcp://tools/paid_tool"}, Requirements: []v2.PaymentRequirements{requirement}},
+			},
+		},
+		facilitator: mock,
+	}
+
+	payment, _ := json.Marshal(v2.PaymentPayload{X402Version: 2, Accepted: requirement, Payload: map[string]interface{}{"signature": "0xsig"}})
+	body := `[
+		{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"paid_tool","_meta":{"x402/payment":` + string(payment) + `}}},
+		{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"paid_tool","_meta":"not-an-object"}}
+	]`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/mcp", strings.NewReader(body)))
+
+	var responses []struct {
+		Result struct {
+			Meta map[string]v2.SettleResponse `json:"_meta"`
+		} `json:"result"`
+		Error *rpcError `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &responses); err != nil || len(responses) != 2 {
+		t.Fatalf("expected 2 batched responses, got %q: %v", w.Body.String(), err)
+	}
+	if settled := responses[0].Result.Meta["x402/payment-response"]; !settled.Success || settled.Transaction != "0xtx" {
+		t.Errorf("expected settlement in _meta of paid item, got %+v", responses[0])
+	}
+	if responses[1].Error == nil || responses[1].Error.Code != ErrorCodeInvalidParams {
+		t.Errorf("expected malformed paid call not to be forwarded, got %+v", responses[1])
+	}
+}
+
+func BenchmarkParseToolCall(b *testing.B) {
+	payment, _ := json.Marshal(v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: "10000"},
+		Payload: map[string]interface{}{
+			"signature":     "0x" + strings.Repeat("ab", 65),
+			"authorization": map[string]interface{}{"from": "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266", "value": "10000"},
+		},
+	})
+	params := json.RawMessage(`{"name":"paid_tool","arguments":{"query":"x"},"_meta":{"progressToken":1,"x402/payment":` + string(payment) + `}}`)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		call, rpcErr := parseToolCall(params)
+		if rpcErr != nil {
+			b.Fatalf("parseToolCall failed: %v", rpcErr)
+		}
+		if _, rpcErr := call.payment(); rpcErr != nil {
+			b.Fatalf("payment failed: %v", rpcErr)
+		}
+	}
+}
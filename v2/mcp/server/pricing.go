@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// RequirementsFunc computes the payment requirements of a single tool call from
+// its arguments and the tool's configured requirements. It enables pricing by
+// argument, e.g. by the number of search results requested. Returned errors
+// reject the call as invalid params.
+type RequirementsFunc func(ctx context.Context, toolName string, arguments map[string]interface{}, base []v2.PaymentRequirements) ([]v2.PaymentRequirements, error)
+
+// ArgumentPricing prices a tool call by one of its numeric arguments: the
+// configured amount plus PerUnit for each unit of the argument. Its
+// Requirements method is a RequirementsFunc.
+type ArgumentPricing struct {
+	// Argument is the name of the numeric argument, e.g. "max_results".
+	Argument string
+
+	// PerUnit is the amount added per unit, in atomic units of each
+	// requirement's asset.
+	PerUnit *big.Int
+
+	// Default is the value used when the argument is absent.
+	Default int64
+
+	// Min and Max bound the argument. Calls outside them are rejected. A zero
+	// Max means no upper bound.
+	Min int64
+	Max int64
+}
+
+// Requirements returns base with each amount raised by PerUnit times the
+// argument.
+func (p ArgumentPricing) Requirements(_ context.Context, _ string, arguments map[string]interface{}, base []v2.PaymentRequirements) ([]v2.PaymentRequirements, error) {
+	units, err := p.units(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	requirements := make([]v2.PaymentRequirements, len(base))
+	for i, req := range base {
+		amount, ok := new(big.Int).SetString(req.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount %q", req.Amount)
+		}
+		if p.PerUnit != nil {
+			amount.Add(amount, new(big.Int).Mul(p.PerUnit, big.NewInt(units)))
+		}
+		req.Amount = amount.String()
+		requirements[i] = req
+	}
+	return requirements, nil
+}
+
+// units returns the value of the priced argument.
+func (p ArgumentPricing) units(arguments map[string]interface{}) (int64, error) {
+	value, ok := arguments[p.Argument]
+	if !ok || value == nil {
+		return p.Default, nil
+	}
+	var f float64
+	switch v := value.(type) {
+	case float64:
+		f = v
+	case json.Number:
+		var err error
+		if f, err = v.Float64(); err != nil {
+			return 0, fmt.Errorf("%s must be a number", p.Argument)
+		}
+	default:
+		return 0, fmt.Errorf("%s must be a number", p.Argument)
+	}
+	if f != math.Trunc(f) || f < math.MinInt64 || f > math.MaxInt64 {
+		return 0, fmt.Errorf("%s must be an integer", p.Argument)
+	}
+	units := int64(f)
+	if units < p.Min || (p.Max > 0 && units > p.Max) {
+		return 0, fmt.Errorf("%s must be between %d and %d", p.Argument, p.Min, p.Max)
+	}
+	return units, nil
+}
+
+// priceCall applies the tool's RequirementsFunc to the call's arguments,
+// replacing config's requirements.
+func (h *X402Handler) priceCall(ctx context.Context, call *toolCallParams, config *ToolPaymentConfig) *rpcError {
+	arguments := map[string]interface{}{}
+	if len(call.Arguments) > 0 && !isJSONNull(call.Arguments) {
+		if err := json.Unmarshal(call.Arguments, &arguments); err != nil {
+			return invalidParams("arguments must be an object")
+		}
+	}
+	requirements, err := config.RequirementsFunc(ctx, call.Name, arguments, config.Requirements)
+	if err != nil {
+		return invalidParams(err.Error())
+	}
+	if len(requirements) == 0 {
+		return invalidParams("no payment requirements for call")
+	}
+	config.Requirements = requirements
+	return nil
+}
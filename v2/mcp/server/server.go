@@ -1,18 +1,28 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"maps"
 	"net/http"
+	"sync"
 
 	mcpproto "github.com/mark3labs/mcp-go/mcp"
 	mcpserver "github.com/mark3labs/mcp-go/server"
 	v2 "github.com/mark3labs/x402-go/v2"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+	"github.com/mark3labs/x402-go/v2/mcp"
 )
 
 // X402Server wraps an MCP server and adds x402 v2 payment protection.
 type X402Server struct {
 	mcpServer *mcpserver.MCPServer
 	config    *Config
+
+	mu       sync.Mutex
+	handlers []*X402Handler
 }
 
 // NewX402Server creates a new MCP server with x402 v2 payment support.
@@ -43,7 +53,23 @@ func (s *X402Server) AddTool(tool mcpproto.Tool, handler mcpserver.ToolHandlerFu
 
 // AddPayableTool adds a paid tool with payment requirements.
 // The resource and requirements specify what payment options the server will accept.
+// The tool's prices are listed in its _meta under mcp.ToolPriceMetaKey.
 func (s *X402Server) AddPayableTool(tool mcpproto.Tool, resource v2.ResourceInfo, requirements []v2.PaymentRequirements, handler mcpserver.ToolHandlerFunc) error {
+	return s.addPaidTool(tool, resource, requirements, nil, handler)
+}
+
+// AddPricedTool adds a paid tool whose price depends on its arguments. Each
+// call is priced by pricing, starting from requirements, e.g. with the
+// Requirements method of an ArgumentPricing. Its listed prices are marked
+// dynamic.
+func (s *X402Server) AddPricedTool(tool mcpproto.Tool, resource v2.ResourceInfo, requirements []v2.PaymentRequirements, pricing RequirementsFunc, handler mcpserver.ToolHandlerFunc) error {
+	if pricing == nil {
+		return fmt.Errorf("no pricing given for priced tool %s", tool.Name)
+	}
+	return s.addPaidTool(tool, resource, requirements, pricing, handler)
+}
+
+func (s *X402Server) addPaidTool(tool mcpproto.Tool, resource v2.ResourceInfo, requirements []v2.PaymentRequirements, pricing RequirementsFunc, handler mcpserver.ToolHandlerFunc) error {
 	if len(requirements) == 0 {
 		return fmt.Errorf("at least one payment requirement must be provided for payable tool %s", tool.Name)
 	}
@@ -53,6 +79,9 @@ func (s *X402Server) AddPayableTool(tool mcpproto.Tool, resource v2.ResourceInfo
 		if err := ValidateRequirement(req); err != nil {
 			return fmt.Errorf("invalid requirement %d for tool %s: %w", i, tool.Name, err)
 		}
+		if err := s.config.Allowlist.Check(req); err != nil {
+			return fmt.Errorf("invalid requirement %d for tool %s: %w", i, tool.Name, err)
+		}
 	}
 
 	// Set resource URL if not specified
@@ -62,15 +91,55 @@ func (s *X402Server) AddPayableTool(tool mcpproto.Tool, resource v2.ResourceInfo
 
 	// Add payment configuration to config
 	s.config.PaymentTools[tool.Name] = ToolPaymentConfig{
-		Resource:     resource,
-		Requirements: requirements,
+		Resource:         resource,
+		Requirements:     requirements,
+		RequirementsFunc: pricing,
 	}
 
-	// Add tool to MCP server
+	// Add tool to MCP server, listing its prices
+	tool.Meta = withPrices(tool.Meta, mcp.ToolPrices(requirements, pricing != nil))
 	s.mcpServer.AddTool(tool, handler)
 	return nil
 }
 
+// AddBundle offers a bundle of prepaid calls of paid tools added before. Its
+// vouchers are issued by the config's Vouchers, which is created with a
+// generated key if unset.
+func (s *X402Server) AddBundle(bundle Bundle) error {
+	for i, req := range bundle.Requirements {
+		if err := ValidateRequirement(req); err != nil {
+			return fmt.Errorf("invalid requirement %d for bundle: %w", i, err)
+		}
+	}
+	if s.config.Vouchers == nil {
+		vouchers, err := NewVoucherIssuer(nil)
+		if err != nil {
+			return err
+		}
+		s.config.Vouchers = vouchers
+	}
+	bundles := append(s.config.Bundles, bundle)
+	config := *s.config
+	config.Bundles = bundles
+	if err := config.checkBundles(); err != nil {
+		return err
+	}
+	s.config.Bundles = bundles
+	return nil
+}
+
+// withPrices returns a copy of meta with prices under mcp.ToolPriceMetaKey.
+func withPrices(meta *mcpproto.Meta, prices []mcp.ToolPrice) *mcpproto.Meta {
+	fields := make(map[string]any)
+	var progressToken mcpproto.ProgressToken
+	if meta != nil {
+		maps.Copy(fields, meta.AdditionalFields)
+		progressToken = meta.ProgressToken
+	}
+	fields[mcp.ToolPriceMetaKey] = prices
+	return &mcpproto.Meta{ProgressToken: progressToken, AdditionalFields: fields}
+}
+
 // Handler returns an HTTP handler wrapped with x402 v2 payment middleware.
 // Returns an error if the handler cannot be created (e.g., invalid configuration).
 func (s *X402Server) Handler() (http.Handler, error) {
@@ -78,7 +147,68 @@ func (s *X402Server) Handler() (http.Handler, error) {
 	httpServer := mcpserver.NewStreamableHTTPServer(s.mcpServer)
 
 	// Wrap with x402 payment handler
-	return NewX402Handler(httpServer, s.config)
+	handler, err := NewX402Handler(httpServer, s.config)
+	if err != nil {
+		return nil, err
+	}
+	if s.config.PaymentNotifications && handler.notifier == nil {
+		handler.notifier = s.notifySession
+	}
+
+	s.mu.Lock()
+	s.handlers = append(s.handlers, handler)
+	s.mu.Unlock()
+	return handler, nil
+}
+
+// Shutdown stops accepting paid tool calls on every handler returned by Handler
+// and waits for in-flight calls to finish settling or ctx to expire.
+// The reports of all handlers are summed.
+func (s *X402Server) Shutdown(ctx context.Context) (v2http.ShutdownReport, error) {
+	s.mu.Lock()
+	handlers := append([]*X402Handler(nil), s.handlers...)
+	s.mu.Unlock()
+
+	var total v2http.ShutdownReport
+	var firstErr error
+	for _, handler := range handlers {
+		report, err := handler.Shutdown(ctx)
+		total.InFlight += report.InFlight
+		total.Flushed += report.Flushed
+		total.Abandoned += report.Abandoned
+		total.Rejected += report.Rejected
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return total, firstErr
+}
+
+// notifySession sends a payment notification to the MCP session that made the request.
+// Notifications for requests without a session are dropped.
+func (s *X402Server) notifySession(ctx context.Context, sessionID string, notification mcp.PaymentNotification) {
+	if sessionID == "" {
+		return
+	}
+	logger := s.config.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	data, err := json.Marshal(notification)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to marshal payment notification", "error", err)
+		return
+	}
+	var params map[string]any
+	if err := json.Unmarshal(data, &params); err != nil {
+		logger.WarnContext(ctx, "failed to convert payment notification", "error", err)
+		return
+	}
+
+	if err := s.mcpServer.SendNotificationToSpecificClient(sessionID, mcp.PaymentNotificationMethod, params); err != nil {
+		logger.DebugContext(ctx, "failed to send payment notification", "session", sessionID, "error", err)
+	}
 }
 
 // Start starts the MCP server on the given address.
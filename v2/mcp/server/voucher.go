@@ -0,0 +1,418 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/mcp"
+	"github.com/mark3labs/x402-go/v2/storage"
+)
+
+// Bundle offers Calls calls of Tools for one payment, settled once. The
+// buyer receives a voucher redeemed for the remaining calls without further
+// payments, saving a facilitator round trip and fee per call.
+type Bundle struct {
+	// Tools are the paid tools the bundle's calls may be spent on. Tools
+	// priced by their arguments cannot be bundled.
+	Tools []string
+
+	// Calls is the number of calls, including the call buying the bundle.
+	Calls int
+
+	// Validity is how long the voucher is valid after purchase. Zero means
+	// it does not expire.
+	Validity time.Duration
+
+	// Requirements are the ways to pay for the bundle. Their amounts must
+	// differ from the per-call prices of the bundle's tools, as the amount
+	// paid tells a bundle purchase from a single call.
+	Requirements []v2.PaymentRequirements
+}
+
+// offer returns the bundle as advertised to clients.
+func (b Bundle) offer() mcp.BundleOffer {
+	return mcp.BundleOffer{
+		Tools:           b.Tools,
+		Calls:           b.Calls,
+		ValiditySeconds: int64(b.Validity / time.Second),
+		Accepts:         b.Requirements,
+	}
+}
+
+// includes reports whether the bundle's calls may be spent on tool.
+func (b Bundle) includes(tool string) bool {
+	for _, t := range b.Tools {
+		if t == tool {
+			return true
+		}
+	}
+	return false
+}
+
+// voucherKeyPrefix prefixes the store keys of the calls redeemed per voucher.
+const voucherKeyPrefix = "voucher:"
+
+// voucherClaims are the signed contents of a voucher token.
+type voucherClaims struct {
+	ID          string    `json:"id"`
+	Tools       []string  `json:"tools"`
+	Calls       int       `json:"calls"`
+	Expires     time.Time `json:"expires,omitempty"`
+	Payer       string    `json:"payer,omitempty"`
+	Network     string    `json:"network"`
+	Transaction string    `json:"transaction,omitempty"`
+}
+
+// VoucherIssuer issues bundle vouchers and redeems their calls. Vouchers are
+// signed with an ed25519 key and the calls redeemed are counted in a store;
+// replicas sharing the key and store accept each other's vouchers. It is safe
+// for concurrent use.
+//
+// Vouchers are bearer tokens: they record the payer but are not bound to it,
+// and anyone holding a voucher's token can redeem its remaining calls.
+type VoucherIssuer struct {
+	key   ed25519.PrivateKey
+	store storage.Store
+	clock v2.Clock
+}
+
+// VoucherOption configures a VoucherIssuer.
+type VoucherOption func(*VoucherIssuer)
+
+// WithVoucherStore counts redeemed calls in store instead of memory, so
+// vouchers survive restarts and can be redeemed on any replica. Use a
+// storage.Prefixed view when the store is shared.
+func WithVoucherStore(store storage.Store) VoucherOption {
+	return func(i *VoucherIssuer) {
+		i.store = store
+	}
+}
+
+// WithVoucherClock sets the clock vouchers expire by.
+func WithVoucherClock(clock v2.Clock) VoucherOption {
+	return func(i *VoucherIssuer) {
+		i.clock = v2.ClockOrSystem(clock)
+	}
+}
+
+// NewVoucherIssuer creates a VoucherIssuer signing with key. A nil key
+// generates one, valid until the process exits; since it is never exposed, it
+// cannot be shared, and replicas must be given the same key instead.
+func NewVoucherIssuer(key ed25519.PrivateKey, opts ...VoucherOption) (*VoucherIssuer, error) {
+	if key == nil {
+		var err error
+		if _, key, err = ed25519.GenerateKey(rand.Reader); err != nil {
+			return nil, fmt.Errorf("generating voucher key: %w", err)
+		}
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid voucher key size %d", len(key))
+	}
+	i := &VoucherIssuer{key: key, clock: v2.SystemClock}
+	for _, opt := range opts {
+		opt(i)
+	}
+	if i.store == nil {
+		i.store = storage.NewMemory()
+	}
+	return i, nil
+}
+
+// Issue issues a voucher for bundle, paid by payer in transaction on
+// network, and redeems its first call: the call buying it.
+func (i *VoucherIssuer) Issue(ctx context.Context, bundle Bundle, payer, network, transaction string) (mcp.Voucher, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return mcp.Voucher{}, err
+	}
+	claims := voucherClaims{
+		ID:          hex.EncodeToString(id),
+		Tools:       bundle.Tools,
+		Calls:       bundle.Calls,
+		Payer:       payer,
+		Network:     network,
+		Transaction: transaction,
+	}
+	if bundle.Validity > 0 {
+		claims.Expires = i.clock.Now().Add(bundle.Validity).UTC().Truncate(time.Second)
+	}
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return mcp.Voucher{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(data) + "." +
+		base64.RawURLEncoding.EncodeToString(ed25519.Sign(i.key, data))
+
+	remaining, err := i.redeem(ctx, claims)
+	if err != nil {
+		return mcp.Voucher{}, err
+	}
+	return voucher(token, claims, remaining), nil
+}
+
+// Redeem redeems one call of tool with the voucher token, returning the
+// voucher with its remaining calls. It fails with mcp.ErrInvalidVoucher,
+// mcp.ErrVoucherExpired or mcp.ErrVoucherExhausted.
+func (i *VoucherIssuer) Redeem(ctx context.Context, token, tool string) (mcp.Voucher, error) {
+	claims, err := i.verify(token)
+	if err != nil {
+		return mcp.Voucher{}, err
+	}
+	if !(Bundle{Tools: claims.Tools}).includes(tool) {
+		return mcp.Voucher{}, fmt.Errorf("%w: not valid for tool %s", mcp.ErrInvalidVoucher, tool)
+	}
+	if !claims.Expires.IsZero() && !i.clock.Now().Before(claims.Expires) {
+		return mcp.Voucher{}, mcp.ErrVoucherExpired
+	}
+	remaining, err := i.redeem(ctx, claims)
+	if err != nil {
+		return mcp.Voucher{}, err
+	}
+	return voucher(token, claims, remaining), nil
+}
+
+// Refund returns a call redeemed with the voucher token, e.g. when the tool
+// failed.
+func (i *VoucherIssuer) Refund(ctx context.Context, token string) error {
+	claims, err := i.verify(token)
+	if err != nil {
+		return err
+	}
+	_, err = storage.Update(ctx, i.store, voucherKeyPrefix+claims.ID, i.ttl(claims), func(old []byte, found bool) ([]byte, error) {
+		used, err := usedCalls(old, found)
+		if err != nil {
+			return nil, err
+		}
+		if used > 0 {
+			used--
+		}
+		return []byte(strconv.Itoa(used)), nil
+	})
+	return err
+}
+
+// redeem counts a call of the voucher, returning the calls remaining.
+func (i *VoucherIssuer) redeem(ctx context.Context, claims voucherClaims) (int, error) {
+	value, err := storage.Update(ctx, i.store, voucherKeyPrefix+claims.ID, i.ttl(claims), func(old []byte, found bool) ([]byte, error) {
+		used, err := usedCalls(old, found)
+		if err != nil {
+			return nil, err
+		}
+		if used >= claims.Calls {
+			return nil, mcp.ErrVoucherExhausted
+		}
+		return []byte(strconv.Itoa(used + 1)), nil
+	})
+	if err != nil {
+		if errors.Is(err, mcp.ErrVoucherExhausted) {
+			return 0, err
+		}
+		return 0, fmt.Errorf("redeeming voucher: %w", err)
+	}
+	used, _ := strconv.Atoi(string(value))
+	return claims.Calls - used, nil
+}
+
+// ttl returns how long the calls redeemed with a voucher are kept: until
+// shortly after it expires, or forever.
+func (i *VoucherIssuer) ttl(claims voucherClaims) time.Duration {
+	if claims.Expires.IsZero() {
+		return 0
+	}
+	return max(claims.Expires.Sub(i.clock.Now())+time.Hour, time.Minute)
+}
+
+// verify checks the signature of a voucher token and returns its claims.
+func (i *VoucherIssuer) verify(token string) (voucherClaims, error) {
+	var claims voucherClaims
+	encoded, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return claims, mcp.ErrInvalidVoucher
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return claims, mcp.ErrInvalidVoucher
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !ed25519.Verify(i.key.Public().(ed25519.PublicKey), data, signature) {
+		return claims, mcp.ErrInvalidVoucher
+	}
+	if err := json.Unmarshal(data, &claims); err != nil || claims.ID == "" || claims.Calls <= 0 {
+		return claims, mcp.ErrInvalidVoucher
+	}
+	return claims, nil
+}
+
+func usedCalls(old []byte, found bool) (int, error) {
+	if !found {
+		return 0, nil
+	}
+	return strconv.Atoi(string(old))
+}
+
+func voucher(token string, claims voucherClaims, remaining int) mcp.Voucher {
+	return mcp.Voucher{
+		Token:     token,
+		Tools:     claims.Tools,
+		Calls:     claims.Calls,
+		Remaining: remaining,
+		Expires:   claims.Expires,
+	}
+}
+
+// bundleSchema is the schema published with the bundle extension's info.
+var bundleSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []interface{}{"offers"},
+	"properties": map[string]interface{}{
+		"offers": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"tools", "calls", "accepts"},
+				"properties": map[string]interface{}{
+					"tools":           map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+					"calls":           map[string]interface{}{"type": "integer", "minimum": 1},
+					"validitySeconds": map[string]interface{}{"type": "integer", "minimum": 0},
+					"accepts":         map[string]interface{}{"type": "array"},
+				},
+			},
+		},
+	},
+}
+
+// bundleExtension returns the bundle extension offering the bundles that
+// include toolName, if any.
+func (h *X402Handler) bundleExtension(toolName string) (v2.Extension, bool) {
+	if h.config.Vouchers == nil {
+		return v2.Extension{}, false
+	}
+	var info mcp.BundleInfo
+	for _, bundle := range h.config.Bundles {
+		if bundle.includes(toolName) {
+			info.Offers = append(info.Offers, bundle.offer())
+		}
+	}
+	if len(info.Offers) == 0 {
+		return v2.Extension{}, false
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return v2.Extension{}, false
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return v2.Extension{}, false
+	}
+	return v2.Extension{Info: fields, Schema: bundleSchema}, true
+}
+
+// findBundle returns the bundle including toolName whose price payment pays,
+// and the requirement paid, or nil if payment pays for the call alone.
+func (h *X402Handler) findBundle(toolName string, payment *v2.PaymentPayload) (*Bundle, *v2.PaymentRequirements) {
+	if h.config.Vouchers == nil {
+		return nil, nil
+	}
+	for i := range h.config.Bundles {
+		bundle := &h.config.Bundles[i]
+		if !bundle.includes(toolName) {
+			continue
+		}
+		for j := range bundle.Requirements {
+			if samePrice(bundle.Requirements[j], payment.Accepted) {
+				requirement := bundle.Requirements[j]
+				return bundle, &requirement
+			}
+		}
+	}
+	return nil, nil
+}
+
+// serveVoucher runs a paid tool call redeeming one call of a bundle's
+// voucher. The call is refunded if the tool fails. Rejected vouchers get a
+// payment required error, so the client can pay or buy a new bundle.
+func (h *X402Handler) serveVoucher(w http.ResponseWriter, r *http.Request, requestBody []byte, requestID interface{}, toolName, token string, config *ToolPaymentConfig, logger *slog.Logger) {
+	if h.drainer != nil {
+		release, err := h.drainer.Acquire()
+		if err != nil {
+			h.writeError(w, requestID, ErrorCodeInternal, "Server shutting down", nil)
+			return
+		}
+		defer release()
+	}
+
+	voucher, err := h.config.Vouchers.Redeem(r.Context(), token, toolName)
+	if err != nil {
+		if h.config.Verbose {
+			logger.InfoContext(r.Context(), "Voucher rejected", "error", err)
+		}
+		h.notify(r, mcp.PaymentNotification{
+			Stage:    mcp.PaymentStageFailed,
+			Tool:     toolName,
+			Resource: config.Resource.URL,
+			Reason:   err.Error(),
+		})
+		h.writeError(w, requestID, ErrorCodePaymentRequired, fmt.Sprintf("Voucher invalid: %v", err), h.paymentRequiredData(toolName, config))
+		return
+	}
+	refund := func() {
+		if err := h.config.Vouchers.Refund(r.Context(), token); err != nil {
+			logger.WarnContext(r.Context(), "Failed to refund voucher call", "error", err)
+		}
+	}
+
+	recorder := h.newRecorder(true)
+	defer recorder.body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(requestBody))
+	h.mcpHandler.ServeHTTP(recorder, r)
+
+	if recorder.body.Overflowed() {
+		refund()
+		h.writeError(w, requestID, ErrorCodeInternal, "Response too large", fmt.Sprintf("tool response exceeds %d bytes", recorder.body.limit))
+		return
+	}
+	shape, err := inspectResponse(io.NewSectionReader(recorder.body.ReaderAt(), 0, recorder.body.Len()))
+	if err != nil || shape.IsError {
+		refund()
+		recorder.forward(w)
+		return
+	}
+	h.notify(r, mcp.PaymentNotification{
+		Stage:    mcp.PaymentStageRedeemed,
+		Tool:     toolName,
+		Resource: config.Resource.URL,
+	})
+
+	for k, v := range recorder.headerMap {
+		w.Header()[k] = v
+	}
+	if !shape.ResultObject {
+		w.WriteHeader(recorder.statusCode)
+		_, _ = recorder.body.WriteTo(w)
+		return
+	}
+	meta := shape.Meta
+	if meta == nil {
+		meta = make(map[string]interface{})
+	}
+	meta[mcp.VoucherMetaKey] = voucher
+	w.Header().Del("Content-Length")
+	w.WriteHeader(recorder.statusCode)
+	if err := writeWithMeta(w, &recorder.body, shape, meta); err != nil && h.config.Verbose {
+		logger.ErrorContext(r.Context(), "Failed to write MCP response", "error", err)
+	}
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/extensions"
+	"github.com/mark3labs/x402-go/v2/mcp"
+	"github.com/mark3labs/x402-go/v2/storage"
+)
+
+func TestVoucherIssuer(t *testing.T) {
+	ctx := context.Background()
+	clock := v2.NewFakeClock(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	store := storage.NewMemory()
+	issuer, err := NewVoucherIssuer(nil, WithVoucherStore(store), WithVoucherClock(clock))
+	if err != nil {
+		t.Fatalf("NewVoucherIssuer failed: %v", err)
+	}
+	bundle := Bundle{Tools: []string{"search", "fetch"}, Calls: 3, Validity: time.Hour}
+
+	voucher, err := issuer.Issue(ctx, bundle, "0xPayer", "eip155:84532", "0xtx")
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if voucher.Remaining != 2 || voucher.Calls != 3 || !voucher.Expires.Equal(clock.Now().Add(time.Hour)) {
+		t.Fatalf("unexpected voucher %+v", voucher)
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		tool      string
+		wantErr   error
+		remaining int
+	}{
+		{name: "second call", token: voucher.Token, tool: "fetch", remaining: 1},
+		{name: "other tool", token: voucher.Token, tool: "report", wantErr: mcp.ErrInvalidVoucher},
+		{name: "tampered", token: "x" + voucher.Token, tool: "search", wantErr: mcp.ErrInvalidVoucher},
+		{name: "malformed", token: "voucher", tool: "search", wantErr: mcp.ErrInvalidVoucher},
+		{name: "last call", token: voucher.Token, tool: "search", remaining: 0},
+		{name: "exhausted", token: voucher.Token, tool: "search", wantErr: mcp.ErrVoucherExhausted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redeemed, err := issuer.Redeem(ctx, tt.token, tt.tool)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && redeemed.Remaining != tt.remaining {
+				t.Errorf("expected %d calls remaining, got %d", tt.remaining, redeemed.Remaining)
+			}
+		})
+	}
+
+	// A refunded call can be redeemed again, until the voucher expires
+	if err := issuer.Refund(ctx, voucher.Token); err != nil {
+		t.Fatalf("Refund failed: %v", err)
+	}
+	if _, err := issuer.Redeem(ctx, voucher.Token, "search"); err != nil {
+		t.Errorf("expected the refunded call to be redeemed, got %v", err)
+	}
+	voucher, _ = issuer.Issue(ctx, bundle, "0xPayer", "eip155:84532", "0xtx")
+	clock.Advance(time.Hour)
+	if _, err := issuer.Redeem(ctx, voucher.Token, "search"); !errors.Is(err, mcp.ErrVoucherExpired) {
+		t.Errorf("expected ErrVoucherExpired, got %v", err)
+	}
+
+	// Vouchers of another key are not accepted, even with the same store
+	other, _ := NewVoucherIssuer(nil, WithVoucherStore(store), WithVoucherClock(clock))
+	voucher, _ = other.Issue(ctx, Bundle{Tools: []string{"search"}, Calls: 3}, "0xPayer", "eip155:84532", "0xtx")
+	if _, err := issuer.Redeem(ctx, voucher.Token, "search"); !errors.Is(err, mcp.ErrInvalidVoucher) {
+		t.Errorf("expected ErrInvalidVoucher, got %v", err)
+	}
+}
+
+func TestHandler_Bundles(t *testing.T) {
+	call := v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: "1000", Asset: "0xAsset", PayTo: "0xPayTo"}
+	price := call
+	price.Amount = "2500"
+	vouchers, _ := NewVoucherIssuer(nil)
+	config := &Config{
+		PaymentTools: map[string]ToolPaymentConfig{"search": {Requirements: []v2.PaymentRequirements{call}}},
+		Bundles:      []Bundle{{Tools: []string{"search"}, Calls: 3, Requirements: []v2.PaymentRequirements{price}}},
+		Vouchers:     vouchers,
+	}
+	mock := &mockFacilitator{
+		verifyResponse: &v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"},
+		settleResponse: &v2.SettleResponse{Success: true, Transaction: "0xtx", Payer: "0xPayerAddress"},
+	}
+	succeeds := &mockMCPHandler{response: map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": map[string]interface{}{"content": []interface{}{}}}, statusCode: http.StatusOK}
+	fails := &mockMCPHandler{response: map[string]interface{}{"jsonrpc": "2.0", "id": 1, "error": map[string]interface{}{"code": -32603, "message": "tool failed"}}, statusCode: http.StatusOK}
+	handler := &X402Handler{mcpHandler: succeeds, facilitator: mock, config: config}
+
+	type response struct {
+		Result struct {
+			Meta struct {
+				Voucher *mcp.Voucher       `json:"x402/voucher"`
+				Payment *v2.SettleResponse `json:"x402/payment-response"`
+			} `json:"_meta"`
+		} `json:"result"`
+		Error *struct {
+			Code    int                     `json:"code"`
+			Message string                  `json:"message"`
+			Data    mcp.PaymentRequirements `json:"data"`
+		} `json:"error"`
+	}
+	send := func(meta string) response {
+		body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search","_meta":{` + meta + `}}}`
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/mcp", bytes.NewReader([]byte(body))))
+		var resp response
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decoding response failed: %v: %s", err, w.Body.String())
+		}
+		return resp
+	}
+	pay := func(requirement v2.PaymentRequirements) string {
+		payment, _ := json.Marshal(v2.PaymentPayload{X402Version: 2, Accepted: requirement, Payload: map[string]interface{}{"signature": "0xsig"}})
+		return `"x402/payment":` + string(payment)
+	}
+	redeem := func(token string) string {
+		return `"x402/voucher":"` + token + `"`
+	}
+
+	// Payment required errors offer the bundle
+	resp := send("")
+	if resp.Error == nil || resp.Error.Code != ErrorCodePaymentRequired {
+		t.Fatalf("expected a payment required error, got %+v", resp)
+	}
+	var info mcp.BundleInfo
+	data, _ := json.Marshal(resp.Error.Data.Extensions[mcp.BundleExtension].Info)
+	if err := json.Unmarshal(data, &info); err != nil || len(info.Offers) != 1 || info.Offers[0].Calls != 3 || info.Offers[0].Accepts[0].Amount != "2500" {
+		t.Fatalf("expected the bundle to be offered, got %s", data)
+	}
+	registry, _ := extensions.NewRegistry()
+	if err := registry.Validate(resp.Error.Data.Extensions); err != nil {
+		t.Errorf("expected the bundle extension to satisfy its schema, got %v", err)
+	}
+
+	// Paying a call buys no voucher
+	resp = send(pay(call))
+	if resp.Error != nil || resp.Result.Meta.Voucher != nil || resp.Result.Meta.Payment == nil {
+		t.Fatalf("expected a settled call without voucher, got %+v", resp)
+	}
+
+	// Paying the bundle settles once and issues a voucher for the other calls
+	resp = send(pay(price))
+	voucher := resp.Result.Meta.Voucher
+	if resp.Error != nil || voucher == nil || voucher.Remaining != 2 || resp.Result.Meta.Payment == nil {
+		t.Fatalf("expected a voucher with 2 calls, got %+v", resp)
+	}
+
+	*mock = mockFacilitator{}
+	resp = send(redeem(voucher.Token))
+	if resp.Error != nil || resp.Result.Meta.Voucher == nil || resp.Result.Meta.Voucher.Remaining != 1 {
+		t.Fatalf("expected the voucher to be redeemed, got %+v", resp)
+	}
+	if mock.verifyCalled || mock.settleCalled {
+		t.Error("expected no facilitator call for a voucher")
+	}
+
+	// Failed calls are refunded
+	handler.mcpHandler = fails
+	if resp = send(redeem(voucher.Token)); resp.Error == nil || resp.Error.Message != "tool failed" {
+		t.Fatalf("expected the tool error, got %+v", resp)
+	}
+	handler.mcpHandler = succeeds
+	if resp = send(redeem(voucher.Token)); resp.Error != nil || resp.Result.Meta.Voucher.Remaining != 0 {
+		t.Fatalf("expected the last call to be redeemed, got %+v", resp)
+	}
+
+	for _, meta := range []string{redeem(voucher.Token), redeem("forged"), `"x402/voucher":42`} {
+		resp = send(meta)
+		if resp.Error == nil || resp.Error.Code != ErrorCodePaymentRequired || !strings.HasPrefix(resp.Error.Message, "Voucher invalid") || len(resp.Error.Data.Accepts) != 1 {
+			t.Errorf("%s: expected a payment required error, got %+v", meta, resp)
+		}
+	}
+
+	// A bundle settled without a voucher is an error naming the transaction
+	config.Vouchers, _ = NewVoucherIssuer(nil, WithVoucherStore(failingStore{storage.NewMemory()}))
+	*mock = mockFacilitator{
+		verifyResponse: &v2.VerifyResponse{IsValid: true, Payer: "0xPayerAddress"},
+		settleResponse: &v2.SettleResponse{Success: true, Transaction: "0xtx", Payer: "0xPayerAddress"},
+	}
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search","_meta":{` + pay(price) + `}}}`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/mcp", bytes.NewReader([]byte(body))))
+	var failed struct {
+		Error *struct {
+			Data struct {
+				Payment v2.SettleResponse `json:"x402/payment-response"`
+			} `json:"data"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &failed); err != nil || failed.Error == nil || failed.Error.Data.Payment.Transaction != "0xtx" {
+		t.Errorf("expected an error carrying the settlement, got %s", w.Body.String())
+	}
+}
+
+// failingStore is a store whose reads fail.
+type failingStore struct {
+	storage.Store
+}
+
+func (failingStore) Get(context.Context, string) ([]byte, error) {
+	return nil, errors.New("store down")
+}
+
+func TestConfig_CheckBundles(t *testing.T) {
+	call := v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Amount: "1000", Asset: "0xAsset", PayTo: "0xPayTo"}
+	price := call
+	price.Amount = "2500"
+	vouchers, _ := NewVoucherIssuer(nil)
+	tools := map[string]ToolPaymentConfig{
+		"search": {Requirements: []v2.PaymentRequirements{call}},
+		"priced": {Requirements: []v2.PaymentRequirements{call}, RequirementsFunc: ArgumentPricing{Argument: "n"}.Requirements},
+	}
+
+	tests := []struct {
+		name     string
+		bundle   Bundle
+		vouchers *VoucherIssuer
+		wantErr  string
+	}{
+		{name: "valid", bundle: Bundle{Tools: []string{"search"}, Calls: 10, Requirements: []v2.PaymentRequirements{price}}, vouchers: vouchers},
+		{name: "no voucher issuer", bundle: Bundle{Tools: []string{"search"}, Calls: 10, Requirements: []v2.PaymentRequirements{price}}, wantErr: "voucher issuer"},
+		{name: "no calls", bundle: Bundle{Tools: []string{"search"}, Requirements: []v2.PaymentRequirements{price}}, vouchers: vouchers, wantErr: "required"},
+		{name: "free tool", bundle: Bundle{Tools: []string{"echo"}, Calls: 10, Requirements: []v2.PaymentRequirements{price}}, vouchers: vouchers, wantErr: "not a paid tool"},
+		{name: "priced tool", bundle: Bundle{Tools: []string{"priced"}, Calls: 10, Requirements: []v2.PaymentRequirements{price}}, vouchers: vouchers, wantErr: "priced by its arguments"},
+		{name: "price of a call", bundle: Bundle{Tools: []string{"search"}, Calls: 10, Requirements: []v2.PaymentRequirements{call}}, vouchers: vouchers, wantErr: "price equals"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{FacilitatorURL: "https://facilitator.example.com", PaymentTools: tools, Bundles: []Bundle{tt.bundle}, Vouchers: tt.vouchers}
+			_, err := NewX402Handler(&mockMCPHandler{}, config)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
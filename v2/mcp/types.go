@@ -25,6 +25,119 @@ type PaymentRequirements struct {
 	// Accepts is an array of payment options the server will accept.
 	Accepts []v2.PaymentRequirements `json:"accepts"`
 
-	// Extensions contains protocol extensions (passthrough, not validated).
+	// Extensions contains protocol extensions, keyed by name (see the
+	// extensions package).
 	Extensions map[string]v2.Extension `json:"extensions,omitempty"`
 }
+
+// PaymentNotificationMethod is the JSON-RPC method used for x402 payment notifications
+// sent from an MCP server to its clients.
+const PaymentNotificationMethod = "notifications/x402/payment"
+
+// VerificationFailedMessage prefixes the message of the internal error a server
+// returns when the facilitator could not verify a payment. The payment was not
+// settled, so clients may retry the call with the same payment.
+const VerificationFailedMessage = "Verification failed"
+
+// PaymentStage identifies the point in the payment lifecycle a notification refers to.
+type PaymentStage string
+
+const (
+	// PaymentStageRequired indicates the server asked the client to pay for a tool call.
+	PaymentStageRequired PaymentStage = "required"
+
+	// PaymentStageVerified indicates the facilitator accepted the client's payment.
+	PaymentStageVerified PaymentStage = "verified"
+
+	// PaymentStageSettled indicates the payment was settled on-chain.
+	PaymentStageSettled PaymentStage = "settled"
+
+	// PaymentStageFailed indicates verification or settlement failed.
+	PaymentStageFailed PaymentStage = "failed"
+
+	// PaymentStageRedeemed indicates a call was paid with a bundle's voucher.
+	PaymentStageRedeemed PaymentStage = "redeemed"
+)
+
+// PaymentNotification is the params object of a notifications/x402/payment message.
+// It lets agent UIs display spend in real time without inspecting _meta on each result.
+type PaymentNotification struct {
+	// Stage is the lifecycle stage this notification describes.
+	Stage PaymentStage `json:"stage"`
+
+	// Tool is the name of the tool being paid for.
+	Tool string `json:"tool"`
+
+	// Resource is the URL of the protected resource.
+	Resource string `json:"resource,omitempty"`
+
+	// Scheme is the payment scheme (e.g., "exact").
+	Scheme string `json:"scheme,omitempty"`
+
+	// Network is the blockchain network (CAIP-2 format).
+	Network string `json:"network,omitempty"`
+
+	// Amount is the payment amount in atomic units.
+	Amount string `json:"amount,omitempty"`
+
+	// Asset is the token contract or mint address.
+	Asset string `json:"asset,omitempty"`
+
+	// PayTo is the payment recipient address.
+	PayTo string `json:"payTo,omitempty"`
+
+	// Payer is the address that made the payment (verified and settled stages).
+	Payer string `json:"payer,omitempty"`
+
+	// Transaction is the settlement transaction hash (settled stage only).
+	Transaction string `json:"transaction,omitempty"`
+
+	// Reason describes why the payment failed (failed stage only).
+	Reason string `json:"reason,omitempty"`
+
+	// Accepts lists the payment options offered (required stage only).
+	Accepts []v2.PaymentRequirements `json:"accepts,omitempty"`
+}
+
+// ToolPriceMetaKey is the _meta key under which servers list the prices of a
+// paid tool in tools/list results, as an array of ToolPrice.
+const ToolPriceMetaKey = "x402/price"
+
+// ToolPrice is one way to pay for a tool, advertised before it is called so
+// clients can display its cost.
+type ToolPrice struct {
+	// Scheme is the payment scheme (e.g., "exact").
+	Scheme string `json:"scheme"`
+
+	// Network is the blockchain network (CAIP-2 format).
+	Network string `json:"network"`
+
+	// Amount is the price in atomic units of Asset.
+	Amount string `json:"amount"`
+
+	// Asset is the token contract or mint address.
+	Asset string `json:"asset"`
+
+	// PayTo is the payment recipient address.
+	PayTo string `json:"payTo"`
+
+	// Dynamic is true if the price of a call depends on its arguments. Amount
+	// is then the base price the call's price is computed from.
+	Dynamic bool `json:"dynamic,omitempty"`
+}
+
+// ToolPrices returns the prices advertised for a tool accepting requirements.
+func ToolPrices(requirements []v2.PaymentRequirements, dynamic bool) []ToolPrice {
+	prices := make([]ToolPrice, len(requirements))
+	for i, req := range requirements {
+		prices[i] = ToolPrice{
+			Scheme:  req.Scheme,
+			Network: req.Network,
+			Amount:  req.Amount,
+			Asset:   req.Asset,
+			PayTo:   req.PayTo,
+			Dynamic: dynamic,
+		}
+	}
+	return prices
+}
@@ -0,0 +1,81 @@
+package v2
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// MinimumAmounts maps asset addresses to the smallest amount, in atomic units,
+// worth transferring. Servers use it as a floor price and clients as a dust
+// threshold, so that no transfer costs more in gas or fees than it is worth.
+// Addresses are compared case-insensitively; assets without an entry have no
+// minimum.
+type MinimumAmounts map[string]string
+
+// Validate ensures every minimum is a positive integer amount.
+func (m MinimumAmounts) Validate() error {
+	for asset, amount := range m {
+		if _, err := parseMinimum(amount); err != nil {
+			return fmt.Errorf("minimum amount for %s: %w", asset, err)
+		}
+	}
+	return nil
+}
+
+// Check returns an error wrapping ErrBelowMinimum if the amount of requirements
+// is below the minimum for its asset.
+func (m MinimumAmounts) Check(requirements PaymentRequirements) error {
+	minimum, ok := m.lookup(requirements.Asset)
+	if !ok {
+		return nil
+	}
+	floor, err := parseMinimum(minimum)
+	if err != nil {
+		return fmt.Errorf("minimum amount for %s: %w", requirements.Asset, err)
+	}
+	amount, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrInvalidAmount, requirements.Amount)
+	}
+	if amount.Cmp(floor) < 0 {
+		return fmt.Errorf("%w: %s of %s is below %s", ErrBelowMinimum, requirements.Amount, requirements.Asset, minimum)
+	}
+	return nil
+}
+
+// Minimum returns the minimum for asset, or nil if it has none or it is
+// invalid.
+func (m MinimumAmounts) Minimum(asset string) *big.Int {
+	minimum, ok := m.lookup(asset)
+	if !ok {
+		return nil
+	}
+	floor, err := parseMinimum(minimum)
+	if err != nil {
+		return nil
+	}
+	return floor
+}
+
+// lookup returns the minimum for asset.
+func (m MinimumAmounts) lookup(asset string) (string, bool) {
+	if minimum, ok := m[asset]; ok {
+		return minimum, true
+	}
+	for key, minimum := range m {
+		if strings.EqualFold(key, asset) {
+			return minimum, true
+		}
+	}
+	return "", false
+}
+
+// parseMinimum parses a positive atomic amount.
+func parseMinimum(amount string) (*big.Int, error) {
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok || value.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	return value, nil
+}
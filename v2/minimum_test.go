@@ -0,0 +1,45 @@
+package v2
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMinimumAmounts(t *testing.T) {
+	const usdc = "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
+	minimums := MinimumAmounts{usdc: "1000"}
+
+	tests := []struct {
+		name    string
+		asset   string
+		amount  string
+		wantErr error
+	}{
+		{name: "at minimum", asset: usdc, amount: "1000"},
+		{name: "above minimum", asset: usdc, amount: "250000"},
+		{name: "below minimum", asset: usdc, amount: "999", wantErr: ErrBelowMinimum},
+		{name: "address case ignored", asset: "0x036cbd53842c5426634e7929541ec2318f3dcf7e", amount: "1", wantErr: ErrBelowMinimum},
+		{name: "asset without minimum", asset: "0xother", amount: "1"},
+		{name: "invalid amount", asset: usdc, amount: "1.5", wantErr: ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := minimums.Check(PaymentRequirements{Asset: tt.asset, Amount: tt.amount})
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Check() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Check() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := minimums.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	for _, invalid := range []string{"0", "-1", "", "1e6"} {
+		if err := (MinimumAmounts{usdc: invalid}).Validate(); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("Validate(%q) error = %v, want ErrInvalidAmount", invalid, err)
+		}
+	}
+}
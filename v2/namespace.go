@@ -0,0 +1,112 @@
+package v2
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Built-in CAIP-2 namespaces.
+const (
+	NamespaceEIP155 = "eip155"
+	NamespaceSolana = "solana"
+)
+
+// ChainNamespace adds support for a CAIP-2 namespace beyond eip155 and solana.
+// Registered namespaces are consulted by ValidateNetwork, GetChainConfig, and the
+// validation package, so new ecosystems can be supported out-of-tree.
+type ChainNamespace interface {
+	// Namespace returns the CAIP-2 namespace (e.g., "sui").
+	Namespace() string
+
+	// ValidateReference checks the reference part of a network identifier.
+	ValidateReference(reference string) error
+
+	// ValidateAddress checks an account or asset address on the namespace.
+	ValidateAddress(address string) error
+
+	// ChainConfig returns the configuration for a known network of the namespace.
+	ChainConfig(network string) (ChainConfig, bool)
+}
+
+// SignerFactory is optionally implemented by a ChainNamespace that can create
+// signers from a private key. It is used by NewSignerForNetwork.
+type SignerFactory interface {
+	NewSigner(network, privateKey string, tokens []TokenConfig) (Signer, error)
+}
+
+var (
+	namespacesMu sync.RWMutex
+	namespaces   = make(map[string]ChainNamespace)
+)
+
+// RegisterNamespace registers a chain namespace.
+// Returns ErrNamespaceRegistered if the namespace is built in or already registered.
+func RegisterNamespace(namespace ChainNamespace) error {
+	name := namespace.Namespace()
+	if name == "" {
+		return fmt.Errorf("%w: empty namespace", ErrInvalidNetwork)
+	}
+	if name == NamespaceEIP155 || name == NamespaceSolana {
+		return fmt.Errorf("%w: %s", ErrNamespaceRegistered, name)
+	}
+
+	namespacesMu.Lock()
+	defer namespacesMu.Unlock()
+	if _, exists := namespaces[name]; exists {
+		return fmt.Errorf("%w: %s", ErrNamespaceRegistered, name)
+	}
+	namespaces[name] = namespace
+	return nil
+}
+
+// UnregisterNamespace removes a registered chain namespace.
+func UnregisterNamespace(name string) {
+	namespacesMu.Lock()
+	defer namespacesMu.Unlock()
+	delete(namespaces, name)
+}
+
+// LookupNamespace returns the chain namespace registered under name.
+func LookupNamespace(name string) (ChainNamespace, bool) {
+	namespacesMu.RLock()
+	defer namespacesMu.RUnlock()
+	namespace, ok := namespaces[name]
+	return namespace, ok
+}
+
+// RegisteredNamespaces returns the names of all registered chain namespaces, sorted.
+func RegisteredNamespaces() []string {
+	namespacesMu.RLock()
+	defer namespacesMu.RUnlock()
+	names := make([]string, 0, len(namespaces))
+	for name := range namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NamespaceOf returns the registered chain namespace of a network identifier.
+func NamespaceOf(network string) (ChainNamespace, bool) {
+	name, _, ok := strings.Cut(network, ":")
+	if !ok {
+		return nil, false
+	}
+	return LookupNamespace(name)
+}
+
+// NewSignerForNetwork creates a signer through the SignerFactory of the
+// network's registered namespace.
+func NewSignerForNetwork(network, privateKey string, tokens []TokenConfig) (Signer, error) {
+	namespace, ok := NamespaceOf(network)
+	if !ok {
+		return nil, fmt.Errorf("%w: no registered namespace for %s", ErrInvalidNetwork, network)
+	}
+	factory, ok := namespace.(SignerFactory)
+	if !ok {
+		return nil, fmt.Errorf("%w: namespace %s cannot create signers", ErrInvalidNetwork, namespace.Namespace())
+	}
+	return factory.NewSigner(network, privateKey, tokens)
+}
@@ -0,0 +1,90 @@
+package v2
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// tonNamespace is a test namespace that can create signers.
+type tonNamespace struct{}
+
+func (tonNamespace) Namespace() string { return "ton" }
+
+func (tonNamespace) ValidateReference(reference string) error {
+	if reference != "-239" && reference != "-3" {
+		return errors.New("unknown workchain")
+	}
+	return nil
+}
+
+func (tonNamespace) ValidateAddress(address string) error { return nil }
+
+func (tonNamespace) ChainConfig(network string) (ChainConfig, bool) {
+	return ChainConfig{Network: network, Decimals: 6}, network == "ton:-239"
+}
+
+func (tonNamespace) NewSigner(network, privateKey string, tokens []TokenConfig) (Signer, error) {
+	return &mockSigner{network: network, scheme: SchemeExact, tokens: tokens, maxAmount: big.NewInt(1)}, nil
+}
+
+func TestRegisterNamespace(t *testing.T) {
+	if _, err := ValidateNetwork("ton:-239"); !errors.Is(err, ErrInvalidNetwork) {
+		t.Errorf("expected unregistered namespace to be invalid, got %v", err)
+	}
+
+	if err := RegisterNamespace(tonNamespace{}); err != nil {
+		t.Fatalf("RegisterNamespace failed: %v", err)
+	}
+	defer UnregisterNamespace("ton")
+
+	if err := RegisterNamespace(tonNamespace{}); !errors.Is(err, ErrNamespaceRegistered) {
+		t.Errorf("expected ErrNamespaceRegistered, got %v", err)
+	}
+
+	tests := []struct {
+		network  string
+		wantType NetworkType
+		wantErr  bool
+	}{
+		{network: "ton:-239", wantType: NetworkTypeCustom},
+		{network: "ton:-1", wantErr: true},
+		{network: NetworkBase, wantType: NetworkTypeEVM},
+	}
+	for _, tt := range tests {
+		networkType, err := ValidateNetwork(tt.network)
+		if (err != nil) != tt.wantErr || networkType != tt.wantType {
+			t.Errorf("ValidateNetwork(%s) = %v, %v", tt.network, networkType, err)
+		}
+	}
+
+	if _, err := GetChainConfig("ton:-239"); err != nil {
+		t.Errorf("expected chain config from namespace, got %v", err)
+	}
+	if _, err := GetChainConfig("ton:-3"); !errors.Is(err, ErrInvalidNetwork) {
+		t.Errorf("expected ErrInvalidNetwork for unknown chain, got %v", err)
+	}
+
+	signer, err := NewSignerForNetwork("ton:-239", "key", nil)
+	if err != nil || signer.Network() != "ton:-239" {
+		t.Errorf("expected signer from factory, got %v, %v", signer, err)
+	}
+	if _, err := NewSignerForNetwork(NetworkBase, "key", nil); !errors.Is(err, ErrInvalidNetwork) {
+		t.Errorf("expected built-in namespaces to have no factory, got %v", err)
+	}
+}
+
+func TestRegisterNamespace_BuiltIn(t *testing.T) {
+	for _, name := range []string{NamespaceEIP155, NamespaceSolana} {
+		if err := RegisterNamespace(builtinNamespace(name)); !errors.Is(err, ErrNamespaceRegistered) {
+			t.Errorf("expected built-in %s to be reserved, got %v", name, err)
+		}
+	}
+}
+
+type builtinNamespace string
+
+func (b builtinNamespace) Namespace() string                    { return string(b) }
+func (builtinNamespace) ValidateReference(string) error         { return nil }
+func (builtinNamespace) ValidateAddress(string) error           { return nil }
+func (builtinNamespace) ChainConfig(string) (ChainConfig, bool) { return ChainConfig{}, false }
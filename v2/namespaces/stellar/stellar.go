@@ -0,0 +1,94 @@
+// Package stellar registers the Stellar CAIP-2 namespace with x402 v2. Servers
+// accepting Stellar payments import it for its side effects:
+//
+//	import _ "github.com/mark3labs/x402-go/v2/namespaces/stellar"
+//
+// Accounts are "G..." StrKeys and assets use the SEP-11 "CODE:ISSUER" form
+// (or "native" for XLM). Signers are provided by the signers/stellar package.
+package stellar
+
+import (
+	"fmt"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/stellar"
+)
+
+// Stellar CAIP-2 network identifiers.
+const (
+	NetworkPubnet  = "stellar:pubnet"
+	NetworkTestnet = "stellar:testnet"
+)
+
+var (
+	// Pubnet is the configuration for the Stellar public network.
+	Pubnet = v2.ChainConfig{
+		Network:     NetworkPubnet,
+		USDCAddress: "USDC:GA5ZSEJYB37JRC5AVCIA5MOP4RHTM335X2KGX3IHOJAPP5RE34K4KZVN",
+		Decimals:    7,
+	}
+
+	// Testnet is the configuration for the Stellar test network.
+	Testnet = v2.ChainConfig{
+		Network:     NetworkTestnet,
+		USDCAddress: "USDC:GBBD47IF6LWK7P7MDEVSCWR7DPUWV3NY3DTQEVFL4NAT4AQH3ZLLFLA5",
+		Decimals:    7,
+	}
+)
+
+func init() {
+	if err := v2.RegisterNamespace(Namespace{}); err != nil {
+		panic(err)
+	}
+}
+
+// Passphrase returns the network passphrase of a Stellar network.
+func Passphrase(network string) (string, error) {
+	switch network {
+	case NetworkPubnet:
+		return stellar.PassphrasePubnet, nil
+	case NetworkTestnet:
+		return stellar.PassphraseTestnet, nil
+	default:
+		return "", fmt.Errorf("%w: %s", v2.ErrInvalidNetwork, network)
+	}
+}
+
+// Namespace implements v2.ChainNamespace for Stellar.
+type Namespace struct{}
+
+// Namespace returns "stellar".
+func (Namespace) Namespace() string {
+	return "stellar"
+}
+
+// ValidateReference accepts the pubnet and testnet references.
+func (Namespace) ValidateReference(reference string) error {
+	if _, err := Passphrase("stellar:" + reference); err != nil {
+		return fmt.Errorf("unknown Stellar network %q", reference)
+	}
+	return nil
+}
+
+// ValidateAddress accepts account IDs and CODE:ISSUER or native assets.
+func (Namespace) ValidateAddress(address string) error {
+	if _, err := stellar.DecodeAccountID(address); err == nil {
+		return nil
+	}
+	if _, err := stellar.ParseAsset(address); err != nil {
+		return fmt.Errorf("invalid Stellar address %s: expected account ID or asset", address)
+	}
+	return nil
+}
+
+// ChainConfig returns the configuration for pubnet and testnet.
+func (Namespace) ChainConfig(network string) (v2.ChainConfig, bool) {
+	switch network {
+	case NetworkPubnet:
+		return Pubnet, true
+	case NetworkTestnet:
+		return Testnet, true
+	default:
+		return v2.ChainConfig{}, false
+	}
+}
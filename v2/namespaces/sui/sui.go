@@ -0,0 +1,102 @@
+// Package sui registers the Sui CAIP-2 namespace with x402 v2. It is a reference
+// implementation of v2.ChainNamespace; import it for its side effects:
+//
+//	import _ "github.com/mark3labs/x402-go/v2/namespaces/sui"
+//
+// Signing Sui payments is not supported, so the namespace does not implement
+// v2.SignerFactory.
+package sui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// Sui CAIP-2 network identifiers.
+const (
+	NetworkMainnet = "sui:mainnet"
+	NetworkTestnet = "sui:testnet"
+	NetworkDevnet  = "sui:devnet"
+)
+
+var (
+	// Mainnet is the configuration for Sui mainnet.
+	Mainnet = v2.ChainConfig{
+		Network:     NetworkMainnet,
+		USDCAddress: "0xdba34672e30cb065b1f93e3ab55318768fd6fef66c15942c9f7cb846e2f900e7::usdc::USDC",
+		Decimals:    6,
+	}
+
+	// Testnet is the configuration for Sui testnet.
+	Testnet = v2.ChainConfig{
+		Network:     NetworkTestnet,
+		USDCAddress: "0xa1ec7fc00a6f40db9693ad1415d0c193ad3906494428cf252621037bd7117e29::usdc::USDC",
+		Decimals:    6,
+	}
+)
+
+var (
+	// addressRegex matches Sui addresses (0x followed by up to 64 hex chars)
+	addressRegex = regexp.MustCompile(`^0x[a-fA-F0-9]{1,64}$`)
+
+	// identifierRegex matches Move module and struct identifiers
+	identifierRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+)
+
+func init() {
+	if err := v2.RegisterNamespace(Namespace{}); err != nil {
+		panic(err)
+	}
+}
+
+// Namespace implements v2.ChainNamespace for Sui.
+type Namespace struct{}
+
+// Namespace returns "sui".
+func (Namespace) Namespace() string {
+	return "sui"
+}
+
+// ValidateReference accepts the mainnet, testnet, devnet, and localnet references.
+func (Namespace) ValidateReference(reference string) error {
+	switch reference {
+	case "mainnet", "testnet", "devnet", "localnet":
+		return nil
+	default:
+		return fmt.Errorf("unknown Sui network %q", reference)
+	}
+}
+
+// ValidateAddress accepts account addresses and coin types
+// (e.g., "0x2::sui::SUI").
+func (Namespace) ValidateAddress(address string) error {
+	parts := strings.Split(address, "::")
+	switch len(parts) {
+	case 1:
+	case 3:
+		if !identifierRegex.MatchString(parts[1]) || !identifierRegex.MatchString(parts[2]) {
+			return fmt.Errorf("invalid Sui coin type: %s", address)
+		}
+	default:
+		return fmt.Errorf("invalid Sui address format: %s", address)
+	}
+	if !addressRegex.MatchString(parts[0]) {
+		return fmt.Errorf("invalid Sui address format: %s (expected 0x followed by up to 64 hex characters)", address)
+	}
+	return nil
+}
+
+// ChainConfig returns the configuration for Sui mainnet and testnet.
+func (Namespace) ChainConfig(network string) (v2.ChainConfig, bool) {
+	switch network {
+	case NetworkMainnet:
+		return Mainnet, true
+	case NetworkTestnet:
+		return Testnet, true
+	default:
+		return v2.ChainConfig{}, false
+	}
+}
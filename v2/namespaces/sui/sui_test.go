@@ -0,0 +1,53 @@
+package sui
+
+import (
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/validation"
+)
+
+func TestNamespace(t *testing.T) {
+	networkType, err := v2.ValidateNetwork(NetworkMainnet)
+	if err != nil || networkType != v2.NetworkTypeCustom {
+		t.Fatalf("expected sui:mainnet to be a custom network, got %v, %v", networkType, err)
+	}
+	if _, err := v2.ValidateNetwork("sui:nowhere"); err == nil {
+		t.Error("expected unknown Sui reference to be rejected")
+	}
+
+	config, err := v2.GetChainConfig(NetworkTestnet)
+	if err != nil || config.USDCAddress != Testnet.USDCAddress {
+		t.Errorf("expected testnet config, got %+v, %v", config, err)
+	}
+
+	if _, err := v2.NewSignerForNetwork(NetworkMainnet, "key", nil); err == nil {
+		t.Error("expected Sui signer creation to be unsupported")
+	}
+}
+
+func TestValidateAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		wantErr bool
+	}{
+		{name: "account", address: "0x209693bc6afc0c5328ba36faf03c514ef312287c209693bc6afc0c5328ba36fa"},
+		{name: "short account", address: "0x2"},
+		{name: "coin type", address: Mainnet.USDCAddress},
+		{name: "EVM style is valid hex", address: "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"},
+		{name: "no prefix", address: "209693bc6afc", wantErr: true},
+		{name: "too long", address: "0x" + "a" + "209693bc6afc0c5328ba36faf03c514ef312287c209693bc6afc0c5328ba36fa", wantErr: true},
+		{name: "bad coin type", address: "0x2::sui", wantErr: true},
+		{name: "bad identifier", address: "0x2::1sui::SUI", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validation.ValidateAddress(tt.address, NetworkMainnet)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAddress(%q) error = %v, wantErr %v", tt.address, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+// Package tron registers the TRON CAIP-2 namespace with x402 v2. Servers
+// accepting TRON payments import it for its side effects:
+//
+//	import _ "github.com/mark3labs/x402-go/v2/namespaces/tron"
+//
+// Addresses are base58check "T..." addresses. Signers are provided by the
+// signers/tron package.
+package tron
+
+import (
+	"fmt"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/tron"
+)
+
+// TRON CAIP-2 network identifiers.
+const (
+	NetworkMainnet = "tron:mainnet"
+	NetworkNile    = "tron:nile"
+	NetworkShasta  = "tron:shasta"
+)
+
+// USDT-TRC20 contract addresses.
+const (
+	USDTMainnet = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+	USDTNile    = "TXYZopYRdj2D9XRtbG411XZZ3kM5VkAeBf"
+)
+
+// chainIDs are the TIP-712 chain IDs: the last four bytes of each genesis block hash.
+var chainIDs = map[string]int64{
+	NetworkMainnet: 0x2b6653dc,
+	NetworkNile:    0xcd8690dc,
+	NetworkShasta:  0x94a9059e,
+}
+
+func init() {
+	if err := v2.RegisterNamespace(Namespace{}); err != nil {
+		panic(err)
+	}
+}
+
+// ChainID returns the TIP-712 chain ID of a TRON network.
+func ChainID(network string) (int64, error) {
+	chainID, ok := chainIDs[network]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", v2.ErrInvalidNetwork, network)
+	}
+	return chainID, nil
+}
+
+// NewUSDTTokenConfig creates a TokenConfig for USDT-TRC20 on network with the specified priority.
+func NewUSDTTokenConfig(network string, priority int) (v2.TokenConfig, error) {
+	var address string
+	switch network {
+	case NetworkMainnet:
+		address = USDTMainnet
+	case NetworkNile:
+		address = USDTNile
+	default:
+		return v2.TokenConfig{}, fmt.Errorf("%w: no USDT on %s", v2.ErrInvalidToken, network)
+	}
+	return v2.TokenConfig{
+		Address:  address,
+		Symbol:   "USDT",
+		Decimals: 6,
+		Priority: priority,
+		Name:     "Tether USD",
+	}, nil
+}
+
+// Namespace implements v2.ChainNamespace for TRON.
+type Namespace struct{}
+
+// Namespace returns "tron".
+func (Namespace) Namespace() string {
+	return "tron"
+}
+
+// ValidateReference accepts the mainnet, nile, and shasta references.
+func (Namespace) ValidateReference(reference string) error {
+	if _, ok := chainIDs["tron:"+reference]; !ok {
+		return fmt.Errorf("unknown TRON network %q", reference)
+	}
+	return nil
+}
+
+// ValidateAddress accepts base58check TRON addresses.
+func (Namespace) ValidateAddress(address string) error {
+	_, err := tron.DecodeAddress(address)
+	return err
+}
+
+// ChainConfig returns the configuration of a TRON network. USDC is not issued
+// on TRON, so USDCAddress is empty; use NewUSDTTokenConfig for tokens.
+func (Namespace) ChainConfig(network string) (v2.ChainConfig, bool) {
+	if _, ok := chainIDs[network]; !ok {
+		return v2.ChainConfig{}, false
+	}
+	return v2.ChainConfig{Network: network, Decimals: 6}, true
+}
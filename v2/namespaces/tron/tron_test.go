@@ -0,0 +1,42 @@
+package tron
+
+import (
+	"testing"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/validation"
+)
+
+func TestNamespace(t *testing.T) {
+	tests := []struct {
+		network string
+		wantErr bool
+	}{
+		{network: NetworkMainnet},
+		{network: NetworkNile},
+		{network: NetworkShasta},
+		{network: "tron:0x2b6653dc", wantErr: true},
+	}
+	for _, tt := range tests {
+		networkType, err := v2.ValidateNetwork(tt.network)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && networkType != v2.NetworkTypeCustom) {
+			t.Errorf("ValidateNetwork(%s) = %v, %v", tt.network, networkType, err)
+		}
+	}
+
+	for _, address := range []string{USDTMainnet, USDTNile} {
+		if err := validation.ValidateAddress(address, NetworkMainnet); err != nil {
+			t.Errorf("expected %s to be valid, got %v", address, err)
+		}
+	}
+	if err := validation.ValidateAddress("0xa614f803b6fd780986a42c78ec9c7f77e6ded13c", NetworkMainnet); err == nil {
+		t.Error("expected hex address to be rejected on TRON")
+	}
+
+	if chainID, err := ChainID(NetworkMainnet); err != nil || chainID != 728126428 {
+		t.Errorf("expected mainnet chain ID 728126428, got %d, %v", chainID, err)
+	}
+	if _, err := NewUSDTTokenConfig(NetworkShasta, 1); err == nil {
+		t.Error("expected no USDT on shasta")
+	}
+}
@@ -0,0 +1,139 @@
+package passkey
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// maxCBORDepth bounds the nesting of decoded CBOR values.
+const maxCBORDepth = 8
+
+// cborDecoder decodes the subset of CBOR (RFC 8949) used by WebAuthn
+// attestation objects and COSE keys: integers, byte and text strings, arrays,
+// maps and the simple values false, true and null. Integers decode as int64,
+// byte strings as []byte, text strings as string, arrays as []any and maps as
+// map[any]any keyed by int64 or string. Tags, floats and indefinite lengths
+// are rejected.
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+// decodeCBOR decodes the CBOR value at the start of data and returns the
+// bytes that follow it.
+func decodeCBOR(data []byte) (any, []byte, error) {
+	d := &cborDecoder{data: data}
+	value, err := d.value(0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidAssertion, err)
+	}
+	return value, data[d.pos:], nil
+}
+
+func (d *cborDecoder) value(depth int) (any, error) {
+	if depth > maxCBORDepth {
+		return nil, errors.New("cbor: nesting too deep")
+	}
+	if d.pos >= len(d.data) {
+		return nil, errors.New("cbor: unexpected end of data")
+	}
+	major, info := d.data[d.pos]>>5, d.data[d.pos]&0x1f
+	d.pos++
+
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+	}
+	n, err := d.argument(info)
+	if err != nil {
+		return nil, err
+	}
+	remaining := uint64(len(d.data) - d.pos)
+
+	switch major {
+	case 0, 1:
+		if n > math.MaxInt64 {
+			return nil, errors.New("cbor: integer overflow")
+		}
+		if major == 1 {
+			return -1 - int64(n), nil
+		}
+		return int64(n), nil
+	case 2, 3:
+		if n > remaining {
+			return nil, errors.New("cbor: string exceeds data")
+		}
+		s := d.data[d.pos : d.pos+int(n)]
+		d.pos += int(n)
+		if major == 3 {
+			return string(s), nil
+		}
+		return s, nil
+	case 4:
+		// Every item takes at least a byte
+		if n > remaining {
+			return nil, errors.New("cbor: array exceeds data")
+		}
+		items := make([]any, 0, n)
+		for i := uint64(0); i < n; i++ {
+			item, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 5:
+		if n > remaining/2 {
+			return nil, errors.New("cbor: map exceeds data")
+		}
+		entries := make(map[any]any, n)
+		for i := uint64(0); i < n; i++ {
+			key, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, fmt.Errorf("cbor: unsupported map key %T", key)
+			}
+			if _, ok := entries[key]; ok {
+				return nil, fmt.Errorf("cbor: duplicate map key %v", key)
+			}
+			if entries[key], err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return entries, nil
+	}
+	return nil, fmt.Errorf("cbor: unsupported major type %d", major)
+}
+
+// argument reads the argument of an item whose initial byte had additional
+// information info.
+func (d *cborDecoder) argument(info byte) (uint64, error) {
+	if info < 24 {
+		return uint64(info), nil
+	}
+	if info > 27 {
+		return 0, errors.New("cbor: indefinite or reserved length")
+	}
+	size := 1 << (info - 24)
+	if len(d.data)-d.pos < size {
+		return 0, errors.New("cbor: unexpected end of data")
+	}
+	var buf [8]byte
+	copy(buf[8-size:], d.data[d.pos:d.pos+size])
+	d.pos += size
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
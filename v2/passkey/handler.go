@@ -0,0 +1,491 @@
+package passkey
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ServeHTTP serves the approval page and the endpoints it calls, relative to
+// where the Approver is mounted.
+func (a *Approver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if origin := r.Header.Get("Origin"); origin != "" && !a.allowedOrigin(origin) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	path := "/" + strings.TrimPrefix(r.URL.Path, "/")
+	switch {
+	case path == "/" && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; connect-src 'self'")
+		_, _ = w.Write([]byte(approvalPage))
+	case path == "/pending" && r.Method == http.MethodGet:
+		a.servePending(w, r)
+	case path == "/register/begin" && r.Method == http.MethodPost:
+		a.serveRegisterBegin(w, r)
+	case path == "/register" && r.Method == http.MethodPost:
+		a.serveRegister(w, r)
+	case path == "/approve" && r.Method == http.MethodPost:
+		a.serveApprove(w, r)
+	case path == "/deny" && r.Method == http.MethodPost:
+		a.serveDeny(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// allowedOrigin reports whether origin is one of the Approver's origins.
+func (a *Approver) allowedOrigin(origin string) bool {
+	for _, allowed := range a.origins {
+		if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// servePending lists the payments waiting for approval and the passkeys that
+// can approve them.
+func (a *Approver) servePending(w http.ResponseWriter, r *http.Request) {
+	credentials, err := a.Credentials(r.Context())
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	ids := make([]string, 0, len(credentials))
+	for _, credential := range credentials {
+		ids = append(ids, credential.ID)
+	}
+	approvals := a.Pending()
+	sort.Slice(approvals, func(i, j int) bool { return approvals[i].Requested.Before(approvals[j].Requested) })
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"rpId":        a.rpID,
+		"credentials": ids,
+		"approvals":   approvals,
+	})
+}
+
+// serveRegisterBegin issues a registration challenge.
+func (a *Approver) serveRegisterBegin(w http.ResponseWriter, r *http.Request) {
+	challenge := make([]byte, 32)
+	userID := make([]byte, 16)
+	if _, err := rand.Read(challenge); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if _, err := rand.Read(userID); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(challenge)
+
+	now := time.Now()
+	a.mu.Lock()
+	for issued, expires := range a.registrations {
+		if now.After(expires) {
+			delete(a.registrations, issued)
+		}
+	}
+	a.registrations[encoded] = now.Add(registrationTTL)
+	a.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"rpId":      a.rpID,
+		"challenge": encoded,
+		"userId":    base64.RawURLEncoding.EncodeToString(userID),
+	})
+}
+
+// registration is the page's answer to a registration challenge.
+type registration struct {
+	Name              string `json:"name"`
+	Challenge         string `json:"challenge"`
+	ID                string `json:"id"`
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AttestationObject string `json:"attestationObject"`
+
+	// Authorization is an assertion of a registered passkey over the
+	// challenge, required once a passkey is registered.
+	Authorization *assertion `json:"authorization,omitempty"`
+}
+
+// serveRegister registers a new passkey. The first passkey is registered by
+// whoever holds the token; later ones must be authorized by a registered
+// passkey, so the token alone cannot add a key that approves payments.
+func (a *Approver) serveRegister(w http.ResponseWriter, r *http.Request) {
+	var reg registration
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&reg); err != nil {
+		http.Error(w, "Invalid registration", http.StatusBadRequest)
+		return
+	}
+	a.mu.Lock()
+	expires, ok := a.registrations[reg.Challenge]
+	delete(a.registrations, reg.Challenge)
+	a.mu.Unlock()
+	if !ok || time.Now().After(expires) {
+		http.Error(w, "Unknown or expired challenge", http.StatusBadRequest)
+		return
+	}
+
+	challenge, err := decodeField("challenge", reg.Challenge)
+	if err == nil {
+		var clientDataJSON []byte
+		if clientDataJSON, err = decodeField("clientDataJSON", reg.ClientDataJSON); err == nil {
+			err = a.verifyClientData(clientDataJSON, "webauthn.create", challenge)
+		}
+	}
+	var attested attestedCredential
+	if err == nil {
+		var attestationObject []byte
+		if attestationObject, err = decodeField("attestationObject", reg.AttestationObject); err == nil {
+			attested, err = a.parseAttestation(attestationObject)
+		}
+	}
+	if err == nil {
+		var id []byte
+		if id, err = decodeField("id", reg.ID); err == nil && !bytes.Equal(id, attested.ID) {
+			err = errors.Join(ErrInvalidAssertion, errors.New("credential ID does not match the attestation"))
+		}
+	}
+	var authorization *decodedAssertion
+	if err == nil && reg.Authorization != nil {
+		authorization, err = decodeAssertion(*reg.Authorization)
+		if err == nil {
+			err = a.verifyClientData(authorization.clientDataJSON, "webauthn.get", challenge)
+		}
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	credential := Credential{ID: reg.ID, Name: reg.Name, PublicKey: attested.PublicKey, SignCount: attested.SignCount, Created: time.Now()}
+	err = a.updateCredentials(r.Context(), func(credentials []Credential) ([]Credential, error) {
+		for _, existing := range credentials {
+			if existing.ID == credential.ID {
+				return nil, errPasskeyRegistered
+			}
+		}
+		if len(credentials) > 0 {
+			if authorization == nil {
+				return nil, errors.Join(ErrInvalidAssertion, errors.New("registration must be authorized by a registered passkey"))
+			}
+			if err := a.verifyCredentialAssertion(credentials, *authorization); err != nil {
+				return nil, err
+			}
+		}
+		return append(credentials, credential), nil
+	})
+	switch {
+	case errors.Is(err, errPasskeyRegistered):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrInvalidAssertion):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case err != nil:
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// assertion is the page's WebAuthn assertion approving a payment.
+type assertion struct {
+	ID                string `json:"id"`
+	CredentialID      string `json:"credentialId"`
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AuthenticatorData string `json:"authenticatorData"`
+	Signature         string `json:"signature"`
+}
+
+// decodedAssertion is an assertion with its fields decoded.
+type decodedAssertion struct {
+	credentialID      string
+	clientDataJSON    []byte
+	authenticatorData []byte
+	signature         []byte
+}
+
+// decodeAssertion decodes the base64url fields of body.
+func decodeAssertion(body assertion) (*decodedAssertion, error) {
+	decoded := &decodedAssertion{credentialID: body.CredentialID}
+	var err error
+	if decoded.clientDataJSON, err = decodeField("clientDataJSON", body.ClientDataJSON); err != nil {
+		return nil, err
+	}
+	if decoded.authenticatorData, err = decodeField("authenticatorData", body.AuthenticatorData); err != nil {
+		return nil, err
+	}
+	if decoded.signature, err = decodeField("signature", body.Signature); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// verifyCredentialAssertion verifies an assertion of one of credentials and
+// advances its signature counter in place.
+func (a *Approver) verifyCredentialAssertion(credentials []Credential, decoded decodedAssertion) error {
+	for i, credential := range credentials {
+		if credential.ID != decoded.credentialID {
+			continue
+		}
+		signCount, err := a.verifyAssertion(credential, decoded.authenticatorData, decoded.clientDataJSON, decoded.signature)
+		if err != nil {
+			return err
+		}
+		credentials[i].SignCount = signCount
+		return nil
+	}
+	return errors.Join(ErrInvalidAssertion, errors.New("unknown passkey"))
+}
+
+// serveApprove verifies an assertion over a pending payment's hash and
+// approves the payment.
+func (a *Approver) serveApprove(w http.ResponseWriter, r *http.Request) {
+	var body assertion
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&body); err != nil {
+		http.Error(w, "Invalid assertion", http.StatusBadRequest)
+		return
+	}
+	a.mu.Lock()
+	approval, ok := a.pending[body.ID]
+	a.mu.Unlock()
+	if !ok {
+		http.Error(w, "Unknown approval", http.StatusNotFound)
+		return
+	}
+
+	decoded, err := decodeAssertion(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// The challenge is the hash of the payment, binding the approval to it
+	if err := a.verifyClientData(decoded.clientDataJSON, "webauthn.get", approval.hash[:]); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Verify and advance the signature counter atomically, so an assertion
+	// is accepted once
+	err = a.updateCredentials(r.Context(), func(credentials []Credential) ([]Credential, error) {
+		if err := a.verifyCredentialAssertion(credentials, *decoded); err != nil {
+			return nil, err
+		}
+		return credentials, nil
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidAssertion) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if !a.resolve(body.ID, nil) {
+		http.Error(w, "Unknown approval", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveDeny refuses a pending payment.
+func (a *Approver) serveDeny(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&body); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if !a.resolve(body.ID, ErrDenied) {
+		http.Error(w, "Unknown approval", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// approvalPage registers passkeys and approves payments with them.
+const approvalPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>x402 payment approvals</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 40em; margin: 3em auto; padding: 0 1em; }
+.approval { border: 1px solid #ccc; border-radius: 6px; padding: 0.5em 1em; margin: 1em 0; }
+.approval dd { font-family: monospace; word-break: break-all; }
+#status { color: #555; }
+</style>
+</head>
+<body>
+<h1>x402 payment approvals</h1>
+<p>Payments your agent makes above its approval threshold appear here. Approve them with your passkey, or deny them.</p>
+<p id="status"></p>
+<div id="approvals"></div>
+<p><input id="name" placeholder="Passkey name"> <button id="register">Register a passkey</button></p>
+<script>
+const token = new URLSearchParams(location.search).get("token");
+const q = "?token=" + encodeURIComponent(token);
+const status = document.getElementById("status");
+const post = (path, body) => fetch(path + q, { method: "POST", headers: { "Content-Type": "application/json" }, body: JSON.stringify(body) });
+const b64u = (buf) => btoa(String.fromCharCode(...new Uint8Array(buf))).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+const unb64u = (s) => Uint8Array.from(atob(s.replace(/-/g, "+").replace(/_/g, "/")), (c) => c.charCodeAt(0));
+let state = { approvals: [], credentials: [] };
+
+async function check(resp) {
+  if (!resp.ok) {
+    throw new Error((await resp.text()) || resp.statusText);
+  }
+}
+
+async function approve(a) {
+  status.textContent = "Waiting for your passkey...";
+  try {
+    const cred = await navigator.credentials.get({ publicKey: {
+      challenge: unb64u(a.challenge),
+      rpId: state.rpId,
+      allowCredentials: state.credentials.map((id) => ({ type: "public-key", id: unb64u(id) })),
+      userVerification: "required",
+      timeout: 60000,
+    } });
+    await check(await post("approve", {
+      id: a.id,
+      credentialId: cred.id,
+      clientDataJSON: b64u(cred.response.clientDataJSON),
+      authenticatorData: b64u(cred.response.authenticatorData),
+      signature: b64u(cred.response.signature),
+    }));
+    status.textContent = "Approved.";
+  } catch (e) {
+    status.textContent = "Not approved: " + ((e && e.message) || e);
+  }
+  refresh();
+}
+
+async function deny(a) {
+  try {
+    await check(await post("deny", { id: a.id }));
+    status.textContent = "Denied.";
+  } catch (e) {
+    status.textContent = "Deny failed: " + ((e && e.message) || e);
+  }
+  refresh();
+}
+
+function render() {
+  const list = document.getElementById("approvals");
+  list.replaceChildren();
+  if (state.approvals.length === 0) {
+    list.textContent = "No payments waiting.";
+  }
+  for (const a of state.approvals) {
+    const div = document.createElement("div");
+    div.className = "approval";
+    const dl = document.createElement("dl");
+    const fields = [["Resource", a.resource ? a.resource.url : ""], ["Amount", a.amount], ["Asset", a.asset], ["Network", a.network], ["Pay to", a.payTo], ["Requested", new Date(a.requested).toLocaleString()]];
+    for (const [label, value] of fields) {
+      const dt = document.createElement("dt");
+      dt.textContent = label;
+      const dd = document.createElement("dd");
+      dd.textContent = value;
+      dl.append(dt, dd);
+    }
+    const ok = document.createElement("button");
+    ok.textContent = "Approve";
+    ok.disabled = state.credentials.length === 0;
+    ok.onclick = () => approve(a);
+    const no = document.createElement("button");
+    no.textContent = "Deny";
+    no.onclick = () => deny(a);
+    div.append(dl, ok, " ", no);
+    list.append(div);
+  }
+  if (state.credentials.length === 0) {
+    status.textContent = "Register a passkey to approve payments.";
+  }
+}
+
+async function refresh() {
+  try {
+    const resp = await fetch("pending" + q);
+    await check(resp);
+    state = await resp.json();
+    render();
+  } catch (e) {
+    status.textContent = "Approver unreachable.";
+  }
+}
+
+document.getElementById("register").onclick = async () => {
+  const name = document.getElementById("name").value || "Passkey";
+  try {
+    const begin = await post("register/begin", {});
+    await check(begin);
+    const options = await begin.json();
+    // Once a passkey is registered, it must authorize new ones
+    let authorization;
+    if (state.credentials.length > 0) {
+      status.textContent = "Confirm with a registered passkey...";
+      const auth = await navigator.credentials.get({ publicKey: {
+        challenge: unb64u(options.challenge),
+        rpId: options.rpId,
+        allowCredentials: state.credentials.map((id) => ({ type: "public-key", id: unb64u(id) })),
+        userVerification: "required",
+        timeout: 60000,
+      } });
+      authorization = {
+        credentialId: auth.id,
+        clientDataJSON: b64u(auth.response.clientDataJSON),
+        authenticatorData: b64u(auth.response.authenticatorData),
+        signature: b64u(auth.response.signature),
+      };
+    }
+    status.textContent = "Create the new passkey...";
+    const cred = await navigator.credentials.create({ publicKey: {
+      challenge: unb64u(options.challenge),
+      rp: { id: options.rpId, name: "x402 payment approvals" },
+      user: { id: unb64u(options.userId), name: name, displayName: name },
+      pubKeyCredParams: [{ type: "public-key", alg: -7 }, { type: "public-key", alg: -8 }, { type: "public-key", alg: -257 }],
+      authenticatorSelection: { userVerification: "required", residentKey: "preferred" },
+      attestation: "none",
+      timeout: 60000,
+    } });
+    await check(await post("register", {
+      name: name,
+      challenge: options.challenge,
+      id: cred.id,
+      clientDataJSON: b64u(cred.response.clientDataJSON),
+      attestationObject: b64u(cred.response.attestationObject),
+      authorization: authorization,
+    }));
+    status.textContent = "Passkey registered.";
+  } catch (e) {
+    status.textContent = "Registration failed: " + ((e && e.message) || e);
+  }
+  refresh();
+};
+
+refresh();
+setInterval(refresh, 2000);
+</script>
+</body>
+</html>
+`
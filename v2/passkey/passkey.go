@@ -0,0 +1,306 @@
+// Package passkey requires payments above a threshold to be confirmed by an
+// operator with a passkey, for agents that pay on their own but under
+// supervision.
+//
+// An Approver's Approve method is a v2.ApprovalFunc: clients call it with
+// every signed payment before sending it. Payments above the threshold wait
+// until the operator, on the approval page the Approver serves, reviews them
+// and signs a WebAuthn assertion whose challenge is the payment's
+// v2.PaymentHash. The approval is therefore bound to the exact payment
+// signed: it cannot be replayed for another payment, and a payment changed
+// after approval is not the one approved.
+package passkey
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/storage"
+)
+
+// ErrNoCredentials is returned by Approve when a payment needs approval but
+// no passkey is registered.
+var ErrNoCredentials = errors.New("x402: no passkey registered")
+
+// ErrInvalidAssertion is returned for WebAuthn responses that do not verify.
+var ErrInvalidAssertion = errors.New("x402: invalid passkey assertion")
+
+// ErrDenied is returned by Approve for payments the operator denied.
+var ErrDenied = errors.New("x402: payment denied by operator")
+
+// errPasskeyRegistered is returned when registering a passkey twice.
+var errPasskeyRegistered = errors.New("passkey already registered")
+
+// credentialsKey is the store key of the registered credentials.
+const credentialsKey = "passkey:credentials"
+
+// registrationTTL is how long a registration challenge can be answered.
+const registrationTTL = 5 * time.Minute
+
+// Credential is a registered passkey.
+type Credential struct {
+	// ID is the base64url credential ID.
+	ID string `json:"id"`
+
+	// Name labels the passkey for the operator, like "Alice's phone".
+	Name string `json:"name"`
+
+	// PublicKey is the DER-encoded SubjectPublicKeyInfo of the passkey.
+	PublicKey []byte `json:"publicKey"`
+
+	// SignCount is the authenticator's signature counter at the last use.
+	SignCount uint32 `json:"signCount"`
+
+	// Created is when the passkey was registered.
+	Created time.Time `json:"created"`
+}
+
+// Approval is a payment waiting for the operator, as listed on the page.
+type Approval struct {
+	ID        string           `json:"id"`
+	Challenge string           `json:"challenge"`
+	Scheme    string           `json:"scheme"`
+	Network   string           `json:"network"`
+	Amount    string           `json:"amount"`
+	Asset     string           `json:"asset"`
+	PayTo     string           `json:"payTo"`
+	Resource  *v2.ResourceInfo `json:"resource,omitempty"`
+	Requested time.Time        `json:"requested"`
+
+	hash   [32]byte
+	result chan error
+}
+
+// Approver approves payments with the operator's passkeys. Mount it as an
+// http.Handler on an HTTPS server, or on localhost: browsers only offer
+// passkeys to secure origins.
+//
+// The page and its endpoints require the random token in the page URL.
+// Approvals additionally need a valid assertion of a registered passkey, with
+// user verification. The first passkey is registered by whoever holds the
+// token, so register it before the supervised agent runs; every later passkey
+// must be authorized by an assertion of a registered one.
+type Approver struct {
+	rpID       string
+	origins    []string
+	thresholds []threshold
+	store      storage.Store
+	token      string
+
+	mu            sync.Mutex
+	pending       map[string]*Approval
+	registrations map[string]time.Time
+}
+
+// Option configures an Approver.
+type Option func(*Approver)
+
+// threshold is the largest payment of an asset approved without the operator.
+type threshold struct {
+	network string
+	asset   string
+	amount  *big.Int
+}
+
+// WithThreshold approves payments of at most amount of asset on network, in
+// the asset's atomic units, without asking the operator. Thresholds are per
+// asset, as atomic units of assets with different decimals do not compare.
+// Payments in assets without a threshold, and by default every payment, need
+// approval.
+func WithThreshold(network, asset string, amount *big.Int) Option {
+	return func(a *Approver) {
+		a.thresholds = append(a.thresholds, threshold{network: network, asset: asset, amount: amount})
+	}
+}
+
+// belowThreshold reports whether requirements pay at most the threshold of
+// their asset.
+func (a *Approver) belowThreshold(requirements v2.PaymentRequirements) (bool, error) {
+	for _, t := range a.thresholds {
+		if t.network != requirements.Network || !strings.EqualFold(t.asset, requirements.Asset) {
+			continue
+		}
+		amount, ok := new(big.Int).SetString(requirements.Amount, 10)
+		if !ok {
+			return false, v2.ErrInvalidAmount
+		}
+		return amount.Cmp(t.amount) <= 0, nil
+	}
+	return false, nil
+}
+
+// WithCredentialStore keeps the registered passkeys in store, so they survive
+// restarts. Use a storage.Prefixed view when the store is shared.
+func WithCredentialStore(store storage.Store) Option {
+	return func(a *Approver) {
+		a.store = store
+	}
+}
+
+// NewApprover creates an Approver for the WebAuthn relying party rpID, the
+// host name of the approval page like "ops.example.com", accepting assertions
+// made on origins, like "https://ops.example.com".
+func NewApprover(rpID string, origins []string, opts ...Option) (*Approver, error) {
+	if rpID == "" || len(origins) == 0 {
+		return nil, errors.New("passkey: relying party ID and origins are required")
+	}
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("generating approval token: %w", err)
+	}
+	a := &Approver{
+		rpID:          rpID,
+		origins:       origins,
+		token:         hex.EncodeToString(token),
+		pending:       make(map[string]*Approval),
+		registrations: make(map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.store == nil {
+		a.store = storage.NewMemory()
+	}
+	return a, nil
+}
+
+// Token returns the token the approval page authenticates with.
+func (a *Approver) Token() string {
+	return a.token
+}
+
+// Approve implements v2.ApprovalFunc. Payments above the threshold wait until
+// the operator approves them with a passkey or denies them, or until ctx is
+// done, which returns v2.ErrApprovalTimeout.
+func (a *Approver) Approve(ctx context.Context, payment *v2.PaymentPayload) error {
+	below, err := a.belowThreshold(payment.Accepted)
+	if err != nil || below {
+		return err
+	}
+	credentials, err := a.Credentials(ctx)
+	if err != nil {
+		return err
+	}
+	if len(credentials) == 0 {
+		return ErrNoCredentials
+	}
+
+	hash, err := v2.PaymentHash(payment)
+	if err != nil {
+		return err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	approval := &Approval{
+		ID:        hex.EncodeToString(id),
+		Challenge: base64.RawURLEncoding.EncodeToString(hash[:]),
+		Scheme:    payment.Accepted.Scheme,
+		Network:   payment.Accepted.Network,
+		Amount:    payment.Accepted.Amount,
+		Asset:     payment.Accepted.Asset,
+		PayTo:     payment.Accepted.PayTo,
+		Resource:  payment.Resource,
+		Requested: time.Now(),
+		hash:      hash,
+		result:    make(chan error, 1),
+	}
+	a.mu.Lock()
+	a.pending[approval.ID] = approval
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		delete(a.pending, approval.ID)
+		a.mu.Unlock()
+	}()
+
+	select {
+	case err := <-approval.result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("%w: waiting for operator approval: %w", v2.ErrApprovalTimeout, ctx.Err())
+	}
+}
+
+// Pending returns the payments waiting for approval.
+func (a *Approver) Pending() []Approval {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	approvals := make([]Approval, 0, len(a.pending))
+	for _, approval := range a.pending {
+		approvals = append(approvals, *approval)
+	}
+	return approvals
+}
+
+// resolve answers the pending approval id with err.
+func (a *Approver) resolve(id string, err error) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	approval, ok := a.pending[id]
+	if !ok {
+		return false
+	}
+	delete(a.pending, id)
+	approval.result <- err
+	return true
+}
+
+// Credentials returns the registered passkeys.
+func (a *Approver) Credentials(ctx context.Context) ([]Credential, error) {
+	data, err := a.store.Get(ctx, credentialsKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading passkeys: %w", err)
+	}
+	var credentials []Credential
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return nil, fmt.Errorf("loading passkeys: %w", err)
+	}
+	return credentials, nil
+}
+
+// RemoveCredential unregisters the passkey with the base64url ID id.
+func (a *Approver) RemoveCredential(ctx context.Context, id string) error {
+	return a.updateCredentials(ctx, func(credentials []Credential) ([]Credential, error) {
+		kept := credentials[:0]
+		for _, credential := range credentials {
+			if credential.ID != id {
+				kept = append(kept, credential)
+			}
+		}
+		return kept, nil
+	})
+}
+
+// updateCredentials atomically replaces the registered passkeys with the
+// result of fn.
+func (a *Approver) updateCredentials(ctx context.Context, fn func([]Credential) ([]Credential, error)) error {
+	_, err := storage.Update(ctx, a.store, credentialsKey, 0, func(old []byte, found bool) ([]byte, error) {
+		var credentials []Credential
+		if found {
+			if err := json.Unmarshal(old, &credentials); err != nil {
+				return nil, err
+			}
+		}
+		credentials, err := fn(credentials)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(credentials)
+	})
+	return err
+}
@@ -0,0 +1,377 @@
+package passkey
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+const (
+	testRPID   = "ops.example.com"
+	testOrigin = "https://ops.example.com"
+)
+
+// authenticator is a software passkey, answering as a browser would.
+type authenticator struct {
+	id        string
+	key       *ecdsa.PrivateKey
+	signCount uint32
+}
+
+func newAuthenticator(t *testing.T, id string) *authenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key failed: %v", err)
+	}
+	return &authenticator{id: base64.RawURLEncoding.EncodeToString([]byte(id)), key: key}
+}
+
+func clientDataJSON(typ, challenge, origin string) string {
+	data, _ := json.Marshal(clientData{Type: typ, Challenge: challenge, Origin: origin})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// cborHead encodes the head of a CBOR item of the given major type.
+func cborHead(major byte, n int) []byte {
+	if n < 24 {
+		return []byte{major<<5 | byte(n)}
+	}
+	return []byte{major<<5 | 25, byte(n >> 8), byte(n)}
+}
+
+func cborBytes(b []byte) []byte {
+	return append(cborHead(2, len(b)), b...)
+}
+
+// attestationObject encodes a "none" attestation of the passkey for rpID.
+func (p *authenticator) attestationObject(rpID string, flags byte) string {
+	id, _ := base64.RawURLEncoding.DecodeString(p.id)
+	point, _ := p.key.PublicKey.Bytes()
+	// {1: 2 (EC2), 3: -7 (ES256), -1: 1 (P-256), -2: x, -3: y}
+	coseKey := []byte{0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21}
+	coseKey = append(append(coseKey, cborBytes(point[1:33])...), 0x22)
+	coseKey = append(coseKey, cborBytes(point[33:])...)
+
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	authData := append(rpIDHash[:], flags, 0, 0, 0, 0)
+	authData = append(authData, make([]byte, 16)...)
+	authData = append(authData, byte(len(id)>>8), byte(len(id)))
+	authData = append(append(authData, id...), coseKey...)
+
+	object := []byte{0xa3}
+	object = append(append(object, 0x63, 'f', 'm', 't'), 0x64, 'n', 'o', 'n', 'e')
+	object = append(append(object, 0x67, 'a', 't', 't', 'S', 't', 'm', 't'), 0xa0)
+	object = append(append(object, 0x68, 'a', 'u', 't', 'h', 'D', 'a', 't', 'a'), cborBytes(authData)...)
+	return base64.RawURLEncoding.EncodeToString(object)
+}
+
+// beginRegistration returns a registration challenge.
+func beginRegistration(t *testing.T, endpoint func(string) string) string {
+	resp, err := http.Post(endpoint("/register/begin"), "application/json", nil)
+	if err != nil {
+		t.Fatalf("register/begin failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var options struct {
+		Challenge string `json:"challenge"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&options)
+	return options.Challenge
+}
+
+// register registers the passkey through the approval endpoints, authorized
+// by authorizer if not nil.
+func (p *authenticator) register(t *testing.T, endpoint func(string) string, authorizer *authenticator) int {
+	challenge := beginRegistration(t, endpoint)
+	reg := registration{
+		Name:              "test",
+		Challenge:         challenge,
+		ID:                p.id,
+		ClientDataJSON:    clientDataJSON("webauthn.create", challenge, testOrigin),
+		AttestationObject: p.attestationObject(testRPID, flagUserPresent|flagUserVerified|flagAttestedData),
+	}
+	if authorizer != nil {
+		authorization := authorizer.assert("", challenge, testRPID, testOrigin, flagUserPresent|flagUserVerified)
+		reg.Authorization = &authorization
+	}
+	return post(t, endpoint("/register"), reg)
+}
+
+// assert signs an assertion over challenge for rpID.
+func (p *authenticator) assert(id, challenge, rpID, origin string, flags byte) assertion {
+	p.signCount++
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	authenticatorData := append(rpIDHash[:], flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(authenticatorData[33:], p.signCount)
+	clientData := clientDataJSON("webauthn.get", challenge, origin)
+	raw, _ := base64.RawURLEncoding.DecodeString(clientData)
+	clientDataHash := sha256.Sum256(raw)
+	digest := sha256.Sum256(append(append([]byte(nil), authenticatorData...), clientDataHash[:]...))
+	signature, _ := ecdsa.SignASN1(rand.Reader, p.key, digest[:])
+	return assertion{
+		ID:                id,
+		CredentialID:      p.id,
+		ClientDataJSON:    clientData,
+		AuthenticatorData: base64.RawURLEncoding.EncodeToString(authenticatorData),
+		Signature:         base64.RawURLEncoding.EncodeToString(signature),
+	}
+}
+
+func post(t *testing.T, url string, body interface{}) int {
+	data, _ := json.Marshal(body)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", url, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// waitPending returns the first payment waiting for approval.
+func waitPending(t *testing.T, approver *Approver) Approval {
+	for i := 0; i < 200; i++ {
+		if pending := approver.Pending(); len(pending) > 0 {
+			return pending[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("no payment waiting for approval")
+	return Approval{}
+}
+
+func testPayment(amount, nonce string) *v2.PaymentPayload {
+	return &v2.PaymentPayload{
+		X402Version: 2,
+		Accepted: v2.PaymentRequirements{
+			Scheme:  "exact",
+			Network: "eip155:84532",
+			Amount:  amount,
+			Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+			PayTo:   "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		},
+		Payload: map[string]interface{}{"signature": "0x01", "nonce": nonce},
+	}
+}
+
+func TestApprover(t *testing.T) {
+	approver, err := NewApprover(testRPID, []string{testOrigin}, WithThreshold("eip155:84532", "0x036cbd53842c5426634e7929541ec2318f3dcf7e", big.NewInt(1000)))
+	if err != nil {
+		t.Fatalf("NewApprover failed: %v", err)
+	}
+	server := httptest.NewServer(approver)
+	defer server.Close()
+	endpoint := func(path string) string { return server.URL + path + "?token=" + approver.Token() }
+	ctx := context.Background()
+
+	if resp, err := http.Get(server.URL + "/pending"); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected 403 without the token, got %d", resp.StatusCode)
+		}
+	}
+
+	// Small payments need no approval; others, and payments in assets
+	// without a threshold, need a passkey
+	if err := approver.Approve(ctx, testPayment("1000", "0x01")); err != nil {
+		t.Errorf("expected payments below the threshold to be approved, got %v", err)
+	}
+	if err := approver.Approve(ctx, testPayment("1001", "0x01")); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("expected ErrNoCredentials, got %v", err)
+	}
+	otherAsset := testPayment("1", "0x01")
+	otherAsset.Accepted.Asset = "0x7ceB23fD6bC0adD59E62ac25578270cFf1b9f619"
+	if err := approver.Approve(ctx, otherAsset); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("expected a payment in another asset to need approval, got %v", err)
+	}
+
+	passkey := newAuthenticator(t, "credential-1")
+	if status := passkey.register(t, endpoint, nil); status != http.StatusNoContent {
+		t.Fatalf("registration failed with %d", status)
+	}
+	if credentials, _ := approver.Credentials(ctx); len(credentials) != 1 || credentials[0].ID != passkey.id {
+		t.Fatalf("Credentials() = %v", credentials)
+	}
+
+	t.Run("approved", func(t *testing.T) {
+		payment := testPayment("5000", "0x02")
+		result := make(chan error, 1)
+		go func() { result <- approver.Approve(ctx, payment) }()
+		pending := waitPending(t, approver)
+
+		// Assertions over another payment, relying party or origin, or
+		// without user verification, do not approve it
+		other, _ := v2.PaymentHash(testPayment("5000", "0x03"))
+		rejected := []assertion{
+			passkey.assert(pending.ID, base64.RawURLEncoding.EncodeToString(other[:]), testRPID, testOrigin, flagUserPresent|flagUserVerified),
+			passkey.assert(pending.ID, pending.Challenge, "evil.example.com", testOrigin, flagUserPresent|flagUserVerified),
+			passkey.assert(pending.ID, pending.Challenge, testRPID, "https://evil.example.com", flagUserPresent|flagUserVerified),
+			passkey.assert(pending.ID, pending.Challenge, testRPID, testOrigin, flagUserPresent),
+		}
+		for i, body := range rejected {
+			if status := post(t, endpoint("/approve"), body); status != http.StatusBadRequest {
+				t.Errorf("assertion %d: expected 400, got %d", i, status)
+			}
+		}
+
+		valid := passkey.assert(pending.ID, pending.Challenge, testRPID, testOrigin, flagUserPresent|flagUserVerified)
+		if status := post(t, endpoint("/approve"), valid); status != http.StatusNoContent {
+			t.Fatalf("expected the approval to be accepted, got %d", status)
+		}
+		if err := <-result; err != nil {
+			t.Fatalf("Approve() = %v", err)
+		}
+		hash, _ := v2.PaymentHash(payment)
+		if pending.Challenge != base64.RawURLEncoding.EncodeToString(hash[:]) {
+			t.Error("expected the challenge to be the payment hash")
+		}
+
+		// A replayed assertion does not approve the next payment
+		go func() { result <- approver.Approve(ctx, testPayment("5000", "0x04")) }()
+		next := waitPending(t, approver)
+		valid.ID = next.ID
+		if status := post(t, endpoint("/approve"), valid); status != http.StatusBadRequest {
+			t.Errorf("expected a replayed assertion to be refused, got %d", status)
+		}
+		if status := post(t, endpoint("/deny"), map[string]string{"id": next.ID}); status != http.StatusNoContent {
+			t.Fatalf("deny failed with %d", status)
+		}
+		if err := <-result; !errors.Is(err, ErrDenied) {
+			t.Errorf("expected ErrDenied, got %v", err)
+		}
+	})
+
+	t.Run("timed out", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		if err := approver.Approve(ctx, testPayment("5000", "0x05")); !errors.Is(err, v2.ErrApprovalTimeout) {
+			t.Errorf("expected ErrApprovalTimeout, got %v", err)
+		}
+		if pending := approver.Pending(); len(pending) != 0 {
+			t.Errorf("expected the abandoned approval to be withdrawn, got %v", pending)
+		}
+	})
+
+	t.Run("registration needs a fresh challenge", func(t *testing.T) {
+		other := newAuthenticator(t, "credential-2")
+		challenge := base64.RawURLEncoding.EncodeToString([]byte("made up"))
+		authorization := passkey.assert("", challenge, testRPID, testOrigin, flagUserPresent|flagUserVerified)
+		status := post(t, endpoint("/register"), registration{
+			ID:                other.id,
+			Challenge:         challenge,
+			ClientDataJSON:    clientDataJSON("webauthn.create", challenge, testOrigin),
+			AttestationObject: other.attestationObject(testRPID, flagUserPresent|flagUserVerified|flagAttestedData),
+			Authorization:     &authorization,
+		})
+		if status != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", status)
+		}
+	})
+
+	t.Run("registration needs a registered passkey", func(t *testing.T) {
+		// The token alone does not register a passkey once one exists
+		intruder := newAuthenticator(t, "credential-3")
+		if status := intruder.register(t, endpoint, nil); status != http.StatusForbidden {
+			t.Errorf("expected 403 without authorization, got %d", status)
+		}
+		if status := intruder.register(t, endpoint, intruder); status != http.StatusForbidden {
+			t.Errorf("expected 403 for a self-authorized passkey, got %d", status)
+		}
+
+		second := newAuthenticator(t, "credential-4")
+		if status := second.register(t, endpoint, passkey); status != http.StatusNoContent {
+			t.Fatalf("expected an authorized registration to succeed, got %d", status)
+		}
+		credentials, _ := approver.Credentials(ctx)
+		if len(credentials) != 2 || credentials[1].ID != second.id {
+			t.Fatalf("Credentials() = %v", credentials)
+		}
+		publicKey, _ := x509.MarshalPKIXPublicKey(&second.key.PublicKey)
+		if !bytes.Equal(credentials[1].PublicKey, publicKey) {
+			t.Error("expected the public key of the attested credential")
+		}
+	})
+
+	t.Run("attestation is checked", func(t *testing.T) {
+		other := newAuthenticator(t, "credential-5")
+		tests := []struct {
+			name        string
+			id          string
+			attestation string
+		}{
+			{name: "other relying party", id: other.id, attestation: other.attestationObject("evil.example.com", flagUserPresent|flagUserVerified|flagAttestedData)},
+			{name: "user not verified", id: other.id, attestation: other.attestationObject(testRPID, flagUserPresent|flagAttestedData)},
+			{name: "no attested credential", id: other.id, attestation: other.attestationObject(testRPID, flagUserPresent|flagUserVerified)},
+			{name: "credential ID mismatch", id: passkey.id, attestation: other.attestationObject(testRPID, flagUserPresent|flagUserVerified|flagAttestedData)},
+			{name: "not CBOR", id: other.id, attestation: base64.RawURLEncoding.EncodeToString([]byte("{}"))},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				challenge := beginRegistration(t, endpoint)
+				authorization := passkey.assert("", challenge, testRPID, testOrigin, flagUserPresent|flagUserVerified)
+				status := post(t, endpoint("/register"), registration{
+					ID:                tt.id,
+					Challenge:         challenge,
+					ClientDataJSON:    clientDataJSON("webauthn.create", challenge, testOrigin),
+					AttestationObject: tt.attestation,
+					Authorization:     &authorization,
+				})
+				if status != http.StatusBadRequest {
+					t.Errorf("expected 400, got %d", status)
+				}
+			})
+		}
+	})
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	nested := bytes.Repeat([]byte{0x81}, maxCBORDepth+2)
+	tests := []struct {
+		name    string
+		data    []byte
+		want    any
+		rest    int
+		wantErr bool
+	}{
+		{name: "map", data: []byte{0xa2, 0x01, 0x02, 0x61, 'k', 0x20}, want: map[any]any{int64(1): int64(2), "k": int64(-1)}},
+		{name: "trailing data", data: []byte{0x42, 0x01, 0x02, 0xff}, want: []byte{1, 2}, rest: 1},
+		{name: "two-byte length", data: append([]byte{0x59, 0x00, 0x02}, 'a', 'b'), want: []byte("ab")},
+		{name: "simple values", data: []byte{0x83, 0xf4, 0xf5, 0xf6}, want: []any{false, true, nil}},
+		{name: "truncated string", data: []byte{0x45, 0x01}, wantErr: true},
+		{name: "indefinite length", data: []byte{0x5f, 0x41, 0x01, 0xff}, wantErr: true},
+		{name: "duplicate key", data: []byte{0xa2, 0x01, 0x01, 0x01, 0x02}, wantErr: true},
+		{name: "float", data: []byte{0xf9, 0x3c, 0x00}, wantErr: true},
+		{name: "tag", data: []byte{0xc1, 0x01}, wantErr: true},
+		{name: "oversized array", data: []byte{0x9a, 0xff, 0xff, 0xff, 0xff}, wantErr: true},
+		{name: "too deep", data: append(nested, 0x00), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := decodeCBOR(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeCBOR() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) || len(rest) != tt.rest {
+				t.Errorf("decodeCBOR() = %#v with %d bytes left, want %#v with %d", got, len(rest), tt.want, tt.rest)
+			}
+		})
+	}
+}
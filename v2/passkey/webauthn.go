@@ -0,0 +1,249 @@
+package passkey
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Authenticator data flags checked in assertions and registrations.
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+// COSE key parameters and algorithms of the keys the page offers.
+const (
+	coseKeyType   = 1
+	coseAlgorithm = 3
+	coseCurve     = -1
+	coseX         = -2
+	coseY         = -3
+	coseRSAN      = -1
+	coseRSAE      = -2
+
+	coseES256 = -7
+	coseEdDSA = -8
+	coseRS256 = -257
+)
+
+// clientData is the client data a browser signs with the authenticator data.
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// verifyClientData checks the client data of a WebAuthn ceremony of type typ
+// answers challenge on one of the Approver's origins.
+func (a *Approver) verifyClientData(raw []byte, typ string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("%w: client data: %v", ErrInvalidAssertion, err)
+	}
+	if data.Type != typ {
+		return fmt.Errorf("%w: ceremony %q, want %q", ErrInvalidAssertion, data.Type, typ)
+	}
+	got, err := base64.RawURLEncoding.DecodeString(data.Challenge)
+	if err != nil || !bytes.Equal(got, challenge) {
+		return fmt.Errorf("%w: challenge does not match", ErrInvalidAssertion)
+	}
+	if data.CrossOrigin {
+		return fmt.Errorf("%w: cross-origin request", ErrInvalidAssertion)
+	}
+	for _, origin := range a.origins {
+		if data.Origin == origin {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: origin %q not allowed", ErrInvalidAssertion, data.Origin)
+}
+
+// checkAuthenticatorData checks authenticator data is for the Approver's
+// relying party, with the user present and verified, and returns its flags
+// and signature counter.
+func (a *Approver) checkAuthenticatorData(authenticatorData []byte) (byte, uint32, error) {
+	// rpIdHash (32) | flags (1) | signCount (4) | attested credential data | extensions
+	if len(authenticatorData) < 37 {
+		return 0, 0, fmt.Errorf("%w: authenticator data too short", ErrInvalidAssertion)
+	}
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	if !bytes.Equal(authenticatorData[:32], rpIDHash[:]) {
+		return 0, 0, fmt.Errorf("%w: relying party does not match", ErrInvalidAssertion)
+	}
+	flags := authenticatorData[32]
+	if flags&flagUserPresent == 0 || flags&flagUserVerified == 0 {
+		return 0, 0, fmt.Errorf("%w: user not verified", ErrInvalidAssertion)
+	}
+	return flags, binary.BigEndian.Uint32(authenticatorData[33:37]), nil
+}
+
+// verifyAssertion checks a WebAuthn assertion of credential over clientDataJSON
+// and returns the authenticator's new signature counter. Assertions must be
+// for the Approver's relying party, with the user present and verified.
+func (a *Approver) verifyAssertion(credential Credential, authenticatorData, clientDataJSON, signature []byte) (uint32, error) {
+	_, signCount, err := a.checkAuthenticatorData(authenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	// Authenticators without counters always report zero; others must
+	// increase, or the passkey was cloned
+	if (signCount != 0 || credential.SignCount != 0) && signCount <= credential.SignCount {
+		return 0, fmt.Errorf("%w: signature counter did not increase", ErrInvalidAssertion)
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, fmt.Errorf("%w: public key: %v", ErrInvalidAssertion, err)
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), authenticatorData...), clientDataHash[:]...)
+	if err := verifySignature(publicKey, signed, signature); err != nil {
+		return 0, err
+	}
+	return signCount, nil
+}
+
+// attestedCredential is the credential a registration creates.
+type attestedCredential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+// parseAttestation reads the new credential from the authenticator data of a
+// WebAuthn attestation object. The credential's public key is taken from the
+// authenticator data, never from the page. The attestation statement is not
+// verified: the page asks for none, as passkeys are trusted by the operator
+// registering them, not by their make.
+func (a *Approver) parseAttestation(attestationObject []byte) (attestedCredential, error) {
+	value, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return attestedCredential{}, err
+	}
+	object, _ := value.(map[any]any)
+	authenticatorData, ok := object["authData"].([]byte)
+	if !ok {
+		return attestedCredential{}, fmt.Errorf("%w: attestation object without authenticator data", ErrInvalidAssertion)
+	}
+	flags, signCount, err := a.checkAuthenticatorData(authenticatorData)
+	if err != nil {
+		return attestedCredential{}, err
+	}
+	if flags&flagAttestedData == 0 {
+		return attestedCredential{}, fmt.Errorf("%w: no attested credential", ErrInvalidAssertion)
+	}
+
+	// aaguid (16) | credentialIdLength (2) | credentialId | credentialPublicKey
+	data := authenticatorData[37:]
+	if len(data) < 18 {
+		return attestedCredential{}, fmt.Errorf("%w: attested credential too short", ErrInvalidAssertion)
+	}
+	idLength := int(binary.BigEndian.Uint16(data[16:18]))
+	data = data[18:]
+	if idLength == 0 || len(data) < idLength {
+		return attestedCredential{}, fmt.Errorf("%w: invalid credential ID", ErrInvalidAssertion)
+	}
+	id := data[:idLength]
+	coseKey, _, err := decodeCBOR(data[idLength:])
+	if err != nil {
+		return attestedCredential{}, err
+	}
+	key, ok := coseKey.(map[any]any)
+	if !ok {
+		return attestedCredential{}, fmt.Errorf("%w: credential public key is not a COSE key", ErrInvalidAssertion)
+	}
+	publicKey, err := parseCOSEKey(key)
+	if err != nil {
+		return attestedCredential{}, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return attestedCredential{}, fmt.Errorf("%w: public key: %v", ErrInvalidAssertion, err)
+	}
+	return attestedCredential{ID: id, PublicKey: der, SignCount: signCount}, nil
+}
+
+// parseCOSEKey converts a COSE key for one of the algorithms the page offers
+// into a public key verifySignature accepts.
+func parseCOSEKey(key map[any]any) (any, error) {
+	kty, _ := key[int64(coseKeyType)].(int64)
+	alg, _ := key[int64(coseAlgorithm)].(int64)
+	switch {
+	case kty == 2 && alg == coseES256:
+		crv, _ := key[int64(coseCurve)].(int64)
+		x, _ := key[int64(coseX)].([]byte)
+		y, _ := key[int64(coseY)].([]byte)
+		// Curve 1 is P-256
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("%w: invalid ES256 key", ErrInvalidAssertion)
+		}
+		publicKey, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(append([]byte{4}, x...), y...))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid ES256 key: %v", ErrInvalidAssertion, err)
+		}
+		return publicKey, nil
+	case kty == 1 && alg == coseEdDSA:
+		crv, _ := key[int64(coseCurve)].(int64)
+		x, _ := key[int64(coseX)].([]byte)
+		// Curve 6 is Ed25519
+		if crv != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid EdDSA key", ErrInvalidAssertion)
+		}
+		return ed25519.PublicKey(x), nil
+	case kty == 3 && alg == coseRS256:
+		n, _ := key[int64(coseRSAN)].([]byte)
+		e, _ := key[int64(coseRSAE)].([]byte)
+		modulus := new(big.Int).SetBytes(n)
+		exponent := new(big.Int).SetBytes(e)
+		if modulus.BitLen() < 2048 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > math.MaxInt32 {
+			return nil, fmt.Errorf("%w: invalid RS256 key", ErrInvalidAssertion)
+		}
+		return &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported key type %d with algorithm %d", ErrInvalidAssertion, kty, alg)
+}
+
+// verifySignature checks signature over signed with one of the algorithms
+// the page offers: ES256, EdDSA or RS256.
+func verifySignature(publicKey any, signed, signature []byte) error {
+	digest := sha256.Sum256(signed)
+	var ok bool
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(key, signed, signature)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return fmt.Errorf("%w: unsupported key type %T", ErrInvalidAssertion, publicKey)
+	}
+	if !ok {
+		return fmt.Errorf("%w: bad signature", ErrInvalidAssertion)
+	}
+	return nil
+}
+
+// decodeField decodes a base64url field of a WebAuthn response.
+func decodeField(name, value string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.Join(ErrInvalidAssertion, fmt.Errorf("invalid %s", name))
+	}
+	return data, nil
+}
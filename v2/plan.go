@@ -0,0 +1,103 @@
+package v2
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDryRun indicates a client in dry-run mode stopped before signing a payment.
+// The error returned by the client is a *DryRunError carrying the PaymentPlan.
+var ErrDryRun = errors.New("x402: dry run, payment not sent")
+
+// PaymentPlan describes the payment a client would make for a request.
+// It is produced in dry-run mode after requirement selection and policy checks,
+// without signing or sending anything.
+type PaymentPlan struct {
+	// Scheme is the payment scheme (e.g., "exact").
+	Scheme string `json:"scheme"`
+
+	// Network is the blockchain network (CAIP-2 format).
+	Network string `json:"network"`
+
+	// Amount is the payment amount in atomic units.
+	Amount string `json:"amount"`
+
+	// Asset is the token contract or mint address.
+	Asset string `json:"asset"`
+
+	// PayTo is the payment recipient address.
+	PayTo string `json:"payTo"`
+
+	// MaxTimeoutSeconds is the validity window requested by the server.
+	MaxTimeoutSeconds int `json:"maxTimeoutSeconds"`
+
+	// Resource describes the resource being paid for, if the server provided it.
+	Resource *ResourceInfo `json:"resource,omitempty"`
+
+	// Requirements is the full list of options offered by the server.
+	Requirements []PaymentRequirements `json:"requirements"`
+}
+
+// NewPaymentPlan builds a plan from the selected requirement and the options offered by the server.
+func NewPaymentPlan(selected *PaymentRequirements, requirements []PaymentRequirements, resource *ResourceInfo) *PaymentPlan {
+	return &PaymentPlan{
+		Scheme:            selected.Scheme,
+		Network:           selected.Network,
+		Amount:            selected.Amount,
+		Asset:             selected.Asset,
+		PayTo:             selected.PayTo,
+		MaxTimeoutSeconds: selected.MaxTimeoutSeconds,
+		Resource:          resource,
+		Requirements:      requirements,
+	}
+}
+
+// DryRunError is returned by clients in dry-run mode instead of sending a payment.
+type DryRunError struct {
+	// Plan is the payment the client would have made.
+	Plan *PaymentPlan
+}
+
+// Error implements the error interface.
+func (e *DryRunError) Error() string {
+	return fmt.Sprintf("%s: would pay %s of %s on %s to %s",
+		ErrDryRun.Error(), e.Plan.Amount, e.Plan.Asset, e.Plan.Network, e.Plan.PayTo)
+}
+
+// Unwrap returns ErrDryRun so callers can use errors.Is.
+func (e *DryRunError) Unwrap() error {
+	return ErrDryRun
+}
+
+// PaymentPlanFromError extracts the PaymentPlan from an error returned in dry-run mode.
+// Returns false if err does not wrap a *DryRunError.
+func PaymentPlanFromError(err error) (*PaymentPlan, bool) {
+	var dryRunErr *DryRunError
+	if !errors.As(err, &dryRunErr) {
+		return nil, false
+	}
+	return dryRunErr.Plan, true
+}
+
+// PlanPayment performs requirement selection and policy checks without signing.
+// The selector must implement RequirementSelector. If guard is non-nil, the plan
+// is checked against its limits without counting it.
+func PlanPayment(selector PaymentSelector, signers []Signer, requirements []PaymentRequirements, resource *ResourceInfo, guard *SpendGuard) (*PaymentPlan, error) {
+	planner, ok := selector.(RequirementSelector)
+	if !ok {
+		return nil, fmt.Errorf("x402: dry run requires a selector implementing RequirementSelector, got %T", selector)
+	}
+
+	_, selected, err := planner.Select(signers, requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	if guard != nil {
+		if err := guard.Check(*selected); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewPaymentPlan(selected, requirements, resource), nil
+}
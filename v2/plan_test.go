@@ -0,0 +1,74 @@
+package v2
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestPlanPayment(t *testing.T) {
+	signErr := errors.New("must not sign")
+	signers := []Signer{&mockSigner{
+		network: NetworkBaseSepolia,
+		scheme:  "exact",
+		tokens:  []TokenConfig{{Address: BaseSepolia.USDCAddress}},
+		signErr: signErr,
+	}}
+	requirements := []PaymentRequirements{
+		{Scheme: "exact", Network: NetworkSolanaMainnet, Amount: "500", Asset: SolanaMainnet.USDCAddress, PayTo: "sol"},
+		{Scheme: "exact", Network: NetworkBaseSepolia, Amount: "1000", Asset: BaseSepolia.USDCAddress, PayTo: "0xpayee", MaxTimeoutSeconds: 60},
+	}
+	resource := &ResourceInfo{URL: "https://api.example.com/data"}
+
+	tests := []struct {
+		name    string
+		guard   *SpendGuard
+		wantErr error
+	}{
+		{name: "no guard"},
+		{name: "within limit", guard: NewSpendGuard(big.NewInt(1000))},
+		{name: "over limit", guard: NewSpendGuard(big.NewInt(999)), wantErr: ErrSpendLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := PlanPayment(NewDefaultPaymentSelector(), signers, requirements, resource, tt.guard)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if plan.Network != NetworkBaseSepolia || plan.Amount != "1000" || plan.PayTo != "0xpayee" || plan.MaxTimeoutSeconds != 60 {
+				t.Errorf("unexpected plan: %+v", plan)
+			}
+			if plan.Resource != resource || len(plan.Requirements) != 2 {
+				t.Errorf("expected plan to carry resource and all options, got %+v", plan)
+			}
+			if tt.guard != nil {
+				if spent := tt.guard.Status().Spent; spent.Sign() != 0 {
+					t.Errorf("dry run must not count against the guard, spent %s", spent)
+				}
+			}
+		})
+	}
+}
+
+func TestDryRunError(t *testing.T) {
+	plan := &PaymentPlan{Network: NetworkBase, Amount: "10", Asset: "0xasset", PayTo: "0xpayee"}
+	err := error(&DryRunError{Plan: plan})
+
+	if !errors.Is(err, ErrDryRun) {
+		t.Error("expected DryRunError to match ErrDryRun")
+	}
+	got, ok := PaymentPlanFromError(errors.Join(errors.New("wrapped"), err))
+	if !ok || got != plan {
+		t.Errorf("expected plan to be extracted from wrapped error, got %v, %v", got, ok)
+	}
+	if _, ok := PaymentPlanFromError(ErrNoValidSigner); ok {
+		t.Error("expected no plan for unrelated error")
+	}
+}
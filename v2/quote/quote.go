@@ -0,0 +1,230 @@
+// Package quote issues and verifies expiring price quotes, so a server with
+// dynamic prices honors the price it advertised in a 402 response even if the
+// live price changes before the client pays.
+//
+// The server signs a quote ID, the resource URL, the accepted requirements and
+// an expiry with an HMAC key, and sends them in PaymentRequired.Extensions under
+// v2.PriceQuoteExtension. The client echoes the extension unchanged in
+// PaymentPayload.Extensions (see Echo). On payment, Issuer.Verify checks the
+// signature and expiry and returns the quoted requirements, which the server
+// matches the payment against instead of its live prices.
+package quote
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// DefaultTTL is how long a quote is honored when no TTL is configured.
+const DefaultTTL = time.Minute
+
+// MinSecretLength is the minimum length of the HMAC secret accepted by NewIssuer.
+const MinSecretLength = 32
+
+// ErrNoQuote is returned by Verify and FromExtensions when no quote is present.
+// Servers then price the payment at their live prices.
+var ErrNoQuote = errors.New("quote: no price quote")
+
+// Quote is the signed statement carried in the extension info.
+type Quote struct {
+	// ID uniquely identifies the quote.
+	ID string `json:"id"`
+
+	// Resource is the URL of the quoted resource.
+	Resource string `json:"resource"`
+
+	// Accepts are the quoted payment requirements.
+	Accepts []v2.PaymentRequirements `json:"accepts"`
+
+	// IssuedAt is the Unix time the quote was created.
+	IssuedAt int64 `json:"issuedAt"`
+
+	// ExpiresAt is the Unix time after which the quote is no longer honored.
+	ExpiresAt int64 `json:"expiresAt"`
+
+	// Signature is the hex-encoded HMAC-SHA256 of Message.
+	Signature string `json:"signature,omitempty"`
+}
+
+// Message returns the canonical message signed for q: its JSON encoding
+// without the signature, with object keys sorted.
+func Message(q Quote) ([]byte, error) {
+	q.Signature = ""
+	raw, err := json.Marshal(q)
+	if err != nil {
+		return nil, fmt.Errorf("encode quote: %w", err)
+	}
+
+	// Re-encode through a generic value so Extra maps and struct fields share one
+	// key order and numbers keep their text regardless of how the quote was decoded.
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("canonicalize quote: %w", err)
+	}
+	return json.Marshal(generic)
+}
+
+// FromExtensions extracts the quote from the extensions of a PaymentRequired
+// or PaymentPayload. Returns ErrNoQuote if there is none.
+func FromExtensions(extensions map[string]v2.Extension) (*Quote, error) {
+	ext, ok := extensions[v2.PriceQuoteExtension]
+	if !ok || ext.Info == nil {
+		return nil, ErrNoQuote
+	}
+	raw, err := json.Marshal(ext.Info)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", v2.ErrInvalidQuote, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var q Quote
+	if err := decoder.Decode(&q); err != nil {
+		return nil, fmt.Errorf("%w: %v", v2.ErrInvalidQuote, err)
+	}
+	return &q, nil
+}
+
+// Echo copies the quote of pr, if any, into the extensions of payment. Clients
+// call it after signing so the server honors the quoted price.
+func Echo(pr v2.PaymentRequired, payment *v2.PaymentPayload) {
+	ext, ok := pr.Extensions[v2.PriceQuoteExtension]
+	if !ok {
+		return
+	}
+	if payment.Extensions == nil {
+		payment.Extensions = make(map[string]v2.Extension)
+	}
+	payment.Extensions[v2.PriceQuoteExtension] = ext
+}
+
+// Issuer signs and verifies quotes with a server-side secret.
+type Issuer struct {
+	secret []byte
+	ttl    time.Duration
+	clock  v2.Clock
+}
+
+// Option configures an Issuer.
+type Option func(*Issuer)
+
+// WithTTL sets how long quotes are honored (default: DefaultTTL).
+func WithTTL(ttl time.Duration) Option {
+	return func(i *Issuer) {
+		i.ttl = ttl
+	}
+}
+
+// WithClock sets the clock used for IssuedAt, ExpiresAt and expiry checks.
+func WithClock(clock v2.Clock) Option {
+	return func(i *Issuer) {
+		i.clock = clock
+	}
+}
+
+// NewIssuer creates an Issuer signing with secret, which must be at least
+// MinSecretLength bytes. Servers behind a load balancer share one secret.
+func NewIssuer(secret []byte, opts ...Option) (*Issuer, error) {
+	if len(secret) < MinSecretLength {
+		return nil, fmt.Errorf("%w: quote secret must be at least %d bytes", v2.ErrInvalidKey, MinSecretLength)
+	}
+	i := &Issuer{
+		secret: append([]byte(nil), secret...),
+		ttl:    DefaultTTL,
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i, nil
+}
+
+// sign returns the HMAC of q.
+func (i *Issuer) sign(q Quote) ([]byte, error) {
+	message, err := Message(q)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, i.secret)
+	mac.Write(message)
+	return mac.Sum(nil), nil
+}
+
+// Quote signs the resource and requirements of pr and stores the quote in its
+// extensions. pr.Resource must be set.
+func (i *Issuer) Quote(pr *v2.PaymentRequired) error {
+	if pr.Resource == nil {
+		return fmt.Errorf("quote: payment required response has no resource")
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Errorf("quote: generating ID: %w", err)
+	}
+
+	issuedAt := v2.ClockOrSystem(i.clock).Now()
+	q := Quote{
+		ID:        hex.EncodeToString(id),
+		Resource:  pr.Resource.URL,
+		Accepts:   pr.Accepts,
+		IssuedAt:  issuedAt.Unix(),
+		ExpiresAt: issuedAt.Add(i.ttl).Unix(),
+	}
+	signature, err := i.sign(q)
+	if err != nil {
+		return err
+	}
+
+	if pr.Extensions == nil {
+		pr.Extensions = make(map[string]v2.Extension)
+	}
+	pr.Extensions[v2.PriceQuoteExtension] = v2.Extension{
+		Info: map[string]interface{}{
+			"id":        q.ID,
+			"resource":  q.Resource,
+			"accepts":   q.Accepts,
+			"issuedAt":  q.IssuedAt,
+			"expiresAt": q.ExpiresAt,
+			"signature": hex.EncodeToString(signature),
+		},
+	}
+	return nil
+}
+
+// Verify checks the quote echoed in payment for resourceURL and returns it.
+// Returns ErrNoQuote if the payment carries none, v2.ErrQuoteExpired if it has
+// expired, and v2.ErrInvalidQuote if it is forged, altered, or for another
+// resource.
+func (i *Issuer) Verify(payment v2.PaymentPayload, resourceURL string) (*Quote, error) {
+	q, err := FromExtensions(payment.Extensions)
+	if err != nil {
+		return nil, err
+	}
+
+	want, err := i.sign(*q)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", v2.ErrInvalidQuote, err)
+	}
+	got, err := hex.DecodeString(q.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", v2.ErrInvalidQuote)
+	}
+	if !hmac.Equal(got, want) {
+		return nil, fmt.Errorf("%w: signature mismatch", v2.ErrInvalidQuote)
+	}
+	if q.Resource != resourceURL {
+		return nil, fmt.Errorf("%w: quote is for %s", v2.ErrInvalidQuote, q.Resource)
+	}
+	if now := v2.ClockOrSystem(i.clock).Now().Unix(); now > q.ExpiresAt {
+		return nil, fmt.Errorf("%w: quote %s expired %ds ago", v2.ErrQuoteExpired, q.ID, now-q.ExpiresAt)
+	}
+	return q, nil
+}
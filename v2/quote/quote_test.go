@@ -0,0 +1,151 @@
+package quote
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func testPaymentRequired() v2.PaymentRequired {
+	return v2.PaymentRequired{
+		X402Version: 2,
+		Resource:    &v2.ResourceInfo{URL: "https://api.example.com/data"},
+		Accepts: []v2.PaymentRequirements{{
+			Scheme:            "exact",
+			Network:           "eip155:84532",
+			Amount:            "10000",
+			Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+			PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+			MaxTimeoutSeconds: 60,
+			Extra:             map[string]interface{}{"name": "USDC", "version": "2", "decimals": 6},
+		}},
+	}
+}
+
+// echoed returns the payment a client would send for pr after a JSON round trip.
+func echoed(t *testing.T, pr v2.PaymentRequired) v2.PaymentPayload {
+	t.Helper()
+	raw, err := json.Marshal(pr)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var decoded v2.PaymentRequired
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	payment := v2.PaymentPayload{X402Version: 2, Accepted: decoded.Accepts[0]}
+	Echo(decoded, &payment)
+
+	raw, err = json.Marshal(payment)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var sent v2.PaymentPayload
+	if err := json.Unmarshal(raw, &sent); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	return sent
+}
+
+func TestIssuer_Verify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	clock := v2.NewFakeClock(now)
+	issuer, err := NewIssuer(testSecret, WithTTL(time.Minute), WithClock(clock))
+	if err != nil {
+		t.Fatalf("NewIssuer failed: %v", err)
+	}
+	other, err := NewIssuer([]byte("fedcba9876543210fedcba9876543210"), WithClock(clock))
+	if err != nil {
+		t.Fatalf("NewIssuer failed: %v", err)
+	}
+
+	quoted := func(i *Issuer) v2.PaymentRequired {
+		pr := testPaymentRequired()
+		if err := i.Quote(&pr); err != nil {
+			t.Fatalf("Quote failed: %v", err)
+		}
+		return pr
+	}
+
+	tests := []struct {
+		name     string
+		payment  func() v2.PaymentPayload
+		resource string
+		advance  time.Duration
+		wantErr  error
+	}{
+		{
+			name:    "valid",
+			payment: func() v2.PaymentPayload { return echoed(t, quoted(issuer)) },
+		},
+		{
+			name:    "no quote",
+			payment: func() v2.PaymentPayload { return echoed(t, testPaymentRequired()) },
+			wantErr: ErrNoQuote,
+		},
+		{
+			name: "altered price",
+			payment: func() v2.PaymentPayload {
+				payment := echoed(t, quoted(issuer))
+				info := payment.Extensions[v2.PriceQuoteExtension].Info
+				info["accepts"].([]interface{})[0].(map[string]interface{})["amount"] = "1"
+				return payment
+			},
+			wantErr: v2.ErrInvalidQuote,
+		},
+		{
+			name:    "other issuer",
+			payment: func() v2.PaymentPayload { return echoed(t, quoted(other)) },
+			wantErr: v2.ErrInvalidQuote,
+		},
+		{
+			name:     "other resource",
+			payment:  func() v2.PaymentPayload { return echoed(t, quoted(issuer)) },
+			resource: "https://api.example.com/other",
+			wantErr:  v2.ErrInvalidQuote,
+		},
+		{
+			name:    "expired",
+			payment: func() v2.PaymentPayload { return echoed(t, quoted(issuer)) },
+			advance: 2 * time.Minute,
+			wantErr: v2.ErrQuoteExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Set(now)
+			payment := tt.payment()
+			clock.Advance(tt.advance)
+
+			resource := tt.resource
+			if resource == "" {
+				resource = "https://api.example.com/data"
+			}
+			q, err := issuer.Verify(payment, resource)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify failed: %v", err)
+			}
+			if len(q.Accepts) != 1 || q.Accepts[0].Amount != "10000" || q.ID == "" {
+				t.Errorf("Unexpected quote: %+v", q)
+			}
+		})
+	}
+}
+
+func TestNewIssuer_ShortSecret(t *testing.T) {
+	if _, err := NewIssuer([]byte("short")); !errors.Is(err, v2.ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package v2
+
+import (
+	"context"
+	"reflect"
+)
+
+// DefaultRaceWidth is the number of requirements a RacingSelector signs for
+// concurrently when no width is configured.
+const DefaultRaceWidth = 2
+
+// RacingSelector is a PaymentSelector that, when a server accepts several
+// requirements the client can pay, signs payments for the best ranked ones
+// concurrently and returns the first signed. It cuts the tail latency of
+// multi-chain clients whose signers differ in speed, e.g. when fetching a
+// Solana blockhash is slow.
+//
+// The payments that lose the race are discarded: they are never sent, so they
+// cannot be settled, and they are not counted by spend guards, which only see
+// the returned payment. A signer that fails does not fail the race while
+// another is still signing; the error of the best ranked requirement is
+// returned only if all of them fail.
+//
+// RacingSelector is safe for concurrent use.
+type RacingSelector struct {
+	selector RequirementSelector
+	width    int
+}
+
+// RacingSelectorOption configures a RacingSelector.
+type RacingSelectorOption func(*RacingSelector)
+
+// WithRaceWidth sets how many requirements are signed for concurrently
+// (default: DefaultRaceWidth).
+func WithRaceWidth(width int) RacingSelectorOption {
+	return func(s *RacingSelector) {
+		if width > 0 {
+			s.width = width
+		}
+	}
+}
+
+// WithRaceSelector sets the selector ranking the requirements (default:
+// DefaultPaymentSelector).
+func WithRaceSelector(selector RequirementSelector) RacingSelectorOption {
+	return func(s *RacingSelector) {
+		s.selector = selector
+	}
+}
+
+// NewRacingSelector creates a RacingSelector.
+func NewRacingSelector(opts ...RacingSelectorOption) *RacingSelector {
+	s := &RacingSelector{
+		selector: NewDefaultPaymentSelector(),
+		width:    DefaultRaceWidth,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Select implements RequirementSelector, returning the best ranked signer and
+// requirement.
+func (s *RacingSelector) Select(signers []Signer, requirements []PaymentRequirements) (Signer, *PaymentRequirements, error) {
+	return s.selector.Select(signers, requirements)
+}
+
+// SelectAndSign implements PaymentSelector.
+func (s *RacingSelector) SelectAndSign(signers []Signer, requirements []PaymentRequirements) (*PaymentPayload, error) {
+	return s.SelectAndSignContext(context.Background(), signers, requirements)
+}
+
+// raceCandidate is a signer and the requirement it signs in a race.
+type raceCandidate struct {
+	signer      Signer
+	requirement *PaymentRequirements
+}
+
+// raceResult is the outcome of one signature of a race.
+type raceResult struct {
+	rank    int
+	payment *PaymentPayload
+	err     error
+}
+
+// SelectAndSignContext implements ContextPaymentSelector. It returns when the
+// first payment is signed or ctx is done; signatures still running finish in
+// the background and are discarded.
+func (s *RacingSelector) SelectAndSignContext(ctx context.Context, signers []Signer, requirements []PaymentRequirements) (*PaymentPayload, error) {
+	candidates, err := s.candidates(signers, requirements)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 1 {
+		return signCandidate(candidates[0])
+	}
+
+	// Buffered so that losing signers never block
+	results := make(chan raceResult, len(candidates))
+	for rank, candidate := range candidates {
+		go func() {
+			payment, err := signCandidate(candidate)
+			results <- raceResult{rank: rank, payment: payment, err: err}
+		}()
+	}
+
+	errs := make([]error, len(candidates))
+	for range candidates {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case result := <-results:
+			if result.err == nil {
+				return result.payment, nil
+			}
+			errs[result.rank] = result.err
+		}
+	}
+	return nil, errs[0]
+}
+
+// candidates returns up to width signers and distinct requirements, best
+// ranked first, by selecting repeatedly among the requirements not yet chosen.
+func (s *RacingSelector) candidates(signers []Signer, requirements []PaymentRequirements) ([]raceCandidate, error) {
+	remaining := requirements
+	var candidates []raceCandidate
+	for len(candidates) < s.width && len(remaining) > 0 {
+		signer, requirement, err := s.selector.Select(signers, remaining)
+		if err != nil {
+			if len(candidates) == 0 {
+				return nil, err
+			}
+			break
+		}
+		candidates = append(candidates, raceCandidate{signer: signer, requirement: requirement})
+		remaining = withoutRequirement(remaining, requirement)
+	}
+	return candidates, nil
+}
+
+// withoutRequirement returns a copy of requirements without selected, which
+// is one of its elements or equal to one.
+func withoutRequirement(requirements []PaymentRequirements, selected *PaymentRequirements) []PaymentRequirements {
+	index := -1
+	for i := range requirements {
+		if &requirements[i] == selected {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		for i := range requirements {
+			if reflect.DeepEqual(requirements[i], *selected) {
+				index = i
+				break
+			}
+		}
+	}
+	if index < 0 {
+		// A selector returning foreign requirements cannot be raced further
+		return nil
+	}
+	out := make([]PaymentRequirements, 0, len(requirements)-1)
+	out = append(out, requirements[:index]...)
+	return append(out, requirements[index+1:]...)
+}
+
+// signCandidate signs candidate's requirement.
+func signCandidate(candidate raceCandidate) (*PaymentPayload, error) {
+	payment, err := candidate.signer.Sign(candidate.requirement)
+	if err != nil {
+		return nil, NewPaymentError(ErrCodeSigningFailed, "failed to sign payment", err)
+	}
+	return payment, nil
+}
@@ -0,0 +1,106 @@
+package v2
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// raceTestRequirements are requirements on two networks, Base ranked first.
+func raceTestRequirements() []PaymentRequirements {
+	return []PaymentRequirements{
+		{Scheme: "exact", Network: "eip155:8453", Amount: "1000", Asset: "0xUSDC", PayTo: "0xmerchant"},
+		{Scheme: "exact", Network: "solana:mainnet", Amount: "1000", Asset: "USDCmint", PayTo: "merchant"},
+	}
+}
+
+func TestRacingSelector(t *testing.T) {
+	never := make(chan struct{})
+	defer close(never)
+	released := make(chan struct{})
+	close(released)
+	signErr := errors.New("rpc unavailable")
+
+	tests := []struct {
+		name        string
+		evm, svm    *countingSigner
+		width       int
+		wantNetwork string
+		wantErr     error
+	}{
+		{
+			name:        "best ranked ready first",
+			evm:         &countingSigner{release: released},
+			svm:         &countingSigner{release: never},
+			wantNetwork: "eip155:8453",
+		},
+		{
+			name:        "slow best ranked loses",
+			evm:         &countingSigner{release: never},
+			svm:         &countingSigner{release: released},
+			wantNetwork: "solana:mainnet",
+		},
+		{
+			name:        "failed signer falls back",
+			evm:         &countingSigner{mockSigner: mockSigner{signErr: signErr}},
+			svm:         &countingSigner{},
+			wantNetwork: "solana:mainnet",
+		},
+		{
+			name:    "all fail with the best ranked error",
+			evm:     &countingSigner{mockSigner: mockSigner{signErr: signErr}},
+			svm:     &countingSigner{mockSigner: mockSigner{signErr: errors.New("other")}},
+			wantErr: signErr,
+		},
+		{
+			name:        "width of one signs the best ranked only",
+			evm:         &countingSigner{},
+			svm:         &countingSigner{release: never},
+			width:       1,
+			wantNetwork: "eip155:8453",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.evm.network, tt.evm.scheme, tt.evm.tokens, tt.evm.priority = "eip155:8453", "exact", []TokenConfig{{Address: "0xUSDC"}}, 1
+			tt.svm.network, tt.svm.scheme, tt.svm.tokens, tt.svm.priority = "solana:mainnet", "exact", []TokenConfig{{Address: "USDCmint"}}, 2
+			selector := NewRacingSelector(WithRaceWidth(tt.width))
+
+			payment, err := selector.SelectAndSign([]Signer{tt.evm, tt.svm}, raceTestRequirements())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectAndSign failed: %v", err)
+			}
+			if payment.Accepted.Network != tt.wantNetwork {
+				t.Errorf("expected a payment on %s, got %s", tt.wantNetwork, payment.Accepted.Network)
+			}
+		})
+	}
+}
+
+func TestRacingSelector_Context(t *testing.T) {
+	never := make(chan struct{})
+	defer close(never)
+	evm := &countingSigner{release: never, mockSigner: mockSigner{network: "eip155:8453", scheme: "exact", tokens: []TokenConfig{{Address: "0xUSDC"}}}}
+	svm := &countingSigner{release: never, mockSigner: mockSigner{network: "solana:mainnet", scheme: "exact", tokens: []TokenConfig{{Address: "USDCmint"}}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := NewRacingSelector().SelectAndSignContext(ctx, []Signer{evm, svm}, raceTestRequirements()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context error, got %v", err)
+	}
+}
+
+func TestRacingSelector_NoSigner(t *testing.T) {
+	_, err := NewRacingSelector().SelectAndSign(nil, raceTestRequirements())
+	if !errors.Is(err, ErrNoValidSigner) {
+		t.Errorf("expected ErrNoValidSigner, got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package v2
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultMaxTimeoutSeconds is the authorization validity used by
+// RequirementBuilder unless MaxTimeout is set.
+const DefaultMaxTimeoutSeconds = 300
+
+// RequirementBuilder builds PaymentRequirements from amounts in display units,
+// resolving the token and its EIP-3009 parameters from the chain registry.
+//
+// Example:
+//
+//	req, err := v2.NewRequirement().
+//	    On(v2.NetworkBase).
+//	    PayTo("0x209693Bc6afc0C5328bA36FaF03C514EF312287C").
+//	    Amount("0.25 USDC").
+//	    Build()
+//
+// Errors are collected and returned by Build, so calls can be chained freely.
+type RequirementBuilder struct {
+	req      PaymentRequirements
+	amount   string
+	symbol   string
+	decimals int
+	err      error
+}
+
+// NewRequirement starts a requirement for the "exact" scheme with a
+// DefaultMaxTimeoutSeconds validity.
+func NewRequirement() *RequirementBuilder {
+	return &RequirementBuilder{
+		req: PaymentRequirements{
+			Scheme:            SchemeExact,
+			MaxTimeoutSeconds: DefaultMaxTimeoutSeconds,
+		},
+		decimals: -1,
+	}
+}
+
+// On sets the CAIP-2 network, e.g. NetworkBase.
+func (b *RequirementBuilder) On(network string) *RequirementBuilder {
+	if _, err := ValidateNetwork(network); err != nil {
+		b.fail(err)
+	}
+	b.req.Network = network
+	return b
+}
+
+// PayTo sets the recipient address.
+func (b *RequirementBuilder) PayTo(address string) *RequirementBuilder {
+	b.req.PayTo = address
+	return b
+}
+
+// Amount sets the price in display units, optionally followed by the token
+// symbol, like "0.25 USDC" or "0.25". Without a symbol the token is USDC
+// unless Token was called.
+func (b *RequirementBuilder) Amount(amount string) *RequirementBuilder {
+	fields := strings.Fields(amount)
+	switch len(fields) {
+	case 1:
+		b.amount = fields[0]
+	case 2:
+		b.amount, b.symbol = fields[0], fields[1]
+	default:
+		b.fail(fmt.Errorf("%w: %q", ErrInvalidAmount, amount))
+	}
+	return b
+}
+
+// Token selects a token other than USDC by its address and decimals. Amount
+// is then interpreted with those decimals.
+func (b *RequirementBuilder) Token(address string, decimals int) *RequirementBuilder {
+	b.req.Asset = address
+	b.decimals = decimals
+	return b
+}
+
+// Scheme sets the payment scheme (default SchemeExact).
+func (b *RequirementBuilder) Scheme(scheme string) *RequirementBuilder {
+	b.req.Scheme = scheme
+	return b
+}
+
+// MaxTimeout sets the authorization validity, rounded down to whole seconds.
+func (b *RequirementBuilder) MaxTimeout(timeout time.Duration) *RequirementBuilder {
+	b.req.MaxTimeoutSeconds = int(timeout / time.Second)
+	return b
+}
+
+// Extra sets a scheme-specific Extra entry. Entries set here take precedence
+// over the EIP-3009 parameters filled in from the registry.
+func (b *RequirementBuilder) Extra(key string, value interface{}) *RequirementBuilder {
+	if b.req.Extra == nil {
+		b.req.Extra = make(map[string]interface{})
+	}
+	b.req.Extra[key] = value
+	return b
+}
+
+// fail records the first error.
+func (b *RequirementBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Build returns the requirement with Amount in atomic units.
+func (b *RequirementBuilder) Build() (PaymentRequirements, error) {
+	if b.err != nil {
+		return PaymentRequirements{}, b.err
+	}
+	req := b.req
+	if b.req.Extra != nil {
+		req.Extra = make(map[string]interface{}, len(b.req.Extra))
+		for k, v := range b.req.Extra {
+			req.Extra[k] = v
+		}
+	}
+	if req.Network == "" {
+		return PaymentRequirements{}, fmt.Errorf("%w: network is required", ErrInvalidRequirements)
+	}
+	if req.PayTo == "" {
+		return PaymentRequirements{}, fmt.Errorf("%w: payTo is required", ErrInvalidRequirements)
+	}
+	if b.amount == "" {
+		return PaymentRequirements{}, fmt.Errorf("%w: amount is required", ErrInvalidRequirements)
+	}
+	if req.MaxTimeoutSeconds <= 0 {
+		return PaymentRequirements{}, fmt.Errorf("%w: maxTimeout must be positive", ErrInvalidRequirements)
+	}
+
+	decimals := b.decimals
+	if req.Asset == "" {
+		if b.symbol != "" && !strings.EqualFold(b.symbol, "USDC") {
+			return PaymentRequirements{}, fmt.Errorf("%w: unknown token %s on %s, use Token", ErrInvalidToken, b.symbol, req.Network)
+		}
+		chain, err := GetChainConfig(req.Network)
+		if err != nil {
+			return PaymentRequirements{}, err
+		}
+		req.Asset = chain.USDCAddress
+		decimals = int(chain.Decimals)
+		if chain.EIP3009Name != "" {
+			extra := map[string]interface{}{
+				"name":    chain.EIP3009Name,
+				"version": chain.EIP3009Version,
+			}
+			for k, v := range req.Extra {
+				extra[k] = v
+			}
+			req.Extra = extra
+		}
+	} else if decimals < 0 {
+		return PaymentRequirements{}, fmt.Errorf("%w: token decimals must be non-negative", ErrInvalidToken)
+	}
+
+	amount, err := AmountToBigInt(b.amount, decimals)
+	if err != nil {
+		return PaymentRequirements{}, fmt.Errorf("%w: %q has more than %d decimals or is negative", ErrInvalidAmount, b.amount, decimals)
+	}
+	req.Amount = amount.String()
+	return req, nil
+}
+
+// MustBuild is like Build but panics on error. It is meant for requirements
+// declared at package level.
+func (b *RequirementBuilder) MustBuild() PaymentRequirements {
+	req, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return req
+}
@@ -0,0 +1,86 @@
+package v2
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRequirementBuilder(t *testing.T) {
+	payTo := "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+
+	tests := []struct {
+		name      string
+		builder   *RequirementBuilder
+		want      PaymentRequirements
+		wantExtra map[string]interface{}
+		wantErr   error
+	}{
+		{
+			name:    "usdc with symbol",
+			builder: NewRequirement().On(NetworkBase).PayTo(payTo).Amount("0.25 USDC"),
+			want: PaymentRequirements{
+				Scheme: SchemeExact, Network: NetworkBase, Amount: "250000",
+				Asset: BaseMainnet.USDCAddress, PayTo: payTo, MaxTimeoutSeconds: DefaultMaxTimeoutSeconds,
+			},
+			wantExtra: map[string]interface{}{"name": "USD Coin", "version": "2"},
+		},
+		{
+			name:    "solana without extra",
+			builder: NewRequirement().On(NetworkSolanaDevnet).PayTo("recipient").Amount("1").MaxTimeout(time.Minute),
+			want: PaymentRequirements{
+				Scheme: SchemeExact, Network: NetworkSolanaDevnet, Amount: "1000000",
+				Asset: SolanaDevnet.USDCAddress, PayTo: "recipient", MaxTimeoutSeconds: 60,
+			},
+		},
+		{
+			name:    "custom token and extra",
+			builder: NewRequirement().On(NetworkBaseSepolia).PayTo(payTo).Token("0xToken", 18).Amount("1.5 TKN").Scheme(SchemeUpTo).Extra("name", "Token"),
+			want: PaymentRequirements{
+				Scheme: SchemeUpTo, Network: NetworkBaseSepolia, Amount: "1500000000000000000",
+				Asset: "0xToken", PayTo: payTo, MaxTimeoutSeconds: DefaultMaxTimeoutSeconds,
+			},
+			wantExtra: map[string]interface{}{"name": "Token"},
+		},
+		{
+			name:    "too many decimals",
+			builder: NewRequirement().On(NetworkBase).PayTo(payTo).Amount("0.0000001"),
+			wantErr: ErrInvalidAmount,
+		},
+		{
+			name:    "unknown symbol",
+			builder: NewRequirement().On(NetworkBase).PayTo(payTo).Amount("1 DAI"),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "invalid network",
+			builder: NewRequirement().On("base").PayTo(payTo).Amount("1"),
+			wantErr: ErrInvalidNetwork,
+		},
+		{
+			name:    "missing payTo",
+			builder: NewRequirement().On(NetworkBase).Amount("1"),
+			wantErr: ErrInvalidRequirements,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.builder.Build()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Build failed: %v", err)
+			}
+			tt.want.Extra = tt.wantExtra
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SchemeHandler implements a payment scheme outside of the v2 core. Registered
+// handlers are consulted by FindMatchingRequirement, the validation package, and
+// SchemeSigner, so a new scheme only needs to be registered to be usable.
+type SchemeHandler interface {
+	// Scheme returns the scheme identifier used in PaymentRequirements.Scheme.
+	Scheme() string
+
+	// NewPayload returns a pointer to a zero value of the scheme's payload type.
+	// PaymentPayload.Payload is decoded into it before validation.
+	NewPayload() interface{}
+
+	// BuildPayload creates the scheme payload satisfying requirements. It is used
+	// on the client side by SchemeSigner.
+	BuildPayload(requirements *PaymentRequirements) (interface{}, error)
+
+	// ValidateRequirements checks the scheme-specific fields of requirements.
+	ValidateRequirements(requirements PaymentRequirements) error
+
+	// ValidatePayload checks a decoded payload against the requirements it was
+	// created for. It is used on the server side before verification.
+	ValidatePayload(payload interface{}, requirements PaymentRequirements) error
+}
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = make(map[string]SchemeHandler)
+)
+
+// RegisterScheme registers a handler for a custom payment scheme.
+// Returns ErrSchemeRegistered if the scheme is built in or already registered.
+func RegisterScheme(handler SchemeHandler) error {
+	name := handler.Scheme()
+	if name == "" {
+		return fmt.Errorf("%w: empty scheme name", ErrUnsupportedScheme)
+	}
+	if name == SchemeExact || name == SchemeDeferred || name == SchemeUpTo || name == SchemeDirect {
+		return fmt.Errorf("%w: %s", ErrSchemeRegistered, name)
+	}
+
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	if _, exists := schemes[name]; exists {
+		return fmt.Errorf("%w: %s", ErrSchemeRegistered, name)
+	}
+	schemes[name] = handler
+	return nil
+}
+
+// UnregisterScheme removes a registered scheme handler.
+func UnregisterScheme(name string) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	delete(schemes, name)
+}
+
+// LookupScheme returns the handler registered for a scheme.
+func LookupScheme(name string) (SchemeHandler, bool) {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+	handler, ok := schemes[name]
+	return handler, ok
+}
+
+// RegisteredSchemes returns the names of all registered custom schemes, sorted.
+func RegisteredSchemes() []string {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+	names := make([]string, 0, len(schemes))
+	for name := range schemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DecodeSchemePayload decodes payment.Payload into the payload type of a
+// registered scheme handler.
+func DecodeSchemePayload(handler SchemeHandler, payment *PaymentPayload) (interface{}, error) {
+	raw, err := json.Marshal(payment.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode payload: %w", err)
+	}
+	payload := handler.NewPayload()
+	if err := json.Unmarshal(raw, payload); err != nil {
+		return nil, fmt.Errorf("decode %s payload: %w", handler.Scheme(), err)
+	}
+	return payload, nil
+}
+
+// SchemeSigner is a Signer for a registered custom scheme. Payloads are built by
+// the scheme's handler.
+type SchemeSigner struct {
+	network   string
+	handler   SchemeHandler
+	tokens    []TokenConfig
+	priority  int
+	maxAmount *big.Int
+}
+
+// SchemeSignerOption configures a SchemeSigner.
+type SchemeSignerOption func(*SchemeSigner)
+
+// WithSchemeTokens restricts the signer to the given tokens. Without tokens,
+// the signer accepts any asset.
+func WithSchemeTokens(tokens ...TokenConfig) SchemeSignerOption {
+	return func(s *SchemeSigner) {
+		s.tokens = append(s.tokens, tokens...)
+	}
+}
+
+// WithSchemePriority sets the signer priority (default: 0).
+func WithSchemePriority(priority int) SchemeSignerOption {
+	return func(s *SchemeSigner) {
+		s.priority = priority
+	}
+}
+
+// WithSchemeMaxAmount sets the per-call spending limit in atomic units.
+func WithSchemeMaxAmount(amount *big.Int) SchemeSignerOption {
+	return func(s *SchemeSigner) {
+		s.maxAmount = amount
+	}
+}
+
+// NewSchemeSigner creates a signer for a registered custom scheme on network.
+// Returns ErrUnsupportedScheme if the scheme is not registered.
+func NewSchemeSigner(network, scheme string, opts ...SchemeSignerOption) (*SchemeSigner, error) {
+	handler, ok := LookupScheme(scheme)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedScheme, scheme)
+	}
+	if _, err := ValidateNetwork(network); err != nil {
+		return nil, err
+	}
+
+	s := &SchemeSigner{network: network, handler: handler}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+func (s *SchemeSigner) Network() string {
+	return s.network
+}
+
+func (s *SchemeSigner) Scheme() string {
+	return s.handler.Scheme()
+}
+
+func (s *SchemeSigner) CanSign(requirements *PaymentRequirements) bool {
+	if requirements.Scheme != s.handler.Scheme() || requirements.Network != s.network {
+		return false
+	}
+	if len(s.tokens) == 0 {
+		return true
+	}
+	for _, token := range s.tokens {
+		if strings.EqualFold(token.Address, requirements.Asset) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SchemeSigner) Sign(requirements *PaymentRequirements) (*PaymentPayload, error) {
+	if !s.CanSign(requirements) {
+		return nil, ErrNoValidSigner
+	}
+	if s.maxAmount != nil {
+		amount, ok := new(big.Int).SetString(requirements.Amount, 10)
+		if !ok {
+			return nil, ErrInvalidAmount
+		}
+		if amount.Cmp(s.maxAmount) > 0 {
+			return nil, ErrAmountExceeded
+		}
+	}
+
+	payload, err := s.handler.BuildPayload(requirements)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSigningFailed, err)
+	}
+	return &PaymentPayload{
+		X402Version: X402Version,
+		Accepted:    *requirements,
+		Payload:     payload,
+	}, nil
+}
+
+func (s *SchemeSigner) GetPriority() int {
+	return s.priority
+}
+
+func (s *SchemeSigner) GetTokens() []TokenConfig {
+	return s.tokens
+}
+
+func (s *SchemeSigner) GetMaxAmount() *big.Int {
+	return s.maxAmount
+}
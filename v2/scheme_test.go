@@ -0,0 +1,135 @@
+package v2
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// streamPayload is the payload of the test "stream" scheme.
+type streamPayload struct {
+	Channel string `json:"channel"`
+}
+
+// streamScheme is a custom scheme paying through a channel named in Extra.
+type streamScheme struct{}
+
+func (streamScheme) Scheme() string          { return "stream" }
+func (streamScheme) NewPayload() interface{} { return &streamPayload{} }
+
+func (streamScheme) BuildPayload(req *PaymentRequirements) (interface{}, error) {
+	channel, _ := req.Extra["channel"].(string)
+	if channel == "" {
+		return nil, errors.New("no channel")
+	}
+	return streamPayload{Channel: channel}, nil
+}
+
+func (streamScheme) ValidateRequirements(req PaymentRequirements) error {
+	if _, ok := req.Extra["channel"].(string); !ok {
+		return errors.New("channel is required")
+	}
+	return nil
+}
+
+func (streamScheme) ValidatePayload(payload interface{}, req PaymentRequirements) error {
+	if payload.(*streamPayload).Channel != req.Extra["channel"] {
+		return fmt.Errorf("wrong channel")
+	}
+	return nil
+}
+
+func TestRegisterScheme(t *testing.T) {
+	if err := RegisterScheme(streamScheme{}); err != nil {
+		t.Fatalf("RegisterScheme failed: %v", err)
+	}
+	defer UnregisterScheme("stream")
+
+	if err := RegisterScheme(streamScheme{}); !errors.Is(err, ErrSchemeRegistered) {
+		t.Errorf("expected ErrSchemeRegistered for duplicate, got %v", err)
+	}
+	if _, ok := LookupScheme("stream"); !ok {
+		t.Error("expected stream scheme to be registered")
+	}
+	if got := RegisteredSchemes(); len(got) != 1 || got[0] != "stream" {
+		t.Errorf("unexpected registered schemes %v", got)
+	}
+
+	UnregisterScheme("stream")
+	if _, ok := LookupScheme("stream"); ok {
+		t.Error("expected stream scheme to be unregistered")
+	}
+}
+
+func TestRegisterScheme_BuiltIn(t *testing.T) {
+	for _, scheme := range []string{SchemeExact, SchemeDeferred} {
+		if err := RegisterScheme(builtinScheme(scheme)); !errors.Is(err, ErrSchemeRegistered) {
+			t.Errorf("expected built-in %s to be reserved, got %v", scheme, err)
+		}
+	}
+}
+
+type builtinScheme string
+
+func (b builtinScheme) Scheme() string { return string(b) }
+func (builtinScheme) NewPayload() interface{} {
+	return &map[string]interface{}{}
+}
+func (builtinScheme) BuildPayload(*PaymentRequirements) (interface{}, error) { return nil, nil }
+func (builtinScheme) ValidateRequirements(PaymentRequirements) error         { return nil }
+func (builtinScheme) ValidatePayload(interface{}, PaymentRequirements) error {
+	return nil
+}
+
+func TestSchemeSigner(t *testing.T) {
+	if _, err := NewSchemeSigner("eip155:8453", "stream"); !errors.Is(err, ErrUnsupportedScheme) {
+		t.Errorf("expected ErrUnsupportedScheme before registration, got %v", err)
+	}
+
+	if err := RegisterScheme(streamScheme{}); err != nil {
+		t.Fatalf("RegisterScheme failed: %v", err)
+	}
+	defer UnregisterScheme("stream")
+
+	signer, err := NewSchemeSigner("eip155:8453", "stream", WithSchemeMaxAmount(big.NewInt(1000)), WithSchemePriority(2))
+	if err != nil {
+		t.Fatalf("NewSchemeSigner failed: %v", err)
+	}
+
+	requirements := []PaymentRequirements{
+		{Scheme: "stream", Network: "eip155:8453", Amount: "500", Extra: map[string]interface{}{"channel": "a"}},
+		{Scheme: "stream", Network: "eip155:8453", Amount: "500", Extra: map[string]interface{}{"channel": "b"}},
+	}
+
+	payment, err := signer.Sign(&requirements[1])
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if payment.Payload.(streamPayload).Channel != "b" || signer.GetPriority() != 2 {
+		t.Errorf("unexpected payment %+v", payment)
+	}
+
+	// The registry disambiguates requirements with the same scheme and network
+	matched, err := FindMatchingRequirement(payment, requirements)
+	if err != nil {
+		t.Fatalf("FindMatchingRequirement failed: %v", err)
+	}
+	if matched.Extra["channel"] != "b" {
+		t.Errorf("expected channel b requirement, got %+v", matched)
+	}
+
+	payment.Payload = map[string]interface{}{"channel": "c"}
+	if _, err := FindMatchingRequirement(payment, requirements); !errors.Is(err, ErrUnsupportedScheme) {
+		t.Errorf("expected ErrUnsupportedScheme for rejected payload, got %v", err)
+	}
+
+	expensive := requirements[0]
+	expensive.Amount = "5000"
+	if _, err := signer.Sign(&expensive); !errors.Is(err, ErrAmountExceeded) {
+		t.Errorf("expected ErrAmountExceeded, got %v", err)
+	}
+	if signer.CanSign(&PaymentRequirements{Scheme: "exact", Network: "eip155:8453"}) {
+		t.Error("expected scheme signer not to sign other schemes")
+	}
+}
@@ -0,0 +1,263 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/keymem"
+)
+
+// AWSCredentials are the credentials used to sign AWS requests.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// AWSSecretsManagerProvider reads a secret from AWS Secrets Manager. Requests
+// are signed with Signature Version 4.
+type AWSSecretsManagerProvider struct {
+	secretID     string
+	region       string
+	versionStage string
+	jsonField    string
+	endpoint     string
+	credentials  AWSCredentials
+	client       *http.Client
+	clock        v2.Clock
+}
+
+// AWSOption configures an AWSSecretsManagerProvider.
+type AWSOption func(*AWSSecretsManagerProvider)
+
+// WithAWSRegion sets the region (default: $AWS_REGION, then $AWS_DEFAULT_REGION).
+func WithAWSRegion(region string) AWSOption {
+	return func(p *AWSSecretsManagerProvider) {
+		p.region = region
+	}
+}
+
+// WithAWSCredentials sets static credentials (default: $AWS_ACCESS_KEY_ID,
+// $AWS_SECRET_ACCESS_KEY, and $AWS_SESSION_TOKEN).
+func WithAWSCredentials(credentials AWSCredentials) AWSOption {
+	return func(p *AWSSecretsManagerProvider) {
+		p.credentials = credentials
+	}
+}
+
+// WithAWSVersionStage reads a specific version stage (default: AWSCURRENT).
+func WithAWSVersionStage(stage string) AWSOption {
+	return func(p *AWSSecretsManagerProvider) {
+		p.versionStage = stage
+	}
+}
+
+// WithAWSJSONField reads field from a secret stored as a JSON object.
+func WithAWSJSONField(field string) AWSOption {
+	return func(p *AWSSecretsManagerProvider) {
+		p.jsonField = field
+	}
+}
+
+// WithAWSEndpoint overrides the Secrets Manager endpoint (e.g., a VPC endpoint).
+func WithAWSEndpoint(endpoint string) AWSOption {
+	return func(p *AWSSecretsManagerProvider) {
+		p.endpoint = endpoint
+	}
+}
+
+// WithAWSHTTPClient sets the HTTP client used to reach AWS.
+func WithAWSHTTPClient(client *http.Client) AWSOption {
+	return func(p *AWSSecretsManagerProvider) {
+		p.client = client
+	}
+}
+
+// WithAWSClock sets the clock used for request signing.
+func WithAWSClock(clock v2.Clock) AWSOption {
+	return func(p *AWSSecretsManagerProvider) {
+		p.clock = clock
+	}
+}
+
+// NewAWSSecretsManagerProvider creates a provider for the secret with the given
+// name or ARN.
+func NewAWSSecretsManagerProvider(secretID string, opts ...AWSOption) *AWSSecretsManagerProvider {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	p := &AWSSecretsManagerProvider{
+		secretID: secretID,
+		region:   region,
+		credentials: AWSCredentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		client: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Load calls GetSecretValue.
+func (p *AWSSecretsManagerProvider) Load(ctx context.Context) ([]byte, error) {
+	if p.region == "" {
+		return nil, fmt.Errorf("aws region is not configured")
+	}
+	if p.credentials.AccessKeyID == "" || p.credentials.SecretAccessKey == "" {
+		return nil, fmt.Errorf("aws credentials are not configured")
+	}
+
+	input := map[string]string{"SecretId": p.secretID}
+	if p.versionStage != "" {
+		input["VersionStage"] = p.versionStage
+	}
+	body, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := p.endpoint
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + p.region + ".amazonaws.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	signAWSRequest(req, body, p.credentials, p.region, "secretsmanager", v2.ClockOrSystem(p.clock).Now())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("secrets manager request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var awsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&awsErr)
+		if strings.HasSuffix(awsErr.Type, "ResourceNotFoundException") {
+			return nil, fmt.Errorf("%w: aws %s", ErrSecretNotFound, p.secretID)
+		}
+		return nil, fmt.Errorf("secrets manager request failed: status %d: %s %s", resp.StatusCode, awsErr.Type, awsErr.Message)
+	}
+
+	var out struct {
+		SecretString string `json:"SecretString"`
+		SecretBinary string `json:"SecretBinary"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode secrets manager response: %w", err)
+	}
+
+	value := []byte(out.SecretString)
+	if len(value) == 0 && out.SecretBinary != "" {
+		decoded, err := base64.StdEncoding.DecodeString(out.SecretBinary)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode binary secret: %w", err)
+		}
+		value = decoded
+	}
+
+	if p.jsonField == "" {
+		return value, nil
+	}
+	defer keymem.Wipe(value)
+	var fields map[string]interface{}
+	if err := json.Unmarshal(value, &fields); err != nil {
+		return nil, fmt.Errorf("secret %s is not a JSON object: %w", p.secretID, err)
+	}
+	field, ok := fields[p.jsonField].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: aws %s field %s", ErrSecretNotFound, p.secretID, p.jsonField)
+	}
+	return []byte(field), nil
+}
+
+// signAWSRequest adds Signature Version 4 headers to req.
+func signAWSRequest(req *http.Request, body []byte, credentials AWSCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	signed := []string{"content-type", "host", "x-amz-date", "x-amz-target"}
+	if credentials.SessionToken != "" {
+		signed = append(signed, "x-amz-security-token")
+	}
+	var headers strings.Builder
+	for _, name := range signed {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(signed, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		headers.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signature := hex.EncodeToString(hmacSHA256(awsSigningKey(credentials.SecretAccessKey, date, region, service), stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+credentials.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// awsSigningKey derives the Signature Version 4 signing key.
+func awsSigningKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by key, as SigV4 requires.
+func canonicalQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
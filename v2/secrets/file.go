@@ -0,0 +1,56 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+)
+
+// FileProvider reads a secret from a file, such as a Kubernetes secret volume
+// or a Docker secret. Surrounding whitespace is trimmed. The file is re-read on
+// every Load, so rotated files are picked up by Secret.Watch.
+type FileProvider struct {
+	path string
+}
+
+// NewFileProvider creates a provider reading the file at path.
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+// Load reads the file.
+func (p *FileProvider) Load(ctx context.Context) ([]byte, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrSecretNotFound, p.path)
+		}
+		return nil, fmt.Errorf("read secret file: %w", err)
+	}
+	return bytes.TrimSpace(data), nil
+}
+
+// EnvProvider reads a secret from the environment. Following the common
+// "_FILE" convention, if NAME_FILE is set the secret is read from the file it
+// names; otherwise the value of NAME is used. Prefer NAME_FILE for private
+// keys: the process environment keeps a copy of NAME that cannot be wiped.
+type EnvProvider struct {
+	name string
+}
+
+// NewEnvProvider creates a provider for the environment variable name.
+func NewEnvProvider(name string) *EnvProvider {
+	return &EnvProvider{name: name}
+}
+
+// Load reads NAME_FILE or NAME.
+func (p *EnvProvider) Load(ctx context.Context) ([]byte, error) {
+	if path := os.Getenv(p.name + "_FILE"); path != "" {
+		return NewFileProvider(path).Load(ctx)
+	}
+	if value, ok := os.LookupEnv(p.name); ok && value != "" {
+		return []byte(value), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrSecretNotFound, p.name)
+}
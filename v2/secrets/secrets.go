@@ -0,0 +1,176 @@
+// Package secrets loads signer private keys and facilitator credentials from
+// secret stores (HashiCorp Vault, AWS Secrets Manager, or files injected by an
+// orchestrator) and keeps them current as they rotate.
+//
+// A Provider fetches a secret value. A Secret caches the value of a Provider,
+// refreshes it periodically with Watch, and notifies subscribers when it
+// changes. RotatingSigner and AuthorizationProvider build on Secret to hot-swap
+// signers and facilitator tokens without restarting the process.
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	v2http "github.com/mark3labs/x402-go/v2/http"
+	"github.com/mark3labs/x402-go/v2/internal/keymem"
+)
+
+// DefaultRefreshInterval is how often Watch reloads a secret when no interval is configured.
+const DefaultRefreshInterval = time.Minute
+
+// ErrSecretNotFound is returned when a provider's secret or field does not exist.
+var ErrSecretNotFound = errors.New("x402: secret not found")
+
+// Provider loads the current value of a secret. The returned slice is owned
+// by the caller, which wipes it once the value is replaced.
+type Provider interface {
+	Load(ctx context.Context) ([]byte, error)
+}
+
+// ProviderFunc adapts a function to a Provider.
+type ProviderFunc func(ctx context.Context) ([]byte, error)
+
+// Load calls f.
+func (f ProviderFunc) Load(ctx context.Context) ([]byte, error) {
+	return f(ctx)
+}
+
+// Secret holds the latest value of a Provider. The value is kept as bytes
+// and wiped when it is replaced.
+type Secret struct {
+	provider Provider
+	interval time.Duration
+	clock    v2.Clock
+
+	mu        sync.RWMutex
+	value     []byte
+	listeners []func([]byte)
+}
+
+// Option configures a Secret.
+type Option func(*Secret)
+
+// WithRefreshInterval sets how often Watch reloads the secret (default: DefaultRefreshInterval).
+func WithRefreshInterval(interval time.Duration) Option {
+	return func(s *Secret) {
+		s.interval = interval
+	}
+}
+
+// WithClock sets the clock used to schedule refreshes.
+func WithClock(clock v2.Clock) Option {
+	return func(s *Secret) {
+		s.clock = clock
+	}
+}
+
+// Load loads the initial value of provider. Call Watch to keep it current.
+func Load(ctx context.Context, provider Provider, opts ...Option) (*Secret, error) {
+	s := &Secret{
+		provider: provider,
+		interval: DefaultRefreshInterval,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	value, err := provider.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load secret: %w", err)
+	}
+	s.value = value
+	return s, nil
+}
+
+// Value returns the current secret value as a string, which cannot be wiped.
+// Use it for tokens sent in headers; use Bytes for private keys.
+func (s *Secret) Value() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return string(s.value)
+}
+
+// Bytes returns a copy of the current secret value. The caller owns the copy
+// and should wipe it when done.
+func (s *Secret) Bytes() []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]byte(nil), s.value...)
+}
+
+// OnChange registers fn to be called with the new value after each rotation.
+// value is a copy that is wiped when fn returns; fn must not retain it.
+func (s *Secret) OnChange(fn func(value []byte)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// Refresh reloads the secret and notifies listeners if the value changed.
+// On error the previous value is kept.
+func (s *Secret) Refresh(ctx context.Context) (bool, error) {
+	value, err := s.provider.Load(ctx)
+	if err != nil {
+		return false, fmt.Errorf("refresh secret: %w", err)
+	}
+
+	s.mu.Lock()
+	if bytes.Equal(value, s.value) {
+		s.mu.Unlock()
+		keymem.Wipe(value)
+		return false, nil
+	}
+	keymem.Wipe(s.value)
+	s.value = value
+	listeners := append([]func([]byte){}, s.listeners...)
+	s.mu.Unlock()
+
+	for _, fn := range listeners {
+		changed := append([]byte(nil), value...)
+		fn(changed)
+		keymem.Wipe(changed)
+	}
+	return true, nil
+}
+
+// Watch refreshes the secret every refresh interval until ctx is done.
+// Refresh errors are logged and the previous value stays in use.
+func (s *Secret) Watch(ctx context.Context) {
+	clock := v2.ClockOrSystem(s.clock)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(s.interval):
+		}
+
+		changed, err := s.Refresh(ctx)
+		if err != nil {
+			slog.Default().Warn("failed to refresh secret, keeping previous value", "error", err)
+			continue
+		}
+		if changed {
+			slog.Default().Info("secret rotated")
+		}
+	}
+}
+
+// AuthorizationProvider returns a facilitator AuthorizationProvider sending
+// prefix followed by the current secret value (e.g., prefix "Bearer ").
+func (s *Secret) AuthorizationProvider(prefix string) v2http.AuthorizationProvider {
+	return func(*http.Request) string {
+		value := s.Value()
+		if value == "" {
+			return ""
+		}
+		return prefix + value
+	}
+}
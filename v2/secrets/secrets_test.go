@@ -0,0 +1,362 @@
+package secrets
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// sequenceProvider returns the values set on it, failing while err is set.
+// It remembers the slices it returned.
+type sequenceProvider struct {
+	mu       sync.Mutex
+	value    string
+	err      error
+	returned [][]byte
+}
+
+func (p *sequenceProvider) set(value string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.value, p.err = value, err
+}
+
+func (p *sequenceProvider) Load(context.Context) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	value := []byte(p.value)
+	p.returned = append(p.returned, value)
+	return value, nil
+}
+
+func TestSecret_Watch(t *testing.T) {
+	provider := &sequenceProvider{value: "key-1"}
+	clock := v2.NewFakeClock(time.Now())
+	secret, err := Load(context.Background(), provider, WithRefreshInterval(time.Minute), WithClock(clock))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	changes := make(chan string, 1)
+	secret.OnChange(func(value []byte) { changes <- string(value) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go secret.Watch(ctx)
+
+	// A failed refresh keeps the previous value
+	provider.set("", errors.New("vault sealed"))
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	if secret.Value() != "key-1" {
+		t.Errorf("expected previous value after failed refresh, got %q", secret.Value())
+	}
+
+	provider.set("key-2", nil)
+	clock.Advance(time.Minute)
+	if got := <-changes; got != "key-2" || secret.Value() != "key-2" {
+		t.Errorf("expected rotation to key-2, got %q", got)
+	}
+}
+
+func TestSecret_AuthorizationProvider(t *testing.T) {
+	provider := &sequenceProvider{value: "token-1"}
+	secret, err := Load(context.Background(), provider)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	auth := secret.AuthorizationProvider("Bearer ")
+	if got := auth(nil); got != "Bearer token-1" {
+		t.Errorf("expected Bearer token-1, got %q", got)
+	}
+
+	provider.set("token-2", nil)
+	if changed, err := secret.Refresh(context.Background()); !changed || err != nil {
+		t.Fatalf("expected refresh to change the secret, got %v, %v", changed, err)
+	}
+	if got := auth(nil); got != "Bearer token-2" {
+		t.Errorf("expected rotated token, got %q", got)
+	}
+}
+
+func TestFileAndEnvProviders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signer-key")
+	if err := os.WriteFile(path, []byte("0xabc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("X402_TEST_KEY", "inline")
+	t.Setenv("X402_TEST_FILE_KEY_FILE", path)
+
+	tests := []struct {
+		name     string
+		provider Provider
+		want     string
+		wantErr  error
+	}{
+		{name: "file", provider: NewFileProvider(path), want: "0xabc"},
+		{name: "missing file", provider: NewFileProvider(path + ".missing"), wantErr: ErrSecretNotFound},
+		{name: "env", provider: NewEnvProvider("X402_TEST_KEY"), want: "inline"},
+		{name: "env file", provider: NewEnvProvider("X402_TEST_FILE_KEY"), want: "0xabc"},
+		{name: "missing env", provider: NewEnvProvider("X402_TEST_UNSET"), wantErr: ErrSecretNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.provider.Load(context.Background())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || string(got) != tt.want {
+				t.Errorf("expected %q, got %q (%v)", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/x402/signer":
+			_, _ = w.Write([]byte(`{"data":{"data":{"private_key":"0xkv2"},"metadata":{"version":3}}}`))
+		case "/v1/kv/x402/signer":
+			_, _ = w.Write([]byte(`{"data":{"private_key":"0xkv1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		field   string
+		opts    []VaultOption
+		want    string
+		wantErr error
+	}{
+		{name: "kv v2", path: "x402/signer", field: "private_key", want: "0xkv2"},
+		{name: "kv v1", path: "x402/signer", field: "private_key", opts: []VaultOption{WithVaultMount("kv"), WithVaultKVv1()}, want: "0xkv1"},
+		{name: "missing field", path: "x402/signer", field: "token", wantErr: ErrSecretNotFound},
+		{name: "missing secret", path: "x402/other", field: "private_key", wantErr: ErrSecretNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]VaultOption{WithVaultAddress(server.URL), WithVaultToken("root")}, tt.opts...)
+			got, err := NewVaultProvider(tt.path, tt.field, opts...).Load(context.Background())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || string(got) != tt.want {
+				t.Errorf("expected %q, got %q (%v)", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestAWSSigningKey(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation
+	key := awsSigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	if got := hex.EncodeToString(key); got != "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d" {
+		t.Errorf("unexpected signing key %s", got)
+	}
+}
+
+func TestAWSSecretsManagerProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240101/us-east-1/secretsmanager/aws4_request") ||
+			!strings.Contains(auth, "SignedHeaders=content-type;host;x-amz-date;x-amz-target;x-amz-security-token") {
+			t.Errorf("unexpected Authorization %q", auth)
+		}
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" || r.Header.Get("X-Amz-Security-Token") != "session" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+
+		var input struct{ SecretId string }
+		_ = json.NewDecoder(r.Body).Decode(&input)
+		if input.SecretId != "x402/facilitator" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"SecretString":"{\"token\":\"abc\"}"}`))
+	}))
+	defer server.Close()
+
+	opts := []AWSOption{
+		WithAWSEndpoint(server.URL),
+		WithAWSRegion("us-east-1"),
+		WithAWSCredentials(AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", SessionToken: "session"}),
+		WithAWSClock(v2.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithAWSJSONField("token"),
+	}
+
+	got, err := NewAWSSecretsManagerProvider("x402/facilitator", opts...).Load(context.Background())
+	if err != nil || string(got) != "abc" {
+		t.Errorf("expected abc, got %q (%v)", got, err)
+	}
+	if _, err := NewAWSSecretsManagerProvider("x402/missing", opts...).Load(context.Background()); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("expected ErrSecretNotFound, got %v", err)
+	}
+}
+
+// keySigner is a v2.Signer recording the key it was created from. If hold is
+// set, Sign reports on entered and blocks until hold is closed.
+type keySigner struct {
+	key     string
+	entered chan struct{}
+	hold    chan struct{}
+	closed  atomic.Bool
+}
+
+func (*keySigner) Network() string                      { return "eip155:8453" }
+func (*keySigner) Scheme() string                       { return "exact" }
+func (*keySigner) CanSign(*v2.PaymentRequirements) bool { return true }
+func (*keySigner) GetPriority() int                     { return 0 }
+func (*keySigner) GetTokens() []v2.TokenConfig          { return nil }
+func (*keySigner) GetMaxAmount() *big.Int               { return nil }
+func (s *keySigner) Sign(*v2.PaymentRequirements) (*v2.PaymentPayload, error) {
+	if s.hold != nil {
+		close(s.entered)
+		<-s.hold
+	}
+	if s.closed.Load() {
+		return nil, v2.ErrSignerClosed
+	}
+	return &v2.PaymentPayload{Payload: s.key}, nil
+}
+func (s *keySigner) Close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+func TestRotatingSigner(t *testing.T) {
+	provider := &sequenceProvider{value: "key-1"}
+	secret, err := Load(context.Background(), provider)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	var created []*keySigner
+	signer, err := NewRotatingSigner(secret, func(key []byte) (v2.Signer, error) {
+		if string(key) == "bad" {
+			return nil, errors.New("invalid key")
+		}
+		created = append(created, &keySigner{key: string(key)})
+		return created[len(created)-1], nil
+	})
+	if err != nil {
+		t.Fatalf("NewRotatingSigner failed: %v", err)
+	}
+
+	sign := func() string {
+		payload, _ := signer.Sign(&v2.PaymentRequirements{})
+		return payload.Payload.(string)
+	}
+	if got := sign(); got != "key-1" {
+		t.Errorf("expected key-1, got %s", got)
+	}
+
+	provider.set("key-2", nil)
+	_, _ = secret.Refresh(context.Background())
+	if got := sign(); got != "key-2" {
+		t.Errorf("expected rotated key-2, got %s", got)
+	}
+	if !created[0].closed.Load() {
+		t.Error("expected the previous signer to be closed")
+	}
+	if first := provider.returned[0]; string(first) != "\x00\x00\x00\x00\x00" {
+		t.Errorf("expected the replaced secret to be wiped, got %q", first)
+	}
+
+	// An unusable rotated key keeps the previous signer
+	provider.set("bad", nil)
+	_, _ = secret.Refresh(context.Background())
+	if got := sign(); got != "key-2" {
+		t.Errorf("expected key-2 to stay active, got %s", got)
+	}
+
+	if err := signer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if !created[1].closed.Load() {
+		t.Error("expected Close to close the active signer")
+	}
+	if _, err := signer.Sign(&v2.PaymentRequirements{}); !errors.Is(err, v2.ErrSignerClosed) {
+		t.Errorf("expected ErrSignerClosed after Close, got %v", err)
+	}
+}
+
+func TestRotatingSigner_RotateWaitsForSigning(t *testing.T) {
+	hold := make(chan struct{})
+	first := &keySigner{key: "key-1", entered: make(chan struct{}), hold: hold}
+	secret, err := Load(context.Background(), &sequenceProvider{value: "key-1"})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	signer, err := NewRotatingSigner(secret, func(key []byte) (v2.Signer, error) {
+		if string(key) == "key-1" {
+			return first, nil
+		}
+		return &keySigner{key: string(key)}, nil
+	})
+	if err != nil {
+		t.Fatalf("NewRotatingSigner failed: %v", err)
+	}
+
+	signed := make(chan error, 1)
+	go func() {
+		_, err := signer.Sign(&v2.PaymentRequirements{})
+		signed <- err
+	}()
+	<-first.entered
+
+	rotated := make(chan error, 1)
+	go func() { rotated <- signer.Rotate([]byte("key-2")) }()
+	select {
+	case <-rotated:
+		t.Fatal("expected Rotate to wait for the payment in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if first.closed.Load() {
+		t.Fatal("expected the signer to stay open while signing")
+	}
+
+	close(hold)
+	if err := <-signed; err != nil {
+		t.Errorf("expected the payment in progress to finish, got %v", err)
+	}
+	if err := <-rotated; err != nil {
+		t.Errorf("Rotate failed: %v", err)
+	}
+	if !first.closed.Load() {
+		t.Error("expected the previous signer to be closed after signing")
+	}
+}
@@ -0,0 +1,165 @@
+package secrets
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"sync"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/keymem"
+)
+
+// SignerFactory creates a signer from a private key. privateKey is wiped when
+// the factory returns, so the factory must not retain it; converting it to a
+// string leaves a copy that cannot be wiped.
+//
+// Example:
+//
+//	factory := func(privateKey []byte) (v2.Signer, error) {
+//	    raw := make([]byte, hex.DecodedLen(len(privateKey)))
+//	    defer clear(raw)
+//	    if _, err := hex.Decode(raw, privateKey); err != nil {
+//	        return nil, err
+//	    }
+//	    key, err := crypto.ToECDSA(raw)
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return evm.NewSignerFromKey("eip155:8453", key, tokens)
+//	}
+type SignerFactory func(privateKey []byte) (v2.Signer, error)
+
+// RotatingSigner is a v2.Signer backed by a Secret holding its private key.
+// When the secret rotates, a new signer is created and swapped in; payments in
+// progress finish with the signer they started with, which is then closed if
+// it implements io.Closer.
+type RotatingSigner struct {
+	factory SignerFactory
+
+	mu     sync.RWMutex
+	active *activeSigner
+	closed bool
+}
+
+// activeSigner counts the Sign calls in progress on a signer.
+type activeSigner struct {
+	signer   v2.Signer
+	inflight sync.WaitGroup
+}
+
+// NewRotatingSigner creates a signer from the current value of secret and
+// replaces it whenever the secret rotates. If a rotated key cannot be used, the
+// error is logged and the previous signer stays active. Call Close to wipe the
+// active signer's key.
+func NewRotatingSigner(secret *Secret, factory SignerFactory) (*RotatingSigner, error) {
+	privateKey := secret.Bytes()
+	signer, err := factory(privateKey)
+	keymem.Wipe(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("create signer: %w", err)
+	}
+
+	r := &RotatingSigner{factory: factory, active: &activeSigner{signer: signer}}
+	secret.OnChange(func(privateKey []byte) {
+		if err := r.Rotate(privateKey); err != nil {
+			slog.Default().Error("failed to rotate signer, keeping previous key", "error", err)
+		}
+	})
+	return r, nil
+}
+
+// Rotate replaces the underlying signer with one created from privateKey and
+// wipes privateKey. It returns once the previous signer has finished its Sign
+// calls in progress and has been closed.
+func (r *RotatingSigner) Rotate(privateKey []byte) error {
+	signer, err := r.factory(privateKey)
+	keymem.Wipe(privateKey)
+	if err != nil {
+		return fmt.Errorf("create signer: %w", err)
+	}
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		closeSigner(signer)
+		return v2.ErrSignerClosed
+	}
+	previous := r.active
+	r.active = &activeSigner{signer: signer}
+	r.mu.Unlock()
+
+	previous.inflight.Wait()
+	return closeSigner(previous.signer)
+}
+
+// Close waits for Sign calls in progress and closes the active signer. Later
+// Sign calls and rotations fail with v2.ErrSignerClosed.
+func (r *RotatingSigner) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	active := r.active
+	r.mu.Unlock()
+
+	active.inflight.Wait()
+	return closeSigner(active.signer)
+}
+
+// closeSigner closes signer if it holds resources.
+func closeSigner(signer v2.Signer) error {
+	if closer, ok := signer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Current returns the active signer. It is closed after the next rotation, so
+// sign through the RotatingSigner rather than keeping the result.
+func (r *RotatingSigner) Current() v2.Signer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.active.signer
+}
+
+func (r *RotatingSigner) Network() string {
+	return r.Current().Network()
+}
+
+func (r *RotatingSigner) Scheme() string {
+	return r.Current().Scheme()
+}
+
+func (r *RotatingSigner) CanSign(requirements *v2.PaymentRequirements) bool {
+	return r.Current().CanSign(requirements)
+}
+
+func (r *RotatingSigner) Sign(requirements *v2.PaymentRequirements) (*v2.PaymentPayload, error) {
+	r.mu.RLock()
+	if r.closed {
+		r.mu.RUnlock()
+		return nil, v2.ErrSignerClosed
+	}
+	active := r.active
+	active.inflight.Add(1)
+	r.mu.RUnlock()
+	defer active.inflight.Done()
+
+	return active.signer.Sign(requirements)
+}
+
+func (r *RotatingSigner) GetPriority() int {
+	return r.Current().GetPriority()
+}
+
+func (r *RotatingSigner) GetTokens() []v2.TokenConfig {
+	return r.Current().GetTokens()
+}
+
+func (r *RotatingSigner) GetMaxAmount() *big.Int {
+	return r.Current().GetMaxAmount()
+}
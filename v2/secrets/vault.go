@@ -0,0 +1,149 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// VaultProvider reads a field of a HashiCorp Vault KV secret over the Vault HTTP API.
+type VaultProvider struct {
+	address   string
+	token     string
+	namespace string
+	mount     string
+	path      string
+	field     string
+	kvV1      bool
+	client    *http.Client
+}
+
+// VaultOption configures a VaultProvider.
+type VaultOption func(*VaultProvider)
+
+// WithVaultAddress sets the Vault address (default: $VAULT_ADDR).
+func WithVaultAddress(address string) VaultOption {
+	return func(p *VaultProvider) {
+		p.address = address
+	}
+}
+
+// WithVaultToken sets the Vault token (default: $VAULT_TOKEN).
+func WithVaultToken(token string) VaultOption {
+	return func(p *VaultProvider) {
+		p.token = token
+	}
+}
+
+// WithVaultNamespace sets the Vault Enterprise namespace (default: $VAULT_NAMESPACE).
+func WithVaultNamespace(namespace string) VaultOption {
+	return func(p *VaultProvider) {
+		p.namespace = namespace
+	}
+}
+
+// WithVaultMount sets the KV secrets engine mount (default: "secret").
+func WithVaultMount(mount string) VaultOption {
+	return func(p *VaultProvider) {
+		p.mount = mount
+	}
+}
+
+// WithVaultKVv1 reads from a version 1 KV secrets engine instead of version 2.
+func WithVaultKVv1() VaultOption {
+	return func(p *VaultProvider) {
+		p.kvV1 = true
+	}
+}
+
+// WithVaultHTTPClient sets the HTTP client used to reach Vault.
+func WithVaultHTTPClient(client *http.Client) VaultOption {
+	return func(p *VaultProvider) {
+		p.client = client
+	}
+}
+
+// NewVaultProvider creates a provider reading field of the KV secret at path
+// (e.g., path "x402/signer", field "private_key").
+func NewVaultProvider(path, field string, opts ...VaultOption) *VaultProvider {
+	p := &VaultProvider{
+		address:   os.Getenv("VAULT_ADDR"),
+		token:     os.Getenv("VAULT_TOKEN"),
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		mount:     "secret",
+		path:      path,
+		field:     field,
+		client:    http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Load reads the secret from Vault.
+func (p *VaultProvider) Load(ctx context.Context) ([]byte, error) {
+	if p.address == "" {
+		return nil, fmt.Errorf("vault address is not configured")
+	}
+
+	url := strings.TrimSuffix(p.address, "/") + "/v1/" + strings.Trim(p.mount, "/")
+	if !p.kvV1 {
+		url += "/data"
+	}
+	url += "/" + strings.TrimPrefix(p.path, "/")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: vault %s", ErrSecretNotFound, p.path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault request failed: status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode vault response: %w", err)
+	}
+
+	data := body.Data
+	if !p.kvV1 {
+		// KV version 2 nests the fields next to the secret metadata
+		var versioned struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(data, &versioned); err != nil {
+			return nil, fmt.Errorf("failed to decode vault response: %w", err)
+		}
+		data = versioned.Data
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode vault secret: %w", err)
+	}
+	value, ok := fields[p.field].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: vault %s field %s", ErrSecretNotFound, p.path, p.field)
+	}
+	return []byte(value), nil
+}
@@ -14,6 +14,13 @@ type PaymentSelector interface {
 	SelectAndSign(signers []Signer, requirements []PaymentRequirements) (*PaymentPayload, error)
 }
 
+// RequirementSelector is implemented by selectors that can choose a signer and
+// requirement without signing. It is required for dry-run mode.
+type RequirementSelector interface {
+	// Select returns the signer and requirement that SelectAndSign would use.
+	Select(signers []Signer, requirements []PaymentRequirements) (Signer, *PaymentRequirements, error)
+}
+
 // DefaultPaymentSelector implements the standard payment selection algorithm.
 // It selects signers based on:
 // 1. Ability to satisfy requirements (network and token match)
@@ -29,12 +36,28 @@ func NewDefaultPaymentSelector() *DefaultPaymentSelector {
 
 // SelectAndSign implements PaymentSelector.
 func (s *DefaultPaymentSelector) SelectAndSign(signers []Signer, requirements []PaymentRequirements) (*PaymentPayload, error) {
+	signer, requirement, err := s.Select(signers, requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sign the payment
+	payment, err := signer.Sign(requirement)
+	if err != nil {
+		return nil, NewPaymentError(ErrCodeSigningFailed, "failed to sign payment", err)
+	}
+
+	return payment, nil
+}
+
+// Select implements RequirementSelector.
+func (s *DefaultPaymentSelector) Select(signers []Signer, requirements []PaymentRequirements) (Signer, *PaymentRequirements, error) {
 	if len(signers) == 0 {
-		return nil, NewPaymentError(ErrCodeNoValidSigner, "no signers configured", ErrNoValidSigner)
+		return nil, nil, NewPaymentError(ErrCodeNoValidSigner, "no signers configured", ErrNoValidSigner)
 	}
 
 	if len(requirements) == 0 {
-		return nil, NewPaymentError(ErrCodeInvalidRequirements, "no payment requirements provided", ErrInvalidRequirements)
+		return nil, nil, NewPaymentError(ErrCodeInvalidRequirements, "no payment requirements provided", ErrInvalidRequirements)
 	}
 
 	// Try each requirement option and find the best signer match
@@ -97,7 +120,7 @@ func (s *DefaultPaymentSelector) SelectAndSign(signers []Signer, requirements []
 
 	// If no valid requirements were found, return an error
 	if !hasValidRequirement {
-		return nil, NewPaymentError(ErrCodeInvalidRequirements, "invalid amount in requirements", ErrInvalidRequirements)
+		return nil, nil, NewPaymentError(ErrCodeInvalidRequirements, "invalid amount in requirements", ErrInvalidRequirements)
 	}
 
 	if len(allCandidates) == 0 {
@@ -106,7 +129,7 @@ func (s *DefaultPaymentSelector) SelectAndSign(signers []Signer, requirements []
 		for _, req := range requirements {
 			errorDetails = append(errorDetails, req.Network+":"+req.Asset)
 		}
-		return nil, NewPaymentError(ErrCodeNoValidSigner, "no signer can satisfy any payment requirement", ErrNoValidSigner).
+		return nil, nil, NewPaymentError(ErrCodeNoValidSigner, "no signer can satisfy any payment requirement", ErrNoValidSigner).
 			WithDetails("options", strings.Join(errorDetails, ", "))
 	}
 
@@ -128,14 +151,7 @@ func (s *DefaultPaymentSelector) SelectAndSign(signers []Signer, requirements []
 
 	// Use the highest priority signer and requirement combination
 	selectedCandidate := allCandidates[0]
-
-	// Sign the payment
-	payment, err := selectedCandidate.signer.Sign(selectedCandidate.requirement)
-	if err != nil {
-		return nil, NewPaymentError(ErrCodeSigningFailed, "failed to sign payment", err)
-	}
-
-	return payment, nil
+	return selectedCandidate.signer, selectedCandidate.requirement, nil
 }
 
 // FindMatchingRequirement finds a payment requirement that matches the given payment's scheme and network.
@@ -144,13 +160,30 @@ func (s *DefaultPaymentSelector) SelectAndSign(signers []Signer, requirements []
 // This is useful for both middleware (verifying incoming payments) and clients (creating payments)
 // to ensure the payment matches one of the server's accepted requirements.
 //
+// For schemes registered with RegisterScheme, a requirement only matches if the
+// scheme handler accepts the payload for it.
+//
 // Returns ErrUnsupportedScheme if no matching requirement is found.
 func FindMatchingRequirement(payment *PaymentPayload, requirements []PaymentRequirements) (*PaymentRequirements, error) {
+	handler, custom := LookupScheme(payment.Accepted.Scheme)
+	var payload interface{}
+	if custom {
+		var err error
+		if payload, err = DecodeSchemePayload(handler, payment); err != nil {
+			return nil, NewPaymentError(ErrCodeUnsupportedScheme, "malformed scheme payload", err).
+				WithDetails("scheme", payment.Accepted.Scheme)
+		}
+	}
+
 	for i := range requirements {
 		req := &requirements[i]
-		if req.Network == payment.Accepted.Network && req.Scheme == payment.Accepted.Scheme {
-			return req, nil
+		if req.Network != payment.Accepted.Network || req.Scheme != payment.Accepted.Scheme {
+			continue
+		}
+		if custom && handler.ValidatePayload(payload, *req) != nil {
+			continue
 		}
+		return req, nil
 	}
 	return nil, NewPaymentError(
 		ErrCodeUnsupportedScheme,
@@ -354,3 +354,20 @@ func TestFindMatchingRequirement(t *testing.T) {
 		})
 	}
 }
+
+func BenchmarkFindMatchingRequirement(b *testing.B) {
+	networks := []string{NetworkBase, NetworkPolygon, NetworkAvalanche, NetworkSolanaMainnet, NetworkBaseSepolia}
+	requirements := make([]PaymentRequirements, len(networks))
+	for i, network := range networks {
+		requirements[i] = PaymentRequirements{Scheme: "exact", Network: network, Amount: "10000"}
+	}
+	payment := &PaymentPayload{X402Version: 2, Accepted: requirements[len(requirements)-1]}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := FindMatchingRequirement(payment, requirements); err != nil {
+			b.Fatalf("FindMatchingRequirement failed: %v", err)
+		}
+	}
+}
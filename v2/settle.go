@@ -0,0 +1,92 @@
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// settleResponseFields has the fields of SettleResponse without its JSON
+// methods.
+type settleResponseFields SettleResponse
+
+// MarshalJSON encodes the response with its unknown fields.
+func (r SettleResponse) MarshalJSON() ([]byte, error) {
+	return marshalWithUnknown(settleResponseFields(r), settleResponseKeys, r.UnknownFields)
+}
+
+// UnmarshalJSON decodes the response, keeping its unknown fields. Block numbers, slots and timestamps are accepted as numbers or
+// strings, since facilitators encode them differently; timestamps may also be
+// RFC 3339 times.
+func (r *SettleResponse) UnmarshalJSON(data []byte) error {
+	decoded := struct {
+		*settleResponseFields
+		BlockNumber flexibleUint `json:"blockNumber"`
+		Slot        flexibleUint `json:"slot"`
+		Timestamp   flexibleTime `json:"timestamp"`
+	}{settleResponseFields: (*settleResponseFields)(r)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	r.BlockNumber = uint64(decoded.BlockNumber)
+	r.Slot = uint64(decoded.Slot)
+	r.Timestamp = int64(decoded.Timestamp)
+
+	var err error
+	r.UnknownFields, err = unknownFields(data, settleResponseKeys)
+	return err
+}
+
+// SettledAt returns Timestamp as a time, and false if it was not reported.
+func (r SettleResponse) SettledAt() (time.Time, bool) {
+	if r.Timestamp <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(r.Timestamp, 0), true
+}
+
+// flexibleUint decodes a JSON number, decimal string or 0x-prefixed hex
+// string.
+type flexibleUint uint64
+
+func (u *flexibleUint) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "null" || text == "" {
+		return nil
+	}
+	var value uint64
+	var err error
+	if hex, ok := strings.CutPrefix(strings.ToLower(text), "0x"); ok {
+		value, err = strconv.ParseUint(hex, 16, 64)
+	} else {
+		value, err = strconv.ParseUint(text, 10, 64)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid unsigned integer %s", data)
+	}
+	*u = flexibleUint(value)
+	return nil
+}
+
+// flexibleTime decodes Unix seconds, as a number or string, or an RFC 3339
+// time into Unix seconds.
+type flexibleTime int64
+
+func (t *flexibleTime) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "null" || text == "" {
+		return nil
+	}
+	if seconds, err := strconv.ParseInt(text, 10, 64); err == nil {
+		*t = flexibleTime(seconds)
+		return nil
+	}
+	at, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %s", data)
+	}
+	*t = flexibleTime(at.Unix())
+	return nil
+}
@@ -0,0 +1,150 @@
+package evm
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/allowance"
+	"github.com/mark3labs/x402-go/v2/internal/keymem"
+)
+
+// WithTreasury enables allowance payments for requirements whose Extra has
+// "assetTransferMethod": "allowance". The signer's key acts as a hot key: it
+// holds no funds and signs relay orders that the relayer named in
+// Extra["spender"] executes as transferFrom(treasury, payTo, amount), against
+// an allowance the treasury granted the relayer beforehand. The relayer must
+// also know that the hot key may sign for the treasury.
+//
+// When trustedRelayers is non-empty, any other relayer is rejected with
+// v2.ErrUntrustedSpender. Balance preflights check the treasury's balance.
+func WithTreasury(treasury string, trustedRelayers ...string) Option {
+	return func(s *Signer) error {
+		if !common.IsHexAddress(treasury) {
+			return fmt.Errorf("invalid treasury address %q", treasury)
+		}
+		for _, relayer := range trustedRelayers {
+			if !common.IsHexAddress(relayer) {
+				return fmt.Errorf("invalid allowance relayer address %q", relayer)
+			}
+			s.allowanceRelayers = append(s.allowanceRelayers, common.HexToAddress(relayer))
+		}
+		address := common.HexToAddress(treasury)
+		s.treasury = &address
+		return nil
+	}
+}
+
+// Treasury returns the treasury allowance payments are made from, or false if
+// the signer was not created with WithTreasury.
+func (s *Signer) Treasury() (common.Address, bool) {
+	if s.treasury == nil {
+		return common.Address{}, false
+	}
+	return *s.treasury, true
+}
+
+// allowanceRelayer resolves and checks the relayer for an allowance payment.
+func (s *Signer) allowanceRelayer(requirements *v2.PaymentRequirements) (common.Address, error) {
+	value, _ := requirements.Extra["spender"].(string)
+	if !common.IsHexAddress(value) {
+		return common.Address{}, fmt.Errorf("missing or invalid allowance spender in requirements")
+	}
+	relayer := common.HexToAddress(value)
+
+	if len(s.allowanceRelayers) == 0 {
+		return relayer, nil
+	}
+	for _, trusted := range s.allowanceRelayers {
+		if trusted == relayer {
+			return relayer, nil
+		}
+	}
+	return common.Address{}, fmt.Errorf("%w: %s", v2.ErrUntrustedSpender, relayer.Hex())
+}
+
+// signAllowance signs a relay order paying the requirements from the treasury.
+func (s *Signer) signAllowance(requirements *v2.PaymentRequirements, tokenAddress common.Address, amount *big.Int, validAfter, validBefore time.Time) (*v2.PaymentPayload, error) {
+	relayer, err := s.allowanceRelayer(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceParams := NonceParams{
+		From:    *s.treasury,
+		To:      common.HexToAddress(requirements.PayTo),
+		Value:   amount,
+		Token:   tokenAddress,
+		ChainID: big.NewInt(s.chainID),
+	}
+	if key, ok := requirements.Extra["idempotencyKey"].(string); ok {
+		nonceParams.Key = key
+	}
+	nonce, err := s.nonceSource.Nonce(nonceParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	order := &allowance.Order{
+		Owner:       *s.treasury,
+		Signer:      s.address,
+		Spender:     relayer,
+		Token:       tokenAddress,
+		To:          nonceParams.To,
+		Value:       amount,
+		ValidAfter:  big.NewInt(validAfter.Unix()),
+		ValidBefore: big.NewInt(validBefore.Unix()),
+		Nonce:       nonce,
+	}
+
+	if s.nonceRecorder != nil {
+		err := s.nonceRecorder.RecordNonce(NonceRecord{
+			Network:     s.network,
+			Token:       tokenAddress.Hex(),
+			From:        order.Owner.Hex(),
+			To:          order.To.Hex(),
+			Value:       amount.String(),
+			Nonce:       common.BytesToHash(nonce[:]).Hex(),
+			ValidAfter:  validAfter,
+			ValidBefore: validBefore,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record nonce: %w", err)
+		}
+	}
+
+	signature, err := keymem.WithECDSA(s.key, func(key *ecdsa.PrivateKey) (string, error) {
+		return allowance.Sign(key, big.NewInt(s.chainID), order)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    *requirements,
+		Payload: v2.AllowancePayload{
+			Signature: signature,
+			AllowanceAuthorization: v2.AllowanceAuthorization{
+				Owner:       order.Owner.Hex(),
+				Signer:      order.Signer.Hex(),
+				Spender:     order.Spender.Hex(),
+				Token:       order.Token.Hex(),
+				To:          order.To.Hex(),
+				Value:       amount.String(),
+				ValidAfter:  order.ValidAfter.String(),
+				ValidBefore: order.ValidBefore.String(),
+				Nonce:       common.BytesToHash(nonce[:]).Hex(),
+			},
+		},
+	}, nil
+}
+
+// isAllowance reports whether the requirements ask for an allowance payment.
+func isAllowance(requirements *v2.PaymentRequirements) bool {
+	return assetTransferMethod(requirements) == v2.AssetTransferMethodAllowance
+}
@@ -0,0 +1,408 @@
+package evm
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// DefaultBridgePollTimeout is how long the bridge page's requests for work
+// are held open before it asks again.
+const DefaultBridgePollTimeout = 25 * time.Second
+
+// BrowserBridge relays signature requests to an EIP-1193 browser wallet, like
+// MetaMask, through a page served on a loopback address: the page connects
+// the wallet, long-polls the bridge for requests, asks the wallet to sign
+// each one with eth_signTypedData_v4 and posts the signature back. Desktop
+// and command-line applications use it with a WalletSigner so users approve
+// payments in their own wallet instead of handing over a private key.
+//
+// Every endpoint requires the random token in the page URL, and requests
+// must be addressed to a loopback host, so other sites open in the browser
+// cannot read or answer signature requests.
+type BrowserBridge struct {
+	token       string
+	pollTimeout time.Duration
+
+	mu       sync.Mutex
+	queue    []*bridgeRequest
+	pending  map[string]*bridgeRequest
+	changed  chan struct{}
+	accounts []common.Address
+	ready    chan struct{}
+
+	server *http.Server
+}
+
+// bridgeRequest is a signature request, as sent to the page.
+type bridgeRequest struct {
+	ID        string             `json:"id"`
+	Account   string             `json:"account"`
+	ChainID   string             `json:"chainId,omitempty"`
+	TypedData apitypes.TypedData `json:"typedData"`
+
+	result chan bridgeResult
+}
+
+// bridgeResult is the page's answer to a request.
+type bridgeResult struct {
+	ID        string `json:"id"`
+	Signature string `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Code      int    `json:"code,omitempty"`
+}
+
+// eip1193UserRejected is the EIP-1193 error code of a request the user rejected.
+const eip1193UserRejected = 4001
+
+// BrowserBridgeOption configures a BrowserBridge.
+type BrowserBridgeOption func(*BrowserBridge)
+
+// WithBridgePollTimeout sets how long the page's requests for work are held
+// open (default: DefaultBridgePollTimeout).
+func WithBridgePollTimeout(d time.Duration) BrowserBridgeOption {
+	return func(b *BrowserBridge) {
+		b.pollTimeout = d
+	}
+}
+
+// NewBrowserBridge creates a bridge. Serve it with Listen, or mount it as an
+// http.Handler on a loopback listener and open its URL.
+func NewBrowserBridge(opts ...BrowserBridgeOption) (*BrowserBridge, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("generating bridge token: %w", err)
+	}
+	b := &BrowserBridge{
+		token:       hex.EncodeToString(token),
+		pollTimeout: DefaultBridgePollTimeout,
+		pending:     make(map[string]*bridgeRequest),
+		changed:     make(chan struct{}),
+		ready:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b, nil
+}
+
+// Token returns the token the bridge page authenticates with.
+func (b *BrowserBridge) Token() string {
+	return b.token
+}
+
+// Listen serves the bridge on addr, a loopback address like "127.0.0.1:0",
+// and returns the URL of the page for the user to open.
+func (b *BrowserBridge) Listen(addr string) (string, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if !isLoopback(host) {
+		return "", fmt.Errorf("bridge address %q is not a loopback address", addr)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	b.server = &http.Server{Handler: b, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = b.server.Serve(listener) }()
+	return "http://" + listener.Addr().String() + "/?token=" + b.token, nil
+}
+
+// Close stops serving the bridge started with Listen.
+func (b *BrowserBridge) Close() error {
+	if b.server == nil {
+		return nil
+	}
+	return b.server.Close()
+}
+
+// Accounts waits until the page has connected the wallet and returns its
+// accounts, the selected one first.
+func (b *BrowserBridge) Accounts(ctx context.Context) ([]common.Address, error) {
+	select {
+	case <-b.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]common.Address(nil), b.accounts...), nil
+}
+
+// SignTypedData implements TypedDataSigner. It waits until the page delivers
+// the request to the wallet and the user approves or rejects it; rejections
+// return v2.ErrSignatureRejected.
+func (b *BrowserBridge) SignTypedData(ctx context.Context, account common.Address, data apitypes.TypedData) (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	req := &bridgeRequest{
+		ID:        hex.EncodeToString(id),
+		Account:   account.Hex(),
+		TypedData: data,
+		result:    make(chan bridgeResult, 1),
+	}
+	if data.Domain.ChainId != nil {
+		req.ChainID = hexutil.EncodeBig((*big.Int)(data.Domain.ChainId))
+	}
+
+	b.mu.Lock()
+	b.queue = append(b.queue, req)
+	b.pending[req.ID] = req
+	b.notifyLocked()
+	b.mu.Unlock()
+	defer b.remove(req)
+
+	select {
+	case result := <-req.result:
+		switch {
+		case result.Code == eip1193UserRejected:
+			return "", fmt.Errorf("%w: %s", v2.ErrSignatureRejected, result.Error)
+		case result.Error != "":
+			return "", errors.New(result.Error)
+		}
+		return result.Signature, nil
+	case <-ctx.Done():
+		return "", fmt.Errorf("waiting for wallet approval: %w", ctx.Err())
+	}
+}
+
+// remove forgets req, answered or abandoned.
+func (b *BrowserBridge) remove(req *bridgeRequest) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.pending, req.ID)
+	for i, queued := range b.queue {
+		if queued == req {
+			b.queue = append(b.queue[:i], b.queue[i+1:]...)
+			break
+		}
+	}
+}
+
+// notifyLocked wakes the page's pending polls.
+func (b *BrowserBridge) notifyLocked() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// ServeHTTP serves the bridge page and the endpoints it calls.
+func (b *BrowserBridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(r.Host); err == nil {
+		host = h
+	}
+	if !isLoopback(host) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(b.token)) != 1 {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if origin := r.Header.Get("Origin"); origin != "" && origin != "http://"+r.Host {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	switch {
+	case r.URL.Path == "/" && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; connect-src 'self'")
+		_, _ = w.Write([]byte(bridgePage))
+	case r.URL.Path == "/connect" && r.Method == http.MethodPost:
+		b.serveConnect(w, r)
+	case r.URL.Path == "/next" && r.Method == http.MethodGet:
+		b.serveNext(w, r)
+	case r.URL.Path == "/result" && r.Method == http.MethodPost:
+		b.serveResult(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveConnect records the accounts of the connected wallet.
+func (b *BrowserBridge) serveConnect(w http.ResponseWriter, r *http.Request) {
+	var connected struct {
+		Accounts []string `json:"accounts"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&connected); err != nil || len(connected.Accounts) == 0 {
+		http.Error(w, "Invalid connection", http.StatusBadRequest)
+		return
+	}
+	accounts := make([]common.Address, 0, len(connected.Accounts))
+	for _, account := range connected.Accounts {
+		if !common.IsHexAddress(account) {
+			http.Error(w, "Invalid account", http.StatusBadRequest)
+			return
+		}
+		accounts = append(accounts, common.HexToAddress(account))
+	}
+
+	b.mu.Lock()
+	b.accounts = accounts
+	select {
+	case <-b.ready:
+	default:
+		close(b.ready)
+	}
+	b.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveNext hands the page the oldest undelivered request, waiting up to the
+// poll timeout for one.
+func (b *BrowserBridge) serveNext(w http.ResponseWriter, r *http.Request) {
+	timer := time.NewTimer(b.pollTimeout)
+	defer timer.Stop()
+	for {
+		b.mu.Lock()
+		if len(b.queue) > 0 {
+			req := b.queue[0]
+			b.queue = b.queue[1:]
+			b.mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(req)
+			return
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// serveResult delivers the page's answer to the waiting SignTypedData.
+func (b *BrowserBridge) serveResult(w http.ResponseWriter, r *http.Request) {
+	var result bridgeResult
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&result); err != nil {
+		http.Error(w, "Invalid result", http.StatusBadRequest)
+		return
+	}
+	b.mu.Lock()
+	req, ok := b.pending[result.ID]
+	delete(b.pending, result.ID)
+	b.mu.Unlock()
+	if !ok {
+		http.Error(w, "Unknown request", http.StatusNotFound)
+		return
+	}
+	req.result <- result
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isLoopback reports whether host names the local machine.
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// bridgePage connects the wallet and relays signature requests.
+const bridgePage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>x402 wallet bridge</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 40em; margin: 3em auto; padding: 0 1em; }
+#log { font-family: monospace; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>x402 wallet bridge</h1>
+<p>Connect your wallet and keep this tab open. Payment requests from your application appear in your wallet for approval.</p>
+<button id="connect">Connect wallet</button>
+<p id="status">Not connected.</p>
+<div id="log"></div>
+<script>
+const token = new URLSearchParams(location.search).get("token");
+const q = "?token=" + encodeURIComponent(token);
+const status = document.getElementById("status");
+const log = (line) => { document.getElementById("log").textContent += new Date().toLocaleTimeString() + " " + line + "\n"; };
+const post = (path, body) => fetch(path + q, { method: "POST", headers: { "Content-Type": "application/json" }, body: JSON.stringify(body) });
+let chainId = null;
+
+async function handle(req) {
+  const message = req.typedData.message || {};
+  log("Signing payment of " + message.value + " to " + message.to);
+  try {
+    if (req.chainId && req.chainId !== chainId) {
+      await ethereum.request({ method: "wallet_switchEthereumChain", params: [{ chainId: req.chainId }] });
+      chainId = req.chainId;
+    }
+    const signature = await ethereum.request({ method: "eth_signTypedData_v4", params: [req.account, JSON.stringify(req.typedData)] });
+    await post("/result", { id: req.id, signature: signature });
+    log("Signed.");
+  } catch (e) {
+    await post("/result", { id: req.id, error: (e && e.message) || String(e), code: (e && e.code) || 0 });
+    log("Not signed: " + ((e && e.message) || e));
+  }
+}
+
+async function poll() {
+  for (;;) {
+    try {
+      const resp = await fetch("/next" + q);
+      if (resp.status === 200) {
+        await handle(await resp.json());
+      } else if (resp.status !== 204) {
+        await new Promise((r) => setTimeout(r, 2000));
+      }
+    } catch (e) {
+      status.textContent = "Application disconnected.";
+      await new Promise((r) => setTimeout(r, 2000));
+    }
+  }
+}
+
+document.getElementById("connect").onclick = async () => {
+  if (!window.ethereum) {
+    status.textContent = "No browser wallet found.";
+    return;
+  }
+  try {
+    const accounts = await ethereum.request({ method: "eth_requestAccounts" });
+    chainId = await ethereum.request({ method: "eth_chainId" });
+    await post("/connect", { accounts: accounts, chainId: chainId });
+    status.textContent = "Connected " + accounts[0] + ".";
+    document.getElementById("connect").disabled = true;
+    poll();
+  } catch (e) {
+    status.textContent = "Connection failed: " + ((e && e.message) || e);
+  }
+};
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,224 @@
+package evm
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/eip3009"
+	"github.com/mark3labs/x402-go/v2/internal/keymem"
+)
+
+// DeferredAuthorization is an outstanding authorization issued for a deferred payment.
+type DeferredAuthorization struct {
+	// Network is the CAIP-2 network of the authorization.
+	Network string
+
+	// Asset is the token contract address.
+	Asset string
+
+	// PayTo is the seller's address.
+	PayTo string
+
+	// Amount is the authorized amount in atomic units.
+	Amount string
+
+	// Nonce is the 32-byte hex-encoded authorization nonce.
+	Nonce string
+
+	// IssuedAt is when the authorization was signed.
+	IssuedAt time.Time
+
+	// ClaimDeadline is the authorization's validBefore; after it the seller can
+	// no longer claim the payment.
+	ClaimDeadline time.Time
+
+	requirements v2.PaymentRequirements
+}
+
+// DeferredSigner signs payments for the "deferred" scheme: the seller claims the
+// payment within a claim window after delivering the resource. It tracks every
+// authorization it issues until it is settled, cancelled, or expires.
+//
+// Deferred payments use EIP-3009 authorizations; Permit2 and allowance
+// requirements are not supported.
+type DeferredSigner struct {
+	signer *Signer
+
+	mu          sync.Mutex
+	outstanding map[string]DeferredAuthorization
+}
+
+// NewDeferredSigner creates a DeferredSigner that signs with signer's key, tokens,
+// limits, and nonce source.
+func NewDeferredSigner(signer *Signer) *DeferredSigner {
+	return &DeferredSigner{
+		signer:      signer,
+		outstanding: make(map[string]DeferredAuthorization),
+	}
+}
+
+// Network returns the CAIP-2 network identifier.
+func (d *DeferredSigner) Network() string {
+	return d.signer.Network()
+}
+
+// Scheme returns the payment scheme identifier.
+func (d *DeferredSigner) Scheme() string {
+	return v2.SchemeDeferred
+}
+
+// CanSign reports whether the requirements use the deferred scheme with an
+// EIP-3009 token this signer holds.
+func (d *DeferredSigner) CanSign(requirements *v2.PaymentRequirements) bool {
+	if requirements.Scheme != v2.SchemeDeferred || assetTransferMethod(requirements) != v2.AssetTransferMethodEIP3009 {
+		return false
+	}
+	return d.signer.canSignAsset(requirements)
+}
+
+// Sign creates an authorization valid for the requirement's claim window
+// (see v2.ClaimWindow) and records it as outstanding.
+func (d *DeferredSigner) Sign(requirements *v2.PaymentRequirements) (*v2.PaymentPayload, error) {
+	if !d.CanSign(requirements) {
+		return nil, v2.ErrNoValidSigner
+	}
+
+	payload, auth, err := d.signer.sign(requirements, v2.ClaimWindow(requirements))
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := common.BytesToHash(auth.Nonce[:]).Hex()
+	d.mu.Lock()
+	d.outstanding[nonce] = DeferredAuthorization{
+		Network:       requirements.Network,
+		Asset:         requirements.Asset,
+		PayTo:         requirements.PayTo,
+		Amount:        auth.Value.String(),
+		Nonce:         nonce,
+		IssuedAt:      d.signer.clock.Now(),
+		ClaimDeadline: time.Unix(auth.ValidBefore.Int64(), 0),
+		requirements:  *requirements,
+	}
+	d.mu.Unlock()
+
+	return payload, nil
+}
+
+// GetPriority returns the signer's priority level.
+func (d *DeferredSigner) GetPriority() int {
+	return d.signer.GetPriority()
+}
+
+// GetTokens returns the list of supported tokens.
+func (d *DeferredSigner) GetTokens() []v2.TokenConfig {
+	return d.signer.GetTokens()
+}
+
+// GetMaxAmount returns the per-call spending limit, or nil if no limit is set.
+func (d *DeferredSigner) GetMaxAmount() *big.Int {
+	return d.signer.GetMaxAmount()
+}
+
+// Outstanding returns the authorizations that may still be claimed, oldest first.
+// Expired authorizations are dropped.
+func (d *DeferredSigner) Outstanding() []DeferredAuthorization {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.signer.clock.Now()
+	auths := make([]DeferredAuthorization, 0, len(d.outstanding))
+	for nonce, auth := range d.outstanding {
+		if !now.Before(auth.ClaimDeadline) {
+			delete(d.outstanding, nonce)
+			continue
+		}
+		auths = append(auths, auth)
+	}
+	sort.Slice(auths, func(i, j int) bool {
+		return auths[i].IssuedAt.Before(auths[j].IssuedAt)
+	})
+	return auths
+}
+
+// OutstandingAmount returns the total amount that may still be claimed for an asset.
+func (d *DeferredSigner) OutstandingAmount(asset string) *big.Int {
+	total := new(big.Int)
+	for _, auth := range d.Outstanding() {
+		if !strings.EqualFold(auth.Asset, asset) {
+			continue
+		}
+		if amount, ok := new(big.Int).SetString(auth.Amount, 10); ok {
+			total.Add(total, amount)
+		}
+	}
+	return total
+}
+
+// MarkSettled stops tracking the authorization with the given nonce, e.g. after
+// observing the seller's settlement transaction.
+func (d *DeferredSigner) MarkSettled(nonce string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.outstanding, normalizeNonce(nonce))
+}
+
+// Cancel signs an EIP-3009 cancelAuthorization for an outstanding authorization
+// and stops tracking it. The cancellation only takes effect once submitted
+// on-chain before the seller claims the payment.
+//
+// Returns v2.ErrUnknownAuthorization if the nonce is not outstanding.
+func (d *DeferredSigner) Cancel(nonce string) (*v2.DeferredCancellation, error) {
+	nonce = normalizeNonce(nonce)
+
+	d.mu.Lock()
+	auth, ok := d.outstanding[nonce]
+	if ok && !d.signer.clock.Now().Before(auth.ClaimDeadline) {
+		delete(d.outstanding, nonce)
+		ok = false
+	}
+	d.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", v2.ErrUnknownAuthorization, nonce)
+	}
+
+	token := common.HexToAddress(auth.Asset)
+	domain, err := d.signer.resolveDomain(&auth.requirements, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonceBytes [32]byte
+	copy(nonceBytes[:], common.HexToHash(nonce).Bytes())
+	signature, err := keymem.WithECDSA(d.signer.key, func(key *ecdsa.PrivateKey) (string, error) {
+		return eip3009.SignCancelAuthorizationWithDomain(key, domain, d.signer.address, nonceBytes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	delete(d.outstanding, nonce)
+	d.mu.Unlock()
+
+	return &v2.DeferredCancellation{
+		Network:    auth.Network,
+		Asset:      token.Hex(),
+		Authorizer: d.signer.address.Hex(),
+		Nonce:      nonce,
+		Signature:  signature,
+	}, nil
+}
+
+// normalizeNonce returns the canonical hex encoding of a nonce.
+func normalizeNonce(nonce string) string {
+	return common.HexToHash(nonce).Hex()
+}
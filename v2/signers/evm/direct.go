@@ -0,0 +1,193 @@
+package evm
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/keymem"
+)
+
+// DefaultSubmitTimeout bounds how long DirectSigner spends preparing and
+// submitting a transfer.
+const DefaultSubmitTimeout = 30 * time.Second
+
+// selectorTransfer is the function selector of ERC-20 transfer(address,uint256).
+var selectorTransfer = crypto.Keccak256([]byte("transfer(address,uint256)"))[:4]
+
+// DirectBackend is the RPC access DirectSigner needs to submit transfers. It is
+// satisfied by *ethclient.Client.
+type DirectBackend interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+}
+
+// DirectSigner pays requirements of the "direct" scheme by submitting an ERC-20
+// transfer to payTo itself and presenting the transaction hash, for sellers
+// that confirm payments on-chain instead of using a facilitator. Unlike other
+// schemes, Sign spends funds (and gas) even if the server then refuses the
+// payment, so only use it with servers you trust.
+type DirectSigner struct {
+	signer  *Signer
+	backend DirectBackend
+	timeout time.Duration
+}
+
+// NewDirectSigner creates a DirectSigner that submits transfers through backend
+// with signer's key, tokens, and limits.
+func NewDirectSigner(signer *Signer, backend DirectBackend) *DirectSigner {
+	return &DirectSigner{signer: signer, backend: backend, timeout: DefaultSubmitTimeout}
+}
+
+// Network returns the CAIP-2 network identifier.
+func (d *DirectSigner) Network() string {
+	return d.signer.Network()
+}
+
+// Scheme returns the payment scheme identifier.
+func (d *DirectSigner) Scheme() string {
+	return v2.SchemeDirect
+}
+
+// CanSign reports whether the requirements use the direct scheme on this
+// signer's network with a token it holds.
+func (d *DirectSigner) CanSign(requirements *v2.PaymentRequirements) bool {
+	if requirements.Scheme != v2.SchemeDirect || requirements.Network != d.signer.network {
+		return false
+	}
+	for _, token := range d.signer.tokens {
+		if strings.EqualFold(token.Address, requirements.Asset) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign submits the transfer and returns a payload presenting its hash. It does
+// not wait for the transaction to be mined; the server waits for confirmation.
+func (d *DirectSigner) Sign(requirements *v2.PaymentRequirements) (*v2.PaymentPayload, error) {
+	if !d.CanSign(requirements) {
+		return nil, v2.ErrNoValidSigner
+	}
+	amount, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		return nil, v2.ErrInvalidAmount
+	}
+	if d.signer.maxAmount != nil && amount.Cmp(d.signer.maxAmount) > 0 {
+		return nil, v2.ErrAmountExceeded
+	}
+	if !common.IsHexAddress(requirements.PayTo) {
+		return nil, fmt.Errorf("%w: invalid payTo %q", v2.ErrInvalidRequirements, requirements.PayTo)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	tx, err := d.transferTx(ctx, common.HexToAddress(requirements.Asset), common.HexToAddress(requirements.PayTo), amount)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", v2.ErrSigningFailed, err)
+	}
+
+	chainID := big.NewInt(d.signer.chainID)
+	type signed struct {
+		tx        *types.Transaction
+		signature []byte
+	}
+	result, err := keymem.WithECDSA(d.signer.key, func(key *ecdsa.PrivateKey) (signed, error) {
+		tx, err := types.SignTx(tx, types.LatestSignerForChainID(chainID), key)
+		if err != nil {
+			return signed{}, err
+		}
+		signature, err := crypto.Sign(accounts.TextHash(tx.Hash().Bytes()), key)
+		if err != nil {
+			return signed{}, err
+		}
+		signature[crypto.RecoveryIDOffset] += 27
+		return signed{tx: tx, signature: signature}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.backend.SendTransaction(ctx, result.tx); err != nil {
+		return nil, fmt.Errorf("%w: submitting transfer: %v", v2.ErrSigningFailed, err)
+	}
+
+	return &v2.PaymentPayload{
+		X402Version: v2.X402Version,
+		Accepted:    *requirements,
+		Payload: v2.DirectPayload{
+			Transaction: result.tx.Hash().Hex(),
+			Signature:   hexutil.Encode(result.signature),
+		},
+	}, nil
+}
+
+// transferTx builds an unsigned EIP-1559 transaction transferring amount of
+// token to payTo.
+func (d *DirectSigner) transferTx(ctx context.Context, token, payTo common.Address, amount *big.Int) (*types.Transaction, error) {
+	data := make([]byte, 0, 68)
+	data = append(data, selectorTransfer...)
+	data = append(data, common.LeftPadBytes(payTo.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(amount.Bytes(), 32)...)
+
+	nonce, err := d.backend.PendingNonceAt(ctx, d.signer.address)
+	if err != nil {
+		return nil, fmt.Errorf("getting nonce: %w", err)
+	}
+	tip, err := d.backend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting gas tip: %w", err)
+	}
+	head, err := d.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("getting latest header: %w", err)
+	}
+	feeCap := new(big.Int).Set(tip)
+	if head.BaseFee != nil {
+		feeCap.Add(feeCap, new(big.Int).Mul(head.BaseFee, big.NewInt(2)))
+	}
+	gas, err := d.backend.EstimateGas(ctx, ethereum.CallMsg{From: d.signer.address, To: &token, Data: data})
+	if err != nil {
+		return nil, fmt.Errorf("estimating gas: %w", err)
+	}
+
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(d.signer.chainID),
+		Nonce:     nonce,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       gas,
+		To:        &token,
+		Data:      data,
+	}), nil
+}
+
+// GetPriority returns the signer's priority level.
+func (d *DirectSigner) GetPriority() int {
+	return d.signer.GetPriority()
+}
+
+// GetTokens returns the list of supported tokens.
+func (d *DirectSigner) GetTokens() []v2.TokenConfig {
+	return d.signer.GetTokens()
+}
+
+// GetMaxAmount returns the per-call spending limit, or nil if no limit is set.
+func (d *DirectSigner) GetMaxAmount() *big.Int {
+	return d.signer.GetMaxAmount()
+}
@@ -0,0 +1,158 @@
+package evm
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/eip3009"
+)
+
+// DomainOverride pins the EIP-712 domain used to sign authorizations for a token.
+// Empty fields fall back to the built-in chain configuration, then to the
+// requirement's Extra.
+type DomainOverride struct {
+	// Name is the EIP-712 domain name (e.g., "USD Coin").
+	Name string
+
+	// Version is the EIP-712 domain version (e.g., "2").
+	Version string
+
+	// VerifyingContract overrides the verifying contract address.
+	// Defaults to the token address.
+	VerifyingContract string
+
+	// Salt is an optional 32-byte hex-encoded domain salt.
+	Salt string
+}
+
+// WithDomainOverride pins the EIP-712 domain for tokenAddress.
+// When set, any name, version, verifyingContract, or salt in the server's Extra
+// that differs from the override is rejected with v2.ErrUntrustedDomain.
+func WithDomainOverride(tokenAddress string, override DomainOverride) Option {
+	return func(s *Signer) error {
+		if !common.IsHexAddress(tokenAddress) {
+			return fmt.Errorf("%w: invalid token address %q", v2.ErrInvalidToken, tokenAddress)
+		}
+		if override.VerifyingContract != "" && !common.IsHexAddress(override.VerifyingContract) {
+			return fmt.Errorf("%w: invalid verifying contract %q", v2.ErrInvalidToken, override.VerifyingContract)
+		}
+		if override.Salt != "" && len(common.FromHex(override.Salt)) != common.HashLength {
+			return fmt.Errorf("%w: salt must be 32 bytes", v2.ErrInvalidToken)
+		}
+		s.domains[strings.ToLower(tokenAddress)] = override
+		return nil
+	}
+}
+
+// WithStrictDomain refuses to sign for tokens without a trusted EIP-712 domain,
+// i.e. tokens that have neither a DomainOverride nor a built-in chain configuration.
+// By default the domain from the server's Extra is used for such tokens.
+func WithStrictDomain() Option {
+	return func(s *Signer) error {
+		s.strict = true
+		return nil
+	}
+}
+
+// trustedDomain returns the known-good domain for tokenAddress, if any.
+func (s *Signer) trustedDomain(tokenAddress common.Address) (DomainOverride, bool) {
+	trusted, hasOverride := s.domains[strings.ToLower(tokenAddress.Hex())]
+
+	chain, err := v2.GetChainConfig(s.network)
+	hasChain := err == nil && chain.EIP3009Name != "" && strings.EqualFold(chain.USDCAddress, tokenAddress.Hex())
+	if hasChain {
+		if trusted.Name == "" {
+			trusted.Name = chain.EIP3009Name
+		}
+		if trusted.Version == "" {
+			trusted.Version = chain.EIP3009Version
+		}
+	}
+
+	return trusted, hasOverride || hasChain
+}
+
+// resolveDomain builds the EIP-712 domain for a payment, validating the server's
+// Extra against trusted values so a malicious server cannot redirect the signature
+// to a different contract or domain.
+func (s *Signer) resolveDomain(requirements *v2.PaymentRequirements, tokenAddress common.Address) (eip3009.Domain, error) {
+	trusted, ok := s.trustedDomain(tokenAddress)
+	if !ok {
+		if s.strict {
+			return eip3009.Domain{}, fmt.Errorf("%w: no trusted domain for token %s", v2.ErrUntrustedDomain, tokenAddress.Hex())
+		}
+		name, version, err := extractEIP3009Params(requirements)
+		if err != nil {
+			return eip3009.Domain{}, err
+		}
+		trusted = DomainOverride{Name: name, Version: version}
+	}
+
+	domain := eip3009.Domain{
+		Name:              trusted.Name,
+		Version:           trusted.Version,
+		ChainID:           big.NewInt(s.chainID),
+		VerifyingContract: tokenAddress,
+	}
+	if trusted.VerifyingContract != "" {
+		domain.VerifyingContract = common.HexToAddress(trusted.VerifyingContract)
+	}
+	if trusted.Salt != "" {
+		salt := common.HexToHash(trusted.Salt)
+		domain.Salt = &salt
+	}
+
+	// Fill gaps in a partial override from Extra
+	if domain.Name == "" || domain.Version == "" {
+		name, version, err := extractEIP3009Params(requirements)
+		if err != nil {
+			return eip3009.Domain{}, err
+		}
+		if domain.Name == "" {
+			domain.Name = name
+		}
+		if domain.Version == "" {
+			domain.Version = version
+		}
+	}
+
+	if err := checkExtra(requirements.Extra, domain); err != nil {
+		return eip3009.Domain{}, err
+	}
+	return domain, nil
+}
+
+// checkExtra rejects any domain field in extra that differs from the resolved domain.
+func checkExtra(extra map[string]interface{}, domain eip3009.Domain) error {
+	mismatch := func(field string, got interface{}, want string) error {
+		return fmt.Errorf("%w: %s %v does not match trusted value %s", v2.ErrUntrustedDomain, field, got, want)
+	}
+
+	if value, ok := extra["name"]; ok && value != domain.Name {
+		return mismatch("name", value, domain.Name)
+	}
+	if value, ok := extra["version"]; ok && value != domain.Version {
+		return mismatch("version", value, domain.Version)
+	}
+	if value, ok := extra["verifyingContract"]; ok {
+		address, isString := value.(string)
+		if !isString || !common.IsHexAddress(address) || common.HexToAddress(address) != domain.VerifyingContract {
+			return mismatch("verifyingContract", value, domain.VerifyingContract.Hex())
+		}
+	}
+	if value, ok := extra["salt"]; ok {
+		salt, isString := value.(string)
+		if !isString || domain.Salt == nil || common.HexToHash(salt) != *domain.Salt {
+			want := "none"
+			if domain.Salt != nil {
+				want = domain.Salt.Hex()
+			}
+			return mismatch("salt", value, want)
+		}
+	}
+	return nil
+}
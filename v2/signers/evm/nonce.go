@@ -0,0 +1,128 @@
+package evm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/mark3labs/x402-go/v2/internal/eip3009"
+)
+
+// NonceParams describes the authorization a nonce is generated for.
+type NonceParams = eip3009.NonceParams
+
+// NonceSource generates EIP-3009 authorization nonces.
+type NonceSource = eip3009.NonceSource
+
+// RandomNonceSource generates cryptographically random nonces. It is the default.
+type RandomNonceSource = eip3009.RandomNonceSource
+
+// HMACNonceSource derives nonces deterministically from the authorization
+// parameters and an idempotency key. Retrying the same payment with the same key
+// yields the same nonce, so at most one of the resulting authorizations can ever
+// settle.
+type HMACNonceSource = eip3009.HMACNonceSource
+
+// SequentialNonceSource issues nonces made of a fixed 24-byte prefix followed by an
+// incrementing 64-bit counter, which makes issued nonces easy to audit.
+type SequentialNonceSource = eip3009.SequentialNonceSource
+
+// NewHMACNonceSource creates an HMACNonceSource keyed with secret (at least 16 bytes).
+//
+// The nonce covers chain, token, payer, payee, amount, and the requirement's
+// "idempotencyKey" Extra value. Without an idempotency key, each signing attempt
+// mixes in random bytes, so repeat purchases of the same amount from the same
+// payee get distinct nonces and can all settle.
+func NewHMACNonceSource(secret []byte) (*HMACNonceSource, error) {
+	return eip3009.NewHMACNonceSource(secret)
+}
+
+// NewSequentialNonceSource creates a SequentialNonceSource with the given prefix
+// (at most 24 bytes, left-padded with zeros) starting at counter value start.
+// Use a distinct prefix per signer process to avoid collisions after restarts.
+func NewSequentialNonceSource(prefix []byte, start uint64) (*SequentialNonceSource, error) {
+	return eip3009.NewSequentialNonceSource(prefix, start)
+}
+
+// NonceRecord describes an issued authorization nonce.
+type NonceRecord struct {
+	// Network is the CAIP-2 network the authorization was issued for.
+	Network string
+
+	// Token is the token contract address.
+	Token string
+
+	// From is the payer address.
+	From string
+
+	// To is the payee address.
+	To string
+
+	// Value is the authorized amount in atomic units.
+	Value string
+
+	// Nonce is the 32-byte hex-encoded nonce.
+	Nonce string
+
+	// ValidAfter and ValidBefore are the authorization validity window.
+	ValidAfter  time.Time
+	ValidBefore time.Time
+}
+
+// NonceRecorder stores issued nonces, e.g. to reconcile client records with
+// AuthorizationUsed events on-chain.
+type NonceRecorder interface {
+	// RecordNonce is called after a nonce is issued and before the authorization
+	// is signed. Returning an error aborts signing.
+	RecordNonce(record NonceRecord) error
+}
+
+// NonceRecorderFunc adapts a function to the NonceRecorder interface.
+type NonceRecorderFunc func(record NonceRecord) error
+
+// RecordNonce calls f(record).
+func (f NonceRecorderFunc) RecordNonce(record NonceRecord) error {
+	return f(record)
+}
+
+// WithNonceSource sets the nonce source used for authorizations (default: RandomNonceSource).
+func WithNonceSource(source NonceSource) Option {
+	return func(s *Signer) error {
+		if source == nil {
+			return fmt.Errorf("nonce source must not be nil")
+		}
+		s.nonceSource = source
+		return nil
+	}
+}
+
+// WithNonceRecorder records every issued nonce with recorder.
+func WithNonceRecorder(recorder NonceRecorder) Option {
+	return func(s *Signer) error {
+		s.nonceRecorder = recorder
+		return nil
+	}
+}
+
+// recordNonce forwards an issued authorization to the configured recorder, if any.
+func (s *Signer) recordNonce(token common.Address, auth *eip3009.Authorization) error {
+	if s.nonceRecorder == nil {
+		return nil
+	}
+
+	err := s.nonceRecorder.RecordNonce(NonceRecord{
+		Network:     s.network,
+		Token:       token.Hex(),
+		From:        auth.From.Hex(),
+		To:          auth.To.Hex(),
+		Value:       auth.Value.String(),
+		Nonce:       common.BytesToHash(auth.Nonce[:]).Hex(),
+		ValidAfter:  time.Unix(auth.ValidAfter.Int64(), 0),
+		ValidBefore: time.Unix(auth.ValidBefore.Int64(), 0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record nonce: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package evm
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/keymem"
+	"github.com/mark3labs/x402-go/v2/internal/permit2"
+)
+
+// Permit2Address is the canonical Uniswap Permit2 contract address.
+var Permit2Address = permit2.Address
+
+// WithPermit2 enables Permit2 payments for requirements whose Extra has
+// "assetTransferMethod": "permit2". This allows paying with any ERC-20 the wallet
+// has approved to the Permit2 contract, not only EIP-3009 tokens.
+//
+// The spender is taken from Extra["spender"]. When trustedSpenders is non-empty,
+// any other spender is rejected with v2.ErrUntrustedSpender. The recipient and
+// amount are always bound by the signature, so a spender can never redirect funds.
+func WithPermit2(trustedSpenders ...string) Option {
+	return func(s *Signer) error {
+		for _, spender := range trustedSpenders {
+			if !common.IsHexAddress(spender) {
+				return fmt.Errorf("invalid Permit2 spender address %q", spender)
+			}
+			s.permit2Spenders = append(s.permit2Spenders, common.HexToAddress(spender))
+		}
+		s.permit2 = true
+		return nil
+	}
+}
+
+// assetTransferMethod returns the transfer method requested by the requirements.
+func assetTransferMethod(requirements *v2.PaymentRequirements) string {
+	if method, ok := requirements.Extra["assetTransferMethod"].(string); ok && method != "" {
+		return strings.ToLower(method)
+	}
+	return v2.AssetTransferMethodEIP3009
+}
+
+// permit2Spender resolves and checks the spender for a Permit2 payment.
+func (s *Signer) permit2Spender(requirements *v2.PaymentRequirements) (common.Address, error) {
+	value, _ := requirements.Extra["spender"].(string)
+	if !common.IsHexAddress(value) {
+		return common.Address{}, fmt.Errorf("missing or invalid Permit2 spender in requirements")
+	}
+	spender := common.HexToAddress(value)
+
+	if len(s.permit2Spenders) == 0 {
+		return spender, nil
+	}
+	for _, trusted := range s.permit2Spenders {
+		if trusted == spender {
+			return spender, nil
+		}
+	}
+	return common.Address{}, fmt.Errorf("%w: %s", v2.ErrUntrustedSpender, spender.Hex())
+}
+
+// signPermit2 signs a Permit2 PermitWitnessTransferFrom for the requirements.
+func (s *Signer) signPermit2(requirements *v2.PaymentRequirements, tokenAddress common.Address, amount *big.Int, validAfter, deadline time.Time) (*v2.PaymentPayload, error) {
+	spender, err := s.permit2Spender(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceParams := NonceParams{
+		From:    s.address,
+		To:      common.HexToAddress(requirements.PayTo),
+		Value:   amount,
+		Token:   tokenAddress,
+		ChainID: big.NewInt(s.chainID),
+	}
+	if key, ok := requirements.Extra["idempotencyKey"].(string); ok {
+		nonceParams.Key = key
+	}
+	nonce, err := s.nonceSource.Nonce(nonceParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	permit := &permit2.Permit{
+		Token:    tokenAddress,
+		Amount:   amount,
+		Spender:  spender,
+		Nonce:    new(big.Int).SetBytes(nonce[:]),
+		Deadline: big.NewInt(deadline.Unix()),
+		Witness: permit2.Witness{
+			To:         nonceParams.To,
+			ValidAfter: big.NewInt(validAfter.Unix()),
+		},
+	}
+
+	if s.nonceRecorder != nil {
+		err := s.nonceRecorder.RecordNonce(NonceRecord{
+			Network:     s.network,
+			Token:       tokenAddress.Hex(),
+			From:        s.address.Hex(),
+			To:          permit.Witness.To.Hex(),
+			Value:       amount.String(),
+			Nonce:       common.BytesToHash(nonce[:]).Hex(),
+			ValidAfter:  validAfter,
+			ValidBefore: deadline,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record nonce: %w", err)
+		}
+	}
+
+	signature, err := keymem.WithECDSA(s.key, func(key *ecdsa.PrivateKey) (string, error) {
+		return permit2.Sign(key, big.NewInt(s.chainID), permit)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v2.PaymentPayload{
+		X402Version: 2,
+		Accepted:    *requirements,
+		Payload: v2.Permit2Payload{
+			Signature: signature,
+			Permit2Authorization: v2.Permit2Authorization{
+				From: s.address.Hex(),
+				Permitted: v2.Permit2TokenPermissions{
+					Token:  tokenAddress.Hex(),
+					Amount: amount.String(),
+				},
+				Spender:  spender.Hex(),
+				Nonce:    permit.Nonce.String(),
+				Deadline: permit.Deadline.String(),
+				Witness: v2.Permit2Witness{
+					To:         permit.Witness.To.Hex(),
+					ValidAfter: permit.Witness.ValidAfter.String(),
+					Extra:      "0x",
+				},
+			},
+		},
+	}, nil
+}
+
+// isPermit2 reports whether the requirements ask for a Permit2 payment.
+func isPermit2(requirements *v2.PaymentRequirements) bool {
+	return assetTransferMethod(requirements) == v2.AssetTransferMethodPermit2
+}
@@ -0,0 +1,272 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// DefaultPreflightTTL is how long a Preflight reuses the balances it fetched.
+const DefaultPreflightTTL = 10 * time.Second
+
+// Multicall3Address is the address Multicall3 is deployed at on most EVM chains.
+var Multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// Function selectors of the ERC-20 and EIP-3009 views a Preflight calls.
+var (
+	selectorBalanceOf          = crypto.Keccak256([]byte("balanceOf(address)"))[:4]
+	selectorAuthorizationState = crypto.Keccak256([]byte("authorizationState(address,bytes32)"))[:4]
+)
+
+// multicallABI is the ABI of Multicall3's aggregate3.
+var multicallABI = mustParseABI(`[{"name":"aggregate3","type":"function","stateMutability":"payable",
+	"inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}]}],
+	"outputs":[{"name":"returnData","type":"tuple[]","components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}]}]}]`)
+
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// multicallCall and multicallResult are the Call3 and Result structs of Multicall3.
+type multicallCall struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type multicallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// CallBackend is the RPC access a Preflight needs. It is satisfied by
+// *ethclient.Client.
+type CallBackend interface {
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// AuthorizationNonce identifies an EIP-3009 authorization nonce of a token.
+type AuthorizationNonce struct {
+	Token common.Address
+	Nonce [32]byte
+}
+
+// PreflightState is the on-chain state of an account fetched by a Preflight.
+type PreflightState struct {
+	// Account is the account the state was fetched for.
+	Account common.Address
+
+	// Balances are the account's balances per token. Tokens whose balanceOf
+	// call failed are missing.
+	Balances map[common.Address]*big.Int
+
+	// UsedNonces reports, per nonce requested, whether the authorization was
+	// used or canceled. Nonces whose authorizationState call failed are missing.
+	UsedNonces map[AuthorizationNonce]bool
+
+	// Time is when the state was fetched.
+	Time time.Time
+}
+
+// Preflight fetches token balances and EIP-3009 authorization states of an
+// account in a single Multicall3 RPC call, and caches balances briefly so that
+// frequent checks, such as Signer.CanSign with WithPreflight, do not each cost
+// an RPC call. It is safe for concurrent use and may be shared by signers of
+// the same network.
+type Preflight struct {
+	backend   CallBackend
+	tokens    []common.Address
+	multicall common.Address
+	ttl       time.Duration
+	clock     v2.Clock
+
+	mu    sync.Mutex
+	cache map[common.Address]*PreflightState
+}
+
+// PreflightOption configures a Preflight.
+type PreflightOption func(*Preflight)
+
+// WithPreflightTTL sets how long balances are reused (default: DefaultPreflightTTL).
+func WithPreflightTTL(ttl time.Duration) PreflightOption {
+	return func(p *Preflight) {
+		p.ttl = ttl
+	}
+}
+
+// WithMulticallAddress sets the Multicall3 contract address (default:
+// Multicall3Address).
+func WithMulticallAddress(address common.Address) PreflightOption {
+	return func(p *Preflight) {
+		p.multicall = address
+	}
+}
+
+// WithPreflightClock sets the clock used for caching (default: v2.SystemClock).
+func WithPreflightClock(clock v2.Clock) PreflightOption {
+	return func(p *Preflight) {
+		p.clock = clock
+	}
+}
+
+// NewPreflight creates a Preflight checking tokens through backend.
+func NewPreflight(backend CallBackend, tokens []v2.TokenConfig, opts ...PreflightOption) *Preflight {
+	p := &Preflight{
+		backend:   backend,
+		multicall: Multicall3Address,
+		ttl:       DefaultPreflightTTL,
+		cache:     make(map[common.Address]*PreflightState),
+	}
+	for _, token := range tokens {
+		p.tokens = append(p.tokens, common.HexToAddress(token.Address))
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Balances returns the balances of account, fetching them if the cached ones
+// are older than the TTL. Concurrent callers share one fetch.
+func (p *Preflight) Balances(ctx context.Context, account common.Address) (map[common.Address]*big.Int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if state, ok := p.cache[account]; ok && v2.ClockOrSystem(p.clock).Now().Sub(state.Time) < p.ttl {
+		return state.Balances, nil
+	}
+	state, err := p.fetch(ctx, account, nil)
+	if err != nil {
+		return nil, err
+	}
+	p.cache[account] = state
+	return state.Balances, nil
+}
+
+// Check fetches the balances of account and the states of nonces in one call,
+// bypassing and refreshing the balance cache.
+func (p *Preflight) Check(ctx context.Context, account common.Address, nonces ...AuthorizationNonce) (*PreflightState, error) {
+	state, err := p.fetch(ctx, account, nonces)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.cache[account] = state
+	p.mu.Unlock()
+	return state, nil
+}
+
+// fetch calls balanceOf of every token and authorizationState of every nonce
+// through Multicall3.
+func (p *Preflight) fetch(ctx context.Context, account common.Address, nonces []AuthorizationNonce) (*PreflightState, error) {
+	calls := make([]multicallCall, 0, len(p.tokens)+len(nonces))
+	for _, token := range p.tokens {
+		data := append(append([]byte(nil), selectorBalanceOf...), common.LeftPadBytes(account.Bytes(), 32)...)
+		calls = append(calls, multicallCall{Target: token, AllowFailure: true, CallData: data})
+	}
+	for _, nonce := range nonces {
+		data := append(append([]byte(nil), selectorAuthorizationState...), common.LeftPadBytes(account.Bytes(), 32)...)
+		data = append(data, nonce.Nonce[:]...)
+		calls = append(calls, multicallCall{Target: nonce.Token, AllowFailure: true, CallData: data})
+	}
+
+	input, err := multicallABI.Pack("aggregate3", calls)
+	if err != nil {
+		return nil, fmt.Errorf("encoding multicall: %w", err)
+	}
+	output, err := p.backend.CallContract(ctx, ethereum.CallMsg{To: &p.multicall, Data: input}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: multicall: %v", v2.ErrNetworkError, err)
+	}
+	unpacked, err := multicallABI.Unpack("aggregate3", output)
+	if err != nil || len(unpacked) != 1 {
+		return nil, fmt.Errorf("decoding multicall result: %v", err)
+	}
+	results := *abi.ConvertType(unpacked[0], new([]multicallResult)).(*[]multicallResult)
+	if len(results) != len(calls) {
+		return nil, fmt.Errorf("multicall returned %d results for %d calls", len(results), len(calls))
+	}
+
+	state := &PreflightState{
+		Account:    account,
+		Balances:   make(map[common.Address]*big.Int, len(p.tokens)),
+		UsedNonces: make(map[AuthorizationNonce]bool, len(nonces)),
+		Time:       v2.ClockOrSystem(p.clock).Now(),
+	}
+	for i, token := range p.tokens {
+		if result := results[i]; result.Success && len(result.ReturnData) == 32 {
+			state.Balances[token] = new(big.Int).SetBytes(result.ReturnData)
+		}
+	}
+	for i, nonce := range nonces {
+		if result := results[len(p.tokens)+i]; result.Success && len(result.ReturnData) == 32 {
+			state.UsedNonces[nonce] = new(big.Int).SetBytes(result.ReturnData).Sign() != 0
+		}
+	}
+	return state, nil
+}
+
+// WithPreflight makes CanSign refuse requirements whose amount exceeds the
+// signer's balance of the asset, as reported by preflight. If the balance
+// cannot be fetched, CanSign does not refuse and the facilitator decides.
+func WithPreflight(preflight *Preflight) Option {
+	return func(s *Signer) error {
+		s.preflight = preflight
+		return nil
+	}
+}
+
+// hasBalance reports whether owner may hold amount of token, according to the
+// signer's preflight if one is configured.
+func (s *Signer) hasBalance(owner, token common.Address, amount string) bool {
+	if s.preflight == nil {
+		return true
+	}
+	required, ok := new(big.Int).SetString(amount, 10)
+	if !ok {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), v2.DefaultTimeouts.VerifyTimeout)
+	defer cancel()
+	balances, err := s.preflight.Balances(ctx, owner)
+	if err != nil {
+		return true
+	}
+	balance, ok := balances[token]
+	return !ok || balance.Cmp(required) >= 0
+}
+
+var _ v2.BalanceReporter = (*Signer)(nil)
+
+// Balance returns the signer's balance of asset as reported by its preflight,
+// implementing v2.BalanceReporter. Balances are reused within the preflight's
+// TTL. Without WithPreflight, it fails with v2.ErrBalanceUnavailable.
+func (s *Signer) Balance(ctx context.Context, asset string) (*big.Int, error) {
+	if s.preflight == nil {
+		return nil, fmt.Errorf("%w: signer has no preflight configured", v2.ErrBalanceUnavailable)
+	}
+	balances, err := s.preflight.Balances(ctx, s.address)
+	if err != nil {
+		return nil, err
+	}
+	balance, ok := balances[common.HexToAddress(asset)]
+	if !ok {
+		return nil, fmt.Errorf("%w: balanceOf failed for %s", v2.ErrBalanceUnavailable, asset)
+	}
+	return new(big.Int).Set(balance), nil
+}
@@ -5,42 +5,71 @@ import (
 	"fmt"
 	"math/big"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 
 	v2 "github.com/mark3labs/x402-go/v2"
 	"github.com/mark3labs/x402-go/v2/internal/eip3009"
+	"github.com/mark3labs/x402-go/v2/internal/keymem"
 )
 
 type Signer struct {
-	privateKey *ecdsa.PrivateKey
+	key        *keymem.Key
 	address    common.Address
 	network    string
 	chainID    int64
 	tokens     []v2.TokenConfig
 	priority   int
 	maxAmount  *big.Int
+	domains    map[string]DomainOverride
+	strict     bool
+	backdate   time.Duration
+	maxTimeout time.Duration
+	clock      v2.Clock
+
+	nonceSource   eip3009.NonceSource
+	nonceRecorder NonceRecorder
+
+	permit2         bool
+	permit2Spenders []common.Address
+
+	treasury          *common.Address
+	allowanceRelayers []common.Address
+
+	preflight *Preflight
+
+	approvalTimeout time.Duration
 }
 
 type Option func(*Signer) error
 
+// NewSigner creates a signer from a hex-encoded private key. The key is held in
+// locked memory until Close is called.
 func NewSigner(network string, privateKeyHex string, tokens []v2.TokenConfig, opts ...Option) (*Signer, error) {
 	privateKeyHex = strings.TrimPrefix(privateKeyHex, "0x")
 	privateKey, err := crypto.HexToECDSA(privateKeyHex)
 	if err != nil {
 		return nil, v2.ErrInvalidKey
 	}
+	defer keymem.WipeECDSA(privateKey)
 
 	s := &Signer{
-		privateKey: privateKey,
-		network:    network,
-		tokens:     tokens,
-		priority:   0,
+		key:      keymem.FromECDSA(privateKey),
+		network:  network,
+		tokens:   tokens,
+		priority: 0,
+		domains:  make(map[string]DomainOverride),
+		backdate: eip3009.DefaultValidAfterBackdate,
+		clock:    v2.SystemClock,
+
+		nonceSource: eip3009.RandomNonceSource{},
 	}
 
 	for _, opt := range opts {
 		if err := opt(s); err != nil {
+			s.Close()
 			return nil, err
 		}
 	}
@@ -49,6 +78,7 @@ func NewSigner(network string, privateKeyHex string, tokens []v2.TokenConfig, op
 
 	chainID, err := GetChainID(network)
 	if err != nil {
+		s.Close()
 		return nil, err
 	}
 	s.chainID = chainID
@@ -56,16 +86,24 @@ func NewSigner(network string, privateKeyHex string, tokens []v2.TokenConfig, op
 	return s, nil
 }
 
+// NewSignerFromKey creates a signer from an existing private key. The signer
+// keeps its own copy in locked memory; callers should wipe key when done with it.
 func NewSignerFromKey(network string, key *ecdsa.PrivateKey, tokens []v2.TokenConfig, opts ...Option) (*Signer, error) {
 	s := &Signer{
-		privateKey: key,
-		network:    network,
-		tokens:     tokens,
-		priority:   0,
+		key:      keymem.FromECDSA(key),
+		network:  network,
+		tokens:   tokens,
+		priority: 0,
+		domains:  make(map[string]DomainOverride),
+		backdate: eip3009.DefaultValidAfterBackdate,
+		clock:    v2.SystemClock,
+
+		nonceSource: eip3009.RandomNonceSource{},
 	}
 
 	for _, opt := range opts {
 		if err := opt(s); err != nil {
+			s.Close()
 			return nil, err
 		}
 	}
@@ -74,6 +112,7 @@ func NewSignerFromKey(network string, key *ecdsa.PrivateKey, tokens []v2.TokenCo
 
 	chainID, err := GetChainID(network)
 	if err != nil {
+		s.Close()
 		return nil, err
 	}
 	s.chainID = chainID
@@ -81,6 +120,11 @@ func NewSignerFromKey(network string, key *ecdsa.PrivateKey, tokens []v2.TokenCo
 	return s, nil
 }
 
+// Close wipes the signer's private key. Later Sign calls fail with v2.ErrSignerClosed.
+func (s *Signer) Close() error {
+	return s.key.Close()
+}
+
 func WithPriority(priority int) Option {
 	return func(s *Signer) error {
 		s.priority = priority
@@ -95,6 +139,39 @@ func WithMaxAmount(amount *big.Int) Option {
 	}
 }
 
+// WithValidAfterBackdate sets how far validAfter is backdated from the local clock
+// (default: 10s). Increase it when a facilitator's clock runs behind, or set it to
+// zero for facilitators that reject backdated authorizations.
+func WithValidAfterBackdate(backdate time.Duration) Option {
+	return func(s *Signer) error {
+		if backdate < 0 {
+			return fmt.Errorf("validAfter backdate must not be negative, got %v", backdate)
+		}
+		s.backdate = backdate
+		return nil
+	}
+}
+
+// WithMaxTimeout clamps the authorization lifetime (validBefore - now) to d,
+// regardless of the MaxTimeoutSeconds requested by the server.
+func WithMaxTimeout(d time.Duration) Option {
+	return func(s *Signer) error {
+		if d <= 0 {
+			return fmt.Errorf("max timeout must be positive, got %v", d)
+		}
+		s.maxTimeout = d
+		return nil
+	}
+}
+
+// WithClock sets the clock used for authorization windows (default: v2.SystemClock).
+func WithClock(clock v2.Clock) Option {
+	return func(s *Signer) error {
+		s.clock = v2.ClockOrSystem(clock)
+		return nil
+	}
+}
+
 func (s *Signer) Network() string {
 	return s.network
 }
@@ -108,13 +185,33 @@ func (s *Signer) CanSign(requirements *v2.PaymentRequirements) bool {
 		return false
 	}
 
+	return s.canSignAsset(requirements)
+}
+
+// canSignAsset checks the network, transfer method, token, and, with a
+// preflight, the balance for the requirements.
+func (s *Signer) canSignAsset(requirements *v2.PaymentRequirements) bool {
 	if requirements.Network != s.network {
 		return false
 	}
 
+	switch assetTransferMethod(requirements) {
+	case v2.AssetTransferMethodEIP3009:
+	case v2.AssetTransferMethodPermit2:
+		if !s.permit2 {
+			return false
+		}
+	case v2.AssetTransferMethodAllowance:
+		if s.treasury == nil {
+			return false
+		}
+	default:
+		return false
+	}
+
 	for _, token := range s.tokens {
 		if strings.EqualFold(token.Address, requirements.Asset) {
-			return true
+			return s.hasBalance(s.payer(requirements), common.HexToAddress(token.Address), requirements.Amount)
 		}
 	}
 
@@ -126,13 +223,20 @@ func (s *Signer) Sign(requirements *v2.PaymentRequirements) (*v2.PaymentPayload,
 		return nil, v2.ErrNoValidSigner
 	}
 
+	payload, _, err := s.sign(requirements, time.Duration(requirements.MaxTimeoutSeconds)*time.Second)
+	return payload, err
+}
+
+// sign creates a payload authorizing payment for timeout from now. The returned
+// authorization is nil for Permit2 and allowance payments.
+func (s *Signer) sign(requirements *v2.PaymentRequirements, timeout time.Duration) (*v2.PaymentPayload, *eip3009.Authorization, error) {
 	amount, ok := new(big.Int).SetString(requirements.Amount, 10)
 	if !ok {
-		return nil, v2.ErrInvalidAmount
+		return nil, nil, v2.ErrInvalidAmount
 	}
 
 	if s.maxAmount != nil && amount.Cmp(s.maxAmount) > 0 {
-		return nil, v2.ErrAmountExceeded
+		return nil, nil, v2.ErrAmountExceeded
 	}
 
 	var tokenAddress common.Address
@@ -143,27 +247,58 @@ func (s *Signer) Sign(requirements *v2.PaymentRequirements) (*v2.PaymentPayload,
 		}
 	}
 
-	name, version, err := extractEIP3009Params(requirements)
+	if s.maxTimeout > 0 && timeout > s.maxTimeout {
+		timeout = s.maxTimeout
+	}
+
+	now := s.clock.Now()
+	if isPermit2(requirements) {
+		payload, err := s.signPermit2(requirements, tokenAddress, amount, now.Add(-s.backdate), now.Add(timeout))
+		return payload, nil, err
+	}
+	if isAllowance(requirements) {
+		payload, err := s.signAllowance(requirements, tokenAddress, amount, now.Add(-s.backdate), now.Add(timeout))
+		return payload, nil, err
+	}
+
+	domain, err := s.resolveDomain(requirements, tokenAddress)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	auth, err := eip3009.CreateAuthorization(
-		s.address,
-		common.HexToAddress(requirements.PayTo),
-		amount,
-		requirements.MaxTimeoutSeconds,
-	)
+	nonceParams := eip3009.NonceParams{
+		From:    s.address,
+		To:      common.HexToAddress(requirements.PayTo),
+		Value:   amount,
+		Token:   tokenAddress,
+		ChainID: big.NewInt(s.chainID),
+	}
+	if key, ok := requirements.Extra["idempotencyKey"].(string); ok {
+		nonceParams.Key = key
+	}
+
+	auth, err := eip3009.CreateAuthorizationFromSource(s.nonceSource, nonceParams, now.Add(-s.backdate), now.Add(timeout))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	signature, err := eip3009.SignAuthorization(s.privateKey, tokenAddress, big.NewInt(s.chainID), auth, name, version)
+	if err := s.recordNonce(tokenAddress, auth); err != nil {
+		return nil, nil, err
+	}
+
+	signature, err := keymem.WithECDSA(s.key, func(key *ecdsa.PrivateKey) (string, error) {
+		return eip3009.SignAuthorizationWithDomain(key, domain, auth)
+	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	payload := &v2.PaymentPayload{
+	return exactPayload(requirements, auth, signature), auth, nil
+}
+
+// exactPayload returns the payload presenting the signed EIP-3009 auth.
+func exactPayload(requirements *v2.PaymentRequirements, auth *eip3009.Authorization, signature string) *v2.PaymentPayload {
+	return &v2.PaymentPayload{
 		X402Version: 2,
 		Accepted:    *requirements,
 		Payload: v2.EVMPayload{
@@ -178,8 +313,6 @@ func (s *Signer) Sign(requirements *v2.PaymentRequirements) (*v2.PaymentPayload,
 			},
 		},
 	}
-
-	return payload, nil
 }
 
 func (s *Signer) GetPriority() int {
@@ -198,6 +331,15 @@ func (s *Signer) Address() common.Address {
 	return s.address
 }
 
+// payer returns the address requirements are paid from: the treasury for
+// allowance payments, and the signer's own address otherwise.
+func (s *Signer) payer(requirements *v2.PaymentRequirements) common.Address {
+	if s.treasury != nil && isAllowance(requirements) {
+		return *s.treasury
+	}
+	return s.address
+}
+
 func GetChainID(network string) (int64, error) {
 	switch network {
 	case "eip155:8453":
@@ -1,10 +1,35 @@
 package evm
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 
 	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/storage"
 )
 
 // testPrivateKey is the Foundry/Anvil first default account private key.
@@ -157,6 +182,39 @@ func TestSign(t *testing.T) {
 	}
 }
 
+func TestSignerClose(t *testing.T) {
+	tokens := []v2.TokenConfig{
+		{Address: "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913", Symbol: "USDC", Decimals: 6},
+	}
+	signer, err := NewSigner("eip155:84532", testPrivateKey, tokens)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	// Formatting the signer must not reveal the key
+	for _, format := range []string{"%v", "%+v", "%#v"} {
+		if strings.Contains(fmt.Sprintf(format, signer), testPrivateKey[:16]) {
+			t.Errorf("%s formatting leaked the private key", format)
+		}
+	}
+
+	if err := signer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	requirements := &v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Asset:             "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		Amount:            "1000",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+		Extra:             map[string]interface{}{"name": "USD Coin", "version": "2"},
+	}
+	if _, err := signer.Sign(requirements); !errors.Is(err, v2.ErrSignerClosed) {
+		t.Errorf("expected ErrSignerClosed after Close, got %v", err)
+	}
+}
+
 func TestSignAmountExceeded(t *testing.T) {
 	network := "eip155:84532"
 	tokens := []v2.TokenConfig{
@@ -222,3 +280,1201 @@ func TestGetChainID(t *testing.T) {
 		})
 	}
 }
+
+func TestSignDomainValidation(t *testing.T) {
+	usdc := v2.BaseSepolia.USDCAddress
+	custom := "0x1111111111111111111111111111111111111111"
+	tokens := []v2.TokenConfig{
+		{Address: usdc, Symbol: "USDC", Decimals: 6},
+		{Address: custom, Symbol: "TKN", Decimals: 18},
+	}
+
+	newRequirements := func(asset string, extra map[string]interface{}) *v2.PaymentRequirements {
+		return &v2.PaymentRequirements{
+			Scheme:            "exact",
+			Network:           v2.NetworkBaseSepolia,
+			Asset:             asset,
+			Amount:            "1000",
+			PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+			MaxTimeoutSeconds: 300,
+			Extra:             extra,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		opts         []Option
+		requirements *v2.PaymentRequirements
+		wantErr      error
+	}{
+		{
+			name:         "built-in domain matches",
+			requirements: newRequirements(usdc, map[string]interface{}{"name": v2.BaseSepolia.EIP3009Name, "version": v2.BaseSepolia.EIP3009Version}),
+		},
+		{
+			name:         "built-in domain without extra",
+			requirements: newRequirements(usdc, nil),
+		},
+		{
+			name:         "built-in domain with spoofed name",
+			requirements: newRequirements(usdc, map[string]interface{}{"name": "Evil Coin", "version": "2"}),
+			wantErr:      v2.ErrUntrustedDomain,
+		},
+		{
+			name:         "spoofed verifying contract",
+			requirements: newRequirements(usdc, map[string]interface{}{"verifyingContract": custom}),
+			wantErr:      v2.ErrUntrustedDomain,
+		},
+		{
+			name:         "unknown token trusts extra by default",
+			requirements: newRequirements(custom, map[string]interface{}{"name": "Token", "version": "1"}),
+		},
+		{
+			name:         "unknown token rejected in strict mode",
+			opts:         []Option{WithStrictDomain()},
+			requirements: newRequirements(custom, map[string]interface{}{"name": "Token", "version": "1"}),
+			wantErr:      v2.ErrUntrustedDomain,
+		},
+		{
+			name:         "override with invalid salt",
+			opts:         []Option{WithStrictDomain(), WithDomainOverride(custom, DomainOverride{Name: "Token", Version: "1", Salt: "0x01"})},
+			requirements: newRequirements(custom, nil),
+			wantErr:      v2.ErrInvalidToken,
+		},
+		{
+			name: "override matches extra",
+			opts: []Option{WithStrictDomain(), WithDomainOverride(custom, DomainOverride{
+				Name:    "Token",
+				Version: "1",
+				Salt:    "0x000000000000000000000000000000000000000000000000000000000000abcd",
+			})},
+			requirements: newRequirements(custom, map[string]interface{}{
+				"name": "Token",
+				"salt": "0x000000000000000000000000000000000000000000000000000000000000abcd",
+			}),
+		},
+		{
+			name:         "override rejects different version",
+			opts:         []Option{WithDomainOverride(custom, DomainOverride{Name: "Token", Version: "1"})},
+			requirements: newRequirements(custom, map[string]interface{}{"name": "Token", "version": "2"}),
+			wantErr:      v2.ErrUntrustedDomain,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, tokens, tt.opts...)
+			if err != nil {
+				if tt.wantErr != nil && errors.Is(err, tt.wantErr) {
+					return
+				}
+				t.Fatalf("Failed to create signer: %v", err)
+			}
+
+			_, err = signer.Sign(tt.requirements)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSignAuthorizationWindow(t *testing.T) {
+	tokens := []v2.TokenConfig{{Address: v2.BaseSepolia.USDCAddress, Symbol: "USDC", Decimals: 6}}
+	requirements := &v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           v2.NetworkBaseSepolia,
+		Asset:             v2.BaseSepolia.USDCAddress,
+		Amount:            "1000",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 3600,
+	}
+
+	tests := []struct {
+		name            string
+		opts            []Option
+		wantBackdate    int64
+		wantMaxLifetime int64
+	}{
+		{name: "defaults", wantBackdate: 10, wantMaxLifetime: 3600},
+		{name: "custom backdate", opts: []Option{WithValidAfterBackdate(2 * time.Minute)}, wantBackdate: 120, wantMaxLifetime: 3600},
+		{name: "no backdate", opts: []Option{WithValidAfterBackdate(0)}, wantBackdate: 0, wantMaxLifetime: 3600},
+		{name: "clamped timeout", opts: []Option{WithMaxTimeout(5 * time.Minute)}, wantBackdate: 10, wantMaxLifetime: 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, tokens, tt.opts...)
+			if err != nil {
+				t.Fatalf("Failed to create signer: %v", err)
+			}
+
+			before := time.Now().Unix()
+			payload, err := signer.Sign(requirements)
+			if err != nil {
+				t.Fatalf("Failed to sign: %v", err)
+			}
+			after := time.Now().Unix()
+
+			auth := payload.Payload.(v2.EVMPayload).Authorization
+			validAfter, _ := strconv.ParseInt(auth.ValidAfter, 10, 64)
+			validBefore, _ := strconv.ParseInt(auth.ValidBefore, 10, 64)
+
+			if validAfter < before-tt.wantBackdate || validAfter > after-tt.wantBackdate {
+				t.Errorf("Expected validAfter backdated by %ds, got %d (now %d)", tt.wantBackdate, validAfter, before)
+			}
+			if validBefore < before+tt.wantMaxLifetime || validBefore > after+tt.wantMaxLifetime {
+				t.Errorf("Expected validBefore %ds from now, got %d (now %d)", tt.wantMaxLifetime, validBefore, before)
+			}
+		})
+	}
+
+	if _, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, tokens, WithValidAfterBackdate(-time.Second)); err == nil {
+		t.Error("Expected error for negative backdate")
+	}
+	if _, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, tokens, WithMaxTimeout(0)); err == nil {
+		t.Error("Expected error for zero max timeout")
+	}
+}
+
+func TestSignNonceSourceAndRecorder(t *testing.T) {
+	tokens := []v2.TokenConfig{{Address: v2.BaseSepolia.USDCAddress, Symbol: "USDC", Decimals: 6}}
+	requirements := &v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           v2.NetworkBaseSepolia,
+		Asset:             v2.BaseSepolia.USDCAddress,
+		Amount:            "1000",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+		Extra:             map[string]interface{}{"idempotencyKey": "order-42"},
+	}
+
+	source, err := NewHMACNonceSource([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("Failed to create nonce source: %v", err)
+	}
+
+	var records []NonceRecord
+	signer, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, tokens,
+		WithNonceSource(source),
+		WithNonceRecorder(NonceRecorderFunc(func(record NonceRecord) error {
+			records = append(records, record)
+			return nil
+		})),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	first, err := signer.Sign(requirements)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	second, err := signer.Sign(requirements)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	firstNonce := first.Payload.(v2.EVMPayload).Authorization.Nonce
+	if firstNonce != second.Payload.(v2.EVMPayload).Authorization.Nonce {
+		t.Error("Expected retries to reuse the same nonce")
+	}
+	// The same purchase made twice without an idempotency key is two payments
+	delete(requirements.Extra, "idempotencyKey")
+	third, err := signer.Sign(requirements)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	fourth, err := signer.Sign(requirements)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if third.Payload.(v2.EVMPayload).Authorization.Nonce == fourth.Payload.(v2.EVMPayload).Authorization.Nonce {
+		t.Error("Expected back-to-back purchases without an idempotency key to use distinct nonces")
+	}
+	if len(records) != 4 || records[0].Nonce != firstNonce || records[0].Value != "1000" || records[0].Network != v2.NetworkBaseSepolia {
+		t.Errorf("Unexpected nonce records: %+v", records)
+	}
+
+	failing, _ := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, tokens,
+		WithNonceRecorder(NonceRecorderFunc(func(NonceRecord) error { return errors.New("store down") })))
+	if _, err := failing.Sign(requirements); err == nil {
+		t.Error("Expected signing to fail when the nonce cannot be recorded")
+	}
+}
+
+func TestSignPermit2(t *testing.T) {
+	token := "0x1111111111111111111111111111111111111111"
+	spender := "0x3333333333333333333333333333333333333333"
+	tokens := []v2.TokenConfig{{Address: token, Symbol: "TKN", Decimals: 18}}
+
+	newRequirements := func(spender string) *v2.PaymentRequirements {
+		return &v2.PaymentRequirements{
+			Scheme:            "exact",
+			Network:           v2.NetworkBaseSepolia,
+			Asset:             token,
+			Amount:            "1000",
+			PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+			MaxTimeoutSeconds: 300,
+			Extra: map[string]interface{}{
+				"assetTransferMethod": v2.AssetTransferMethodPermit2,
+				"spender":             spender,
+			},
+		}
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		signer, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, tokens)
+		if err != nil {
+			t.Fatalf("Failed to create signer: %v", err)
+		}
+		if signer.CanSign(newRequirements(spender)) {
+			t.Error("expected Permit2 requirements to be rejected without WithPermit2")
+		}
+	})
+
+	t.Run("signs permit", func(t *testing.T) {
+		signer, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, tokens, WithPermit2())
+		if err != nil {
+			t.Fatalf("Failed to create signer: %v", err)
+		}
+		payload, err := signer.Sign(newRequirements(spender))
+		if err != nil {
+			t.Fatalf("Sign failed: %v", err)
+		}
+
+		permit, ok := payload.Payload.(v2.Permit2Payload)
+		if !ok {
+			t.Fatalf("expected Permit2Payload, got %T", payload.Payload)
+		}
+		auth := permit.Permit2Authorization
+		if auth.From != testAddress || auth.Permitted.Amount != "1000" || auth.Witness.To != "0x209693Bc6afc0C5328bA36FaF03C514EF312287C" {
+			t.Errorf("unexpected authorization %+v", auth)
+		}
+		if auth.Spender != spender {
+			t.Errorf("expected spender %s, got %s", spender, auth.Spender)
+		}
+	})
+
+	t.Run("untrusted spender", func(t *testing.T) {
+		signer, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, tokens, WithPermit2(spender))
+		if err != nil {
+			t.Fatalf("Failed to create signer: %v", err)
+		}
+		_, err = signer.Sign(newRequirements("0x4444444444444444444444444444444444444444"))
+		if !errors.Is(err, v2.ErrUntrustedSpender) {
+			t.Errorf("expected ErrUntrustedSpender, got %v", err)
+		}
+	})
+
+	t.Run("unknown transfer method", func(t *testing.T) {
+		signer, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, tokens, WithPermit2())
+		if err != nil {
+			t.Fatalf("Failed to create signer: %v", err)
+		}
+		requirements := newRequirements(spender)
+		requirements.Extra["assetTransferMethod"] = "erc7597"
+		if signer.CanSign(requirements) {
+			t.Error("expected unknown transfer method to be rejected")
+		}
+	})
+}
+
+func TestSignAllowance(t *testing.T) {
+	token := "0x1111111111111111111111111111111111111111"
+	relayer := "0x3333333333333333333333333333333333333333"
+	treasury := "0x5555555555555555555555555555555555555555"
+	tokens := []v2.TokenConfig{{Address: token, Symbol: "TKN", Decimals: 18}}
+
+	newRequirements := func(spender string) *v2.PaymentRequirements {
+		return &v2.PaymentRequirements{
+			Scheme:            "exact",
+			Network:           v2.NetworkBaseSepolia,
+			Asset:             token,
+			Amount:            "1000",
+			PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+			MaxTimeoutSeconds: 300,
+			Extra: map[string]interface{}{
+				"assetTransferMethod": v2.AssetTransferMethodAllowance,
+				"spender":             spender,
+			},
+		}
+	}
+
+	t.Run("disabled without treasury", func(t *testing.T) {
+		signer, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, tokens)
+		if err != nil {
+			t.Fatalf("Failed to create signer: %v", err)
+		}
+		if signer.CanSign(newRequirements(relayer)) {
+			t.Error("expected allowance requirements to be rejected without WithTreasury")
+		}
+	})
+
+	t.Run("signs relay order", func(t *testing.T) {
+		signer, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, tokens, WithTreasury(treasury))
+		if err != nil {
+			t.Fatalf("Failed to create signer: %v", err)
+		}
+		payload, err := signer.Sign(newRequirements(relayer))
+		if err != nil {
+			t.Fatalf("Sign failed: %v", err)
+		}
+
+		allowance, ok := payload.Payload.(v2.AllowancePayload)
+		if !ok {
+			t.Fatalf("expected AllowancePayload, got %T", payload.Payload)
+		}
+		auth := allowance.AllowanceAuthorization
+		if auth.Owner != treasury || auth.Signer != testAddress || auth.Spender != relayer {
+			t.Errorf("unexpected parties %+v", auth)
+		}
+		if auth.Value != "1000" || auth.To != "0x209693Bc6afc0C5328bA36FaF03C514EF312287C" {
+			t.Errorf("unexpected transfer %+v", auth)
+		}
+	})
+
+	t.Run("untrusted relayer", func(t *testing.T) {
+		signer, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, tokens, WithTreasury(treasury, relayer))
+		if err != nil {
+			t.Fatalf("Failed to create signer: %v", err)
+		}
+		_, err = signer.Sign(newRequirements("0x4444444444444444444444444444444444444444"))
+		if !errors.Is(err, v2.ErrUntrustedSpender) {
+			t.Errorf("expected ErrUntrustedSpender, got %v", err)
+		}
+	})
+
+	t.Run("invalid treasury", func(t *testing.T) {
+		if _, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, tokens, WithTreasury("treasury")); err == nil {
+			t.Error("expected invalid treasury address to be rejected")
+		}
+	})
+
+	t.Run("deferred scheme unsupported", func(t *testing.T) {
+		signer, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, tokens, WithTreasury(treasury))
+		if err != nil {
+			t.Fatalf("Failed to create signer: %v", err)
+		}
+		requirements := newRequirements(relayer)
+		requirements.Scheme = v2.SchemeDeferred
+		if NewDeferredSigner(signer).CanSign(requirements) {
+			t.Error("expected deferred allowance requirements to be rejected")
+		}
+	})
+}
+
+func TestDeferredSigner(t *testing.T) {
+	usdc := v2.BaseSepolia.USDCAddress
+	clock := v2.NewFakeClock(time.Now())
+	base, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, []v2.TokenConfig{{Address: usdc, Symbol: "USDC", Decimals: 6}}, WithClock(clock))
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	signer := NewDeferredSigner(base)
+
+	requirements := &v2.PaymentRequirements{
+		Scheme:            v2.SchemeDeferred,
+		Network:           v2.NetworkBaseSepolia,
+		Asset:             usdc,
+		Amount:            "1000",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+		Extra:             map[string]interface{}{"claimWindowSeconds": float64(3600)},
+	}
+
+	exact := *requirements
+	exact.Scheme = "exact"
+	if signer.CanSign(&exact) {
+		t.Error("Expected deferred signer to reject exact scheme")
+	}
+	if base.CanSign(requirements) {
+		t.Error("Expected exact signer to reject deferred scheme")
+	}
+
+	payload, err := signer.Sign(requirements)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	auth := payload.Payload.(v2.EVMPayload).Authorization
+	validBefore, _ := strconv.ParseInt(auth.ValidBefore, 10, 64)
+	if window := validBefore - time.Now().Unix(); window < 3590 || window > 3600 {
+		t.Errorf("Expected claim window of about 3600s, got %ds", window)
+	}
+
+	outstanding := signer.Outstanding()
+	if len(outstanding) != 1 || outstanding[0].Nonce != auth.Nonce {
+		t.Fatalf("Expected one outstanding authorization, got %+v", outstanding)
+	}
+	if got := signer.OutstandingAmount(usdc); got.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("Expected outstanding amount 1000, got %s", got)
+	}
+
+	cancellation, err := signer.Cancel(auth.Nonce)
+	if err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if cancellation.Authorizer != testAddress || cancellation.Nonce != auth.Nonce || len(cancellation.Signature) != 132 {
+		t.Errorf("Unexpected cancellation %+v", cancellation)
+	}
+	if len(signer.Outstanding()) != 0 {
+		t.Error("Expected cancelled authorization to be removed")
+	}
+	if _, err := signer.Cancel(auth.Nonce); !errors.Is(err, v2.ErrUnknownAuthorization) {
+		t.Errorf("Expected ErrUnknownAuthorization, got %v", err)
+	}
+
+	// Settled and expired authorizations are no longer outstanding
+	payload, err = signer.Sign(requirements)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	signer.MarkSettled(payload.Payload.(v2.EVMPayload).Authorization.Nonce)
+	if _, err := signer.Sign(requirements); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	clock.Advance(2 * time.Hour)
+	if len(signer.Outstanding()) != 0 {
+		t.Error("Expected settled and expired authorizations to be removed")
+	}
+}
+
+func TestUpToSigner(t *testing.T) {
+	token := "0x1111111111111111111111111111111111111111"
+	base, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, []v2.TokenConfig{{Address: token, Symbol: "TKN", Decimals: 18}}, WithPermit2())
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	signer := NewUpToSigner(base)
+
+	requirements := &v2.PaymentRequirements{
+		Scheme:            v2.SchemeUpTo,
+		Network:           v2.NetworkBaseSepolia,
+		Asset:             token,
+		Amount:            "5000",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 300,
+		Extra: map[string]interface{}{
+			"assetTransferMethod": v2.AssetTransferMethodPermit2,
+			"spender":             "0x3333333333333333333333333333333333333333",
+		},
+	}
+	if base.CanSign(requirements) {
+		t.Error("Expected exact signer to reject upto scheme")
+	}
+
+	eip3009 := *requirements
+	eip3009.Extra = nil
+	if signer.CanSign(&eip3009) {
+		t.Error("Expected upto signer to require Permit2")
+	}
+
+	payload, err := signer.Sign(requirements)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	permit := payload.Payload.(v2.Permit2Payload)
+	if permit.Permit2Authorization.Permitted.Amount != "5000" || payload.Accepted.Scheme != v2.SchemeUpTo {
+		t.Errorf("Expected permit for the maximum amount, got %+v", permit.Permit2Authorization)
+	}
+}
+
+func BenchmarkSigner_Sign(b *testing.B) {
+	tokens := []v2.TokenConfig{
+		{Address: "0x036CbD53842c5426634e7929541eC2318f3dCF7e", Symbol: "USDC", Decimals: 6},
+	}
+	signer, err := NewSigner("eip155:84532", testPrivateKey, tokens)
+	if err != nil {
+		b.Fatalf("Failed to create signer: %v", err)
+	}
+	requirements := &v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+		Extra:             map[string]interface{}{"name": "USDC", "version": "2"},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := signer.Sign(requirements); err != nil {
+			b.Fatalf("Sign failed: %v", err)
+		}
+	}
+}
+
+// directBackend is a DirectBackend recording the submitted transaction.
+type directBackend struct {
+	sent *types.Transaction
+}
+
+func (b *directBackend) PendingNonceAt(context.Context, common.Address) (uint64, error) {
+	return 7, nil
+}
+
+func (b *directBackend) SuggestGasTipCap(context.Context) (*big.Int, error) {
+	return big.NewInt(1_000_000), nil
+}
+
+func (b *directBackend) HeaderByNumber(context.Context, *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(100), BaseFee: big.NewInt(5_000_000)}, nil
+}
+
+func (b *directBackend) EstimateGas(context.Context, ethereum.CallMsg) (uint64, error) {
+	return 50_000, nil
+}
+
+func (b *directBackend) SendTransaction(_ context.Context, tx *types.Transaction) error {
+	b.sent = tx
+	return nil
+}
+
+func TestDirectSigner(t *testing.T) {
+	token := "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
+	payTo := "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+	signer, err := NewSigner("eip155:84532", testPrivateKey, []v2.TokenConfig{{Address: token, Symbol: "USDC", Decimals: 6}}, WithMaxAmount(big.NewInt(1000)))
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	backend := &directBackend{}
+	direct := NewDirectSigner(signer, backend)
+
+	requirements := &v2.PaymentRequirements{Scheme: v2.SchemeDirect, Network: "eip155:84532", Asset: token, PayTo: payTo, Amount: "500"}
+	if !direct.CanSign(requirements) {
+		t.Fatal("expected direct signer to sign direct requirements")
+	}
+	exact := *requirements
+	exact.Scheme = v2.SchemeExact
+	if direct.CanSign(&exact) {
+		t.Error("expected direct signer to refuse exact requirements")
+	}
+
+	payment, err := direct.Sign(requirements)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	tx := backend.sent
+	if tx == nil {
+		t.Fatal("expected a transaction to be submitted")
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(big.NewInt(84532)), tx)
+	if err != nil || from.Hex() != testAddress {
+		t.Errorf("transaction sender = %s, %v", from.Hex(), err)
+	}
+	if tx.Nonce() != 7 || tx.Gas() != 50_000 || tx.GasFeeCap().Int64() != 11_000_000 {
+		t.Errorf("unexpected transaction parameters: nonce %d, gas %d, fee cap %s", tx.Nonce(), tx.Gas(), tx.GasFeeCap())
+	}
+	if !strings.EqualFold(tx.To().Hex(), token) {
+		t.Errorf("transaction to = %s, want token %s", tx.To().Hex(), token)
+	}
+	data := tx.Data()
+	if len(data) != 68 || common.BytesToAddress(data[4:36]).Hex() != payTo || new(big.Int).SetBytes(data[36:]).Int64() != 500 {
+		t.Errorf("unexpected transfer calldata %x", data)
+	}
+
+	payload, ok := payment.Payload.(v2.DirectPayload)
+	if !ok || payload.Transaction != tx.Hash().Hex() {
+		t.Fatalf("unexpected payload %+v", payment.Payload)
+	}
+	signature := common.FromHex(payload.Signature)
+	signature[crypto.RecoveryIDOffset] -= 27
+	key, err := crypto.SigToPub(accounts.TextHash(tx.Hash().Bytes()), signature)
+	if err != nil || crypto.PubkeyToAddress(*key).Hex() != testAddress {
+		t.Errorf("payload signature does not recover to the payer: %v", err)
+	}
+
+	requirements.Amount = "5000"
+	if _, err := direct.Sign(requirements); !errors.Is(err, v2.ErrAmountExceeded) {
+		t.Errorf("expected ErrAmountExceeded, got %v", err)
+	}
+}
+
+// multicallBackend is a CallBackend answering Multicall3 aggregate3 calls from
+// fixed balances and used nonces.
+type multicallBackend struct {
+	balances map[common.Address]*big.Int
+	used     map[[32]byte]bool
+	calls    int
+	err      error
+}
+
+func (b *multicallBackend) CallContract(_ context.Context, call ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	b.calls++
+	if b.err != nil {
+		return nil, b.err
+	}
+	if *call.To != Multicall3Address {
+		return nil, fmt.Errorf("unexpected call to %s", call.To)
+	}
+	method := multicallABI.Methods["aggregate3"]
+	args, err := method.Inputs.Unpack(call.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	var calls []multicallCall
+	if err := method.Inputs.Copy(&calls, args); err != nil {
+		return nil, err
+	}
+
+	results := make([]multicallResult, len(calls))
+	for i, c := range calls {
+		switch {
+		case string(c.CallData[:4]) == string(selectorBalanceOf):
+			if balance, ok := b.balances[c.Target]; ok {
+				results[i] = multicallResult{Success: true, ReturnData: common.LeftPadBytes(balance.Bytes(), 32)}
+			}
+		case string(c.CallData[:4]) == string(selectorAuthorizationState):
+			var nonce [32]byte
+			copy(nonce[:], c.CallData[36:68])
+			state := big.NewInt(0)
+			if b.used[nonce] {
+				state.SetInt64(1)
+			}
+			results[i] = multicallResult{Success: true, ReturnData: common.LeftPadBytes(state.Bytes(), 32)}
+		}
+	}
+	return method.Outputs.Pack(results)
+}
+
+func TestPreflight(t *testing.T) {
+	usdc := common.HexToAddress("0x036CbD53842c5426634e7929541eC2318f3dCF7e")
+	eurc := common.HexToAddress("0x808456652fdb597867f38412077A9182bf77359F")
+	tokens := []v2.TokenConfig{
+		{Address: usdc.Hex(), Symbol: "USDC", Decimals: 6},
+		{Address: eurc.Hex(), Symbol: "EURC", Decimals: 6},
+	}
+	used := [32]byte{1}
+	backend := &multicallBackend{
+		balances: map[common.Address]*big.Int{usdc: big.NewInt(5000)},
+		used:     map[[32]byte]bool{used: true},
+	}
+	clock := v2.NewFakeClock(time.Unix(1700000000, 0))
+	preflight := NewPreflight(backend, tokens, WithPreflightTTL(time.Minute), WithPreflightClock(clock))
+
+	state, err := preflight.Check(context.Background(), common.HexToAddress(testAddress),
+		AuthorizationNonce{Token: usdc, Nonce: used}, AuthorizationNonce{Token: usdc, Nonce: [32]byte{2}})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if backend.calls != 1 {
+		t.Errorf("expected one RPC call, got %d", backend.calls)
+	}
+	if state.Balances[usdc].Int64() != 5000 {
+		t.Errorf("USDC balance = %v, want 5000", state.Balances[usdc])
+	}
+	if _, ok := state.Balances[eurc]; ok {
+		t.Error("expected failed balanceOf call to be missing")
+	}
+	if !state.UsedNonces[AuthorizationNonce{Token: usdc, Nonce: used}] || state.UsedNonces[AuthorizationNonce{Token: usdc, Nonce: [32]byte{2}}] {
+		t.Errorf("unexpected nonce states %v", state.UsedNonces)
+	}
+
+	signer, err := NewSigner("eip155:84532", testPrivateKey, tokens, WithPreflight(preflight))
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	requirements := func(asset common.Address, amount string) *v2.PaymentRequirements {
+		return &v2.PaymentRequirements{Scheme: "exact", Network: "eip155:84532", Asset: asset.Hex(), Amount: amount, PayTo: testAddress}
+	}
+
+	// Balances fetched by Check are reused within the TTL
+	if !signer.CanSign(requirements(usdc, "5000")) {
+		t.Error("expected CanSign within the balance")
+	}
+	if signer.CanSign(requirements(usdc, "5001")) {
+		t.Error("expected CanSign to refuse an amount above the balance")
+	}
+	if !signer.CanSign(requirements(eurc, "1")) {
+		t.Error("expected CanSign not to refuse a token with unknown balance")
+	}
+	if backend.calls != 1 {
+		t.Errorf("expected cached balances to be reused, got %d RPC calls", backend.calls)
+	}
+
+	// Stale balances are refreshed
+	backend.balances[usdc] = big.NewInt(6000)
+	clock.Advance(time.Minute)
+	if !signer.CanSign(requirements(usdc, "5001")) || backend.calls != 2 {
+		t.Errorf("expected refreshed balance to be used, got %d RPC calls", backend.calls)
+	}
+
+	// The signer reports balances to a v2.BalanceMonitor
+	if balance, err := signer.Balance(context.Background(), strings.ToLower(usdc.Hex())); err != nil || balance.Int64() != 6000 {
+		t.Errorf("Balance() = %v, %v; want 6000", balance, err)
+	}
+	if _, err := signer.Balance(context.Background(), eurc.Hex()); !errors.Is(err, v2.ErrBalanceUnavailable) {
+		t.Errorf("expected ErrBalanceUnavailable for a failed balanceOf, got %v", err)
+	}
+
+	// RPC failures do not make CanSign refuse
+	backend.err = errors.New("connection refused")
+	clock.Advance(time.Minute)
+	if !signer.CanSign(requirements(usdc, "100000")) {
+		t.Error("expected CanSign not to refuse when balances cannot be fetched")
+	}
+
+	plain, err := NewSigner("eip155:84532", testPrivateKey, tokens)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	if _, err := plain.Balance(context.Background(), usdc.Hex()); !errors.Is(err, v2.ErrBalanceUnavailable) {
+		t.Errorf("expected ErrBalanceUnavailable without a preflight, got %v", err)
+	}
+}
+
+// keyWallet is a TypedDataSigner signing with key, as a browser wallet would.
+type keyWallet struct {
+	key *ecdsa.PrivateKey
+
+	// recoveryOffset is added to the recovery id: 27 for most wallets, 0 for
+	// those returning 0 or 1
+	recoveryOffset byte
+	err            error
+}
+
+func (w keyWallet) SignTypedData(ctx context.Context, account common.Address, data apitypes.TypedData) (string, error) {
+	if w.err != nil {
+		return "", w.err
+	}
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return "", err
+	}
+	signature, err := crypto.Sign(hash, w.key)
+	if err != nil {
+		return "", err
+	}
+	signature[64] += w.recoveryOffset
+	return hexutil.Encode(signature), nil
+}
+
+func TestWalletSigner(t *testing.T) {
+	key, _ := crypto.HexToECDSA(testPrivateKey)
+	otherKey, _ := crypto.GenerateKey()
+	tokens := []v2.TokenConfig{{Address: "0x036CbD53842c5426634e7929541eC2318f3dCF7e", Symbol: "USDC", Decimals: 6}}
+	requirements := &v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		Amount:            "10000",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+		Extra:             map[string]interface{}{"name": "USDC", "version": "2"},
+	}
+
+	tests := []struct {
+		name    string
+		wallet  keyWallet
+		wantErr error
+	}{
+		{name: "signed", wallet: keyWallet{key: key, recoveryOffset: 27}},
+		{name: "zero recovery id", wallet: keyWallet{key: key}},
+		{name: "other account", wallet: keyWallet{key: otherKey, recoveryOffset: 27}, wantErr: v2.ErrSigningFailed},
+		{name: "rejected", wallet: keyWallet{err: v2.ErrSignatureRejected}, wantErr: v2.ErrSignatureRejected},
+		{name: "timed out", wallet: keyWallet{err: context.DeadlineExceeded}, wantErr: v2.ErrApprovalTimeout},
+		{name: "wallet error", wallet: keyWallet{err: errors.New("disconnected")}, wantErr: v2.ErrSigningFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewWalletSigner("eip155:84532", testAddress, tt.wallet, tokens, WithMaxAmount(big.NewInt(20000)))
+			if err != nil {
+				t.Fatalf("NewWalletSigner failed: %v", err)
+			}
+			payload, err := signer.Sign(requirements)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Sign failed: %v", err)
+			}
+			evmPayload := payload.Payload.(v2.EVMPayload)
+			if evmPayload.Authorization.From != testAddress {
+				t.Errorf("Authorization.From = %s; want %s", evmPayload.Authorization.From, testAddress)
+			}
+			// Signatures carry a recovery id of 27 or 28 whatever the wallet returns
+			if v := evmPayload.Signature[len(evmPayload.Signature)-2:]; v != "1b" && v != "1c" {
+				t.Errorf("Signature %s has recovery id %s", evmPayload.Signature, v)
+			}
+		})
+	}
+
+	signer, _ := NewWalletSigner("eip155:84532", testAddress, keyWallet{key: key}, tokens)
+	permit2 := *requirements
+	permit2.Extra = map[string]interface{}{"assetTransferMethod": v2.AssetTransferMethodPermit2}
+	if signer.CanSign(&permit2) {
+		t.Error("wallet signers only sign EIP-3009 authorizations")
+	}
+	if _, err := NewWalletSigner("eip155:84532", "not an address", keyWallet{}, tokens); err == nil {
+		t.Error("expected an invalid account to fail")
+	}
+}
+
+func TestBrowserBridge(t *testing.T) {
+	key, _ := crypto.HexToECDSA(testPrivateKey)
+	bridge, err := NewBrowserBridge(WithBridgePollTimeout(50 * time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewBrowserBridge failed: %v", err)
+	}
+	server := httptest.NewServer(bridge)
+	defer server.Close()
+	endpoint := func(path string) string { return server.URL + path + "?token=" + bridge.Token() }
+
+	// Requests without the token are refused
+	resp, err := http.Get(server.URL + "/next")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 without the token, got %d", resp.StatusCode)
+	}
+
+	// The page connects the wallet
+	resp, err = http.Post(endpoint("/connect"), "application/json", strings.NewReader(`{"accounts":["`+testAddress+`"],"chainId":"0x14a34"}`))
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	resp.Body.Close()
+	accounts, err := bridge.Accounts(context.Background())
+	if err != nil || len(accounts) != 1 || accounts[0].Hex() != testAddress {
+		t.Fatalf("Accounts() = %v, %v", accounts, err)
+	}
+
+	// The page answers requests as the user approves them
+	answer := func(reject bool) {
+		for {
+			resp, err := http.Get(endpoint("/next"))
+			if err != nil {
+				t.Errorf("poll failed: %v", err)
+				return
+			}
+			if resp.StatusCode == http.StatusNoContent {
+				resp.Body.Close()
+				continue
+			}
+			var req struct {
+				ID        string             `json:"id"`
+				ChainID   string             `json:"chainId"`
+				TypedData apitypes.TypedData `json:"typedData"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&req)
+			resp.Body.Close()
+			if req.ChainID != "0x14a34" {
+				t.Errorf("chainId = %q; want 0x14a34", req.ChainID)
+			}
+			result := map[string]interface{}{"id": req.ID, "code": 4001, "error": "User rejected the request."}
+			if !reject {
+				signature, err := keyWallet{key: key, recoveryOffset: 27}.SignTypedData(context.Background(), accounts[0], req.TypedData)
+				if err != nil {
+					t.Errorf("signing failed: %v", err)
+				}
+				result = map[string]interface{}{"id": req.ID, "signature": signature}
+			}
+			body, _ := json.Marshal(result)
+			resp, err = http.Post(endpoint("/result"), "application/json", bytes.NewReader(body))
+			if err == nil {
+				resp.Body.Close()
+			}
+			return
+		}
+	}
+
+	signer, err := NewWalletSigner("eip155:84532", testAddress, bridge, []v2.TokenConfig{{Address: "0x036CbD53842c5426634e7929541eC2318f3dCF7e"}})
+	if err != nil {
+		t.Fatalf("NewWalletSigner failed: %v", err)
+	}
+	requirements := &v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		Amount:            "10000",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+		Extra:             map[string]interface{}{"name": "USDC", "version": "2"},
+	}
+
+	go answer(false)
+	if _, err := signer.Sign(requirements); err != nil {
+		t.Fatalf("Sign through the bridge failed: %v", err)
+	}
+	go answer(true)
+	if _, err := signer.Sign(requirements); !errors.Is(err, v2.ErrSignatureRejected) {
+		t.Fatalf("expected a rejection, got %v", err)
+	}
+
+	// Unanswered requests time out and are withdrawn
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := bridge.SignTypedData(ctx, accounts[0], apitypes.TypedData{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	resp, err = http.Get(endpoint("/next"))
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Errorf("expected the abandoned request to be withdrawn, got %d", resp.StatusCode)
+		}
+	}
+}
+
+// relayHub is an in-memory WalletConnect relay server.
+type relayHub struct {
+	mu      sync.Mutex
+	history map[string][]string
+	clients []*hubRelay
+}
+
+// hubRelay is a client of a relayHub.
+type hubRelay struct {
+	hub      *relayHub
+	topics   map[string]bool
+	messages chan WalletConnectMessage
+}
+
+func (h *relayHub) client() *hubRelay {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.history == nil {
+		h.history = make(map[string][]string)
+	}
+	r := &hubRelay{hub: h, topics: make(map[string]bool), messages: make(chan WalletConnectMessage, 64)}
+	h.clients = append(h.clients, r)
+	return r
+}
+
+func (r *hubRelay) Subscribe(ctx context.Context, topic string) error {
+	r.hub.mu.Lock()
+	defer r.hub.mu.Unlock()
+	r.topics[topic] = true
+	for _, message := range r.hub.history[topic] {
+		r.messages <- WalletConnectMessage{Topic: topic, Message: message}
+	}
+	return nil
+}
+
+func (r *hubRelay) Publish(ctx context.Context, topic, message string, tag int, ttl time.Duration) error {
+	r.hub.mu.Lock()
+	defer r.hub.mu.Unlock()
+	r.hub.history[topic] = append(r.hub.history[topic], message)
+	for _, client := range r.hub.clients {
+		if client != r && client.topics[topic] {
+			client.messages <- WalletConnectMessage{Topic: topic, Message: message}
+		}
+	}
+	return nil
+}
+
+func (r *hubRelay) Messages() <-chan WalletConnectMessage { return r.messages }
+func (r *hubRelay) Close() error                          { return nil }
+
+// testWallet is a mobile wallet answering WalletConnect proposals and requests.
+type testWallet struct {
+	t      *testing.T
+	relay  *hubRelay
+	key    *ecdsa.PrivateKey
+	keys   map[string][]byte
+	answer func(method string) *wcError // nil approves
+}
+
+func (w *testWallet) publish(topic string, msg wcMessage) {
+	data, _ := json.Marshal(msg)
+	message, err := wcEncrypt(w.keys[topic], data)
+	if err != nil {
+		w.t.Errorf("encrypting failed: %v", err)
+	}
+	_ = w.relay.Publish(context.Background(), topic, message, 0, time.Minute)
+}
+
+// run pairs through uri and answers requests until the relay closes.
+func (w *testWallet) run(uri string) {
+	parsed, _ := url.Parse(strings.Replace(uri, "@2", "", 1))
+	topic := parsed.Opaque
+	symKey, _ := hex.DecodeString(parsed.Query().Get("symKey"))
+	w.keys = map[string][]byte{topic: symKey}
+	_ = w.relay.Subscribe(context.Background(), topic)
+
+	for received := range w.relay.messages {
+		data, err := wcDecrypt(w.keys[received.Topic], received.Message)
+		if err != nil {
+			w.t.Errorf("decrypting failed: %v", err)
+			continue
+		}
+		var msg wcMessage
+		_ = json.Unmarshal(data, &msg)
+		if msg.Method == "" {
+			continue
+		}
+		if rejection := w.answer(msg.Method); rejection != nil {
+			w.publish(received.Topic, wcMessage{ID: msg.ID, JSONRPC: "2.0", Error: rejection})
+			continue
+		}
+
+		switch msg.Method {
+		case "wc_sessionPropose":
+			var proposal struct {
+				Proposer struct {
+					PublicKey string `json:"publicKey"`
+				} `json:"proposer"`
+			}
+			_ = json.Unmarshal(msg.Params, &proposal)
+			private, _ := ecdh.X25519().GenerateKey(rand.Reader)
+			sessionKey, err := wcSessionKey(private, proposal.Proposer.PublicKey)
+			if err != nil {
+				w.t.Errorf("deriving session key failed: %v", err)
+				continue
+			}
+			result, _ := json.Marshal(map[string]interface{}{
+				"relay":              map[string]string{"protocol": "irn"},
+				"responderPublicKey": hex.EncodeToString(private.PublicKey().Bytes()),
+			})
+			w.publish(received.Topic, wcMessage{ID: msg.ID, JSONRPC: "2.0", Result: result})
+
+			sessionTopic := wcTopic(sessionKey)
+			w.keys[sessionTopic] = sessionKey
+			_ = w.relay.Subscribe(context.Background(), sessionTopic)
+			settle, _ := json.Marshal(map[string]interface{}{
+				"namespaces": map[string]wcNamespace{"eip155": {Accounts: []string{"eip155:84532:" + testAddress}}},
+				"controller": map[string]interface{}{"metadata": WalletConnectMetadata{Name: "Test Wallet"}},
+				"expiry":     time.Now().Add(24 * time.Hour).Unix(),
+			})
+			w.publish(sessionTopic, wcMessage{ID: wcPayloadID(), JSONRPC: "2.0", Method: "wc_sessionSettle", Params: settle})
+		case "wc_sessionRequest":
+			var request struct {
+				Request struct {
+					Method string   `json:"method"`
+					Params []string `json:"params"`
+				} `json:"request"`
+				ChainID string `json:"chainId"`
+			}
+			_ = json.Unmarshal(msg.Params, &request)
+			if request.Request.Method != "eth_signTypedData_v4" || request.ChainID != "eip155:84532" {
+				w.t.Errorf("unexpected request %s on %s", request.Request.Method, request.ChainID)
+			}
+			var typedData apitypes.TypedData
+			_ = json.Unmarshal([]byte(request.Request.Params[1]), &typedData)
+			signature, err := keyWallet{key: w.key, recoveryOffset: 27}.SignTypedData(context.Background(), common.HexToAddress(request.Request.Params[0]), typedData)
+			if err != nil {
+				w.t.Errorf("signing failed: %v", err)
+			}
+			result, _ := json.Marshal(signature)
+			w.publish(received.Topic, wcMessage{ID: msg.ID, JSONRPC: "2.0", Result: result})
+		}
+	}
+}
+
+func TestWalletConnect(t *testing.T) {
+	key, _ := crypto.HexToECDSA(testPrivateKey)
+	ctx := context.Background()
+	hub := &relayHub{}
+	store := storage.NewMemory()
+	tokens := []v2.TokenConfig{{Address: "0x036CbD53842c5426634e7929541eC2318f3dCF7e"}}
+	requirements := &v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		Amount:            "10000",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+		Extra:             map[string]interface{}{"name": "USDC", "version": "2"},
+	}
+
+	var mu sync.Mutex
+	answers := map[string]*wcError{}
+	wallet := &testWallet{t: t, relay: hub.client(), key: key, answer: func(method string) *wcError {
+		mu.Lock()
+		defer mu.Unlock()
+		return answers[method]
+	}}
+	setAnswer := func(method string, answer *wcError) {
+		mu.Lock()
+		defer mu.Unlock()
+		answers[method] = answer
+	}
+
+	wc, err := NewWalletConnect(ctx, "project", []string{"eip155:84532"}, WithWalletConnectRelay(hub.client()), WithWalletConnectStore(store))
+	if err != nil {
+		t.Fatalf("NewWalletConnect failed: %v", err)
+	}
+	uri, err := wc.Pair(ctx)
+	if err != nil {
+		t.Fatalf("Pair failed: %v", err)
+	}
+	if !strings.HasPrefix(uri, "wc:") || !strings.Contains(uri, "@2?") {
+		t.Fatalf("unexpected pairing URI %q", uri)
+	}
+	go wallet.run(uri)
+
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	accounts, err := wc.Accounts(waitCtx)
+	if err != nil || len(accounts) != 1 || accounts[0].Hex() != testAddress {
+		t.Fatalf("Accounts() = %v, %v", accounts, err)
+	}
+	if peer, ok := wc.Peer(); !ok || peer.Name != "Test Wallet" {
+		t.Errorf("Peer() = %v, %v", peer, ok)
+	}
+
+	signer, err := NewWalletSigner("eip155:84532", testAddress, wc, tokens, WithApprovalTimeout(5*time.Second))
+	if err != nil {
+		t.Fatalf("NewWalletSigner failed: %v", err)
+	}
+	if _, err := signer.Sign(requirements); err != nil {
+		t.Fatalf("Sign through WalletConnect failed: %v", err)
+	}
+	setAnswer("wc_sessionRequest", &wcError{Code: 5000, Message: "User rejected."})
+	if _, err := signer.Sign(requirements); !errors.Is(err, v2.ErrSignatureRejected) {
+		t.Fatalf("expected a rejection, got %v", err)
+	}
+	setAnswer("wc_sessionRequest", nil)
+
+	// The session survives restarts
+	_ = wc.Close()
+	restored, err := NewWalletConnect(ctx, "project", []string{"eip155:84532"}, WithWalletConnectRelay(hub.client()), WithWalletConnectStore(store))
+	if err != nil {
+		t.Fatalf("NewWalletConnect failed: %v", err)
+	}
+	defer restored.Close()
+	readyCtx, cancelReady := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelReady()
+	if accounts, err := restored.Accounts(readyCtx); err != nil || len(accounts) != 1 {
+		t.Fatalf("expected the stored session, got %v, %v", accounts, err)
+	}
+	signer, _ = NewWalletSigner("eip155:84532", testAddress, restored, tokens, WithApprovalTimeout(5*time.Second))
+	if _, err := signer.Sign(requirements); err != nil {
+		t.Fatalf("Sign with the restored session failed: %v", err)
+	}
+
+	if err := restored.Disconnect(ctx); err != nil {
+		t.Fatalf("Disconnect failed: %v", err)
+	}
+	if _, err := store.Get(ctx, walletConnectSessionKey); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected the session to be removed from the store, got %v", err)
+	}
+	if _, err := restored.SignTypedData(ctx, accounts[0], apitypes.TypedData{}); !errors.Is(err, ErrNoWalletConnectSession) {
+		t.Errorf("expected ErrNoWalletConnectSession, got %v", err)
+	}
+
+	// Rejected proposals end the pairing
+	setAnswer("wc_sessionPropose", &wcError{Code: 5000, Message: "User rejected."})
+	rejecting := &testWallet{t: t, relay: hub.client(), key: key, answer: wallet.answer}
+	uri, err = restored.Pair(ctx)
+	if err != nil {
+		t.Fatalf("Pair failed: %v", err)
+	}
+	go rejecting.run(uri)
+	if _, err := restored.Accounts(waitCtx); !errors.Is(err, v2.ErrSignatureRejected) {
+		t.Errorf("expected the rejected proposal, got %v", err)
+	}
+}
@@ -0,0 +1,413 @@
+package evm
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/node"
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// simulatedChainID is the chain ID of the simulated backend. It is Base Sepolia's
+// so that signers can be created for a supported network.
+const simulatedChainID = 84532
+
+// Function selectors of the test token.
+var (
+	selectorMint                      = crypto.Keccak256([]byte("mint(address,uint256)"))[:4]
+	selectorTransferWithAuthorization = crypto.Keccak256([]byte("transferWithAuthorization(address,address,uint256,uint256,uint256,bytes32,uint8,bytes32,bytes32)"))[:4]
+)
+
+// simulatedChain is an in-memory EVM chain with a funded deployer account.
+type simulatedChain struct {
+	backend  *simulated.Backend
+	client   simulated.Client
+	deployer *ecdsa.PrivateKey
+}
+
+// newSimulatedChain starts an in-memory chain that is closed when the test ends.
+func newSimulatedChain(t *testing.T) *simulatedChain {
+	t.Helper()
+	deployer, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate deployer key: %v", err)
+	}
+
+	alloc := types.GenesisAlloc{
+		crypto.PubkeyToAddress(deployer.PublicKey): {Balance: new(big.Int).Mul(big.NewInt(1e18), big.NewInt(100))},
+	}
+	backend := simulated.NewBackend(alloc, func(_ *node.Config, ethConf *ethconfig.Config) {
+		config := *ethConf.Genesis.Config
+		config.ChainID = big.NewInt(simulatedChainID)
+		ethConf.Genesis.Config = &config
+		ethConf.NetworkId = simulatedChainID
+	})
+	t.Cleanup(func() { _ = backend.Close() })
+
+	return &simulatedChain{backend: backend, client: backend.Client(), deployer: deployer}
+}
+
+// send mines a transaction from the deployer and returns its receipt. Reverted
+// transactions are mined and reported through the receipt status.
+func (c *simulatedChain) send(t *testing.T, to *common.Address, data []byte) *types.Receipt {
+	t.Helper()
+	ctx := context.Background()
+	from := crypto.PubkeyToAddress(c.deployer.PublicKey)
+
+	nonce, err := c.client.PendingNonceAt(ctx, from)
+	if err != nil {
+		t.Fatalf("Failed to get nonce: %v", err)
+	}
+	gasPrice, err := c.client.SuggestGasPrice(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get gas price: %v", err)
+	}
+
+	tx, err := types.SignTx(types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		To:       to,
+		Gas:      1_000_000,
+		GasPrice: gasPrice,
+		Data:     data,
+	}), types.LatestSignerForChainID(big.NewInt(simulatedChainID)), c.deployer)
+	if err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+	if err := c.client.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("Failed to send transaction: %v", err)
+	}
+	c.backend.Commit()
+
+	receipt, err := c.client.TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		t.Fatalf("Failed to get receipt: %v", err)
+	}
+	return receipt
+}
+
+// testToken is a minimal EIP-3009 token deployed on a simulatedChain. It
+// implements balanceOf, an unrestricted mint, authorizationState, and the
+// v/r/s variant of transferWithAuthorization, verifying the EIP-712 signature
+// with ecrecover against a domain separator computed on-chain from its name,
+// version, the chain ID, and its own address.
+type testToken struct {
+	chain   *simulatedChain
+	address common.Address
+}
+
+// deployTestToken deploys a testToken with the given EIP-712 domain name and version.
+func deployTestToken(t *testing.T, chain *simulatedChain, name, version string) *testToken {
+	t.Helper()
+	receipt := chain.send(t, nil, testTokenInitCode(name, version))
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatal("Test token deployment reverted")
+	}
+	return &testToken{chain: chain, address: receipt.ContractAddress}
+}
+
+// mint credits amount to account.
+func (tk *testToken) mint(t *testing.T, account common.Address, amount *big.Int) {
+	t.Helper()
+	data := append(append(append([]byte{}, selectorMint...), common.LeftPadBytes(account.Bytes(), 32)...), common.LeftPadBytes(amount.Bytes(), 32)...)
+	if receipt := tk.chain.send(t, &tk.address, data); receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatal("Mint reverted")
+	}
+}
+
+// balanceOf returns the balance of account.
+func (tk *testToken) balanceOf(t *testing.T, account common.Address) *big.Int {
+	t.Helper()
+	return new(big.Int).SetBytes(tk.call(t, selectorBalanceOf, account.Bytes()))
+}
+
+// authorizationUsed reports whether the nonce of authorizer has been used.
+func (tk *testToken) authorizationUsed(t *testing.T, authorizer common.Address, nonce common.Hash) bool {
+	t.Helper()
+	return new(big.Int).SetBytes(tk.call(t, selectorAuthorizationState, authorizer.Bytes(), nonce.Bytes())).Sign() != 0
+}
+
+func (tk *testToken) call(t *testing.T, selector []byte, args ...[]byte) []byte {
+	t.Helper()
+	data := append([]byte{}, selector...)
+	for _, arg := range args {
+		data = append(data, common.LeftPadBytes(arg, 32)...)
+	}
+	result, err := tk.chain.client.CallContract(context.Background(), ethereum.CallMsg{To: &tk.address, Data: data}, nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	return result
+}
+
+// transferWithAuthorization submits a signed EIP-3009 payload exactly as a
+// facilitator would and reports whether the transfer succeeded.
+func (tk *testToken) transferWithAuthorization(t *testing.T, payload v2.EVMPayload) bool {
+	t.Helper()
+	signature := common.FromHex(payload.Signature)
+	if len(signature) != 65 {
+		t.Fatalf("Expected 65-byte signature, got %d bytes", len(signature))
+	}
+
+	data := append([]byte{}, selectorTransferWithAuthorization...)
+	for _, word := range [][]byte{
+		common.HexToAddress(payload.Authorization.From).Bytes(),
+		common.HexToAddress(payload.Authorization.To).Bytes(),
+		decimalBytes(t, payload.Authorization.Value),
+		decimalBytes(t, payload.Authorization.ValidAfter),
+		decimalBytes(t, payload.Authorization.ValidBefore),
+		common.HexToHash(payload.Authorization.Nonce).Bytes(),
+		{signature[64]},
+		signature[:32],
+		signature[32:64],
+	} {
+		data = append(data, common.LeftPadBytes(word, 32)...)
+	}
+	return tk.chain.send(t, &tk.address, data).Status == types.ReceiptStatusSuccessful
+}
+
+func decimalBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("Invalid decimal %q", s)
+	}
+	return n.Bytes()
+}
+
+// testTokenInitCode returns the deployment code of the test token.
+//
+// Storage: balanceOf(a) is at keccak256(a . 0) and authorizationState(a, n)
+// at keccak256(a . n . 1).
+func testTokenInitCode(name, version string) []byte {
+	domainTypeHash := crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	transferTypeHash := crypto.Keccak256([]byte("TransferWithAuthorization(address from,address to,uint256 value,uint256 validAfter,uint256 validBefore,bytes32 nonce)"))
+
+	a := newAssembler()
+
+	// Dispatch on the selector
+	a.push([]byte{0}).op(vm.CALLDATALOAD).push([]byte{0xe0}).op(vm.SHR)
+	for _, function := range []struct {
+		selector []byte
+		label    string
+	}{
+		{selectorBalanceOf, "balanceOf"},
+		{selectorMint, "mint"},
+		{selectorAuthorizationState, "authorizationState"},
+		{selectorTransferWithAuthorization, "transferWithAuthorization"},
+	} {
+		a.op(vm.DUP1).push(function.selector).op(vm.EQ).jumpi(function.label)
+	}
+	a.label("revert").push([]byte{0}).op(vm.DUP1, vm.REVERT)
+
+	// balanceOf(address)
+	a.label("balanceOf").push([]byte{4}).op(vm.CALLDATALOAD)
+	a.balanceSlot().op(vm.SLOAD).returnWord()
+
+	// mint(address,uint256)
+	a.label("mint").push([]byte{4}).op(vm.CALLDATALOAD)
+	a.balanceSlot().op(vm.DUP1, vm.SLOAD).push([]byte{36}).op(vm.CALLDATALOAD, vm.ADD, vm.SWAP1, vm.SSTORE, vm.STOP)
+
+	// authorizationState(address,bytes32)
+	a.label("authorizationState").push([]byte{36}).op(vm.CALLDATALOAD).push([]byte{4}).op(vm.CALLDATALOAD)
+	a.authorizationSlot().op(vm.SLOAD).returnWord()
+
+	// transferWithAuthorization(from, to, value, validAfter, validBefore, nonce, v, r, s)
+	a.label("transferWithAuthorization")
+	// validAfter < block.timestamp < validBefore
+	a.push([]byte{100}).op(vm.CALLDATALOAD, vm.TIMESTAMP, vm.GT, vm.ISZERO).jumpi("revert")
+	a.push([]byte{132}).op(vm.CALLDATALOAD, vm.TIMESTAMP, vm.LT, vm.ISZERO).jumpi("revert")
+	// The nonce is unused; mark it used
+	a.push([]byte{164}).op(vm.CALLDATALOAD).push([]byte{4}).op(vm.CALLDATALOAD)
+	a.authorizationSlot().op(vm.DUP1, vm.SLOAD).jumpi("revert")
+	a.push([]byte{1}).op(vm.SWAP1, vm.SSTORE)
+	// domainSeparator = keccak256(typeHash . keccak256(name) . keccak256(version) . chainid . address)
+	a.push(domainTypeHash).mstore(0x80)
+	a.push(crypto.Keccak256([]byte(name))).mstore(0xa0)
+	a.push(crypto.Keccak256([]byte(version))).mstore(0xc0)
+	a.op(vm.CHAINID).mstore(0xe0)
+	a.op(vm.ADDRESS).mstore(0x100)
+	a.push([]byte{160}).push([]byte{0x80}).op(vm.KECCAK256)
+	// structHash = keccak256(typeHash . from . to . value . validAfter . validBefore . nonce)
+	a.push(transferTypeHash).mstore(0x120)
+	a.push([]byte{192}).push([]byte{4}).push([]byte{0x01, 0x40}).op(vm.CALLDATACOPY)
+	a.push([]byte{224}).push([]byte{0x01, 0x20}).op(vm.KECCAK256)
+	// digest = keccak256(0x1901 . domainSeparator . structHash)
+	a.push(common.RightPadBytes([]byte{0x19, 0x01}, 32)).mstore(0x200)
+	a.mstore(0x222).mstore(0x202)
+	a.push([]byte{66}).push([]byte{0x02, 0x00}).op(vm.KECCAK256)
+	// ecrecover(digest, v, r, s) == from
+	a.mstore(0x300)
+	a.push([]byte{96}).push([]byte{196}).push([]byte{0x03, 0x20}).op(vm.CALLDATACOPY)
+	a.push([]byte{0}).mstore(0x400)
+	a.push([]byte{32}).push([]byte{0x04, 0x00}).push([]byte{128}).push([]byte{0x03, 0x00}).push([]byte{1}).op(vm.GAS, vm.STATICCALL, vm.POP)
+	a.push([]byte{0x04, 0x00}).op(vm.MLOAD, vm.DUP1, vm.ISZERO).jumpi("revert")
+	a.push([]byte{4}).op(vm.CALLDATALOAD, vm.EQ, vm.ISZERO).jumpi("revert")
+	// balances[from] -= value, reverting if insufficient
+	a.push([]byte{4}).op(vm.CALLDATALOAD)
+	a.balanceSlot().op(vm.DUP1, vm.SLOAD).push([]byte{68}).op(vm.CALLDATALOAD, vm.DUP2, vm.DUP2, vm.GT).jumpi("revert")
+	a.op(vm.SWAP1, vm.SUB, vm.SWAP1, vm.SSTORE)
+	// balances[to] += value
+	a.push([]byte{36}).op(vm.CALLDATALOAD)
+	a.balanceSlot().op(vm.DUP1, vm.SLOAD).push([]byte{68}).op(vm.CALLDATALOAD, vm.ADD, vm.SWAP1, vm.SSTORE, vm.STOP)
+
+	runtime := a.assemble()
+
+	// Constructor: return the runtime code that follows it
+	const constructorSize = 13
+	size := []byte{byte(len(runtime) >> 8), byte(len(runtime))}
+	constructor := newAssembler()
+	constructor.push(size).op(vm.DUP1).push([]byte{0, constructorSize}).push([]byte{0}).op(vm.CODECOPY).push([]byte{0}).op(vm.RETURN)
+	return append(constructor.assemble(), runtime...)
+}
+
+// assembler builds EVM bytecode with named jump targets.
+type assembler struct {
+	code   []byte
+	labels map[string]int
+	jumps  map[int]string
+}
+
+func newAssembler() *assembler {
+	return &assembler{labels: make(map[string]int), jumps: make(map[int]string)}
+}
+
+func (a *assembler) op(ops ...vm.OpCode) *assembler {
+	for _, op := range ops {
+		a.code = append(a.code, byte(op))
+	}
+	return a
+}
+
+// push pushes value with the PUSH opcode of its length.
+func (a *assembler) push(value []byte) *assembler {
+	a.code = append(a.code, byte(vm.PUSH1)+byte(len(value)-1))
+	a.code = append(a.code, value...)
+	return a
+}
+
+// label marks a jump target.
+func (a *assembler) label(name string) *assembler {
+	a.labels[name] = len(a.code)
+	return a.op(vm.JUMPDEST)
+}
+
+// jumpi jumps to label if the top of the stack is non-zero.
+func (a *assembler) jumpi(label string) *assembler {
+	a.jumps[len(a.code)+1] = label
+	return a.push([]byte{0, 0}).op(vm.JUMPI)
+}
+
+// mstore stores the top of the stack at offset.
+func (a *assembler) mstore(offset uint16) *assembler {
+	return a.push([]byte{byte(offset >> 8), byte(offset)}).op(vm.MSTORE)
+}
+
+// returnWord returns the top of the stack.
+func (a *assembler) returnWord() *assembler {
+	return a.mstore(0).push([]byte{32}).push([]byte{0}).op(vm.RETURN)
+}
+
+// balanceSlot replaces the account on the stack with its balance slot.
+func (a *assembler) balanceSlot() *assembler {
+	a.mstore(0).push([]byte{0}).mstore(32)
+	return a.push([]byte{64}).push([]byte{0}).op(vm.KECCAK256)
+}
+
+// authorizationSlot replaces the authorizer and nonce on the stack with their
+// authorization state slot.
+func (a *assembler) authorizationSlot() *assembler {
+	a.mstore(0).mstore(32).push([]byte{1}).mstore(64)
+	return a.push([]byte{96}).push([]byte{0}).op(vm.KECCAK256)
+}
+
+func (a *assembler) assemble() []byte {
+	for at, label := range a.jumps {
+		target, ok := a.labels[label]
+		if !ok {
+			panic("undefined label " + label)
+		}
+		a.code[at], a.code[at+1] = byte(target>>8), byte(target)
+	}
+	return a.code
+}
+
+func TestSigner_SimulatedTransferWithAuthorization(t *testing.T) {
+	chain := newSimulatedChain(t)
+	token := deployTestToken(t, chain, "USD Coin", "2")
+
+	signer, err := NewSigner(v2.NetworkBaseSepolia, testPrivateKey, []v2.TokenConfig{
+		{Address: token.address.Hex(), Symbol: "USDC", Decimals: 6},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	payer := signer.Address()
+	payee := common.HexToAddress("0x209693Bc6afc0C5328bA36FaF03C514EF312287C")
+	token.mint(t, payer, big.NewInt(1_000_000))
+
+	requirements := func(amount, name, version string) *v2.PaymentRequirements {
+		return &v2.PaymentRequirements{
+			Scheme:            "exact",
+			Network:           v2.NetworkBaseSepolia,
+			Amount:            amount,
+			Asset:             token.address.Hex(),
+			PayTo:             payee.Hex(),
+			MaxTimeoutSeconds: 60,
+			Extra:             map[string]interface{}{"name": name, "version": version},
+		}
+	}
+	sign := func(t *testing.T, req *v2.PaymentRequirements) v2.EVMPayload {
+		t.Helper()
+		payment, err := signer.Sign(req)
+		if err != nil {
+			t.Fatalf("Sign failed: %v", err)
+		}
+		return payment.Payload.(v2.EVMPayload)
+	}
+
+	t.Run("valid authorization transfers", func(t *testing.T) {
+		payload := sign(t, requirements("10000", "USD Coin", "2"))
+		if !token.transferWithAuthorization(t, payload) {
+			t.Fatal("Expected transferWithAuthorization to succeed")
+		}
+		if got := token.balanceOf(t, payee); got.Cmp(big.NewInt(10000)) != 0 {
+			t.Errorf("Expected payee balance 10000, got %s", got)
+		}
+		if !token.authorizationUsed(t, payer, common.HexToHash(payload.Authorization.Nonce)) {
+			t.Error("Expected nonce to be marked used")
+		}
+
+		// Replays are rejected
+		if token.transferWithAuthorization(t, payload) {
+			t.Error("Expected replayed authorization to revert")
+		}
+	})
+
+	t.Run("tampered value is rejected", func(t *testing.T) {
+		payload := sign(t, requirements("10000", "USD Coin", "2"))
+		payload.Authorization.Value = "20000"
+		if token.transferWithAuthorization(t, payload) {
+			t.Error("Expected tampered authorization to revert")
+		}
+	})
+
+	t.Run("wrong domain is rejected", func(t *testing.T) {
+		payload := sign(t, requirements("10000", "USDC", "2"))
+		if token.transferWithAuthorization(t, payload) {
+			t.Error("Expected authorization for another domain to revert")
+		}
+	})
+
+	if got := token.balanceOf(t, payer); got.Cmp(big.NewInt(990_000)) != 0 {
+		t.Errorf("Expected payer balance 990000, got %s", got)
+	}
+}
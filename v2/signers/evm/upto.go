@@ -0,0 +1,65 @@
+package evm
+
+import (
+	"math/big"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// UpToSigner signs payments for the "upto" scheme: a Permit2 permit for the
+// requirement's Amount, of which the seller settles only what the response
+// actually cost. The signer must be created with WithPermit2.
+type UpToSigner struct {
+	signer *Signer
+}
+
+// NewUpToSigner creates an UpToSigner that signs with signer's key, tokens,
+// limits, and Permit2 spenders.
+func NewUpToSigner(signer *Signer) *UpToSigner {
+	return &UpToSigner{signer: signer}
+}
+
+// Network returns the CAIP-2 network identifier.
+func (u *UpToSigner) Network() string {
+	return u.signer.Network()
+}
+
+// Scheme returns the payment scheme identifier.
+func (u *UpToSigner) Scheme() string {
+	return v2.SchemeUpTo
+}
+
+// CanSign reports whether the requirements use the upto scheme with Permit2 and
+// a token this signer holds.
+func (u *UpToSigner) CanSign(requirements *v2.PaymentRequirements) bool {
+	if requirements.Scheme != v2.SchemeUpTo || !isPermit2(requirements) {
+		return false
+	}
+	return u.signer.canSignAsset(requirements)
+}
+
+// Sign creates a Permit2 permit for the maximum amount of the requirements.
+func (u *UpToSigner) Sign(requirements *v2.PaymentRequirements) (*v2.PaymentPayload, error) {
+	if !u.CanSign(requirements) {
+		return nil, v2.ErrNoValidSigner
+	}
+
+	payload, _, err := u.signer.sign(requirements, time.Duration(requirements.MaxTimeoutSeconds)*time.Second)
+	return payload, err
+}
+
+// GetPriority returns the signer's priority level.
+func (u *UpToSigner) GetPriority() int {
+	return u.signer.GetPriority()
+}
+
+// GetTokens returns the list of supported tokens.
+func (u *UpToSigner) GetTokens() []v2.TokenConfig {
+	return u.signer.GetTokens()
+}
+
+// GetMaxAmount returns the per-call spending limit, or nil if no limit is set.
+func (u *UpToSigner) GetMaxAmount() *big.Int {
+	return u.signer.GetMaxAmount()
+}
@@ -0,0 +1,216 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/eip3009"
+)
+
+// DefaultApprovalTimeout bounds how long WalletSigner waits for the user to
+// approve a signature in their wallet.
+const DefaultApprovalTimeout = 2 * time.Minute
+
+// WithApprovalTimeout sets how long a WalletSigner waits for the user to
+// approve a payment (default: DefaultApprovalTimeout). Authorizations are
+// valid from the request, so keep it below the MaxTimeoutSeconds of the
+// requirements paid.
+func WithApprovalTimeout(d time.Duration) Option {
+	return func(s *Signer) error {
+		s.approvalTimeout = d
+		return nil
+	}
+}
+
+// TypedDataSigner signs EIP-712 typed data with a wallet holding the key of
+// account, as eth_signTypedData_v4 does. It is satisfied by BrowserBridge and
+// WalletConnect.
+type TypedDataSigner interface {
+	SignTypedData(ctx context.Context, account common.Address, data apitypes.TypedData) (string, error)
+}
+
+// WalletSigner pays "exact" requirements with EIP-3009 authorizations signed
+// by an external wallet, such as MetaMask through a BrowserBridge, for
+// applications that never hold the user's private key. Every payment waits
+// for the user to approve it in the wallet.
+type WalletSigner struct {
+	signer *Signer
+	wallet TypedDataSigner
+}
+
+// NewWalletSigner creates a WalletSigner paying from account with signatures
+// from wallet. The options of NewSigner that do not need the key, like
+// WithPriority, WithMaxAmount, WithApprovalTimeout and the domain and nonce
+// options, apply.
+func NewWalletSigner(network string, account string, wallet TypedDataSigner, tokens []v2.TokenConfig, opts ...Option) (*WalletSigner, error) {
+	if !common.IsHexAddress(account) {
+		return nil, fmt.Errorf("%w: invalid account %q", v2.ErrInvalidKey, account)
+	}
+	s := &Signer{
+		address:  common.HexToAddress(account),
+		network:  network,
+		tokens:   tokens,
+		domains:  make(map[string]DomainOverride),
+		backdate: eip3009.DefaultValidAfterBackdate,
+		clock:    v2.SystemClock,
+
+		nonceSource: eip3009.RandomNonceSource{},
+	}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	chainID, err := GetChainID(network)
+	if err != nil {
+		return nil, err
+	}
+	s.chainID = chainID
+
+	return &WalletSigner{signer: s, wallet: wallet}, nil
+}
+
+// Network returns the CAIP-2 network identifier.
+func (w *WalletSigner) Network() string {
+	return w.signer.Network()
+}
+
+// Scheme returns the payment scheme identifier.
+func (w *WalletSigner) Scheme() string {
+	return "exact"
+}
+
+// CanSign reports whether the requirements are EIP-3009 "exact" payments on
+// this signer's network with one of its tokens.
+func (w *WalletSigner) CanSign(requirements *v2.PaymentRequirements) bool {
+	if requirements.Scheme != "exact" || requirements.Network != w.signer.network {
+		return false
+	}
+	if assetTransferMethod(requirements) != v2.AssetTransferMethodEIP3009 {
+		return false
+	}
+	for _, token := range w.signer.tokens {
+		if strings.EqualFold(token.Address, requirements.Asset) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign asks the wallet to sign the authorization and checks the signature
+// was made by the account. It returns v2.ErrSignatureRejected when the user
+// declines and v2.ErrApprovalTimeout when they do not answer in time.
+func (w *WalletSigner) Sign(requirements *v2.PaymentRequirements) (*v2.PaymentPayload, error) {
+	if !w.CanSign(requirements) {
+		return nil, v2.ErrNoValidSigner
+	}
+	s := w.signer
+	amount, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok {
+		return nil, v2.ErrInvalidAmount
+	}
+	if s.maxAmount != nil && amount.Cmp(s.maxAmount) > 0 {
+		return nil, v2.ErrAmountExceeded
+	}
+
+	tokenAddress := common.HexToAddress(requirements.Asset)
+	domain, err := s.resolveDomain(requirements, tokenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.Duration(requirements.MaxTimeoutSeconds) * time.Second
+	if s.maxTimeout > 0 && timeout > s.maxTimeout {
+		timeout = s.maxTimeout
+	}
+	now := s.clock.Now()
+	nonceParams := eip3009.NonceParams{
+		From:    s.address,
+		To:      common.HexToAddress(requirements.PayTo),
+		Value:   amount,
+		Token:   tokenAddress,
+		ChainID: big.NewInt(s.chainID),
+	}
+	if key, ok := requirements.Extra["idempotencyKey"].(string); ok {
+		nonceParams.Key = key
+	}
+	auth, err := eip3009.CreateAuthorizationFromSource(s.nonceSource, nonceParams, now.Add(-s.backdate), now.Add(timeout))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recordNonce(tokenAddress, auth); err != nil {
+		return nil, err
+	}
+
+	approval := s.approvalTimeout
+	if approval <= 0 {
+		approval = DefaultApprovalTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), approval)
+	defer cancel()
+	signature, err := w.wallet.SignTypedData(ctx, s.address, eip3009.TypedData(domain, auth))
+	if err != nil {
+		switch {
+		case errors.Is(err, v2.ErrSignatureRejected), errors.Is(err, v2.ErrApprovalTimeout):
+			return nil, err
+		case errors.Is(err, context.DeadlineExceeded):
+			return nil, fmt.Errorf("%w: %v", v2.ErrApprovalTimeout, err)
+		}
+		return nil, fmt.Errorf("%w: %v", v2.ErrSigningFailed, err)
+	}
+	signature, err = normalizeSignature(signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", v2.ErrSigningFailed, err)
+	}
+	signer, err := eip3009.Recover(domain, auth, signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", v2.ErrSigningFailed, err)
+	}
+	if signer != s.address {
+		return nil, fmt.Errorf("%w: wallet signed with %s, not %s", v2.ErrSigningFailed, signer.Hex(), s.address.Hex())
+	}
+	return exactPayload(requirements, auth, signature), nil
+}
+
+// normalizeSignature returns signature with a recovery id of 27 or 28, as
+// some wallets return 0 or 1.
+func normalizeSignature(signature string) (string, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != 65 {
+		return "", fmt.Errorf("invalid signature encoding")
+	}
+	if sig[64] < 27 {
+		sig[64] += 27
+	}
+	return hexutil.Encode(sig), nil
+}
+
+// GetPriority returns the signer's priority level.
+func (w *WalletSigner) GetPriority() int {
+	return w.signer.GetPriority()
+}
+
+// GetTokens returns the list of supported tokens.
+func (w *WalletSigner) GetTokens() []v2.TokenConfig {
+	return w.signer.GetTokens()
+}
+
+// GetMaxAmount returns the per-call spending limit, or nil if no limit is set.
+func (w *WalletSigner) GetMaxAmount() *big.Int {
+	return w.signer.GetMaxAmount()
+}
+
+// Address returns the account payments are made from.
+func (w *WalletSigner) Address() common.Address {
+	return w.signer.Address()
+}
@@ -0,0 +1,823 @@
+package evm
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/storage"
+)
+
+// ErrNoWalletConnectSession is returned when signing without an approved
+// WalletConnect session.
+var ErrNoWalletConnectSession = errors.New("x402: no WalletConnect session")
+
+// DefaultWalletConnectRelayURL is the WalletConnect relay used when none is
+// configured.
+const DefaultWalletConnectRelayURL = "wss://relay.walletconnect.com"
+
+// walletConnectSessionKey is the store key of the persisted session.
+const walletConnectSessionKey = "walletconnect:session"
+
+// walletConnectTTL is how long the relay keeps proposals and requests for a
+// wallet that is offline, and how long a proposal waits for approval.
+const walletConnectTTL = 5 * time.Minute
+
+// Tags of the WalletConnect messages sent, which the relay uses to decide
+// whether to wake the wallet with a push notification.
+const (
+	wcTagSessionPropose = 1100
+	wcTagSessionRequest = 1108
+	wcTagSessionDelete  = 1112
+)
+
+// wcResponseTags are the tags of the responses to the wallet's requests.
+var wcResponseTags = map[string]int{
+	"wc_pairingDelete": 1001,
+	"wc_pairingPing":   1003,
+	"wc_sessionSettle": 1103,
+	"wc_sessionUpdate": 1105,
+	"wc_sessionExtend": 1107,
+	"wc_sessionEvent":  1111,
+	"wc_sessionDelete": 1113,
+	"wc_sessionPing":   1115,
+}
+
+// wcUserRejected is the WalletConnect error code of a request the user
+// rejected. Wallets forwarding EIP-1193 errors use eip1193UserRejected.
+const wcUserRejected = 5000
+
+// WalletConnectMetadata describes an application to the wallets it pairs with.
+type WalletConnectMetadata struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	URL         string   `json:"url"`
+	Icons       []string `json:"icons"`
+}
+
+// WalletConnectMessage is an encrypted message received on a relay topic.
+type WalletConnectMessage struct {
+	Topic   string
+	Message string
+}
+
+// WalletConnectRelay carries encrypted messages between WalletConnect peers.
+// DialWalletConnectRelay connects to a WalletConnect relay server.
+type WalletConnectRelay interface {
+	// Subscribe delivers the messages published on topic to Messages,
+	// including those published while not subscribed that have not expired.
+	Subscribe(ctx context.Context, topic string) error
+
+	// Publish publishes message on topic, kept for ttl for absent peers.
+	Publish(ctx context.Context, topic, message string, tag int, ttl time.Duration) error
+
+	// Messages returns the messages received, closed when the relay closes.
+	Messages() <-chan WalletConnectMessage
+
+	// Close disconnects from the relay.
+	Close() error
+}
+
+// WalletConnect pairs with a mobile wallet over WalletConnect v2 and relays
+// signature requests to it, so users approve the payments of a Go client or
+// agent on their phone. It is a TypedDataSigner for WalletSigner.
+//
+// Pair returns a wc: URI to show as a QR code; once the wallet approves the
+// session, Accounts returns its accounts. With a store the session is kept
+// across restarts until it expires or either side disconnects.
+type WalletConnect struct {
+	relayURL string
+	metadata WalletConnectMetadata
+	networks []string
+	store    storage.Store
+	relay    WalletConnectRelay
+
+	mu       sync.Mutex
+	keys     map[string][]byte
+	pending  map[int64]*wcPending
+	settling map[string]bool
+	session  *wcSession
+	ready    chan struct{}
+	err      error
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// wcSession is an approved session, as persisted.
+type wcSession struct {
+	Topic    string                `json:"topic"`
+	Key      string                `json:"key"`
+	Accounts []string              `json:"accounts"`
+	Expiry   int64                 `json:"expiry"`
+	Peer     WalletConnectMetadata `json:"peer"`
+}
+
+// wcPending is a request waiting for the wallet's response.
+type wcPending struct {
+	topic    string
+	response chan wcMessage
+}
+
+// wcMessage is a JSON-RPC request or response exchanged with the wallet.
+type wcMessage struct {
+	ID      int64           `json:"id"`
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *wcError        `json:"error,omitempty"`
+}
+
+// wcError is a JSON-RPC error.
+type wcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// wcNamespace is the eip155 namespace of a session.
+type wcNamespace struct {
+	Chains   []string `json:"chains,omitempty"`
+	Accounts []string `json:"accounts,omitempty"`
+	Methods  []string `json:"methods"`
+	Events   []string `json:"events"`
+}
+
+// WalletConnectOption configures a WalletConnect.
+type WalletConnectOption func(*WalletConnect)
+
+// WithWalletConnectRelayURL sets the relay server (default:
+// DefaultWalletConnectRelayURL).
+func WithWalletConnectRelayURL(relayURL string) WalletConnectOption {
+	return func(w *WalletConnect) {
+		w.relayURL = relayURL
+	}
+}
+
+// WithWalletConnectRelay uses relay instead of dialing the relay server.
+func WithWalletConnectRelay(relay WalletConnectRelay) WalletConnectOption {
+	return func(w *WalletConnect) {
+		w.relay = relay
+	}
+}
+
+// WithWalletConnectMetadata sets the application shown in the wallet.
+func WithWalletConnectMetadata(metadata WalletConnectMetadata) WalletConnectOption {
+	return func(w *WalletConnect) {
+		w.metadata = metadata
+	}
+}
+
+// WithWalletConnectStore persists the session in store, so it survives
+// restarts. Use a storage.Prefixed view when the store is shared.
+func WithWalletConnectStore(store storage.Store) WalletConnectOption {
+	return func(w *WalletConnect) {
+		w.store = store
+	}
+}
+
+// NewWalletConnect connects to the relay for a WalletConnect Cloud project
+// and restores the stored session, if any. Sessions request the CAIP-2
+// networks given, like "eip155:8453".
+func NewWalletConnect(ctx context.Context, projectID string, networks []string, opts ...WalletConnectOption) (*WalletConnect, error) {
+	if len(networks) == 0 {
+		return nil, errors.New("walletconnect: no networks")
+	}
+	for _, network := range networks {
+		if !strings.HasPrefix(network, "eip155:") {
+			return nil, fmt.Errorf("walletconnect: %q is not an EVM network", network)
+		}
+	}
+	w := &WalletConnect{
+		relayURL: DefaultWalletConnectRelayURL,
+		networks: networks,
+		keys:     make(map[string][]byte),
+		pending:  make(map[int64]*wcPending),
+		settling: make(map[string]bool),
+		ready:    make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.relay == nil {
+		relay, err := DialWalletConnectRelay(ctx, w.relayURL, projectID)
+		if err != nil {
+			return nil, err
+		}
+		w.relay = relay
+	}
+	go w.receive()
+
+	if err := w.restore(ctx); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// restore resumes the stored session.
+func (w *WalletConnect) restore(ctx context.Context) error {
+	if w.store == nil {
+		return nil
+	}
+	data, err := w.store.Get(ctx, walletConnectSessionKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("loading WalletConnect session: %w", err)
+	}
+	var session wcSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return fmt.Errorf("loading WalletConnect session: %w", err)
+	}
+	key, err := hex.DecodeString(session.Key)
+	if err != nil || time.Now().Unix() >= session.Expiry {
+		return w.store.Delete(ctx, walletConnectSessionKey)
+	}
+
+	w.mu.Lock()
+	w.keys[session.Topic] = key
+	w.mu.Unlock()
+	if err := w.relay.Subscribe(ctx, session.Topic); err != nil {
+		return fmt.Errorf("resuming WalletConnect session: %w", err)
+	}
+	w.mu.Lock()
+	w.session = &session
+	close(w.ready)
+	w.mu.Unlock()
+	return nil
+}
+
+// Pair proposes a session and returns the pairing URI for the wallet to
+// scan. Accounts waits for the wallet to approve the proposal, which expires
+// after five minutes.
+func (w *WalletConnect) Pair(ctx context.Context) (string, error) {
+	w.mu.Lock()
+	if w.session != nil {
+		w.mu.Unlock()
+		return "", errors.New("walletconnect: already connected, disconnect first")
+	}
+	select {
+	case <-w.ready:
+		// A previous pairing failed
+		w.ready = make(chan struct{})
+		w.err = nil
+	default:
+	}
+	w.mu.Unlock()
+
+	symKey := make([]byte, 32)
+	if _, err := rand.Read(symKey); err != nil {
+		return "", err
+	}
+	private, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	topic := wcTopic(symKey)
+	w.mu.Lock()
+	w.keys[topic] = symKey
+	w.mu.Unlock()
+	if err := w.relay.Subscribe(ctx, topic); err != nil {
+		return "", fmt.Errorf("subscribing to pairing: %w", err)
+	}
+
+	expiry := time.Now().Add(walletConnectTTL)
+	proposal := map[string]interface{}{
+		"relays":             []map[string]string{{"protocol": "irn"}},
+		"requiredNamespaces": map[string]interface{}{},
+		"optionalNamespaces": map[string]wcNamespace{"eip155": {
+			Chains:  w.networks,
+			Methods: []string{"eth_signTypedData_v4"},
+			Events:  []string{"accountsChanged", "chainChanged"},
+		}},
+		"proposer": map[string]interface{}{
+			"publicKey": hex.EncodeToString(private.PublicKey().Bytes()),
+			"metadata":  w.metadata,
+		},
+		"expiryTimestamp": expiry.Unix(),
+	}
+	response, err := w.request(ctx, topic, "wc_sessionPropose", proposal, wcTagSessionPropose)
+	if err != nil {
+		return "", err
+	}
+	go w.awaitApproval(private, response, expiry)
+
+	query := url.Values{}
+	query.Set("relay-protocol", "irn")
+	query.Set("symKey", hex.EncodeToString(symKey))
+	query.Set("expiryTimestamp", fmt.Sprint(expiry.Unix()))
+	return "wc:" + topic + "@2?" + query.Encode(), nil
+}
+
+// awaitApproval derives the session key once the wallet approves the
+// proposal and waits for it to settle the session.
+func (w *WalletConnect) awaitApproval(private *ecdh.PrivateKey, pending *wcPending, expiry time.Time) {
+	timer := time.NewTimer(time.Until(expiry))
+	defer timer.Stop()
+	defer w.forget(pending)
+
+	var response wcMessage
+	select {
+	case response = <-pending.response:
+	case <-timer.C:
+		w.fail(fmt.Errorf("%w: session proposal expired", v2.ErrApprovalTimeout))
+		return
+	case <-w.done:
+		return
+	}
+	if err := responseError(response); err != nil {
+		w.fail(err)
+		return
+	}
+
+	var result struct {
+		ResponderPublicKey string `json:"responderPublicKey"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		w.fail(fmt.Errorf("walletconnect: invalid proposal response: %w", err))
+		return
+	}
+	key, err := wcSessionKey(private, result.ResponderPublicKey)
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	topic := wcTopic(key)
+	w.mu.Lock()
+	w.keys[topic] = key
+	w.settling[topic] = true
+	ready := w.ready
+	w.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Until(expiry))
+	defer cancel()
+	if err := w.relay.Subscribe(ctx, topic); err != nil {
+		w.fail(fmt.Errorf("subscribing to session: %w", err))
+		return
+	}
+	select {
+	case <-ready:
+	case <-timer.C:
+		w.fail(fmt.Errorf("%w: session was not settled", v2.ErrApprovalTimeout))
+	case <-w.done:
+	}
+}
+
+// fail ends a pairing attempt with err.
+func (w *WalletConnect) fail(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	select {
+	case <-w.ready:
+	default:
+		w.err = err
+		close(w.ready)
+	}
+}
+
+// Accounts waits until the wallet has approved a session and returns its
+// accounts. It returns the pairing error when the wallet rejected the
+// proposal or did not answer in time.
+func (w *WalletConnect) Accounts(ctx context.Context) ([]common.Address, error) {
+	w.mu.Lock()
+	ready := w.ready
+	w.mu.Unlock()
+	select {
+	case <-ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.session == nil {
+		if w.err != nil {
+			return nil, w.err
+		}
+		return nil, ErrNoWalletConnectSession
+	}
+	var accounts []common.Address
+	seen := make(map[common.Address]bool)
+	for _, account := range w.session.Accounts {
+		// CAIP-10 account IDs, like eip155:8453:0xab16...
+		parts := strings.Split(account, ":")
+		if len(parts) != 3 || !common.IsHexAddress(parts[2]) {
+			continue
+		}
+		address := common.HexToAddress(parts[2])
+		if !seen[address] {
+			seen[address] = true
+			accounts = append(accounts, address)
+		}
+	}
+	return accounts, nil
+}
+
+// Peer returns the metadata of the connected wallet.
+func (w *WalletConnect) Peer() (WalletConnectMetadata, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.session == nil {
+		return WalletConnectMetadata{}, false
+	}
+	return w.session.Peer, true
+}
+
+// SignTypedData implements TypedDataSigner. It sends an eth_signTypedData_v4
+// request for the chain of the data's domain and waits until the user
+// approves or rejects it in the wallet; rejections return
+// v2.ErrSignatureRejected.
+func (w *WalletConnect) SignTypedData(ctx context.Context, account common.Address, data apitypes.TypedData) (string, error) {
+	w.mu.Lock()
+	session := w.session
+	w.mu.Unlock()
+	if session == nil {
+		return "", ErrNoWalletConnectSession
+	}
+	if data.Domain.ChainId == nil {
+		return "", errors.New("walletconnect: typed data has no chain ID")
+	}
+	chainID := "eip155:" + (*big.Int)(data.Domain.ChainId).String()
+
+	typedData, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	params := map[string]interface{}{
+		"request": map[string]interface{}{
+			"method": "eth_signTypedData_v4",
+			"params": []string{account.Hex(), string(typedData)},
+		},
+		"chainId": chainID,
+	}
+	pending, err := w.request(ctx, session.Topic, "wc_sessionRequest", params, wcTagSessionRequest)
+	if err != nil {
+		return "", err
+	}
+	defer w.forget(pending)
+
+	select {
+	case response := <-pending.response:
+		if err := responseError(response); err != nil {
+			return "", err
+		}
+		var signature string
+		if err := json.Unmarshal(response.Result, &signature); err != nil {
+			return "", fmt.Errorf("walletconnect: invalid signature: %w", err)
+		}
+		return signature, nil
+	case <-ctx.Done():
+		return "", fmt.Errorf("waiting for wallet approval: %w", ctx.Err())
+	case <-w.done:
+		return "", errors.New("walletconnect: closed")
+	}
+}
+
+// Disconnect ends the session and removes it from the store.
+func (w *WalletConnect) Disconnect(ctx context.Context) error {
+	w.mu.Lock()
+	session := w.session
+	w.mu.Unlock()
+	if session == nil {
+		return nil
+	}
+	message, err := w.encode(session.Topic, wcMessage{
+		ID:      wcPayloadID(),
+		JSONRPC: "2.0",
+		Method:  "wc_sessionDelete",
+		Params:  json.RawMessage(`{"code":6000,"message":"User disconnected."}`),
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.relay.Publish(ctx, session.Topic, message, wcTagSessionDelete, 24*time.Hour); err != nil {
+		return err
+	}
+	return w.ended(ctx, session.Topic)
+}
+
+// Close disconnects from the relay. The session stays in the store.
+func (w *WalletConnect) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		err = w.relay.Close()
+	})
+	return err
+}
+
+// request sends a JSON-RPC request to the wallet on topic.
+func (w *WalletConnect) request(ctx context.Context, topic, method string, params interface{}, tag int) (*wcPending, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	msg := wcMessage{ID: wcPayloadID(), JSONRPC: "2.0", Method: method, Params: data}
+	message, err := w.encode(topic, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := &wcPending{topic: topic, response: make(chan wcMessage, 1)}
+	w.mu.Lock()
+	w.pending[msg.ID] = pending
+	w.mu.Unlock()
+	if err := w.relay.Publish(ctx, topic, message, tag, walletConnectTTL); err != nil {
+		w.forget(pending)
+		return nil, fmt.Errorf("publishing %s: %w", method, err)
+	}
+	return pending, nil
+}
+
+// forget stops waiting for the response to pending.
+func (w *WalletConnect) forget(pending *wcPending) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for id, p := range w.pending {
+		if p == pending {
+			delete(w.pending, id)
+		}
+	}
+}
+
+// encode encrypts msg with the key of topic.
+func (w *WalletConnect) encode(topic string, msg wcMessage) (string, error) {
+	w.mu.Lock()
+	key := w.keys[topic]
+	w.mu.Unlock()
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	return wcEncrypt(key, data)
+}
+
+// receive dispatches the messages of the relay until it closes.
+func (w *WalletConnect) receive() {
+	for {
+		var received WalletConnectMessage
+		select {
+		case msg, ok := <-w.relay.Messages():
+			if !ok {
+				return
+			}
+			received = msg
+		case <-w.done:
+			return
+		}
+
+		w.mu.Lock()
+		key := w.keys[received.Topic]
+		w.mu.Unlock()
+		if key == nil {
+			continue
+		}
+		data, err := wcDecrypt(key, received.Message)
+		if err != nil {
+			continue
+		}
+		var msg wcMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		if msg.Method != "" {
+			// Answering publishes through the relay, which must keep reading
+			go w.handle(received.Topic, msg)
+			continue
+		}
+		w.mu.Lock()
+		if pending, ok := w.pending[msg.ID]; ok {
+			delete(w.pending, msg.ID)
+			pending.response <- msg
+		}
+		w.mu.Unlock()
+	}
+}
+
+// handle answers a request of the wallet.
+func (w *WalletConnect) handle(topic string, msg wcMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tag, ok := wcResponseTags[msg.Method]
+	if !ok {
+		return
+	}
+	var err error
+	switch msg.Method {
+	case "wc_sessionSettle":
+		err = w.settle(ctx, topic, msg.Params)
+	case "wc_sessionUpdate", "wc_sessionExtend":
+		err = w.update(ctx, topic, msg.Params)
+	case "wc_sessionDelete", "wc_pairingDelete":
+		err = w.ended(ctx, topic)
+	}
+
+	response := wcMessage{ID: msg.ID, JSONRPC: "2.0", Result: json.RawMessage("true")}
+	if err != nil {
+		response = wcMessage{ID: msg.ID, JSONRPC: "2.0", Error: &wcError{Code: 5000, Message: err.Error()}}
+	}
+	message, err := w.encode(topic, response)
+	if err != nil {
+		return
+	}
+	_ = w.relay.Publish(ctx, topic, message, tag, walletConnectTTL)
+}
+
+// wcSessionParams are the parameters of wc_sessionSettle, wc_sessionUpdate and
+// wc_sessionExtend, which each carry some of them.
+type wcSessionParams struct {
+	Namespaces map[string]wcNamespace `json:"namespaces"`
+	Controller struct {
+		Metadata WalletConnectMetadata `json:"metadata"`
+	} `json:"controller"`
+	Expiry int64 `json:"expiry"`
+}
+
+// settle records the session the wallet settled on topic.
+func (w *WalletConnect) settle(ctx context.Context, topic string, params json.RawMessage) error {
+	var p wcSessionParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	// Only sessions this client proposed, settled once, are accepted
+	if !w.settling[topic] {
+		w.mu.Unlock()
+		return errors.New("no session proposed on topic")
+	}
+	delete(w.settling, topic)
+	session := &wcSession{
+		Topic:    topic,
+		Key:      hex.EncodeToString(w.keys[topic]),
+		Accounts: p.Namespaces["eip155"].Accounts,
+		Expiry:   p.Expiry,
+		Peer:     p.Controller.Metadata,
+	}
+	w.session = session
+	select {
+	case <-w.ready:
+	default:
+		close(w.ready)
+	}
+	w.mu.Unlock()
+	return w.persist(ctx, session)
+}
+
+// update applies a change of accounts or expiry of the session on topic.
+func (w *WalletConnect) update(ctx context.Context, topic string, params json.RawMessage) error {
+	var p wcSessionParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	if w.session == nil || w.session.Topic != topic {
+		w.mu.Unlock()
+		return nil
+	}
+	session := *w.session
+	if namespace, ok := p.Namespaces["eip155"]; ok {
+		session.Accounts = namespace.Accounts
+	}
+	if p.Expiry > 0 {
+		session.Expiry = p.Expiry
+	}
+	w.session = &session
+	w.mu.Unlock()
+	return w.persist(ctx, &session)
+}
+
+// ended forgets the session on topic, failing the requests waiting for it.
+func (w *WalletConnect) ended(ctx context.Context, topic string) error {
+	w.mu.Lock()
+	if w.session == nil || w.session.Topic != topic {
+		w.mu.Unlock()
+		return nil
+	}
+	w.session = nil
+	w.ready = make(chan struct{})
+	for id, pending := range w.pending {
+		if pending.topic == topic {
+			delete(w.pending, id)
+			pending.response <- wcMessage{ID: id, Error: &wcError{Code: 6000, Message: "session ended"}}
+		}
+	}
+	w.mu.Unlock()
+	if w.store == nil {
+		return nil
+	}
+	return w.store.Delete(ctx, walletConnectSessionKey)
+}
+
+// persist stores session until it expires.
+func (w *WalletConnect) persist(ctx context.Context, session *wcSession) error {
+	if w.store == nil {
+		return nil
+	}
+	ttl := time.Until(time.Unix(session.Expiry, 0))
+	if ttl <= 0 {
+		return w.store.Delete(ctx, walletConnectSessionKey)
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return w.store.Set(ctx, walletConnectSessionKey, data, ttl)
+}
+
+// responseError returns the error of a wallet response, wrapping
+// v2.ErrSignatureRejected when the user rejected the request.
+func responseError(response wcMessage) error {
+	if response.Error == nil {
+		return nil
+	}
+	if response.Error.Code == wcUserRejected || response.Error.Code == eip1193UserRejected {
+		return fmt.Errorf("%w: %s", v2.ErrSignatureRejected, response.Error.Message)
+	}
+	return fmt.Errorf("walletconnect: %s (code %d)", response.Error.Message, response.Error.Code)
+}
+
+// wcTopic returns the topic of messages encrypted with key.
+func wcTopic(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:])
+}
+
+// wcSessionKey derives the session key shared with the wallet's X25519 public
+// key.
+func wcSessionKey(private *ecdh.PrivateKey, peerPublicKey string) ([]byte, error) {
+	peer, err := hex.DecodeString(peerPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("walletconnect: invalid responder key: %w", err)
+	}
+	public, err := ecdh.X25519().NewPublicKey(peer)
+	if err != nil {
+		return nil, fmt.Errorf("walletconnect: invalid responder key: %w", err)
+	}
+	shared, err := private.ECDH(public)
+	if err != nil {
+		return nil, err
+	}
+	return hkdf.Key(sha256.New, shared, nil, "", 32)
+}
+
+// wcEncrypt seals data in a type 0 envelope: the type byte, the 12-byte
+// nonce and the ChaCha20-Poly1305 ciphertext, base64 encoded.
+func wcEncrypt(key, data []byte) (string, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return "", err
+	}
+	envelope := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(envelope[1:]); err != nil {
+		return "", err
+	}
+	envelope = aead.Seal(envelope, envelope[1:], data, nil)
+	return base64.StdEncoding.EncodeToString(envelope), nil
+}
+
+// wcDecrypt opens a type 0 envelope.
+func wcDecrypt(key []byte, message string) ([]byte, error) {
+	envelope, err := base64.StdEncoding.DecodeString(message)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(envelope) < 1+aead.NonceSize() || envelope[0] != 0 {
+		return nil, errors.New("walletconnect: unsupported envelope")
+	}
+	nonce := envelope[1 : 1+aead.NonceSize()]
+	return aead.Open(nil, nonce, envelope[1+aead.NonceSize():], nil)
+}
+
+// wcPayloadID returns a JSON-RPC ID as WalletConnect clients make them: the
+// time in milliseconds followed by three random digits.
+func wcPayloadID() int64 {
+	var random [2]byte
+	_, _ = rand.Read(random[:])
+	return time.Now().UnixMilli()*1000 + int64(binary.BigEndian.Uint16(random[:])%1000)
+}
@@ -0,0 +1,232 @@
+package evm
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mr-tron/base58"
+)
+
+// errRelayClosed is returned by calls to a closed relay.
+var errRelayClosed = errors.New("walletconnect: relay closed")
+
+// wsRelay is a WalletConnectRelay speaking the relay's JSON-RPC protocol over
+// a WebSocket.
+type wsRelay struct {
+	conn     *websocket.Conn
+	writeMu  sync.Mutex
+	messages chan WalletConnectMessage
+
+	mu      sync.Mutex
+	pending map[int64]chan wsResponse
+	err     error
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// wsResponse is the relay's answer to a call.
+type wsResponse struct {
+	Result json.RawMessage
+	Error  *wcError
+}
+
+// DialWalletConnectRelay connects to the relay at relayURL, like
+// DefaultWalletConnectRelayURL, for a WalletConnect Cloud project. It
+// authenticates with a fresh client key.
+func DialWalletConnectRelay(ctx context.Context, relayURL string, projectID string) (WalletConnectRelay, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := relayAuthToken(key, relayURL, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(relayURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid relay URL: %w", err)
+	}
+	query := u.Query()
+	query.Set("auth", auth)
+	query.Set("projectId", projectID)
+	query.Set("ua", "wc-2/x402-go")
+	u.RawQuery = query.Encode()
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to relay: %w", err)
+	}
+	r := &wsRelay{
+		conn:     conn,
+		messages: make(chan WalletConnectMessage, 16),
+		pending:  make(map[int64]chan wsResponse),
+		done:     make(chan struct{}),
+	}
+	go r.read()
+	return r, nil
+}
+
+// Subscribe implements WalletConnectRelay.
+func (r *wsRelay) Subscribe(ctx context.Context, topic string) error {
+	return r.call(ctx, "irn_subscribe", map[string]interface{}{"topic": topic})
+}
+
+// Publish implements WalletConnectRelay.
+func (r *wsRelay) Publish(ctx context.Context, topic, message string, tag int, ttl time.Duration) error {
+	return r.call(ctx, "irn_publish", map[string]interface{}{
+		"topic":   topic,
+		"message": message,
+		"ttl":     int(ttl / time.Second),
+		"tag":     tag,
+		"prompt":  tag == wcTagSessionPropose || tag == wcTagSessionRequest,
+	})
+}
+
+// Messages implements WalletConnectRelay.
+func (r *wsRelay) Messages() <-chan WalletConnectMessage {
+	return r.messages
+}
+
+// Close implements WalletConnectRelay.
+func (r *wsRelay) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.done)
+		err = r.conn.Close()
+	})
+	return err
+}
+
+// call sends a request to the relay and waits for its answer.
+func (r *wsRelay) call(ctx context.Context, method string, params interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	id := wcPayloadID()
+	response := make(chan wsResponse, 1)
+	r.mu.Lock()
+	if r.err != nil {
+		r.mu.Unlock()
+		return r.err
+	}
+	select {
+	case <-r.done:
+		r.mu.Unlock()
+		return errRelayClosed
+	default:
+	}
+	r.pending[id] = response
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.pending, id)
+		r.mu.Unlock()
+	}()
+
+	if err := r.write(wcMessage{ID: id, JSONRPC: "2.0", Method: method, Params: data}); err != nil {
+		return err
+	}
+	select {
+	case resp := <-response:
+		if resp.Error != nil {
+			return fmt.Errorf("relay %s failed: %s (code %d)", method, resp.Error.Message, resp.Error.Code)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.done:
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.err != nil {
+			return r.err
+		}
+		return errRelayClosed
+	}
+}
+
+// write sends msg to the relay.
+func (r *wsRelay) write(msg wcMessage) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+	return r.conn.WriteJSON(msg)
+}
+
+// read dispatches the relay's messages until the connection fails.
+func (r *wsRelay) read() {
+	defer close(r.messages)
+	for {
+		var msg wcMessage
+		if err := r.conn.ReadJSON(&msg); err != nil {
+			r.mu.Lock()
+			r.err = fmt.Errorf("relay connection closed: %w", err)
+			r.mu.Unlock()
+			r.closeOnce.Do(func() { close(r.done) })
+			return
+		}
+
+		if msg.Method == "" {
+			r.mu.Lock()
+			if response, ok := r.pending[msg.ID]; ok {
+				response <- wsResponse{Result: msg.Result, Error: msg.Error}
+				delete(r.pending, msg.ID)
+			}
+			r.mu.Unlock()
+			continue
+		}
+		if msg.Method != "irn_subscription" {
+			continue
+		}
+		var params struct {
+			Data struct {
+				Topic   string `json:"topic"`
+				Message string `json:"message"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			continue
+		}
+		// The relay redelivers messages until they are acknowledged
+		_ = r.write(wcMessage{ID: msg.ID, JSONRPC: "2.0", Result: json.RawMessage("true")})
+		select {
+		case r.messages <- WalletConnectMessage{Topic: params.Data.Topic, Message: params.Data.Message}:
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// relayAuthToken returns the EdDSA JWT authenticating a client to the relay
+// at aud, issued by the did:key of the client key.
+func relayAuthToken(key ed25519.PrivateKey, aud string, now time.Time) (string, error) {
+	// did:key multicodec prefix of Ed25519 public keys
+	public := append([]byte{0xed, 0x01}, key.Public().(ed25519.PublicKey)...)
+	subject := make([]byte, 32)
+	if _, err := rand.Read(subject); err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": "did:key:z" + base58.Encode(public),
+		"sub": hex.EncodeToString(subject),
+		"aud": aud,
+		"iat": now.Unix(),
+		"exp": now.Add(24 * time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	encoding := base64.RawURLEncoding
+	input := encoding.EncodeToString([]byte(`{"alg":"EdDSA","typ":"JWT"}`)) + "." + encoding.EncodeToString(claims)
+	return input + "." + encoding.EncodeToString(ed25519.Sign(key, []byte(input))), nil
+}
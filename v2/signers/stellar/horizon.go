@@ -0,0 +1,67 @@
+package stellar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	ns "github.com/mark3labs/x402-go/v2/namespaces/stellar"
+)
+
+// Public Horizon servers.
+const (
+	HorizonPubnet  = "https://horizon.stellar.org"
+	HorizonTestnet = "https://horizon-testnet.stellar.org"
+)
+
+// DefaultHorizonURL returns the public Horizon server of a Stellar network.
+func DefaultHorizonURL(network string) string {
+	if network == ns.NetworkTestnet {
+		return HorizonTestnet
+	}
+	return HorizonPubnet
+}
+
+// HorizonClient loads account sequence numbers from a Horizon server.
+type HorizonClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewHorizonClient creates a client for the Horizon server at baseURL.
+func NewHorizonClient(baseURL string) *HorizonClient {
+	return &HorizonClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+}
+
+// SequenceNumber returns the current sequence number of an account.
+func (c *HorizonClient) SequenceNumber(ctx context.Context, accountID string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/accounts/"+accountID, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("horizon returned status %d for account %s", resp.StatusCode, accountID)
+	}
+
+	var account struct {
+		Sequence string `json:"sequence"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&account); err != nil {
+		return 0, fmt.Errorf("decode account: %w", err)
+	}
+	return strconv.ParseInt(account.Sequence, 10, 64)
+}
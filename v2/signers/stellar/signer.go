@@ -0,0 +1,323 @@
+// Package stellar provides a Stellar signer for the x402 v2 protocol.
+//
+// The signer builds a payment transaction from the payer's account to PayTo,
+// signs it, and sends the base64 XDR envelope as a v2.StellarPayload. The
+// facilitator verifies and submits the envelope, optionally sponsoring the fee
+// with a fee-bump transaction. Anchors that route deposits by memo can set
+// Extra["memo"] and Extra["memoType"] ("text", "id", or "hash") in the
+// requirements, matching the SEP-31 memo conventions.
+package stellar
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/keymem"
+	"github.com/mark3labs/x402-go/v2/internal/stellar"
+	ns "github.com/mark3labs/x402-go/v2/namespaces/stellar"
+)
+
+// DefaultBaseFee is the default fee per operation in stroops.
+const DefaultBaseFee = 100
+
+// defaultValidity bounds transactions whose requirements set no timeout.
+const defaultValidity = 5 * time.Minute
+
+// AccountLoader loads the current sequence number of an account.
+// This allows for dependency injection and easier testing.
+type AccountLoader interface {
+	SequenceNumber(ctx context.Context, accountID string) (int64, error)
+}
+
+// Signer implements the v2.Signer interface for Stellar.
+type Signer struct {
+	key        *keymem.Key
+	publicKey  [32]byte
+	address    string
+	network    string // CAIP-2 format (e.g., "stellar:pubnet")
+	passphrase string
+	tokens     []v2.TokenConfig
+	priority   int
+	maxAmount  *big.Int
+	accounts   AccountLoader
+	baseFee    uint32
+	clock      v2.Clock
+}
+
+// Option configures a Signer.
+type Option func(*Signer) error
+
+// NewSigner creates a new Stellar signer from a secret seed ("S...").
+func NewSigner(network string, secretSeed string, tokens []v2.TokenConfig, opts ...Option) (*Signer, error) {
+	seed, err := stellar.DecodeStrKey(stellar.VersionSeed, secretSeed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", v2.ErrInvalidKey, err)
+	}
+	defer keymem.Wipe(seed)
+	key := ed25519.NewKeyFromSeed(seed)
+	defer keymem.Wipe(key)
+	return NewSignerFromKey(network, key, tokens, opts...)
+}
+
+// NewSignerFromKey creates a new Stellar signer from an ed25519 private key. The
+// signer keeps its own copy in locked memory; callers should wipe key when done with it.
+func NewSignerFromKey(network string, key ed25519.PrivateKey, tokens []v2.TokenConfig, opts ...Option) (*Signer, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, v2.ErrInvalidKey
+	}
+
+	passphrase, err := ns.Passphrase(network)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, v2.ErrInvalidToken
+	}
+	for _, token := range tokens {
+		if _, err := stellar.ParseAsset(token.Address); err != nil {
+			return nil, fmt.Errorf("%w: %v", v2.ErrInvalidToken, err)
+		}
+	}
+
+	s := &Signer{
+		key:        keymem.Copy(key),
+		network:    network,
+		passphrase: passphrase,
+		tokens:     tokens,
+		baseFee:    DefaultBaseFee,
+		clock:      v2.SystemClock,
+	}
+	copy(s.publicKey[:], key.Public().(ed25519.PublicKey))
+	s.address = stellar.EncodeStrKey(stellar.VersionAccountID, s.publicKey[:])
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+
+	if s.accounts == nil {
+		s.accounts = NewHorizonClient(DefaultHorizonURL(network))
+	}
+
+	return s, nil
+}
+
+// Close wipes the signer's private key. Later Sign calls fail with v2.ErrSignerClosed.
+func (s *Signer) Close() error {
+	return s.key.Close()
+}
+
+// WithMaxAmount sets the maximum amount per payment call.
+func WithMaxAmount(amount *big.Int) Option {
+	return func(s *Signer) error {
+		s.maxAmount = amount
+		return nil
+	}
+}
+
+// WithPriority sets the signer priority.
+func WithPriority(priority int) Option {
+	return func(s *Signer) error {
+		s.priority = priority
+		return nil
+	}
+}
+
+// WithHorizonURL loads sequence numbers from a custom Horizon server.
+func WithHorizonURL(url string) Option {
+	return func(s *Signer) error {
+		s.accounts = NewHorizonClient(url)
+		return nil
+	}
+}
+
+// WithAccountLoader sets a custom account loader.
+func WithAccountLoader(loader AccountLoader) Option {
+	return func(s *Signer) error {
+		s.accounts = loader
+		return nil
+	}
+}
+
+// WithBaseFee sets the fee per operation in stroops (default: 100).
+func WithBaseFee(stroops uint32) Option {
+	return func(s *Signer) error {
+		if stroops == 0 {
+			return fmt.Errorf("base fee must be positive")
+		}
+		s.baseFee = stroops
+		return nil
+	}
+}
+
+// WithClock sets the clock used for transaction time bounds (default: v2.SystemClock).
+func WithClock(clock v2.Clock) Option {
+	return func(s *Signer) error {
+		s.clock = v2.ClockOrSystem(clock)
+		return nil
+	}
+}
+
+// Network returns the CAIP-2 network identifier.
+func (s *Signer) Network() string {
+	return s.network
+}
+
+// Scheme returns the payment scheme identifier.
+func (s *Signer) Scheme() string {
+	return v2.SchemeExact
+}
+
+// CanSign checks if this signer can satisfy the given payment requirements.
+func (s *Signer) CanSign(requirements *v2.PaymentRequirements) bool {
+	if requirements.Scheme != v2.SchemeExact || requirements.Network != s.network {
+		return false
+	}
+
+	// Asset codes and issuers are case-sensitive
+	for _, token := range s.tokens {
+		if token.Address == requirements.Asset {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign creates a signed PaymentPayload for the given requirements.
+func (s *Signer) Sign(requirements *v2.PaymentRequirements) (*v2.PaymentPayload, error) {
+	if !s.CanSign(requirements) {
+		return nil, v2.ErrNoValidSigner
+	}
+
+	amount := new(big.Int)
+	if _, ok := amount.SetString(requirements.Amount, 10); !ok || amount.Sign() <= 0 {
+		return nil, v2.ErrInvalidAmount
+	}
+	if s.maxAmount != nil && amount.Cmp(s.maxAmount) > 0 {
+		return nil, v2.ErrAmountExceeded
+	}
+	if !amount.IsInt64() {
+		return nil, v2.ErrAmountExceeded
+	}
+
+	asset, err := stellar.ParseAsset(requirements.Asset)
+	if err != nil {
+		return nil, err
+	}
+	destination, err := stellar.DecodeAccountID(requirements.PayTo)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address: %w", err)
+	}
+	memo, err := memoFromRequirements(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), v2.DefaultTimeouts.VerifyTimeout)
+	defer cancel()
+	sequence, err := s.accounts.SequenceNumber(ctx, s.address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: load sequence number: %v", v2.ErrNetworkError, err)
+	}
+	if sequence == math.MaxInt64 {
+		return nil, fmt.Errorf("%w: sequence number exhausted", v2.ErrSigningFailed)
+	}
+
+	validity := time.Duration(requirements.MaxTimeoutSeconds) * time.Second
+	if validity <= 0 {
+		validity = defaultValidity
+	}
+
+	tx := stellar.Payment{
+		Source:      s.publicKey,
+		Destination: destination,
+		Asset:       asset,
+		Amount:      amount.Int64(),
+		Fee:         s.baseFee,
+		Sequence:    sequence + 1,
+		MaxTime:     uint64(s.clock.Now().Add(validity).Unix()),
+		Memo:        memo,
+	}
+	txXDR, err := tx.MarshalXDR()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", v2.ErrSigningFailed, err)
+	}
+	envelope, err := keymem.WithBytes(s.key, func(key []byte) ([]byte, error) {
+		return stellar.SignEnvelope(s.passphrase, txXDR, ed25519.PrivateKey(key)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v2.PaymentPayload{
+		X402Version: v2.X402Version,
+		Accepted:    *requirements,
+		Payload: v2.StellarPayload{
+			Transaction: base64.StdEncoding.EncodeToString(envelope),
+		},
+	}, nil
+}
+
+// GetPriority returns the signer's priority level.
+func (s *Signer) GetPriority() int {
+	return s.priority
+}
+
+// GetTokens returns the list of tokens supported by this signer.
+func (s *Signer) GetTokens() []v2.TokenConfig {
+	return s.tokens
+}
+
+// GetMaxAmount returns the per-call spending limit, or nil if no limit is set.
+func (s *Signer) GetMaxAmount() *big.Int {
+	return s.maxAmount
+}
+
+// Address returns the signer's account ID ("G...").
+func (s *Signer) Address() string {
+	return s.address
+}
+
+// memoFromRequirements builds the transaction memo from Extra["memo"] and Extra["memoType"].
+func memoFromRequirements(requirements *v2.PaymentRequirements) (stellar.Memo, error) {
+	value, ok := requirements.Extra["memo"].(string)
+	if !ok || value == "" {
+		return stellar.Memo{}, nil
+	}
+
+	memoType, _ := requirements.Extra["memoType"].(string)
+	switch memoType {
+	case "", "text":
+		if len(value) > stellar.MaxMemoTextLength {
+			return stellar.Memo{}, fmt.Errorf("%w: memo exceeds %d bytes", v2.ErrInvalidRequirements, stellar.MaxMemoTextLength)
+		}
+		return stellar.Memo{Type: stellar.MemoText, Text: value}, nil
+	case "id":
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return stellar.Memo{}, fmt.Errorf("%w: invalid memo id %q", v2.ErrInvalidRequirements, value)
+		}
+		return stellar.Memo{Type: stellar.MemoID, ID: id}, nil
+	case "hash":
+		hash, err := hex.DecodeString(value)
+		if err != nil || len(hash) != 32 {
+			return stellar.Memo{}, fmt.Errorf("%w: memo hash must be 32 hex-encoded bytes", v2.ErrInvalidRequirements)
+		}
+		memo := stellar.Memo{Type: stellar.MemoHash}
+		copy(memo.Hash[:], hash)
+		return memo, nil
+	default:
+		return stellar.Memo{}, fmt.Errorf("%w: unsupported memo type %q", v2.ErrInvalidRequirements, memoType)
+	}
+}
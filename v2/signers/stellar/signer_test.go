@@ -0,0 +1,191 @@
+package stellar
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/stellar"
+	ns "github.com/mark3labs/x402-go/v2/namespaces/stellar"
+	"github.com/mark3labs/x402-go/v2/validation"
+)
+
+type staticLoader struct {
+	sequence int64
+	err      error
+}
+
+func (l staticLoader) SequenceNumber(ctx context.Context, accountID string) (int64, error) {
+	return l.sequence, l.err
+}
+
+func newTestSigner(t *testing.T, opts ...Option) (*Signer, ed25519.PublicKey) {
+	t.Helper()
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	seed := stellar.EncodeStrKey(stellar.VersionSeed, privateKey.Seed())
+	opts = append([]Option{WithAccountLoader(staticLoader{sequence: 41})}, opts...)
+	signer, err := NewSigner(ns.NetworkTestnet, seed, []v2.TokenConfig{v2.NewUSDCTokenConfig(ns.Testnet, 1)}, opts...)
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+	return signer, publicKey
+}
+
+func testRequirements(payTo string) *v2.PaymentRequirements {
+	return &v2.PaymentRequirements{
+		Scheme:            v2.SchemeExact,
+		Network:           ns.NetworkTestnet,
+		Amount:            "10000000",
+		Asset:             ns.Testnet.USDCAddress,
+		PayTo:             payTo,
+		MaxTimeoutSeconds: 60,
+	}
+}
+
+func TestNewSigner(t *testing.T) {
+	tokens := []v2.TokenConfig{v2.NewUSDCTokenConfig(ns.Testnet, 1)}
+	tests := []struct {
+		name    string
+		network string
+		seed    string
+		tokens  []v2.TokenConfig
+		wantErr error
+	}{
+		{name: "invalid seed", network: ns.NetworkTestnet, seed: "SBAD", tokens: tokens, wantErr: v2.ErrInvalidKey},
+		{name: "EVM network", network: v2.NetworkBase, seed: stellar.EncodeStrKey(stellar.VersionSeed, make([]byte, 32)), tokens: tokens, wantErr: v2.ErrInvalidNetwork},
+		{name: "no tokens", network: ns.NetworkTestnet, seed: stellar.EncodeStrKey(stellar.VersionSeed, make([]byte, 32)), wantErr: v2.ErrInvalidToken},
+		{name: "bad asset", network: ns.NetworkTestnet, seed: stellar.EncodeStrKey(stellar.VersionSeed, make([]byte, 32)), tokens: []v2.TokenConfig{{Address: "0xabc"}}, wantErr: v2.ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSigner(tt.network, tt.seed, tt.tokens); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	signer, publicKey := newTestSigner(t)
+	if signer.Address() != stellar.EncodeStrKey(stellar.VersionAccountID, publicKey) {
+		t.Errorf("unexpected address %s", signer.Address())
+	}
+	if signer.GetTokens()[0].Decimals != 7 {
+		t.Errorf("expected 7 decimals for Stellar USDC, got %d", signer.GetTokens()[0].Decimals)
+	}
+}
+
+func TestSigner_Sign(t *testing.T) {
+	clock := v2.NewFakeClock(time.Unix(1700000000, 0))
+	signer, publicKey := newTestSigner(t, WithClock(clock), WithMaxAmount(big.NewInt(50000000)))
+	payTo := stellar.EncodeStrKey(stellar.VersionAccountID, make([]byte, 32))
+
+	requirements := testRequirements(payTo)
+	if !signer.CanSign(requirements) {
+		t.Fatal("expected signer to accept requirements")
+	}
+	payment, err := signer.Sign(requirements)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	// Validation accepts the Stellar requirements once the namespace is registered
+	if err := validation.ValidatePaymentRequirements(payment.Accepted); err != nil {
+		t.Errorf("expected valid requirements, got %v", err)
+	}
+
+	envelope, err := base64.StdEncoding.DecodeString(payment.Payload.(v2.StellarPayload).Transaction)
+	if err != nil {
+		t.Fatalf("invalid base64 envelope: %v", err)
+	}
+	txXDR := envelope[4 : len(envelope)-76]
+
+	var source [32]byte
+	copy(source[:], publicKey)
+	destination, _ := stellar.DecodeAccountID(payTo)
+	asset, _ := stellar.ParseAsset(requirements.Asset)
+	expected := stellar.Payment{
+		Source:      source,
+		Destination: destination,
+		Asset:       asset,
+		Amount:      10000000,
+		Fee:         DefaultBaseFee,
+		Sequence:    42,
+		MaxTime:     1700000060,
+	}
+	expectedXDR, _ := expected.MarshalXDR()
+	if string(txXDR) != string(expectedXDR) {
+		t.Error("envelope does not contain the expected transaction")
+	}
+
+	hash := stellar.Hash(stellar.PassphraseTestnet, txXDR)
+	if !ed25519.Verify(publicKey, hash[:], envelope[len(envelope)-64:]) {
+		t.Error("expected envelope signature to verify")
+	}
+
+	requirements.Amount = "60000000"
+	if _, err := signer.Sign(requirements); !errors.Is(err, v2.ErrAmountExceeded) {
+		t.Errorf("expected ErrAmountExceeded, got %v", err)
+	}
+
+	requirements.Asset = ns.Pubnet.USDCAddress
+	if signer.CanSign(requirements) {
+		t.Error("expected signer to reject unknown asset")
+	}
+}
+
+func TestSigner_Memo(t *testing.T) {
+	signer, _ := newTestSigner(t)
+	payTo := stellar.EncodeStrKey(stellar.VersionAccountID, make([]byte, 32))
+
+	tests := []struct {
+		name    string
+		extra   map[string]interface{}
+		wantErr bool
+	}{
+		{name: "text", extra: map[string]interface{}{"memo": "invoice-7"}},
+		{name: "id", extra: map[string]interface{}{"memo": "123456", "memoType": "id"}},
+		{name: "hash", extra: map[string]interface{}{"memo": "aa00000000000000000000000000000000000000000000000000000000000000", "memoType": "hash"}},
+		{name: "text too long", extra: map[string]interface{}{"memo": "this memo is longer than 28 bytes"}, wantErr: true},
+		{name: "bad id", extra: map[string]interface{}{"memo": "abc", "memoType": "id"}, wantErr: true},
+		{name: "unknown type", extra: map[string]interface{}{"memo": "x", "memoType": "return"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requirements := testRequirements(payTo)
+			requirements.Extra = tt.extra
+			_, err := signer.Sign(requirements)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Sign error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, v2.ErrInvalidRequirements) {
+				t.Errorf("expected ErrInvalidRequirements, got %v", err)
+			}
+		})
+	}
+}
+
+func TestHorizonClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/GABC" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"GABC","sequence":"103420918407103888"}`))
+	}))
+	defer server.Close()
+
+	client := NewHorizonClient(server.URL + "/")
+	sequence, err := client.SequenceNumber(context.Background(), "GABC")
+	if err != nil || sequence != 103420918407103888 {
+		t.Errorf("expected sequence, got %d, %v", sequence, err)
+	}
+	if _, err := client.SequenceNumber(context.Background(), "GXYZ"); err == nil {
+		t.Error("expected error for unknown account")
+	}
+}
@@ -0,0 +1,63 @@
+package svm
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	solutil "github.com/mark3labs/x402-go/v2/internal/solana"
+)
+
+// BalanceClient is the RPC method used by Signer.Balance.
+// *rpc.Client satisfies it.
+type BalanceClient interface {
+	GetTokenAccountBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetTokenAccountBalanceResult, error)
+}
+
+var _ v2.BalanceReporter = (*Signer)(nil)
+
+// Balance returns the signer's balance of the SPL token mint asset in atomic
+// units, implementing v2.BalanceReporter. It reads the signer's associated
+// token account through the client set with WithRPCClient if that client
+// implements BalanceClient, and through the network's default endpoint
+// otherwise. A missing token account has a zero balance.
+func (s *Signer) Balance(ctx context.Context, asset string) (*big.Int, error) {
+	mint, err := solana.PublicKeyFromBase58(asset)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid mint %s", v2.ErrInvalidToken, asset)
+	}
+	account, err := solutil.DeriveAssociatedTokenAddress(s.publicKey, mint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive token account: %w", err)
+	}
+
+	client, ok := s.rpcClient.(BalanceClient)
+	if !ok {
+		rpcURL, err := solutil.GetRPCURL(s.network)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", v2.ErrBalanceUnavailable, err)
+		}
+		client = rpc.New(rpcURL)
+	}
+
+	result, err := client.GetTokenAccountBalance(ctx, account, rpc.CommitmentConfirmed)
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find account") {
+			return new(big.Int), nil
+		}
+		return nil, fmt.Errorf("%w: failed to get token account balance: %v", v2.ErrNetworkError, err)
+	}
+	if result == nil || result.Value == nil {
+		return nil, fmt.Errorf("%w: empty token account balance", v2.ErrBalanceUnavailable)
+	}
+	balance, ok := new(big.Int).SetString(result.Value.Amount, 10)
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid token amount %q", v2.ErrBalanceUnavailable, result.Value.Amount)
+	}
+	return balance, nil
+}
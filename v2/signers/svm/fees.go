@@ -0,0 +1,89 @@
+package svm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// PriorityFeeEstimator estimates the compute unit price for a payment transaction.
+type PriorityFeeEstimator interface {
+	// EstimateComputeUnitPrice returns a price in micro-lamports per compute unit
+	// for a transaction that writes to the given accounts.
+	EstimateComputeUnitPrice(ctx context.Context, accounts []solana.PublicKey) (uint64, error)
+}
+
+// PrioritizationFeeClient is the RPC method used by RecentFeeEstimator.
+// *rpc.Client satisfies it.
+type PrioritizationFeeClient interface {
+	GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error)
+}
+
+// RecentFeeEstimator estimates priority fees from the getRecentPrioritizationFees RPC.
+// It takes a percentile of the fees paid by recently landed transactions touching the
+// same accounts, clamped to [Min, Max], so payments land during congestion without
+// overpaying when the network is idle.
+type RecentFeeEstimator struct {
+	// Client is the RPC client used to fetch recent fees.
+	Client PrioritizationFeeClient
+
+	// Percentile is the percentile of recent fees to use, from 0 to 100 (default: 75).
+	Percentile int
+
+	// Min is the minimum price in micro-lamports per compute unit.
+	Min uint64
+
+	// Max caps the price in micro-lamports per compute unit. Zero means no cap.
+	// Facilitators may reject transactions above their own cap, so set this accordingly.
+	Max uint64
+}
+
+// NewRecentFeeEstimator creates a RecentFeeEstimator using the 75th percentile,
+// with no minimum and the given maximum price in micro-lamports per compute unit.
+func NewRecentFeeEstimator(client PrioritizationFeeClient, max uint64) *RecentFeeEstimator {
+	return &RecentFeeEstimator{
+		Client:     client,
+		Percentile: 75,
+		Max:        max,
+	}
+}
+
+// EstimateComputeUnitPrice implements PriorityFeeEstimator.
+func (e *RecentFeeEstimator) EstimateComputeUnitPrice(ctx context.Context, accounts []solana.PublicKey) (uint64, error) {
+	results, err := e.Client.GetRecentPrioritizationFees(ctx, accounts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get recent prioritization fees: %w", err)
+	}
+
+	var price uint64
+	if len(results) > 0 {
+		fees := make([]uint64, len(results))
+		for i, result := range results {
+			fees[i] = result.PrioritizationFee
+		}
+		sort.Slice(fees, func(i, j int) bool { return fees[i] < fees[j] })
+
+		percentile := e.Percentile
+		if percentile <= 0 || percentile > 100 {
+			percentile = 75
+		}
+		price = fees[(len(fees)-1)*percentile/100]
+	}
+
+	if price < e.Min {
+		price = e.Min
+	}
+	if e.Max > 0 && price > e.Max {
+		price = e.Max
+	}
+	return price, nil
+}
+
+// computeBudget holds the ComputeBudget instruction parameters for a transaction.
+type computeBudget struct {
+	units uint32
+	price uint64
+}
@@ -0,0 +1,88 @@
+package svm
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	solutil "github.com/mark3labs/x402-go/v2/internal/solana"
+)
+
+// PaymentReferencePrefix prefixes memos produced by PaymentReferenceMemo.
+const PaymentReferencePrefix = "x402:"
+
+// MemoFunc returns the memo to attach to the payment transaction for requirements.
+// Returning an empty string omits the memo.
+type MemoFunc func(requirements *v2.PaymentRequirements) (string, error)
+
+// WithMemo adds an SPL Memo instruction produced by fn to every payment
+// transaction, after the transfer. The facilitator must accept transactions
+// with a trailing memo instruction.
+func WithMemo(fn MemoFunc) Option {
+	return func(s *Signer) error {
+		s.memo = fn
+		return nil
+	}
+}
+
+// PaymentReference derives an on-chain payment reference from a resource URL and nonce:
+// "x402:" followed by the hex-encoded first 16 bytes of SHA-256(resourceURL || nonce).
+func PaymentReference(resourceURL string, nonce []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(resourceURL))
+	hash.Write(nonce)
+	return PaymentReferencePrefix + hex.EncodeToString(hash.Sum(nil)[:16])
+}
+
+// PaymentReferenceMemo returns a MemoFunc that tags each payment for resourceURL
+// with a PaymentReference over a fresh random nonce. If the server supplies a
+// "paymentReference" string in the requirement's Extra, it is used instead, so
+// the server can match the transfer to the request it issued.
+func PaymentReferenceMemo(resourceURL string) MemoFunc {
+	return func(requirements *v2.PaymentRequirements) (string, error) {
+		if reference, ok := requirements.Extra["paymentReference"].(string); ok && reference != "" {
+			return reference, nil
+		}
+
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return "", fmt.Errorf("failed to generate memo nonce: %w", err)
+		}
+		return PaymentReference(resourceURL, nonce), nil
+	}
+}
+
+// ExtractMemo returns the SPL Memo carried by a Solana payment payload.
+// Servers use it to reconcile which request a transfer paid for.
+// Returns false if the payment has no memo.
+func ExtractMemo(payment v2.PaymentPayload) (string, bool, error) {
+	var svmPayload v2.SVMPayload
+	switch p := payment.Payload.(type) {
+	case v2.SVMPayload:
+		svmPayload = p
+	default:
+		data, err := json.Marshal(payment.Payload)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		if err := json.Unmarshal(data, &svmPayload); err != nil {
+			return "", false, fmt.Errorf("failed to unmarshal SVM payload: %w", err)
+		}
+	}
+	if svmPayload.Transaction == "" {
+		return "", false, fmt.Errorf("payload has no transaction")
+	}
+
+	var tx solana.Transaction
+	if err := tx.UnmarshalBase64(svmPayload.Transaction); err != nil {
+		return "", false, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+
+	memo, ok := solutil.ExtractMemo(&tx)
+	return memo, ok, nil
+}
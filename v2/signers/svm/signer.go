@@ -13,6 +13,7 @@ import (
 	"github.com/gagliardetto/solana-go/rpc"
 
 	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/keymem"
 	solutil "github.com/mark3labs/x402-go/v2/internal/solana"
 )
 
@@ -24,29 +25,39 @@ type RPCClient interface {
 
 // Signer implements the v2.Signer interface for Solana (SVM).
 type Signer struct {
-	privateKey solana.PrivateKey
-	publicKey  solana.PublicKey
-	network    string // CAIP-2 format (e.g., "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp")
-	tokens     []v2.TokenConfig
-	priority   int
-	maxAmount  *big.Int
-	rpcClient  RPCClient
+	key       *keymem.Key
+	publicKey solana.PublicKey
+	network   string // CAIP-2 format (e.g., "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp")
+	tokens    []v2.TokenConfig
+	priority  int
+	maxAmount *big.Int
+	rpcClient RPCClient
+
+	computeUnits     uint32
+	computeUnitPrice uint64
+	feeEstimator     PriorityFeeEstimator
+	memo             MemoFunc
+	feePayers        []solana.PublicKey
+	simulator        SimulationClient
 }
 
 // Option configures a Signer.
 type Option func(*Signer) error
 
 // NewSigner creates a new Solana signer from a base58-encoded private key.
+// The key is held in locked memory until Close is called.
 func NewSigner(network string, privateKeyBase58 string, tokens []v2.TokenConfig, opts ...Option) (*Signer, error) {
 	privateKey, err := solana.PrivateKeyFromBase58(privateKeyBase58)
 	if err != nil {
 		return nil, v2.ErrInvalidKey
 	}
+	defer keymem.Wipe(privateKey)
 
 	return NewSignerFromKey(network, privateKey, tokens, opts...)
 }
 
-// NewSignerFromKey creates a new Solana signer from an existing private key.
+// NewSignerFromKey creates a new Solana signer from an existing private key. The
+// signer keeps its own copy in locked memory; callers should wipe key when done with it.
 func NewSignerFromKey(network string, key solana.PrivateKey, tokens []v2.TokenConfig, opts ...Option) (*Signer, error) {
 	// Validate network is a Solana CAIP-2 identifier
 	networkType, err := v2.ValidateNetwork(network)
@@ -62,15 +73,19 @@ func NewSignerFromKey(network string, key solana.PrivateKey, tokens []v2.TokenCo
 	}
 
 	s := &Signer{
-		privateKey: key,
-		publicKey:  key.PublicKey(),
-		network:    network,
-		tokens:     tokens,
-		priority:   0,
+		key:       keymem.Copy(key),
+		publicKey: key.PublicKey(),
+		network:   network,
+		tokens:    tokens,
+		priority:  0,
+
+		computeUnits:     solutil.DefaultComputeUnits,
+		computeUnitPrice: solutil.DefaultComputeUnitPrice,
 	}
 
 	for _, opt := range opts {
 		if err := opt(s); err != nil {
+			s.Close()
 			return nil, err
 		}
 	}
@@ -85,9 +100,11 @@ func NewSignerFromKeygenFile(network string, path string, tokens []v2.TokenConfi
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", v2.ErrInvalidKey, err)
 	}
+	defer keymem.Wipe(data)
 
 	// Parse JSON array format: [1, 2, 3, ...]
 	var keyBytes []byte
+	defer func() { keymem.Wipe(keyBytes) }()
 	if err := json.Unmarshal(data, &keyBytes); err != nil {
 		return nil, fmt.Errorf("%w: invalid JSON format", v2.ErrInvalidKey)
 	}
@@ -100,6 +117,11 @@ func NewSignerFromKeygenFile(network string, path string, tokens []v2.TokenConfi
 	return NewSignerFromKey(network, privateKey, tokens, opts...)
 }
 
+// Close wipes the signer's private key. Later Sign calls fail with v2.ErrSignerClosed.
+func (s *Signer) Close() error {
+	return s.key.Close()
+}
+
 // WithMaxAmount sets the maximum amount per payment call.
 func WithMaxAmount(amount *big.Int) Option {
 	return func(s *Signer) error {
@@ -125,6 +147,53 @@ func WithRPCClient(client RPCClient) Option {
 	}
 }
 
+// WithComputeUnitLimit sets the compute unit limit of payment transactions
+// (default: 200,000).
+func WithComputeUnitLimit(units uint32) Option {
+	return func(s *Signer) error {
+		if units == 0 {
+			return fmt.Errorf("compute unit limit must be positive")
+		}
+		s.computeUnits = units
+		return nil
+	}
+}
+
+// WithComputeUnitPrice sets a fixed compute unit price in micro-lamports
+// (default: 10,000). It is ignored when a PriorityFeeEstimator is configured,
+// except as the fallback when estimation fails.
+func WithComputeUnitPrice(microlamports uint64) Option {
+	return func(s *Signer) error {
+		s.computeUnitPrice = microlamports
+		return nil
+	}
+}
+
+// WithPriorityFeeEstimator estimates the compute unit price for each payment,
+// e.g. with a RecentFeeEstimator. If estimation fails, the fixed price is used.
+func WithPriorityFeeEstimator(estimator PriorityFeeEstimator) Option {
+	return func(s *Signer) error {
+		s.feeEstimator = estimator
+		return nil
+	}
+}
+
+// WithTrustedFeePayers restricts the fee payers the signer sponsors
+// transactions for. Requirements naming any other feePayer are rejected with
+// v2.ErrUntrustedFeePayer. By default any feePayer is accepted.
+func WithTrustedFeePayers(addresses ...string) Option {
+	return func(s *Signer) error {
+		for _, address := range addresses {
+			feePayer, err := solana.PublicKeyFromBase58(address)
+			if err != nil {
+				return fmt.Errorf("invalid fee payer address %q: %w", address, err)
+			}
+			s.feePayers = append(s.feePayers, feePayer)
+		}
+		return nil
+	}
+}
+
 // Network returns the CAIP-2 network identifier.
 func (s *Signer) Network() string {
 	return s.network
@@ -220,43 +289,50 @@ func (s *Signer) Sign(requirements *v2.PaymentRequirements) (*v2.PaymentPayload,
 	}
 
 	// Extract fee payer from requirements.Extra
-	feePayer, err := extractFeePayer(requirements)
+	feePayer, err := s.feePayer(requirements)
 	if err != nil {
-		return nil, fmt.Errorf("invalid fee payer: %w", err)
-	}
-
-	// Get or create RPC client
-	client := s.rpcClient
-	if client == nil {
-		rpcURL, err := solutil.GetRPCURL(s.network)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get RPC URL: %w", err)
-		}
-		client = rpc.New(rpcURL)
+		return nil, err
 	}
 
 	// Fetch recent blockhash from the network with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), v2.DefaultTimeouts.VerifyTimeout)
 	defer cancel()
-	recent, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	blockhash, err := s.latestBlockhash(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get blockhash: %w", err)
+		return nil, err
+	}
+
+	// Build the optional memo carrying the payment reference
+	var memo string
+	if s.memo != nil {
+		memo, err = s.memo(requirements)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build memo: %w", err)
+		}
 	}
 
 	// Build the partially signed transaction
-	txBase64, err := buildPartiallySignedTransfer(
-		s.privateKey,
-		s.publicKey,
-		mintAddress,
-		recipient,
-		amount.Uint64(),
-		decimals,
-		feePayer,
-		recent.Value.Blockhash,
-	)
+	budget := s.computeBudget(ctx, mintAddress, recipient)
+	txBase64, err := keymem.WithBytes(s.key, func(key []byte) (string, error) {
+		return buildPartiallySignedTransfer(
+			solana.PrivateKey(key),
+			s.publicKey,
+			mintAddress,
+			recipient,
+			amount.Uint64(),
+			decimals,
+			feePayer,
+			blockhash,
+			budget,
+			memo,
+		)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to build transaction: %w", err)
 	}
+	if err := s.simulate(ctx, txBase64); err != nil {
+		return nil, err
+	}
 
 	// Build payment payload
 	payload := &v2.PaymentPayload{
@@ -290,6 +366,65 @@ func (s *Signer) Address() solana.PublicKey {
 	return s.publicKey
 }
 
+// latestBlockhash fetches a recent blockhash using the configured or default RPC client.
+func (s *Signer) latestBlockhash(ctx context.Context) (solana.Hash, error) {
+	client := s.rpcClient
+	if client == nil {
+		rpcURL, err := solutil.GetRPCURL(s.network)
+		if err != nil {
+			return solana.Hash{}, fmt.Errorf("failed to get RPC URL: %w", err)
+		}
+		client = rpc.New(rpcURL)
+	}
+
+	recent, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return solana.Hash{}, fmt.Errorf("failed to get blockhash: %w", err)
+	}
+	return recent.Value.Blockhash, nil
+}
+
+// computeBudget returns the compute budget for a transfer, estimating the price
+// when a PriorityFeeEstimator is configured.
+func (s *Signer) computeBudget(ctx context.Context, mint, recipient solana.PublicKey) computeBudget {
+	budget := computeBudget{units: s.computeUnits, price: s.computeUnitPrice}
+	if s.feeEstimator == nil {
+		return budget
+	}
+
+	sourceATA, err := solutil.DeriveAssociatedTokenAddress(s.publicKey, mint)
+	if err != nil {
+		return budget
+	}
+	destATA, err := solutil.DeriveAssociatedTokenAddress(recipient, mint)
+	if err != nil {
+		return budget
+	}
+
+	if price, err := s.feeEstimator.EstimateComputeUnitPrice(ctx, []solana.PublicKey{sourceATA, destATA}); err == nil {
+		budget.price = price
+	}
+	return budget
+}
+
+// feePayer extracts the feePayer of requirements and checks it against the
+// trusted fee payers.
+func (s *Signer) feePayer(requirements *v2.PaymentRequirements) (solana.PublicKey, error) {
+	feePayer, err := extractFeePayer(requirements)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("invalid fee payer: %w", err)
+	}
+	if len(s.feePayers) == 0 {
+		return feePayer, nil
+	}
+	for _, trusted := range s.feePayers {
+		if trusted.Equals(feePayer) {
+			return feePayer, nil
+		}
+	}
+	return solana.PublicKey{}, fmt.Errorf("%w: %s", v2.ErrUntrustedFeePayer, feePayer)
+}
+
 // extractFeePayer extracts the feePayer address from the payment requirements.
 // The feePayer is specified in requirements.Extra["feePayer"] as per the exact_svm spec.
 func extractFeePayer(requirements *v2.PaymentRequirements) (solana.PublicKey, error) {
@@ -321,6 +456,8 @@ func buildPartiallySignedTransfer(
 	decimals uint8,
 	feePayer solana.PublicKey,
 	blockhash solana.Hash,
+	budget computeBudget,
+	memo string,
 ) (string, error) {
 	// Get associated token accounts
 	sourceATA, err := solutil.DeriveAssociatedTokenAddress(clientPublicKey, mint)
@@ -343,9 +480,9 @@ func buildPartiallySignedTransfer(
 	// Build instructions according to exact_svm spec
 	instructions := []solana.Instruction{
 		// Instruction 0: SetComputeUnitLimit
-		solutil.BuildSetComputeUnitLimitInstruction(solutil.DefaultComputeUnits),
+		solutil.BuildSetComputeUnitLimitInstruction(budget.units),
 		// Instruction 1: SetComputeUnitPrice
-		solutil.BuildSetComputeUnitPriceInstruction(solutil.DefaultComputeUnitPrice),
+		solutil.BuildSetComputeUnitPriceInstruction(budget.price),
 		// Instruction 2: Create associated token account (idempotent - won't fail if it exists)
 		// The feePayer sponsors the rent-exempt balance for the destination ATA
 		createATAInstruction,
@@ -353,6 +490,29 @@ func buildPartiallySignedTransfer(
 		solutil.BuildTransferCheckedInstruction(sourceATA, mint, destATA, clientPublicKey, amount, decimals),
 	}
 
+	// Optional instruction 4: Memo with the payment reference
+	if memo != "" {
+		memoInstruction, err := solutil.BuildMemoInstruction(memo)
+		if err != nil {
+			return "", fmt.Errorf("failed to build memo instruction: %w", err)
+		}
+		instructions = append(instructions, memoInstruction)
+	}
+
+	return partiallySign(clientPrivateKey, instructions, blockhash, feePayer)
+}
+
+// partiallySign builds a transaction paid for by feePayer and signs it with the
+// client key only, leaving the fee payer signature for the facilitator.
+// Returns the base64-encoded transaction.
+func partiallySign(
+	clientPrivateKey solana.PrivateKey,
+	instructions []solana.Instruction,
+	blockhash solana.Hash,
+	feePayer solana.PublicKey,
+) (string, error) {
+	clientPublicKey := clientPrivateKey.PublicKey()
+
 	// Create transaction with recent blockhash from the network
 	tx, err := solana.NewTransaction(
 		instructions,
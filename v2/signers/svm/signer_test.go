@@ -2,11 +2,15 @@ package svm
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/gagliardetto/solana-go"
@@ -474,6 +478,50 @@ func TestSign_ValidPayment(t *testing.T) {
 	}
 }
 
+func TestSign_TrustedFeePayers(t *testing.T) {
+	const trusted = "EwWqGE4ZFKLofuestmU4LDdK7XM1N4ALgdZccwYugwGd"
+	tokens := []v2.TokenConfig{
+		{Address: v2.SolanaMainnet.USDCAddress, Symbol: "USDC", Decimals: 6},
+	}
+	signer, err := NewSigner(v2.NetworkSolanaMainnet, newTestWallet().PrivateKey.String(), tokens,
+		WithRPCClient(newMockRPCClient()),
+		WithTrustedFeePayers(trusted),
+	)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		feePayer string
+		wantErr  error
+	}{
+		{name: "trusted fee payer", feePayer: trusted},
+		{name: "unknown fee payer", feePayer: newTestWallet().PublicKey().String(), wantErr: v2.ErrUntrustedFeePayer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := signer.Sign(&v2.PaymentRequirements{
+				Scheme:            "exact",
+				Network:           v2.NetworkSolanaMainnet,
+				Asset:             v2.SolanaMainnet.USDCAddress,
+				Amount:            "1000000",
+				PayTo:             "9B5XszUGdMaxCZ7uSQhPzdks5ZQSmWxrmzCSvtJ6Ns6g",
+				MaxTimeoutSeconds: 60,
+				Extra:             map[string]interface{}{"feePayer": tt.feePayer},
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if _, err := NewSigner(v2.NetworkSolanaMainnet, newTestWallet().PrivateKey.String(), tokens, WithTrustedFeePayers("not-base58!")); err == nil {
+		t.Error("expected invalid fee payer address to be rejected")
+	}
+}
+
 func TestTransactionStructure(t *testing.T) {
 	testWallet := newTestWallet()
 	tokens := []v2.TokenConfig{
@@ -789,3 +837,599 @@ func indexOfSubstring(s, substr string) int {
 	}
 	return -1
 }
+
+// mockFeeClient implements PrioritizationFeeClient for testing.
+type mockFeeClient struct {
+	fees []uint64
+	err  error
+}
+
+func (m *mockFeeClient) GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	results := make([]rpc.PriorizationFeeResult, len(m.fees))
+	for i, fee := range m.fees {
+		results[i] = rpc.PriorizationFeeResult{Slot: uint64(i), PrioritizationFee: fee}
+	}
+	return results, nil
+}
+
+func TestRecentFeeEstimator(t *testing.T) {
+	tests := []struct {
+		name      string
+		client    *mockFeeClient
+		estimator func(*mockFeeClient) *RecentFeeEstimator
+		want      uint64
+		wantErr   bool
+	}{
+		{
+			name:      "75th percentile",
+			client:    &mockFeeClient{fees: []uint64{400, 100, 300, 200, 500}},
+			estimator: func(c *mockFeeClient) *RecentFeeEstimator { return NewRecentFeeEstimator(c, 0) },
+			want:      400,
+		},
+		{
+			name:      "capped at max",
+			client:    &mockFeeClient{fees: []uint64{1_000_000}},
+			estimator: func(c *mockFeeClient) *RecentFeeEstimator { return NewRecentFeeEstimator(c, 50_000) },
+			want:      50_000,
+		},
+		{
+			name:   "idle network uses minimum",
+			client: &mockFeeClient{fees: []uint64{0, 0, 0}},
+			estimator: func(c *mockFeeClient) *RecentFeeEstimator {
+				return &RecentFeeEstimator{Client: c, Percentile: 50, Min: 1_000}
+			},
+			want: 1_000,
+		},
+		{
+			name:      "rpc error",
+			client:    &mockFeeClient{err: errors.New("rpc down")},
+			estimator: func(c *mockFeeClient) *RecentFeeEstimator { return NewRecentFeeEstimator(c, 0) },
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.estimator(tt.client).EstimateComputeUnitPrice(context.Background(), nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected price %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSign_ComputeBudget(t *testing.T) {
+	testWallet := newTestWallet()
+	tokens := []v2.TokenConfig{
+		{Address: v2.SolanaMainnet.USDCAddress, Symbol: "USDC", Decimals: 6},
+	}
+	requirements := &v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           v2.NetworkSolanaMainnet,
+		Asset:             v2.SolanaMainnet.USDCAddress,
+		Amount:            "1000000",
+		PayTo:             "9B5XszUGdMaxCZ7uSQhPzdks5ZQSmWxrmzCSvtJ6Ns6g",
+		MaxTimeoutSeconds: 60,
+		Extra: map[string]interface{}{
+			"feePayer": "EwWqGE4ZFKLofuestmU4LDdK7XM1N4ALgdZccwYugwGd",
+		},
+	}
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantUnits uint32
+		wantPrice uint64
+	}{
+		{name: "defaults", wantUnits: 200_000, wantPrice: 10_000},
+		{name: "fixed", opts: []Option{WithComputeUnitLimit(50_000), WithComputeUnitPrice(1)}, wantUnits: 50_000, wantPrice: 1},
+		{
+			name:      "estimated",
+			opts:      []Option{WithPriorityFeeEstimator(NewRecentFeeEstimator(&mockFeeClient{fees: []uint64{7_500}}, 0))},
+			wantUnits: 200_000,
+			wantPrice: 7_500,
+		},
+		{
+			name: "estimator failure falls back to fixed price",
+			opts: []Option{
+				WithComputeUnitPrice(42),
+				WithPriorityFeeEstimator(NewRecentFeeEstimator(&mockFeeClient{err: errors.New("rpc down")}, 0)),
+			},
+			wantUnits: 200_000,
+			wantPrice: 42,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithRPCClient(newMockRPCClient())}, tt.opts...)
+			signer, err := NewSigner(v2.NetworkSolanaMainnet, testWallet.PrivateKey.String(), tokens, opts...)
+			if err != nil {
+				t.Fatalf("failed to create signer: %v", err)
+			}
+
+			payload, err := signer.Sign(requirements)
+			if err != nil {
+				t.Fatalf("failed to sign: %v", err)
+			}
+
+			var tx solana.Transaction
+			if err := tx.UnmarshalBase64(payload.Payload.(v2.SVMPayload).Transaction); err != nil {
+				t.Fatalf("failed to unmarshal transaction: %v", err)
+			}
+
+			units := binary.LittleEndian.Uint32(tx.Message.Instructions[0].Data[1:])
+			price := binary.LittleEndian.Uint64(tx.Message.Instructions[1].Data[1:])
+			if units != tt.wantUnits {
+				t.Errorf("expected %d compute units, got %d", tt.wantUnits, units)
+			}
+			if price != tt.wantPrice {
+				t.Errorf("expected compute unit price %d, got %d", tt.wantPrice, price)
+			}
+		})
+	}
+
+	if _, err := NewSigner(v2.NetworkSolanaMainnet, testWallet.PrivateKey.String(), tokens, WithComputeUnitLimit(0)); err == nil {
+		t.Error("expected error for zero compute unit limit")
+	}
+}
+
+func TestSign_Memo(t *testing.T) {
+	testWallet := newTestWallet()
+	tokens := []v2.TokenConfig{
+		{Address: v2.SolanaMainnet.USDCAddress, Symbol: "USDC", Decimals: 6},
+	}
+	newRequirements := func(extra map[string]interface{}) *v2.PaymentRequirements {
+		extra["feePayer"] = "EwWqGE4ZFKLofuestmU4LDdK7XM1N4ALgdZccwYugwGd"
+		return &v2.PaymentRequirements{
+			Scheme:            "exact",
+			Network:           v2.NetworkSolanaMainnet,
+			Asset:             v2.SolanaMainnet.USDCAddress,
+			Amount:            "1000000",
+			PayTo:             "9B5XszUGdMaxCZ7uSQhPzdks5ZQSmWxrmzCSvtJ6Ns6g",
+			MaxTimeoutSeconds: 60,
+			Extra:             extra,
+		}
+	}
+
+	signer, err := NewSigner(v2.NetworkSolanaMainnet, testWallet.PrivateKey.String(), tokens,
+		WithRPCClient(newMockRPCClient()),
+		WithMemo(PaymentReferenceMemo("https://api.example.com/data")))
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	t.Run("generated reference", func(t *testing.T) {
+		payload, err := signer.Sign(newRequirements(map[string]interface{}{}))
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+
+		memo, ok, err := ExtractMemo(*payload)
+		if err != nil || !ok {
+			t.Fatalf("expected memo, got ok=%v err=%v", ok, err)
+		}
+		if !strings.HasPrefix(memo, PaymentReferencePrefix) || len(memo) != len(PaymentReferencePrefix)+32 {
+			t.Errorf("unexpected memo %q", memo)
+		}
+
+		var tx solana.Transaction
+		if err := tx.UnmarshalBase64(payload.Payload.(v2.SVMPayload).Transaction); err != nil {
+			t.Fatalf("failed to unmarshal transaction: %v", err)
+		}
+		if len(tx.Message.Instructions) != 5 {
+			t.Fatalf("expected 5 instructions, got %d", len(tx.Message.Instructions))
+		}
+		programID, _ := tx.Message.Program(tx.Message.Instructions[4].ProgramIDIndex)
+		if !programID.Equals(solutil.MemoProgramID) {
+			t.Errorf("expected memo as the last instruction, got program %s", programID)
+		}
+	})
+
+	t.Run("server supplied reference", func(t *testing.T) {
+		payload, err := signer.Sign(newRequirements(map[string]interface{}{"paymentReference": "order-123"}))
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+
+		// Decode via a generic map, as a server would after parsing the header
+		data, _ := json.Marshal(payload)
+		var decoded v2.PaymentPayload
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("failed to decode payload: %v", err)
+		}
+
+		memo, ok, err := ExtractMemo(decoded)
+		if err != nil || !ok || memo != "order-123" {
+			t.Errorf("expected memo order-123, got %q ok=%v err=%v", memo, ok, err)
+		}
+	})
+
+	t.Run("no memo by default", func(t *testing.T) {
+		plain, err := NewSigner(v2.NetworkSolanaMainnet, testWallet.PrivateKey.String(), tokens, WithRPCClient(newMockRPCClient()))
+		if err != nil {
+			t.Fatalf("failed to create signer: %v", err)
+		}
+		payload, err := plain.Sign(newRequirements(map[string]interface{}{}))
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		if _, ok, err := ExtractMemo(*payload); err != nil || ok {
+			t.Errorf("expected no memo, got ok=%v err=%v", ok, err)
+		}
+	})
+}
+
+func TestPaymentReference(t *testing.T) {
+	a := PaymentReference("https://api.example.com/data", []byte{1})
+	b := PaymentReference("https://api.example.com/data", []byte{1})
+	c := PaymentReference("https://api.example.com/data", []byte{2})
+	if a != b {
+		t.Error("expected deterministic reference")
+	}
+	if a == c {
+		t.Error("expected different nonces to produce different references")
+	}
+}
+
+// mockSwapProvider returns a fixed swap quote and records the last request.
+type mockSwapProvider struct {
+	quote   *SwapQuote
+	err     error
+	lastReq SwapRequest
+}
+
+func (m *mockSwapProvider) SwapInstructions(ctx context.Context, req SwapRequest) (*SwapQuote, error) {
+	m.lastReq = req
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.quote, nil
+}
+
+func TestSwapSigner(t *testing.T) {
+	testWallet := newTestWallet()
+	tokens := []v2.TokenConfig{
+		{Address: v2.SolanaMainnet.USDCAddress, Symbol: "USDC", Decimals: 6},
+	}
+	base, err := NewSigner(v2.NetworkSolanaMainnet, testWallet.PrivateKey.String(), tokens, WithRPCClient(newMockRPCClient()))
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	swapProgram := solana.NewWallet().PublicKey()
+	provider := &mockSwapProvider{quote: &SwapQuote{
+		MaxInAmount:  5_000_000,
+		Instructions: []solana.Instruction{solana.NewInstruction(swapProgram, solana.AccountMetaSlice{}, []byte{1})},
+	}}
+
+	requirements := &v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           v2.NetworkSolanaMainnet,
+		Asset:             v2.SolanaMainnet.USDCAddress,
+		Amount:            "1000000",
+		PayTo:             "9B5XszUGdMaxCZ7uSQhPzdks5ZQSmWxrmzCSvtJ6Ns6g",
+		MaxTimeoutSeconds: 60,
+		Extra:             map[string]interface{}{"feePayer": "EwWqGE4ZFKLofuestmU4LDdK7XM1N4ALgdZccwYugwGd"},
+	}
+
+	t.Run("can sign", func(t *testing.T) {
+		signer, err := NewSwapSigner(base, provider, solana.SolMint.String(), tokens)
+		if err != nil {
+			t.Fatalf("failed to create swap signer: %v", err)
+		}
+		if !signer.CanSign(requirements) {
+			t.Error("expected swap signer to accept USDC requirement")
+		}
+
+		other := *requirements
+		other.Asset = solana.SolMint.String()
+		if signer.CanSign(&other) {
+			t.Error("expected swap signer to reject requirement for its input mint")
+		}
+
+		other = *requirements
+		other.Network = v2.NetworkSolanaDevnet
+		if signer.CanSign(&other) {
+			t.Error("expected swap signer to reject other network")
+		}
+	})
+
+	t.Run("builds swap and transfer", func(t *testing.T) {
+		signer, err := NewSwapSigner(base, provider, solana.SolMint.String(), tokens, WithSlippageBps(100))
+		if err != nil {
+			t.Fatalf("failed to create swap signer: %v", err)
+		}
+		payload, err := signer.Sign(requirements)
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+
+		if provider.lastReq.OutAmount != 1000000 || provider.lastReq.SlippageBps != 100 {
+			t.Errorf("unexpected swap request %+v", provider.lastReq)
+		}
+		if !provider.lastReq.InputMint.Equals(solana.SolMint) || !provider.lastReq.User.Equals(testWallet.PublicKey()) {
+			t.Errorf("unexpected swap request %+v", provider.lastReq)
+		}
+
+		var tx solana.Transaction
+		if err := tx.UnmarshalBase64(payload.Payload.(v2.SVMPayload).Transaction); err != nil {
+			t.Fatalf("failed to unmarshal transaction: %v", err)
+		}
+		if len(tx.Message.Instructions) != 5 {
+			t.Fatalf("expected 5 instructions, got %d", len(tx.Message.Instructions))
+		}
+
+		limit := tx.Message.Instructions[0].Data
+		if got := binary.LittleEndian.Uint32(limit[1:5]); got != DefaultSwapComputeUnits {
+			t.Errorf("expected compute unit limit %d, got %d", DefaultSwapComputeUnits, got)
+		}
+		programID, _ := tx.Message.Program(tx.Message.Instructions[2].ProgramIDIndex)
+		if !programID.Equals(swapProgram) {
+			t.Errorf("expected swap instruction third, got program %s", programID)
+		}
+		programID, _ = tx.Message.Program(tx.Message.Instructions[4].ProgramIDIndex)
+		if !programID.Equals(solana.TokenProgramID) {
+			t.Errorf("expected transfer as the last instruction, got program %s", programID)
+		}
+	})
+
+	t.Run("max input exceeded", func(t *testing.T) {
+		signer, err := NewSwapSigner(base, provider, solana.SolMint.String(), tokens, WithMaxInputAmount(big.NewInt(1_000_000)))
+		if err != nil {
+			t.Fatalf("failed to create swap signer: %v", err)
+		}
+		if _, err := signer.Sign(requirements); !errors.Is(err, v2.ErrAmountExceeded) {
+			t.Errorf("expected ErrAmountExceeded, got %v", err)
+		}
+	})
+
+	t.Run("invalid slippage", func(t *testing.T) {
+		if _, err := NewSwapSigner(base, provider, solana.SolMint.String(), tokens, WithSlippageBps(20_000)); err == nil {
+			t.Error("expected error for invalid slippage")
+		}
+	})
+}
+
+func TestJupiterClient(t *testing.T) {
+	user := solana.NewWallet().PublicKey()
+	program := solana.NewWallet().PublicKey()
+	instruction := map[string]interface{}{
+		"programId": program.String(),
+		"accounts":  []map[string]interface{}{{"pubkey": user.String(), "isSigner": true, "isWritable": true}},
+		"data":      "AQI=",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/quote":
+			if r.URL.Query().Get("swapMode") != "ExactOut" || r.URL.Query().Get("amount") != "1000000" {
+				t.Errorf("unexpected quote query %s", r.URL.RawQuery)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"inAmount": "4900000", "otherAmountThreshold": "5000000"})
+		case "/swap-instructions":
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["userPublicKey"] != user.String() || body["quoteResponse"] == nil {
+				t.Errorf("unexpected swap request %v", body)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"setupInstructions": []interface{}{instruction},
+				"swapInstruction":   instruction,
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := &JupiterClient{BaseURL: server.URL}
+	quote, err := client.SwapInstructions(context.Background(), SwapRequest{
+		User:        user,
+		InputMint:   solana.SolMint,
+		OutputMint:  solana.MustPublicKeyFromBase58(v2.SolanaMainnet.USDCAddress),
+		OutAmount:   1000000,
+		SlippageBps: 50,
+	})
+	if err != nil {
+		t.Fatalf("SwapInstructions failed: %v", err)
+	}
+	if quote.MaxInAmount != 5000000 {
+		t.Errorf("expected max input 5000000, got %d", quote.MaxInAmount)
+	}
+	if len(quote.Instructions) != 2 {
+		t.Fatalf("expected 2 instructions, got %d", len(quote.Instructions))
+	}
+	data, _ := quote.Instructions[1].Data()
+	if !quote.Instructions[1].ProgramID().Equals(program) || len(data) != 2 || data[0] != 1 {
+		t.Errorf("unexpected instruction conversion")
+	}
+	if !quote.Instructions[1].Accounts()[0].IsSigner {
+		t.Error("expected signer account meta")
+	}
+}
+
+func BenchmarkBuildPartiallySignedTransfer(b *testing.B) {
+	wallet := newTestWallet()
+	mint := solana.MustPublicKeyFromBase58(v2.SolanaMainnet.USDCAddress)
+	recipient := solana.MustPublicKeyFromBase58("9B5XszUGdMaxCZ7uSQhPzdks5ZQSmWxrmzCSvtJ6Ns6g")
+	feePayer := solana.MustPublicKeyFromBase58("EwWqGE4ZFKLofuestmU4LDdK7XM1N4ALgdZccwYugwGd")
+	blockhash := newMockRPCClient().blockhash
+	budget := computeBudget{units: solutil.DefaultComputeUnits, price: solutil.DefaultComputeUnitPrice}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := buildPartiallySignedTransfer(wallet.PrivateKey, wallet.PublicKey(), mint, recipient, 10000, 6, feePayer, blockhash, budget, ""); err != nil {
+			b.Fatalf("buildPartiallySignedTransfer failed: %v", err)
+		}
+	}
+}
+
+// mockSimulationClient returns a fixed simulation result.
+type mockSimulationClient struct {
+	result *rpc.SimulateTransactionResult
+	err    error
+	calls  int
+	opts   *rpc.SimulateTransactionOpts
+}
+
+func (m *mockSimulationClient) SimulateRawTransactionWithOpts(ctx context.Context, txData []byte, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error) {
+	m.calls++
+	m.opts = opts
+	if _, err := solana.TransactionFromBytes(txData); err != nil {
+		return nil, err
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &rpc.SimulateTransactionResponse{Value: m.result}, nil
+}
+
+func TestSign_Simulation(t *testing.T) {
+	tokens := []v2.TokenConfig{{Address: v2.SolanaMainnet.USDCAddress, Symbol: "USDC", Decimals: 6}}
+	requirements := &v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           v2.NetworkSolanaMainnet,
+		Asset:             v2.SolanaMainnet.USDCAddress,
+		Amount:            "500000",
+		PayTo:             "9B5XszUGdMaxCZ7uSQhPzdks5ZQSmWxrmzCSvtJ6Ns6g",
+		MaxTimeoutSeconds: 60,
+		Extra: map[string]interface{}{
+			"feePayer": "EwWqGE4ZFKLofuestmU4LDdK7XM1N4ALgdZccwYugwGd",
+		},
+	}
+
+	tests := []struct {
+		name    string
+		client  *mockSimulationClient
+		wantErr error
+	}{
+		{
+			name:   "success",
+			client: &mockSimulationClient{result: &rpc.SimulateTransactionResult{Logs: []string{"Program log: Instruction: TransferChecked"}}},
+		},
+		{
+			name: "insufficient funds",
+			client: &mockSimulationClient{result: &rpc.SimulateTransactionResult{
+				Err:  map[string]interface{}{"InstructionError": []interface{}{3, map[string]interface{}{"Custom": 1}}},
+				Logs: []string{"Program log: Instruction: TransferChecked", "Program log: Error: insufficient funds"},
+			}},
+			wantErr: v2.ErrInsufficientFunds,
+		},
+		{
+			name: "missing token account",
+			client: &mockSimulationClient{result: &rpc.SimulateTransactionResult{
+				Err: map[string]interface{}{"InstructionError": []interface{}{3, "InvalidAccountData"}},
+			}},
+			wantErr: v2.ErrTokenAccountNotFound,
+		},
+		{
+			name: "compute budget exceeded",
+			client: &mockSimulationClient{result: &rpc.SimulateTransactionResult{
+				Err:  map[string]interface{}{"InstructionError": []interface{}{3, "ComputationalBudgetExceeded"}},
+				Logs: []string{"Program TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA consumed 200 of 200 compute units"},
+			}},
+			wantErr: v2.ErrComputeBudgetExceeded,
+		},
+		{
+			name: "unrecognized failure",
+			client: &mockSimulationClient{result: &rpc.SimulateTransactionResult{
+				Err: "BlockhashNotFound",
+			}},
+			wantErr: v2.ErrSimulationFailed,
+		},
+		{
+			name:    "rpc failure",
+			client:  &mockSimulationClient{err: errors.New("connection refused")},
+			wantErr: v2.ErrNetworkError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewSigner(v2.NetworkSolanaMainnet, newTestWallet().PrivateKey.String(), tokens,
+				WithRPCClient(newMockRPCClient()), WithSimulation(tt.client))
+			if err != nil {
+				t.Fatalf("failed to create signer: %v", err)
+			}
+
+			payload, err := signer.Sign(requirements)
+			if tt.client.calls != 1 || tt.client.opts == nil || tt.client.opts.SigVerify {
+				t.Errorf("expected one simulation without signature verification, got %d calls with %+v", tt.client.calls, tt.client.opts)
+			}
+			if tt.wantErr == nil {
+				if err != nil || payload == nil {
+					t.Fatalf("Sign() = %v, %v", payload, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Sign() error = %v, want %v", err, tt.wantErr)
+			}
+			var simErr *SimulationError
+			if errors.As(err, &simErr) && !errors.Is(err, v2.ErrSimulationFailed) {
+				t.Errorf("expected simulation error to match v2.ErrSimulationFailed: %v", err)
+			}
+		})
+	}
+}
+
+// balanceRPCClient is a mockRPCClient that also answers getTokenAccountBalance.
+type balanceRPCClient struct {
+	mockRPCClient
+	balances map[solana.PublicKey]string
+}
+
+func (m *balanceRPCClient) GetTokenAccountBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetTokenAccountBalanceResult, error) {
+	amount, ok := m.balances[account]
+	if !ok {
+		return nil, errors.New("Invalid param: could not find account")
+	}
+	return &rpc.GetTokenAccountBalanceResult{Value: &rpc.UiTokenAmount{Amount: amount, Decimals: 6}}, nil
+}
+
+func TestBalance(t *testing.T) {
+	wallet := newTestWallet()
+	usdc := solana.MustPublicKeyFromBase58(v2.SolanaMainnet.USDCAddress)
+	account, err := solutil.DeriveAssociatedTokenAddress(wallet.PublicKey(), usdc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &balanceRPCClient{mockRPCClient: *newMockRPCClient(), balances: map[solana.PublicKey]string{account: "1500000"}}
+	tokens := []v2.TokenConfig{{Address: v2.SolanaMainnet.USDCAddress, Symbol: "USDC", Decimals: 6}}
+	signer, err := NewSigner(v2.NetworkSolanaMainnet, wallet.PrivateKey.String(), tokens, WithRPCClient(client))
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		asset   string
+		want    string
+		wantErr error
+	}{
+		{name: "token account", asset: v2.SolanaMainnet.USDCAddress, want: "1500000"},
+		{name: "missing token account", asset: v2.SolanaDevnet.USDCAddress, want: "0"},
+		{name: "invalid mint", asset: "not-a-mint", wantErr: v2.ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balance, err := signer.Balance(context.Background(), tt.asset)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || balance.String() != tt.want {
+				t.Errorf("Balance() = %v, %v; want %s", balance, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package svm
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gagliardetto/solana-go/rpc"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	solutil "github.com/mark3labs/x402-go/v2/internal/solana"
+)
+
+// SimulationClient is the RPC method used to simulate payment transactions.
+// *rpc.Client satisfies it.
+type SimulationClient interface {
+	SimulateRawTransactionWithOpts(ctx context.Context, txData []byte, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error)
+}
+
+// SimulationError describes a payment transaction that failed in simulation.
+// It matches v2.ErrSimulationFailed and, when the failure is recognized,
+// v2.ErrInsufficientFunds, v2.ErrTokenAccountNotFound, or
+// v2.ErrComputeBudgetExceeded with errors.Is.
+type SimulationError struct {
+	// Cause is the recognized failure, or nil.
+	Cause error
+
+	// Err is the transaction error reported by the RPC node.
+	Err interface{}
+
+	// Logs are the program logs of the simulation.
+	Logs []string
+}
+
+// Error implements error.
+func (e *SimulationError) Error() string {
+	detail, _ := json.Marshal(e.Err)
+	if e.Cause != nil {
+		return fmt.Sprintf("%v: %v: %s", v2.ErrSimulationFailed, e.Cause, detail)
+	}
+	return fmt.Sprintf("%v: %s", v2.ErrSimulationFailed, detail)
+}
+
+// Unwrap returns v2.ErrSimulationFailed and the recognized cause.
+func (e *SimulationError) Unwrap() []error {
+	if e.Cause == nil {
+		return []error{v2.ErrSimulationFailed}
+	}
+	return []error{v2.ErrSimulationFailed, e.Cause}
+}
+
+// WithSimulation simulates every payment transaction through client before it
+// is presented, without verifying signatures since the fee payer has not signed
+// yet. Transactions that would fail, e.g. because the payer's token account is
+// missing or underfunded, make Sign fail with a *SimulationError instead of
+// being rejected by the facilitator. A nil client uses the default RPC endpoint
+// of the network.
+func WithSimulation(client SimulationClient) Option {
+	return func(s *Signer) error {
+		if client == nil {
+			rpcURL, err := solutil.GetRPCURL(s.network)
+			if err != nil {
+				return fmt.Errorf("failed to get RPC URL: %w", err)
+			}
+			client = rpc.New(rpcURL)
+		}
+		s.simulator = client
+		return nil
+	}
+}
+
+// simulate simulates the base64-encoded transaction if simulation is enabled.
+func (s *Signer) simulate(ctx context.Context, txBase64 string) error {
+	if s.simulator == nil {
+		return nil
+	}
+	txData, err := base64.StdEncoding.DecodeString(txBase64)
+	if err != nil {
+		return fmt.Errorf("failed to decode transaction: %w", err)
+	}
+
+	resp, err := s.simulator.SimulateRawTransactionWithOpts(ctx, txData, &rpc.SimulateTransactionOpts{
+		SigVerify:  false,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: failed to simulate transaction: %v", v2.ErrNetworkError, err)
+	}
+	if resp.Value == nil || resp.Value.Err == nil {
+		return nil
+	}
+	return &SimulationError{
+		Cause: simulationCause(resp.Value.Err, resp.Value.Logs),
+		Err:   resp.Value.Err,
+		Logs:  resp.Value.Logs,
+	}
+}
+
+// simulationCause recognizes common payment failures from the transaction
+// error and the SPL Token program logs.
+func simulationCause(txErr interface{}, logs []string) error {
+	detail, _ := json.Marshal(txErr)
+	text := strings.ToLower(string(detail) + "\n" + strings.Join(logs, "\n"))
+	switch {
+	case strings.Contains(text, "insufficient funds") || strings.Contains(text, "insufficientfunds"):
+		return v2.ErrInsufficientFunds
+	case strings.Contains(text, "computationalbudgetexceeded") || strings.Contains(text, "exceeded cus meter"):
+		return v2.ErrComputeBudgetExceeded
+	case strings.Contains(text, "accountnotfound") || strings.Contains(text, "invalidaccountdata") ||
+		strings.Contains(text, "uninitializedaccount") || strings.Contains(text, "incorrectprogramid"):
+		// A missing source account is owned by the System program
+		return v2.ErrTokenAccountNotFound
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,459 @@
+package svm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/keymem"
+	solutil "github.com/mark3labs/x402-go/v2/internal/solana"
+)
+
+// DefaultSwapComputeUnits is the default compute unit limit for swap-and-pay transactions.
+const DefaultSwapComputeUnits uint32 = 600_000
+
+// DefaultSlippageBps is the default swap slippage tolerance in basis points.
+const DefaultSlippageBps = 50
+
+// SwapRequest asks a SwapProvider for instructions that swap into an exact output amount.
+type SwapRequest struct {
+	// User is the wallet that holds the input token and receives the output token.
+	User solana.PublicKey
+
+	// InputMint is the mint the user pays with (use solana.SolMint for native SOL).
+	InputMint solana.PublicKey
+
+	// OutputMint is the mint required by the payment.
+	OutputMint solana.PublicKey
+
+	// OutAmount is the exact output amount in atomic units.
+	OutAmount uint64
+
+	// SlippageBps is the tolerated slippage in basis points.
+	SlippageBps int
+}
+
+// SwapQuote contains the instructions for a swap and its worst-case input amount.
+type SwapQuote struct {
+	// MaxInAmount is the maximum input amount the swap can consume, including slippage.
+	MaxInAmount uint64
+
+	// Instructions swap InputMint into exactly OutAmount of OutputMint in the
+	// user's associated token account, including any setup and cleanup.
+	Instructions []solana.Instruction
+}
+
+// SwapProvider builds swap instructions, e.g. from the Jupiter aggregator.
+type SwapProvider interface {
+	SwapInstructions(ctx context.Context, req SwapRequest) (*SwapQuote, error)
+}
+
+// SwapSigner pays requirements in a token the wallet does not hold by swapping
+// into it within the same transaction. It is opt-in: add it alongside a regular
+// Signer (typically with a lower priority) so swaps are only used when no direct
+// payment is possible.
+//
+// Swap-and-pay transactions contain more instructions than a plain transfer, so
+// the facilitator must accept them.
+type SwapSigner struct {
+	signer       *Signer
+	provider     SwapProvider
+	inputMint    solana.PublicKey
+	outputs      []v2.TokenConfig
+	slippageBps  int
+	maxInput     *big.Int
+	priority     int
+	computeUnits uint32
+}
+
+// SwapOption configures a SwapSigner.
+type SwapOption func(*SwapSigner) error
+
+// WithSlippageBps sets the tolerated swap slippage in basis points (default: 50).
+func WithSlippageBps(bps int) SwapOption {
+	return func(s *SwapSigner) error {
+		if bps < 0 || bps > 10_000 {
+			return fmt.Errorf("slippage must be between 0 and 10000 bps, got %d", bps)
+		}
+		s.slippageBps = bps
+		return nil
+	}
+}
+
+// WithMaxInputAmount limits the input amount a single swap may consume, in atomic
+// units of the input token.
+func WithMaxInputAmount(amount *big.Int) SwapOption {
+	return func(s *SwapSigner) error {
+		s.maxInput = amount
+		return nil
+	}
+}
+
+// WithSwapPriority sets the swap signer's priority (default: 1, after direct payment signers).
+func WithSwapPriority(priority int) SwapOption {
+	return func(s *SwapSigner) error {
+		s.priority = priority
+		return nil
+	}
+}
+
+// WithSwapComputeUnitLimit sets the compute unit limit for swap transactions (default: 600,000).
+func WithSwapComputeUnitLimit(units uint32) SwapOption {
+	return func(s *SwapSigner) error {
+		if units == 0 {
+			return fmt.Errorf("compute unit limit must be positive")
+		}
+		s.computeUnits = units
+		return nil
+	}
+}
+
+// NewSwapSigner creates a SwapSigner that pays with inputMint using signer's wallet.
+// outputs lists the tokens it may swap into; their decimals are used for TransferChecked.
+func NewSwapSigner(signer *Signer, provider SwapProvider, inputMint string, outputs []v2.TokenConfig, opts ...SwapOption) (*SwapSigner, error) {
+	if signer == nil || provider == nil {
+		return nil, fmt.Errorf("signer and swap provider are required")
+	}
+	if len(outputs) == 0 {
+		return nil, v2.ErrNoTokens
+	}
+
+	mint, err := solana.PublicKeyFromBase58(inputMint)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid input mint: %v", v2.ErrInvalidToken, err)
+	}
+
+	s := &SwapSigner{
+		signer:       signer,
+		provider:     provider,
+		inputMint:    mint,
+		outputs:      outputs,
+		slippageBps:  DefaultSlippageBps,
+		priority:     1,
+		computeUnits: DefaultSwapComputeUnits,
+	}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Network returns the CAIP-2 network identifier.
+func (s *SwapSigner) Network() string {
+	return s.signer.Network()
+}
+
+// Scheme returns the payment scheme identifier.
+func (s *SwapSigner) Scheme() string {
+	return "exact"
+}
+
+// CanSign reports whether the requirement asks for one of the configured output
+// tokens, other than the input token itself.
+func (s *SwapSigner) CanSign(requirements *v2.PaymentRequirements) bool {
+	if requirements.Scheme != "exact" || requirements.Network != s.signer.Network() {
+		return false
+	}
+	if requirements.Asset == s.inputMint.String() {
+		return false
+	}
+	_, ok := s.outputToken(requirements.Asset)
+	return ok
+}
+
+// Sign builds a partially signed transaction that swaps into the required asset
+// and transfers it to the payee.
+func (s *SwapSigner) Sign(requirements *v2.PaymentRequirements) (*v2.PaymentPayload, error) {
+	if !s.CanSign(requirements) {
+		return nil, v2.ErrNoValidSigner
+	}
+
+	amount := new(big.Int)
+	if _, ok := amount.SetString(requirements.Amount, 10); !ok || amount.Sign() <= 0 || !amount.IsUint64() {
+		return nil, v2.ErrInvalidAmount
+	}
+
+	token, _ := s.outputToken(requirements.Asset)
+	if token.Decimals < 0 || token.Decimals > 255 {
+		return nil, fmt.Errorf("%w: invalid token decimals %d", v2.ErrInvalidToken, token.Decimals)
+	}
+
+	mint, err := solana.PublicKeyFromBase58(requirements.Asset)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mint address: %w", err)
+	}
+	recipient, err := solana.PublicKeyFromBase58(requirements.PayTo)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address: %w", err)
+	}
+	feePayer, err := s.signer.feePayer(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), v2.DefaultTimeouts.VerifyTimeout)
+	defer cancel()
+
+	quote, err := s.provider.SwapInstructions(ctx, SwapRequest{
+		User:        s.signer.publicKey,
+		InputMint:   s.inputMint,
+		OutputMint:  mint,
+		OutAmount:   amount.Uint64(),
+		SlippageBps: s.slippageBps,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get swap instructions: %w", err)
+	}
+	if s.maxInput != nil && new(big.Int).SetUint64(quote.MaxInAmount).Cmp(s.maxInput) > 0 {
+		return nil, v2.ErrAmountExceeded
+	}
+
+	blockhash, err := s.signer.latestBlockhash(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceATA, err := solutil.DeriveAssociatedTokenAddress(s.signer.publicKey, mint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find source ATA: %w", err)
+	}
+	destATA, err := solutil.DeriveAssociatedTokenAddress(recipient, mint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find destination ATA: %w", err)
+	}
+	createATAInstruction, err := solutil.BuildCreateIdempotentATAInstruction(feePayer, recipient, mint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build ATA creation instruction: %w", err)
+	}
+
+	budget := s.signer.computeBudget(ctx, mint, recipient)
+	instructions := []solana.Instruction{
+		solutil.BuildSetComputeUnitLimitInstruction(s.computeUnits),
+		solutil.BuildSetComputeUnitPriceInstruction(budget.price),
+	}
+	instructions = append(instructions, quote.Instructions...)
+	instructions = append(instructions,
+		createATAInstruction,
+		solutil.BuildTransferCheckedInstruction(sourceATA, mint, destATA, s.signer.publicKey, amount.Uint64(), uint8(token.Decimals)),
+	)
+
+	txBase64, err := keymem.WithBytes(s.signer.key, func(key []byte) (string, error) {
+		return partiallySign(solana.PrivateKey(key), instructions, blockhash, feePayer)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build transaction: %w", err)
+	}
+	if err := s.signer.simulate(ctx, txBase64); err != nil {
+		return nil, err
+	}
+
+	return &v2.PaymentPayload{
+		X402Version: v2.X402Version,
+		Accepted:    *requirements,
+		Payload: v2.SVMPayload{
+			Transaction: txBase64,
+		},
+	}, nil
+}
+
+// GetPriority returns the swap signer's priority level.
+func (s *SwapSigner) GetPriority() int {
+	return s.priority
+}
+
+// GetTokens returns the tokens the swap signer can pay in.
+func (s *SwapSigner) GetTokens() []v2.TokenConfig {
+	return s.outputs
+}
+
+// GetMaxAmount returns nil; limits apply to the input amount (see WithMaxInputAmount).
+func (s *SwapSigner) GetMaxAmount() *big.Int {
+	return nil
+}
+
+// outputToken finds the configured output token for a mint address.
+func (s *SwapSigner) outputToken(asset string) (v2.TokenConfig, bool) {
+	for _, token := range s.outputs {
+		if token.Address == asset {
+			return token, true
+		}
+	}
+	return v2.TokenConfig{}, false
+}
+
+// DefaultJupiterURL is the base URL of the public Jupiter swap API.
+const DefaultJupiterURL = "https://lite-api.jup.ag/swap/v1"
+
+// JupiterClient is a SwapProvider backed by the Jupiter aggregator API.
+// It requests ExactOut quotes as legacy transactions so no address lookup
+// tables are needed.
+type JupiterClient struct {
+	// BaseURL is the Jupiter swap API URL (default: DefaultJupiterURL).
+	BaseURL string
+
+	// Client is the HTTP client to use. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// APIKey is sent as the x-api-key header when set.
+	APIKey string
+}
+
+// NewJupiterClient creates a JupiterClient for the public API.
+func NewJupiterClient() *JupiterClient {
+	return &JupiterClient{BaseURL: DefaultJupiterURL}
+}
+
+// jupiterInstruction is an instruction as returned by the Jupiter API.
+type jupiterInstruction struct {
+	ProgramID string `json:"programId"`
+	Accounts  []struct {
+		Pubkey     string `json:"pubkey"`
+		IsSigner   bool   `json:"isSigner"`
+		IsWritable bool   `json:"isWritable"`
+	} `json:"accounts"`
+	Data string `json:"data"`
+}
+
+// jupiterSwapInstructions is the response of the /swap-instructions endpoint.
+type jupiterSwapInstructions struct {
+	SetupInstructions  []jupiterInstruction `json:"setupInstructions"`
+	SwapInstruction    jupiterInstruction   `json:"swapInstruction"`
+	CleanupInstruction *jupiterInstruction  `json:"cleanupInstruction"`
+	OtherInstructions  []jupiterInstruction `json:"otherInstructions"`
+}
+
+// SwapInstructions implements SwapProvider.
+func (c *JupiterClient) SwapInstructions(ctx context.Context, req SwapRequest) (*SwapQuote, error) {
+	query := url.Values{}
+	query.Set("inputMint", req.InputMint.String())
+	query.Set("outputMint", req.OutputMint.String())
+	query.Set("amount", strconv.FormatUint(req.OutAmount, 10))
+	query.Set("slippageBps", strconv.Itoa(req.SlippageBps))
+	query.Set("swapMode", "ExactOut")
+	query.Set("asLegacyTransaction", "true")
+
+	var quote map[string]interface{}
+	if err := c.do(ctx, "GET", "/quote?"+query.Encode(), nil, &quote); err != nil {
+		return nil, fmt.Errorf("quote failed: %w", err)
+	}
+
+	// For ExactOut quotes, otherAmountThreshold is the maximum input including slippage
+	threshold, _ := quote["otherAmountThreshold"].(string)
+	maxIn, err := strconv.ParseUint(threshold, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid otherAmountThreshold in quote: %q", threshold)
+	}
+
+	var swap jupiterSwapInstructions
+	body := map[string]interface{}{
+		"quoteResponse":       quote,
+		"userPublicKey":       req.User.String(),
+		"wrapAndUnwrapSol":    true,
+		"asLegacyTransaction": true,
+	}
+	if err := c.do(ctx, "POST", "/swap-instructions", body, &swap); err != nil {
+		return nil, fmt.Errorf("swap instructions failed: %w", err)
+	}
+
+	raw := append([]jupiterInstruction{}, swap.OtherInstructions...)
+	raw = append(raw, swap.SetupInstructions...)
+	raw = append(raw, swap.SwapInstruction)
+	if swap.CleanupInstruction != nil {
+		raw = append(raw, *swap.CleanupInstruction)
+	}
+
+	instructions := make([]solana.Instruction, 0, len(raw))
+	for _, inst := range raw {
+		converted, err := inst.toInstruction()
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, converted)
+	}
+
+	return &SwapQuote{MaxInAmount: maxIn, Instructions: instructions}, nil
+}
+
+// do sends a request to the Jupiter API and decodes the JSON response into out.
+func (c *JupiterClient) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultJupiterURL
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.APIKey != "" {
+		req.Header.Set("x-api-key", c.APIKey)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// toInstruction converts a Jupiter API instruction to a solana.Instruction.
+func (inst jupiterInstruction) toInstruction() (solana.Instruction, error) {
+	programID, err := solana.PublicKeyFromBase58(inst.ProgramID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid program ID %q: %w", inst.ProgramID, err)
+	}
+
+	accounts := make(solana.AccountMetaSlice, 0, len(inst.Accounts))
+	for _, account := range inst.Accounts {
+		pubkey, err := solana.PublicKeyFromBase58(account.Pubkey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid account %q: %w", account.Pubkey, err)
+		}
+		accounts = append(accounts, &solana.AccountMeta{
+			PublicKey:  pubkey,
+			IsSigner:   account.IsSigner,
+			IsWritable: account.IsWritable,
+		})
+	}
+
+	data, err := base64.StdEncoding.DecodeString(inst.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid instruction data: %w", err)
+	}
+
+	return solana.NewInstruction(programID, accounts, data), nil
+}
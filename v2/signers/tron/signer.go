@@ -0,0 +1,296 @@
+// Package tron provides a TRON signer for the x402 v2 protocol.
+//
+// The signer creates TIP-712 TransferWithAuthorization signatures for TRC-20
+// tokens such as USDT. USDT-TRC20 does not verify authorizations itself, so
+// servers name the contract that does (typically the facilitator's relayer) in
+// Extra["verifyingContract"], along with the domain Extra["name"] and
+// Extra["version"]. Without a verifying contract, the token address is used.
+//
+// The signer only signs for a verifying contract it was configured to trust
+// with WithVerifyingContract, or for the token itself, so a server cannot
+// obtain an authorization redeemable through a contract of its choosing.
+package tron
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/eip3009"
+	"github.com/mark3labs/x402-go/v2/internal/keymem"
+	"github.com/mark3labs/x402-go/v2/internal/tron"
+	ns "github.com/mark3labs/x402-go/v2/namespaces/tron"
+)
+
+// Signer implements the v2.Signer interface for TRON.
+type Signer struct {
+	key        *keymem.Key
+	account    common.Address
+	address    string
+	network    string // CAIP-2 format (e.g., "tron:mainnet")
+	chainID    int64
+	tokens     []v2.TokenConfig
+	priority   int
+	maxAmount  *big.Int
+	maxTimeout time.Duration
+	clock      v2.Clock
+
+	// verifiers are the trusted verifying contracts per token account
+	verifiers map[common.Address]common.Address
+}
+
+// Option configures a Signer.
+type Option func(*Signer) error
+
+// NewSigner creates a new TRON signer from a hex-encoded private key.
+// The key is held in locked memory until Close is called.
+func NewSigner(network string, privateKeyHex string, tokens []v2.TokenConfig, opts ...Option) (*Signer, error) {
+	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(privateKeyHex, "0x"))
+	if err != nil {
+		return nil, v2.ErrInvalidKey
+	}
+	defer keymem.WipeECDSA(privateKey)
+	return NewSignerFromKey(network, privateKey, tokens, opts...)
+}
+
+// NewSignerFromKey creates a new TRON signer from an existing private key. The
+// signer keeps its own copy in locked memory; callers should wipe key when done with it.
+func NewSignerFromKey(network string, key *ecdsa.PrivateKey, tokens []v2.TokenConfig, opts ...Option) (*Signer, error) {
+	chainID, err := ns.ChainID(network)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, v2.ErrInvalidToken
+	}
+	for _, token := range tokens {
+		if _, err := tron.DecodeAddress(token.Address); err != nil {
+			return nil, fmt.Errorf("%w: %v", v2.ErrInvalidToken, err)
+		}
+	}
+
+	s := &Signer{
+		key:     keymem.FromECDSA(key),
+		account: crypto.PubkeyToAddress(key.PublicKey),
+		network: network,
+		chainID: chainID,
+		tokens:  tokens,
+		clock:   v2.SystemClock,
+	}
+	s.address = tron.EncodeAddress(s.account)
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Close wipes the signer's private key. Later Sign calls fail with v2.ErrSignerClosed.
+func (s *Signer) Close() error {
+	return s.key.Close()
+}
+
+// WithMaxAmount sets the maximum amount per payment call.
+func WithMaxAmount(amount *big.Int) Option {
+	return func(s *Signer) error {
+		s.maxAmount = amount
+		return nil
+	}
+}
+
+// WithPriority sets the signer priority.
+func WithPriority(priority int) Option {
+	return func(s *Signer) error {
+		s.priority = priority
+		return nil
+	}
+}
+
+// WithMaxTimeout clamps the authorization lifetime (validBefore - now) to d,
+// regardless of the MaxTimeoutSeconds requested by the server.
+func WithMaxTimeout(d time.Duration) Option {
+	return func(s *Signer) error {
+		if d <= 0 {
+			return fmt.Errorf("max timeout must be positive, got %v", d)
+		}
+		s.maxTimeout = d
+		return nil
+	}
+}
+
+// WithVerifyingContract trusts contract, a base58check TRON address, to verify
+// authorizations for token, typically the facilitator's relayer for USDT.
+// Requirements naming any other verifying contract than the token itself are
+// rejected with v2.ErrUntrustedDomain.
+func WithVerifyingContract(token, contract string) Option {
+	return func(s *Signer) error {
+		tokenAccount, err := tron.DecodeAddress(token)
+		if err != nil {
+			return fmt.Errorf("%w: %v", v2.ErrInvalidToken, err)
+		}
+		contractAccount, err := tron.DecodeAddress(contract)
+		if err != nil {
+			return fmt.Errorf("%w: invalid verifying contract: %v", v2.ErrInvalidToken, err)
+		}
+		if s.verifiers == nil {
+			s.verifiers = make(map[common.Address]common.Address)
+		}
+		s.verifiers[tokenAccount] = contractAccount
+		return nil
+	}
+}
+
+// WithClock sets the clock used for authorization windows (default: v2.SystemClock).
+func WithClock(clock v2.Clock) Option {
+	return func(s *Signer) error {
+		s.clock = v2.ClockOrSystem(clock)
+		return nil
+	}
+}
+
+// Network returns the CAIP-2 network identifier.
+func (s *Signer) Network() string {
+	return s.network
+}
+
+// Scheme returns the payment scheme identifier.
+func (s *Signer) Scheme() string {
+	return v2.SchemeExact
+}
+
+// CanSign checks if this signer can satisfy the given payment requirements.
+func (s *Signer) CanSign(requirements *v2.PaymentRequirements) bool {
+	if requirements.Scheme != v2.SchemeExact || requirements.Network != s.network {
+		return false
+	}
+	for _, token := range s.tokens {
+		if token.Address == requirements.Asset {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign creates a signed PaymentPayload for the given requirements.
+func (s *Signer) Sign(requirements *v2.PaymentRequirements) (*v2.PaymentPayload, error) {
+	if !s.CanSign(requirements) {
+		return nil, v2.ErrNoValidSigner
+	}
+
+	amount, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		return nil, v2.ErrInvalidAmount
+	}
+	if s.maxAmount != nil && amount.Cmp(s.maxAmount) > 0 {
+		return nil, v2.ErrAmountExceeded
+	}
+
+	recipient, err := tron.DecodeAddress(requirements.PayTo)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address: %w", err)
+	}
+	domain, err := s.domain(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	timeout := time.Duration(requirements.MaxTimeoutSeconds) * time.Second
+	if s.maxTimeout > 0 && timeout > s.maxTimeout {
+		timeout = s.maxTimeout
+	}
+	auth, err := eip3009.CreateAuthorizationFromSource(
+		eip3009.RandomNonceSource{},
+		eip3009.NonceParams{From: s.account, To: recipient, Value: amount},
+		now.Add(-eip3009.DefaultValidAfterBackdate),
+		now.Add(timeout),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := keymem.WithECDSA(s.key, func(key *ecdsa.PrivateKey) (string, error) {
+		return eip3009.SignAuthorizationWithDomain(key, domain, auth)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", v2.ErrSigningFailed, err)
+	}
+
+	return &v2.PaymentPayload{
+		X402Version: v2.X402Version,
+		Accepted:    *requirements,
+		Payload: v2.TronPayload{
+			Signature: signature,
+			Authorization: v2.EVMAuthorization{
+				From:        s.address,
+				To:          requirements.PayTo,
+				Value:       auth.Value.String(),
+				ValidAfter:  auth.ValidAfter.String(),
+				ValidBefore: auth.ValidBefore.String(),
+				Nonce:       common.BytesToHash(auth.Nonce[:]).Hex(),
+			},
+		},
+	}, nil
+}
+
+// domain builds the TIP-712 domain from the requirements' Extra. The verifying
+// contract must be the token or the contract trusted for it.
+func (s *Signer) domain(requirements *v2.PaymentRequirements) (eip3009.Domain, error) {
+	name, _ := requirements.Extra["name"].(string)
+	version, _ := requirements.Extra["version"].(string)
+	if name == "" || version == "" {
+		return eip3009.Domain{}, fmt.Errorf("%w: missing TIP-712 name or version", v2.ErrInvalidRequirements)
+	}
+
+	token, err := tron.DecodeAddress(requirements.Asset)
+	if err != nil {
+		return eip3009.Domain{}, fmt.Errorf("%w: invalid asset: %v", v2.ErrInvalidRequirements, err)
+	}
+	contract := token
+	if verifyingContract, ok := requirements.Extra["verifyingContract"].(string); ok && verifyingContract != "" {
+		if contract, err = tron.DecodeAddress(verifyingContract); err != nil {
+			return eip3009.Domain{}, fmt.Errorf("%w: invalid verifying contract: %v", v2.ErrInvalidRequirements, err)
+		}
+	}
+	if trusted, ok := s.verifiers[token]; contract != token && (!ok || contract != trusted) {
+		return eip3009.Domain{}, fmt.Errorf("%w: verifying contract %s is not trusted for %s", v2.ErrUntrustedDomain, tron.EncodeAddress(contract), requirements.Asset)
+	}
+
+	return eip3009.Domain{
+		Name:              name,
+		Version:           version,
+		ChainID:           big.NewInt(s.chainID),
+		VerifyingContract: contract,
+	}, nil
+}
+
+// GetPriority returns the signer's priority level.
+func (s *Signer) GetPriority() int {
+	return s.priority
+}
+
+// GetTokens returns the list of tokens supported by this signer.
+func (s *Signer) GetTokens() []v2.TokenConfig {
+	return s.tokens
+}
+
+// GetMaxAmount returns the per-call spending limit, or nil if no limit is set.
+func (s *Signer) GetMaxAmount() *big.Int {
+	return s.maxAmount
+}
+
+// Address returns the signer's base58check TRON address.
+func (s *Signer) Address() string {
+	return s.address
+}
@@ -0,0 +1,200 @@
+package tron
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/tron"
+	ns "github.com/mark3labs/x402-go/v2/namespaces/tron"
+)
+
+const testKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+func newTestSigner(t *testing.T, opts ...Option) *Signer {
+	t.Helper()
+	usdt, _ := ns.NewUSDTTokenConfig(ns.NetworkNile, 1)
+	signer, err := NewSigner(ns.NetworkNile, testKey, []v2.TokenConfig{usdt}, opts...)
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+	return signer
+}
+
+func TestNewSigner(t *testing.T) {
+	usdt, _ := ns.NewUSDTTokenConfig(ns.NetworkNile, 1)
+	tests := []struct {
+		name    string
+		network string
+		key     string
+		tokens  []v2.TokenConfig
+		wantErr error
+	}{
+		{name: "invalid key", network: ns.NetworkNile, key: "zz", tokens: []v2.TokenConfig{usdt}, wantErr: v2.ErrInvalidKey},
+		{name: "EVM network", network: v2.NetworkBase, key: testKey, tokens: []v2.TokenConfig{usdt}, wantErr: v2.ErrInvalidNetwork},
+		{name: "no tokens", network: ns.NetworkNile, key: testKey, wantErr: v2.ErrInvalidToken},
+		{name: "hex token", network: ns.NetworkNile, key: testKey, tokens: []v2.TokenConfig{{Address: "0xa614f803b6fd780986a42c78ec9c7f77e6ded13c"}}, wantErr: v2.ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSigner(tt.network, tt.key, tt.tokens); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	signer := newTestSigner(t)
+	key, _ := crypto.HexToECDSA(testKey)
+	if want := tron.EncodeAddress(crypto.PubkeyToAddress(key.PublicKey)); signer.Address() != want {
+		t.Errorf("expected address %s, got %s", want, signer.Address())
+	}
+}
+
+func TestSigner_Sign(t *testing.T) {
+	clock := v2.NewFakeClock(time.Unix(1700000000, 0))
+	relayer := tron.EncodeAddress(common.HexToAddress("0x1111111111111111111111111111111111111111"))
+	signer := newTestSigner(t, WithClock(clock), WithMaxAmount(big.NewInt(5000000)), WithVerifyingContract(ns.USDTNile, relayer))
+	payTo := tron.EncodeAddress(common.HexToAddress("0x2222222222222222222222222222222222222222"))
+
+	requirements := &v2.PaymentRequirements{
+		Scheme:            v2.SchemeExact,
+		Network:           ns.NetworkNile,
+		Amount:            "1000000",
+		Asset:             ns.USDTNile,
+		PayTo:             payTo,
+		MaxTimeoutSeconds: 60,
+		Extra: map[string]interface{}{
+			"name":              "x402 Relayer",
+			"version":           "1",
+			"verifyingContract": relayer,
+		},
+	}
+	payment, err := signer.Sign(requirements)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	payload := payment.Payload.(v2.TronPayload)
+	auth := payload.Authorization
+	if auth.From != signer.Address() || auth.To != payTo || auth.ValidBefore != "1700000060" {
+		t.Errorf("unexpected authorization %+v", auth)
+	}
+
+	// Recover the signer from the TIP-712 digest
+	typedData := apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"TransferWithAuthorization": {
+				{Name: "from", Type: "address"},
+				{Name: "to", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "validAfter", Type: "uint256"},
+				{Name: "validBefore", Type: "uint256"},
+				{Name: "nonce", Type: "bytes32"},
+			},
+		},
+		PrimaryType: "TransferWithAuthorization",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "x402 Relayer",
+			Version:           "1",
+			ChainId:           math.NewHexOrDecimal256(0xcd8690dc),
+			VerifyingContract: "0x1111111111111111111111111111111111111111",
+		},
+		Message: apitypes.TypedDataMessage{
+			"from":        mustAccount(t, auth.From).Hex(),
+			"to":          mustAccount(t, auth.To).Hex(),
+			"value":       auth.Value,
+			"validAfter":  auth.ValidAfter,
+			"validBefore": auth.ValidBefore,
+			"nonce":       auth.Nonce,
+		},
+	}
+	digest, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		t.Fatalf("failed to hash typed data: %v", err)
+	}
+	signature := hexutil.MustDecode(payload.Signature)
+	signature[64] -= 27
+	publicKey, err := crypto.SigToPub(digest, signature)
+	if err != nil {
+		t.Fatalf("failed to recover signer: %v", err)
+	}
+	if recovered := tron.EncodeAddress(crypto.PubkeyToAddress(*publicKey)); recovered != signer.Address() {
+		t.Errorf("expected signature by %s, recovered %s", signer.Address(), recovered)
+	}
+
+	// The server's timeout is capped by the signer's
+	capped := newTestSigner(t, WithClock(clock), WithVerifyingContract(ns.USDTNile, relayer), WithMaxTimeout(30*time.Second))
+	requirements.MaxTimeoutSeconds = 3600
+	payment, err = capped.Sign(requirements)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if validBefore := payment.Payload.(v2.TronPayload).Authorization.ValidBefore; validBefore != "1700000030" {
+		t.Errorf("expected validBefore capped at 1700000030, got %s", validBefore)
+	}
+
+	requirements.Amount = "6000000"
+	if _, err := signer.Sign(requirements); !errors.Is(err, v2.ErrAmountExceeded) {
+		t.Errorf("expected ErrAmountExceeded, got %v", err)
+	}
+}
+
+func TestSigner_Domain(t *testing.T) {
+	relayer := tron.EncodeAddress(common.HexToAddress("0x1111111111111111111111111111111111111111"))
+	signer := newTestSigner(t, WithVerifyingContract(ns.USDTNile, relayer))
+	payTo := tron.EncodeAddress(common.HexToAddress("0x2222222222222222222222222222222222222222"))
+
+	tests := []struct {
+		name    string
+		extra   map[string]interface{}
+		wantErr error
+	}{
+		{name: "missing name", extra: map[string]interface{}{"version": "1"}, wantErr: v2.ErrInvalidRequirements},
+		{name: "missing version", extra: map[string]interface{}{"name": "Relayer"}, wantErr: v2.ErrInvalidRequirements},
+		{name: "hex verifying contract", extra: map[string]interface{}{"name": "Relayer", "version": "1", "verifyingContract": "0x1111111111111111111111111111111111111111"}, wantErr: v2.ErrInvalidRequirements},
+		{name: "untrusted verifying contract", extra: map[string]interface{}{"name": "Relayer", "version": "1", "verifyingContract": payTo}, wantErr: v2.ErrUntrustedDomain},
+		{name: "trusted verifying contract", extra: map[string]interface{}{"name": "Relayer", "version": "1", "verifyingContract": relayer}},
+		{name: "token as verifying contract", extra: map[string]interface{}{"name": "Tether USD", "version": "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := signer.Sign(&v2.PaymentRequirements{
+				Scheme:  v2.SchemeExact,
+				Network: ns.NetworkNile,
+				Amount:  "1000",
+				Asset:   ns.USDTNile,
+				PayTo:   payTo,
+				Extra:   tt.extra,
+			})
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func mustAccount(t *testing.T, address string) common.Address {
+	t.Helper()
+	account, err := tron.DecodeAddress(address)
+	if err != nil {
+		t.Fatalf("invalid address %s: %v", address, err)
+	}
+	return account
+}
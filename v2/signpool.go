@@ -0,0 +1,267 @@
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DefaultSigningWorkers is the number of concurrent signatures a SigningPool
+// computes when no worker count is configured.
+const DefaultSigningWorkers = 4
+
+// DefaultPresignMaxAge is how long a pre-signed payment is used before it is
+// discarded. It is kept well below typical authorization and Solana blockhash
+// lifetimes, and is further capped at half the requirement's MaxTimeoutSeconds.
+const DefaultPresignMaxAge = 20 * time.Second
+
+// ContextPaymentSelector is implemented by selectors whose signing can be
+// cancelled. The HTTP client uses it with the request context when available.
+type ContextPaymentSelector interface {
+	SelectAndSignContext(ctx context.Context, signers []Signer, requirements []PaymentRequirements) (*PaymentPayload, error)
+}
+
+// SelectAndSignContext signs with selector, passing ctx when selector is a
+// ContextPaymentSelector.
+func SelectAndSignContext(ctx context.Context, selector PaymentSelector, signers []Signer, requirements []PaymentRequirements) (*PaymentPayload, error) {
+	if s, ok := selector.(ContextPaymentSelector); ok {
+		return s.SelectAndSignContext(ctx, signers, requirements)
+	}
+	return selector.SelectAndSign(signers, requirements)
+}
+
+// SigningPool is a PaymentSelector that signs on a bounded pool of workers
+// and, once a requirement has been paid, keeps a few payments for it
+// pre-signed, so a burst of paid requests does not wait for secp256k1
+// signing or Solana RPC calls one at a time.
+//
+// A pre-signed payment is handed out at most once; unused ones simply expire.
+// Requirements carrying an "idempotencyKey" Extra value are never pre-signed:
+// signers derive their nonce from that key, so every payment signed for them
+// would be the same authorization. Calls waiting for a free worker apply
+// backpressure and return when their context is done.
+//
+// SigningPool is safe for concurrent use. Close stops pre-signing.
+type SigningPool struct {
+	selector RequirementSelector
+	workers  chan struct{}
+	prefetch int
+	maxAge   time.Duration
+	clock    Clock
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	ready   map[string][]presigned
+	filling map[string]int
+}
+
+// presigned is a payment signed ahead of use.
+type presigned struct {
+	payment *PaymentPayload
+	expires time.Time
+}
+
+// SigningPoolOption configures a SigningPool.
+type SigningPoolOption func(*SigningPool)
+
+// WithSigningWorkers sets the number of concurrent signatures (default:
+// DefaultSigningWorkers).
+func WithSigningWorkers(workers int) SigningPoolOption {
+	return func(p *SigningPool) {
+		if workers > 0 {
+			p.workers = make(chan struct{}, workers)
+		}
+	}
+}
+
+// WithPresign sets how many payments are kept pre-signed per requirement
+// (default: 0, pre-signing disabled).
+func WithPresign(count int) SigningPoolOption {
+	return func(p *SigningPool) {
+		p.prefetch = count
+	}
+}
+
+// WithPresignMaxAge sets how long pre-signed payments are used (default:
+// DefaultPresignMaxAge).
+func WithPresignMaxAge(maxAge time.Duration) SigningPoolOption {
+	return func(p *SigningPool) {
+		p.maxAge = maxAge
+	}
+}
+
+// WithPoolSelector sets the selector choosing the signer and requirement
+// (default: DefaultPaymentSelector).
+func WithPoolSelector(selector RequirementSelector) SigningPoolOption {
+	return func(p *SigningPool) {
+		p.selector = selector
+	}
+}
+
+// WithPoolClock sets the clock used for pre-signed payment expiry.
+func WithPoolClock(clock Clock) SigningPoolOption {
+	return func(p *SigningPool) {
+		p.clock = clock
+	}
+}
+
+// NewSigningPool creates a SigningPool.
+func NewSigningPool(opts ...SigningPoolOption) *SigningPool {
+	p := &SigningPool{
+		selector: NewDefaultPaymentSelector(),
+		workers:  make(chan struct{}, DefaultSigningWorkers),
+		maxAge:   DefaultPresignMaxAge,
+		ready:    make(map[string][]presigned),
+		filling:  make(map[string]int),
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Close stops pre-signing and discards pre-signed payments.
+func (p *SigningPool) Close() error {
+	p.cancel()
+	p.mu.Lock()
+	p.ready = make(map[string][]presigned)
+	p.mu.Unlock()
+	return nil
+}
+
+// Select implements RequirementSelector.
+func (p *SigningPool) Select(signers []Signer, requirements []PaymentRequirements) (Signer, *PaymentRequirements, error) {
+	return p.selector.Select(signers, requirements)
+}
+
+// SelectAndSign implements PaymentSelector.
+func (p *SigningPool) SelectAndSign(signers []Signer, requirements []PaymentRequirements) (*PaymentPayload, error) {
+	return p.SelectAndSignContext(context.Background(), signers, requirements)
+}
+
+// SelectAndSignContext implements ContextPaymentSelector. It returns a
+// pre-signed payment when one is available and otherwise signs on a worker,
+// waiting for one to be free until ctx is done.
+func (p *SigningPool) SelectAndSignContext(ctx context.Context, signers []Signer, requirements []PaymentRequirements) (*PaymentPayload, error) {
+	signer, requirement, err := p.selector.Select(signers, requirements)
+	if err != nil {
+		return nil, err
+	}
+	key, err := presignKey(signers, signer, requirement)
+	if err != nil {
+		return nil, NewPaymentError(ErrCodeInvalidRequirements, "failed to encode requirements", err)
+	}
+
+	payment := p.take(key)
+	if payment == nil {
+		if payment, err = p.sign(ctx, signer, requirement); err != nil {
+			return nil, err
+		}
+	}
+	p.refill(key, signer, *requirement)
+	return payment, nil
+}
+
+// take returns an unexpired pre-signed payment for key, or nil.
+func (p *SigningPool) take(key string) *PaymentPayload {
+	now := ClockOrSystem(p.clock).Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	queue := p.ready[key]
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if now.Before(next.expires) {
+			p.ready[key] = queue
+			return next.payment
+		}
+	}
+	delete(p.ready, key)
+	return nil
+}
+
+// sign signs requirement on a worker, waiting for a free one until ctx is done.
+func (p *SigningPool) sign(ctx context.Context, signer Signer, requirement *PaymentRequirements) (*PaymentPayload, error) {
+	select {
+	case p.workers <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-p.workers }()
+
+	payment, err := signer.Sign(requirement)
+	if err != nil {
+		return nil, NewPaymentError(ErrCodeSigningFailed, "failed to sign payment", err)
+	}
+	return payment, nil
+}
+
+// refill starts pre-signing payments for key until prefetch are ready or
+// being signed.
+func (p *SigningPool) refill(key string, signer Signer, requirement PaymentRequirements) {
+	if p.prefetch <= 0 || p.ctx.Err() != nil {
+		return
+	}
+	if _, ok := requirement.Extra["idempotencyKey"]; ok {
+		return
+	}
+	p.mu.Lock()
+	missing := p.prefetch - len(p.ready[key]) - p.filling[key]
+	if missing > 0 {
+		p.filling[key] += missing
+	}
+	p.mu.Unlock()
+
+	for i := 0; i < missing; i++ {
+		go func() {
+			payment, err := p.sign(p.ctx, signer, &requirement)
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			if p.filling[key]--; p.filling[key] <= 0 {
+				delete(p.filling, key)
+			}
+			if err != nil || p.ctx.Err() != nil {
+				return
+			}
+			p.ready[key] = append(p.ready[key], presigned{
+				payment: payment,
+				expires: ClockOrSystem(p.clock).Now().Add(p.presignMaxAge(requirement)),
+			})
+		}()
+	}
+}
+
+// presignMaxAge returns how long a pre-signed payment for requirement is used.
+func (p *SigningPool) presignMaxAge(requirement PaymentRequirements) time.Duration {
+	maxAge := p.maxAge
+	if requirement.MaxTimeoutSeconds > 0 {
+		if half := time.Duration(requirement.MaxTimeoutSeconds) * time.Second / 2; half < maxAge {
+			maxAge = half
+		}
+	}
+	return maxAge
+}
+
+// presignKey identifies the signer and requirement a payment was signed for.
+func presignKey(signers []Signer, signer Signer, requirement *PaymentRequirements) (string, error) {
+	index := -1
+	if reflect.TypeOf(signer).Comparable() {
+		for i, s := range signers {
+			if reflect.TypeOf(s).Comparable() && s == signer {
+				index = i
+				break
+			}
+		}
+	}
+	encoded, err := json.Marshal(requirement)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d/%T/%s", index, signer, encoded), nil
+}
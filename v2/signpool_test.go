@@ -0,0 +1,145 @@
+package v2
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingSigner numbers its payments and optionally blocks until released.
+type countingSigner struct {
+	mockSigner
+	signed  int32
+	release chan struct{}
+}
+
+func (c *countingSigner) Sign(req *PaymentRequirements) (*PaymentPayload, error) {
+	if c.release != nil {
+		<-c.release
+	}
+	payment, err := c.mockSigner.Sign(req)
+	if err != nil {
+		return nil, err
+	}
+	payment.Accepted.Extra = map[string]interface{}{"n": atomic.AddInt32(&c.signed, 1)}
+	return payment, nil
+}
+
+func poolTestRequirements() []PaymentRequirements {
+	return []PaymentRequirements{{
+		Scheme:            "exact",
+		Network:           "eip155:8453",
+		Amount:            "1000",
+		Asset:             "0xUSDC",
+		PayTo:             "0xmerchant",
+		MaxTimeoutSeconds: 60,
+	}}
+}
+
+func TestSigningPool_Presign(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1_700_000_000, 0))
+	signer := &countingSigner{mockSigner: mockSigner{
+		network: "eip155:8453",
+		scheme:  "exact",
+		tokens:  []TokenConfig{{Address: "0xUSDC"}},
+	}}
+	pool := NewSigningPool(WithPresign(2), WithPoolClock(clock))
+	defer pool.Close()
+	signers := []Signer{signer}
+
+	first, err := pool.SelectAndSign(signers, poolTestRequirements())
+	if err != nil {
+		t.Fatalf("SelectAndSign failed: %v", err)
+	}
+	waitFor(t, func() bool { return atomic.LoadInt32(&signer.signed) == 3 })
+
+	// The next payments are served from the pre-signed queue, each only once
+	seen := map[interface{}]bool{first.Accepted.Extra["n"]: true}
+	for i := 0; i < 2; i++ {
+		payment, err := pool.SelectAndSign(signers, poolTestRequirements())
+		if err != nil {
+			t.Fatalf("SelectAndSign failed: %v", err)
+		}
+		if n := payment.Accepted.Extra["n"]; seen[n] {
+			t.Fatalf("payment %v handed out twice", n)
+		} else {
+			seen[n] = true
+		}
+	}
+
+	// Pre-signed payments older than half the timeout are discarded
+	waitFor(t, func() bool { return atomic.LoadInt32(&signer.signed) == 5 })
+	clock.Advance(30 * time.Second)
+	payment, err := pool.SelectAndSign(signers, poolTestRequirements())
+	if err != nil {
+		t.Fatalf("SelectAndSign failed: %v", err)
+	}
+	if n := payment.Accepted.Extra["n"].(int32); n != 6 {
+		t.Errorf("expected a freshly signed payment, got payment %d", n)
+	}
+}
+
+func TestSigningPool_IdempotencyKey(t *testing.T) {
+	signer := &countingSigner{mockSigner: mockSigner{
+		network: "eip155:8453",
+		scheme:  "exact",
+		tokens:  []TokenConfig{{Address: "0xUSDC"}},
+	}}
+	pool := NewSigningPool(WithPresign(2))
+	defer pool.Close()
+
+	// A keyed requirement signs to the same nonce every time, so nothing is pooled
+	requirements := poolTestRequirements()
+	requirements[0].Extra = map[string]interface{}{"idempotencyKey": "order-1"}
+	for i := int32(1); i <= 2; i++ {
+		if _, err := pool.SelectAndSign([]Signer{signer}, requirements); err != nil {
+			t.Fatalf("SelectAndSign failed: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if n := atomic.LoadInt32(&signer.signed); n != i {
+			t.Fatalf("expected %d signatures without pre-signing, got %d", i, n)
+		}
+	}
+}
+
+func TestSigningPool_Backpressure(t *testing.T) {
+	signer := &countingSigner{
+		mockSigner: mockSigner{network: "eip155:8453", scheme: "exact", tokens: []TokenConfig{{Address: "0xUSDC"}}},
+		release:    make(chan struct{}),
+	}
+	pool := NewSigningPool(WithSigningWorkers(1))
+	defer pool.Close()
+	signers := []Signer{signer}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := pool.SelectAndSign(signers, poolTestRequirements())
+		done <- err
+	}()
+	waitFor(t, func() bool { return len(pool.workers) == 1 })
+
+	// The only worker is busy, so a second caller waits until its context ends
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.SelectAndSignContext(ctx, signers, poolTestRequirements()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	close(signer.release)
+	if err := <-done; err != nil {
+		t.Errorf("first payment failed: %v", err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
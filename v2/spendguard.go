@@ -0,0 +1,368 @@
+package v2
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SharedSpendTimeout bounds the shared counter calls of a payment counted by
+// a SpendGuard created WithSharedSpend.
+const SharedSpendTimeout = 5 * time.Second
+
+// SpendCounter is a counter shared between processes, such as a
+// cluster.Coordinator or a storage.Redis store. IncrBy adds delta to key,
+// creating it with ttl if absent (zero for no expiry), and returns the new
+// value.
+type SpendCounter interface {
+	IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+}
+
+// SpendGuard enforces a hard cap on the total outbound spend of a process.
+// A single guard can be shared by any number of HTTP clients and MCP transports.
+//
+// Every signed payment is counted against the limit before it is sent, whether
+// or not the server ultimately settles it, since a signed authorization can be
+// settled at any time until it expires. Once a payment would exceed the limit the
+// guard trips: that payment and every later one is refused, the Done channel is
+// closed, and the OnLimitReached callback is invoked once.
+//
+// SpendGuard is safe for concurrent use.
+type SpendGuard struct {
+	mu          sync.Mutex
+	limit       *big.Int
+	assetLimits map[string]*big.Int
+	spent       *big.Int
+	assetSpent  map[string]*big.Int
+	tripped     bool
+	done        chan struct{}
+	onLimit     func(SpendGuardStatus)
+	shared      SpendCounter
+	sharedKey   string
+}
+
+// SpendGuardStatus is a snapshot of a SpendGuard's accounting.
+type SpendGuardStatus struct {
+	// Limit is the global limit in atomic units, or nil if only per-asset limits are set.
+	Limit *big.Int
+
+	// Spent is the total amount counted against the global limit, in atomic units.
+	Spent *big.Int
+
+	// Tripped reports whether the guard has stopped accepting payments.
+	Tripped bool
+
+	// Rejected is the payment that tripped the guard (only set in OnLimitReached).
+	Rejected *PaymentRequirements
+}
+
+// SpendGuardOption configures a SpendGuard.
+type SpendGuardOption func(*SpendGuard)
+
+// WithAssetLimit sets an additional limit for a single asset on a single network.
+// Amounts of that asset count against both the asset limit and the global limit.
+func WithAssetLimit(network, asset string, limit *big.Int) SpendGuardOption {
+	return func(g *SpendGuard) {
+		g.assetLimits[assetKey(network, asset)] = new(big.Int).Set(limit)
+	}
+}
+
+// WithOnLimitReached sets a callback invoked once, when the guard trips.
+// Use it to trigger a process shutdown or alert an operator.
+func WithOnLimitReached(callback func(SpendGuardStatus)) SpendGuardOption {
+	return func(g *SpendGuard) {
+		g.onLimit = callback
+	}
+}
+
+// WithSharedSpend counts spending in counter under name, so every process
+// configured with the same counter and name shares one budget. Limits are
+// checked against the shared totals, while Status and Spent report the
+// spending of this process. Amounts must fit in an int64, and a counter error
+// refuses the payment. Counter calls are bounded by the request context and
+// SharedSpendTimeout.
+func WithSharedSpend(counter SpendCounter, name string) SpendGuardOption {
+	return func(g *SpendGuard) {
+		g.shared = counter
+		g.sharedKey = "spend:" + name
+	}
+}
+
+// NewSpendGuard creates a SpendGuard with a global limit in atomic units.
+// The global limit sums amounts across all assets, which is meaningful when all
+// configured assets share the same decimals (e.g., USDC on every chain).
+// Pass nil to rely only on per-asset limits set with WithAssetLimit.
+func NewSpendGuard(limit *big.Int, opts ...SpendGuardOption) *SpendGuard {
+	g := &SpendGuard{
+		assetLimits: make(map[string]*big.Int),
+		spent:       new(big.Int),
+		assetSpent:  make(map[string]*big.Int),
+		done:        make(chan struct{}),
+	}
+	if limit != nil {
+		g.limit = new(big.Int).Set(limit)
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Reserve counts a payment for the given requirements against the limits.
+// Returns a PaymentError with ErrCodeSpendLimitExceeded if the payment would
+// exceed a limit or the guard has already tripped.
+func (g *SpendGuard) Reserve(requirements PaymentRequirements) error {
+	return g.ReserveContext(context.Background(), requirements)
+}
+
+// ReserveContext is like Reserve, bounding the shared counter calls of a
+// guard created WithSharedSpend by ctx and SharedSpendTimeout.
+func (g *SpendGuard) ReserveContext(ctx context.Context, requirements PaymentRequirements) error {
+	amount, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok {
+		return NewPaymentError(ErrCodeInvalidRequirements, "invalid amount in requirements", ErrInvalidAmount)
+	}
+	key := assetKey(requirements.Network, requirements.Asset)
+
+	g.mu.Lock()
+	if g.tripped {
+		g.mu.Unlock()
+		return g.limitError(requirements)
+	}
+	if g.shared == nil {
+		newSpent := new(big.Int).Add(g.spent, amount)
+		newAssetSpent := new(big.Int).Add(valueOrZero(g.assetSpent[key]), amount)
+		if g.exceeds(key, newSpent, newAssetSpent) {
+			return g.tripLocked(requirements)
+		}
+		g.spent = newSpent
+		g.assetSpent[key] = newAssetSpent
+		g.mu.Unlock()
+		return nil
+	}
+	g.mu.Unlock()
+
+	// The shared counter is called without holding g.mu, so a slow counter
+	// only delays the payments waiting for it
+	ctx, cancel := context.WithTimeout(ctx, SharedSpendTimeout)
+	defer cancel()
+	exceeds, err := g.reserveShared(ctx, key, amount)
+	if err != nil {
+		return NewPaymentError(ErrCodeSpendLimitExceeded, "shared spend counter unavailable", err)
+	}
+
+	g.mu.Lock()
+	if exceeds {
+		return g.tripLocked(requirements)
+	}
+	if g.tripped {
+		// Another payment tripped the guard meanwhile; this one is refused too
+		g.mu.Unlock()
+		_, _, _ = g.sharedTotals(ctx, key, -amount.Int64())
+		return g.limitError(requirements)
+	}
+	g.spent = new(big.Int).Add(g.spent, amount)
+	g.assetSpent[key] = new(big.Int).Add(valueOrZero(g.assetSpent[key]), amount)
+	g.mu.Unlock()
+	return nil
+}
+
+// tripLocked trips the guard, if not yet tripped, and returns the error for
+// requirements. Callers must hold g.mu, which it releases.
+func (g *SpendGuard) tripLocked(requirements PaymentRequirements) error {
+	if g.tripped {
+		g.mu.Unlock()
+		return g.limitError(requirements)
+	}
+	g.tripped = true
+	close(g.done)
+	status := g.statusLocked()
+	g.mu.Unlock()
+
+	if g.onLimit != nil {
+		rejected := requirements
+		status.Rejected = &rejected
+		g.onLimit(status)
+	}
+	return g.limitError(requirements)
+}
+
+// Check reports whether a payment for the given requirements would be accepted,
+// without counting it or tripping the guard. It is used by dry-run mode.
+func (g *SpendGuard) Check(requirements PaymentRequirements) error {
+	return g.CheckContext(context.Background(), requirements)
+}
+
+// CheckContext is like Check, bounding the shared counter calls of a guard
+// created WithSharedSpend by ctx and SharedSpendTimeout.
+func (g *SpendGuard) CheckContext(ctx context.Context, requirements PaymentRequirements) error {
+	amount, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok {
+		return NewPaymentError(ErrCodeInvalidRequirements, "invalid amount in requirements", ErrInvalidAmount)
+	}
+	key := assetKey(requirements.Network, requirements.Asset)
+
+	g.mu.Lock()
+	tripped := g.tripped
+	newSpent := new(big.Int).Add(g.spent, amount)
+	newAssetSpent := new(big.Int).Add(valueOrZero(g.assetSpent[key]), amount)
+	g.mu.Unlock()
+
+	if g.shared != nil && !tripped {
+		ctx, cancel := context.WithTimeout(ctx, SharedSpendTimeout)
+		defer cancel()
+		spent, assetSpent, err := g.sharedTotals(ctx, key, 0)
+		if err != nil {
+			return NewPaymentError(ErrCodeSpendLimitExceeded, "shared spend counter unavailable", err)
+		}
+		newSpent = new(big.Int).Add(spent, amount)
+		newAssetSpent = new(big.Int).Add(assetSpent, amount)
+	}
+	if tripped || g.exceeds(key, newSpent, newAssetSpent) {
+		return g.limitError(requirements)
+	}
+	return nil
+}
+
+// reserveShared adds amount to the shared totals, rolling back if a limit is
+// exceeded.
+func (g *SpendGuard) reserveShared(ctx context.Context, key string, amount *big.Int) (bool, error) {
+	if !amount.IsInt64() {
+		return false, fmt.Errorf("amount %s too large for a shared spend counter", amount)
+	}
+	spent, assetSpent, err := g.sharedTotals(ctx, key, amount.Int64())
+	if err != nil {
+		return false, err
+	}
+	if !g.exceeds(key, spent, assetSpent) {
+		return false, nil
+	}
+	if _, _, err := g.sharedTotals(ctx, key, -amount.Int64()); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// sharedTotals adds delta to the shared global and asset totals and returns
+// them.
+func (g *SpendGuard) sharedTotals(ctx context.Context, key string, delta int64) (*big.Int, *big.Int, error) {
+	spent, err := g.shared.IncrBy(ctx, g.sharedKey, delta, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	assetSpent, err := g.shared.IncrBy(ctx, g.sharedKey+"|"+key, delta, 0)
+	if err != nil {
+		if delta != 0 {
+			_, _ = g.shared.IncrBy(ctx, g.sharedKey, -delta, 0)
+		}
+		return nil, nil, err
+	}
+	return big.NewInt(spent), big.NewInt(assetSpent), nil
+}
+
+// spendGuardKey is the context key for request-scoped spend guards.
+type spendGuardKey struct{}
+
+// ContextWithSpendGuard returns a copy of ctx carrying guard. Transports count
+// the payments of a request made with this context against guard, in addition
+// to their configured guard, so a budget can apply to a subset of the requests
+// of a shared client (e.g., one GraphQL operation).
+func ContextWithSpendGuard(ctx context.Context, guard *SpendGuard) context.Context {
+	return context.WithValue(ctx, spendGuardKey{}, guard)
+}
+
+// SpendGuardFromContext returns the spend guard carried by ctx, or nil.
+func SpendGuardFromContext(ctx context.Context) *SpendGuard {
+	guard, _ := ctx.Value(spendGuardKey{}).(*SpendGuard)
+	return guard
+}
+
+// ReserveSpend counts a payment for requirements against guard, if set, and
+// the guard carried by ctx. The guard carried by ctx is checked first, so a
+// payment it refuses is not counted against guard.
+func ReserveSpend(ctx context.Context, guard *SpendGuard, requirements PaymentRequirements) error {
+	scoped := SpendGuardFromContext(ctx)
+	if scoped != nil {
+		if err := scoped.CheckContext(ctx, requirements); err != nil {
+			return err
+		}
+	}
+	if guard != nil {
+		if err := guard.ReserveContext(ctx, requirements); err != nil {
+			return err
+		}
+	}
+	if scoped != nil {
+		return scoped.ReserveContext(ctx, requirements)
+	}
+	return nil
+}
+
+// Done returns a channel that is closed when the guard trips.
+func (g *SpendGuard) Done() <-chan struct{} {
+	return g.done
+}
+
+// Status returns a snapshot of the guard's accounting.
+func (g *SpendGuard) Status() SpendGuardStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.statusLocked()
+}
+
+// Spent returns the amount counted against the given asset, in atomic units.
+func (g *SpendGuard) Spent(network, asset string) *big.Int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return new(big.Int).Set(valueOrZero(g.assetSpent[assetKey(network, asset)]))
+}
+
+// exceeds reports whether the new totals would exceed a limit. The limits
+// are fixed at construction, so it does not need g.mu.
+func (g *SpendGuard) exceeds(key string, newSpent, newAssetSpent *big.Int) bool {
+	if g.limit != nil && newSpent.Cmp(g.limit) > 0 {
+		return true
+	}
+	assetLimit, ok := g.assetLimits[key]
+	return ok && newAssetSpent.Cmp(assetLimit) > 0
+}
+
+// statusLocked builds a status snapshot. Callers must hold g.mu.
+func (g *SpendGuard) statusLocked() SpendGuardStatus {
+	status := SpendGuardStatus{
+		Spent:   new(big.Int).Set(g.spent),
+		Tripped: g.tripped,
+	}
+	if g.limit != nil {
+		status.Limit = new(big.Int).Set(g.limit)
+	}
+	return status
+}
+
+// limitError builds the error returned for refused payments.
+func (g *SpendGuard) limitError(requirements PaymentRequirements) error {
+	return NewPaymentError(ErrCodeSpendLimitExceeded, "spend limit reached", ErrSpendLimitExceeded).
+		WithDetails("network", requirements.Network).
+		WithDetails("asset", requirements.Asset).
+		WithDetails("amount", requirements.Amount)
+}
+
+// assetKey builds the map key for per-asset accounting.
+// EVM addresses are case-insensitive, so asset addresses starting with 0x are lowercased.
+func assetKey(network, asset string) string {
+	if strings.HasPrefix(asset, "0x") {
+		asset = strings.ToLower(asset)
+	}
+	return network + "|" + asset
+}
+
+// valueOrZero returns v, or zero if v is nil.
+func valueOrZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
@@ -0,0 +1,226 @@
+package v2
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSpendGuard_GlobalLimit(t *testing.T) {
+	var reached []SpendGuardStatus
+	guard := NewSpendGuard(big.NewInt(25000), WithOnLimitReached(func(status SpendGuardStatus) {
+		reached = append(reached, status)
+	}))
+
+	req := PaymentRequirements{Network: NetworkBaseSepolia, Asset: BaseSepolia.USDCAddress, Amount: "10000"}
+
+	for i := 0; i < 2; i++ {
+		if err := guard.Reserve(req); err != nil {
+			t.Fatalf("reserve %d: unexpected error: %v", i, err)
+		}
+	}
+
+	err := guard.Reserve(req)
+	if !errors.Is(err, ErrSpendLimitExceeded) {
+		t.Fatalf("expected ErrSpendLimitExceeded, got %v", err)
+	}
+	var paymentErr *PaymentError
+	if !errors.As(err, &paymentErr) || paymentErr.Code != ErrCodeSpendLimitExceeded {
+		t.Errorf("expected PaymentError with code %s, got %v", ErrCodeSpendLimitExceeded, err)
+	}
+
+	select {
+	case <-guard.Done():
+	default:
+		t.Error("expected Done channel to be closed")
+	}
+
+	// Once tripped, even small payments are refused and the callback is not repeated
+	small := req
+	small.Amount = "1"
+	if err := guard.Reserve(small); !errors.Is(err, ErrSpendLimitExceeded) {
+		t.Errorf("expected tripped guard to refuse payment, got %v", err)
+	}
+
+	if len(reached) != 1 {
+		t.Fatalf("expected OnLimitReached once, got %d", len(reached))
+	}
+	if reached[0].Spent.String() != "20000" || reached[0].Rejected == nil || reached[0].Rejected.Amount != "10000" {
+		t.Errorf("unexpected status: %+v", reached[0])
+	}
+	if !guard.Status().Tripped {
+		t.Error("expected status to report tripped")
+	}
+}
+
+func TestSpendGuard_AssetLimit(t *testing.T) {
+	guard := NewSpendGuard(nil, WithAssetLimit(NetworkBase, BaseMainnet.USDCAddress, big.NewInt(100)))
+
+	// Asset addresses are compared case-insensitively for EVM
+	base := PaymentRequirements{Network: NetworkBase, Asset: "0x833589FCD6EDB6E08F4C7C32D4F71B54BDA02913", Amount: "60"}
+	other := PaymentRequirements{Network: NetworkPolygon, Asset: PolygonMainnet.USDCAddress, Amount: "1000"}
+
+	if err := guard.Reserve(base); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := guard.Reserve(other); err != nil {
+		t.Fatalf("unlimited asset should be allowed: %v", err)
+	}
+	if got := guard.Spent(NetworkBase, BaseMainnet.USDCAddress); got.String() != "60" {
+		t.Errorf("expected 60 spent on Base, got %s", got)
+	}
+	if err := guard.Reserve(base); !errors.Is(err, ErrSpendLimitExceeded) {
+		t.Errorf("expected asset limit to trip, got %v", err)
+	}
+}
+
+func TestSpendGuard_InvalidAmount(t *testing.T) {
+	guard := NewSpendGuard(big.NewInt(1))
+	if err := guard.Reserve(PaymentRequirements{Amount: "abc"}); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("expected ErrInvalidAmount, got %v", err)
+	}
+}
+
+func TestSpendGuard_Concurrent(t *testing.T) {
+	guard := NewSpendGuard(big.NewInt(50))
+	req := PaymentRequirements{Network: NetworkBase, Asset: BaseMainnet.USDCAddress, Amount: "1"}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if guard.Reserve(req) == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted > 50 {
+		t.Errorf("guard accepted %d payments over a limit of 50", accepted)
+	}
+}
+
+// mapCounter is an in-memory SpendCounter standing in for a shared store.
+type mapCounter struct {
+	mu     sync.Mutex
+	values map[string]int64
+}
+
+func (c *mapCounter) IncrBy(_ context.Context, key string, delta int64, _ time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.values == nil {
+		c.values = make(map[string]int64)
+	}
+	c.values[key] += delta
+	return c.values[key], nil
+}
+
+func TestSpendGuard_SharedSpend(t *testing.T) {
+	counter := &mapCounter{}
+	req := PaymentRequirements{Network: NetworkBase, Asset: BaseMainnet.USDCAddress, Amount: "40"}
+
+	// Two processes sharing one budget
+	a := NewSpendGuard(big.NewInt(100), WithSharedSpend(counter, "fleet"))
+	b := NewSpendGuard(big.NewInt(100), WithSharedSpend(counter, "fleet"))
+
+	if err := a.Reserve(req); err != nil {
+		t.Fatalf("reserve on a: %v", err)
+	}
+	if err := b.Reserve(req); err != nil {
+		t.Fatalf("reserve on b: %v", err)
+	}
+	if err := a.Check(req); !errors.Is(err, ErrSpendLimitExceeded) {
+		t.Errorf("expected Check to see the shared total, got %v", err)
+	}
+	if err := a.Reserve(req); !errors.Is(err, ErrSpendLimitExceeded) {
+		t.Fatalf("expected shared limit to trip, got %v", err)
+	}
+
+	// The refused payment is rolled back from the shared total
+	if got := counter.values["spend:fleet"]; got != 80 {
+		t.Errorf("shared total = %d, want 80", got)
+	}
+	// Status reports the spending of this process only
+	if got := b.Status().Spent.String(); got != "40" {
+		t.Errorf("local spent = %s, want 40", got)
+	}
+
+	// Other budgets are independent
+	other := NewSpendGuard(big.NewInt(100), WithSharedSpend(counter, "other"))
+	if err := other.Reserve(req); err != nil {
+		t.Errorf("reserve on independent budget: %v", err)
+	}
+}
+
+// stalledCounter blocks until its context is done, like an unreachable store.
+type stalledCounter struct{}
+
+func (stalledCounter) IncrBy(ctx context.Context, _ string, _ int64, _ time.Duration) (int64, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func TestSpendGuard_SharedSpendStalled(t *testing.T) {
+	guard := NewSpendGuard(big.NewInt(100), WithSharedSpend(stalledCounter{}, "fleet"))
+	req := PaymentRequirements{Network: NetworkBase, Asset: BaseMainnet.USDCAddress, Amount: "40"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	reserved := make(chan error, 1)
+	go func() { reserved <- ReserveSpend(ctx, guard, req) }()
+
+	// The guard stays usable while the counter call is pending
+	status := make(chan SpendGuardStatus, 1)
+	go func() { status <- guard.Status() }()
+	select {
+	case <-status:
+	case <-time.After(time.Second):
+		t.Fatal("Status blocked on the shared counter")
+	}
+
+	// The request context bounds the counter call, and the payment is refused
+	select {
+	case err := <-reserved:
+		var paymentErr *PaymentError
+		if !errors.As(err, &paymentErr) || paymentErr.Code != ErrCodeSpendLimitExceeded || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the payment to be refused with the context error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Reserve ignored the request deadline")
+	}
+}
+
+func TestReserveSpend_ContextGuard(t *testing.T) {
+	global := NewSpendGuard(big.NewInt(100))
+	scoped := NewSpendGuard(big.NewInt(30))
+	ctx := ContextWithSpendGuard(context.Background(), scoped)
+	req := PaymentRequirements{Network: NetworkBaseSepolia, Asset: BaseSepolia.USDCAddress, Amount: "20"}
+
+	if err := ReserveSpend(ctx, global, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The scoped guard refuses the payment before the global guard counts it
+	if err := ReserveSpend(ctx, global, req); !errors.Is(err, ErrSpendLimitExceeded) {
+		t.Fatalf("expected ErrSpendLimitExceeded, got %v", err)
+	}
+	if spent := global.Status().Spent.String(); spent != "20" {
+		t.Errorf("global guard spent %s, want 20", spent)
+	}
+	// Requests without the scoped guard are only limited by the global one
+	if err := ReserveSpend(context.Background(), global, req); err != nil {
+		t.Errorf("unexpected error without scoped guard: %v", err)
+	}
+	if scoped.Status().Tripped {
+		t.Error("expected scoped guard not to trip on a refused check")
+	}
+}
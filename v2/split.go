@@ -0,0 +1,114 @@
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// RevenueSplitExtension is the extension identifier facilitators list in
+// SupportedResponse.Extensions when they settle split payments.
+const RevenueSplitExtension = "revenue-split"
+
+// SplitExtraKey is the PaymentRequirements.Extra key holding revenue splits.
+const SplitExtraKey = "splits"
+
+// Split routes a share of a payment to an additional recipient, such as a
+// platform fee. Shares are in basis points of the payment amount; whatever is
+// not split off goes to the requirement's PayTo.
+type Split struct {
+	// Recipient is the address receiving the share.
+	Recipient string `json:"recipient"`
+
+	// Bps is the share in basis points (1/100 of a percent).
+	Bps int `json:"bps"`
+}
+
+// Payout is the amount one recipient receives from a split payment.
+type Payout struct {
+	// Recipient is the receiving address.
+	Recipient string `json:"recipient"`
+
+	// Amount is the payout in atomic units.
+	Amount string `json:"amount"`
+}
+
+// WithSplits returns a copy of requirements carrying splits in Extra, so the
+// facilitator settles the payment across all recipients.
+func WithSplits(requirements PaymentRequirements, splits []Split) (PaymentRequirements, error) {
+	if err := validateSplits(splits); err != nil {
+		return requirements, err
+	}
+
+	extra := make(map[string]interface{}, len(requirements.Extra)+1)
+	for key, value := range requirements.Extra {
+		extra[key] = value
+	}
+	extra[SplitExtraKey] = append([]Split(nil), splits...)
+	requirements.Extra = extra
+	return requirements, nil
+}
+
+// SplitsFromRequirements returns the splits in requirements' Extra, whether
+// stored as []Split or decoded from JSON. It returns nil if there are none.
+func SplitsFromRequirements(requirements PaymentRequirements) ([]Split, error) {
+	value, ok := requirements.Extra[SplitExtraKey]
+	if !ok {
+		return nil, nil
+	}
+	if splits, ok := value.([]Split); ok {
+		return splits, validateSplits(splits)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid splits: %w", err)
+	}
+	var splits []Split
+	if err := json.Unmarshal(data, &splits); err != nil {
+		return nil, fmt.Errorf("invalid splits: %w", err)
+	}
+	return splits, validateSplits(splits)
+}
+
+// ComputePayouts divides the requirement's amount between the split recipients
+// and PayTo. Split shares are rounded down; PayTo receives the remainder, so the
+// payouts always sum to the full amount.
+func ComputePayouts(requirements PaymentRequirements) ([]Payout, error) {
+	amount, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, ErrInvalidAmount
+	}
+	splits, err := SplitsFromRequirements(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	payouts := make([]Payout, 0, len(splits)+1)
+	remainder := new(big.Int).Set(amount)
+	for _, split := range splits {
+		share := new(big.Int).Mul(amount, big.NewInt(int64(split.Bps)))
+		share.Quo(share, big.NewInt(10_000))
+		remainder.Sub(remainder, share)
+		payouts = append(payouts, Payout{Recipient: split.Recipient, Amount: share.String()})
+	}
+	return append([]Payout{{Recipient: requirements.PayTo, Amount: remainder.String()}}, payouts...), nil
+}
+
+// validateSplits checks that shares are positive and sum to at most 100%.
+func validateSplits(splits []Split) error {
+	total := 0
+	for _, split := range splits {
+		if split.Recipient == "" {
+			return fmt.Errorf("invalid splits: recipient cannot be empty")
+		}
+		if split.Bps <= 0 {
+			return fmt.Errorf("invalid splits: share for %s must be positive, got %d bps", split.Recipient, split.Bps)
+		}
+		total += split.Bps
+	}
+	if total > 10_000 {
+		return fmt.Errorf("invalid splits: shares total %d bps, exceeding 10000", total)
+	}
+	return nil
+}
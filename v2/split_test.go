@@ -0,0 +1,98 @@
+package v2
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestComputePayouts(t *testing.T) {
+	base := PaymentRequirements{
+		Scheme:  "exact",
+		Network: NetworkBaseSepolia,
+		Amount:  "1001",
+		PayTo:   "0xseller",
+	}
+
+	tests := []struct {
+		name    string
+		splits  []Split
+		want    []Payout
+		wantErr bool
+	}{
+		{
+			name: "no splits",
+			want: []Payout{{Recipient: "0xseller", Amount: "1001"}},
+		},
+		{
+			name:   "platform fee rounds down, remainder to payTo",
+			splits: []Split{{Recipient: "0xplatform", Bps: 250}},
+			want:   []Payout{{Recipient: "0xseller", Amount: "976"}, {Recipient: "0xplatform", Amount: "25"}},
+		},
+		{
+			name:   "full split",
+			splits: []Split{{Recipient: "0xa", Bps: 5000}, {Recipient: "0xb", Bps: 5000}},
+			want:   []Payout{{Recipient: "0xseller", Amount: "1"}, {Recipient: "0xa", Amount: "500"}, {Recipient: "0xb", Amount: "500"}},
+		},
+		{name: "over 100%", splits: []Split{{Recipient: "0xa", Bps: 6000}, {Recipient: "0xb", Bps: 5000}}, wantErr: true},
+		{name: "zero share", splits: []Split{{Recipient: "0xa", Bps: 0}}, wantErr: true},
+		{name: "missing recipient", splits: []Split{{Bps: 100}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := base
+			if tt.splits != nil {
+				var err error
+				req, err = WithSplits(base, tt.splits)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("WithSplits() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if tt.wantErr {
+					return
+				}
+			}
+
+			payouts, err := ComputePayouts(req)
+			if err != nil {
+				t.Fatalf("ComputePayouts() error = %v", err)
+			}
+			if len(payouts) != len(tt.want) {
+				t.Fatalf("got %d payouts, want %d", len(payouts), len(tt.want))
+			}
+			for i := range payouts {
+				if payouts[i] != tt.want[i] {
+					t.Errorf("payout[%d] = %+v, want %+v", i, payouts[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSplitsFromRequirements_JSON(t *testing.T) {
+	req, err := WithSplits(PaymentRequirements{Amount: "100", PayTo: "0xseller"}, []Split{{Recipient: "0xplatform", Bps: 1000}})
+	if err != nil {
+		t.Fatalf("WithSplits() error = %v", err)
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	var decoded PaymentRequirements
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+
+	splits, err := SplitsFromRequirements(decoded)
+	if err != nil {
+		t.Fatalf("SplitsFromRequirements() error = %v", err)
+	}
+	if len(splits) != 1 || splits[0] != (Split{Recipient: "0xplatform", Bps: 1000}) {
+		t.Errorf("unexpected splits: %+v", splits)
+	}
+
+	decoded.Extra[SplitExtraKey] = "not a list"
+	if _, err := SplitsFromRequirements(decoded); err == nil {
+		t.Error("expected malformed splits to be rejected")
+	}
+}
@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// Disk is a Store keeping each key in a file of a directory, so state survives
+// restarts of a single process, e.g. a client's cache. Writes replace files
+// atomically, but conditional writes are only atomic within one process:
+// do not share a directory between processes that Add or CompareAndSwap.
+// Expired keys are removed lazily and by Sweep.
+type Disk struct {
+	dir   string
+	clock v2.Clock
+
+	mu sync.Mutex
+}
+
+// DiskOption configures a Disk store.
+type DiskOption func(*Disk)
+
+// WithDiskClock sets the clock used to expire keys (default: v2.SystemClock).
+func WithDiskClock(clock v2.Clock) DiskOption {
+	return func(d *Disk) {
+		d.clock = clock
+	}
+}
+
+// NewDisk creates a Disk store in dir, creating the directory if needed.
+func NewDisk(dir string, opts ...DiskOption) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("storage: creating %s: %w", dir, err)
+	}
+	d := &Disk{dir: dir}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.clock = v2.ClockOrSystem(d.clock)
+	return d, nil
+}
+
+// Get returns the value of key, or ErrNotFound.
+func (d *Disk) Get(_ context.Context, key string) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	value, ok, err := d.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+// Set stores value at key.
+func (d *Disk) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.store(key, value, ttl)
+}
+
+// Add stores value at key only if key is absent.
+func (d *Disk) Add(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok, err := d.lookup(key); err != nil || ok {
+		return false, err
+	}
+	return true, d.store(key, value, ttl)
+}
+
+// CompareAndSwap replaces the value of key with new only if it is old.
+func (d *Disk) CompareAndSwap(_ context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	value, ok, err := d.lookup(key)
+	if err != nil || !ok || !bytes.Equal(value, old) {
+		return false, err
+	}
+	return true, d.store(key, new, ttl)
+}
+
+// Delete removes key.
+func (d *Disk) Delete(_ context.Context, key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := os.Remove(d.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("storage: deleting %s: %w", key, err)
+	}
+	return nil
+}
+
+// IncrBy adds delta to the integer at key.
+func (d *Disk) IncrBy(_ context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	path := d.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, fmt.Errorf("storage: reading %s: %w", key, err)
+	}
+	var current int64
+	if err == nil {
+		if value, expired := decodeDiskEntry(data, d.clock.Now()); !expired {
+			if current, err = strconv.ParseInt(string(value), 10, 64); err != nil {
+				return 0, fmt.Errorf("storage: value of %s is not an integer", key)
+			}
+			// Keep the expiry of the existing key
+			current += delta
+			return current, d.write(key, append(data[:8:8], strconv.FormatInt(current, 10)...))
+		}
+	}
+	current = delta
+	return current, d.store(key, []byte(strconv.FormatInt(current, 10)), ttl)
+}
+
+// Sweep removes expired keys.
+func (d *Disk) Sweep() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return fmt.Errorf("storage: reading %s: %w", d.dir, err)
+	}
+	now := d.clock.Now()
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".entry" {
+			continue
+		}
+		path := filepath.Join(d.dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if _, expired := decodeDiskEntry(data, now); expired {
+			_ = os.Remove(path)
+		}
+	}
+	return nil
+}
+
+// path returns the file of key. Keys are hashed, so any key maps to a valid
+// file name.
+func (d *Disk) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+".entry")
+}
+
+// lookup returns the live value of key, removing it if expired. The caller
+// holds d.mu.
+func (d *Disk) lookup(key string) ([]byte, bool, error) {
+	path := d.path(key)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("storage: reading %s: %w", key, err)
+	}
+	value, expired := decodeDiskEntry(data, d.clock.Now())
+	if expired {
+		_ = os.Remove(path)
+		return nil, false, nil
+	}
+	return value, true, nil
+}
+
+// store writes value at key, expiring after ttl. The caller holds d.mu.
+func (d *Disk) store(key string, value []byte, ttl time.Duration) error {
+	var expires int64
+	if ttl > 0 {
+		expires = d.clock.Now().Add(ttl).UnixNano()
+	}
+	data := binary.BigEndian.AppendUint64(nil, uint64(expires))
+	return d.write(key, append(data, value...))
+}
+
+// write writes the file of key to a temporary file and renames it into
+// place. The caller holds d.mu.
+func (d *Disk) write(key string, data []byte) error {
+	tmp, err := os.CreateTemp(d.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("storage: writing %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("storage: writing %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("storage: writing %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), d.path(key)); err != nil {
+		return fmt.Errorf("storage: writing %s: %w", key, err)
+	}
+	return nil
+}
+
+// decodeDiskEntry splits a file into its value and whether it expired at now.
+// Files too short to hold an expiry are treated as expired.
+func decodeDiskEntry(data []byte, now time.Time) ([]byte, bool) {
+	if len(data) < 8 {
+		return nil, true
+	}
+	expires := int64(binary.BigEndian.Uint64(data[:8]))
+	if expires != 0 && !now.Before(time.Unix(0, expires)) {
+		return nil, true
+	}
+	return data[8:], false
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// Memory is a Store kept in process memory. Expired keys are removed lazily
+// and by Sweep.
+type Memory struct {
+	clock v2.Clock
+
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// MemoryOption configures a Memory store.
+type MemoryOption func(*Memory)
+
+// WithMemoryClock sets the clock used to expire keys (default: v2.SystemClock).
+func WithMemoryClock(clock v2.Clock) MemoryOption {
+	return func(m *Memory) {
+		m.clock = clock
+	}
+}
+
+// NewMemory creates an empty Memory store.
+func NewMemory(opts ...MemoryOption) *Memory {
+	m := &Memory{entries: make(map[string]memoryEntry)}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.clock = v2.ClockOrSystem(m.clock)
+	return m
+}
+
+// Get returns the value of key, or ErrNotFound.
+func (m *Memory) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.lookup(key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return bytes.Clone(entry.value), nil
+}
+
+// Set stores value at key.
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store(key, value, ttl)
+	return nil
+}
+
+// Add stores value at key only if key is absent.
+func (m *Memory) Add(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.lookup(key); ok {
+		return false, nil
+	}
+	m.store(key, value, ttl)
+	return true, nil
+}
+
+// CompareAndSwap replaces the value of key with new only if it is old.
+func (m *Memory) CompareAndSwap(_ context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.lookup(key)
+	if !ok || !bytes.Equal(entry.value, old) {
+		return false, nil
+	}
+	m.store(key, new, ttl)
+	return true, nil
+}
+
+// Delete removes key.
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+// IncrBy adds delta to the integer at key.
+func (m *Memory) IncrBy(_ context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var current int64
+	entry, ok := m.lookup(key)
+	if ok {
+		var err error
+		if current, err = strconv.ParseInt(string(entry.value), 10, 64); err != nil {
+			return 0, fmt.Errorf("storage: value of %s is not an integer", key)
+		}
+	}
+	current += delta
+	value := []byte(strconv.FormatInt(current, 10))
+	if ok {
+		m.entries[key] = memoryEntry{value: value, expires: entry.expires}
+	} else {
+		m.store(key, value, ttl)
+	}
+	return current, nil
+}
+
+// Len returns the number of keys, including expired keys not yet swept.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
+
+// Sweep removes expired keys.
+func (m *Memory) Sweep() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	for key, entry := range m.entries {
+		if !entry.expires.IsZero() && !now.Before(entry.expires) {
+			delete(m.entries, key)
+		}
+	}
+}
+
+// lookup returns the live entry of key, removing it if expired. The caller
+// holds m.mu.
+func (m *Memory) lookup(key string) (memoryEntry, bool) {
+	entry, ok := m.entries[key]
+	if !ok {
+		return memoryEntry{}, false
+	}
+	if !entry.expires.IsZero() && !m.clock.Now().Before(entry.expires) {
+		delete(m.entries, key)
+		return memoryEntry{}, false
+	}
+	return entry, true
+}
+
+// store sets key. The caller holds m.mu.
+func (m *Memory) store(key string, value []byte, ttl time.Duration) {
+	entry := memoryEntry{value: bytes.Clone(value)}
+	if ttl > 0 {
+		entry.expires = m.clock.Now().Add(ttl)
+	}
+	m.entries[key] = entry
+}
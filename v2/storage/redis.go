@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// RedisFunc sends one command to Redis and returns its reply, with nil for a
+// nil reply. It adapts any client library; with go-redis:
+//
+//	storage.NewRedis(func(ctx context.Context, args ...any) (any, error) {
+//		reply, err := rdb.Do(ctx, args...).Result()
+//		if errors.Is(err, redis.Nil) {
+//			return nil, nil
+//		}
+//		return reply, err
+//	})
+type RedisFunc func(ctx context.Context, args ...any) (any, error)
+
+// Redis is a Store kept in Redis. Conditional writes run as Lua scripts, so
+// they are atomic on a single Redis server or cluster shard.
+type Redis struct {
+	do RedisFunc
+}
+
+// NewRedis creates a Redis store sending commands with do.
+func NewRedis(do RedisFunc) *Redis {
+	return &Redis{do: do}
+}
+
+// redisCompareAndSwap sets KEYS[1] to ARGV[2] if it holds ARGV[1], expiring
+// it after ARGV[3] milliseconds unless that is 0.
+const redisCompareAndSwap = `if redis.call("GET", KEYS[1]) ~= ARGV[1] then return 0 end
+if ARGV[3] == "0" then redis.call("SET", KEYS[1], ARGV[2]) else redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3]) end
+return 1`
+
+// redisIncrBy adds ARGV[1] to KEYS[1], expiring a new key after ARGV[2]
+// milliseconds unless that is 0.
+const redisIncrBy = `local created = redis.call("EXISTS", KEYS[1]) == 0
+local value = redis.call("INCRBY", KEYS[1], ARGV[1])
+if created and ARGV[2] ~= "0" then redis.call("PEXPIRE", KEYS[1], ARGV[2]) end
+return value`
+
+// Get returns the value of key, or ErrNotFound.
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := r.do(ctx, "GET", key)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, ErrNotFound
+	}
+	return redisBytes(reply)
+}
+
+// Set stores value at key.
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []any{"SET", key, value}
+	if ttl > 0 {
+		args = append(args, "PX", redisMillis(ttl))
+	}
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+// Add stores value at key only if key is absent.
+func (r *Redis) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	args := []any{"SET", key, value, "NX"}
+	if ttl > 0 {
+		args = append(args, "PX", redisMillis(ttl))
+	}
+	reply, err := r.do(ctx, args...)
+	return reply != nil, err
+}
+
+// CompareAndSwap replaces the value of key with new only if it is old.
+func (r *Redis) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	reply, err := r.do(ctx, "EVAL", redisCompareAndSwap, 1, key, old, new, redisMillis(ttl))
+	if err != nil {
+		return false, err
+	}
+	n, err := redisInt(reply)
+	return n == 1, err
+}
+
+// Delete removes key.
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	_, err := r.do(ctx, "DEL", key)
+	return err
+}
+
+// IncrBy adds delta to the integer at key, keeping the expiry of an existing
+// key.
+func (r *Redis) IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	reply, err := r.do(ctx, "EVAL", redisIncrBy, 1, key, delta, redisMillis(ttl))
+	if err != nil {
+		return 0, err
+	}
+	return redisInt(reply)
+}
+
+// redisMillis formats ttl as a script argument, "0" meaning no expiry.
+func redisMillis(ttl time.Duration) string {
+	if ttl <= 0 {
+		return "0"
+	}
+	return strconv.FormatInt(max(ttl.Milliseconds(), 1), 10)
+}
+
+// redisBytes converts a bulk string reply.
+func redisBytes(reply any) ([]byte, error) {
+	switch v := reply.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("storage: unexpected Redis reply %T", reply)
+	}
+}
+
+// redisInt converts an integer reply.
+func redisInt(reply any) (int64, error) {
+	switch v := reply.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case []byte, string:
+		b, _ := redisBytes(v)
+		return strconv.ParseInt(string(b), 10, 64)
+	case nil:
+		return 0, errors.New("storage: nil Redis reply")
+	default:
+		return 0, fmt.Errorf("storage: unexpected Redis reply %T", reply)
+	}
+}
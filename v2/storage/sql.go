@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// DefaultSQLTable is the table used by SQL unless WithSQLTable is given.
+const DefaultSQLTable = "x402_storage"
+
+// sqlTableName restricts table names, which cannot be bound as parameters.
+var sqlTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// SQL is a Store kept in a database table. It works with SQLite (3.24 or
+// later) and PostgreSQL; use WithDollarPlaceholders for PostgreSQL drivers.
+// Create the table with CreateTable. Expired rows are ignored and removed by
+// Sweep.
+type SQL struct {
+	db     *sql.DB
+	table  string
+	dollar bool
+	clock  v2.Clock
+}
+
+// SQLOption configures a SQL store.
+type SQLOption func(*SQL)
+
+// WithSQLTable sets the table name (default: DefaultSQLTable).
+func WithSQLTable(table string) SQLOption {
+	return func(s *SQL) {
+		s.table = table
+	}
+}
+
+// WithDollarPlaceholders writes query parameters as $1, $2, ... as PostgreSQL
+// drivers expect, instead of ?.
+func WithDollarPlaceholders() SQLOption {
+	return func(s *SQL) {
+		s.dollar = true
+	}
+}
+
+// WithSQLClock sets the clock used to expire keys (default: v2.SystemClock).
+func WithSQLClock(clock v2.Clock) SQLOption {
+	return func(s *SQL) {
+		s.clock = clock
+	}
+}
+
+// NewSQL creates a SQL store on db.
+func NewSQL(db *sql.DB, opts ...SQLOption) (*SQL, error) {
+	s := &SQL{db: db, table: DefaultSQLTable}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if !sqlTableName.MatchString(s.table) {
+		return nil, fmt.Errorf("storage: invalid table name %q", s.table)
+	}
+	s.clock = v2.ClockOrSystem(s.clock)
+	return s, nil
+}
+
+// CreateTable creates the table if it does not exist.
+func (s *SQL) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s (k TEXT PRIMARY KEY, v BYTEA NOT NULL, expires_at BIGINT NOT NULL)`, s.table))
+	return err
+}
+
+// Get returns the value of key, or ErrNotFound.
+func (s *SQL) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.QueryRowContext(ctx,
+		s.query(`SELECT v FROM %s WHERE k = ? AND (expires_at = 0 OR expires_at > ?)`),
+		key, s.now()).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+// Set stores value at key.
+func (s *SQL) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := s.db.ExecContext(ctx,
+		s.query(`INSERT INTO %s (k, v, expires_at) VALUES (?, ?, ?) ON CONFLICT (k) DO UPDATE SET v = excluded.v, expires_at = excluded.expires_at`),
+		key, value, s.expiresAt(ttl))
+	return err
+}
+
+// Add stores value at key only if key is absent or expired.
+func (s *SQL) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		s.query(`INSERT INTO %[1]s (k, v, expires_at) VALUES (?, ?, ?) ON CONFLICT (k) DO UPDATE SET v = excluded.v, expires_at = excluded.expires_at WHERE %[1]s.expires_at <> 0 AND %[1]s.expires_at <= ?`),
+		key, value, s.expiresAt(ttl), s.now())
+	return affected(result, err)
+}
+
+// CompareAndSwap replaces the value of key with new only if it is old.
+func (s *SQL) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		s.query(`UPDATE %s SET v = ?, expires_at = ? WHERE k = ? AND v = ? AND (expires_at = 0 OR expires_at > ?)`),
+		new, s.expiresAt(ttl), key, old, s.now())
+	return affected(result, err)
+}
+
+// Delete removes key.
+func (s *SQL) Delete(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, s.query(`DELETE FROM %s WHERE k = ?`), key)
+	return err
+}
+
+// IncrBy adds delta to the integer at key, keeping the expiry of an existing
+// key.
+func (s *SQL) IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		var value []byte
+		var expiresAt int64
+		err := s.db.QueryRowContext(ctx,
+			s.query(`SELECT v, expires_at FROM %s WHERE k = ? AND (expires_at = 0 OR expires_at > ?)`),
+			key, s.now()).Scan(&value, &expiresAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			added, err := s.Add(ctx, key, []byte(strconv.FormatInt(delta, 10)), ttl)
+			if err != nil || added {
+				return delta, err
+			}
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		current, err := strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("storage: value of %s is not an integer", key)
+		}
+		next := []byte(strconv.FormatInt(current+delta, 10))
+		result, err := s.db.ExecContext(ctx,
+			s.query(`UPDATE %s SET v = ? WHERE k = ? AND v = ? AND expires_at = ?`),
+			next, key, value, expiresAt)
+		if swapped, err := affected(result, err); err != nil || swapped {
+			return current + delta, err
+		}
+	}
+	return 0, ErrConflict
+}
+
+// Sweep deletes expired rows.
+func (s *SQL) Sweep(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, s.query(`DELETE FROM %s WHERE expires_at <> 0 AND expires_at <= ?`), s.now())
+	return err
+}
+
+// query formats a statement for the table and placeholder style.
+func (s *SQL) query(format string) string {
+	q := fmt.Sprintf(format, s.table)
+	if !s.dollar {
+		return q
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range q {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// now returns the current time in the unit of expires_at.
+func (s *SQL) now() int64 {
+	return s.clock.Now().UnixNano()
+}
+
+// expiresAt returns the expires_at column for ttl, 0 meaning never.
+func (s *SQL) expiresAt(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return s.clock.Now().Add(ttl).UnixNano()
+}
+
+// affected reports whether a conditional statement changed a row.
+func affected(result sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
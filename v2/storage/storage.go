@@ -0,0 +1,159 @@
+// Package storage defines the key-value store shared by the components that
+// keep state across requests and replicas, so one backing store can be
+// configured for all of them.
+//
+// Memory keeps state in the process, Disk in files of a local directory, SQL
+// in a database table (SQLite or PostgreSQL) and Redis in a Redis server.
+// Components sharing a store should each use a Prefixed view of it.
+//
+// Stores back payment claims and settlement reuse (cluster.New), paid
+// response caching (http.WithPaymentCacheStore), used direct-payment
+// transactions (direct.WithUsedStore), MCP session billing and quotas
+// (server.WithBillingStore), MCP vouchers (server.WithVoucherStore and
+// client.WithVoucherStore), passkeys (passkey.WithCredentialStore) and
+// WalletConnect sessions (evm.WithWalletConnectStore).
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrNotFound is returned by Store.Get for missing or expired keys.
+var ErrNotFound = errors.New("storage: key not found")
+
+// ErrConflict is returned by Update when the key kept changing concurrently.
+var ErrConflict = errors.New("storage: too many concurrent updates")
+
+// maxUpdateAttempts bounds the compare-and-swap retries of Update.
+const maxUpdateAttempts = 16
+
+// Store is a key-value store with expiring keys and atomic conditional writes.
+// A ttl of zero means the key does not expire. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Get returns the value of key, or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set stores value at key, replacing any previous value.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Add stores value at key only if key is absent, reporting whether it did.
+	Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+
+	// CompareAndSwap replaces the value of key with new only if it is old,
+	// reporting whether it did.
+	CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error)
+
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Incrementer is implemented by stores that add to integer values natively.
+// IncrBy uses it when available.
+type Incrementer interface {
+	// IncrBy adds delta to the integer at key, creating it with ttl if absent,
+	// and returns the new value.
+	IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+}
+
+// Update atomically replaces the value of key with the result of fn, which
+// receives the current value and whether key exists. It retries fn when key
+// changes concurrently and fails with ErrConflict if it keeps changing. An
+// error from fn aborts the update and is returned.
+func Update(ctx context.Context, s Store, key string, ttl time.Duration, fn func(old []byte, found bool) ([]byte, error)) ([]byte, error) {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		old, err := s.Get(ctx, key)
+		found := err == nil
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+
+		value, err := fn(old, found)
+		if err != nil {
+			return nil, err
+		}
+
+		var swapped bool
+		if found {
+			if bytes.Equal(old, value) {
+				return value, nil
+			}
+			swapped, err = s.CompareAndSwap(ctx, key, old, value, ttl)
+		} else {
+			swapped, err = s.Add(ctx, key, value, ttl)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if swapped {
+			return value, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return nil, ErrConflict
+}
+
+// IncrBy adds delta to the integer stored at key in decimal, creating it with
+// ttl if absent, and returns the new value. Stores that do not implement
+// Incrementer restart the ttl on every increment.
+func IncrBy(ctx context.Context, s Store, key string, delta int64, ttl time.Duration) (int64, error) {
+	if incrementer, ok := s.(Incrementer); ok {
+		return incrementer.IncrBy(ctx, key, delta, ttl)
+	}
+
+	var result int64
+	_, err := Update(ctx, s, key, ttl, func(old []byte, found bool) ([]byte, error) {
+		var current int64
+		if found {
+			var err error
+			if current, err = strconv.ParseInt(string(old), 10, 64); err != nil {
+				return nil, fmt.Errorf("storage: value of %s is not an integer", key)
+			}
+		}
+		result = current + delta
+		return []byte(strconv.FormatInt(result, 10)), nil
+	})
+	return result, err
+}
+
+// Prefixed returns a view of s that prepends prefix to every key, so several
+// components can share one store without colliding.
+func Prefixed(s Store, prefix string) Store {
+	return prefixed{store: s, prefix: prefix}
+}
+
+type prefixed struct {
+	store  Store
+	prefix string
+}
+
+func (p prefixed) Get(ctx context.Context, key string) ([]byte, error) {
+	return p.store.Get(ctx, p.prefix+key)
+}
+
+func (p prefixed) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return p.store.Set(ctx, p.prefix+key, value, ttl)
+}
+
+func (p prefixed) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return p.store.Add(ctx, p.prefix+key, value, ttl)
+}
+
+func (p prefixed) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	return p.store.CompareAndSwap(ctx, p.prefix+key, old, new, ttl)
+}
+
+func (p prefixed) Delete(ctx context.Context, key string) error {
+	return p.store.Delete(ctx, p.prefix+key)
+}
+
+func (p prefixed) IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return IncrBy(ctx, p.store, p.prefix+key, delta, ttl)
+}
@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// stores returns every Store implementation driven by clock.
+func stores(t *testing.T, clock *v2.FakeClock) map[string]Store {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", "file:"+t.Name()+"?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("Failed to open SQLite: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	sqlStore, err := NewSQL(db, WithSQLClock(clock))
+	if err != nil {
+		t.Fatalf("NewSQL failed: %v", err)
+	}
+	if err := sqlStore.CreateTable(context.Background()); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	disk, err := NewDisk(t.TempDir(), WithDiskClock(clock))
+	if err != nil {
+		t.Fatalf("NewDisk failed: %v", err)
+	}
+
+	return map[string]Store{
+		"memory":   NewMemory(WithMemoryClock(clock)),
+		"disk":     disk,
+		"sql":      sqlStore,
+		"redis":    NewRedis(newFakeRedis(clock).do),
+		"prefixed": Prefixed(NewMemory(WithMemoryClock(clock)), "app:"),
+	}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range stores(t, v2.NewFakeClock(time.Unix(1700000000, 0))) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound, got %v", err)
+			}
+
+			if err := store.Set(ctx, "k", []byte("a"), 0); err != nil {
+				t.Fatalf("Set failed: %v", err)
+			}
+			if value, err := store.Get(ctx, "k"); err != nil || string(value) != "a" {
+				t.Errorf("Get = %q, %v; want a", value, err)
+			}
+
+			if added, err := store.Add(ctx, "k", []byte("b"), 0); err != nil || added {
+				t.Errorf("Add on an existing key = %v, %v", added, err)
+			}
+			if added, err := store.Add(ctx, "new", []byte("b"), 0); err != nil || !added {
+				t.Errorf("Add on a new key = %v, %v", added, err)
+			}
+
+			if swapped, err := store.CompareAndSwap(ctx, "k", []byte("x"), []byte("c"), 0); err != nil || swapped {
+				t.Errorf("CompareAndSwap with a stale value = %v, %v", swapped, err)
+			}
+			if swapped, err := store.CompareAndSwap(ctx, "k", []byte("a"), []byte("c"), 0); err != nil || !swapped {
+				t.Errorf("CompareAndSwap = %v, %v", swapped, err)
+			}
+			if value, _ := store.Get(ctx, "k"); string(value) != "c" {
+				t.Errorf("Expected c after CompareAndSwap, got %q", value)
+			}
+
+			if err := store.Delete(ctx, "k"); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if _, err := store.Get(ctx, "k"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound after Delete, got %v", err)
+			}
+			if err := store.Delete(ctx, "k"); err != nil {
+				t.Errorf("Deleting a missing key failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestStore_Expiry(t *testing.T) {
+	ctx := context.Background()
+	clock := v2.NewFakeClock(time.Unix(1700000000, 0))
+	for name, store := range stores(t, clock) {
+		t.Run(name, func(t *testing.T) {
+			if err := store.Set(ctx, "k", []byte("a"), time.Minute); err != nil {
+				t.Fatalf("Set failed: %v", err)
+			}
+			if _, err := IncrBy(ctx, store, "n", 1, time.Minute); err != nil {
+				t.Fatalf("IncrBy failed: %v", err)
+			}
+			clock.Advance(30 * time.Second)
+			if _, err := store.Get(ctx, "k"); err != nil {
+				t.Errorf("Expected key before expiry, got %v", err)
+			}
+			if added, _ := store.Add(ctx, "k", []byte("b"), time.Minute); added {
+				t.Error("Expected Add to fail before expiry")
+			}
+			// Incrementing does not extend the expiry of an existing key
+			if _, err := IncrBy(ctx, store, "n", 1, time.Minute); err != nil {
+				t.Fatalf("IncrBy failed: %v", err)
+			}
+
+			clock.Advance(31 * time.Second)
+			if _, err := store.Get(ctx, "k"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound after expiry, got %v", err)
+			}
+			if n, err := IncrBy(ctx, store, "n", 1, time.Minute); err != nil || n != 1 {
+				t.Errorf("Expected the counter to restart after expiry, got %d, %v", n, err)
+			}
+			if added, err := store.Add(ctx, "k", []byte("b"), time.Minute); err != nil || !added {
+				t.Errorf("Expected Add to succeed after expiry, got %v, %v", added, err)
+			}
+		})
+	}
+}
+
+func TestIncrBy_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range stores(t, v2.NewFakeClock(time.Unix(1700000000, 0))) {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := IncrBy(ctx, store, "counter", 5, 0); err != nil {
+						t.Errorf("IncrBy failed: %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+			if value, _ := store.Get(ctx, "counter"); string(value) != "100" {
+				t.Errorf("Expected 100, got %q", value)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+
+	withdraw := func(amount int) func([]byte, bool) ([]byte, error) {
+		return func(old []byte, found bool) ([]byte, error) {
+			balance, _ := strconv.Atoi(string(old))
+			if balance < amount {
+				return nil, fmt.Errorf("insufficient credit: %d", balance)
+			}
+			return []byte(strconv.Itoa(balance - amount)), nil
+		}
+	}
+
+	_ = store.Set(ctx, "credit", []byte("10"), 0)
+	if value, err := Update(ctx, store, "credit", 0, withdraw(4)); err != nil || string(value) != "6" {
+		t.Errorf("Update = %q, %v; want 6", value, err)
+	}
+	if _, err := Update(ctx, store, "credit", 0, withdraw(7)); err == nil {
+		t.Error("Expected the update function's error")
+	}
+	if value, _ := store.Get(ctx, "credit"); string(value) != "6" {
+		t.Errorf("Expected a failed update to leave 6, got %q", value)
+	}
+}
+
+func TestNewSQL_InvalidTable(t *testing.T) {
+	if _, err := NewSQL(nil, WithSQLTable("x; DROP TABLE users")); err == nil {
+		t.Error("Expected an invalid table name to be rejected")
+	}
+	s, _ := NewSQL(nil, WithDollarPlaceholders())
+	if got := s.query(`SELECT v FROM %s WHERE k = ? AND v = ?`); got != `SELECT v FROM x402_storage WHERE k = $1 AND v = $2` {
+		t.Errorf("Unexpected query: %s", got)
+	}
+}
+
+// fakeRedis interprets the commands and scripts sent by Redis on top of a
+// Memory store.
+type fakeRedis struct {
+	mu    sync.Mutex
+	store *Memory
+}
+
+func newFakeRedis(clock v2.Clock) *fakeRedis {
+	return &fakeRedis{store: NewMemory(WithMemoryClock(clock))}
+}
+
+func (f *fakeRedis) do(ctx context.Context, args ...any) (any, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	arg := func(i int) string {
+		switch v := args[i].(type) {
+		case []byte:
+			return string(v)
+		default:
+			return fmt.Sprint(v)
+		}
+	}
+	ttl := func(ms string) time.Duration {
+		n, _ := strconv.ParseInt(ms, 10, 64)
+		return time.Duration(n) * time.Millisecond
+	}
+
+	switch arg(0) {
+	case "GET":
+		value, err := f.store.Get(ctx, arg(1))
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return value, err
+	case "SET":
+		var expiry time.Duration
+		nx := false
+		for i := 3; i < len(args); i++ {
+			switch arg(i) {
+			case "NX":
+				nx = true
+			case "PX":
+				i++
+				expiry = ttl(arg(i))
+			}
+		}
+		if nx {
+			added, err := f.store.Add(ctx, arg(1), []byte(arg(2)), expiry)
+			if !added {
+				return nil, err
+			}
+			return "OK", err
+		}
+		return "OK", f.store.Set(ctx, arg(1), []byte(arg(2)), expiry)
+	case "DEL":
+		return int64(1), f.store.Delete(ctx, arg(1))
+	case "EVAL":
+		switch arg(1) {
+		case redisCompareAndSwap:
+			swapped, err := f.store.CompareAndSwap(ctx, arg(3), []byte(arg(4)), []byte(arg(5)), ttl(arg(6)))
+			if swapped {
+				return int64(1), err
+			}
+			return int64(0), err
+		case redisIncrBy:
+			delta, _ := strconv.ParseInt(arg(4), 10, 64)
+			return f.store.IncrBy(ctx, arg(3), delta, ttl(arg(5)))
+		}
+	}
+	return nil, fmt.Errorf("fake Redis: unsupported command %v", args)
+}
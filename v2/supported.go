@@ -0,0 +1,167 @@
+package v2
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// FacilitatorSignersExtraKey is the PaymentRequirements.Extra key listing the
+// facilitator signer addresses expected to submit the payment, as advertised
+// in SupportedResponse.Signers.
+const FacilitatorSignersExtraKey = "facilitatorSigners"
+
+// MatchNetworkPattern reports whether a CAIP-2 network matches pattern, which
+// is a network like "eip155:8453", a namespace wildcard like "solana:*", or "*".
+func MatchNetworkPattern(pattern, network string) bool {
+	if pattern == "*" || pattern == network {
+		return true
+	}
+	namespace, ok := strings.CutSuffix(pattern, ":*")
+	return ok && strings.HasPrefix(network, namespace+":")
+}
+
+// Kind returns the supported kind for network and scheme at X402Version.
+// Facilitators serving several protocol versions list a kind per version;
+// kinds of other versions are never returned, so their extra data is not
+// mixed into v2 requirements. A kind without a version is returned only if
+// none lists X402Version.
+func (s *SupportedResponse) Kind(network, scheme string) (SupportedKind, bool) {
+	return s.KindFor(X402Version, network, scheme)
+}
+
+// KindFor returns the supported kind for network and scheme at version, or
+// else the first listed without a version.
+func (s *SupportedResponse) KindFor(version int, network, scheme string) (SupportedKind, bool) {
+	unversioned := -1
+	for i, kind := range s.Kinds {
+		if kind.Network != network || kind.Scheme != scheme {
+			continue
+		}
+		if kind.X402Version == version {
+			return kind, true
+		}
+		if kind.X402Version == 0 && unversioned < 0 {
+			unversioned = i
+		}
+	}
+	if unversioned >= 0 {
+		return s.Kinds[unversioned], true
+	}
+	return SupportedKind{}, false
+}
+
+// Versions returns the protocol versions at which network and scheme are
+// supported, in ascending order, excluding kinds without a version.
+func (s *SupportedResponse) Versions(network, scheme string) []int {
+	var versions []int
+	for _, kind := range s.Kinds {
+		if kind.Network == network && kind.Scheme == scheme && kind.X402Version > 0 && !slices.Contains(versions, kind.X402Version) {
+			versions = append(versions, kind.X402Version)
+		}
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// NegotiateVersion returns the protocol version to speak with the facilitator
+// for network and scheme, which is X402Version when it is supported. It fails
+// with ErrUnsupportedVersion if the facilitator supports them only at other
+// versions, and with ErrUnsupportedScheme if it does not support them at all.
+func (s *SupportedResponse) NegotiateVersion(network, scheme string) (int, error) {
+	if _, ok := s.Kind(network, scheme); ok {
+		return X402Version, nil
+	}
+	if versions := s.Versions(network, scheme); len(versions) > 0 {
+		return 0, fmt.Errorf("%w: facilitator supports %s on %s only at x402Version %v", ErrUnsupportedVersion, scheme, network, versions)
+	}
+	return 0, fmt.Errorf("%w: facilitator does not support %s on %s", ErrUnsupportedScheme, scheme, network)
+}
+
+// SignersFor returns the signer addresses advertised for network, as resolved
+// by SignersForNetwork.
+func (s *SupportedResponse) SignersFor(network string) []string {
+	return SignersForNetwork(s.Signers, network)
+}
+
+// SignersForNetwork returns the addresses signers lists for network, keyed by
+// network pattern (see MatchNetworkPattern). Addresses listed under the exact
+// network come first, followed by those of matching wildcard patterns, more
+// specific patterns first; duplicates are removed. It returns nil if no
+// pattern matches.
+func SignersForNetwork(signers map[string][]string, network string) []string {
+	patterns := make([]string, 0, len(signers))
+	for pattern := range signers {
+		if pattern != network && MatchNetworkPattern(pattern, network) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	// More specific patterns first: "solana:*" before "*".
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	var matched []string
+	seen := make(map[string]bool)
+	for _, pattern := range append([]string{network}, patterns...) {
+		for _, signer := range signers[pattern] {
+			if !seen[signer] {
+				seen[signer] = true
+				matched = append(matched, signer)
+			}
+		}
+	}
+	return matched
+}
+
+// WithFacilitatorSigners returns a copy of requirements listing signers under
+// FacilitatorSignersExtraKey.
+func WithFacilitatorSigners(requirements PaymentRequirements, signers []string) PaymentRequirements {
+	extra := make(map[string]interface{}, len(requirements.Extra)+1)
+	for key, value := range requirements.Extra {
+		extra[key] = value
+	}
+	extra[FacilitatorSignersExtraKey] = append([]string(nil), signers...)
+	requirements.Extra = extra
+	return requirements
+}
+
+// FacilitatorSignersFromRequirements returns the facilitator signers listed in
+// requirements' Extra, whether stored as []string or decoded from JSON.
+func FacilitatorSignersFromRequirements(requirements PaymentRequirements) []string {
+	switch value := requirements.Extra[FacilitatorSignersExtraKey].(type) {
+	case []string:
+		return value
+	case []interface{}:
+		signers := make([]string, 0, len(value))
+		for _, v := range value {
+			if signer, ok := v.(string); ok {
+				signers = append(signers, signer)
+			}
+		}
+		return signers
+	default:
+		return nil
+	}
+}
+
+// ValidateFeePayer checks that the fee payer in requirements.Extra["feePayer"]
+// is one of signers. Requirements without a fee payer are valid. Addresses on
+// EVM networks are compared case-insensitively.
+func ValidateFeePayer(requirements PaymentRequirements, signers []string) error {
+	feePayer, _ := requirements.Extra["feePayer"].(string)
+	if feePayer == "" {
+		return nil
+	}
+	networkType, _ := ValidateNetwork(requirements.Network)
+	for _, signer := range signers {
+		if signer == feePayer || (networkType == NetworkTypeEVM && strings.EqualFold(signer, feePayer)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s on %s", ErrUntrustedFeePayer, feePayer, requirements.Network)
+}
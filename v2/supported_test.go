@@ -0,0 +1,157 @@
+package v2
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMatchNetworkPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		network string
+		want    bool
+	}{
+		{"eip155:8453", "eip155:8453", true},
+		{"eip155:8453", "eip155:84532", false},
+		{"eip155:*", "eip155:84532", true},
+		{"solana:*", "eip155:8453", false},
+		{"solana:*", "solana", false},
+		{"*", "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp", true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchNetworkPattern(tt.pattern, tt.network); got != tt.want {
+			t.Errorf("MatchNetworkPattern(%q, %q) = %v; want %v", tt.pattern, tt.network, got, tt.want)
+		}
+	}
+}
+
+func TestSupportedResponse_SignersFor(t *testing.T) {
+	supported := &SupportedResponse{
+		Signers: map[string][]string{
+			"*":        {"fallback"},
+			"solana:*": {"solana-signer"},
+			"solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp": {"mainnet-signer", "solana-signer"},
+		},
+	}
+
+	tests := []struct {
+		network string
+		want    []string
+	}{
+		{"solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp", []string{"mainnet-signer", "solana-signer", "fallback"}},
+		{"solana:EtWTRABZaYq6iMfeYKouRu166VU2xqa1", []string{"solana-signer", "fallback"}},
+		{"eip155:8453", []string{"fallback"}},
+	}
+
+	for _, tt := range tests {
+		if got := supported.SignersFor(tt.network); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SignersFor(%q) = %v; want %v", tt.network, got, tt.want)
+		}
+	}
+}
+
+func TestValidateFeePayer(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     PaymentRequirements
+		signers []string
+		wantErr bool
+	}{
+		{
+			name:    "no fee payer",
+			req:     PaymentRequirements{Network: "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp"},
+			signers: nil,
+		},
+		{
+			name:    "advertised signer",
+			req:     PaymentRequirements{Network: "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp", Extra: map[string]interface{}{"feePayer": "signer"}},
+			signers: []string{"other", "signer"},
+		},
+		{
+			name:    "unknown signer",
+			req:     PaymentRequirements{Network: "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp", Extra: map[string]interface{}{"feePayer": "attacker"}},
+			signers: []string{"signer"},
+			wantErr: true,
+		},
+		{
+			name:    "evm addresses ignore case",
+			req:     PaymentRequirements{Network: "eip155:8453", Extra: map[string]interface{}{"feePayer": "0xABCDEF0123456789ABCDEF0123456789ABCDEF01"}},
+			signers: []string{"0xabcdef0123456789abcdef0123456789abcdef01"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFeePayer(tt.req, tt.signers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateFeePayer() error = %v; wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrUntrustedFeePayer) {
+				t.Errorf("expected ErrUntrustedFeePayer, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFacilitatorSignersFromRequirements(t *testing.T) {
+	req := WithFacilitatorSigners(PaymentRequirements{}, []string{"a", "b"})
+	if got := FacilitatorSignersFromRequirements(req); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("got %v", got)
+	}
+
+	decoded := PaymentRequirements{Extra: map[string]interface{}{FacilitatorSignersExtraKey: []interface{}{"a", "b"}}}
+	if got := FacilitatorSignersFromRequirements(decoded); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("got %v from decoded JSON", got)
+	}
+}
+
+func TestSupportedResponse_NegotiateVersion(t *testing.T) {
+	supported := &SupportedResponse{Kinds: []SupportedKind{
+		{X402Version: 1, Scheme: "exact", Network: "eip155:8453", Extra: map[string]interface{}{"v": 1}},
+		{X402Version: 2, Scheme: "exact", Network: "eip155:8453", Extra: map[string]interface{}{"v": 2}},
+		{X402Version: 1, Scheme: "exact", Network: "eip155:84532"},
+		{Scheme: "upto", Network: "eip155:8453"},
+	}}
+
+	tests := []struct {
+		name    string
+		network string
+		scheme  string
+		want    int
+		wantErr error
+	}{
+		{name: "v2 preferred over v1", network: "eip155:8453", scheme: "exact", want: X402Version},
+		{name: "v1 only", network: "eip155:84532", scheme: "exact", wantErr: ErrUnsupportedVersion},
+		{name: "unversioned kind", network: "eip155:8453", scheme: "upto", want: X402Version},
+		{name: "unsupported", network: "solana:devnet", scheme: "exact", wantErr: ErrUnsupportedScheme},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := supported.NegotiateVersion(tt.network, tt.scheme)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("NegotiateVersion() = %d, %v; want %d", got, err, tt.want)
+			}
+		})
+	}
+
+	// Kind never mixes in the extra data of another version
+	kind, ok := supported.Kind("eip155:8453", "exact")
+	if !ok || kind.Extra["v"] != 2 {
+		t.Errorf("expected the v2 kind, got %+v", kind)
+	}
+	if _, ok := supported.Kind("eip155:84532", "exact"); ok {
+		t.Error("expected no v2 kind for a v1-only network")
+	}
+	if got := supported.Versions("eip155:8453", "exact"); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("Versions() = %v", got)
+	}
+}
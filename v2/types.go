@@ -9,7 +9,10 @@
 // Import path: github.com/mark3labs/x402-go/v2
 package v2
 
-import "math/big"
+import (
+	"encoding/json"
+	"math/big"
+)
 
 // Protocol version constant
 const X402Version = 2
@@ -49,6 +52,10 @@ type PaymentRequirements struct {
 
 	// Extra contains scheme-specific additional data.
 	Extra map[string]interface{} `json:"extra,omitempty"`
+
+	// UnknownFields holds the JSON fields this type does not define, kept
+	// verbatim and written back when it is encoded.
+	UnknownFields map[string]json.RawMessage `json:"-"`
 }
 
 // Extension represents a protocol extension with its data and schema.
@@ -56,10 +63,21 @@ type Extension struct {
 	// Info contains the extension data.
 	Info map[string]interface{} `json:"info"`
 
-	// Schema contains the JSON schema for validating info (passthrough only, not validated).
+	// Schema contains the JSON schema for validating info (see the extensions
+	// package).
 	Schema map[string]interface{} `json:"schema"`
 }
 
+// RequirementsAttestationExtension is the PaymentRequired extension carrying a
+// signature over the requirements (see the attestation package). Facilitators
+// list it in SupportedResponse.Extensions when they publish AttestationKeys.
+const RequirementsAttestationExtension = "requirements-attestation"
+
+// PriceQuoteExtension is the PaymentRequired extension carrying a signed,
+// expiring price quote (see the quote package). Clients echo it unchanged in
+// PaymentPayload.Extensions so the server honors the quoted price.
+const PriceQuoteExtension = "price-quote"
+
 // PaymentRequired is the 402 response body sent by resource servers.
 type PaymentRequired struct {
 	// X402Version is the protocol version (2 for v2).
@@ -74,8 +92,19 @@ type PaymentRequired struct {
 	// Accepts is an array of payment options the server will accept.
 	Accepts []PaymentRequirements `json:"accepts"`
 
-	// Extensions contains protocol extensions (passthrough, not validated).
+	// DocsURL optionally links to documentation on paying for the resource.
+	DocsURL string `json:"docsUrl,omitempty"`
+
+	// Support is an optional support contact, such as an email address or URL.
+	Support string `json:"support,omitempty"`
+
+	// Extensions contains protocol extensions, keyed by name (see the
+	// extensions package).
 	Extensions map[string]Extension `json:"extensions,omitempty"`
+
+	// UnknownFields holds the JSON fields this type does not define, kept
+	// verbatim and written back when it is encoded.
+	UnknownFields map[string]json.RawMessage `json:"-"`
 }
 
 // PaymentPayload is sent by clients to pay for resources.
@@ -94,8 +123,13 @@ type PaymentPayload struct {
 	// For Solana: SVMPayload with partially signed transaction
 	Payload interface{} `json:"payload"`
 
-	// Extensions contains protocol extensions (passthrough, not validated).
+	// Extensions contains protocol extensions, keyed by name (see the
+	// extensions package).
 	Extensions map[string]Extension `json:"extensions,omitempty"`
+
+	// UnknownFields holds the JSON fields this type does not define, kept
+	// verbatim and written back when it is encoded.
+	UnknownFields map[string]json.RawMessage `json:"-"`
 }
 
 // EVMPayload contains EIP-3009 authorization data for EVM payments.
@@ -128,6 +162,114 @@ type EVMAuthorization struct {
 	Nonce string `json:"nonce"`
 }
 
+// Asset transfer methods for EVM "exact" payments, selected by the server via
+// the requirement's Extra["assetTransferMethod"]. EIP-3009 is the default.
+const (
+	AssetTransferMethodEIP3009 = "eip3009"
+	AssetTransferMethodPermit2 = "permit2"
+
+	// AssetTransferMethodAllowance pays from a treasury that approved the
+	// relayer in Extra["spender"] to spend its tokens: a hot key signs a relay
+	// order (see AllowancePayload) that the relayer executes with transferFrom.
+	AssetTransferMethodAllowance = "allowance"
+)
+
+// Permit2Payload contains a Uniswap Permit2 SignatureTransfer authorization. It lets
+// any ERC-20 approved to the Permit2 contract be paid by signature, with the
+// spender pulling the funds at settlement.
+type Permit2Payload struct {
+	// Signature is the hex-encoded ECDSA signature over the permit.
+	Signature string `json:"signature"`
+
+	// Permit2Authorization contains the signed PermitWitnessTransferFrom parameters.
+	Permit2Authorization Permit2Authorization `json:"permit2Authorization"`
+}
+
+// Permit2Authorization contains PermitWitnessTransferFrom parameters.
+type Permit2Authorization struct {
+	// From is the payer's address.
+	From string `json:"from"`
+
+	// Permitted is the token and maximum amount the spender may transfer.
+	Permitted Permit2TokenPermissions `json:"permitted"`
+
+	// Spender is the address allowed to execute the transfer (usually the facilitator).
+	Spender string `json:"spender"`
+
+	// Nonce is the unordered Permit2 nonce as a decimal string.
+	Nonce string `json:"nonce"`
+
+	// Deadline is the unix timestamp after which the permit is invalid.
+	Deadline string `json:"deadline"`
+
+	// Witness binds the recipient and validity start into the signature.
+	Witness Permit2Witness `json:"witness"`
+}
+
+// Permit2TokenPermissions is the token and amount covered by a permit.
+type Permit2TokenPermissions struct {
+	// Token is the ERC-20 contract address.
+	Token string `json:"token"`
+
+	// Amount is the permitted amount in atomic units.
+	Amount string `json:"amount"`
+}
+
+// Permit2Witness is the payment data signed alongside a permit.
+type Permit2Witness struct {
+	// To is the payment recipient's address.
+	To string `json:"to"`
+
+	// ValidAfter is the unix timestamp after which the permit may be used.
+	ValidAfter string `json:"validAfter"`
+
+	// Extra is hex-encoded additional data (usually "0x").
+	Extra string `json:"extra"`
+}
+
+// AllowancePayload contains a relay order for an ERC-20 allowance payment. The
+// hot key in Signer authorizes the spender, usually the facilitator, to
+// transferFrom the treasury in Owner, which approved the spender beforehand.
+// The relayer decides which hot keys may sign for which treasuries.
+type AllowancePayload struct {
+	// Signature is the hex-encoded EIP-712 signature of the hot key over the
+	// relay order.
+	Signature string `json:"signature"`
+
+	// AllowanceAuthorization contains the signed relay order.
+	AllowanceAuthorization AllowanceAuthorization `json:"allowanceAuthorization"`
+}
+
+// AllowanceAuthorization contains the parameters of a relay order.
+type AllowanceAuthorization struct {
+	// Owner is the treasury address the tokens are transferred from.
+	Owner string `json:"owner"`
+
+	// Signer is the hot key address that signed the order.
+	Signer string `json:"signer"`
+
+	// Spender is the relayer address approved by the treasury.
+	Spender string `json:"spender"`
+
+	// Token is the ERC-20 contract address.
+	Token string `json:"token"`
+
+	// To is the payment recipient's address.
+	To string `json:"to"`
+
+	// Value is the payment amount in atomic units.
+	Value string `json:"value"`
+
+	// ValidAfter is the unix timestamp after which the order is valid.
+	ValidAfter string `json:"validAfter"`
+
+	// ValidBefore is the unix timestamp before which the order is valid.
+	ValidBefore string `json:"validBefore"`
+
+	// Nonce is a unique 32-byte hex string to prevent replay attacks.
+	Nonce string `json:"nonce"`
+}
+
 // SVMPayload contains a partially signed Solana transaction.
 type SVMPayload struct {
 	// Transaction is the base64-encoded partially signed Solana transaction.
@@ -135,6 +277,36 @@ type SVMPayload struct {
 	Transaction string `json:"transaction"`
 }
 
+// StellarPayload contains a signed Stellar payment transaction.
+type StellarPayload struct {
+	// Transaction is the base64-encoded XDR TransactionEnvelope, signed by the payer.
+	// The facilitator may wrap it in a fee-bump transaction before submitting it.
+	Transaction string `json:"transaction"`
+}
+
+// TronPayload contains a TIP-712 TransferWithAuthorization signature for a
+// TRC-20 token. TIP-712 hashes typed data exactly like EIP-712, using the
+// 20-byte account of each TRON address; From and To are base58check addresses.
+type TronPayload struct {
+	// Signature is the hex-encoded TIP-712 signature.
+	Signature string `json:"signature"`
+
+	// Authorization contains the signed transfer parameters.
+	Authorization EVMAuthorization `json:"authorization"`
+}
+
+// DirectPayload identifies an ERC-20 transfer the payer already submitted
+// on-chain, for the direct scheme (see SchemeDirect).
+type DirectPayload struct {
+	// Transaction is the hex-encoded hash of the transfer transaction.
+	Transaction string `json:"transaction"`
+
+	// Signature is the payer's hex-encoded EIP-191 signature over the
+	// transaction hash. It proves the payment is presented by the account that
+	// sent the transfer, since transaction hashes are public.
+	Signature string `json:"signature"`
+}
+
 // VerifyResponse is returned by the facilitator /verify endpoint.
 // Note: v2 simplifies this by removing the paymentPayload echo.
 type VerifyResponse struct {
@@ -170,6 +342,31 @@ type SettleResponse struct {
 
 	// Payer is the address that made the payment.
 	Payer string `json:"payer,omitempty"`
+
+	// Amount is the amount settled in atomic units, for schemes that may settle
+	// less than the authorized amount (see SchemeUpTo).
+	Amount string `json:"amount,omitempty"`
+
+	// BlockNumber is the EVM block that included the transaction, if reported.
+	BlockNumber uint64 `json:"blockNumber,omitempty"`
+
+	// Slot is the Solana slot that included the transaction, if reported.
+	Slot uint64 `json:"slot,omitempty"`
+
+	// FeePaid is the network fee the facilitator paid for the transaction, in
+	// atomic units of the network's native token, if reported.
+	FeePaid string `json:"feePaid,omitempty"`
+
+	// Timestamp is when the payment was settled, in Unix seconds, if reported.
+	Timestamp int64 `json:"timestamp,omitempty"`
+
+	// FacilitatorID identifies the facilitator, or the facilitator instance,
+	// that settled the payment, if reported.
+	FacilitatorID string `json:"facilitatorId,omitempty"`
+
+	// UnknownFields holds the JSON fields this type does not define, kept
+	// verbatim and written back when it is encoded.
+	UnknownFields map[string]json.RawMessage `json:"-"`
 }
 
 // SupportedKind describes a payment type supported by a facilitator.
@@ -197,6 +394,10 @@ type SupportedResponse struct {
 
 	// Signers maps CAIP-2 network patterns to signer addresses.
 	Signers map[string][]string `json:"signers"`
+
+	// AttestationKeys lists the addresses whose requirements attestations
+	// clients should trust (see RequirementsAttestationExtension).
+	AttestationKeys []string `json:"attestationKeys,omitempty"`
 }
 
 // TokenConfig defines a token supported by a signer.
@@ -3,6 +3,7 @@ package v2
 import (
 	"encoding/json"
 	"math/big"
+	"reflect"
 	"testing"
 )
 
@@ -176,6 +177,54 @@ func TestPaymentPayloadJSON(t *testing.T) {
 	}
 }
 
+func TestPaymentPayloadJSON_UnknownFields(t *testing.T) {
+	body := `{"x402Version":2,"accepted":{"scheme":"exact","network":"eip155:8453","amount":"1000","asset":"0xasset","payTo":"0xpayto","maxTimeoutSeconds":60,"settlementWindow":30},"payload":{"signature":"0xsig"},"sessionId":"abc","routing":{"hops":[1,2]}}`
+
+	var payload PaymentPayload
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if string(payload.UnknownFields["sessionId"]) != `"abc"` || string(payload.UnknownFields["routing"]) != `{"hops":[1,2]}` {
+		t.Errorf("UnknownFields = %v; want sessionId and routing", payload.UnknownFields)
+	}
+	if string(payload.Accepted.UnknownFields["settlementWindow"]) != "30" {
+		t.Errorf("Accepted.UnknownFields = %v; want settlementWindow", payload.Accepted.UnknownFields)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var want, got map[string]interface{}
+	_ = json.Unmarshal([]byte(body), &want)
+	_ = json.Unmarshal(data, &got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %s; want %s", data, body)
+	}
+
+	// Types decoded without unknown fields compare equal to literals
+	var requirements PaymentRequirements
+	if err := json.Unmarshal([]byte(`{"scheme":"exact","network":"eip155:8453"}`), &requirements); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(requirements, PaymentRequirements{Scheme: "exact", Network: "eip155:8453"}) {
+		t.Errorf("decoded %+v; want no unknown fields", requirements)
+	}
+
+	// Unknown fields never override a defined field, and an empty object
+	// gains them without a stray comma
+	requirements = PaymentRequirements{UnknownFields: map[string]json.RawMessage{"scheme": json.RawMessage(`"upto"`), "z": json.RawMessage("1")}}
+	requirements.Scheme = "exact"
+	data, _ = json.Marshal(requirements)
+	if err := json.Unmarshal(data, &requirements); err != nil || requirements.Scheme != "exact" {
+		t.Errorf("unknown field overrode scheme: %s", data)
+	}
+	data, _ = json.Marshal(PaymentRequired{UnknownFields: map[string]json.RawMessage{"a": json.RawMessage(`"b"`)}})
+	if !json.Valid(data) {
+		t.Errorf("invalid JSON %s", data)
+	}
+}
+
 func TestExtensionJSON(t *testing.T) {
 	ext := Extension{
 		Info: map[string]interface{}{
@@ -269,6 +318,88 @@ func TestSettleResponseJSON(t *testing.T) {
 	}
 }
 
+func TestSettleResponseJSON_Metadata(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want SettleResponse
+	}{
+		{
+			name: "numbers",
+			body: `{"success":true,"transaction":"0xtx","network":"eip155:8453","blockNumber":19000000,"feePaid":"21000000000000","timestamp":1700000000,"facilitatorId":"fac-1"}`,
+			want: SettleResponse{Success: true, Transaction: "0xtx", Network: "eip155:8453", BlockNumber: 19000000, FeePaid: "21000000000000", Timestamp: 1700000000, FacilitatorID: "fac-1"},
+		},
+		{
+			name: "strings",
+			body: `{"success":true,"transaction":"0xtx","network":"eip155:8453","blockNumber":"0x121eac0","timestamp":"2023-11-14T22:13:20Z"}`,
+			want: SettleResponse{Success: true, Transaction: "0xtx", Network: "eip155:8453", BlockNumber: 19000000, Timestamp: 1700000000},
+		},
+		{
+			name: "solana slot",
+			body: `{"success":true,"transaction":"sig","network":"solana:devnet","slot":"250000000","timestamp":"1700000000"}`,
+			want: SettleResponse{Success: true, Transaction: "sig", Network: "solana:devnet", Slot: 250000000, Timestamp: 1700000000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got SettleResponse
+			if err := json.Unmarshal([]byte(tt.body), &got); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := json.Marshal(SettleResponse{}); err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var invalid SettleResponse
+	if err := json.Unmarshal([]byte(`{"success":true,"blockNumber":"latest"}`), &invalid); err == nil {
+		t.Error("expected an invalid block number to fail")
+	}
+}
+
+func TestSettleResponseJSON_UnknownFields(t *testing.T) {
+	body := `{"success":true,"transaction":"0xtx","network":"eip155:8453","gasUsed":"52000","receipt":{"status":1}}`
+
+	var resp SettleResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if string(resp.UnknownFields["gasUsed"]) != `"52000"` || string(resp.UnknownFields["receipt"]) != `{"status":1}` {
+		t.Fatalf("UnknownFields = %v; want the unknown fields", resp.UnknownFields)
+	}
+	if _, ok := resp.UnknownFields["transaction"]; ok {
+		t.Error("known fields must not be kept in UnknownFields")
+	}
+
+	// Re-encoding preserves the unknown fields
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var want, got map[string]interface{}
+	_ = json.Unmarshal([]byte(body), &want)
+	_ = json.Unmarshal(data, &got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %s; want %s", data, body)
+	}
+
+	// UnknownFields never override a defined field
+	resp.UnknownFields["success"] = json.RawMessage("false")
+	data, _ = json.Marshal(resp)
+	var decoded SettleResponse
+	if err := json.Unmarshal(data, &decoded); err != nil || !decoded.Success {
+		t.Errorf("UnknownFields overrode success: %s", data)
+	}
+	if at, ok := decoded.SettledAt(); ok {
+		t.Errorf("SettledAt() = %v; want unreported", at)
+	}
+}
+
 func TestSupportedResponseJSON(t *testing.T) {
 	resp := SupportedResponse{
 		Kinds: []SupportedKind{
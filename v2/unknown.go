@@ -0,0 +1,151 @@
+package v2
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// PaymentRequirements, PaymentRequired, PaymentPayload and SettleResponse
+// keep the JSON fields they do not define in UnknownFields and write them back
+// when encoded, so messages passing through middleware, MCP handlers and
+// facilitator clients keep the fields of newer protocol revisions and
+// facilitator extensions.
+
+// jsonFieldNames returns the JSON names of the fields of struct type t.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// unknownFields returns the fields of the JSON object data that are not in
+// known, or nil if there are none.
+func unknownFields(data []byte, known map[string]bool) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var unknown map[string]json.RawMessage
+	for name, value := range fields {
+		if known[name] {
+			continue
+		}
+		if unknown == nil {
+			unknown = make(map[string]json.RawMessage)
+		}
+		unknown[name] = value
+	}
+	return unknown, nil
+}
+
+// marshalWithUnknown encodes v, a struct without JSON methods, and appends
+// the unknown fields not in known, sorted by name. Unknown fields never
+// replace defined ones.
+func marshalWithUnknown(v any, known map[string]bool, unknown map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(unknown) == 0 {
+		return data, err
+	}
+
+	names := make([]string, 0, len(unknown))
+	for name := range unknown {
+		if !known[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	empty := bytes.Equal(bytes.TrimSpace(data), []byte("{}"))
+	for _, name := range names {
+		value := unknown[name]
+		if !json.Valid(value) {
+			continue
+		}
+		if !empty {
+			buf.WriteByte(',')
+		}
+		empty = false
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+type (
+	paymentRequirementsFields PaymentRequirements
+	paymentRequiredFields     PaymentRequired
+	paymentPayloadFields      PaymentPayload
+)
+
+var (
+	paymentRequirementsKeys = jsonFieldNames(reflect.TypeOf(PaymentRequirements{}))
+	paymentRequiredKeys     = jsonFieldNames(reflect.TypeOf(PaymentRequired{}))
+	paymentPayloadKeys      = jsonFieldNames(reflect.TypeOf(PaymentPayload{}))
+	settleResponseKeys      = jsonFieldNames(reflect.TypeOf(SettleResponse{}))
+)
+
+// MarshalJSON encodes the requirements with their unknown fields.
+func (r PaymentRequirements) MarshalJSON() ([]byte, error) {
+	return marshalWithUnknown(paymentRequirementsFields(r), paymentRequirementsKeys, r.UnknownFields)
+}
+
+// UnmarshalJSON decodes the requirements, keeping their unknown fields.
+func (r *PaymentRequirements) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*paymentRequirementsFields)(r)); err != nil {
+		return err
+	}
+	var err error
+	r.UnknownFields, err = unknownFields(data, paymentRequirementsKeys)
+	return err
+}
+
+// MarshalJSON encodes the 402 response with its unknown fields.
+func (r PaymentRequired) MarshalJSON() ([]byte, error) {
+	return marshalWithUnknown(paymentRequiredFields(r), paymentRequiredKeys, r.UnknownFields)
+}
+
+// UnmarshalJSON decodes the 402 response, keeping its unknown fields.
+func (r *PaymentRequired) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*paymentRequiredFields)(r)); err != nil {
+		return err
+	}
+	var err error
+	r.UnknownFields, err = unknownFields(data, paymentRequiredKeys)
+	return err
+}
+
+// MarshalJSON encodes the payment with its unknown fields.
+func (p PaymentPayload) MarshalJSON() ([]byte, error) {
+	return marshalWithUnknown(paymentPayloadFields(p), paymentPayloadKeys, p.UnknownFields)
+}
+
+// UnmarshalJSON decodes the payment, keeping its unknown fields.
+func (p *PaymentPayload) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*paymentPayloadFields)(p)); err != nil {
+		return err
+	}
+	var err error
+	p.UnknownFields, err = unknownFields(data, paymentPayloadKeys)
+	return err
+}
@@ -0,0 +1,119 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/allowance"
+)
+
+// allowanceAuthorization extracts the relay order from a payload, whether it
+// holds a typed v2.AllowancePayload or the generic map produced by JSON decoding.
+func allowanceAuthorization(payload v2.PaymentPayload) (v2.AllowanceAuthorization, bool) {
+	order, _, ok := allowancePayload(payload)
+	return order, ok
+}
+
+// allowancePayload extracts the relay order and signature from a payload.
+func allowancePayload(payload v2.PaymentPayload) (v2.AllowanceAuthorization, string, bool) {
+	if allowancePayload, ok := payload.Payload.(v2.AllowancePayload); ok {
+		return allowancePayload.AllowanceAuthorization, allowancePayload.Signature, true
+	}
+
+	data, err := json.Marshal(payload.Payload)
+	if err != nil {
+		return v2.AllowanceAuthorization{}, "", false
+	}
+	var allowancePayload v2.AllowancePayload
+	if err := json.Unmarshal(data, &allowancePayload); err != nil || allowancePayload.AllowanceAuthorization.ValidBefore == "" {
+		return v2.AllowanceAuthorization{}, "", false
+	}
+	return allowancePayload.AllowanceAuthorization, allowancePayload.Signature, true
+}
+
+// ValidateAllowancePayload validates an allowance payment payload against the
+// requirements it accepted: the token and value must match the asset and
+// amount, the recipient must match payTo, the spender must match
+// Extra["spender"] when present, and the signature must recover to the
+// order's signer. Whether the signer may spend from the owner, and whether the
+// owner's allowance covers the value, is for the relayer to check.
+func ValidateAllowancePayload(payload v2.PaymentPayload) error {
+	order, signature, ok := allowancePayload(payload)
+	if !ok {
+		return fmt.Errorf("payload is not an allowance relay order")
+	}
+	req := payload.Accepted
+
+	for field, address := range map[string]string{
+		"owner":   order.Owner,
+		"signer":  order.Signer,
+		"spender": order.Spender,
+		"token":   order.Token,
+		"to":      order.To,
+	} {
+		if !evmAddressRegex.MatchString(address) {
+			return fmt.Errorf("invalid allowance %s address: %q", field, address)
+		}
+	}
+
+	value, ok := new(big.Int).SetString(order.Value, 10)
+	if !ok || value.Sign() < 0 {
+		return fmt.Errorf("invalid allowance value: %q", order.Value)
+	}
+	validAfter, ok := new(big.Int).SetString(order.ValidAfter, 10)
+	if !ok {
+		return fmt.Errorf("invalid allowance validAfter: %q", order.ValidAfter)
+	}
+	validBefore, ok := new(big.Int).SetString(order.ValidBefore, 10)
+	if !ok {
+		return fmt.Errorf("invalid allowance validBefore: %q", order.ValidBefore)
+	}
+	nonce, err := hexutil.Decode(order.Nonce)
+	if err != nil || len(nonce) != 32 {
+		return fmt.Errorf("invalid allowance nonce: %q", order.Nonce)
+	}
+
+	if !strings.EqualFold(order.Token, req.Asset) {
+		return fmt.Errorf("allowance token %s does not match asset %s", order.Token, req.Asset)
+	}
+	if order.Value != req.Amount {
+		return fmt.Errorf("allowance value %s does not match required amount %s", order.Value, req.Amount)
+	}
+	if !strings.EqualFold(order.To, req.PayTo) {
+		return fmt.Errorf("allowance recipient %s does not match payTo %s", order.To, req.PayTo)
+	}
+	if spender, ok := req.Extra["spender"].(string); ok && !strings.EqualFold(spender, order.Spender) {
+		return fmt.Errorf("allowance spender %s does not match required spender %s", order.Spender, spender)
+	}
+
+	chainID, err := evmChainID(req.Network)
+	if err != nil {
+		return err
+	}
+	relayOrder := &allowance.Order{
+		Owner:       common.HexToAddress(order.Owner),
+		Signer:      common.HexToAddress(order.Signer),
+		Spender:     common.HexToAddress(order.Spender),
+		Token:       common.HexToAddress(order.Token),
+		To:          common.HexToAddress(order.To),
+		Value:       value,
+		ValidAfter:  validAfter,
+		ValidBefore: validBefore,
+	}
+	copy(relayOrder.Nonce[:], nonce)
+	signer, err := allowance.Recover(chainID, relayOrder, signature)
+	if err != nil {
+		return fmt.Errorf("invalid allowance signature: %w", err)
+	}
+	if signer != relayOrder.Signer {
+		return fmt.Errorf("allowance signature was made by %s, not %s", signer.Hex(), order.Signer)
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/eip3009"
+)
+
+// Failure reasons reported by Diagnose in MismatchError.Reason, in addition to
+// those of ValidateAccepted.
+const (
+	ReasonAmountBelow      = "amount_below_requirement"
+	ReasonNotYetValid      = "authorization_not_yet_valid"
+	ReasonExpired          = "authorization_expired"
+	ReasonWindowTooLong    = "authorization_window_too_long"
+	ReasonChainIDMismatch  = "signature_chain_id_mismatch"
+	ReasonDomainMismatch   = "signature_domain_mismatch"
+	ReasonSignatureInvalid = "signature_invalid"
+)
+
+// diagnosedNetworks are the chains tried when an EIP-3009 signature does not
+// verify for the required chain.
+var diagnosedNetworks = []string{
+	v2.NetworkBase, v2.NetworkPolygon, v2.NetworkAvalanche, v2.NetworkEthereum,
+	v2.NetworkBaseSepolia, v2.NetworkPolygonAmoy, v2.NetworkAvalancheFuji, v2.NetworkSepolia,
+}
+
+// diagnosedDomains are the EIP-712 domain names and versions tried when an
+// EIP-3009 signature does not verify for the required domain.
+var (
+	diagnosedNames    = []string{"USD Coin", "USDC"}
+	diagnosedVersions = []string{"1", "2"}
+)
+
+// Diagnose explains why a facilitator could reject payload for requirement,
+// decoding it locally. It returns a *MismatchError naming the first problem
+// found: an amount below the requirement, a mismatch found by
+// ValidateAccepted, an authorization window that is expired, not yet valid or
+// too long at now (tolerating skew), or an EIP-3009 signature made for another
+// chain ID, domain name or version. It returns nil when no problem is found,
+// for example when the payer lacks funds.
+//
+// Diagnose is meant for development: it is slower than the facilitator's own
+// checks and its output helps a client craft a valid signature.
+func Diagnose(payload v2.PaymentPayload, requirement v2.PaymentRequirements, now time.Time, skew time.Duration) error {
+	if err := diagnoseAmount("amount", payload.Accepted.Amount, requirement.Amount); err != nil {
+		return err
+	}
+	if auth, ok := evmAuthorization(payload); ok {
+		if err := diagnoseAmount("authorization.value", auth.Value, requirement.Amount); err != nil {
+			return err
+		}
+	} else if permit, ok := permit2Authorization(payload); ok {
+		if err := diagnoseAmount("permitted.amount", permit.Permitted.Amount, requirement.Amount); err != nil {
+			return err
+		}
+	} else if order, ok := allowanceAuthorization(payload); ok {
+		if err := diagnoseAmount("allowanceAuthorization.value", order.Value, requirement.Amount); err != nil {
+			return err
+		}
+	}
+
+	if err := ValidateAccepted(payload, requirement); err != nil {
+		return err
+	}
+
+	if reason, err := checkAuthorizationWindow(payload, requirement.MaxTimeoutSeconds, now, skew); err != nil {
+		return &MismatchError{Reason: reason, Field: err.Error()}
+	}
+
+	return diagnoseEIP3009Signature(payload, requirement)
+}
+
+// diagnoseAmount reports got below want. Unparseable amounts are left to the
+// other checks.
+func diagnoseAmount(field, got, want string) error {
+	gotAmount, ok := new(big.Int).SetString(got, 10)
+	if !ok {
+		return nil
+	}
+	wantAmount, ok := new(big.Int).SetString(want, 10)
+	if !ok || gotAmount.Cmp(wantAmount) >= 0 {
+		return nil
+	}
+	return &MismatchError{Reason: ReasonAmountBelow, Field: field, Got: got, Want: want}
+}
+
+// diagnoseEIP3009Signature checks the EIP-3009 signature of payload against the
+// domain of requirement and, when it was made by someone else, tries other
+// chain IDs and domains to find the one the client signed for.
+func diagnoseEIP3009Signature(payload v2.PaymentPayload, requirement v2.PaymentRequirements) error {
+	evmPayload, ok := eip3009Payload(payload)
+	if !ok {
+		return nil
+	}
+	chainID, err := evmChainID(requirement.Network)
+	if err != nil {
+		return nil
+	}
+	name, _ := requirement.Extra["name"].(string)
+	version, _ := requirement.Extra["version"].(string)
+	if name == "" || version == "" {
+		return &MismatchError{Reason: ReasonDomainMismatch, Field: "requirement extra has no EIP-712 name or version"}
+	}
+
+	auth, err := decodeEIP3009Authorization(evmPayload.Authorization)
+	if err != nil {
+		return &MismatchError{Reason: ReasonPayloadMalformed, Field: err.Error()}
+	}
+	domain := eip3009.Domain{Name: name, Version: version, ChainID: chainID, VerifyingContract: common.HexToAddress(requirement.Asset)}
+	signer, err := eip3009.Recover(domain, auth, evmPayload.Signature)
+	if err != nil {
+		return &MismatchError{Reason: ReasonPayloadMalformed, Field: "signature: " + err.Error()}
+	}
+	if signer == auth.From {
+		return nil
+	}
+
+	signedBy := func(d eip3009.Domain) bool {
+		recovered, err := eip3009.Recover(d, auth, evmPayload.Signature)
+		return err == nil && recovered == auth.From
+	}
+	for _, network := range diagnosedNetworks {
+		candidate, err := evmChainID(network)
+		if err != nil || candidate.Cmp(chainID) == 0 {
+			continue
+		}
+		d := domain
+		d.ChainID = candidate
+		if signedBy(d) {
+			return &MismatchError{Reason: ReasonChainIDMismatch, Field: "domain chainId", Got: candidate.String(), Want: chainID.String()}
+		}
+	}
+	for _, candidateName := range diagnosedNames {
+		for _, candidateVersion := range diagnosedVersions {
+			if candidateName == name && candidateVersion == version {
+				continue
+			}
+			d := domain
+			d.Name, d.Version = candidateName, candidateVersion
+			if signedBy(d) {
+				return &MismatchError{
+					Reason: ReasonDomainMismatch,
+					Field:  "domain name/version",
+					Got:    candidateName + "/" + candidateVersion,
+					Want:   name + "/" + version,
+				}
+			}
+		}
+	}
+	return &MismatchError{Reason: ReasonSignatureInvalid, Field: "signature signer", Got: signer.Hex(), Want: auth.From.Hex()}
+}
+
+// eip3009Payload extracts the EIP-3009 payload, whether payload holds a typed
+// v2.EVMPayload or the generic map produced by JSON decoding.
+func eip3009Payload(payload v2.PaymentPayload) (v2.EVMPayload, bool) {
+	if evmPayload, ok := payload.Payload.(v2.EVMPayload); ok {
+		return evmPayload, true
+	}
+
+	data, err := json.Marshal(payload.Payload)
+	if err != nil {
+		return v2.EVMPayload{}, false
+	}
+	var evmPayload v2.EVMPayload
+	if err := json.Unmarshal(data, &evmPayload); err != nil || evmPayload.Authorization.ValidBefore == "" {
+		return v2.EVMPayload{}, false
+	}
+	return evmPayload, true
+}
+
+// decodeEIP3009Authorization parses the fields of a signed authorization.
+func decodeEIP3009Authorization(auth v2.EVMAuthorization) (*eip3009.Authorization, error) {
+	decoded := &eip3009.Authorization{
+		From: common.HexToAddress(auth.From),
+		To:   common.HexToAddress(auth.To),
+	}
+	for _, field := range []struct {
+		name  string
+		value string
+		dst   **big.Int
+	}{
+		{"value", auth.Value, &decoded.Value},
+		{"validAfter", auth.ValidAfter, &decoded.ValidAfter},
+		{"validBefore", auth.ValidBefore, &decoded.ValidBefore},
+	} {
+		n, ok := new(big.Int).SetString(field.value, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid authorization %s: %q", field.name, field.value)
+		}
+		*field.dst = n
+	}
+	nonce, err := hexutil.Decode(auth.Nonce)
+	if err != nil || len(nonce) != 32 {
+		return nil, fmt.Errorf("invalid authorization nonce: %q", auth.Nonce)
+	}
+	copy(decoded.Nonce[:], nonce)
+	return decoded, nil
+}
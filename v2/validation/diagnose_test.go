@@ -0,0 +1,151 @@
+package validation
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/eip3009"
+)
+
+func TestDiagnose_EIP3009(t *testing.T) {
+	key, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           v2.NetworkBaseSepolia,
+		Amount:            "10000",
+		Asset:             v2.BaseSepolia.USDCAddress,
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+		Extra:             map[string]interface{}{"name": "USDC", "version": "2"},
+	}
+
+	// payment signs an authorization of value for domain, valid until validBefore
+	payment := func(t *testing.T, domain eip3009.Domain, value string, validBefore time.Time) v2.PaymentPayload {
+		t.Helper()
+		amount, _ := new(big.Int).SetString(value, 10)
+		auth := &eip3009.Authorization{
+			From:        crypto.PubkeyToAddress(key.PublicKey),
+			To:          common.HexToAddress(requirement.PayTo),
+			Value:       amount,
+			ValidAfter:  big.NewInt(now.Add(-10 * time.Second).Unix()),
+			ValidBefore: big.NewInt(validBefore.Unix()),
+			Nonce:       [32]byte{1},
+		}
+		signature, err := eip3009.SignAuthorizationWithDomain(key, domain, auth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		accepted := requirement
+		accepted.Amount = value
+		return v2.PaymentPayload{
+			X402Version: v2.X402Version,
+			Accepted:    accepted,
+			Payload: v2.EVMPayload{
+				Signature: signature,
+				Authorization: v2.EVMAuthorization{
+					From:        auth.From.Hex(),
+					To:          auth.To.Hex(),
+					Value:       value,
+					ValidAfter:  strconv.FormatInt(auth.ValidAfter.Int64(), 10),
+					ValidBefore: strconv.FormatInt(auth.ValidBefore.Int64(), 10),
+					Nonce:       hexutil.Encode(auth.Nonce[:]),
+				},
+			},
+		}
+	}
+	domain := eip3009.Domain{Name: "USDC", Version: "2", ChainID: big.NewInt(84532), VerifyingContract: common.HexToAddress(requirement.Asset)}
+	withDomain := func(change func(*eip3009.Domain)) eip3009.Domain {
+		d := domain
+		change(&d)
+		return d
+	}
+	otherKey, _ := crypto.GenerateKey()
+
+	tests := []struct {
+		name       string
+		payload    func(t *testing.T) v2.PaymentPayload
+		wantReason string
+		wantGot    string
+	}{
+		{
+			name:    "valid",
+			payload: func(t *testing.T) v2.PaymentPayload { return payment(t, domain, "10000", now.Add(time.Minute)) },
+		},
+		{
+			name:       "amount below requirement",
+			payload:    func(t *testing.T) v2.PaymentPayload { return payment(t, domain, "9999", now.Add(time.Minute)) },
+			wantReason: ReasonAmountBelow,
+		},
+		{
+			name:       "expired",
+			payload:    func(t *testing.T) v2.PaymentPayload { return payment(t, domain, "10000", now.Add(-time.Minute)) },
+			wantReason: ReasonExpired,
+		},
+		{
+			name:       "window too long",
+			payload:    func(t *testing.T) v2.PaymentPayload { return payment(t, domain, "10000", now.Add(time.Hour)) },
+			wantReason: ReasonWindowTooLong,
+		},
+		{
+			name: "wrong chain ID",
+			payload: func(t *testing.T) v2.PaymentPayload {
+				return payment(t, withDomain(func(d *eip3009.Domain) { d.ChainID = big.NewInt(8453) }), "10000", now.Add(time.Minute))
+			},
+			wantReason: ReasonChainIDMismatch,
+			wantGot:    "8453",
+		},
+		{
+			name: "wrong domain name",
+			payload: func(t *testing.T) v2.PaymentPayload {
+				return payment(t, withDomain(func(d *eip3009.Domain) { d.Name = "USD Coin" }), "10000", now.Add(time.Minute))
+			},
+			wantReason: ReasonDomainMismatch,
+			wantGot:    "USD Coin/2",
+		},
+		{
+			name: "wrong signer",
+			payload: func(t *testing.T) v2.PaymentPayload {
+				p := payment(t, domain, "10000", now.Add(time.Minute))
+				evmPayload := p.Payload.(v2.EVMPayload)
+				evmPayload.Authorization.From = crypto.PubkeyToAddress(otherKey.PublicKey).Hex()
+				p.Payload = evmPayload
+				return p
+			},
+			wantReason: ReasonSignatureInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Diagnose(tt.payload(t), requirement, now, 5*time.Second)
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Fatalf("Expected no diagnosis, got %v", err)
+				}
+				return
+			}
+			var mismatch *MismatchError
+			if !errors.As(err, &mismatch) {
+				t.Fatalf("Expected a MismatchError, got %v", err)
+			}
+			if mismatch.Reason != tt.wantReason {
+				t.Errorf("Expected reason %s, got %s (%v)", tt.wantReason, mismatch.Reason, err)
+			}
+			if tt.wantGot != "" && mismatch.Got != tt.wantGot {
+				t.Errorf("Expected got %q, got %q", tt.wantGot, mismatch.Got)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+)
+
+// Mismatch reasons reported by ValidateAccepted in MismatchError.Reason.
+const (
+	ReasonSchemeMismatch     = "accepted_scheme_mismatch"
+	ReasonNetworkMismatch    = "accepted_network_mismatch"
+	ReasonAmountMismatch     = "accepted_amount_mismatch"
+	ReasonAssetMismatch      = "accepted_asset_mismatch"
+	ReasonPayToMismatch      = "accepted_pay_to_mismatch"
+	ReasonTimeoutMismatch    = "accepted_max_timeout_mismatch"
+	ReasonPayloadMalformed   = "payload_malformed"
+	ReasonPayloadAmount      = "payload_amount_mismatch"
+	ReasonPayloadAsset       = "payload_asset_mismatch"
+	ReasonPayloadRecipient   = "payload_recipient_mismatch"
+	ReasonPayloadNoTransfer  = "payload_transfer_missing"
+	ReasonPayloadNetworkType = "payload_network_unsupported"
+)
+
+// MismatchError reports a payment that does not match the requirement it
+// claims to accept.
+type MismatchError struct {
+	// Reason is a machine-readable code, one of the Reason constants.
+	Reason string
+
+	// Field names the mismatching field, like "payTo" or "authorization.value",
+	// or describes what is malformed.
+	Field string
+
+	// Got is the value in the payment and Want the required value.
+	Got, Want string
+}
+
+func (e *MismatchError) Error() string {
+	if e.Got == "" && e.Want == "" {
+		return fmt.Sprintf("%s: %s", e.Reason, e.Field)
+	}
+	return fmt.Sprintf("%s: %s %q does not match %q", e.Reason, e.Field, e.Got, e.Want)
+}
+
+// ValidateAccepted cross-checks a payment against the advertised requirement
+// it was matched to: every field of payload.Accepted must equal the
+// requirement, and the signed authorization inside the payload must pay the
+// required amount of the required asset to payTo. For the "exact" and "upto"
+// schemes, EIP-3009, Permit2 and allowance payloads on EVM networks and SPL
+// token transfers on Solana are decoded; other payloads are only checked
+// through Accepted.
+func ValidateAccepted(payload v2.PaymentPayload, requirement v2.PaymentRequirements) error {
+	accepted := payload.Accepted
+	networkType, _ := v2.ValidateNetwork(requirement.Network)
+	sameAddress := func(a, b string) bool {
+		if networkType == v2.NetworkTypeEVM {
+			return strings.EqualFold(a, b)
+		}
+		return a == b
+	}
+
+	switch {
+	case accepted.Scheme != requirement.Scheme:
+		return &MismatchError{Reason: ReasonSchemeMismatch, Field: "scheme", Got: accepted.Scheme, Want: requirement.Scheme}
+	case accepted.Network != requirement.Network:
+		return &MismatchError{Reason: ReasonNetworkMismatch, Field: "network", Got: accepted.Network, Want: requirement.Network}
+	case accepted.Amount != requirement.Amount:
+		return &MismatchError{Reason: ReasonAmountMismatch, Field: "amount", Got: accepted.Amount, Want: requirement.Amount}
+	case !sameAddress(accepted.Asset, requirement.Asset):
+		return &MismatchError{Reason: ReasonAssetMismatch, Field: "asset", Got: accepted.Asset, Want: requirement.Asset}
+	case !sameAddress(accepted.PayTo, requirement.PayTo):
+		return &MismatchError{Reason: ReasonPayToMismatch, Field: "payTo", Got: accepted.PayTo, Want: requirement.PayTo}
+	case accepted.MaxTimeoutSeconds != requirement.MaxTimeoutSeconds:
+		return &MismatchError{
+			Reason: ReasonTimeoutMismatch,
+			Field:  "maxTimeoutSeconds",
+			Got:    fmt.Sprint(accepted.MaxTimeoutSeconds),
+			Want:   fmt.Sprint(requirement.MaxTimeoutSeconds),
+		}
+	}
+
+	if requirement.Scheme != v2.SchemeExact && requirement.Scheme != v2.SchemeUpTo {
+		return nil
+	}
+	switch networkType {
+	case v2.NetworkTypeEVM:
+		return validateEVMAuthorization(payload, requirement)
+	case v2.NetworkTypeSVM:
+		return validateSVMTransfer(payload, requirement)
+	default:
+		return nil
+	}
+}
+
+// validateEVMAuthorization checks the EIP-3009, Permit2 or allowance
+// authorization of payload.
+func validateEVMAuthorization(payload v2.PaymentPayload, requirement v2.PaymentRequirements) error {
+	if auth, ok := evmAuthorization(payload); ok {
+		if !evmAddressRegex.MatchString(auth.To) || !evmAddressRegex.MatchString(auth.From) {
+			return &MismatchError{Reason: ReasonPayloadMalformed, Field: "authorization addresses"}
+		}
+		if !strings.EqualFold(auth.To, requirement.PayTo) {
+			return &MismatchError{Reason: ReasonPayloadRecipient, Field: "authorization.to", Got: auth.To, Want: requirement.PayTo}
+		}
+		if auth.Value != requirement.Amount {
+			return &MismatchError{Reason: ReasonPayloadAmount, Field: "authorization.value", Got: auth.Value, Want: requirement.Amount}
+		}
+		return nil
+	}
+
+	if order, ok := allowanceAuthorization(payload); ok {
+		switch {
+		case !strings.EqualFold(order.Token, requirement.Asset):
+			return &MismatchError{Reason: ReasonPayloadAsset, Field: "allowanceAuthorization.token", Got: order.Token, Want: requirement.Asset}
+		case order.Value != requirement.Amount:
+			return &MismatchError{Reason: ReasonPayloadAmount, Field: "allowanceAuthorization.value", Got: order.Value, Want: requirement.Amount}
+		case !strings.EqualFold(order.To, requirement.PayTo):
+			return &MismatchError{Reason: ReasonPayloadRecipient, Field: "allowanceAuthorization.to", Got: order.To, Want: requirement.PayTo}
+		}
+		if err := ValidateAllowancePayload(payload); err != nil {
+			return &MismatchError{Reason: ReasonPayloadMalformed, Field: err.Error()}
+		}
+		return nil
+	}
+
+	permit, _, ok := permit2Payload(payload)
+	if !ok {
+		return &MismatchError{Reason: ReasonPayloadMalformed, Field: "payload is not an EIP-3009, Permit2 or allowance authorization"}
+	}
+	switch {
+	case !strings.EqualFold(permit.Permitted.Token, requirement.Asset):
+		return &MismatchError{Reason: ReasonPayloadAsset, Field: "permitted.token", Got: permit.Permitted.Token, Want: requirement.Asset}
+	case permit.Permitted.Amount != requirement.Amount:
+		return &MismatchError{Reason: ReasonPayloadAmount, Field: "permitted.amount", Got: permit.Permitted.Amount, Want: requirement.Amount}
+	case !strings.EqualFold(permit.Witness.To, requirement.PayTo):
+		return &MismatchError{Reason: ReasonPayloadRecipient, Field: "witness.to", Got: permit.Witness.To, Want: requirement.PayTo}
+	}
+	if err := ValidatePermit2Payload(payload); err != nil {
+		return &MismatchError{Reason: ReasonPayloadMalformed, Field: err.Error()}
+	}
+	return nil
+}
+
+// validateSVMTransfer decodes the Solana transaction of payload and checks its
+// SPL token TransferChecked instruction.
+func validateSVMTransfer(payload v2.PaymentPayload, requirement v2.PaymentRequirements) error {
+	var svmPayload v2.SVMPayload
+	if p, ok := payload.Payload.(v2.SVMPayload); ok {
+		svmPayload = p
+	} else if m, ok := payload.Payload.(map[string]interface{}); ok {
+		svmPayload.Transaction, _ = m["transaction"].(string)
+	}
+	if svmPayload.Transaction == "" {
+		return &MismatchError{Reason: ReasonPayloadMalformed, Field: "transaction is missing"}
+	}
+	tx, err := solana.TransactionFromBase64(svmPayload.Transaction)
+	if err != nil {
+		return &MismatchError{Reason: ReasonPayloadMalformed, Field: "transaction: " + err.Error()}
+	}
+
+	mint, err := solana.PublicKeyFromBase58(requirement.Asset)
+	if err != nil {
+		return &MismatchError{Reason: ReasonPayloadNetworkType, Field: "asset is not a Solana mint", Got: requirement.Asset}
+	}
+	payTo, err := solana.PublicKeyFromBase58(requirement.PayTo)
+	if err != nil {
+		return &MismatchError{Reason: ReasonPayloadNetworkType, Field: "payTo is not a Solana address", Got: requirement.PayTo}
+	}
+	destination, _, err := solana.FindAssociatedTokenAddress(payTo, mint)
+	if err != nil {
+		return &MismatchError{Reason: ReasonPayloadMalformed, Field: "deriving payTo token account: " + err.Error()}
+	}
+
+	for _, inst := range tx.Message.Instructions {
+		program, err := tx.Message.ResolveProgramIDIndex(inst.ProgramIDIndex)
+		if err != nil || !program.Equals(solana.TokenProgramID) {
+			continue
+		}
+		accounts, err := inst.ResolveInstructionAccounts(&tx.Message)
+		if err != nil {
+			return &MismatchError{Reason: ReasonPayloadMalformed, Field: "token instruction accounts: " + err.Error()}
+		}
+		decoded, err := token.DecodeInstruction(accounts, inst.Data)
+		if err != nil {
+			continue
+		}
+		transfer, ok := decoded.Impl.(*token.TransferChecked)
+		if !ok || transfer.Amount == nil {
+			continue
+		}
+
+		if got := transfer.GetMintAccount().PublicKey; !got.Equals(mint) {
+			return &MismatchError{Reason: ReasonPayloadAsset, Field: "transfer mint", Got: got.String(), Want: mint.String()}
+		}
+		if got := transfer.GetDestinationAccount().PublicKey; !got.Equals(destination) {
+			return &MismatchError{Reason: ReasonPayloadRecipient, Field: "transfer destination", Got: got.String(), Want: destination.String()}
+		}
+		if got := fmt.Sprint(*transfer.Amount); got != requirement.Amount {
+			return &MismatchError{Reason: ReasonPayloadAmount, Field: "transfer amount", Got: got, Want: requirement.Amount}
+		}
+		return nil
+	}
+	return &MismatchError{Reason: ReasonPayloadNoTransfer, Field: "transaction has no TransferChecked instruction"}
+}
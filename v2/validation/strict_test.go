@@ -0,0 +1,128 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+
+	v2 "github.com/mark3labs/x402-go/v2"
+	x402solana "github.com/mark3labs/x402-go/v2/internal/solana"
+)
+
+func TestValidateAccepted_EVM(t *testing.T) {
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "10000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+	}
+	authorization := v2.EVMAuthorization{
+		From:  "0x857b06519E91e3A54538791bDbb0E22373e36b66",
+		To:    "0x209693bc6afc0c5328ba36faf03c514ef312287c",
+		Value: "10000",
+	}
+
+	tests := []struct {
+		name       string
+		accepted   func(*v2.PaymentRequirements)
+		auth       func(*v2.EVMAuthorization)
+		wantReason string
+	}{
+		{name: "matching"},
+		{name: "amount", accepted: func(r *v2.PaymentRequirements) { r.Amount = "1" }, wantReason: ReasonAmountMismatch},
+		{name: "asset", accepted: func(r *v2.PaymentRequirements) { r.Asset = authorization.From }, wantReason: ReasonAssetMismatch},
+		{name: "payTo", accepted: func(r *v2.PaymentRequirements) { r.PayTo = authorization.From }, wantReason: ReasonPayToMismatch},
+		{name: "timeout", accepted: func(r *v2.PaymentRequirements) { r.MaxTimeoutSeconds = 3600 }, wantReason: ReasonTimeoutMismatch},
+		{name: "signed value", auth: func(a *v2.EVMAuthorization) { a.Value = "1" }, wantReason: ReasonPayloadAmount},
+		{name: "signed recipient", auth: func(a *v2.EVMAuthorization) { a.To = a.From }, wantReason: ReasonPayloadRecipient},
+		{name: "malformed address", auth: func(a *v2.EVMAuthorization) { a.From = "0xnope" }, wantReason: ReasonPayloadMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accepted, auth := requirement, authorization
+			if tt.accepted != nil {
+				tt.accepted(&accepted)
+			}
+			if tt.auth != nil {
+				tt.auth(&auth)
+			}
+			payload := v2.PaymentPayload{
+				X402Version: 2,
+				Accepted:    accepted,
+				Payload:     map[string]interface{}{"signature": "0x00", "authorization": map[string]interface{}{"from": auth.From, "to": auth.To, "value": auth.Value, "validAfter": "0", "validBefore": "1"}},
+			}
+			assertMismatch(t, ValidateAccepted(payload, requirement), tt.wantReason)
+		})
+	}
+}
+
+func TestValidateAccepted_SVM(t *testing.T) {
+	payer := solana.NewWallet().PublicKey()
+	payTo := solana.NewWallet().PublicKey()
+	mint := solana.MustPublicKeyFromBase58(v2.SolanaDevnet.USDCAddress)
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           v2.NetworkSolanaDevnet,
+		Amount:            "10000",
+		Asset:             mint.String(),
+		PayTo:             payTo.String(),
+		MaxTimeoutSeconds: 60,
+	}
+
+	transaction := func(destination solana.PublicKey, amount uint64) string {
+		source, _, _ := solana.FindAssociatedTokenAddress(payer, mint)
+		tx, err := solana.NewTransaction([]solana.Instruction{
+			x402solana.BuildSetComputeUnitLimitInstruction(x402solana.DefaultComputeUnits),
+			x402solana.BuildTransferCheckedInstruction(source, mint, destination, payer, amount, 6),
+		}, solana.Hash{}, solana.TransactionPayer(payer))
+		if err != nil {
+			t.Fatalf("Building transaction failed: %v", err)
+		}
+		encoded, err := tx.ToBase64()
+		if err != nil {
+			t.Fatalf("Encoding transaction failed: %v", err)
+		}
+		return encoded
+	}
+	destination, _, _ := solana.FindAssociatedTokenAddress(payTo, mint)
+	other, _, _ := solana.FindAssociatedTokenAddress(payer, mint)
+
+	tests := []struct {
+		name        string
+		transaction string
+		wantReason  string
+	}{
+		{name: "matching", transaction: transaction(destination, 10000)},
+		{name: "amount", transaction: transaction(destination, 1), wantReason: ReasonPayloadAmount},
+		{name: "destination", transaction: transaction(other, 10000), wantReason: ReasonPayloadRecipient},
+		{name: "not a transaction", transaction: "bm9wZQ==", wantReason: ReasonPayloadMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := v2.PaymentPayload{
+				X402Version: 2,
+				Accepted:    requirement,
+				Payload:     v2.SVMPayload{Transaction: tt.transaction},
+			}
+			assertMismatch(t, ValidateAccepted(payload, requirement), tt.wantReason)
+		})
+	}
+}
+
+func assertMismatch(t *testing.T, err error, wantReason string) {
+	t.Helper()
+	if wantReason == "" {
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		return
+	}
+	var mismatch *MismatchError
+	if !errors.As(err, &mismatch) || mismatch.Reason != wantReason {
+		t.Errorf("Expected %s, got %v", wantReason, err)
+	}
+}
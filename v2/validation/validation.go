@@ -3,12 +3,20 @@
 package validation
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"net/url"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 
 	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/internal/permit2"
 )
 
 var (
@@ -19,7 +27,7 @@ var (
 	solanaAddressRegex = regexp.MustCompile(`^[1-9A-HJ-NP-Za-km-z]{32,44}$`)
 
 	// caip2Regex matches CAIP-2 network identifiers (namespace:reference)
-	caip2Regex = regexp.MustCompile(`^[a-z0-9]+:[a-zA-Z0-9]+$`)
+	caip2Regex = regexp.MustCompile(`^[a-z0-9]+:[-_a-zA-Z0-9]+$`)
 )
 
 // ValidateAmount validates that an amount string is a valid non-negative integer.
@@ -86,6 +94,16 @@ func ValidateAddress(address string, network string) error {
 		}
 		return nil
 
+	case v2.NetworkTypeCustom:
+		namespace, ok := v2.NamespaceOf(network)
+		if !ok {
+			return fmt.Errorf("cannot validate address: unregistered namespace for %s", network)
+		}
+		if err := namespace.ValidateAddress(address); err != nil {
+			return fmt.Errorf("invalid %s address: %w", namespace.Namespace(), err)
+		}
+		return nil
+
 	default:
 		return fmt.Errorf("unsupported network type for address validation: %d", networkType)
 	}
@@ -135,12 +153,28 @@ func ValidatePaymentRequirements(req v2.PaymentRequirements) error {
 
 	// Validate scheme
 	switch req.Scheme {
-	case "exact":
-		// Valid scheme for v2
+	case v2.SchemeExact, v2.SchemeDeferred:
+		// Valid schemes for v2
+	case v2.SchemeUpTo:
+		// Only Permit2 lets the seller settle less than the authorized amount
+		if method, _ := req.Extra["assetTransferMethod"].(string); !strings.EqualFold(method, v2.AssetTransferMethodPermit2) {
+			return fmt.Errorf("invalid requirements: scheme %s requires assetTransferMethod %s", v2.SchemeUpTo, v2.AssetTransferMethodPermit2)
+		}
+	case v2.SchemeDirect:
+		// Direct payments are ERC-20 transfers confirmed on-chain
+		if networkType, _ := v2.ValidateNetwork(req.Network); networkType != v2.NetworkTypeEVM {
+			return fmt.Errorf("invalid requirements: scheme %s requires an EVM network", v2.SchemeDirect)
+		}
 	case "":
 		return fmt.Errorf("invalid requirements: scheme cannot be empty")
 	default:
-		return fmt.Errorf("invalid requirements: unsupported scheme %s", req.Scheme)
+		handler, ok := v2.LookupScheme(req.Scheme)
+		if !ok {
+			return fmt.Errorf("invalid requirements: unsupported scheme %s", req.Scheme)
+		}
+		if err := handler.ValidateRequirements(req); err != nil {
+			return fmt.Errorf("invalid requirements: %s: %w", req.Scheme, err)
+		}
 	}
 
 	// Validate timeout (must be non-negative)
@@ -148,6 +182,17 @@ func ValidatePaymentRequirements(req v2.PaymentRequirements) error {
 		return fmt.Errorf("invalid requirements: timeout cannot be negative: %d", req.MaxTimeoutSeconds)
 	}
 
+	// Validate revenue splits
+	splits, err := v2.SplitsFromRequirements(req)
+	if err != nil {
+		return fmt.Errorf("invalid requirements: %w", err)
+	}
+	for _, split := range splits {
+		if err := ValidateAddress(split.Recipient, req.Network); err != nil {
+			return fmt.Errorf("invalid requirements: split recipient %w", err)
+		}
+	}
+
 	// Validate EIP-3009 parameters for EVM chains
 	networkType, _ := v2.ValidateNetwork(req.Network)
 	if networkType == v2.NetworkTypeEVM && req.Extra != nil {
@@ -161,6 +206,23 @@ func ValidatePaymentRequirements(req v2.PaymentRequirements) error {
 				return fmt.Errorf("invalid requirements: EIP-3009 version cannot be empty")
 			}
 		}
+		if method, ok := req.Extra["assetTransferMethod"]; ok {
+			switch method {
+			case v2.AssetTransferMethodEIP3009:
+			case v2.AssetTransferMethodPermit2:
+				spender, _ := req.Extra["spender"].(string)
+				if !evmAddressRegex.MatchString(spender) {
+					return fmt.Errorf("invalid requirements: Permit2 spender must be an EVM address")
+				}
+			case v2.AssetTransferMethodAllowance:
+				spender, _ := req.Extra["spender"].(string)
+				if !evmAddressRegex.MatchString(spender) {
+					return fmt.Errorf("invalid requirements: allowance spender must be an EVM address")
+				}
+			default:
+				return fmt.Errorf("invalid requirements: unsupported assetTransferMethod %v", method)
+			}
+		}
 	}
 
 	return nil
@@ -190,6 +252,17 @@ func ValidatePaymentPayload(payload v2.PaymentPayload) error {
 		return fmt.Errorf("payload cannot be nil")
 	}
 
+	// Validate custom scheme payloads against the accepted requirements
+	if handler, ok := v2.LookupScheme(payload.Accepted.Scheme); ok {
+		decoded, err := v2.DecodeSchemePayload(handler, &payload)
+		if err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		if err := handler.ValidatePayload(decoded, payload.Accepted); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+	}
+
 	// Validate resource if present
 	if payload.Resource != nil {
 		if err := ValidateResourceInfo(*payload.Resource); err != nil {
@@ -225,3 +298,250 @@ func ValidatePaymentRequired(pr v2.PaymentRequired) error {
 
 	return nil
 }
+
+// ValidateAuthorizationWindow checks the validAfter/validBefore window of an EVM
+// (EIP-3009, Permit2 or allowance) payment against the local clock, tolerating up to skew of clock drift
+// in either direction. It rejects authorizations that are not yet valid, already
+// expired, or valid for longer than maxTimeoutSeconds. For Permit2 payloads the
+// deadline is used as validBefore. Other payloads (e.g., Solana) are not checked.
+func ValidateAuthorizationWindow(payload v2.PaymentPayload, maxTimeoutSeconds int, now time.Time, skew time.Duration) error {
+	_, err := checkAuthorizationWindow(payload, maxTimeoutSeconds, now, skew)
+	return err
+}
+
+// checkAuthorizationWindow implements ValidateAuthorizationWindow, also
+// returning the Reason constant describing the failure.
+func checkAuthorizationWindow(payload v2.PaymentPayload, maxTimeoutSeconds int, now time.Time, skew time.Duration) (string, error) {
+	var validAfterStr, validBeforeStr string
+	if auth, ok := evmAuthorization(payload); ok {
+		validAfterStr, validBeforeStr = auth.ValidAfter, auth.ValidBefore
+	} else if permit, ok := permit2Authorization(payload); ok {
+		validAfterStr, validBeforeStr = permit.Witness.ValidAfter, permit.Deadline
+	} else if order, ok := allowanceAuthorization(payload); ok {
+		validAfterStr, validBeforeStr = order.ValidAfter, order.ValidBefore
+	} else {
+		return "", nil
+	}
+
+	validAfter, err := strconv.ParseInt(validAfterStr, 10, 64)
+	if err != nil {
+		return ReasonPayloadMalformed, fmt.Errorf("invalid validAfter: %q", validAfterStr)
+	}
+	validBefore, err := strconv.ParseInt(validBeforeStr, 10, 64)
+	if err != nil {
+		return ReasonPayloadMalformed, fmt.Errorf("invalid validBefore: %q", validBeforeStr)
+	}
+
+	skewSeconds := int64(skew / time.Second)
+	nowUnix := now.Unix()
+
+	if validAfter > nowUnix+skewSeconds {
+		return ReasonNotYetValid, fmt.Errorf("authorization not yet valid: validAfter %d is %ds in the future", validAfter, validAfter-nowUnix)
+	}
+	if validBefore <= nowUnix-skewSeconds {
+		return ReasonExpired, fmt.Errorf("%w: validBefore %d is %ds in the past", v2.ErrAuthorizationExpired, validBefore, nowUnix-validBefore)
+	}
+	if maxTimeoutSeconds > 0 && validBefore > nowUnix+int64(maxTimeoutSeconds)+skewSeconds {
+		return ReasonWindowTooLong, fmt.Errorf("authorization window too long: validBefore %d exceeds maxTimeoutSeconds %d", validBefore, maxTimeoutSeconds)
+	}
+
+	return "", nil
+}
+
+// ValidateNotExpired checks that the authorization deadline of an EVM payment
+// (see AuthorizationDeadline) has not passed, tolerating up to skew of clock
+// drift. Unlike ValidateAuthorizationWindow, it does not check validAfter or
+// the window's length. Expired payments fail with an error wrapping
+// v2.ErrAuthorizationExpired; other payloads are not checked.
+func ValidateNotExpired(payload v2.PaymentPayload, now time.Time, skew time.Duration) error {
+	deadline, ok := AuthorizationDeadline(payload)
+	if !ok {
+		return nil
+	}
+	if late := now.Sub(deadline); late >= skew {
+		return fmt.Errorf("%w: validBefore %d is %ds in the past", v2.ErrAuthorizationExpired, deadline.Unix(), int64(late/time.Second))
+	}
+	return nil
+}
+
+// AuthorizationDeadline returns the time after which an EVM payment can no longer
+// be settled: validBefore for EIP-3009 and allowance payloads and the deadline
+// for Permit2.
+// It returns false for other payloads.
+func AuthorizationDeadline(payload v2.PaymentPayload) (time.Time, bool) {
+	var deadline string
+	if auth, ok := evmAuthorization(payload); ok {
+		deadline = auth.ValidBefore
+	} else if permit, ok := permit2Authorization(payload); ok {
+		deadline = permit.Deadline
+	} else if order, ok := allowanceAuthorization(payload); ok {
+		deadline = order.ValidBefore
+	} else {
+		return time.Time{}, false
+	}
+
+	seconds, err := strconv.ParseInt(deadline, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+// AuthorizationStart returns the time from which an EVM payment can be
+// settled: validAfter for EIP-3009, Permit2 and allowance payloads.
+// It returns false for other payloads.
+func AuthorizationStart(payload v2.PaymentPayload) (time.Time, bool) {
+	var start string
+	if auth, ok := evmAuthorization(payload); ok {
+		start = auth.ValidAfter
+	} else if permit, ok := permit2Authorization(payload); ok {
+		start = permit.Witness.ValidAfter
+	} else if order, ok := allowanceAuthorization(payload); ok {
+		start = order.ValidAfter
+	} else {
+		return time.Time{}, false
+	}
+
+	seconds, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+// evmAuthorization extracts the EIP-3009 authorization from a payload, whether it
+// holds a typed v2.EVMPayload or the generic map produced by JSON decoding.
+func evmAuthorization(payload v2.PaymentPayload) (v2.EVMAuthorization, bool) {
+	if evmPayload, ok := payload.Payload.(v2.EVMPayload); ok {
+		return evmPayload.Authorization, true
+	}
+
+	data, err := json.Marshal(payload.Payload)
+	if err != nil {
+		return v2.EVMAuthorization{}, false
+	}
+	var evmPayload v2.EVMPayload
+	if err := json.Unmarshal(data, &evmPayload); err != nil || evmPayload.Authorization.ValidBefore == "" {
+		return v2.EVMAuthorization{}, false
+	}
+	return evmPayload.Authorization, true
+}
+
+// permit2Authorization extracts the Permit2 authorization from a payload, whether it
+// holds a typed v2.Permit2Payload or the generic map produced by JSON decoding.
+func permit2Authorization(payload v2.PaymentPayload) (v2.Permit2Authorization, bool) {
+	permit, _, ok := permit2Payload(payload)
+	return permit, ok
+}
+
+// permit2Payload extracts the Permit2 authorization and signature from a payload.
+func permit2Payload(payload v2.PaymentPayload) (v2.Permit2Authorization, string, bool) {
+	if permitPayload, ok := payload.Payload.(v2.Permit2Payload); ok {
+		return permitPayload.Permit2Authorization, permitPayload.Signature, true
+	}
+
+	data, err := json.Marshal(payload.Payload)
+	if err != nil {
+		return v2.Permit2Authorization{}, "", false
+	}
+	var permitPayload v2.Permit2Payload
+	if err := json.Unmarshal(data, &permitPayload); err != nil || permitPayload.Permit2Authorization.Deadline == "" {
+		return v2.Permit2Authorization{}, "", false
+	}
+	return permitPayload.Permit2Authorization, permitPayload.Signature, true
+}
+
+// ValidatePermit2Payload validates a Permit2 payment payload against the requirements
+// it accepted: the permitted token and amount must match the asset and amount, the
+// witness recipient must match payTo, the spender must match Extra["spender"] when
+// present, and the signature must recover to the payer.
+func ValidatePermit2Payload(payload v2.PaymentPayload) error {
+	permit, signature, ok := permit2Payload(payload)
+	if !ok {
+		return fmt.Errorf("payload is not a Permit2 authorization")
+	}
+	req := payload.Accepted
+
+	for field, address := range map[string]string{
+		"from":    permit.From,
+		"token":   permit.Permitted.Token,
+		"spender": permit.Spender,
+		"to":      permit.Witness.To,
+	} {
+		if !evmAddressRegex.MatchString(address) {
+			return fmt.Errorf("invalid Permit2 %s address: %q", field, address)
+		}
+	}
+
+	amount, ok := new(big.Int).SetString(permit.Permitted.Amount, 10)
+	if !ok || amount.Sign() < 0 {
+		return fmt.Errorf("invalid Permit2 amount: %q", permit.Permitted.Amount)
+	}
+	nonce, ok := new(big.Int).SetString(permit.Nonce, 10)
+	if !ok || nonce.Sign() < 0 {
+		return fmt.Errorf("invalid Permit2 nonce: %q", permit.Nonce)
+	}
+	deadline, ok := new(big.Int).SetString(permit.Deadline, 10)
+	if !ok {
+		return fmt.Errorf("invalid Permit2 deadline: %q", permit.Deadline)
+	}
+	validAfter, ok := new(big.Int).SetString(permit.Witness.ValidAfter, 10)
+	if !ok {
+		return fmt.Errorf("invalid Permit2 validAfter: %q", permit.Witness.ValidAfter)
+	}
+	extra, err := hexutil.Decode(permit.Witness.Extra)
+	if err != nil {
+		return fmt.Errorf("invalid Permit2 witness extra: %w", err)
+	}
+
+	if !strings.EqualFold(permit.Permitted.Token, req.Asset) {
+		return fmt.Errorf("Permit2 token %s does not match asset %s", permit.Permitted.Token, req.Asset)
+	}
+	if permit.Permitted.Amount != req.Amount {
+		return fmt.Errorf("Permit2 amount %s does not match required amount %s", permit.Permitted.Amount, req.Amount)
+	}
+	if !strings.EqualFold(permit.Witness.To, req.PayTo) {
+		return fmt.Errorf("Permit2 recipient %s does not match payTo %s", permit.Witness.To, req.PayTo)
+	}
+	if spender, ok := req.Extra["spender"].(string); ok && !strings.EqualFold(spender, permit.Spender) {
+		return fmt.Errorf("Permit2 spender %s does not match required spender %s", permit.Spender, spender)
+	}
+
+	chainID, err := evmChainID(req.Network)
+	if err != nil {
+		return err
+	}
+	signer, err := permit2.Recover(chainID, &permit2.Permit{
+		Token:    common.HexToAddress(permit.Permitted.Token),
+		Amount:   amount,
+		Spender:  common.HexToAddress(permit.Spender),
+		Nonce:    nonce,
+		Deadline: deadline,
+		Witness: permit2.Witness{
+			To:         common.HexToAddress(permit.Witness.To),
+			ValidAfter: validAfter,
+			Extra:      extra,
+		},
+	}, signature)
+	if err != nil {
+		return fmt.Errorf("invalid Permit2 signature: %w", err)
+	}
+	if signer != common.HexToAddress(permit.From) {
+		return fmt.Errorf("Permit2 signature was made by %s, not %s", signer.Hex(), permit.From)
+	}
+
+	return nil
+}
+
+// evmChainID parses the chain ID from an eip155 CAIP-2 network identifier.
+func evmChainID(network string) (*big.Int, error) {
+	reference, ok := strings.CutPrefix(network, "eip155:")
+	if !ok {
+		return nil, fmt.Errorf("not an EVM network: %s", network)
+	}
+	chainID, ok := new(big.Int).SetString(reference, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid EVM chain ID in network %s", network)
+	}
+	return chainID, nil
+}
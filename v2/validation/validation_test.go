@@ -1,10 +1,15 @@
 package validation
 
 import (
+	"encoding/json"
+	"errors"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	v2 "github.com/mark3labs/x402-go/v2"
+	"github.com/mark3labs/x402-go/v2/signers/evm"
 )
 
 func TestValidateAmount(t *testing.T) {
@@ -606,3 +611,292 @@ func TestValidateSolanaRequirements(t *testing.T) {
 		t.Errorf("ValidatePaymentRequirements() error = %v for valid Solana requirements", err)
 	}
 }
+
+func TestValidateAuthorizationWindow(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	evmPayload := func(validAfter, validBefore int64) v2.PaymentPayload {
+		return v2.PaymentPayload{
+			X402Version: 2,
+			Payload: v2.EVMPayload{
+				Authorization: v2.EVMAuthorization{
+					ValidAfter:  strconv.FormatInt(validAfter, 10),
+					ValidBefore: strconv.FormatInt(validBefore, 10),
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		payload v2.PaymentPayload
+		skew    time.Duration
+		wantErr bool
+	}{
+		{name: "valid window", payload: evmPayload(now.Unix()-10, now.Unix()+60)},
+		{name: "not yet valid", payload: evmPayload(now.Unix()+30, now.Unix()+60), wantErr: true},
+		{name: "not yet valid within skew", payload: evmPayload(now.Unix()+30, now.Unix()+60), skew: 30 * time.Second},
+		{name: "expired", payload: evmPayload(now.Unix()-120, now.Unix()-5), wantErr: true},
+		{name: "expired within skew", payload: evmPayload(now.Unix()-120, now.Unix()-5), skew: 10 * time.Second},
+		{name: "window too long", payload: evmPayload(now.Unix()-10, now.Unix()+3600), wantErr: true},
+		{name: "zero window", payload: evmPayload(0, 0), wantErr: true},
+		{
+			name: "decoded map payload",
+			payload: v2.PaymentPayload{Payload: map[string]interface{}{
+				"signature": "0xsig",
+				"authorization": map[string]interface{}{
+					"validAfter":  strconv.FormatInt(now.Unix()-10, 10),
+					"validBefore": strconv.FormatInt(now.Unix()-1, 10),
+				},
+			}},
+			wantErr: true,
+		},
+		{name: "non-EVM payload", payload: v2.PaymentPayload{Payload: v2.SVMPayload{Transaction: "base64"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAuthorizationWindow(tt.payload, 60, now, tt.skew)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAuthorizationWindow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateNotExpired(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	evmPayload := func(validAfter, validBefore int64) v2.PaymentPayload {
+		return v2.PaymentPayload{
+			X402Version: 2,
+			Payload: v2.EVMPayload{
+				Authorization: v2.EVMAuthorization{
+					ValidAfter:  strconv.FormatInt(validAfter, 10),
+					ValidBefore: strconv.FormatInt(validBefore, 10),
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		payload v2.PaymentPayload
+		skew    time.Duration
+		wantErr bool
+	}{
+		{name: "valid", payload: evmPayload(now.Unix()-10, now.Unix()+60)},
+		{name: "not yet valid is not checked", payload: evmPayload(now.Unix()+30, now.Unix()+60)},
+		{name: "long window is not checked", payload: evmPayload(now.Unix()-10, now.Unix()+3600)},
+		{name: "expired", payload: evmPayload(now.Unix()-120, now.Unix()-5), wantErr: true},
+		{name: "expired within skew", payload: evmPayload(now.Unix()-120, now.Unix()-5), skew: 10 * time.Second},
+		{name: "expired beyond skew", payload: evmPayload(now.Unix()-120, now.Unix()-10), skew: 10 * time.Second, wantErr: true},
+		{name: "non-EVM payload", payload: v2.PaymentPayload{Payload: v2.SVMPayload{Transaction: "base64"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNotExpired(tt.payload, now, tt.skew)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateNotExpired() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, v2.ErrAuthorizationExpired) {
+				t.Errorf("expected ErrAuthorizationExpired, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidatePermit2Payload(t *testing.T) {
+	token := "0x1111111111111111111111111111111111111111"
+	requirements := &v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           v2.NetworkBaseSepolia,
+		Asset:             token,
+		Amount:            "1000",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 300,
+		Extra: map[string]interface{}{
+			"assetTransferMethod": v2.AssetTransferMethodPermit2,
+			"spender":             "0x3333333333333333333333333333333333333333",
+		},
+	}
+	if err := ValidatePaymentRequirements(*requirements); err != nil {
+		t.Fatalf("ValidatePaymentRequirements failed: %v", err)
+	}
+
+	signer, err := evm.NewSigner(v2.NetworkBaseSepolia, "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80",
+		[]v2.TokenConfig{{Address: token, Decimals: 18}}, evm.WithPermit2())
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	payload, err := signer.Sign(requirements)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+
+	if err := ValidatePermit2Payload(*payload); err != nil {
+		t.Errorf("expected valid payload, got %v", err)
+	}
+	if err := ValidateAuthorizationWindow(*payload, 300, time.Now(), 0); err != nil {
+		t.Errorf("expected valid window, got %v", err)
+	}
+
+	// Decoded from JSON, as a server would receive it
+	data, _ := json.Marshal(payload)
+	var decoded v2.PaymentPayload
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if err := ValidatePermit2Payload(decoded); err != nil {
+		t.Errorf("expected decoded payload to be valid, got %v", err)
+	}
+
+	tampered := *payload
+	tampered.Accepted.Amount = "2000"
+	if err := ValidatePermit2Payload(tampered); err == nil {
+		t.Error("expected amount mismatch to be rejected")
+	}
+
+	forged := *payload
+	permit := payload.Payload.(v2.Permit2Payload)
+	permit.Permit2Authorization.Witness.To = "0x4444444444444444444444444444444444444444"
+	forged.Payload = permit
+	forged.Accepted.PayTo = "0x4444444444444444444444444444444444444444"
+	if err := ValidatePermit2Payload(forged); err == nil {
+		t.Error("expected forged recipient to fail signature check")
+	}
+
+	invalid := *requirements
+	invalid.Extra = map[string]interface{}{"assetTransferMethod": v2.AssetTransferMethodPermit2}
+	if err := ValidatePaymentRequirements(invalid); err == nil {
+		t.Error("expected missing spender to be rejected")
+	}
+}
+
+func TestValidateAllowancePayload(t *testing.T) {
+	token := "0x1111111111111111111111111111111111111111"
+	requirement := v2.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           v2.NetworkBaseSepolia,
+		Asset:             token,
+		Amount:            "1000",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 300,
+		Extra: map[string]interface{}{
+			"assetTransferMethod": v2.AssetTransferMethodAllowance,
+			"spender":             "0x3333333333333333333333333333333333333333",
+		},
+	}
+	if err := ValidatePaymentRequirements(requirement); err != nil {
+		t.Fatalf("ValidatePaymentRequirements failed: %v", err)
+	}
+
+	signer, err := evm.NewSigner(v2.NetworkBaseSepolia, "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80",
+		[]v2.TokenConfig{{Address: token, Decimals: 18}}, evm.WithTreasury("0x5555555555555555555555555555555555555555"))
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	payload, err := signer.Sign(&requirement)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+
+	// Decoded from JSON, as a server would receive it
+	data, _ := json.Marshal(payload)
+	var decoded v2.PaymentPayload
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	for name, p := range map[string]v2.PaymentPayload{"typed": *payload, "decoded": decoded} {
+		if err := ValidateAllowancePayload(p); err != nil {
+			t.Errorf("%s: expected valid payload, got %v", name, err)
+		}
+		if err := ValidateAccepted(p, requirement); err != nil {
+			t.Errorf("%s: expected accepted payload, got %v", name, err)
+		}
+		if err := ValidateAuthorizationWindow(p, 300, time.Now(), 0); err != nil {
+			t.Errorf("%s: expected valid window, got %v", name, err)
+		}
+		if _, ok := AuthorizationDeadline(p); !ok {
+			t.Errorf("%s: expected a deadline", name)
+		}
+	}
+
+	tampered := *payload
+	tampered.Accepted.Amount = "2000"
+	if err := ValidateAllowancePayload(tampered); err == nil {
+		t.Error("expected amount mismatch to be rejected")
+	}
+
+	forged := *payload
+	order := payload.Payload.(v2.AllowancePayload)
+	order.AllowanceAuthorization.Owner = "0x4444444444444444444444444444444444444444"
+	forged.Payload = order
+	if err := ValidateAllowancePayload(forged); err == nil {
+		t.Error("expected forged owner to fail signature check")
+	}
+
+	invalid := requirement
+	invalid.Extra = map[string]interface{}{"assetTransferMethod": v2.AssetTransferMethodAllowance}
+	if err := ValidatePaymentRequirements(invalid); err == nil {
+		t.Error("expected missing spender to be rejected")
+	}
+}
+
+// channelScheme is a custom scheme requiring Extra["channel"] and a matching payload.
+type channelScheme struct{}
+
+func (channelScheme) Scheme() string          { return "channel" }
+func (channelScheme) NewPayload() interface{} { return &map[string]string{} }
+func (channelScheme) BuildPayload(*v2.PaymentRequirements) (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (channelScheme) ValidateRequirements(req v2.PaymentRequirements) error {
+	if _, ok := req.Extra["channel"].(string); !ok {
+		return errors.New("channel is required")
+	}
+	return nil
+}
+
+func (channelScheme) ValidatePayload(payload interface{}, req v2.PaymentRequirements) error {
+	if (*payload.(*map[string]string))["channel"] != req.Extra["channel"] {
+		return errors.New("wrong channel")
+	}
+	return nil
+}
+
+func TestValidateCustomScheme(t *testing.T) {
+	req := v2.PaymentRequirements{
+		Scheme:  "channel",
+		Network: "eip155:8453",
+		Amount:  "1000000",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		PayTo:   "0x1234567890123456789012345678901234567890",
+		Extra:   map[string]interface{}{"channel": "a"},
+	}
+	if err := ValidatePaymentRequirements(req); err == nil {
+		t.Error("expected unregistered scheme to be rejected")
+	}
+
+	if err := v2.RegisterScheme(channelScheme{}); err != nil {
+		t.Fatalf("RegisterScheme failed: %v", err)
+	}
+	defer v2.UnregisterScheme("channel")
+
+	if err := ValidatePaymentRequirements(req); err != nil {
+		t.Errorf("expected registered scheme to be valid, got %v", err)
+	}
+	missing := req
+	missing.Extra = nil
+	if err := ValidatePaymentRequirements(missing); err == nil {
+		t.Error("expected scheme validator to reject missing channel")
+	}
+
+	payload := v2.PaymentPayload{X402Version: 2, Accepted: req, Payload: map[string]interface{}{"channel": "a"}}
+	if err := ValidatePaymentPayload(payload); err != nil {
+		t.Errorf("expected payload to be valid, got %v", err)
+	}
+	payload.Payload = map[string]interface{}{"channel": "b"}
+	if err := ValidatePaymentPayload(payload); err == nil {
+		t.Error("expected scheme validator to reject wrong channel")
+	}
+}